@@ -19,6 +19,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -27,13 +28,16 @@ import (
 	"github.com/pipe-cd/pipe/pkg/app/ops/firestoreindexensurer"
 	"github.com/pipe-cd/pipe/pkg/app/ops/handler"
 	"github.com/pipe-cd/pipe/pkg/app/ops/insightcollector"
+	"github.com/pipe-cd/pipe/pkg/app/ops/metricscollector"
 	"github.com/pipe-cd/pipe/pkg/app/ops/mysqlensurer"
 	"github.com/pipe-cd/pipe/pkg/app/ops/orphancommandcleaner"
 	"github.com/pipe-cd/pipe/pkg/app/ops/pipedstatsbuilder"
+	"github.com/pipe-cd/pipe/pkg/cache/cachemetrics"
 	"github.com/pipe-cd/pipe/pkg/cache/rediscache"
 	"github.com/pipe-cd/pipe/pkg/cli"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/datastore"
+	"github.com/pipe-cd/pipe/pkg/datastore/datastoremetrics"
 	"github.com/pipe-cd/pipe/pkg/insight/insightstore"
 	"github.com/pipe-cd/pipe/pkg/model"
 	"github.com/pipe-cd/pipe/pkg/redis"
@@ -72,6 +76,9 @@ func NewOpsCommand() *cobra.Command {
 }
 
 func (s *ops) run(ctx context.Context, t cli.Telemetry) error {
+	// Register all metrics.
+	registerOpsMetrics()
+
 	group, ctx := errgroup.WithContext(ctx)
 
 	// Load control plane configuration from the specified file.
@@ -112,6 +119,9 @@ func (s *ops) run(ctx context.Context, t cli.Telemetry) error {
 		t.Logger.Error("failed to create datastore", zap.Error(err))
 		return err
 	}
+	if t.Flags.Metrics {
+		ds = datastoremetrics.NewInstrumentedDataStore(ds)
+	}
 	defer func() {
 		if err := ds.Close(); err != nil {
 			t.Logger.Error("failed to close datastore client", zap.Error(err))
@@ -142,6 +152,14 @@ func (s *ops) run(ctx context.Context, t cli.Telemetry) error {
 		return ic.Run(ctx)
 	})
 
+	// Start running metrics collector.
+	if t.Flags.Metrics {
+		mc := metricscollector.NewCollector(ds, t.Logger)
+		group.Go(func() error {
+			return mc.Run(ctx)
+		})
+	}
+
 	// Start running HTTP server.
 	{
 		handler := handler.NewHandler(s.httpPort, datastore.NewProjectStore(ds), insightstore.NewStore(fs), cfg.SharedSSOConfigs, s.gracePeriod, t.Logger)
@@ -167,7 +185,10 @@ func (s *ops) run(ctx context.Context, t cli.Telemetry) error {
 		admin.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte("ok"))
 		})
-		admin.Handle("/metrics", t.CustomMetricsHandlerFor(psb))
+		admin.Handle("/metrics", t.CustomMetricsHandlerFor(
+			psb,
+			cli.GathererMetricsBuilder{Gatherer: prometheus.DefaultGatherer},
+		))
 
 		group.Go(func() error {
 			return admin.Run(ctx)
@@ -185,6 +206,13 @@ func (s *ops) run(ctx context.Context, t cli.Telemetry) error {
 	return nil
 }
 
+func registerOpsMetrics() {
+	r := prometheus.DefaultRegisterer
+	cachemetrics.Register(r)
+	datastoremetrics.Register(r)
+	metricscollector.Register(r)
+}
+
 func ensureSQLDatabase(ctx context.Context, cfg *config.ControlPlaneSpec, logger *zap.Logger) error {
 	mysqlEnsurer, err := mysqlensurer.NewMySQLEnsurer(
 		cfg.Datastore.MySQLConfig.URL,
@@ -24,18 +24,27 @@ import (
 
 	"github.com/NYTimes/gziphandler"
 	jwtgo "github.com/dgrijalva/jwt-go"
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/pipe-cd/pipe/pkg/admin"
+	"github.com/pipe-cd/pipe/pkg/app/api/analysisresultstore"
+	"github.com/pipe-cd/pipe/pkg/app/api/apigateway"
 	"github.com/pipe-cd/pipe/pkg/app/api/apikeyverifier"
 	"github.com/pipe-cd/pipe/pkg/app/api/applicationlivestatestore"
 	"github.com/pipe-cd/pipe/pkg/app/api/authhandler"
 	"github.com/pipe-cd/pipe/pkg/app/api/commandoutputstore"
 	"github.com/pipe-cd/pipe/pkg/app/api/commandstore"
+	"github.com/pipe-cd/pipe/pkg/app/api/deploymentlockstore"
+	"github.com/pipe-cd/pipe/pkg/app/api/deploymentprovenancestore"
+	"github.com/pipe-cd/pipe/pkg/app/api/gitwebhookhandler"
 	"github.com/pipe-cd/pipe/pkg/app/api/grpcapi"
+	"github.com/pipe-cd/pipe/pkg/app/api/manifestdiffstore"
+	"github.com/pipe-cd/pipe/pkg/app/api/pipedcertissuer"
+	"github.com/pipe-cd/pipe/pkg/app/api/pipedconfigstore"
 	"github.com/pipe-cd/pipe/pkg/app/api/pipedverifier"
 	"github.com/pipe-cd/pipe/pkg/app/api/service/webservice"
 	"github.com/pipe-cd/pipe/pkg/app/api/stagelogstore"
@@ -45,6 +54,7 @@ import (
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/crypto"
 	"github.com/pipe-cd/pipe/pkg/datastore"
+	"github.com/pipe-cd/pipe/pkg/datastore/datastoremetrics"
 	"github.com/pipe-cd/pipe/pkg/datastore/firestore"
 	"github.com/pipe-cd/pipe/pkg/datastore/mysql"
 	"github.com/pipe-cd/pipe/pkg/filestore"
@@ -56,6 +66,7 @@ import (
 	"github.com/pipe-cd/pipe/pkg/model"
 	"github.com/pipe-cd/pipe/pkg/redis"
 	"github.com/pipe-cd/pipe/pkg/rpc"
+	"github.com/pipe-cd/pipe/pkg/rpc/rpcclient"
 	"github.com/pipe-cd/pipe/pkg/version"
 )
 
@@ -66,6 +77,12 @@ var (
 const (
 	defaultPipedStatHashKey = "HASHKEY:PIPED:STATS"
 	pipedStatTTL            = 2 * time.Minute
+	// The locks are extended periodically by the pipeds running the deployments.
+	deploymentLockTTL = 10 * time.Minute
+	// The service whose RPCs are exposed through the API gateway.
+	apiServiceName = "pipe.api.service.apiservice.APIService"
+	// The API gateway reaches the external apis only through the loopback interface.
+	apiGatewayHost = "127.0.0.1"
 )
 
 type httpHandler interface {
@@ -87,10 +104,16 @@ type server struct {
 	keyFile        string
 	insecureCookie bool
 
+	pipedClientCAFile    string
+	pipedClientCAKeyFile string
+	pipedCertTTL         time.Duration
+
 	encryptionKeyFile string
 	configFile        string
 
 	enableGRPCReflection bool
+	enableAPIGateway     bool
+	apiGatewayPort       int
 }
 
 // NewServerCommand creates a new cobra command for executing api server.
@@ -104,6 +127,9 @@ func NewServerCommand() *cobra.Command {
 		staticDir:    "pkg/app/web/public_files",
 		cacheAddress: "cache:6379",
 		gracePeriod:  30 * time.Second,
+		pipedCertTTL: 30 * 24 * time.Hour,
+
+		apiGatewayPort: 9084,
 	}
 	cmd := &cobra.Command{
 		Use:   "server",
@@ -125,10 +151,16 @@ func NewServerCommand() *cobra.Command {
 	cmd.Flags().StringVar(&s.keyFile, "key-file", s.keyFile, "The path to the TLS key file.")
 	cmd.Flags().BoolVar(&s.insecureCookie, "insecure-cookie", s.insecureCookie, "Allow cookie to be sent over an unsecured HTTP connection.")
 
+	cmd.Flags().StringVar(&s.pipedClientCAFile, "piped-client-ca-file", s.pipedClientCAFile, "The path to the CA certificate file used to verify the client certificates of pipeds. Setting this requires all pipeds to connect with mutual TLS.")
+	cmd.Flags().StringVar(&s.pipedClientCAKeyFile, "piped-client-ca-key-file", s.pipedClientCAKeyFile, "The path to the key file of the CA used to issue the renewed client certificates of pipeds.")
+	cmd.Flags().DurationVar(&s.pipedCertTTL, "piped-cert-ttl", s.pipedCertTTL, "How long the renewed client certificates of pipeds are valid.")
+
 	cmd.Flags().StringVar(&s.encryptionKeyFile, "encryption-key-file", s.encryptionKeyFile, "The path to file containing a random string of bits used to encrypt sensitive data.")
 	cmd.MarkFlagRequired("encryption-key-file")
 	cmd.Flags().StringVar(&s.configFile, "config-file", s.configFile, "The path to the configuration file.")
 	cmd.MarkFlagRequired("config-file")
+	cmd.Flags().BoolVar(&s.enableAPIGateway, "enable-api-gateway", s.enableAPIGateway, "Whether to serve the external apis as JSON over HTTP at /api/v1/ of the http server or not.")
+	cmd.Flags().IntVar(&s.apiGatewayPort, "api-gateway-port", s.apiGatewayPort, "The port number on the loopback interface used to forward the requests from the api gateway to the external apis.")
 
 	// For debugging early in development
 	cmd.Flags().BoolVar(&s.enableGRPCReflection, "enable-grpc-reflection", s.enableGRPCReflection, "Whether to enable the reflection service or not.")
@@ -158,6 +190,9 @@ func (s *server) run(ctx context.Context, t cli.Telemetry) error {
 		t.Logger.Error("failed to create datastore", zap.Error(err))
 		return err
 	}
+	if t.Flags.Metrics {
+		ds = datastoremetrics.NewInstrumentedDataStore(ds)
+	}
 	defer func() {
 		if err := ds.Close(); err != nil {
 			t.Logger.Error("failed to close datastore client", zap.Error(err))
@@ -190,7 +225,21 @@ func (s *server) run(ctx context.Context, t cli.Telemetry) error {
 	cmds := commandstore.NewStore(ds, cache, t.Logger)
 	is := insightstore.NewStore(fs)
 	cmdOutputStore := commandoutputstore.NewStore(fs, t.Logger)
+	manifestDiffStore := manifestdiffstore.NewStore(fs, t.Logger)
+	analysisResultStore := analysisresultstore.NewStore(fs, t.Logger)
+	deploymentProvenanceStore := deploymentprovenancestore.NewStore(fs, t.Logger)
+	pipedConfigStore := pipedconfigstore.NewStore(fs, t.Logger)
 	statCache := rediscache.NewTTLHashCache(rd, pipedStatTTL, defaultPipedStatHashKey)
+	deploymentLockStore := deploymentlockstore.NewStore(rd, deploymentLockTTL)
+
+	var pipedCertIssuer *pipedcertissuer.Issuer
+	if s.pipedClientCAFile != "" && s.pipedClientCAKeyFile != "" {
+		pipedCertIssuer, err = pipedcertissuer.NewIssuer(s.pipedClientCAFile, s.pipedClientCAKeyFile, s.pipedCertTTL)
+		if err != nil {
+			t.Logger.Error("failed to create piped certificate issuer", zap.Error(err))
+			return err
+		}
+	}
 
 	// Start a gRPC server for handling PipedAPI requests.
 	{
@@ -202,19 +251,31 @@ func (s *server) run(ctx context.Context, t cli.Telemetry) error {
 				datastore.NewPipedStore(ds),
 				t.Logger,
 			)
-			service = grpcapi.NewPipedAPI(ctx, ds, sls, alss, cmds, statCache, cmdOutputStore, t.Logger)
+			service = grpcapi.NewPipedAPI(ctx, ds, sls, alss, cmds, statCache, cmdOutputStore, manifestDiffStore, analysisResultStore, deploymentProvenanceStore, pipedConfigStore, deploymentLockStore, pipedCertIssuer, cfg.Quotas, t.Logger)
 			opts    = []rpc.Option{
 				rpc.WithPort(s.pipedAPIPort),
 				rpc.WithGracePeriod(s.gracePeriod),
 				rpc.WithLogger(t.Logger),
 				rpc.WithLogUnaryInterceptor(t.Logger),
 				rpc.WithPipedTokenAuthUnaryInterceptor(verifier, t.Logger),
+				rpc.WithPipedTokenAuthStreamInterceptor(verifier, t.Logger),
 				rpc.WithRequestValidationUnaryInterceptor(),
 			}
 		)
 		if s.tls {
 			opts = append(opts, rpc.WithTLS(s.certFile, s.keyFile))
 		}
+		if s.pipedClientCAFile != "" {
+			if !s.tls {
+				t.Logger.Error("piped-client-ca-file requires tls to be enabled")
+				return fmt.Errorf("piped-client-ca-file requires tls to be enabled")
+			}
+			opts = append(opts,
+				rpc.WithClientCA(s.pipedClientCAFile),
+				rpc.WithPipedCertificateAuthUnaryInterceptor(t.Logger),
+				rpc.WithPipedCertificateAuthStreamInterceptor(t.Logger),
+			)
+		}
 		if s.enableGRPCReflection {
 			opts = append(opts, rpc.WithGRPCReflection())
 		}
@@ -236,9 +297,8 @@ func (s *server) run(ctx context.Context, t cli.Telemetry) error {
 				datastore.NewAPIKeyStore(ds),
 				t.Logger,
 			)
-			service = grpcapi.NewAPI(ds, cmds, cmdOutputStore, cfg.Address, t.Logger)
+			service = grpcapi.NewAPI(ds, alss, cmds, cmdOutputStore, manifestDiffStore, deploymentProvenanceStore, cfg.Quotas, cfg.EventDeduplicationWindow.Duration(), cfg.Address, t.Logger)
 			opts    = []rpc.Option{
-				rpc.WithPort(s.apiPort),
 				rpc.WithGracePeriod(s.gracePeriod),
 				rpc.WithLogger(t.Logger),
 				rpc.WithLogUnaryInterceptor(t.Logger),
@@ -246,17 +306,35 @@ func (s *server) run(ctx context.Context, t cli.Telemetry) error {
 				rpc.WithRequestValidationUnaryInterceptor(),
 			}
 		)
-		if s.tls {
-			opts = append(opts, rpc.WithTLS(s.certFile, s.keyFile))
+		if l := cfg.RateLimit.APIKey; l.Enabled() {
+			opts = append(opts, rpc.WithAPIKeyRateLimitUnaryInterceptor(l.RequestsPerSecond, l.Burst, t.Logger))
+		}
+		if l := cfg.RateLimit.IP; l.Enabled() {
+			opts = append(opts, rpc.WithIPRateLimitUnaryInterceptor(l.RequestsPerSecond, l.Burst, cfg.RateLimit.TrustedProxies, t.Logger))
 		}
 		if t.Flags.Metrics {
 			opts = append(opts, rpc.WithPrometheusUnaryInterceptor())
 		}
 
-		server := rpc.NewServer(service, opts...)
+		serverOpts := append([]rpc.Option{rpc.WithPort(s.apiPort)}, opts...)
+		if s.tls {
+			serverOpts = append(serverOpts, rpc.WithTLS(s.certFile, s.keyFile))
+		}
+		server := rpc.NewServer(service, serverOpts...)
 		group.Go(func() error {
 			return server.Run(ctx)
 		})
+
+		// The API gateway forwards the requests to this server over plaintext loopback
+		// so that they go through the same authentication, rate limits and validation
+		// without requiring the TLS certificate to be valid for localhost.
+		if s.enableAPIGateway {
+			gatewayOpts := append([]rpc.Option{rpc.WithHost(apiGatewayHost), rpc.WithPort(s.apiGatewayPort)}, opts...)
+			server := rpc.NewServer(service, gatewayOpts...)
+			group.Go(func() error {
+				return server.Run(ctx)
+			})
+		}
 	}
 
 	encryptDecrypter, err := crypto.NewAESEncryptDecrypter(s.encryptionKeyFile)
@@ -273,7 +351,7 @@ func (s *server) run(ctx context.Context, t cli.Telemetry) error {
 			return err
 		}
 
-		service := grpcapi.NewWebAPI(ctx, ds, fs, sls, alss, cmds, is, rd, cfg.ProjectMap(), encryptDecrypter, t.Logger)
+		service := grpcapi.NewWebAPI(ctx, ds, fs, sls, alss, cmds, is, manifestDiffStore, analysisResultStore, pipedConfigStore, rd, cfg.ProjectMap(), cfg.Quotas, encryptDecrypter, t.Logger)
 		opts := []rpc.Option{
 			rpc.WithPort(s.webAPIPort),
 			rpc.WithGracePeriod(s.gracePeriod),
@@ -326,6 +404,30 @@ func (s *server) run(ctx context.Context, t cli.Telemetry) error {
 				t.Logger,
 			),
 		}
+		if cfg.GitWebhook.Secret != "" {
+			handlers = append(handlers, gitwebhookhandler.NewHandler(
+				cfg.GitWebhook.Secret,
+				datastore.NewPipedStore(ds),
+				cmds,
+				t.Logger,
+			))
+		}
+
+		if s.enableAPIGateway {
+			conn, err := rpcclient.DialContext(ctx, fmt.Sprintf("%s:%d", apiGatewayHost, s.apiGatewayPort), rpcclient.WithInsecure())
+			if err != nil {
+				t.Logger.Error("failed to connect to the api server", zap.Error(err))
+				return err
+			}
+			defer conn.Close()
+
+			h, err := apigateway.NewHandler(conn, apiServiceName, cfg.RateLimit.TrustedProxies, t.Logger)
+			if err != nil {
+				t.Logger.Error("failed to create api gateway", zap.Error(err))
+				return err
+			}
+			handlers = append(handlers, h)
+		}
 
 		for _, h := range handlers {
 			h.Register(mux.HandleFunc)
@@ -502,4 +604,9 @@ func createFilestore(ctx context.Context, cfg *config.ControlPlaneSpec, logger *
 func registerMetrics() {
 	r := prometheus.DefaultRegisterer
 	cachemetrics.Register(r)
+	datastoremetrics.Register(r)
+	rpc.RegisterMetrics(r)
+
+	// Observe the handling latency of each RPC in addition to the handled counts.
+	grpc_prometheus.EnableHandlingTimeHistogram()
 }
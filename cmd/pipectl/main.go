@@ -19,6 +19,7 @@ import (
 	"os"
 
 	"github.com/pipe-cd/pipe/pkg/app/pipectl/cmd/application"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/cmd/dashboard"
 	"github.com/pipe-cd/pipe/pkg/app/pipectl/cmd/deployment"
 	"github.com/pipe-cd/pipe/pkg/app/pipectl/cmd/event"
 	"github.com/pipe-cd/pipe/pkg/app/pipectl/cmd/piped"
@@ -38,6 +39,7 @@ func main() {
 		event.NewCommand(),
 		planpreview.NewCommand(),
 		piped.NewCommand(),
+		dashboard.NewCommand(),
 	)
 
 	if err := app.Run(); err != nil {
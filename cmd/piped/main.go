@@ -28,6 +28,7 @@ func main() {
 	)
 	app.AddCommands(
 		piped.NewCommand(),
+		piped.NewDoctorCommand(),
 	)
 	if err := app.Run(); err != nil {
 		log.Fatal(err)
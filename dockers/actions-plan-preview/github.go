@@ -33,7 +33,9 @@ type githubEvent struct {
 	BaseBranch  string
 	SenderLogin string
 	IsComment   bool
+	CommentID   int64
 	CommentURL  string
+	CommentBody string
 }
 
 // parsePullRequestEvent uses the given environment variables
@@ -99,7 +101,9 @@ func parseGitHubEvent(ctx context.Context, client *github.Client) (*githubEvent,
 			BaseBranch:  pr.Base.GetRef(),
 			SenderLogin: e.Sender.GetLogin(),
 			IsComment:   true,
+			CommentID:   e.Comment.GetID(),
 			CommentURL:  e.Comment.GetHTMLURL(),
+			CommentBody: e.Comment.GetBody(),
 		}, nil
 
 	default:
@@ -118,3 +122,8 @@ func getPullRequest(ctx context.Context, client *github.Client, owner, repo stri
 	pr, _, err := client.PullRequests.Get(ctx, owner, repo, prNum)
 	return pr, err
 }
+
+func addCommentReaction(ctx context.Context, client *github.Client, owner, repo string, commentID int64, content string) error {
+	_, _, err := client.Reactions.CreateIssueCommentReaction(ctx, owner, repo, commentID, content)
+	return err
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/google/go-github/v36/github"
+)
+
+const (
+	noChangesLabel          = "pipecd/no-changes"
+	destructiveChangesLabel = "pipecd/destructive-changes"
+	planErrorLabel          = "pipecd/plan-error"
+)
+
+// All labels managed by this action.
+// The ones no longer matching the latest result are removed from the pull request.
+var managedLabels = []string{
+	noChangesLabel,
+	destructiveChangesLabel,
+	planErrorLabel,
+}
+
+// Matches the deletion part of plan summaries, for example:
+// - KUBERNETES: "1 added manifests, 0 changed manifests, 2 deleted manifests"
+// - TERRAFORM: "1 to add, 0 to change, 2 to destroy"
+var destructiveSummaryRegex = regexp.MustCompile(`(\d+) (?:deleted manifests|to destroy)`)
+
+// decideLabels returns the list of labels that should be applied
+// to the pull request based on the given plan-preview result.
+// A nil result means plan-preview could not be executed at all.
+func decideLabels(r *PlanPreviewResult) []string {
+	if r == nil {
+		return []string{planErrorLabel}
+	}
+
+	var labels []string
+	if r.NoChange() {
+		labels = append(labels, noChangesLabel)
+	}
+	if r.HasError() {
+		labels = append(labels, planErrorLabel)
+	}
+	for _, app := range r.Applications {
+		if isDestructiveSummary(app.PlanSummary) {
+			labels = append(labels, destructiveChangesLabel)
+			break
+		}
+	}
+	return labels
+}
+
+func isDestructiveSummary(summary string) bool {
+	for _, m := range destructiveSummaryRegex.FindAllStringSubmatch(summary, -1) {
+		if n, err := strconv.Atoi(m[1]); err == nil && n > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// updateLabels adds the given labels to the pull request
+// and removes the other managed labels that are no longer applicable.
+func updateLabels(ctx context.Context, client *github.Client, owner, repo string, prNum int, labels []string) error {
+	desired := make(map[string]struct{}, len(labels))
+	for _, l := range labels {
+		desired[l] = struct{}{}
+	}
+
+	current, _, err := client.Issues.ListLabelsByIssue(ctx, owner, repo, prNum, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return fmt.Errorf("failed to list labels of pull request: %v", err)
+	}
+	existing := make(map[string]struct{}, len(current))
+	for _, l := range current {
+		existing[l.GetName()] = struct{}{}
+	}
+
+	for _, l := range managedLabels {
+		if _, ok := desired[l]; ok {
+			continue
+		}
+		if _, ok := existing[l]; !ok {
+			continue
+		}
+		resp, err := client.Issues.RemoveLabelForIssue(ctx, owner, repo, prNum, l)
+		if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+			return fmt.Errorf("failed to remove label %s from pull request: %v", l, err)
+		}
+	}
+
+	var adds []string
+	for _, l := range labels {
+		if _, ok := existing[l]; !ok {
+			adds = append(adds, l)
+		}
+	}
+	if len(adds) == 0 {
+		return nil
+	}
+	if _, _, err := client.Issues.AddLabelsToIssue(ctx, owner, repo, prNum, adds); err != nil {
+		return fmt.Errorf("failed to add labels to pull request: %v", err)
+	}
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -29,7 +30,12 @@ import (
 )
 
 const (
-	defaultTimeout = 5 * time.Minute
+	defaultTimeout        = 5 * time.Minute
+	defaultTriggerKeyword = "/pipecd plan-preview"
+
+	// The reaction added to the triggering comment to acknowledge
+	// that plan-preview has been started.
+	acknowledgeReaction = "eyes"
 )
 
 func main() {
@@ -56,6 +62,28 @@ func main() {
 	}
 	log.Printf("Successfully parsed GitHub event\n\tbase-branch %s\n\thead-branch %s\n\thead-commit %s\n", event.BaseBranch, event.HeadBranch, event.HeadCommit)
 
+	if event.IsComment {
+		if !strings.Contains(event.CommentBody, args.TriggerKeyword) {
+			log.Printf("Skipped running plan-preview because the comment does not contain the trigger keyword %q\n", args.TriggerKeyword)
+			return
+		}
+
+		allowed, err := isAllowedToTrigger(ctx, ghClient, event, args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !allowed {
+			log.Printf("Skipped running plan-preview because user %s is not allowed to trigger it\n", event.SenderLogin)
+			return
+		}
+
+		// Acknowledge the triggering comment before the result is ready.
+		// This is best-effort, so a failure does not stop plan-preview.
+		if err := addCommentReaction(ctx, ghClient, event.Owner, event.Repo, event.CommentID, acknowledgeReaction); err != nil {
+			log.Printf("Failed to add a reaction to the triggering comment: %v\n", err)
+		}
+	}
+
 	result, err := retrievePlanPreview(
 		ctx,
 		event.RepoRemote,
@@ -67,10 +95,35 @@ func main() {
 		args.Timeout,
 	)
 	if err != nil {
+		if args.OutputFile != "" {
+			if err := writeOutput(makeErrorOutput(event, err), args.OutputFile); err != nil {
+				log.Printf("Failed to write plan-preview error to %s: %v\n", args.OutputFile, err)
+			}
+		}
+		if args.AddLabels {
+			if err := updateLabels(ctx, ghClient, event.Owner, event.Repo, event.PRNumber, decideLabels(nil)); err != nil {
+				log.Printf("Failed to update labels of pull request: %v\n", err)
+			}
+		}
 		log.Fatal(err)
 	}
 	log.Println("Successfully retrieved plan-preview result")
 
+	if args.OutputFile != "" {
+		if err := writeOutput(makeOutput(event, result), args.OutputFile); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Successfully wrote plan-preview result to %s\n", args.OutputFile)
+	}
+
+	if args.AddLabels {
+		labels := decideLabels(result)
+		if err := updateLabels(ctx, ghClient, event.Owner, event.Repo, event.PRNumber, labels); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Successfully updated labels of pull request %v\n", labels)
+	}
+
 	body := makeCommentBody(event, result)
 	comment, err := sendComment(
 		ctx,
@@ -88,10 +141,21 @@ func main() {
 }
 
 type arguments struct {
-	Address string
-	APIKey  string
-	Token   string
-	Timeout time.Duration
+	Address        string
+	APIKey         string
+	Token          string
+	Timeout        time.Duration
+	TriggerKeyword string
+	// List of teams in "org/team-slug" format whose members
+	// are allowed to trigger plan-preview by commenting.
+	AllowedTeams []string
+	// Whether the code owners defined in the CODEOWNERS file
+	// are allowed to trigger plan-preview by commenting.
+	AllowCodeOwners bool
+	// Whether to label the pull request based on the plan-preview result.
+	AddLabels bool
+	// The path to the file where the plan-preview result is written as JSON.
+	OutputFile string
 }
 
 func parseArgs(args []string) (arguments, error) {
@@ -115,6 +179,28 @@ func parseArgs(args []string) (arguments, error) {
 				return arguments{}, err
 			}
 			out.Timeout = d
+		case "trigger-keyword":
+			out.TriggerKeyword = strings.TrimSpace(ps[1])
+		case "allowed-teams":
+			for _, t := range strings.Split(ps[1], ",") {
+				if t = strings.TrimSpace(t); t != "" {
+					out.AllowedTeams = append(out.AllowedTeams, t)
+				}
+			}
+		case "allow-codeowners":
+			v, err := strconv.ParseBool(ps[1])
+			if err != nil {
+				return arguments{}, fmt.Errorf("invalid allow-codeowners argument: %v", err)
+			}
+			out.AllowCodeOwners = v
+		case "add-labels":
+			v, err := strconv.ParseBool(ps[1])
+			if err != nil {
+				return arguments{}, fmt.Errorf("invalid add-labels argument: %v", err)
+			}
+			out.AddLabels = v
+		case "output-file":
+			out.OutputFile = ps[1]
 		}
 	}
 
@@ -130,6 +216,14 @@ func parseArgs(args []string) (arguments, error) {
 	if out.Timeout == 0 {
 		out.Timeout = defaultTimeout
 	}
+	if out.TriggerKeyword == "" {
+		out.TriggerKeyword = defaultTriggerKeyword
+	}
+	for _, t := range out.AllowedTeams {
+		if ps := strings.Split(t, "/"); len(ps) != 2 || ps[0] == "" || ps[1] == "" {
+			return out, fmt.Errorf("invalid team %q in allowed-teams argument, it must be in org/team-slug format", t)
+		}
+	}
 
 	return out, nil
 }
@@ -0,0 +1,219 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+)
+
+// maxResultOutputSize is the maximum size of the "result" step output.
+// GitHub Actions limits the total size of the outputs of a job to 1MB,
+// so a result larger than this is shortened before being exposed.
+const maxResultOutputSize = 512 * 1024
+
+// PlanPreviewOutput is the machine-readable form of the plan-preview result
+// written by this action so that downstream workflow steps can use it.
+type PlanPreviewOutput struct {
+	HeadCommit            string                     `json:"head_commit"`
+	NoChange              bool                       `json:"no_change"`
+	HasError              bool                       `json:"has_error"`
+	HasDestructiveChanges bool                       `json:"has_destructive_changes"`
+	Error                 string                     `json:"error,omitempty"`
+	Applications          []ApplicationOutput        `json:"applications"`
+	FailureApplications   []FailureApplicationOutput `json:"failure_applications"`
+	FailurePipeds         []FailurePipedOutput       `json:"failure_pipeds"`
+}
+
+type ApplicationInfoOutput struct {
+	ApplicationID        string `json:"application_id"`
+	ApplicationName      string `json:"application_name"`
+	ApplicationURL       string `json:"application_url"`
+	EnvID                string `json:"env_id"`
+	EnvName              string `json:"env_name"`
+	EnvURL               string `json:"env_url"`
+	ApplicationKind      string `json:"application_kind"`
+	ApplicationDirectory string `json:"application_directory"`
+}
+
+type ApplicationOutput struct {
+	ApplicationInfoOutput
+	SyncStrategy string `json:"sync_strategy"`
+	PlanSummary  string `json:"plan_summary"`
+	PlanDetails  string `json:"plan_details,omitempty"`
+}
+
+type FailureApplicationOutput struct {
+	ApplicationInfoOutput
+	Reason      string `json:"reason"`
+	PlanDetails string `json:"plan_details,omitempty"`
+}
+
+type FailurePipedOutput struct {
+	PipedID  string `json:"piped_id"`
+	PipedURL string `json:"piped_url"`
+	Reason   string `json:"reason"`
+}
+
+func makeOutput(event *githubEvent, r *PlanPreviewResult) *PlanPreviewOutput {
+	out := &PlanPreviewOutput{
+		HeadCommit:          event.HeadCommit,
+		NoChange:            r.NoChange(),
+		HasError:            r.HasError(),
+		Applications:        make([]ApplicationOutput, 0, len(r.Applications)),
+		FailureApplications: make([]FailureApplicationOutput, 0, len(r.FailureApplications)),
+		FailurePipeds:       make([]FailurePipedOutput, 0, len(r.FailurePipeds)),
+	}
+	for _, app := range r.Applications {
+		if isDestructiveSummary(app.PlanSummary) {
+			out.HasDestructiveChanges = true
+		}
+		out.Applications = append(out.Applications, ApplicationOutput{
+			ApplicationInfoOutput: makeApplicationInfoOutput(app.ApplicationInfo),
+			SyncStrategy:          app.SyncStrategy,
+			PlanSummary:           app.PlanSummary,
+			PlanDetails:           app.PlanDetails,
+		})
+	}
+	for _, app := range r.FailureApplications {
+		out.FailureApplications = append(out.FailureApplications, FailureApplicationOutput{
+			ApplicationInfoOutput: makeApplicationInfoOutput(app.ApplicationInfo),
+			Reason:                app.Reason,
+			PlanDetails:           app.PlanDetails,
+		})
+	}
+	for _, p := range r.FailurePipeds {
+		out.FailurePipeds = append(out.FailurePipeds, FailurePipedOutput{
+			PipedID:  p.PipedID,
+			PipedURL: p.PipedURL,
+			Reason:   p.Reason,
+		})
+	}
+	return out
+}
+
+// makeErrorOutput makes the output for the case plan-preview could not be run at all
+// so that downstream workflow steps can still read a result.
+func makeErrorOutput(event *githubEvent, err error) *PlanPreviewOutput {
+	return &PlanPreviewOutput{
+		HeadCommit:          event.HeadCommit,
+		HasError:            true,
+		Error:               err.Error(),
+		Applications:        []ApplicationOutput{},
+		FailureApplications: []FailureApplicationOutput{},
+		FailurePipeds:       []FailurePipedOutput{},
+	}
+}
+
+func makeApplicationInfoOutput(info ApplicationInfo) ApplicationInfoOutput {
+	return ApplicationInfoOutput{
+		ApplicationID:        info.ApplicationID,
+		ApplicationName:      info.ApplicationName,
+		ApplicationURL:       info.ApplicationURL,
+		EnvID:                info.EnvID,
+		EnvName:              info.EnvName,
+		EnvURL:               info.EnvURL,
+		ApplicationKind:      info.ApplicationKind,
+		ApplicationDirectory: info.ApplicationDirectory,
+	}
+}
+
+// withoutPlanDetails returns a copy of the output whose plan details are dropped.
+func (o PlanPreviewOutput) withoutPlanDetails() *PlanPreviewOutput {
+	apps := make([]ApplicationOutput, 0, len(o.Applications))
+	for _, app := range o.Applications {
+		app.PlanDetails = ""
+		apps = append(apps, app)
+	}
+	failures := make([]FailureApplicationOutput, 0, len(o.FailureApplications))
+	for _, app := range o.FailureApplications {
+		app.PlanDetails = ""
+		failures = append(failures, app)
+	}
+	o.Applications = apps
+	o.FailureApplications = failures
+	return &o
+}
+
+// writeOutput writes the given output as JSON into the specified file
+// and exposes it as outputs of the current GitHub Actions step.
+func writeOutput(out *PlanPreviewOutput, path string) error {
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal output (%w)", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write output file (%w)", err)
+	}
+
+	// GITHUB_OUTPUT is not set when running outside of GitHub Actions.
+	outputPath := os.Getenv("GITHUB_OUTPUT")
+	if outputPath == "" {
+		return nil
+	}
+	f, err := os.OpenFile(outputPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open GitHub Actions output file (%w)", err)
+	}
+	defer f.Close()
+
+	result, err := makeResultOutput(out)
+	if err != nil {
+		return err
+	}
+	outputs := [][2]string{
+		{"result-file", path},
+		{"result", result},
+		{"no-change", strconv.FormatBool(out.NoChange)},
+		{"has-error", strconv.FormatBool(out.HasError)},
+		{"has-destructive-changes", strconv.FormatBool(out.HasDestructiveChanges)},
+	}
+	for _, o := range outputs {
+		// The compact JSON never contains a newline, so the single-line format is safe.
+		if _, err := fmt.Fprintf(f, "%s=%s\n", o[0], o[1]); err != nil {
+			return fmt.Errorf("failed to write GitHub Actions output (%w)", err)
+		}
+	}
+	return nil
+}
+
+// makeResultOutput returns the compact JSON to be exposed as the "result" step output.
+// The plan details are dropped when the result is too large, and an empty string is
+// returned when it is still too large. The full result is always available in the file.
+func makeResultOutput(out *PlanPreviewOutput) (string, error) {
+	compact, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal output (%w)", err)
+	}
+	if len(compact) <= maxResultOutputSize {
+		return string(compact), nil
+	}
+
+	log.Printf("Dropped plan details from the result output since its size %d exceeds the limit %d\n", len(compact), maxResultOutputSize)
+	compact, err = json.Marshal(out.withoutPlanDetails())
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal output (%w)", err)
+	}
+	if len(compact) <= maxResultOutputSize {
+		return string(compact), nil
+	}
+
+	log.Printf("Left the result output empty since its size %d exceeds the limit %d even without plan details\n", len(compact), maxResultOutputSize)
+	return "", nil
+}
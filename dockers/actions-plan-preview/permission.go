@@ -0,0 +1,152 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v36/github"
+)
+
+// The locations where GitHub looks for the CODEOWNERS file, in order.
+// https://docs.github.com/en/repositories/managing-your-repositorys-settings-and-features/customizing-your-repository/about-code-owners#codeowners-file-location
+var codeOwnersFilePaths = []string{
+	".github/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+}
+
+// isAllowedToTrigger checks whether the sender of the given comment event
+// is allowed to trigger plan-preview.
+// When neither allowed-teams nor allow-codeowners was specified, everyone is allowed.
+func isAllowedToTrigger(ctx context.Context, client *github.Client, event *githubEvent, args arguments) (bool, error) {
+	if len(args.AllowedTeams) == 0 && !args.AllowCodeOwners {
+		return true, nil
+	}
+
+	for _, team := range args.AllowedTeams {
+		ok, err := isTeamMember(ctx, client, team, event.SenderLogin)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	if !args.AllowCodeOwners {
+		return false, nil
+	}
+
+	owners, err := getCodeOwners(ctx, client, event.Owner, event.Repo, event.BaseBranch)
+	if err != nil {
+		return false, err
+	}
+	for _, owner := range owners {
+		owner = strings.TrimPrefix(owner, "@")
+		// The owner is a team in "org/team-slug" format.
+		if strings.Contains(owner, "/") {
+			ok, err := isTeamMember(ctx, client, owner, event.SenderLogin)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+			continue
+		}
+		if strings.EqualFold(owner, event.SenderLogin) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// isTeamMember checks whether the given user is an active member of
+// the specified team in "org/team-slug" format.
+func isTeamMember(ctx context.Context, client *github.Client, team, user string) (bool, error) {
+	ps := strings.SplitN(team, "/", 2)
+	if len(ps) != 2 {
+		return false, fmt.Errorf("invalid team %q, it must be in org/team-slug format", team)
+	}
+
+	m, resp, err := client.Teams.GetTeamMembershipBySlug(ctx, ps[0], ps[1], user)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get membership of user %s in team %s: %v", user, team, err)
+	}
+	return m.GetState() == "active", nil
+}
+
+// getCodeOwners returns the list of all owners defined in the CODEOWNERS file
+// of the given repository at the given ref.
+// An empty list is returned if the repository does not have that file.
+func getCodeOwners(ctx context.Context, client *github.Client, owner, repo, ref string) ([]string, error) {
+	opts := &github.RepositoryContentGetOptions{Ref: ref}
+	for _, path := range codeOwnersFilePaths {
+		file, _, resp, err := client.Repositories.GetContents(ctx, owner, repo, path, opts)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get %s file: %v", path, err)
+		}
+		if file == nil {
+			continue
+		}
+		content, err := file.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s file: %v", path, err)
+		}
+		return parseCodeOwners(content), nil
+	}
+	return nil, nil
+}
+
+// parseCodeOwners returns the unique user and team owners listed in the given CODEOWNERS content.
+// Email owners are ignored since they cannot be mapped to the comment sender.
+func parseCodeOwners(content string) []string {
+	var (
+		owners []string
+		seen   = make(map[string]struct{})
+	)
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		// The first field is the file pattern.
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "@") {
+				continue
+			}
+			if _, ok := seen[f]; ok {
+				continue
+			}
+			seen[f] = struct{}{}
+			owners = append(owners, f)
+		}
+	}
+	return owners
+}
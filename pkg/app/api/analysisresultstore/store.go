@@ -0,0 +1,84 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysisresultstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/filestore"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+)
+
+// Store persists the detailed data collected by piped while running an ANALYSIS stage.
+type Store interface {
+	Get(ctx context.Context, deploymentID, stageID string) (*model.AnalysisResult, error)
+	Put(ctx context.Context, result *model.AnalysisResult) error
+}
+
+type store struct {
+	backend filestore.Store
+	logger  *zap.Logger
+}
+
+func NewStore(fs filestore.Store, logger *zap.Logger) Store {
+	return &store{
+		backend: fs,
+		logger:  logger.Named("analysis-result-store"),
+	}
+}
+
+func (s *store) Get(ctx context.Context, deploymentID, stageID string) (*model.AnalysisResult, error) {
+	path := dataPath(deploymentID, stageID)
+	obj, err := s.backend.GetObject(ctx, path)
+	if err != nil {
+		if err == filestore.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		s.logger.Error("failed to get analysis result from filestore",
+			zap.String("deployment", deploymentID),
+			zap.String("stage", stageID),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	var result model.AnalysisResult
+	if err := json.Unmarshal(obj.Content, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal analysis result: %w", err)
+	}
+	return &result, nil
+}
+
+func (s *store) Put(ctx context.Context, result *model.AnalysisResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal analysis result: %w", err)
+	}
+	path := dataPath(result.DeploymentId, result.StageId)
+	return s.backend.PutObject(ctx, path, data)
+}
+
+func dataPath(deploymentID, stageID string) string {
+	return fmt.Sprintf("analysis-result/%s/%s.json", deploymentID, stageID)
+}
@@ -0,0 +1,239 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package apigateway provides a http handler exposing the RPCs of a gRPC service
+// as JSON over HTTP endpoints along with an OpenAPI specification generated
+// from their proto definitions. It lets users who have no gRPC client at hand
+// (curl, internal portals, low-code tools...) integrate with the public API.
+package apigateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/pipe-cd/pipe/pkg/rpc"
+)
+
+const (
+	// pathPrefix is the prefix of the paths of all RPC endpoints.
+	// Each RPC is served at pathPrefix + its method name, e.g. /api/v1/GetApplication.
+	pathPrefix = "/api/v1/"
+	// specPath is the path where the OpenAPI specification is served.
+	specPath = pathPrefix + "openapi.json"
+
+	maxRequestSize = 5 << 20
+)
+
+type invoker interface {
+	Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error
+}
+
+// errorResponse is the body returned when a request failed.
+type errorResponse struct {
+	// The name of the gRPC status code, e.g. NotFound.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type method struct {
+	// The full gRPC method name, e.g. /pipe.api.service.apiservice.APIService/GetApplication.
+	fullName string
+	input    protoreflect.MessageType
+	output   protoreflect.MessageType
+}
+
+// Handler translates JSON over HTTP requests into gRPC calls.
+type Handler struct {
+	conn           invoker
+	methods        map[string]method
+	spec           []byte
+	trustedProxies []*net.IPNet
+	logger         *zap.Logger
+}
+
+// NewHandler returns a handler serving all unary RPCs of the given service
+// by forwarding them through the given gRPC connection.
+// The service must be registered in the global proto registry,
+// which is done by importing its generated Go package.
+// X-Forwarded-For header is taken into account only when the request was sent
+// from the loopback address or one of the given trusted proxies.
+func NewHandler(conn invoker, service protoreflect.FullName, trustedProxies []string, logger *zap.Logger) (*Handler, error) {
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(service)
+	if err != nil {
+		return nil, fmt.Errorf("service %s was not found: %w", service, err)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+
+	methods := make(map[string]method, sd.Methods().Len())
+	for i := 0; i < sd.Methods().Len(); i++ {
+		md := sd.Methods().Get(i)
+		if md.IsStreamingClient() || md.IsStreamingServer() {
+			continue
+		}
+		input, err := protoregistry.GlobalTypes.FindMessageByName(md.Input().FullName())
+		if err != nil {
+			return nil, fmt.Errorf("input type of %s was not found: %w", md.FullName(), err)
+		}
+		output, err := protoregistry.GlobalTypes.FindMessageByName(md.Output().FullName())
+		if err != nil {
+			return nil, fmt.Errorf("output type of %s was not found: %w", md.FullName(), err)
+		}
+		methods[string(md.Name())] = method{
+			fullName: fmt.Sprintf("/%s/%s", sd.FullName(), md.Name()),
+			input:    input,
+			output:   output,
+		}
+	}
+
+	spec, err := generateSpec(sd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OpenAPI specification: %w", err)
+	}
+
+	return &Handler{
+		conn:           conn,
+		methods:        methods,
+		spec:           spec,
+		trustedProxies: rpc.ParseTrustedProxies(trustedProxies),
+		logger:         logger.Named("api-gateway"),
+	}, nil
+}
+
+// Register registers all handling functions to the given mux.
+func (h *Handler) Register(reg func(string, func(http.ResponseWriter, *http.Request))) {
+	reg(pathPrefix, h.handle)
+}
+
+func (h *Handler) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == specPath {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, codes.Unimplemented, "method not allowed")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(h.spec)
+		return
+	}
+
+	m, ok := h.methods[strings.TrimPrefix(r.URL.Path, pathPrefix)]
+	if !ok {
+		writeError(w, http.StatusNotFound, codes.NotFound, fmt.Sprintf("%s was not found", r.URL.Path))
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, codes.Unimplemented, "method not allowed")
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codes.InvalidArgument, "failed to read request body")
+		return
+	}
+	req := m.input.New().Interface()
+	if len(body) > 0 {
+		if err := protojson.Unmarshal(body, req); err != nil {
+			writeError(w, http.StatusBadRequest, codes.InvalidArgument, fmt.Sprintf("malformed request body: %v", err))
+			return
+		}
+	}
+
+	// The credentials are forwarded as they are
+	// to let the gRPC server authenticate the caller.
+	ctx := r.Context()
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", auth)
+	}
+	// The client address is forwarded to let the gRPC server
+	// apply the rate limit per client IP address.
+	// X-Forwarded-For header of the request is not passed through
+	// since it can be set to any value by the client, only the address
+	// resolved by walking it through the trusted proxies is forwarded.
+	if ip := rpc.ResolveClientIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For"), h.trustedProxies); ip != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-forwarded-for", ip)
+	}
+
+	// The gRPC codec works with the messages of the v1 API.
+	resp := m.output.New().Interface()
+	if err := h.conn.Invoke(ctx, m.fullName, proto.MessageV1(req), proto.MessageV1(resp)); err != nil {
+		s := status.Convert(err)
+		writeError(w, httpStatusFromCode(s.Code()), s.Code(), s.Message())
+		return
+	}
+
+	data, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(resp)
+	if err != nil {
+		h.logger.Error("failed to marshal response", zap.String("method", m.fullName), zap.Error(err))
+		writeError(w, http.StatusInternalServerError, codes.Internal, "failed to marshal response")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, httpStatus int, code codes.Code, message string) {
+	data, _ := json.Marshal(errorResponse{Code: code.String(), Message: message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	w.Write(data)
+}
+
+// httpStatusFromCode returns the HTTP status corresponding to the given gRPC code.
+// https://github.com/googleapis/googleapis/blob/master/google/rpc/code.proto
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apigateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const healthService = "grpc.health.v1.Health"
+
+type fakeInvoker struct {
+	method       string
+	auth         []string
+	forwardedFor []string
+	err          error
+}
+
+func (f *fakeInvoker) Invoke(ctx context.Context, method string, args, reply interface{}, _ ...grpc.CallOption) error {
+	f.method = method
+	md, _ := metadata.FromOutgoingContext(ctx)
+	f.auth = md.Get("authorization")
+	f.forwardedFor = md.Get("x-forwarded-for")
+	if f.err != nil {
+		return f.err
+	}
+	req := args.(*grpc_health_v1.HealthCheckRequest)
+	resp := reply.(*grpc_health_v1.HealthCheckResponse)
+	if req.Service == "unknown" {
+		resp.Status = grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN
+		return nil
+	}
+	resp.Status = grpc_health_v1.HealthCheckResponse_SERVING
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	testcases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		invokeErr      error
+		expectedStatus int
+		expectedBody   string
+		expectedMethod string
+	}{
+		{
+			name:           "forward request",
+			method:         http.MethodPost,
+			path:           "/api/v1/Check",
+			body:           `{"service": "unknown"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"SERVICE_UNKNOWN"}`,
+			expectedMethod: "/grpc.health.v1.Health/Check",
+		},
+		{
+			name:           "empty body",
+			method:         http.MethodPost,
+			path:           "/api/v1/Check",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"SERVING"}`,
+			expectedMethod: "/grpc.health.v1.Health/Check",
+		},
+		{
+			name:           "streaming method is not served",
+			method:         http.MethodPost,
+			path:           "/api/v1/Watch",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"NotFound","message":"/api/v1/Watch was not found"}`,
+		},
+		{
+			name:           "wrong http method",
+			method:         http.MethodGet,
+			path:           "/api/v1/Check",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   `{"code":"Unimplemented","message":"method not allowed"}`,
+		},
+		{
+			name:           "malformed body",
+			method:         http.MethodPost,
+			path:           "/api/v1/Check",
+			body:           `{"unknown_field": 1}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "grpc error",
+			method:         http.MethodPost,
+			path:           "/api/v1/Check",
+			invokeErr:      status.Error(codes.Unauthenticated, "missing credentials"),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"code":"Unauthenticated","message":"missing credentials"}`,
+			expectedMethod: "/grpc.health.v1.Health/Check",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			invoker := &fakeInvoker{err: tc.invokeErr}
+			h, err := NewHandler(invoker, healthService, nil, zap.NewNop())
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Authorization", "API-KEY foo")
+			req.Header.Set("X-Forwarded-For", "203.0.113.1")
+			rec := httptest.NewRecorder()
+			h.handle(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rec.Body.String())
+			}
+			assert.Equal(t, tc.expectedMethod, invoker.method)
+			if tc.expectedMethod != "" {
+				assert.Equal(t, []string{"API-KEY foo"}, invoker.auth)
+				// The address set by the client must not be trusted.
+				assert.Equal(t, []string{"192.0.2.1"}, invoker.forwardedFor)
+			}
+		})
+	}
+}
+
+func TestHandlerForwardClientIP(t *testing.T) {
+	testcases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		expected     string
+	}{
+		{
+			name:         "forwarded address from untrusted peer is ignored",
+			remoteAddr:   "192.0.2.1:1234",
+			forwardedFor: "203.0.113.1",
+			expected:     "192.0.2.1",
+		},
+		{
+			name:         "forwarded address from trusted proxy",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: "203.0.113.1",
+			expected:     "203.0.113.1",
+		},
+		{
+			name:         "spoofed address before untrusted hop is ignored",
+			remoteAddr:   "10.0.0.1:1234",
+			forwardedFor: "1.2.3.4, 203.0.113.1, 10.0.0.2",
+			expected:     "203.0.113.1",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			invoker := &fakeInvoker{}
+			h, err := NewHandler(invoker, healthService, []string{"10.0.0.0/8"}, zap.NewNop())
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/Check", strings.NewReader(`{}`))
+			req.RemoteAddr = tc.remoteAddr
+			req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			rec := httptest.NewRecorder()
+			h.handle(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, []string{tc.expected}, invoker.forwardedFor)
+		})
+	}
+}
+
+func TestNewHandlerUnknownService(t *testing.T) {
+	_, err := NewHandler(&fakeInvoker{}, "unknown.Service", nil, zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestHandlerServeSpec(t *testing.T) {
+	h, err := NewHandler(&fakeInvoker{}, healthService, nil, zap.NewNop())
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.handle(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Equal(t, healthService, doc.Info.Title)
+	require.Len(t, doc.Paths, 1)
+	op := doc.Paths["/api/v1/Check"].Post
+	require.NotNil(t, op)
+	assert.Equal(t, "Check", op.OperationID)
+	assert.Equal(t, "#/components/schemas/grpc.health.v1.HealthCheckRequest", op.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/grpc.health.v1.HealthCheckResponse", op.Responses["200"].Content["application/json"].Schema.Ref)
+
+	assert.Equal(t, &openAPISchema{
+		Type: "object",
+		Properties: map[string]*openAPISchema{
+			"service": {Type: "string"},
+		},
+	}, doc.Components.Schemas["grpc.health.v1.HealthCheckRequest"])
+	assert.Equal(t, &openAPISchema{
+		Type: "object",
+		Properties: map[string]*openAPISchema{
+			"status": {
+				Type: "string",
+				Enum: []string{"UNKNOWN", "SERVING", "NOT_SERVING", "SERVICE_UNKNOWN"},
+			},
+		},
+	}, doc.Components.Schemas["grpc.health.v1.HealthCheckResponse"])
+}
@@ -0,0 +1,242 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apigateway
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	schemaRefPrefix    = "#/components/schemas/"
+	errorSchemaName    = "Error"
+	securitySchemeName = "APIKey"
+	jsonContentType    = "application/json"
+)
+
+// The subset of OpenAPI 3 objects used to describe the service.
+// https://swagger.io/specification/
+type openAPIDocument struct {
+	OpenAPI    string                 `json:"openapi"`
+	Info       openAPIInfo            `json:"info"`
+	Paths      map[string]openAPIPath `json:"paths"`
+	Components openAPIComponents      `json:"components"`
+	Security   []map[string][]string  `json:"security"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIPath struct {
+	Post *openAPIOperation `json:"post,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Tags        []string                   `json:"tags"`
+	RequestBody openAPIRequestBody         `json:"requestBody"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*openAPISchema        `json:"schemas"`
+	SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type        string `json:"type"`
+	In          string `json:"in"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+// generateSpec builds the OpenAPI specification describing the unary RPCs
+// of the given service as they are served by Handler.
+func generateSpec(sd protoreflect.ServiceDescriptor) ([]byte, error) {
+	doc := openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:   string(sd.FullName()),
+			Version: "v1",
+		},
+		Paths: make(map[string]openAPIPath, sd.Methods().Len()),
+		Components: openAPIComponents{
+			Schemas: map[string]*openAPISchema{
+				errorSchemaName: {
+					Type: "object",
+					Properties: map[string]*openAPISchema{
+						"code":    {Type: "string"},
+						"message": {Type: "string"},
+					},
+				},
+			},
+			SecuritySchemes: map[string]openAPISecurityScheme{
+				securitySchemeName: {
+					Type:        "apiKey",
+					In:          "header",
+					Name:        "Authorization",
+					Description: "The API key in the form of \"API-KEY <key>\".",
+				},
+			},
+		},
+		Security: []map[string][]string{
+			{securitySchemeName: {}},
+		},
+	}
+
+	schemas := doc.Components.Schemas
+	for i := 0; i < sd.Methods().Len(); i++ {
+		md := sd.Methods().Get(i)
+		if md.IsStreamingClient() || md.IsStreamingServer() {
+			continue
+		}
+		doc.Paths[pathPrefix+string(md.Name())] = openAPIPath{
+			Post: &openAPIOperation{
+				OperationID: string(md.Name()),
+				Tags:        []string{string(sd.Name())},
+				RequestBody: openAPIRequestBody{
+					Required: true,
+					Content: map[string]openAPIMediaType{
+						jsonContentType: {Schema: messageSchemaRef(md.Input(), schemas)},
+					},
+				},
+				Responses: map[string]openAPIResponse{
+					"200": {
+						Description: "A successful response.",
+						Content: map[string]openAPIMediaType{
+							jsonContentType: {Schema: messageSchemaRef(md.Output(), schemas)},
+						},
+					},
+					"default": {
+						Description: "An error response.",
+						Content: map[string]openAPIMediaType{
+							jsonContentType: {Schema: &openAPISchema{Ref: schemaRefPrefix + errorSchemaName}},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal OpenAPI document: %w", err)
+	}
+	return data, nil
+}
+
+// messageSchemaRef returns a reference to the schema of the given message
+// after adding it and all messages it depends on into the given schemas.
+func messageSchemaRef(md protoreflect.MessageDescriptor, schemas map[string]*openAPISchema) *openAPISchema {
+	name := string(md.FullName())
+	ref := &openAPISchema{Ref: schemaRefPrefix + name}
+	if _, ok := schemas[name]; ok {
+		return ref
+	}
+
+	s := &openAPISchema{
+		Type:       "object",
+		Properties: make(map[string]*openAPISchema, md.Fields().Len()),
+	}
+	// Registering before visiting the fields to stop recursive messages.
+	schemas[name] = s
+
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		s.Properties[fd.JSONName()] = fieldSchema(fd, schemas)
+	}
+	return ref
+}
+
+func fieldSchema(fd protoreflect.FieldDescriptor, schemas map[string]*openAPISchema) *openAPISchema {
+	switch {
+	case fd.IsMap():
+		return &openAPISchema{
+			Type:                 "object",
+			AdditionalProperties: singularFieldSchema(fd.MapValue(), schemas),
+		}
+	case fd.IsList():
+		return &openAPISchema{
+			Type:  "array",
+			Items: singularFieldSchema(fd, schemas),
+		}
+	default:
+		return singularFieldSchema(fd, schemas)
+	}
+}
+
+// singularFieldSchema returns the schema of a single value of the given field
+// following the JSON mapping of protobuf.
+// https://developers.google.com/protocol-buffers/docs/proto3#json
+func singularFieldSchema(fd protoreflect.FieldDescriptor, schemas map[string]*openAPISchema) *openAPISchema {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return &openAPISchema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &openAPISchema{Type: "integer", Format: "uint32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		// 64-bit integers are encoded as strings.
+		return &openAPISchema{Type: "string", Format: "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &openAPISchema{Type: "string", Format: "uint64"}
+	case protoreflect.FloatKind:
+		return &openAPISchema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case protoreflect.StringKind:
+		return &openAPISchema{Type: "string"}
+	case protoreflect.BytesKind:
+		return &openAPISchema{Type: "string", Format: "byte"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		enum := make([]string, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			enum = append(enum, string(values.Get(i).Name()))
+		}
+		return &openAPISchema{Type: "string", Enum: enum}
+	default:
+		return messageSchemaRef(fd.Message(), schemas)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploymentlockstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	redigo "github.com/gomodule/redigo/redis"
+
+	"github.com/pipe-cd/pipe/pkg/redis"
+)
+
+// acquireScript sets all given keys to the deployment ID only when
+// none of them is held by another deployment. It returns the pairs
+// of the key and the holding deployment ID of the conflicted ones.
+var acquireScript = redigo.NewScript(-1, `
+local holders = {}
+for _, key in ipairs(KEYS) do
+  local holder = redis.call("GET", key)
+  if holder and holder ~= ARGV[1] then
+    table.insert(holders, key)
+    table.insert(holders, holder)
+  end
+end
+if #holders > 0 then
+  return holders
+end
+for _, key in ipairs(KEYS) do
+  redis.call("SET", key, ARGV[1], "PX", ARGV[2])
+end
+return holders
+`)
+
+// releaseScript deletes the given keys held by the deployment.
+var releaseScript = redigo.NewScript(-1, `
+for _, key in ipairs(KEYS) do
+  if redis.call("GET", key) == ARGV[1] then
+    redis.call("DEL", key)
+  end
+end
+return 0
+`)
+
+type Store interface {
+	// Acquire tries to acquire all of the given locks for the deployment.
+	// When one of them is held by another deployment, nothing is acquired
+	// and a map from the lock name to the holding deployment ID is returned.
+	Acquire(ctx context.Context, projectID, deploymentID string, locks []string) (map[string]string, error)
+	// Release releases the given locks held by the deployment.
+	Release(ctx context.Context, projectID, deploymentID string, locks []string) error
+	// AcquireLease tries to acquire the lease of the deployment for the given piped instance.
+	// The ID of the instance holding the lease is returned when it is held by another one.
+	AcquireLease(ctx context.Context, projectID, deploymentID, instanceID string) (string, error)
+	// ReleaseLease releases the lease of the deployment held by the given piped instance.
+	ReleaseLease(ctx context.Context, projectID, deploymentID, instanceID string) error
+}
+
+type store struct {
+	redis redis.Redis
+	ttl   time.Duration
+}
+
+// NewStore creates a store keeping the locks and the leases of deployments in Redis.
+// The locks expire after the given TTL unless they are acquired again by the same deployment
+// so that the locks held by the crashed pipeds are released eventually.
+func NewStore(rd redis.Redis, ttl time.Duration) Store {
+	return &store{
+		redis: rd,
+		ttl:   ttl,
+	}
+}
+
+func (s *store) Acquire(ctx context.Context, projectID, deploymentID string, locks []string) (map[string]string, error) {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	// The number of keys must be given first since the scripts accept any number of keys.
+	args := make([]interface{}, 0, len(locks)+3)
+	args = append(args, len(locks))
+	for _, l := range locks {
+		args = append(args, lockKey(projectID, l))
+	}
+	args = append(args, deploymentID, s.ttl.Milliseconds())
+
+	reply, err := redigo.Strings(acquireScript.Do(conn, args...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire deployment locks: %w", err)
+	}
+	if len(reply) == 0 {
+		return nil, nil
+	}
+
+	names := make(map[string]string, len(locks))
+	for _, l := range locks {
+		names[lockKey(projectID, l)] = l
+	}
+	holders := make(map[string]string, len(reply)/2)
+	for i := 0; i+1 < len(reply); i += 2 {
+		holders[names[reply[i]]] = reply[i+1]
+	}
+	return holders, nil
+}
+
+func (s *store) Release(ctx context.Context, projectID, deploymentID string, locks []string) error {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	args := make([]interface{}, 0, len(locks)+2)
+	args = append(args, len(locks))
+	for _, l := range locks {
+		args = append(args, lockKey(projectID, l))
+	}
+	args = append(args, deploymentID)
+
+	if _, err := releaseScript.Do(conn, args...); err != nil {
+		return fmt.Errorf("failed to release deployment locks: %w", err)
+	}
+	return nil
+}
+
+func (s *store) AcquireLease(ctx context.Context, projectID, deploymentID, instanceID string) (string, error) {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	key := leaseKey(projectID, deploymentID)
+	reply, err := redigo.Strings(acquireScript.Do(conn, 1, key, instanceID, s.ttl.Milliseconds()))
+	if err != nil {
+		return "", fmt.Errorf("failed to acquire deployment lease: %w", err)
+	}
+	if len(reply) < 2 {
+		return "", nil
+	}
+	return reply[1], nil
+}
+
+func (s *store) ReleaseLease(ctx context.Context, projectID, deploymentID, instanceID string) error {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	key := leaseKey(projectID, deploymentID)
+	if _, err := releaseScript.Do(conn, 1, key, instanceID); err != nil {
+		return fmt.Errorf("failed to release deployment lease: %w", err)
+	}
+	return nil
+}
+
+func lockKey(projectID, lock string) string {
+	return fmt.Sprintf("deployment-lock/%s/%s", projectID, lock)
+}
+
+func leaseKey(projectID, deploymentID string) string {
+	return fmt.Sprintf("deployment-lease/%s/%s", projectID, deploymentID)
+}
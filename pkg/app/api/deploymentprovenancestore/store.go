@@ -0,0 +1,83 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploymentprovenancestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/filestore"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+)
+
+// Store persists the provenance recorded by piped while executing a deployment.
+type Store interface {
+	Get(ctx context.Context, deploymentID string) (*model.DeploymentProvenance, error)
+	Put(ctx context.Context, provenance *model.DeploymentProvenance) error
+}
+
+type store struct {
+	backend filestore.Store
+	logger  *zap.Logger
+}
+
+func NewStore(fs filestore.Store, logger *zap.Logger) Store {
+	return &store{
+		backend: fs,
+		logger:  logger.Named("deployment-provenance-store"),
+	}
+}
+
+func (s *store) Get(ctx context.Context, deploymentID string) (*model.DeploymentProvenance, error) {
+	path := dataPath(deploymentID)
+	obj, err := s.backend.GetObject(ctx, path)
+	if err != nil {
+		if err == filestore.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		s.logger.Error("failed to get deployment provenance from filestore",
+			zap.String("deployment", deploymentID),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	var provenance model.DeploymentProvenance
+	if err := json.Unmarshal(obj.Content, &provenance); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal deployment provenance: %w", err)
+	}
+	return &provenance, nil
+}
+
+func (s *store) Put(ctx context.Context, provenance *model.DeploymentProvenance) error {
+	data, err := json.Marshal(provenance)
+	if err != nil {
+		return fmt.Errorf("failed to marshal deployment provenance: %w", err)
+	}
+	path := dataPath(provenance.DeploymentId)
+	return s.backend.PutObject(ctx, path, data)
+}
+
+func dataPath(deploymentID string) string {
+	return fmt.Sprintf("deployment-provenance/%s.json", deploymentID)
+}
@@ -0,0 +1,312 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gitwebhookhandler provides a http handler receiving push events
+// from Git hosting services such as GitHub and GitLab.
+// The responsible pipeds are notified through the command channel to check
+// the new commits immediately instead of waiting for their next polling.
+package gitwebhookhandler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/datastore"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	// githubPath is the path configured as the payload URL in the GitHub webhook settings.
+	githubPath = "/webhooks/github"
+	// gitlabPath is the path configured as the URL in the GitLab webhook settings.
+	gitlabPath = "/webhooks/gitlab"
+
+	maxPayloadSize  = 5 << 20
+	branchRefPrefix = "refs/heads/"
+)
+
+type pipedLister interface {
+	ListPipeds(ctx context.Context, opts datastore.ListOptions) ([]*model.Piped, error)
+}
+
+type commandAdder interface {
+	AddCommand(ctx context.Context, command *model.Command) error
+}
+
+// Handler handles the push events sent from Git hosting services.
+type Handler struct {
+	secret       string
+	pipedLister  pipedLister
+	commandAdder commandAdder
+	logger       *zap.Logger
+}
+
+// pushEvent contains the information of a push event
+// which is common among the Git hosting services.
+type pushEvent struct {
+	// The remote URLs of the pushed repository.
+	remotes []string
+	branch  string
+	commit  string
+}
+
+// NewHandler returns a handler that will be used for receiving push events.
+func NewHandler(
+	secret string,
+	pipedLister pipedLister,
+	commandAdder commandAdder,
+	logger *zap.Logger,
+) *Handler {
+	return &Handler{
+		secret:       secret,
+		pipedLister:  pipedLister,
+		commandAdder: commandAdder,
+		logger:       logger.Named("git-webhook-handler"),
+	}
+}
+
+// Register registers all handler into the specified registry.
+func (h *Handler) Register(r func(string, func(http.ResponseWriter, *http.Request))) {
+	r(githubPath, h.handleGitHub)
+	r(gitlabPath, h.handleGitLab)
+}
+
+func (h *Handler) handleGitHub(w http.ResponseWriter, r *http.Request) {
+	payload, ok := h.readPayload(w, r)
+	if !ok {
+		return
+	}
+	if !verifyGitHubSignature(h.secret, r.Header.Get("X-Hub-Signature-256"), payload) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	// Other events such as ping are accepted but ignored.
+	if r.Header.Get("X-GitHub-Event") != "push" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var p struct {
+		Ref        string `json:"ref"`
+		After      string `json:"after"`
+		Repository struct {
+			CloneURL string `json:"clone_url"`
+			SSHURL   string `json:"ssh_url"`
+			GitURL   string `json:"git_url"`
+			HTMLURL  string `json:"html_url"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	h.handlePushEvent(w, r, p.Ref, pushEvent{
+		remotes: []string{p.Repository.CloneURL, p.Repository.SSHURL, p.Repository.GitURL, p.Repository.HTMLURL},
+		commit:  p.After,
+	})
+}
+
+func (h *Handler) handleGitLab(w http.ResponseWriter, r *http.Request) {
+	payload, ok := h.readPayload(w, r)
+	if !ok {
+		return
+	}
+	token := r.Header.Get("X-Gitlab-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) != 1 {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get("X-Gitlab-Event") != "Push Hook" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var p struct {
+		Ref         string `json:"ref"`
+		CheckoutSHA string `json:"checkout_sha"`
+		Project     struct {
+			GitSSHURL  string `json:"git_ssh_url"`
+			GitHTTPURL string `json:"git_http_url"`
+			WebURL     string `json:"web_url"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	h.handlePushEvent(w, r, p.Ref, pushEvent{
+		remotes: []string{p.Project.GitSSHURL, p.Project.GitHTTPURL, p.Project.WebURL},
+		commit:  p.CheckoutSHA,
+	})
+}
+
+func (h *Handler) readPayload(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+	payload, err := ioutil.ReadAll(io.LimitReader(r.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return nil, false
+	}
+	return payload, true
+}
+
+func (h *Handler) handlePushEvent(w http.ResponseWriter, r *http.Request, ref string, e pushEvent) {
+	// Pushing tags does not change any branch.
+	if !strings.HasPrefix(ref, branchRefPrefix) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	e.branch = strings.TrimPrefix(ref, branchRefPrefix)
+
+	if err := h.notifyPipeds(r.Context(), e); err != nil {
+		http.Error(w, "failed to notify pipeds", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// notifyPipeds adds a command to refresh the pushed repository
+// to all pipeds which are handling that repository.
+func (h *Handler) notifyPipeds(ctx context.Context, e pushEvent) error {
+	remotes := make(map[string]struct{}, len(e.remotes))
+	for _, r := range e.remotes {
+		if r == "" {
+			continue
+		}
+		remotes[normalizeRemote(r)] = struct{}{}
+	}
+
+	// TODO: Cache the list of pipeds if the number of webhook calls become large.
+	pipeds, err := h.pipedLister.ListPipeds(ctx, datastore.ListOptions{
+		Filters: []datastore.ListFilter{
+			{
+				Field:    "Disabled",
+				Operator: datastore.OperatorEqual,
+				Value:    false,
+			},
+		},
+	})
+	if err != nil {
+		h.logger.Error("failed to list pipeds", zap.Error(err))
+		return err
+	}
+
+	for _, p := range pipeds {
+		for _, repo := range p.Repositories {
+			if repo.Branch != e.branch {
+				continue
+			}
+			if _, ok := remotes[normalizeRemote(repo.Remote)]; !ok {
+				continue
+			}
+			cmd := model.Command{
+				Id:        uuid.New().String(),
+				PipedId:   p.Id,
+				ProjectId: p.ProjectId,
+				Type:      model.Command_REFRESH_REPOSITORY,
+				Commander: "git-webhook",
+				RefreshRepository: &model.Command_RefreshRepository{
+					RepositoryId: repo.Id,
+					CommitHash:   e.commit,
+				},
+			}
+			if err := h.commandAdder.AddCommand(ctx, &cmd); err != nil {
+				h.logger.Error("failed to add refresh repository command",
+					zap.String("piped-id", p.Id),
+					zap.String("repo-id", repo.Id),
+					zap.Error(err),
+				)
+				return err
+			}
+			h.logger.Info("notified piped of the pushed repository",
+				zap.String("piped-id", p.Id),
+				zap.String("repo-id", repo.Id),
+				zap.String("commit", e.commit),
+			)
+		}
+	}
+	return nil
+}
+
+func verifyGitHubSignature(secret, signature string, payload []byte) bool {
+	const prefix = "sha256="
+	if secret == "" || !strings.HasPrefix(signature, prefix) {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, prefix))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// normalizeRemote converts the various formats of a remote URL
+// such as "git@github.com:org/repo.git" and "https://github.com/org/repo"
+// into the same form "github.com/org/repo".
+func normalizeRemote(remote string) string {
+	r := strings.TrimSpace(remote)
+	for _, scheme := range []string{"https://", "http://", "ssh://", "git://"} {
+		if strings.HasPrefix(r, scheme) {
+			r = strings.TrimPrefix(r, scheme)
+			break
+		}
+	}
+	r = strings.TrimSuffix(strings.TrimSuffix(r, "/"), ".git")
+
+	host, path := r, ""
+	if i := strings.Index(r, "/"); i >= 0 {
+		host, path = r[:i], r[i:]
+	}
+	// Remove the user info.
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	// Remove the port number or convert the scp-like syntax.
+	if i := strings.Index(host, ":"); i >= 0 {
+		rest := host[i+1:]
+		host = host[:i]
+		if !isDigits(rest) {
+			path = "/" + rest + path
+		}
+	}
+	return strings.ToLower(host + path)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
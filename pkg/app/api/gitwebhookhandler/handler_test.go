@@ -0,0 +1,165 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitwebhookhandler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/datastore"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakePipedLister struct {
+	pipeds []*model.Piped
+}
+
+func (l *fakePipedLister) ListPipeds(_ context.Context, _ datastore.ListOptions) ([]*model.Piped, error) {
+	return l.pipeds, nil
+}
+
+type fakeCommandAdder struct {
+	commands []*model.Command
+}
+
+func (a *fakeCommandAdder) AddCommand(_ context.Context, cmd *model.Command) error {
+	a.commands = append(a.commands, cmd)
+	return nil
+}
+
+func TestNormalizeRemote(t *testing.T) {
+	testcases := []struct {
+		remote   string
+		expected string
+	}{
+		{"git@github.com:pipe-cd/examples.git", "github.com/pipe-cd/examples"},
+		{"https://github.com/pipe-cd/examples.git", "github.com/pipe-cd/examples"},
+		{"https://github.com/Pipe-CD/examples/", "github.com/pipe-cd/examples"},
+		{"ssh://git@gitlab.example.com:2222/group/sub/repo.git", "gitlab.example.com/group/sub/repo"},
+		{"git://github.com/pipe-cd/examples.git", "github.com/pipe-cd/examples"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.remote, func(t *testing.T) {
+			assert.Equal(t, tc.expected, normalizeRemote(tc.remote))
+		})
+	}
+}
+
+func TestHandleGitHub(t *testing.T) {
+	const (
+		secret  = "secret"
+		payload = `{
+  "ref": "refs/heads/master",
+  "after": "abc123",
+  "repository": {
+    "clone_url": "https://github.com/pipe-cd/examples.git",
+    "ssh_url": "git@github.com:pipe-cd/examples.git"
+  }
+}`
+	)
+	sign := func(key string) string {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(payload))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	pipeds := []*model.Piped{
+		{
+			Id:        "piped-1",
+			ProjectId: "project-1",
+			Repositories: []*model.ApplicationGitRepository{
+				{Id: "examples", Remote: "git@github.com:pipe-cd/examples.git", Branch: "master"},
+				{Id: "other", Remote: "git@github.com:pipe-cd/other.git", Branch: "master"},
+			},
+		},
+		{
+			Id:        "piped-2",
+			ProjectId: "project-2",
+			Repositories: []*model.ApplicationGitRepository{
+				{Id: "examples-dev", Remote: "https://github.com/pipe-cd/examples", Branch: "dev"},
+			},
+		},
+	}
+
+	testcases := []struct {
+		name             string
+		event            string
+		signature        string
+		expectedCode     int
+		expectedCommands []*model.Command
+	}{
+		{
+			name:         "invalid signature",
+			event:        "push",
+			signature:    sign("wrong"),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "ping event",
+			event:        "ping",
+			signature:    sign(secret),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "push event",
+			event:        "push",
+			signature:    sign(secret),
+			expectedCode: http.StatusAccepted,
+			expectedCommands: []*model.Command{
+				{
+					PipedId:   "piped-1",
+					ProjectId: "project-1",
+					Type:      model.Command_REFRESH_REPOSITORY,
+					Commander: "git-webhook",
+					RefreshRepository: &model.Command_RefreshRepository{
+						RepositoryId: "examples",
+						CommitHash:   "abc123",
+					},
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			adder := &fakeCommandAdder{}
+			h := NewHandler(secret, &fakePipedLister{pipeds: pipeds}, adder, zap.NewNop())
+
+			req := httptest.NewRequest(http.MethodPost, githubPath, strings.NewReader(payload))
+			req.Header.Set("X-GitHub-Event", tc.event)
+			req.Header.Set("X-Hub-Signature-256", tc.signature)
+			w := httptest.NewRecorder()
+			h.handleGitHub(w, req)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			require.Equal(t, len(tc.expectedCommands), len(adder.commands))
+			for i := range adder.commands {
+				// The ID is randomly generated.
+				adder.commands[i].Id = ""
+			}
+			if len(tc.expectedCommands) > 0 {
+				assert.Equal(t, tc.expectedCommands, adder.commands)
+			}
+		})
+	}
+}
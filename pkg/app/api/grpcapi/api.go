@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -28,7 +29,9 @@ import (
 	"google.golang.org/grpc/status"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/commandstore"
+	"github.com/pipe-cd/pipe/pkg/app/api/deploymentprovenancestore"
 	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/datastore"
 	"github.com/pipe-cd/pipe/pkg/model"
 	"github.com/pipe-cd/pipe/pkg/rpc/rpcauth"
@@ -36,36 +39,51 @@ import (
 
 // API implements the behaviors for the gRPC definitions of API.
 type API struct {
-	applicationStore    datastore.ApplicationStore
-	environmentStore    datastore.EnvironmentStore
-	deploymentStore     datastore.DeploymentStore
-	pipedStore          datastore.PipedStore
-	eventStore          datastore.EventStore
-	commandStore        commandstore.Store
-	commandOutputGetter commandOutputGetter
-
-	webBaseURL string
-	logger     *zap.Logger
+	applicationStore          datastore.ApplicationStore
+	environmentStore          datastore.EnvironmentStore
+	deploymentStore           datastore.DeploymentStore
+	pipedStore                datastore.PipedStore
+	eventStore                datastore.EventStore
+	commandStore              commandstore.Store
+	commandOutputGetter       commandOutputGetter
+	applicationLiveStateStore applicationLiveStateGetter
+	manifestDiffGetter        manifestDiffGetter
+	provenanceGetter          deploymentProvenanceGetter
+	quotaChecker              *quotaChecker
+
+	eventDeduplicationWindow time.Duration
+	webBaseURL               string
+	logger                   *zap.Logger
 }
 
 // NewAPI creates a new API instance.
 func NewAPI(
 	ds datastore.DataStore,
+	alss applicationLiveStateGetter,
 	cmds commandstore.Store,
 	cog commandOutputGetter,
+	mdg manifestDiffGetter,
+	dpg deploymentProvenanceGetter,
+	quotas config.ControlPlaneQuotas,
+	eventDeduplicationWindow time.Duration,
 	webBaseURL string,
 	logger *zap.Logger,
 ) *API {
 	a := &API{
-		applicationStore:    datastore.NewApplicationStore(ds),
-		environmentStore:    datastore.NewEnvironmentStore(ds),
-		deploymentStore:     datastore.NewDeploymentStore(ds),
-		pipedStore:          datastore.NewPipedStore(ds),
-		eventStore:          datastore.NewEventStore(ds),
-		commandStore:        cmds,
-		commandOutputGetter: cog,
-		webBaseURL:          webBaseURL,
-		logger:              logger.Named("api"),
+		applicationStore:          datastore.NewApplicationStore(ds),
+		environmentStore:          datastore.NewEnvironmentStore(ds),
+		deploymentStore:           datastore.NewDeploymentStore(ds),
+		pipedStore:                datastore.NewPipedStore(ds),
+		eventStore:                datastore.NewEventStore(ds),
+		commandStore:              cmds,
+		commandOutputGetter:       cog,
+		applicationLiveStateStore: alss,
+		manifestDiffGetter:        mdg,
+		provenanceGetter:          dpg,
+		quotaChecker:              newQuotaChecker(ds, quotas, logger.Named("api")),
+		eventDeduplicationWindow:  eventDeduplicationWindow,
+		webBaseURL:                webBaseURL,
+		logger:                    logger.Named("api"),
 	}
 	return a
 }
@@ -89,6 +107,13 @@ func (a *API) AddApplication(ctx context.Context, req *apiservice.AddApplication
 	if key.ProjectId != piped.ProjectId {
 		return nil, status.Error(codes.InvalidArgument, "Requested piped does not belong to your project")
 	}
+	if !piped.AllowsEnvironment(req.EnvId) {
+		return nil, status.Error(codes.InvalidArgument, "Requested piped is not allowed to handle the applications of the environment")
+	}
+
+	if err := a.quotaChecker.checkApplications(ctx, key.ProjectId); err != nil {
+		return nil, err
+	}
 
 	gitpath, err := makeGitPath(
 		req.GitPath.Repo.Id,
@@ -110,7 +135,13 @@ func (a *API) AddApplication(ctx context.Context, req *apiservice.AddApplication
 		GitPath:       gitpath,
 		Kind:          req.Kind,
 		CloudProvider: req.CloudProvider,
+		Dependencies:  req.Dependencies,
+		Labels:        req.Labels,
+	}
+	if _, err := resolveApplicationDependencies(ctx, a.applicationStore, key.ProjectId, &app, a.logger); err != nil {
+		return nil, err
 	}
+
 	err = a.applicationStore.AddApplication(ctx, &app)
 	if errors.Is(err, datastore.ErrAlreadyExists) {
 		return nil, status.Error(codes.AlreadyExists, "The application already exists")
@@ -140,19 +171,62 @@ func (a *API) SyncApplication(ctx context.Context, req *apiservice.SyncApplicati
 		return nil, status.Error(codes.InvalidArgument, "Requested application does not belong to your project")
 	}
 
-	cmd := model.Command{
-		Id:            uuid.New().String(),
-		PipedId:       app.PipedId,
-		ApplicationId: app.Id,
-		ProjectId:     app.ProjectId,
-		Type:          model.Command_SYNC_APPLICATION,
-		Commander:     key.Id,
-		SyncApplication: &model.Command_SyncApplication{
+	var deps []*model.Application
+	if req.WithDependencies {
+		deps, err = resolveApplicationDependencies(ctx, a.applicationStore, key.ProjectId, app, a.logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// All applications are validated before sending any command
+	// to not sync only a part of the dependencies.
+	for _, app := range append([]*model.Application{app}, deps...) {
+		if err := a.validateSyncableApplication(ctx, app, req.DryRun); err != nil {
+			return nil, err
+		}
+	}
+
+	// The dependencies are sorted so that the commands of the dependencies
+	// of every application have been added before its own command.
+	commandIDs := make(map[string]string, len(deps)+1)
+	newCommand := func(app *model.Application, commit string) (*model.Command, error) {
+		dependencyCommandIDs := make([]string, 0, len(app.Dependencies))
+		if req.WithDependencies {
+			for _, id := range app.Dependencies {
+				dependencyCommandIDs = append(dependencyCommandIDs, commandIDs[id])
+			}
+		}
+		cmd := model.Command{
+			Id:            uuid.New().String(),
+			PipedId:       app.PipedId,
 			ApplicationId: app.Id,
-			SyncStrategy:  model.SyncStrategy_AUTO,
-		},
+			ProjectId:     app.ProjectId,
+			Type:          model.Command_SYNC_APPLICATION,
+			Commander:     key.Id,
+			SyncApplication: &model.Command_SyncApplication{
+				ApplicationId:        app.Id,
+				SyncStrategy:         model.SyncStrategy_AUTO,
+				DryRun:               req.DryRun,
+				TargetCommit:         commit,
+				DependencyCommandIds: dependencyCommandIDs,
+			},
+		}
+		if err := addCommand(ctx, a.commandStore, &cmd, a.logger); err != nil {
+			return nil, err
+		}
+		commandIDs[app.Id] = cmd.Id
+		return &cmd, nil
 	}
-	if err := addCommand(ctx, a.commandStore, &cmd, a.logger); err != nil {
+
+	// The dependencies are always synced at the head commit of their branches.
+	for _, dep := range deps {
+		if _, err := newCommand(dep, ""); err != nil {
+			return nil, err
+		}
+	}
+	cmd, err := newCommand(app, req.Commit)
+	if err != nil {
 		return nil, err
 	}
 
@@ -161,6 +235,132 @@ func (a *API) SyncApplication(ctx context.Context, req *apiservice.SyncApplicati
 	}, nil
 }
 
+// validateSyncableApplication checks whether a sync command can be sent for the given application.
+func (a *API) validateSyncableApplication(ctx context.Context, app *model.Application, dryRun bool) error {
+	// Dry-run deployments are still allowed since they change nothing.
+	if !dryRun && app.InMaintenance(time.Now()) {
+		return status.Error(codes.FailedPrecondition, fmt.Sprintf("The application %s is in maintenance mode and can be synced only by project admins from the web console", app.Name))
+	}
+
+	piped, err := getPiped(ctx, a.pipedStore, app.PipedId, a.logger)
+	if err != nil {
+		return err
+	}
+	if !piped.AllowsEnvironment(app.EnvId) {
+		return status.Error(codes.FailedPrecondition, fmt.Sprintf("The piped of the application %s is not allowed to handle the applications of its environment", app.Name))
+	}
+	return nil
+}
+
+// SyncAffectedApplications sends a command to sync each of the given applications
+// affected by the changes of a commit in the given repository branch.
+// The applications are synced fully at that commit.
+func (a *API) SyncAffectedApplications(ctx context.Context, req *apiservice.SyncAffectedApplicationsRequest) (*apiservice.SyncAffectedApplicationsResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_WRITE, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// The applications are fetched by batched queries since the datastore limits the number of values of an "in" filter.
+	const batchSize = 10
+	appByID := make(map[string]*model.Application, len(req.ApplicationIds))
+	for start := 0; start < len(req.ApplicationIds); start += batchSize {
+		end := start + batchSize
+		if end > len(req.ApplicationIds) {
+			end = len(req.ApplicationIds)
+		}
+		opts := datastore.ListOptions{
+			Filters: []datastore.ListFilter{
+				{
+					Field:    "ProjectId",
+					Operator: datastore.OperatorEqual,
+					Value:    key.ProjectId,
+				},
+				{
+					Field:    "Id",
+					Operator: datastore.OperatorIn,
+					Value:    req.ApplicationIds[start:end],
+				},
+			},
+		}
+		apps, _, err := listApplications(ctx, a.applicationStore, opts, a.logger)
+		if err != nil {
+			return nil, err
+		}
+		for _, app := range apps {
+			appByID[app.Id] = app
+		}
+	}
+	pipeds := make(map[string]*model.Piped)
+	affected := make([]*apiservice.SyncAffectedApplicationsResponse_AffectedApplication, 0, len(req.ApplicationIds))
+	for _, id := range req.ApplicationIds {
+		result := &apiservice.SyncAffectedApplicationsResponse_AffectedApplication{
+			ApplicationId: id,
+		}
+		affected = append(affected, result)
+
+		app, ok := appByID[id]
+		if !ok || app.Deleted || app.Disabled {
+			result.Error = "The application was not found or is disabled"
+			continue
+		}
+		result.ApplicationName = app.Name
+		result.EnvId = app.EnvId
+
+		if app.GitPath == nil || app.GitPath.Repo == nil || app.GitPath.Repo.Remote != req.RepoRemoteUrl || app.GitPath.Repo.Branch != req.Branch {
+			result.Error = "The application is not placed in the given repository branch"
+			continue
+		}
+
+		if !req.DryRun && app.InMaintenance(time.Now()) {
+			result.Error = "The application is in maintenance mode"
+			continue
+		}
+
+		piped, ok := pipeds[app.PipedId]
+		if !ok {
+			if piped, err = getPiped(ctx, a.pipedStore, app.PipedId, a.logger); err != nil {
+				result.Error = "Failed to get the piped of the application"
+				continue
+			}
+			pipeds[app.PipedId] = piped
+		}
+		if !piped.AllowsEnvironment(app.EnvId) {
+			result.Error = "The piped of the application is not allowed to handle the applications of its environment"
+			continue
+		}
+
+		cmd := model.Command{
+			Id:            uuid.New().String(),
+			PipedId:       app.PipedId,
+			ApplicationId: app.Id,
+			ProjectId:     app.ProjectId,
+			Type:          model.Command_SYNC_APPLICATION,
+			Commander:     key.Id,
+			SyncApplication: &model.Command_SyncApplication{
+				ApplicationId: app.Id,
+				SyncStrategy:  model.SyncStrategy_AUTO,
+				DryRun:        req.DryRun,
+				TargetCommit:  req.Commit,
+			},
+		}
+		if err := addCommand(ctx, a.commandStore, &cmd, a.logger); err != nil {
+			result.Error = "Failed to send a command to sync the application"
+			continue
+		}
+		result.CommandId = cmd.Id
+	}
+
+	a.logger.Info(fmt.Sprintf("sent commands to sync %d applications affected by commit %s", len(affected), req.Commit),
+		zap.String("project", key.ProjectId),
+		zap.String("repo-remote-url", req.RepoRemoteUrl),
+	)
+
+	return &apiservice.SyncAffectedApplicationsResponse{
+		Applications: affected,
+	}, nil
+}
+
 func (a *API) GetApplication(ctx context.Context, req *apiservice.GetApplicationRequest) (*apiservice.GetApplicationResponse, error) {
 	key, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
 	if err != nil {
@@ -302,111 +502,887 @@ func (a *API) ListApplications(ctx context.Context, req *apiservice.ListApplicat
 	}, nil
 }
 
-func (a *API) GetDeployment(ctx context.Context, req *apiservice.GetDeploymentRequest) (*apiservice.GetDeploymentResponse, error) {
+// UpdateApplicationDependencies replaces the list of applications the given application depends on.
+func (a *API) UpdateApplicationDependencies(ctx context.Context, req *apiservice.UpdateApplicationDependenciesRequest) (*apiservice.UpdateApplicationDependenciesResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_WRITE, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := getApplication(ctx, a.applicationStore, req.ApplicationId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+	if app.ProjectId != key.ProjectId {
+		return nil, status.Error(codes.InvalidArgument, "Requested application does not belong to your project")
+	}
+
+	// Validate against a copy to ensure that the new dependencies do not form a cycle.
+	updated := *app
+	updated.Dependencies = req.Dependencies
+	if _, err := resolveApplicationDependencies(ctx, a.applicationStore, key.ProjectId, &updated, a.logger); err != nil {
+		return nil, err
+	}
+
+	updater := func(app *model.Application) error {
+		app.Dependencies = req.Dependencies
+		return nil
+	}
+	if err := a.applicationStore.UpdateApplication(ctx, app.Id, updater); err != nil {
+		a.logger.Error("failed to update application dependencies", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to update application dependencies")
+	}
+
+	return &apiservice.UpdateApplicationDependenciesResponse{}, nil
+}
+
+// ListApplicationDependencies returns all direct and transitive dependencies of the given application
+// in the order they should be deployed, so that every application comes after its own dependencies.
+func (a *API) ListApplicationDependencies(ctx context.Context, req *apiservice.ListApplicationDependenciesRequest) (*apiservice.ListApplicationDependenciesResponse, error) {
 	key, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
 	if err != nil {
 		return nil, err
 	}
 
-	deployment, err := getDeployment(ctx, a.deploymentStore, req.DeploymentId, a.logger)
+	app, err := getApplication(ctx, a.applicationStore, req.ApplicationId, a.logger)
 	if err != nil {
 		return nil, err
 	}
+	if app.ProjectId != key.ProjectId {
+		return nil, status.Error(codes.InvalidArgument, "Requested application does not belong to your project")
+	}
 
-	if key.ProjectId != deployment.ProjectId {
-		return nil, status.Error(codes.InvalidArgument, "Requested deployment does not belong to your project")
+	deps, err := resolveApplicationDependencies(ctx, a.applicationStore, key.ProjectId, app, a.logger)
+	if err != nil {
+		return nil, err
 	}
 
-	return &apiservice.GetDeploymentResponse{
-		Deployment: deployment,
+	return &apiservice.ListApplicationDependenciesResponse{
+		Applications: deps,
 	}, nil
 }
 
-func (a *API) GetCommand(ctx context.Context, req *apiservice.GetCommandRequest) (*apiservice.GetCommandResponse, error) {
-	_, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
+// GetApplicationHealth returns the health summary of the specified application.
+func (a *API) GetApplicationHealth(ctx context.Context, req *apiservice.GetApplicationHealthRequest) (*apiservice.GetApplicationHealthResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
 	if err != nil {
 		return nil, err
 	}
 
-	cmd, err := getCommand(ctx, a.commandStore, req.CommandId, a.logger)
+	app, err := getApplication(ctx, a.applicationStore, req.ApplicationId, a.logger)
 	if err != nil {
 		return nil, err
 	}
+	if app.ProjectId != key.ProjectId {
+		return nil, status.Error(codes.InvalidArgument, "Requested application does not belong to your project")
+	}
 
-	return &apiservice.GetCommandResponse{
-		Command: cmd,
+	health, err := makeApplicationHealth(ctx, app, a.applicationLiveStateStore, a.deploymentStore, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiservice.GetApplicationHealthResponse{
+		Health: health,
 	}, nil
 }
 
-func (a *API) EnablePiped(ctx context.Context, req *apiservice.EnablePipedRequest) (*apiservice.EnablePipedResponse, error) {
-	if err := a.updatePiped(ctx, req.PipedId, a.pipedStore.EnablePiped); err != nil {
+// EnableApplicationMaintenance stops triggering the automatic deployments of the application
+// until the maintenance mode is disabled or expired.
+func (a *API) EnableApplicationMaintenance(ctx context.Context, req *apiservice.EnableApplicationMaintenanceRequest) (*apiservice.EnableApplicationMaintenanceResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_WRITE, a.logger)
+	if err != nil {
 		return nil, err
 	}
-	return &apiservice.EnablePipedResponse{}, nil
-}
 
-func (a *API) DisablePiped(ctx context.Context, req *apiservice.DisablePipedRequest) (*apiservice.DisablePipedResponse, error) {
-	if err := a.updatePiped(ctx, req.PipedId, a.pipedStore.DisablePiped); err != nil {
+	maintenance := makeApplicationMaintenance(req.Reason, key.Id, time.Duration(req.Duration)*time.Second, time.Now())
+	if err := a.updateApplicationMaintenance(ctx, key.ProjectId, req.ApplicationId, maintenance); err != nil {
 		return nil, err
 	}
-	return &apiservice.DisablePipedResponse{}, nil
+	return &apiservice.EnableApplicationMaintenanceResponse{}, nil
 }
 
-func (a *API) updatePiped(ctx context.Context, pipedID string, updater func(context.Context, string) error) error {
+func (a *API) DisableApplicationMaintenance(ctx context.Context, req *apiservice.DisableApplicationMaintenanceRequest) (*apiservice.DisableApplicationMaintenanceResponse, error) {
 	key, err := requireAPIKey(ctx, model.APIKey_READ_WRITE, a.logger)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	piped, err := getPiped(ctx, a.pipedStore, pipedID, a.logger)
+	if err := a.updateApplicationMaintenance(ctx, key.ProjectId, req.ApplicationId, nil); err != nil {
+		return nil, err
+	}
+	return &apiservice.DisableApplicationMaintenanceResponse{}, nil
+}
+
+func (a *API) updateApplicationMaintenance(ctx context.Context, projectID, appID string, maintenance *model.ApplicationMaintenance) error {
+	app, err := getApplication(ctx, a.applicationStore, appID, a.logger)
 	if err != nil {
 		return err
 	}
+	if app.ProjectId != projectID {
+		return status.Error(codes.InvalidArgument, "Requested application does not belong to your project")
+	}
 
-	if key.ProjectId != piped.ProjectId {
-		return status.Error(codes.PermissionDenied, "Requested piped doesn't belong to your project")
+	err = a.applicationStore.UpdateApplication(ctx, app.Id, func(app *model.Application) error {
+		app.Maintenance = maintenance
+		return nil
+	})
+	if err != nil {
+		a.logger.Error("failed to update application maintenance mode", zap.String("app-id", app.Id), zap.Error(err))
+		return status.Error(codes.Internal, "Failed to update application maintenance mode")
 	}
+	return nil
+}
 
-	if err := updater(ctx, pipedID); err != nil {
-		switch err {
-		case datastore.ErrNotFound:
-			return status.Error(codes.InvalidArgument, "The piped is not found")
-		case datastore.ErrInvalidArgument:
-			return status.Error(codes.InvalidArgument, "Invalid value for update")
-		default:
-			a.logger.Error("failed to update the piped",
-				zap.String("piped-id", pipedID),
-				zap.Error(err),
-			)
-			return status.Error(codes.Internal, "Failed to update the piped")
+// ListApplicationHealths returns the health summaries of the enabled applications in the project.
+func (a *API) ListApplicationHealths(ctx context.Context, req *apiservice.ListApplicationHealthsRequest) (*apiservice.ListApplicationHealthsResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	const defaultLimit = 20
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultLimit
+	}
+
+	filters := []datastore.ListFilter{
+		{
+			Field:    "ProjectId",
+			Operator: datastore.OperatorEqual,
+			Value:    key.ProjectId,
+		},
+		{
+			Field:    "Disabled",
+			Operator: datastore.OperatorEqual,
+			Value:    false,
+		},
+	}
+	if req.EnvId != "" {
+		filters = append(filters, datastore.ListFilter{
+			Field:    "EnvId",
+			Operator: datastore.OperatorEqual,
+			Value:    req.EnvId,
+		})
+	}
+
+	apps, cursor, err := listApplications(ctx, a.applicationStore, datastore.ListOptions{
+		Filters: filters,
+		Orders: []datastore.Order{
+			{
+				Field:     "UpdatedAt",
+				Direction: datastore.Desc,
+			},
+			{
+				Field:     "Id",
+				Direction: datastore.Asc,
+			},
+		},
+		Limit:  limit,
+		Cursor: req.Cursor,
+	}, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]*model.Application, 0, len(apps))
+	for _, app := range apps {
+		if !app.Deleted {
+			active = append(active, app)
 		}
 	}
-	return nil
+	healths, err := makeApplicationHealths(ctx, active, a.applicationLiveStateStore, a.deploymentStore, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiservice.ListApplicationHealthsResponse{
+		Healths: healths,
+		Cursor:  cursor,
+	}, nil
 }
 
-func (a *API) RegisterEvent(ctx context.Context, req *apiservice.RegisterEventRequest) (*apiservice.RegisterEventResponse, error) {
-	key, err := requireAPIKey(ctx, model.APIKey_READ_WRITE, a.logger)
+func (a *API) GetDeployment(ctx context.Context, req *apiservice.GetDeploymentRequest) (*apiservice.GetDeploymentResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
 	if err != nil {
 		return nil, err
 	}
 
-	err = a.eventStore.AddEvent(ctx, model.Event{
-		Id:        uuid.New().String(),
-		Name:      req.Name,
-		Data:      req.Data,
-		Labels:    req.Labels,
-		EventKey:  model.MakeEventKey(req.Name, req.Labels),
-		ProjectId: key.ProjectId,
-	})
-	if errors.Is(err, datastore.ErrAlreadyExists) {
-		return nil, status.Error(codes.AlreadyExists, "The event already exists")
+	deployment, err := getDeployment(ctx, a.deploymentStore, req.DeploymentId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.ProjectId != deployment.ProjectId {
+		return nil, status.Error(codes.InvalidArgument, "Requested deployment does not belong to your project")
 	}
+
+	return &apiservice.GetDeploymentResponse{
+		Deployment: deployment,
+	}, nil
+}
+
+func (a *API) GetDeploymentManifestDiff(ctx context.Context, req *apiservice.GetDeploymentManifestDiffRequest) (*apiservice.GetDeploymentManifestDiffResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
 	if err != nil {
-		a.logger.Error("failed to register event", zap.Error(err))
-		return nil, status.Error(codes.Internal, "Failed to register event")
+		return nil, err
 	}
 
-	return &apiservice.RegisterEventResponse{}, nil
+	deployment, err := getDeployment(ctx, a.deploymentStore, req.DeploymentId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.ProjectId != deployment.ProjectId {
+		return nil, status.Error(codes.InvalidArgument, "Requested deployment does not belong to your project")
+	}
+
+	diff, err := getDeploymentManifestDiff(ctx, a.manifestDiffGetter, deployment.Id, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiservice.GetDeploymentManifestDiffResponse{
+		Diff: diff,
+	}, nil
+}
+
+func (a *API) GetDeploymentStageGraph(ctx context.Context, req *apiservice.GetDeploymentStageGraphRequest) (*apiservice.GetDeploymentStageGraphResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment, err := getDeployment(ctx, a.deploymentStore, req.DeploymentId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.ProjectId != deployment.ProjectId {
+		return nil, status.Error(codes.InvalidArgument, "Requested deployment does not belong to your project")
+	}
+
+	return &apiservice.GetDeploymentStageGraphResponse{
+		Graph: model.MakeStageGraph(deployment),
+	}, nil
+}
+
+func (a *API) GetDeploymentProvenance(ctx context.Context, req *apiservice.GetDeploymentProvenanceRequest) (*apiservice.GetDeploymentProvenanceResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment, err := getDeployment(ctx, a.deploymentStore, req.DeploymentId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.ProjectId != deployment.ProjectId {
+		return nil, status.Error(codes.InvalidArgument, "Requested deployment does not belong to your project")
+	}
+
+	provenance, err := a.provenanceGetter.Get(ctx, deployment.Id)
+	if errors.Is(err, deploymentprovenancestore.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "No provenance was recorded for the deployment")
+	}
+	if err != nil {
+		a.logger.Error("failed to get deployment provenance", zap.String("deployment-id", deployment.Id), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to get deployment provenance")
+	}
+
+	return &apiservice.GetDeploymentProvenanceResponse{
+		Provenance: provenance,
+	}, nil
+}
+
+// ListDeployments returns the latest deployments of the project, the most recently updated first.
+func (a *API) ListDeployments(ctx context.Context, req *apiservice.ListDeploymentsRequest) (*apiservice.ListDeploymentsResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	const defaultLimit = 20
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultLimit
+	}
+
+	orders := []datastore.Order{
+		{
+			Field:     "UpdatedAt",
+			Direction: datastore.Desc,
+		},
+		{
+			Field:     "Id",
+			Direction: datastore.Asc,
+		},
+	}
+	filters := []datastore.ListFilter{
+		{
+			Field:    "ProjectId",
+			Operator: datastore.OperatorEqual,
+			Value:    key.ProjectId,
+		},
+	}
+	if len(req.Statuses) > 0 {
+		filters = append(filters, datastore.ListFilter{
+			Field:    "Status",
+			Operator: datastore.OperatorIn,
+			Value:    req.Statuses,
+		})
+	}
+	if req.ApplicationId != "" {
+		filters = append(filters, datastore.ListFilter{
+			Field:    "ApplicationId",
+			Operator: datastore.OperatorEqual,
+			Value:    req.ApplicationId,
+		})
+	}
+
+	deployments, cursor, err := a.deploymentStore.ListDeployments(ctx, datastore.ListOptions{
+		Filters: filters,
+		Orders:  orders,
+		Limit:   limit,
+		Cursor:  req.Cursor,
+	})
+	if err != nil {
+		a.logger.Error("failed to list deployments", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list deployments")
+	}
+
+	return &apiservice.ListDeploymentsResponse{
+		Deployments: deployments,
+		Cursor:      cursor,
+	}, nil
+}
+
+// ExportDeploymentHistory returns the history records of the deployments of an application
+// those were created in the specified time range, the most recently updated first.
+func (a *API) ExportDeploymentHistory(ctx context.Context, req *apiservice.ExportDeploymentHistoryRequest) (*apiservice.ExportDeploymentHistoryResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := getApplication(ctx, a.applicationStore, req.ApplicationId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.ProjectId != app.ProjectId {
+		return nil, status.Error(codes.InvalidArgument, "Requested application does not belong to your project")
+	}
+
+	const defaultLimit = 100
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultLimit
+	}
+
+	// Deployments are filtered by their updated time to use the existing index.
+	// Since a deployment is never updated before its creation, no deployment
+	// created in the range is missed, and the rest is filtered out later.
+	deployments, cursor, err := a.deploymentStore.ListDeployments(ctx, datastore.ListOptions{
+		Filters: []datastore.ListFilter{
+			{
+				Field:    "ApplicationId",
+				Operator: datastore.OperatorEqual,
+				Value:    app.Id,
+			},
+			{
+				Field:    "UpdatedAt",
+				Operator: datastore.OperatorGreaterThanOrEqual,
+				Value:    req.Since,
+			},
+		},
+		Orders: []datastore.Order{
+			{
+				Field:     "UpdatedAt",
+				Direction: datastore.Desc,
+			},
+			{
+				Field:     "Id",
+				Direction: datastore.Asc,
+			},
+		},
+		Limit:  limit,
+		Cursor: req.Cursor,
+	})
+	if err != nil {
+		a.logger.Error("failed to list deployments", zap.String("app-id", app.Id), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list deployments")
+	}
+	if len(deployments) < limit {
+		cursor = ""
+	}
+
+	records := make([]*apiservice.DeploymentHistoryRecord, 0, len(deployments))
+	for _, d := range deployments {
+		if d.CreatedAt < req.Since {
+			continue
+		}
+		if req.Until > 0 && d.CreatedAt >= req.Until {
+			continue
+		}
+		records = append(records, makeDeploymentHistoryRecord(d))
+	}
+
+	return &apiservice.ExportDeploymentHistoryResponse{
+		Records: records,
+		Cursor:  cursor,
+	}, nil
+}
+
+func makeDeploymentHistoryRecord(d *model.Deployment) *apiservice.DeploymentHistoryRecord {
+	r := &apiservice.DeploymentHistoryRecord{
+		DeploymentId:    d.Id,
+		ApplicationId:   d.ApplicationId,
+		ApplicationName: d.ApplicationName,
+		EnvId:           d.EnvId,
+		Kind:            d.Kind,
+		Status:          d.Status,
+		StatusReason:    d.StatusReason,
+		Version:         d.Version,
+		Summary:         d.Summary,
+		Approvers:       d.Approvers(),
+		CreatedAt:       d.CreatedAt,
+		CompletedAt:     d.CompletedAt,
+	}
+	if t := d.Trigger; t != nil {
+		r.Commander = t.Commander
+		if c := t.Commit; c != nil {
+			r.CommitHash = c.Hash
+			r.CommitMessage = c.Message
+			r.CommitAuthor = c.Author
+		}
+	}
+	if d.CompletedAt > 0 && d.CompletedAt >= d.CreatedAt {
+		r.Duration = d.CompletedAt - d.CreatedAt
+	}
+	return r
+}
+
+// RollbackApplication triggers a new deployment of the given application
+// at the specified commit or at the commit of its previous successful deployment.
+func (a *API) RollbackApplication(ctx context.Context, req *apiservice.RollbackApplicationRequest) (*apiservice.RollbackApplicationResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_WRITE, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := getApplication(ctx, a.applicationStore, req.ApplicationId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.ProjectId != app.ProjectId {
+		return nil, status.Error(codes.InvalidArgument, "Requested application does not belong to your project")
+	}
+
+	piped, err := getPiped(ctx, a.pipedStore, app.PipedId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+	if !piped.AllowsEnvironment(app.EnvId) {
+		return nil, status.Error(codes.FailedPrecondition, "The piped of the application is not allowed to handle the applications of its environment")
+	}
+
+	targetCommit := req.TargetCommit
+	if targetCommit == "" {
+		current := app.MostRecentlySuccessfulDeployment
+		if current == nil || current.Trigger == nil || current.Trigger.Commit == nil {
+			return nil, status.Error(codes.FailedPrecondition, "The application has no successful deployment to roll back from")
+		}
+
+		deployments, _, err := a.deploymentStore.ListDeployments(ctx, datastore.ListOptions{
+			Filters: []datastore.ListFilter{
+				{
+					Field:    "ApplicationId",
+					Operator: datastore.OperatorEqual,
+					Value:    app.Id,
+				},
+				{
+					Field:    "Status",
+					Operator: datastore.OperatorEqual,
+					Value:    model.DeploymentStatus_DEPLOYMENT_SUCCESS,
+				},
+			},
+			Orders: []datastore.Order{
+				{
+					Field:     "UpdatedAt",
+					Direction: datastore.Desc,
+				},
+				{
+					Field:     "Id",
+					Direction: datastore.Asc,
+				},
+			},
+			Limit: 100,
+		})
+		if err != nil {
+			a.logger.Error("failed to list successful deployments", zap.String("app-id", app.Id), zap.Error(err))
+			return nil, status.Error(codes.Internal, "Failed to list successful deployments")
+		}
+
+		targetCommit = findPreviousSuccessfulCommit(deployments, current.Trigger.Commit.Hash)
+		if targetCommit == "" {
+			return nil, status.Error(codes.FailedPrecondition, "The application has no previous successful deployment to roll back to")
+		}
+	}
+
+	cmd := model.Command{
+		Id:            uuid.New().String(),
+		PipedId:       app.PipedId,
+		ApplicationId: app.Id,
+		ProjectId:     app.ProjectId,
+		Type:          model.Command_SYNC_APPLICATION,
+		Commander:     key.Id,
+		SyncApplication: &model.Command_SyncApplication{
+			ApplicationId: app.Id,
+			SyncStrategy:  model.SyncStrategy_AUTO,
+			TargetCommit:  targetCommit,
+		},
+	}
+	if err := addCommand(ctx, a.commandStore, &cmd, a.logger); err != nil {
+		return nil, err
+	}
+	a.logger.Info("added a command to roll back application",
+		zap.String("app-id", app.Id),
+		zap.String("target-commit", targetCommit),
+		zap.String("commander", key.Id),
+	)
+
+	return &apiservice.RollbackApplicationResponse{
+		CommandId:    cmd.Id,
+		TargetCommit: targetCommit,
+	}, nil
+}
+
+// findPreviousSuccessfulCommit returns the commit of the latest deployment
+// which deployed a different commit than the current one.
+// The given deployments must be ordered from the latest.
+func findPreviousSuccessfulCommit(deployments []*model.Deployment, currentCommit string) string {
+	for _, d := range deployments {
+		if d.Trigger == nil || d.Trigger.Commit == nil {
+			continue
+		}
+		// Dry-run deployments did not change the running state.
+		if d.Trigger.DryRun {
+			continue
+		}
+		if hash := d.Trigger.Commit.Hash; hash != currentCommit {
+			return hash
+		}
+	}
+	return ""
+}
+
+func (a *API) CancelDeployment(ctx context.Context, req *apiservice.CancelDeploymentRequest) (*apiservice.CancelDeploymentResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_WRITE, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment, err := getDeployment(ctx, a.deploymentStore, req.DeploymentId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.ProjectId != deployment.ProjectId {
+		return nil, status.Error(codes.InvalidArgument, "Requested deployment does not belong to your project")
+	}
+	if model.IsCompletedDeployment(deployment.Status) {
+		return nil, status.Error(codes.FailedPrecondition, "Could not cancel the deployment because it was already completed")
+	}
+
+	cmd := model.Command{
+		Id:            uuid.New().String(),
+		PipedId:       deployment.PipedId,
+		ApplicationId: deployment.ApplicationId,
+		ProjectId:     deployment.ProjectId,
+		DeploymentId:  deployment.Id,
+		Type:          model.Command_CANCEL_DEPLOYMENT,
+		Commander:     key.Id,
+		CancelDeployment: &model.Command_CancelDeployment{
+			DeploymentId:    deployment.Id,
+			ForceRollback:   req.ForceRollback,
+			ForceNoRollback: req.ForceNoRollback,
+		},
+	}
+	if err := addCommand(ctx, a.commandStore, &cmd, a.logger); err != nil {
+		return nil, err
+	}
+
+	return &apiservice.CancelDeploymentResponse{
+		CommandId: cmd.Id,
+	}, nil
+}
+
+func (a *API) ApproveStage(ctx context.Context, req *apiservice.ApproveStageRequest) (*apiservice.ApproveStageResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_WRITE, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment, err := getDeployment(ctx, a.deploymentStore, req.DeploymentId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.ProjectId != deployment.ProjectId {
+		return nil, status.Error(codes.InvalidArgument, "Requested deployment does not belong to your project")
+	}
+	stage, ok := deployment.FindStage(req.StageId)
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "The stage was not found in the deployment")
+	}
+	if model.IsCompletedStage(stage.Status) {
+		return nil, status.Error(codes.FailedPrecondition, "Could not approve the stage because it was already completed")
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if err := validateApprovalComment(stage, comment); err != nil {
+		return nil, err
+	}
+
+	cmd := model.Command{
+		Id:            uuid.New().String(),
+		PipedId:       deployment.PipedId,
+		ApplicationId: deployment.ApplicationId,
+		ProjectId:     deployment.ProjectId,
+		DeploymentId:  deployment.Id,
+		StageId:       req.StageId,
+		Type:          model.Command_APPROVE_STAGE,
+		Commander:     key.Id,
+		ApproveStage: &model.Command_ApproveStage{
+			DeploymentId: deployment.Id,
+			StageId:      req.StageId,
+			Comment:      comment,
+			Reject:       req.Reject,
+		},
+	}
+	if err := addCommand(ctx, a.commandStore, &cmd, a.logger); err != nil {
+		return nil, err
+	}
+
+	return &apiservice.ApproveStageResponse{
+		CommandId: cmd.Id,
+	}, nil
+}
+
+func (a *API) GetCommand(ctx context.Context, req *apiservice.GetCommandRequest) (*apiservice.GetCommandResponse, error) {
+	_, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd, err := getCommand(ctx, a.commandStore, req.CommandId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiservice.GetCommandResponse{
+		Command: cmd,
+	}, nil
+}
+
+func (a *API) EnablePiped(ctx context.Context, req *apiservice.EnablePipedRequest) (*apiservice.EnablePipedResponse, error) {
+	if err := a.updatePiped(ctx, req.PipedId, a.pipedStore.EnablePiped); err != nil {
+		return nil, err
+	}
+	return &apiservice.EnablePipedResponse{}, nil
+}
+
+func (a *API) DisablePiped(ctx context.Context, req *apiservice.DisablePipedRequest) (*apiservice.DisablePipedResponse, error) {
+	if err := a.updatePiped(ctx, req.PipedId, a.pipedStore.DisablePiped); err != nil {
+		return nil, err
+	}
+	return &apiservice.DisablePipedResponse{}, nil
+}
+
+func (a *API) GetPipedDiagnostics(ctx context.Context, req *apiservice.GetPipedDiagnosticsRequest) (*apiservice.GetPipedDiagnosticsResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	piped, err := getPiped(ctx, a.pipedStore, req.PipedId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.ProjectId != piped.ProjectId {
+		return nil, status.Error(codes.PermissionDenied, "Requested piped doesn't belong to your project")
+	}
+
+	return &apiservice.GetPipedDiagnosticsResponse{
+		Diagnostics: piped.Diagnostics,
+	}, nil
+}
+
+func (a *API) updatePiped(ctx context.Context, pipedID string, updater func(context.Context, string) error) error {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_WRITE, a.logger)
+	if err != nil {
+		return err
+	}
+
+	piped, err := getPiped(ctx, a.pipedStore, pipedID, a.logger)
+	if err != nil {
+		return err
+	}
+
+	if key.ProjectId != piped.ProjectId {
+		return status.Error(codes.PermissionDenied, "Requested piped doesn't belong to your project")
+	}
+
+	if err := updater(ctx, pipedID); err != nil {
+		switch err {
+		case datastore.ErrNotFound:
+			return status.Error(codes.InvalidArgument, "The piped is not found")
+		case datastore.ErrInvalidArgument:
+			return status.Error(codes.InvalidArgument, "Invalid value for update")
+		default:
+			a.logger.Error("failed to update the piped",
+				zap.String("piped-id", pipedID),
+				zap.Error(err),
+			)
+			return status.Error(codes.Internal, "Failed to update the piped")
+		}
+	}
+	return nil
+}
+
+func (a *API) RegisterEvent(ctx context.Context, req *apiservice.RegisterEventRequest) (*apiservice.RegisterEventResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_WRITE, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.IdempotencyKey != "" {
+		original, err := a.findDuplicatedEvent(ctx, key.ProjectId, req.IdempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		if original != nil {
+			a.logger.Info("deduplicated an event registration",
+				zap.String("event-id", original.Id),
+				zap.String("idempotency-key", req.IdempotencyKey),
+			)
+			return &apiservice.RegisterEventResponse{
+				EventId:      original.Id,
+				Deduplicated: true,
+			}, nil
+		}
+	}
+
+	id := uuid.New().String()
+	err = a.eventStore.AddEvent(ctx, model.Event{
+		Id:             id,
+		Name:           req.Name,
+		Data:           req.Data,
+		Labels:         req.Labels,
+		EventKey:       model.MakeEventKey(req.Name, req.Labels),
+		ProjectId:      key.ProjectId,
+		IdempotencyKey: req.IdempotencyKey,
+	})
+	if errors.Is(err, datastore.ErrAlreadyExists) {
+		return nil, status.Error(codes.AlreadyExists, "The event already exists")
+	}
+	if err != nil {
+		a.logger.Error("failed to register event", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to register event")
+	}
+
+	return &apiservice.RegisterEventResponse{
+		EventId: id,
+	}, nil
+}
+
+// findDuplicatedEvent returns the most recent event registered with the given idempotency key
+// within the deduplication window. Nil is returned when there is no such event.
+// The deduplication is done on a best-effort basis since the concurrent registrations
+// with the same key can pass the check at the same time.
+func (a *API) findDuplicatedEvent(ctx context.Context, projectID, idempotencyKey string) (*model.Event, error) {
+	if a.eventDeduplicationWindow <= 0 {
+		return nil, nil
+	}
+
+	// The events are sorted here instead of in the query to avoid requiring another composite index.
+	events, err := a.eventStore.ListEvents(ctx, datastore.ListOptions{
+		Filters: []datastore.ListFilter{
+			{
+				Field:    "ProjectId",
+				Operator: datastore.OperatorEqual,
+				Value:    projectID,
+			},
+			{
+				Field:    "IdempotencyKey",
+				Operator: datastore.OperatorEqual,
+				Value:    idempotencyKey,
+			},
+		},
+	})
+	if err != nil {
+		a.logger.Error("failed to list events by idempotency key", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to register event")
+	}
+
+	since := time.Now().Add(-a.eventDeduplicationWindow).Unix()
+	var latest *model.Event
+	for _, e := range events {
+		if e.CreatedAt < since {
+			continue
+		}
+		if latest == nil || e.CreatedAt > latest.CreatedAt {
+			latest = e
+		}
+	}
+	return latest, nil
+}
+
+// ListEvents returns the latest events registered in the project, the most recently created first.
+func (a *API) ListEvents(ctx context.Context, req *apiservice.ListEventsRequest) (*apiservice.ListEventsResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	const defaultLimit = 20
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultLimit
+	}
+
+	events, err := a.eventStore.ListEvents(ctx, datastore.ListOptions{
+		Filters: []datastore.ListFilter{
+			{
+				Field:    "ProjectId",
+				Operator: datastore.OperatorEqual,
+				Value:    key.ProjectId,
+			},
+		},
+		Orders: []datastore.Order{
+			{
+				Field:     "CreatedAt",
+				Direction: datastore.Desc,
+			},
+			{
+				Field:     "Id",
+				Direction: datastore.Asc,
+			},
+		},
+		Limit: limit,
+	})
+	if err != nil {
+		a.logger.Error("failed to list events", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list events")
+	}
+
+	return &apiservice.ListEventsResponse{
+		Events: events,
+	}, nil
 }
 
 func (a *API) RequestPlanPreview(ctx context.Context, req *apiservice.RequestPlanPreviewRequest) (*apiservice.RequestPlanPreviewResponse, error) {
@@ -21,6 +21,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
+	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
 	"github.com/pipe-cd/pipe/pkg/model"
 	"github.com/pipe-cd/pipe/pkg/rpc/rpcauth"
 )
@@ -102,3 +103,153 @@ func TestRequireAPIKey(t *testing.T) {
 		})
 	}
 }
+
+func TestFindPreviousSuccessfulCommit(t *testing.T) {
+	makeDeployment := func(hash string, dryRun bool) *model.Deployment {
+		return &model.Deployment{
+			Trigger: &model.DeploymentTrigger{
+				Commit: &model.Commit{Hash: hash},
+				DryRun: dryRun,
+			},
+		}
+	}
+	testcases := []struct {
+		name          string
+		deployments   []*model.Deployment
+		currentCommit string
+		expected      string
+	}{
+		{
+			name:          "no deployment",
+			currentCommit: "c3",
+			expected:      "",
+		},
+		{
+			name: "only the current commit was deployed",
+			deployments: []*model.Deployment{
+				makeDeployment("c3", false),
+				makeDeployment("c3", false),
+			},
+			currentCommit: "c3",
+			expected:      "",
+		},
+		{
+			name: "skip redeployments of the current commit",
+			deployments: []*model.Deployment{
+				makeDeployment("c3", false),
+				makeDeployment("c3", false),
+				makeDeployment("c2", false),
+				makeDeployment("c1", false),
+			},
+			currentCommit: "c3",
+			expected:      "c2",
+		},
+		{
+			name: "skip dry-run deployments",
+			deployments: []*model.Deployment{
+				makeDeployment("c4", true),
+				makeDeployment("c3", false),
+				makeDeployment("c2", false),
+			},
+			currentCommit: "c3",
+			expected:      "c2",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := findPreviousSuccessfulCommit(tc.deployments, tc.currentCommit)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestMakeDeploymentHistoryRecord(t *testing.T) {
+	testcases := []struct {
+		name       string
+		deployment *model.Deployment
+		expected   *apiservice.DeploymentHistoryRecord
+	}{
+		{
+			name: "completed deployment with approvers",
+			deployment: &model.Deployment{
+				Id:              "deployment-1",
+				ApplicationId:   "app-1",
+				ApplicationName: "app",
+				EnvId:           "env-1",
+				Kind:            model.ApplicationKind_KUBERNETES,
+				Status:          model.DeploymentStatus_DEPLOYMENT_SUCCESS,
+				Version:         "v0.1.0",
+				Trigger: &model.DeploymentTrigger{
+					Commit: &model.Commit{
+						Hash:    "hash",
+						Message: "message",
+						Author:  "author",
+					},
+					Commander: "commander",
+				},
+				Stages: []*model.PipelineStage{
+					{
+						Name: model.StageK8sSync.String(),
+					},
+					{
+						Name: model.StageWaitApproval.String(),
+						Metadata: map[string]string{
+							model.StageMetadataKeyApprovedBy: "user-1",
+						},
+					},
+					{
+						Name: model.StageWaitApproval.String(),
+						Metadata: map[string]string{
+							model.StageMetadataKeyApprovedBy: "user-2",
+						},
+					},
+				},
+				CreatedAt:   100,
+				CompletedAt: 160,
+			},
+			expected: &apiservice.DeploymentHistoryRecord{
+				DeploymentId:    "deployment-1",
+				ApplicationId:   "app-1",
+				ApplicationName: "app",
+				EnvId:           "env-1",
+				Kind:            model.ApplicationKind_KUBERNETES,
+				Status:          model.DeploymentStatus_DEPLOYMENT_SUCCESS,
+				Version:         "v0.1.0",
+				CommitHash:      "hash",
+				CommitMessage:   "message",
+				CommitAuthor:    "author",
+				Commander:       "commander",
+				Approvers:       []string{"user-1", "user-2"},
+				CreatedAt:       100,
+				CompletedAt:     160,
+				Duration:        60,
+			},
+		},
+		{
+			name: "running deployment",
+			deployment: &model.Deployment{
+				Id:     "deployment-2",
+				Status: model.DeploymentStatus_DEPLOYMENT_RUNNING,
+				Trigger: &model.DeploymentTrigger{
+					Commit: &model.Commit{
+						Hash: "hash",
+					},
+				},
+				CreatedAt: 100,
+			},
+			expected: &apiservice.DeploymentHistoryRecord{
+				DeploymentId: "deployment-2",
+				Status:       model.DeploymentStatus_DEPLOYMENT_RUNNING,
+				CommitHash:   "hash",
+				CreatedAt:    100,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := makeDeploymentHistoryRecord(tc.deployment)
+			assert.Equal(t, tc.expected, r)
+		})
+	}
+}
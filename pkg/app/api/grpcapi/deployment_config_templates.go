@@ -52,4 +52,7 @@ var (
 	lambdaDeploymentConfigTemplates     = []*webservice.DeploymentConfigTemplate{}
 	cloudrunDeploymentConfigTemplates   = []*webservice.DeploymentConfigTemplate{}
 	ecsDeploymentConfigTemplates        = []*webservice.DeploymentConfigTemplate{}
+	vmDeploymentConfigTemplates         = []*webservice.DeploymentConfigTemplate{}
+	pulumiDeploymentConfigTemplates     = []*webservice.DeploymentConfigTemplate{}
+	ansibleDeploymentConfigTemplates    = []*webservice.DeploymentConfigTemplate{}
 )
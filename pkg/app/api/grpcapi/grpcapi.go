@@ -17,13 +17,18 @@ package grpcapi
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/commandstore"
+	"github.com/pipe-cd/pipe/pkg/app/api/manifestdiffstore"
 	"github.com/pipe-cd/pipe/pkg/datastore"
+	"github.com/pipe-cd/pipe/pkg/filestore"
 	"github.com/pipe-cd/pipe/pkg/git"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
@@ -36,6 +41,43 @@ type commandOutputPutter interface {
 	Put(ctx context.Context, commandID string, data []byte) error
 }
 
+type manifestDiffGetter interface {
+	Get(ctx context.Context, deploymentID string) ([]byte, error)
+}
+
+type manifestDiffPutter interface {
+	Put(ctx context.Context, deploymentID string, data []byte) error
+}
+
+type analysisResultGetter interface {
+	Get(ctx context.Context, deploymentID, stageID string) (*model.AnalysisResult, error)
+}
+
+type analysisResultPutter interface {
+	Put(ctx context.Context, result *model.AnalysisResult) error
+}
+
+type applicationLiveStateGetter interface {
+	GetStateSnapshot(ctx context.Context, applicationID string) (*model.ApplicationLiveStateSnapshot, error)
+}
+
+type deploymentProvenanceGetter interface {
+	Get(ctx context.Context, deploymentID string) (*model.DeploymentProvenance, error)
+}
+
+type deploymentProvenancePutter interface {
+	Put(ctx context.Context, provenance *model.DeploymentProvenance) error
+}
+
+type pipedConfigGetter interface {
+	Get(ctx context.Context, pipedID string, version int64) (*model.PipedConfig, error)
+}
+
+type pipedConfigStore interface {
+	pipedConfigGetter
+	Put(ctx context.Context, cfg *model.PipedConfig) error
+}
+
 func getPiped(ctx context.Context, store datastore.PipedStore, id string, logger *zap.Logger) (*model.Piped, error) {
 	piped, err := store.GetPiped(ctx, id)
 	if errors.Is(err, datastore.ErrNotFound) {
@@ -62,6 +104,52 @@ func getApplication(ctx context.Context, store datastore.ApplicationStore, id st
 	return app, nil
 }
 
+// resolveApplicationDependencies loads all direct and transitive dependencies of the given application
+// and returns them in the order they should be deployed.
+// All dependencies must belong to the given project, must not be deleted and must not form a cycle.
+func resolveApplicationDependencies(ctx context.Context, store datastore.ApplicationStore, projectID string, app *model.Application, logger *zap.Logger) ([]*model.Application, error) {
+	apps := map[string]*model.Application{
+		app.Id: app,
+	}
+	queue := append([]string(nil), app.Dependencies...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if _, ok := apps[id]; ok {
+			continue
+		}
+
+		dep, err := store.GetApplication(ctx, id)
+		if errors.Is(err, datastore.ErrNotFound) {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Dependency application %s is not found", id))
+		}
+		if err != nil {
+			logger.Error("failed to get dependency application", zap.String("application-id", id), zap.Error(err))
+			return nil, status.Error(codes.Internal, "Failed to get dependency application")
+		}
+		if dep.ProjectId != projectID {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Dependency application %s does not belong to your project", id))
+		}
+		if dep.Deleted {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Dependency application %s was deleted", id))
+		}
+
+		apps[id] = dep
+		queue = append(queue, dep.Dependencies...)
+	}
+
+	order, err := model.SortApplicationDependencies(app.Id, apps)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Invalid application dependencies: %v", err))
+	}
+
+	deps := make([]*model.Application, 0, len(order))
+	for _, id := range order {
+		deps = append(deps, apps[id])
+	}
+	return deps, nil
+}
+
 func listApplications(ctx context.Context, store datastore.ApplicationStore, opts datastore.ListOptions, logger *zap.Logger) ([]*model.Application, string, error) {
 	apps, cursor, err := store.ListApplications(ctx, opts)
 	if err != nil {
@@ -85,6 +173,135 @@ func getDeployment(ctx context.Context, store datastore.DeploymentStore, id stri
 	return deployment, nil
 }
 
+// makeApplicationHealth rolls up the current states of the given application into its health summary.
+// The live state is considered as unknown when it could not be loaded.
+func makeApplicationHealth(ctx context.Context, app *model.Application, liveStates applicationLiveStateGetter, deployments datastore.DeploymentStore, logger *zap.Logger) (*model.ApplicationHealth, error) {
+	healths, err := makeApplicationHealths(ctx, []*model.Application{app}, liveStates, deployments, logger)
+	if err != nil {
+		return nil, err
+	}
+	return healths[0], nil
+}
+
+// makeApplicationHealths is like makeApplicationHealth but for multiple applications.
+// The most recent deployments are loaded in batches and the live states are loaded concurrently.
+func makeApplicationHealths(ctx context.Context, apps []*model.Application, liveStates applicationLiveStateGetter, deployments datastore.DeploymentStore, logger *zap.Logger) ([]*model.ApplicationHealth, error) {
+	// The outcome of the most recent deployment is not stored in the application,
+	// so it is loaded only when the most recent deployment was not the successful one.
+	var ids []string
+	for _, app := range apps {
+		if triggered := app.MostRecentlyTriggeredDeployment; triggered != nil {
+			if succeeded := app.MostRecentlySuccessfulDeployment; succeeded == nil || succeeded.DeploymentId != triggered.DeploymentId {
+				ids = append(ids, triggered.DeploymentId)
+			}
+		}
+	}
+	loaded := make(map[string]*model.Deployment, len(ids))
+	// Firestore does not allow more than 10 values in an "in" filter.
+	const batchSize = 10
+	for start := 0; start < len(ids); start += batchSize {
+		end := start + batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		ds, _, err := deployments.ListDeployments(ctx, datastore.ListOptions{
+			Filters: []datastore.ListFilter{
+				{
+					Field:    "Id",
+					Operator: datastore.OperatorIn,
+					Value:    ids[start:end],
+				},
+			},
+		})
+		if err != nil {
+			logger.Error("failed to list the most recent deployments", zap.Error(err))
+			return nil, status.Error(codes.Internal, "Failed to list the most recent deployments")
+		}
+		for _, d := range ds {
+			loaded[d.Id] = d
+		}
+	}
+
+	// The live states are stored in the filestore which does not support batch reads.
+	const maxConcurrentReads = 10
+	var (
+		snapshots = make([]*model.ApplicationLiveStateSnapshot, len(apps))
+		sem       = make(chan struct{}, maxConcurrentReads)
+		wg        sync.WaitGroup
+	)
+	for i, app := range apps {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, app *model.Application) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			snapshot, err := liveStates.GetStateSnapshot(ctx, app.Id)
+			if err != nil {
+				if !errors.Is(err, filestore.ErrNotFound) {
+					logger.Warn("failed to get application live state", zap.String("application-id", app.Id), zap.Error(err))
+				}
+				return
+			}
+			snapshots[i] = snapshot
+		}(i, app)
+	}
+	wg.Wait()
+
+	healths := make([]*model.ApplicationHealth, 0, len(apps))
+	for i, app := range apps {
+		var mostRecent *model.Deployment
+		if triggered := app.MostRecentlyTriggeredDeployment; triggered != nil {
+			if d, ok := loaded[triggered.DeploymentId]; ok {
+				mostRecent = d
+			} else if succeeded := app.MostRecentlySuccessfulDeployment; succeeded != nil && succeeded.DeploymentId == triggered.DeploymentId {
+				mostRecent = &model.Deployment{
+					Id:     triggered.DeploymentId,
+					Status: model.DeploymentStatus_DEPLOYMENT_SUCCESS,
+				}
+			}
+		}
+		healths = append(healths, model.MakeApplicationHealth(app, snapshots[i], mostRecent))
+	}
+	return healths, nil
+}
+
+// makeApplicationMaintenance returns the maintenance mode enabled at the given time.
+// Zero duration means the maintenance mode never expires.
+func makeApplicationMaintenance(reason, enabledBy string, duration time.Duration, now time.Time) *model.ApplicationMaintenance {
+	m := &model.ApplicationMaintenance{
+		Reason:    reason,
+		EnabledBy: enabledBy,
+		EnabledAt: now.Unix(),
+	}
+	if duration > 0 {
+		m.ExpiresAt = now.Add(duration).Unix()
+	}
+	return m
+}
+
+// validateApprovalComment checks whether the given comment is enough
+// to approve or reject the given WAIT_APPROVAL stage.
+func validateApprovalComment(stage *model.PipelineStage, comment string) error {
+	if comment == "" && stage.Metadata[model.StageMetadataKeyApprovalCommentRequired] == "true" {
+		return status.Error(codes.InvalidArgument, "A comment is required to approve or reject this stage")
+	}
+	return nil
+}
+
+func getDeploymentManifestDiff(ctx context.Context, getter manifestDiffGetter, deploymentID string, logger *zap.Logger) (string, error) {
+	data, err := getter.Get(ctx, deploymentID)
+	if errors.Is(err, manifestdiffstore.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		logger.Error("failed to get manifest diff", zap.String("deployment-id", deploymentID), zap.Error(err))
+		return "", status.Error(codes.Internal, "Failed to get manifest diff")
+	}
+	return string(data), nil
+}
+
 func getCommand(ctx context.Context, store commandstore.Store, id string, logger *zap.Logger) (*model.Command, error) {
 	cmd, err := store.GetCommand(ctx, id)
 	if errors.Is(err, datastore.ErrNotFound) {
@@ -0,0 +1,167 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/manifestdiffstore"
+	"github.com/pipe-cd/pipe/pkg/datastore"
+	"github.com/pipe-cd/pipe/pkg/datastore/datastoretest"
+	"github.com/pipe-cd/pipe/pkg/filestore"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeManifestDiffGetter struct {
+	data []byte
+	err  error
+}
+
+func (g fakeManifestDiffGetter) Get(_ context.Context, _ string) ([]byte, error) {
+	return g.data, g.err
+}
+
+func TestGetDeploymentManifestDiff(t *testing.T) {
+	testcases := []struct {
+		name         string
+		getter       fakeManifestDiffGetter
+		expected     string
+		expectedCode codes.Code
+	}{
+		{
+			name:     "no diff was stored",
+			getter:   fakeManifestDiffGetter{err: manifestdiffstore.ErrNotFound},
+			expected: "",
+		},
+		{
+			name:         "failed to get diff",
+			getter:       fakeManifestDiffGetter{err: errors.New("unavailable")},
+			expectedCode: codes.Internal,
+		},
+		{
+			name:     "found diff",
+			getter:   fakeManifestDiffGetter{data: []byte("--- Running Commit\n+++ Target Commit\n")},
+			expected: "--- Running Commit\n+++ Target Commit\n",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := getDeploymentManifestDiff(context.Background(), tc.getter, "deployment-id", zap.NewNop())
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestValidateApprovalComment(t *testing.T) {
+	testcases := []struct {
+		name    string
+		stage   *model.PipelineStage
+		comment string
+		wantErr bool
+	}{
+		{
+			name:  "no comment is not required",
+			stage: &model.PipelineStage{},
+		},
+		{
+			name: "required comment is given",
+			stage: &model.PipelineStage{
+				Metadata: map[string]string{model.StageMetadataKeyApprovalCommentRequired: "true"},
+			},
+			comment: "checked the staging result",
+		},
+		{
+			name: "required comment is missing",
+			stage: &model.PipelineStage{
+				Metadata: map[string]string{model.StageMetadataKeyApprovalCommentRequired: "true"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateApprovalComment(tc.stage, tc.comment)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+type fakeApplicationLiveStateGetter map[string]*model.ApplicationLiveStateSnapshot
+
+func (g fakeApplicationLiveStateGetter) GetStateSnapshot(_ context.Context, id string) (*model.ApplicationLiveStateSnapshot, error) {
+	if s, ok := g[id]; ok {
+		return s, nil
+	}
+	return nil, filestore.ErrNotFound
+}
+
+func TestMakeApplicationHealths(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	apps := []*model.Application{
+		{
+			Id: "never-deployed",
+		},
+		{
+			Id:                               "succeeded",
+			MostRecentlyTriggeredDeployment:  &model.ApplicationDeploymentReference{DeploymentId: "deployment-1"},
+			MostRecentlySuccessfulDeployment: &model.ApplicationDeploymentReference{DeploymentId: "deployment-1"},
+		},
+		{
+			Id:                               "failed",
+			MostRecentlyTriggeredDeployment:  &model.ApplicationDeploymentReference{DeploymentId: "deployment-3"},
+			MostRecentlySuccessfulDeployment: &model.ApplicationDeploymentReference{DeploymentId: "deployment-2"},
+		},
+	}
+	liveStates := fakeApplicationLiveStateGetter{
+		"succeeded": {HealthStatus: model.ApplicationLiveStateSnapshot_HEALTHY},
+		"failed":    {HealthStatus: model.ApplicationLiveStateSnapshot_HEALTHY},
+	}
+	// Only the deployments whose outcome is unknown are loaded at once.
+	deployments := datastoretest.NewMockDeploymentStore(ctrl)
+	deployments.EXPECT().
+		ListDeployments(gomock.Any(), datastore.ListOptions{
+			Filters: []datastore.ListFilter{
+				{
+					Field:    "Id",
+					Operator: datastore.OperatorIn,
+					Value:    []string{"deployment-3"},
+				},
+			},
+		}).
+		Return([]*model.Deployment{{Id: "deployment-3", Status: model.DeploymentStatus_DEPLOYMENT_FAILURE}}, "", nil)
+
+	healths, err := makeApplicationHealths(context.Background(), apps, liveStates, deployments, zap.NewNop())
+	require.NoError(t, err)
+	require.Len(t, healths, 3)
+
+	assert.Equal(t, model.ApplicationLiveStateSnapshot_UNKNOWN, healths[0].LiveStateStatus)
+	assert.Equal(t, "", healths[0].MostRecentDeploymentId)
+	assert.Equal(t, model.ApplicationLiveStateSnapshot_HEALTHY, healths[1].LiveStateStatus)
+	assert.Equal(t, model.DeploymentStatus_DEPLOYMENT_SUCCESS, healths[1].MostRecentDeploymentStatus)
+	assert.Equal(t, "deployment-3", healths[2].MostRecentDeploymentId)
+	assert.Equal(t, model.DeploymentStatus_DEPLOYMENT_FAILURE, healths[2].MostRecentDeploymentStatus)
+}
@@ -17,24 +17,35 @@ package grpcapi
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/applicationlivestatestore"
 	"github.com/pipe-cd/pipe/pkg/app/api/commandstore"
+	"github.com/pipe-cd/pipe/pkg/app/api/deploymentlockstore"
+	"github.com/pipe-cd/pipe/pkg/app/api/pipedcertissuer"
+	"github.com/pipe-cd/pipe/pkg/app/api/pipedconfigstore"
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
 	"github.com/pipe-cd/pipe/pkg/app/api/stagelogstore"
 	"github.com/pipe-cd/pipe/pkg/cache"
 	"github.com/pipe-cd/pipe/pkg/cache/memorycache"
+	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/datastore"
 	"github.com/pipe-cd/pipe/pkg/model"
 	"github.com/pipe-cd/pipe/pkg/rpc/rpcauth"
 )
 
+// pipedHealthWriteInterval is the maximum interval between writes of the reported health
+// of a piped whose status is unchanged, to keep its timestamps and resource usage fresh enough.
+const pipedHealthWriteInterval = 10 * time.Minute
+
 // PipedAPI implements the behaviors for the gRPC definitions of PipedAPI.
 type PipedAPI struct {
 	applicationStore          datastore.ApplicationStore
@@ -47,17 +58,27 @@ type PipedAPI struct {
 	applicationLiveStateStore applicationlivestatestore.Store
 	commandStore              commandstore.Store
 	commandOutputPutter       commandOutputPutter
+	manifestDiffPutter        manifestDiffPutter
+	analysisResultPutter      analysisResultPutter
+	provenancePutter          deploymentProvenancePutter
+	pipedConfigGetter         pipedConfigGetter
+	deploymentLockStore       deploymentlockstore.Store
+	pipedCertIssuer           *pipedcertissuer.Issuer
+	quotaChecker              *quotaChecker
 
 	appPipedCache        cache.Cache
+	appEnvCache          cache.Cache
+	pipedEnvCache        cache.Cache
 	deploymentPipedCache cache.Cache
 	envProjectCache      cache.Cache
 	pipedStatCache       cache.Cache
+	pipedHealthCache     cache.Cache
 
 	logger *zap.Logger
 }
 
 // NewPipedAPI creates a new PipedAPI instance.
-func NewPipedAPI(ctx context.Context, ds datastore.DataStore, sls stagelogstore.Store, alss applicationlivestatestore.Store, cs commandstore.Store, hc cache.Cache, cop commandOutputPutter, logger *zap.Logger) *PipedAPI {
+func NewPipedAPI(ctx context.Context, ds datastore.DataStore, sls stagelogstore.Store, alss applicationlivestatestore.Store, cs commandstore.Store, hc cache.Cache, cop commandOutputPutter, mdp manifestDiffPutter, arp analysisResultPutter, dpp deploymentProvenancePutter, pcg pipedConfigGetter, dls deploymentlockstore.Store, pci *pipedcertissuer.Issuer, quotas config.ControlPlaneQuotas, logger *zap.Logger) *PipedAPI {
 	a := &PipedAPI{
 		applicationStore:          datastore.NewApplicationStore(ds),
 		deploymentStore:           datastore.NewDeploymentStore(ds),
@@ -69,10 +90,20 @@ func NewPipedAPI(ctx context.Context, ds datastore.DataStore, sls stagelogstore.
 		applicationLiveStateStore: alss,
 		commandStore:              cs,
 		commandOutputPutter:       cop,
+		manifestDiffPutter:        mdp,
+		analysisResultPutter:      arp,
+		provenancePutter:          dpp,
+		pipedConfigGetter:         pcg,
+		deploymentLockStore:       dls,
+		pipedCertIssuer:           pci,
+		quotaChecker:              newQuotaChecker(ds, quotas, logger.Named("piped-api")),
 		appPipedCache:             memorycache.NewTTLCache(ctx, 24*time.Hour, 3*time.Hour),
+		appEnvCache:               memorycache.NewTTLCache(ctx, 24*time.Hour, 3*time.Hour),
+		pipedEnvCache:             memorycache.NewTTLCache(ctx, 5*time.Minute, time.Minute),
 		deploymentPipedCache:      memorycache.NewTTLCache(ctx, 24*time.Hour, 3*time.Hour),
 		envProjectCache:           memorycache.NewTTLCache(ctx, 24*time.Hour, 3*time.Hour),
 		pipedStatCache:            hc,
+		pipedHealthCache:          memorycache.NewTTLCache(ctx, pipedHealthWriteInterval, time.Minute),
 		logger:                    logger.Named("piped-api"),
 	}
 	return a
@@ -105,9 +136,60 @@ func (a *PipedAPI) ReportStat(ctx context.Context, req *pipedservice.ReportStatR
 		)
 		return nil, status.Error(codes.Internal, "failed to store the reported piped stat")
 	}
+	// The health is just an additional data for viewing,
+	// so a failure while storing it does not fail the report.
+	if req.Health != nil {
+		a.storePipedHealth(ctx, pipedID, req.Health)
+	}
 	return &pipedservice.ReportStatResponse{}, nil
 }
 
+// storePipedHealth writes the given health of piped to the datastore
+// only when its status was changed since the last write
+// or the last write is older than pipedHealthWriteInterval.
+func (a *PipedAPI) storePipedHealth(ctx context.Context, pipedID string, health *model.PipedHealth) {
+	healthStatus, err := pipedHealthStatus(health)
+	if err != nil {
+		a.logger.Error("failed to marshal the reported piped health",
+			zap.String("piped-id", pipedID),
+			zap.Error(err),
+		)
+		return
+	}
+	if last, err := a.pipedHealthCache.Get(pipedID); err == nil && last.(string) == healthStatus {
+		return
+	}
+	if err := a.pipedStore.UpdatePiped(ctx, pipedID, datastore.PipedHealthUpdater(health)); err != nil {
+		a.logger.Error("failed to store the reported piped health",
+			zap.String("piped-id", pipedID),
+			zap.Error(err),
+		)
+		return
+	}
+	a.pipedHealthCache.Put(pipedID, healthStatus)
+}
+
+// pipedHealthStatus returns the status part of the given health
+// which excludes the timestamps and resource usage changing at every report.
+func pipedHealthStatus(health *model.PipedHealth) (string, error) {
+	h := &model.PipedHealth{
+		CloudProviders: health.CloudProviders,
+		Repositories:   make([]*model.PipedHealth_Repository, 0, len(health.Repositories)),
+		Tools:          health.Tools,
+	}
+	for _, r := range health.Repositories {
+		h.Repositories = append(h.Repositories, &model.PipedHealth_Repository{
+			Id:        r.Id,
+			LastError: r.LastError,
+		})
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
 // ReportPipedMeta is sent by piped while starting up to report its metadata
 // such as configured cloud providers.
 func (a *PipedAPI) ReportPipedMeta(ctx context.Context, req *pipedservice.ReportPipedMetaRequest) (*pipedservice.ReportPipedMetaResponse, error) {
@@ -136,6 +218,68 @@ func (a *PipedAPI) ReportPipedMeta(ctx context.Context, req *pipedservice.Report
 	return &pipedservice.ReportPipedMetaResponse{}, nil
 }
 
+// ReportPipedDiagnostics is sent by piped to report the result of its self-diagnostics.
+func (a *PipedAPI) ReportPipedDiagnostics(ctx context.Context, req *pipedservice.ReportPipedDiagnosticsRequest) (*pipedservice.ReportPipedDiagnosticsResponse, error) {
+	_, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = a.pipedStore.UpdatePiped(ctx, pipedID, datastore.PipedDiagnosticsUpdater(req.Diagnostics)); err != nil {
+		switch err {
+		case datastore.ErrNotFound:
+			return nil, status.Error(codes.InvalidArgument, "piped is not found")
+		case datastore.ErrInvalidArgument:
+			return nil, status.Error(codes.InvalidArgument, "invalid value for update")
+		default:
+			a.logger.Error("failed to update the piped diagnostics",
+				zap.String("piped-id", pipedID),
+				zap.Error(err),
+			)
+			return nil, status.Error(codes.Internal, "failed to update the piped diagnostics")
+		}
+	}
+	return &pipedservice.ReportPipedDiagnosticsResponse{}, nil
+}
+
+// GetPipedConfig returns the configuration of piped
+// which is stored and managed in the control-plane.
+func (a *PipedAPI) GetPipedConfig(ctx context.Context, req *pipedservice.GetPipedConfigRequest) (*pipedservice.GetPipedConfigResponse, error) {
+	_, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	version := req.Version
+	if version == 0 {
+		piped, err := getPiped(ctx, a.pipedStore, pipedID, a.logger)
+		if err != nil {
+			return nil, err
+		}
+		if piped.ConfigVersion == 0 {
+			return nil, status.Error(codes.NotFound, "piped config is not managed by control-plane")
+		}
+		version = piped.ConfigVersion
+	}
+
+	cfg, err := a.pipedConfigGetter.Get(ctx, pipedID, version)
+	if errors.Is(err, pipedconfigstore.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "piped config is not found")
+	}
+	if err != nil {
+		a.logger.Error("failed to get piped config",
+			zap.String("piped-id", pipedID),
+			zap.Int64("version", version),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, "failed to get piped config")
+	}
+
+	return &pipedservice.GetPipedConfigResponse{
+		Config: cfg,
+	}, nil
+}
+
 // GetEnvironment finds and returns the environment for the specified ID.
 func (a *PipedAPI) GetEnvironment(ctx context.Context, req *pipedservice.GetEnvironmentRequest) (*pipedservice.GetEnvironmentResponse, error) {
 	projectID, _, _, err := rpcauth.ExtractPipedToken(ctx)
@@ -193,8 +337,22 @@ func (a *PipedAPI) ListApplications(ctx context.Context, req *pipedservice.ListA
 		a.logger.Error("failed to fetch applications", zap.Error(err))
 		return nil, status.Error(codes.Internal, "failed to fetch applications")
 	}
+
+	piped, err := a.pipedStore.GetPiped(ctx, pipedID)
+	if err != nil {
+		a.logger.Error("failed to get piped", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to get piped")
+	}
+	// The applications assigned before the piped was bound to its environments
+	// are not sent to ensure that the piped never receives their data.
+	allowed := apps[:0]
+	for _, app := range apps {
+		if piped.AllowsEnvironment(app.EnvId) {
+			allowed = append(allowed, app)
+		}
+	}
 	return &pipedservice.ListApplicationsResponse{
-		Applications: apps,
+		Applications: allowed,
 	}, nil
 }
 
@@ -349,8 +507,22 @@ func (a *PipedAPI) ListNotCompletedDeployments(ctx context.Context, req *pipedse
 		a.logger.Error("failed to fetch deployments", zap.Error(err))
 		return nil, status.Error(codes.Internal, "failed to fetch deployments")
 	}
+
+	piped, err := a.pipedStore.GetPiped(ctx, pipedID)
+	if err != nil {
+		a.logger.Error("failed to get piped", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to get piped")
+	}
+	// The deployments created before the piped was bound to its environments
+	// are not sent to ensure that the piped never executes them.
+	allowed := deployments[:0]
+	for _, d := range deployments {
+		if piped.AllowsEnvironment(d.EnvId) {
+			allowed = append(allowed, d)
+		}
+	}
 	return &pipedservice.ListNotCompletedDeploymentsResponse{
-		Deployments: deployments,
+		Deployments: allowed,
 		Cursor:      cursor,
 	}, nil
 }
@@ -359,13 +531,16 @@ func (a *PipedAPI) ListNotCompletedDeployments(ctx context.Context, req *pipedse
 // that is managed by this piped.
 // This will be used by DeploymentTrigger component.
 func (a *PipedAPI) CreateDeployment(ctx context.Context, req *pipedservice.CreateDeploymentRequest) (*pipedservice.CreateDeploymentResponse, error) {
-	_, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	projectID, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
 	if err != nil {
 		return nil, err
 	}
 	if err := a.validateAppBelongsToPiped(ctx, req.Deployment.ApplicationId, pipedID); err != nil {
 		return nil, err
 	}
+	if err := a.quotaChecker.checkConcurrentDeployments(ctx, projectID); err != nil {
+		return nil, err
+	}
 
 	err = a.deploymentStore.AddDeployment(ctx, req.Deployment)
 	if errors.Is(err, datastore.ErrAlreadyExists) {
@@ -389,7 +564,18 @@ func (a *PipedAPI) ReportDeploymentPlanned(ctx context.Context, req *pipedservic
 		return nil, err
 	}
 
-	updater := datastore.DeploymentToPlannedUpdater(req.Summary, req.StatusReason, req.RunningCommitHash, req.Version, req.Stages)
+	// The manifest diff is just an additional data for viewing,
+	// so a failure while storing it should not block the deployment.
+	if req.ManifestDiff != "" {
+		if err := a.manifestDiffPutter.Put(ctx, req.DeploymentId, []byte(req.ManifestDiff)); err != nil {
+			a.logger.Error("failed to store manifest diff of deployment",
+				zap.String("deployment-id", req.DeploymentId),
+				zap.Error(err),
+			)
+		}
+	}
+
+	updater := datastore.DeploymentToPlannedUpdater(req.Summary, req.StatusReason, req.RunningCommitHash, req.Version, req.Versions, req.Stages)
 	err = a.deploymentStore.UpdateDeployment(ctx, req.DeploymentId, updater)
 	if err != nil {
 		switch err {
@@ -492,6 +678,36 @@ func (a *PipedAPI) SaveDeploymentMetadata(ctx context.Context, req *pipedservice
 	return &pipedservice.SaveDeploymentMetadataResponse{}, nil
 }
 
+// SaveDeploymentCheckpoint used by piped to persist the progress
+// of the pipeline of a specific deployment.
+func (a *PipedAPI) SaveDeploymentCheckpoint(ctx context.Context, req *pipedservice.SaveDeploymentCheckpointRequest) (*pipedservice.SaveDeploymentCheckpointResponse, error) {
+	_, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.validateDeploymentBelongsToPiped(ctx, req.DeploymentId, pipedID); err != nil {
+		return nil, err
+	}
+
+	updater := datastore.DeploymentCheckpointUpdater(req.Checkpoint)
+	err = a.deploymentStore.UpdateDeployment(ctx, req.DeploymentId, updater)
+	if err != nil {
+		switch {
+		case errors.Is(err, datastore.ErrNotFound):
+			return nil, status.Error(codes.InvalidArgument, "deployment is not found")
+		case errors.Is(err, datastore.ErrInvalidArgument):
+			return nil, status.Error(codes.InvalidArgument, "deployment is already completed")
+		default:
+			a.logger.Error("failed to save deployment checkpoint",
+				zap.String("deployment-id", req.DeploymentId),
+				zap.Error(err),
+			)
+			return nil, status.Error(codes.Internal, "failed to save deployment checkpoint")
+		}
+	}
+	return &pipedservice.SaveDeploymentCheckpointResponse{}, nil
+}
+
 // SaveStageMetadata used by piped to persist the metadata
 // of a specific stage of a deployment.
 func (a *PipedAPI) SaveStageMetadata(ctx context.Context, req *pipedservice.SaveStageMetadataRequest) (*pipedservice.SaveStageMetadataResponse, error) {
@@ -522,6 +738,82 @@ func (a *PipedAPI) SaveStageMetadata(ctx context.Context, req *pipedservice.Save
 	return &pipedservice.SaveStageMetadataResponse{}, nil
 }
 
+// SaveStageResults used by piped to persist the key results
+// of a specific stage of a deployment.
+func (a *PipedAPI) SaveStageResults(ctx context.Context, req *pipedservice.SaveStageResultsRequest) (*pipedservice.SaveStageResultsResponse, error) {
+	_, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.validateDeploymentBelongsToPiped(ctx, req.DeploymentId, pipedID); err != nil {
+		return nil, err
+	}
+
+	err = a.deploymentStore.PutDeploymentStageResults(ctx, req.DeploymentId, req.StageId, req.Results)
+	if err != nil {
+		switch errors.Unwrap(err) {
+		case datastore.ErrNotFound:
+			return nil, status.Error(codes.InvalidArgument, "deployment is not found")
+		case datastore.ErrInvalidArgument:
+			return nil, status.Error(codes.InvalidArgument, "invalid value for update")
+		default:
+			a.logger.Error("failed to save deployment stage results",
+				zap.String("deployment-id", req.DeploymentId),
+				zap.String("stage-id", req.StageId),
+				zap.Error(err),
+			)
+			return nil, status.Error(codes.Internal, "failed to save deployment stage results")
+		}
+	}
+	return &pipedservice.SaveStageResultsResponse{}, nil
+}
+
+// ReportAnalysisResult is sent by piped to persist the detailed data
+// collected while running an ANALYSIS stage.
+func (a *PipedAPI) ReportAnalysisResult(ctx context.Context, req *pipedservice.ReportAnalysisResultRequest) (*pipedservice.ReportAnalysisResultResponse, error) {
+	_, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.validateDeploymentBelongsToPiped(ctx, req.Result.DeploymentId, pipedID); err != nil {
+		return nil, err
+	}
+
+	if err := a.analysisResultPutter.Put(ctx, req.Result); err != nil {
+		a.logger.Error("failed to save analysis result",
+			zap.String("deployment-id", req.Result.DeploymentId),
+			zap.String("stage-id", req.Result.StageId),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, "failed to save analysis result")
+	}
+	return &pipedservice.ReportAnalysisResultResponse{}, nil
+}
+
+// ReportDeploymentProvenance is sent by piped to persist the provenance
+// recorded while applying the manifests of a deployment.
+func (a *PipedAPI) ReportDeploymentProvenance(ctx context.Context, req *pipedservice.ReportDeploymentProvenanceRequest) (*pipedservice.ReportDeploymentProvenanceResponse, error) {
+	_, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.validateDeploymentBelongsToPiped(ctx, req.Provenance.DeploymentId, pipedID); err != nil {
+		return nil, err
+	}
+	if req.Provenance.PipedId != pipedID {
+		return nil, status.Error(codes.PermissionDenied, "The provenance must be recorded by the current piped")
+	}
+
+	if err := a.provenancePutter.Put(ctx, req.Provenance); err != nil {
+		a.logger.Error("failed to save deployment provenance",
+			zap.String("deployment-id", req.Provenance.DeploymentId),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, "failed to save deployment provenance")
+	}
+	return &pipedservice.ReportDeploymentProvenanceResponse{}, nil
+}
+
 // ReportStageLogs is sent by piped to save the log of a pipeline stage.
 func (a *PipedAPI) ReportStageLogs(ctx context.Context, req *pipedservice.ReportStageLogsRequest) (*pipedservice.ReportStageLogsResponse, error) {
 	_, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
@@ -614,11 +906,187 @@ func (a *PipedAPI) ListUnhandledCommands(ctx context.Context, req *pipedservice.
 		a.logger.Error("failed to fetch unhandled commands", zap.Error(err))
 		return nil, status.Error(codes.Internal, "failed to unhandled commands")
 	}
+
+	cmds, err = a.filterCommandsInEnvScope(ctx, pipedID, cmds)
+	if err != nil {
+		return nil, err
+	}
+	cmds, err = a.filterCommandsWaitingDependencies(ctx, cmds)
+	if err != nil {
+		return nil, err
+	}
 	return &pipedservice.ListUnhandledCommandsResponse{
 		Commands: cmds,
 	}, nil
 }
 
+// filterCommandsInEnvScope drops the commands for the applications
+// of the environments the given piped is not bound to.
+func (a *PipedAPI) filterCommandsInEnvScope(ctx context.Context, pipedID string, cmds []*model.Command) ([]*model.Command, error) {
+	if len(cmds) == 0 {
+		return cmds, nil
+	}
+	piped, err := a.pipedStore.GetPiped(ctx, pipedID)
+	if err != nil {
+		a.logger.Error("failed to get piped", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to get piped")
+	}
+	if len(piped.EnvIds) == 0 {
+		return cmds, nil
+	}
+
+	appEnvs, err := a.getApplicationEnvs(ctx, cmds)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make([]*model.Command, 0, len(cmds))
+	for _, cmd := range cmds {
+		if cmd.ApplicationId == "" {
+			allowed = append(allowed, cmd)
+			continue
+		}
+		envID, ok := appEnvs[cmd.ApplicationId]
+		if !ok || !piped.AllowsEnvironment(envID) {
+			a.logger.Warn("ignore the command for an application outside the environments of the piped",
+				zap.String("command-id", cmd.Id),
+				zap.String("application-id", cmd.ApplicationId),
+				zap.String("piped-id", pipedID),
+			)
+			continue
+		}
+		allowed = append(allowed, cmd)
+	}
+	return allowed, nil
+}
+
+// The key of the command metadata where piped stores the ID of the deployment triggered by a sync command.
+const triggeredDeploymentIDKey = "TriggeredDeploymentID"
+
+// filterCommandsWaitingDependencies holds back the sync commands until all commands
+// syncing the dependencies of their applications have triggered a deployment that completed successfully.
+// The held commands are marked as failed once any of those did not succeed.
+func (a *PipedAPI) filterCommandsWaitingDependencies(ctx context.Context, cmds []*model.Command) ([]*model.Command, error) {
+	ready := make([]*model.Command, 0, len(cmds))
+	for _, cmd := range cmds {
+		ids := cmd.GetSyncApplication().GetDependencyCommandIds()
+		if len(ids) == 0 {
+			ready = append(ready, cmd)
+			continue
+		}
+
+		done, failure, err := a.checkDependencyCommands(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		if failure != "" {
+			a.logger.Info("sync command will not be handled since one of its dependencies did not succeed",
+				zap.String("command-id", cmd.Id),
+				zap.String("application-id", cmd.ApplicationId),
+				zap.String("reason", failure),
+			)
+			if err := a.commandStore.UpdateCommandHandled(ctx, cmd.Id, model.CommandStatus_COMMAND_FAILED, nil, time.Now().Unix()); err != nil {
+				a.logger.Error("failed to mark sync command as failed", zap.String("command-id", cmd.Id), zap.Error(err))
+			}
+			continue
+		}
+		if done {
+			ready = append(ready, cmd)
+		}
+	}
+	return ready, nil
+}
+
+// checkDependencyCommands returns true when the deployments triggered by all given sync commands have succeeded.
+// A non-empty failure is returned when any of them will never succeed.
+func (a *PipedAPI) checkDependencyCommands(ctx context.Context, ids []string) (done bool, failure string, err error) {
+	for _, id := range ids {
+		cmd, err := a.commandStore.GetCommand(ctx, id)
+		if err != nil {
+			a.logger.Error("failed to get dependency command", zap.String("command-id", id), zap.Error(err))
+			return false, "", status.Error(codes.Internal, "failed to get dependency command")
+		}
+		switch cmd.Status {
+		case model.CommandStatus_COMMAND_NOT_HANDLED_YET:
+			return false, "", nil
+		case model.CommandStatus_COMMAND_SUCCEEDED:
+		default:
+			return false, fmt.Sprintf("sync command %s of application %s is at %s status", id, cmd.ApplicationId, cmd.Status), nil
+		}
+
+		deploymentID := cmd.Metadata[triggeredDeploymentIDKey]
+		if deploymentID == "" {
+			return false, fmt.Sprintf("sync command %s of application %s did not trigger any deployment", id, cmd.ApplicationId), nil
+		}
+		d, err := a.deploymentStore.GetDeployment(ctx, deploymentID)
+		if err != nil {
+			a.logger.Error("failed to get deployment of dependency", zap.String("deployment-id", deploymentID), zap.Error(err))
+			return false, "", status.Error(codes.Internal, "failed to get deployment of dependency")
+		}
+		if !model.IsCompletedDeployment(d.Status) {
+			return false, "", nil
+		}
+		if d.Status != model.DeploymentStatus_DEPLOYMENT_SUCCESS {
+			return false, fmt.Sprintf("deployment %s of application %s finished with %s status", d.Id, d.ApplicationId, d.Status), nil
+		}
+	}
+	return true, "", nil
+}
+
+// getApplicationEnvs returns a map from application ID to environment ID
+// for the applications the given commands are targeting.
+// The applications missing in the cache are fetched by batched queries.
+func (a *PipedAPI) getApplicationEnvs(ctx context.Context, cmds []*model.Command) (map[string]string, error) {
+	var (
+		envs    = make(map[string]string, len(cmds))
+		missing = make([]string, 0, len(cmds))
+		seen    = make(map[string]struct{}, len(cmds))
+	)
+	for _, cmd := range cmds {
+		if cmd.ApplicationId == "" {
+			continue
+		}
+		if _, ok := seen[cmd.ApplicationId]; ok {
+			continue
+		}
+		seen[cmd.ApplicationId] = struct{}{}
+
+		if envID, err := a.appEnvCache.Get(cmd.ApplicationId); err == nil {
+			envs[cmd.ApplicationId] = envID.(string)
+			continue
+		}
+		missing = append(missing, cmd.ApplicationId)
+	}
+
+	// Firestore does not allow more than 10 values in an "in" filter.
+	const batchSize = 10
+	for start := 0; start < len(missing); start += batchSize {
+		end := start + batchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		opts := datastore.ListOptions{
+			Filters: []datastore.ListFilter{
+				{
+					Field:    "Id",
+					Operator: datastore.OperatorIn,
+					Value:    missing[start:end],
+				},
+			},
+		}
+		apps, _, err := a.applicationStore.ListApplications(ctx, opts)
+		if err != nil {
+			a.logger.Error("failed to list applications", zap.Error(err))
+			return nil, status.Error(codes.Internal, "failed to list applications")
+		}
+		for _, app := range apps {
+			envs[app.Id] = app.EnvId
+			a.appEnvCache.Put(app.Id, app.EnvId)
+		}
+	}
+	return envs, nil
+}
+
 // ReportCommandHandled is called by piped to mark a specific command as handled.
 // The request payload will contain the handle status as well as any additional result data.
 // The handle result should be updated to both datastore and cache (for reading from web).
@@ -831,6 +1299,163 @@ func (a *PipedAPI) ListEvents(ctx context.Context, req *pipedservice.ListEventsR
 	}, nil
 }
 
+// RegisterEvent registers a new event received by the webhook receiver of piped.
+func (a *PipedAPI) RegisterEvent(ctx context.Context, req *pipedservice.RegisterEventRequest) (*pipedservice.RegisterEventResponse, error) {
+	projectID, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.eventStore.AddEvent(ctx, model.Event{
+		Id:        uuid.New().String(),
+		Name:      req.Name,
+		Data:      req.Data,
+		Labels:    req.Labels,
+		EventKey:  model.MakeEventKey(req.Name, req.Labels),
+		ProjectId: projectID,
+	})
+	if errors.Is(err, datastore.ErrAlreadyExists) {
+		return nil, status.Error(codes.AlreadyExists, "the event already exists")
+	}
+	if err != nil {
+		a.logger.Error("failed to register event",
+			zap.String("piped-id", pipedID),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, "failed to register event")
+	}
+
+	return &pipedservice.RegisterEventResponse{}, nil
+}
+
+// AcquireDeploymentLocks tries to acquire all of the given locks for a deployment.
+// Nothing is acquired when one of them is held by another deployment in the same project.
+// Calling this again for the same deployment extends the expiration of its locks.
+func (a *PipedAPI) AcquireDeploymentLocks(ctx context.Context, req *pipedservice.AcquireDeploymentLocksRequest) (*pipedservice.AcquireDeploymentLocksResponse, error) {
+	projectID, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.validateDeploymentBelongsToPiped(ctx, req.DeploymentId, pipedID); err != nil {
+		return nil, err
+	}
+
+	holders, err := a.deploymentLockStore.Acquire(ctx, projectID, req.DeploymentId, req.Locks)
+	if err != nil {
+		a.logger.Error("failed to acquire deployment locks",
+			zap.String("deployment-id", req.DeploymentId),
+			zap.Strings("locks", req.Locks),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, "failed to acquire deployment locks")
+	}
+
+	return &pipedservice.AcquireDeploymentLocksResponse{
+		Acquired: len(holders) == 0,
+		Holders:  holders,
+	}, nil
+}
+
+// ReleaseDeploymentLocks releases the locks held by a deployment.
+func (a *PipedAPI) ReleaseDeploymentLocks(ctx context.Context, req *pipedservice.ReleaseDeploymentLocksRequest) (*pipedservice.ReleaseDeploymentLocksResponse, error) {
+	projectID, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.validateDeploymentBelongsToPiped(ctx, req.DeploymentId, pipedID); err != nil {
+		return nil, err
+	}
+
+	if err := a.deploymentLockStore.Release(ctx, projectID, req.DeploymentId, req.Locks); err != nil {
+		a.logger.Error("failed to release deployment locks",
+			zap.String("deployment-id", req.DeploymentId),
+			zap.Strings("locks", req.Locks),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, "failed to release deployment locks")
+	}
+
+	return &pipedservice.ReleaseDeploymentLocksResponse{}, nil
+}
+
+// AcquireDeploymentLease tries to acquire the lease of a deployment for a piped instance.
+// Calling this again by the same instance extends the expiration of the lease.
+func (a *PipedAPI) AcquireDeploymentLease(ctx context.Context, req *pipedservice.AcquireDeploymentLeaseRequest) (*pipedservice.AcquireDeploymentLeaseResponse, error) {
+	projectID, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.validateDeploymentBelongsToPiped(ctx, req.DeploymentId, pipedID); err != nil {
+		return nil, err
+	}
+
+	holder, err := a.deploymentLockStore.AcquireLease(ctx, projectID, req.DeploymentId, req.InstanceId)
+	if err != nil {
+		a.logger.Error("failed to acquire deployment lease",
+			zap.String("deployment-id", req.DeploymentId),
+			zap.String("instance-id", req.InstanceId),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, "failed to acquire deployment lease")
+	}
+
+	return &pipedservice.AcquireDeploymentLeaseResponse{
+		Acquired: holder == "",
+		Holder:   holder,
+	}, nil
+}
+
+// ReleaseDeploymentLease releases the lease of a deployment held by a piped instance.
+func (a *PipedAPI) ReleaseDeploymentLease(ctx context.Context, req *pipedservice.ReleaseDeploymentLeaseRequest) (*pipedservice.ReleaseDeploymentLeaseResponse, error) {
+	projectID, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.validateDeploymentBelongsToPiped(ctx, req.DeploymentId, pipedID); err != nil {
+		return nil, err
+	}
+
+	if err := a.deploymentLockStore.ReleaseLease(ctx, projectID, req.DeploymentId, req.InstanceId); err != nil {
+		a.logger.Error("failed to release deployment lease",
+			zap.String("deployment-id", req.DeploymentId),
+			zap.String("instance-id", req.InstanceId),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, "failed to release deployment lease")
+	}
+
+	return &pipedservice.ReleaseDeploymentLeaseResponse{}, nil
+}
+
+// RenewPipedCertificate issues a new client certificate for the piped
+// by signing the given certificate signing request.
+func (a *PipedAPI) RenewPipedCertificate(ctx context.Context, req *pipedservice.RenewPipedCertificateRequest) (*pipedservice.RenewPipedCertificateResponse, error) {
+	_, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if a.pipedCertIssuer == nil {
+		return nil, status.Error(codes.FailedPrecondition, "issuing piped certificates is not enabled in the control plane")
+	}
+
+	cert, err := a.pipedCertIssuer.Issue(req.Csr, pipedID)
+	if errors.Is(err, pipedcertissuer.ErrInvalidCSR) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		a.logger.Error("failed to issue piped certificate",
+			zap.String("piped-id", pipedID),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, "failed to issue piped certificate")
+	}
+	a.logger.Info("issued a new certificate for piped", zap.String("piped-id", pipedID))
+
+	return &pipedservice.RenewPipedCertificateResponse{
+		Certificate: cert,
+	}, nil
+}
+
 // validateAppBelongsToPiped checks if the given application belongs to the given piped.
 // It gives back an error unless the application belongs to the piped.
 func (a *PipedAPI) validateAppBelongsToPiped(ctx context.Context, appID, pipedID string) error {
@@ -839,7 +1464,7 @@ func (a *PipedAPI) validateAppBelongsToPiped(ctx context.Context, appID, pipedID
 		if pid != pipedID {
 			return status.Error(codes.PermissionDenied, "requested application doesn't belong to the piped")
 		}
-		return nil
+		return a.validateAppInEnvScope(ctx, appID, pipedID)
 	}
 
 	app, err := a.applicationStore.GetApplication(ctx, appID)
@@ -851,10 +1476,48 @@ func (a *PipedAPI) validateAppBelongsToPiped(ctx context.Context, appID, pipedID
 		return status.Error(codes.Internal, "failed to get application")
 	}
 	a.appPipedCache.Put(appID, app.PipedId)
+	a.appEnvCache.Put(appID, app.EnvId)
 
 	if app.PipedId != pipedID {
 		return status.Error(codes.PermissionDenied, "requested application doesn't belong to the piped")
 	}
+	return a.validateAppInEnvScope(ctx, appID, pipedID)
+}
+
+// validateAppInEnvScope checks if the given application is placed
+// in one of the environments the given piped is bound to.
+// The environments of pipeds are cached only for a few minutes to apply their changes soon.
+func (a *PipedAPI) validateAppInEnvScope(ctx context.Context, appID, pipedID string) error {
+	var envIDs []string
+	if v, err := a.pipedEnvCache.Get(pipedID); err == nil {
+		envIDs = v.([]string)
+	} else {
+		piped, err := a.pipedStore.GetPiped(ctx, pipedID)
+		if err != nil {
+			a.logger.Error("failed to get piped", zap.Error(err))
+			return status.Error(codes.Internal, "failed to get piped")
+		}
+		envIDs = piped.EnvIds
+		a.pipedEnvCache.Put(pipedID, envIDs)
+	}
+	if len(envIDs) == 0 {
+		return nil
+	}
+
+	envID, err := a.appEnvCache.Get(appID)
+	if err != nil {
+		app, err := a.applicationStore.GetApplication(ctx, appID)
+		if err != nil {
+			a.logger.Error("failed to get application", zap.Error(err))
+			return status.Error(codes.Internal, "failed to get application")
+		}
+		envID = app.EnvId
+		a.appEnvCache.Put(appID, app.EnvId)
+	}
+	piped := &model.Piped{EnvIds: envIDs}
+	if !piped.AllowsEnvironment(envID.(string)) {
+		return status.Error(codes.PermissionDenied, "requested application is outside the environments of the piped")
+	}
 	return nil
 }
 
@@ -18,12 +18,16 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 
+	"github.com/pipe-cd/pipe/pkg/app/api/commandstore"
 	"github.com/pipe-cd/pipe/pkg/cache"
 	"github.com/pipe-cd/pipe/pkg/cache/cachetest"
+	"github.com/pipe-cd/pipe/pkg/cache/memorycache"
 	"github.com/pipe-cd/pipe/pkg/datastore"
 	"github.com/pipe-cd/pipe/pkg/datastore/datastoretest"
 	"github.com/pipe-cd/pipe/pkg/model"
@@ -41,6 +45,9 @@ func TestValidateAppBelongsToPiped(t *testing.T) {
 		appID            string
 		pipedID          string
 		appPipedCache    cache.Cache
+		appEnvCache      cache.Cache
+		pipedEnvCache    cache.Cache
+		pipedStore       datastore.PipedStore
 		applicationStore datastore.ApplicationStore
 		wantErr          bool
 	}{
@@ -54,6 +61,12 @@ func TestValidateAppBelongsToPiped(t *testing.T) {
 					Get("appID").Return("pipedID", nil)
 				return c
 			}(),
+			pipedEnvCache: func() cache.Cache {
+				c := cachetest.NewMockCache(ctrl)
+				c.EXPECT().
+					Get("pipedID").Return([]string{}, nil)
+				return c
+			}(),
 			wantErr: false,
 		},
 		{
@@ -80,12 +93,24 @@ func TestValidateAppBelongsToPiped(t *testing.T) {
 					Put("appID", "pipedID").Return(nil)
 				return c
 			}(),
+			appEnvCache: func() cache.Cache {
+				c := cachetest.NewMockCache(ctrl)
+				c.EXPECT().
+					Put("appID", "").Return(nil)
+				return c
+			}(),
 			applicationStore: func() datastore.ApplicationStore {
 				s := datastoretest.NewMockApplicationStore(ctrl)
 				s.EXPECT().
 					GetApplication(gomock.Any(), "appID").Return(&model.Application{PipedId: "pipedID"}, nil)
 				return s
 			}(),
+			pipedEnvCache: func() cache.Cache {
+				c := cachetest.NewMockCache(ctrl)
+				c.EXPECT().
+					Get("pipedID").Return([]string{}, nil)
+				return c
+			}(),
 			wantErr: false,
 		},
 		{
@@ -100,6 +125,12 @@ func TestValidateAppBelongsToPiped(t *testing.T) {
 					Put("appID", "pipedID").Return(nil)
 				return c
 			}(),
+			appEnvCache: func() cache.Cache {
+				c := cachetest.NewMockCache(ctrl)
+				c.EXPECT().
+					Put("appID", "").Return(nil)
+				return c
+			}(),
 			applicationStore: func() datastore.ApplicationStore {
 				s := datastoretest.NewMockApplicationStore(ctrl)
 				s.EXPECT().
@@ -108,12 +139,72 @@ func TestValidateAppBelongsToPiped(t *testing.T) {
 			}(),
 			wantErr: true,
 		},
+		{
+			name:    "application in the environment of piped",
+			appID:   "appID",
+			pipedID: "pipedID",
+			appPipedCache: func() cache.Cache {
+				c := cachetest.NewMockCache(ctrl)
+				c.EXPECT().
+					Get("appID").Return("pipedID", nil)
+				return c
+			}(),
+			pipedEnvCache: func() cache.Cache {
+				c := cachetest.NewMockCache(ctrl)
+				c.EXPECT().
+					Get("pipedID").Return(nil, errors.New("not found"))
+				c.EXPECT().
+					Put("pipedID", []string{"dev"}).Return(nil)
+				return c
+			}(),
+			pipedStore: func() datastore.PipedStore {
+				s := datastoretest.NewMockPipedStore(ctrl)
+				s.EXPECT().
+					GetPiped(gomock.Any(), "pipedID").Return(&model.Piped{EnvIds: []string{"dev"}}, nil)
+				return s
+			}(),
+			appEnvCache: func() cache.Cache {
+				c := cachetest.NewMockCache(ctrl)
+				c.EXPECT().
+					Get("appID").Return("dev", nil)
+				return c
+			}(),
+			wantErr: false,
+		},
+		{
+			name:    "application outside the environments of piped",
+			appID:   "appID",
+			pipedID: "pipedID",
+			appPipedCache: func() cache.Cache {
+				c := cachetest.NewMockCache(ctrl)
+				c.EXPECT().
+					Get("appID").Return("pipedID", nil)
+				return c
+			}(),
+			pipedEnvCache: func() cache.Cache {
+				c := cachetest.NewMockCache(ctrl)
+				c.EXPECT().
+					Get("pipedID").Return([]string{"dev"}, nil)
+				return c
+			}(),
+			appEnvCache: func() cache.Cache {
+				c := cachetest.NewMockCache(ctrl)
+				c.EXPECT().
+					Get("appID").Return("prod", nil)
+				return c
+			}(),
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			api := &PipedAPI{
 				appPipedCache:    tt.appPipedCache,
+				appEnvCache:      tt.appEnvCache,
+				pipedEnvCache:    tt.pipedEnvCache,
+				pipedStore:       tt.pipedStore,
 				applicationStore: tt.applicationStore,
+				logger:           zap.NewNop(),
 			}
 			err := api.validateAppBelongsToPiped(ctx, tt.appID, tt.pipedID)
 			assert.Equal(t, tt.wantErr, err != nil)
@@ -304,3 +395,204 @@ func TestValidateEnvBelongsToProject(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterCommandsInEnvScope(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmds := []*model.Command{
+		{Id: "cmd-1", ApplicationId: "dev-app"},
+		{Id: "cmd-2", ApplicationId: "prod-app"},
+		{Id: "cmd-3"},
+	}
+
+	tests := []struct {
+		name             string
+		pipedStore       datastore.PipedStore
+		applicationStore datastore.ApplicationStore
+		appEnvCache      cache.Cache
+		want             []string
+	}{
+		{
+			name: "piped not bound to any environment",
+			pipedStore: func() datastore.PipedStore {
+				s := datastoretest.NewMockPipedStore(ctrl)
+				s.EXPECT().
+					GetPiped(gomock.Any(), "pipedID").Return(&model.Piped{}, nil)
+				return s
+			}(),
+			applicationStore: datastoretest.NewMockApplicationStore(ctrl),
+			appEnvCache:      cachetest.NewMockCache(ctrl),
+			want:             []string{"cmd-1", "cmd-2", "cmd-3"},
+		},
+		{
+			name: "piped bound to dev environment",
+			pipedStore: func() datastore.PipedStore {
+				s := datastoretest.NewMockPipedStore(ctrl)
+				s.EXPECT().
+					GetPiped(gomock.Any(), "pipedID").Return(&model.Piped{EnvIds: []string{"dev"}}, nil)
+				return s
+			}(),
+			applicationStore: func() datastore.ApplicationStore {
+				s := datastoretest.NewMockApplicationStore(ctrl)
+				s.EXPECT().
+					ListApplications(gomock.Any(), datastore.ListOptions{
+						Filters: []datastore.ListFilter{
+							{
+								Field:    "Id",
+								Operator: datastore.OperatorIn,
+								Value:    []string{"prod-app"},
+							},
+						},
+					}).Return([]*model.Application{{Id: "prod-app", EnvId: "prod"}}, "", nil)
+				return s
+			}(),
+			appEnvCache: func() cache.Cache {
+				c := cachetest.NewMockCache(ctrl)
+				c.EXPECT().
+					Get("dev-app").Return("dev", nil)
+				c.EXPECT().
+					Get("prod-app").Return("", errors.New("not found"))
+				c.EXPECT().
+					Put("prod-app", "prod").Return(nil)
+				return c
+			}(),
+			want: []string{"cmd-1", "cmd-3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &PipedAPI{
+				pipedStore:       tt.pipedStore,
+				applicationStore: tt.applicationStore,
+				appEnvCache:      tt.appEnvCache,
+				logger:           zap.NewNop(),
+			}
+			got, err := api.filterCommandsInEnvScope(ctx, "pipedID", cmds)
+			assert.NoError(t, err)
+			ids := make([]string, 0, len(got))
+			for _, cmd := range got {
+				ids = append(ids, cmd.Id)
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+type fakeCommandStore struct {
+	commandstore.Store
+	commands map[string]*model.Command
+	failed   []string
+}
+
+func (s *fakeCommandStore) GetCommand(_ context.Context, id string) (*model.Command, error) {
+	cmd, ok := s.commands[id]
+	if !ok {
+		return nil, datastore.ErrNotFound
+	}
+	return cmd, nil
+}
+
+func (s *fakeCommandStore) UpdateCommandHandled(_ context.Context, id string, status model.CommandStatus, _ map[string]string, _ int64) error {
+	if status == model.CommandStatus_COMMAND_FAILED {
+		s.failed = append(s.failed, id)
+	}
+	return nil
+}
+
+func TestFilterCommandsWaitingDependencies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	syncCommand := func(id string, status model.CommandStatus, deploymentID string, dependencies ...string) *model.Command {
+		cmd := &model.Command{
+			Id:              id,
+			ApplicationId:   "app-" + id,
+			Status:          status,
+			SyncApplication: &model.Command_SyncApplication{ApplicationId: "app-" + id, DependencyCommandIds: dependencies},
+		}
+		if deploymentID != "" {
+			cmd.Metadata = map[string]string{triggeredDeploymentIDKey: deploymentID}
+		}
+		return cmd
+	}
+	commandStore := &fakeCommandStore{
+		commands: map[string]*model.Command{
+			"succeeded":     syncCommand("succeeded", model.CommandStatus_COMMAND_SUCCEEDED, "deployment-success"),
+			"running":       syncCommand("running", model.CommandStatus_COMMAND_SUCCEEDED, "deployment-running"),
+			"deploy-failed": syncCommand("deploy-failed", model.CommandStatus_COMMAND_SUCCEEDED, "deployment-failure"),
+			"not-handled":   syncCommand("not-handled", model.CommandStatus_COMMAND_NOT_HANDLED_YET, ""),
+			"timeout":       syncCommand("timeout", model.CommandStatus_COMMAND_TIMEOUT, ""),
+		},
+	}
+	deploymentStore := datastoretest.NewMockDeploymentStore(ctrl)
+	deploymentStore.EXPECT().
+		GetDeployment(gomock.Any(), "deployment-success").Return(&model.Deployment{Id: "deployment-success", Status: model.DeploymentStatus_DEPLOYMENT_SUCCESS}, nil).AnyTimes()
+	deploymentStore.EXPECT().
+		GetDeployment(gomock.Any(), "deployment-running").Return(&model.Deployment{Id: "deployment-running", Status: model.DeploymentStatus_DEPLOYMENT_RUNNING}, nil).AnyTimes()
+	deploymentStore.EXPECT().
+		GetDeployment(gomock.Any(), "deployment-failure").Return(&model.Deployment{Id: "deployment-failure", Status: model.DeploymentStatus_DEPLOYMENT_FAILURE}, nil).AnyTimes()
+
+	api := &PipedAPI{
+		commandStore:    commandStore,
+		deploymentStore: deploymentStore,
+		logger:          zap.NewNop(),
+	}
+	cmds := []*model.Command{
+		{Id: "refresh", RefreshRepository: &model.Command_RefreshRepository{RepositoryId: "repo"}},
+		syncCommand("no-dependencies", model.CommandStatus_COMMAND_NOT_HANDLED_YET, ""),
+		syncCommand("dependencies-succeeded", model.CommandStatus_COMMAND_NOT_HANDLED_YET, "", "succeeded"),
+		syncCommand("dependency-running", model.CommandStatus_COMMAND_NOT_HANDLED_YET, "", "succeeded", "running"),
+		syncCommand("dependency-not-handled", model.CommandStatus_COMMAND_NOT_HANDLED_YET, "", "not-handled"),
+		syncCommand("dependency-deploy-failed", model.CommandStatus_COMMAND_NOT_HANDLED_YET, "", "succeeded", "deploy-failed"),
+		syncCommand("dependency-timeout", model.CommandStatus_COMMAND_NOT_HANDLED_YET, "", "timeout"),
+	}
+	got, err := api.filterCommandsWaitingDependencies(context.Background(), cmds)
+	assert.NoError(t, err)
+	ids := make([]string, 0, len(got))
+	for _, cmd := range got {
+		ids = append(ids, cmd.Id)
+	}
+	assert.Equal(t, []string{"refresh", "no-dependencies", "dependencies-succeeded"}, ids)
+	assert.Equal(t, []string{"dependency-deploy-failed", "dependency-timeout"}, commandStore.failed)
+}
+
+func TestStorePipedHealth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pipedStore := datastoretest.NewMockPipedStore(ctrl)
+	pipedStore.EXPECT().UpdatePiped(gomock.Any(), "piped-id", gomock.Any()).Return(nil).Times(2)
+
+	api := &PipedAPI{
+		pipedStore:       pipedStore,
+		pipedHealthCache: memorycache.NewTTLCache(ctx, time.Hour, 0),
+		logger:           zap.NewNop(),
+	}
+	health := func(fetchedAt int64, fetchErr string) *model.PipedHealth {
+		return &model.PipedHealth{
+			CloudProviders: []*model.PipedHealth_CloudProvider{
+				{Name: "k8s", Reachable: true},
+			},
+			Repositories: []*model.PipedHealth_Repository{
+				{Id: "repo", LastFetchedAt: fetchedAt, LastError: fetchErr},
+			},
+			ResourceUsage: &model.PipedHealth_ResourceUsage{Goroutines: int32(fetchedAt)},
+			CreatedAt:     fetchedAt,
+		}
+	}
+
+	// The first report is always stored.
+	api.storePipedHealth(ctx, "piped-id", health(1, ""))
+	// Only the timestamps and resource usage were changed.
+	api.storePipedHealth(ctx, "piped-id", health(2, ""))
+	// The status was changed.
+	api.storePipedHealth(ctx, "piped-id", health(3, "failed to fetch"))
+	api.storePipedHealth(ctx, "piped-id", health(4, "failed to fetch"))
+}
@@ -0,0 +1,164 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/datastore"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// quotaChecker checks whether a project can have one more resource
+// without exceeding the quota configured for that project.
+// The quotas are enforced on a best-effort basis since the counting
+// and the creation of a resource are not done atomically.
+type quotaChecker struct {
+	applicationStore datastore.ApplicationStore
+	deploymentStore  datastore.DeploymentStore
+	pipedStore       datastore.PipedStore
+	apiKeyStore      datastore.APIKeyStore
+	quotas           config.ControlPlaneQuotas
+	logger           *zap.Logger
+}
+
+func newQuotaChecker(ds datastore.DataStore, quotas config.ControlPlaneQuotas, logger *zap.Logger) *quotaChecker {
+	return &quotaChecker{
+		applicationStore: datastore.NewApplicationStore(ds),
+		deploymentStore:  datastore.NewDeploymentStore(ds),
+		pipedStore:       datastore.NewPipedStore(ds),
+		apiKeyStore:      datastore.NewAPIKeyStore(ds),
+		quotas:           quotas,
+		logger:           logger,
+	}
+}
+
+// checkApplications returns a ResourceExhausted error when the given project
+// already has the maximum number of applications.
+func (c *quotaChecker) checkApplications(ctx context.Context, projectID string) error {
+	limit := c.quotas.ProjectQuota(projectID).MaxApplications
+	if limit == 0 {
+		return nil
+	}
+	apps, _, err := c.applicationStore.ListApplications(ctx, datastore.ListOptions{
+		Filters: []datastore.ListFilter{
+			projectFilter(projectID),
+			{
+				Field:    "Deleted",
+				Operator: datastore.OperatorEqual,
+				Value:    false,
+			},
+		},
+	})
+	if err != nil {
+		c.logger.Error("failed to count applications for quota", zap.String("project-id", projectID), zap.Error(err))
+		return status.Error(codes.Internal, "Failed to check the quota of applications")
+	}
+	if len(apps) >= limit {
+		return status.Error(codes.ResourceExhausted, fmt.Sprintf("The project has reached its quota of %d applications", limit))
+	}
+	return nil
+}
+
+// checkPipeds returns a ResourceExhausted error when the given project
+// already has the maximum number of registered pipeds.
+func (c *quotaChecker) checkPipeds(ctx context.Context, projectID string) error {
+	limit := c.quotas.ProjectQuota(projectID).MaxPipeds
+	if limit == 0 {
+		return nil
+	}
+	pipeds, err := c.pipedStore.ListPipeds(ctx, datastore.ListOptions{
+		Filters: []datastore.ListFilter{
+			projectFilter(projectID),
+		},
+	})
+	if err != nil {
+		c.logger.Error("failed to count pipeds for quota", zap.String("project-id", projectID), zap.Error(err))
+		return status.Error(codes.Internal, "Failed to check the quota of pipeds")
+	}
+	if len(pipeds) >= limit {
+		return status.Error(codes.ResourceExhausted, fmt.Sprintf("The project has reached its quota of %d pipeds", limit))
+	}
+	return nil
+}
+
+// checkAPIKeys returns a ResourceExhausted error when the given project
+// already has the maximum number of enabled API keys.
+func (c *quotaChecker) checkAPIKeys(ctx context.Context, projectID string) error {
+	limit := c.quotas.ProjectQuota(projectID).MaxAPIKeys
+	if limit == 0 {
+		return nil
+	}
+	keys, err := c.apiKeyStore.ListAPIKeys(ctx, datastore.ListOptions{
+		Filters: []datastore.ListFilter{
+			projectFilter(projectID),
+			{
+				Field:    "Disabled",
+				Operator: datastore.OperatorEqual,
+				Value:    false,
+			},
+		},
+	})
+	if err != nil {
+		c.logger.Error("failed to count API keys for quota", zap.String("project-id", projectID), zap.Error(err))
+		return status.Error(codes.Internal, "Failed to check the quota of API keys")
+	}
+	if len(keys) >= limit {
+		return status.Error(codes.ResourceExhausted, fmt.Sprintf("The project has reached its quota of %d API keys", limit))
+	}
+	return nil
+}
+
+// checkConcurrentDeployments returns a ResourceExhausted error when the given project
+// already has the maximum number of not completed deployments.
+// Since this is used by PipedAPI the messages are formatted in its style.
+func (c *quotaChecker) checkConcurrentDeployments(ctx context.Context, projectID string) error {
+	limit := c.quotas.ProjectQuota(projectID).MaxConcurrentDeployments
+	if limit == 0 {
+		return nil
+	}
+	deployments, _, err := c.deploymentStore.ListDeployments(ctx, datastore.ListOptions{
+		Filters: []datastore.ListFilter{
+			projectFilter(projectID),
+			{
+				Field:    "Status",
+				Operator: datastore.OperatorIn,
+				Value:    model.GetNotCompletedDeploymentStatuses(),
+			},
+		},
+	})
+	if err != nil {
+		c.logger.Error("failed to count deployments for quota", zap.String("project-id", projectID), zap.Error(err))
+		return status.Error(codes.Internal, "failed to check the quota of concurrent deployments")
+	}
+	if len(deployments) >= limit {
+		return status.Error(codes.ResourceExhausted, fmt.Sprintf("project has reached its quota of %d concurrent deployments", limit))
+	}
+	return nil
+}
+
+func projectFilter(projectID string) datastore.ListFilter {
+	return datastore.ListFilter{
+		Field:    "ProjectId",
+		Operator: datastore.OperatorEqual,
+		Value:    projectID,
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/datastore"
+	"github.com/pipe-cd/pipe/pkg/datastore/datastoretest"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestQuotaCheckerCheckApplications(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	quotas := config.ControlPlaneQuotas{
+		Default: config.ProjectQuota{
+			MaxApplications: 2,
+		},
+		Projects: []config.ProjectQuota{
+			{
+				ProjectID: "unlimited",
+			},
+		},
+	}
+
+	testcases := []struct {
+		name             string
+		projectID        string
+		applicationStore func() datastore.ApplicationStore
+		expectedCode     codes.Code
+	}{
+		{
+			name:      "no limit",
+			projectID: "unlimited",
+			applicationStore: func() datastore.ApplicationStore {
+				return datastoretest.NewMockApplicationStore(ctrl)
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name:      "under the limit",
+			projectID: "project",
+			applicationStore: func() datastore.ApplicationStore {
+				s := datastoretest.NewMockApplicationStore(ctrl)
+				s.EXPECT().
+					ListApplications(gomock.Any(), gomock.Any()).Return([]*model.Application{{}}, "", nil)
+				return s
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name:      "reached the limit",
+			projectID: "project",
+			applicationStore: func() datastore.ApplicationStore {
+				s := datastoretest.NewMockApplicationStore(ctrl)
+				s.EXPECT().
+					ListApplications(gomock.Any(), gomock.Any()).Return([]*model.Application{{}, {}}, "", nil)
+				return s
+			},
+			expectedCode: codes.ResourceExhausted,
+		},
+		{
+			name:      "failed to list",
+			projectID: "project",
+			applicationStore: func() datastore.ApplicationStore {
+				s := datastoretest.NewMockApplicationStore(ctrl)
+				s.EXPECT().
+					ListApplications(gomock.Any(), gomock.Any()).Return(nil, "", errors.New("error"))
+				return s
+			},
+			expectedCode: codes.Internal,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &quotaChecker{
+				applicationStore: tc.applicationStore(),
+				quotas:           quotas,
+				logger:           zap.NewNop(),
+			}
+			err := c.checkApplications(context.Background(), tc.projectID)
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestQuotaCheckerCheckConcurrentDeployments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := datastoretest.NewMockDeploymentStore(ctrl)
+	s.EXPECT().
+		ListDeployments(gomock.Any(), gomock.Any()).Return([]*model.Deployment{{}, {}, {}}, "", nil).Times(2)
+
+	c := &quotaChecker{
+		deploymentStore: s,
+		quotas: config.ControlPlaneQuotas{
+			Projects: []config.ProjectQuota{
+				{
+					ProjectID:                "small",
+					MaxConcurrentDeployments: 3,
+				},
+				{
+					ProjectID:                "large",
+					MaxConcurrentDeployments: 10,
+				},
+			},
+		},
+		logger: zap.NewNop(),
+	}
+	assert.Equal(t, codes.ResourceExhausted, status.Code(c.checkConcurrentDeployments(context.Background(), "small")))
+	assert.NoError(t, c.checkConcurrentDeployments(context.Background(), "large"))
+	// The default quota has no limit so nothing is listed.
+	assert.NoError(t, c.checkConcurrentDeployments(context.Background(), "other"))
+}
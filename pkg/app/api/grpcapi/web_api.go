@@ -17,8 +17,10 @@ package grpcapi
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -28,8 +30,10 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/pipe-cd/pipe/pkg/app/api/analysisresultstore"
 	"github.com/pipe-cd/pipe/pkg/app/api/applicationlivestatestore"
 	"github.com/pipe-cd/pipe/pkg/app/api/commandstore"
+	"github.com/pipe-cd/pipe/pkg/app/api/pipedconfigstore"
 	"github.com/pipe-cd/pipe/pkg/app/api/service/webservice"
 	"github.com/pipe-cd/pipe/pkg/app/api/stagelogstore"
 	"github.com/pipe-cd/pipe/pkg/cache"
@@ -40,6 +44,7 @@ import (
 	"github.com/pipe-cd/pipe/pkg/datastore"
 	"github.com/pipe-cd/pipe/pkg/filestore"
 	"github.com/pipe-cd/pipe/pkg/git"
+	"github.com/pipe-cd/pipe/pkg/insight"
 	"github.com/pipe-cd/pipe/pkg/insight/insightstore"
 	"github.com/pipe-cd/pipe/pkg/model"
 	"github.com/pipe-cd/pipe/pkg/redis"
@@ -58,11 +63,16 @@ type WebAPI struct {
 	pipedStore                datastore.PipedStore
 	projectStore              datastore.ProjectStore
 	apiKeyStore               datastore.APIKeyStore
+	deploymentSavedViewStore  datastore.DeploymentSavedViewStore
 	stageLogStore             stagelogstore.Store
 	applicationLiveStateStore applicationlivestatestore.Store
 	commandStore              commandstore.Store
 	insightStore              insightstore.Store
+	manifestDiffGetter        manifestDiffGetter
+	analysisResultGetter      analysisResultGetter
+	pipedConfigStore          pipedConfigStore
 	encrypter                 encrypter
+	quotaChecker              *quotaChecker
 
 	appProjectCache        cache.Cache
 	deploymentProjectCache cache.Cache
@@ -83,8 +93,12 @@ func NewWebAPI(
 	alss applicationlivestatestore.Store,
 	cmds commandstore.Store,
 	is insightstore.Store,
+	mdg manifestDiffGetter,
+	arg analysisResultGetter,
+	pcs pipedConfigStore,
 	rd redis.Redis,
 	projs map[string]config.ControlPlaneProject,
+	quotas config.ControlPlaneQuotas,
 	encrypter encrypter,
 	logger *zap.Logger) *WebAPI {
 	a := &WebAPI{
@@ -94,12 +108,17 @@ func NewWebAPI(
 		pipedStore:                datastore.NewPipedStore(ds),
 		projectStore:              datastore.NewProjectStore(ds),
 		apiKeyStore:               datastore.NewAPIKeyStore(ds),
+		deploymentSavedViewStore:  datastore.NewDeploymentSavedViewStore(ds),
 		stageLogStore:             sls,
 		applicationLiveStateStore: alss,
 		commandStore:              cmds,
 		insightStore:              is,
+		manifestDiffGetter:        mdg,
+		analysisResultGetter:      arg,
+		pipedConfigStore:          pcs,
 		projectsInConfig:          projs,
 		encrypter:                 encrypter,
+		quotaChecker:              newQuotaChecker(ds, quotas, logger.Named("web-api")),
 		appProjectCache:           memorycache.NewTTLCache(ctx, 24*time.Hour, 3*time.Hour),
 		deploymentProjectCache:    memorycache.NewTTLCache(ctx, 24*time.Hour, 3*time.Hour),
 		pipedProjectCache:         memorycache.NewTTLCache(ctx, 24*time.Hour, 3*time.Hour),
@@ -363,6 +382,10 @@ func (a *WebAPI) RegisterPiped(ctx context.Context, req *webservice.RegisterPipe
 		return nil, err
 	}
 
+	if err := a.quotaChecker.checkPipeds(ctx, claims.Role.ProjectId); err != nil {
+		return nil, err
+	}
+
 	key, keyHash, err := model.GeneratePipedKey()
 	if err != nil {
 		a.logger.Error("failed to generate piped key", zap.Error(err))
@@ -496,6 +519,142 @@ func (a *WebAPI) updatePiped(ctx context.Context, pipedID string, updater func(c
 	return nil
 }
 
+var errPipedConfigVersionConflict = errors.New("piped config version conflict")
+
+// UpdatePipedConfig stores a new version of the piped configuration
+// and notifies the piped to reload it.
+func (a *WebAPI) UpdatePipedConfig(ctx context.Context, req *webservice.UpdatePipedConfigRequest) (*webservice.UpdatePipedConfigResponse, error) {
+	claims, err := rpcauth.ExtractClaims(ctx)
+	if err != nil {
+		a.logger.Error("failed to authenticate the current user", zap.Error(err))
+		return nil, err
+	}
+
+	piped, err := getPiped(ctx, a.pipedStore, req.PipedId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+	if piped.ProjectId != claims.Role.ProjectId {
+		return nil, status.Error(codes.PermissionDenied, "Requested piped doesn't belong to the project you logged in")
+	}
+
+	cfg, err := config.DecodeYAML([]byte(req.Data))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid piped config: %v", err)
+	}
+	if cfg.Kind != config.KindPiped {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid piped config: wrong kind %s", cfg.Kind)
+	}
+	if cfg.PipedSpec.PipedID != piped.Id || cfg.PipedSpec.ProjectID != piped.ProjectId {
+		return nil, status.Error(codes.InvalidArgument, "Invalid piped config: projectID and pipedID must be the ones of the piped")
+	}
+
+	pipedCfg := &model.PipedConfig{
+		PipedId:   piped.Id,
+		ProjectId: piped.ProjectId,
+		Version:   piped.ConfigVersion + 1,
+		Data:      req.Data,
+		Creator:   claims.Subject,
+		Comment:   req.Comment,
+		CreatedAt: time.Now().Unix(),
+	}
+	// Reserve the new version first so that the stored config of a version
+	// is never overwritten by the concurrent requests.
+	err = a.pipedStore.UpdatePiped(ctx, piped.Id, func(p *model.Piped) error {
+		if p.ConfigVersion != piped.ConfigVersion {
+			return errPipedConfigVersionConflict
+		}
+		p.ConfigVersion = pipedCfg.Version
+		return nil
+	})
+	if errors.Is(err, errPipedConfigVersionConflict) {
+		return nil, status.Error(codes.Aborted, "The piped config was updated by another request, please try again")
+	}
+	if err != nil {
+		a.logger.Error("failed to update piped config version",
+			zap.String("piped-id", piped.Id),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, "Failed to update the piped config version")
+	}
+
+	if err := a.pipedConfigStore.Put(ctx, pipedCfg); err != nil {
+		a.logger.Error("failed to store piped config", zap.Error(err))
+		// Give the reserved version back unless it was already taken by another request.
+		err = a.pipedStore.UpdatePiped(ctx, piped.Id, func(p *model.Piped) error {
+			if p.ConfigVersion != pipedCfg.Version {
+				return errPipedConfigVersionConflict
+			}
+			p.ConfigVersion = piped.ConfigVersion
+			return nil
+		})
+		if err != nil {
+			a.logger.Error("failed to revert piped config version",
+				zap.String("piped-id", piped.Id),
+				zap.Error(err),
+			)
+		}
+		return nil, status.Error(codes.Internal, "Failed to store the piped config")
+	}
+
+	cmd := model.Command{
+		Id:        uuid.New().String(),
+		PipedId:   piped.Id,
+		ProjectId: piped.ProjectId,
+		Type:      model.Command_RELOAD_PIPED_CONFIG,
+		Commander: claims.Subject,
+		ReloadPipedConfig: &model.Command_ReloadPipedConfig{
+			Version: pipedCfg.Version,
+		},
+	}
+	if err := addCommand(ctx, a.commandStore, &cmd, a.logger); err != nil {
+		return nil, err
+	}
+
+	return &webservice.UpdatePipedConfigResponse{
+		Version:   pipedCfg.Version,
+		CommandId: cmd.Id,
+	}, nil
+}
+
+// GetPipedConfig returns a version of the piped configuration stored in the control-plane.
+func (a *WebAPI) GetPipedConfig(ctx context.Context, req *webservice.GetPipedConfigRequest) (*webservice.GetPipedConfigResponse, error) {
+	claims, err := rpcauth.ExtractClaims(ctx)
+	if err != nil {
+		a.logger.Error("failed to authenticate the current user", zap.Error(err))
+		return nil, err
+	}
+
+	piped, err := getPiped(ctx, a.pipedStore, req.PipedId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+	if piped.ProjectId != claims.Role.ProjectId {
+		return nil, status.Error(codes.PermissionDenied, "Requested piped doesn't belong to the project you logged in")
+	}
+
+	version := req.Version
+	if version == 0 {
+		version = piped.ConfigVersion
+	}
+	if version == 0 {
+		return nil, status.Error(codes.NotFound, "The piped config is not managed by control-plane")
+	}
+
+	cfg, err := a.pipedConfigStore.Get(ctx, piped.Id, version)
+	if errors.Is(err, pipedconfigstore.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "The piped config is not found")
+	}
+	if err != nil {
+		a.logger.Error("failed to get piped config", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to get the piped config")
+	}
+
+	return &webservice.GetPipedConfigResponse{
+		Config: cfg,
+	}, nil
+}
+
 // TODO: Consider using piped-stats to decide piped connection status.
 func (a *WebAPI) ListPipeds(ctx context.Context, req *webservice.ListPipedsRequest) (*webservice.ListPipedsResponse, error) {
 	claims, err := rpcauth.ExtractClaims(ctx)
@@ -586,7 +745,6 @@ func (a *WebAPI) validatePipedBelongsToProject(ctx context.Context, pipedID, pro
 	return nil
 }
 
-// TODO: Validate the specified piped to ensure that it belongs to the specified environment.
 func (a *WebAPI) AddApplication(ctx context.Context, req *webservice.AddApplicationRequest) (*webservice.AddApplicationResponse, error) {
 	claims, err := rpcauth.ExtractClaims(ctx)
 	if err != nil {
@@ -602,6 +760,13 @@ func (a *WebAPI) AddApplication(ctx context.Context, req *webservice.AddApplicat
 	if piped.ProjectId != claims.Role.ProjectId {
 		return nil, status.Error(codes.InvalidArgument, "Requested piped does not belong to your project")
 	}
+	if !piped.AllowsEnvironment(req.EnvId) {
+		return nil, status.Error(codes.InvalidArgument, "Requested piped is not allowed to handle the applications of the environment")
+	}
+
+	if err := a.quotaChecker.checkApplications(ctx, claims.Role.ProjectId); err != nil {
+		return nil, err
+	}
 
 	gitpath, err := makeGitPath(
 		req.GitPath.Repo.Id,
@@ -624,6 +789,7 @@ func (a *WebAPI) AddApplication(ctx context.Context, req *webservice.AddApplicat
 		Kind:          req.Kind,
 		CloudProvider: req.CloudProvider,
 		Description:   req.Description,
+		Labels:        req.Labels,
 	}
 	err = a.applicationStore.AddApplication(ctx, &app)
 	if errors.Is(err, datastore.ErrAlreadyExists) {
@@ -646,10 +812,13 @@ func (a *WebAPI) UpdateApplication(ctx context.Context, req *webservice.UpdateAp
 		app.PipedId = req.PipedId
 		app.Kind = req.Kind
 		app.CloudProvider = req.CloudProvider
+		if req.Labels != nil {
+			app.Labels = req.Labels.Values
+		}
 		return nil
 	}
 
-	if err := a.updateApplication(ctx, req.ApplicationId, req.PipedId, updater); err != nil {
+	if err := a.updateApplication(ctx, req.ApplicationId, req.PipedId, req.EnvId, updater); err != nil {
 		return nil, err
 	}
 	return &webservice.UpdateApplicationResponse{}, nil
@@ -661,13 +830,13 @@ func (a *WebAPI) UpdateApplicationDescription(ctx context.Context, req *webservi
 		return nil
 	}
 
-	if err := a.updateApplication(ctx, req.ApplicationId, "", updater); err != nil {
+	if err := a.updateApplication(ctx, req.ApplicationId, "", "", updater); err != nil {
 		return nil, err
 	}
 	return &webservice.UpdateApplicationDescriptionResponse{}, nil
 }
 
-func (a *WebAPI) updateApplication(ctx context.Context, id, pipedID string, updater func(app *model.Application) error) error {
+func (a *WebAPI) updateApplication(ctx context.Context, id, pipedID, envID string, updater func(app *model.Application) error) error {
 	claims, err := rpcauth.ExtractClaims(ctx)
 	if err != nil {
 		a.logger.Error("failed to authenticate the current user", zap.Error(err))
@@ -684,6 +853,9 @@ func (a *WebAPI) updateApplication(ctx context.Context, id, pipedID string, upda
 		if piped.ProjectId != claims.Role.ProjectId {
 			return status.Error(codes.InvalidArgument, "Requested piped does not belong to your project")
 		}
+		if !piped.AllowsEnvironment(envID) {
+			return status.Error(codes.InvalidArgument, "Requested piped is not allowed to handle the applications of the environment")
+		}
 	}
 
 	err = a.applicationStore.UpdateApplication(ctx, id, updater)
@@ -709,6 +881,51 @@ func (a *WebAPI) DisableApplication(ctx context.Context, req *webservice.Disable
 	return &webservice.DisableApplicationResponse{}, nil
 }
 
+// EnableApplicationMaintenance stops triggering the automatic deployments of the application
+// until the maintenance mode is disabled or expired.
+func (a *WebAPI) EnableApplicationMaintenance(ctx context.Context, req *webservice.EnableApplicationMaintenanceRequest) (*webservice.EnableApplicationMaintenanceResponse, error) {
+	claims, err := rpcauth.ExtractClaims(ctx)
+	if err != nil {
+		a.logger.Error("failed to authenticate the current user", zap.Error(err))
+		return nil, err
+	}
+
+	if err := a.validateAppBelongsToProject(ctx, req.ApplicationId, claims.Role.ProjectId); err != nil {
+		return nil, err
+	}
+
+	maintenance := makeApplicationMaintenance(req.Reason, claims.Subject, time.Duration(req.Duration)*time.Second, time.Now())
+	updater := func(app *model.Application) error {
+		app.Maintenance = maintenance
+		return nil
+	}
+	if err := a.updateApplication(ctx, req.ApplicationId, "", "", updater); err != nil {
+		return nil, err
+	}
+	return &webservice.EnableApplicationMaintenanceResponse{}, nil
+}
+
+func (a *WebAPI) DisableApplicationMaintenance(ctx context.Context, req *webservice.DisableApplicationMaintenanceRequest) (*webservice.DisableApplicationMaintenanceResponse, error) {
+	claims, err := rpcauth.ExtractClaims(ctx)
+	if err != nil {
+		a.logger.Error("failed to authenticate the current user", zap.Error(err))
+		return nil, err
+	}
+
+	if err := a.validateAppBelongsToProject(ctx, req.ApplicationId, claims.Role.ProjectId); err != nil {
+		return nil, err
+	}
+
+	updater := func(app *model.Application) error {
+		app.Maintenance = nil
+		return nil
+	}
+	if err := a.updateApplication(ctx, req.ApplicationId, "", "", updater); err != nil {
+		return nil, err
+	}
+	return &webservice.DisableApplicationMaintenanceResponse{}, nil
+}
+
 func (a *WebAPI) DeleteApplication(ctx context.Context, req *webservice.DeleteApplicationRequest) (*webservice.DeleteApplicationResponse, error) {
 	claims, err := rpcauth.ExtractClaims(ctx)
 	if err != nil {
@@ -720,6 +937,11 @@ func (a *WebAPI) DeleteApplication(ctx context.Context, req *webservice.DeleteAp
 		return nil, err
 	}
 
+	app, err := getApplication(ctx, a.applicationStore, req.ApplicationId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := a.applicationStore.DeleteApplication(ctx, req.ApplicationId); err != nil {
 		switch err {
 		case datastore.ErrNotFound:
@@ -735,6 +957,25 @@ func (a *WebAPI) DeleteApplication(ctx context.Context, req *webservice.DeleteAp
 		}
 	}
 
+	// Let piped clean up the resources created for the application such as its namespace.
+	if app.Kind == model.ApplicationKind_KUBERNETES {
+		cmd := model.Command{
+			Id:            uuid.New().String(),
+			PipedId:       app.PipedId,
+			ApplicationId: app.Id,
+			ProjectId:     app.ProjectId,
+			Type:          model.Command_DELETE_APPLICATION,
+			Commander:     claims.Subject,
+			DeleteApplication: &model.Command_DeleteApplication{
+				ApplicationId: app.Id,
+				Kind:          app.Kind,
+				CloudProvider: app.CloudProvider,
+			},
+		}
+		// The application has already been deleted so the failure is only logged by addCommand.
+		addCommand(ctx, a.commandStore, &cmd, a.logger)
+	}
+
 	return &webservice.DeleteApplicationResponse{}, nil
 }
 
@@ -867,6 +1108,19 @@ func (a *WebAPI) SyncApplication(ctx context.Context, req *webservice.SyncApplic
 		return nil, status.Error(codes.InvalidArgument, "Requested application does not belong to your project")
 	}
 
+	// Only the admins can sync the application manually while it is in maintenance mode.
+	if app.InMaintenance(time.Now()) && claims.Role.ProjectRole != model.Role_ADMIN {
+		return nil, status.Error(codes.PermissionDenied, "The application is in maintenance mode and can be synced only by project admins")
+	}
+
+	piped, err := getPiped(ctx, a.pipedStore, app.PipedId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+	if !piped.AllowsEnvironment(app.EnvId) {
+		return nil, status.Error(codes.FailedPrecondition, "The piped of the application is not allowed to handle the applications of its environment")
+	}
+
 	cmd := model.Command{
 		Id:            uuid.New().String(),
 		PipedId:       app.PipedId,
@@ -877,6 +1131,7 @@ func (a *WebAPI) SyncApplication(ctx context.Context, req *webservice.SyncApplic
 		SyncApplication: &model.Command_SyncApplication{
 			ApplicationId: app.Id,
 			SyncStrategy:  req.SyncStrategy,
+			TargetCommit:  req.Commit,
 		},
 	}
 	if err := addCommand(ctx, a.commandStore, &cmd, a.logger); err != nil {
@@ -1186,13 +1441,17 @@ func (a *WebAPI) ApproveStage(ctx context.Context, req *webservice.ApproveStageR
 	if err := a.validateDeploymentBelongsToProject(ctx, req.DeploymentId, claims.Role.ProjectId); err != nil {
 		return nil, err
 	}
-	stage, ok := deployment.StageStatusMap()[req.StageId]
+	stage, ok := deployment.FindStage(req.StageId)
 	if !ok {
 		return nil, status.Error(codes.FailedPrecondition, "The stage was not found in the deployment")
 	}
-	if model.IsCompletedStage(stage) {
+	if model.IsCompletedStage(stage.Status) {
 		return nil, status.Errorf(codes.FailedPrecondition, "Could not approve the stage because it was already completed")
 	}
+	comment := strings.TrimSpace(req.Comment)
+	if err := validateApprovalComment(stage, comment); err != nil {
+		return nil, err
+	}
 
 	commandID := uuid.New().String()
 	cmd := model.Command{
@@ -1207,17 +1466,369 @@ func (a *WebAPI) ApproveStage(ctx context.Context, req *webservice.ApproveStageR
 		ApproveStage: &model.Command_ApproveStage{
 			DeploymentId: req.DeploymentId,
 			StageId:      req.StageId,
+			Comment:      comment,
+			Reject:       req.Reject,
 		},
 	}
 	if err := addCommand(ctx, a.commandStore, &cmd, a.logger); err != nil {
 		return nil, err
 	}
+	a.logger.Info("added a command to approve or reject a stage",
+		zap.String("deployment-id", req.DeploymentId),
+		zap.String("stage-id", req.StageId),
+		zap.String("commander", claims.Subject),
+		zap.Bool("reject", req.Reject),
+		zap.String("comment", comment),
+	)
 
 	return &webservice.ApproveStageResponse{
 		CommandId: commandID,
 	}, nil
 }
 
+// GetDeploymentManifestDiff returns the manifest diff computed by piped while planning the given deployment.
+func (a *WebAPI) GetDeploymentManifestDiff(ctx context.Context, req *webservice.GetDeploymentManifestDiffRequest) (*webservice.GetDeploymentManifestDiffResponse, error) {
+	claims, err := rpcauth.ExtractClaims(ctx)
+	if err != nil {
+		a.logger.Error("failed to authenticate the current user", zap.Error(err))
+		return nil, err
+	}
+
+	if err := a.validateDeploymentBelongsToProject(ctx, req.DeploymentId, claims.Role.ProjectId); err != nil {
+		return nil, err
+	}
+
+	diff, err := getDeploymentManifestDiff(ctx, a.manifestDiffGetter, req.DeploymentId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webservice.GetDeploymentManifestDiffResponse{
+		Diff: diff,
+	}, nil
+}
+
+// GetDeploymentStageGraph returns the stage graph of the given deployment with the timings of each stage.
+func (a *WebAPI) GetDeploymentStageGraph(ctx context.Context, req *webservice.GetDeploymentStageGraphRequest) (*webservice.GetDeploymentStageGraphResponse, error) {
+	claims, err := rpcauth.ExtractClaims(ctx)
+	if err != nil {
+		a.logger.Error("failed to authenticate the current user", zap.Error(err))
+		return nil, err
+	}
+
+	deployment, err := getDeployment(ctx, a.deploymentStore, req.DeploymentId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Role.ProjectId != deployment.ProjectId {
+		return nil, status.Error(codes.InvalidArgument, "Requested deployment does not belong to your project")
+	}
+
+	return &webservice.GetDeploymentStageGraphResponse{
+		Graph: model.MakeStageGraph(deployment),
+	}, nil
+}
+
+// GetAnalysisResult returns the detailed data collected while running the given ANALYSIS stage.
+func (a *WebAPI) GetAnalysisResult(ctx context.Context, req *webservice.GetAnalysisResultRequest) (*webservice.GetAnalysisResultResponse, error) {
+	claims, err := rpcauth.ExtractClaims(ctx)
+	if err != nil {
+		a.logger.Error("failed to authenticate the current user", zap.Error(err))
+		return nil, err
+	}
+
+	if err := a.validateDeploymentBelongsToProject(ctx, req.DeploymentId, claims.Role.ProjectId); err != nil {
+		return nil, err
+	}
+
+	result, err := a.analysisResultGetter.Get(ctx, req.DeploymentId, req.StageId)
+	if errors.Is(err, analysisresultstore.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "Analysis result is not found")
+	}
+	if err != nil {
+		a.logger.Error("failed to get analysis result", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to get analysis result")
+	}
+
+	return &webservice.GetAnalysisResultResponse{
+		Result: result,
+	}, nil
+}
+
+const (
+	defaultSearchDeploymentsPageSize = 20
+	searchDeploymentsBatchSize       = 100
+	// The maximum number of deployments checked against the query in one request
+	// to keep the latency bounded while searching through a long history.
+	maxSearchDeploymentsScanned = 2000
+)
+
+// searchCursor points to the position where the next search continues from.
+// Since the datastore cursor can only point to the end of a batch,
+// the number of already scanned deployments in that batch is also kept.
+type searchCursor struct {
+	Cursor string `json:"cursor"`
+	Offset int    `json:"offset"`
+}
+
+func decodeSearchCursor(s string) (searchCursor, error) {
+	var c searchCursor
+	if s == "" {
+		return c, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+func (c searchCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (a *WebAPI) SearchDeployments(ctx context.Context, req *webservice.SearchDeploymentsRequest) (*webservice.SearchDeploymentsResponse, error) {
+	claims, err := rpcauth.ExtractClaims(ctx)
+	if err != nil {
+		a.logger.Error("failed to authenticate the current user", zap.Error(err))
+		return nil, err
+	}
+
+	cursor, err := decodeSearchCursor(req.Cursor)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid cursor")
+	}
+	pageSize := int(req.PageSize)
+	if pageSize == 0 {
+		pageSize = defaultSearchDeploymentsPageSize
+	}
+
+	filters := []datastore.ListFilter{
+		{
+			Field:    "ProjectId",
+			Operator: datastore.OperatorEqual,
+			Value:    claims.Role.ProjectId,
+		},
+	}
+	var query string
+	if f := req.Filter; f != nil {
+		// Currently only the first value is used as same as ListDeployments.
+		if len(f.Statuses) > 0 {
+			filters = append(filters, datastore.ListFilter{
+				Field:    "Status",
+				Operator: datastore.OperatorEqual,
+				Value:    f.Statuses[0],
+			})
+		}
+		if len(f.Kinds) > 0 {
+			filters = append(filters, datastore.ListFilter{
+				Field:    "Kind",
+				Operator: datastore.OperatorEqual,
+				Value:    f.Kinds[0],
+			})
+		}
+		if len(f.ApplicationIds) > 0 {
+			filters = append(filters, datastore.ListFilter{
+				Field:    "ApplicationId",
+				Operator: datastore.OperatorEqual,
+				Value:    f.ApplicationIds[0],
+			})
+		}
+		if len(f.EnvIds) > 0 {
+			filters = append(filters, datastore.ListFilter{
+				Field:    "EnvId",
+				Operator: datastore.OperatorEqual,
+				Value:    f.EnvIds[0],
+			})
+		}
+		query = f.Query
+	}
+
+	deployments, next, err := searchDeployments(ctx, a.deploymentStore, filters, query, pageSize, cursor)
+	if err != nil {
+		a.logger.Error("failed to search deployments", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to search deployments")
+	}
+	return &webservice.SearchDeploymentsResponse{
+		Deployments: deployments,
+		Cursor:      next,
+	}, nil
+}
+
+// searchDeployments scans the deployments matching the given filters from the newest one
+// and returns up to pageSize deployments matching the given free text query.
+// The returned cursor is empty when all deployments have been scanned.
+func searchDeployments(ctx context.Context, store datastore.DeploymentStore, filters []datastore.ListFilter, query string, pageSize int, cursor searchCursor) ([]*model.Deployment, string, error) {
+	var (
+		orders = []datastore.Order{
+			{
+				Field:     "UpdatedAt",
+				Direction: datastore.Desc,
+			},
+			{
+				Field:     "Id",
+				Direction: datastore.Asc,
+			},
+		}
+		terms       = strings.Fields(strings.ToLower(query))
+		deployments = make([]*model.Deployment, 0, pageSize)
+		scanned     = 0
+	)
+
+	for {
+		batch, next, err := store.ListDeployments(ctx, datastore.ListOptions{
+			Filters: filters,
+			Orders:  orders,
+			Limit:   searchDeploymentsBatchSize,
+			Cursor:  cursor.Cursor,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		for i := cursor.Offset; i < len(batch); i++ {
+			scanned++
+			if deploymentMatchesQuery(batch[i], terms) {
+				deployments = append(deployments, batch[i])
+			}
+			if len(deployments) < pageSize && scanned < maxSearchDeploymentsScanned {
+				continue
+			}
+			if i+1 < len(batch) {
+				cursor.Offset = i + 1
+				return deployments, cursor.encode(), nil
+			}
+			break
+		}
+		// There are no more deployments to scan.
+		if len(batch) < searchDeploymentsBatchSize || next == "" {
+			return deployments, "", nil
+		}
+		cursor = searchCursor{Cursor: next}
+		if len(deployments) >= pageSize || scanned >= maxSearchDeploymentsScanned {
+			return deployments, cursor.encode(), nil
+		}
+	}
+}
+
+// deploymentMatchesQuery reports whether all given lower-cased terms are found
+// in the searchable fields of the deployment.
+func deploymentMatchesQuery(d *model.Deployment, terms []string) bool {
+	if len(terms) == 0 {
+		return true
+	}
+	fields := []string{d.ApplicationName, d.Version}
+	if t := d.Trigger; t != nil {
+		fields = append(fields, t.Commander)
+		if c := t.Commit; c != nil {
+			fields = append(fields, c.Message, c.Author)
+		}
+	}
+	text := strings.ToLower(strings.Join(fields, "\n"))
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *WebAPI) AddDeploymentSavedView(ctx context.Context, req *webservice.AddDeploymentSavedViewRequest) (*webservice.AddDeploymentSavedViewResponse, error) {
+	claims, err := rpcauth.ExtractClaims(ctx)
+	if err != nil {
+		a.logger.Error("failed to authenticate the current user", zap.Error(err))
+		return nil, err
+	}
+
+	view := model.DeploymentSavedView{
+		Id:        uuid.New().String(),
+		Name:      req.Name,
+		ProjectId: claims.Role.ProjectId,
+		Owner:     claims.Subject,
+		Filter:    req.Filter,
+	}
+	err = a.deploymentSavedViewStore.AddDeploymentSavedView(ctx, &view)
+	if errors.Is(err, datastore.ErrAlreadyExists) {
+		return nil, status.Error(codes.AlreadyExists, "The saved view already exists")
+	}
+	if err != nil {
+		a.logger.Error("failed to add saved view", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to add saved view")
+	}
+
+	return &webservice.AddDeploymentSavedViewResponse{
+		View: &view,
+	}, nil
+}
+
+func (a *WebAPI) DeleteDeploymentSavedView(ctx context.Context, req *webservice.DeleteDeploymentSavedViewRequest) (*webservice.DeleteDeploymentSavedViewResponse, error) {
+	claims, err := rpcauth.ExtractClaims(ctx)
+	if err != nil {
+		a.logger.Error("failed to authenticate the current user", zap.Error(err))
+		return nil, err
+	}
+
+	if err := a.deploymentSavedViewStore.DeleteDeploymentSavedView(ctx, req.Id, claims.Role.ProjectId, claims.Subject); err != nil {
+		switch err {
+		case datastore.ErrNotFound:
+			return nil, status.Error(codes.InvalidArgument, "The saved view is not found")
+		case datastore.ErrInvalidArgument:
+			return nil, status.Error(codes.InvalidArgument, "Invalid value for update")
+		default:
+			a.logger.Error("failed to delete the saved view",
+				zap.String("view-id", req.Id),
+				zap.Error(err),
+			)
+			return nil, status.Error(codes.Internal, "Failed to delete the saved view")
+		}
+	}
+
+	return &webservice.DeleteDeploymentSavedViewResponse{}, nil
+}
+
+func (a *WebAPI) ListDeploymentSavedViews(ctx context.Context, req *webservice.ListDeploymentSavedViewsRequest) (*webservice.ListDeploymentSavedViewsResponse, error) {
+	claims, err := rpcauth.ExtractClaims(ctx)
+	if err != nil {
+		a.logger.Error("failed to authenticate the current user", zap.Error(err))
+		return nil, err
+	}
+
+	views, err := a.deploymentSavedViewStore.ListDeploymentSavedViews(ctx, datastore.ListOptions{
+		Filters: []datastore.ListFilter{
+			{
+				Field:    "ProjectId",
+				Operator: datastore.OperatorEqual,
+				Value:    claims.Role.ProjectId,
+			},
+			{
+				Field:    "Owner",
+				Operator: datastore.OperatorEqual,
+				Value:    claims.Subject,
+			},
+			{
+				Field:    "Deleted",
+				Operator: datastore.OperatorEqual,
+				Value:    false,
+			},
+		},
+	})
+	if err != nil {
+		a.logger.Error("failed to list saved views", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list saved views")
+	}
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].CreatedAt < views[j].CreatedAt
+	})
+
+	return &webservice.ListDeploymentSavedViewsResponse{
+		Views: views,
+	}, nil
+}
+
 func (a *WebAPI) GetApplicationLiveState(ctx context.Context, req *webservice.GetApplicationLiveStateRequest) (*webservice.GetApplicationLiveStateResponse, error) {
 	claims, err := rpcauth.ExtractClaims(ctx)
 	if err != nil {
@@ -1239,6 +1850,76 @@ func (a *WebAPI) GetApplicationLiveState(ctx context.Context, req *webservice.Ge
 	}, nil
 }
 
+// ListApplicationHealths returns the health summaries of all applications in the project.
+func (a *WebAPI) ListApplicationHealths(ctx context.Context, req *webservice.ListApplicationHealthsRequest) (*webservice.ListApplicationHealthsResponse, error) {
+	claims, err := rpcauth.ExtractClaims(ctx)
+	if err != nil {
+		a.logger.Error("failed to authenticate the current user", zap.Error(err))
+		return nil, err
+	}
+
+	const defaultLimit = 50
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultLimit
+	}
+
+	filters := []datastore.ListFilter{
+		{
+			Field:    "ProjectId",
+			Operator: datastore.OperatorEqual,
+			Value:    claims.Role.ProjectId,
+		},
+		{
+			Field:    "Disabled",
+			Operator: datastore.OperatorEqual,
+			Value:    false,
+		},
+	}
+	if req.EnvId != "" {
+		filters = append(filters, datastore.ListFilter{
+			Field:    "EnvId",
+			Operator: datastore.OperatorEqual,
+			Value:    req.EnvId,
+		})
+	}
+
+	apps, cursor, err := listApplications(ctx, a.applicationStore, datastore.ListOptions{
+		Filters: filters,
+		Orders: []datastore.Order{
+			{
+				Field:     "UpdatedAt",
+				Direction: datastore.Desc,
+			},
+			{
+				Field:     "Id",
+				Direction: datastore.Asc,
+			},
+		},
+		Limit:  limit,
+		Cursor: req.Cursor,
+	}, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]*model.Application, 0, len(apps))
+	for _, app := range apps {
+		if !app.Deleted {
+			active = append(active, app)
+		}
+	}
+	healths, err := makeApplicationHealths(ctx, active, a.applicationLiveStateStore, a.deploymentStore, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webservice.ListApplicationHealthsResponse{
+		Healths: healths,
+		Cursor:  cursor,
+	}, nil
+}
+
 // GetProject gets the specified porject without sensitive data.
 func (a *WebAPI) GetProject(ctx context.Context, req *webservice.GetProjectRequest) (*webservice.GetProjectResponse, error) {
 	claims, err := rpcauth.ExtractClaims(ctx)
@@ -1449,6 +2130,12 @@ func (a *WebAPI) ListDeploymentConfigTemplates(ctx context.Context, req *webserv
 		templates = cloudrunDeploymentConfigTemplates
 	case model.ApplicationKind_ECS:
 		templates = ecsDeploymentConfigTemplates
+	case model.ApplicationKind_VM:
+		templates = vmDeploymentConfigTemplates
+	case model.ApplicationKind_PULUMI:
+		templates = pulumiDeploymentConfigTemplates
+	case model.ApplicationKind_ANSIBLE:
+		templates = ansibleDeploymentConfigTemplates
 	default:
 		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Unknown application kind %v", app.Kind))
 	}
@@ -1495,6 +2182,10 @@ func (a *WebAPI) GenerateAPIKey(ctx context.Context, req *webservice.GenerateAPI
 		return nil, err
 	}
 
+	if err := a.quotaChecker.checkAPIKeys(ctx, claims.Role.ProjectId); err != nil {
+		return nil, err
+	}
+
 	id := uuid.New().String()
 	key, hash, err := model.GenerateAPIKey(id)
 	if err != nil {
@@ -1601,17 +2292,117 @@ func (a *WebAPI) GetInsightData(ctx context.Context, req *webservice.GetInsightD
 		return nil, err
 	}
 
+	if req.ApplicationId != "" && (len(req.Labels) > 0 || req.GroupByLabel != "") {
+		return nil, status.Error(codes.InvalidArgument, "Application ID and labels cannot be specified at the same time")
+	}
+	if len(req.Labels) > 0 && req.GroupByLabel != "" {
+		return nil, status.Error(codes.InvalidArgument, "Labels and group-by label cannot be specified at the same time")
+	}
+
+	if req.GroupByLabel != "" {
+		return a.getInsightDataGroupByLabel(ctx, claims.Role.ProjectId, req)
+	}
+
+	id := req.ApplicationId
+	for k, v := range req.Labels {
+		id = insight.MakeLabelGroupID(k, v)
+	}
+
+	idp, updatedAt, err := a.loadInsightDataPoints(ctx, claims.Role.ProjectId, id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webservice.GetInsightDataResponse{
+		UpdatedAt:  updatedAt,
+		DataPoints: idp,
+		Type:       model.InsightResultType_MATRIX,
+		Matrix: []*model.InsightSampleStream{
+			{
+				Labels:     req.Labels,
+				DataPoints: idp,
+			},
+		},
+	}, nil
+}
+
+// getInsightDataGroupByLabel returns the insight data of each value of the requested label
+// those are used by the applications in the project.
+func (a *WebAPI) getInsightDataGroupByLabel(ctx context.Context, projectID string, req *webservice.GetInsightDataRequest) (*webservice.GetInsightDataResponse, error) {
+	apps, _, err := a.applicationStore.ListApplications(ctx, datastore.ListOptions{
+		Filters: []datastore.ListFilter{
+			{
+				Field:    "ProjectId",
+				Operator: datastore.OperatorEqual,
+				Value:    projectID,
+			},
+			{
+				Field:    "Deleted",
+				Operator: datastore.OperatorEqual,
+				Value:    false,
+			},
+		},
+	})
+	if err != nil {
+		a.logger.Error("failed to list applications", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list applications")
+	}
+
+	values := make(map[string]struct{})
+	for _, app := range apps {
+		if v, ok := app.Labels[req.GroupByLabel]; ok {
+			values[v] = struct{}{}
+		}
+	}
+	sortedValues := make([]string, 0, len(values))
+	for v := range values {
+		sortedValues = append(sortedValues, v)
+	}
+	sort.Strings(sortedValues)
+
+	var (
+		matrix    = make([]*model.InsightSampleStream, 0, len(sortedValues))
+		updatedAt int64
+	)
+	for _, v := range sortedValues {
+		idp, accumulatedTo, err := a.loadInsightDataPoints(ctx, projectID, insight.MakeLabelGroupID(req.GroupByLabel, v), req)
+		if errors.Is(err, filestore.ErrNotFound) {
+			// No deployment of the applications having this label was collected yet.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if accumulatedTo > updatedAt {
+			updatedAt = accumulatedTo
+		}
+		matrix = append(matrix, &model.InsightSampleStream{
+			Labels:     map[string]string{req.GroupByLabel: v},
+			DataPoints: idp,
+		})
+	}
+
+	return &webservice.GetInsightDataResponse{
+		UpdatedAt: updatedAt,
+		Type:      model.InsightResultType_MATRIX,
+		Matrix:    matrix,
+	}, nil
+}
+
+// loadInsightDataPoints loads the requested data points of the given application or label group
+// along with the time the data was accumulated to.
+func (a *WebAPI) loadInsightDataPoints(ctx context.Context, projectID, id string, req *webservice.GetInsightDataRequest) ([]*model.InsightDataPoint, int64, error) {
 	count := int(req.DataPointCount)
 	from := time.Unix(req.RangeFrom, 0)
 
-	chunks, err := insightstore.LoadChunksFromCache(a.insightCache, claims.Role.ProjectId, req.ApplicationId, req.MetricsKind, req.Step, from, count)
+	chunks, err := insightstore.LoadChunksFromCache(a.insightCache, projectID, id, req.MetricsKind, req.Step, from, count)
 	if err != nil {
 		a.logger.Error("failed to load chunks from cache", zap.Error(err))
 
-		chunks, err = a.insightStore.LoadChunks(ctx, claims.Role.ProjectId, req.ApplicationId, req.MetricsKind, req.Step, from, count)
+		chunks, err = a.insightStore.LoadChunks(ctx, projectID, id, req.MetricsKind, req.Step, from, count)
 		if err != nil {
 			a.logger.Error("failed to load chunks from insightstore", zap.Error(err))
-			return nil, err
+			return nil, 0, err
 		}
 		if err := insightstore.PutChunksToCache(a.insightCache, chunks); err != nil {
 			a.logger.Error("failed to put chunks to cache", zap.Error(err))
@@ -1630,17 +2421,7 @@ func (a *WebAPI) GetInsightData(ctx context.Context, req *webservice.GetInsightD
 			updateAt = accumulatedTo
 		}
 	}
-
-	return &webservice.GetInsightDataResponse{
-		UpdatedAt:  updateAt,
-		DataPoints: idp,
-		Type:       model.InsightResultType_MATRIX,
-		Matrix: []*model.InsightSampleStream{
-			{
-				DataPoints: idp,
-			},
-		},
-	}, nil
+	return idp, updateAt, nil
 }
 
 func (a *WebAPI) GetInsightApplicationCount(ctx context.Context, req *webservice.GetInsightApplicationCountRequest) (*webservice.GetInsightApplicationCountResponse, error) {
@@ -17,7 +17,9 @@ package grpcapi
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -387,3 +389,111 @@ func TestValidatePipedBelongsToProject(t *testing.T) {
 		})
 	}
 }
+
+func TestDeploymentMatchesQuery(t *testing.T) {
+	d := &model.Deployment{
+		ApplicationName: "Canary-App",
+		Version:         "v1.2.3",
+		Trigger: &model.DeploymentTrigger{
+			Commit: &model.Commit{
+				Message: "Update the replicas number",
+				Author:  "nghialv",
+			},
+			Commander: "",
+		},
+	}
+	tests := []struct {
+		name  string
+		query string
+		want  bool
+	}{
+		{
+			name: "empty query",
+			want: true,
+		},
+		{
+			name:  "match application name case-insensitively",
+			query: "canary",
+			want:  true,
+		},
+		{
+			name:  "match version",
+			query: "v1.2",
+			want:  true,
+		},
+		{
+			name:  "match all terms",
+			query: "REPLICAS nghia",
+			want:  true,
+		},
+		{
+			name:  "one term does not match",
+			query: "replicas foo",
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := deploymentMatchesQuery(d, strings.Fields(strings.ToLower(tt.query)))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSearchDeployments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	newBatch := func(prefix string, matched map[int]bool) []*model.Deployment {
+		batch := make([]*model.Deployment, searchDeploymentsBatchSize)
+		for i := range batch {
+			name := "other"
+			if matched[i] {
+				name = "target"
+			}
+			batch[i] = &model.Deployment{
+				Id:              fmt.Sprintf("%s-%d", prefix, i),
+				ApplicationName: name,
+			}
+		}
+		return batch
+	}
+	first := newBatch("first", map[int]bool{1: true, 50: true, 99: true})
+	second := newBatch("second", map[int]bool{0: true})[:10]
+
+	s := datastoretest.NewMockDeploymentStore(ctrl)
+	s.EXPECT().
+		ListDeployments(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, opts datastore.ListOptions) ([]*model.Deployment, string, error) {
+			if opts.Cursor == "" {
+				return first, "next", nil
+			}
+			return second, "", nil
+		}).AnyTimes()
+
+	// The first page ends in the middle of the first batch.
+	got, cursor, err := searchDeployments(ctx, s, nil, "target", 2, searchCursor{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.Deployment{first[1], first[50]}, got)
+	assert.NotEmpty(t, cursor)
+
+	c, err := decodeSearchCursor(cursor)
+	assert.NoError(t, err)
+	assert.Equal(t, searchCursor{Cursor: "", Offset: 51}, c)
+
+	// The second page continues from there and scans until the end.
+	got, cursor, err = searchDeployments(ctx, s, nil, "target", 2, c)
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.Deployment{first[99], second[0]}, got)
+	assert.NotEmpty(t, cursor)
+
+	c, err = decodeSearchCursor(cursor)
+	assert.NoError(t, err)
+	got, cursor, err = searchDeployments(ctx, s, nil, "target", 2, c)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+	assert.Empty(t, cursor)
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifestdiffstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/filestore"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+)
+
+// Store persists the manifest diff computed by piped while planning a deployment.
+type Store interface {
+	Get(ctx context.Context, deploymentID string) ([]byte, error)
+	Put(ctx context.Context, deploymentID string, data []byte) error
+}
+
+type store struct {
+	backend filestore.Store
+	logger  *zap.Logger
+}
+
+func NewStore(fs filestore.Store, logger *zap.Logger) Store {
+	return &store{
+		backend: fs,
+		logger:  logger.Named("manifest-diff-store"),
+	}
+}
+
+func (s *store) Get(ctx context.Context, deploymentID string) ([]byte, error) {
+	path := dataPath(deploymentID)
+	obj, err := s.backend.GetObject(ctx, path)
+	if err != nil {
+		if err == filestore.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		s.logger.Error("failed to get manifest diff from filestore",
+			zap.String("deployment", deploymentID),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return obj.Content, nil
+}
+
+func (s *store) Put(ctx context.Context, deploymentID string, data []byte) error {
+	path := dataPath(deploymentID)
+	return s.backend.PutObject(ctx, path, data)
+}
+
+func dataPath(deploymentID string) string {
+	return fmt.Sprintf("manifest-diff/%s.txt", deploymentID)
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifestdiffstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/filestore"
+	"github.com/pipe-cd/pipe/pkg/filestore/filestoretest"
+)
+
+func TestStoreGet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	fs := filestoretest.NewMockStore(ctrl)
+	store := NewStore(fs, zap.NewNop())
+
+	testcases := []struct {
+		name        string
+		content     string
+		readerErr   error
+		expected    []byte
+		expectedErr error
+	}{
+		{
+			name:        "file not found in filestore",
+			readerErr:   filestore.ErrNotFound,
+			expectedErr: ErrNotFound,
+		},
+		{
+			name:        "failed to read from filestore",
+			readerErr:   errors.New("unavailable"),
+			expectedErr: errors.New("unavailable"),
+		},
+		{
+			name:     "found file in filestore",
+			content:  "--- Running Commit\n+++ Target Commit\n",
+			expected: []byte("--- Running Commit\n+++ Target Commit\n"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			fs.EXPECT().GetObject(ctx, "manifest-diff/deployment-id.txt").Return(filestore.Object{Content: []byte(tc.content)}, tc.readerErr)
+
+			got, err := store.Get(ctx, "deployment-id")
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestStorePut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	fs := filestoretest.NewMockStore(ctrl)
+	store := NewStore(fs, zap.NewNop())
+
+	ctx := context.Background()
+	fs.EXPECT().PutObject(ctx, "manifest-diff/deployment-id.txt", []byte("diff")).Return(nil)
+	assert.NoError(t, store.Put(ctx, "deployment-id", []byte("diff")))
+}
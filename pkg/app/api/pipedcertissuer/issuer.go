@@ -0,0 +1,151 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pipedcertissuer issues the client certificates used by pipeds
+// to connect to the control plane over mutual TLS.
+package pipedcertissuer
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"time"
+)
+
+// clockSkew is subtracted from NotBefore of the issued certificates
+// to tolerate the small clock differences between hosts.
+const clockSkew = 5 * time.Minute
+
+// ErrInvalidCSR is returned when the given certificate signing request
+// is malformed or not signed by the key it contains.
+var ErrInvalidCSR = errors.New("invalid certificate signing request")
+
+// Issuer signs the client certificates of pipeds with a CA key.
+// The piped ID is used as the common name of the issued certificates
+// so that the control plane can verify which piped is connecting.
+type Issuer struct {
+	caCert  *x509.Certificate
+	caKey   crypto.Signer
+	ttl     time.Duration
+	nowFunc func() time.Time
+}
+
+// NewIssuer creates a new Issuer from the PEM encoded CA certificate and key files.
+func NewIssuer(caCertFile, caKeyFile string, ttl time.Duration) (*Issuer, error) {
+	certPEM, err := ioutil.ReadFile(caCertFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate file: %w", err)
+	}
+	keyPEM, err := ioutil.ReadFile(caKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA key file: %w", err)
+	}
+	return newIssuer(certPEM, keyPEM, ttl)
+}
+
+func newIssuer(certPEM, keyPEM []byte, ttl time.Duration) (*Issuer, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data was found in CA certificate")
+	}
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+	if !caCert.IsCA {
+		return nil, fmt.Errorf("the given certificate is not a CA certificate")
+	}
+
+	block, _ = pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data was found in CA key")
+	}
+	caKey, err := parsePrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA key: %w", err)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("ttl must be positive")
+	}
+
+	return &Issuer{
+		caCert:  caCert,
+		caKey:   caKey,
+		ttl:     ttl,
+		nowFunc: time.Now,
+	}, nil
+}
+
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return x509.ParsePKCS1PrivateKey(der)
+}
+
+// Issue signs the given PEM encoded certificate signing request
+// and returns the PEM encoded client certificate for the given piped.
+// Only the public key is taken from the request, the other fields are decided by the issuer.
+func (i *Issuer) Issue(csrPEM []byte, pipedID string) ([]byte, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, ErrInvalidCSR
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	now := i.nowFunc()
+	notAfter := now.Add(i.ttl)
+	// The issued certificate must not outlive the CA certificate.
+	if notAfter.After(i.caCert.NotAfter) {
+		notAfter = i.caCert.NotAfter
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName: pipedID,
+		},
+		NotBefore:   now.Add(-clockSkew),
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, i.caCert, csr.PublicKey, i.caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign certificate: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
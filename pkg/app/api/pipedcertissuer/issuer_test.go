@@ -0,0 +1,125 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipedcertissuer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCA(t *testing.T) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return
+}
+
+func newTestCSR(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "another-piped"},
+	}, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func TestIssue(t *testing.T) {
+	caCertPEM, caKeyPEM := newTestCA(t)
+	issuer, err := newIssuer(caCertPEM, caKeyPEM, time.Hour)
+	require.NoError(t, err)
+
+	certPEM, err := issuer.Issue(newTestCSR(t), "piped-1")
+	require.NoError(t, err)
+
+	block, _ := pem.Decode(certPEM)
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+
+	// The common name requested by the piped is ignored.
+	assert.Equal(t, "piped-1", cert.Subject.CommonName)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), cert.NotAfter, time.Minute)
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caCertPEM)
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	assert.NoError(t, err)
+
+	// The certificate does not outlive the CA certificate.
+	issuer.ttl = 48 * time.Hour
+	certPEM, err = issuer.Issue(newTestCSR(t), "piped-1")
+	require.NoError(t, err)
+	block, _ = pem.Decode(certPEM)
+	cert, err = x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, issuer.caCert.NotAfter, cert.NotAfter)
+}
+
+func TestIssueInvalidCSR(t *testing.T) {
+	caCertPEM, caKeyPEM := newTestCA(t)
+	issuer, err := newIssuer(caCertPEM, caKeyPEM, time.Hour)
+	require.NoError(t, err)
+
+	_, err = issuer.Issue([]byte("invalid"), "piped-1")
+	assert.True(t, errors.Is(err, ErrInvalidCSR))
+
+	// A certificate is not a certificate signing request.
+	_, err = issuer.Issue(caCertPEM, "piped-1")
+	assert.True(t, errors.Is(err, ErrInvalidCSR))
+}
+
+func TestNewIssuer(t *testing.T) {
+	caCertPEM, caKeyPEM := newTestCA(t)
+
+	_, err := newIssuer(caCertPEM, caKeyPEM, 0)
+	assert.Error(t, err)
+
+	_, err = newIssuer(caKeyPEM, caKeyPEM, time.Hour)
+	assert.Error(t, err)
+
+	_, err = newIssuer(caCertPEM, caCertPEM, time.Hour)
+	assert.Error(t, err)
+}
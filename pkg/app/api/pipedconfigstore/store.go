@@ -0,0 +1,84 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipedconfigstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/filestore"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+)
+
+// Store persists all versions of the piped configurations managed in the control-plane.
+type Store interface {
+	Get(ctx context.Context, pipedID string, version int64) (*model.PipedConfig, error)
+	Put(ctx context.Context, cfg *model.PipedConfig) error
+}
+
+type store struct {
+	backend filestore.Store
+	logger  *zap.Logger
+}
+
+func NewStore(fs filestore.Store, logger *zap.Logger) Store {
+	return &store{
+		backend: fs,
+		logger:  logger.Named("piped-config-store"),
+	}
+}
+
+func (s *store) Get(ctx context.Context, pipedID string, version int64) (*model.PipedConfig, error) {
+	path := dataPath(pipedID, version)
+	obj, err := s.backend.GetObject(ctx, path)
+	if err != nil {
+		if err == filestore.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		s.logger.Error("failed to get piped config from filestore",
+			zap.String("piped", pipedID),
+			zap.Int64("version", version),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	var cfg model.PipedConfig
+	if err := json.Unmarshal(obj.Content, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal piped config: %w", err)
+	}
+	return &cfg, nil
+}
+
+func (s *store) Put(ctx context.Context, cfg *model.PipedConfig) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal piped config: %w", err)
+	}
+	path := dataPath(cfg.PipedId, cfg.Version)
+	return s.backend.PutObject(ctx, path, data)
+}
+
+func dataPath(pipedID string, version int64) string {
+	return fmt.Sprintf("piped-config/%s/%d.json", pipedID, version)
+}
@@ -121,6 +121,19 @@ func (c *fakeClient) ReportPipedMeta(ctx context.Context, req *pipedservice.Repo
 	return &pipedservice.ReportPipedMetaResponse{}, nil
 }
 
+// ReportPipedDiagnostics is sent by piped to report the result of its self-diagnostics.
+func (c *fakeClient) ReportPipedDiagnostics(ctx context.Context, req *pipedservice.ReportPipedDiagnosticsRequest, opts ...grpc.CallOption) (*pipedservice.ReportPipedDiagnosticsResponse, error) {
+	c.logger.Info("fake client received ReportPipedDiagnostics rpc", zap.Any("request", req))
+	return &pipedservice.ReportPipedDiagnosticsResponse{}, nil
+}
+
+// GetPipedConfig returns the configuration of piped
+// which is stored and managed in the control-plane.
+func (c *fakeClient) GetPipedConfig(ctx context.Context, req *pipedservice.GetPipedConfigRequest, opts ...grpc.CallOption) (*pipedservice.GetPipedConfigResponse, error) {
+	c.logger.Info("fake client received GetPipedConfig rpc", zap.Any("request", req))
+	return nil, status.Error(codes.NotFound, "piped config is not managed by control-plane")
+}
+
 // GetEnvironment finds and returns the environment for the specified ID.
 func (c *fakeClient) GetEnvironment(ctx context.Context, req *pipedservice.GetEnvironmentRequest, opts ...grpc.CallOption) (*pipedservice.GetEnvironmentResponse, error) {
 	c.logger.Info("fake client received GetEnvironment rpc", zap.Any("request", req))
@@ -287,6 +300,7 @@ func (c *fakeClient) ReportDeploymentPlanned(ctx context.Context, req *pipedserv
 	d.StatusReason = req.StatusReason
 	d.RunningCommitHash = req.RunningCommitHash
 	d.Version = req.Version
+	d.Versions = req.Versions
 	if len(req.Stages) > 0 {
 		d.Stages = req.Stages
 	}
@@ -365,6 +379,20 @@ func (c *fakeClient) SaveDeploymentMetadata(ctx context.Context, req *pipedservi
 	return &pipedservice.SaveDeploymentMetadataResponse{}, nil
 }
 
+func (c *fakeClient) SaveDeploymentCheckpoint(ctx context.Context, req *pipedservice.SaveDeploymentCheckpointRequest, opts ...grpc.CallOption) (*pipedservice.SaveDeploymentCheckpointResponse, error) {
+	c.logger.Info("fake client received SaveDeploymentCheckpoint rpc", zap.Any("request", req))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d, ok := c.deployments[req.DeploymentId]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "deployment was not found")
+	}
+
+	d.Checkpoint = req.Checkpoint
+	return &pipedservice.SaveDeploymentCheckpointResponse{}, nil
+}
+
 // SaveStageMetadata used by piped to persist the metadata
 // of a specific stage of a deployment.
 func (c *fakeClient) SaveStageMetadata(ctx context.Context, req *pipedservice.SaveStageMetadataRequest, opts ...grpc.CallOption) (*pipedservice.SaveStageMetadataResponse, error) {
@@ -387,6 +415,50 @@ func (c *fakeClient) SaveStageMetadata(ctx context.Context, req *pipedservice.Sa
 	return nil, status.Error(codes.NotFound, "stage was not found")
 }
 
+// SaveStageResults used by piped to persist the key results
+// of a specific stage of a deployment.
+func (c *fakeClient) SaveStageResults(ctx context.Context, req *pipedservice.SaveStageResultsRequest, opts ...grpc.CallOption) (*pipedservice.SaveStageResultsResponse, error) {
+	c.logger.Info("fake client received SaveStageResults rpc", zap.Any("request", req))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d, ok := c.deployments[req.DeploymentId]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "deployment was not found")
+	}
+
+	for _, s := range d.Stages {
+		if s.Id != req.StageId {
+			continue
+		}
+		s.Results = req.Results
+		return &pipedservice.SaveStageResultsResponse{}, nil
+	}
+	return nil, status.Error(codes.NotFound, "stage was not found")
+}
+
+// ReportAnalysisResult is sent by piped to persist the detailed data
+// collected while running an ANALYSIS stage.
+func (c *fakeClient) ReportAnalysisResult(ctx context.Context, req *pipedservice.ReportAnalysisResultRequest, opts ...grpc.CallOption) (*pipedservice.ReportAnalysisResultResponse, error) {
+	c.logger.Info("fake client received ReportAnalysisResult rpc",
+		zap.String("deployment-id", req.Result.DeploymentId),
+		zap.String("stage-id", req.Result.StageId),
+		zap.Int("queries", len(req.Result.Queries)),
+	)
+	return &pipedservice.ReportAnalysisResultResponse{}, nil
+}
+
+// ReportDeploymentProvenance is sent by piped to persist the provenance
+// recorded while applying the manifests of a deployment.
+func (c *fakeClient) ReportDeploymentProvenance(ctx context.Context, req *pipedservice.ReportDeploymentProvenanceRequest, opts ...grpc.CallOption) (*pipedservice.ReportDeploymentProvenanceResponse, error) {
+	c.logger.Info("fake client received ReportDeploymentProvenance rpc",
+		zap.String("deployment-id", req.Provenance.DeploymentId),
+		zap.String("commit-hash", req.Provenance.CommitHash),
+		zap.Int("images", len(req.Provenance.Images)),
+	)
+	return &pipedservice.ReportDeploymentProvenanceResponse{}, nil
+}
+
 // ReportStageLogs is sent by piped to save the log of a pipeline stage.
 func (c *fakeClient) ReportStageLogs(ctx context.Context, req *pipedservice.ReportStageLogsRequest, opts ...grpc.CallOption) (*pipedservice.ReportStageLogsResponse, error) {
 	c.logger.Info("fake client received ReportStageLogs rpc", zap.Any("request", req))
@@ -415,6 +487,9 @@ func (c *fakeClient) ReportStageStatusChanged(ctx context.Context, req *pipedser
 		if s.Id != req.StageId {
 			continue
 		}
+		if req.Status == model.StageStatus_STAGE_RUNNING && s.Status != model.StageStatus_STAGE_RUNNING {
+			s.StartedAt = req.CompletedAt
+		}
 		s.Status = req.Status
 		s.RetriedCount = req.RetriedCount
 		s.Visible = req.Visible
@@ -486,4 +561,34 @@ func (c *fakeClient) ListEvents(ctx context.Context, req *pipedservice.ListEvent
 	return &pipedservice.ListEventsResponse{}, nil
 }
 
+func (c *fakeClient) RegisterEvent(ctx context.Context, req *pipedservice.RegisterEventRequest, opts ...grpc.CallOption) (*pipedservice.RegisterEventResponse, error) {
+	c.logger.Info("fake client received RegisterEvent rpc", zap.Any("request", req))
+	return &pipedservice.RegisterEventResponse{}, nil
+}
+
+func (c *fakeClient) AcquireDeploymentLocks(ctx context.Context, req *pipedservice.AcquireDeploymentLocksRequest, opts ...grpc.CallOption) (*pipedservice.AcquireDeploymentLocksResponse, error) {
+	c.logger.Info("fake client received AcquireDeploymentLocks rpc", zap.Any("request", req))
+	return &pipedservice.AcquireDeploymentLocksResponse{Acquired: true}, nil
+}
+
+func (c *fakeClient) ReleaseDeploymentLocks(ctx context.Context, req *pipedservice.ReleaseDeploymentLocksRequest, opts ...grpc.CallOption) (*pipedservice.ReleaseDeploymentLocksResponse, error) {
+	c.logger.Info("fake client received ReleaseDeploymentLocks rpc", zap.Any("request", req))
+	return &pipedservice.ReleaseDeploymentLocksResponse{}, nil
+}
+
+func (c *fakeClient) AcquireDeploymentLease(ctx context.Context, req *pipedservice.AcquireDeploymentLeaseRequest, opts ...grpc.CallOption) (*pipedservice.AcquireDeploymentLeaseResponse, error) {
+	c.logger.Info("fake client received AcquireDeploymentLease rpc", zap.Any("request", req))
+	return &pipedservice.AcquireDeploymentLeaseResponse{Acquired: true}, nil
+}
+
+func (c *fakeClient) ReleaseDeploymentLease(ctx context.Context, req *pipedservice.ReleaseDeploymentLeaseRequest, opts ...grpc.CallOption) (*pipedservice.ReleaseDeploymentLeaseResponse, error) {
+	c.logger.Info("fake client received ReleaseDeploymentLease rpc", zap.Any("request", req))
+	return &pipedservice.ReleaseDeploymentLeaseResponse{}, nil
+}
+
+func (c *fakeClient) RenewPipedCertificate(ctx context.Context, req *pipedservice.RenewPipedCertificateRequest, opts ...grpc.CallOption) (*pipedservice.RenewPipedCertificateResponse, error) {
+	c.logger.Info("fake client received RenewPipedCertificate rpc")
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
 var _ pipedservice.PipedServiceClient = (*fakeClient)(nil)
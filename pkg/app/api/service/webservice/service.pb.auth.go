@@ -60,6 +60,10 @@ func (a *authorizer) Authorize(method string, r model.Role) bool {
 		return isAdmin(r)
 	case "/pipe.api.service.webservice.WebService/DisablePiped":
 		return isAdmin(r)
+	case "/pipe.api.service.webservice.WebService/UpdatePipedConfig":
+		return isAdmin(r)
+	case "/pipe.api.service.webservice.WebService/GetPipedConfig":
+		return isAdmin(r)
 	case "/pipe.api.service.webservice.WebService/UpdateProjectStaticAdmin":
 		return isAdmin(r)
 	case "/pipe.api.service.webservice.WebService/EnableStaticAdmin":
@@ -87,6 +91,10 @@ func (a *authorizer) Authorize(method string, r model.Role) bool {
 		return isAdmin(r) || isEditor(r)
 	case "/pipe.api.service.webservice.WebService/DisableApplication":
 		return isAdmin(r) || isEditor(r)
+	case "/pipe.api.service.webservice.WebService/EnableApplicationMaintenance":
+		return isAdmin(r) || isEditor(r)
+	case "/pipe.api.service.webservice.WebService/DisableApplicationMaintenance":
+		return isAdmin(r) || isEditor(r)
 	case "/pipe.api.service.webservice.WebService/DeleteApplication":
 		return isAdmin(r) || isEditor(r)
 	case "/pipe.api.service.webservice.WebService/SyncApplication":
@@ -100,6 +108,8 @@ func (a *authorizer) Authorize(method string, r model.Role) bool {
 
 	case "/pipe.api.service.webservice.WebService/GetApplicationLiveState":
 		return isAdmin(r) || isEditor(r) || isViewer(r)
+	case "/pipe.api.service.webservice.WebService/ListApplicationHealths":
+		return isAdmin(r) || isEditor(r) || isViewer(r)
 	case "/pipe.api.service.webservice.WebService/GetProject":
 		return isAdmin(r) || isEditor(r) || isViewer(r)
 	case "/pipe.api.service.webservice.WebService/GetCommand":
@@ -122,6 +132,20 @@ func (a *authorizer) Authorize(method string, r model.Role) bool {
 		return isAdmin(r) || isEditor(r) || isViewer(r)
 	case "/pipe.api.service.webservice.WebService/GetStageLog":
 		return isAdmin(r) || isEditor(r) || isViewer(r)
+	case "/pipe.api.service.webservice.WebService/GetDeploymentManifestDiff":
+		return isAdmin(r) || isEditor(r) || isViewer(r)
+	case "/pipe.api.service.webservice.WebService/GetDeploymentStageGraph":
+		return isAdmin(r) || isEditor(r) || isViewer(r)
+	case "/pipe.api.service.webservice.WebService/GetAnalysisResult":
+		return isAdmin(r) || isEditor(r) || isViewer(r)
+	case "/pipe.api.service.webservice.WebService/SearchDeployments":
+		return isAdmin(r) || isEditor(r) || isViewer(r)
+	case "/pipe.api.service.webservice.WebService/AddDeploymentSavedView":
+		return isAdmin(r) || isEditor(r) || isViewer(r)
+	case "/pipe.api.service.webservice.WebService/DeleteDeploymentSavedView":
+		return isAdmin(r) || isEditor(r) || isViewer(r)
+	case "/pipe.api.service.webservice.WebService/ListDeploymentSavedViews":
+		return isAdmin(r) || isEditor(r) || isViewer(r)
 	case "/pipe.api.service.webservice.WebService/GetMe":
 		return isAdmin(r) || isEditor(r) || isViewer(r)
 	case "/pipe.api.service.webservice.WebService/GetInsightData":
@@ -93,6 +93,21 @@ func (c *Collector) listApplications(ctx context.Context, to time.Time) ([]*mode
 	return applications, nil
 }
 
+// listApplicationLabels returns the labels of all applications having labels, keyed by the application ID.
+func (c *Collector) listApplicationLabels(ctx context.Context) (map[string]map[string]string, error) {
+	apps, err := c.listApplications(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[string]map[string]string, len(apps))
+	for _, app := range apps {
+		if len(app.Labels) > 0 {
+			labels[app.Id] = app.Labels
+		}
+	}
+	return labels, nil
+}
+
 // groupApplicationsByProjectID groups applications by projectID
 func groupApplicationsByProjectID(applications []*model.Application) map[string][]*model.Application {
 	apps := make(map[string][]*model.Application)
@@ -39,8 +39,8 @@ type Collector struct {
 	insightstore     insightstore.Store
 
 	applicationsHandlers              []func(ctx context.Context, applications []*model.Application, target time.Time) error
-	newlyCreatedDeploymentsHandlers   []func(ctx context.Context, developments []*model.Deployment, target time.Time) error
-	newlyCompletedDeploymentsHandlers []func(ctx context.Context, developments []*model.Deployment, target time.Time) error
+	newlyCreatedDeploymentsHandlers   []func(ctx context.Context, developments []*model.Deployment, appLabels map[string]map[string]string, target time.Time) error
+	newlyCompletedDeploymentsHandlers []func(ctx context.Context, developments []*model.Deployment, appLabels map[string]map[string]string, target time.Time) error
 
 	config config.ControlPlaneInsightCollector
 	logger *zap.Logger
@@ -136,12 +136,26 @@ func (c *Collector) collectDeploymentMetrics(ctx context.Context) {
 	cfg := c.config.Deployment
 	retry := backoff.NewRetry(cfg.Retries, backoff.NewConstant(cfg.RetryInterval.Duration()))
 
-	var doneNewlyCompleted, doneNewlyCreated bool
+	var (
+		doneNewlyCompleted, doneNewlyCreated bool
+		// The labels are listed once and shared by both kinds of deployments.
+		appLabels map[string]map[string]string
+	)
 
 	for retry.WaitNext(ctx) {
+		if appLabels == nil {
+			labels, err := c.listApplicationLabels(ctx)
+			if err != nil {
+				c.logger.Error("failed to list applications to aggregate data by label", zap.Error(err))
+				c.logger.Info("will do another try to collect insight data")
+				continue
+			}
+			appLabels = labels
+		}
+
 		if !doneNewlyCompleted {
 			start := time.Now()
-			if err := c.processNewlyCompletedDeployments(ctx); err != nil {
+			if err := c.processNewlyCompletedDeployments(ctx, appLabels); err != nil {
 				c.logger.Error("failed to process the newly completed deployments", zap.Error(err))
 			} else {
 				c.logger.Info("successfully processed the newly completed deployments",
@@ -153,7 +167,7 @@ func (c *Collector) collectDeploymentMetrics(ctx context.Context) {
 
 		if !doneNewlyCreated {
 			start := time.Now()
-			if err := c.processNewlyCreatedDeployments(ctx); err != nil {
+			if err := c.processNewlyCreatedDeployments(ctx, appLabels); err != nil {
 				c.logger.Error("failed to process the newly created deployments", zap.Error(err))
 			} else {
 				c.logger.Info("successfully processed the newly created deployments",
@@ -170,7 +184,7 @@ func (c *Collector) collectDeploymentMetrics(ctx context.Context) {
 	}
 }
 
-func (c *Collector) processNewlyCreatedDeployments(ctx context.Context) error {
+func (c *Collector) processNewlyCreatedDeployments(ctx context.Context, appLabels map[string]map[string]string) error {
 	c.logger.Info("will retrieve newly created deployments to build insight data")
 	now := time.Now()
 	targetDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
@@ -192,7 +206,7 @@ func (c *Collector) processNewlyCreatedDeployments(ctx context.Context) error {
 
 	var handleErr error
 	for _, handler := range c.newlyCreatedDeploymentsHandlers {
-		if err := handler(ctx, dc, targetDate); err != nil {
+		if err := handler(ctx, dc, appLabels, targetDate); err != nil {
 			c.logger.Error("failed to execute a handler for newly created deployments", zap.Error(err))
 			// In order to give all handlers the chance to handle the received data, we do not return here.
 			handleErr = err
@@ -212,7 +226,7 @@ func (c *Collector) processNewlyCreatedDeployments(ctx context.Context) error {
 	return nil
 }
 
-func (c *Collector) processNewlyCompletedDeployments(ctx context.Context) error {
+func (c *Collector) processNewlyCompletedDeployments(ctx context.Context, appLabels map[string]map[string]string) error {
 	c.logger.Info("will retrieve newly completed deployments to build insight data")
 	now := time.Now()
 	targetDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
@@ -234,7 +248,7 @@ func (c *Collector) processNewlyCompletedDeployments(ctx context.Context) error
 
 	var handleErr error
 	for _, handler := range c.newlyCompletedDeploymentsHandlers {
-		if err := handler(ctx, dc, targetDate); err != nil {
+		if err := handler(ctx, dc, appLabels, targetDate); err != nil {
 			c.logger.Error("failed to execute a handler for newly completed deployments", zap.Error(err))
 			// In order to give all handlers the chance to handle the received data, we do not return here.
 			handleErr = err
@@ -29,7 +29,7 @@ import (
 
 const limit = 50
 
-func (c *Collector) collectDeploymentChangeFailureRate(ctx context.Context, ds []*model.Deployment, target time.Time) error {
+func (c *Collector) collectDeploymentChangeFailureRate(ctx context.Context, ds []*model.Deployment, appLabels map[string]map[string]string, target time.Time) error {
 	apps, projects := groupDeployments(ds)
 
 	var updateErr error
@@ -45,11 +45,14 @@ func (c *Collector) collectDeploymentChangeFailureRate(ctx context.Context, ds [
 			updateErr = err
 		}
 	}
+	if err := c.updateLabelChunks(ctx, ds, appLabels, model.InsightMetricsKind_CHANGE_FAILURE_RATE, target); err != nil {
+		updateErr = err
+	}
 
 	return updateErr
 }
 
-func (c *Collector) collectDevelopmentFrequency(ctx context.Context, ds []*model.Deployment, target time.Time) error {
+func (c *Collector) collectDevelopmentFrequency(ctx context.Context, ds []*model.Deployment, appLabels map[string]map[string]string, target time.Time) error {
 	apps, projects := groupDeployments(ds)
 
 	var updateErr error
@@ -65,7 +68,23 @@ func (c *Collector) collectDevelopmentFrequency(ctx context.Context, ds []*model
 			updateErr = err
 		}
 	}
+	if err := c.updateLabelChunks(ctx, ds, appLabels, model.InsightMetricsKind_DEPLOYMENT_FREQUENCY, target); err != nil {
+		updateErr = err
+	}
+
+	return updateErr
+}
 
+// updateLabelChunks updates the chunks aggregated from the deployments
+// of the applications having the same label, such as the same team.
+func (c *Collector) updateLabelChunks(ctx context.Context, ds []*model.Deployment, appLabels map[string]map[string]string, kind model.InsightMetricsKind, target time.Time) error {
+	var updateErr error
+	for g, ds := range groupDeploymentsByLabel(ds, appLabels) {
+		if err := c.updateApplicationChunks(ctx, g.projectID, g.id, ds, kind, target); err != nil {
+			c.logger.Error("failed to update label chunks", zap.String("label-group", g.id), zap.Error(err))
+			updateErr = err
+		}
+	}
 	return updateErr
 }
 
@@ -338,6 +357,27 @@ func extractChangeFailureRate(deployments []*model.Deployment, from, to int64, t
 	}, rest
 }
 
+// labelGroup identifies the applications having the same label in a project.
+type labelGroup struct {
+	projectID string
+	id        string
+}
+
+// groupDeploymentsByLabel groups deployments by each label of their applications.
+func groupDeploymentsByLabel(deployments []*model.Deployment, appLabels map[string]map[string]string) map[labelGroup][]*model.Deployment {
+	groups := make(map[labelGroup][]*model.Deployment)
+	for _, d := range deployments {
+		for k, v := range appLabels[d.ApplicationId] {
+			g := labelGroup{
+				projectID: d.ProjectId,
+				id:        insight.MakeLabelGroupID(k, v),
+			}
+			groups[g] = append(groups[g], d)
+		}
+	}
+	return groups
+}
+
 // groupDeployments groups deployments by applicationID and projectID
 func groupDeployments(deployments []*model.Deployment) (apps, projects map[string][]*model.Deployment) {
 	apps = make(map[string][]*model.Deployment)
@@ -1088,3 +1088,60 @@ func TestGroupDeployments(t *testing.T) {
 		})
 	}
 }
+
+func TestGroupDeploymentsByLabel(t *testing.T) {
+	var (
+		d111 = &model.Deployment{
+			Id:            "deployment-1-1-1",
+			ProjectId:     "project-1",
+			ApplicationId: "application-1-1",
+		}
+		d121 = &model.Deployment{
+			Id:            "deployment-1-2-1",
+			ProjectId:     "project-1",
+			ApplicationId: "application-1-2",
+		}
+		d131 = &model.Deployment{
+			Id:            "deployment-1-3-1",
+			ProjectId:     "project-1",
+			ApplicationId: "application-1-3",
+		}
+		d211 = &model.Deployment{
+			Id:            "deployment-2-1-1",
+			ProjectId:     "project-2",
+			ApplicationId: "application-2-1",
+		}
+	)
+	appLabels := map[string]map[string]string{
+		"application-1-1": {"team": "payment", "tier": "backend"},
+		"application-1-2": {"team": "payment"},
+		"application-2-1": {"team": "payment"},
+	}
+
+	testcases := []struct {
+		name        string
+		deployments []*model.Deployment
+		expected    map[labelGroup][]*model.Deployment
+	}{
+		{
+			name:     "no deployment",
+			expected: map[labelGroup][]*model.Deployment{},
+		},
+		{
+			name:        "multiple deployments",
+			deployments: []*model.Deployment{d111, d121, d131, d211},
+			expected: map[labelGroup][]*model.Deployment{
+				{projectID: "project-1", id: "label/team/payment"}: {d111, d121},
+				{projectID: "project-1", id: "label/tier/backend"}: {d111},
+				{projectID: "project-2", id: "label/team/payment"}: {d211},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := groupDeploymentsByLabel(tc.deployments, appLabels)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
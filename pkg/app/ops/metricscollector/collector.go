@@ -0,0 +1,109 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricscollector
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/datastore"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+var (
+	interval = time.Minute
+)
+
+// Collector periodically collects the state of the control plane
+// such as the number of queued commands and connected pipeds
+// and exposes them as Prometheus metrics.
+type Collector struct {
+	commandStore datastore.CommandStore
+	pipedStore   datastore.PipedStore
+	logger       *zap.Logger
+}
+
+func NewCollector(ds datastore.DataStore, logger *zap.Logger) *Collector {
+	return &Collector{
+		commandStore: datastore.NewCommandStore(ds),
+		pipedStore:   datastore.NewPipedStore(ds),
+		logger:       logger.Named("metrics-collector"),
+	}
+}
+
+func (c *Collector) Run(ctx context.Context) error {
+	c.logger.Info("start running metrics collector")
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		c.collectCommandQueueDepth(ctx)
+		c.collectConnectedPipeds(ctx)
+
+		select {
+		case <-ctx.Done():
+			c.logger.Info("metrics collector has been stopped")
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+func (c *Collector) collectCommandQueueDepth(ctx context.Context) {
+	opts := datastore.ListOptions{
+		Filters: []datastore.ListFilter{
+			{
+				Field:    "Status",
+				Operator: datastore.OperatorEqual,
+				Value:    model.CommandStatus_COMMAND_NOT_HANDLED_YET,
+			},
+		},
+	}
+	commands, err := c.commandStore.ListCommands(ctx, opts)
+	if err != nil {
+		c.logger.Error("failed to list not-handled commands", zap.Error(err))
+		return
+	}
+
+	depths := make(map[model.Command_Type]int, len(model.Command_Type_name))
+	for t := range model.Command_Type_name {
+		depths[model.Command_Type(t)] = 0
+	}
+	for _, cmd := range commands {
+		depths[cmd.Type]++
+	}
+	for t, d := range depths {
+		commandQueueDepthGauge.WithLabelValues(t.String()).Set(float64(d))
+	}
+}
+
+func (c *Collector) collectConnectedPipeds(ctx context.Context) {
+	pipeds, err := c.pipedStore.ListPipeds(ctx, datastore.ListOptions{})
+	if err != nil {
+		c.logger.Error("failed to list pipeds", zap.Error(err))
+		return
+	}
+
+	var connected int
+	for _, p := range pipeds {
+		if !p.Disabled && p.Status == model.Piped_ONLINE {
+			connected++
+		}
+	}
+	connectedPipedsGauge.Set(float64(connected))
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricscollector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	commandTypeKey = "type"
+)
+
+var (
+	commandQueueDepthGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "pipecd_command_queue_depth",
+			Help: "Number of commands which have not been handled by piped yet",
+		},
+		[]string{
+			commandTypeKey,
+		},
+	)
+	connectedPipedsGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "pipecd_connected_pipeds",
+			Help: "Number of enabled pipeds connecting to the control plane",
+		},
+	)
+)
+
+func Register(r prometheus.Registerer) {
+	r.MustRegister(
+		commandQueueDepthGauge,
+		connectedPipedsGauge,
+	)
+}
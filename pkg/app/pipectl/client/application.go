@@ -27,10 +27,16 @@ import (
 
 // SyncApplication sents a command to sync a given application and waits until it has been triggered.
 // The deployment ID will be returned or an error.
+// When dryRun is true, the triggered deployment only verifies the changes without applying them.
+// When withDependencies is true, the application is synced after all of its dependencies
+// so the returned deployment is triggered only once they were deployed successfully.
 func SyncApplication(
 	ctx context.Context,
 	cli apiservice.Client,
 	appID string,
+	dryRun bool,
+	commit string,
+	withDependencies bool,
 	checkInterval, timeout time.Duration,
 	logger *zap.Logger,
 ) (string, error) {
@@ -38,7 +44,10 @@ func SyncApplication(
 	defer cancel()
 
 	req := &apiservice.SyncApplicationRequest{
-		ApplicationId: appID,
+		ApplicationId:    appID,
+		DryRun:           dryRun,
+		Commit:           commit,
+		WithDependencies: withDependencies,
 	}
 	resp, err := cli.SyncApplication(ctx, req)
 	if err != nil {
@@ -47,13 +56,32 @@ func SyncApplication(
 
 	logger.Info("Sent a request to sync application and waiting to be accepted...")
 
+	return waitTriggeredDeployment(ctx, cli, resp.CommandId, checkInterval, logger)
+}
+
+// WaitTriggeredDeployment waits until the given sync command has been handled
+// and returns the ID of the deployment triggered by that command.
+func WaitTriggeredDeployment(
+	ctx context.Context,
+	cli apiservice.Client,
+	commandID string,
+	checkInterval, timeout time.Duration,
+	logger *zap.Logger,
+) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return waitTriggeredDeployment(ctx, cli, commandID, checkInterval, logger)
+}
+
+func waitTriggeredDeployment(ctx context.Context, cli apiservice.Client, commandID string, checkInterval time.Duration, logger *zap.Logger) (string, error) {
 	ticker := time.NewTicker(checkInterval)
 	defer ticker.Stop()
 
 	check := func() (deploymentID string, shouldRetry bool) {
 		const triggeredDeploymentIDKey = "TriggeredDeploymentID"
 
-		cmd, err := getCommand(ctx, cli, resp.CommandId)
+		cmd, err := getCommand(ctx, cli, commandID)
 		if err != nil {
 			logger.Error(fmt.Sprintf("Failed while retrieving command information. Try again. (%v)", err))
 			shouldRetry = true
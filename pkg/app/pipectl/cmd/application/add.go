@@ -17,10 +17,13 @@ package application
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/spf13/cobra"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/printer"
 	"github.com/pipe-cd/pipe/pkg/cli"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
@@ -33,6 +36,8 @@ type add struct {
 	envID         string
 	pipedID       string
 	cloudProvider string
+	dependencies  []string
+	labels        map[string]string
 
 	repoID         string
 	appDir         string
@@ -55,6 +60,8 @@ func newAddCommand(root *command) *cobra.Command {
 	cmd.Flags().StringVar(&c.envID, "env-id", c.envID, "The ID of environment where this application should belong to.")
 	cmd.Flags().StringVar(&c.pipedID, "piped-id", c.pipedID, "The ID of piped that should handle this applicaiton.")
 	cmd.Flags().StringVar(&c.cloudProvider, "cloud-provider", c.cloudProvider, "The cloud provider name. One of the registered providers in the piped configuration.")
+	cmd.Flags().StringSliceVar(&c.dependencies, "dependency", c.dependencies, "The ID of application in the same project that this application depends on. Can be specified multiple times.")
+	cmd.Flags().StringToStringVar(&c.labels, "labels", c.labels, "The list of labels for application such as its team and tier. Format: key=value,key2=value2")
 
 	cmd.Flags().StringVar(&c.repoID, "repo-id", c.repoID, "The repository ID. One the registered repositories in the piped configuration.")
 	cmd.Flags().StringVar(&c.appDir, "app-dir", c.appDir, "The relative path from the root of repository to the application directory.")
@@ -96,6 +103,8 @@ func (c *add) run(ctx context.Context, t cli.Telemetry) error {
 		},
 		Kind:          model.ApplicationKind(appKind),
 		CloudProvider: c.cloudProvider,
+		Dependencies:  c.dependencies,
+		Labels:        c.labels,
 	}
 
 	resp, err := cli.AddApplication(ctx, req)
@@ -104,5 +113,13 @@ func (c *add) run(ctx context.Context, t cli.Telemetry) error {
 	}
 
 	t.Logger.Info(fmt.Sprintf("Successfully added application id = %s", resp.ApplicationId))
-	return nil
+	return c.root.printOptions.Print(os.Stdout, resp, func(w io.Writer, wide bool) error {
+		table := printer.Table{
+			Columns: []printer.Column{
+				{Name: "APPLICATION ID"},
+			},
+		}
+		table.AddRow(resp.ApplicationId)
+		return table.Write(w, wide)
+	})
 }
@@ -18,29 +18,38 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/pipe-cd/pipe/pkg/app/pipectl/client"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/printer"
 )
 
 type command struct {
 	clientOptions *client.Options
+	printOptions  *printer.Options
 }
 
 func NewCommand() *cobra.Command {
 	c := &command{
 		clientOptions: &client.Options{},
+		printOptions:  &printer.Options{},
 	}
 	cmd := &cobra.Command{
 		Use:   "application",
 		Short: "Manage application resources.",
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			return c.printOptions.Validate()
+		},
 	}
 
 	cmd.AddCommand(
 		newAddCommand(c),
 		newSyncCommand(c),
+		newSyncAffectedCommand(c),
 		newGetCommand(c),
 		newListCommand(c),
+		newUpdateDependenciesCommand(c),
 	)
 
 	c.clientOptions.RegisterPersistentFlags(cmd)
+	c.printOptions.RegisterPersistentFlags(cmd)
 
 	return cmd
 }
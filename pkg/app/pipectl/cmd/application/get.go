@@ -16,7 +16,6 @@ package application
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -24,7 +23,9 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/printer"
 	"github.com/pipe-cd/pipe/pkg/cli"
+	"github.com/pipe-cd/pipe/pkg/model"
 )
 
 type get struct {
@@ -67,11 +68,8 @@ func (c *get) run(ctx context.Context, _ cli.Telemetry) error {
 		return fmt.Errorf("failed to get application: %w", err)
 	}
 
-	bytes, err := json.Marshal(resp.Application)
-	if err != nil {
-		return fmt.Errorf("failed to marshal application: %w", err)
-	}
-
-	fmt.Fprintln(c.stdout, string(bytes))
-	return nil
+	// JSON is the default to keep the output compatible with the previous versions.
+	return c.root.printOptions.WithDefault(printer.FormatJSON).Print(c.stdout, resp.Application, func(w io.Writer, wide bool) error {
+		return writeApplicationTable(w, []*model.Application{resp.Application}, wide)
+	})
 }
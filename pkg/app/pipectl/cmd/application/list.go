@@ -16,15 +16,16 @@ package application
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/printer"
 	"github.com/pipe-cd/pipe/pkg/cli"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
@@ -89,11 +90,54 @@ func (c *list) run(ctx context.Context, _ cli.Telemetry) error {
 		return fmt.Errorf("failed to list application: %w", err)
 	}
 
-	bytes, err := json.Marshal(resp)
-	if err != nil {
-		return fmt.Errorf("failed to marshal applications: %w", err)
-	}
+	// JSON is the default to keep the output compatible with the previous versions.
+	return c.root.printOptions.WithDefault(printer.FormatJSON).Print(c.stdout, resp, func(w io.Writer, wide bool) error {
+		if err := writeApplicationTable(w, resp.Applications, wide); err != nil {
+			return err
+		}
+		if resp.Cursor != "" {
+			fmt.Fprintf(w, "\nThere are more applications, use --cursor=%s to show them\n", resp.Cursor)
+		}
+		return nil
+	})
+}
 
-	fmt.Fprintln(c.stdout, string(bytes))
-	return nil
+func writeApplicationTable(w io.Writer, apps []*model.Application, wide bool) error {
+	t := printer.Table{
+		Columns: []printer.Column{
+			{Name: "ID"},
+			{Name: "NAME"},
+			{Name: "KIND"},
+			{Name: "ENV ID"},
+			{Name: "SYNC STATUS"},
+			{Name: "PIPED ID", Wide: true},
+			{Name: "REPOSITORY", Wide: true},
+			{Name: "PATH", Wide: true},
+			{Name: "DISABLED", Wide: true},
+		},
+	}
+	for _, app := range apps {
+		var syncStatus, repo, path string
+		if app.SyncState != nil {
+			syncStatus = app.SyncState.Status.String()
+		}
+		if app.GitPath != nil {
+			path = app.GitPath.Path
+			if app.GitPath.Repo != nil {
+				repo = app.GitPath.Repo.Id
+			}
+		}
+		t.AddRow(
+			app.Id,
+			app.Name,
+			app.Kind.String(),
+			app.EnvId,
+			syncStatus,
+			app.PipedId,
+			repo,
+			path,
+			strconv.FormatBool(app.Disabled),
+		)
+	}
+	return t.Write(w, wide)
 }
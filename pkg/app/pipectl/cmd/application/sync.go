@@ -17,12 +17,15 @@ package application
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/pipe-cd/pipe/pkg/app/pipectl/client"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/printer"
 	"github.com/pipe-cd/pipe/pkg/cli"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
@@ -30,10 +33,13 @@ import (
 type sync struct {
 	root *command
 
-	appID         string
-	statuses      []string
-	checkInterval time.Duration
-	timeout       time.Duration
+	appID            string
+	dryRun           bool
+	commit           string
+	withDependencies bool
+	statuses         []string
+	checkInterval    time.Duration
+	timeout          time.Duration
 }
 
 func newSyncCommand(root *command) *cobra.Command {
@@ -49,6 +55,9 @@ func newSyncCommand(root *command) *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&c.appID, "app-id", c.appID, "The application ID.")
+	cmd.Flags().BoolVar(&c.dryRun, "dry-run", c.dryRun, "Whether to only plan and verify the changes without applying them.")
+	cmd.Flags().StringVar(&c.commit, "commit", c.commit, "The commit hash to be deployed instead of the head commit of the branch. The application is synced fully at that commit.")
+	cmd.Flags().BoolVar(&c.withDependencies, "with-dependencies", c.withDependencies, "Whether to sync all dependencies of the application first. Control-plane syncs every application only after its own dependencies were deployed successfully.")
 	cmd.Flags().StringSliceVar(&c.statuses, "wait-status", c.statuses, fmt.Sprintf("The list of waiting statuses. Empty means returning immediately after triggered. (%s)", strings.Join(model.DeploymentStatusStrings(), "|")))
 	cmd.Flags().DurationVar(&c.checkInterval, "check-interval", c.checkInterval, "The interval of checking the requested command.")
 	cmd.Flags().DurationVar(&c.timeout, "timeout", c.timeout, "Maximum execution time.")
@@ -70,12 +79,30 @@ func (c *sync) run(ctx context.Context, t cli.Telemetry) error {
 	}
 	defer cli.Close()
 
-	deploymentID, err := client.SyncApplication(ctx, cli, c.appID, c.checkInterval, c.timeout, t.Logger)
+	deploymentID, err := client.SyncApplication(ctx, cli, c.appID, c.dryRun, c.commit, c.withDependencies, c.checkInterval, c.timeout, t.Logger)
 	if err != nil {
 		return err
 	}
 
 	t.Logger.Info(fmt.Sprintf("Successfully triggered deployment %s", deploymentID))
+	result := syncResult{
+		ApplicationID: c.appID,
+		DeploymentID:  deploymentID,
+	}
+	err = c.root.printOptions.Print(os.Stdout, result, func(w io.Writer, wide bool) error {
+		table := printer.Table{
+			Columns: []printer.Column{
+				{Name: "APPLICATION ID"},
+				{Name: "DEPLOYMENT ID"},
+			},
+		}
+		table.AddRow(result.ApplicationID, result.DeploymentID)
+		return table.Write(w, wide)
+	})
+	if err != nil {
+		return err
+	}
+
 	if len(statuses) == 0 {
 		return nil
 	}
@@ -92,3 +119,8 @@ func (c *sync) run(ctx context.Context, t cli.Telemetry) error {
 		t.Logger,
 	)
 }
+
+type syncResult struct {
+	ApplicationID string `json:"application_id"`
+	DeploymentID  string `json:"deployment_id"`
+}
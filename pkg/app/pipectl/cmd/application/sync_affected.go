@@ -0,0 +1,238 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package application
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/client"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/printer"
+	"github.com/pipe-cd/pipe/pkg/cli"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/git"
+)
+
+// The hash of the empty tree of git.
+const emptyTreeHash = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+type syncAffected struct {
+	root *command
+
+	repoRemoteURL string
+	branch        string
+	commit        string
+	repoDir       string
+	dryRun        bool
+	checkInterval time.Duration
+	timeout       time.Duration
+}
+
+func newSyncAffectedCommand(root *command) *cobra.Command {
+	c := &syncAffected{
+		root:          root,
+		repoDir:       ".",
+		checkInterval: 15 * time.Second,
+		timeout:       5 * time.Minute,
+	}
+	cmd := &cobra.Command{
+		Use:   "sync-affected",
+		Short: "Sync all applications affected by the changes of a commit.",
+		RunE:  cli.WithContext(c.run),
+	}
+
+	cmd.Flags().StringVar(&c.repoRemoteURL, "repo-remote-url", c.repoRemoteURL, "The remote URL of Git repository registered in the piped configuration.")
+	cmd.Flags().StringVar(&c.branch, "branch", c.branch, "The branch of Git repository registered in the piped configuration.")
+	cmd.Flags().StringVar(&c.commit, "commit", c.commit, "The commit whose changes are used to find the affected applications.")
+	cmd.Flags().StringVar(&c.repoDir, "repo-dir", c.repoDir, "The path to the local clone of Git repository containing the commit.")
+	cmd.Flags().BoolVar(&c.dryRun, "dry-run", c.dryRun, "Whether to only plan and verify the changes without applying them.")
+	cmd.Flags().DurationVar(&c.checkInterval, "check-interval", c.checkInterval, "The interval of checking the requested commands.")
+	cmd.Flags().DurationVar(&c.timeout, "timeout", c.timeout, "Maximum execution time.")
+
+	cmd.MarkFlagRequired("repo-remote-url")
+	cmd.MarkFlagRequired("branch")
+	cmd.MarkFlagRequired("commit")
+
+	return cmd
+}
+
+func (c *syncAffected) run(ctx context.Context, t cli.Telemetry) error {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return fmt.Errorf("failed to find git executable: %w", err)
+	}
+	repo := git.NewRepo(c.repoDir, gitPath, c.repoRemoteURL, c.branch)
+	// The root commit is compared with the empty tree to list all of its files.
+	parent := c.commit + "^"
+	if _, err := repo.GetCommitHashForRev(ctx, parent); err != nil {
+		parent = emptyTreeHash
+	}
+	changedPaths, err := repo.ChangedFiles(ctx, parent, c.commit)
+	if err != nil {
+		return fmt.Errorf("failed to list the changed files of commit %s: %w", c.commit, err)
+	}
+	if len(changedPaths) == 0 {
+		t.Logger.Info(fmt.Sprintf("Commit %s does not change any file", c.commit))
+		return c.print(nil)
+	}
+
+	cli, err := c.root.clientOptions.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize client: %w", err)
+	}
+	defer cli.Close()
+
+	appIDs, err := c.listAffectedApplications(ctx, cli, changedPaths, t.Logger)
+	if err != nil {
+		return err
+	}
+	if len(appIDs) == 0 {
+		t.Logger.Info(fmt.Sprintf("There is no application affected by commit %s", c.commit))
+		return c.print(nil)
+	}
+
+	req := &apiservice.SyncAffectedApplicationsRequest{
+		RepoRemoteUrl:  c.repoRemoteURL,
+		Branch:         c.branch,
+		Commit:         c.commit,
+		ApplicationIds: appIDs,
+		DryRun:         c.dryRun,
+	}
+	resp, err := cli.SyncAffectedApplications(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to sync affected applications: %w", err)
+	}
+
+	t.Logger.Info(fmt.Sprintf("Sent requests to sync %d affected applications and waiting to be accepted...", len(resp.Applications)))
+
+	var (
+		results = make([]syncAffectedResult, 0, len(resp.Applications))
+		failed  int
+	)
+	for _, app := range resp.Applications {
+		var deploymentID string
+		if app.CommandId != "" {
+			id, err := client.WaitTriggeredDeployment(ctx, cli, app.CommandId, c.checkInterval, c.timeout, t.Logger)
+			if err != nil {
+				app.Error = err.Error()
+			}
+			deploymentID = id
+		}
+		if app.Error != "" {
+			failed++
+		}
+		results = append(results, syncAffectedResult{
+			ApplicationID:   app.ApplicationId,
+			ApplicationName: app.ApplicationName,
+			EnvID:           app.EnvId,
+			DeploymentID:    deploymentID,
+			Error:           app.Error,
+		})
+	}
+	if err := c.print(results); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to trigger %d of %d affected applications", failed, len(resp.Applications))
+	}
+	return nil
+}
+
+// listAffectedApplications returns the IDs of the enabled applications placed in the repository branch
+// which are affected by the given changed paths in the same way as piped triggers them,
+// i.e. by the changes inside their directories or matching their trigger paths.
+// The deployment configuration files are read from the local clone of the repository.
+func (c *syncAffected) listAffectedApplications(ctx context.Context, cli apiservice.Client, changedPaths []string, logger *zap.Logger) ([]string, error) {
+	var (
+		ids    []string
+		cursor string
+	)
+	for {
+		resp, err := cli.ListApplications(ctx, &apiservice.ListApplicationsRequest{
+			Cursor: cursor,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list applications: %w", err)
+		}
+
+		for _, app := range resp.Applications {
+			if app.Deleted || app.GitPath == nil || app.GitPath.Repo == nil {
+				continue
+			}
+			if app.GitPath.Repo.Remote != c.repoRemoteURL || app.GitPath.Repo.Branch != c.branch {
+				continue
+			}
+
+			var triggerPaths []string
+			cfg, err := config.LoadApplication(c.repoDir, app.GitPath.GetDeploymentConfigFilePath())
+			if err != nil {
+				logger.Warn(fmt.Sprintf("Unable to load the deployment configuration of application %s, only its directory is checked (%v)", app.Id, err))
+			} else if spec, ok := cfg.GetGenericDeployment(); ok {
+				triggerPaths = spec.TriggerPaths
+			}
+
+			touched, err := config.IsTouchedByChangedFiles(app.GitPath.Path, triggerPaths, changedPaths)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check the changes of application %s: %w", app.Id, err)
+			}
+			if touched {
+				ids = append(ids, app.Id)
+			}
+		}
+
+		if resp.Cursor == "" {
+			return ids, nil
+		}
+		cursor = resp.Cursor
+	}
+}
+
+type syncAffectedResult struct {
+	ApplicationID   string `json:"application_id"`
+	ApplicationName string `json:"application_name"`
+	EnvID           string `json:"env_id"`
+	DeploymentID    string `json:"deployment_id"`
+	Error           string `json:"error,omitempty"`
+}
+
+func (c *syncAffected) print(results []syncAffectedResult) error {
+	if results == nil {
+		results = []syncAffectedResult{}
+	}
+	return c.root.printOptions.Print(os.Stdout, results, func(w io.Writer, wide bool) error {
+		t := printer.Table{
+			Columns: []printer.Column{
+				{Name: "APPLICATION"},
+				{Name: "APPLICATION ID"},
+				{Name: "ENV ID", Wide: true},
+				{Name: "DEPLOYMENT ID"},
+				{Name: "ERROR"},
+			},
+		}
+		for _, r := range results {
+			t.AddRow(r.ApplicationName, r.ApplicationID, r.EnvID, r.DeploymentID, r.Error)
+		}
+		return t.Write(w, wide)
+	})
+}
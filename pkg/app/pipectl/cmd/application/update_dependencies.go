@@ -0,0 +1,69 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/cli"
+)
+
+type updateDependencies struct {
+	root *command
+
+	appID        string
+	dependencies []string
+}
+
+func newUpdateDependenciesCommand(root *command) *cobra.Command {
+	c := &updateDependencies{
+		root: root,
+	}
+	cmd := &cobra.Command{
+		Use:   "update-dependencies",
+		Short: "Update the list of applications an application depends on.",
+		RunE:  cli.WithContext(c.run),
+	}
+
+	cmd.Flags().StringVar(&c.appID, "app-id", c.appID, "The application ID.")
+	cmd.Flags().StringSliceVar(&c.dependencies, "dependency", c.dependencies, "The ID of application in the same project that this application depends on. Can be specified multiple times. Empty means removing all dependencies.")
+
+	cmd.MarkFlagRequired("app-id")
+
+	return cmd
+}
+
+func (c *updateDependencies) run(ctx context.Context, t cli.Telemetry) error {
+	cli, err := c.root.clientOptions.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize client: %w", err)
+	}
+	defer cli.Close()
+
+	req := &apiservice.UpdateApplicationDependenciesRequest{
+		ApplicationId: c.appID,
+		Dependencies:  c.dependencies,
+	}
+	if _, err := cli.UpdateApplicationDependencies(ctx, req); err != nil {
+		return fmt.Errorf("failed to update application dependencies: %w", err)
+	}
+
+	t.Logger.Info(fmt.Sprintf("Successfully updated dependencies of application %s", c.appID))
+	return nil
+}
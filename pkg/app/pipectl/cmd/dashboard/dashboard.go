@@ -0,0 +1,204 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/client"
+	"github.com/pipe-cd/pipe/pkg/cli"
+)
+
+const (
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+	exitAltScreen  = "\x1b[?25h\x1b[?1049l"
+	clearScreen    = "\x1b[H\x1b[2J"
+)
+
+type command struct {
+	clientOptions *client.Options
+
+	refreshInterval time.Duration
+	deploymentLimit int
+	eventLimit      int
+}
+
+func NewCommand() *cobra.Command {
+	c := &command{
+		clientOptions:   &client.Options{},
+		refreshInterval: 5 * time.Second,
+		deploymentLimit: 10,
+		eventLimit:      5,
+	}
+	cmd := &cobra.Command{
+		Use:   "dashboard",
+		Short: "Show an interactive dashboard of live deployments and recent events in the terminal.",
+		RunE:  cli.WithContext(c.run),
+	}
+
+	cmd.Flags().DurationVar(&c.refreshInterval, "refresh-interval", c.refreshInterval, "The interval of refreshing the shown data.")
+	cmd.Flags().IntVar(&c.deploymentLimit, "deployment-limit", c.deploymentLimit, "The maximum number of shown deployments.")
+	cmd.Flags().IntVar(&c.eventLimit, "event-limit", c.eventLimit, "The maximum number of shown events.")
+
+	c.clientOptions.RegisterPersistentFlags(cmd)
+
+	return cmd
+}
+
+func (c *command) run(ctx context.Context, _ cli.Telemetry) error {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return errors.New("dashboard must be run in an interactive terminal")
+	}
+
+	cli, err := c.clientOptions.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize client: %w", err)
+	}
+	defer cli.Close()
+
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to put the terminal into raw mode: %w", err)
+	}
+	defer terminal.Restore(fd, state)
+
+	fmt.Fprint(os.Stdout, enterAltScreen)
+	defer fmt.Fprint(os.Stdout, exitAltScreen)
+
+	keyCh := make(chan string)
+	go readKeys(ctx, os.Stdin, keyCh)
+
+	v := newView()
+	c.refresh(ctx, cli, v)
+	c.draw(v)
+
+	ticker := time.NewTicker(c.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+			c.refresh(ctx, cli, v)
+
+		case key := <-keyCh:
+			switch v.handleKey(key) {
+			case actionQuit:
+				return nil
+			case actionRefresh:
+				c.refresh(ctx, cli, v)
+			case actionApprove:
+				c.approve(ctx, cli, v)
+				c.refresh(ctx, cli, v)
+			case actionCancel:
+				c.cancel(ctx, cli, v)
+				c.refresh(ctx, cli, v)
+			}
+		}
+		c.draw(v)
+	}
+}
+
+func (c *command) refresh(ctx context.Context, cli apiservice.Client, v *view) {
+	deployments, err := cli.ListDeployments(ctx, &apiservice.ListDeploymentsRequest{
+		Limit: int32(c.deploymentLimit),
+	})
+	if err != nil {
+		v.message = fmt.Sprintf("Failed to list deployments: %v", err)
+		return
+	}
+	events, err := cli.ListEvents(ctx, &apiservice.ListEventsRequest{
+		Limit: int32(c.eventLimit),
+	})
+	if err != nil {
+		v.message = fmt.Sprintf("Failed to list events: %v", err)
+		return
+	}
+	v.update(deployments.Deployments, events.Events)
+}
+
+func (c *command) approve(ctx context.Context, cli apiservice.Client, v *view) {
+	d := v.selectedDeployment()
+	if d == nil {
+		return
+	}
+	stage := approvableStage(d)
+	if stage == nil {
+		v.message = fmt.Sprintf("Deployment %s has no stage waiting for approval", d.Id)
+		return
+	}
+	resp, err := cli.ApproveStage(ctx, &apiservice.ApproveStageRequest{
+		DeploymentId: d.Id,
+		StageId:      stage.Id,
+	})
+	if err != nil {
+		v.message = fmt.Sprintf("Failed to approve stage %s: %v", stage.Name, err)
+		return
+	}
+	v.message = fmt.Sprintf("Requested to approve stage %s (command %s)", stage.Name, resp.CommandId)
+}
+
+func (c *command) cancel(ctx context.Context, cli apiservice.Client, v *view) {
+	d := v.selectedDeployment()
+	if d == nil {
+		return
+	}
+	resp, err := cli.CancelDeployment(ctx, &apiservice.CancelDeploymentRequest{
+		DeploymentId: d.Id,
+	})
+	if err != nil {
+		v.message = fmt.Sprintf("Failed to cancel deployment %s: %v", d.Id, err)
+		return
+	}
+	v.message = fmt.Sprintf("Requested to cancel deployment %s (command %s)", d.Id, resp.CommandId)
+}
+
+func (c *command) draw(v *view) {
+	width, height, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width, height = 0, 0
+	}
+	fmt.Fprint(os.Stdout, clearScreen)
+	v.render(os.Stdout, width, height)
+}
+
+func readKeys(ctx context.Context, r io.Reader, keyCh chan<- string) {
+	buf := make([]byte, 16)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			select {
+			case keyCh <- key:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/printer"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type action int
+
+const (
+	actionNone action = iota
+	actionQuit
+	actionRefresh
+	actionApprove
+	actionCancel
+)
+
+// view holds the state of the dashboard and renders it.
+// It does not do any I/O so that it can be tested easily.
+type view struct {
+	deployments []*model.Deployment
+	events      []*model.Event
+	selected    int
+	// The action waiting for the confirmation from the user.
+	pending   action
+	message   string
+	updatedAt time.Time
+	nowFunc   func() time.Time
+}
+
+func newView() *view {
+	return &view{
+		nowFunc: time.Now,
+	}
+}
+
+// update replaces the shown data while keeping the selected deployment if it still exists.
+func (v *view) update(deployments []*model.Deployment, events []*model.Event) {
+	var selectedID string
+	if d := v.selectedDeployment(); d != nil {
+		selectedID = d.Id
+	}
+
+	v.deployments = deployments
+	v.events = events
+	v.updatedAt = v.nowFunc()
+
+	v.selected = 0
+	for i, d := range deployments {
+		if d.Id == selectedID {
+			v.selected = i
+			break
+		}
+	}
+}
+
+func (v *view) selectedDeployment() *model.Deployment {
+	if v.selected < 0 || v.selected >= len(v.deployments) {
+		return nil
+	}
+	return v.deployments[v.selected]
+}
+
+// handleKey updates the state based on the given key
+// and returns the action that should be executed.
+func (v *view) handleKey(key string) action {
+	if v.pending != actionNone {
+		pending := v.pending
+		v.pending = actionNone
+		if key == "y" {
+			return pending
+		}
+		v.message = "Cancelled"
+		return actionNone
+	}
+
+	switch key {
+	case "q", "ctrl-c":
+		return actionQuit
+	case "r":
+		return actionRefresh
+	case "up", "k":
+		if v.selected > 0 {
+			v.selected--
+		}
+	case "down", "j":
+		if v.selected < len(v.deployments)-1 {
+			v.selected++
+		}
+	case "a":
+		d := v.selectedDeployment()
+		if d == nil {
+			return actionNone
+		}
+		stage := approvableStage(d)
+		if stage == nil {
+			v.message = fmt.Sprintf("Deployment %s has no stage waiting for approval", d.Id)
+			return actionNone
+		}
+		v.pending = actionApprove
+		v.message = fmt.Sprintf("Approve stage %s of %s deployment %s? [y/n]", stage.Name, d.ApplicationName, d.Id)
+	case "c":
+		d := v.selectedDeployment()
+		if d == nil {
+			return actionNone
+		}
+		if model.IsCompletedDeployment(d.Status) {
+			v.message = fmt.Sprintf("Deployment %s was already completed", d.Id)
+			return actionNone
+		}
+		v.pending = actionCancel
+		v.message = fmt.Sprintf("Cancel %s deployment %s? [y/n]", d.ApplicationName, d.Id)
+	}
+	return actionNone
+}
+
+// approvableStage returns the WAIT_APPROVAL stage of the given deployment
+// which is currently waiting for an approval.
+func approvableStage(d *model.Deployment) *model.PipelineStage {
+	for _, s := range d.Stages {
+		if s.Name == model.StageWaitApproval.String() && s.Status == model.StageStatus_STAGE_RUNNING {
+			return s
+		}
+	}
+	return nil
+}
+
+const helpLine = "[↑/k ↓/j] move  [a] approve  [c] cancel  [r] refresh  [q] quit"
+
+// render writes the whole screen into w.
+// Lines exceeding the given width or height are cut off.
+func (v *view) render(w io.Writer, width, height int) error {
+	var b bytes.Buffer
+	now := v.nowFunc()
+
+	fmt.Fprintf(&b, "PipeCD Dashboard  (updated %s)\n", v.updatedAt.Format("15:04:05"))
+	fmt.Fprintln(&b, helpLine)
+	fmt.Fprintln(&b)
+
+	fmt.Fprintln(&b, "DEPLOYMENTS")
+	deployments := printer.Table{
+		Columns: []printer.Column{
+			{Name: ""},
+			{Name: "STATUS"},
+			{Name: "APPLICATION"},
+			{Name: "ENV ID"},
+			{Name: "PROGRESS"},
+			{Name: "COMMIT"},
+			{Name: "UPDATED"},
+		},
+	}
+	for i, d := range v.deployments {
+		var cursor string
+		if i == v.selected {
+			cursor = ">"
+		}
+		var commit string
+		if d.Trigger != nil && d.Trigger.Commit != nil {
+			commit = shortHash(d.Trigger.Commit.Hash)
+		}
+		deployments.AddRow(
+			cursor,
+			strings.TrimPrefix(d.Status.String(), "DEPLOYMENT_"),
+			d.ApplicationName,
+			d.EnvId,
+			stageProgress(d),
+			commit,
+			age(now, d.UpdatedAt),
+		)
+	}
+	if err := deployments.Write(&b, false); err != nil {
+		return err
+	}
+
+	if d := v.selectedDeployment(); d != nil {
+		fmt.Fprintln(&b)
+		fmt.Fprintf(&b, "STAGES OF %s\n", d.Id)
+		stages := printer.Table{
+			Columns: []printer.Column{
+				{Name: "NAME"},
+				{Name: "STATUS"},
+				{Name: "DESCRIPTION"},
+			},
+		}
+		for _, s := range visibleStages(d) {
+			stages.AddRow(s.Name, strings.TrimPrefix(s.Status.String(), "STAGE_"), s.Desc)
+		}
+		if err := stages.Write(&b, false); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "RECENT EVENTS")
+	events := printer.Table{
+		Columns: []printer.Column{
+			{Name: "NAME"},
+			{Name: "DATA"},
+			{Name: "LABELS"},
+			{Name: "CREATED"},
+		},
+	}
+	for _, e := range v.events {
+		events.AddRow(e.Name, e.Data, formatLabels(e.Labels), age(now, e.CreatedAt))
+	}
+	if err := events.Write(&b, false); err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+	// Keep the last line for the message.
+	if height > 1 && len(lines) > height-1 {
+		lines = lines[:height-1]
+	}
+	lines = append(lines, v.message)
+	for i := range lines {
+		lines[i] = truncate(lines[i], width)
+	}
+	// The terminal is in raw mode, so the carriage return is required.
+	_, err := io.WriteString(w, strings.Join(lines, "\r\n"))
+	return err
+}
+
+func visibleStages(d *model.Deployment) []*model.PipelineStage {
+	stages := make([]*model.PipelineStage, 0, len(d.Stages))
+	for _, s := range d.Stages {
+		if s.Visible {
+			stages = append(stages, s)
+		}
+	}
+	sort.SliceStable(stages, func(i, j int) bool {
+		return stages[i].Index < stages[j].Index
+	})
+	return stages
+}
+
+func stageProgress(d *model.Deployment) string {
+	stages := visibleStages(d)
+	var completed int
+	for _, s := range stages {
+		if model.IsCompletedStage(s.Status) {
+			completed++
+		}
+	}
+	return fmt.Sprintf("%d/%d", completed, len(stages))
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// age returns the elapsed time since the given unix time in a short form, e.g. 5m.
+func age(now time.Time, unix int64) string {
+	if unix == 0 {
+		return ""
+	}
+	d := now.Sub(time.Unix(unix, 0))
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	return string(r[:width])
+}
+
+// parseKeys converts the bytes read from the terminal in raw mode into key names.
+func parseKeys(buf []byte) []string {
+	var keys []string
+	for i := 0; i < len(buf); i++ {
+		switch c := buf[i]; {
+		case c == 3:
+			keys = append(keys, "ctrl-c")
+		case c == 0x1b && i+2 < len(buf) && buf[i+1] == '[':
+			switch buf[i+2] {
+			case 'A':
+				keys = append(keys, "up")
+			case 'B':
+				keys = append(keys, "down")
+			}
+			i += 2
+		case c >= 0x20 && c < 0x7f:
+			keys = append(keys, string(c))
+		}
+	}
+	return keys
+}
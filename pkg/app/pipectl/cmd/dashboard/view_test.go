@@ -0,0 +1,153 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestParseKeys(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    []byte
+		expected []string
+	}{
+		{
+			name:     "letters",
+			input:    []byte("jka"),
+			expected: []string{"j", "k", "a"},
+		},
+		{
+			name:     "arrows",
+			input:    []byte("\x1b[A\x1b[B"),
+			expected: []string{"up", "down"},
+		},
+		{
+			name:     "ctrl-c",
+			input:    []byte{3},
+			expected: []string{"ctrl-c"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, parseKeys(tc.input))
+		})
+	}
+}
+
+func TestViewHandleKey(t *testing.T) {
+	v := newView()
+	v.update([]*model.Deployment{
+		{
+			Id:     "deployment-1",
+			Status: model.DeploymentStatus_DEPLOYMENT_SUCCESS,
+		},
+		{
+			Id:     "deployment-2",
+			Status: model.DeploymentStatus_DEPLOYMENT_RUNNING,
+			Stages: []*model.PipelineStage{
+				{
+					Id:     "stage-1",
+					Name:   model.StageWaitApproval.String(),
+					Status: model.StageStatus_STAGE_RUNNING,
+				},
+			},
+		},
+	}, nil)
+
+	// The selection stays in the range.
+	assert.Equal(t, actionNone, v.handleKey("up"))
+	assert.Equal(t, 0, v.selected)
+	assert.Equal(t, actionNone, v.handleKey("down"))
+	assert.Equal(t, actionNone, v.handleKey("j"))
+	assert.Equal(t, 1, v.selected)
+
+	// Actions require confirmation.
+	assert.Equal(t, actionNone, v.handleKey("a"))
+	assert.Equal(t, actionApprove, v.handleKey("y"))
+	assert.Equal(t, actionNone, v.handleKey("c"))
+	assert.Equal(t, actionNone, v.handleKey("n"))
+	assert.Equal(t, "Cancelled", v.message)
+
+	// Completed deployments can not be approved or cancelled.
+	v.handleKey("k")
+	assert.Equal(t, actionNone, v.handleKey("a"))
+	assert.Equal(t, actionNone, v.handleKey("c"))
+	assert.Equal(t, actionNone, v.handleKey("y"))
+
+	// The selected deployment is kept after updating.
+	v.handleKey("j")
+	v.update([]*model.Deployment{{Id: "deployment-3"}, {Id: "deployment-1"}, {Id: "deployment-2"}}, nil)
+	assert.Equal(t, "deployment-2", v.selectedDeployment().Id)
+
+	assert.Equal(t, actionRefresh, v.handleKey("r"))
+	assert.Equal(t, actionQuit, v.handleKey("q"))
+}
+
+func TestViewRender(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	v := newView()
+	v.nowFunc = func() time.Time { return now }
+	v.update([]*model.Deployment{
+		{
+			Id:              "deployment-1",
+			ApplicationName: "demo",
+			EnvId:           "dev",
+			Status:          model.DeploymentStatus_DEPLOYMENT_RUNNING,
+			Trigger: &model.DeploymentTrigger{
+				Commit: &model.Commit{Hash: "0123456789abcdef"},
+			},
+			Stages: []*model.PipelineStage{
+				{Name: "K8S_CANARY_ROLLOUT", Index: 0, Visible: true, Status: model.StageStatus_STAGE_SUCCESS},
+				{Name: "WAIT_APPROVAL", Index: 1, Visible: true, Status: model.StageStatus_STAGE_RUNNING},
+				{Name: "K8S_CANARY_CLEAN", Index: 2, Visible: false},
+			},
+			UpdatedAt: now.Add(-3 * time.Minute).Unix(),
+		},
+	}, []*model.Event{
+		{
+			Name:      "image-update",
+			Data:      "v1.0.0",
+			Labels:    map[string]string{"app": "demo"},
+			CreatedAt: now.Add(-2 * time.Hour).Unix(),
+		},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, v.render(&buf, 0, 0))
+	out := buf.String()
+
+	assert.Contains(t, out, ">  RUNNING  demo         dev     1/2       0123456  3m")
+	assert.Contains(t, out, "WAIT_APPROVAL       RUNNING")
+	assert.NotContains(t, out, "K8S_CANARY_CLEAN")
+	assert.Contains(t, out, "image-update  v1.0.0  app=demo  2h")
+
+	// The output is cut off by the given size.
+	buf.Reset()
+	require.NoError(t, v.render(&buf, 10, 3))
+	lines := strings.Split(buf.String(), "\r\n")
+	assert.Len(t, lines, 3)
+	for _, l := range lines {
+		assert.LessOrEqual(t, len([]rune(l)), 10)
+	}
+}
@@ -18,24 +18,35 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/pipe-cd/pipe/pkg/app/pipectl/client"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/printer"
 )
 
 type command struct {
 	clientOptions *client.Options
+	printOptions  *printer.Options
 }
 
 func NewCommand() *cobra.Command {
 	c := &command{
 		clientOptions: &client.Options{},
+		printOptions:  &printer.Options{},
 	}
 	cmd := &cobra.Command{
 		Use:   "deployment",
 		Short: "Manage deployment resources.",
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			return c.printOptions.Validate()
+		},
 	}
 
 	cmd.AddCommand(newWaitStatusCommand(c))
+	cmd.AddCommand(newDiffCommand(c))
+	cmd.AddCommand(newProvenanceCommand(c))
+	cmd.AddCommand(newRollbackCommand(c))
+	cmd.AddCommand(newExportCommand(c))
 
 	c.clientOptions.RegisterPersistentFlags(cmd)
+	c.printOptions.RegisterPersistentFlags(cmd)
 
 	return cmd
 }
@@ -0,0 +1,87 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/cli"
+)
+
+type diff struct {
+	root *command
+
+	deploymentID string
+	stdout       io.Writer
+}
+
+func newDiffCommand(root *command) *cobra.Command {
+	c := &diff{
+		root:   root,
+		stdout: os.Stdout,
+	}
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show the manifest diff computed while planning the specified deployment.",
+		RunE:  cli.WithContext(c.run),
+	}
+
+	cmd.Flags().StringVar(&c.deploymentID, "deployment-id", c.deploymentID, "The deployment ID.")
+	cmd.MarkFlagRequired("deployment-id")
+
+	return cmd
+}
+
+func (c *diff) run(ctx context.Context, _ cli.Telemetry) error {
+	cli, err := c.root.clientOptions.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize client: %w", err)
+	}
+	defer cli.Close()
+
+	req := &apiservice.GetDeploymentManifestDiffRequest{
+		DeploymentId: c.deploymentID,
+	}
+
+	resp, err := cli.GetDeploymentManifestDiff(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to get manifest diff: %w", err)
+	}
+
+	result := diffResult{
+		DeploymentID: c.deploymentID,
+		Diff:         resp.Diff,
+	}
+	return c.root.printOptions.Print(c.stdout, result, func(w io.Writer, _ bool) error {
+		if resp.Diff == "" {
+			fmt.Fprintln(w, "No manifest diff was recorded for this deployment")
+			return nil
+		}
+		fmt.Fprint(w, resp.Diff)
+		return nil
+	})
+}
+
+type diffResult struct {
+	DeploymentID string `json:"deployment_id"`
+	// Empty means no manifest diff was recorded for the deployment.
+	Diff string `json:"diff"`
+}
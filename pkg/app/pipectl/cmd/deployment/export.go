@@ -0,0 +1,285 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/cli"
+)
+
+const (
+	exportFormatCSV  = "csv"
+	exportFormatJSON = "json"
+)
+
+type export struct {
+	root *command
+
+	appID  string
+	since  string
+	until  string
+	format string
+	stdout io.Writer
+	now    func() time.Time
+}
+
+func newExportCommand(root *command) *cobra.Command {
+	c := &export{
+		root:   root,
+		since:  "720h",
+		format: exportFormatCSV,
+		stdout: os.Stdout,
+		now:    time.Now,
+	}
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the deployment history of an application.",
+		RunE:  cli.WithContext(c.run),
+	}
+
+	cmd.Flags().StringVar(&c.appID, "app-id", c.appID, "The application ID.")
+	cmd.Flags().StringVar(&c.since, "since", c.since, "Export the deployments created since this time. Either a relative duration such as 720h, a date such as 2021-06-01 or a RFC3339 timestamp.")
+	cmd.Flags().StringVar(&c.until, "until", c.until, "Export the deployments created before this time. Same formats as --since are accepted. Empty means now.")
+	cmd.Flags().StringVar(&c.format, "format", c.format, "The format of exported records. One of: csv|json.")
+
+	cmd.MarkFlagRequired("app-id")
+
+	return cmd
+}
+
+func (c *export) run(ctx context.Context, _ cli.Telemetry) error {
+	now := c.now()
+	since, err := parseExportTime(c.since, now)
+	if err != nil {
+		return fmt.Errorf("invalid value for --since: %w", err)
+	}
+	var until time.Time
+	if c.until != "" {
+		if until, err = parseExportTime(c.until, now); err != nil {
+			return fmt.Errorf("invalid value for --until: %w", err)
+		}
+	}
+
+	w, err := newHistoryWriter(c.format, c.stdout)
+	if err != nil {
+		return err
+	}
+
+	cli, err := c.root.clientOptions.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize client: %w", err)
+	}
+	defer cli.Close()
+
+	req := &apiservice.ExportDeploymentHistoryRequest{
+		ApplicationId: c.appID,
+		Since:         since.Unix(),
+	}
+	if !until.IsZero() {
+		req.Until = until.Unix()
+	}
+
+	// Records are written as soon as each page is received
+	// to keep the memory usage low even for a long history.
+	for {
+		resp, err := cli.ExportDeploymentHistory(ctx, req)
+		if err != nil {
+			return fmt.Errorf("failed to export deployment history: %w", err)
+		}
+		for _, r := range resp.Records {
+			if err := w.write(makeHistoryRecord(r)); err != nil {
+				return err
+			}
+		}
+		if resp.Cursor == "" {
+			break
+		}
+		req.Cursor = resp.Cursor
+	}
+
+	return w.flush()
+}
+
+// parseExportTime parses the given value as a RFC3339 timestamp, a date
+// or a duration which is relative to the given current time.
+func parseExportTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a timestamp, a date nor a duration", value)
+	}
+	return now.Add(-d), nil
+}
+
+type historyRecord struct {
+	DeploymentID    string   `json:"deployment_id"`
+	ApplicationID   string   `json:"application_id"`
+	ApplicationName string   `json:"application_name"`
+	EnvID           string   `json:"env_id"`
+	Kind            string   `json:"kind"`
+	Status          string   `json:"status"`
+	StatusReason    string   `json:"status_reason"`
+	Version         string   `json:"version"`
+	Summary         string   `json:"summary"`
+	CommitHash      string   `json:"commit_hash"`
+	CommitMessage   string   `json:"commit_message"`
+	CommitAuthor    string   `json:"commit_author"`
+	Commander       string   `json:"commander"`
+	Approvers       []string `json:"approvers"`
+	CreatedAt       string   `json:"created_at"`
+	CompletedAt     string   `json:"completed_at"`
+	DurationSeconds int64    `json:"duration_seconds"`
+}
+
+var historyRecordCSVHeader = []string{
+	"deployment_id",
+	"application_id",
+	"application_name",
+	"env_id",
+	"kind",
+	"status",
+	"status_reason",
+	"version",
+	"summary",
+	"commit_hash",
+	"commit_message",
+	"commit_author",
+	"commander",
+	"approvers",
+	"created_at",
+	"completed_at",
+	"duration_seconds",
+}
+
+func makeHistoryRecord(r *apiservice.DeploymentHistoryRecord) historyRecord {
+	formatTime := func(t int64) string {
+		if t == 0 {
+			return ""
+		}
+		return time.Unix(t, 0).UTC().Format(time.RFC3339)
+	}
+	approvers := r.Approvers
+	if approvers == nil {
+		approvers = []string{}
+	}
+	return historyRecord{
+		DeploymentID:    r.DeploymentId,
+		ApplicationID:   r.ApplicationId,
+		ApplicationName: r.ApplicationName,
+		EnvID:           r.EnvId,
+		Kind:            r.Kind.String(),
+		Status:          r.Status.String(),
+		StatusReason:    r.StatusReason,
+		Version:         r.Version,
+		Summary:         r.Summary,
+		CommitHash:      r.CommitHash,
+		CommitMessage:   r.CommitMessage,
+		CommitAuthor:    r.CommitAuthor,
+		Commander:       r.Commander,
+		Approvers:       approvers,
+		CreatedAt:       formatTime(r.CreatedAt),
+		CompletedAt:     formatTime(r.CompletedAt),
+		DurationSeconds: r.Duration,
+	}
+}
+
+func (r historyRecord) csvRow() []string {
+	return []string{
+		r.DeploymentID,
+		r.ApplicationID,
+		r.ApplicationName,
+		r.EnvID,
+		r.Kind,
+		r.Status,
+		r.StatusReason,
+		r.Version,
+		r.Summary,
+		r.CommitHash,
+		r.CommitMessage,
+		r.CommitAuthor,
+		r.Commander,
+		strings.Join(r.Approvers, ";"),
+		r.CreatedAt,
+		r.CompletedAt,
+		strconv.FormatInt(r.DurationSeconds, 10),
+	}
+}
+
+// historyWriter writes the exported records one by one.
+type historyWriter struct {
+	write func(r historyRecord) error
+	flush func() error
+}
+
+func newHistoryWriter(format string, w io.Writer) (*historyWriter, error) {
+	switch format {
+	case exportFormatCSV:
+		cw := csv.NewWriter(w)
+		wroteHeader := false
+		return &historyWriter{
+			write: func(r historyRecord) error {
+				if !wroteHeader {
+					if err := cw.Write(historyRecordCSVHeader); err != nil {
+						return err
+					}
+					wroteHeader = true
+				}
+				return cw.Write(r.csvRow())
+			},
+			flush: func() error {
+				if !wroteHeader {
+					if err := cw.Write(historyRecordCSVHeader); err != nil {
+						return err
+					}
+				}
+				cw.Flush()
+				return cw.Error()
+			},
+		}, nil
+
+	case exportFormatJSON:
+		// Each record is written as a JSON object in its own line
+		// so that the output can be processed in a streaming way.
+		enc := json.NewEncoder(w)
+		return &historyWriter{
+			write: func(r historyRecord) error {
+				return enc.Encode(r)
+			},
+			flush: func() error {
+				return nil
+			},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported export format %q, must be one of csv|json", format)
+	}
+}
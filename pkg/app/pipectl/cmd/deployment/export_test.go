@@ -0,0 +1,126 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestParseExportTime(t *testing.T) {
+	now := time.Date(2021, 6, 15, 10, 0, 0, 0, time.UTC)
+	testcases := []struct {
+		name        string
+		value       string
+		expected    time.Time
+		expectedErr bool
+	}{
+		{
+			name:     "duration",
+			value:    "48h",
+			expected: time.Date(2021, 6, 13, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "date",
+			value:    "2021-06-01",
+			expected: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "timestamp",
+			value:    "2021-06-01T12:30:00Z",
+			expected: time.Date(2021, 6, 1, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name:        "invalid value",
+			value:       "last month",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseExportTime(tc.value, now)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.True(t, tc.expected.Equal(got), "expected %s but got %s", tc.expected, got)
+		})
+	}
+}
+
+func TestHistoryWriter(t *testing.T) {
+	record := makeHistoryRecord(&apiservice.DeploymentHistoryRecord{
+		DeploymentId:    "deployment-1",
+		ApplicationId:   "app-1",
+		ApplicationName: "app",
+		EnvId:           "env-1",
+		Kind:            model.ApplicationKind_KUBERNETES,
+		Status:          model.DeploymentStatus_DEPLOYMENT_SUCCESS,
+		CommitHash:      "hash",
+		CommitMessage:   "Update replicas, again",
+		CommitAuthor:    "author",
+		Approvers:       []string{"user-1", "user-2"},
+		CreatedAt:       1622548800,
+		CompletedAt:     1622548860,
+		Duration:        60,
+	})
+
+	testcases := []struct {
+		name     string
+		format   string
+		records  []historyRecord
+		expected string
+	}{
+		{
+			name:     "csv without records",
+			format:   exportFormatCSV,
+			expected: "deployment_id,application_id,application_name,env_id,kind,status,status_reason,version,summary,commit_hash,commit_message,commit_author,commander,approvers,created_at,completed_at,duration_seconds\n",
+		},
+		{
+			name:    "csv",
+			format:  exportFormatCSV,
+			records: []historyRecord{record},
+			expected: "deployment_id,application_id,application_name,env_id,kind,status,status_reason,version,summary,commit_hash,commit_message,commit_author,commander,approvers,created_at,completed_at,duration_seconds\n" +
+				"deployment-1,app-1,app,env-1,KUBERNETES,DEPLOYMENT_SUCCESS,,,,hash,\"Update replicas, again\",author,,user-1;user-2,2021-06-01T12:00:00Z,2021-06-01T12:01:00Z,60\n",
+		},
+		{
+			name:     "json",
+			format:   exportFormatJSON,
+			records:  []historyRecord{record},
+			expected: `{"deployment_id":"deployment-1","application_id":"app-1","application_name":"app","env_id":"env-1","kind":"KUBERNETES","status":"DEPLOYMENT_SUCCESS","status_reason":"","version":"","summary":"","commit_hash":"hash","commit_message":"Update replicas, again","commit_author":"author","commander":"","approvers":["user-1","user-2"],"created_at":"2021-06-01T12:00:00Z","completed_at":"2021-06-01T12:01:00Z","duration_seconds":60}` + "\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := newHistoryWriter(tc.format, &buf)
+			require.NoError(t, err)
+			for _, r := range tc.records {
+				require.NoError(t, w.write(r))
+			}
+			require.NoError(t, w.flush())
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+
+	_, err := newHistoryWriter("xml", &bytes.Buffer{})
+	assert.Error(t, err)
+}
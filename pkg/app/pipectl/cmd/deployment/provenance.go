@@ -0,0 +1,115 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/printer"
+	"github.com/pipe-cd/pipe/pkg/cli"
+)
+
+type provenance struct {
+	root *command
+
+	deploymentID string
+	stdout       io.Writer
+}
+
+func newProvenanceCommand(root *command) *cobra.Command {
+	c := &provenance{
+		root:   root,
+		stdout: os.Stdout,
+	}
+	cmd := &cobra.Command{
+		Use:   "provenance",
+		Short: "Show the provenance recorded while applying the specified deployment.",
+		RunE:  cli.WithContext(c.run),
+	}
+
+	cmd.Flags().StringVar(&c.deploymentID, "deployment-id", c.deploymentID, "The deployment ID.")
+	cmd.MarkFlagRequired("deployment-id")
+
+	return cmd
+}
+
+func (c *provenance) run(ctx context.Context, _ cli.Telemetry) error {
+	cli, err := c.root.clientOptions.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize client: %w", err)
+	}
+	defer cli.Close()
+
+	req := &apiservice.GetDeploymentProvenanceRequest{
+		DeploymentId: c.deploymentID,
+	}
+
+	resp, err := cli.GetDeploymentProvenance(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to get deployment provenance: %w", err)
+	}
+
+	return c.root.printOptions.Print(c.stdout, resp.Provenance, func(w io.Writer, wide bool) error {
+		p := resp.Provenance
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintf(tw, "Deployment ID:\t%s\n", p.DeploymentId)
+		fmt.Fprintf(tw, "Application ID:\t%s\n", p.ApplicationId)
+		fmt.Fprintf(tw, "Kind:\t%s\n", p.Kind)
+		fmt.Fprintf(tw, "Piped ID:\t%s\n", p.PipedId)
+		fmt.Fprintf(tw, "Piped Version:\t%s\n", p.PipedVersion)
+		fmt.Fprintf(tw, "Repository:\t%s\n", p.RepositoryRemote)
+		fmt.Fprintf(tw, "Commit:\t%s\n", p.CommitHash)
+		fmt.Fprintf(tw, "Stage:\t%s\n", p.StageId)
+		fmt.Fprintf(tw, "Manifests Digest:\t%s\n", p.ManifestsDigest)
+		fmt.Fprintf(tw, "Recorded At:\t%s\n", time.Unix(p.CreatedAt, 0).Format(time.RFC3339))
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		images := printer.Table{
+			Columns: []printer.Column{
+				{Name: "IMAGE"},
+				{Name: "DIGEST"},
+			},
+		}
+		for _, img := range p.Images {
+			images.AddRow(img.Reference, img.Digest)
+		}
+		fmt.Fprintln(w)
+		if err := images.Write(w, wide); err != nil {
+			return err
+		}
+
+		tools := printer.Table{
+			Columns: []printer.Column{
+				{Name: "TOOL"},
+				{Name: "VERSION"},
+			},
+		}
+		for _, tool := range p.Tools {
+			tools.AddRow(tool.Name, tool.Version)
+		}
+		fmt.Fprintln(w)
+		return tools.Write(w, wide)
+	})
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/client"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/printer"
+	"github.com/pipe-cd/pipe/pkg/cli"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type rollback struct {
+	root *command
+
+	appID         string
+	toCommit      string
+	statuses      []string
+	checkInterval time.Duration
+	timeout       time.Duration
+	stdout        io.Writer
+}
+
+func newRollbackCommand(root *command) *cobra.Command {
+	c := &rollback{
+		root:          root,
+		checkInterval: 15 * time.Second,
+		timeout:       5 * time.Minute,
+		stdout:        os.Stdout,
+	}
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Roll back an application to the commit of its previous successful deployment or to a specified commit.",
+		RunE:  cli.WithContext(c.run),
+	}
+
+	cmd.Flags().StringVar(&c.appID, "app-id", c.appID, "The application ID.")
+	cmd.Flags().StringVar(&c.toCommit, "to-commit", c.toCommit, "The commit to roll back to. Empty means the commit of the previous successful deployment.")
+	cmd.Flags().StringSliceVar(&c.statuses, "wait-status", c.statuses, fmt.Sprintf("The list of waiting statuses. Empty means returning immediately after triggered. (%s)", strings.Join(model.DeploymentStatusStrings(), "|")))
+	cmd.Flags().DurationVar(&c.checkInterval, "check-interval", c.checkInterval, "The interval of checking the requested command.")
+	cmd.Flags().DurationVar(&c.timeout, "timeout", c.timeout, "Maximum execution time.")
+
+	cmd.MarkFlagRequired("app-id")
+
+	return cmd
+}
+
+func (c *rollback) run(ctx context.Context, t cli.Telemetry) error {
+	statuses, err := model.DeploymentStatusesFromStrings(c.statuses)
+	if err != nil {
+		return fmt.Errorf("invalid deployment status: %w", err)
+	}
+
+	cli, err := c.root.clientOptions.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize client: %w", err)
+	}
+	defer cli.Close()
+
+	req := &apiservice.RollbackApplicationRequest{
+		ApplicationId: c.appID,
+		TargetCommit:  c.toCommit,
+	}
+	resp, err := cli.RollbackApplication(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to roll back application: %w", err)
+	}
+
+	t.Logger.Info(fmt.Sprintf("Sent a request to roll back application to commit %s and waiting to be accepted...", resp.TargetCommit))
+	deploymentID, err := client.WaitTriggeredDeployment(ctx, cli, resp.CommandId, c.checkInterval, c.timeout, t.Logger)
+	if err != nil {
+		return err
+	}
+	t.Logger.Info(fmt.Sprintf("Successfully triggered deployment %s", deploymentID))
+
+	result := rollbackResult{
+		ApplicationID: c.appID,
+		DeploymentID:  deploymentID,
+		TargetCommit:  resp.TargetCommit,
+	}
+	err = c.root.printOptions.Print(c.stdout, result, func(w io.Writer, wide bool) error {
+		table := printer.Table{
+			Columns: []printer.Column{
+				{Name: "APPLICATION ID"},
+				{Name: "DEPLOYMENT ID"},
+				{Name: "TARGET COMMIT"},
+			},
+		}
+		table.AddRow(result.ApplicationID, result.DeploymentID, result.TargetCommit)
+		return table.Write(w, wide)
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	t.Logger.Info("Waiting until the deployment reaches one of the specified statuses")
+
+	return client.WaitDeploymentStatuses(
+		ctx,
+		cli,
+		deploymentID,
+		statuses,
+		c.checkInterval,
+		c.timeout,
+		t.Logger,
+	)
+}
+
+type rollbackResult struct {
+	ApplicationID string `json:"application_id"`
+	DeploymentID  string `json:"deployment_id"`
+	TargetCommit  string `json:"target_commit"`
+}
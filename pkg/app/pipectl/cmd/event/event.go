@@ -18,19 +18,25 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/pipe-cd/pipe/pkg/app/pipectl/client"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/printer"
 )
 
 type command struct {
 	clientOptions *client.Options
+	printOptions  *printer.Options
 }
 
 func NewCommand() *cobra.Command {
 	c := &command{
 		clientOptions: &client.Options{},
+		printOptions:  &printer.Options{},
 	}
 	cmd := &cobra.Command{
 		Use:   "event",
 		Short: "Manage event resources.",
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			return c.printOptions.Validate()
+		},
 	}
 
 	cmd.AddCommand(
@@ -38,6 +44,7 @@ func NewCommand() *cobra.Command {
 	)
 
 	c.clientOptions.RegisterPersistentFlags(cmd)
+	c.printOptions.RegisterPersistentFlags(cmd)
 
 	return cmd
 }
@@ -17,19 +17,26 @@ package event
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/printer"
 	"github.com/pipe-cd/pipe/pkg/cli"
 )
 
 type register struct {
 	root *command
 
-	name   string
-	data   string
-	labels map[string]string
+	name           string
+	data           string
+	labels         map[string]string
+	idempotencyKey string
 }
 
 func newRegisterCommand(root *command) *cobra.Command {
@@ -45,6 +52,7 @@ func newRegisterCommand(root *command) *cobra.Command {
 	cmd.Flags().StringVar(&r.name, "name", r.name, "The name of event.")
 	cmd.Flags().StringVar(&r.data, "data", r.data, "The string value of event data.")
 	cmd.Flags().StringToStringVar(&r.labels, "labels", r.labels, "The list of labels for event. Format: key=value,key2=value2")
+	cmd.Flags().StringVar(&r.idempotencyKey, "idempotency-key", r.idempotencyKey, "The key to avoid registering the same event twice when retrying, e.g. the ID of the CI job.")
 
 	cmd.MarkFlagRequired("name")
 	cmd.MarkFlagRequired("data")
@@ -60,15 +68,38 @@ func (r *register) run(ctx context.Context, t cli.Telemetry) error {
 	defer cli.Close()
 
 	req := &apiservice.RegisterEventRequest{
-		Name:   r.name,
-		Data:   r.data,
-		Labels: r.labels,
+		Name:           r.name,
+		Data:           r.data,
+		Labels:         r.labels,
+		IdempotencyKey: r.idempotencyKey,
 	}
 
-	if _, err := cli.RegisterEvent(ctx, req); err != nil {
+	resp, err := cli.RegisterEvent(ctx, req)
+	if err != nil {
 		return fmt.Errorf("failed to register event: %w", err)
 	}
 
-	t.Logger.Info("Successfully registered event")
-	return nil
+	if resp.Deduplicated {
+		t.Logger.Info(fmt.Sprintf("Skipped registering event since event %s was already registered with the same idempotency key", resp.EventId))
+	} else {
+		t.Logger.Info("Successfully registered event")
+	}
+	return r.root.printOptions.Print(os.Stdout, resp, func(w io.Writer, wide bool) error {
+		table := printer.Table{
+			Columns: []printer.Column{
+				{Name: "ID"},
+				{Name: "NAME"},
+				{Name: "DATA"},
+				{Name: "DEDUPLICATED"},
+				{Name: "LABELS", Wide: true},
+			},
+		}
+		labels := make([]string, 0, len(req.Labels))
+		for k, v := range req.Labels {
+			labels = append(labels, k+"="+v)
+		}
+		sort.Strings(labels)
+		table.AddRow(resp.EventId, req.Name, req.Data, strconv.FormatBool(resp.Deduplicated), strings.Join(labels, ","))
+		return table.Write(w, wide)
+	})
 }
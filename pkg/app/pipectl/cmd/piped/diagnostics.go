@@ -0,0 +1,95 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package piped
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/printer"
+	"github.com/pipe-cd/pipe/pkg/cli"
+)
+
+type diagnostics struct {
+	root *command
+
+	pipedID string
+	stdout  io.Writer
+}
+
+func newDiagnosticsCommand(root *command) *cobra.Command {
+	c := &diagnostics{
+		root:   root,
+		stdout: os.Stdout,
+	}
+	cmd := &cobra.Command{
+		Use:   "diagnostics",
+		Short: "Show the latest self-diagnostics result reported by a given Piped.",
+		RunE:  cli.WithContext(c.run),
+	}
+
+	cmd.Flags().StringVar(&c.pipedID, "piped-id", c.pipedID, "The Piped ID.")
+	cmd.MarkFlagRequired("piped-id")
+
+	return cmd
+}
+
+func (c *diagnostics) run(ctx context.Context, _ cli.Telemetry) error {
+	cli, err := c.root.clientOptions.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize client: %w", err)
+	}
+	defer cli.Close()
+
+	req := &apiservice.GetPipedDiagnosticsRequest{
+		PipedId: c.pipedID,
+	}
+	resp, err := cli.GetPipedDiagnostics(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to get diagnostics: %w", err)
+	}
+
+	return c.root.printOptions.Print(c.stdout, resp, func(w io.Writer, wide bool) error {
+		diag := resp.Diagnostics
+		if diag == nil {
+			fmt.Fprintf(w, "Piped %s has not reported any diagnostics yet\n", c.pipedID)
+			return nil
+		}
+
+		fmt.Fprintf(w, "Reported at %s\n\n", time.Unix(diag.CreatedAt, 0).Format(time.RFC3339))
+		t := printer.Table{
+			Columns: []printer.Column{
+				{Name: "RESULT"},
+				{Name: "CATEGORY"},
+				{Name: "NAME"},
+				{Name: "MESSAGE"},
+			},
+		}
+		for _, check := range diag.Checks {
+			result := "PASS"
+			if !check.Passed {
+				result = "FAIL"
+			}
+			t.AddRow(result, check.Category, check.Name, check.Message)
+		}
+		return t.Write(w, wide)
+	})
+}
@@ -65,6 +65,12 @@ func (c *enable) run(ctx context.Context, t cli.Telemetry) error {
 		return err
 	}
 
-	fmt.Fprintf(c.stdout, "Successfully enabled Piped %s\n", c.pipedID)
-	return nil
+	result := pipedResult{
+		PipedID:  c.pipedID,
+		Disabled: false,
+	}
+	return c.root.printOptions.Print(c.stdout, result, func(w io.Writer, _ bool) error {
+		fmt.Fprintf(w, "Successfully enabled Piped %s\n", c.pipedID)
+		return nil
+	})
 }
@@ -18,27 +18,41 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/pipe-cd/pipe/pkg/app/pipectl/client"
+	"github.com/pipe-cd/pipe/pkg/app/pipectl/printer"
 )
 
 type command struct {
 	clientOptions *client.Options
+	printOptions  *printer.Options
 }
 
 func NewCommand() *cobra.Command {
 	c := &command{
 		clientOptions: &client.Options{},
+		printOptions:  &printer.Options{},
 	}
 	cmd := &cobra.Command{
 		Use:   "piped",
 		Short: "Manage piped resources.",
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			return c.printOptions.Validate()
+		},
 	}
 
 	cmd.AddCommand(
 		newEnableCommand(c),
 		newDisableCommand(c),
+		newDiagnosticsCommand(c),
 	)
 
 	c.clientOptions.RegisterPersistentFlags(cmd)
+	c.printOptions.RegisterPersistentFlags(cmd)
 
 	return cmd
 }
+
+// pipedResult is the output of the enable and disable commands.
+type pipedResult struct {
+	PipedID  string `json:"piped_id"`
+	Disabled bool   `json:"disabled"`
+}
@@ -0,0 +1,149 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package printer provides a standardized way for pipectl commands
+// to output their results in a human-readable table or a machine-readable format.
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+type Format string
+
+const (
+	// FormatDefault uses the default format of the command,
+	// which is the table unless the command specifies another one.
+	FormatDefault Format = ""
+	// FormatTable prints the result in a human-readable table.
+	FormatTable Format = "table"
+	// FormatWide prints the result in a human-readable table with additional columns.
+	FormatWide Format = "wide"
+	// FormatJSON prints the result in JSON.
+	FormatJSON Format = "json"
+	// FormatYAML prints the result in YAML.
+	FormatYAML Format = "yaml"
+)
+
+type Options struct {
+	Output string
+}
+
+func (o *Options) RegisterPersistentFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&o.Output, "output", "o", o.Output, "Output format. One of: table|wide|json|yaml. Default is a human-readable table unless the command says otherwise.")
+}
+
+func (o *Options) Validate() error {
+	switch Format(o.Output) {
+	case FormatDefault, FormatTable, FormatWide, FormatJSON, FormatYAML:
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q, must be one of table|wide|json|yaml", o.Output)
+	}
+}
+
+// HumanPrinter writes the result in a human-readable form.
+// Wide is true when the additional information was requested.
+type HumanPrinter func(w io.Writer, wide bool) error
+
+// WithDefault returns the options which use the given format
+// when no output format was specified.
+func (o *Options) WithDefault(format Format) *Options {
+	if Format(o.Output) != FormatDefault {
+		return o
+	}
+	return &Options{Output: string(format)}
+}
+
+// Print writes the given value to w in the requested output format.
+// The value is encoded as-is for the JSON and YAML formats,
+// so its JSON field names are the stable schema consumed by scripts.
+func (o *Options) Print(w io.Writer, v interface{}, human HumanPrinter) error {
+	switch Format(o.Output) {
+	case FormatJSON:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal output to JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+
+	case FormatYAML:
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to marshal output to YAML: %w", err)
+		}
+		_, err = w.Write(data)
+		return err
+
+	case FormatDefault, FormatTable, FormatWide:
+		return human(w, Format(o.Output) == FormatWide)
+
+	default:
+		return fmt.Errorf("unsupported output format %q", o.Output)
+	}
+}
+
+type Column struct {
+	Name string
+	// Wide columns are shown only in the wide output format.
+	Wide bool
+}
+
+type Table struct {
+	Columns []Column
+	// Each row must contain one cell for each column, including the wide ones.
+	Rows [][]string
+}
+
+func (t *Table) AddRow(cells ...string) {
+	t.Rows = append(t.Rows, cells)
+}
+
+// Write writes the table to w with aligned columns.
+func (t *Table) Write(w io.Writer, wide bool) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	var headers []string
+	for _, c := range t.Columns {
+		if c.Wide && !wide {
+			continue
+		}
+		headers = append(headers, c.Name)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, row := range t.Rows {
+		cells := make([]string, 0, len(headers))
+		for i, c := range t.Columns {
+			if c.Wide && !wide {
+				continue
+			}
+			var cell string
+			if i < len(row) {
+				cell = row[i]
+			}
+			cells = append(cells, cell)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+func TestPrint(t *testing.T) {
+	table := Table{
+		Columns: []Column{
+			{Name: "NAME"},
+			{Name: "STATUS"},
+			{Name: "DETAIL", Wide: true},
+		},
+	}
+	table.AddRow("app-1", "SUCCESS", "deployed by foo")
+	table.AddRow("application-2", "FAILURE", "")
+
+	testcases := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{
+			name:   "default",
+			output: "",
+			expected: "NAME           STATUS\n" +
+				"app-1          SUCCESS\n" +
+				"application-2  FAILURE\n",
+		},
+		{
+			name:   "table",
+			output: "table",
+			expected: "NAME           STATUS\n" +
+				"app-1          SUCCESS\n" +
+				"application-2  FAILURE\n",
+		},
+		{
+			name:   "wide",
+			output: "wide",
+			expected: "NAME           STATUS   DETAIL\n" +
+				"app-1          SUCCESS  deployed by foo\n" +
+				"application-2  FAILURE  \n",
+		},
+		{
+			name:     "json",
+			output:   "json",
+			expected: "{\n  \"name\": \"app-1\",\n  \"status\": \"SUCCESS\"\n}\n",
+		},
+		{
+			name:     "yaml",
+			output:   "yaml",
+			expected: "name: app-1\nstatus: SUCCESS\n",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &Options{Output: tc.output}
+			require.NoError(t, o.Validate())
+
+			var buf bytes.Buffer
+			err := o.Print(&buf, testResult{Name: "app-1", Status: "SUCCESS"}, func(w io.Writer, wide bool) error {
+				return table.Write(w, wide)
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+func TestWithDefault(t *testing.T) {
+	assert.Equal(t, "json", (&Options{}).WithDefault(FormatJSON).Output)
+	assert.Equal(t, "table", (&Options{Output: "table"}).WithDefault(FormatJSON).Output)
+}
+
+func TestValidate(t *testing.T) {
+	assert.Error(t, (&Options{Output: "xml"}).Validate())
+}
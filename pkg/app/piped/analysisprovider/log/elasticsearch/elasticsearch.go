@@ -0,0 +1,269 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/log"
+)
+
+const (
+	ProviderType          = "Elasticsearch"
+	defaultTimeout        = 30 * time.Second
+	defaultTimestampField = "@timestamp"
+)
+
+// Provider works as an HTTP client for the count API of Elasticsearch or OpenSearch.
+type Provider struct {
+	client         *http.Client
+	address        string
+	index          string
+	timestampField string
+	username       string
+	password       string
+	apiKey         string
+
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+func NewProvider(address string, opts ...Option) (*Provider, error) {
+	if address == "" {
+		return nil, fmt.Errorf("address is required")
+	}
+
+	p := &Provider{
+		client:         &http.Client{},
+		address:        strings.TrimRight(address, "/"),
+		timestampField: defaultTimestampField,
+		timeout:        defaultTimeout,
+		logger:         zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+type Option func(*Provider)
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Provider) {
+		if timeout > 0 {
+			p.timeout = timeout
+		}
+	}
+}
+
+func WithLogger(logger *zap.Logger) Option {
+	return func(p *Provider) {
+		p.logger = logger.Named("elasticsearch-provider")
+	}
+}
+
+// WithIndex sets the index or the index pattern to search, e.g. "logs-*".
+// All indices are searched if not specified.
+func WithIndex(index string) Option {
+	return func(p *Provider) {
+		p.index = index
+	}
+}
+
+// WithTimestampField sets the field used to filter the documents within the analysis window.
+func WithTimestampField(field string) Option {
+	return func(p *Provider) {
+		if field != "" {
+			p.timestampField = field
+		}
+	}
+}
+
+func WithBasicAuth(username, password string) Option {
+	return func(p *Provider) {
+		p.username = username
+		p.password = password
+	}
+}
+
+// WithAPIKey sets the base64 encoded API key sent in the Authorization header.
+func WithAPIKey(apiKey string) Option {
+	return func(p *Provider) {
+		p.apiKey = apiKey
+	}
+}
+
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Evaluate counts the documents matching the given query DSL within the query range
+// and checks if the count does not exceed the threshold.
+// For the count API, see: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-count.html
+func (p *Provider) Evaluate(ctx context.Context, query string, queryRange log.QueryRange, threshold int) (bool, string, error) {
+	body, err := p.buildRequestBody(query, queryRange)
+	if err != nil {
+		return false, "", err
+	}
+
+	p.logger.Info("run query", zap.String("query", query))
+	status, respBody, err := p.post(ctx, "/_count", body)
+	if err != nil {
+		return false, "", err
+	}
+	if status != http.StatusOK {
+		return false, "", fmt.Errorf("unexpected HTTP status code from %s: %d, %s", p.address, status, string(respBody))
+	}
+
+	var out countResponse
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return false, "", fmt.Errorf("failed to unmarshal the response: %w", err)
+	}
+	return evaluate(out.Count, threshold)
+}
+
+type countResponse struct {
+	Count int `json:"count"`
+}
+
+// ValidateQuery sends the given query DSL to the validate API
+// to check whether it is a valid query without counting any document.
+// For the validate API, see: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-validate.html
+func (p *Provider) ValidateQuery(ctx context.Context, query string) error {
+	var q json.RawMessage
+	if err := json.Unmarshal([]byte(query), &q); err != nil {
+		return fmt.Errorf("%w: the query must be a valid JSON of query DSL: %v", log.ErrInvalidQuery, err)
+	}
+	body, err := json.Marshal(map[string]interface{}{"query": q})
+	if err != nil {
+		return err
+	}
+
+	status, respBody, err := p.post(ctx, "/_validate/query?explain=true", body)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case http.StatusOK:
+	case http.StatusBadRequest:
+		return fmt.Errorf("%w: %s", log.ErrInvalidQuery, string(respBody))
+	default:
+		return fmt.Errorf("unexpected HTTP status code from %s: %d, %s", p.address, status, string(respBody))
+	}
+
+	var out validateResponse
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return fmt.Errorf("failed to unmarshal the response: %w", err)
+	}
+	if out.Valid {
+		return nil
+	}
+	reason := out.Error
+	for _, e := range out.Explanations {
+		if reason == "" && e.Error != "" {
+			reason = e.Error
+		}
+	}
+	return fmt.Errorf("%w: %s", log.ErrInvalidQuery, reason)
+}
+
+type validateResponse struct {
+	Valid        bool   `json:"valid"`
+	Error        string `json:"error"`
+	Explanations []struct {
+		Error string `json:"error"`
+	} `json:"explanations"`
+}
+
+// post sends the given body to the given path of the configured index
+// and returns the status code and the body of the response.
+func (p *Provider) post(ctx context.Context, path string, body []byte) (int, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	url := p.address + path
+	if p.index != "" {
+		url = p.address + "/" + p.index + path
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch {
+	case p.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+p.apiKey)
+	case p.username != "" && p.password != "":
+		req.SetBasicAuth(p.username, p.password)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// buildRequestBody combines the given query DSL with a range filter of the analysis window.
+// The query is the content of the "query" field, e.g. {"match": {"level": "error"}}.
+func (p *Provider) buildRequestBody(query string, queryRange log.QueryRange) ([]byte, error) {
+	var q json.RawMessage
+	if err := json.Unmarshal([]byte(query), &q); err != nil {
+		return nil, fmt.Errorf("the query must be a valid JSON of query DSL: %w", err)
+	}
+	body := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": []interface{}{q},
+				"filter": []interface{}{
+					map[string]interface{}{
+						"range": map[string]interface{}{
+							p.timestampField: map[string]interface{}{
+								"gte":    queryRange.From.Unix(),
+								"lte":    queryRange.To.Unix(),
+								"format": "epoch_second",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	return json.Marshal(body)
+}
+
+func evaluate(count, threshold int) (bool, string, error) {
+	if count > threshold {
+		reason := fmt.Sprintf("found %d matching documents that exceeded the threshold (%d)", count, threshold)
+		return false, reason, nil
+	}
+	reason := fmt.Sprintf("found %d matching documents within the threshold (%d)", count, threshold)
+	return true, reason, nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/log"
+)
+
+func TestEvaluate(t *testing.T) {
+	queryRange := log.QueryRange{
+		From: time.Unix(1617000000, 0),
+		To:   time.Unix(1617000060, 0),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/logs-*/_count", r.URL.Path)
+		assert.Equal(t, "ApiKey a2V5", r.Header.Get("Authorization"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		expected := `{"query":{"bool":{"filter":[{"range":{"timestamp":{"format":"epoch_second","gte":1617000000,"lte":1617000060}}}],"must":[{"match":{"level":"error"}}]}}}`
+		assert.JSONEq(t, expected, string(body))
+
+		json.NewEncoder(w).Encode(countResponse{Count: 3})
+	}))
+	defer server.Close()
+
+	p, err := NewProvider(server.URL, WithIndex("logs-*"), WithTimestampField("timestamp"), WithAPIKey("a2V5"))
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name      string
+		query     string
+		threshold int
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name:      "exceeded the threshold",
+			query:     `{"match": {"level": "error"}}`,
+			threshold: 2,
+			want:      false,
+		},
+		{
+			name:      "within the threshold",
+			query:     `{"match": {"level": "error"}}`,
+			threshold: 3,
+			want:      true,
+		},
+		{
+			name:    "invalid query",
+			query:   `level:error`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _, err := p.Evaluate(context.Background(), tc.query, queryRange, tc.threshold)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestValidateQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/logs-*/_validate/query", r.URL.Path)
+		assert.Equal(t, "true", r.URL.Query().Get("explain"))
+
+		var body struct {
+			Query map[string]interface{} `json:"query"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if _, ok := body.Query["match"]; ok {
+			json.NewEncoder(w).Encode(map[string]interface{}{"valid": true})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"valid": false,
+			"explanations": []map[string]interface{}{
+				{"valid": false, "error": "unknown query"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	p, err := NewProvider(server.URL, WithIndex("logs-*"))
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{
+			name:  "valid query",
+			query: `{"match": {"level": "error"}}`,
+		},
+		{
+			name:    "rejected by the validate API",
+			query:   `{"unknown": {"level": "error"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "not a JSON",
+			query:   `level:error`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := p.ValidateQuery(context.Background(), tc.query)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if tc.wantErr {
+				assert.True(t, errors.Is(err, log.ErrInvalidQuery))
+			}
+		})
+	}
+}
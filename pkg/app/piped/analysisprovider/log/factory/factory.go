@@ -17,17 +17,19 @@ package factory
 import (
 	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/log"
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/log/elasticsearch"
 	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/log/stackdriver"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
 // NewProvider generates an appropriate provider according to analysis provider config.
-func NewProvider(providerCfg *config.PipedAnalysisProvider, logger *zap.Logger) (provider log.Provider, err error) {
+func NewProvider(analysisTempCfg *config.TemplatableAnalysisLog, providerCfg *config.PipedAnalysisProvider, logger *zap.Logger) (provider log.Provider, err error) {
 	switch providerCfg.Type {
 	case model.AnalysisProviderStackdriver:
 		cfg := providerCfg.StackdriverConfig
@@ -39,6 +41,36 @@ func NewProvider(providerCfg *config.PipedAnalysisProvider, logger *zap.Logger)
 		if err != nil {
 			return nil, err
 		}
+	case model.AnalysisProviderElasticsearch:
+		cfg := providerCfg.ElasticsearchConfig
+		options := []elasticsearch.Option{
+			elasticsearch.WithLogger(logger),
+			elasticsearch.WithTimeout(analysisTempCfg.Timeout.Duration()),
+			elasticsearch.WithIndex(cfg.Index),
+			elasticsearch.WithTimestampField(cfg.TimestampField),
+		}
+		if cfg.APIKeyFile != "" {
+			apiKey, err := ioutil.ReadFile(cfg.APIKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the api key file: %w", err)
+			}
+			options = append(options, elasticsearch.WithAPIKey(strings.TrimSpace(string(apiKey))))
+		}
+		if cfg.UsernameFile != "" && cfg.PasswordFile != "" {
+			username, err := ioutil.ReadFile(cfg.UsernameFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the username file: %w", err)
+			}
+			password, err := ioutil.ReadFile(cfg.PasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the password file: %w", err)
+			}
+			options = append(options, elasticsearch.WithBasicAuth(strings.TrimSpace(string(username)), strings.TrimSpace(string(password))))
+		}
+		provider, err = elasticsearch.NewProvider(cfg.Address, options...)
+		if err != nil {
+			return nil, err
+		}
 
 	default:
 		return nil, fmt.Errorf("any of providers config not found")
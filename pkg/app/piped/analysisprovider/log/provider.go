@@ -16,13 +16,35 @@ package log
 
 import (
 	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidQuery = errors.New("invalid query")
 )
 
 // Provider represents a client for log provider which provides logs for analysis.
 type Provider interface {
 	Type() string
-	// Evaluate runs the given query against the log provider,
-	// and then checks if there is at least one error log.
+	// Evaluate runs the given query against the log provider within the given range,
+	// and then checks if the number of matching logs does not exceed the threshold.
 	// Returns the result reason if non-error occurred.
-	Evaluate(ctx context.Context, query string) (result bool, reason string, err error)
+	Evaluate(ctx context.Context, query string, queryRange QueryRange, threshold int) (result bool, reason string, err error)
+}
+
+// QueryValidator is implemented by the providers able to check the given query
+// without evaluating its result, e.g. to fail a deployment at planning.
+type QueryValidator interface {
+	// ValidateQuery returns an error wrapping ErrInvalidQuery if the provider rejected the query.
+	// Any other error means the query could not be validated, e.g. the provider was unreachable.
+	ValidateQuery(ctx context.Context, query string) error
+}
+
+// QueryRange represents a sliced time range.
+type QueryRange struct {
+	// Start of the queried time period.
+	From time.Time
+	// End of the queried time period.
+	To time.Time
 }
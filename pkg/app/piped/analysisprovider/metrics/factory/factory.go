@@ -23,7 +23,11 @@ import (
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
 	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics/datadog"
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics/graphite"
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics/influxdb"
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics/plugin"
 	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics/prometheus"
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics/wavefront"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
@@ -74,6 +78,56 @@ func NewProvider(analysisTempCfg *config.TemplatableAnalysisMetrics, providerCfg
 			options = append(options, datadog.WithAddress(cfg.Address))
 		}
 		return datadog.NewProvider(apiKey, applicationKey, options...)
+	case model.AnalysisProviderGraphite:
+		options := []graphite.Option{
+			graphite.WithLogger(logger),
+			graphite.WithTimeout(analysisTempCfg.Timeout.Duration()),
+		}
+		cfg := providerCfg.GraphiteConfig
+		if cfg.UsernameFile != "" && cfg.PasswordFile != "" {
+			username, err := ioutil.ReadFile(cfg.UsernameFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the username file: %w", err)
+			}
+			password, err := ioutil.ReadFile(cfg.PasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the password file: %w", err)
+			}
+			options = append(options, graphite.WithBasicAuth(strings.TrimSpace(string(username)), strings.TrimSpace(string(password))))
+		}
+		return graphite.NewProvider(cfg.Address, options...)
+	case model.AnalysisProviderInfluxDB:
+		options := []influxdb.Option{
+			influxdb.WithLogger(logger),
+			influxdb.WithTimeout(analysisTempCfg.Timeout.Duration()),
+		}
+		cfg := providerCfg.InfluxDBConfig
+		if cfg.TokenFile != "" {
+			token, err := ioutil.ReadFile(cfg.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the token file: %w", err)
+			}
+			options = append(options, influxdb.WithToken(strings.TrimSpace(string(token))))
+		}
+		return influxdb.NewProvider(cfg.Address, cfg.Organization, options...)
+	case model.AnalysisProviderWavefront:
+		options := []wavefront.Option{
+			wavefront.WithLogger(logger),
+			wavefront.WithTimeout(analysisTempCfg.Timeout.Duration()),
+		}
+		cfg := providerCfg.WavefrontConfig
+		token, err := ioutil.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the token file: %w", err)
+		}
+		return wavefront.NewProvider(cfg.Address, strings.TrimSpace(string(token)), options...)
+	case model.AnalysisProviderPlugin:
+		options := []plugin.Option{
+			plugin.WithLogger(logger),
+			plugin.WithTimeout(analysisTempCfg.Timeout.Duration()),
+		}
+		cfg := providerCfg.PluginConfig
+		return plugin.NewProvider(cfg.Plugin, cfg.Options, options...)
 	default:
 		return nil, fmt.Errorf("any of providers config not found")
 	}
@@ -0,0 +1,169 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+)
+
+const (
+	ProviderType   = "Graphite"
+	defaultTimeout = 30 * time.Second
+)
+
+// Provider works as an HTTP client for Graphite render API.
+type Provider struct {
+	client   *http.Client
+	address  string
+	username string
+	password string
+
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+func NewProvider(address string, opts ...Option) (*Provider, error) {
+	if address == "" {
+		return nil, fmt.Errorf("address is required")
+	}
+
+	p := &Provider{
+		client:  &http.Client{},
+		address: strings.TrimRight(address, "/"),
+		timeout: defaultTimeout,
+		logger:  zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+type Option func(*Provider)
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Provider) {
+		p.timeout = timeout
+	}
+}
+
+func WithLogger(logger *zap.Logger) Option {
+	return func(p *Provider) {
+		p.logger = logger.Named("graphite-provider")
+	}
+}
+
+func WithBasicAuth(username, password string) Option {
+	return func(p *Provider) {
+		p.username = username
+		p.password = password
+	}
+}
+
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Evaluate queries the render API with the given target and checks if all non-null data points are within the expected range.
+// For the render API, see: https://graphite.readthedocs.io/en/latest/render_api.html
+func (p *Provider) Evaluate(ctx context.Context, query string, queryRange metrics.QueryRange, evaluator metrics.Evaluator) (bool, string, error) {
+	if err := queryRange.Validate(); err != nil {
+		return false, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	params := url.Values{}
+	params.Set("target", query)
+	params.Set("from", strconv.FormatInt(queryRange.From.Unix(), 10))
+	params.Set("until", strconv.FormatInt(queryRange.To.Unix(), 10))
+	params.Set("format", "json")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.address+"/render?"+params.Encode(), nil)
+	if err != nil {
+		return false, "", err
+	}
+	if p.username != "" && p.password != "" {
+		req.SetBasicAuth(p.username, p.password)
+	}
+
+	p.logger.Info("run query", zap.String("query", query))
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, "", fmt.Errorf("unexpected HTTP status code from %s: %d, %s", req.URL.Host, resp.StatusCode, string(body))
+	}
+
+	var series []timeSeries
+	if err := json.Unmarshal(body, &series); err != nil {
+		return false, "", fmt.Errorf("failed to unmarshal the response: %w", err)
+	}
+	return evaluate(evaluator, series)
+}
+
+// timeSeries represents a series returned by the render API.
+type timeSeries struct {
+	Target string `json:"target"`
+	// Each data point is a pair of [value, timestamp].
+	// The value is null when no data was recorded at that time.
+	Datapoints [][2]*float64 `json:"datapoints"`
+}
+
+// evaluate checks if all non-null data points for all time series are within the expected range.
+func evaluate(evaluator metrics.Evaluator, series []timeSeries) (bool, string, error) {
+	if len(series) == 0 {
+		return false, "", fmt.Errorf("no time series found: %w", metrics.ErrNoDataFound)
+	}
+	for _, s := range series {
+		var found bool
+		for _, point := range s.Datapoints {
+			value := point[0]
+			if value == nil {
+				continue
+			}
+			found = true
+			if !evaluator.InRange(*value) {
+				reason := fmt.Sprintf("found a value (%g) of %s that is out of the expected range (%s)", *value, s.Target, evaluator)
+				return false, reason, nil
+			}
+		}
+		if !found {
+			return false, "", fmt.Errorf("no data points found for %s within the queried range: %w", s.Target, metrics.ErrNoDataFound)
+		}
+	}
+	reason := fmt.Sprintf("all values are within the expected range (%s)", evaluator)
+	return true, reason, nil
+}
@@ -0,0 +1,228 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package influxdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+)
+
+const (
+	ProviderType   = "InfluxDB"
+	defaultTimeout = 30 * time.Second
+	valueColumn    = "_value"
+)
+
+// Provider works as an HTTP client for InfluxDB v2 query API.
+type Provider struct {
+	client       *http.Client
+	address      string
+	organization string
+	token        string
+
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+func NewProvider(address, organization string, opts ...Option) (*Provider, error) {
+	if address == "" {
+		return nil, fmt.Errorf("address is required")
+	}
+	if organization == "" {
+		return nil, fmt.Errorf("organization is required")
+	}
+
+	p := &Provider{
+		client:       &http.Client{},
+		address:      strings.TrimRight(address, "/"),
+		organization: organization,
+		timeout:      defaultTimeout,
+		logger:       zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+type Option func(*Provider)
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Provider) {
+		p.timeout = timeout
+	}
+}
+
+func WithLogger(logger *zap.Logger) Option {
+	return func(p *Provider) {
+		p.logger = logger.Named("influxdb-provider")
+	}
+}
+
+func WithToken(token string) Option {
+	return func(p *Provider) {
+		p.token = token
+	}
+}
+
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Evaluate runs the given Flux query and checks if all values in the "_value" column are within the expected range.
+// The query range is available in the query as "v.timeRangeStart" and "v.timeRangeStop".
+// For the query API, see: https://docs.influxdata.com/influxdb/v2.0/api/#operation/PostQuery
+func (p *Provider) Evaluate(ctx context.Context, query string, queryRange metrics.QueryRange, evaluator metrics.Evaluator) (bool, string, error) {
+	if err := queryRange.Validate(); err != nil {
+		return false, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	body, err := json.Marshal(queryRequest{
+		Query: withQueryRange(query, queryRange),
+		Type:  "flux",
+		Dialect: queryDialect{
+			Header:      true,
+			Annotations: []string{},
+		},
+	})
+	if err != nil {
+		return false, "", err
+	}
+
+	u := p.address + "/api/v2/query?" + url.Values{"org": []string{p.organization}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return false, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/csv")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Token "+p.token)
+	}
+
+	p.logger.Info("run query", zap.String("query", query))
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return false, "", fmt.Errorf("unexpected HTTP status code from %s: %d, %s", req.URL.Host, resp.StatusCode, string(msg))
+	}
+
+	values, err := parseValues(resp.Body)
+	if err != nil {
+		return false, "", err
+	}
+	return evaluate(evaluator, values)
+}
+
+type queryRequest struct {
+	Query   string       `json:"query"`
+	Type    string       `json:"type"`
+	Dialect queryDialect `json:"dialect"`
+}
+
+type queryDialect struct {
+	Header      bool     `json:"header"`
+	Annotations []string `json:"annotations"`
+}
+
+// withQueryRange defines the "v" option used by the Flux query
+// to refer to the queried time range like the InfluxDB UI does.
+func withQueryRange(query string, queryRange metrics.QueryRange) string {
+	return fmt.Sprintf("option v = {timeRangeStart: %s, timeRangeStop: %s}\n%s",
+		queryRange.From.UTC().Format(time.RFC3339),
+		queryRange.To.UTC().Format(time.RFC3339),
+		query,
+	)
+}
+
+// parseValues reads the CSV response and returns all values in the "_value" column.
+// The response may contain multiple tables, each of them starts with its own header row.
+func parseValues(r io.Reader) ([]float64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	var (
+		values   []float64
+		valueIdx = -1
+	)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the response: %w", err)
+		}
+		if idx := indexOf(record, valueColumn); idx >= 0 {
+			valueIdx = idx
+			continue
+		}
+		if valueIdx < 0 || valueIdx >= len(record) || record[valueIdx] == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(record[valueIdx], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q found in the %s column: %w", record[valueIdx], valueColumn, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func indexOf(record []string, column string) int {
+	for i, c := range record {
+		if c == column {
+			return i
+		}
+	}
+	return -1
+}
+
+// evaluate checks if all values are within the expected range.
+func evaluate(evaluator metrics.Evaluator, values []float64) (bool, string, error) {
+	if len(values) == 0 {
+		return false, "", fmt.Errorf("no values found within the queried range: %w", metrics.ErrNoDataFound)
+	}
+	for _, v := range values {
+		if !evaluator.InRange(v) {
+			reason := fmt.Sprintf("found a value (%g) that is out of the expected range (%s)", v, evaluator)
+			return false, reason, nil
+		}
+	}
+	reason := fmt.Sprintf("all values are within the expected range (%s)", evaluator)
+	return true, reason, nil
+}
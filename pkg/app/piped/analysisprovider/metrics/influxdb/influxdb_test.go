@@ -0,0 +1,94 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package influxdb
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+)
+
+type fakeEvaluator struct {
+	expected bool
+}
+
+func (f *fakeEvaluator) InRange(_ float64) bool {
+	return f.expected
+}
+
+func (f *fakeEvaluator) String() string {
+	return ""
+}
+
+func TestParseValues(t *testing.T) {
+	testcases := []struct {
+		name     string
+		response string
+		want     []float64
+		wantErr  bool
+	}{
+		{
+			name:     "empty response",
+			response: "",
+			want:     nil,
+		},
+		{
+			name: "multiple tables",
+			response: strings.Join([]string{
+				",result,table,_start,_stop,_time,_value,_field",
+				",_result,0,2021-03-01T00:00:00Z,2021-03-01T00:05:00Z,2021-03-01T00:01:00Z,0.5,errors",
+				",_result,0,2021-03-01T00:00:00Z,2021-03-01T00:05:00Z,2021-03-01T00:02:00Z,1.5,errors",
+				"",
+				",result,table,_value,_field",
+				",_result,1,2,errors",
+			}, "\r\n"),
+			want: []float64{0.5, 1.5, 2},
+		},
+		{
+			name: "invalid value",
+			response: strings.Join([]string{
+				",result,table,_value",
+				",_result,0,foo",
+			}, "\r\n"),
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseValues(strings.NewReader(tc.response))
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	_, _, err := evaluate(&fakeEvaluator{}, nil)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, metrics.ErrNoDataFound))
+
+	got, _, err := evaluate(&fakeEvaluator{expected: false}, []float64{1})
+	require.NoError(t, err)
+	assert.False(t, got)
+
+	got, _, err = evaluate(&fakeEvaluator{expected: true}, []float64{1, 2})
+	require.NoError(t, err)
+	assert.True(t, got)
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+	"github.com/pipe-cd/pipe/pkg/app/piped/plugin/pluginapi"
+)
+
+const (
+	ProviderType   = "Plugin"
+	defaultTimeout = 30 * time.Second
+)
+
+var (
+	clients   = make(map[string]pluginapi.AnalysisPluginServiceClient)
+	clientsMu sync.RWMutex
+)
+
+// RegisterClients makes the given clients of analysis plugins,
+// keyed by the plugin name, available to the providers.
+func RegisterClients(cs map[string]pluginapi.AnalysisPluginServiceClient) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	for name, c := range cs {
+		clients[name] = c
+	}
+}
+
+// Provider forwards the queries to a piped plugin providing a custom analysis provider.
+type Provider struct {
+	client  pluginapi.AnalysisPluginServiceClient
+	name    string
+	options []byte
+
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+func NewProvider(name string, options []byte, opts ...Option) (*Provider, error) {
+	clientsMu.RLock()
+	client, ok := clients[name]
+	clientsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("plugin %s providing analysis provider was not found", name)
+	}
+	return newProvider(client, name, options, opts...), nil
+}
+
+func newProvider(client pluginapi.AnalysisPluginServiceClient, name string, options []byte, opts ...Option) *Provider {
+	p := &Provider{
+		client:  client,
+		name:    name,
+		options: options,
+		timeout: defaultTimeout,
+		logger:  zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+type Option func(*Provider)
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Provider) {
+		p.timeout = timeout
+	}
+}
+
+func WithLogger(logger *zap.Logger) Option {
+	return func(p *Provider) {
+		p.logger = logger.Named("plugin-provider")
+	}
+}
+
+func (p *Provider) Type() string {
+	return fmt.Sprintf("%s(%s)", ProviderType, p.name)
+}
+
+// Evaluate asks the plugin to run the query and checks if values in all returned data points are within the expected range.
+func (p *Provider) Evaluate(ctx context.Context, query string, queryRange metrics.QueryRange, evaluator metrics.Evaluator) (bool, string, error) {
+	if err := queryRange.Validate(); err != nil {
+		return false, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	p.logger.Info("run query", zap.String("plugin", p.name), zap.String("query", query))
+	resp, err := p.client.Evaluate(ctx, &pluginapi.EvaluateRequest{
+		ProviderConfig: p.options,
+		Query:          query,
+		From:           queryRange.From.Unix(),
+		To:             queryRange.To.Unix(),
+	})
+	if err != nil {
+		return false, "", fmt.Errorf("failed to evaluate the query by plugin %s: %w", p.name, err)
+	}
+	return evaluate(evaluator, resp.Values)
+}
+
+func evaluate(evaluator metrics.Evaluator, values []float64) (bool, string, error) {
+	if len(values) == 0 {
+		return false, "", fmt.Errorf("no data points returned from the plugin: %w", metrics.ErrNoDataFound)
+	}
+	for _, v := range values {
+		if math.IsNaN(v) {
+			return false, "", fmt.Errorf("the value is not a number: %w", metrics.ErrNoDataFound)
+		}
+		if !evaluator.InRange(v) {
+			reason := fmt.Sprintf("found a value (%g) that is out of the expected range (%s)", v, evaluator)
+			return false, reason, nil
+		}
+	}
+	return true, fmt.Sprintf("all values are within the expected range (%s)", evaluator), nil
+}
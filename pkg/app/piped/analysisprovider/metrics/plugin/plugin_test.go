@@ -0,0 +1,156 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+	"github.com/pipe-cd/pipe/pkg/app/piped/plugin/pluginapi"
+)
+
+type fakeEvaluator struct {
+	expected bool
+}
+
+func (f *fakeEvaluator) InRange(_ float64) bool {
+	return f.expected
+}
+
+func (f *fakeEvaluator) String() string {
+	return ""
+}
+
+type fakeClient struct {
+	req    *pluginapi.EvaluateRequest
+	values []float64
+	err    error
+}
+
+func (c *fakeClient) Evaluate(_ context.Context, req *pluginapi.EvaluateRequest, _ ...grpc.CallOption) (*pluginapi.EvaluateResponse, error) {
+	c.req = req
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &pluginapi.EvaluateResponse{Values: c.values}, nil
+}
+
+func TestNewProvider(t *testing.T) {
+	_, err := NewProvider("unknown", nil)
+	assert.Error(t, err)
+
+	RegisterClients(map[string]pluginapi.AnalysisPluginServiceClient{
+		"foo": &fakeClient{},
+	})
+	p, err := NewProvider("foo", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Plugin(foo)", p.Type())
+}
+
+func TestProviderEvaluate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		client  *fakeClient
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:    "plugin error occurred",
+			client:  &fakeClient{err: fmt.Errorf("error")},
+			want:    false,
+			wantErr: true,
+		},
+		{
+			name:    "values are returned",
+			client:  &fakeClient{values: []float64{1, 2}},
+			want:    true,
+			wantErr: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newProvider(tc.client, "foo", []byte(`{"key":"value"}`))
+			from := time.Unix(1600000000, 0)
+			to := from.Add(time.Minute)
+			got, _, err := p.Evaluate(context.Background(), "query", metrics.QueryRange{From: from, To: to}, &fakeEvaluator{expected: true})
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+
+			require.NotNil(t, tc.client.req)
+			assert.Equal(t, "query", tc.client.req.Query)
+			assert.Equal(t, from.Unix(), tc.client.req.From)
+			assert.Equal(t, to.Unix(), tc.client.req.To)
+			assert.Equal(t, []byte(`{"key":"value"}`), tc.client.req.ProviderConfig)
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		evaluator metrics.Evaluator
+		values    []float64
+		want      bool
+		wantErr   bool
+		errNoData bool
+	}{
+		{
+			name:      "no data points found",
+			evaluator: &fakeEvaluator{},
+			want:      false,
+			wantErr:   true,
+			errNoData: true,
+		},
+		{
+			name:      "NaN found",
+			evaluator: &fakeEvaluator{expected: true},
+			values:    []float64{1, math.NaN()},
+			want:      false,
+			wantErr:   true,
+			errNoData: true,
+		},
+		{
+			name:      "value is out of range",
+			evaluator: &fakeEvaluator{expected: false},
+			values:    []float64{1},
+			want:      false,
+			wantErr:   false,
+		},
+		{
+			name:      "all values are within the expected range",
+			evaluator: &fakeEvaluator{expected: true},
+			values:    []float64{1, 2, 3},
+			want:      true,
+			wantErr:   false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _, err := evaluate(tc.evaluator, tc.values)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.errNoData, errors.Is(err, metrics.ErrNoDataFound))
+		})
+	}
+}
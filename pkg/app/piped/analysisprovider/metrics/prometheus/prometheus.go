@@ -16,6 +16,7 @@ package prometheus
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"time"
@@ -127,6 +128,21 @@ func (p *Provider) Evaluate(ctx context.Context, query string, queryRange metric
 	return evaluate(evaluator, response)
 }
 
+// ValidateQuery runs the given query against the instant query endpoint
+// to check whether it is a valid PromQL expression. The returned data is not evaluated.
+// For the instant query endpoint, see: https://prometheus.io/docs/prometheus/latest/querying/api/#instant-queries
+func (p *Provider) ValidateQuery(ctx context.Context, query string) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	_, _, err := p.api.Query(ctx, query, time.Now())
+	var apiErr *v1.Error
+	if errors.As(err, &apiErr) && apiErr.Type == v1.ErrBadData {
+		return fmt.Errorf("%w: %s", metrics.ErrInvalidQuery, apiErr.Msg)
+	}
+	return err
+}
+
 func evaluate(evaluator metrics.Evaluator, response model.Value) (bool, string, error) {
 	evaluateValue := func(value float64) (bool, error) {
 		if math.IsNaN(value) {
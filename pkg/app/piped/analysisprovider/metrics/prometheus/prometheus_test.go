@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -61,6 +62,45 @@ func TestProviderEvaluate(t *testing.T) {
 
 }
 
+func TestValidateQuery(t *testing.T) {
+	cases := []struct {
+		name         string
+		queryError   error
+		wantErr      bool
+		invalidQuery bool
+	}{
+		{
+			name: "valid query",
+		},
+		{
+			name:         "invalid query",
+			queryError:   &v1.Error{Type: v1.ErrBadData, Msg: "parse error"},
+			wantErr:      true,
+			invalidQuery: true,
+		},
+		{
+			name:       "server error occurred",
+			queryError: &v1.Error{Type: v1.ErrServer, Msg: "server error: 503"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := Provider{
+				api: fakeAPI{
+					err: tc.queryError,
+				},
+				timeout: defaultTimeout,
+				logger:  zap.NewNop(),
+			}
+			err := p.ValidateQuery(context.Background(), "query")
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.invalidQuery, errors.Is(err, metrics.ErrInvalidQuery))
+		})
+	}
+}
+
 func TestEvaluate(t *testing.T) {
 	testcases := []struct {
 		name      string
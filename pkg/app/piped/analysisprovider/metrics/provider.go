@@ -22,7 +22,8 @@ import (
 )
 
 var (
-	ErrNoDataFound = errors.New("no data found")
+	ErrNoDataFound  = errors.New("no data found")
+	ErrInvalidQuery = errors.New("invalid query")
 )
 
 // Provider represents a client for metrics provider which provides metrics for analysis.
@@ -35,6 +36,14 @@ type Provider interface {
 	Evaluate(ctx context.Context, query string, queryRange QueryRange, evaluator Evaluator) (expected bool, reason string, err error)
 }
 
+// QueryValidator is implemented by the providers able to check the given query
+// without evaluating its result, e.g. to fail a deployment at planning.
+type QueryValidator interface {
+	// ValidateQuery returns an error wrapping ErrInvalidQuery if the provider rejected the query.
+	// Any other error means the query could not be validated, e.g. the provider was unreachable.
+	ValidateQuery(ctx context.Context, query string) error
+}
+
 // Evaluator evaluates the response from the metrics provider.
 type Evaluator interface {
 	// InRange checks if the value is expected one.
@@ -0,0 +1,169 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wavefront
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+)
+
+const (
+	ProviderType   = "Wavefront"
+	defaultTimeout = 30 * time.Second
+)
+
+// Provider works as an HTTP client for Wavefront chart API.
+type Provider struct {
+	client  *http.Client
+	address string
+	token   string
+
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+func NewProvider(address, token string, opts ...Option) (*Provider, error) {
+	if address == "" {
+		return nil, fmt.Errorf("address is required")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("api token is required")
+	}
+
+	p := &Provider{
+		client:  &http.Client{},
+		address: strings.TrimRight(address, "/"),
+		token:   token,
+		timeout: defaultTimeout,
+		logger:  zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+type Option func(*Provider)
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Provider) {
+		p.timeout = timeout
+	}
+}
+
+func WithLogger(logger *zap.Logger) Option {
+	return func(p *Provider) {
+		p.logger = logger.Named("wavefront-provider")
+	}
+}
+
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Evaluate runs the given WQL query against the chart API and checks if all data points are within the expected range.
+// For the chart API, see: https://docs.wavefront.com/wavefront_api.html
+func (p *Provider) Evaluate(ctx context.Context, query string, queryRange metrics.QueryRange, evaluator metrics.Evaluator) (bool, string, error) {
+	if err := queryRange.Validate(); err != nil {
+		return false, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("s", strconv.FormatInt(queryRange.From.Unix()*1000, 10))
+	params.Set("e", strconv.FormatInt(queryRange.To.Unix()*1000, 10))
+	// Use the minutely granularity since the analysis interval is usually a few minutes.
+	params.Set("g", "m")
+	// Do not return the points outside of the queried range.
+	params.Set("strict", "true")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.address+"/api/v2/chart/api?"+params.Encode(), nil)
+	if err != nil {
+		return false, "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	req.Header.Set("Accept", "application/json")
+
+	p.logger.Info("run query", zap.String("query", query))
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, "", fmt.Errorf("unexpected HTTP status code from %s: %d, %s", req.URL.Host, resp.StatusCode, string(body))
+	}
+
+	var result queryResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return false, "", fmt.Errorf("failed to unmarshal the response: %w", err)
+	}
+	if len(result.Warnings) > 0 {
+		p.logger.Warn("query returned warnings", zap.String("query", query), zap.String("warnings", result.Warnings))
+	}
+	return evaluate(evaluator, result.TimeSeries)
+}
+
+// queryResult represents the response of the chart API.
+type queryResult struct {
+	Warnings   string       `json:"warnings"`
+	TimeSeries []timeSeries `json:"timeseries"`
+}
+
+type timeSeries struct {
+	Label string `json:"label"`
+	// Each data point is a pair of [timestamp, value].
+	Data [][2]float64 `json:"data"`
+}
+
+// evaluate checks if all data points for all time series are within the expected range.
+func evaluate(evaluator metrics.Evaluator, series []timeSeries) (bool, string, error) {
+	if len(series) == 0 {
+		return false, "", fmt.Errorf("no time series found: %w", metrics.ErrNoDataFound)
+	}
+	for _, s := range series {
+		if len(s.Data) == 0 {
+			return false, "", fmt.Errorf("no data points found for %s within the queried range: %w", s.Label, metrics.ErrNoDataFound)
+		}
+		for _, point := range s.Data {
+			value := point[1]
+			if !evaluator.InRange(value) {
+				reason := fmt.Sprintf("found a value (%g) of %s that is out of the expected range (%s)", value, s.Label, evaluator)
+				return false, reason, nil
+			}
+		}
+	}
+	reason := fmt.Sprintf("all values are within the expected range (%s)", evaluator)
+	return true, reason, nil
+}
@@ -0,0 +1,218 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package webhook provides a way to analyze by asking an external judge over HTTP.
+// This allows users to plug in their own judges, such as ML-based ones or the ones
+// checking business metrics, by just serving an HTTP endpoint.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ProviderType   = "Webhook"
+	defaultTimeout = 30 * time.Second
+)
+
+// Verdict is the decision made by the external judge.
+type Verdict string
+
+const (
+	VerdictPass Verdict = "PASS"
+	VerdictFail Verdict = "FAIL"
+)
+
+// Request is the payload sent to the external judge.
+type Request struct {
+	Deployment Deployment        `json:"deployment"`
+	Stage      Stage             `json:"stage"`
+	Args       map[string]string `json:"args,omitempty"`
+	// Unix time of the start of the analysis.
+	From int64 `json:"from"`
+	// Unix time when the request was sent.
+	To int64 `json:"to"`
+}
+
+type Deployment struct {
+	ID              string `json:"id"`
+	ApplicationID   string `json:"applicationId"`
+	ApplicationName string `json:"applicationName"`
+	EnvName         string `json:"envName"`
+	Kind            string `json:"kind"`
+	CommitHash      string `json:"commitHash"`
+}
+
+type Stage struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Response is the JSON verdict returned by the external judge.
+// Either the verdict or the score must be given.
+type Response struct {
+	Verdict Verdict  `json:"verdict"`
+	Score   *float64 `json:"score"`
+	Reason  string   `json:"reason"`
+}
+
+// Provider works as an HTTP client for an external judge.
+type Provider struct {
+	client  *http.Client
+	url     string
+	token   string
+	headers map[string]string
+
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+func NewProvider(url string, opts ...Option) (*Provider, error) {
+	if url == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	p := &Provider{
+		client:  &http.Client{},
+		url:     url,
+		timeout: defaultTimeout,
+		logger:  zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+type Option func(*Provider)
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Provider) {
+		if timeout > 0 {
+			p.timeout = timeout
+		}
+	}
+}
+
+func WithLogger(logger *zap.Logger) Option {
+	return func(p *Provider) {
+		p.logger = logger.Named("webhook-provider")
+	}
+}
+
+// WithToken sets the token sent as a bearer token in the Authorization header.
+func WithToken(token string) Option {
+	return func(p *Provider) {
+		p.token = token
+	}
+}
+
+func WithHeaders(headers map[string]string) Option {
+	return func(p *Provider) {
+		p.headers = headers
+	}
+}
+
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+func (p *Provider) URL() string {
+	return p.url
+}
+
+// Evaluate sends the deployment context to the external judge and interprets its verdict.
+// When the judge returns only a score, it is considered as passed if the score is not less than minScore.
+func (p *Provider) Evaluate(ctx context.Context, in Request, minScore *float64) (bool, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	body, err := json.Marshal(in)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to marshal the request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return false, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	p.logger.Info("send request to the external judge", zap.String("url", p.url))
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false, "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, "", fmt.Errorf("unexpected HTTP status code from %s: %d, %s", req.URL.Host, resp.StatusCode, string(respBody))
+	}
+
+	var out Response
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return false, "", fmt.Errorf("failed to unmarshal the response: %w", err)
+	}
+	return judge(out, minScore)
+}
+
+// judge interprets the response from the external judge.
+// The verdict takes precedence over the score.
+func judge(resp Response, minScore *float64) (bool, string, error) {
+	reason := resp.Reason
+	switch resp.Verdict {
+	case VerdictPass:
+		if reason == "" {
+			reason = "the external judge passed the analysis"
+		}
+		return true, reason, nil
+	case VerdictFail:
+		if reason == "" {
+			reason = "the external judge failed the analysis"
+		}
+		return false, reason, nil
+	case "":
+	default:
+		return false, "", fmt.Errorf("unknown verdict %q", resp.Verdict)
+	}
+
+	if resp.Score == nil {
+		return false, "", fmt.Errorf("neither verdict nor score was returned")
+	}
+	if minScore == nil {
+		return false, "", fmt.Errorf("minScore must be configured to judge by the returned score")
+	}
+	expected := *resp.Score >= *minScore
+	if reason == "" {
+		reason = fmt.Sprintf("the returned score is %g while the minimum score is %g", *resp.Score, *minScore)
+	}
+	return expected, reason, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+func TestJudge(t *testing.T) {
+	testcases := []struct {
+		name     string
+		resp     Response
+		minScore *float64
+		want     bool
+		wantErr  bool
+	}{
+		{
+			name: "passed by verdict",
+			resp: Response{Verdict: VerdictPass, Score: float64Ptr(0)},
+			// The verdict takes precedence over the score.
+			minScore: float64Ptr(50),
+			want:     true,
+		},
+		{
+			name: "failed by verdict",
+			resp: Response{Verdict: VerdictFail},
+			want: false,
+		},
+		{
+			name:    "unknown verdict",
+			resp:    Response{Verdict: "MAYBE"},
+			wantErr: true,
+		},
+		{
+			name:     "passed by score",
+			resp:     Response{Score: float64Ptr(80)},
+			minScore: float64Ptr(80),
+			want:     true,
+		},
+		{
+			name:     "failed by score",
+			resp:     Response{Score: float64Ptr(79.9)},
+			minScore: float64Ptr(80),
+			want:     false,
+		},
+		{
+			name:    "score without minScore",
+			resp:    Response{Score: float64Ptr(80)},
+			wantErr: true,
+		},
+		{
+			name:    "empty response",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _, err := judge(tc.resp, tc.minScore)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	var received Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "team-a", r.Header.Get("X-Team"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.Write([]byte(`{"verdict": "FAIL", "reason": "conversion rate dropped"}`))
+	}))
+	defer server.Close()
+
+	p, err := NewProvider(server.URL, WithToken("secret"), WithHeaders(map[string]string{"X-Team": "team-a"}))
+	require.NoError(t, err)
+
+	in := Request{
+		Deployment: Deployment{ID: "deployment-1", ApplicationName: "app-1"},
+		Stage:      Stage{ID: "stage-1", Name: "ANALYSIS"},
+		Args:       map[string]string{"metric": "conversion"},
+		From:       1617000000,
+		To:         1617000600,
+	}
+	expected, reason, err := p.Evaluate(context.Background(), in, nil)
+	require.NoError(t, err)
+	assert.False(t, expected)
+	assert.Equal(t, "conversion rate dropped", reason)
+	assert.Equal(t, in, received)
+}
@@ -43,6 +43,7 @@ type Lister interface {
 	ListDeploymentCommands() []model.ReportableCommand
 	ListStageCommands(deploymentID, stageID string) []model.ReportableCommand
 	ListBuildPlanPreviewCommands() []model.ReportableCommand
+	ListPipedCommands() []model.ReportableCommand
 }
 
 type store struct {
@@ -54,6 +55,7 @@ type store struct {
 	deploymentCommands  []model.ReportableCommand
 	stageCommands       []model.ReportableCommand
 	planPreviewCommands []model.ReportableCommand
+	pipedCommands       []model.ReportableCommand
 	handledCommands     map[string]time.Time
 	mu                  sync.RWMutex
 	gracePeriod         time.Duration
@@ -119,10 +121,11 @@ func (s *store) sync(ctx context.Context) error {
 		deploymentCommands  = make([]model.ReportableCommand, 0)
 		stageCommands       = make([]model.ReportableCommand, 0)
 		planPreviewCommands = make([]model.ReportableCommand, 0)
+		pipedCommands       = make([]model.ReportableCommand, 0)
 	)
 	for _, cmd := range resp.Commands {
 		switch cmd.Type {
-		case model.Command_SYNC_APPLICATION, model.Command_UPDATE_APPLICATION_CONFIG:
+		case model.Command_SYNC_APPLICATION, model.Command_UPDATE_APPLICATION_CONFIG, model.Command_REFRESH_REPOSITORY, model.Command_DELETE_APPLICATION:
 			applicationCommands = append(applicationCommands, s.makeReportableCommand(cmd))
 		case model.Command_CANCEL_DEPLOYMENT:
 			deploymentCommands = append(deploymentCommands, s.makeReportableCommand(cmd))
@@ -130,6 +133,8 @@ func (s *store) sync(ctx context.Context) error {
 			stageCommands = append(stageCommands, s.makeReportableCommand(cmd))
 		case model.Command_BUILD_PLAN_PREVIEW:
 			planPreviewCommands = append(planPreviewCommands, s.makeReportableCommand(cmd))
+		case model.Command_RELOAD_PIPED_CONFIG:
+			pipedCommands = append(pipedCommands, s.makeReportableCommand(cmd))
 		}
 	}
 
@@ -138,6 +143,7 @@ func (s *store) sync(ctx context.Context) error {
 	s.deploymentCommands = deploymentCommands
 	s.stageCommands = stageCommands
 	s.planPreviewCommands = planPreviewCommands
+	s.pipedCommands = pipedCommands
 	s.mu.Unlock()
 
 	return nil
@@ -219,6 +225,20 @@ func (s *store) ListBuildPlanPreviewCommands() []model.ReportableCommand {
 	return commands
 }
 
+func (s *store) ListPipedCommands() []model.ReportableCommand {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	commands := make([]model.ReportableCommand, 0, len(s.pipedCommands))
+	for _, cmd := range s.pipedCommands {
+		if _, ok := s.handledCommands[cmd.Id]; ok {
+			continue
+		}
+		commands = append(commands, cmd)
+	}
+	return commands
+}
+
 func (s *store) makeReportableCommand(c *model.Command) model.ReportableCommand {
 	return model.ReportableCommand{
 		Command: c,
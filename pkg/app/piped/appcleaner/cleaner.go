@@ -0,0 +1,129 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package appcleaner provides a piped component
+// that cleans up the resources created by piped for the deleted applications
+// such as the namespaces of Kubernetes applications.
+package appcleaner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type commandLister interface {
+	ListApplicationCommands() []model.ReportableCommand
+}
+
+type namespaceDeleterFactory func(cluster *config.CloudProviderKubernetesConfig, logger *zap.Logger) provider.NamespaceDeleter
+
+var commandCheckInterval = 5 * time.Second
+
+type Cleaner struct {
+	commandLister       commandLister
+	config              *config.PipedSpec
+	newNamespaceDeleter namespaceDeleterFactory
+	mu                  sync.RWMutex
+	logger              *zap.Logger
+}
+
+// NewCleaner creates a new Cleaner which handles
+// the DELETE_APPLICATION commands listed by the given lister.
+func NewCleaner(cl commandLister, cfg *config.PipedSpec, logger *zap.Logger) *Cleaner {
+	return &Cleaner{
+		commandLister:       cl,
+		config:              cfg,
+		newNamespaceDeleter: provider.NewNamespaceDeleter,
+		logger:              logger.Named("app-cleaner"),
+	}
+}
+
+// Run starts handling the commands until the specified context has done.
+func (c *Cleaner) Run(ctx context.Context) error {
+	c.logger.Info("start running app cleaner")
+
+	ticker := time.NewTicker(commandCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("app cleaner has been stopped")
+			return nil
+
+		case <-ticker.C:
+			c.checkCommands(ctx)
+		}
+	}
+}
+
+// ReloadConfig makes Cleaner use the cloud providers of the given configuration
+// for the subsequent commands.
+func (c *Cleaner) ReloadConfig(cfg *config.PipedSpec) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config = cfg
+	return nil
+}
+
+func (c *Cleaner) checkCommands(ctx context.Context) {
+	for _, cmd := range c.commandLister.ListApplicationCommands() {
+		deleteCmd := cmd.GetDeleteApplication()
+		if deleteCmd == nil {
+			continue
+		}
+
+		status := model.CommandStatus_COMMAND_SUCCEEDED
+		if err := c.cleanApplication(ctx, deleteCmd); err != nil {
+			c.logger.Error("failed to clean up the deleted application",
+				zap.String("app-id", deleteCmd.ApplicationId),
+				zap.Error(err),
+			)
+			status = model.CommandStatus_COMMAND_FAILED
+		}
+		if err := cmd.Report(ctx, status, nil, nil); err != nil {
+			c.logger.Error("failed to report command status", zap.Error(err))
+		}
+	}
+}
+
+// cleanApplication deletes the resources which were created by piped
+// for the given application and are not removed together with it.
+func (c *Cleaner) cleanApplication(ctx context.Context, cmd *model.Command_DeleteApplication) error {
+	if cmd.Kind != model.ApplicationKind_KUBERNETES {
+		return nil
+	}
+
+	c.mu.RLock()
+	cp, ok := c.config.FindCloudProvider(cmd.CloudProvider, model.CloudProviderKubernetes)
+	c.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("cloud provider %s was not found", cmd.CloudProvider)
+	}
+
+	c.logger.Info("deleting the namespaces owned by the deleted application",
+		zap.String("app-id", cmd.ApplicationId),
+		zap.String("cloud-provider", cmd.CloudProvider),
+	)
+	deleter := c.newNamespaceDeleter(cp.KubernetesConfig, c.logger)
+	return deleter.DeleteOwnedNamespaces(ctx, cmd.ApplicationId)
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appcleaner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeCommandLister struct {
+	commands []model.ReportableCommand
+}
+
+func (l *fakeCommandLister) ListApplicationCommands() []model.ReportableCommand {
+	return l.commands
+}
+
+type fakeNamespaceDeleter struct {
+	deleted []string
+}
+
+func (d *fakeNamespaceDeleter) DeleteOwnedNamespaces(_ context.Context, appID string) error {
+	d.deleted = append(d.deleted, appID)
+	return nil
+}
+
+func TestCheckCommands(t *testing.T) {
+	reported := make(map[string]model.CommandStatus)
+	makeCommand := func(id string, cmd *model.Command) model.ReportableCommand {
+		cmd.Id = id
+		return model.ReportableCommand{
+			Command: cmd,
+			Report: func(_ context.Context, status model.CommandStatus, _ map[string]string, _ []byte) error {
+				reported[id] = status
+				return nil
+			},
+		}
+	}
+	lister := &fakeCommandLister{
+		commands: []model.ReportableCommand{
+			makeCommand("sync", &model.Command{
+				SyncApplication: &model.Command_SyncApplication{ApplicationId: "app-1"},
+			}),
+			makeCommand("delete-k8s", &model.Command{
+				DeleteApplication: &model.Command_DeleteApplication{
+					ApplicationId: "app-2",
+					Kind:          model.ApplicationKind_KUBERNETES,
+					CloudProvider: "kubernetes-default",
+				},
+			}),
+			makeCommand("delete-lambda", &model.Command{
+				DeleteApplication: &model.Command_DeleteApplication{
+					ApplicationId: "app-3",
+					Kind:          model.ApplicationKind_LAMBDA,
+					CloudProvider: "lambda-default",
+				},
+			}),
+			makeCommand("delete-unknown-provider", &model.Command{
+				DeleteApplication: &model.Command_DeleteApplication{
+					ApplicationId: "app-4",
+					Kind:          model.ApplicationKind_KUBERNETES,
+					CloudProvider: "unknown",
+				},
+			}),
+		},
+	}
+	cfg := &config.PipedSpec{
+		CloudProviders: []config.PipedCloudProvider{
+			{
+				Name:             "kubernetes-default",
+				Type:             model.CloudProviderKubernetes,
+				KubernetesConfig: &config.CloudProviderKubernetesConfig{},
+			},
+		},
+	}
+	deleter := &fakeNamespaceDeleter{}
+
+	c := NewCleaner(lister, cfg, zap.NewNop())
+	c.newNamespaceDeleter = func(_ *config.CloudProviderKubernetesConfig, _ *zap.Logger) provider.NamespaceDeleter {
+		return deleter
+	}
+	c.checkCommands(context.Background())
+
+	assert.Equal(t, []string{"app-2"}, deleter.deleted)
+	assert.Equal(t, map[string]model.CommandStatus{
+		"delete-k8s":              model.CommandStatus_COMMAND_SUCCEEDED,
+		"delete-lambda":           model.CommandStatus_COMMAND_SUCCEEDED,
+		"delete-unknown-provider": model.CommandStatus_COMMAND_FAILED,
+	}, reported)
+}
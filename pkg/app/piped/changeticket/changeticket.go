@@ -0,0 +1,158 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package changeticket manages the change tickets of deployments
+// in the external change management systems such as Jira and ServiceNow.
+package changeticket
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/httpjson"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	defaultSummaryTemplate     = "Deploy {{ .Deployment.ApplicationName }} to {{ .EnvName }}"
+	defaultDescriptionTemplate = "PipeCD deployment {{ .Deployment.Id }} of application {{ .Deployment.ApplicationName }} at commit {{ .Deployment.Trigger.Commit.Hash }}.\n\n{{ .Deployment.Trigger.Commit.Message }}"
+)
+
+const (
+	// ProviderMetadataKey is the key of the deployment metadata
+	// storing the name of the provider managing the ticket to be completed.
+	ProviderMetadataKey = "change-ticket-provider"
+	// IDMetadataKey is the key of the deployment metadata
+	// storing the ID of the ticket to be completed.
+	IDMetadataKey = "change-ticket-id"
+)
+
+// Status represents the approval status of a ticket.
+type Status string
+
+const (
+	StatusPending  Status = "PENDING"
+	StatusApproved Status = "APPROVED"
+	StatusRejected Status = "REJECTED"
+)
+
+// Ticket represents a change ticket managed in the external system.
+type Ticket struct {
+	// The identifier used to refer the ticket later.
+	ID string
+	// The human readable number of the ticket, e.g. CHG0030001 or OPS-123.
+	Number string
+	// The link to the ticket.
+	URL    string
+	Status Status
+}
+
+// CreateRequest contains the content of the ticket to be created.
+type CreateRequest struct {
+	// The ID of the deployment the ticket is created for.
+	// The ticket already created for the same deployment is returned instead of creating a new one,
+	// e.g. when piped was restarted before recording the created ticket.
+	DeploymentID string
+	Summary      string
+	Description  string
+	Fields       map[string]string
+}
+
+// Provider creates, tracks and updates the change tickets.
+type Provider interface {
+	// Create creates a new ticket unless one was already created for the same deployment.
+	Create(ctx context.Context, req CreateRequest) (*Ticket, error)
+	// Get returns the ticket whose ID or number is the given one.
+	Get(ctx context.Context, id string) (*Ticket, error)
+	// Complete records the result of the deployment into the ticket.
+	Complete(ctx context.Context, id string, success bool, comment string) error
+}
+
+// NewProvider generates an appropriate provider according to the change management provider config.
+func NewProvider(cfg config.PipedChangeManagementProvider, logger *zap.Logger) (Provider, error) {
+	logger = logger.Named("change-ticket").With(zap.String("provider", cfg.Name))
+	switch cfg.Type {
+	case config.ChangeManagementProviderJira:
+		c := cfg.JiraConfig
+		username, err := httpjson.ReadSecretFile(c.UsernameFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the username file: %w", err)
+		}
+		token, err := httpjson.ReadSecretFile(c.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the token file: %w", err)
+		}
+		return newJiraProvider(c, username, token, logger), nil
+
+	case config.ChangeManagementProviderServiceNow:
+		c := cfg.ServiceNowConfig
+		username, err := httpjson.ReadSecretFile(c.UsernameFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the username file: %w", err)
+		}
+		password, err := httpjson.ReadSecretFile(c.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the password file: %w", err)
+		}
+		return newServiceNowProvider(c, username, password, logger), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported change management provider type: %s", cfg.Type)
+	}
+}
+
+// BuildCreateRequest renders the given ticket template with the given data.
+// The default summary and description are used when they are not specified.
+func BuildCreateRequest(tmpl config.ChangeTicketTemplate, data interface{}) (CreateRequest, error) {
+	var (
+		req = CreateRequest{Fields: make(map[string]string, len(tmpl.Fields))}
+		err error
+	)
+	summary := tmpl.Summary
+	if summary == "" {
+		summary = defaultSummaryTemplate
+	}
+	if req.Summary, err = render("summary", summary, data); err != nil {
+		return req, err
+	}
+	description := tmpl.Description
+	if description == "" {
+		description = defaultDescriptionTemplate
+	}
+	if req.Description, err = render("description", description, data); err != nil {
+		return req, err
+	}
+	for k, v := range tmpl.Fields {
+		if req.Fields[k], err = render(k, v, data); err != nil {
+			return req, err
+		}
+	}
+	return req, nil
+}
+
+func render(name, text string, data interface{}) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template of %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template of %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changeticket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestBuildCreateRequest(t *testing.T) {
+	data := struct {
+		Deployment *model.Deployment
+		EnvName    string
+	}{
+		Deployment: &model.Deployment{
+			Id:              "deployment-id",
+			ApplicationName: "helloworld",
+			Trigger: &model.DeploymentTrigger{
+				Commit: &model.Commit{
+					Hash:    "abc",
+					Message: "Update image",
+				},
+			},
+		},
+		EnvName: "prod",
+	}
+	testcases := []struct {
+		name        string
+		tmpl        config.ChangeTicketTemplate
+		expected    CreateRequest
+		expectedErr bool
+	}{
+		{
+			name: "default template",
+			expected: CreateRequest{
+				Summary:     "Deploy helloworld to prod",
+				Description: "PipeCD deployment deployment-id of application helloworld at commit abc.\n\nUpdate image",
+				Fields:      map[string]string{},
+			},
+		},
+		{
+			name: "specified template",
+			tmpl: config.ChangeTicketTemplate{
+				Summary:     "Release {{ .Deployment.ApplicationName }}",
+				Description: "Commit {{ .Deployment.Trigger.Commit.Hash }}",
+				Fields: map[string]string{
+					"assignment_group": "sre-{{ .EnvName }}",
+				},
+			},
+			expected: CreateRequest{
+				Summary:     "Release helloworld",
+				Description: "Commit abc",
+				Fields: map[string]string{
+					"assignment_group": "sre-prod",
+				},
+			},
+		},
+		{
+			name: "invalid template",
+			tmpl: config.ChangeTicketTemplate{
+				Summary: "{{ .Unknown }}",
+			},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := BuildCreateRequest(tc.tmpl, data)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			if err == nil {
+				assert.Equal(t, tc.expected, req)
+			}
+		})
+	}
+}
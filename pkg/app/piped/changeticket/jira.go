@@ -0,0 +1,216 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changeticket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/httpjson"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+var (
+	defaultJiraApprovedStatuses = []string{"Approved"}
+	defaultJiraRejectedStatuses = []string{"Declined", "Rejected"}
+)
+
+// jiraProvider manages the change tickets as Jira issues through the REST API v2.
+type jiraProvider struct {
+	address           string
+	project           string
+	issueType         string
+	username          string
+	token             string
+	approvedStatuses  []string
+	rejectedStatuses  []string
+	successTransition string
+	failureTransition string
+	client            *http.Client
+	logger            *zap.Logger
+}
+
+type jiraIssue struct {
+	ID     string `json:"id"`
+	Key    string `json:"key"`
+	Fields struct {
+		Status struct {
+			Name string `json:"name"`
+		} `json:"status"`
+	} `json:"fields"`
+}
+
+func newJiraProvider(cfg *config.ChangeManagementJiraConfig, username, token string, logger *zap.Logger) *jiraProvider {
+	p := &jiraProvider{
+		address:           strings.TrimSuffix(cfg.Address, "/"),
+		project:           cfg.Project,
+		issueType:         cfg.IssueType,
+		username:          username,
+		token:             token,
+		approvedStatuses:  cfg.ApprovedStatuses,
+		rejectedStatuses:  cfg.RejectedStatuses,
+		successTransition: cfg.SuccessTransition,
+		failureTransition: cfg.FailureTransition,
+		client:            httpjson.NewClient(),
+		logger:            logger,
+	}
+	if p.issueType == "" {
+		p.issueType = "Change"
+	}
+	if len(p.approvedStatuses) == 0 {
+		p.approvedStatuses = defaultJiraApprovedStatuses
+	}
+	if len(p.rejectedStatuses) == 0 {
+		p.rejectedStatuses = defaultJiraRejectedStatuses
+	}
+	return p
+}
+
+func (p *jiraProvider) Create(ctx context.Context, req CreateRequest) (*Ticket, error) {
+	// The issue is labeled with the deployment ID to find it when creating again.
+	label := jiraDeploymentLabel(req.DeploymentID)
+	if req.DeploymentID != "" {
+		key, err := p.findIssue(ctx, label)
+		if err != nil {
+			return nil, err
+		}
+		if key != "" {
+			p.logger.Info("found the jira issue already created for the deployment", zap.String("issue", key))
+			return p.Get(ctx, key)
+		}
+	}
+
+	fields := map[string]interface{}{
+		"project":     map[string]string{"key": p.project},
+		"issuetype":   map[string]string{"name": p.issueType},
+		"summary":     req.Summary,
+		"description": req.Description,
+	}
+	if req.DeploymentID != "" {
+		fields["labels"] = []string{label}
+	}
+	for k, v := range req.Fields {
+		fields[k] = v
+	}
+	var issue jiraIssue
+	if err := p.do(ctx, http.MethodPost, "/rest/api/2/issue", map[string]interface{}{"fields": fields}, &issue); err != nil {
+		return nil, fmt.Errorf("failed to create jira issue: %w", err)
+	}
+	p.logger.Info("created a jira issue", zap.String("issue", issue.Key))
+	return p.Get(ctx, issue.Key)
+}
+
+// findIssue returns the key of the issue having the given label in the project.
+// Empty is returned when no issue was found.
+func (p *jiraProvider) findIssue(ctx context.Context, label string) (string, error) {
+	query := url.Values{}
+	query.Set("jql", fmt.Sprintf("project = %q AND labels = %q", p.project, label))
+	query.Set("fields", "status")
+	query.Set("maxResults", "1")
+
+	var resp struct {
+		Issues []jiraIssue `json:"issues"`
+	}
+	if err := p.do(ctx, http.MethodGet, "/rest/api/2/search?"+query.Encode(), nil, &resp); err != nil {
+		return "", fmt.Errorf("failed to search jira issues: %w", err)
+	}
+	if len(resp.Issues) == 0 {
+		return "", nil
+	}
+	return resp.Issues[0].Key, nil
+}
+
+func jiraDeploymentLabel(deploymentID string) string {
+	return "pipecd-deployment-" + deploymentID
+}
+
+func (p *jiraProvider) Get(ctx context.Context, id string) (*Ticket, error) {
+	var issue jiraIssue
+	path := fmt.Sprintf("/rest/api/2/issue/%s?fields=status", url.PathEscape(id))
+	if err := p.do(ctx, http.MethodGet, path, nil, &issue); err != nil {
+		return nil, fmt.Errorf("failed to get jira issue %s: %w", id, err)
+	}
+	return &Ticket{
+		ID:     issue.Key,
+		Number: issue.Key,
+		URL:    fmt.Sprintf("%s/browse/%s", p.address, issue.Key),
+		Status: p.status(issue.Fields.Status.Name),
+	}, nil
+}
+
+func (p *jiraProvider) Complete(ctx context.Context, id string, success bool, comment string) error {
+	path := fmt.Sprintf("/rest/api/2/issue/%s/comment", url.PathEscape(id))
+	if err := p.do(ctx, http.MethodPost, path, map[string]string{"body": comment}, nil); err != nil {
+		return fmt.Errorf("failed to comment on jira issue %s: %w", id, err)
+	}
+
+	name := p.successTransition
+	if !success {
+		name = p.failureTransition
+	}
+	if name == "" {
+		return nil
+	}
+
+	var transitions struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"transitions"`
+	}
+	path = fmt.Sprintf("/rest/api/2/issue/%s/transitions", url.PathEscape(id))
+	if err := p.do(ctx, http.MethodGet, path, nil, &transitions); err != nil {
+		return fmt.Errorf("failed to list transitions of jira issue %s: %w", id, err)
+	}
+	for _, t := range transitions.Transitions {
+		if !strings.EqualFold(t.Name, name) {
+			continue
+		}
+		body := map[string]interface{}{"transition": map[string]string{"id": t.ID}}
+		if err := p.do(ctx, http.MethodPost, path, body, nil); err != nil {
+			return fmt.Errorf("failed to transition jira issue %s: %w", id, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("transition %q is not available for jira issue %s", name, id)
+}
+
+func (p *jiraProvider) status(name string) Status {
+	for _, s := range p.approvedStatuses {
+		if strings.EqualFold(s, name) {
+			return StatusApproved
+		}
+	}
+	for _, s := range p.rejectedStatuses {
+		if strings.EqualFold(s, name) {
+			return StatusRejected
+		}
+	}
+	return StatusPending
+}
+
+func (p *jiraProvider) do(ctx context.Context, method, path string, in, out interface{}) error {
+	req, err := httpjson.NewRequest(ctx, method, p.address+path, in)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(p.username, p.token)
+	return httpjson.Do(p.client, req, out)
+}
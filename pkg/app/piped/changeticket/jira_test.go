@@ -0,0 +1,127 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changeticket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestJiraProvider(t *testing.T) {
+	var (
+		status      = "Waiting for approval"
+		created     map[string]interface{}
+		comment     string
+		transitedTo string
+		creations   int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, _ := r.BasicAuth(); u != "user@example.com" || p != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /rest/api/2/search":
+			if created == nil || r.URL.Query().Get("jql") != `project = "OPS" AND labels = "pipecd-deployment-deployment-1"` {
+				w.Write([]byte(`{"issues":[]}`))
+				return
+			}
+			w.Write([]byte(`{"issues":[{"id":"10001","key":"OPS-1"}]}`))
+		case "POST /rest/api/2/issue":
+			creations++
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(`{"id":"10001","key":"OPS-1"}`))
+		case "GET /rest/api/2/issue/OPS-1":
+			w.Write([]byte(`{"id":"10001","key":"OPS-1","fields":{"status":{"name":"` + status + `"}}}`))
+		case "POST /rest/api/2/issue/OPS-1/comment":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			comment = body["body"]
+		case "GET /rest/api/2/issue/OPS-1/transitions":
+			w.Write([]byte(`{"transitions":[{"id":"31","name":"Done"},{"id":"41","name":"Failed"}]}`))
+		case "POST /rest/api/2/issue/OPS-1/transitions":
+			var body struct {
+				Transition struct {
+					ID string `json:"id"`
+				} `json:"transition"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			transitedTo = body.Transition.ID
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := newJiraProvider(&config.ChangeManagementJiraConfig{
+		Address:           server.URL + "/",
+		Project:           "OPS",
+		SuccessTransition: "Done",
+		FailureTransition: "Failed",
+	}, "user@example.com", "token", zap.NewNop())
+	ctx := context.Background()
+
+	req := CreateRequest{
+		DeploymentID: "deployment-1",
+		Summary:      "Deploy helloworld",
+		Fields:       map[string]string{"priority": "High"},
+	}
+	ticket, err := p.Create(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, &Ticket{
+		ID:     "OPS-1",
+		Number: "OPS-1",
+		URL:    server.URL + "/browse/OPS-1",
+		Status: StatusPending,
+	}, ticket)
+	fields := created["fields"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"key": "OPS"}, fields["project"])
+	assert.Equal(t, map[string]interface{}{"name": "Change"}, fields["issuetype"])
+	assert.Equal(t, []interface{}{"pipecd-deployment-deployment-1"}, fields["labels"])
+	assert.Equal(t, "High", fields["priority"])
+
+	// The issue created for the same deployment should be reused.
+	ticket, err = p.Create(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "OPS-1", ticket.ID)
+	assert.Equal(t, 1, creations)
+
+	status = "Approved"
+	ticket, err = p.Get(ctx, "OPS-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusApproved, ticket.Status)
+
+	status = "Declined"
+	ticket, err = p.Get(ctx, "OPS-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRejected, ticket.Status)
+
+	err = p.Complete(ctx, "OPS-1", false, "Deployment failed")
+	require.NoError(t, err)
+	assert.Equal(t, "Deployment failed", comment)
+	assert.Equal(t, "41", transitedTo)
+
+	_, err = p.Get(ctx, "OPS-2")
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changeticket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/httpjson"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const serviceNowChangeRequestPath = "/api/now/table/change_request"
+
+// serviceNowProvider manages the change tickets as ServiceNow change requests through the Table API.
+type serviceNowProvider struct {
+	address     string
+	username    string
+	password    string
+	closedState string
+	client      *http.Client
+	logger      *zap.Logger
+}
+
+type serviceNowChangeRequest struct {
+	SysID    string `json:"sys_id"`
+	Number   string `json:"number"`
+	Approval string `json:"approval"`
+}
+
+func newServiceNowProvider(cfg *config.ChangeManagementServiceNowConfig, username, password string, logger *zap.Logger) *serviceNowProvider {
+	p := &serviceNowProvider{
+		address:     strings.TrimSuffix(cfg.Address, "/"),
+		username:    username,
+		password:    password,
+		closedState: cfg.ClosedState,
+		client:      httpjson.NewClient(),
+		logger:      logger,
+	}
+	if p.closedState == "" {
+		p.closedState = "3"
+	}
+	return p
+}
+
+func (p *serviceNowProvider) Create(ctx context.Context, req CreateRequest) (*Ticket, error) {
+	// The change request is correlated with the deployment ID to find it when creating again.
+	if req.DeploymentID != "" {
+		cr, ok, err := p.find(ctx, "correlation_id="+req.DeploymentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find servicenow change request of deployment %s: %w", req.DeploymentID, err)
+		}
+		if ok {
+			p.logger.Info("found the servicenow change request already created for the deployment", zap.String("number", cr.Number))
+			return p.ticket(cr), nil
+		}
+	}
+
+	body := map[string]string{
+		"short_description": req.Summary,
+		"description":       req.Description,
+	}
+	if req.DeploymentID != "" {
+		body["correlation_id"] = req.DeploymentID
+	}
+	for k, v := range req.Fields {
+		body[k] = v
+	}
+	var resp struct {
+		Result serviceNowChangeRequest `json:"result"`
+	}
+	if err := p.do(ctx, http.MethodPost, serviceNowChangeRequestPath, body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to create servicenow change request: %w", err)
+	}
+	p.logger.Info("created a servicenow change request", zap.String("number", resp.Result.Number))
+	return p.ticket(resp.Result), nil
+}
+
+// Get finds the change request by either its sys_id or its number.
+func (p *serviceNowProvider) Get(ctx context.Context, id string) (*Ticket, error) {
+	cr, ok, err := p.find(ctx, fmt.Sprintf("sys_id=%s^ORnumber=%s", id, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get servicenow change request %s: %w", id, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("servicenow change request %s was not found", id)
+	}
+	return p.ticket(cr), nil
+}
+
+// find returns the first change request matching the given encoded query.
+func (p *serviceNowProvider) find(ctx context.Context, sysparmQuery string) (serviceNowChangeRequest, bool, error) {
+	query := url.Values{}
+	query.Set("sysparm_query", sysparmQuery)
+	query.Set("sysparm_fields", "sys_id,number,approval")
+	query.Set("sysparm_limit", "1")
+
+	var resp struct {
+		Result []serviceNowChangeRequest `json:"result"`
+	}
+	if err := p.do(ctx, http.MethodGet, serviceNowChangeRequestPath+"?"+query.Encode(), nil, &resp); err != nil {
+		return serviceNowChangeRequest{}, false, err
+	}
+	if len(resp.Result) == 0 {
+		return serviceNowChangeRequest{}, false, nil
+	}
+	return resp.Result[0], true, nil
+}
+
+func (p *serviceNowProvider) Complete(ctx context.Context, id string, success bool, comment string) error {
+	code := "successful"
+	if !success {
+		code = "unsuccessful"
+	}
+	body := map[string]string{
+		"state":       p.closedState,
+		"close_code":  code,
+		"close_notes": comment,
+		"work_notes":  comment,
+	}
+	path := fmt.Sprintf("%s/%s", serviceNowChangeRequestPath, url.PathEscape(id))
+	if err := p.do(ctx, http.MethodPatch, path, body, nil); err != nil {
+		return fmt.Errorf("failed to update servicenow change request %s: %w", id, err)
+	}
+	return nil
+}
+
+func (p *serviceNowProvider) ticket(cr serviceNowChangeRequest) *Ticket {
+	t := &Ticket{
+		ID:     cr.SysID,
+		Number: cr.Number,
+		URL:    fmt.Sprintf("%s/nav_to.do?uri=change_request.do?sys_id=%s", p.address, cr.SysID),
+		Status: StatusPending,
+	}
+	switch cr.Approval {
+	case "approved":
+		t.Status = StatusApproved
+	case "rejected":
+		t.Status = StatusRejected
+	}
+	return t
+}
+
+func (p *serviceNowProvider) do(ctx context.Context, method, path string, in, out interface{}) error {
+	req, err := httpjson.NewRequest(ctx, method, p.address+path, in)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(p.username, p.password)
+	return httpjson.Do(p.client, req, out)
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changeticket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestServiceNowProvider(t *testing.T) {
+	var (
+		approval  = "requested"
+		created   map[string]string
+		updated   map[string]string
+		creations int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, _ := r.BasicAuth(); u != "admin" || p != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/now/table/change_request":
+			creations++
+			json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"result":{"sys_id":"a1b2","number":"CHG0030001","approval":"not requested"}}`))
+		case "GET /api/now/table/change_request":
+			query := r.URL.Query().Get("sysparm_query")
+			if query == "correlation_id=deployment-1" && created != nil {
+				query = "sys_id=CHG0030001^ORnumber=CHG0030001"
+			}
+			if query != "sys_id=CHG0030001^ORnumber=CHG0030001" {
+				w.Write([]byte(`{"result":[]}`))
+				return
+			}
+			w.Write([]byte(`{"result":[{"sys_id":"a1b2","number":"CHG0030001","approval":"` + approval + `"}]}`))
+		case "PATCH /api/now/table/change_request/a1b2":
+			json.NewDecoder(r.Body).Decode(&updated)
+			w.Write([]byte(`{"result":{}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := newServiceNowProvider(&config.ChangeManagementServiceNowConfig{
+		Address: server.URL,
+	}, "admin", "password", zap.NewNop())
+	ctx := context.Background()
+
+	req := CreateRequest{
+		DeploymentID: "deployment-1",
+		Summary:      "Deploy helloworld",
+		Description:  "description",
+		Fields:       map[string]string{"assignment_group": "sre"},
+	}
+	ticket, err := p.Create(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, &Ticket{
+		ID:     "a1b2",
+		Number: "CHG0030001",
+		URL:    server.URL + "/nav_to.do?uri=change_request.do?sys_id=a1b2",
+		Status: StatusPending,
+	}, ticket)
+	assert.Equal(t, map[string]string{
+		"short_description": "Deploy helloworld",
+		"description":       "description",
+		"assignment_group":  "sre",
+		"correlation_id":    "deployment-1",
+	}, created)
+
+	// The change request created for the same deployment should be reused.
+	ticket, err = p.Create(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "a1b2", ticket.ID)
+	assert.Equal(t, 1, creations)
+
+	approval = "approved"
+	ticket, err = p.Get(ctx, "CHG0030001")
+	require.NoError(t, err)
+	assert.Equal(t, StatusApproved, ticket.Status)
+
+	approval = "rejected"
+	ticket, err = p.Get(ctx, "CHG0030001")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRejected, ticket.Status)
+
+	_, err = p.Get(ctx, "CHG0030002")
+	assert.Error(t, err)
+
+	err = p.Complete(ctx, "a1b2", true, "Deployment succeeded")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"state":       "3",
+		"close_code":  "successful",
+		"close_notes": "Deployment succeeded",
+		"work_notes":  "Deployment succeeded",
+	}, updated)
+}
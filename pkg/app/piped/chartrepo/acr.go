@@ -0,0 +1,182 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartrepo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	// The username must be used along with the refresh token issued by ACR.
+	acrUsername = "00000000-0000-0000-0000-000000000000"
+	// The resource whose Azure AD token can be exchanged for an ACR refresh token.
+	azureManagementResource = "https://management.azure.com/"
+)
+
+// acrCredentialsProvider issues the credentials to pull the charts from Azure Container Registry
+// by exchanging an Azure AD token obtained with the managed identity or the service principal.
+type acrCredentialsProvider struct {
+	client *http.Client
+	// The endpoint of the Azure Instance Metadata Service to get tokens of managed identities.
+	imdsEndpoint string
+	// The endpoint of Azure AD to get tokens of service principals.
+	aadEndpoint string
+	// The scheme used to access the registries.
+	registryScheme string
+}
+
+func newACRCredentialsProvider() *acrCredentialsProvider {
+	return &acrCredentialsProvider{
+		client:         &http.Client{Timeout: 30 * time.Second},
+		imdsEndpoint:   "http://169.254.169.254/metadata/identity/oauth2/token",
+		aadEndpoint:    "https://login.microsoftonline.com",
+		registryScheme: "https",
+	}
+}
+
+// Credentials returns the username and password to access the registry of the given repository address.
+func (p *acrCredentialsProvider) Credentials(ctx context.Context, address string, creds config.AzureCredentials) (string, string, error) {
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" {
+		return "", "", fmt.Errorf("invalid address of azure container registry: %s", address)
+	}
+	token, err := p.aadToken(ctx, creds)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err := p.exchange(ctx, u.Host, creds.TenantID, token)
+	if err != nil {
+		return "", "", err
+	}
+	return acrUsername, refreshToken, nil
+}
+
+func (p *acrCredentialsProvider) aadToken(ctx context.Context, creds config.AzureCredentials) (string, error) {
+	var (
+		req *http.Request
+		err error
+	)
+	switch creds.Type {
+	case config.AzureCredentialsManagedIdentity:
+		q := url.Values{}
+		q.Set("api-version", "2018-02-01")
+		q.Set("resource", azureManagementResource)
+		if creds.ClientID != "" {
+			q.Set("client_id", creds.ClientID)
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, p.imdsEndpoint+"?"+q.Encode(), nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata", "true")
+
+	case config.AzureCredentialsServicePrincipal:
+		secret, err := ioutil.ReadFile(creds.ClientSecretFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read client secret file: %w", err)
+		}
+		form := url.Values{}
+		form.Set("grant_type", "client_credentials")
+		form.Set("client_id", creds.ClientID)
+		form.Set("client_secret", strings.TrimSpace(string(secret)))
+		form.Set("resource", azureManagementResource)
+		endpoint := fmt.Sprintf("%s/%s/oauth2/token", p.aadEndpoint, creds.TenantID)
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	default:
+		return "", fmt.Errorf("unsupported azure credentials type: %s", creds.Type)
+	}
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := p.do(req, &resp); err != nil {
+		return "", fmt.Errorf("failed to get azure ad token: %w", err)
+	}
+	return resp.AccessToken, nil
+}
+
+func (p *acrCredentialsProvider) exchange(ctx context.Context, registry, tenantID, token string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "access_token")
+	form.Set("service", registry)
+	form.Set("access_token", token)
+	if tenantID != "" {
+		form.Set("tenant", tenantID)
+	}
+	endpoint := fmt.Sprintf("%s://%s/oauth2/exchange", p.registryScheme, registry)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var resp struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := p.do(req, &resp); err != nil {
+		return "", fmt.Errorf("failed to exchange azure ad token for acr refresh token: %w", err)
+	}
+	return resp.RefreshToken, nil
+}
+
+func (p *acrCredentialsProvider) do(req *http.Request, out interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// tokenExpiry returns the expiration time written in the given JWT such as an ACR refresh token.
+// The token is not verified since it is just used to determine when to refresh it.
+// Zero is returned when the expiration time is unknown.
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartrepo
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestACRCredentials(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metadata/identity/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("client_id") != "identity-id" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"msi-token"}`)
+	})
+	mux.HandleFunc("/tenant-id/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("client_id") != "client-id" || r.PostFormValue("client_secret") != "client-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"spn-token"}`)
+	})
+	mux.HandleFunc("/oauth2/exchange", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"refresh_token":"refresh-%s"}`, r.PostFormValue("access_token"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, ioutil.WriteFile(secretFile, []byte("client-secret\n"), 0600))
+
+	p := &acrCredentialsProvider{
+		client:         server.Client(),
+		imdsEndpoint:   server.URL + "/metadata/identity/oauth2/token",
+		aadEndpoint:    server.URL,
+		registryScheme: "http",
+	}
+	address := server.URL + "/helm/v1/repo"
+
+	testcases := []struct {
+		name             string
+		creds            config.AzureCredentials
+		expectedPassword string
+		expectedErr      bool
+	}{
+		{
+			name: "managed identity",
+			creds: config.AzureCredentials{
+				Type:     config.AzureCredentialsManagedIdentity,
+				ClientID: "identity-id",
+			},
+			expectedPassword: "refresh-msi-token",
+		},
+		{
+			name: "service principal",
+			creds: config.AzureCredentials{
+				Type:             config.AzureCredentialsServicePrincipal,
+				TenantID:         "tenant-id",
+				ClientID:         "client-id",
+				ClientSecretFile: secretFile,
+			},
+			expectedPassword: "refresh-spn-token",
+		},
+		{
+			name: "wrong service principal",
+			creds: config.AzureCredentials{
+				Type:             config.AzureCredentialsServicePrincipal,
+				TenantID:         "tenant-id",
+				ClientID:         "unknown",
+				ClientSecretFile: secretFile,
+			},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			username, password, err := p.Credentials(context.Background(), address, tc.creds)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			if err == nil {
+				assert.Equal(t, acrUsername, username)
+				assert.Equal(t, tc.expectedPassword, password)
+			}
+		})
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	jwt := func(payload string) string {
+		return "header." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+	}
+	assert.Equal(t, time.Unix(1634000000, 0), tokenExpiry(jwt(`{"exp":1634000000}`)))
+	assert.True(t, tokenExpiry(jwt(`{"sub":"user"}`)).IsZero())
+	assert.True(t, tokenExpiry("refresh-token").IsZero())
+}
@@ -18,15 +18,37 @@ package chartrepo
 import (
 	"context"
 	"fmt"
-	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"golang.org/x/sync/singleflight"
 
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
 	"github.com/pipe-cd/pipe/pkg/config"
 )
 
-var updateGroup = &singleflight.Group{}
+var (
+	updateGroup = &singleflight.Group{}
+
+	// The refresh tokens issued by Azure Container Registry expire in a few hours
+	// so the repositories authenticated by Azure credentials are re-added
+	// with the new tokens at the update before their tokens expire.
+	azureRepos   = make(map[string]*azureRepo)
+	azureReposMu sync.Mutex
+	acrProvider  = newACRCredentialsProvider()
+)
+
+// The token is refreshed when it expires within this duration.
+const acrTokenRefreshMargin = 30 * time.Minute
+
+type azureRepo struct {
+	repo config.HelmChartRepository
+	// Zero means the expiration of the token is unknown.
+	expiresAt time.Time
+}
 
 type registry interface {
 	Helm(ctx context.Context, version string) (string, bool, error)
@@ -43,23 +65,58 @@ func Add(ctx context.Context, repos []config.HelmChartRepository, reg registry,
 	}
 
 	for _, repo := range repos {
-		args := []string{"repo", "add", repo.Name, repo.Address}
-		if repo.Insecure {
-			args = append(args, "--insecure-skip-tls-verify")
-		}
-		if repo.Username != "" || repo.Password != "" {
-			args = append(args, "--username", repo.Username, "--password", repo.Password)
-		}
-		cmd := exec.CommandContext(ctx, helm, args...)
-		out, err := cmd.CombinedOutput()
+		expiresAt, err := add(ctx, helm, repo, false)
 		if err != nil {
-			return fmt.Errorf("failed to add chart repository %s: %s (%w)", repo.Name, string(out), err)
+			return err
+		}
+		if repo.Azure.IsEnabled() {
+			// The repository added again with the same name replaces the old one.
+			azureReposMu.Lock()
+			azureRepos[repo.Name] = &azureRepo{
+				repo:      repo,
+				expiresAt: expiresAt,
+			}
+			azureReposMu.Unlock()
 		}
 		logger.Info(fmt.Sprintf("successfully added chart repository: %s", repo.Name))
 	}
 	return nil
 }
 
+// add adds the given repository and returns the expiration time of its token.
+// Zero is returned for the repositories not authenticated by Azure credentials.
+func add(ctx context.Context, helm string, repo config.HelmChartRepository, forceUpdate bool) (time.Time, error) {
+	var (
+		username, password = repo.Username, repo.Password
+		expiresAt          time.Time
+	)
+	if repo.Azure.IsEnabled() {
+		var err error
+		username, password, err = acrProvider.Credentials(ctx, repo.Address, repo.Azure)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to get credentials of chart repository %s from azure (%w)", repo.Name, err)
+		}
+		expiresAt = tokenExpiry(password)
+	}
+
+	args := []string{"repo", "add", repo.Name, repo.Address}
+	if repo.Insecure {
+		args = append(args, "--insecure-skip-tls-verify")
+	}
+	if username != "" || password != "" {
+		args = append(args, "--username", username, "--password", password)
+	}
+	if forceUpdate {
+		args = append(args, "--force-update")
+	}
+	cmd := toolexec.CommandContext(ctx, helm, args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to add chart repository %s: %s (%w)", repo.Name, string(out), err)
+	}
+	return expiresAt, nil
+}
+
 func Update(ctx context.Context, reg registry, logger *zap.Logger) error {
 	_, err, _ := updateGroup.Do("update", func() (interface{}, error) {
 		return nil, update(ctx, reg, logger)
@@ -75,13 +132,47 @@ func update(ctx context.Context, reg registry, logger *zap.Logger) error {
 		return fmt.Errorf("failed to find helm to update repos (%w)", err)
 	}
 
+	// The failure of a repository must not block refreshing the others.
+	var failed []string
+	for _, r := range expiringAzureRepos(time.Now()) {
+		expiresAt, err := add(ctx, helm, r.repo, true)
+		if err != nil {
+			logger.Error("failed to refresh the token of chart repository", zap.String("repo", r.repo.Name), zap.Error(err))
+			failed = append(failed, r.repo.Name)
+			continue
+		}
+		azureReposMu.Lock()
+		r.expiresAt = expiresAt
+		azureReposMu.Unlock()
+	}
+
 	args := []string{"repo", "update"}
-	cmd := exec.CommandContext(ctx, helm, args...)
+	cmd := toolexec.CommandContext(ctx, helm, args...)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to update Helm chart repositories: %s (%w)", string(out), err)
 	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to refresh the tokens of chart repositories: %s", strings.Join(failed, ", "))
+	}
 
 	logger.Info("successfully updated Helm chart repositories")
 	return nil
 }
+
+// expiringAzureRepos returns the repositories whose tokens expire soon or have unknown expiration.
+func expiringAzureRepos(now time.Time) []*azureRepo {
+	azureReposMu.Lock()
+	defer azureReposMu.Unlock()
+
+	repos := make([]*azureRepo, 0, len(azureRepos))
+	for _, r := range azureRepos {
+		if r.expiresAt.IsZero() || now.Add(acrTokenRefreshMargin).After(r.expiresAt) {
+			repos = append(repos, r)
+		}
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].repo.Name < repos[j].repo.Name
+	})
+	return repos
+}
@@ -0,0 +1,43 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartrepo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestExpiringAzureRepos(t *testing.T) {
+	now := time.Now()
+	azureRepos = map[string]*azureRepo{
+		"valid":   {repo: config.HelmChartRepository{Name: "valid"}, expiresAt: now.Add(3 * time.Hour)},
+		"expired": {repo: config.HelmChartRepository{Name: "expired"}, expiresAt: now.Add(-time.Minute)},
+		"soon":    {repo: config.HelmChartRepository{Name: "soon"}, expiresAt: now.Add(10 * time.Minute)},
+		"unknown": {repo: config.HelmChartRepository{Name: "unknown"}},
+	}
+	defer func() {
+		azureRepos = make(map[string]*azureRepo)
+	}()
+
+	var names []string
+	for _, r := range expiringAzureRepos(now) {
+		names = append(names, r.repo.Name)
+	}
+	assert.Equal(t, []string{"expired", "soon", "unknown"}, names)
+}
@@ -0,0 +1,206 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clientcert provides a piped component that holds the client certificate
+// used for the mutual TLS with the control plane and renews it before it expires.
+package clientcert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+)
+
+type apiClient interface {
+	RenewPipedCertificate(ctx context.Context, req *pipedservice.RenewPipedCertificateRequest, opts ...grpc.CallOption) (*pipedservice.RenewPipedCertificateResponse, error)
+}
+
+var (
+	checkInterval = time.Hour
+	// The certificate is renewed after this fraction of its lifetime has elapsed.
+	renewFraction = 2.0 / 3.0
+)
+
+// Rotator serves the current client certificate to the TLS handshakes
+// and periodically renews it through the control plane.
+// The renewed certificate and key are written back to the files
+// so that they are used after restarting piped.
+type Rotator struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	leaf *x509.Certificate
+
+	nowFunc func() time.Time
+	logger  *zap.Logger
+}
+
+// NewRotator loads the client certificate and key from the given files.
+func NewRotator(certFile, keyFile string, logger *zap.Logger) (*Rotator, error) {
+	r := &Rotator{
+		certFile: certFile,
+		keyFile:  keyFile,
+		nowFunc:  time.Now,
+		logger:   logger.Named("client-cert-rotator"),
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	if err := r.set(&cert); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Rotator) set(cert *tls.Certificate) error {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse client certificate: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = cert
+	r.leaf = leaf
+	return nil
+}
+
+// GetClientCertificate returns the current client certificate.
+// This is intended to be used as tls.Config.GetClientCertificate
+// so that the new connections use the renewed certificate.
+func (r *Rotator) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Run periodically renews the client certificate until the given context is done.
+func (r *Rotator) Run(ctx context.Context, client apiClient) error {
+	r.logger.Info("start running client certificate rotator")
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		if r.shouldRenew() {
+			if err := r.renew(ctx, client); err != nil {
+				// The current certificate can still be used
+				// so it will be retried at the next check.
+				r.logger.Error("failed to renew client certificate", zap.Error(err))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			r.logger.Info("client certificate rotator has been stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Rotator) shouldRenew() bool {
+	r.mu.RLock()
+	leaf := r.leaf
+	r.mu.RUnlock()
+
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	renewAt := leaf.NotBefore.Add(time.Duration(float64(lifetime) * renewFraction))
+	return !r.nowFunc().Before(renewAt)
+}
+
+func (r *Rotator) renew(ctx context.Context, client apiClient) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate signing request: %w", err)
+	}
+	resp, err := client.RenewPipedCertificate(ctx, &pipedservice.RenewPipedCertificateRequest{
+		Csr: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to request a new certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal key: %w", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	cert, err := tls.X509KeyPair(resp.Certificate, keyPEM)
+	if err != nil {
+		return fmt.Errorf("received an invalid certificate: %w", err)
+	}
+
+	// The key is written first since the old certificate does not match the new key
+	// and the pair fails to be loaded anyway while the files are being replaced.
+	if err := writeFile(r.keyFile, keyPEM); err != nil {
+		return err
+	}
+	if err := writeFile(r.certFile, resp.Certificate); err != nil {
+		return err
+	}
+	if err := r.set(&cert); err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	notAfter := r.leaf.NotAfter
+	r.mu.RUnlock()
+	r.logger.Info("successfully renewed client certificate", zap.Time("not-after", notAfter))
+	return nil
+}
+
+// writeFile replaces the content of the given file atomically.
+func writeFile(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(f.Name(), 0600); err != nil {
+		return fmt.Errorf("failed to change mode of %s: %w", path, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
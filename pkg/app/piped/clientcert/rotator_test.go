@@ -0,0 +1,128 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientcert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-24 * time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) sign(t *testing.T, pub interface{}, notBefore, notAfter time.Time) []byte {
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "piped-1"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, pub, ca.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+type fakeAPIClient struct {
+	t  *testing.T
+	ca *testCA
+}
+
+func (c *fakeAPIClient) RenewPipedCertificate(ctx context.Context, req *pipedservice.RenewPipedCertificateRequest, opts ...grpc.CallOption) (*pipedservice.RenewPipedCertificateResponse, error) {
+	block, _ := pem.Decode(req.Csr)
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	require.NoError(c.t, err)
+	require.NoError(c.t, csr.CheckSignature())
+	cert := c.ca.sign(c.t, csr.PublicKey, time.Now(), time.Now().Add(3*time.Hour))
+	return &pipedservice.RenewPipedCertificateResponse{Certificate: cert}, nil
+}
+
+func TestRotator(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	// Two thirds of the lifetime of the initial certificate has elapsed.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	now := time.Now()
+	certPEM := ca.sign(t, &key.PublicKey, now.Add(-2*time.Hour), now.Add(time.Hour))
+	require.NoError(t, ioutil.WriteFile(certFile, certPEM, 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	r, err := NewRotator(certFile, keyFile, zap.NewNop())
+	require.NoError(t, err)
+	r.nowFunc = func() time.Time { return now.Add(-time.Minute) }
+	assert.False(t, r.shouldRenew())
+	r.nowFunc = func() time.Time { return now }
+	require.True(t, r.shouldRenew())
+
+	old, err := r.GetClientCertificate(nil)
+	require.NoError(t, err)
+
+	require.NoError(t, r.renew(context.Background(), &fakeAPIClient{t: t, ca: ca}))
+	assert.False(t, r.shouldRenew())
+
+	renewed, err := r.GetClientCertificate(nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, old.Certificate[0], renewed.Certificate[0])
+
+	// The renewed pair was written back to the files.
+	loaded, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, renewed.Certificate[0], loaded.Certificate[0])
+}
@@ -0,0 +1,226 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ansible
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const defaultPlaybookCommand = "ansible-playbook"
+
+type options struct {
+	extraVars         map[string]string
+	limit             string
+	tags              []string
+	remoteUser        string
+	privateKeyFile    string
+	vaultPasswordFile string
+}
+
+type Option func(*options)
+
+// WithExtraVars sets the given variables to the playbook.
+func WithExtraVars(vars map[string]string) Option {
+	return func(opts *options) {
+		opts.extraVars = vars
+	}
+}
+
+// WithLimit limits the hosts the playbook runs against to the given pattern.
+func WithLimit(limit string) Option {
+	return func(opts *options) {
+		opts.limit = limit
+	}
+}
+
+// WithTags runs only the tasks and roles tagged with the given values.
+func WithTags(tags []string) Option {
+	return func(opts *options) {
+		opts.tags = tags
+	}
+}
+
+// WithCloudProviderConfig sets the connection and vault settings
+// configured in the given cloud provider.
+func WithCloudProviderConfig(cfg *config.CloudProviderAnsibleConfig) Option {
+	return func(opts *options) {
+		opts.remoteUser = cfg.RemoteUser
+		opts.privateKeyFile = cfg.PrivateKeyFile
+		opts.vaultPasswordFile = cfg.VaultPasswordFile
+	}
+}
+
+type Ansible struct {
+	execPath  string
+	dir       string
+	playbook  string
+	inventory string
+
+	options options
+}
+
+func NewAnsible(execPath, dir, playbook, inventory string, opts ...Option) *Ansible {
+	opt := options{}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return &Ansible{
+		execPath:  execPath,
+		dir:       dir,
+		playbook:  playbook,
+		inventory: inventory,
+		options:   opt,
+	}
+}
+
+// FindPlaybookCommand returns the path to the ansible-playbook command
+// configured in the given cloud provider, or the one found in PATH.
+func FindPlaybookCommand(cfg *config.CloudProviderAnsibleConfig) (string, error) {
+	if cfg.PlaybookPath != "" {
+		return cfg.PlaybookPath, nil
+	}
+	return exec.LookPath(defaultPlaybookCommand)
+}
+
+func (a *Ansible) command(ctx context.Context, args ...string) *toolexec.Cmd {
+	cmd := toolexec.CommandContext(ctx, a.execPath, args...)
+	cmd.Dir = a.dir
+	cmd.Env = append(os.Environ(),
+		"ANSIBLE_NOCOLOR=true",
+		"ANSIBLE_RETRY_FILES_ENABLED=false",
+	)
+	return cmd
+}
+
+// Version returns the first line of the version output
+// such as "ansible-playbook [core 2.11.6]".
+func (a *Ansible) Version(ctx context.Context) (string, error) {
+	out, err := a.command(ctx, "--version").CombinedOutput()
+	if err != nil {
+		return string(out), err
+	}
+
+	line := strings.SplitN(string(out), "\n", 2)[0]
+	return strings.TrimSpace(line), nil
+}
+
+type RecapResult struct {
+	Hosts       int
+	Ok          int
+	Changed     int
+	Unreachable int
+	Failed      int
+}
+
+func (r RecapResult) NoChanges() bool {
+	return r.Changed == 0
+}
+
+func (r RecapResult) String() string {
+	return fmt.Sprintf("%d changed, %d unreachable, %d failed on %d hosts", r.Changed, r.Unreachable, r.Failed, r.Hosts)
+}
+
+// Check runs the playbook in check mode which reports
+// the changes would be made on the hosts without making them.
+func (a *Ansible) Check(ctx context.Context, w io.Writer) (RecapResult, error) {
+	return a.run(ctx, w, "--check", "--diff")
+}
+
+// Run runs the playbook to make the hosts in the desired state.
+func (a *Ansible) Run(ctx context.Context, w io.Writer) (RecapResult, error) {
+	return a.run(ctx, w, "--diff")
+}
+
+func (a *Ansible) run(ctx context.Context, w io.Writer, flags ...string) (RecapResult, error) {
+	args := []string{"--inventory", a.inventory}
+	args = append(args, flags...)
+	if len(a.options.extraVars) > 0 {
+		vars, err := json.Marshal(a.options.extraVars)
+		if err != nil {
+			return RecapResult{}, err
+		}
+		args = append(args, "--extra-vars", string(vars))
+	}
+	if a.options.limit != "" {
+		args = append(args, "--limit", a.options.limit)
+	}
+	if len(a.options.tags) > 0 {
+		args = append(args, "--tags", strings.Join(a.options.tags, ","))
+	}
+	if a.options.remoteUser != "" {
+		args = append(args, "--user", a.options.remoteUser)
+	}
+	if a.options.privateKeyFile != "" {
+		args = append(args, "--private-key", a.options.privateKeyFile)
+	}
+	if a.options.vaultPasswordFile != "" {
+		args = append(args, "--vault-password-file", a.options.vaultPasswordFile)
+	}
+	args = append(args, a.playbook)
+
+	var buf bytes.Buffer
+	cmd := a.command(ctx, args...)
+	cmd.Stdout = io.MultiWriter(w, &buf)
+	cmd.Stderr = w
+
+	io.WriteString(w, fmt.Sprintf("ansible-playbook %s\n", strings.Join(args, " ")))
+	if err := cmd.Run(); err != nil {
+		return RecapResult{}, err
+	}
+	return parseRecapResult(buf.String())
+}
+
+var (
+	recapHeaderRegex = regexp.MustCompile(`(?m)^PLAY RECAP\b`)
+	recapHostRegex   = regexp.MustCompile(`(?m)^\S+\s*:\s*ok=(\d+)\s+changed=(\d+)\s+unreachable=(\d+)\s+failed=(\d+)`)
+)
+
+// parseRecapResult sums up the counts of all hosts in the "PLAY RECAP" printed
+// at the end of the playbook output, which contains a line like
+// "web1 : ok=3 changed=1 unreachable=0 failed=0 skipped=0" for each host.
+func parseRecapResult(out string) (RecapResult, error) {
+	loc := recapHeaderRegex.FindStringIndex(out)
+	if loc == nil {
+		return RecapResult{}, fmt.Errorf("unable to parse playbook output")
+	}
+
+	var r RecapResult
+	for _, m := range recapHostRegex.FindAllStringSubmatch(out[loc[1]:], -1) {
+		ok, _ := strconv.Atoi(m[1])
+		changed, _ := strconv.Atoi(m[2])
+		unreachable, _ := strconv.Atoi(m[3])
+		failed, _ := strconv.Atoi(m[4])
+
+		r.Hosts++
+		r.Ok += ok
+		r.Changed += changed
+		r.Unreachable += unreachable
+		r.Failed += failed
+	}
+	return r, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ansible
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRecapResult(t *testing.T) {
+	testcases := []struct {
+		name     string
+		out      string
+		expected RecapResult
+		wantErr  bool
+	}{
+		{
+			name: "changes",
+			out: `PLAY [web] *********************************************************************
+
+TASK [Gathering Facts] *********************************************************
+ok: [web1]
+ok: [web2]
+
+TASK [nginx : Install nginx] ***************************************************
+changed: [web1]
+ok: [web2]
+
+PLAY RECAP *********************************************************************
+web1                       : ok=2    changed=1    unreachable=0    failed=0    skipped=0    rescued=0    ignored=0
+web2                       : ok=2    changed=0    unreachable=0    failed=0    skipped=0    rescued=0    ignored=0
+`,
+			expected: RecapResult{
+				Hosts:   2,
+				Ok:      4,
+				Changed: 1,
+			},
+		},
+		{
+			name: "no changes",
+			out: `PLAY RECAP *********************************************************************
+web1                       : ok=2    changed=0    unreachable=0    failed=0    skipped=1    rescued=0    ignored=0
+`,
+			expected: RecapResult{
+				Hosts: 1,
+				Ok:    2,
+			},
+		},
+		{
+			name: "unreachable host",
+			out: `PLAY RECAP *********************************************************************
+web1                       : ok=0    changed=0    unreachable=1    failed=0    skipped=0    rescued=0    ignored=0
+`,
+			expected: RecapResult{
+				Hosts:       1,
+				Unreachable: 1,
+			},
+		},
+		{
+			name:    "invalid output",
+			out:     "ERROR! the playbook: playbook.yaml could not be found",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := parseRecapResult(tc.out)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
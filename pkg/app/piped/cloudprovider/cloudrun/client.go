@@ -17,13 +17,14 @@ package cloudrun
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 
 	"go.uber.org/zap"
 	"google.golang.org/api/googleapi"
+	"google.golang.org/api/logging/v2"
 	"google.golang.org/api/option"
 	"google.golang.org/api/run/v1"
+	htransport "google.golang.org/api/transport/http"
 	"sigs.k8s.io/yaml"
 )
 
@@ -31,34 +32,45 @@ type client struct {
 	projectID string
 	region    string
 	client    *run.APIService
+	logging   *logging.Service
 	logger    *zap.Logger
+
+	// Used to call the job APIs that are not supported by the generated client.
+	httpClient *http.Client
+	endpoint   string
 }
 
-func newClient(ctx context.Context, projectID, region, credentialsFile string, logger *zap.Logger) (*client, error) {
+func newClient(ctx context.Context, projectID, region, credentialsFile, serviceAccount string, delegates []string, logger *zap.Logger) (*client, error) {
 	c := &client{
 		projectID: projectID,
 		region:    region,
 		logger:    logger.Named("cloudrun"),
 	}
 
-	var options []option.ClientOption
-	if len(credentialsFile) > 0 {
-		data, err := ioutil.ReadFile(credentialsFile)
-		if err != nil {
-			return nil, fmt.Errorf("unable to read credentials file (%w)", err)
-		}
-		options = append(options, option.WithCredentialsJSON(data))
+	options, err := credentialsOptions(ctx, credentialsFile, serviceAccount, delegates)
+	if err != nil {
+		return nil, err
 	}
-	options = append(options,
-		option.WithEndpoint(fmt.Sprintf("https://%s-run.googleapis.com/", region)),
-	)
+	c.endpoint = fmt.Sprintf("https://%s-run.googleapis.com/", region)
 
-	runClient, err := run.NewService(ctx, options...)
+	runClient, err := run.NewService(ctx, append(options, option.WithEndpoint(c.endpoint))...)
 	if err != nil {
 		return nil, err
 	}
 	c.client = runClient
 
+	loggingClient, err := logging.NewService(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	c.logging = loggingClient
+
+	httpClient, _, err := htransport.NewClient(ctx, append(options, option.WithScopes(cloudPlatformScope))...)
+	if err != nil {
+		return nil, err
+	}
+	c.httpClient = httpClient
+
 	return c, nil
 }
 
@@ -108,22 +120,83 @@ func (c *client) Update(ctx context.Context, sm ServiceManifest) (*Service, erro
 	return (*Service)(service), nil
 }
 
-func (c *client) List(ctx context.Context) error {
+func (c *client) GetService(ctx context.Context, serviceName string) (*Service, error) {
 	var (
-		svc    = run.NewNamespacesServicesService(c.client)
-		parent = makeCloudRunParent(c.projectID)
-		call   = svc.List(parent)
+		svc  = run.NewNamespacesServicesService(c.client)
+		name = makeCloudRunServiceName(c.projectID, serviceName)
+		call = svc.Get(name)
 	)
-
 	call.Context(ctx)
 
-	resp, err := call.Do()
+	service, err := call.Do()
 	if err != nil {
-		return err
+		if e, ok := err.(*googleapi.Error); ok && e.Code == http.StatusNotFound {
+			return nil, ErrServiceNotFound
+		}
+		return nil, err
 	}
+	return (*Service)(service), nil
+}
 
-	fmt.Println(resp)
-	return nil
+func (c *client) ListServices(ctx context.Context, labelSelector string) ([]*Service, error) {
+	var (
+		svc       = run.NewNamespacesServicesService(c.client)
+		parent    = makeCloudRunParent(c.projectID)
+		services  []*Service
+		nextToken string
+	)
+
+	for {
+		call := svc.List(parent).LabelSelector(labelSelector)
+		if nextToken != "" {
+			call.Continue(nextToken)
+		}
+		call.Context(ctx)
+
+		resp, err := call.Do()
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range resp.Items {
+			services = append(services, (*Service)(s))
+		}
+
+		if resp.Metadata == nil || resp.Metadata.Continue == "" {
+			return services, nil
+		}
+		nextToken = resp.Metadata.Continue
+	}
+}
+
+func (c *client) ListRevisions(ctx context.Context, serviceName string) ([]*Revision, error) {
+	var (
+		svc       = run.NewNamespacesRevisionsService(c.client)
+		parent    = makeCloudRunParent(c.projectID)
+		selector  = fmt.Sprintf("%s=%s", serviceNameLabel, serviceName)
+		revisions []*Revision
+		nextToken string
+	)
+
+	for {
+		call := svc.List(parent).LabelSelector(selector)
+		if nextToken != "" {
+			call.Continue(nextToken)
+		}
+		call.Context(ctx)
+
+		resp, err := call.Do()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resp.Items {
+			revisions = append(revisions, (*Revision)(r))
+		}
+
+		if resp.Metadata == nil || resp.Metadata.Continue == "" {
+			return revisions, nil
+		}
+		nextToken = resp.Metadata.Continue
+	}
 }
 
 func makeCloudRunParent(projectID string) string {
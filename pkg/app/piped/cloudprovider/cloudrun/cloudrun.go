@@ -19,6 +19,7 @@ import (
 	"errors"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"golang.org/x/sync/singleflight"
@@ -29,17 +30,50 @@ import (
 
 const (
 	DefaultServiceManifestFilename = "service.yaml"
+	DefaultJobManifestFilename     = "job.yaml"
+
+	// LabelManagedBy is the label key added to every service deployed by piped.
+	LabelManagedBy = "pipecd-dev-managed-by"
+	// LabelApplication is the label key holding the ID of the application the service belongs to.
+	LabelApplication = "pipecd-dev-application"
+	// ManagedByPiped is the value of LabelManagedBy for services deployed by piped.
+	ManagedByPiped = "piped"
+
+	// The label added by Cloud Run to every revision to point to its service.
+	serviceNameLabel = "serving.knative.dev/service"
+	// The label added by Cloud Run to every execution to point to its job.
+	jobNameLabel = "run.googleapis.com/job"
+	// The label added by Cloud Run to the log entries of an execution.
+	executionNameLabel = "run.googleapis.com/execution_name"
+
+	conditionCompleted     = "Completed"
+	conditionStatusTrue    = "True"
+	conditionStatusUnknown = "Unknown"
 )
 
 var (
 	ErrServiceNotFound = errors.New("not found")
+	ErrJobNotFound     = errors.New("not found")
 )
 
 type Service run.Service
 
+type Revision run.Revision
+
 type Client interface {
 	Create(ctx context.Context, sm ServiceManifest) (*Service, error)
 	Update(ctx context.Context, sm ServiceManifest) (*Service, error)
+	GetService(ctx context.Context, serviceName string) (*Service, error)
+	ListServices(ctx context.Context, labelSelector string) ([]*Service, error)
+	ListRevisions(ctx context.Context, serviceName string) ([]*Revision, error)
+
+	CreateJob(ctx context.Context, jm JobManifest) (*Job, error)
+	UpdateJob(ctx context.Context, jm JobManifest) (*Job, error)
+	RunJob(ctx context.Context, name string) (*Execution, error)
+	GetExecution(ctx context.Context, name string) (*Execution, error)
+	ListJobs(ctx context.Context, labelSelector string) ([]*Job, error)
+	ListExecutions(ctx context.Context, jobName string) ([]*Execution, error)
+	ListExecutionLogs(ctx context.Context, executionName string, since time.Time) ([]*LogEntry, error)
 }
 
 type Registry interface {
@@ -78,7 +112,7 @@ func (r *registry) Client(ctx context.Context, name string, cfg *config.CloudPro
 	}
 
 	c, err, _ := r.newGroup.Do(name, func() (interface{}, error) {
-		return newClient(ctx, cfg.Project, cfg.Region, cfg.CredentialsFile, logger)
+		return newClient(ctx, cfg.Project, cfg.Region, cfg.CredentialsFile, cfg.ImpersonateServiceAccount, cfg.ImpersonateDelegates, logger)
 	})
 	if err != nil {
 		return nil, err
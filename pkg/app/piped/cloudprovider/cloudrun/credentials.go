@@ -0,0 +1,104 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/iamcredentials/v1"
+	"google.golang.org/api/option"
+)
+
+const (
+	cloudPlatformScope        = "https://www.googleapis.com/auth/cloud-platform"
+	impersonatedTokenLifetime = "3600s"
+)
+
+// credentialsOptions returns the client options to authenticate the requests.
+// Without credentials file the ambient credentials such as GKE Workload Identity are used.
+// When a service account to impersonate was specified, the loaded credentials
+// are only used to issue the access tokens of that service account.
+func credentialsOptions(ctx context.Context, credentialsFile, serviceAccount string, delegates []string) ([]option.ClientOption, error) {
+	var options []option.ClientOption
+	if len(credentialsFile) > 0 {
+		data, err := ioutil.ReadFile(credentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read credentials file (%w)", err)
+		}
+		options = append(options, option.WithCredentialsJSON(data))
+	}
+	if serviceAccount == "" {
+		return options, nil
+	}
+
+	service, err := iamcredentials.NewService(ctx, append(options, option.WithScopes(cloudPlatformScope))...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create iam credentials client (%w)", err)
+	}
+	ts := newImpersonatedTokenSource(service, serviceAccount, delegates)
+	return []option.ClientOption{
+		option.WithTokenSource(oauth2.ReuseTokenSource(nil, ts)),
+	}, nil
+}
+
+// impersonatedTokenSource issues the access tokens of a service account
+// by calling the IAM Credentials API with the base credentials.
+type impersonatedTokenSource struct {
+	service   *iamcredentials.Service
+	name      string
+	delegates []string
+}
+
+func newImpersonatedTokenSource(service *iamcredentials.Service, serviceAccount string, delegates []string) *impersonatedTokenSource {
+	ts := &impersonatedTokenSource{
+		service: service,
+		name:    serviceAccountResourceName(serviceAccount),
+	}
+	for _, d := range delegates {
+		ts.delegates = append(ts.delegates, serviceAccountResourceName(d))
+	}
+	return ts
+}
+
+func (s *impersonatedTokenSource) Token() (*oauth2.Token, error) {
+	req := &iamcredentials.GenerateAccessTokenRequest{
+		Scope:     []string{cloudPlatformScope},
+		Delegates: s.delegates,
+		Lifetime:  impersonatedTokenLifetime,
+	}
+	// The token source is shared by the cached client
+	// so it must not be bound to the context of a specific request.
+	resp, err := s.service.Projects.ServiceAccounts.GenerateAccessToken(s.name, req).Context(context.Background()).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token of %s: %w", s.name, err)
+	}
+	expiry, err := time.Parse(time.RFC3339, resp.ExpireTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expire time of access token: %w", err)
+	}
+	return &oauth2.Token{
+		AccessToken: resp.AccessToken,
+		TokenType:   "Bearer",
+		Expiry:      expiry,
+	}, nil
+}
+
+func serviceAccountResourceName(email string) string {
+	return fmt.Sprintf("projects/-/serviceAccounts/%s", email)
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/iamcredentials/v1"
+	"google.golang.org/api/option"
+)
+
+func TestImpersonatedTokenSource(t *testing.T) {
+	var (
+		gotPath string
+		gotReq  iamcredentials.GenerateAccessTokenRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+		json.NewEncoder(w).Encode(iamcredentials.GenerateAccessTokenResponse{
+			AccessToken: "impersonated-token",
+			ExpireTime:  "2021-10-01T10:00:00Z",
+		})
+	}))
+	defer server.Close()
+
+	service, err := iamcredentials.NewService(context.Background(),
+		option.WithEndpoint(server.URL+"/"),
+		option.WithoutAuthentication(),
+	)
+	require.NoError(t, err)
+
+	ts := newImpersonatedTokenSource(service, "deployer@prod.iam.gserviceaccount.com", []string{"delegate@shared.iam.gserviceaccount.com"})
+	token, err := ts.Token()
+	require.NoError(t, err)
+
+	assert.Equal(t, "impersonated-token", token.AccessToken)
+	assert.Equal(t, time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC), token.Expiry.UTC())
+	assert.Equal(t, "/v1/projects/-/serviceAccounts/deployer@prod.iam.gserviceaccount.com:generateAccessToken", gotPath)
+	assert.Equal(t, []string{"projects/-/serviceAccounts/delegate@shared.iam.gserviceaccount.com"}, gotReq.Delegates)
+	assert.Equal(t, []string{cloudPlatformScope}, gotReq.Scope)
+}
@@ -0,0 +1,330 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/logging/v2"
+	"google.golang.org/api/run/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Job is a Cloud Run job.
+// The types of jobs and executions are defined here because
+// the version of the generated Cloud Run client in use does not support them yet.
+type Job struct {
+	APIVersion string          `json:"apiVersion,omitempty"`
+	Kind       string          `json:"kind,omitempty"`
+	Metadata   *run.ObjectMeta `json:"metadata,omitempty"`
+	Status     *JobStatus      `json:"status,omitempty"`
+}
+
+type JobStatus struct {
+	Conditions             []*run.GoogleCloudRunV1Condition `json:"conditions,omitempty"`
+	ExecutionCount         int64                            `json:"executionCount,omitempty"`
+	LatestCreatedExecution *ExecutionReference              `json:"latestCreatedExecution,omitempty"`
+}
+
+type ExecutionReference struct {
+	Name string `json:"name,omitempty"`
+}
+
+// Execution is a single run of a Cloud Run job.
+type Execution struct {
+	Metadata *run.ObjectMeta  `json:"metadata,omitempty"`
+	Spec     *ExecutionSpec   `json:"spec,omitempty"`
+	Status   *ExecutionStatus `json:"status,omitempty"`
+}
+
+type ExecutionSpec struct {
+	TaskCount int64             `json:"taskCount,omitempty"`
+	Template  *TaskTemplateSpec `json:"template,omitempty"`
+}
+
+type TaskTemplateSpec struct {
+	Spec *TaskSpec `json:"spec,omitempty"`
+}
+
+type TaskSpec struct {
+	Containers []*run.Container `json:"containers,omitempty"`
+}
+
+type ExecutionStatus struct {
+	Conditions     []*run.GoogleCloudRunV1Condition `json:"conditions,omitempty"`
+	StartTime      string                           `json:"startTime,omitempty"`
+	CompletionTime string                           `json:"completionTime,omitempty"`
+	RunningCount   int64                            `json:"runningCount,omitempty"`
+	SucceededCount int64                            `json:"succeededCount,omitempty"`
+	FailedCount    int64                            `json:"failedCount,omitempty"`
+	CancelledCount int64                            `json:"cancelledCount,omitempty"`
+	LogUri         string                           `json:"logUri,omitempty"`
+}
+
+// Image returns the container image the tasks of this execution are running.
+func (e *Execution) Image() string {
+	if e.Spec == nil || e.Spec.Template == nil || e.Spec.Template.Spec == nil || len(e.Spec.Template.Spec.Containers) == 0 {
+		return ""
+	}
+	return e.Spec.Template.Spec.Containers[0].Image
+}
+
+// Completed returns the completion condition of this execution.
+// False is returned while the execution is still running.
+func (e *Execution) Completed() (condition *run.GoogleCloudRunV1Condition, completed bool) {
+	if e.Status == nil {
+		return nil, false
+	}
+	for _, c := range e.Status.Conditions {
+		if c.Type == conditionCompleted && c.Status != conditionStatusUnknown {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// Succeeded returns true if this execution was completed successfully.
+func (e *Execution) Succeeded() bool {
+	c, ok := e.Completed()
+	return ok && c.Status == conditionStatusTrue
+}
+
+// LogEntry is a single log line written by the tasks of an execution.
+type LogEntry struct {
+	Timestamp time.Time
+	Message   string
+}
+
+type runObjectList struct {
+	Items    []json.RawMessage `json:"items"`
+	Metadata *run.ListMeta     `json:"metadata"`
+}
+
+func (c *client) CreateJob(ctx context.Context, jm JobManifest) (*Job, error) {
+	body, err := manifestToJSON(jm)
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := c.doRunRequest(ctx, http.MethodPost, c.jobsPath(""), nil, body, &job); err != nil {
+		if e, ok := err.(*googleapi.Error); ok {
+			return nil, fmt.Errorf("failed to create job: code=%d, message=%s, details=%s", e.Code, e.Message, e.Details)
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (c *client) UpdateJob(ctx context.Context, jm JobManifest) (*Job, error) {
+	body, err := manifestToJSON(jm)
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := c.doRunRequest(ctx, http.MethodPut, c.jobsPath(jm.Name), nil, body, &job); err != nil {
+		if e, ok := err.(*googleapi.Error); ok && e.Code == http.StatusNotFound {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (c *client) RunJob(ctx context.Context, name string) (*Execution, error) {
+	var execution Execution
+	if err := c.doRunRequest(ctx, http.MethodPost, c.jobsPath(name)+":run", nil, []byte("{}"), &execution); err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
+func (c *client) GetExecution(ctx context.Context, name string) (*Execution, error) {
+	var execution Execution
+	if err := c.doRunRequest(ctx, http.MethodGet, c.executionsPath(name), nil, nil, &execution); err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
+func (c *client) ListJobs(ctx context.Context, labelSelector string) ([]*Job, error) {
+	var jobs []*Job
+	err := c.listRunObjects(ctx, c.jobsPath(""), labelSelector, func(data json.RawMessage) error {
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			return err
+		}
+		jobs = append(jobs, &job)
+		return nil
+	})
+	return jobs, err
+}
+
+func (c *client) ListExecutions(ctx context.Context, jobName string) ([]*Execution, error) {
+	var (
+		executions []*Execution
+		selector   = fmt.Sprintf("%s=%s", jobNameLabel, jobName)
+	)
+	err := c.listRunObjects(ctx, c.executionsPath(""), selector, func(data json.RawMessage) error {
+		var execution Execution
+		if err := json.Unmarshal(data, &execution); err != nil {
+			return err
+		}
+		executions = append(executions, &execution)
+		return nil
+	})
+	return executions, err
+}
+
+// ListExecutionLogs returns the logs of the given execution written after the specified time
+// in chronological order.
+func (c *client) ListExecutionLogs(ctx context.Context, executionName string, since time.Time) ([]*LogEntry, error) {
+	var (
+		filter = fmt.Sprintf(`resource.type="cloud_run_job" AND labels."%s"="%s" AND timestamp>"%s"`,
+			executionNameLabel, executionName, since.UTC().Format(time.RFC3339Nano))
+		entries   []*LogEntry
+		nextToken string
+	)
+
+	for {
+		req := &logging.ListLogEntriesRequest{
+			ResourceNames: []string{fmt.Sprintf("projects/%s", c.projectID)},
+			Filter:        filter,
+			OrderBy:       "timestamp asc",
+			PageSize:      1000,
+			PageToken:     nextToken,
+		}
+		resp, err := c.logging.Entries.List(req).Context(ctx).Do()
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range resp.Entries {
+			entries = append(entries, makeLogEntry(e))
+		}
+
+		if resp.NextPageToken == "" {
+			return entries, nil
+		}
+		nextToken = resp.NextPageToken
+	}
+}
+
+func makeLogEntry(e *logging.LogEntry) *LogEntry {
+	entry := &LogEntry{
+		Message: e.TextPayload,
+	}
+	if t, err := time.Parse(time.RFC3339Nano, e.Timestamp); err == nil {
+		entry.Timestamp = t
+	}
+	if entry.Message == "" && len(e.JsonPayload) > 0 {
+		var payload struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(e.JsonPayload, &payload); err == nil && payload.Message != "" {
+			entry.Message = payload.Message
+		} else {
+			entry.Message = string(e.JsonPayload)
+		}
+	}
+	return entry
+}
+
+func (c *client) jobsPath(name string) string {
+	path := fmt.Sprintf("apis/run.googleapis.com/v1/namespaces/%s/jobs", c.projectID)
+	if name != "" {
+		path += "/" + name
+	}
+	return path
+}
+
+func (c *client) executionsPath(name string) string {
+	path := fmt.Sprintf("apis/run.googleapis.com/v1/namespaces/%s/executions", c.projectID)
+	if name != "" {
+		path += "/" + name
+	}
+	return path
+}
+
+func (c *client) listRunObjects(ctx context.Context, path, labelSelector string, f func(json.RawMessage) error) error {
+	var nextToken string
+	for {
+		query := url.Values{}
+		if labelSelector != "" {
+			query.Set("labelSelector", labelSelector)
+		}
+		if nextToken != "" {
+			query.Set("continue", nextToken)
+		}
+
+		var list runObjectList
+		if err := c.doRunRequest(ctx, http.MethodGet, path, query, nil, &list); err != nil {
+			return err
+		}
+		for _, item := range list.Items {
+			if err := f(item); err != nil {
+				return err
+			}
+		}
+
+		if list.Metadata == nil || list.Metadata.Continue == "" {
+			return nil
+		}
+		nextToken = list.Metadata.Continue
+	}
+}
+
+// doRunRequest sends a request to the Cloud Run Admin API and decodes the response into out.
+// The returned error is a *googleapi.Error when the API responded with an error.
+func (c *client) doRunRequest(ctx context.Context, method, path string, query url.Values, body []byte, out interface{}) error {
+	u := strings.TrimSuffix(c.endpoint, "/") + "/" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func manifestToJSON(jm JobManifest) ([]byte, error) {
+	data, err := jm.YamlBytes()
+	if err != nil {
+		return nil, err
+	}
+	return yaml.YAMLToJSON(data)
+}
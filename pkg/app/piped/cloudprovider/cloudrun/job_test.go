@@ -0,0 +1,146 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/run/v1"
+)
+
+const testJobManifest = `
+apiVersion: run.googleapis.com/v1
+kind: Job
+metadata:
+  name: migration
+spec:
+  template:
+    spec:
+      taskCount: 1
+      template:
+        spec:
+          containers:
+          - image: gcr.io/pipecd/migration:v0.1.0
+`
+
+func TestParseJobManifest(t *testing.T) {
+	jm, err := ParseJobManifest([]byte(testJobManifest))
+	require.NoError(t, err)
+	assert.Equal(t, "migration", jm.Name)
+
+	image, err := jm.FindImage()
+	require.NoError(t, err)
+	assert.Equal(t, "gcr.io/pipecd/migration:v0.1.0", image)
+
+	_, err = ParseJobManifest([]byte(`
+apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: helloworld
+`))
+	assert.Error(t, err)
+}
+
+func TestJobClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apis/run.googleapis.com/v1/namespaces/project/jobs/migration", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":{"code":404,"message":"not found"}}`)
+	})
+	mux.HandleFunc("/apis/run.googleapis.com/v1/namespaces/project/jobs/migration:run", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		fmt.Fprint(w, `{"metadata":{"name":"migration-abcde"}}`)
+	})
+	mux.HandleFunc("/apis/run.googleapis.com/v1/namespaces/project/executions", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "run.googleapis.com/job=migration", r.URL.Query().Get("labelSelector"))
+		if r.URL.Query().Get("continue") == "" {
+			fmt.Fprint(w, `{"items":[{"metadata":{"name":"migration-1"}}],"metadata":{"continue":"next"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"items":[{"metadata":{"name":"migration-2"}}]}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := &client{
+		projectID:  "project",
+		httpClient: server.Client(),
+		endpoint:   server.URL + "/",
+	}
+	ctx := context.Background()
+
+	jm, err := ParseJobManifest([]byte(testJobManifest))
+	require.NoError(t, err)
+	_, err = c.UpdateJob(ctx, jm)
+	assert.Equal(t, ErrJobNotFound, err)
+
+	execution, err := c.RunJob(ctx, "migration")
+	require.NoError(t, err)
+	assert.Equal(t, "migration-abcde", execution.Metadata.Name)
+
+	executions, err := c.ListExecutions(ctx, "migration")
+	require.NoError(t, err)
+	require.Len(t, executions, 2)
+	assert.Equal(t, "migration-1", executions[0].Metadata.Name)
+	assert.Equal(t, "migration-2", executions[1].Metadata.Name)
+}
+
+func TestExecutionCompleted(t *testing.T) {
+	testcases := []struct {
+		name              string
+		conditions        []*run.GoogleCloudRunV1Condition
+		expectedCompleted bool
+		expectedSucceeded bool
+	}{
+		{
+			name: "running",
+			conditions: []*run.GoogleCloudRunV1Condition{
+				{Type: "Completed", Status: "Unknown"},
+			},
+		},
+		{
+			name: "succeeded",
+			conditions: []*run.GoogleCloudRunV1Condition{
+				{Type: "Started", Status: "True"},
+				{Type: "Completed", Status: "True"},
+			},
+			expectedCompleted: true,
+			expectedSucceeded: true,
+		},
+		{
+			name: "failed",
+			conditions: []*run.GoogleCloudRunV1Condition{
+				{Type: "Completed", Status: "False", Message: "task failed"},
+			},
+			expectedCompleted: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &Execution{
+				Status: &ExecutionStatus{Conditions: tc.conditions},
+			}
+			_, completed := e.Completed()
+			assert.Equal(t, tc.expectedCompleted, completed)
+			assert.Equal(t, tc.expectedSucceeded, e.Succeeded())
+		})
+	}
+}
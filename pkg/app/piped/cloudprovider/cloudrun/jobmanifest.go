@@ -0,0 +1,109 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+const jobKind = "Job"
+
+type JobManifest struct {
+	Name string
+	u    *unstructured.Unstructured
+}
+
+// AddLabels adds the given labels to the job's metadata.
+// The existing labels with the same keys will be overwritten.
+func (m JobManifest) AddLabels(labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+
+	current := m.u.GetLabels()
+	if current == nil {
+		current = make(map[string]string, len(labels))
+	}
+	for k, v := range labels {
+		current[k] = v
+	}
+	m.u.SetLabels(current)
+}
+
+// FindImage returns the image of the first container of the job's tasks.
+func (m JobManifest) FindImage() (string, error) {
+	containers, ok, err := unstructured.NestedSlice(m.u.Object, "spec", "template", "spec", "template", "spec", "containers")
+	if err != nil {
+		return "", err
+	}
+	if !ok || len(containers) == 0 {
+		return "", fmt.Errorf("spec.template.spec.template.spec.containers was missing")
+	}
+
+	container, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&containers[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid container format")
+	}
+
+	image, ok, err := unstructured.NestedString(container, "image")
+	if err != nil {
+		return "", err
+	}
+	if !ok || image == "" {
+		return "", fmt.Errorf("image was missing")
+	}
+	return image, nil
+}
+
+func (m JobManifest) YamlBytes() ([]byte, error) {
+	return yaml.Marshal(m.u)
+}
+
+func LoadJobManifest(appDir, jobFilename string) (JobManifest, error) {
+	if jobFilename == "" {
+		jobFilename = DefaultJobManifestFilename
+	}
+	path := filepath.Join(appDir, jobFilename)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return JobManifest{}, err
+	}
+	return ParseJobManifest(data)
+}
+
+func ParseJobManifest(data []byte) (JobManifest, error) {
+	var obj unstructured.Unstructured
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return JobManifest{}, err
+	}
+	if obj.GetKind() != jobKind {
+		return JobManifest{}, fmt.Errorf("unexpected kind %q, expected %q", obj.GetKind(), jobKind)
+	}
+	if obj.GetName() == "" {
+		return JobManifest{}, fmt.Errorf("metadata.name was missing")
+	}
+
+	return JobManifest{
+		Name: obj.GetName(),
+		u:    &obj,
+	}, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// ServiceManifestFromService builds the manifest of the given live service
+// to update it while keeping its current configuration.
+func ServiceManifestFromService(svc *Service) (ServiceManifest, error) {
+	data, err := json.Marshal(svc)
+	if err != nil {
+		return ServiceManifest{}, err
+	}
+	sm, err := ParseServiceManifest(data)
+	if err != nil {
+		return ServiceManifest{}, err
+	}
+	delete(sm.u.Object, "status")
+	return sm, nil
+}
+
+// UpdateRevisionTags returns the traffic targets where the given tags are assigned to the revisions
+// and the tags to remove are removed. The traffic percentages are kept unchanged.
+// The assign is a map from tag to the name of revision the tag points to.
+func UpdateRevisionTags(traffics []RevisionTraffic, assign map[string]string, remove []string) []RevisionTraffic {
+	drop := make(map[string]struct{}, len(assign)+len(remove))
+	for tag := range assign {
+		drop[tag] = struct{}{}
+	}
+	for _, tag := range remove {
+		drop[tag] = struct{}{}
+	}
+
+	out := make([]RevisionTraffic, 0, len(traffics)+len(assign))
+	for _, t := range traffics {
+		if _, ok := drop[t.Tag]; ok {
+			t.Tag = ""
+		}
+		// The target only for the tag is no longer needed.
+		if t.Tag == "" && t.Percent == 0 {
+			continue
+		}
+		out = append(out, t)
+	}
+
+	tags := make([]string, 0, len(assign))
+	for tag := range assign {
+		tags = append(tags, tag)
+	}
+	// Sort to make the order of targets stable.
+	sort.Strings(tags)
+	for _, tag := range tags {
+		out = append(out, RevisionTraffic{
+			RevisionName: assign[tag],
+			Tag:          tag,
+		})
+	}
+	return out
+}
+
+// TaggedURL returns the URL to access the revision having the given tag.
+// Cloud Run serves the tagged revisions at the subdomain prefixed by the tag.
+func TaggedURL(serviceURL, tag string) string {
+	const scheme = "https://"
+	if !strings.HasPrefix(serviceURL, scheme) {
+		return ""
+	}
+	return scheme + tag + "---" + strings.TrimPrefix(serviceURL, scheme)
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/run/v1"
+)
+
+func TestUpdateRevisionTags(t *testing.T) {
+	testcases := []struct {
+		name     string
+		traffics []RevisionTraffic
+		assign   map[string]string
+		remove   []string
+		expected []RevisionTraffic
+	}{
+		{
+			name: "assign new tag",
+			traffics: []RevisionTraffic{
+				{RevisionName: "helloworld-v010", Percent: 100},
+			},
+			assign: map[string]string{"preview": "helloworld-v020"},
+			expected: []RevisionTraffic{
+				{RevisionName: "helloworld-v010", Percent: 100},
+				{RevisionName: "helloworld-v020", Tag: "preview"},
+			},
+		},
+		{
+			name: "move tag to another revision",
+			traffics: []RevisionTraffic{
+				{RevisionName: "helloworld-v010", Percent: 100, Tag: "preview"},
+				{RevisionName: "helloworld-v005", Tag: "stable"},
+			},
+			assign: map[string]string{"preview": "helloworld-v020"},
+			expected: []RevisionTraffic{
+				{RevisionName: "helloworld-v010", Percent: 100},
+				{RevisionName: "helloworld-v005", Tag: "stable"},
+				{RevisionName: "helloworld-v020", Tag: "preview"},
+			},
+		},
+		{
+			name: "remove tags",
+			traffics: []RevisionTraffic{
+				{RevisionName: "helloworld-v010", Percent: 90, Tag: "stable"},
+				{RevisionName: "helloworld-v020", Percent: 10},
+				{RevisionName: "helloworld-v020", Tag: "candidate"},
+			},
+			remove: []string{"stable", "candidate"},
+			expected: []RevisionTraffic{
+				{RevisionName: "helloworld-v010", Percent: 90},
+				{RevisionName: "helloworld-v020", Percent: 10},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := UpdateRevisionTags(tc.traffics, tc.assign, tc.remove)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestServiceManifestFromService(t *testing.T) {
+	svc := &Service{
+		ApiVersion: "serving.knative.dev/v1",
+		Kind:       "Service",
+		Metadata:   &run.ObjectMeta{Name: "helloworld"},
+		Spec: &run.ServiceSpec{
+			Traffic: []*run.TrafficTarget{
+				{RevisionName: "helloworld-v010", Percent: 100},
+				{RevisionName: "helloworld-v020", Tag: "preview"},
+			},
+		},
+		Status: &run.ServiceStatus{Url: "https://helloworld-abcdefg-an.a.run.app"},
+	}
+	sm, err := ServiceManifestFromService(svc)
+	require.NoError(t, err)
+	assert.Equal(t, "helloworld", sm.Name)
+	_, ok := sm.u.Object["status"]
+	assert.False(t, ok)
+
+	traffics, err := sm.Traffic()
+	require.NoError(t, err)
+	expected := []RevisionTraffic{
+		{RevisionName: "helloworld-v010", Percent: 100},
+		{RevisionName: "helloworld-v020", Tag: "preview"},
+	}
+	assert.Equal(t, expected, traffics)
+}
+
+func TestTaggedURL(t *testing.T) {
+	assert.Equal(t, "https://preview---helloworld-abcdefg-an.a.run.app", TaggedURL("https://helloworld-abcdefg-an.a.run.app", "preview"))
+	assert.Equal(t, "", TaggedURL("", "preview"))
+}
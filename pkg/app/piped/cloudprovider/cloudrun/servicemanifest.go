@@ -34,8 +34,10 @@ func (m ServiceManifest) SetRevision(name string) error {
 }
 
 type RevisionTraffic struct {
-	RevisionName string `json:"revisionName"`
-	Percent      int    `json:"percent"`
+	RevisionName   string `json:"revisionName,omitempty"`
+	Percent        int    `json:"percent"`
+	Tag            string `json:"tag,omitempty"`
+	LatestRevision bool   `json:"latestRevision,omitempty"`
 }
 
 func (m ServiceManifest) UpdateTraffic(revisions []RevisionTraffic) error {
@@ -51,6 +53,27 @@ func (m ServiceManifest) UpdateTraffic(revisions []RevisionTraffic) error {
 	return unstructured.SetNestedSlice(m.u.Object, items, "spec", "traffic")
 }
 
+// Traffic returns the traffic targets specified in the service manifest.
+func (m ServiceManifest) Traffic() ([]RevisionTraffic, error) {
+	items, _, err := unstructured.NestedSlice(m.u.Object, "spec", "traffic")
+	if err != nil {
+		return nil, err
+	}
+	traffics := make([]RevisionTraffic, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid traffic format")
+		}
+		var t RevisionTraffic
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, &t); err != nil {
+			return nil, fmt.Errorf("invalid traffic format: %w", err)
+		}
+		traffics = append(traffics, t)
+	}
+	return traffics, nil
+}
+
 func (m ServiceManifest) UpdateAllTraffic(revision string) error {
 	return m.UpdateTraffic([]RevisionTraffic{
 		{
@@ -60,6 +83,23 @@ func (m ServiceManifest) UpdateAllTraffic(revision string) error {
 	})
 }
 
+// AddLabels adds the given labels to the service's metadata.
+// The existing labels with the same keys will be overwritten.
+func (m ServiceManifest) AddLabels(labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+
+	current := m.u.GetLabels()
+	if current == nil {
+		current = make(map[string]string, len(labels))
+	}
+	for k, v := range labels {
+		current[k] = v
+	}
+	m.u.SetLabels(current)
+}
+
 func (m ServiceManifest) YamlBytes() ([]byte, error) {
 	return yaml.Marshal(m.u)
 }
@@ -13,3 +13,41 @@
 // limitations under the License.
 
 package cloudrun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceManifestAddLabels(t *testing.T) {
+	const data = `
+apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: helloworld
+  labels:
+    cloud.googleapis.com/location: asia-northeast1
+    pipecd-dev-application: old
+spec:
+  template:
+    spec:
+      containers:
+      - image: gcr.io/pipecd/helloworld:v0.1.0
+`
+	sm, err := ParseServiceManifest([]byte(data))
+	require.NoError(t, err)
+
+	sm.AddLabels(map[string]string{
+		LabelManagedBy:   ManagedByPiped,
+		LabelApplication: "app-id",
+	})
+
+	expected := map[string]string{
+		"cloud.googleapis.com/location": "asia-northeast1",
+		"pipecd-dev-managed-by":         "piped",
+		"pipecd-dev-application":        "app-id",
+	}
+	assert.Equal(t, expected, sm.u.GetLabels())
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
 	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider"
@@ -37,7 +38,7 @@ type client struct {
 	logger    *zap.Logger
 }
 
-func newClient(region, profile, credentialsFile, roleARN, tokenPath string, logger *zap.Logger) (Client, error) {
+func newClient(region, profile, credentialsFile, roleARN, tokenPath, assumeRoleARN, externalID string, logger *zap.Logger) (Client, error) {
 	if region == "" {
 		return nil, fmt.Errorf("region is required field")
 	}
@@ -64,6 +65,16 @@ func newClient(region, profile, credentialsFile, roleARN, tokenPath string, logg
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config to create ecs client: %w", err)
 	}
+	if assumeRoleARN != "" {
+		// Chain the loaded credentials to assume the target role,
+		// e.g. from the IRSA role of piped to a role in another account.
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), assumeRoleARN, func(o *stscreds.AssumeRoleOptions) {
+			if externalID != "" {
+				o.ExternalID = aws.String(externalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
 	c.ecsClient = ecs.NewFromConfig(cfg)
 	c.elbClient = elasticloadbalancingv2.NewFromConfig(cfg)
 
@@ -283,29 +294,50 @@ func (c *client) getLoadBalancerArn(ctx context.Context, targetGroupArn string)
 }
 
 func (c *client) ModifyListener(ctx context.Context, listenerArn string, routingTrafficCfg RoutingTrafficConfig) error {
-	if len(routingTrafficCfg) != 2 {
-		return fmt.Errorf("invalid listener configuration: requires 2 target groups")
+	actions, err := forwardActions(routingTrafficCfg)
+	if err != nil {
+		return err
 	}
 	input := &elasticloadbalancingv2.ModifyListenerInput{
-		ListenerArn: aws.String(listenerArn),
-		DefaultActions: []elbtypes.Action{
-			{
-				Type: elbtypes.ActionTypeEnumForward,
-				ForwardConfig: &elbtypes.ForwardActionConfig{
-					TargetGroups: []elbtypes.TargetGroupTuple{
-						{
-							TargetGroupArn: aws.String(routingTrafficCfg[0].TargetGroupArn),
-							Weight:         aws.Int32(int32(routingTrafficCfg[0].Weight)),
-						},
-						{
-							TargetGroupArn: aws.String(routingTrafficCfg[1].TargetGroupArn),
-							Weight:         aws.Int32(int32(routingTrafficCfg[1].Weight)),
-						},
+		ListenerArn:    aws.String(listenerArn),
+		DefaultActions: actions,
+	}
+	_, err = c.elbClient.ModifyListener(ctx, input)
+	return err
+}
+
+func (c *client) ModifyListenerRule(ctx context.Context, ruleArn string, routingTrafficCfg RoutingTrafficConfig) error {
+	actions, err := forwardActions(routingTrafficCfg)
+	if err != nil {
+		return err
+	}
+	input := &elasticloadbalancingv2.ModifyRuleInput{
+		RuleArn: aws.String(ruleArn),
+		Actions: actions,
+	}
+	_, err = c.elbClient.ModifyRule(ctx, input)
+	return err
+}
+
+func forwardActions(routingTrafficCfg RoutingTrafficConfig) ([]elbtypes.Action, error) {
+	if len(routingTrafficCfg) != 2 {
+		return nil, fmt.Errorf("invalid listener configuration: requires 2 target groups")
+	}
+	return []elbtypes.Action{
+		{
+			Type: elbtypes.ActionTypeEnumForward,
+			ForwardConfig: &elbtypes.ForwardActionConfig{
+				TargetGroups: []elbtypes.TargetGroupTuple{
+					{
+						TargetGroupArn: aws.String(routingTrafficCfg[0].TargetGroupArn),
+						Weight:         aws.Int32(int32(routingTrafficCfg[0].Weight)),
+					},
+					{
+						TargetGroupArn: aws.String(routingTrafficCfg[1].TargetGroupArn),
+						Weight:         aws.Int32(int32(routingTrafficCfg[1].Weight)),
 					},
 				},
 			},
 		},
-	}
-	_, err := c.elbClient.ModifyListener(ctx, input)
-	return err
+	}, nil
 }
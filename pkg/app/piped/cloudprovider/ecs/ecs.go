@@ -46,6 +46,7 @@ type ECS interface {
 type ELB interface {
 	GetListener(ctx context.Context, targetGroup types.LoadBalancer) (string, error)
 	ModifyListener(ctx context.Context, listenerArn string, routingTrafficCfg RoutingTrafficConfig) error
+	ModifyListenerRule(ctx context.Context, ruleArn string, routingTrafficCfg RoutingTrafficConfig) error
 }
 
 // Registry holds a pool of aws client wrappers.
@@ -85,7 +86,7 @@ func (r *registry) Client(name string, cfg *config.CloudProviderECSConfig, logge
 	}
 
 	c, err, _ := r.newGroup.Do(name, func() (interface{}, error) {
-		return newClient(cfg.Region, cfg.Profile, cfg.CredentialsFile, cfg.RoleARN, cfg.TokenFile, logger)
+		return newClient(cfg.Region, cfg.Profile, cfg.CredentialsFile, cfg.RoleARN, cfg.TokenFile, cfg.AssumeRoleARN, cfg.AssumeRoleExternalID, logger)
 	})
 	if err != nil {
 		return nil, err
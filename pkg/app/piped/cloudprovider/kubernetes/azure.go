@@ -0,0 +1,138 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	// The well-known application ID of the Azure Kubernetes Service AAD server.
+	aksServerID = "6dae42f8-4368-4678-94ff-3960e28e3630"
+	// The exec credential plugin used to authenticate to AKS clusters.
+	kubeloginCommand = "kubelogin"
+)
+
+var (
+	// Cache of the generated kubeconfig files keyed by the original file, context and credentials.
+	azureKubeConfigs   = make(map[string]string)
+	azureKubeConfigsMu sync.Mutex
+)
+
+// resolveCluster returns the configuration whose kubeconfig file should be used to connect to the cluster.
+// When Azure credentials are specified, a kubeconfig file authenticating via kubelogin
+// with those credentials is generated from the given one.
+func resolveCluster(cfg *config.CloudProviderKubernetesConfig) (*config.CloudProviderKubernetesConfig, error) {
+	if cfg == nil || !cfg.Azure.IsEnabled() {
+		return cfg, nil
+	}
+
+	key := fmt.Sprintf("%s/%s/%s/%s/%s/%s", cfg.KubeConfigPath, cfg.KubeConfigContext, cfg.Azure.Type, cfg.Azure.TenantID, cfg.Azure.ClientID, cfg.Azure.ClientSecretFile)
+	azureKubeConfigsMu.Lock()
+	defer azureKubeConfigsMu.Unlock()
+
+	path, ok := azureKubeConfigs[key]
+	if !ok {
+		var err error
+		if path, err = generateAzureKubeConfig(cfg); err != nil {
+			return nil, err
+		}
+		azureKubeConfigs[key] = path
+	}
+
+	resolved := *cfg
+	resolved.KubeConfigPath = path
+	return &resolved, nil
+}
+
+func generateAzureKubeConfig(cfg *config.CloudProviderKubernetesConfig) (string, error) {
+	kc, err := clientcmd.LoadFromFile(cfg.KubeConfigPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load kubeconfig file %s: %w", cfg.KubeConfigPath, err)
+	}
+	name := cfg.KubeConfigContext
+	if name == "" {
+		name = kc.CurrentContext
+	}
+	ctx, ok := kc.Contexts[name]
+	if !ok {
+		return "", fmt.Errorf("context %q was not found in kubeconfig file %s", name, cfg.KubeConfigPath)
+	}
+
+	exec, err := azureExecConfig(cfg.Azure)
+	if err != nil {
+		return "", err
+	}
+	// A dedicated user is added to keep the other contexts sharing the original user unchanged.
+	user := ctx.AuthInfo + "-azure"
+	kc.AuthInfos[user] = &clientcmdapi.AuthInfo{Exec: exec}
+	ctx.AuthInfo = user
+
+	dir, err := ioutil.TempDir("", "kubeconfig-azure")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a temporary directory for kubeconfig: %w", err)
+	}
+	path := filepath.Join(dir, "kubeconfig")
+	if err := clientcmd.WriteToFile(*kc, path); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to write kubeconfig file: %w", err)
+	}
+	return path, nil
+}
+
+func azureExecConfig(creds config.AzureCredentials) (*clientcmdapi.ExecConfig, error) {
+	exec := &clientcmdapi.ExecConfig{
+		APIVersion: "client.authentication.k8s.io/v1beta1",
+		Command:    kubeloginCommand,
+	}
+	switch creds.Type {
+	case config.AzureCredentialsManagedIdentity:
+		exec.Args = []string{"get-token", "--login", "msi", "--server-id", aksServerID}
+		if creds.ClientID != "" {
+			exec.Args = append(exec.Args, "--client-id", creds.ClientID)
+		}
+
+	case config.AzureCredentialsServicePrincipal:
+		secret, err := ioutil.ReadFile(creds.ClientSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client secret file: %w", err)
+		}
+		exec.Args = []string{
+			"get-token",
+			"--login", "spn",
+			"--environment", "AzurePublicCloud",
+			"--tenant-id", creds.TenantID,
+			"--server-id", aksServerID,
+		}
+		exec.Env = []clientcmdapi.ExecEnvVar{
+			{Name: "AAD_SERVICE_PRINCIPAL_CLIENT_ID", Value: creds.ClientID},
+			{Name: "AAD_SERVICE_PRINCIPAL_CLIENT_SECRET", Value: strings.TrimSpace(string(secret))},
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported azure credentials type: %s", creds.Type)
+	}
+	return exec, nil
+}
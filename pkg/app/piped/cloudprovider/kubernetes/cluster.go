@@ -0,0 +1,152 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// BuildRESTConfig builds the client configuration to connect to the cluster of the given cloud provider.
+// The in-cluster configuration is used when neither masterURL nor kubeConfigPath was specified.
+// The exec credential plugins such as "aws eks get-token" or "gke-gcloud-auth-plugin"
+// configured in the kubeconfig file are supported.
+func BuildRESTConfig(cfg *config.CloudProviderKubernetesConfig) (*rest.Config, error) {
+	cfg, err := resolveCluster(cfg)
+	if err != nil {
+		return nil, err
+	}
+	restCfg, err := buildRESTConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.QPS > 0 {
+		restCfg.QPS = cfg.RateLimit.QPS
+	}
+	if cfg.RateLimit.Burst > 0 {
+		restCfg.Burst = cfg.RateLimit.Burst
+	}
+	return restCfg, nil
+}
+
+func buildRESTConfig(cfg *config.CloudProviderKubernetesConfig) (*rest.Config, error) {
+	if cfg.KubeConfigContext == "" {
+		return clientcmd.BuildConfigFromFlags(cfg.MasterURL, cfg.KubeConfigPath)
+	}
+	rules := &clientcmd.ClientConfigLoadingRules{
+		ExplicitPath: cfg.KubeConfigPath,
+	}
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: cfg.KubeConfigContext,
+		ClusterInfo: clientcmdapi.Cluster{
+			Server: cfg.MasterURL,
+		},
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+}
+
+// FindExecPlugin returns the command of the exec credential plugin
+// used to authenticate to the cluster of the given cloud provider.
+// Empty is returned if the kubeconfig file does not configure any exec plugin.
+func FindExecPlugin(cfg *config.CloudProviderKubernetesConfig) (string, error) {
+	cfg, err := resolveCluster(cfg)
+	if err != nil {
+		return "", err
+	}
+	if cfg.KubeConfigPath == "" {
+		return "", nil
+	}
+	kc, err := clientcmd.LoadFromFile(cfg.KubeConfigPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load kubeconfig file %s: %w", cfg.KubeConfigPath, err)
+	}
+
+	name := cfg.KubeConfigContext
+	if name == "" {
+		name = kc.CurrentContext
+	}
+	ctx, ok := kc.Contexts[name]
+	if !ok {
+		return "", fmt.Errorf("context %q was not found in kubeconfig file %s", name, cfg.KubeConfigPath)
+	}
+	auth, ok := kc.AuthInfos[ctx.AuthInfo]
+	if !ok || auth.Exec == nil {
+		return "", nil
+	}
+	return auth.Exec.Command, nil
+}
+
+// kubectlClusterFlags returns the global flags of kubectl
+// to connect to the cluster of the given cloud provider.
+// Nil means kubectl uses its default configuration, e.g. the in-cluster one.
+func kubectlClusterFlags(cfg *config.CloudProviderKubernetesConfig) ([]string, error) {
+	cfg, err := resolveCluster(cfg)
+	if err != nil || cfg == nil {
+		return nil, err
+	}
+	var flags []string
+	if cfg.KubeConfigPath != "" {
+		flags = append(flags, "--kubeconfig", cfg.KubeConfigPath)
+	}
+	if cfg.KubeConfigContext != "" {
+		flags = append(flags, "--context", cfg.KubeConfigContext)
+	}
+	if cfg.MasterURL != "" {
+		flags = append(flags, "--server", cfg.MasterURL)
+	}
+	return flags, nil
+}
+
+// helmClusterFlags returns the global flags of helm
+// to connect to the cluster of the given cloud provider.
+// Nil means helm uses its default configuration, e.g. the in-cluster one.
+func helmClusterFlags(cfg *config.CloudProviderKubernetesConfig) ([]string, error) {
+	cfg, err := resolveCluster(cfg)
+	if err != nil || cfg == nil {
+		return nil, err
+	}
+	var flags []string
+	if cfg.KubeConfigPath != "" {
+		flags = append(flags, "--kubeconfig", cfg.KubeConfigPath)
+	}
+	if cfg.KubeConfigContext != "" {
+		flags = append(flags, "--kube-context", cfg.KubeConfigContext)
+	}
+	if cfg.MasterURL != "" {
+		flags = append(flags, "--kube-apiserver", cfg.MasterURL)
+	}
+	return flags, nil
+}
+
+// ResolveToolVersions returns the given input whose kubectl and helm versions
+// were filled by the ones configured for the given cluster when not specified by the application.
+// The tools of the resolved versions are downloaded by piped when they were not installed yet.
+func ResolveToolVersions(input config.KubernetesDeploymentInput, cfg *config.CloudProviderKubernetesConfig) config.KubernetesDeploymentInput {
+	if cfg == nil {
+		return input
+	}
+	if input.KubectlVersion == "" {
+		input.KubectlVersion = cfg.KubectlVersion
+	}
+	if input.HelmVersion == "" {
+		input.HelmVersion = cfg.HelmVersion
+	}
+	return input
+}
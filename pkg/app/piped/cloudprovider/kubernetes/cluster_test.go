@@ -0,0 +1,268 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const testKubeConfigPath = "testdata/kubeconfig.yaml"
+
+func TestBuildRESTConfig(t *testing.T) {
+	testcases := []struct {
+		name         string
+		cfg          *config.CloudProviderKubernetesConfig
+		expectedHost string
+		expectedExec string
+	}{
+		{
+			name: "current context",
+			cfg: &config.CloudProviderKubernetesConfig{
+				KubeConfigPath: testKubeConfigPath,
+			},
+			expectedHost: "https://127.0.0.1:6443",
+		},
+		{
+			name: "specified context with exec plugin",
+			cfg: &config.CloudProviderKubernetesConfig{
+				KubeConfigPath:    testKubeConfigPath,
+				KubeConfigContext: "eks",
+			},
+			expectedHost: "https://ABCDEF.gr7.ap-northeast-1.eks.amazonaws.com",
+			expectedExec: "aws",
+		},
+		{
+			name: "master url overrides the server of context",
+			cfg: &config.CloudProviderKubernetesConfig{
+				MasterURL:         "https://10.0.0.1",
+				KubeConfigPath:    testKubeConfigPath,
+				KubeConfigContext: "gke",
+			},
+			expectedHost: "https://10.0.0.1",
+			expectedExec: "gke-gcloud-auth-plugin",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := BuildRESTConfig(tc.cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedHost, cfg.Host)
+			if tc.expectedExec == "" {
+				assert.Nil(t, cfg.ExecProvider)
+				return
+			}
+			require.NotNil(t, cfg.ExecProvider)
+			assert.Equal(t, tc.expectedExec, cfg.ExecProvider.Command)
+		})
+	}
+}
+
+func TestFindExecPlugin(t *testing.T) {
+	testcases := []struct {
+		name        string
+		cfg         *config.CloudProviderKubernetesConfig
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name:     "in-cluster",
+			cfg:      &config.CloudProviderKubernetesConfig{},
+			expected: "",
+		},
+		{
+			name: "no exec plugin",
+			cfg: &config.CloudProviderKubernetesConfig{
+				KubeConfigPath: testKubeConfigPath,
+			},
+			expected: "",
+		},
+		{
+			name: "exec plugin",
+			cfg: &config.CloudProviderKubernetesConfig{
+				KubeConfigPath:    testKubeConfigPath,
+				KubeConfigContext: "gke",
+			},
+			expected: "gke-gcloud-auth-plugin",
+		},
+		{
+			name: "unknown context",
+			cfg: &config.CloudProviderKubernetesConfig{
+				KubeConfigPath:    testKubeConfigPath,
+				KubeConfigContext: "unknown",
+			},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := FindExecPlugin(tc.cfg)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestKubectlClusterFlags(t *testing.T) {
+	flags, err := kubectlClusterFlags(nil)
+	require.NoError(t, err)
+	assert.Nil(t, flags)
+
+	flags, err = kubectlClusterFlags(&config.CloudProviderKubernetesConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, flags)
+
+	flags, err = kubectlClusterFlags(&config.CloudProviderKubernetesConfig{
+		MasterURL:         "https://10.0.0.1",
+		KubeConfigPath:    "/etc/piped/kubeconfig",
+		KubeConfigContext: "prod",
+	})
+	require.NoError(t, err)
+	expected := []string{"--kubeconfig", "/etc/piped/kubeconfig", "--context", "prod", "--server", "https://10.0.0.1"}
+	assert.Equal(t, expected, flags)
+}
+
+func TestHelmClusterFlags(t *testing.T) {
+	flags, err := helmClusterFlags(nil)
+	require.NoError(t, err)
+	assert.Nil(t, flags)
+
+	flags, err = helmClusterFlags(&config.CloudProviderKubernetesConfig{
+		MasterURL:         "https://10.0.0.1",
+		KubeConfigPath:    "/etc/piped/kubeconfig",
+		KubeConfigContext: "prod",
+	})
+	require.NoError(t, err)
+	expected := []string{"--kubeconfig", "/etc/piped/kubeconfig", "--kube-context", "prod", "--kube-apiserver", "https://10.0.0.1"}
+	assert.Equal(t, expected, flags)
+}
+
+func TestAzureCluster(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, ioutil.WriteFile(secretFile, []byte("client-secret\n"), 0600))
+
+	testcases := []struct {
+		name         string
+		creds        config.AzureCredentials
+		expectedArgs []string
+		expectedEnv  []clientcmdapi.ExecEnvVar
+	}{
+		{
+			name: "managed identity",
+			creds: config.AzureCredentials{
+				Type:     config.AzureCredentialsManagedIdentity,
+				ClientID: "identity-id",
+			},
+			expectedArgs: []string{"get-token", "--login", "msi", "--server-id", aksServerID, "--client-id", "identity-id"},
+		},
+		{
+			name: "service principal",
+			creds: config.AzureCredentials{
+				Type:             config.AzureCredentialsServicePrincipal,
+				TenantID:         "tenant-id",
+				ClientID:         "client-id",
+				ClientSecretFile: secretFile,
+			},
+			expectedArgs: []string{"get-token", "--login", "spn", "--environment", "AzurePublicCloud", "--tenant-id", "tenant-id", "--server-id", aksServerID},
+			expectedEnv: []clientcmdapi.ExecEnvVar{
+				{Name: "AAD_SERVICE_PRINCIPAL_CLIENT_ID", Value: "client-id"},
+				{Name: "AAD_SERVICE_PRINCIPAL_CLIENT_SECRET", Value: "client-secret"},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.CloudProviderKubernetesConfig{
+				KubeConfigPath:    testKubeConfigPath,
+				KubeConfigContext: "eks",
+				Azure:             tc.creds,
+			}
+			restCfg, err := BuildRESTConfig(cfg)
+			require.NoError(t, err)
+			assert.Equal(t, "https://ABCDEF.gr7.ap-northeast-1.eks.amazonaws.com", restCfg.Host)
+			require.NotNil(t, restCfg.ExecProvider)
+			assert.Equal(t, kubeloginCommand, restCfg.ExecProvider.Command)
+			assert.Equal(t, tc.expectedArgs, restCfg.ExecProvider.Args)
+			assert.Equal(t, tc.expectedEnv, restCfg.ExecProvider.Env)
+
+			plugin, err := FindExecPlugin(cfg)
+			require.NoError(t, err)
+			assert.Equal(t, kubeloginCommand, plugin)
+
+			// The generated kubeconfig file is reused.
+			flags, err := kubectlClusterFlags(cfg)
+			require.NoError(t, err)
+			require.Len(t, flags, 4)
+			assert.NotEqual(t, testKubeConfigPath, flags[1])
+			flags2, err := kubectlClusterFlags(cfg)
+			require.NoError(t, err)
+			assert.Equal(t, flags, flags2)
+		})
+	}
+}
+
+func TestResolveToolVersions(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    config.KubernetesDeploymentInput
+		cfg      *config.CloudProviderKubernetesConfig
+		expected config.KubernetesDeploymentInput
+	}{
+		{
+			name:     "no cluster",
+			input:    config.KubernetesDeploymentInput{HelmVersion: "3.5.0"},
+			expected: config.KubernetesDeploymentInput{HelmVersion: "3.5.0"},
+		},
+		{
+			name:  "use cluster versions",
+			input: config.KubernetesDeploymentInput{},
+			cfg: &config.CloudProviderKubernetesConfig{
+				KubectlVersion: "1.16.15",
+				HelmVersion:    "3.2.1",
+			},
+			expected: config.KubernetesDeploymentInput{
+				KubectlVersion: "1.16.15",
+				HelmVersion:    "3.2.1",
+			},
+		},
+		{
+			name: "application versions take precedence",
+			input: config.KubernetesDeploymentInput{
+				KubectlVersion: "1.18.2",
+			},
+			cfg: &config.CloudProviderKubernetesConfig{
+				KubectlVersion: "1.16.15",
+				HelmVersion:    "3.2.1",
+			},
+			expected: config.KubernetesDeploymentInput{
+				KubectlVersion: "1.18.2",
+				HelmVersion:    "3.2.1",
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ResolveToolVersions(tc.input, tc.cfg)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -68,7 +68,10 @@ func DiffList(olds, news []Manifest, opts ...diff.Option) (*DiffListResult, erro
 	return cr, nil
 }
 
-func (r *DiffListResult) DiffString() string {
+// DiffString renders the changes of all manifests.
+// The data of Secrets and ConfigMaps is always masked and
+// the given options can be used to mask the other values.
+func (r *DiffListResult) DiffString(opts ...diff.RenderOption) string {
 	var b strings.Builder
 	index := 0
 	for _, delete := range r.Deletes {
@@ -84,16 +87,16 @@ func (r *DiffListResult) DiffString() string {
 	var prints = 0
 	for _, change := range r.Changes {
 		key := change.Old.Key
-		opts := []diff.RenderOption{
+		renderOpts := []diff.RenderOption{
 			diff.WithLeftPadding(1),
 		}
 		switch {
 		case key.IsSecret():
-			opts = append(opts, diff.WithMaskPath("data"))
+			renderOpts = append(renderOpts, diff.WithMaskPath("data"), diff.WithMaskPath("stringData"))
 		case key.IsConfigMap():
-			opts = append(opts, diff.WithMaskPath("data"))
+			renderOpts = append(renderOpts, diff.WithMaskPath("data"))
 		}
-		renderer := diff.NewRenderer(opts...)
+		renderer := diff.NewRenderer(append(renderOpts, opts...)...)
 
 		index++
 		b.WriteString(fmt.Sprintf("# %d. %s\n\n", index, key.ReadableString()))
@@ -114,12 +117,16 @@ func (r *DiffListResult) DiffString() string {
 }
 
 func groupManifests(olds, news []Manifest) (adds, deletes, newChanges, oldChanges []Manifest) {
+	alignUnnamespacedKeys(olds, news)
+
 	// Sort the manifests before comparing.
+	// The namespace is also compared since the manifests of one application
+	// can be placed in multiple namespaces with the same name.
 	sort.Slice(news, func(i, j int) bool {
-		return news[i].Key.IsLessWithIgnoringNamespace(news[j].Key)
+		return news[i].Key.IsLess(news[j].Key)
 	})
 	sort.Slice(olds, func(i, j int) bool {
-		return olds[i].Key.IsLessWithIgnoringNamespace(olds[j].Key)
+		return olds[i].Key.IsLess(olds[j].Key)
 	})
 
 	var n, o int
@@ -127,7 +134,7 @@ func groupManifests(olds, news []Manifest) (adds, deletes, newChanges, oldChange
 		if n >= len(news) || o >= len(olds) {
 			break
 		}
-		if news[n].Key.IsEqualWithIgnoringNamespace(olds[o].Key) {
+		if news[n].Key == olds[o].Key {
 			newChanges = append(newChanges, news[n])
 			oldChanges = append(oldChanges, olds[o])
 			n++
@@ -135,7 +142,7 @@ func groupManifests(olds, news []Manifest) (adds, deletes, newChanges, oldChange
 			continue
 		}
 		// Has in news but not in olds so this should be a added one.
-		if news[n].Key.IsLessWithIgnoringNamespace(olds[o].Key) {
+		if news[n].Key.IsLess(olds[o].Key) {
 			adds = append(adds, news[n])
 			n++
 			continue
@@ -153,3 +160,37 @@ func groupManifests(olds, news []Manifest) (adds, deletes, newChanges, oldChange
 	}
 	return
 }
+
+// alignUnnamespacedKeys makes the manifests of news which are not specifying any namespace
+// use the same key with their counterparts of olds which are also not specifying any namespace.
+// That is required for the resources not belonging to any namespace, such as the cluster-scoped
+// custom resources, because they can be keyed with the application namespace on one side
+// but with the default namespace on the other side.
+func alignUnnamespacedKeys(olds, news []Manifest) {
+	var (
+		keys         = make(map[ResourceKey]struct{}, len(olds))
+		unnamespaced = make(map[ResourceKey]string)
+	)
+	for _, m := range olds {
+		keys[m.Key] = struct{}{}
+		if m.HasNamespace() {
+			continue
+		}
+		key := m.Key
+		key.Namespace = ""
+		unnamespaced[key] = m.Key.Namespace
+	}
+	for i := range news {
+		if news[i].HasNamespace() {
+			continue
+		}
+		if _, ok := keys[news[i].Key]; ok {
+			continue
+		}
+		key := news[i].Key
+		key.Namespace = ""
+		if namespace, ok := unnamespaced[key]; ok {
+			news[i].Key.Namespace = namespace
+		}
+	}
+}
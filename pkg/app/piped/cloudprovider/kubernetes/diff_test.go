@@ -18,6 +18,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 func TestGroupManifests(t *testing.T) {
@@ -101,6 +103,44 @@ func TestGroupManifests(t *testing.T) {
 				{Key: ResourceKey{Name: "b"}},
 			},
 		},
+		{
+			name: "multiple namespaces",
+			olds: []Manifest{
+				{Key: ResourceKey{Namespace: "y", Name: "a"}, u: makeNamespacedObject("y")},
+				{Key: ResourceKey{Namespace: "x", Name: "a"}, u: makeNamespacedObject("x")},
+			},
+			news: []Manifest{
+				{Key: ResourceKey{Namespace: "z", Name: "a"}, u: makeNamespacedObject("z")},
+				{Key: ResourceKey{Namespace: "y", Name: "a"}, u: makeNamespacedObject("y")},
+			},
+			expectedAdds: []Manifest{
+				{Key: ResourceKey{Namespace: "z", Name: "a"}, u: makeNamespacedObject("z")},
+			},
+			expectedDeletes: []Manifest{
+				{Key: ResourceKey{Namespace: "x", Name: "a"}, u: makeNamespacedObject("x")},
+			},
+			expectedNewChanges: []Manifest{
+				{Key: ResourceKey{Namespace: "y", Name: "a"}, u: makeNamespacedObject("y")},
+			},
+			expectedOldChanges: []Manifest{
+				{Key: ResourceKey{Namespace: "y", Name: "a"}, u: makeNamespacedObject("y")},
+			},
+		},
+		{
+			name: "not belonging to any namespace",
+			olds: []Manifest{
+				{Key: ResourceKey{Namespace: "app", Name: "a"}, u: makeNamespacedObject("")},
+			},
+			news: []Manifest{
+				{Key: ResourceKey{Namespace: DefaultNamespace, Name: "a"}, u: makeNamespacedObject("")},
+			},
+			expectedNewChanges: []Manifest{
+				{Key: ResourceKey{Namespace: "app", Name: "a"}, u: makeNamespacedObject("")},
+			},
+			expectedOldChanges: []Manifest{
+				{Key: ResourceKey{Namespace: "app", Name: "a"}, u: makeNamespacedObject("")},
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -113,3 +153,45 @@ func TestGroupManifests(t *testing.T) {
 		})
 	}
 }
+
+func makeNamespacedObject(namespace string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	u.SetNamespace(namespace)
+	return u
+}
+
+func TestDiffListResultDiffStringMasksSecrets(t *testing.T) {
+	olds, err := ParseManifests(`
+apiVersion: v1
+kind: Secret
+metadata:
+  name: secret
+data:
+  password: b2xkLXBhc3N3b3Jk
+stringData:
+  token: old-token
+`)
+	require.NoError(t, err)
+	news, err := ParseManifests(`
+apiVersion: v1
+kind: Secret
+metadata:
+  name: secret
+data:
+  password: bmV3LXBhc3N3b3Jk
+stringData:
+  token: new-token
+`)
+	require.NoError(t, err)
+
+	result, err := DiffList(olds, news)
+	require.NoError(t, err)
+	require.Len(t, result.Changes, 1)
+
+	got := result.DiffString()
+	assert.Contains(t, got, "#data.password")
+	assert.Contains(t, got, "#stringData.token")
+	for _, v := range []string{"b2xkLXBhc3N3b3Jk", "bmV3LXBhc3N3b3Jk", "old-token", "new-token"} {
+		assert.NotContains(t, got, v)
+	}
+}
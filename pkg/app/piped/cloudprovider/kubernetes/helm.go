@@ -17,16 +17,19 @@ package kubernetes
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/chartrepo"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
 	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
 	"github.com/pipe-cd/pipe/pkg/config"
 )
@@ -46,10 +49,7 @@ func NewHelm(version, path string, logger *zap.Logger) *Helm {
 }
 
 func (c *Helm) TemplateLocalChart(ctx context.Context, appName, appDir, namespace, chartPath string, opts *config.InputHelmOptions) (string, error) {
-	releaseName := appName
-	if opts != nil && opts.ReleaseName != "" {
-		releaseName = opts.ReleaseName
-	}
+	releaseName := helmReleaseName(appName, opts)
 
 	args := []string{
 		"template",
@@ -72,7 +72,7 @@ func (c *Helm) TemplateLocalChart(ctx context.Context, appName, appDir, namespac
 	}
 
 	var stdout, stderr bytes.Buffer
-	cmd := exec.CommandContext(ctx, c.execPath, args...)
+	cmd := toolexec.CommandContext(ctx, c.execPath, args...)
 	cmd.Dir = appDir
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -101,6 +101,18 @@ func (c *Helm) TemplateRemoteGitChart(ctx context.Context, appName, appDir, name
 	}
 	defer os.RemoveAll(repoDir)
 
+	chartPath, err := cloneRemoteGitChart(ctx, repoDir, chart, gitClient)
+	if err != nil {
+		return "", err
+	}
+
+	// After that handle it as a local chart.
+	return c.TemplateLocalChart(ctx, appName, appDir, namespace, chartPath, opts)
+}
+
+// cloneRemoteGitChart clones the repository containing the given chart into repoDir
+// and returns the path to the chart directory.
+func cloneRemoteGitChart(ctx context.Context, repoDir string, chart helmRemoteGitChart, gitClient gitClient) (string, error) {
 	repo, err := gitClient.Clone(ctx, chart.GitRemote, chart.GitRemote, "", repoDir)
 	if err != nil {
 		return "", fmt.Errorf("unable to clone git repository containing remote helm chart: %w", err)
@@ -111,10 +123,7 @@ func (c *Helm) TemplateRemoteGitChart(ctx context.Context, appName, appDir, name
 			return "", fmt.Errorf("unable to checkout to specified ref %s: %w", chart.Ref, err)
 		}
 	}
-	chartPath := filepath.Join(repoDir, chart.Path)
-
-	// After that handle it as a local chart.
-	return c.TemplateLocalChart(ctx, appName, appDir, namespace, chartPath, opts)
+	return filepath.Join(repoDir, chart.Path), nil
 }
 
 type helmRemoteChart struct {
@@ -125,10 +134,7 @@ type helmRemoteChart struct {
 }
 
 func (c *Helm) TemplateRemoteChart(ctx context.Context, appName, appDir, namespace string, chart helmRemoteChart, opts *config.InputHelmOptions) (string, error) {
-	releaseName := appName
-	if opts != nil && opts.ReleaseName != "" {
-		releaseName = opts.ReleaseName
-	}
+	releaseName := helmReleaseName(appName, opts)
 
 	args := []string{
 		"template",
@@ -161,7 +167,7 @@ func (c *Helm) TemplateRemoteChart(ctx context.Context, appName, appDir, namespa
 
 	executor := func() (string, error) {
 		var stdout, stderr bytes.Buffer
-		cmd := exec.CommandContext(ctx, c.execPath, args...)
+		cmd := toolexec.CommandContext(ctx, c.execPath, args...)
 		cmd.Dir = appDir
 		cmd.Stdout = &stdout
 		cmd.Stderr = &stderr
@@ -188,3 +194,95 @@ func (c *Helm) TemplateRemoteChart(ctx context.Context, appName, appDir, namespa
 	}
 	return executor()
 }
+
+// helmReleaseName returns the name of the release of the given application.
+func helmReleaseName(appName string, opts *config.InputHelmOptions) string {
+	if opts != nil && opts.ReleaseName != "" {
+		return opts.ReleaseName
+	}
+	return appName
+}
+
+// UpgradeInstall installs the chart as a release, or upgrades the release if it already exists,
+// and waits until all resources of the release become ready.
+// The chart contains the reference to the chart followed by the flags to fetch it, e.g. "--version".
+func (c *Helm) UpgradeInstall(ctx context.Context, w io.Writer, appDir, releaseName, namespace string, chart []string, opts *config.InputHelmOptions, clusterFlags []string) error {
+	args := []string{"upgrade", releaseName}
+	args = append(args, chart...)
+	args = append(args, "--install", "--wait")
+
+	if namespace != "" {
+		args = append(args, fmt.Sprintf("--namespace=%s", namespace))
+	}
+
+	if opts != nil {
+		if opts.Timeout > 0 {
+			args = append(args, fmt.Sprintf("--timeout=%s", opts.Timeout.Duration()))
+		}
+		for _, v := range opts.ValueFiles {
+			args = append(args, "-f", v)
+		}
+		for k, v := range opts.SetFiles {
+			args = append(args, "--set-file", fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+	args = append(args, clusterFlags...)
+
+	return c.run(ctx, w, appDir, args)
+}
+
+// RollbackRelease rolls back the release to the given revision
+// and waits until all resources of the release become ready.
+func (c *Helm) RollbackRelease(ctx context.Context, w io.Writer, releaseName, namespace string, revision int, timeout time.Duration, clusterFlags []string) error {
+	args := []string{"rollback", releaseName, fmt.Sprintf("%d", revision), "--wait"}
+
+	if namespace != "" {
+		args = append(args, fmt.Sprintf("--namespace=%s", namespace))
+	}
+	if timeout > 0 {
+		args = append(args, fmt.Sprintf("--timeout=%s", timeout))
+	}
+	args = append(args, clusterFlags...)
+
+	return c.run(ctx, w, "", args)
+}
+
+// ReleaseRevision returns the current revision of the release.
+// Zero is returned when the release has not been installed yet.
+func (c *Helm) ReleaseRevision(ctx context.Context, releaseName, namespace string, clusterFlags []string) (int, error) {
+	args := []string{"status", releaseName, "--output=json"}
+	if namespace != "" {
+		args = append(args, fmt.Sprintf("--namespace=%s", namespace))
+	}
+	args = append(args, clusterFlags...)
+
+	var stdout, stderr bytes.Buffer
+	cmd := toolexec.CommandContext(ctx, c.execPath, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(stderr.String(), "release: not found") {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("%w: %s", err, stderr.String())
+	}
+
+	var status struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &status); err != nil {
+		return 0, fmt.Errorf("unable to parse the status of release %s: %w", releaseName, err)
+	}
+	return status.Version, nil
+}
+
+func (c *Helm) run(ctx context.Context, w io.Writer, dir string, args []string) error {
+	cmd := toolexec.CommandContext(ctx, c.execPath, args...)
+	cmd.Dir = dir
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	io.WriteString(w, fmt.Sprintf("helm %s\n", strings.Join(args, " ")))
+	return cmd.Run()
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// FindContainerImages returns the sorted list of images used by the containers
+// and init containers of the given workload manifest.
+// Nil is returned for the manifests which are not workloads.
+func FindContainerImages(m Manifest) []string {
+	podSpec := podSpecFields(m.Key.Kind)
+	if podSpec == nil {
+		return nil
+	}
+
+	images := make(map[string]struct{})
+	for _, field := range []string{"containers", "initContainers"} {
+		containers, _, _ := unstructured.NestedSlice(m.u.Object, append(podSpec, field)...)
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if image, ok := container["image"].(string); ok && image != "" {
+				images[image] = struct{}{}
+			}
+		}
+	}
+	if len(images) == 0 {
+		return nil
+	}
+
+	out := make([]string, 0, len(images))
+	for image := range images {
+		out = append(out, image)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// FindContainerImage returns the image used by the container or init container
+// having the given name in the given workload manifest.
+func FindContainerImage(m Manifest, name string) (string, bool) {
+	podSpec := podSpecFields(m.Key.Kind)
+	if podSpec == nil {
+		return "", false
+	}
+
+	for _, field := range []string{"containers", "initContainers"} {
+		containers, _, _ := unstructured.NestedSlice(m.u.Object, append(podSpec, field)...)
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok || container["name"] != name {
+				continue
+			}
+			if image, ok := container["image"].(string); ok && image != "" {
+				return image, true
+			}
+		}
+	}
+	return "", false
+}
+
+// podSpecFields returns the path to the pod spec of the given workload kind.
+func podSpecFields(kind string) []string {
+	switch kind {
+	case KindDeployment, KindStatefulSet, KindDaemonSet, KindReplicaSet, KindJob:
+		return []string{"spec", "template", "spec"}
+	case KindCronJob:
+		return []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	case KindPod:
+		return []string{"spec"}
+	default:
+		return nil
+	}
+}
+
+// FindImageVersions returns the versions of all container images used by
+// the workloads in the new manifests, sorted by the image name.
+// The running version of each image is taken from the old manifests.
+func FindImageVersions(olds, news []Manifest) []*model.ArtifactVersion {
+	running := make(map[string]string)
+	for _, m := range olds {
+		for _, image := range FindContainerImages(m) {
+			name, tag := ParseContainerImage(image)
+			if _, ok := running[name]; !ok {
+				running[name] = tag
+			}
+		}
+	}
+
+	seen := make(map[string]struct{})
+	versions := make([]*model.ArtifactVersion, 0)
+	for _, m := range news {
+		for _, image := range FindContainerImages(m) {
+			if _, ok := seen[image]; ok {
+				continue
+			}
+			seen[image] = struct{}{}
+			name, tag := ParseContainerImage(image)
+			versions = append(versions, &model.ArtifactVersion{
+				Name:           name,
+				Version:        tag,
+				RunningVersion: running[name],
+			})
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if versions[i].Name == versions[j].Name {
+			return versions[i].Version < versions[j].Version
+		}
+		return versions[i].Name < versions[j].Name
+	})
+	return versions
+}
+
+// ParseContainerImage splits the given image reference into its name and tag.
+// The digest is returned as the tag for the image referenced by digest,
+// and "latest" is returned for the image without tag.
+func ParseContainerImage(image string) (name, tag string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[:i], image[i+1:]
+	}
+	slash := strings.LastIndex(image, "/")
+	if colon := strings.LastIndex(image, ":"); colon > slash {
+		return image[:colon], image[colon+1:]
+	}
+	return image, "latest"
+}
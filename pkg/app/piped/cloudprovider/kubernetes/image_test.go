@@ -0,0 +1,194 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestFindContainerImages(t *testing.T) {
+	podSpec := map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "app", "image": "gcr.io/pipecd/helloworld:v0.1.0"},
+			map[string]interface{}{"name": "sidecar", "image": "envoyproxy/envoy:v1.18.3"},
+		},
+		"initContainers": []interface{}{
+			map[string]interface{}{"name": "init", "image": "gcr.io/pipecd/helloworld:v0.1.0"},
+		},
+	}
+	testcases := []struct {
+		name     string
+		manifest Manifest
+		expected []string
+	}{
+		{
+			name: "deployment",
+			manifest: MakeManifest(ResourceKey{Kind: KindDeployment}, &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"spec": map[string]interface{}{
+						"template": map[string]interface{}{"spec": podSpec},
+					},
+				},
+			}),
+			expected: []string{"envoyproxy/envoy:v1.18.3", "gcr.io/pipecd/helloworld:v0.1.0"},
+		},
+		{
+			name: "cronjob",
+			manifest: MakeManifest(ResourceKey{Kind: KindCronJob}, &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"spec": map[string]interface{}{
+						"jobTemplate": map[string]interface{}{
+							"spec": map[string]interface{}{
+								"template": map[string]interface{}{"spec": podSpec},
+							},
+						},
+					},
+				},
+			}),
+			expected: []string{"envoyproxy/envoy:v1.18.3", "gcr.io/pipecd/helloworld:v0.1.0"},
+		},
+		{
+			name: "not a workload",
+			manifest: MakeManifest(ResourceKey{Kind: KindService}, &unstructured.Unstructured{
+				Object: map[string]interface{}{"spec": map[string]interface{}{}},
+			}),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FindContainerImages(tc.manifest)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestFindContainerImage(t *testing.T) {
+	m := MakeManifest(ResourceKey{Kind: KindStatefulSet}, &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{"name": "app", "image": "gcr.io/pipecd/helloworld:v0.1.0"},
+							map[string]interface{}{"name": "sidecar", "image": "envoyproxy/envoy:v1.18.3"},
+						},
+						"initContainers": []interface{}{
+							map[string]interface{}{"name": "migrate", "image": "gcr.io/pipecd/migrate:v0.2.0"},
+						},
+					},
+				},
+			},
+		},
+	})
+	testcases := []struct {
+		name          string
+		containerName string
+		expected      string
+		expectedOK    bool
+	}{
+		{
+			name:          "container",
+			containerName: "sidecar",
+			expected:      "envoyproxy/envoy:v1.18.3",
+			expectedOK:    true,
+		},
+		{
+			name:          "init container",
+			containerName: "migrate",
+			expected:      "gcr.io/pipecd/migrate:v0.2.0",
+			expectedOK:    true,
+		},
+		{
+			name:          "not found",
+			containerName: "worker",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := FindContainerImage(m, tc.containerName)
+			assert.Equal(t, tc.expected, got)
+			assert.Equal(t, tc.expectedOK, ok)
+		})
+	}
+}
+
+func TestFindImageVersions(t *testing.T) {
+	makeDeployment := func(images ...string) Manifest {
+		containers := make([]interface{}, 0, len(images))
+		for _, image := range images {
+			containers = append(containers, map[string]interface{}{"image": image})
+		}
+		return MakeManifest(ResourceKey{Kind: KindDeployment}, &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{"containers": containers},
+					},
+				},
+			},
+		})
+	}
+	olds := []Manifest{
+		makeDeployment("gcr.io/pipecd/foo:v0.1.0", "envoyproxy/envoy:v1.18.3"),
+	}
+	news := []Manifest{
+		makeDeployment("gcr.io/pipecd/foo:v0.2.0", "envoyproxy/envoy:v1.18.3"),
+		makeDeployment("gcr.io/pipecd/bar:v0.1.0"),
+	}
+
+	got := FindImageVersions(olds, news)
+	expected := []*model.ArtifactVersion{
+		{Name: "envoyproxy/envoy", Version: "v1.18.3", RunningVersion: "v1.18.3"},
+		{Name: "gcr.io/pipecd/bar", Version: "v0.1.0"},
+		{Name: "gcr.io/pipecd/foo", Version: "v0.2.0", RunningVersion: "v0.1.0"},
+	}
+	assert.Equal(t, expected, got)
+}
+
+func TestParseContainerImage(t *testing.T) {
+	testcases := []struct {
+		image        string
+		expectedName string
+		expectedTag  string
+	}{
+		{
+			image:        "gcr.io/pipecd/helloworld:v0.1.0",
+			expectedName: "gcr.io/pipecd/helloworld",
+			expectedTag:  "v0.1.0",
+		},
+		{
+			image:        "localhost:5000/helloworld",
+			expectedName: "localhost:5000/helloworld",
+			expectedTag:  "latest",
+		},
+		{
+			image:        "helloworld@sha256:abc",
+			expectedName: "helloworld",
+			expectedTag:  "sha256:abc",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.image, func(t *testing.T) {
+			name, tag := ParseContainerImage(tc.image)
+			assert.Equal(t, tc.expectedName, name)
+			assert.Equal(t, tc.expectedTag, tag)
+		})
+	}
+}
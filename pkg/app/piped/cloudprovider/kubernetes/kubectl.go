@@ -18,18 +18,26 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"os/exec"
 	"strings"
+	"time"
 
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/kubernetesmetrics"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
+	"github.com/pipe-cd/pipe/pkg/backoff"
 )
 
 type Kubectl struct {
 	version  string
 	execPath string
 	config   *rest.Config
+	// The global flags specifying the cluster to connect to.
+	clusterFlags []string
+	// The client-side rate limiter of the cluster.
+	// Nil means the commands are not limited.
+	rateLimiter flowcontrol.RateLimiter
 }
 
 func NewKubectl(version, path string) *Kubectl {
@@ -48,22 +56,40 @@ func (c *Kubectl) Apply(ctx context.Context, namespace string, manifest Manifest
 		)
 	}()
 
+	return c.apply(ctx, namespace, manifest, false)
+}
+
+// DryRunApply sends the given manifest to the API server to be validated
+// as a real apply request but without persisting it.
+func (c *Kubectl) DryRunApply(ctx context.Context, namespace string, manifest Manifest) (err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelDryRunApplyCommand,
+			err == nil,
+		)
+	}()
+
+	return c.apply(ctx, namespace, manifest, true)
+}
+
+func (c *Kubectl) apply(ctx context.Context, namespace string, manifest Manifest, dryRun bool) error {
 	data, err := manifest.YamlBytes()
 	if err != nil {
 		return err
 	}
 
-	args := make([]string, 0, 5)
+	args := make([]string, 0, 6+len(c.clusterFlags))
+	args = append(args, c.clusterFlags...)
 	if namespace != "" {
 		args = append(args, "-n", namespace)
 	}
 	args = append(args, "apply", "-f", "-")
+	if dryRun {
+		args = append(args, "--dry-run=server")
+	}
 
-	cmd := exec.CommandContext(ctx, c.execPath, args...)
-	r := bytes.NewReader(data)
-	cmd.Stdin = r
-
-	out, err := cmd.CombinedOutput()
+	out, err := c.run(ctx, args, data)
 	if err != nil {
 		return fmt.Errorf("failed to apply: %s (%v)", string(out), err)
 	}
@@ -79,14 +105,14 @@ func (c *Kubectl) Delete(ctx context.Context, namespace string, r ResourceKey) (
 		)
 	}()
 
-	args := make([]string, 0, 5)
+	args := make([]string, 0, 5+len(c.clusterFlags))
+	args = append(args, c.clusterFlags...)
 	if namespace != "" {
 		args = append(args, "-n", namespace)
 	}
 	args = append(args, "delete", r.Kind, r.Name)
 
-	cmd := exec.CommandContext(ctx, c.execPath, args...)
-	out, err := cmd.CombinedOutput()
+	out, err := c.run(ctx, args, nil)
 
 	if strings.Contains(string(out), "(NotFound)") {
 		return fmt.Errorf("failed to delete: %s, (%w), %v", string(out), ErrNotFound, err)
@@ -96,3 +122,190 @@ func (c *Kubectl) Delete(ctx context.Context, namespace string, r ResourceKey) (
 	}
 	return nil
 }
+
+// Get returns the live manifest of the given resource.
+func (c *Kubectl) Get(ctx context.Context, namespace string, r ResourceKey) (m Manifest, err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelGetCommand,
+			err == nil,
+		)
+	}()
+
+	args := make([]string, 0, 7+len(c.clusterFlags))
+	args = append(args, c.clusterFlags...)
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	args = append(args, "get", r.Kind, r.Name, "-o", "json")
+
+	out, err := c.run(ctx, args, nil)
+
+	if strings.Contains(string(out), "(NotFound)") {
+		return Manifest{}, fmt.Errorf("failed to get: %s, (%w), %v", string(out), ErrNotFound, err)
+	}
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to get: %s, %v", string(out), err)
+	}
+	manifests, err := ParseManifests(string(out))
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to parse the output of kubectl get: %v", err)
+	}
+	if len(manifests) != 1 {
+		return Manifest{}, fmt.Errorf("unexpected number of resources returned by kubectl get: %d", len(manifests))
+	}
+	return manifests[0], nil
+}
+
+// ListNames returns the names of all resources of the given kind matching the label selector.
+func (c *Kubectl) ListNames(ctx context.Context, namespace, kind, selector string) (names []string, err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelGetCommand,
+			err == nil,
+		)
+	}()
+
+	args := make([]string, 0, 8+len(c.clusterFlags))
+	args = append(args, c.clusterFlags...)
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	args = append(args, "get", kind, "-l", selector, "-o", "name")
+
+	out, err := c.run(ctx, args, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get: %s, %v", string(out), err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		// Each line is formatted as "kind/name".
+		if i := strings.LastIndex(line, "/"); i >= 0 {
+			line = line[i+1:]
+		}
+		names = append(names, line)
+	}
+	return names, nil
+}
+
+// Wait waits until the given resource has the specified condition.
+func (c *Kubectl) Wait(ctx context.Context, namespace string, r ResourceKey, condition string, timeout time.Duration) (err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelWaitCommand,
+			err == nil,
+		)
+	}()
+
+	args := make([]string, 0, 6+len(c.clusterFlags))
+	args = append(args, c.clusterFlags...)
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	args = append(args,
+		"wait",
+		fmt.Sprintf("%s/%s", r.Kind, r.Name),
+		fmt.Sprintf("--for=condition=%s", condition),
+		fmt.Sprintf("--timeout=%s", timeout),
+	)
+
+	out, err := c.run(ctx, args, nil)
+	if err != nil {
+		return fmt.Errorf("failed to wait: %s, %v", string(out), err)
+	}
+	return nil
+}
+
+// RolloutStatus waits until the rollout of the given workload has completed.
+func (c *Kubectl) RolloutStatus(ctx context.Context, namespace string, r ResourceKey, timeout time.Duration) (err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelWaitCommand,
+			err == nil,
+		)
+	}()
+
+	args := make([]string, 0, 6+len(c.clusterFlags))
+	args = append(args, c.clusterFlags...)
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	args = append(args,
+		"rollout",
+		"status",
+		fmt.Sprintf("%s/%s", r.Kind, r.Name),
+		fmt.Sprintf("--timeout=%s", timeout),
+	)
+
+	out, err := c.run(ctx, args, nil)
+	if err != nil {
+		return fmt.Errorf("failed to wait for rollout: %s, %v", string(out), err)
+	}
+	return nil
+}
+
+// Annotate adds the given annotations to the live resource
+// while overwriting the existing values of the same keys.
+func (c *Kubectl) Annotate(ctx context.Context, namespace string, r ResourceKey, annotations map[string]string) (err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelAnnotateCommand,
+			err == nil,
+		)
+	}()
+
+	args := make([]string, 0, 6+len(c.clusterFlags)+len(annotations))
+	args = append(args, c.clusterFlags...)
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	args = append(args, "annotate", "--overwrite", r.Kind, r.Name)
+	for k, v := range annotations {
+		args = append(args, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	out, err := c.run(ctx, args, nil)
+
+	if strings.Contains(string(out), "(NotFound)") {
+		return fmt.Errorf("failed to annotate: %s, (%w), %v", string(out), ErrNotFound, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to annotate: %s, %v", string(out), err)
+	}
+	return nil
+}
+
+// run executes kubectl with the given arguments while respecting the client-side rate limit of the cluster.
+// The command is retried with an exponential backoff while the API server is throttling the requests.
+func (c *Kubectl) run(ctx context.Context, args []string, stdin []byte) (out []byte, err error) {
+	retry := backoff.NewRetry(throttledRetries+1, backoff.NewExponential(throttledRetryBaseInterval, throttledRetryMaxInterval))
+	for retry.WaitNext(ctx) {
+		if c.rateLimiter != nil {
+			if err := c.rateLimiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		cmd := toolexec.CommandContext(ctx, c.execPath, args...)
+		if stdin != nil {
+			cmd.Stdin = bytes.NewReader(stdin)
+		}
+		out, err = cmd.CombinedOutput()
+		if err == nil || !isThrottled(out) {
+			return out, err
+		}
+		kubernetesmetrics.IncKubectlThrottledCounter(c.version)
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	return out, err
+}
@@ -18,9 +18,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -41,7 +44,10 @@ const (
 	LabelResourceKey          = "pipecd.dev/resource-key"           // The resource key generated by apiVersion, namespace and name. e.g. apps/v1/Deployment/namespace/demo-app
 	LabelOriginalAPIVersion   = "pipecd.dev/original-api-version"   // The api version defined in git configuration. e.g. apps/v1
 	LabelIgnoreDriftDirection = "pipecd.dev/ignore-drift-detection" // Whether the drift detection should ignore this resource.
+	LabelVariant              = "pipecd.dev/variant"                // Variant name: primary, canary, baseline
 	AnnotationConfigHash      = "pipecd.dev/config-hash"            // The hash value of all mouting config resources.
+	LabelNamespaceOwner       = "pipecd.dev/namespace-owner"        // The application which deletes this namespace on its deletion.
+	AnnotationSyncWave        = "pipecd.dev/sync-wave"              // The wave number in which this resource is applied. Lower waves are applied and become ready first.
 	ManagedByPiped            = "piped"
 	IgnoreDriftDetectionTrue  = "true"
 
@@ -59,6 +65,7 @@ const (
 type Provider interface {
 	ManifestLoader
 	Applier
+	NamespaceGetter
 }
 
 type ManifestLoader interface {
@@ -71,8 +78,27 @@ type Applier interface {
 	Apply(ctx context.Context) error
 	// ApplyManifest does applying the given manifest.
 	ApplyManifest(ctx context.Context, manifest Manifest) error
+	// DryRunApplyManifest verifies the given manifest by a server-side dry-run apply.
+	DryRunApplyManifest(ctx context.Context, manifest Manifest) error
 	// Delete deletes the given resource from Kubernetes cluster.
 	Delete(ctx context.Context, key ResourceKey) error
+	// WaitForReady waits until the given resource becomes ready.
+	WaitForReady(ctx context.Context, key ResourceKey) error
+}
+
+// HelmReleaser deploys the chart of an application as a Helm release
+// instead of applying the rendered manifests.
+type HelmReleaser interface {
+	ManifestLoader
+	// UpgradeInstall installs or upgrades the release by "helm upgrade --install".
+	UpgradeInstall(ctx context.Context, w io.Writer) error
+	// ReleaseRevision returns the current revision of the release.
+	// Zero means the release has not been installed yet.
+	ReleaseRevision(ctx context.Context) (int, error)
+	// RollbackRelease rolls back the release to the given revision by "helm rollback".
+	RollbackRelease(ctx context.Context, revision int, w io.Writer) error
+	// Annotate adds the given annotations to the live resource.
+	Annotate(ctx context.Context, key ResourceKey, annotations map[string]string) error
 }
 
 type gitClient interface {
@@ -91,11 +117,13 @@ type provider struct {
 	repoDir        string
 	configFileName string
 	input          config.KubernetesDeploymentInput
+	cluster        *config.CloudProviderKubernetesConfig
 	logger         *zap.Logger
 
 	kubectl          *Kubectl
 	kustomize        *Kustomize
 	helm             *Helm
+	helmClusterFlags []string
 	templatingMethod TemplatingMethod
 	initOnce         sync.Once
 	initErr          error
@@ -109,19 +137,30 @@ func initSharedGitClient(logger *zap.Logger) error {
 	return err
 }
 
-func NewProvider(appName, appDir, repoDir, configFileName string, input config.KubernetesDeploymentInput, logger *zap.Logger) Provider {
+// NewProvider creates a provider to load and apply the manifests of an application.
+// The cluster specifies where the manifests are applied to, nil means the default cluster of kubectl.
+func NewProvider(appName, appDir, repoDir, configFileName string, input config.KubernetesDeploymentInput, cluster *config.CloudProviderKubernetesConfig, logger *zap.Logger) Provider {
 	return &provider{
 		appName:        appName,
 		appDir:         appDir,
 		repoDir:        repoDir,
 		configFileName: configFileName,
-		input:          input,
+		input:          ResolveToolVersions(input, cluster),
+		cluster:        cluster,
 		logger:         logger.Named("kubernetes-provider"),
 	}
 }
 
-func NewManifestLoader(appName, appDir, repoDir, configFileName string, input config.KubernetesDeploymentInput, logger *zap.Logger) ManifestLoader {
-	return NewProvider(appName, appDir, repoDir, configFileName, input, logger)
+// NewHelmReleaser creates a releaser to deploy the chart of an application as a Helm release.
+func NewHelmReleaser(appName, appDir, repoDir, configFileName string, input config.KubernetesDeploymentInput, cluster *config.CloudProviderKubernetesConfig, logger *zap.Logger) HelmReleaser {
+	return NewProvider(appName, appDir, repoDir, configFileName, input, cluster, logger).(*provider)
+}
+
+// NewManifestLoader creates a loader to render the manifests of an application
+// by using the kubectl and helm versions configured for the given cluster.
+// The cluster itself is not accessed while loading the manifests.
+func NewManifestLoader(appName, appDir, repoDir, configFileName string, input config.KubernetesDeploymentInput, cluster *config.CloudProviderKubernetesConfig, logger *zap.Logger) ManifestLoader {
+	return NewProvider(appName, appDir, repoDir, configFileName, ResolveToolVersions(input, cluster), nil, logger)
 }
 
 func (p *provider) init(ctx context.Context) {
@@ -130,17 +169,26 @@ func (p *provider) init(ctx context.Context) {
 		return
 	}
 
-	p.templatingMethod = determineTemplatingMethod(p.input, p.appDir)
+	p.templatingMethod = DetermineTemplatingMethod(p.input, p.appDir)
 
 	// We need kubectl for all templating methods.
 	p.kubectl, p.initErr = p.findKubectl(ctx, p.input.KubectlVersion)
 	if p.initErr != nil {
 		return
 	}
+	p.kubectl.clusterFlags, p.initErr = kubectlClusterFlags(p.cluster)
+	if p.initErr != nil {
+		return
+	}
+	p.kubectl.rateLimiter = clusterRateLimiter(p.cluster)
 
 	switch p.templatingMethod {
 	case TemplatingMethodHelm:
 		p.helm, p.initErr = p.findHelm(ctx, p.input.HelmVersion)
+		if p.initErr != nil {
+			return
+		}
+		p.helmClusterFlags, p.initErr = helmClusterFlags(p.cluster)
 
 	case TemplatingMethodKustomize:
 		p.kustomize, p.initErr = p.findKustomize(ctx, p.input.KustomizeVersion)
@@ -157,44 +205,9 @@ func (p *provider) LoadManifests(ctx context.Context) (manifests []Manifest, err
 	switch p.templatingMethod {
 	case TemplatingMethodHelm:
 		var data string
-		switch {
-		case p.input.HelmChart.GitRemote != "":
-			chart := helmRemoteGitChart{
-				GitRemote: p.input.HelmChart.GitRemote,
-				Ref:       p.input.HelmChart.Ref,
-				Path:      p.input.HelmChart.Path,
-			}
-			data, err = p.helm.TemplateRemoteGitChart(ctx,
-				p.appName,
-				p.appDir,
-				p.input.Namespace,
-				chart,
-				sharedGitClient,
-				p.input.HelmOptions)
-
-		case p.input.HelmChart.Repository != "":
-			chart := helmRemoteChart{
-				Repository: p.input.HelmChart.Repository,
-				Name:       p.input.HelmChart.Name,
-				Version:    p.input.HelmChart.Version,
-				Insecure:   p.input.HelmChart.Insecure,
-			}
-			data, err = p.helm.TemplateRemoteChart(ctx,
-				p.appName,
-				p.appDir,
-				p.input.Namespace,
-				chart,
-				p.input.HelmOptions)
-
-		default:
-			data, err = p.helm.TemplateLocalChart(ctx,
-				p.appName,
-				p.appDir,
-				p.input.Namespace,
-				p.input.HelmChart.Path,
-				p.input.HelmOptions)
-		}
-
+		data, err = p.renderWithCache(ctx, func() (string, error) {
+			return p.templateHelm(ctx)
+		})
 		if err != nil {
 			err = fmt.Errorf("unable to run helm template: %w", err)
 			return
@@ -203,7 +216,9 @@ func (p *provider) LoadManifests(ctx context.Context) (manifests []Manifest, err
 
 	case TemplatingMethodKustomize:
 		var data string
-		data, err = p.kustomize.Template(ctx, p.appName, p.appDir, p.input.KustomizeOptions)
+		data, err = p.renderWithCache(ctx, func() (string, error) {
+			return p.kustomize.Template(ctx, p.appName, p.appDir, p.input.KustomizeOptions)
+		})
 		if err != nil {
 			err = fmt.Errorf("unable to run kustomize template: %w", err)
 			return
@@ -216,10 +231,173 @@ func (p *provider) LoadManifests(ctx context.Context) (manifests []Manifest, err
 	default:
 		err = fmt.Errorf("unsupport templating method %v", p.templatingMethod)
 	}
+	if err != nil {
+		return
+	}
 
+	setDefaultNamespace(manifests, p.input.Namespace)
 	return
 }
 
+// setDefaultNamespace makes the keys of the namespaced manifests which are not specifying
+// any namespace point to the given one, the namespace where they will be applied.
+// The manifests specifying their own namespace are kept as is so that one application
+// can deploy its resources to multiple namespaces.
+func setDefaultNamespace(manifests []Manifest, namespace string) {
+	if namespace == "" {
+		return
+	}
+	for i := range manifests {
+		if manifests[i].HasNamespace() || manifests[i].Key.IsClusterScoped() {
+			continue
+		}
+		manifests[i].Key.Namespace = namespace
+	}
+}
+
+// templateHelm renders the manifests from the configured chart.
+func (p *provider) templateHelm(ctx context.Context) (string, error) {
+	switch {
+	case p.input.HelmChart.GitRemote != "":
+		chart := helmRemoteGitChart{
+			GitRemote: p.input.HelmChart.GitRemote,
+			Ref:       p.input.HelmChart.Ref,
+			Path:      p.input.HelmChart.Path,
+		}
+		return p.helm.TemplateRemoteGitChart(ctx,
+			p.appName,
+			p.appDir,
+			p.input.Namespace,
+			chart,
+			sharedGitClient,
+			p.input.HelmOptions)
+
+	case p.input.HelmChart.Repository != "":
+		chart := helmRemoteChart{
+			Repository: p.input.HelmChart.Repository,
+			Name:       p.input.HelmChart.Name,
+			Version:    p.input.HelmChart.Version,
+			Insecure:   p.input.HelmChart.Insecure,
+		}
+		return p.helm.TemplateRemoteChart(ctx,
+			p.appName,
+			p.appDir,
+			p.input.Namespace,
+			chart,
+			p.input.HelmOptions)
+
+	default:
+		return p.helm.TemplateLocalChart(ctx,
+			p.appName,
+			p.appDir,
+			p.input.Namespace,
+			p.input.HelmChart.Path,
+			p.input.HelmOptions)
+	}
+}
+
+// UpgradeInstall installs or upgrades the release by "helm upgrade --install".
+func (p *provider) UpgradeInstall(ctx context.Context, w io.Writer) error {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return p.initErr
+	}
+	if p.helm == nil {
+		return fmt.Errorf("helm chart was not configured")
+	}
+
+	var chart []string
+	switch {
+	case p.input.HelmChart.GitRemote != "":
+		repoDir, err := ioutil.TempDir("", "helm-remote-chart")
+		if err != nil {
+			return fmt.Errorf("unable to created temporary directory for storing remote helm chart: %w", err)
+		}
+		defer os.RemoveAll(repoDir)
+
+		remote := helmRemoteGitChart{
+			GitRemote: p.input.HelmChart.GitRemote,
+			Ref:       p.input.HelmChart.Ref,
+			Path:      p.input.HelmChart.Path,
+		}
+		chartPath, err := cloneRemoteGitChart(ctx, repoDir, remote, sharedGitClient)
+		if err != nil {
+			return err
+		}
+		chart = []string{chartPath}
+
+	case p.input.HelmChart.Repository != "":
+		chart = []string{
+			fmt.Sprintf("%s/%s", p.input.HelmChart.Repository, p.input.HelmChart.Name),
+			fmt.Sprintf("--version=%s", p.input.HelmChart.Version),
+		}
+		if p.input.HelmChart.Insecure {
+			chart = append(chart, "--insecure-skip-tls-verify")
+		}
+
+	default:
+		chart = []string{p.input.HelmChart.Path}
+	}
+
+	return p.helm.UpgradeInstall(ctx,
+		w,
+		p.appDir,
+		helmReleaseName(p.appName, p.input.HelmOptions),
+		p.input.Namespace,
+		chart,
+		p.input.HelmOptions,
+		p.helmClusterFlags)
+}
+
+// ReleaseRevision returns the current revision of the release.
+func (p *provider) ReleaseRevision(ctx context.Context) (int, error) {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return 0, p.initErr
+	}
+	if p.helm == nil {
+		return 0, fmt.Errorf("helm chart was not configured")
+	}
+
+	return p.helm.ReleaseRevision(ctx,
+		helmReleaseName(p.appName, p.input.HelmOptions),
+		p.input.Namespace,
+		p.helmClusterFlags)
+}
+
+// RollbackRelease rolls back the release to the given revision by "helm rollback".
+func (p *provider) RollbackRelease(ctx context.Context, revision int, w io.Writer) error {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return p.initErr
+	}
+	if p.helm == nil {
+		return fmt.Errorf("helm chart was not configured")
+	}
+
+	var timeout time.Duration
+	if p.input.HelmOptions != nil {
+		timeout = p.input.HelmOptions.Timeout.Duration()
+	}
+	return p.helm.RollbackRelease(ctx,
+		w,
+		helmReleaseName(p.appName, p.input.HelmOptions),
+		p.input.Namespace,
+		revision,
+		timeout,
+		p.helmClusterFlags)
+}
+
+// Annotate adds the given annotations to the live resource.
+func (p *provider) Annotate(ctx context.Context, key ResourceKey, annotations map[string]string) error {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return p.initErr
+	}
+
+	return p.kubectl.Annotate(ctx, p.getNamespaceToRun(key), key, annotations)
+}
+
 // Apply does applying application manifests by using the tool specified in Input.
 func (p *provider) Apply(ctx context.Context) error {
 	return nil
@@ -235,6 +413,16 @@ func (p *provider) ApplyManifest(ctx context.Context, manifest Manifest) error {
 	return p.kubectl.Apply(ctx, p.getNamespaceToRun(manifest.Key), manifest)
 }
 
+// DryRunApplyManifest verifies the given manifest by a server-side dry-run apply.
+func (p *provider) DryRunApplyManifest(ctx context.Context, manifest Manifest) error {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return p.initErr
+	}
+
+	return p.kubectl.DryRunApply(ctx, p.getNamespaceToRun(manifest.Key), manifest)
+}
+
 // Delete deletes the given resource from Kubernetes cluster.
 func (p *provider) Delete(ctx context.Context, k ResourceKey) (err error) {
 	p.initOnce.Do(func() { p.init(ctx) })
@@ -245,13 +433,46 @@ func (p *provider) Delete(ctx context.Context, k ResourceKey) (err error) {
 	return p.kubectl.Delete(ctx, p.getNamespaceToRun(k), k)
 }
 
+// WaitForReady waits until the given resource becomes ready.
+// CustomResourceDefinitions must be established, workloads must be rolled out,
+// Jobs must be completed and ExternalSecrets, SealedSecrets must be synced.
+// The other resources are ready once applied.
+func (p *provider) WaitForReady(ctx context.Context, k ResourceKey) error {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return p.initErr
+	}
+
+	var (
+		namespace = p.getNamespaceToRun(k)
+		timeout   = p.input.SyncWaveTimeout.Duration()
+	)
+	switch k.Kind {
+	case KindCustomResourceDefinition:
+		return p.kubectl.Wait(ctx, namespace, k, "established", timeout)
+	case KindDeployment, KindStatefulSet, KindDaemonSet:
+		return p.kubectl.RolloutStatus(ctx, namespace, k, timeout)
+	case KindJob:
+		return p.kubectl.Wait(ctx, namespace, k, "complete", timeout)
+	case KindExternalSecret:
+		return p.kubectl.Wait(ctx, namespace, k, "Ready", timeout)
+	case KindSealedSecret:
+		return p.kubectl.Wait(ctx, namespace, k, "Synced", timeout)
+	default:
+		return nil
+	}
+}
+
 // getNamespaceToRun returns namespace used on kubectl apply/delete commands.
-// priority: config.KubernetesDeploymentInput > kubernetes.ResourceKey
+// priority: kubernetes.ResourceKey > config.KubernetesDeploymentInput
 func (p *provider) getNamespaceToRun(k ResourceKey) string {
-	if p.input.Namespace != "" {
-		return p.input.Namespace
+	if k.IsClusterScoped() {
+		return ""
+	}
+	if k.Namespace != "" {
+		return k.Namespace
 	}
-	return k.Namespace
+	return p.input.Namespace
 }
 
 func (p *provider) findKubectl(ctx context.Context, version string) (*Kubectl, error) {
@@ -287,7 +508,8 @@ func (p *provider) findHelm(ctx context.Context, version string) (*Helm, error)
 	return NewHelm(version, path, p.logger), nil
 }
 
-func determineTemplatingMethod(input config.KubernetesDeploymentInput, appDirPath string) TemplatingMethod {
+// DetermineTemplatingMethod returns the method used to render the manifests of the given application.
+func DetermineTemplatingMethod(input config.KubernetesDeploymentInput, appDirPath string) TemplatingMethod {
 	if input.HelmChart != nil {
 		return TemplatingMethodHelm
 	}
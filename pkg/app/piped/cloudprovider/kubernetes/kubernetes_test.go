@@ -19,6 +19,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
@@ -32,3 +33,48 @@ func TestMain(m *testing.M) {
 	}
 	os.Exit(m.Run())
 }
+
+func TestSetDefaultNamespace(t *testing.T) {
+	testcases := []struct {
+		name      string
+		manifests []Manifest
+		namespace string
+		expected  []ResourceKey
+	}{
+		{
+			name: "no namespace given",
+			manifests: []Manifest{
+				{Key: ResourceKey{APIVersion: "v1", Kind: KindService, Namespace: DefaultNamespace, Name: "a"}, u: makeNamespacedObject("")},
+			},
+			expected: []ResourceKey{
+				{APIVersion: "v1", Kind: KindService, Namespace: DefaultNamespace, Name: "a"},
+			},
+		},
+		{
+			name: "only manifests without namespace are changed",
+			manifests: []Manifest{
+				{Key: ResourceKey{APIVersion: "v1", Kind: KindService, Namespace: DefaultNamespace, Name: "a"}, u: makeNamespacedObject("")},
+				{Key: ResourceKey{APIVersion: "v1", Kind: KindService, Namespace: "other", Name: "b"}, u: makeNamespacedObject("other")},
+				{Key: ResourceKey{APIVersion: "v1", Kind: KindNamespace, Namespace: DefaultNamespace, Name: "other"}, u: makeNamespacedObject("")},
+				{Key: ResourceKey{APIVersion: "rbac.authorization.k8s.io/v1", Kind: KindClusterRole, Namespace: DefaultNamespace, Name: "c"}, u: makeNamespacedObject("")},
+			},
+			namespace: "app",
+			expected: []ResourceKey{
+				{APIVersion: "v1", Kind: KindService, Namespace: "app", Name: "a"},
+				{APIVersion: "v1", Kind: KindService, Namespace: "other", Name: "b"},
+				{APIVersion: "v1", Kind: KindNamespace, Namespace: DefaultNamespace, Name: "other"},
+				{APIVersion: "rbac.authorization.k8s.io/v1", Kind: KindClusterRole, Namespace: DefaultNamespace, Name: "c"},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			setDefaultNamespace(tc.manifests, tc.namespace)
+			keys := make([]ResourceKey, 0, len(tc.manifests))
+			for _, m := range tc.manifests {
+				keys = append(keys, m.Key)
+			}
+			assert.Equal(t, tc.expected, keys)
+		})
+	}
+}
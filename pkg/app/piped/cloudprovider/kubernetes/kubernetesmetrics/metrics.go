@@ -34,8 +34,12 @@ const (
 type ToolCommand string
 
 const (
-	LabelApplyCommand  ToolCommand = "apply"
-	LabelDeleteCommand ToolCommand = "delete"
+	LabelApplyCommand       ToolCommand = "apply"
+	LabelDryRunApplyCommand ToolCommand = "dry-run-apply"
+	LabelDeleteCommand      ToolCommand = "delete"
+	LabelAnnotateCommand    ToolCommand = "annotate"
+	LabelWaitCommand        ToolCommand = "wait"
+	LabelGetCommand         ToolCommand = "get"
 )
 
 type CommandOutput string
@@ -58,6 +62,16 @@ var (
 			commandOutputKey,
 		},
 	)
+	toolThrottledCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "piped_cloudprovider_kubernetes_tool_throttled_total",
+			Help: "Number of tool calls rejected by the API server with 429 Too Many Requests.",
+		},
+		[]string{
+			toolKey,
+			versionKey,
+		},
+	)
 )
 
 func IncKubectlCallsCounter(version string, command ToolCommand, success bool) {
@@ -73,6 +87,16 @@ func IncKubectlCallsCounter(version string, command ToolCommand, success bool) {
 	}).Inc()
 }
 
+func IncKubectlThrottledCounter(version string) {
+	toolThrottledCounter.With(prometheus.Labels{
+		toolKey:    string(LabelToolKubectl),
+		versionKey: version,
+	}).Inc()
+}
+
 func Register(r prometheus.Registerer) {
-	r.MustRegister(toolCallsCounter)
+	r.MustRegister(
+		toolCallsCounter,
+		toolThrottledCounter,
+	)
 }
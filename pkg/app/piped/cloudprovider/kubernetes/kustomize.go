@@ -18,9 +18,10 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"os/exec"
 
 	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
 )
 
 type Kustomize struct {
@@ -51,7 +52,7 @@ func (c *Kustomize) Template(ctx context.Context, appName, appDir string, opts m
 	}
 
 	var stdout, stderr bytes.Buffer
-	cmd := exec.CommandContext(ctx, c.execPath, args...)
+	cmd := toolexec.CommandContext(ctx, c.execPath, args...)
 	cmd.Dir = appDir
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -53,6 +53,13 @@ func (m Manifest) Duplicate(name string) Manifest {
 	}
 }
 
+// HasNamespace reports whether the namespace field of the manifest is set.
+// For the manifests loaded from the live state, false means that
+// the resource is not belonging to any namespace.
+func (m Manifest) HasNamespace() bool {
+	return m.u != nil && m.u.GetNamespace() != ""
+}
+
 func (m Manifest) YamlBytes() ([]byte, error) {
 	return yaml.Marshal(m.u)
 }
@@ -81,6 +88,10 @@ func (m Manifest) GetAnnotations() map[string]string {
 	return m.u.GetAnnotations()
 }
 
+func (m Manifest) GetLabels() map[string]string {
+	return m.u.GetLabels()
+}
+
 func (m Manifest) GetNestedStringMap(fields ...string) (map[string]string, error) {
 	sm, _, err := unstructured.NestedStringMap(m.u.Object, fields...)
 	if err != nil {
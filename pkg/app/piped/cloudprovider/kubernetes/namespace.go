@@ -0,0 +1,121 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// NamespaceGetter reads the live namespaces.
+type NamespaceGetter interface {
+	// GetNamespace returns the live manifest of the given namespace.
+	// ErrNotFound is returned when it does not exist.
+	GetNamespace(ctx context.Context, name string) (Manifest, error)
+}
+
+// NamespaceDeleter deletes the namespaces created for applications.
+type NamespaceDeleter interface {
+	// DeleteOwnedNamespaces deletes all namespaces owned by the given application.
+	DeleteOwnedNamespaces(ctx context.Context, appID string) error
+}
+
+// NewNamespaceDeleter creates a deleter to delete the namespaces in the given cluster.
+func NewNamespaceDeleter(cluster *config.CloudProviderKubernetesConfig, logger *zap.Logger) NamespaceDeleter {
+	return NewProvider("", "", "", "", config.KubernetesDeploymentInput{}, cluster, logger).(*provider)
+}
+
+// GetNamespace returns the live manifest of the given namespace.
+func (p *provider) GetNamespace(ctx context.Context, name string) (Manifest, error) {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return Manifest{}, p.initErr
+	}
+
+	key := ResourceKey{
+		APIVersion: "v1",
+		Kind:       KindNamespace,
+		Name:       name,
+	}
+	return p.kubectl.Get(ctx, "", key)
+}
+
+// DeleteOwnedNamespaces deletes all namespaces labeled as owned by the given application.
+// The system namespaces are never deleted even when they were labeled.
+func (p *provider) DeleteOwnedNamespaces(ctx context.Context, appID string) error {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return p.initErr
+	}
+
+	selector := fmt.Sprintf("%s=%s", LabelNamespaceOwner, appID)
+	names, err := p.kubectl.ListNames(ctx, "", KindNamespace, selector)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if IsSystemNamespace(name) {
+			p.logger.Warn("skipped deleting a system namespace labeled as owned by application",
+				zap.String("namespace", name),
+				zap.String("application-id", appID),
+			)
+			continue
+		}
+		key := ResourceKey{
+			APIVersion: "v1",
+			Kind:       KindNamespace,
+			Name:       name,
+		}
+		if err := p.kubectl.Delete(ctx, "", key); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsSystemNamespace reports whether the given namespace is managed by Kubernetes itself
+// and must never be deleted by piped.
+func IsSystemNamespace(name string) bool {
+	return name == "default" || strings.HasPrefix(name, "kube-")
+}
+
+// MakeNamespaceManifest builds the manifest of a namespace having the given labels and annotations.
+// Applying it creates the namespace or adopts the existing one.
+func MakeNamespaceManifest(name string, labels, annotations map[string]string) Manifest {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("v1")
+	u.SetKind(KindNamespace)
+	u.SetName(name)
+	if len(labels) > 0 {
+		u.SetLabels(labels)
+	}
+	if len(annotations) > 0 {
+		u.SetAnnotations(annotations)
+	}
+
+	key := ResourceKey{
+		APIVersion: "v1",
+		Kind:       KindNamespace,
+		Name:       name,
+	}
+	return MakeManifest(key, u)
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
+	"github.com/pipe-cd/pipe/pkg/cache"
+	"github.com/pipe-cd/pipe/pkg/version"
+)
+
+// renderCache stores the rendered manifests shared by all providers.
+// Nil means the rendered manifests are not cached.
+var renderCache cache.Cache
+
+// InitRenderCache enables caching the output of helm and kustomize
+// keyed by the commit, the application path and the tool versions.
+func InitRenderCache(c cache.Cache) {
+	renderCache = c
+}
+
+// renderWithCache returns the cached output for the application
+// or runs the given render function and caches its output.
+func (p *provider) renderWithCache(ctx context.Context, render func() (string, error)) (string, error) {
+	key := p.renderCacheKey(ctx)
+	if key == "" {
+		return render()
+	}
+
+	if item, err := renderCache.Get(key); err == nil {
+		p.logger.Info("using the cached manifests", zap.String("key", key))
+		return string(item.([]byte)), nil
+	} else if !errors.Is(err, cache.ErrNotFound) {
+		p.logger.Warn("failed to read the cached manifests", zap.Error(err))
+	}
+
+	data, err := render()
+	if err != nil {
+		return "", err
+	}
+	if err := renderCache.Put(key, []byte(data)); err != nil {
+		p.logger.Warn("failed to cache the rendered manifests", zap.Error(err))
+	}
+	return data, nil
+}
+
+// renderCacheKey returns the key of the rendered manifests.
+// Empty means the output cannot be determined from the key
+// so it should not be cached.
+func (p *provider) renderCacheKey(ctx context.Context) string {
+	if renderCache == nil {
+		return ""
+	}
+
+	var tool string
+	switch p.templatingMethod {
+	case TemplatingMethodHelm:
+		// The ref of the remote git chart and the latest version
+		// of the remote chart may point to a different content later.
+		if p.input.HelmChart.GitRemote != "" {
+			return ""
+		}
+		if p.input.HelmChart.Repository != "" && p.input.HelmChart.Version == "" {
+			return ""
+		}
+		tool = fmt.Sprintf("helm %s %s", p.helm.version, p.helm.execPath)
+	case TemplatingMethodKustomize:
+		tool = fmt.Sprintf("kustomize %s %s", p.kustomize.version, p.kustomize.execPath)
+	default:
+		return ""
+	}
+
+	commit, err := cleanHeadCommit(ctx, p.repoDir)
+	if err != nil {
+		p.logger.Debug("skip caching the rendered manifests", zap.Error(err))
+		return ""
+	}
+	appPath, err := filepath.Rel(p.repoDir, p.appDir)
+	if err != nil {
+		return ""
+	}
+	input, err := json.Marshal(p.input)
+	if err != nil {
+		return ""
+	}
+
+	h := sha256.New()
+	for _, v := range []string{
+		commit,
+		appPath,
+		p.appName,
+		p.configFileName,
+		tool,
+		// The default version of the tools depends on the piped version.
+		version.Get().Version,
+		string(input),
+	} {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cleanHeadCommit returns the hash of the HEAD commit of the given repository.
+// An error is returned when the working tree contains any change from that commit,
+// e.g. the decrypted secrets, since the commit does not represent the content anymore.
+func cleanHeadCommit(ctx context.Context, repoDir string) (string, error) {
+	out, err := toolexec.CommandContext(ctx, "git", "-C", repoDir, "status", "--porcelain").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the status of repository: %w", err)
+	}
+	if len(strings.TrimSpace(string(out))) > 0 {
+		return "", errors.New("repository contains uncommitted changes")
+	}
+	out, err = toolexec.CommandContext(ctx, "git", "-C", repoDir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the HEAD commit of repository: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/cache/memorycache"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestRenderWithCache(t *testing.T) {
+	c, err := memorycache.NewLRUCache(10)
+	require.NoError(t, err)
+	InitRenderCache(c)
+	defer InitRenderCache(nil)
+
+	repoDir := t.TempDir()
+	appDir := filepath.Join(repoDir, "app")
+	git := func(args ...string) {
+		args = append([]string{"-C", repoDir, "-c", "user.name=test", "-c", "user.email=test@pipecd.dev"}, args...)
+		out, err := exec.Command("git", args...).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(repoDir, "README.md"), []byte("test"), 0644))
+	git("add", ".")
+	git("commit", "-m", "initial")
+
+	p := &provider{
+		appName:          "app",
+		appDir:           appDir,
+		repoDir:          repoDir,
+		templatingMethod: TemplatingMethodKustomize,
+		kustomize:        NewKustomize("3.8.1", "/tools/kustomize", zap.NewNop()),
+		logger:           zap.NewNop(),
+	}
+	rendered := 0
+	render := func() (string, error) {
+		rendered++
+		return "manifests", nil
+	}
+
+	// The second call uses the cached output at the same commit.
+	for i := 0; i < 2; i++ {
+		data, err := p.renderWithCache(context.Background(), render)
+		require.NoError(t, err)
+		assert.Equal(t, "manifests", data)
+	}
+	assert.Equal(t, 1, rendered)
+
+	// The output is cached separately for each tool version.
+	p.kustomize = NewKustomize("3.9.0", "/tools/kustomize", zap.NewNop())
+	_, err = p.renderWithCache(context.Background(), render)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rendered)
+
+	// The output is not cached while the working tree was changed.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(repoDir, "README.md"), []byte("changed"), 0644))
+	for i := 0; i < 2; i++ {
+		_, err = p.renderWithCache(context.Background(), render)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 4, rendered)
+}
+
+func TestRenderCacheKey(t *testing.T) {
+	c, err := memorycache.NewLRUCache(10)
+	require.NoError(t, err)
+	InitRenderCache(c)
+	defer InitRenderCache(nil)
+
+	testcases := []struct {
+		name  string
+		input config.KubernetesDeploymentInput
+	}{
+		{
+			name: "remote git chart",
+			input: config.KubernetesDeploymentInput{
+				HelmChart: &config.InputHelmChart{
+					GitRemote: "git@github.com:pipe-cd/manifests.git",
+					Ref:       "master",
+				},
+			},
+		},
+		{
+			name: "latest version of remote chart",
+			input: config.KubernetesDeploymentInput{
+				HelmChart: &config.InputHelmChart{
+					Repository: "pipecd",
+					Name:       "helloworld",
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &provider{
+				input:            tc.input,
+				templatingMethod: TemplatingMethodHelm,
+				helm:             NewHelm("3.5.3", "/tools/helm", zap.NewNop()),
+				logger:           zap.NewNop(),
+			}
+			assert.Empty(t, p.renderCacheKey(context.Background()))
+		})
+	}
+}
@@ -57,25 +57,48 @@ var builtInApiVersions = map[string]struct{}{
 	"v1":                                   {},
 }
 
+// clusterScopedKinds contains the built-in kinds whose resources
+// do not belong to any namespace.
+var clusterScopedKinds = map[string]struct{}{
+	KindNamespace:                    {},
+	KindPersistentVolume:             {},
+	KindClusterRole:                  {},
+	KindClusterRoleBinding:           {},
+	KindCustomResourceDefinition:     {},
+	"StorageClass":                   {},
+	"PriorityClass":                  {},
+	"IngressClass":                   {},
+	"RuntimeClass":                   {},
+	"PodSecurityPolicy":              {},
+	"APIService":                     {},
+	"MutatingWebhookConfiguration":   {},
+	"ValidatingWebhookConfiguration": {},
+	"CertificateSigningRequest":      {},
+}
+
 const (
-	KindDeployment            = "Deployment"
-	KindStatefulSet           = "StatefulSet"
-	KindDaemonSet             = "DaemonSet"
-	KindReplicaSet            = "ReplicaSet"
-	KindPod                   = "Pod"
-	KindJob                   = "Job"
-	KindCronJob               = "CronJob"
-	KindConfigMap             = "ConfigMap"
-	KindSecret                = "Secret"
-	KindPersistentVolume      = "PersistentVolume"
-	KindPersistentVolumeClaim = "PersistentVolumeClaim"
-	KindService               = "Service"
-	KindIngress               = "Ingress"
-	KindServiceAccount        = "ServiceAccount"
-	KindRole                  = "Role"
-	KindRoleBinding           = "RoleBinding"
-	KindClusterRole           = "ClusterRole"
-	KindClusterRoleBinding    = "ClusterRoleBinding"
+	KindDeployment               = "Deployment"
+	KindStatefulSet              = "StatefulSet"
+	KindDaemonSet                = "DaemonSet"
+	KindReplicaSet               = "ReplicaSet"
+	KindPod                      = "Pod"
+	KindJob                      = "Job"
+	KindCronJob                  = "CronJob"
+	KindConfigMap                = "ConfigMap"
+	KindSecret                   = "Secret"
+	KindPersistentVolume         = "PersistentVolume"
+	KindPersistentVolumeClaim    = "PersistentVolumeClaim"
+	KindService                  = "Service"
+	KindIngress                  = "Ingress"
+	KindServiceAccount           = "ServiceAccount"
+	KindRole                     = "Role"
+	KindRoleBinding              = "RoleBinding"
+	KindClusterRole              = "ClusterRole"
+	KindClusterRoleBinding       = "ClusterRoleBinding"
+	KindNamespace                = "Namespace"
+	KindCustomResourceDefinition = "CustomResourceDefinition"
+	KindExternalSecret           = "ExternalSecret"
+	KindSealedSecret             = "SealedSecret"
 
 	DefaultNamespace = "default"
 )
@@ -176,6 +199,16 @@ func (k ResourceKey) IsSecret() bool {
 	return true
 }
 
+// IsClusterScoped reports whether the key is pointing to a built-in resource
+// that does not belong to any namespace.
+func (k ResourceKey) IsClusterScoped() bool {
+	if !IsKubernetesBuiltInResource(k.APIVersion) {
+		return false
+	}
+	_, ok := clusterScopedKinds[k.Kind]
+	return ok
+}
+
 // IsLess reports whether the key should sort before the given key.
 func (k ResourceKey) IsLess(a ResourceKey) bool {
 	if k.APIVersion < a.APIVersion {
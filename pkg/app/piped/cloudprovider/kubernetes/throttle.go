@@ -0,0 +1,72 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	// How many times a throttled kubectl command is retried.
+	throttledRetries = 5
+	// The base and maximum intervals of the exponential backoff between the retries.
+	throttledRetryBaseInterval = time.Second
+	throttledRetryMaxInterval  = 30 * time.Second
+)
+
+var (
+	// Rate limiters shared by all kubectl commands sent to the same cluster.
+	clusterRateLimiters   = make(map[string]flowcontrol.RateLimiter)
+	clusterRateLimitersMu sync.Mutex
+)
+
+// clusterRateLimiter returns the rate limiter of the kubectl commands sent to the given cluster.
+// Nil is returned when no client-side rate limit was configured.
+func clusterRateLimiter(cfg *config.CloudProviderKubernetesConfig) flowcontrol.RateLimiter {
+	if cfg == nil || cfg.RateLimit.QPS <= 0 {
+		return nil
+	}
+
+	burst := cfg.RateLimit.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(float64(cfg.RateLimit.QPS)))
+	}
+	key := fmt.Sprintf("%s/%s/%s/%v/%d", cfg.MasterURL, cfg.KubeConfigPath, cfg.KubeConfigContext, cfg.RateLimit.QPS, burst)
+
+	clusterRateLimitersMu.Lock()
+	defer clusterRateLimitersMu.Unlock()
+
+	l, ok := clusterRateLimiters[key]
+	if !ok {
+		l = flowcontrol.NewTokenBucketRateLimiter(cfg.RateLimit.QPS, burst)
+		clusterRateLimiters[key] = l
+	}
+	return l
+}
+
+// isThrottled reports whether the given kubectl output shows that
+// the request was rejected by the API server with 429 Too Many Requests.
+func isThrottled(out []byte) bool {
+	s := string(out)
+	return strings.Contains(s, "(TooManyRequests)") || strings.Contains(s, "the server has received too many requests")
+}
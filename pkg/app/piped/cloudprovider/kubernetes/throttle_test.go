@@ -0,0 +1,81 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestClusterRateLimiter(t *testing.T) {
+	assert.Nil(t, clusterRateLimiter(nil))
+	assert.Nil(t, clusterRateLimiter(&config.CloudProviderKubernetesConfig{}))
+
+	cfg := &config.CloudProviderKubernetesConfig{
+		KubeConfigPath: "kubeconfig",
+		RateLimit: config.KubernetesClientRateLimit{
+			QPS: 2.5,
+		},
+	}
+	l := clusterRateLimiter(cfg)
+	require.NotNil(t, l)
+	assert.Equal(t, float32(2.5), l.QPS())
+
+	// The limiter is shared by all providers of the same cluster.
+	assert.Same(t, l, clusterRateLimiter(&config.CloudProviderKubernetesConfig{
+		KubeConfigPath: "kubeconfig",
+		RateLimit: config.KubernetesClientRateLimit{
+			QPS: 2.5,
+		},
+	}))
+
+	// The burst defaults to the rounded up QPS.
+	assert.True(t, l.TryAccept())
+	assert.True(t, l.TryAccept())
+	assert.True(t, l.TryAccept())
+	assert.False(t, l.TryAccept())
+}
+
+func TestIsThrottled(t *testing.T) {
+	testcases := []struct {
+		name     string
+		out      string
+		expected bool
+	}{
+		{
+			name:     "throttled",
+			out:      "Error from server (TooManyRequests): the server has received too many requests and has asked us to try again later",
+			expected: true,
+		},
+		{
+			name:     "not found",
+			out:      `Error from server (NotFound): deployments.apps "simple" not found`,
+			expected: false,
+		},
+		{
+			name:     "empty",
+			expected: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isThrottled([]byte(tc.out)))
+		})
+	}
+}
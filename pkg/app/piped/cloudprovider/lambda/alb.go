@@ -0,0 +1,122 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+const albPermissionStatementID = "pipecd-alb-invoke"
+
+// TargetGroupWeight represents the weight of a target group in the forward action of a listener rule.
+type TargetGroupWeight struct {
+	TargetGroupArn string
+	Weight         int
+}
+
+// RegisterALBTarget makes the given target group target only the given version of the function.
+// The permission to invoke that version from Elastic Load Balancing is also added.
+func (c *client) RegisterALBTarget(ctx context.Context, targetGroupArn string, fm FunctionManifest, version string) error {
+	out, err := c.client.GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(fm.Spec.Name),
+		Qualifier:    aws.String(version),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get version %s of function %s: %w", version, fm.Spec.Name, err)
+	}
+	functionArn := aws.ToString(out.Configuration.FunctionArn)
+
+	_, err = c.client.AddPermission(ctx, &lambda.AddPermissionInput{
+		Action:       aws.String("lambda:InvokeFunction"),
+		FunctionName: aws.String(fm.Spec.Name),
+		Principal:    aws.String("elasticloadbalancing.amazonaws.com"),
+		Qualifier:    aws.String(version),
+		SourceArn:    aws.String(targetGroupArn),
+		StatementId:  aws.String(albPermissionStatementID),
+	})
+	if err != nil {
+		var rce *types.ResourceConflictException
+		if !errors.As(err, &rce) {
+			return fmt.Errorf("failed to allow target group to invoke function %s: %w", functionArn, err)
+		}
+	}
+
+	// A target group of Lambda type can have only one target,
+	// so the previously registered one must be removed first.
+	health, err := c.elbClient.DescribeTargetHealth(ctx, &elasticloadbalancingv2.DescribeTargetHealthInput{
+		TargetGroupArn: aws.String(targetGroupArn),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe targets of target group %s: %w", targetGroupArn, err)
+	}
+	var stale []elbtypes.TargetDescription
+	for _, h := range health.TargetHealthDescriptions {
+		if h.Target == nil || aws.ToString(h.Target.Id) == functionArn {
+			continue
+		}
+		stale = append(stale, elbtypes.TargetDescription{Id: h.Target.Id})
+	}
+	if len(stale) > 0 {
+		_, err := c.elbClient.DeregisterTargets(ctx, &elasticloadbalancingv2.DeregisterTargetsInput{
+			TargetGroupArn: aws.String(targetGroupArn),
+			Targets:        stale,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to deregister targets from target group %s: %w", targetGroupArn, err)
+		}
+	}
+
+	_, err = c.elbClient.RegisterTargets(ctx, &elasticloadbalancingv2.RegisterTargetsInput{
+		TargetGroupArn: aws.String(targetGroupArn),
+		Targets: []elbtypes.TargetDescription{
+			{Id: aws.String(functionArn)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register function %s to target group %s: %w", functionArn, targetGroupArn, err)
+	}
+	return nil
+}
+
+// ModifyListenerRule updates the forward action of the given listener rule to distribute traffic by the given weights.
+func (c *client) ModifyListenerRule(ctx context.Context, ruleArn string, weights []TargetGroupWeight) error {
+	tuples := make([]elbtypes.TargetGroupTuple, 0, len(weights))
+	for _, w := range weights {
+		tuples = append(tuples, elbtypes.TargetGroupTuple{
+			TargetGroupArn: aws.String(w.TargetGroupArn),
+			Weight:         aws.Int32(int32(w.Weight)),
+		})
+	}
+	_, err := c.elbClient.ModifyRule(ctx, &elasticloadbalancingv2.ModifyRuleInput{
+		RuleArn: aws.String(ruleArn),
+		Actions: []elbtypes.Action{
+			{
+				Type: elbtypes.ActionTypeEnumForward,
+				ForwardConfig: &elbtypes.ForwardActionConfig{
+					TargetGroups: tuples,
+				},
+			},
+		},
+	})
+	return err
+}
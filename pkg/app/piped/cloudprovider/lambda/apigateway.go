@@ -0,0 +1,140 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// The Go SDK of API Gateway is not used by piped yet,
+// so the REST API is called directly with the requests signed by Signature V4.
+
+type apiGatewayStage struct {
+	DeploymentID   string                    `json:"deploymentId"`
+	Variables      map[string]string         `json:"variables,omitempty"`
+	CanarySettings *apiGatewayCanarySettings `json:"canarySettings,omitempty"`
+}
+
+type apiGatewayCanarySettings struct {
+	DeploymentID           string            `json:"deploymentId,omitempty"`
+	PercentTraffic         float64           `json:"percentTraffic"`
+	StageVariableOverrides map[string]string `json:"stageVariableOverrides,omitempty"`
+}
+
+type apiGatewayPatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value string `json:"value,omitempty"`
+}
+
+// UpdateAPIGatewayCanary routes the given percent of the traffic coming to the given stage
+// to the canary where the stage variable is overridden by the given version.
+// The canary settings of the stage are removed when the percent is zero.
+func (c *client) UpdateAPIGatewayCanary(ctx context.Context, restAPIID, stage, stageVariable, version string, percent int) error {
+	stagePath := fmt.Sprintf("/restapis/%s/stages/%s", url.PathEscape(restAPIID), url.PathEscape(stage))
+	var current apiGatewayStage
+	if err := c.doAPIGatewayRequest(ctx, http.MethodGet, stagePath, nil, &current); err != nil {
+		return fmt.Errorf("failed to get stage %s of API %s: %w", stage, restAPIID, err)
+	}
+
+	if percent == 0 {
+		if current.CanarySettings == nil {
+			return nil
+		}
+		ops := []apiGatewayPatchOperation{
+			{Op: "remove", Path: "/canarySettings"},
+		}
+		return c.doAPIGatewayRequest(ctx, http.MethodPatch, stagePath, map[string]interface{}{"patchOperations": ops}, nil)
+	}
+
+	// The canary has to be created by a new deployment to the stage.
+	if current.CanarySettings == nil {
+		deployment := map[string]interface{}{
+			"stageName": stage,
+			"canarySettings": apiGatewayCanarySettings{
+				PercentTraffic:         float64(percent),
+				StageVariableOverrides: map[string]string{stageVariable: version},
+			},
+		}
+		deploymentsPath := fmt.Sprintf("/restapis/%s/deployments", url.PathEscape(restAPIID))
+		return c.doAPIGatewayRequest(ctx, http.MethodPost, deploymentsPath, deployment, nil)
+	}
+
+	ops := []apiGatewayPatchOperation{
+		{Op: "replace", Path: "/canarySettings/percentTraffic", Value: strconv.Itoa(percent)},
+		{Op: "replace", Path: "/canarySettings/stageVariableOverrides/" + stageVariable, Value: version},
+	}
+	return c.doAPIGatewayRequest(ctx, http.MethodPatch, stagePath, map[string]interface{}{"patchOperations": ops}, nil)
+}
+
+func (c *client) doAPIGatewayRequest(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = data
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.apiGatewayEndpoint+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	creds, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	if err := v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(sum[:]), "apigateway", c.region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, string(data))
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(data))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateAPIGatewayCanary(t *testing.T) {
+	testcases := []struct {
+		name            string
+		stage           string
+		percent         int
+		expectedRequest string
+		expectedBody    string
+	}{
+		{
+			name:            "create canary by new deployment",
+			stage:           `{"deploymentId":"d1","variables":{"alias":"Service"}}`,
+			percent:         20,
+			expectedRequest: "POST /restapis/api/deployments",
+			expectedBody:    `{"canarySettings":{"percentTraffic":20,"stageVariableOverrides":{"alias":"3"}},"stageName":"prod"}`,
+		},
+		{
+			name:            "update existing canary",
+			stage:           `{"deploymentId":"d1","canarySettings":{"deploymentId":"d2","percentTraffic":20}}`,
+			percent:         50,
+			expectedRequest: "PATCH /restapis/api/stages/prod",
+			expectedBody:    `{"patchOperations":[{"op":"replace","path":"/canarySettings/percentTraffic","value":"50"},{"op":"replace","path":"/canarySettings/stageVariableOverrides/alias","value":"3"}]}`,
+		},
+		{
+			name:            "remove canary",
+			stage:           `{"deploymentId":"d1","canarySettings":{"deploymentId":"d2","percentTraffic":50}}`,
+			percent:         0,
+			expectedRequest: "PATCH /restapis/api/stages/prod",
+			expectedBody:    `{"patchOperations":[{"op":"remove","path":"/canarySettings"}]}`,
+		},
+		{
+			name:    "no canary to remove",
+			stage:   `{"deploymentId":"d1"}`,
+			percent: 0,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var request, body string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Contains(t, r.Header.Get("Authorization"), "AWS4-HMAC-SHA256")
+				if r.Method == http.MethodGet {
+					w.Write([]byte(tc.stage))
+					return
+				}
+				data, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+				request = r.Method + " " + r.URL.Path
+				body = string(data)
+				w.Write([]byte("{}"))
+			}))
+			defer srv.Close()
+
+			c := &client{
+				credentials:        credentials.NewStaticCredentialsProvider("key", "secret", ""),
+				region:             "us-west-2",
+				httpClient:         srv.Client(),
+				apiGatewayEndpoint: srv.URL,
+			}
+			err := c.UpdateAPIGatewayCanary(context.Background(), "api", "prod", "alias", "3", tc.percent)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRequest, request)
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, body)
+			} else {
+				assert.Empty(t, body)
+			}
+		})
+	}
+}
+
+func TestAPIGatewayNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid stage identifier specified"})
+	}))
+	defer srv.Close()
+
+	c := &client{
+		credentials:        credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		region:             "us-west-2",
+		httpClient:         srv.Client(),
+		apiGatewayEndpoint: srv.URL,
+	}
+	err := c.UpdateAPIGatewayCanary(context.Background(), "api", "prod", "alias", "3", 20)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
@@ -18,14 +18,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"reflect"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/backoff"
@@ -43,11 +46,18 @@ const (
 var ErrNotFound = errors.New("lambda resource not found")
 
 type client struct {
-	client *lambda.Client
-	logger *zap.Logger
+	client    *lambda.Client
+	elbClient *elasticloadbalancingv2.Client
+	// Used to sign the requests to API Gateway and Resource Groups Tagging API.
+	credentials        aws.CredentialsProvider
+	region             string
+	httpClient         *http.Client
+	apiGatewayEndpoint string
+	taggingEndpoint    string
+	logger             *zap.Logger
 }
 
-func newClient(region, profile, credentialsFile, roleARN, tokenPath string, logger *zap.Logger) (*client, error) {
+func newClient(region, profile, credentialsFile, roleARN, tokenPath, assumeRoleARN, externalID string, logger *zap.Logger) (*client, error) {
 	if region == "" {
 		return nil, fmt.Errorf("region is required field")
 	}
@@ -86,7 +96,23 @@ func newClient(region, profile, credentialsFile, roleARN, tokenPath string, logg
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config to create lambda client: %w", err)
 	}
+	if assumeRoleARN != "" {
+		// Chain the loaded credentials to assume the target role,
+		// e.g. from the IRSA role of piped to a role in another account.
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), assumeRoleARN, func(o *stscreds.AssumeRoleOptions) {
+			if externalID != "" {
+				o.ExternalID = aws.String(externalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
 	c.client = lambda.NewFromConfig(cfg)
+	c.elbClient = elasticloadbalancingv2.NewFromConfig(cfg)
+	c.credentials = cfg.Credentials
+	c.region = region
+	c.httpClient = http.DefaultClient
+	c.apiGatewayEndpoint = fmt.Sprintf("https://apigateway.%s.amazonaws.com", region)
+	c.taggingEndpoint = fmt.Sprintf("https://tagging.%s.amazonaws.com", region)
 
 	return c, nil
 }
@@ -108,11 +134,13 @@ func (c *client) IsFunctionExist(ctx context.Context, name string) (bool, error)
 }
 
 func (c *client) CreateFunction(ctx context.Context, fm FunctionManifest) error {
+	code, err := functionCode(fm)
+	if err != nil {
+		return err
+	}
 	input := &lambda.CreateFunctionInput{
-		Code: &types.FunctionCode{
-			ImageUri: aws.String(fm.Spec.ImageURI),
-		},
-		PackageType:  types.PackageType("Image"),
+		Code:         code,
+		PackageType:  types.PackageType(fm.Spec.packageType()),
 		Role:         aws.String(fm.Spec.Role),
 		FunctionName: aws.String(fm.Spec.Name),
 		Tags:         fm.Spec.Tags,
@@ -120,7 +148,11 @@ func (c *client) CreateFunction(ctx context.Context, fm FunctionManifest) error
 			Variables: fm.Spec.Environments,
 		},
 	}
-	_, err := c.client.CreateFunction(ctx, input)
+	if fm.Spec.IsZipPackage() {
+		input.Runtime = types.Runtime(fm.Spec.Runtime)
+		input.Handler = aws.String(fm.Spec.Handler)
+	}
+	_, err = c.client.CreateFunction(ctx, input)
 	if err != nil {
 		return fmt.Errorf("failed to create Lambda function %s: %w", fm.Spec.Name, err)
 	}
@@ -131,7 +163,19 @@ func (c *client) UpdateFunction(ctx context.Context, fm FunctionManifest) error
 	// Update function code.
 	codeInput := &lambda.UpdateFunctionCodeInput{
 		FunctionName: aws.String(fm.Spec.Name),
-		ImageUri:     aws.String(fm.Spec.ImageURI),
+	}
+	if fm.Spec.IsZipPackage() {
+		bucket, key, err := parseS3URI(fm.Spec.S3URI)
+		if err != nil {
+			return err
+		}
+		codeInput.S3Bucket = aws.String(bucket)
+		codeInput.S3Key = aws.String(key)
+		if fm.Spec.S3ObjectVersion != "" {
+			codeInput.S3ObjectVersion = aws.String(fm.Spec.S3ObjectVersion)
+		}
+	} else {
+		codeInput.ImageUri = aws.String(fm.Spec.ImageURI)
 	}
 	_, err := c.client.UpdateFunctionCode(ctx, codeInput)
 	if err != nil {
@@ -150,6 +194,10 @@ func (c *client) UpdateFunction(ctx context.Context, fm FunctionManifest) error
 				Variables: fm.Spec.Environments,
 			},
 		}
+		if fm.Spec.IsZipPackage() {
+			configInput.Runtime = types.Runtime(fm.Spec.Runtime)
+			configInput.Handler = aws.String(fm.Spec.Handler)
+		}
 		_, err = c.client.UpdateFunctionConfiguration(ctx, configInput)
 		if err != nil {
 			c.logger.Error("Failed to update function configuration")
@@ -166,6 +214,28 @@ func (c *client) UpdateFunction(ctx context.Context, fm FunctionManifest) error
 	return c.updateTagsConfig(ctx, fm)
 }
 
+// functionCode returns the code of the function to create
+// from either the container image or the zip archive stored in S3.
+func functionCode(fm FunctionManifest) (*types.FunctionCode, error) {
+	if !fm.Spec.IsZipPackage() {
+		return &types.FunctionCode{
+			ImageUri: aws.String(fm.Spec.ImageURI),
+		}, nil
+	}
+	bucket, key, err := parseS3URI(fm.Spec.S3URI)
+	if err != nil {
+		return nil, err
+	}
+	code := &types.FunctionCode{
+		S3Bucket: aws.String(bucket),
+		S3Key:    aws.String(key),
+	}
+	if fm.Spec.S3ObjectVersion != "" {
+		code.S3ObjectVersion = aws.String(fm.Spec.S3ObjectVersion)
+	}
+	return code, nil
+}
+
 func (c *client) PublishFunction(ctx context.Context, fm FunctionManifest) (string, error) {
 	input := &lambda.PublishVersionInput{
 		FunctionName: aws.String(fm.Spec.Name),
@@ -179,8 +249,14 @@ func (c *client) PublishFunction(ctx context.Context, fm FunctionManifest) (stri
 
 // GetTrafficConfig returns lambda provider.ErrNotFound in case remote traffic config is not existed.
 func (c *client) GetTrafficConfig(ctx context.Context, fm FunctionManifest) (routingTrafficCfg RoutingTrafficConfig, err error) {
+	return c.GetAliasTrafficConfig(ctx, fm.Spec.Name)
+}
+
+// GetAliasTrafficConfig returns the traffic config of the alias of the given function.
+// lambda provider.ErrNotFound is returned in case remote traffic config is not existed.
+func (c *client) GetAliasTrafficConfig(ctx context.Context, name string) (routingTrafficCfg RoutingTrafficConfig, err error) {
 	input := &lambda.GetAliasInput{
-		FunctionName: aws.String(fm.Spec.Name),
+		FunctionName: aws.String(name),
 		Name:         aws.String(defaultAliasName),
 	}
 
@@ -241,6 +317,34 @@ func (c *client) GetTrafficConfig(ctx context.Context, fm FunctionManifest) (rou
 	return
 }
 
+// GetFunctionState returns lambda provider.ErrNotFound in case the function is not existed.
+func (c *client) GetFunctionState(ctx context.Context, name string) (*FunctionState, error) {
+	output, err := c.client.GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(name),
+	})
+	if err != nil {
+		var nfe *types.ResourceNotFoundException
+		if errors.As(err, &nfe) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get Lambda function %s: %w", name, err)
+	}
+
+	state := &FunctionState{
+		Name: name,
+	}
+	if output.Code != nil {
+		state.ImageURI = aws.ToString(output.Code.ImageUri)
+	}
+	if cfg := output.Configuration; cfg != nil {
+		state.State = string(cfg.State)
+		state.StateReason = aws.ToString(cfg.StateReason)
+		state.LastUpdateStatus = string(cfg.LastUpdateStatus)
+		state.LastUpdateStatusReason = aws.ToString(cfg.LastUpdateStatusReason)
+	}
+	return state, nil
+}
+
 func (c *client) CreateTrafficConfig(ctx context.Context, fm FunctionManifest, version string) error {
 	input := &lambda.CreateAliasInput{
 		FunctionName:    aws.String(fm.Spec.Name),
@@ -17,6 +17,8 @@ package lambda
 import (
 	"fmt"
 	"io/ioutil"
+	"net/url"
+	"path"
 	"strings"
 
 	"sigs.k8s.io/yaml"
@@ -30,6 +32,9 @@ const (
 	memoryLowerLimit  = 1
 	timeoutLowerLimit = 1
 	timeoutUpperLimit = 900
+
+	packageTypeImage = "Image"
+	packageTypeZip   = "Zip"
 )
 
 type FunctionManifest struct {
@@ -52,10 +57,18 @@ func (fm *FunctionManifest) validate() error {
 }
 
 // FunctionManifestSpec contains configuration for LambdaFunction.
+// The function code is given by either the container image or the zip archive stored in S3.
 type FunctionManifestSpec struct {
-	Name         string            `json:"name"`
-	Role         string            `json:"role"`
-	ImageURI     string            `json:"image"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	ImageURI string `json:"image,omitempty"`
+	// The S3 URI of the zip archive of the function code, e.g. s3://bucket/path/function.zip.
+	S3URI string `json:"s3Uri,omitempty"`
+	// The version of the S3 object. Empty means the latest one.
+	S3ObjectVersion string `json:"s3ObjectVersion,omitempty"`
+	// The runtime and handler are required for the function deployed from zip archive.
+	Runtime      string            `json:"runtime,omitempty"`
+	Handler      string            `json:"handler,omitempty"`
 	Memory       int32             `json:"memory"`
 	Timeout      int32             `json:"timeout"`
 	Tags         map[string]string `json:"tags,omitempty"`
@@ -66,8 +79,18 @@ func (fmp FunctionManifestSpec) validate() error {
 	if len(fmp.Name) == 0 {
 		return fmt.Errorf("lambda function is missing")
 	}
-	if len(fmp.ImageURI) == 0 {
-		return fmt.Errorf("image uri is missing")
+	switch {
+	case len(fmp.ImageURI) == 0 && len(fmp.S3URI) == 0:
+		return fmt.Errorf("either image uri or s3 uri must be specified")
+	case len(fmp.ImageURI) != 0 && len(fmp.S3URI) != 0:
+		return fmt.Errorf("image uri and s3 uri must not be specified at the same time")
+	case len(fmp.S3URI) != 0:
+		if _, _, err := parseS3URI(fmp.S3URI); err != nil {
+			return err
+		}
+		if len(fmp.Runtime) == 0 || len(fmp.Handler) == 0 {
+			return fmt.Errorf("runtime and handler are required for zip archive")
+		}
 	}
 	if len(fmp.Role) == 0 {
 		return fmt.Errorf("role is missing")
@@ -100,9 +123,30 @@ func parseFunctionManifest(data []byte) (FunctionManifest, error) {
 	return obj, nil
 }
 
+// IsZipPackage returns true if the function code is given by the zip archive stored in S3.
+func (fmp FunctionManifestSpec) IsZipPackage() bool {
+	return fmp.S3URI != ""
+}
+
+func (fmp FunctionManifestSpec) packageType() string {
+	if fmp.IsZipPackage() {
+		return packageTypeZip
+	}
+	return packageTypeImage
+}
+
+// parseS3URI returns the bucket and key of the given URI formatted as s3://bucket/key.
+func parseS3URI(uri string) (bucket, key string, err error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "s3" || u.Host == "" || len(u.Path) <= 1 {
+		return "", "", fmt.Errorf("invalid s3 uri: %s", uri)
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
 // DecideRevisionName returns revision name to apply.
 func DecideRevisionName(fm FunctionManifest, commit string) (string, error) {
-	tag, err := FindImageTag(fm)
+	tag, err := FindArtifactVersion(fm)
 	if err != nil {
 		return "", err
 	}
@@ -123,6 +167,23 @@ func FindImageTag(fm FunctionManifest) (string, error) {
 	return tag, nil
 }
 
+// FindArtifactVersion returns the version of the function code.
+// That is the image tag for the container image or
+// the object version (or the file name if not specified) for the zip archive.
+func FindArtifactVersion(fm FunctionManifest) (string, error) {
+	if !fm.Spec.IsZipPackage() {
+		return FindImageTag(fm)
+	}
+	if fm.Spec.S3ObjectVersion != "" {
+		return fm.Spec.S3ObjectVersion, nil
+	}
+	_, key, err := parseS3URI(fm.Spec.S3URI)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(path.Base(key), ".zip"), nil
+}
+
 func parseContainerImage(image string) (name, tag string) {
 	parts := strings.Split(image, ":")
 	if len(parts) == 2 {
@@ -20,7 +20,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestparseFunctionManifest(t *testing.T) {
+func TestParseFunctionManifest(t *testing.T) {
 	testcases := []struct {
 		name     string
 		data     string
@@ -90,6 +90,72 @@ func TestparseFunctionManifest(t *testing.T) {
 	  "timeout": 1000,
 	  "image": "ecr.region.amazonaws.com/lambda-simple-function:v0.0.1"
   }
+}`,
+			wantSpec: FunctionManifest{},
+			wantErr:  true,
+		},
+		{
+			name: "zip archive in s3",
+			data: `{
+  "apiVersion": "pipecd.dev/v1beta1",
+  "kind": "LambdaFunction",
+  "spec": {
+	  "name": "SimpleFunction",
+	  "role": "arn:aws:iam::xxxxx:role/lambda-role",
+	  "memory": 128,
+	  "timeout": 5,
+	  "s3Uri": "s3://pipecd-artifacts/simple/function-v0.0.1.zip",
+	  "runtime": "go1.x",
+	  "handler": "main"
+  }
+}`,
+			wantSpec: FunctionManifest{
+				Kind:       "LambdaFunction",
+				APIVersion: "pipecd.dev/v1beta1",
+				Spec: FunctionManifestSpec{
+					Name:    "SimpleFunction",
+					Role:    "arn:aws:iam::xxxxx:role/lambda-role",
+					Memory:  128,
+					Timeout: 5,
+					S3URI:   "s3://pipecd-artifacts/simple/function-v0.0.1.zip",
+					Runtime: "go1.x",
+					Handler: "main",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "both image and s3 uri",
+			data: `{
+  "apiVersion": "pipecd.dev/v1beta1",
+  "kind": "LambdaFunction",
+  "spec": {
+	  "name": "SimpleFunction",
+	  "role": "arn:aws:iam::xxxxx:role/lambda-role",
+	  "memory": 128,
+	  "timeout": 5,
+	  "image": "ecr.region.amazonaws.com/lambda-simple-function:v0.0.1",
+	  "s3Uri": "s3://pipecd-artifacts/simple/function-v0.0.1.zip",
+	  "runtime": "go1.x",
+	  "handler": "main"
+  }
+}`,
+			wantSpec: FunctionManifest{},
+			wantErr:  true,
+		},
+		{
+			name: "zip archive without handler",
+			data: `{
+  "apiVersion": "pipecd.dev/v1beta1",
+  "kind": "LambdaFunction",
+  "spec": {
+	  "name": "SimpleFunction",
+	  "role": "arn:aws:iam::xxxxx:role/lambda-role",
+	  "memory": 128,
+	  "timeout": 5,
+	  "s3Uri": "s3://pipecd-artifacts/simple/function-v0.0.1.zip",
+	  "runtime": "go1.x"
+  }
 }`,
 			wantSpec: FunctionManifest{},
 			wantErr:  true,
@@ -103,3 +169,37 @@ func TestparseFunctionManifest(t *testing.T) {
 		})
 	}
 }
+
+func TestFindArtifactVersion(t *testing.T) {
+	testcases := []struct {
+		name     string
+		spec     FunctionManifestSpec
+		expected string
+	}{
+		{
+			name:     "container image",
+			spec:     FunctionManifestSpec{ImageURI: "ecr.region.amazonaws.com/lambda-simple-function:v0.0.1"},
+			expected: "v0.0.1",
+		},
+		{
+			name:     "zip archive",
+			spec:     FunctionManifestSpec{S3URI: "s3://pipecd-artifacts/simple/function-v0.0.1.zip"},
+			expected: "function-v0.0.1",
+		},
+		{
+			name: "zip archive with object version",
+			spec: FunctionManifestSpec{
+				S3URI:           "s3://pipecd-artifacts/simple/function.zip",
+				S3ObjectVersion: "3sL4kqtJlcpXroDTDmJ",
+			},
+			expected: "3sL4kqtJlcpXroDTDmJ",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := FindArtifactVersion(FunctionManifest{Spec: tc.spec})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -25,6 +25,28 @@ import (
 	"github.com/pipe-cd/pipe/pkg/config"
 )
 
+const (
+	// TagManagedBy is the tag key added to every function deployed by piped.
+	TagManagedBy = "pipecd-dev-managed-by"
+	// TagApplication is the tag key holding the ID of the application the function belongs to.
+	TagApplication = "pipecd-dev-application"
+	// ManagedByPiped is the value of TagManagedBy for functions deployed by piped.
+	ManagedByPiped = "piped"
+)
+
+// FunctionState represents the current state of a deployed Lambda function.
+type FunctionState struct {
+	Name string
+	// The image URI of the $LATEST version.
+	ImageURI string
+	// The state of the function, e.g. Active, Pending, Failed.
+	State       string
+	StateReason string
+	// The status of the last update, e.g. Successful, InProgress, Failed.
+	LastUpdateStatus       string
+	LastUpdateStatusReason string
+}
+
 // Client is wrapper of AWS client.
 type Client interface {
 	IsFunctionExist(ctx context.Context, name string) (bool, error)
@@ -34,6 +56,12 @@ type Client interface {
 	GetTrafficConfig(ctx context.Context, fm FunctionManifest) (routingTrafficCfg RoutingTrafficConfig, err error)
 	CreateTrafficConfig(ctx context.Context, fm FunctionManifest, version string) error
 	UpdateTrafficConfig(ctx context.Context, fm FunctionManifest, routingTraffic RoutingTrafficConfig) error
+	ListTaggedFunctions(ctx context.Context, tagKey string) (map[string]string, error)
+	GetFunctionState(ctx context.Context, name string) (*FunctionState, error)
+	GetAliasTrafficConfig(ctx context.Context, name string) (RoutingTrafficConfig, error)
+	RegisterALBTarget(ctx context.Context, targetGroupArn string, fm FunctionManifest, version string) error
+	ModifyListenerRule(ctx context.Context, ruleArn string, weights []TargetGroupWeight) error
+	UpdateAPIGatewayCanary(ctx context.Context, restAPIID, stage, stageVariable, version string, percent int) error
 }
 
 // Registry holds a pool of aws client wrappers.
@@ -62,7 +90,7 @@ func (r *registry) Client(name string, cfg *config.CloudProviderLambdaConfig, lo
 	}
 
 	c, err, _ := r.newGroup.Do(name, func() (interface{}, error) {
-		return newClient(cfg.Region, cfg.Profile, cfg.CredentialsFile, cfg.RoleARN, cfg.TokenFile, logger)
+		return newClient(cfg.Region, cfg.Profile, cfg.CredentialsFile, cfg.RoleARN, cfg.TokenFile, cfg.AssumeRoleARN, cfg.AssumeRoleExternalID, logger)
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,142 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// The Resource Groups Tagging API returns the tags of many resources at once,
+// so it is used instead of listing the tags of each function one by one.
+// Its Go SDK is not used by piped yet, so the requests are signed and sent directly.
+
+const taggingAPITarget = "ResourceGroupsTaggingAPI_20170126.GetResources"
+
+type taggingTagFilter struct {
+	Key string `json:"Key"`
+}
+
+type taggingGetResourcesInput struct {
+	PaginationToken     string             `json:"PaginationToken,omitempty"`
+	ResourceTypeFilters []string           `json:"ResourceTypeFilters"`
+	TagFilters          []taggingTagFilter `json:"TagFilters"`
+}
+
+type taggingTag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+type taggingResource struct {
+	ResourceARN string       `json:"ResourceARN"`
+	Tags        []taggingTag `json:"Tags"`
+}
+
+type taggingGetResourcesOutput struct {
+	PaginationToken        string            `json:"PaginationToken"`
+	ResourceTagMappingList []taggingResource `json:"ResourceTagMappingList"`
+}
+
+// ListTaggedFunctions returns a map from name to the tag value of all functions having the given tag key.
+func (c *client) ListTaggedFunctions(ctx context.Context, tagKey string) (map[string]string, error) {
+	var (
+		functions = make(map[string]string)
+		input     = taggingGetResourcesInput{
+			ResourceTypeFilters: []string{"lambda:function"},
+			TagFilters:          []taggingTagFilter{{Key: tagKey}},
+		}
+	)
+	for {
+		var output taggingGetResourcesOutput
+		if err := c.doTaggingRequest(ctx, input, &output); err != nil {
+			return nil, fmt.Errorf("failed to list tagged Lambda functions: %w", err)
+		}
+		for _, r := range output.ResourceTagMappingList {
+			name := functionNameFromARN(r.ResourceARN)
+			if name == "" {
+				continue
+			}
+			for _, t := range r.Tags {
+				if t.Key == tagKey {
+					functions[name] = t.Value
+					break
+				}
+			}
+		}
+		if output.PaginationToken == "" {
+			return functions, nil
+		}
+		input.PaginationToken = output.PaginationToken
+	}
+}
+
+// functionNameFromARN returns the function name from an ARN
+// like arn:aws:lambda:us-west-2:123456789012:function:name[:qualifier].
+func functionNameFromARN(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 7 || parts[5] != "function" {
+		return ""
+	}
+	return parts[6]
+}
+
+func (c *client) doTaggingRequest(ctx context.Context, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.taggingEndpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", taggingAPITarget)
+
+	creds, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	if err := v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(sum[:]), "tagging", c.region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(data))
+	}
+	return json.Unmarshal(data, out)
+}
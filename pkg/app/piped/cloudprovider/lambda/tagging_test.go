@@ -0,0 +1,97 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListTaggedFunctions(t *testing.T) {
+	var requests []taggingGetResourcesInput
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != taggingAPITarget {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var in taggingGetResourcesInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, in)
+
+		out := taggingGetResourcesOutput{
+			PaginationToken: "next",
+			ResourceTagMappingList: []taggingResource{
+				{
+					ResourceARN: "arn:aws:lambda:us-west-2:123456789012:function:foo",
+					Tags:        []taggingTag{{Key: "env", Value: "dev"}, {Key: "pipecd-app", Value: "app-1"}},
+				},
+			},
+		}
+		if in.PaginationToken == "next" {
+			out = taggingGetResourcesOutput{
+				ResourceTagMappingList: []taggingResource{
+					{
+						ResourceARN: "arn:aws:lambda:us-west-2:123456789012:function:bar:live",
+						Tags:        []taggingTag{{Key: "pipecd-app", Value: "app-2"}},
+					},
+				},
+			}
+		}
+		json.NewEncoder(w).Encode(out)
+	}))
+	defer srv.Close()
+
+	c := &client{
+		credentials:     credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		region:          "us-west-2",
+		httpClient:      srv.Client(),
+		taggingEndpoint: srv.URL,
+	}
+	functions, err := c.ListTaggedFunctions(context.Background(), "pipecd-app")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"foo": "app-1", "bar": "app-2"}, functions)
+
+	require.Len(t, requests, 2)
+	assert.Equal(t, []string{"lambda:function"}, requests[0].ResourceTypeFilters)
+	assert.Equal(t, []taggingTagFilter{{Key: "pipecd-app"}}, requests[0].TagFilters)
+	assert.Equal(t, "next", requests[1].PaginationToken)
+}
+
+func TestFunctionNameFromARN(t *testing.T) {
+	testcases := []struct {
+		arn      string
+		expected string
+	}{
+		{arn: "arn:aws:lambda:us-west-2:123456789012:function:foo", expected: "foo"},
+		{arn: "arn:aws:lambda:us-west-2:123456789012:function:foo:1", expected: "foo"},
+		{arn: "arn:aws:lambda:us-west-2:123456789012:layer:foo", expected: ""},
+		{arn: "invalid", expected: ""},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.arn, func(t *testing.T) {
+			assert.Equal(t, tc.expected, functionNameFromARN(tc.arn))
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pulumi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+type options struct {
+	envs []string
+}
+
+type Option func(*options)
+
+// WithEnvs sets the given environment variables formatted by "key=value"
+// to all pulumi commands.
+func WithEnvs(envs []string) Option {
+	return func(opts *options) {
+		opts.envs = append(opts.envs, envs...)
+	}
+}
+
+type Pulumi struct {
+	execPath string
+	dir      string
+
+	options options
+}
+
+func NewPulumi(execPath, dir string, opts ...Option) *Pulumi {
+	opt := options{}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return &Pulumi{
+		execPath: execPath,
+		dir:      dir,
+		options:  opt,
+	}
+}
+
+// EnvsFromConfig returns the environment variables needed to
+// connect to the backend configured in the given cloud provider.
+func EnvsFromConfig(cfg *config.CloudProviderPulumiConfig) ([]string, error) {
+	var envs []string
+	if cfg.BackendURL != "" {
+		envs = append(envs, "PULUMI_BACKEND_URL="+cfg.BackendURL)
+	}
+	if cfg.AccessTokenFile != "" {
+		token, err := ioutil.ReadFile(cfg.AccessTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read access token file %s: %w", cfg.AccessTokenFile, err)
+		}
+		envs = append(envs, "PULUMI_ACCESS_TOKEN="+strings.TrimSpace(string(token)))
+	}
+	if cfg.ConfigPassphraseFile != "" {
+		envs = append(envs, "PULUMI_CONFIG_PASSPHRASE_FILE="+cfg.ConfigPassphraseFile)
+	}
+	return envs, nil
+}
+
+func (p *Pulumi) command(ctx context.Context, args ...string) *toolexec.Cmd {
+	cmd := toolexec.CommandContext(ctx, p.execPath, args...)
+	cmd.Dir = p.dir
+	cmd.Env = append(os.Environ(), "PULUMI_SKIP_UPDATE_CHECK=true")
+	cmd.Env = append(cmd.Env, p.options.envs...)
+	return cmd
+}
+
+func (p *Pulumi) Version(ctx context.Context) (string, error) {
+	out, err := p.command(ctx, "version").CombinedOutput()
+	if err != nil {
+		return string(out), err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (p *Pulumi) SelectStack(ctx context.Context, stack string) error {
+	out, err := p.command(ctx, "stack", "select", stack, "--non-interactive").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to select stack: %s (%w)", string(out), err)
+	}
+
+	return nil
+}
+
+// SetConfig sets the given configuration values to the selected stack.
+func (p *Pulumi) SetConfig(ctx context.Context, values map[string]string) error {
+	for k, v := range values {
+		// The value is put after "--" to allow values starting with "-".
+		out, err := p.command(ctx, "config", "set", k, "--non-interactive", "--", v).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to set config %s: %s (%w)", k, string(out), err)
+		}
+	}
+	return nil
+}
+
+func (p *Pulumi) Refresh(ctx context.Context, w io.Writer) error {
+	args := []string{
+		"refresh",
+		"--yes",
+		"--skip-preview",
+		"--non-interactive",
+		"--color=never",
+	}
+	return p.run(ctx, w, args)
+}
+
+type PreviewResult struct {
+	Creates  int
+	Updates  int
+	Deletes  int
+	Replaces int
+}
+
+func (r PreviewResult) NoChanges() bool {
+	return r.Creates == 0 && r.Updates == 0 && r.Deletes == 0 && r.Replaces == 0
+}
+
+func (r PreviewResult) String() string {
+	return fmt.Sprintf("%d to create, %d to update, %d to delete, %d to replace", r.Creates, r.Updates, r.Deletes, r.Replaces)
+}
+
+func (p *Pulumi) Preview(ctx context.Context, w io.Writer) (PreviewResult, error) {
+	args := []string{
+		"preview",
+		"--diff",
+		"--non-interactive",
+		"--color=never",
+	}
+
+	var buf bytes.Buffer
+	if err := p.run(ctx, io.MultiWriter(w, &buf), args); err != nil {
+		return PreviewResult{}, err
+	}
+	return parsePreviewResult(buf.String())
+}
+
+func (p *Pulumi) Up(ctx context.Context, w io.Writer) error {
+	args := []string{
+		"up",
+		"--yes",
+		"--skip-preview",
+		"--non-interactive",
+		"--color=never",
+	}
+	return p.run(ctx, w, args)
+}
+
+func (p *Pulumi) run(ctx context.Context, w io.Writer, args []string) error {
+	cmd := p.command(ctx, args...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	io.WriteString(w, fmt.Sprintf("pulumi %s\n", strings.Join(args, " ")))
+	return cmd.Run()
+}
+
+var (
+	previewSummaryRegex  = regexp.MustCompile(`(?m)^Resources:\s*$`)
+	previewCreatesRegex  = regexp.MustCompile(`(?m)^\s*\+\s*(\d+) to create$`)
+	previewUpdatesRegex  = regexp.MustCompile(`(?m)^\s*~\s*(\d+) to update$`)
+	previewDeletesRegex  = regexp.MustCompile(`(?m)^\s*-\s*(\d+) to delete$`)
+	previewReplacesRegex = regexp.MustCompile(`(?m)^\s*\+-\s*(\d+) to replace$`)
+)
+
+// parsePreviewResult parses the "Resources:" summary printed at the end of the preview output,
+// which contains a line like "+ 1 to create" or "+-1 to replace" for each kind of changes.
+func parsePreviewResult(out string) (PreviewResult, error) {
+	if !previewSummaryRegex.MatchString(out) {
+		return PreviewResult{}, fmt.Errorf("unable to parse preview output")
+	}
+
+	count := func(re *regexp.Regexp) int {
+		s := re.FindStringSubmatch(out)
+		if len(s) != 2 {
+			return 0
+		}
+		n, _ := strconv.Atoi(s[1])
+		return n
+	}
+
+	return PreviewResult{
+		Creates:  count(previewCreatesRegex),
+		Updates:  count(previewUpdatesRegex),
+		Deletes:  count(previewDeletesRegex),
+		Replaces: count(previewReplacesRegex),
+	}, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pulumi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePreviewResult(t *testing.T) {
+	testcases := []struct {
+		name     string
+		out      string
+		expected PreviewResult
+		wantErr  bool
+	}{
+		{
+			name: "changes",
+			out: `Previewing update (dev):
+     Type                 Name          Plan
+     pulumi:pulumi:Stack  app-dev
+ +   ├─ aws:s3:Bucket     logs          create
+ ~   ├─ aws:s3:Bucket     assets        update     [diff: ~tags]
+ +-  └─ aws:ec2:Instance  web           replace    [diff: ~ami]
+
+Resources:
+    + 1 to create
+    ~ 1 to update
+    +-1 to replace
+    3 changes. 2 unchanged
+`,
+			expected: PreviewResult{
+				Creates:  1,
+				Updates:  1,
+				Replaces: 1,
+			},
+		},
+		{
+			name: "deletes",
+			out: `Resources:
+    - 2 to delete
+    2 changes. 1 unchanged
+`,
+			expected: PreviewResult{
+				Deletes: 2,
+			},
+		},
+		{
+			name: "no changes",
+			out: `Previewing update (dev):
+
+Resources:
+    3 unchanged
+`,
+			expected: PreviewResult{},
+		},
+		{
+			name:    "invalid output",
+			out:     "error: no stack selected",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := parsePreviewResult(tc.out)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected, result)
+			assert.Equal(t, tc.expected == PreviewResult{}, result.NoChanges())
+		})
+	}
+}
@@ -0,0 +1,529 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraform
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	DefaultCloudAddress      = "https://app.terraform.io"
+	defaultCloudPollInterval = 10 * time.Second
+	cloudContentType         = "application/vnd.api+json"
+)
+
+// Cloud executes the plans and applies as runs of Terraform Cloud
+// (or Terraform Enterprise) through its API-driven workflow.
+// https://www.terraform.io/docs/cloud/run/api.html
+type Cloud struct {
+	address      string
+	organization string
+	token        string
+	pollInterval time.Duration
+	client       *http.Client
+}
+
+type CloudOption func(*Cloud)
+
+func WithCloudAddress(address string) CloudOption {
+	return func(c *Cloud) {
+		if address != "" {
+			c.address = strings.TrimSuffix(address, "/")
+		}
+	}
+}
+
+func WithCloudPollInterval(interval time.Duration) CloudOption {
+	return func(c *Cloud) {
+		if interval > 0 {
+			c.pollInterval = interval
+		}
+	}
+}
+
+func NewCloud(organization, token string, opts ...CloudOption) *Cloud {
+	c := &Cloud{
+		address:      DefaultCloudAddress,
+		organization: organization,
+		token:        token,
+		pollInterval: defaultCloudPollInterval,
+		client:       &http.Client{Timeout: time.Minute},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewCloudFromConfig returns a Cloud using the token stored in the configured file.
+func NewCloudFromConfig(cfg *config.TerraformCloudConfig) (*Cloud, error) {
+	token, err := ioutil.ReadFile(cfg.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token file %s: %w", cfg.TokenFile, err)
+	}
+	return NewCloud(
+		cfg.Organization,
+		strings.TrimSpace(string(token)),
+		WithCloudAddress(cfg.Address),
+		WithCloudPollInterval(cfg.PollInterval.Duration()),
+	), nil
+}
+
+// CloudPlan represents the result of the plan of a run.
+type CloudPlan struct {
+	PlanResult
+	// Whether the run is waiting for being applied.
+	// This is false when there are no changes or the run is speculative.
+	Confirmable bool
+}
+
+// The subset of the JSON:API documents used by Terraform Cloud.
+type cloudDocument struct {
+	Data cloudResource `json:"data"`
+}
+
+type cloudResource struct {
+	ID            string                       `json:"id,omitempty"`
+	Type          string                       `json:"type"`
+	Attributes    map[string]interface{}       `json:"attributes,omitempty"`
+	Relationships map[string]cloudRelationship `json:"relationships,omitempty"`
+}
+
+type cloudRelationship struct {
+	Data *cloudResource `json:"data"`
+}
+
+type cloudRunAttributes struct {
+	Status  string `json:"status"`
+	Actions struct {
+		IsConfirmable bool `json:"is-confirmable"`
+		IsCancelable  bool `json:"is-cancelable"`
+		IsDiscardable bool `json:"is-discardable"`
+	} `json:"actions"`
+}
+
+type cloudPlanAttributes struct {
+	HasChanges           bool   `json:"has-changes"`
+	ResourceAdditions    int    `json:"resource-additions"`
+	ResourceChanges      int    `json:"resource-changes"`
+	ResourceDestructions int    `json:"resource-destructions"`
+	LogReadURL           string `json:"log-read-url"`
+}
+
+type cloudApplyAttributes struct {
+	LogReadURL string `json:"log-read-url"`
+}
+
+// CreateRun uploads the configuration files inside the given repository directory
+// and queues a new run for them on the given workspace.
+// The working directory of the workspace is set to the given path relative to the repository root
+// so that the modules referenced from outside of the application directory are also available.
+// A speculative run is a plan-only run which can never be applied.
+func (c *Cloud) CreateRun(ctx context.Context, workspace, repoDir, workingDir, message string, speculative bool, w io.Writer) (string, error) {
+	var ws cloudDocument
+	path := fmt.Sprintf("/organizations/%s/workspaces/%s", url.PathEscape(c.organization), url.PathEscape(workspace))
+	if err := c.do(ctx, http.MethodGet, path, nil, &ws); err != nil {
+		return "", fmt.Errorf("failed to find workspace %s: %w", workspace, err)
+	}
+
+	workingDir = strings.Trim(filepath.ToSlash(workingDir), "/")
+	if workingDir == "." {
+		workingDir = ""
+	}
+	if current, _ := ws.Data.Attributes["working-directory"].(string); current != workingDir {
+		req := cloudDocument{
+			Data: cloudResource{
+				Type: "workspaces",
+				Attributes: map[string]interface{}{
+					"working-directory": workingDir,
+				},
+			},
+		}
+		if err := c.do(ctx, http.MethodPatch, "/workspaces/"+ws.Data.ID, req, nil); err != nil {
+			return "", fmt.Errorf("failed to set working directory of workspace %s: %w", workspace, err)
+		}
+		io.WriteString(w, fmt.Sprintf("Changed working directory of workspace %s from %q to %q\n", workspace, current, workingDir))
+	}
+
+	var cv cloudDocument
+	req := cloudDocument{
+		Data: cloudResource{
+			Type: "configuration-versions",
+			Attributes: map[string]interface{}{
+				"auto-queue-runs": false,
+				"speculative":     speculative,
+			},
+		},
+	}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/workspaces/%s/configuration-versions", ws.Data.ID), req, &cv); err != nil {
+		return "", fmt.Errorf("failed to create configuration version: %w", err)
+	}
+	uploadURL, _ := cv.Data.Attributes["upload-url"].(string)
+	if uploadURL == "" {
+		return "", fmt.Errorf("missing upload url of configuration version %s", cv.Data.ID)
+	}
+
+	archive, err := archiveDir(repoDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to archive configuration files: %w", err)
+	}
+	if err := c.upload(ctx, uploadURL, archive); err != nil {
+		return "", fmt.Errorf("failed to upload configuration files: %w", err)
+	}
+	io.WriteString(w, fmt.Sprintf("Uploaded configuration version %s to workspace %s\n", cv.Data.ID, workspace))
+
+	var run cloudDocument
+	req = cloudDocument{
+		Data: cloudResource{
+			Type: "runs",
+			Attributes: map[string]interface{}{
+				"message":    message,
+				"auto-apply": false,
+			},
+			Relationships: map[string]cloudRelationship{
+				"workspace": {
+					Data: &cloudResource{Type: "workspaces", ID: ws.Data.ID},
+				},
+				"configuration-version": {
+					Data: &cloudResource{Type: "configuration-versions", ID: cv.Data.ID},
+				},
+			},
+		},
+	}
+	if err := c.do(ctx, http.MethodPost, "/runs", req, &run); err != nil {
+		return "", fmt.Errorf("failed to create run: %w", err)
+	}
+	io.WriteString(w, fmt.Sprintf("Created run %s: %s\n", run.Data.ID, c.RunURL(workspace, run.Data.ID)))
+	return run.Data.ID, nil
+}
+
+// RunURL returns the URL where the given run can be seen on the web UI.
+func (c *Cloud) RunURL(workspace, runID string) string {
+	return fmt.Sprintf("%s/app/%s/workspaces/%s/runs/%s", c.address, c.organization, workspace, runID)
+}
+
+// WaitPlan waits until the plan of the given run has finished
+// and writes its log to the given writer.
+func (c *Cloud) WaitPlan(ctx context.Context, runID string, w io.Writer) (CloudPlan, error) {
+	for {
+		run, attrs, err := c.getRun(ctx, runID)
+		if err != nil {
+			return CloudPlan{}, err
+		}
+
+		switch {
+		case attrs.Actions.IsConfirmable, attrs.Status == "planned_and_finished":
+			plan, err := c.getPlan(ctx, run)
+			if err != nil {
+				return CloudPlan{}, err
+			}
+			c.copyLog(ctx, plan.LogReadURL, w)
+			return CloudPlan{
+				PlanResult: PlanResult{
+					Adds:     plan.ResourceAdditions,
+					Changes:  plan.ResourceChanges,
+					Destroys: plan.ResourceDestructions,
+				},
+				Confirmable: attrs.Actions.IsConfirmable,
+			}, nil
+
+		case isCloudRunFailed(attrs.Status), attrs.Status == "policy_override":
+			if plan, err := c.getPlan(ctx, run); err == nil {
+				c.copyLog(ctx, plan.LogReadURL, w)
+			}
+			return CloudPlan{}, fmt.Errorf("run %s finished with status %s", runID, attrs.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return CloudPlan{}, ctx.Err()
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+// Apply confirms the given run, which must have been planned,
+// waits until it has been applied and writes its log to the given writer.
+func (c *Cloud) Apply(ctx context.Context, runID, comment string, w io.Writer) error {
+	req := map[string]string{"comment": comment}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/runs/%s/actions/apply", runID), req, nil); err != nil {
+		return fmt.Errorf("failed to apply run %s: %w", runID, err)
+	}
+
+	for {
+		run, attrs, err := c.getRun(ctx, runID)
+		if err != nil {
+			return err
+		}
+
+		if attrs.Status == "applied" || isCloudRunFailed(attrs.Status) {
+			if apply, err := c.getApply(ctx, run); err == nil {
+				c.copyLog(ctx, apply.LogReadURL, w)
+			}
+			if attrs.Status != "applied" {
+				return fmt.Errorf("run %s finished with status %s", runID, attrs.Status)
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+// Discard discards the given run to unblock the runs queued after it.
+func (c *Cloud) Discard(ctx context.Context, runID, comment string) error {
+	req := map[string]string{"comment": comment}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/runs/%s/actions/discard", runID), req, nil); err != nil {
+		return fmt.Errorf("failed to discard run %s: %w", runID, err)
+	}
+	return nil
+}
+
+// Cancel stops the given run which is still planning or applying,
+// or discards it when it is waiting for being confirmed.
+// Nothing is done when the run has already finished.
+func (c *Cloud) Cancel(ctx context.Context, runID, comment string) error {
+	_, attrs, err := c.getRun(ctx, runID)
+	if err != nil {
+		return err
+	}
+	req := map[string]string{"comment": comment}
+	switch {
+	case attrs.Actions.IsCancelable:
+		if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/runs/%s/actions/cancel", runID), req, nil); err != nil {
+			return fmt.Errorf("failed to cancel run %s: %w", runID, err)
+		}
+	case attrs.Actions.IsDiscardable:
+		return c.Discard(ctx, runID, comment)
+	}
+	return nil
+}
+
+func isCloudRunFailed(status string) bool {
+	switch status {
+	case "errored", "discarded", "canceled", "force_canceled", "policy_soft_failed":
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Cloud) getRun(ctx context.Context, runID string) (cloudDocument, cloudRunAttributes, error) {
+	var (
+		run   cloudDocument
+		attrs cloudRunAttributes
+	)
+	if err := c.do(ctx, http.MethodGet, "/runs/"+runID, nil, &run); err != nil {
+		return run, attrs, fmt.Errorf("failed to get run %s: %w", runID, err)
+	}
+	if err := convertAttributes(run.Data.Attributes, &attrs); err != nil {
+		return run, attrs, fmt.Errorf("malformed run %s: %w", runID, err)
+	}
+	return run, attrs, nil
+}
+
+func (c *Cloud) getPlan(ctx context.Context, run cloudDocument) (cloudPlanAttributes, error) {
+	var attrs cloudPlanAttributes
+	id := relationshipID(run, "plan")
+	if id == "" {
+		return attrs, fmt.Errorf("run %s has no plan", run.Data.ID)
+	}
+	var plan cloudDocument
+	if err := c.do(ctx, http.MethodGet, "/plans/"+id, nil, &plan); err != nil {
+		return attrs, fmt.Errorf("failed to get plan %s: %w", id, err)
+	}
+	err := convertAttributes(plan.Data.Attributes, &attrs)
+	return attrs, err
+}
+
+func (c *Cloud) getApply(ctx context.Context, run cloudDocument) (cloudApplyAttributes, error) {
+	var attrs cloudApplyAttributes
+	id := relationshipID(run, "apply")
+	if id == "" {
+		return attrs, fmt.Errorf("run %s has no apply", run.Data.ID)
+	}
+	var apply cloudDocument
+	if err := c.do(ctx, http.MethodGet, "/applies/"+id, nil, &apply); err != nil {
+		return attrs, fmt.Errorf("failed to get apply %s: %w", id, err)
+	}
+	err := convertAttributes(apply.Data.Attributes, &attrs)
+	return attrs, err
+}
+
+func relationshipID(doc cloudDocument, name string) string {
+	r, ok := doc.Data.Relationships[name]
+	if !ok || r.Data == nil {
+		return ""
+	}
+	return r.Data.ID
+}
+
+func convertAttributes(attrs map[string]interface{}, out interface{}) error {
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// copyLog writes the log at the given URL to the given writer.
+// Failing to fetch the log is reported into the writer
+// since it does not affect the result of the run.
+func (c *Cloud) copyLog(ctx context.Context, logURL string, w io.Writer) {
+	if logURL == "" {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, logURL, nil)
+	if err != nil {
+		io.WriteString(w, fmt.Sprintf("Unable to fetch the log (%v)\n", err))
+		return
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		io.WriteString(w, fmt.Sprintf("Unable to fetch the log (%v)\n", err))
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(w, resp.Body)
+}
+
+func (c *Cloud) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.address+"/api/v2"+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", cloudContentType)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (c *Cloud) upload(ctx context.Context, uploadURL string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// archiveDir returns a tar.gz archive of the regular files inside the given directory.
+// The .git and .terraform directories are excluded since they are not a part of the configuration.
+func archiveDir(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if name := info.Name(); path != dir && (name == ".git" || name == ".terraform") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,219 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraform
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCloud simulates a run going through plan and apply on Terraform Cloud.
+type fakeCloud struct {
+	t          *testing.T
+	server     *httptest.Server
+	uploaded   []byte
+	workingDir string
+	applied    bool
+	cancelled  bool
+	polls      int
+}
+
+func (f *fakeCloud) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/upload" && r.URL.Path != "/logs/plan" && r.URL.Path != "/logs/apply" {
+		assert.Equal(f.t, "Bearer token", r.Header.Get("Authorization"))
+	}
+
+	write := func(id, typ string, attrs map[string]interface{}, rels map[string]cloudRelationship) {
+		json.NewEncoder(w).Encode(cloudDocument{
+			Data: cloudResource{ID: id, Type: typ, Attributes: attrs, Relationships: rels},
+		})
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v2/organizations/org/workspaces/ws":
+		write("ws-1", "workspaces", map[string]interface{}{"working-directory": f.workingDir}, nil)
+	case r.Method == http.MethodPatch && r.URL.Path == "/api/v2/workspaces/ws-1":
+		var doc cloudDocument
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&doc))
+		f.workingDir, _ = doc.Data.Attributes["working-directory"].(string)
+		write("ws-1", "workspaces", doc.Data.Attributes, nil)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v2/workspaces/ws-1/configuration-versions":
+		write("cv-1", "configuration-versions", map[string]interface{}{"upload-url": f.server.URL + "/upload"}, nil)
+	case r.Method == http.MethodPut && r.URL.Path == "/upload":
+		f.uploaded, _ = ioutil.ReadAll(r.Body)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v2/runs":
+		var doc cloudDocument
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&doc))
+		assert.Equal(f.t, "cv-1", doc.Data.Relationships["configuration-version"].Data.ID)
+		assert.Equal(f.t, "ws-1", doc.Data.Relationships["workspace"].Data.ID)
+		write("run-1", "runs", nil, nil)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v2/runs/run-1":
+		f.polls++
+		status, confirmable := "planning", false
+		switch {
+		case f.cancelled:
+			status = "canceled"
+		case f.applied:
+			status = "applied"
+		case f.polls > 1:
+			status, confirmable = "planned", true
+		}
+		write("run-1", "runs", map[string]interface{}{
+			"status": status,
+			"actions": map[string]interface{}{
+				"is-confirmable": confirmable,
+				"is-cancelable":  status == "planning",
+				"is-discardable": confirmable,
+			},
+		}, map[string]cloudRelationship{
+			"plan":  {Data: &cloudResource{ID: "plan-1", Type: "plans"}},
+			"apply": {Data: &cloudResource{ID: "apply-1", Type: "applies"}},
+		})
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v2/plans/plan-1":
+		write("plan-1", "plans", map[string]interface{}{
+			"has-changes":           true,
+			"resource-additions":    1,
+			"resource-changes":      2,
+			"resource-destructions": 3,
+			"log-read-url":          f.server.URL + "/logs/plan",
+		}, nil)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v2/runs/run-1/actions/cancel":
+		f.cancelled = true
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v2/runs/run-1/actions/apply":
+		f.applied = true
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v2/applies/apply-1":
+		write("apply-1", "applies", map[string]interface{}{
+			"log-read-url": f.server.URL + "/logs/apply",
+		}, nil)
+	case r.URL.Path == "/logs/plan":
+		io.WriteString(w, "Plan: 1 to add, 2 to change, 3 to destroy.\n")
+	case r.URL.Path == "/logs/apply":
+		io.WriteString(w, "Apply complete!\n")
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "%s %s was not found", r.Method, r.URL.Path)
+	}
+}
+
+func TestCloudRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "terraform-cloud")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "apps", "app"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "apps", "app", "main.tf"), []byte("# main"), 0644))
+
+	f := &fakeCloud{t: t}
+	f.server = httptest.NewServer(f)
+	defer f.server.Close()
+
+	ctx := context.Background()
+	c := NewCloud("org", "token", WithCloudAddress(f.server.URL), WithCloudPollInterval(time.Millisecond))
+
+	var buf bytes.Buffer
+	runID, err := c.CreateRun(ctx, "ws", dir, filepath.Join("apps", "app"), "message", false, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "run-1", runID)
+	assert.NotEmpty(t, f.uploaded)
+	assert.Equal(t, "apps/app", f.workingDir)
+	assert.Equal(t, []string{"apps/app/main.tf"}, archivedFiles(t, f.uploaded))
+
+	plan, err := c.WaitPlan(ctx, runID, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, CloudPlan{
+		PlanResult:  PlanResult{Adds: 1, Changes: 2, Destroys: 3},
+		Confirmable: true,
+	}, plan)
+	assert.Contains(t, buf.String(), "Plan: 1 to add, 2 to change, 3 to destroy.")
+
+	require.NoError(t, c.Apply(ctx, runID, "comment", &buf))
+	assert.True(t, f.applied)
+	assert.Contains(t, buf.String(), "Apply complete!")
+
+	err = c.Discard(ctx, "unknown", "comment")
+	assert.Error(t, err)
+}
+
+func TestCloudCancel(t *testing.T) {
+	f := &fakeCloud{t: t}
+	f.server = httptest.NewServer(f)
+	defer f.server.Close()
+
+	ctx := context.Background()
+	c := NewCloud("org", "token", WithCloudAddress(f.server.URL), WithCloudPollInterval(time.Millisecond))
+
+	require.NoError(t, c.Cancel(ctx, "run-1", "comment"))
+	assert.True(t, f.cancelled)
+
+	// Nothing is done for the finished run.
+	require.NoError(t, c.Cancel(ctx, "run-1", "comment"))
+}
+
+func TestArchiveDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "terraform-archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"main.tf":                 "main",
+		"modules/vpc/main.tf":     "vpc",
+		".terraform/plugins/file": "plugin",
+		".git/HEAD":               "head",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+
+	data, err := archiveDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.tf", "modules/vpc/main.tf"}, archivedFiles(t, data))
+}
+
+func archivedFiles(t *testing.T, data []byte) []string {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+
+	var names []string
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, h.Name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -23,6 +23,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
 )
 
 type options struct {
@@ -73,7 +75,7 @@ func NewTerraform(execPath, dir string, opts ...Option) *Terraform {
 
 func (t *Terraform) Version(ctx context.Context) (string, error) {
 	args := []string{"version"}
-	cmd := exec.CommandContext(ctx, t.execPath, args...)
+	cmd := toolexec.CommandContext(ctx, t.execPath, args...)
 	cmd.Dir = t.dir
 
 	out, err := cmd.CombinedOutput()
@@ -90,7 +92,7 @@ func (t *Terraform) Init(ctx context.Context, w io.Writer) error {
 	}
 	args = append(args, t.makeCommonCommandArgs()...)
 
-	cmd := exec.CommandContext(ctx, t.execPath, args...)
+	cmd := toolexec.CommandContext(ctx, t.execPath, args...)
 	cmd.Dir = t.dir
 	cmd.Stdout = w
 	cmd.Stderr = w
@@ -105,7 +107,7 @@ func (t *Terraform) SelectWorkspace(ctx context.Context, workspace string) error
 		"select",
 		workspace,
 	}
-	cmd := exec.CommandContext(ctx, t.execPath, args...)
+	cmd := toolexec.CommandContext(ctx, t.execPath, args...)
 	cmd.Dir = t.dir
 
 	out, err := cmd.CombinedOutput()
@@ -147,7 +149,7 @@ func (t *Terraform) Plan(ctx context.Context, w io.Writer) (PlanResult, error) {
 	var buf bytes.Buffer
 	stdout := io.MultiWriter(w, &buf)
 
-	cmd := exec.CommandContext(ctx, t.execPath, args...)
+	cmd := toolexec.CommandContext(ctx, t.execPath, args...)
 	cmd.Dir = t.dir
 	cmd.Stdout = stdout
 	cmd.Stderr = stdout
@@ -238,7 +240,7 @@ func (t *Terraform) Apply(ctx context.Context, w io.Writer) error {
 	}
 	args = append(args, t.makeCommonCommandArgs()...)
 
-	cmd := exec.CommandContext(ctx, t.execPath, args...)
+	cmd := toolexec.CommandContext(ctx, t.execPath, args...)
 	cmd.Dir = t.dir
 	cmd.Stdout = w
 	cmd.Stderr = w
@@ -0,0 +1,384 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	gceOperationDone   = "DONE"
+	gceTemplateHashLen = 10
+	// The maximum length of resource names in Compute Engine.
+	gceMaxNameLen = 63
+)
+
+// gceOperationPollInterval is the interval to check the status of the long-running operations.
+var gceOperationPollInterval = 5 * time.Second
+
+// gceClient controls the managed instance groups of Compute Engine.
+// An instance group is represented by a zonal or regional managed instance group
+// and the rolling update is performed by its updater.
+type gceClient struct {
+	project string
+	zone    string
+	region  string
+	service *compute.Service
+	logger  *zap.Logger
+}
+
+func newGCEClient(ctx context.Context, cfg *config.VMGCEConfig, logger *zap.Logger) (*gceClient, error) {
+	var options []option.ClientOption
+	if cfg.CredentialsFile != "" {
+		data, err := ioutil.ReadFile(cfg.CredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read credentials file (%w)", err)
+		}
+		options = append(options, option.WithCredentialsJSON(data))
+	}
+	service, err := compute.NewService(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &gceClient{
+		project: cfg.Project,
+		zone:    cfg.Zone,
+		region:  cfg.Region,
+		service: service,
+		logger:  logger.Named("gce"),
+	}, nil
+}
+
+func (c *gceClient) GetGroup(ctx context.Context, group string) (*Group, error) {
+	m, err := c.getManager(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	template := m.InstanceTemplate
+	if len(m.Versions) > 0 {
+		template = m.Versions[0].InstanceTemplate
+	}
+	stable := m.Status != nil && m.Status.IsStable
+	if stable && m.Status.VersionTarget != nil {
+		stable = m.Status.VersionTarget.IsReached
+	}
+	return &Group{
+		Name:       m.Name,
+		Template:   path.Base(template),
+		TargetSize: int(m.TargetSize),
+		Stable:     stable,
+	}, nil
+}
+
+func (c *gceClient) BuildTemplate(ctx context.Context, m InstanceGroupManifest) (*Template, *Template, error) {
+	group, err := c.GetGroup(ctx, m.Spec.Name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get group %s: %w", m.Spec.Name, err)
+	}
+	base, err := c.service.InstanceTemplates.Get(c.project, group.Template).Context(ctx).Do()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get instance template %s: %w", group.Template, gceError(err))
+	}
+
+	props, err := buildGCEInstanceProperties(base.Properties, m)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := toTemplate(base.Name, base.Properties)
+	if err != nil {
+		return nil, nil, err
+	}
+	desired, err := toTemplate("", props)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The template name is derived from its properties
+	// so that the same template is reused when nothing was changed.
+	desired.Name = gceTemplateName(m.Spec.Name, desired.Properties)
+	return current, desired, nil
+}
+
+func (c *gceClient) EnsureTemplate(ctx context.Context, t *Template) error {
+	_, err := c.service.InstanceTemplates.Get(c.project, t.Name).Context(ctx).Do()
+	if err == nil {
+		return nil
+	}
+	if gceError(err) != ErrNotFound {
+		return fmt.Errorf("failed to get instance template %s: %w", t.Name, err)
+	}
+
+	var props compute.InstanceProperties
+	if err := convert(t.Properties, &props); err != nil {
+		return err
+	}
+	op, err := c.service.InstanceTemplates.Insert(c.project, &compute.InstanceTemplate{
+		Name:        t.Name,
+		Description: "Created by PipeCD",
+		Properties:  &props,
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to create instance template %s: %w", t.Name, err)
+	}
+	c.logger.Info("creating instance template", zap.String("name", t.Name))
+	return c.waitOperation(ctx, op)
+}
+
+func (c *gceClient) RollingUpdate(ctx context.Context, group string, t *Template, maxSurge, maxUnavailable *config.Replicas) error {
+	return c.patchManager(ctx, group, &compute.InstanceGroupManager{
+		Versions: []*compute.InstanceGroupManagerVersion{
+			{InstanceTemplate: c.templateURL(t.Name)},
+		},
+		UpdatePolicy: &compute.InstanceGroupManagerUpdatePolicy{
+			Type:           "PROACTIVE",
+			MinimalAction:  "REPLACE",
+			MaxSurge:       gceFixedOrPercent(maxSurge),
+			MaxUnavailable: gceFixedOrPercent(maxUnavailable),
+		},
+	})
+}
+
+func (c *gceClient) Resize(ctx context.Context, group string, t *Template, size int) error {
+	if t != nil {
+		if err := c.RollingUpdate(ctx, group, t, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	var (
+		op  *compute.Operation
+		err error
+	)
+	if c.region != "" {
+		op, err = c.service.RegionInstanceGroupManagers.Resize(c.project, c.region, group, int64(size)).Context(ctx).Do()
+	} else {
+		op, err = c.service.InstanceGroupManagers.Resize(c.project, c.zone, group, int64(size)).Context(ctx).Do()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resize group %s: %w", group, gceError(err))
+	}
+	return c.waitOperation(ctx, op)
+}
+
+func (c *gceClient) getManager(ctx context.Context, group string) (*compute.InstanceGroupManager, error) {
+	var (
+		m   *compute.InstanceGroupManager
+		err error
+	)
+	if c.region != "" {
+		m, err = c.service.RegionInstanceGroupManagers.Get(c.project, c.region, group).Context(ctx).Do()
+	} else {
+		m, err = c.service.InstanceGroupManagers.Get(c.project, c.zone, group).Context(ctx).Do()
+	}
+	if err != nil {
+		return nil, gceError(err)
+	}
+	return m, nil
+}
+
+func (c *gceClient) patchManager(ctx context.Context, group string, m *compute.InstanceGroupManager) error {
+	var (
+		op  *compute.Operation
+		err error
+	)
+	if c.region != "" {
+		op, err = c.service.RegionInstanceGroupManagers.Patch(c.project, c.region, group, m).Context(ctx).Do()
+	} else {
+		op, err = c.service.InstanceGroupManagers.Patch(c.project, c.zone, group, m).Context(ctx).Do()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update group %s: %w", group, gceError(err))
+	}
+	return c.waitOperation(ctx, op)
+}
+
+// waitOperation waits until the given operation is done.
+// Note that the operations on managed instance groups are done as soon as the change was accepted,
+// the progress of the update must be checked through the status of the group.
+func (c *gceClient) waitOperation(ctx context.Context, op *compute.Operation) error {
+	var err error
+	for op.Status != gceOperationDone {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(gceOperationPollInterval):
+		}
+
+		switch {
+		case op.Zone != "":
+			op, err = c.service.ZoneOperations.Get(c.project, path.Base(op.Zone), op.Name).Context(ctx).Do()
+		case op.Region != "":
+			op, err = c.service.RegionOperations.Get(c.project, path.Base(op.Region), op.Name).Context(ctx).Do()
+		default:
+			op, err = c.service.GlobalOperations.Get(c.project, op.Name).Context(ctx).Do()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get operation: %w", err)
+		}
+	}
+	if op.Error != nil && len(op.Error.Errors) > 0 {
+		e := op.Error.Errors[0]
+		return fmt.Errorf("operation %s failed: code=%s, message=%s", op.Name, e.Code, e.Message)
+	}
+	return nil
+}
+
+func (c *gceClient) templateURL(name string) string {
+	return fmt.Sprintf("projects/%s/global/instanceTemplates/%s", c.project, name)
+}
+
+// buildGCEInstanceProperties returns a copy of the given properties
+// updated to run the image and the overrides of the given manifest.
+func buildGCEInstanceProperties(base *compute.InstanceProperties, m InstanceGroupManifest) (*compute.InstanceProperties, error) {
+	if base == nil {
+		return nil, fmt.Errorf("the instance template of group %s has no properties", m.Spec.Name)
+	}
+	var props compute.InstanceProperties
+	if err := convert(base, &props); err != nil {
+		return nil, err
+	}
+
+	var bootDisk *compute.AttachedDisk
+	for _, d := range props.Disks {
+		if d.Boot {
+			bootDisk = d
+			break
+		}
+	}
+	if bootDisk == nil || bootDisk.InitializeParams == nil {
+		return nil, fmt.Errorf("the instance template of group %s has no boot disk created from an image", m.Spec.Name)
+	}
+	bootDisk.InitializeParams.SourceImage = gceImageURL(m.Spec.Image)
+
+	spec := m.Spec.GCE
+	if spec == nil {
+		return &props, nil
+	}
+	if spec.MachineType != "" {
+		props.MachineType = spec.MachineType
+	}
+	if len(spec.Labels) > 0 {
+		if props.Labels == nil {
+			props.Labels = make(map[string]string, len(spec.Labels))
+		}
+		for k, v := range spec.Labels {
+			props.Labels[k] = v
+		}
+	}
+	if len(spec.Metadata) > 0 {
+		if props.Metadata == nil {
+			props.Metadata = &compute.Metadata{}
+		}
+		// The fingerprint is only meaningful for the existing resources.
+		props.Metadata.Fingerprint = ""
+
+		keys := make([]string, 0, len(spec.Metadata))
+		for k := range spec.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			setGCEMetadata(props.Metadata, k, spec.Metadata[k])
+		}
+	}
+	return &props, nil
+}
+
+func setGCEMetadata(md *compute.Metadata, key, value string) {
+	v := value
+	for _, item := range md.Items {
+		if item.Key == key {
+			item.Value = &v
+			return
+		}
+	}
+	md.Items = append(md.Items, &compute.MetadataItems{Key: key, Value: &v})
+}
+
+// gceImageURL returns the partial URL of the given image.
+// The image name without any path is treated as the one in the same project.
+func gceImageURL(image string) string {
+	if strings.Contains(image, "/") {
+		return image
+	}
+	return "global/images/" + image
+}
+
+// gceTemplateName returns the name of the template for the given group
+// suffixed by the hash of its properties.
+func gceTemplateName(group string, props map[string]interface{}) string {
+	data, _ := json.Marshal(props)
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])[:gceTemplateHashLen]
+
+	if max := gceMaxNameLen - gceTemplateHashLen - 1; len(group) > max {
+		group = group[:max]
+	}
+	return fmt.Sprintf("%s-%s", group, hash)
+}
+
+// gceFixedOrPercent converts the given replicas to the one of the update policy.
+// Nil means one instance.
+func gceFixedOrPercent(r *config.Replicas) *compute.FixedOrPercent {
+	switch {
+	case r == nil:
+		return &compute.FixedOrPercent{Fixed: 1}
+	case r.IsPercentage:
+		return &compute.FixedOrPercent{Percent: int64(r.Number)}
+	default:
+		return &compute.FixedOrPercent{Fixed: int64(r.Number)}
+	}
+}
+
+func toTemplate(name string, props *compute.InstanceProperties) (*Template, error) {
+	t := &Template{Name: name}
+	if err := convert(props, &t.Properties); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// convert converts the given object to the other type through its JSON representation.
+func convert(from, to interface{}) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
+
+func gceError(err error) error {
+	if e, ok := err.(*googleapi.Error); ok && e.Code == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,195 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/compute/v1"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestBuildGCEInstanceProperties(t *testing.T) {
+	value := "old"
+	base := &compute.InstanceProperties{
+		MachineType: "e2-small",
+		Disks: []*compute.AttachedDisk{
+			{
+				Boot: true,
+				InitializeParams: &compute.AttachedDiskInitializeParams{
+					SourceImage: "global/images/web-20210701",
+				},
+			},
+		},
+		Metadata: &compute.Metadata{
+			Fingerprint: "abc",
+			Items: []*compute.MetadataItems{
+				{Key: "env", Value: &value},
+			},
+		},
+	}
+
+	testcases := []struct {
+		name     string
+		manifest InstanceGroupManifest
+		expected func() *compute.InstanceProperties
+		wantErr  bool
+	}{
+		{
+			name: "only image",
+			manifest: InstanceGroupManifest{
+				Spec: InstanceGroupManifestSpec{
+					Name:  "web",
+					Image: "web-20210801",
+				},
+			},
+			expected: func() *compute.InstanceProperties {
+				v := "old"
+				return &compute.InstanceProperties{
+					MachineType: "e2-small",
+					Disks: []*compute.AttachedDisk{
+						{
+							Boot: true,
+							InitializeParams: &compute.AttachedDiskInitializeParams{
+								SourceImage: "global/images/web-20210801",
+							},
+						},
+					},
+					Metadata: &compute.Metadata{
+						Fingerprint: "abc",
+						Items: []*compute.MetadataItems{
+							{Key: "env", Value: &v},
+						},
+					},
+				}
+			},
+		},
+		{
+			name: "with overrides",
+			manifest: InstanceGroupManifest{
+				Spec: InstanceGroupManifestSpec{
+					Name:  "web",
+					Image: "projects/images/global/images/web-20210801",
+					GCE: &InstanceGroupGCESpec{
+						MachineType: "e2-standard-2",
+						Metadata: map[string]string{
+							"env":     "new",
+							"startup": "run.sh",
+						},
+						Labels: map[string]string{
+							"team": "web",
+						},
+					},
+				},
+			},
+			expected: func() *compute.InstanceProperties {
+				env, startup := "new", "run.sh"
+				return &compute.InstanceProperties{
+					MachineType: "e2-standard-2",
+					Disks: []*compute.AttachedDisk{
+						{
+							Boot: true,
+							InitializeParams: &compute.AttachedDiskInitializeParams{
+								SourceImage: "projects/images/global/images/web-20210801",
+							},
+						},
+					},
+					Metadata: &compute.Metadata{
+						Items: []*compute.MetadataItems{
+							{Key: "env", Value: &env},
+							{Key: "startup", Value: &startup},
+						},
+					},
+					Labels: map[string]string{
+						"team": "web",
+					},
+				}
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := buildGCEInstanceProperties(base, tc.manifest)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected(), got)
+		})
+	}
+
+	// The base properties must not be modified.
+	assert.Equal(t, "global/images/web-20210701", base.Disks[0].InitializeParams.SourceImage)
+	assert.Equal(t, "old", *base.Metadata.Items[0].Value)
+}
+
+func TestBuildGCEInstancePropertiesWithoutBootDisk(t *testing.T) {
+	base := &compute.InstanceProperties{
+		Disks: []*compute.AttachedDisk{
+			{Source: "data-disk"},
+		},
+	}
+	_, err := buildGCEInstanceProperties(base, InstanceGroupManifest{
+		Spec: InstanceGroupManifestSpec{Name: "web", Image: "web-20210801"},
+	})
+	assert.Error(t, err)
+}
+
+func TestGCETemplateName(t *testing.T) {
+	props := map[string]interface{}{
+		"machineType": "e2-small",
+	}
+	name := gceTemplateName("web", props)
+	require.Len(t, name, len("web-")+gceTemplateHashLen)
+	assert.Equal(t, name, gceTemplateName("web", map[string]interface{}{"machineType": "e2-small"}))
+	assert.NotEqual(t, name, gceTemplateName("web", map[string]interface{}{"machineType": "e2-medium"}))
+
+	long := gceTemplateName("a-very-long-group-name-exceeding-the-limit-of-compute-engine-names", props)
+	assert.Len(t, long, gceMaxNameLen)
+}
+
+func TestGCEFixedOrPercent(t *testing.T) {
+	testcases := []struct {
+		name     string
+		replicas *config.Replicas
+		expected *compute.FixedOrPercent
+	}{
+		{
+			name:     "unset",
+			replicas: nil,
+			expected: &compute.FixedOrPercent{Fixed: 1},
+		},
+		{
+			name:     "zero",
+			replicas: &config.Replicas{},
+			expected: &compute.FixedOrPercent{Fixed: 0},
+		},
+		{
+			name:     "fixed",
+			replicas: &config.Replicas{Number: 3},
+			expected: &compute.FixedOrPercent{Fixed: 3},
+		},
+		{
+			name:     "percentage",
+			replicas: &config.Replicas{Number: 25, IsPercentage: true},
+			expected: &compute.FixedOrPercent{Percent: 25},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, gceFixedOrPercent(tc.replicas))
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	versionV1Beta1    = "pipecd.dev/v1beta1"
+	instanceGroupKind = "InstanceGroup"
+)
+
+// InstanceGroupManifest represents a group of instances which should be running the same image.
+type InstanceGroupManifest struct {
+	Kind       string                    `json:"kind"`
+	APIVersion string                    `json:"apiVersion,omitempty"`
+	Spec       InstanceGroupManifestSpec `json:"spec"`
+}
+
+// InstanceGroupManifestSpec contains configuration for InstanceGroup.
+type InstanceGroupManifestSpec struct {
+	// The name or ID of the group in the platform,
+	// e.g. the server group in OpenStack.
+	Name string `json:"name"`
+	// The name or ID of the machine image all instances should be running,
+	// e.g. the one built by Packer.
+	Image string `json:"image"`
+	// Configuration specific to GCE platform.
+	GCE *InstanceGroupGCESpec `json:"gce,omitempty"`
+}
+
+// InstanceGroupGCESpec contains the instance template properties to be overridden
+// and the groups used while deploying to GCE.
+type InstanceGroupGCESpec struct {
+	// The machine type of the instances, e.g. "e2-standard-2".
+	// Empty means the one of the current instance template is kept.
+	MachineType string `json:"machineType,omitempty"`
+	// The metadata items to be set to the instances.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// The labels to be set to the instances.
+	Labels map[string]string `json:"labels,omitempty"`
+	// The name of the managed instance group running the CANARY instances.
+	// It must be attached to the same backend service as the primary group
+	// so that it receives a part of the traffic.
+	CanaryGroup string `json:"canaryGroup,omitempty"`
+}
+
+func (m *InstanceGroupManifest) validate() error {
+	if m.APIVersion != versionV1Beta1 {
+		return fmt.Errorf("unsupported version: %s", m.APIVersion)
+	}
+	if m.Kind != instanceGroupKind {
+		return fmt.Errorf("invalid manifest kind given: %s", m.Kind)
+	}
+	if m.Spec.Name == "" {
+		return fmt.Errorf("instance group name is missing")
+	}
+	if m.Spec.Image == "" {
+		return fmt.Errorf("image is missing")
+	}
+	return nil
+}
+
+// LoadInstanceGroupManifest returns InstanceGroupManifest object from a given manifest file.
+func LoadInstanceGroupManifest(appDir, manifestFilename string) (InstanceGroupManifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(appDir, manifestFilename))
+	if err != nil {
+		return InstanceGroupManifest{}, err
+	}
+	return parseInstanceGroupManifest(data)
+}
+
+func parseInstanceGroupManifest(data []byte) (InstanceGroupManifest, error) {
+	var obj InstanceGroupManifest
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return InstanceGroupManifest{}, err
+	}
+	if err := obj.validate(); err != nil {
+		return InstanceGroupManifest{}, err
+	}
+	return obj, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInstanceGroupManifest(t *testing.T) {
+	testcases := []struct {
+		name     string
+		data     string
+		expected InstanceGroupManifest
+		wantErr  bool
+	}{
+		{
+			name: "valid",
+			data: `apiVersion: pipecd.dev/v1beta1
+kind: InstanceGroup
+spec:
+  name: web
+  image: web-20210801
+`,
+			expected: InstanceGroupManifest{
+				Kind:       "InstanceGroup",
+				APIVersion: "pipecd.dev/v1beta1",
+				Spec: InstanceGroupManifestSpec{
+					Name:  "web",
+					Image: "web-20210801",
+				},
+			},
+		},
+		{
+			name: "valid with gce spec",
+			data: `apiVersion: pipecd.dev/v1beta1
+kind: InstanceGroup
+spec:
+  name: web
+  image: projects/my-project/global/images/web-20210801
+  gce:
+    machineType: e2-standard-2
+    labels:
+      team: web
+    canaryGroup: web-canary
+`,
+			expected: InstanceGroupManifest{
+				Kind:       "InstanceGroup",
+				APIVersion: "pipecd.dev/v1beta1",
+				Spec: InstanceGroupManifestSpec{
+					Name:  "web",
+					Image: "projects/my-project/global/images/web-20210801",
+					GCE: &InstanceGroupGCESpec{
+						MachineType: "e2-standard-2",
+						Labels: map[string]string{
+							"team": "web",
+						},
+						CanaryGroup: "web-canary",
+					},
+				},
+			},
+		},
+		{
+			name: "missing image",
+			data: `apiVersion: pipecd.dev/v1beta1
+kind: InstanceGroup
+spec:
+  name: web
+`,
+			wantErr: true,
+		},
+		{
+			name: "wrong kind",
+			data: `apiVersion: pipecd.dev/v1beta1
+kind: LambdaFunction
+spec:
+  name: web
+  image: web-20210801
+`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseInstanceGroupManifest([]byte(tc.data))
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
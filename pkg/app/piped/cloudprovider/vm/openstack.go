@@ -0,0 +1,337 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	openstackComputeService = "compute"
+	openstackImageService   = "image"
+	// The token is renewed a bit before its expiration
+	// to avoid using an expired one in the middle of a request.
+	openstackTokenExpiryDelta = time.Minute
+)
+
+// openstackClient talks to Keystone, Nova and Glance through their REST APIs.
+// An instance group is represented by a Nova server group.
+type openstackClient struct {
+	cfg        *config.VMOpenStackConfig
+	secret     string
+	httpClient *http.Client
+	logger     *zap.Logger
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+	endpoints map[string]string
+}
+
+func newOpenStackClient(cfg *config.VMOpenStackConfig, logger *zap.Logger) (*openstackClient, error) {
+	secretFile := cfg.PasswordFile
+	if cfg.ApplicationCredentialID != "" {
+		secretFile = cfg.ApplicationCredentialSecretFile
+	}
+	secret, err := ioutil.ReadFile(secretFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read openstack secret file %s: %w", secretFile, err)
+	}
+	return &openstackClient{
+		cfg:        cfg,
+		secret:     strings.TrimSpace(string(secret)),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		logger:     logger.Named("openstack"),
+	}, nil
+}
+
+type openstackAuthResponse struct {
+	Token struct {
+		ExpiresAt time.Time `json:"expires_at"`
+		Catalog   []struct {
+			Type      string `json:"type"`
+			Endpoints []struct {
+				Interface string `json:"interface"`
+				Region    string `json:"region"`
+				URL       string `json:"url"`
+			} `json:"endpoints"`
+		} `json:"catalog"`
+	} `json:"token"`
+}
+
+func (c *openstackClient) authRequest() map[string]interface{} {
+	if c.cfg.ApplicationCredentialID != "" {
+		return map[string]interface{}{
+			"auth": map[string]interface{}{
+				"identity": map[string]interface{}{
+					"methods": []string{"application_credential"},
+					"application_credential": map[string]string{
+						"id":     c.cfg.ApplicationCredentialID,
+						"secret": c.secret,
+					},
+				},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"auth": map[string]interface{}{
+			"identity": map[string]interface{}{
+				"methods": []string{"password"},
+				"password": map[string]interface{}{
+					"user": map[string]interface{}{
+						"name":     c.cfg.Username,
+						"domain":   map[string]string{"name": c.cfg.UserDomainName},
+						"password": c.secret,
+					},
+				},
+			},
+			"scope": map[string]interface{}{
+				"project": map[string]interface{}{
+					"name":   c.cfg.ProjectName,
+					"domain": map[string]string{"name": c.cfg.ProjectDomainName},
+				},
+			},
+		},
+	}
+}
+
+// authenticate returns a valid token and the endpoint of the given service,
+// issuing a new token from Keystone when the cached one is about to expire.
+func (c *openstackClient) authenticate(ctx context.Context, service string) (token, endpoint string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == "" || time.Now().Add(openstackTokenExpiryDelta).After(c.expiresAt) {
+		body, err := json.Marshal(c.authRequest())
+		if err != nil {
+			return "", "", err
+		}
+		authURL := strings.TrimSuffix(c.cfg.AuthURL, "/") + "/auth/tokens"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, authURL, bytes.NewReader(body))
+		if err != nil {
+			return "", "", err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return "", "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			data, _ := ioutil.ReadAll(resp.Body)
+			return "", "", fmt.Errorf("failed to authenticate to keystone: unexpected status code %d: %s", resp.StatusCode, string(data))
+		}
+		var out openstackAuthResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			return "", "", fmt.Errorf("failed to decode keystone response: %w", err)
+		}
+
+		endpoints := make(map[string]string, len(out.Token.Catalog))
+		for _, s := range out.Token.Catalog {
+			for _, e := range s.Endpoints {
+				if e.Interface != "public" {
+					continue
+				}
+				if c.cfg.Region != "" && e.Region != c.cfg.Region {
+					continue
+				}
+				endpoints[s.Type] = strings.TrimSuffix(e.URL, "/")
+				break
+			}
+		}
+		c.token = resp.Header.Get("X-Subject-Token")
+		c.expiresAt = out.Token.ExpiresAt
+		c.endpoints = endpoints
+	}
+
+	endpoint, ok := c.endpoints[service]
+	if !ok {
+		return "", "", fmt.Errorf("no public endpoint of %s service was found in the service catalog", service)
+	}
+	return c.token, endpoint, nil
+}
+
+func (c *openstackClient) do(ctx context.Context, service, method, path string, body, out interface{}) error {
+	token, endpoint, err := c.authenticate(ctx, service)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	if body != nil {
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Auth-Token", token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s %s", ErrNotFound, method, path)
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code %d from %s %s: %s", resp.StatusCode, method, path, string(data))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (c *openstackClient) ResolveImage(ctx context.Context, image string) (string, error) {
+	var img struct {
+		ID string `json:"id"`
+	}
+	err := c.do(ctx, openstackImageService, http.MethodGet, "/v2/images/"+url.PathEscape(image), nil, &img)
+	if err == nil {
+		return img.ID, nil
+	}
+
+	var list struct {
+		Images []struct {
+			ID string `json:"id"`
+		} `json:"images"`
+	}
+	query := url.Values{
+		"name":   []string{image},
+		"status": []string{"active"},
+	}
+	if err := c.do(ctx, openstackImageService, http.MethodGet, "/v2/images?"+query.Encode(), nil, &list); err != nil {
+		return "", err
+	}
+	switch len(list.Images) {
+	case 0:
+		return "", fmt.Errorf("%w: image %s", ErrNotFound, image)
+	case 1:
+		return list.Images[0].ID, nil
+	default:
+		return "", fmt.Errorf("image name %s is ambiguous, %d active images were found", image, len(list.Images))
+	}
+}
+
+type openstackServer struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Image is an empty string instead of an object for the servers booted from volume.
+	Image json.RawMessage `json:"image"`
+}
+
+func (s openstackServer) toInstance() Instance {
+	inst := Instance{
+		ID:     s.ID,
+		Name:   s.Name,
+		Status: openstackInstanceStatus(s.Status),
+	}
+	var img struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(s.Image, &img); err == nil {
+		inst.ImageID = img.ID
+	}
+	return inst
+}
+
+func openstackInstanceStatus(status string) InstanceStatus {
+	switch status {
+	case "ACTIVE":
+		return InstanceStatusRunning
+	case "BUILD", "REBUILD", "REBOOT", "HARD_REBOOT":
+		return InstanceStatusProvisioning
+	case "ERROR":
+		return InstanceStatusFailed
+	default:
+		return InstanceStatusUnknown
+	}
+}
+
+func (c *openstackClient) ListInstances(ctx context.Context, group string) ([]Instance, error) {
+	var out struct {
+		ServerGroups []struct {
+			ID      string   `json:"id"`
+			Name    string   `json:"name"`
+			Members []string `json:"members"`
+		} `json:"server_groups"`
+	}
+	if err := c.do(ctx, openstackComputeService, http.MethodGet, "/os-server-groups", nil, &out); err != nil {
+		return nil, err
+	}
+
+	for _, g := range out.ServerGroups {
+		if g.ID != group && g.Name != group {
+			continue
+		}
+		instances := make([]Instance, 0, len(g.Members))
+		for _, id := range g.Members {
+			inst, err := c.GetInstance(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			instances = append(instances, *inst)
+		}
+		return instances, nil
+	}
+	return nil, fmt.Errorf("%w: server group %s", ErrNotFound, group)
+}
+
+func (c *openstackClient) GetInstance(ctx context.Context, id string) (*Instance, error) {
+	var out struct {
+		Server openstackServer `json:"server"`
+	}
+	if err := c.do(ctx, openstackComputeService, http.MethodGet, "/servers/"+url.PathEscape(id), nil, &out); err != nil {
+		return nil, err
+	}
+	inst := out.Server.toInstance()
+	return &inst, nil
+}
+
+func (c *openstackClient) ReplaceInstance(ctx context.Context, id, imageID string) error {
+	body := map[string]interface{}{
+		"rebuild": map[string]string{
+			"imageRef": imageID,
+		},
+	}
+	return c.do(ctx, openstackComputeService, http.MethodPost, "/servers/"+url.PathEscape(id)+"/action", body, nil)
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func newFakeOpenStack(t *testing.T) (*httptest.Server, *[]string) {
+	var (
+		srv     *httptest.Server
+		actions []string
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/auth/tokens", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		w.Header().Set("X-Subject-Token", "token")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":{"expires_at":%q,"catalog":[
+			{"type":"compute","endpoints":[{"interface":"internal","region":"r1","url":"http://invalid"},{"interface":"public","region":"r1","url":"%s/compute/"}]},
+			{"type":"image","endpoints":[{"interface":"public","region":"r1","url":"%s/image"}]}]}}`,
+			time.Now().Add(time.Hour).Format(time.RFC3339), srv.URL, srv.URL)
+	})
+	mux.HandleFunc("/compute/os-server-groups", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token", r.Header.Get("X-Auth-Token"))
+		w.Write([]byte(`{"server_groups":[{"id":"g1","name":"web","members":["s1","s2"]}]}`))
+	})
+	mux.HandleFunc("/compute/servers/s1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"server":{"id":"s1","name":"web-1","status":"ACTIVE","image":{"id":"i1"}}}`))
+	})
+	mux.HandleFunc("/compute/servers/s2", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"server":{"id":"s2","name":"web-2","status":"REBUILD","image":""}}`))
+	})
+	mux.HandleFunc("/compute/servers/s1/action", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		actions = append(actions, string(data))
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("/image/v2/images", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "web-20210801", r.URL.Query().Get("name"))
+		w.Write([]byte(`{"images":[{"id":"i2"}]}`))
+	})
+	srv = httptest.NewServer(mux)
+	return srv, &actions
+}
+
+func TestOpenStackClient(t *testing.T) {
+	srv, actions := newFakeOpenStack(t)
+	defer srv.Close()
+
+	c := &openstackClient{
+		cfg: &config.VMOpenStackConfig{
+			AuthURL:                 srv.URL + "/v3",
+			Region:                  "r1",
+			ApplicationCredentialID: "app",
+		},
+		secret:     "secret",
+		httpClient: srv.Client(),
+		logger:     zap.NewNop(),
+	}
+	ctx := context.Background()
+
+	instances, err := c.ListInstances(ctx, "web")
+	require.NoError(t, err)
+	assert.Equal(t, []Instance{
+		{ID: "s1", Name: "web-1", ImageID: "i1", Status: InstanceStatusRunning},
+		{ID: "s2", Name: "web-2", Status: InstanceStatusProvisioning},
+	}, instances)
+
+	_, err = c.ListInstances(ctx, "api")
+	assert.Error(t, err)
+
+	imageID, err := c.ResolveImage(ctx, "web-20210801")
+	require.NoError(t, err)
+	assert.Equal(t, "i2", imageID)
+
+	require.NoError(t, c.ReplaceInstance(ctx, "s1", "i2"))
+	assert.Equal(t, []string{`{"rebuild":{"imageRef":"i2"}}`}, *actions)
+}
@@ -0,0 +1,172 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// ErrNotFound is returned when the requested resource was not found in the platform.
+var ErrNotFound = errors.New("not found")
+
+// InstanceStatus represents the status of an instance reported by the platform.
+type InstanceStatus string
+
+const (
+	// InstanceStatusProvisioning means the instance is being created or replaced.
+	InstanceStatusProvisioning InstanceStatus = "PROVISIONING"
+	// InstanceStatusRunning means the instance is running and healthy.
+	InstanceStatusRunning InstanceStatus = "RUNNING"
+	// InstanceStatusFailed means the instance failed to start.
+	InstanceStatusFailed InstanceStatus = "FAILED"
+	// InstanceStatusUnknown means the status could not be mapped to the above ones.
+	InstanceStatusUnknown InstanceStatus = "UNKNOWN"
+)
+
+// Instance represents a virtual machine belonging to an instance group.
+type Instance struct {
+	ID      string
+	Name    string
+	ImageID string
+	Status  InstanceStatus
+}
+
+// Client is the interface to control the instances on a platform.
+type Client interface {
+	// ResolveImage returns the ID of the image specified by name or ID.
+	ResolveImage(ctx context.Context, image string) (string, error)
+	// ListInstances returns all instances belonging to the given group.
+	ListInstances(ctx context.Context, group string) ([]Instance, error)
+	// GetInstance returns the current state of the given instance.
+	GetInstance(ctx context.Context, id string) (*Instance, error)
+	// ReplaceInstance recreates the given instance from the given image.
+	// The instance keeps its ID, addresses and the membership of the group.
+	ReplaceInstance(ctx context.Context, id, imageID string) error
+}
+
+// Template represents the set of properties used to create
+// the instances of a managed group, e.g. the instance template of GCE.
+type Template struct {
+	Name string
+	// The properties in the representation of the platform API.
+	Properties map[string]interface{}
+}
+
+// Group represents the state of a managed instance group.
+type Group struct {
+	Name string
+	// The name of the template the group is updating its instances to.
+	Template   string
+	TargetSize int
+	// Whether all instances have been updated to the template and are healthy.
+	Stable bool
+}
+
+// ManagedGroupClient is the interface to control the groups whose rolling update
+// is performed by the platform itself, e.g. the managed instance groups of GCE.
+type ManagedGroupClient interface {
+	// GetGroup returns the current state of the given group.
+	GetGroup(ctx context.Context, group string) (*Group, error)
+	// BuildTemplate returns the template currently used by the group of the given manifest
+	// and the one built from it to run the given manifest.
+	BuildTemplate(ctx context.Context, m InstanceGroupManifest) (current, desired *Template, err error)
+	// EnsureTemplate creates the given template unless it exists.
+	EnsureTemplate(ctx context.Context, t *Template) error
+	// RollingUpdate starts updating all instances of the given group to the given template.
+	RollingUpdate(ctx context.Context, group string, t *Template, maxSurge, maxUnavailable *config.Replicas) error
+	// Resize changes the number of instances of the given group.
+	// A nil template means the template of the group is kept.
+	Resize(ctx context.Context, group string, t *Template, size int) error
+}
+
+// Registry holds a pool of clients.
+type Registry interface {
+	Client(name string, cfg *config.CloudProviderVMConfig, logger *zap.Logger) (Client, error)
+	ManagedGroupClient(ctx context.Context, name string, cfg *config.CloudProviderVMConfig, logger *zap.Logger) (ManagedGroupClient, error)
+}
+
+type registry struct {
+	clients  map[string]interface{}
+	mu       sync.RWMutex
+	newGroup *singleflight.Group
+}
+
+func (r *registry) Client(name string, cfg *config.CloudProviderVMConfig, logger *zap.Logger) (Client, error) {
+	c, err := r.client(name, func() (interface{}, error) {
+		switch cfg.Platform {
+		case config.VMPlatformOpenStack:
+			return newOpenStackClient(cfg.OpenStack, logger)
+		default:
+			return nil, fmt.Errorf("unsupported vm platform for instance based client: %s", cfg.Platform)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.(Client), nil
+}
+
+func (r *registry) ManagedGroupClient(ctx context.Context, name string, cfg *config.CloudProviderVMConfig, logger *zap.Logger) (ManagedGroupClient, error) {
+	c, err := r.client(name, func() (interface{}, error) {
+		switch cfg.Platform {
+		case config.VMPlatformGCE:
+			return newGCEClient(ctx, cfg.GCE, logger)
+		default:
+			return nil, fmt.Errorf("unsupported vm platform for managed group client: %s", cfg.Platform)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.(ManagedGroupClient), nil
+}
+
+func (r *registry) client(name string, newClient func() (interface{}, error)) (interface{}, error) {
+	r.mu.RLock()
+	client, ok := r.clients[name]
+	r.mu.RUnlock()
+	if ok {
+		return client, nil
+	}
+
+	client, err, _ := r.newGroup.Do(name, newClient)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.clients[name] = client
+	r.mu.Unlock()
+
+	return client, nil
+}
+
+var defaultRegistry = &registry{
+	clients:  make(map[string]interface{}),
+	newGroup: &singleflight.Group{},
+}
+
+// DefaultRegistry returns a pool of clients and a mutex associated with it.
+func DefaultRegistry() Registry {
+	return defaultRegistry
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package piped
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/doctor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/cli"
+	"github.com/pipe-cd/pipe/pkg/git"
+)
+
+type doctorCommand struct {
+	piped
+	skipReport bool
+}
+
+func NewDoctorCommand() *cobra.Command {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		panic(fmt.Sprintf("failed to detect the current user's home directory: %v", err))
+	}
+	d := &doctorCommand{
+		piped: piped{
+			toolsDir: path.Join(home, ".piped", "tools"),
+		},
+	}
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check whether piped is able to work with the given configuration.",
+		RunE:  cli.WithContext(d.run),
+	}
+
+	cmd.Flags().StringVar(&d.configFile, "config-file", d.configFile, "The path to the configuration file.")
+	cmd.Flags().StringVar(&d.configGCPSecret, "config-gcp-secret", d.configGCPSecret, "The resource ID of secret that contains Piped config and be stored in GCP SecretManager.")
+
+	cmd.Flags().BoolVar(&d.insecure, "insecure", d.insecure, "Whether disabling transport security while connecting to control-plane.")
+	cmd.Flags().StringVar(&d.certFile, "cert-file", d.certFile, "The path to the TLS certificate file.")
+	cmd.Flags().StringVar(&d.clientCertFile, "client-cert-file", d.clientCertFile, "The path to the client certificate file used for mutual TLS with control-plane.")
+	cmd.Flags().StringVar(&d.clientKeyFile, "client-key-file", d.clientKeyFile, "The path to the key file of the client certificate.")
+
+	cmd.Flags().StringVar(&d.toolsDir, "tools-dir", d.toolsDir, "The path to directory where to install needed tools such as kubectl, helm, kustomize.")
+	cmd.Flags().BoolVar(&d.useFakeAPIClient, "use-fake-api-client", d.useFakeAPIClient, "Whether the fake api client should be used instead of the real one or not.")
+	cmd.Flags().BoolVar(&d.skipReport, "skip-report", d.skipReport, "Whether to skip reporting the result to the control-plane.")
+
+	return cmd
+}
+
+func (d *doctorCommand) run(ctx context.Context, t cli.Telemetry) error {
+	cfg, err := d.loadConfig(ctx)
+	if err != nil {
+		t.Logger.Error("failed to load piped configuration", zap.Error(err))
+		return err
+	}
+
+	if cfg.Git.ShouldConfigureSSHConfig() {
+		if err := git.AddSSHConfig(cfg.Git); err != nil {
+			t.Logger.Error("failed to configure ssh-config", zap.Error(err))
+			return err
+		}
+	}
+
+	if err := toolregistry.InitDefaultRegistry(d.toolsDir, t.Logger); err != nil {
+		t.Logger.Error("failed to initialize default tool registry", zap.Error(err))
+		return err
+	}
+
+	pipedKey, err := cfg.LoadPipedKey()
+	if err != nil {
+		t.Logger.Error("failed to load piped key", zap.Error(err))
+		return err
+	}
+
+	certRotator, err := d.loadClientCertificate(t.Logger)
+	if err != nil {
+		t.Logger.Error("failed to load client certificate", zap.Error(err))
+		return err
+	}
+
+	apiClient, err := d.createAPIClient(ctx, cfg.APIAddress, cfg.ProjectID, cfg.PipedID, pipedKey, certRotator, t.Logger)
+	if err != nil {
+		t.Logger.Error("failed to create gRPC client to control plane", zap.Error(err))
+		return err
+	}
+
+	doc := doctor.NewDoctor(cfg, apiClient, toolregistry.DefaultRegistry(), t.Logger)
+	diagnostics := doc.Diagnose(ctx)
+	doctor.Write(os.Stdout, diagnostics)
+
+	if !d.skipReport {
+		if err := doc.Report(ctx, diagnostics); err != nil {
+			t.Logger.Error("failed to report diagnostics to control-plane", zap.Error(err))
+		}
+	}
+
+	if !diagnostics.Passed() {
+		return errors.New("some checks were failed")
+	}
+	return nil
+}
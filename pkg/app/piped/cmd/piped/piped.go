@@ -18,7 +18,9 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/exec"
@@ -38,25 +40,36 @@ import (
 	"github.com/pipe-cd/pipe/pkg/admin"
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice/pipedclientfake"
+	metricsplugin "github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics/plugin"
 	"github.com/pipe-cd/pipe/pkg/app/piped/apistore/applicationstore"
 	"github.com/pipe-cd/pipe/pkg/app/piped/apistore/commandstore"
 	"github.com/pipe-cd/pipe/pkg/app/piped/apistore/deploymentstore"
 	"github.com/pipe-cd/pipe/pkg/app/piped/apistore/environmentstore"
 	"github.com/pipe-cd/pipe/pkg/app/piped/apistore/eventstore"
+	"github.com/pipe-cd/pipe/pkg/app/piped/appcleaner"
 	"github.com/pipe-cd/pipe/pkg/app/piped/chartrepo"
+	"github.com/pipe-cd/pipe/pkg/app/piped/clientcert"
+	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
 	k8scloudprovidermetrics "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/kubernetesmetrics"
+	"github.com/pipe-cd/pipe/pkg/app/piped/configreloader"
 	"github.com/pipe-cd/pipe/pkg/app/piped/controller"
+	"github.com/pipe-cd/pipe/pkg/app/piped/doctor"
 	"github.com/pipe-cd/pipe/pkg/app/piped/driftdetector"
 	"github.com/pipe-cd/pipe/pkg/app/piped/eventwatcher"
+	executorregistry "github.com/pipe-cd/pipe/pkg/app/piped/executor/registry"
 	"github.com/pipe-cd/pipe/pkg/app/piped/livestatereporter"
 	"github.com/pipe-cd/pipe/pkg/app/piped/livestatestore"
 	k8slivestatestoremetrics "github.com/pipe-cd/pipe/pkg/app/piped/livestatestore/kubernetes/kubernetesmetrics"
 	"github.com/pipe-cd/pipe/pkg/app/piped/notifier"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planpreview"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planpreview/planpreviewmetrics"
+	"github.com/pipe-cd/pipe/pkg/app/piped/plugin"
 	"github.com/pipe-cd/pipe/pkg/app/piped/statsreporter"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
 	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
 	"github.com/pipe-cd/pipe/pkg/app/piped/trigger"
+	"github.com/pipe-cd/pipe/pkg/app/piped/webhookreceiver"
+	"github.com/pipe-cd/pipe/pkg/cache/diskcache"
 	"github.com/pipe-cd/pipe/pkg/cache/memorycache"
 	"github.com/pipe-cd/pipe/pkg/cli"
 	"github.com/pipe-cd/pipe/pkg/config"
@@ -67,18 +80,21 @@ import (
 	"github.com/pipe-cd/pipe/pkg/rpc/rpcclient"
 	"github.com/pipe-cd/pipe/pkg/version"
 
-	// Import to preload all built-in executors to the default registry.
-	_ "github.com/pipe-cd/pipe/pkg/app/piped/executor/registry"
 	// Import to preload all planners to the default registry.
 	_ "github.com/pipe-cd/pipe/pkg/app/piped/planner/registry"
 )
 
 type piped struct {
-	configFile      string
-	configGCPSecret string
+	configFile             string
+	configGCPSecret        string
+	configOverlayFiles     []string
+	configFromControlPlane bool
+	configReloadInterval   time.Duration
 
 	insecure                             bool
 	certFile                             string
+	clientCertFile                       string
+	clientKeyFile                        string
 	adminPort                            int
 	toolsDir                             string
 	enableDefaultKubernetesCloudProvider bool
@@ -93,9 +109,10 @@ func NewCommand() *cobra.Command {
 		panic(fmt.Sprintf("failed to detect the current user's home directory: %v", err))
 	}
 	p := &piped{
-		adminPort:   9085,
-		toolsDir:    path.Join(home, ".piped", "tools"),
-		gracePeriod: 30 * time.Second,
+		adminPort:            9085,
+		toolsDir:             path.Join(home, ".piped", "tools"),
+		gracePeriod:          30 * time.Second,
+		configReloadInterval: time.Minute,
 	}
 	cmd := &cobra.Command{
 		Use:   "piped",
@@ -105,9 +122,14 @@ func NewCommand() *cobra.Command {
 
 	cmd.Flags().StringVar(&p.configFile, "config-file", p.configFile, "The path to the configuration file.")
 	cmd.Flags().StringVar(&p.configGCPSecret, "config-gcp-secret", p.configGCPSecret, "The resource ID of secret that contains Piped config and be stored in GCP SecretManager.")
+	cmd.Flags().StringSliceVar(&p.configOverlayFiles, "config-overlay-file", p.configOverlayFiles, "The path to the configuration file to be merged into the base one. This can be specified multiple times and they are applied in order.")
+	cmd.Flags().BoolVar(&p.configFromControlPlane, "config-from-control-plane", p.configFromControlPlane, "Whether to use the configuration managed in the control-plane. The local configuration is merged into it to connect to the control-plane.")
+	cmd.Flags().DurationVar(&p.configReloadInterval, "config-reload-interval", p.configReloadInterval, "How often to check the configuration source to apply its changes without restarting. Zero means disabled.")
 
 	cmd.Flags().BoolVar(&p.insecure, "insecure", p.insecure, "Whether disabling transport security while connecting to control-plane.")
 	cmd.Flags().StringVar(&p.certFile, "cert-file", p.certFile, "The path to the TLS certificate file.")
+	cmd.Flags().StringVar(&p.clientCertFile, "client-cert-file", p.clientCertFile, "The path to the client certificate file used for mutual TLS with control-plane. It is renewed automatically before expiring.")
+	cmd.Flags().StringVar(&p.clientKeyFile, "client-key-file", p.clientKeyFile, "The path to the key file of the client certificate.")
 	cmd.Flags().IntVar(&p.adminPort, "admin-port", p.adminPort, "The port number used to run a HTTP server for admin tasks such as metrics, healthz.")
 
 	cmd.Flags().StringVar(&p.toolsDir, "tools-dir", p.toolsDir, "The path to directory where to install needed tools such as kubectl, helm, kustomize.")
@@ -134,6 +156,45 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 		return err
 	}
 
+	pipedKey, err := cfg.LoadPipedKey()
+	if err != nil {
+		t.Logger.Error("failed to load piped key", zap.Error(err))
+		return err
+	}
+
+	// Load the client certificate for mutual TLS if configured.
+	certRotator, err := p.loadClientCertificate(t.Logger)
+	if err != nil {
+		t.Logger.Error("failed to load client certificate", zap.Error(err))
+		return err
+	}
+
+	// Make gRPC client and connect to the API.
+	apiClient, err := p.createAPIClient(ctx, cfg.APIAddress, cfg.ProjectID, cfg.PipedID, pipedKey, certRotator, t.Logger)
+	if err != nil {
+		t.Logger.Error("failed to create gRPC client to control plane", zap.Error(err))
+		return err
+	}
+
+	// Start renewing the client certificate before it expires.
+	if certRotator != nil {
+		group.Go(func() error {
+			return certRotator.Run(ctx, apiClient)
+		})
+	}
+
+	// Replace with the configuration managed in the control-plane if needed.
+	loadConfig := p.loadConfig
+	if p.configFromControlPlane {
+		loadConfig = func(ctx context.Context) (*config.PipedSpec, error) {
+			return p.loadRemoteConfig(ctx, apiClient)
+		}
+		if cfg, err = loadConfig(ctx); err != nil {
+			t.Logger.Error("failed to load piped configuration from control-plane", zap.Error(err))
+			return err
+		}
+	}
+
 	// Register all metrics.
 	registry := registerMetrics(cfg.PipedID)
 
@@ -162,6 +223,47 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 		return err
 	}
 
+	// Apply the configured resource limits to all spawned tools.
+	toolexec.InitDefault(cfg.ToolExecution)
+
+	// Start all configured plugins and register the stages and analysis providers provided by them.
+	if len(cfg.Plugins) > 0 {
+		pm, err := plugin.NewManager(ctx, cfg.Plugins, t.Logger)
+		if err != nil {
+			t.Logger.Error("failed to start plugins", zap.Error(err))
+			return err
+		}
+		defer pm.Stop()
+
+		for _, s := range pm.Stages() {
+			f, _ := pm.ExecutorFactory(s)
+			if err := executorregistry.RegisterExecutor(s, f); err != nil {
+				t.Logger.Error("failed to register plugin executor", zap.Error(err))
+				return err
+			}
+		}
+		metricsplugin.RegisterClients(pm.AnalysisClients())
+	}
+
+	// Cache the rendered Kubernetes manifests to avoid rendering the same commit again.
+	if cfg.RenderCache.MaxSizeMB > 0 {
+		dir := cfg.RenderCache.Dir
+		if dir == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				t.Logger.Error("failed to detect the home directory", zap.Error(err))
+				return err
+			}
+			dir = path.Join(home, ".piped", "render-cache")
+		}
+		c, err := diskcache.New(dir, int64(cfg.RenderCache.MaxSizeMB)*1024*1024)
+		if err != nil {
+			t.Logger.Error("failed to initialize render cache", zap.Error(err))
+			return err
+		}
+		kubernetes.InitRenderCache(c)
+	}
+
 	// Add configured Helm chart repositories.
 	if len(cfg.ChartRepositories) > 0 {
 		reg := toolregistry.DefaultRegistry()
@@ -177,19 +279,6 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 		}
 	}
 
-	pipedKey, err := cfg.LoadPipedKey()
-	if err != nil {
-		t.Logger.Error("failed to load piped key", zap.Error(err))
-		return err
-	}
-
-	// Make gRPC client and connect to the API.
-	apiClient, err := p.createAPIClient(ctx, cfg.APIAddress, cfg.ProjectID, cfg.PipedID, pipedKey, t.Logger)
-	if err != nil {
-		t.Logger.Error("failed to create gRPC client to control plane", zap.Error(err))
-		return err
-	}
-
 	// Send the newest piped meta to the control-plane.
 	if err := p.sendPipedMeta(ctx, apiClient, cfg, t.Logger); err != nil {
 		t.Logger.Error("failed to report piped meta to control-plane", zap.Error(err))
@@ -216,12 +305,19 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 		})
 	}
 
-	// Start running stats reporter.
+	// Run self-diagnostics once and report the result to the control-plane.
+	// Since this is just for reporting, any failure here does not stop piped.
 	{
-		url := fmt.Sprintf("http://localhost:%d/metrics", p.adminPort)
-		r := statsreporter.NewReporter(url, apiClient, t.Logger)
+		d := doctor.NewDoctor(cfg, apiClient, toolregistry.DefaultRegistry(), t.Logger)
 		group.Go(func() error {
-			return r.Run(ctx)
+			diagnostics := d.Diagnose(ctx)
+			if !diagnostics.Passed() {
+				t.Logger.Warn("some self-diagnostic checks were failed, run \"piped doctor\" for more details")
+			}
+			if err := d.Report(ctx, diagnostics); err != nil {
+				t.Logger.Error("failed to report self-diagnostics to control-plane", zap.Error(err))
+			}
+			return nil
 		})
 	}
 
@@ -276,6 +372,12 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 		commandLister = store.Lister()
 	}
 
+	// Initialize config reloader to apply the changes of configuration
+	// to the running components. It will be started after all of them.
+	configReloader := configreloader.NewReloader(cfg, loadConfig, commandLister, p.configReloadInterval, t.Logger)
+	configReloader.Register("notifier", notifier)
+	configReloader.Register("chart-repositories", p.chartRepositoriesReloader(ctx, cfg.ChartRepositories, t.Logger))
+
 	// Start running event store.
 	var eventGetter eventstore.Getter
 	{
@@ -347,6 +449,7 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 			p.gracePeriod,
 			t.Logger,
 		)
+		configReloader.Register("controller", c)
 
 		group.Go(func() error {
 			return c.Run(ctx)
@@ -354,9 +457,13 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 	}
 
 	// Start running deployment trigger.
-	var lastTriggeredCommitGetter trigger.LastTriggeredCommitGetter
+	var (
+		tr                        *trigger.Trigger
+		lastTriggeredCommitGetter trigger.LastTriggeredCommitGetter
+	)
 	{
-		tr, err := trigger.NewTrigger(
+		var err error
+		tr, err = trigger.NewTrigger(
 			apiClient,
 			gitClient,
 			applicationLister,
@@ -372,12 +479,58 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 			return err
 		}
 		lastTriggeredCommitGetter = tr.GetLastTriggeredCommitGetter()
+		configReloader.Register("trigger", tr)
 
 		group.Go(func() error {
 			return tr.Run(ctx)
 		})
 	}
 
+	// Start running app cleaner.
+	{
+		c := appcleaner.NewCleaner(commandLister, cfg, t.Logger)
+		configReloader.Register("app-cleaner", c)
+		group.Go(func() error {
+			return c.Run(ctx)
+		})
+	}
+
+	// Start running stats reporter.
+	{
+		url := fmt.Sprintf("http://localhost:%d/metrics", p.adminPort)
+		d := doctor.NewDoctor(cfg, apiClient, toolregistry.DefaultRegistry(), t.Logger)
+		h := doctor.NewHealthCollector(d, tr)
+		configReloader.Register("health-collector", h)
+		group.Go(func() error {
+			return h.Run(ctx)
+		})
+		r := statsreporter.NewReporter(url, apiClient, h, t.Logger)
+		group.Go(func() error {
+			return r.Run(ctx)
+		})
+	}
+
+	// Start running webhook receiver.
+	if cfg.Webhook.Port > 0 {
+		token, err := cfg.Webhook.LoadToken()
+		if err != nil {
+			t.Logger.Error("failed to load webhook token", zap.Error(err))
+			return err
+		}
+		r := webhookreceiver.NewReceiver(
+			cfg.Webhook,
+			token,
+			apiClient,
+			tr,
+			p.gracePeriod,
+			t.Logger,
+		)
+		configReloader.Register("webhook-receiver", r)
+		group.Go(func() error {
+			return r.Run(ctx)
+		})
+	}
+
 	// Start running event watcher.
 	{
 		w := eventwatcher.NewWatcher(
@@ -420,11 +573,19 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 			cfg,
 			planpreview.WithLogger(t.Logger),
 		)
+		configReloader.Register("planpreview", h)
 		group.Go(func() error {
 			return h.Run(ctx)
 		})
 	}
 
+	// Start running config reloader.
+	if p.configReloadInterval > 0 {
+		group.Go(func() error {
+			return configReloader.Run(ctx)
+		})
+	}
+
 	// Wait until all piped components have finished.
 	// A terminating signal or a finish of any components
 	// could trigger the finish of piped.
@@ -436,8 +597,24 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 	return nil
 }
 
+// loadClientCertificate loads the client certificate used for mutual TLS.
+// Nil is returned when it is not configured.
+func (p *piped) loadClientCertificate(logger *zap.Logger) (*clientcert.Rotator, error) {
+	if p.clientCertFile == "" && p.clientKeyFile == "" {
+		return nil, nil
+	}
+	if p.clientCertFile == "" || p.clientKeyFile == "" {
+		return nil, fmt.Errorf("both client-cert-file and client-key-file must be specified")
+	}
+	if p.insecure {
+		return nil, fmt.Errorf("client certificate cannot be used with insecure connection")
+	}
+	return clientcert.NewRotator(p.clientCertFile, p.clientKeyFile, logger)
+}
+
 // createAPIClient makes a gRPC client to connect to the API.
-func (p *piped) createAPIClient(ctx context.Context, address, projectID, pipedID string, pipedKey []byte, logger *zap.Logger) (pipedservice.Client, error) {
+// The given rotator provides the client certificate if mutual TLS is enabled.
+func (p *piped) createAPIClient(ctx context.Context, address, projectID, pipedID string, pipedKey []byte, certRotator *clientcert.Rotator, logger *zap.Logger) (pipedservice.Client, error) {
 	if p.useFakeAPIClient {
 		return pipedclientfake.NewClient(logger), nil
 	}
@@ -454,7 +631,22 @@ func (p *piped) createAPIClient(ctx context.Context, address, projectID, pipedID
 	)
 
 	if !p.insecure {
-		if p.certFile != "" {
+		if certRotator != nil {
+			config := &tls.Config{
+				GetClientCertificate: certRotator.GetClientCertificate,
+			}
+			if p.certFile != "" {
+				ca, err := ioutil.ReadFile(p.certFile)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read TLS certificate file: %w", err)
+				}
+				config.RootCAs = x509.NewCertPool()
+				if !config.RootCAs.AppendCertsFromPEM(ca) {
+					return nil, fmt.Errorf("no certificate was found in TLS certificate file")
+				}
+			}
+			options = append(options, rpcclient.WithTransportCredentials(credentials.NewTLS(config)))
+		} else if p.certFile != "" {
 			options = append(options, rpcclient.WithTLS(p.certFile))
 		} else {
 			config := &tls.Config{}
@@ -474,26 +666,54 @@ func (p *piped) createAPIClient(ctx context.Context, address, projectID, pipedID
 
 // loadConfig reads the Piped configuration data from the specified source.
 func (p *piped) loadConfig(ctx context.Context) (*config.PipedSpec, error) {
-	if p.configFile != "" && p.configGCPSecret != "" {
-		return nil, fmt.Errorf("only config-file or config-gcp-secret could be set")
+	data, err := p.loadConfigData(ctx)
+	if err != nil {
+		return nil, err
 	}
+	overlays, err := p.loadConfigOverlays()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.DecodeYAMLWithOverlays(data, overlays...)
+	if err != nil {
+		return nil, err
+	}
+	return p.extractPipedSpec(cfg)
+}
 
-	extract := func(cfg *config.Config) (*config.PipedSpec, error) {
-		if cfg.Kind != config.KindPiped {
-			return nil, fmt.Errorf("wrong configuration kind for piped: %v", cfg.Kind)
-		}
-		if p.enableDefaultKubernetesCloudProvider {
-			cfg.PipedSpec.EnableDefaultKubernetesCloudProvider()
-		}
-		return cfg.PipedSpec, nil
+// loadRemoteConfig fetches the latest configuration stored in the control-plane
+// and then merges the local configuration and its overlays into it.
+func (p *piped) loadRemoteConfig(ctx context.Context, client pipedservice.Client) (*config.PipedSpec, error) {
+	resp, err := client.GetPipedConfig(ctx, &pipedservice.GetPipedConfigRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config from control-plane (%w)", err)
+	}
+	data, err := p.loadConfigData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	overlays, err := p.loadConfigOverlays()
+	if err != nil {
+		return nil, err
+	}
+	// The local configuration takes precedence over the remote one
+	// since it contains the settings to connect to the control-plane.
+	overlays = append([][]byte{data}, overlays...)
+	cfg, err := config.DecodeYAMLWithOverlays([]byte(resp.Config.Data), overlays...)
+	if err != nil {
+		return nil, err
+	}
+	return p.extractPipedSpec(cfg)
+}
+
+// loadConfigData reads the raw configuration data from the specified source.
+func (p *piped) loadConfigData(ctx context.Context) ([]byte, error) {
+	if p.configFile != "" && p.configGCPSecret != "" {
+		return nil, fmt.Errorf("only config-file or config-gcp-secret could be set")
 	}
 
 	if p.configFile != "" {
-		cfg, err := config.LoadFromYAML(p.configFile)
-		if err != nil {
-			return nil, err
-		}
-		return extract(cfg)
+		return ioutil.ReadFile(p.configFile)
 	}
 
 	if p.configGCPSecret != "" {
@@ -501,16 +721,62 @@ func (p *piped) loadConfig(ctx context.Context) (*config.PipedSpec, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to load config from SecretManager (%w)", err)
 		}
-		cfg, err := config.DecodeYAML(data)
-		if err != nil {
-			return nil, err
-		}
-		return extract(cfg)
+		return data, nil
 	}
 
 	return nil, fmt.Errorf("either config-file or config-gcp-secret must be set")
 }
 
+func (p *piped) extractPipedSpec(cfg *config.Config) (*config.PipedSpec, error) {
+	if cfg.Kind != config.KindPiped {
+		return nil, fmt.Errorf("wrong configuration kind for piped: %v", cfg.Kind)
+	}
+	if p.enableDefaultKubernetesCloudProvider {
+		cfg.PipedSpec.EnableDefaultKubernetesCloudProvider()
+	}
+	return cfg.PipedSpec, nil
+}
+
+// chartRepositoriesReloader returns a handler to add the newly configured Helm chart repositories.
+func (p *piped) chartRepositoriesReloader(ctx context.Context, repos []config.HelmChartRepository, logger *zap.Logger) configreloader.HandlerFunc {
+	added := make(map[config.HelmChartRepository]struct{}, len(repos))
+	for _, r := range repos {
+		added[r] = struct{}{}
+	}
+	return func(cfg *config.PipedSpec) error {
+		var repos []config.HelmChartRepository
+		for _, r := range cfg.ChartRepositories {
+			if _, ok := added[r]; !ok {
+				repos = append(repos, r)
+			}
+		}
+		if len(repos) == 0 {
+			return nil
+		}
+		reg := toolregistry.DefaultRegistry()
+		if err := chartrepo.Add(ctx, repos, reg, logger); err != nil {
+			return err
+		}
+		for _, r := range repos {
+			added[r] = struct{}{}
+		}
+		return chartrepo.Update(ctx, reg, logger)
+	}
+}
+
+// loadConfigOverlays reads the data of all specified overlay files.
+func (p *piped) loadConfigOverlays() ([][]byte, error) {
+	overlays := make([][]byte, 0, len(p.configOverlayFiles))
+	for _, f := range p.configOverlayFiles {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config overlay file %s (%w)", f, err)
+		}
+		overlays = append(overlays, data)
+	}
+	return overlays, nil
+}
+
 func (p *piped) initializeSecretDecrypter(cfg *config.PipedSpec) (crypto.Decrypter, error) {
 	sm := cfg.GetSecretManagement()
 	if sm == nil {
@@ -0,0 +1,113 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package commitstatus posts the statuses of the deployments
+// on their trigger commits in the Git hosting services such as GitHub and GitLab.
+package commitstatus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/httpjson"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	defaultGitHubAddress = "https://api.github.com"
+	defaultGitLabAddress = "https://gitlab.com"
+)
+
+// State represents the state of a commit status.
+type State string
+
+const (
+	StatePending State = "pending"
+	StateSuccess State = "success"
+	StateFailure State = "failure"
+	StateError   State = "error"
+)
+
+// Status is the status of a deployment posted on its trigger commit.
+type Status struct {
+	State State
+	// The label to differentiate the status from the other ones of the commit.
+	Context     string
+	Description string
+	// The URL to the deployment page.
+	TargetURL string
+}
+
+// Provider posts the commit statuses.
+type Provider interface {
+	// Post creates a status on the given commit of the given repository.
+	// The repository is given in the form of its path, e.g. org/repo.
+	Post(ctx context.Context, repo, commit string, status Status) error
+}
+
+// NewProvider generates an appropriate provider according to the commit status provider config.
+func NewProvider(cfg config.PipedCommitStatusProvider, logger *zap.Logger) (Provider, error) {
+	logger = logger.Named("commit-status").With(zap.String("provider", cfg.Name))
+	switch cfg.Type {
+	case config.CommitStatusProviderGitHub:
+		c := cfg.GitHubConfig
+		token, err := httpjson.ReadSecretFile(c.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the token file: %w", err)
+		}
+		address := c.Address
+		if address == "" {
+			address = defaultGitHubAddress
+		}
+		return &github{
+			address:    strings.TrimSuffix(address, "/"),
+			token:      token,
+			httpClient: httpjson.NewClient(),
+			logger:     logger,
+		}, nil
+
+	case config.CommitStatusProviderGitLab:
+		c := cfg.GitLabConfig
+		token, err := httpjson.ReadSecretFile(c.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the token file: %w", err)
+		}
+		address := c.Address
+		if address == "" {
+			address = defaultGitLabAddress
+		}
+		return &gitlab{
+			address:    strings.TrimSuffix(address, "/"),
+			token:      token,
+			httpClient: httpjson.NewClient(),
+			logger:     logger,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported commit status provider type: %s", cfg.Type)
+	}
+}
+
+// truncate cuts the given text to be at most max characters long
+// without splitting any multi-byte character.
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-3]) + "..."
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commitstatus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGitHubPost(t *testing.T) {
+	var (
+		uri  string
+		body githubStatus
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token github-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		uri = r.RequestURI
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	g := &github{
+		address:    server.URL,
+		token:      "github-token",
+		httpClient: server.Client(),
+		logger:     zap.NewNop(),
+	}
+	err := g.Post(context.Background(), "org/repo", "abc", Status{
+		State:       StateSuccess,
+		Context:     "pipecd/helloworld",
+		Description: strings.Repeat("a", 200),
+		TargetURL:   "https://pipecd.dev/deployments/deployment-id",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/repos/org/repo/statuses/abc", uri)
+	assert.Equal(t, "success", body.State)
+	assert.Equal(t, "pipecd/helloworld", body.Context)
+	assert.Equal(t, "https://pipecd.dev/deployments/deployment-id", body.TargetURL)
+	assert.Equal(t, githubDescriptionMaxLength, len(body.Description))
+
+	g.token = "invalid"
+	err = g.Post(context.Background(), "org/repo", "abc", Status{State: StatePending})
+	assert.Error(t, err)
+}
+
+func TestGitLabPost(t *testing.T) {
+	var (
+		uri  string
+		body gitlabStatus
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "gitlab-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		uri = r.RequestURI
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	g := &gitlab{
+		address:    server.URL,
+		token:      "gitlab-token",
+		httpClient: server.Client(),
+		logger:     zap.NewNop(),
+	}
+
+	testcases := []struct {
+		state    State
+		expected string
+	}{
+		{StatePending, "running"},
+		{StateSuccess, "success"},
+		{StateFailure, "failed"},
+		{StateError, "canceled"},
+	}
+	for _, tc := range testcases {
+		t.Run(string(tc.state), func(t *testing.T) {
+			err := g.Post(context.Background(), "org/group/repo", "abc", Status{
+				State:   tc.state,
+				Context: "pipecd/helloworld",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "/api/v4/projects/org%2Fgroup%2Frepo/statuses/abc", uri)
+			assert.Equal(t, tc.expected, body.State)
+			assert.Equal(t, "pipecd/helloworld", body.Name)
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	testcases := []struct {
+		name     string
+		text     string
+		max      int
+		expected string
+	}{
+		{
+			name:     "short text",
+			text:     "deploying",
+			max:      10,
+			expected: "deploying",
+		},
+		{
+			name:     "long text",
+			text:     "deploying to production",
+			max:      10,
+			expected: "deployi...",
+		},
+		{
+			name:     "multi-byte characters",
+			text:     "デプロイが完了しました",
+			max:      6,
+			expected: "デプロ...",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, truncate(tc.text, tc.max))
+		})
+	}
+}
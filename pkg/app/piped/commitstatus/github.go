@@ -0,0 +1,65 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commitstatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/httpjson"
+)
+
+// GitHub rejects the commit status whose description is longer than this.
+const githubDescriptionMaxLength = 140
+
+type github struct {
+	address    string
+	token      string
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+type githubStatus struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description"`
+	Context     string `json:"context"`
+}
+
+// Post creates a commit status through the GitHub REST API.
+// https://docs.github.com/en/rest/reference/repos#create-a-commit-status
+func (g *github) Post(ctx context.Context, repo, commit string, status Status) error {
+	in := githubStatus{
+		State:       string(status.State),
+		TargetURL:   status.TargetURL,
+		Description: truncate(status.Description, githubDescriptionMaxLength),
+		Context:     status.Context,
+	}
+	req, err := httpjson.NewRequest(ctx, http.MethodPost, fmt.Sprintf("%s/repos/%s/statuses/%s", g.address, repo, commit), in)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+g.token)
+
+	if err := httpjson.Do(g.httpClient, req, nil); err != nil {
+		return fmt.Errorf("failed to post github commit status: %w", err)
+	}
+	g.logger.Info(fmt.Sprintf("posted %s commit status on %s@%s", status.State, repo, commit))
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commitstatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/httpjson"
+)
+
+type gitlab struct {
+	address    string
+	token      string
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+type gitlabStatus struct {
+	State       string `json:"state"`
+	Name        string `json:"name"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description"`
+}
+
+// gitlabStates maps the states to the ones of the GitLab commit statuses.
+var gitlabStates = map[State]string{
+	StatePending: "running",
+	StateSuccess: "success",
+	StateFailure: "failed",
+	StateError:   "canceled",
+}
+
+// Post creates a commit status through the GitLab REST API.
+// https://docs.gitlab.com/ee/api/commits.html#post-the-build-status-to-a-commit
+func (g *gitlab) Post(ctx context.Context, repo, commit string, status Status) error {
+	state, ok := gitlabStates[status.State]
+	if !ok {
+		return fmt.Errorf("unsupported commit status state: %s", status.State)
+	}
+	in := gitlabStatus{
+		State:       state,
+		Name:        status.Context,
+		TargetURL:   status.TargetURL,
+		Description: status.Description,
+	}
+	u := fmt.Sprintf("%s/api/v4/projects/%s/statuses/%s", g.address, url.PathEscape(repo), commit)
+	req, err := httpjson.NewRequest(ctx, http.MethodPost, u, in)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+
+	if err := httpjson.Do(g.httpClient, req, nil); err != nil {
+		return fmt.Errorf("failed to post gitlab commit status: %w", err)
+	}
+	g.logger.Info(fmt.Sprintf("posted %s commit status on %s@%s", status.State, repo, commit))
+	return nil
+}
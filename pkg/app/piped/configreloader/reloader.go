@@ -0,0 +1,205 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package configreloader provides a piped component
+// that periodically reloads the piped configuration from its source
+// and applies the changes to the running components without restarting piped.
+package configreloader
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// Loader loads the latest piped configuration from its source.
+type Loader func(ctx context.Context) (*config.PipedSpec, error)
+
+type commandLister interface {
+	ListPipedCommands() []model.ReportableCommand
+}
+
+var commandCheckInterval = 5 * time.Second
+
+// Handler is implemented by the components which can apply
+// the changes of piped configuration while running.
+type Handler interface {
+	// ReloadConfig applies the given configuration.
+	// This must not affect the in-flight works such as running deployments.
+	// The same configuration may be given again when the other handlers failed to apply it.
+	ReloadConfig(cfg *config.PipedSpec) error
+}
+
+// HandlerFunc is an adapter to allow the use of ordinary functions as Handler.
+type HandlerFunc func(cfg *config.PipedSpec) error
+
+// ReloadConfig calls f(cfg).
+func (f HandlerFunc) ReloadConfig(cfg *config.PipedSpec) error {
+	return f(cfg)
+}
+
+type namedHandler struct {
+	name    string
+	handler Handler
+}
+
+type Reloader struct {
+	current       *config.PipedSpec
+	loader        Loader
+	commandLister commandLister
+	interval      time.Duration
+	handlers      []namedHandler
+	logger        *zap.Logger
+}
+
+// NewReloader creates a new Reloader which checks the configuration
+// returned by the given loader at every interval
+// and also when a RELOAD_PIPED_CONFIG command was received.
+func NewReloader(cfg *config.PipedSpec, loader Loader, cl commandLister, interval time.Duration, logger *zap.Logger) *Reloader {
+	return &Reloader{
+		current:       cfg,
+		loader:        loader,
+		commandLister: cl,
+		interval:      interval,
+		logger:        logger.Named("config-reloader"),
+	}
+}
+
+// Register adds a handler to be notified when the configuration was changed.
+// This must be called before starting Run.
+func (r *Reloader) Register(name string, h Handler) {
+	r.handlers = append(r.handlers, namedHandler{
+		name:    name,
+		handler: h,
+	})
+}
+
+// Run starts checking the configuration until the specified context has done.
+func (r *Reloader) Run(ctx context.Context) error {
+	r.logger.Info(fmt.Sprintf("start running config reloader with %d handlers", len(r.handlers)))
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	commandTicker := time.NewTicker(commandCheckInterval)
+	defer commandTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("config reloader has been stopped")
+			return nil
+
+		case <-ticker.C:
+			if err := r.reload(ctx); err != nil {
+				r.logger.Error("failed to reload piped configuration", zap.Error(err))
+			}
+
+		case <-commandTicker.C:
+			r.checkCommands(ctx)
+		}
+	}
+}
+
+// checkCommands reloads the configuration immediately
+// when it was updated in the control-plane.
+func (r *Reloader) checkCommands(ctx context.Context) {
+	if r.commandLister == nil {
+		return
+	}
+	commands := r.commandLister.ListPipedCommands()
+	if len(commands) == 0 {
+		return
+	}
+
+	// Reloading once is enough to apply the latest one.
+	err := r.reload(ctx)
+	status := model.CommandStatus_COMMAND_SUCCEEDED
+	if err != nil {
+		r.logger.Error("failed to reload piped configuration", zap.Error(err))
+		status = model.CommandStatus_COMMAND_FAILED
+	}
+	for _, cmd := range commands {
+		if err := cmd.Report(ctx, status, nil, nil); err != nil {
+			r.logger.Error("failed to report command status", zap.Error(err))
+		}
+	}
+}
+
+func (r *Reloader) reload(ctx context.Context) error {
+	cfg, err := r.loader(ctx)
+	if err != nil {
+		// Keep using the current configuration until the source becomes valid again.
+		return fmt.Errorf("failed to load piped configuration: %w", err)
+	}
+	if reflect.DeepEqual(cfg, r.current) {
+		return nil
+	}
+	if fields := unreloadableChanges(r.current, cfg); len(fields) > 0 {
+		return fmt.Errorf("piped must be restarted to apply the change of %v", fields)
+	}
+
+	r.logger.Info("detected a change of piped configuration, start applying it")
+	var failed []string
+	for _, h := range r.handlers {
+		if err := h.handler.ReloadConfig(cfg); err != nil {
+			r.logger.Error("failed to apply the new configuration",
+				zap.String("handler", h.name),
+				zap.Error(err),
+			)
+			failed = append(failed, h.name)
+			continue
+		}
+		r.logger.Info("successfully applied the new configuration", zap.String("handler", h.name))
+	}
+
+	// Keep the current one to apply the new configuration again at the next check.
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to apply the new configuration to %v", failed)
+	}
+	r.current = cfg
+	return nil
+}
+
+// unreloadableChanges returns the names of changed fields
+// which cannot be applied without restarting piped.
+// Note that the livestate stores and drift detectors keep using the cloud providers
+// configured at starting, their changes are applied only to the new deployments.
+func unreloadableChanges(old, new *config.PipedSpec) []string {
+	var fields []string
+	check := func(name string, o, n interface{}) {
+		if !reflect.DeepEqual(o, n) {
+			fields = append(fields, name)
+		}
+	}
+	check("projectID", old.ProjectID, new.ProjectID)
+	check("pipedID", old.PipedID, new.PipedID)
+	check("pipedKeyFile", old.PipedKeyFile, new.PipedKeyFile)
+	check("pipedKeyData", old.PipedKeyData, new.PipedKeyData)
+	check("apiAddress", old.APIAddress, new.APIAddress)
+	check("git", old.Git, new.Git)
+	check("sealedSecretManagement", old.SealedSecretManagement, new.SealedSecretManagement)
+	check("secretManagement", old.SecretManagement, new.SecretManagement)
+	check("toolExecution", old.ToolExecution, new.ToolExecution)
+	check("renderCache", old.RenderCache, new.RenderCache)
+	check("webhook.port", old.Webhook.Port, new.Webhook.Port)
+	check("webhook.tokenFile", old.Webhook.TokenFile, new.Webhook.TokenFile)
+	return fields
+}
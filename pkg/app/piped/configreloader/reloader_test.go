@@ -0,0 +1,175 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configreloader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeHandler struct {
+	configs []*config.PipedSpec
+	err     error
+}
+
+func (h *fakeHandler) ReloadConfig(cfg *config.PipedSpec) error {
+	h.configs = append(h.configs, cfg)
+	return h.err
+}
+
+func TestReload(t *testing.T) {
+	base := func() *config.PipedSpec {
+		return &config.PipedSpec{
+			ProjectID:  "project",
+			PipedID:    "piped",
+			APIAddress: "pipecd.dev:443",
+			Repositories: []config.PipedRepository{
+				{RepoID: "repo-1", Remote: "git@github.com:org/repo-1.git", Branch: "main"},
+			},
+		}
+	}
+	withRepo := base()
+	withRepo.Repositories = append(withRepo.Repositories, config.PipedRepository{
+		RepoID: "repo-2",
+		Remote: "git@github.com:org/repo-2.git",
+		Branch: "main",
+	})
+	withPipedID := base()
+	withPipedID.PipedID = "another-piped"
+
+	testcases := []struct {
+		name        string
+		loaded      *config.PipedSpec
+		loadErr     error
+		handlerErr  error
+		expected    []*config.PipedSpec
+		expectedCfg *config.PipedSpec
+		expectedErr bool
+	}{
+		{
+			name:        "no change",
+			loaded:      base(),
+			expectedCfg: base(),
+		},
+		{
+			name:        "failed to load",
+			loadErr:     errors.New("invalid configuration"),
+			expectedCfg: base(),
+			expectedErr: true,
+		},
+		{
+			name:        "reloadable change",
+			loaded:      withRepo,
+			expected:    []*config.PipedSpec{withRepo, withRepo},
+			expectedCfg: withRepo,
+		},
+		{
+			name:        "unreloadable change",
+			loaded:      withPipedID,
+			expectedCfg: base(),
+			expectedErr: true,
+		},
+		{
+			name:        "handler failed",
+			loaded:      withRepo,
+			handlerErr:  errors.New("failed"),
+			expected:    []*config.PipedSpec{withRepo, withRepo},
+			expectedCfg: base(),
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			loader := func(_ context.Context) (*config.PipedSpec, error) {
+				return tc.loaded, tc.loadErr
+			}
+			r := NewReloader(base(), loader, nil, 0, zap.NewNop())
+			h := &fakeHandler{err: tc.handlerErr}
+			r.Register("first", h)
+			r.Register("second", h)
+
+			err := r.reload(context.Background())
+			assert.Equal(t, tc.expectedErr, err != nil)
+			// All handlers are called even if some of them were failed.
+			assert.Equal(t, tc.expected, h.configs)
+			assert.Equal(t, tc.expectedCfg, r.current)
+		})
+	}
+}
+
+func TestUnreloadableChanges(t *testing.T) {
+	old := &config.PipedSpec{
+		PipedID: "piped",
+		Git: config.PipedGit{
+			Username: "user",
+		},
+	}
+	new := &config.PipedSpec{
+		PipedID: "piped",
+		Git: config.PipedGit{
+			Username: "another-user",
+		},
+		SecretManagement: &config.SecretManagement{},
+	}
+	assert.Equal(t, []string{"git", "secretManagement"}, unreloadableChanges(old, new))
+	assert.Empty(t, unreloadableChanges(old, old))
+}
+
+type fakeCommandLister struct {
+	commands []model.ReportableCommand
+}
+
+func (l *fakeCommandLister) ListPipedCommands() []model.ReportableCommand {
+	return l.commands
+}
+
+func TestCheckCommands(t *testing.T) {
+	var reported []model.CommandStatus
+	report := func(_ context.Context, status model.CommandStatus, _ map[string]string, _ []byte) error {
+		reported = append(reported, status)
+		return nil
+	}
+	cl := &fakeCommandLister{
+		commands: []model.ReportableCommand{
+			{Command: &model.Command{Id: "command-1"}, Report: report},
+			{Command: &model.Command{Id: "command-2"}, Report: report},
+		},
+	}
+
+	var loaded int
+	loader := func(_ context.Context) (*config.PipedSpec, error) {
+		loaded++
+		return &config.PipedSpec{PipedID: "piped", SyncInterval: config.Duration(time.Minute)}, nil
+	}
+	r := NewReloader(&config.PipedSpec{PipedID: "piped"}, loader, cl, 0, zap.NewNop())
+	h := &fakeHandler{}
+	r.Register("handler", h)
+
+	r.checkCommands(context.Background())
+	assert.Equal(t, 1, loaded)
+	assert.Len(t, h.configs, 1)
+	assert.Equal(t, []model.CommandStatus{
+		model.CommandStatus_COMMAND_SUCCEEDED,
+		model.CommandStatus_COMMAND_SUCCEEDED,
+	}, reported)
+}
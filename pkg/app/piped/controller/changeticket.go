@@ -0,0 +1,72 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/changeticket"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const completeChangeTicketTimeout = 2 * time.Minute
+
+// completeChangeTicket records the result of the deployment into
+// the change ticket handled by the WAIT_APPROVAL stage if any.
+// It is called in background after reporting the completion of the deployment
+// so it has its own context to not be canceled when the scheduler finishes.
+// The failures are only logged since the deployment has been already finished.
+func (s *scheduler) completeChangeTicket(status model.DeploymentStatus, reason string) {
+	name, ok := s.metadataStore.Get(changeticket.ProviderMetadataKey)
+	if !ok {
+		return
+	}
+	id, ok := s.metadataStore.Get(changeticket.IDMetadataKey)
+	if !ok {
+		return
+	}
+	logger := s.logger.With(
+		zap.String("provider", name),
+		zap.String("ticket", id),
+	)
+
+	cfg, ok := s.pipedConfig.GetChangeManagementProvider(name)
+	if !ok {
+		logger.Error("change management provider was not found")
+		return
+	}
+	provider, err := changeticket.NewProvider(cfg, s.logger)
+	if err != nil {
+		logger.Error("failed to initialize change management provider", zap.Error(err))
+		return
+	}
+
+	comment := fmt.Sprintf("PipeCD deployment %s finished with status %s.", s.deployment.Id, status.String())
+	if reason != "" {
+		comment = fmt.Sprintf("%s\n%s", comment, reason)
+	}
+	success := status == model.DeploymentStatus_DEPLOYMENT_SUCCESS
+	ctx, cancel := context.WithTimeout(context.Background(), completeChangeTicketTimeout)
+	defer cancel()
+	if err := provider.Complete(ctx, id, success, comment); err != nil {
+		logger.Error("failed to complete change ticket", zap.Error(err))
+		return
+	}
+	logger.Info("completed change ticket")
+}
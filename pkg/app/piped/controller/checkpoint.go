@@ -0,0 +1,140 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// The maximum number of times a stage can be resumed after being interrupted.
+// The stage is failed when exceeding it to not fall into the restart loop of piped
+// caused by the stage itself, e.g. running out of memory.
+var maxStageResumes int32 = 3
+
+// The maximum time to save the checkpoint while piped is shutting down.
+var checkpointSaveTimeout = 10 * time.Second
+
+// checkpoint is the progress of the pipeline saved in the control-plane
+// to resume the deployment from where it was interrupted after piped was restarted.
+// The statuses and metadata of the stages are saved separately while executing them.
+type checkpoint struct {
+	startedAt         time.Time
+	runningStageID    string
+	resumedCount      int32
+	stoppedGracefully bool
+}
+
+func loadCheckpoint(d *model.Deployment) checkpoint {
+	if d.Checkpoint == nil {
+		return checkpoint{}
+	}
+	c := checkpoint{
+		runningStageID:    d.Checkpoint.RunningStageId,
+		resumedCount:      d.Checkpoint.ResumedCount,
+		stoppedGracefully: d.Checkpoint.StoppedGracefully,
+	}
+	if d.Checkpoint.StartedAt > 0 {
+		c.startedAt = time.Unix(d.Checkpoint.StartedAt, 0)
+	}
+	return c
+}
+
+// remainingTimeout returns how long the pipeline can still run
+// within the given timeout counted from when it was started at first.
+func (c checkpoint) remainingTimeout(timeout time.Duration, now time.Time) time.Duration {
+	if c.startedAt.IsZero() {
+		return timeout
+	}
+	remaining := timeout - now.Sub(c.startedAt)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// startStage moves the checkpoint to the given stage.
+// True is returned when the stage is resumed since it was interrupted while running.
+// Only the interruptions of piped crashing while running the stage are counted,
+// the stages stopped gracefully and the waiting stages are resumed without counting.
+func (c *checkpoint) startStage(ps *model.PipelineStage) bool {
+	stopped := c.stoppedGracefully
+	c.stoppedGracefully = false
+	if ps.Status != model.StageStatus_STAGE_RUNNING {
+		c.runningStageID = ps.Id
+		c.resumedCount = 0
+		return false
+	}
+	if c.runningStageID != ps.Id {
+		c.runningStageID = ps.Id
+		c.resumedCount = 1
+	} else if !stopped {
+		c.resumedCount++
+	}
+	if isWaitingStage(ps) {
+		c.resumedCount = 0
+	}
+	return true
+}
+
+// stopStage marks that the running stage was stopped by piped shutting down gracefully
+// so that resuming it is not counted as an interruption.
+func (c *checkpoint) stopStage() {
+	c.stoppedGracefully = true
+}
+
+// isWaitingStage reports whether the given stage mostly waits for something,
+// such as time or approval, which makes it likely to be interrupted by unrelated restarts of piped.
+func isWaitingStage(ps *model.PipelineStage) bool {
+	switch model.Stage(ps.Name) {
+	case model.StageWait, model.StageWaitApproval:
+		return true
+	}
+	return false
+}
+
+// saveCheckpoint persists the current checkpoint to the control-plane.
+// The failures are only logged since the deployment can still continue without it.
+func (s *scheduler) saveCheckpoint(ctx context.Context) {
+	var (
+		err error
+		now = s.nowFunc()
+		req = &pipedservice.SaveDeploymentCheckpointRequest{
+			DeploymentId: s.deployment.Id,
+			Checkpoint: &model.DeploymentCheckpoint{
+				StartedAt:         s.checkpoint.startedAt.Unix(),
+				RunningStageId:    s.checkpoint.runningStageID,
+				ResumedCount:      s.checkpoint.resumedCount,
+				StoppedGracefully: s.checkpoint.stoppedGracefully,
+				UpdatedAt:         now.Unix(),
+			},
+		}
+		retry = pipedservice.NewRetry(3)
+	)
+
+	for retry.WaitNext(ctx) {
+		if _, err = s.apiClient.SaveDeploymentCheckpoint(ctx, req); err == nil {
+			return
+		}
+		err = fmt.Errorf("failed to save deployment checkpoint: %w", err)
+	}
+	s.logger.Error("unable to save deployment checkpoint", zap.Error(err))
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestLoadCheckpoint(t *testing.T) {
+	c := loadCheckpoint(&model.Deployment{})
+	assert.Equal(t, checkpoint{}, c)
+
+	c = loadCheckpoint(&model.Deployment{
+		Checkpoint: &model.DeploymentCheckpoint{
+			StartedAt:         100,
+			RunningStageId:    "stage-1",
+			ResumedCount:      2,
+			StoppedGracefully: true,
+			UpdatedAt:         200,
+		},
+	})
+	assert.Equal(t, checkpoint{
+		startedAt:         time.Unix(100, 0),
+		runningStageID:    "stage-1",
+		resumedCount:      2,
+		stoppedGracefully: true,
+	}, c)
+}
+
+func TestCheckpointRemainingTimeout(t *testing.T) {
+	now := time.Unix(1000, 0)
+	testcases := []struct {
+		name       string
+		checkpoint checkpoint
+		expected   time.Duration
+	}{
+		{
+			name:       "not started yet",
+			checkpoint: checkpoint{},
+			expected:   time.Hour,
+		},
+		{
+			name:       "started before",
+			checkpoint: checkpoint{startedAt: now.Add(-20 * time.Minute)},
+			expected:   40 * time.Minute,
+		},
+		{
+			name:       "already timed out",
+			checkpoint: checkpoint{startedAt: now.Add(-2 * time.Hour)},
+			expected:   0,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.checkpoint.remainingTimeout(time.Hour, now)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestCheckpointStartStage(t *testing.T) {
+	c := checkpoint{}
+
+	resumed := c.startStage(&model.PipelineStage{Id: "stage-1", Status: model.StageStatus_STAGE_NOT_STARTED_YET})
+	assert.False(t, resumed)
+	assert.Equal(t, "stage-1", c.runningStageID)
+	assert.Equal(t, int32(0), c.resumedCount)
+
+	// The stage interrupted while running is resumed.
+	running := &model.PipelineStage{Id: "stage-1", Status: model.StageStatus_STAGE_RUNNING}
+	resumed = c.startStage(running)
+	assert.True(t, resumed)
+	assert.Equal(t, int32(1), c.resumedCount)
+
+	resumed = c.startStage(running)
+	assert.True(t, resumed)
+	assert.Equal(t, int32(2), c.resumedCount)
+
+	// The count is reset when moving to the next stage.
+	resumed = c.startStage(&model.PipelineStage{Id: "stage-2", Status: model.StageStatus_STAGE_NOT_STARTED_YET})
+	assert.False(t, resumed)
+	assert.Equal(t, "stage-2", c.runningStageID)
+	assert.Equal(t, int32(0), c.resumedCount)
+
+	// The running stage unknown by the checkpoint is also resumed.
+	running = &model.PipelineStage{Id: "stage-3", Status: model.StageStatus_STAGE_RUNNING}
+	resumed = c.startStage(running)
+	assert.True(t, resumed)
+	assert.Equal(t, "stage-3", c.runningStageID)
+	assert.Equal(t, int32(1), c.resumedCount)
+
+	// The stage stopped gracefully is resumed without counting.
+	c.stopStage()
+	resumed = c.startStage(running)
+	assert.True(t, resumed)
+	assert.Equal(t, int32(1), c.resumedCount)
+	assert.False(t, c.stoppedGracefully)
+
+	resumed = c.startStage(running)
+	assert.True(t, resumed)
+	assert.Equal(t, int32(2), c.resumedCount)
+
+	// The waiting stage is resumed without counting.
+	waiting := &model.PipelineStage{Id: "stage-4", Name: model.StageWaitApproval.String(), Status: model.StageStatus_STAGE_RUNNING}
+	for i := 0; i < 5; i++ {
+		resumed = c.startStage(waiting)
+		assert.True(t, resumed)
+		assert.Equal(t, int32(0), c.resumedCount)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/commitstatus"
+	"github.com/pipe-cd/pipe/pkg/git"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const postCommitStatusTimeout = time.Minute
+
+// postCompletedCommitStatus posts the final status of the completed deployment.
+// It is run asynchronously after reporting the completion, so it uses its own context
+// to not be cancelled together with the deployment.
+func (s *scheduler) postCompletedCommitStatus(status model.DeploymentStatus, description string) {
+	ctx, cancel := context.WithTimeout(context.Background(), postCommitStatusTimeout)
+	defer cancel()
+	s.postCommitStatus(ctx, status, description)
+}
+
+// postCommitStatus posts the status of the deployment on its trigger commit
+// when the application configured to do so.
+// The failures are only logged since they must not affect the deployment.
+func (s *scheduler) postCommitStatus(ctx context.Context, status model.DeploymentStatus, description string) {
+	cs := s.genericDeploymentConfig.CommitStatus
+	if cs == nil || s.deployment.IsDryRun() {
+		return
+	}
+	logger := s.logger.With(zap.String("commit-status-provider", cs.Provider))
+
+	cfg, ok := s.pipedConfig.GetCommitStatusProvider(cs.Provider)
+	if !ok {
+		logger.Error(fmt.Sprintf("commit status provider %s was not found in the piped configuration", cs.Provider))
+		return
+	}
+	provider, err := commitstatus.NewProvider(cfg, s.logger)
+	if err != nil {
+		logger.Error("failed to create commit status provider", zap.Error(err))
+		return
+	}
+
+	d := s.deployment
+	if d.GitPath == nil || d.GitPath.Repo == nil {
+		logger.Error("unable to post commit status since the deployment has no repository information")
+		return
+	}
+	repo, err := git.ParseRepoPath(d.GitPath.Repo.Remote)
+	if err != nil {
+		logger.Error("failed to determine the repository to post commit status", zap.Error(err))
+		return
+	}
+
+	var state commitstatus.State
+	switch status {
+	case model.DeploymentStatus_DEPLOYMENT_SUCCESS:
+		state = commitstatus.StateSuccess
+	case model.DeploymentStatus_DEPLOYMENT_FAILURE:
+		state = commitstatus.StateFailure
+	case model.DeploymentStatus_DEPLOYMENT_CANCELLED:
+		state = commitstatus.StateError
+	default:
+		state = commitstatus.StatePending
+	}
+	statusContext := cs.Context
+	if statusContext == "" {
+		statusContext = fmt.Sprintf("pipecd/%s", d.ApplicationName)
+	}
+
+	err = provider.Post(ctx, repo, d.Trigger.Commit.Hash, commitstatus.Status{
+		State:       state,
+		Context:     statusContext,
+		Description: description,
+		TargetURL:   fmt.Sprintf("%s/deployments/%s", strings.TrimRight(s.pipedConfig.WebAddress, "/"), d.Id),
+	})
+	if err != nil {
+		logger.Error("failed to post commit status", zap.Error(err))
+	}
+}
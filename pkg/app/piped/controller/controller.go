@@ -25,9 +25,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -49,12 +51,20 @@ type apiClient interface {
 	ReportDeploymentStatusChanged(ctx context.Context, req *pipedservice.ReportDeploymentStatusChangedRequest, opts ...grpc.CallOption) (*pipedservice.ReportDeploymentStatusChangedResponse, error)
 	ReportDeploymentCompleted(ctx context.Context, req *pipedservice.ReportDeploymentCompletedRequest, opts ...grpc.CallOption) (*pipedservice.ReportDeploymentCompletedResponse, error)
 	SaveDeploymentMetadata(ctx context.Context, req *pipedservice.SaveDeploymentMetadataRequest, opts ...grpc.CallOption) (*pipedservice.SaveDeploymentMetadataResponse, error)
+	SaveDeploymentCheckpoint(ctx context.Context, req *pipedservice.SaveDeploymentCheckpointRequest, opts ...grpc.CallOption) (*pipedservice.SaveDeploymentCheckpointResponse, error)
 	ReportApplicationMostRecentDeployment(ctx context.Context, req *pipedservice.ReportApplicationMostRecentDeploymentRequest, opts ...grpc.CallOption) (*pipedservice.ReportApplicationMostRecentDeploymentResponse, error)
+	AcquireDeploymentLocks(ctx context.Context, req *pipedservice.AcquireDeploymentLocksRequest, opts ...grpc.CallOption) (*pipedservice.AcquireDeploymentLocksResponse, error)
+	ReleaseDeploymentLocks(ctx context.Context, req *pipedservice.ReleaseDeploymentLocksRequest, opts ...grpc.CallOption) (*pipedservice.ReleaseDeploymentLocksResponse, error)
+	AcquireDeploymentLease(ctx context.Context, req *pipedservice.AcquireDeploymentLeaseRequest, opts ...grpc.CallOption) (*pipedservice.AcquireDeploymentLeaseResponse, error)
+	ReleaseDeploymentLease(ctx context.Context, req *pipedservice.ReleaseDeploymentLeaseRequest, opts ...grpc.CallOption) (*pipedservice.ReleaseDeploymentLeaseResponse, error)
 
 	ReportStageStatusChanged(ctx context.Context, req *pipedservice.ReportStageStatusChangedRequest, opts ...grpc.CallOption) (*pipedservice.ReportStageStatusChangedResponse, error)
 	SaveStageMetadata(ctx context.Context, req *pipedservice.SaveStageMetadataRequest, opts ...grpc.CallOption) (*pipedservice.SaveStageMetadataResponse, error)
+	SaveStageResults(ctx context.Context, req *pipedservice.SaveStageResultsRequest, opts ...grpc.CallOption) (*pipedservice.SaveStageResultsResponse, error)
 	ReportStageLogs(ctx context.Context, req *pipedservice.ReportStageLogsRequest, opts ...grpc.CallOption) (*pipedservice.ReportStageLogsResponse, error)
 	ReportStageLogsFromLastCheckpoint(ctx context.Context, in *pipedservice.ReportStageLogsFromLastCheckpointRequest, opts ...grpc.CallOption) (*pipedservice.ReportStageLogsFromLastCheckpointResponse, error)
+	ReportAnalysisResult(ctx context.Context, req *pipedservice.ReportAnalysisResultRequest, opts ...grpc.CallOption) (*pipedservice.ReportAnalysisResultResponse, error)
+	ReportDeploymentProvenance(ctx context.Context, req *pipedservice.ReportDeploymentProvenanceRequest, opts ...grpc.CallOption) (*pipedservice.ReportDeploymentProvenanceResponse, error)
 }
 
 type gitClient interface {
@@ -74,6 +84,7 @@ type commandLister interface {
 
 type applicationLister interface {
 	Get(id string) (*model.Application, bool)
+	List() []*model.Application
 }
 
 type environmentLister interface {
@@ -94,11 +105,14 @@ type secretDecrypter interface {
 
 type DeploymentController interface {
 	Run(ctx context.Context) error
+	ReloadConfig(cfg *config.PipedSpec) error
 }
 
 var (
-	plannerStaleDuration   = time.Hour
-	schedulerStaleDuration = time.Hour
+	plannerStaleDuration       = time.Hour
+	schedulerStaleDuration     = time.Hour
+	orphanedVariantsCheckDelay = time.Minute
+	handoverCheckInterval      = time.Second
 )
 
 type controller struct {
@@ -115,6 +129,11 @@ type controller struct {
 	appManifestsCache  cache.Cache
 	logPersister       logpersister.Persister
 
+	// Channel to receive the reloaded piped configuration.
+	// The running planners and schedulers keep using the configuration
+	// they were started with, only the new ones will use the reloaded one.
+	pipedConfigCh chan *config.PipedSpec
+
 	// Map from application ID to the planner
 	// of a pending deployment of that application.
 	planners map[string]*planner
@@ -131,9 +150,14 @@ type controller struct {
 	doneSchedulers map[string]time.Time
 	// Map from application ID to its most recently successful commit hash.
 	mostRecentlySuccessfulCommits map[string]string
+	// Map from application ID to the supersede policy
+	// loaded by the most recent scheduler of that application.
+	supersedePolicies map[string]config.SupersedePolicy
 	// WaitGroup for waiting the completions of all planners, schedulers.
 	wg sync.WaitGroup
 
+	// The unique ID of this piped instance used to hold the leases of the running deployments.
+	instanceID   string
 	workspaceDir string
 	syncInternal time.Duration
 	gracePeriod  time.Duration
@@ -174,13 +198,16 @@ func NewController(
 		appManifestsCache:  appManifestsCache,
 		pipedConfig:        pipedConfig,
 		logPersister:       lp,
+		pipedConfigCh:      make(chan *config.PipedSpec, 1),
 
 		planners:                      make(map[string]*planner),
 		donePlanners:                  make(map[string]time.Time),
 		schedulers:                    make(map[string]*scheduler),
 		doneSchedulers:                make(map[string]time.Time),
 		mostRecentlySuccessfulCommits: make(map[string]string),
+		supersedePolicies:             make(map[string]config.SupersedePolicy),
 
+		instanceID:   uuid.New().String(),
 		syncInternal: 10 * time.Second,
 		gracePeriod:  gracePeriod,
 		logger:       lg,
@@ -214,9 +241,20 @@ func (c *controller) Run(ctx context.Context) error {
 		close(lpStoppedCh)
 	}()
 
+	// The planners and schedulers are not stopped as soon as the given ctx is done.
+	// Instead, they are handed over at the boundary of their stages within the grace period
+	// and then terminated by cancelling this context.
+	handlerCtx, stopHandlers := context.WithCancel(context.Background())
+	defer stopHandlers()
+
 	ticker := time.NewTicker(c.syncInternal)
 	defer ticker.Stop()
-	c.logger.Info("start syncing planners and schedulers")
+	c.logger.Info("start syncing planners and schedulers", zap.String("instance-id", c.instanceID))
+
+	var (
+		startedAt               = time.Now()
+		orphanedVariantsChecked bool
+	)
 
 L:
 	for {
@@ -224,16 +262,27 @@ L:
 		case <-ctx.Done():
 			break L
 
+		case cfg := <-c.pipedConfigCh:
+			c.pipedConfig = cfg
+			c.logger.Info("piped configuration was reloaded, it will be used by the new planners and schedulers")
+
 		case <-ticker.C:
 			// syncSchedulers must be called before syncPlanners because
 			// after piped is restarted all running deployments need to be loaded firstly.
-			c.syncSchedulers(ctx)
-			c.syncPlanners(ctx)
+			c.syncSchedulers(ctx, handlerCtx)
+			c.syncPlanners(ctx, handlerCtx)
 			c.checkCommands()
+
+			// Wait a while after startup to have the live states of all applications loaded.
+			if !orphanedVariantsChecked && time.Since(startedAt) >= orphanedVariantsCheckDelay {
+				c.checkOrphanedVariants(ctx)
+				orphanedVariantsChecked = true
+			}
 		}
 	}
 
 	c.logger.Info("waiting for stopping all planners and schedulers")
+	c.handover(stopHandlers)
 	c.wg.Wait()
 
 	// Stop log persiter and wait for its stopping.
@@ -244,6 +293,60 @@ L:
 	return err
 }
 
+// ReloadConfig makes the new planners and schedulers use the given configuration.
+func (c *controller) ReloadConfig(cfg *config.PipedSpec) error {
+	// Drop the pending one since it is outdated.
+	select {
+	case <-c.pipedConfigCh:
+	default:
+	}
+	c.pipedConfigCh <- cfg
+	return nil
+}
+
+// handover stops accepting new deployments and asks the running schedulers
+// to stop before starting their next stages. The ones still running when
+// the grace period is over are terminated by calling the given stop function,
+// their running stages will be executed again by the piped resuming them.
+func (c *controller) handover(stop func()) {
+	for _, s := range c.schedulers {
+		s.Handover()
+	}
+
+	timer := time.NewTimer(c.gracePeriod)
+	defer timer.Stop()
+	ticker := time.NewTicker(handoverCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if c.allHandlersDone() {
+			c.logger.Info("all planners and schedulers have been stopped gracefully")
+			return
+		}
+		select {
+		case <-timer.C:
+			c.logger.Info("terminate the planners and schedulers still running after the grace period")
+			stop()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *controller) allHandlersDone() bool {
+	for _, p := range c.planners {
+		if !p.IsDone() {
+			return false
+		}
+	}
+	for _, s := range c.schedulers {
+		if !s.IsDone() {
+			return false
+		}
+	}
+	return true
+}
+
 // checkCommands lists all unhandled commands for running deployments
 // and forwards them to their planners and schedulers.
 func (c *controller) checkCommands() {
@@ -282,7 +385,7 @@ func (c *controller) checkCommands() {
 }
 
 // syncPlanners adds new planner for newly PENDING deployments.
-func (c *controller) syncPlanners(ctx context.Context) error {
+func (c *controller) syncPlanners(ctx, plannerCtx context.Context) error {
 	// Remove stale planners from the recently completed list.
 	for id, t := range c.donePlanners {
 		if time.Since(t) >= plannerStaleDuration {
@@ -325,7 +428,7 @@ func (c *controller) syncPlanners(ctx context.Context) error {
 		zap.Int("count", len(c.planners)),
 	)
 
-	pendingByApp := make(map[string]*model.Deployment, len(pendings))
+	pendingsByApp := make(map[string][]*model.Deployment, len(pendings))
 	for _, d := range pendings {
 		appID := d.ApplicationId
 		// Ignore already processed one.
@@ -340,15 +443,37 @@ func (c *controller) syncPlanners(ctx context.Context) error {
 		if _, ok := c.schedulers[appID]; ok {
 			continue
 		}
-		// Choose the oldest PENDING deployment of the application to plan.
-		if pre, ok := pendingByApp[appID]; ok && !d.TriggerBefore(pre) {
+		pendingsByApp[appID] = append(pendingsByApp[appID], d)
+	}
+
+	pendingByApp := make(map[string]*model.Deployment, len(pendingsByApp))
+	for appID, ds := range pendingsByApp {
+		sort.Slice(ds, func(i, j int) bool {
+			return ds[i].TriggerBefore(ds[j])
+		})
+		// Choose the oldest PENDING deployment of the application to plan
+		// unless the application wants to deploy only the latest one.
+		if c.supersedePolicies[appID] == "" {
+			pendingByApp[appID] = ds[0]
 			continue
 		}
-		pendingByApp[appID] = d
+		latest := ds[len(ds)-1]
+		for _, d := range ds[:len(ds)-1] {
+			if err := c.cancelSupersededDeployment(ctx, d, latest.Id); err != nil {
+				c.logger.Error("failed to cancel superseded deployment",
+					zap.String("deployment-id", d.Id),
+					zap.String("app-id", appID),
+					zap.Error(err),
+				)
+				continue
+			}
+			c.donePlanners[d.Id] = time.Now()
+		}
+		pendingByApp[appID] = latest
 	}
 
 	for appID, d := range pendingByApp {
-		planner, err := c.startNewPlanner(ctx, d)
+		planner, err := c.startNewPlanner(ctx, plannerCtx, d)
 		if err != nil {
 			c.logger.Error("failed to start a new planner",
 				zap.String("deployment-id", d.Id),
@@ -372,7 +497,7 @@ func (c *controller) syncPlanners(ctx context.Context) error {
 	return nil
 }
 
-func (c *controller) startNewPlanner(ctx context.Context, d *model.Deployment) (*planner, error) {
+func (c *controller) startNewPlanner(ctx, plannerCtx context.Context, d *model.Deployment) (*planner, error) {
 	logger := c.logger.With(
 		zap.String("deployment-id", d.Id),
 		zap.String("app-id", d.ApplicationId),
@@ -436,7 +561,7 @@ func (c *controller) startNewPlanner(ctx context.Context, d *model.Deployment) (
 	go func() {
 		defer c.wg.Done()
 		defer cleanup()
-		if err := planner.Run(ctx); err != nil {
+		if err := planner.Run(plannerCtx); err != nil {
 			logger.Error("failed to run planner", zap.Error(err))
 		}
 	}()
@@ -446,7 +571,7 @@ func (c *controller) startNewPlanner(ctx context.Context, d *model.Deployment) (
 
 // syncSchedulers adds new scheduler for newly PLANNED/RUNNING deployments
 // as well as removes the schedulers for the completed deployments.
-func (c *controller) syncSchedulers(ctx context.Context) error {
+func (c *controller) syncSchedulers(ctx, schedulerCtx context.Context) error {
 	// Update the most recent successful commit hashes.
 	for id, s := range c.schedulers {
 		if !s.IsDone() {
@@ -455,6 +580,10 @@ func (c *controller) syncSchedulers(ctx context.Context) error {
 		if s.DoneDeploymentStatus() != model.DeploymentStatus_DEPLOYMENT_SUCCESS {
 			continue
 		}
+		// A dry-run deployment did not change the running state.
+		if s.IsDryRun() {
+			continue
+		}
 		c.mostRecentlySuccessfulCommits[id] = s.CommitHash()
 	}
 
@@ -489,6 +618,36 @@ func (c *controller) syncSchedulers(ctx context.Context) error {
 		}
 	}
 
+	// Remember the supersede policies for planning the next deployments.
+	// Cancel the running deployments superseded by newer ones if configured.
+	pendings := c.deploymentLister.ListPendings()
+	for appID, s := range c.schedulers {
+		policy, ok := s.SupersedePolicy()
+		if !ok {
+			continue
+		}
+		c.supersedePolicies[appID] = policy
+		if policy != config.SupersedePolicyCancelRunning {
+			continue
+		}
+		for _, d := range pendings {
+			if d.ApplicationId != appID || d.Id == s.ID() || !s.deployment.TriggerBefore(d) {
+				continue
+			}
+			// Ignore the ones already cancelled or planned.
+			if _, ok := c.donePlanners[d.Id]; ok {
+				continue
+			}
+			c.logger.Info("cancel the running deployment superseded by a newer one",
+				zap.String("deployment-id", s.ID()),
+				zap.String("app-id", appID),
+				zap.String("newer-deployment-id", d.Id),
+			)
+			s.Supersede(d.Id)
+			break
+		}
+	}
+
 	// Add missing schedulers.
 	planneds := c.deploymentLister.ListPlanneds()
 	runnings := c.deploymentLister.ListRunnings()
@@ -517,7 +676,7 @@ func (c *controller) syncSchedulers(ctx context.Context) error {
 			}
 			continue
 		}
-		s, err := c.startNewScheduler(ctx, d)
+		s, err := c.startNewScheduler(ctx, schedulerCtx, d)
 		if err != nil {
 			continue
 		}
@@ -536,7 +695,7 @@ func (c *controller) syncSchedulers(ctx context.Context) error {
 // for a specific PLANNED deployment.
 // This adds the newly created one to the scheduler list
 // for tracking its lifetime periodically later.
-func (c *controller) startNewScheduler(ctx context.Context, d *model.Deployment) (*scheduler, error) {
+func (c *controller) startNewScheduler(ctx, schedulerCtx context.Context, d *model.Deployment) (*scheduler, error) {
 	logger := c.logger.With(
 		zap.String("deployment-id", d.Id),
 		zap.String("app-id", d.ApplicationId),
@@ -561,6 +720,7 @@ func (c *controller) startNewScheduler(ctx context.Context, d *model.Deployment)
 		d,
 		env.Name,
 		workingDir,
+		c.instanceID,
 		c.apiClient,
 		c.gitClient,
 		c.commandLister,
@@ -591,7 +751,7 @@ func (c *controller) startNewScheduler(ctx context.Context, d *model.Deployment)
 	go func() {
 		defer c.wg.Done()
 		defer cleanup()
-		if err := scheduler.Run(ctx); err != nil {
+		if err := scheduler.Run(schedulerCtx); err != nil {
 			logger.Error("failed to run scheduler", zap.Error(err))
 		}
 	}()
@@ -631,6 +791,48 @@ func (l appLiveResourceLister) ListKubernetesResources() ([]provider.Manifest, b
 	return l.lister.ListKubernetesAppLiveResources(l.cloudProvider, l.appID)
 }
 
+// cancelSupersededDeployment cancels the given PENDING deployment
+// because the newer one of the same application was triggered.
+func (c *controller) cancelSupersededDeployment(ctx context.Context, d *model.Deployment, newerID string) error {
+	var (
+		err       error
+		commander = fmt.Sprintf("the newer deployment %s", newerID)
+		req       = &pipedservice.ReportDeploymentCompletedRequest{
+			DeploymentId: d.Id,
+			Status:       model.DeploymentStatus_DEPLOYMENT_CANCELLED,
+			StatusReason: fmt.Sprintf("Superseded by %s", commander),
+			CompletedAt:  time.Now().Unix(),
+		}
+		retry = pipedservice.NewRetry(10)
+	)
+
+	for retry.WaitNext(ctx) {
+		if _, err = c.apiClient.ReportDeploymentCompleted(ctx, req); err == nil {
+			break
+		}
+		err = fmt.Errorf("failed to report deployment status to control-plane: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	// We only need the environment name
+	// so the returned error can be ignorable.
+	var envName string
+	if env, err := c.environmentLister.Get(ctx, d.EnvId); err == nil {
+		envName = env.Name
+	}
+	c.notifier.Notify(model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_CANCELLED,
+		Metadata: &model.NotificationEventDeploymentCancelled{
+			Deployment: d,
+			EnvName:    envName,
+			Commander:  commander,
+		},
+	})
+	return nil
+}
+
 func reportApplicationDeployingStatus(ctx context.Context, c apiClient, appID string, deploying bool) error {
 	var (
 		err   error
@@ -0,0 +1,61 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/featureflag"
+)
+
+// rollbackFeatureFlags restores the feature flags changed by the FEATURE_FLAG stages
+// to their states before the deployment, in the reverse order of the changes.
+// The failures are only logged since the rollback of the application has been done.
+func (s *scheduler) rollbackFeatureFlags(ctx context.Context) {
+	value, ok := s.metadataStore.Get(featureflag.ChangesMetadataKey)
+	if !ok {
+		return
+	}
+	changes, err := featureflag.DecodeChanges(value)
+	if err != nil {
+		s.logger.Error("failed to decode feature flag changes", zap.Error(err))
+		return
+	}
+
+	for i := len(changes) - 1; i >= 0; i-- {
+		c := changes[i]
+		logger := s.logger.With(
+			zap.String("provider", c.Provider),
+			zap.String("flag", c.Flag.String()),
+		)
+		cfg, ok := s.pipedConfig.GetFeatureFlagProvider(c.Provider)
+		if !ok {
+			logger.Error("feature flag provider was not found")
+			continue
+		}
+		provider, err := featureflag.NewProvider(cfg, s.logger)
+		if err != nil {
+			logger.Error("failed to initialize feature flag provider", zap.Error(err))
+			continue
+		}
+		if err := provider.Restore(ctx, c.Flag, c.Snapshot); err != nil {
+			logger.Error("failed to restore feature flag", zap.Error(err))
+			continue
+		}
+		logger.Info("restored feature flag")
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"io/ioutil"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploymenthook"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// The post-sync hooks are run after the deployment was reported as completed,
+// so they are limited in total to not keep the scheduler running for long.
+const postSyncHooksTimeout = 15 * time.Minute
+
+// deploymentHookEnv returns the variables passed to the deployment hooks:
+// the ones of the deployment and the secrets configured in the piped configuration.
+func deploymentHookEnv(d *model.Deployment, envName string, cfg *config.PipedSpec) (map[string]string, error) {
+	env, err := cfg.LoadDeploymentHookSecrets()
+	if err != nil {
+		return nil, err
+	}
+	env["PIPECD_DEPLOYMENT_ID"] = d.Id
+	env["PIPECD_APPLICATION_ID"] = d.ApplicationId
+	env["PIPECD_APPLICATION_NAME"] = d.ApplicationName
+	env["PIPECD_ENV_NAME"] = envName
+	env["PIPECD_COMMIT_HASH"] = d.Trigger.Commit.Hash
+	return env, nil
+}
+
+// runPreSyncHooks executes the pre-sync hooks of the deployment configuration at the target commit.
+// Since no stage exists before planning, their outputs are stored into the deployment metadata
+// so that they can also be referenced by the stages.
+func (p *planner) runPreSyncHooks(ctx context.Context, dsp deploysource.Provider) error {
+	ds, err := dsp.GetReadOnly(ctx, ioutil.Discard)
+	if err != nil {
+		return err
+	}
+	hooks := ds.GenericDeploymentConfig.Hooks.PreSync
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	env, err := deploymentHookEnv(p.deployment, p.envName, p.pipedConfig)
+	if err != nil {
+		return err
+	}
+	results, runErr := deploymenthook.NewRunner(p.logger).Run(ctx, hooks, ds.AppDir, env)
+
+	metadata := make(map[string]string, len(p.deployment.Metadata)+2*len(results))
+	for k, v := range p.deployment.Metadata {
+		metadata[k] = v
+	}
+	for _, r := range results {
+		for k, v := range r.Metadata(deploymenthook.PhasePreSync) {
+			metadata[k] = v
+		}
+	}
+	if _, err := p.apiClient.SaveDeploymentMetadata(ctx, &pipedservice.SaveDeploymentMetadataRequest{
+		DeploymentId: p.deployment.Id,
+		Metadata:     metadata,
+	}); err != nil {
+		p.logger.Error("failed to save the outputs of pre-sync hooks", zap.Error(err))
+	}
+	return runErr
+}
+
+// runPostSyncHooks executes the post-sync hooks matching the given status of the deployment.
+// Their outputs are stored into the metadata of the last handled stage.
+// The failures are only logged since the deployment has been already finished.
+func (s *scheduler) runPostSyncHooks(ctx context.Context, status model.DeploymentStatus, lastStage *model.PipelineStage) {
+	hooks := s.genericDeploymentConfig.Hooks.PostSync.OnFailure
+	if status == model.DeploymentStatus_DEPLOYMENT_SUCCESS {
+		hooks = s.genericDeploymentConfig.Hooks.PostSync.OnSuccess
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, postSyncHooksTimeout)
+	defer cancel()
+
+	ds, err := s.targetDSP.GetReadOnly(ctx, ioutil.Discard)
+	if err != nil {
+		s.logger.Error("failed to prepare target deploy source data for post-sync hooks", zap.Error(err))
+		return
+	}
+
+	env, err := deploymentHookEnv(s.deployment, s.envName, s.pipedConfig)
+	if err != nil {
+		s.logger.Error("failed to prepare the variables for post-sync hooks", zap.Error(err))
+		return
+	}
+	env["PIPECD_DEPLOYMENT_STATUS"] = status.String()
+	results, err := deploymenthook.NewRunner(s.logger).Run(ctx, hooks, ds.AppDir, env)
+	if err != nil {
+		s.logger.Error("failed to run post-sync hooks", zap.Error(err))
+	}
+
+	metadata := make(map[string]string)
+	for _, r := range results {
+		for k, v := range r.Metadata(deploymenthook.PhasePostSync) {
+			metadata[k] = v
+		}
+	}
+	if lastStage == nil {
+		for k, v := range metadata {
+			if err := s.metadataStore.Set(ctx, k, v); err != nil {
+				s.logger.Error("failed to save the outputs of post-sync hooks", zap.Error(err))
+				return
+			}
+		}
+		return
+	}
+
+	if current, ok := s.metadataStore.GetStageMetadata(lastStage.Id); ok {
+		for k, v := range current {
+			if _, ok := metadata[k]; !ok {
+				metadata[k] = v
+			}
+		}
+	}
+	if err := s.metadataStore.SetStageMetadata(ctx, lastStage.Id, metadata); err != nil {
+		s.logger.Error("failed to save the outputs of post-sync hooks",
+			zap.String("stage-id", lastStage.Id),
+			zap.Error(err),
+		)
+	}
+}
@@ -27,6 +27,10 @@ type metadataStore struct {
 	deployment    *model.Deployment
 	metadata      sync.Map // map[key-string]string
 	stageMetadata sync.Map // map[stage-id-string]map[string]string
+
+	// Guards the stage results because they are merged on every update.
+	stageResultsMu sync.Mutex
+	stageResults   map[string]map[string]string
 }
 
 func NewMetadataStore(apiClient apiClient, d *model.Deployment) *metadataStore {
@@ -35,6 +39,7 @@ func NewMetadataStore(apiClient apiClient, d *model.Deployment) *metadataStore {
 		deployment:    d,
 		metadata:      sync.Map{},
 		stageMetadata: sync.Map{},
+		stageResults:  make(map[string]map[string]string),
 	}
 	// Store shared metadata of deployment.
 	for k, v := range d.Metadata {
@@ -43,6 +48,9 @@ func NewMetadataStore(apiClient apiClient, d *model.Deployment) *metadataStore {
 	// Store metadata of all stages.
 	for _, stage := range d.Stages {
 		s.stageMetadata.Store(stage.Id, stage.Metadata)
+		if len(stage.Results) > 0 {
+			s.stageResults[stage.Id] = stage.Results
+		}
 	}
 	return s
 }
@@ -91,3 +99,34 @@ func (s *metadataStore) GetStageMetadata(stageID string) (map[string]string, boo
 	}
 	return nil, false
 }
+
+// SetStageResults adds the given results into the published results of the given stage
+// and persists them to the control-plane. Existing results of the same keys are overwritten.
+func (s *metadataStore) SetStageResults(ctx context.Context, stageID string, results map[string]string) error {
+	s.stageResultsMu.Lock()
+	merged := make(map[string]string, len(s.stageResults[stageID])+len(results))
+	for k, v := range s.stageResults[stageID] {
+		merged[k] = v
+	}
+	for k, v := range results {
+		merged[k] = v
+	}
+	s.stageResults[stageID] = merged
+	s.stageResultsMu.Unlock()
+
+	_, err := s.apiClient.SaveStageResults(ctx, &pipedservice.SaveStageResultsRequest{
+		DeploymentId: s.deployment.Id,
+		StageId:      stageID,
+		Results:      merged,
+	})
+	return err
+}
+
+// GetStageResults returns all published results of the given stage.
+func (s *metadataStore) GetStageResults(stageID string) (map[string]string, bool) {
+	s.stageResultsMu.Lock()
+	defer s.stageResultsMu.Unlock()
+
+	results, ok := s.stageResults[stageID]
+	return results, ok
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeStageResultsAPIClient struct {
+	apiClient
+	requests []*pipedservice.SaveStageResultsRequest
+}
+
+func (c *fakeStageResultsAPIClient) SaveStageResults(_ context.Context, req *pipedservice.SaveStageResultsRequest, _ ...grpc.CallOption) (*pipedservice.SaveStageResultsResponse, error) {
+	c.requests = append(c.requests, req)
+	return &pipedservice.SaveStageResultsResponse{}, nil
+}
+
+func TestMetadataStoreSetStageResults(t *testing.T) {
+	client := &fakeStageResultsAPIClient{}
+	d := &model.Deployment{
+		Id: "deployment-1",
+		Stages: []*model.PipelineStage{
+			{
+				Id: "stage-1",
+				Results: map[string]string{
+					"Revision": "v1",
+				},
+			},
+			{
+				Id: "stage-2",
+			},
+		},
+	}
+	s := NewMetadataStore(client, d)
+
+	results, ok := s.GetStageResults("stage-1")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"Revision": "v1"}, results)
+
+	_, ok = s.GetStageResults("stage-2")
+	assert.False(t, ok)
+
+	err := s.SetStageResults(context.Background(), "stage-1", map[string]string{
+		"Revision": "v2",
+		"Traffic":  "v2: 100%",
+	})
+	require.NoError(t, err)
+
+	expected := map[string]string{
+		"Revision": "v2",
+		"Traffic":  "v2: 100%",
+	}
+	results, ok = s.GetStageResults("stage-1")
+	require.True(t, ok)
+	assert.Equal(t, expected, results)
+
+	require.Len(t, client.requests, 1)
+	assert.Equal(t, "deployment-1", client.requests[0].DeploymentId)
+	assert.Equal(t, "stage-1", client.requests[0].StageId)
+	assert.Equal(t, expected, client.requests[0].Results)
+}
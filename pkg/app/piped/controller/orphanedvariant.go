@@ -0,0 +1,140 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// checkOrphanedVariants finds the CANARY and BASELINE variant resources
+// of the Kubernetes applications those have no deployment in progress.
+// They were left by the deployments interrupted before cleaning them,
+// so they are removed when cleanOrphanedVariants was enabled.
+func (c *controller) checkOrphanedVariants(ctx context.Context) {
+	// The applications having a not completed deployment are skipped
+	// since their variants are going to be cleaned by that deployment.
+	busyApps := make(map[string]struct{})
+	for _, list := range [][]*model.Deployment{
+		c.deploymentLister.ListPendings(),
+		c.deploymentLister.ListPlanneds(),
+		c.deploymentLister.ListRunnings(),
+	} {
+		for _, d := range list {
+			busyApps[d.ApplicationId] = struct{}{}
+		}
+	}
+	for appID := range c.planners {
+		busyApps[appID] = struct{}{}
+	}
+	for appID := range c.schedulers {
+		busyApps[appID] = struct{}{}
+	}
+
+	type orphan struct {
+		app       *model.Application
+		manifests []provider.Manifest
+	}
+	var orphans []orphan
+	for _, app := range c.applicationLister.List() {
+		if app.Kind != model.ApplicationKind_KUBERNETES {
+			continue
+		}
+		if _, ok := busyApps[app.Id]; ok {
+			continue
+		}
+		manifests, ok := c.liveResourceLister.ListKubernetesAppLiveResources(app.CloudProvider, app.Id)
+		if !ok {
+			continue
+		}
+		if variants := findVariantManifests(manifests, c.pipedConfig.PipedID); len(variants) > 0 {
+			orphans = append(orphans, orphan{app: app, manifests: variants})
+		}
+	}
+	if len(orphans) == 0 {
+		return
+	}
+
+	if !c.pipedConfig.CleanOrphanedVariants {
+		for _, o := range orphans {
+			keys := make([]string, 0, len(o.manifests))
+			for _, m := range o.manifests {
+				keys = append(keys, m.Key.ReadableString())
+			}
+			c.logger.Warn("found orphaned variant resources, enable cleanOrphanedVariants to remove them automatically",
+				zap.String("app-id", o.app.Id),
+				zap.Strings("resources", keys),
+			)
+		}
+		return
+	}
+
+	// Removing the resources may take a while
+	// so it is done without blocking the planners and schedulers.
+	pipedConfig := c.pipedConfig
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for _, o := range orphans {
+			c.removeOrphanedVariants(ctx, pipedConfig, o.app, o.manifests)
+		}
+	}()
+}
+
+func (c *controller) removeOrphanedVariants(ctx context.Context, pipedConfig *config.PipedSpec, app *model.Application, manifests []provider.Manifest) {
+	logger := c.logger.With(zap.String("app-id", app.Id))
+
+	cp, ok := pipedConfig.FindCloudProvider(app.CloudProvider, model.CloudProviderKubernetes)
+	if !ok {
+		logger.Error("unable to find the cloud provider of orphaned variant resources", zap.String("cloud-provider", app.CloudProvider))
+		return
+	}
+
+	p := provider.NewProvider(app.Name, "", "", "", config.KubernetesDeploymentInput{}, cp.KubernetesConfig, logger)
+	for _, m := range manifests {
+		err := p.Delete(ctx, m.Key)
+		if err != nil && !errors.Is(err, provider.ErrNotFound) {
+			logger.Error("failed to remove orphaned variant resource", zap.String("resource", m.Key.ReadableString()), zap.Error(err))
+			continue
+		}
+		logger.Info("removed orphaned variant resource", zap.String("resource", m.Key.ReadableString()))
+	}
+}
+
+// findVariantManifests returns the manifests of CANARY and BASELINE variants
+// those were applied by the given piped.
+func findVariantManifests(manifests []provider.Manifest, pipedID string) []provider.Manifest {
+	var out []provider.Manifest
+	for _, m := range manifests {
+		annotations := m.GetAnnotations()
+		if annotations[provider.LabelManagedBy] != provider.ManagedByPiped {
+			continue
+		}
+		if annotations[provider.LabelPiped] != pipedID {
+			continue
+		}
+		switch annotations[provider.LabelVariant] {
+		case "canary", "baseline":
+			out = append(out, m)
+		}
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+)
+
+func TestFindVariantManifests(t *testing.T) {
+	makeManifest := func(name string, annotations map[string]string) provider.Manifest {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("apps/v1")
+		u.SetKind("Deployment")
+		u.SetNamespace("default")
+		u.SetName(name)
+		u.SetAnnotations(annotations)
+		return provider.MakeManifest(provider.MakeResourceKey(u), u)
+	}
+	annotations := func(pipedID, variant string) map[string]string {
+		return map[string]string{
+			provider.LabelManagedBy: provider.ManagedByPiped,
+			provider.LabelPiped:     pipedID,
+			provider.LabelVariant:   variant,
+		}
+	}
+
+	manifests := []provider.Manifest{
+		makeManifest("app", annotations("piped-1", "primary")),
+		makeManifest("app-canary", annotations("piped-1", "canary")),
+		makeManifest("app-baseline", annotations("piped-1", "baseline")),
+		makeManifest("app-canary-other", annotations("piped-2", "canary")),
+		makeManifest("app-unmanaged", map[string]string{provider.LabelVariant: "canary"}),
+	}
+
+	got := findVariantManifests(manifests, "piped-1")
+	names := make([]string, 0, len(got))
+	for _, m := range got {
+		names = append(names, m.Key.Name)
+	}
+	assert.Equal(t, []string{"app-canary", "app-baseline"}, names)
+	assert.Empty(t, findVariantManifests(manifests, "piped-3"))
+}
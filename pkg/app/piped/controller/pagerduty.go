@@ -0,0 +1,67 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/pagerduty"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// sendPagerDutyChangeEvent sends a change event about the deployment to the PagerDuty service
+// linked to the application when it is configured.
+// The failures are only logged since they must not affect the deployment.
+func (s *scheduler) sendPagerDutyChangeEvent(ctx context.Context, summary string, status model.DeploymentStatus) {
+	pd := s.genericDeploymentConfig.PagerDuty
+	if pd == nil || !pd.ChangeEvents || s.deployment.IsDryRun() {
+		return
+	}
+	logger := s.logger.With(zap.String("pagerduty-service", pd.ServiceID))
+	if s.pipedConfig.PagerDuty == nil {
+		logger.Warn("pagerDuty is not configured in the piped configuration")
+		return
+	}
+	client, err := pagerduty.GetClient(s.pipedConfig.PagerDuty)
+	if err != nil {
+		logger.Error("failed to create pagerduty client", zap.Error(err))
+		return
+	}
+
+	d := s.deployment
+	event := pagerduty.ChangeEvent{
+		Summary:   summary,
+		Source:    fmt.Sprintf("pipecd/%s", d.PipedId),
+		Timestamp: time.Now(),
+		CustomDetails: map[string]string{
+			"deployment_id":  d.Id,
+			"application":    d.ApplicationName,
+			"environment":    s.envName,
+			"commit_hash":    d.Trigger.Commit.Hash,
+			"commit_message": d.Trigger.Commit.Message,
+			"status":         status.String(),
+		},
+		Link:     fmt.Sprintf("%s/deployments/%s", strings.TrimRight(s.pipedConfig.WebAddress, "/"), d.Id),
+		LinkText: "View the deployment in PipeCD",
+	}
+	if err := client.SendChangeEvent(ctx, pd.ServiceID, event); err != nil {
+		logger.Error("failed to send pagerduty change event", zap.Error(err))
+	}
+}
@@ -17,6 +17,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"path/filepath"
 	"time"
 
@@ -25,8 +26,10 @@ import (
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/analysis"
 	pln "github.com/pipe-cd/pipe/pkg/app/piped/planner"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner/registry"
+	"github.com/pipe-cd/pipe/pkg/app/piped/signatureverifier"
 	"github.com/pipe-cd/pipe/pkg/cache"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
@@ -164,6 +167,7 @@ func (p *planner) Run(ctx context.Context) error {
 	in := pln.Input{
 		ApplicationID:                  p.deployment.ApplicationId,
 		ApplicationName:                p.deployment.ApplicationName,
+		CloudProvider:                  p.deployment.CloudProvider,
 		GitPath:                        *p.deployment.GitPath,
 		Trigger:                        *p.deployment.Trigger,
 		MostRecentSuccessfulCommitHash: p.lastSuccessfulCommitHash,
@@ -173,6 +177,12 @@ func (p *planner) Run(ctx context.Context) error {
 		Logger:                         p.logger,
 	}
 
+	// The pinned commit is deployed by syncing all of its resources
+	// because the changes from the running commit were not the ones its pipeline was designed for.
+	if in.Trigger.IsPinned() && in.Trigger.SyncStrategy == model.SyncStrategy_AUTO {
+		in.Trigger.SyncStrategy = model.SyncStrategy_QUICK_SYNC
+	}
+
 	in.TargetDSP = deploysource.NewProvider(
 		filepath.Join(p.workingDir, "target-deploysource"),
 		repoCfg,
@@ -195,6 +205,20 @@ func (p *planner) Run(ctx context.Context) error {
 		)
 	}
 
+	if err := p.verifyCommitSignature(ctx, in.TargetDSP); err != nil {
+		p.doneDeploymentStatus = model.DeploymentStatus_DEPLOYMENT_FAILURE
+		return p.reportDeploymentFailed(ctx, fmt.Sprintf("Unable to verify the signature of the triggered commit (%v)", err))
+	}
+
+	// The hooks are not executed for a dry-run deployment
+	// since they may change something outside of PipeCD.
+	if !p.deployment.IsDryRun() {
+		if err := p.runPreSyncHooks(ctx, in.TargetDSP); err != nil {
+			p.doneDeploymentStatus = model.DeploymentStatus_DEPLOYMENT_FAILURE
+			return p.reportDeploymentFailed(ctx, fmt.Sprintf("Failed while running the pre-sync hooks (%v)", err))
+		}
+	}
+
 	out, err := planner.Plan(ctx, in)
 
 	// If the deployment was already cancelled, we ignore the plan result.
@@ -214,10 +238,86 @@ func (p *planner) Run(ctx context.Context) error {
 		return p.reportDeploymentFailed(ctx, fmt.Sprintf("Unable to plan the deployment (%v)", err))
 	}
 
+	// The version read from the file configured by the application
+	// takes precedence over the one determined by the planner.
+	if version, ok := p.readVersionFile(ctx, in.TargetDSP); ok {
+		out.Version = version
+	}
+
+	// A dry-run deployment must not change anything in the target environment
+	// so the planned pipeline is replaced by the one only verifying the changes.
+	if p.deployment.IsDryRun() {
+		out.Stages = pln.BuildDryRunPipeline(p.deployment.Kind, p.nowFunc())
+		out.Summary = fmt.Sprintf("Dry run without applying any changes: %s", out.Summary)
+	}
+
+	if err := p.validateAnalysisQueries(ctx, in.TargetDSP, out.Stages); err != nil {
+		p.doneDeploymentStatus = model.DeploymentStatus_DEPLOYMENT_FAILURE
+		return p.reportDeploymentFailed(ctx, fmt.Sprintf("Invalid analysis configuration (%v)", err))
+	}
+
 	p.doneDeploymentStatus = model.DeploymentStatus_DEPLOYMENT_PLANNED
 	return p.reportDeploymentPlanned(ctx, p.lastSuccessfulCommitHash, out)
 }
 
+// verifyCommitSignature checks that the triggered commit was signed by a trusted key
+// when the application requires that.
+func (p *planner) verifyCommitSignature(ctx context.Context, dsp deploysource.Provider) error {
+	ds, err := dsp.GetReadOnly(ctx, ioutil.Discard)
+	if err != nil {
+		return err
+	}
+	if !ds.GenericDeploymentConfig.SignatureVerification.Commit {
+		return nil
+	}
+	v := signatureverifier.NewVerifier(p.pipedConfig.SignatureVerification, p.logger)
+	return v.VerifyCommit(ctx, ds.RepoDir, p.deployment.Trigger.Commit.Hash)
+}
+
+// validateAnalysisQueries checks the queries of all ANALYSIS stages in the planned pipeline
+// to fail the deployment before running any stage when one of them is broken.
+func (p *planner) validateAnalysisQueries(ctx context.Context, dsp deploysource.Provider, stages []*model.PipelineStage) error {
+	var ds *deploysource.DeploySource
+	for _, s := range stages {
+		if s.Predefined || s.Name != model.StageAnalysis.String() {
+			continue
+		}
+		if ds == nil {
+			var err error
+			if ds, err = dsp.GetReadOnly(ctx, ioutil.Discard); err != nil {
+				return err
+			}
+		}
+		cfg, ok := ds.GenericDeploymentConfig.GetStage(s.Index)
+		if !ok || cfg.AnalysisStageOptions == nil {
+			continue
+		}
+		if err := analysis.ValidateQueries(ctx, cfg.AnalysisStageOptions, p.deployment.ApplicationName, ds.DeploymentConfig, ds.RepoDir, p.pipedConfig, p.logger); err != nil {
+			return fmt.Errorf("stage %s: %w", s.Id, err)
+		}
+	}
+	return nil
+}
+
+// readVersionFile reads the application version from the file
+// when the application configured the version extraction from a file.
+func (p *planner) readVersionFile(ctx context.Context, dsp deploysource.Provider) (string, bool) {
+	ds, err := dsp.GetReadOnly(ctx, ioutil.Discard)
+	if err != nil {
+		return "", false
+	}
+	e := ds.GenericDeploymentConfig.VersionExtraction
+	if e == nil || e.Source != config.VersionSourceFile {
+		return "", false
+	}
+	version, err := pln.ReadVersionFile(ds.AppDir, e)
+	if err != nil {
+		p.logger.Warn("unable to read the version file", zap.Error(err))
+		return "", false
+	}
+	return version, true
+}
+
 func (p *planner) reportDeploymentPlanned(ctx context.Context, runningCommitHash string, out pln.Output) error {
 	var (
 		err   error
@@ -228,15 +328,22 @@ func (p *planner) reportDeploymentPlanned(ctx context.Context, runningCommitHash
 			StatusReason:      "The deployment has been planned",
 			RunningCommitHash: runningCommitHash,
 			Version:           out.Version,
+			Versions:          out.Versions,
 			Stages:            out.Stages,
+			ManifestDiff:      out.ManifestDiff,
 		}
 	)
 
 	defer func() {
+		// The deployment model is updated with the planned versions
+		// so that the notification can show it.
+		d := p.deployment.Clone()
+		d.Version = out.Version
+		d.Versions = out.Versions
 		p.notifier.Notify(model.NotificationEvent{
 			Type: model.NotificationEventType_EVENT_DEPLOYMENT_PLANNED,
 			Metadata: &model.NotificationEventDeploymentPlanned{
-				Deployment: p.deployment,
+				Deployment: d,
 				EnvName:    p.envName,
 				Summary:    out.Summary,
 			},
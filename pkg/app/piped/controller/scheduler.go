@@ -16,13 +16,19 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
@@ -35,12 +41,31 @@ import (
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
+var (
+	// The interval to retry acquiring the deployment locks held by other deployments.
+	lockRetryInterval = 30 * time.Second
+	// The interval to extend the expiration of the acquired deployment locks.
+	// This must be shorter than the lock TTL of the control-plane.
+	lockKeepInterval = time.Minute
+	// The interval to retry acquiring the deployment lease held by another piped instance.
+	leaseRetryInterval = 10 * time.Second
+	// The maximum time to wait for acquiring the deployment lease.
+	// This should be longer than the lease TTL of the control-plane
+	// to be able to take over the lease of a piped instance that has gone away.
+	leaseWaitTimeout = 15 * time.Minute
+	// The maximum time to wait for releasing the deployment lease.
+	leaseReleaseTimeout = 10 * time.Second
+
+	errHandedOver = errors.New("the deployment was handed over")
+)
+
 // scheduler is a dedicated object for a specific deployment of a single application.
 type scheduler struct {
 	// Readonly deployment model.
 	deployment         *model.Deployment
 	envName            string
 	workingDir         string
+	instanceID         string
 	executorRegistry   registry.Registry
 	apiClient          apiClient
 	gitClient          gitClient
@@ -64,13 +89,21 @@ type scheduler struct {
 	// We may need a mutex for this field in the future
 	// when the stages can be executed concurrently.
 	stageStatuses           map[string]model.StageStatus
+	checkpoint              checkpoint
 	genericDeploymentConfig config.GenericDeploymentSpec
+	// The supersede policy loaded from the deployment configuration.
+	// This is read by the controller so an atomic value is used.
+	supersedePolicy atomic.Value
 
 	done                 atomic.Bool
 	doneTimestamp        time.Time
 	doneDeploymentStatus model.DeploymentStatus
 	cancelled            bool
 	cancelledCh          chan *model.ReportableCommand
+	// Channel closed when the scheduler should stop before starting the next stage
+	// to hand over the deployment to another piped.
+	handoverCh   chan struct{}
+	handoverOnce sync.Once
 
 	nowFunc func() time.Time
 }
@@ -79,6 +112,7 @@ func newScheduler(
 	d *model.Deployment,
 	envName string,
 	workingDir string,
+	instanceID string,
 	apiClient apiClient,
 	gitClient gitClient,
 	commandLister commandLister,
@@ -105,6 +139,7 @@ func newScheduler(
 		deployment:           d,
 		envName:              envName,
 		workingDir:           workingDir,
+		instanceID:           instanceID,
 		executorRegistry:     registry.DefaultRegistry(),
 		apiClient:            apiClient,
 		gitClient:            gitClient,
@@ -118,7 +153,9 @@ func newScheduler(
 		pipedConfig:          pipedConfig,
 		appManifestsCache:    appManifestsCache,
 		doneDeploymentStatus: d.Status,
+		checkpoint:           loadCheckpoint(d),
 		cancelledCh:          make(chan *model.ReportableCommand, 1),
+		handoverCh:           make(chan struct{}),
 		logger:               logger,
 		nowFunc:              time.Now,
 	}
@@ -143,6 +180,11 @@ func (s *scheduler) CommitHash() string {
 	return s.deployment.CommitHash()
 }
 
+// IsDryRun reports whether the deployment of this scheduler is a dry-run one.
+func (s *scheduler) IsDryRun() bool {
+	return s.deployment.IsDryRun()
+}
+
 // IsDone tells whether this scheduler is done it tasks or not.
 // Returning true means this scheduler can be removable.
 func (s *scheduler) IsDone() bool {
@@ -176,6 +218,38 @@ func (s *scheduler) Cancel(cmd model.ReportableCommand) {
 	close(s.cancelledCh)
 }
 
+// SupersedePolicy returns the supersede policy configured for the deployment.
+// False is returned when the deployment configuration has not been loaded yet.
+func (s *scheduler) SupersedePolicy() (config.SupersedePolicy, bool) {
+	v := s.supersedePolicy.Load()
+	if v == nil {
+		return "", false
+	}
+	return v.(config.SupersedePolicy), true
+}
+
+// Supersede cancels the deployment because the given newer one was triggered.
+func (s *scheduler) Supersede(deploymentID string) {
+	s.Cancel(model.ReportableCommand{
+		Command: &model.Command{
+			Commander: fmt.Sprintf("the newer deployment %s", deploymentID),
+		},
+		Report: func(ctx context.Context, status model.CommandStatus, metadata map[string]string, output []byte) error {
+			return nil
+		},
+	})
+}
+
+// Handover makes the scheduler stop before starting the next stage
+// while letting the running one finish.
+// The deployment is left as it is to be resumed by another piped
+// from the first uncompleted stage.
+func (s *scheduler) Handover() {
+	s.handoverOnce.Do(func() {
+		close(s.handoverCh)
+	})
+}
+
 // Run starts running the scheduler.
 // It determines what stage should be executed next by which executor.
 // The returning error does not mean that the pipeline was failed,
@@ -196,13 +270,30 @@ func (s *scheduler) Run(ctx context.Context) error {
 		return nil
 	}
 
-	// Update deployment status to RUNNING if needed.
-	if model.CanUpdateDeploymentStatus(s.deployment.Status, model.DeploymentStatus_DEPLOYMENT_RUNNING) {
-		err := s.reportDeploymentStatusChanged(ctx, model.DeploymentStatus_DEPLOYMENT_RUNNING, "The piped started handling this deployment")
-		if err != nil {
-			return err
+	// Wait until the lease of this deployment is acquired
+	// to not run it together with the previous piped which may be still shutting down.
+	if err := s.waitForLease(ctx); err != nil {
+		if ctx.Err() != nil || errors.Is(err, errHandedOver) {
+			s.logger.Info("stop scheduler while waiting for deployment lease")
+			return nil
 		}
+		deploymentStatus = model.DeploymentStatus_DEPLOYMENT_FAILURE
+		statusReason := fmt.Sprintf("Unable to acquire the lease of the deployment (%v)", err)
+		s.reportDeploymentCompleted(ctx, deploymentStatus, statusReason, "")
+		return err
 	}
+	// Once the lease was taken by another piped instance,
+	// this scheduler stops in the same way as when piped is shutting down
+	// to not run the deployment together with that instance.
+	pipedCtx := ctx
+	ctx, loseLease := context.WithCancel(ctx)
+	defer loseLease()
+	keepLeaseCtx, stopKeepingLease := context.WithCancel(ctx)
+	defer func() {
+		stopKeepingLease()
+		s.releaseLease()
+	}()
+	go s.keepLease(keepLeaseCtx, loseLease)
 
 	var (
 		cancelCommand   *model.ReportableCommand
@@ -264,8 +355,56 @@ func (s *scheduler) Run(ctx context.Context) error {
 		return err
 	}
 	s.genericDeploymentConfig = ds.GenericDeploymentConfig
+	s.supersedePolicy.Store(s.genericDeploymentConfig.SupersedePolicy)
 
-	timer := time.NewTimer(s.genericDeploymentConfig.Timeout.Duration())
+	// Wait until the locks shared with the deployments of other applications are acquired.
+	locks := s.genericDeploymentConfig.Locks
+	if len(locks) > 0 {
+		cmd, err := s.waitForLocks(ctx, locks)
+		if err != nil {
+			s.logger.Info("stop scheduler while waiting for deployment locks")
+			deploymentStatus = s.deployment.Status
+			return nil
+		}
+		if cmd != nil {
+			deploymentStatus = model.DeploymentStatus_DEPLOYMENT_CANCELLED
+			statusReason = fmt.Sprintf("Cancelled by %s while waiting for deployment locks", cmd.Commander)
+			s.reportDeploymentCompleted(ctx, deploymentStatus, statusReason, cmd.Commander)
+			if err := cmd.Report(ctx, model.CommandStatus_COMMAND_SUCCEEDED, nil, nil); err != nil {
+				s.logger.Error("failed to report command status", zap.Error(err))
+			}
+			return nil
+		}
+
+		keepCtx, stopKeeping := context.WithCancel(ctx)
+		defer stopKeeping()
+		go s.keepLocks(keepCtx, locks)
+	}
+
+	// Update deployment status to RUNNING if needed.
+	if model.CanUpdateDeploymentStatus(s.deployment.Status, model.DeploymentStatus_DEPLOYMENT_RUNNING) {
+		err := s.reportDeploymentStatusChanged(ctx, model.DeploymentStatus_DEPLOYMENT_RUNNING, "The piped started handling this deployment")
+		if err != nil {
+			return err
+		}
+		s.sendPagerDutyChangeEvent(ctx,
+			fmt.Sprintf("Deployment of %s to %s started", s.deployment.ApplicationName, s.envName),
+			model.DeploymentStatus_DEPLOYMENT_RUNNING,
+		)
+		s.postCommitStatus(ctx, model.DeploymentStatus_DEPLOYMENT_RUNNING, fmt.Sprintf("Deploying to %s", s.envName))
+	} else if s.deployment.Status == model.DeploymentStatus_DEPLOYMENT_RUNNING {
+		if err := s.reportDeploymentStatusChanged(ctx, model.DeploymentStatus_DEPLOYMENT_RUNNING, "The piped resumed handling this deployment"); err != nil {
+			s.logger.Error("failed to report the resumed deployment", zap.Error(err))
+		}
+	}
+
+	// The timeout is counted from when the pipeline was started at first
+	// even if this deployment is resumed after piped was restarted.
+	if s.checkpoint.startedAt.IsZero() {
+		s.checkpoint.startedAt = s.nowFunc()
+		s.saveCheckpoint(ctx)
+	}
+	timer := time.NewTimer(s.checkpoint.remainingTimeout(s.genericDeploymentConfig.Timeout.Duration(), s.nowFunc()))
 	defer timer.Stop()
 
 	// Iterate all the stages and execute the uncompleted ones.
@@ -291,6 +430,31 @@ func (s *scheduler) Run(ctx context.Context) error {
 			break
 		}
 
+		// Stop here without starting this stage when the deployment is being handed over.
+		select {
+		case <-s.handoverCh:
+			reason := fmt.Sprintf("The deployment was handed over to another piped before executing stage %s", ps.Id)
+			s.logger.Info(reason, zap.String("stage-id", ps.Id))
+			if err := s.reportDeploymentStatusChanged(ctx, model.DeploymentStatus_DEPLOYMENT_RUNNING, reason); err != nil {
+				s.logger.Error("failed to report the handed over deployment", zap.Error(err))
+			}
+			deploymentStatus = s.deployment.Status
+			return nil
+		default:
+		}
+
+		// Give up the stage interrupted too many times since it may be the cause of the restarts of piped.
+		if resumed := s.checkpoint.startStage(ps); resumed && s.checkpoint.resumedCount > maxStageResumes {
+			s.logger.Warn("stage was interrupted too many times", zap.String("stage-id", ps.Id))
+			if err := s.reportStageStatus(ctx, ps.Id, model.StageStatus_STAGE_FAILURE, ps.Requires); err != nil {
+				s.logger.Error("failed to report stage status", zap.Error(err))
+			}
+			deploymentStatus = model.DeploymentStatus_DEPLOYMENT_FAILURE
+			statusReason = fmt.Sprintf("Stage %s was interrupted %d times while being executed", ps.Id, s.checkpoint.resumedCount)
+			break
+		}
+		s.saveCheckpoint(ctx)
+
 		var (
 			result       model.StageStatus
 			sig, handler = executor.NewStopSignal()
@@ -357,6 +521,15 @@ func (s *scheduler) Run(ctx context.Context) error {
 		}
 
 		s.logger.Info("stop scheduler because of temination signal", zap.String("stage-id", ps.Id))
+		// Resuming the stage stopped by piped shutting down is not counted as an interruption.
+		// The checkpoint is left as it is when the lease was lost since another piped is running the deployment now.
+		if pipedCtx.Err() != nil {
+			s.checkpoint.stopStage()
+			saveCtx, cancel := context.WithTimeout(context.Background(), checkpointSaveTimeout)
+			s.saveCheckpoint(saveCtx)
+			cancel()
+		}
+		deploymentStatus = s.deployment.Status
 		return nil
 	}
 
@@ -369,6 +542,13 @@ func (s *scheduler) Run(ctx context.Context) error {
 			if err := s.reportDeploymentStatusChanged(ctx, model.DeploymentStatus_DEPLOYMENT_ROLLING_BACK, statusReason); err != nil {
 				return err
 			}
+			s.notifier.Notify(model.NotificationEvent{
+				Type: model.NotificationEventType_EVENT_DEPLOYMENT_ROLLING_BACK,
+				Metadata: &model.NotificationEventDeploymentRollingBack{
+					Deployment: s.deployment,
+					EnvName:    s.envName,
+				},
+			})
 
 			// Start running rollback stage.
 			var (
@@ -384,6 +564,32 @@ func (s *scheduler) Run(ctx context.Context) error {
 				close(doneCh)
 			}()
 
+			select {
+			case <-ctx.Done():
+				handler.Terminate()
+				<-doneCh
+				return nil
+
+			case <-doneCh:
+				break
+			}
+			s.rollbackFeatureFlags(ctx)
+		} else if stage, ok := s.deployment.FindVariantCleanStage(); ok {
+			// Without rolling back, clean the variants created by the executed stages
+			// to not leave them receiving the traffic.
+			var (
+				sig, handler = executor.NewStopSignal()
+				doneCh       = make(chan struct{})
+			)
+			go func() {
+				vcs := *stage
+				vcs.Requires = []string{lastStage.Id}
+				s.executeStage(sig, vcs, func(in executor.Input) (executor.Executor, bool) {
+					return s.executorRegistry.Executor(model.Stage(vcs.Name), in)
+				})
+				close(doneCh)
+			}()
+
 			select {
 			case <-ctx.Done():
 				handler.Terminate()
@@ -397,10 +603,27 @@ func (s *scheduler) Run(ctx context.Context) error {
 	}
 
 	if model.IsCompletedDeployment(deploymentStatus) {
+		if !s.deployment.IsDryRun() {
+			s.sendPagerDutyChangeEvent(ctx,
+				fmt.Sprintf("Deployment of %s to %s finished with status %s", s.deployment.ApplicationName, s.envName, deploymentStatus.String()),
+				deploymentStatus,
+			)
+		}
 		err := s.reportDeploymentCompleted(ctx, deploymentStatus, statusReason, cancelCommander)
-		if err == nil && deploymentStatus == model.DeploymentStatus_DEPLOYMENT_SUCCESS {
+		if err == nil && deploymentStatus == model.DeploymentStatus_DEPLOYMENT_SUCCESS && !s.deployment.IsDryRun() {
 			s.reportMostRecentlySuccessfulDeployment(ctx)
 		}
+		if len(locks) > 0 {
+			s.releaseLocks(ctx, locks)
+		}
+		if !s.deployment.IsDryRun() {
+			go s.completeChangeTicket(deploymentStatus, statusReason)
+			go s.postCompletedCommitStatus(deploymentStatus, statusReason)
+		}
+		// The hooks are run after reporting to not delay the completion of the deployment.
+		if !s.deployment.IsDryRun() {
+			s.runPostSyncHooks(ctx, deploymentStatus, lastStage)
+		}
 	}
 
 	if cancelCommand != nil {
@@ -428,6 +651,10 @@ func (s *scheduler) executeStage(sig executor.StopSignal, ps model.PipelineStage
 		lp.Complete(time.Minute)
 	}()
 
+	if ps.Status == model.StageStatus_STAGE_RUNNING {
+		lp.Info("Resuming this stage since it was interrupted while being executed")
+	}
+
 	// Update stage status to RUNNING if needed.
 	if model.CanUpdateStageStatus(ps.Status, model.StageStatus_STAGE_RUNNING) {
 		if err := s.reportStageStatus(ctx, ps.Id, model.StageStatus_STAGE_RUNNING, ps.Requires); err != nil {
@@ -484,14 +711,18 @@ func (s *scheduler) executeStage(sig executor.StopSignal, ps model.PipelineStage
 		StageConfig:           stageConfig,
 		Deployment:            s.deployment,
 		Application:           app,
+		EnvName:               s.envName,
 		PipedConfig:           s.pipedConfig,
 		TargetDSP:             s.targetDSP,
 		RunningDSP:            s.runningDSP,
 		CommandLister:         cmdLister,
 		LogPersister:          lp,
 		MetadataStore:         s.metadataStore,
+		AnalysisResultStore:   analysisResultStore{apiClient: s.apiClient},
+		ProvenanceStore:       provenanceStore{apiClient: s.apiClient},
 		AppManifestsCache:     s.appManifestsCache,
 		AppLiveResourceLister: alrLister,
+		Notifier:              s.notifier,
 		Logger:                s.logger,
 	}
 
@@ -554,6 +785,214 @@ func (s *scheduler) reportStageStatus(ctx context.Context, stageID string, statu
 	return err
 }
 
+// waitForLocks blocks until all of the given locks are acquired for the deployment.
+// While waiting, the status reason of the PLANNED deployment shows the deployments holding the locks.
+// The cancel command is returned when the deployment was cancelled while waiting.
+func (s *scheduler) waitForLocks(ctx context.Context, locks []string) (*model.ReportableCommand, error) {
+	ticker := time.NewTicker(lockRetryInterval)
+	defer ticker.Stop()
+
+	var (
+		cancelledCh = s.cancelledCh
+		lastReason  string
+		req         = &pipedservice.AcquireDeploymentLocksRequest{
+			DeploymentId: s.deployment.Id,
+			Locks:        locks,
+		}
+	)
+	for {
+		resp, err := s.apiClient.AcquireDeploymentLocks(ctx, req)
+		switch {
+		case err != nil:
+			s.logger.Error("failed to acquire deployment locks", zap.Error(err))
+		case resp.Acquired:
+			s.logger.Info("acquired deployment locks", zap.Strings("locks", locks))
+			return nil, nil
+		default:
+			reason := waitingForLocksReason(resp.Holders)
+			s.logger.Info(reason)
+			// The reason is shown only while the deployment is not running yet.
+			if reason != lastReason && s.deployment.Status == model.DeploymentStatus_DEPLOYMENT_PLANNED {
+				if err := s.reportDeploymentStatusChanged(ctx, model.DeploymentStatus_DEPLOYMENT_PLANNED, reason); err != nil {
+					s.logger.Error("failed to report the waiting deployment locks", zap.Error(err))
+				}
+				lastReason = reason
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.handoverCh:
+			return nil, errHandedOver
+		case cmd := <-cancelledCh:
+			if cmd != nil {
+				return cmd, nil
+			}
+			// The channel was closed without any command.
+			cancelledCh = nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// keepLocks periodically extends the expiration of the acquired locks
+// until the given context is done.
+func (s *scheduler) keepLocks(ctx context.Context, locks []string) {
+	ticker := time.NewTicker(lockKeepInterval)
+	defer ticker.Stop()
+
+	req := &pipedservice.AcquireDeploymentLocksRequest{
+		DeploymentId: s.deployment.Id,
+		Locks:        locks,
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			resp, err := s.apiClient.AcquireDeploymentLocks(ctx, req)
+			if err != nil {
+				s.logger.Error("failed to extend deployment locks", zap.Error(err))
+				continue
+			}
+			if !resp.Acquired {
+				s.logger.Warn("deployment locks have been acquired by another deployment", zap.Any("holders", resp.Holders))
+			}
+		}
+	}
+}
+
+func (s *scheduler) releaseLocks(ctx context.Context, locks []string) {
+	var (
+		retry = pipedservice.NewRetry(10)
+		req   = &pipedservice.ReleaseDeploymentLocksRequest{
+			DeploymentId: s.deployment.Id,
+			Locks:        locks,
+		}
+	)
+	for retry.WaitNext(ctx) {
+		_, err := s.apiClient.ReleaseDeploymentLocks(ctx, req)
+		if err == nil {
+			return
+		}
+		s.logger.Error("failed to release deployment locks", zap.Error(err))
+	}
+}
+
+// waitForLease blocks until the lease of the deployment is acquired by this piped instance.
+// The lease is considered as acquired when the control-plane does not support it.
+// An error is returned when it could not be acquired in leaseWaitTimeout.
+func (s *scheduler) waitForLease(ctx context.Context) error {
+	ticker := time.NewTicker(leaseRetryInterval)
+	defer ticker.Stop()
+
+	timer := time.NewTimer(leaseWaitTimeout)
+	defer timer.Stop()
+
+	var (
+		req = &pipedservice.AcquireDeploymentLeaseRequest{
+			DeploymentId: s.deployment.Id,
+			InstanceId:   s.instanceID,
+		}
+		lastErr error
+	)
+	for {
+		resp, err := s.apiClient.AcquireDeploymentLease(ctx, req)
+		switch {
+		case status.Code(err) == codes.Unimplemented:
+			s.logger.Warn("deployment lease is not supported by the control-plane, continue without it")
+			return nil
+		case err != nil:
+			s.logger.Error("failed to acquire deployment lease", zap.Error(err))
+			lastErr = err
+		case resp.Acquired:
+			return nil
+		default:
+			s.logger.Info("waiting for the deployment lease held by another piped instance",
+				zap.String("holder", resp.Holder),
+			)
+			lastErr = fmt.Errorf("the lease is held by another piped instance %s", resp.Holder)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.handoverCh:
+			return errHandedOver
+		case <-timer.C:
+			return fmt.Errorf("timed out after %v waiting for the deployment lease: %w", leaseWaitTimeout, lastErr)
+		case <-ticker.C:
+		}
+	}
+}
+
+// keepLease periodically extends the expiration of the acquired lease
+// until the given context is done.
+// The given lost function is called when the lease has been acquired by another piped instance.
+func (s *scheduler) keepLease(ctx context.Context, lost func()) {
+	ticker := time.NewTicker(lockKeepInterval)
+	defer ticker.Stop()
+
+	req := &pipedservice.AcquireDeploymentLeaseRequest{
+		DeploymentId: s.deployment.Id,
+		InstanceId:   s.instanceID,
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			resp, err := s.apiClient.AcquireDeploymentLease(ctx, req)
+			if status.Code(err) == codes.Unimplemented {
+				return
+			}
+			if err != nil {
+				s.logger.Error("failed to extend deployment lease", zap.Error(err))
+				continue
+			}
+			if !resp.Acquired {
+				s.logger.Warn("deployment lease has been acquired by another piped instance, stop executing the deployment", zap.String("holder", resp.Holder))
+				lost()
+				return
+			}
+		}
+	}
+}
+
+// releaseLease releases the lease of the deployment
+// to let another piped instance resume it immediately.
+// This does not use the context of the scheduler since it may be already done while shutting down.
+func (s *scheduler) releaseLease() {
+	ctx, cancel := context.WithTimeout(context.Background(), leaseReleaseTimeout)
+	defer cancel()
+
+	var (
+		retry = pipedservice.NewRetry(3)
+		req   = &pipedservice.ReleaseDeploymentLeaseRequest{
+			DeploymentId: s.deployment.Id,
+			InstanceId:   s.instanceID,
+		}
+	)
+	for retry.WaitNext(ctx) {
+		_, err := s.apiClient.ReleaseDeploymentLease(ctx, req)
+		if err == nil || status.Code(err) == codes.Unimplemented {
+			return
+		}
+		s.logger.Error("failed to release deployment lease", zap.Error(err))
+	}
+}
+
+// waitingForLocksReason builds the status reason of the deployment waiting for the locks.
+func waitingForLocksReason(holders map[string]string) string {
+	items := make([]string, 0, len(holders))
+	for lock, id := range holders {
+		items = append(items, fmt.Sprintf("%s (held by deployment %s)", lock, id))
+	}
+	sort.Strings(items)
+	return fmt.Sprintf("Waiting for deployment locks: %s", strings.Join(items, ", "))
+}
+
 func (s *scheduler) reportDeploymentStatusChanged(ctx context.Context, status model.DeploymentStatus, desc string) error {
 	var (
 		err   error
@@ -590,12 +1029,14 @@ func (s *scheduler) reportDeploymentCompleted(ctx context.Context, status model.
 	)
 
 	defer func() {
+		// Include the published stage results to show them in the notifications.
+		d := s.deploymentWithStageResults()
 		switch status {
 		case model.DeploymentStatus_DEPLOYMENT_SUCCESS:
 			s.notifier.Notify(model.NotificationEvent{
 				Type: model.NotificationEventType_EVENT_DEPLOYMENT_SUCCEEDED,
 				Metadata: &model.NotificationEventDeploymentSucceeded{
-					Deployment: s.deployment,
+					Deployment: d,
 					EnvName:    s.envName,
 				},
 			})
@@ -604,7 +1045,7 @@ func (s *scheduler) reportDeploymentCompleted(ctx context.Context, status model.
 			s.notifier.Notify(model.NotificationEvent{
 				Type: model.NotificationEventType_EVENT_DEPLOYMENT_FAILED,
 				Metadata: &model.NotificationEventDeploymentFailed{
-					Deployment: s.deployment,
+					Deployment: d,
 					EnvName:    s.envName,
 					Reason:     desc,
 				},
@@ -614,7 +1055,7 @@ func (s *scheduler) reportDeploymentCompleted(ctx context.Context, status model.
 			s.notifier.Notify(model.NotificationEvent{
 				Type: model.NotificationEventType_EVENT_DEPLOYMENT_CANCELLED,
 				Metadata: &model.NotificationEventDeploymentCancelled{
-					Deployment: s.deployment,
+					Deployment: d,
 					EnvName:    s.envName,
 					Commander:  cancelCommander,
 				},
@@ -633,6 +1074,18 @@ func (s *scheduler) reportDeploymentCompleted(ctx context.Context, status model.
 	return err
 }
 
+// deploymentWithStageResults returns a copy of the deployment
+// whose stages contain their latest published results.
+func (s *scheduler) deploymentWithStageResults() *model.Deployment {
+	d := s.deployment.Clone()
+	for _, stage := range d.Stages {
+		if results, ok := s.metadataStore.GetStageResults(stage.Id); ok {
+			stage.Results = results
+		}
+	}
+	return d
+}
+
 func (s *scheduler) reportMostRecentlySuccessfulDeployment(ctx context.Context) error {
 	var (
 		err error
@@ -644,6 +1097,7 @@ func (s *scheduler) reportMostRecentlySuccessfulDeployment(ctx context.Context)
 				Trigger:      s.deployment.Trigger,
 				Summary:      s.deployment.Summary,
 				Version:      s.deployment.Version,
+				Versions:     s.deployment.Versions,
 				StartedAt:    s.deployment.CreatedAt,
 				CompletedAt:  s.deployment.CompletedAt,
 			},
@@ -669,3 +1123,25 @@ type stageCommandLister struct {
 func (s stageCommandLister) ListCommands() []model.ReportableCommand {
 	return s.lister.ListStageCommands(s.deploymentID, s.stageID)
 }
+
+type analysisResultStore struct {
+	apiClient apiClient
+}
+
+func (s analysisResultStore) PutAnalysisResult(ctx context.Context, result *model.AnalysisResult) error {
+	_, err := s.apiClient.ReportAnalysisResult(ctx, &pipedservice.ReportAnalysisResultRequest{
+		Result: result,
+	})
+	return err
+}
+
+type provenanceStore struct {
+	apiClient apiClient
+}
+
+func (s provenanceStore) PutProvenance(ctx context.Context, provenance *model.DeploymentProvenance) error {
+	_, err := s.apiClient.ReportDeploymentProvenance(ctx, &pipedservice.ReportDeploymentProvenanceRequest{
+		Provenance: provenance,
+	})
+	return err
+}
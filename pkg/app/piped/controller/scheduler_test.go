@@ -0,0 +1,224 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeLockAPIClient struct {
+	apiClient
+	responses []*pipedservice.AcquireDeploymentLocksResponse
+	reasons   []string
+}
+
+func (c *fakeLockAPIClient) AcquireDeploymentLocks(ctx context.Context, req *pipedservice.AcquireDeploymentLocksRequest, opts ...grpc.CallOption) (*pipedservice.AcquireDeploymentLocksResponse, error) {
+	resp := c.responses[0]
+	if len(c.responses) > 1 {
+		c.responses = c.responses[1:]
+	}
+	return resp, nil
+}
+
+func (c *fakeLockAPIClient) ReportDeploymentStatusChanged(ctx context.Context, req *pipedservice.ReportDeploymentStatusChangedRequest, opts ...grpc.CallOption) (*pipedservice.ReportDeploymentStatusChangedResponse, error) {
+	c.reasons = append(c.reasons, req.StatusReason)
+	return &pipedservice.ReportDeploymentStatusChangedResponse{}, nil
+}
+
+func TestWaitForLocks(t *testing.T) {
+	lockRetryInterval = time.Millisecond
+	defer func() { lockRetryInterval = 30 * time.Second }()
+
+	holders := map[string]string{"prod-db": "deployment-2", "prod-cache": "deployment-3"}
+	ac := &fakeLockAPIClient{
+		responses: []*pipedservice.AcquireDeploymentLocksResponse{
+			{Holders: holders},
+			{Holders: holders},
+			{Acquired: true},
+		},
+	}
+	s := &scheduler{
+		deployment: &model.Deployment{
+			Id:     "deployment-1",
+			Status: model.DeploymentStatus_DEPLOYMENT_PLANNED,
+		},
+		apiClient:   ac,
+		cancelledCh: make(chan *model.ReportableCommand, 1),
+		logger:      zap.NewNop(),
+	}
+
+	cmd, err := s.waitForLocks(context.Background(), []string{"prod-db", "prod-cache"})
+	require.NoError(t, err)
+	assert.Nil(t, cmd)
+	// The same reason is reported only once.
+	assert.Equal(t, []string{
+		"Waiting for deployment locks: prod-cache (held by deployment deployment-3), prod-db (held by deployment deployment-2)",
+	}, ac.reasons)
+
+	// The waiting is stopped by the cancel command.
+	ac.responses = []*pipedservice.AcquireDeploymentLocksResponse{{Holders: holders}}
+	s.Cancel(model.ReportableCommand{Command: &model.Command{Commander: "user"}})
+	cmd, err = s.waitForLocks(context.Background(), []string{"prod-db"})
+	require.NoError(t, err)
+	require.NotNil(t, cmd)
+	assert.Equal(t, "user", cmd.Commander)
+}
+
+type fakeLeaseAPIClient struct {
+	apiClient
+	responses []*pipedservice.AcquireDeploymentLeaseResponse
+	err       error
+	released  []string
+}
+
+func (c *fakeLeaseAPIClient) AcquireDeploymentLease(ctx context.Context, req *pipedservice.AcquireDeploymentLeaseRequest, opts ...grpc.CallOption) (*pipedservice.AcquireDeploymentLeaseResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	resp := c.responses[0]
+	if len(c.responses) > 1 {
+		c.responses = c.responses[1:]
+	}
+	return resp, nil
+}
+
+func (c *fakeLeaseAPIClient) ReleaseDeploymentLease(ctx context.Context, req *pipedservice.ReleaseDeploymentLeaseRequest, opts ...grpc.CallOption) (*pipedservice.ReleaseDeploymentLeaseResponse, error) {
+	c.released = append(c.released, req.InstanceId)
+	return &pipedservice.ReleaseDeploymentLeaseResponse{}, nil
+}
+
+func TestWaitForLease(t *testing.T) {
+	leaseRetryInterval = time.Millisecond
+	defer func() { leaseRetryInterval = 10 * time.Second }()
+
+	ac := &fakeLeaseAPIClient{
+		responses: []*pipedservice.AcquireDeploymentLeaseResponse{
+			{Holder: "instance-0"},
+			{Holder: "instance-0"},
+			{Acquired: true},
+		},
+	}
+	s := &scheduler{
+		deployment: &model.Deployment{
+			Id:     "deployment-1",
+			Status: model.DeploymentStatus_DEPLOYMENT_RUNNING,
+		},
+		instanceID: "instance-1",
+		apiClient:  ac,
+		handoverCh: make(chan struct{}),
+		logger:     zap.NewNop(),
+	}
+
+	err := s.waitForLease(context.Background())
+	require.NoError(t, err)
+
+	s.releaseLease()
+	assert.Equal(t, []string{"instance-1"}, ac.released)
+
+	// The waiting is stopped when the deployment is handed over.
+	ac.responses = []*pipedservice.AcquireDeploymentLeaseResponse{{Holder: "instance-0"}}
+	s.Handover()
+	s.Handover()
+	err = s.waitForLease(context.Background())
+	assert.Equal(t, errHandedOver, err)
+}
+
+func TestWaitForLeaseFailure(t *testing.T) {
+	leaseRetryInterval = time.Millisecond
+	leaseWaitTimeout = 20 * time.Millisecond
+	defer func() {
+		leaseRetryInterval = 10 * time.Second
+		leaseWaitTimeout = 15 * time.Minute
+	}()
+
+	newScheduler := func(ac apiClient) *scheduler {
+		return &scheduler{
+			deployment: &model.Deployment{
+				Id:     "deployment-1",
+				Status: model.DeploymentStatus_DEPLOYMENT_RUNNING,
+			},
+			instanceID: "instance-1",
+			apiClient:  ac,
+			handoverCh: make(chan struct{}),
+			logger:     zap.NewNop(),
+		}
+	}
+
+	// The lease is considered as acquired when the control-plane does not support it.
+	s := newScheduler(&fakeLeaseAPIClient{err: status.Error(codes.Unimplemented, "unknown method")})
+	err := s.waitForLease(context.Background())
+	require.NoError(t, err)
+
+	// The waiting is bounded when the lease could not be acquired.
+	s = newScheduler(&fakeLeaseAPIClient{err: status.Error(codes.Internal, "failed to acquire deployment lease")})
+	err = s.waitForLease(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, codes.Internal, status.Code(errors.Unwrap(err)))
+
+	s = newScheduler(&fakeLeaseAPIClient{
+		responses: []*pipedservice.AcquireDeploymentLeaseResponse{{Holder: "instance-0"}},
+	})
+	err = s.waitForLease(context.Background())
+	require.Error(t, err)
+}
+
+func TestKeepLease(t *testing.T) {
+	lockKeepInterval = time.Millisecond
+	defer func() { lockKeepInterval = time.Minute }()
+
+	s := &scheduler{
+		deployment: &model.Deployment{
+			Id:     "deployment-1",
+			Status: model.DeploymentStatus_DEPLOYMENT_RUNNING,
+		},
+		instanceID: "instance-1",
+		apiClient: &fakeLeaseAPIClient{
+			responses: []*pipedservice.AcquireDeploymentLeaseResponse{
+				{Acquired: true},
+				{Holder: "instance-2"},
+			},
+		},
+		logger: zap.NewNop(),
+	}
+
+	// The scheduler context is cancelled once the lease was taken by another instance.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		s.keepLease(ctx, cancel)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("keepLease did not stop after losing the lease")
+	}
+	assert.Error(t, ctx.Err())
+}
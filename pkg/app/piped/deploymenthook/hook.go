@@ -0,0 +1,180 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package deploymenthook runs the commands and the HTTP calls configured
+// in the deployment configuration before planning and after finishing a deployment,
+// e.g. to purge caches, invalidate CDN or update tickets.
+package deploymenthook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	defaultTimeout = 5 * time.Minute
+	// The output is stored as metadata so it should be kept small.
+	maxOutputSize = 4 * 1024
+
+	PhasePreSync  = "pre-sync"
+	PhasePostSync = "post-sync"
+)
+
+// Result represents the result of a hook.
+type Result struct {
+	Name   string
+	Output string
+	Err    error
+}
+
+// Metadata returns the metadata keys and values to store the result of a hook in the given phase.
+func (r Result) Metadata(phase string) map[string]string {
+	status := "SUCCESS"
+	output := r.Output
+	if r.Err != nil {
+		status = "FAILURE"
+		if output == "" {
+			output = r.Err.Error()
+		}
+	}
+	prefix := fmt.Sprintf("hook.%s.%s.", phase, r.Name)
+	return map[string]string{
+		prefix + "status": status,
+		prefix + "output": output,
+	}
+}
+
+type Runner struct {
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+func NewRunner(logger *zap.Logger) *Runner {
+	return &Runner{
+		httpClient: &http.Client{},
+		logger:     logger.Named("deployment-hook"),
+	}
+}
+
+// Run executes the given hooks one by one in the given directory.
+// The given environment variables are passed to the commands
+// and expanded in the URLs, headers and bodies of the HTTP calls.
+// Only the given ones are expanded in the HTTP calls to not send the environment of piped outside.
+// It stops at the first failed hook unless the hook allows to continue.
+func (r *Runner) Run(ctx context.Context, hooks []config.DeploymentHook, dir string, env map[string]string) ([]Result, error) {
+	results := make([]Result, 0, len(hooks))
+	for _, h := range hooks {
+		output, err := r.run(ctx, h, dir, env)
+		results = append(results, Result{
+			Name:   h.Name,
+			Output: truncate(output),
+			Err:    err,
+		})
+		if err == nil {
+			r.logger.Info("successfully ran deployment hook", zap.String("hook", h.Name))
+			continue
+		}
+
+		r.logger.Warn("failed to run deployment hook", zap.String("hook", h.Name), zap.Error(err))
+		if !h.ContinueOnError {
+			return results, fmt.Errorf("hook %s failed: %w", h.Name, err)
+		}
+	}
+	return results, nil
+}
+
+func (r *Runner) run(ctx context.Context, h config.DeploymentHook, dir string, env map[string]string) (string, error) {
+	timeout := h.Timeout.Duration()
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if h.HTTP != nil {
+		return r.call(ctx, *h.HTTP, env)
+	}
+	return r.exec(ctx, h.Run, dir, env)
+}
+
+func (r *Runner) exec(ctx context.Context, script, dir string, env map[string]string) (string, error) {
+	cmd := toolexec.CommandContext(ctx, "/bin/sh", "-c", script)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+func (r *Runner) call(ctx context.Context, cfg config.DeploymentHookHTTP, env map[string]string) (string, error) {
+	expand := func(s string) string {
+		return os.Expand(s, func(key string) string {
+			return env[key]
+		})
+	}
+
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	var body io.Reader
+	if cfg.Body != "" {
+		body = strings.NewReader(expand(cfg.Body))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, expand(cfg.URL), body)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, expand(v))
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxOutputSize+1))
+	if err != nil {
+		return "", err
+	}
+	out := strings.TrimSpace(string(data))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return out, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return out, nil
+}
+
+// truncate keeps the tail of the given output
+// since the last lines are usually the most meaningful ones.
+func truncate(s string) string {
+	if len(s) <= maxOutputSize {
+		return s
+	}
+	return "..." + s[len(s)-maxOutputSize:]
+}
@@ -0,0 +1,167 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploymenthook
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestRunCommand(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "marker"), []byte("ok"), 0644))
+	r := NewRunner(zap.NewNop())
+	env := map[string]string{"PIPECD_DEPLOYMENT_ID": "deployment-1"}
+
+	testcases := []struct {
+		name            string
+		hooks           []config.DeploymentHook
+		expectedResults []Result
+		wantErr         bool
+	}{
+		{
+			name: "all succeeded",
+			hooks: []config.DeploymentHook{
+				{Name: "echo", Run: "echo $PIPECD_DEPLOYMENT_ID"},
+				{Name: "dir", Run: "cat marker"},
+			},
+			expectedResults: []Result{
+				{Name: "echo", Output: "deployment-1"},
+				{Name: "dir", Output: "ok"},
+			},
+		},
+		{
+			name: "stop at the failed hook",
+			hooks: []config.DeploymentHook{
+				{Name: "fail", Run: "echo failed && exit 1"},
+				{Name: "echo", Run: "echo never"},
+			},
+			expectedResults: []Result{
+				{Name: "fail", Output: "failed"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "continue on error",
+			hooks: []config.DeploymentHook{
+				{Name: "fail", Run: "exit 1", ContinueOnError: true},
+				{Name: "echo", Run: "echo done"},
+			},
+			expectedResults: []Result{
+				{Name: "fail"},
+				{Name: "echo", Output: "done"},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			results, err := r.Run(context.Background(), tc.hooks, dir, env)
+			assert.Equal(t, tc.wantErr, err != nil)
+			require.Equal(t, len(tc.expectedResults), len(results))
+			for i, expected := range tc.expectedResults {
+				assert.Equal(t, expected.Name, results[i].Name)
+				assert.Equal(t, expected.Output, results[i].Output)
+			}
+		})
+	}
+}
+
+func TestRunHTTP(t *testing.T) {
+	// The environment of piped must not be expanded.
+	os.Setenv("PIPED_TOKEN", "piped-secret")
+	defer os.Unsetenv("PIPED_TOKEN")
+
+	var gotMethod, gotAuth, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotMethod = req.Method
+		gotAuth = req.Header.Get("Authorization")
+		data, _ := ioutil.ReadAll(req.Body)
+		gotBody = string(data)
+		if strings.HasSuffix(req.URL.Path, "/fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal error"))
+			return
+		}
+		w.Write([]byte(`{"id":"purge-1"}`))
+	}))
+	defer server.Close()
+
+	r := NewRunner(zap.NewNop())
+	env := map[string]string{"PIPECD_COMMIT_HASH": "abc", "CDN_TOKEN": "secret"}
+
+	results, err := r.Run(context.Background(), []config.DeploymentHook{
+		{
+			Name: "purge",
+			HTTP: &config.DeploymentHookHTTP{
+				URL:     server.URL + "/purge",
+				Headers: map[string]string{"Authorization": "Bearer $CDN_TOKEN"},
+				Body:    `{"commit":"$PIPECD_COMMIT_HASH","token":"$PIPED_TOKEN"}`,
+			},
+		},
+	}, "", env)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, `{"id":"purge-1"}`, results[0].Output)
+	assert.Equal(t, http.MethodPost, gotMethod)
+	assert.Equal(t, "Bearer secret", gotAuth)
+	assert.Equal(t, `{"commit":"abc","token":""}`, gotBody)
+
+	results, err = r.Run(context.Background(), []config.DeploymentHook{
+		{
+			Name: "fail",
+			HTTP: &config.DeploymentHookHTTP{URL: server.URL + "/fail", Method: http.MethodGet},
+		},
+	}, "", env)
+	assert.Error(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "internal error", results[0].Output)
+	assert.Equal(t, http.MethodGet, gotMethod)
+}
+
+func TestResultMetadata(t *testing.T) {
+	r := Result{Name: "purge", Output: "done"}
+	assert.Equal(t, map[string]string{
+		"hook.post-sync.purge.status": "SUCCESS",
+		"hook.post-sync.purge.output": "done",
+	}, r.Metadata(PhasePostSync))
+
+	r = Result{Name: "purge", Err: errors.New("timed out")}
+	assert.Equal(t, map[string]string{
+		"hook.pre-sync.purge.status": "FAILURE",
+		"hook.pre-sync.purge.output": "timed out",
+	}, r.Metadata(PhasePreSync))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short"))
+
+	long := strings.Repeat("a", maxOutputSize) + "tail"
+	got := truncate(long)
+	assert.Equal(t, maxOutputSize+3, len(got))
+	assert.True(t, strings.HasSuffix(got, "tail"))
+}
@@ -20,11 +20,11 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sync"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/sourcedecrypter"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/git"
 	"github.com/pipe-cd/pipe/pkg/model"
@@ -163,8 +163,7 @@ func (p *provider) prepare(ctx context.Context, lw io.Writer) (*DeploySource, er
 
 	// Load the deployment configuration file.
 	configFileRelativePath := p.appGitPath.GetDeploymentConfigFilePath()
-	configFilePath := filepath.Join(repoDir, configFileRelativePath)
-	cfg, err := config.LoadFromYAML(configFilePath)
+	cfg, err := config.LoadApplication(repoDir, configFileRelativePath)
 	if err != nil {
 		fmt.Fprintf(lw, "Unable to load the deployment configuration file at %s (%v)\n", configFileRelativePath, err)
 		return nil, err
@@ -207,7 +206,7 @@ func (p *provider) copy(lw io.Writer) (*DeploySource, error) {
 	p.copyNum++
 
 	dest := fmt.Sprintf("%s-%d", p.source.RepoDir, p.copyNum)
-	cmd := exec.Command("cp", "-rf", p.source.RepoDir, dest)
+	cmd := toolexec.CommandContext(context.Background(), "cp", "-rf", p.source.RepoDir, dest)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		fmt.Fprintf(lw, "Unable to copy deploy source data (%v, %s)\n", err, string(out))
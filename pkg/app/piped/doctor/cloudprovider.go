@@ -0,0 +1,93 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"go.uber.org/zap"
+	"k8s.io/client-go/discovery"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudrun"
+	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/lambda"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// The name of a function that is expected to be not existing.
+// It is used to verify the credentials without touching any real function.
+const lambdaProbeFunctionName = "pipecd-doctor-probe"
+
+func checkKubernetes(ctx context.Context, cp config.PipedCloudProvider, _ *zap.Logger) (string, error) {
+	cfg := cp.KubernetesConfig
+	// The exec credential plugin such as "aws" or "gke-gcloud-auth-plugin"
+	// must be installed on the piped host to authenticate to the cluster.
+	plugin, err := kubernetes.FindExecPlugin(cfg)
+	if err != nil {
+		return "", err
+	}
+	if plugin != "" {
+		if _, err := exec.LookPath(plugin); err != nil {
+			return "", fmt.Errorf("exec credential plugin %s required by the kubeconfig was not found: %w", plugin, err)
+		}
+	}
+
+	kubeConfig, err := kubernetes.BuildRESTConfig(cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to build kube config: %w", err)
+	}
+	client, err := discovery.NewDiscoveryClientForConfig(kubeConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	version, err := client.ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("failed to get server version: %w", err)
+	}
+
+	source := "in-cluster config"
+	if cfg.KubeConfigPath != "" {
+		source = "kubeconfig " + cfg.KubeConfigPath
+	}
+	return fmt.Sprintf("Connected to Kubernetes cluster %s at %s using %s", version.GitVersion, kubeConfig.Host, source), nil
+}
+
+func checkCloudRun(ctx context.Context, cp config.PipedCloudProvider, logger *zap.Logger) (string, error) {
+	client, err := cloudrun.DefaultRegistry().Client(ctx, cp.Name, cp.CloudRunConfig, logger)
+	if err != nil {
+		return "", fmt.Errorf("failed to create client: %w", err)
+	}
+	services, err := client.ListServices(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to list services: %w", err)
+	}
+	return fmt.Sprintf("Listed %d services in project %s", len(services), cp.CloudRunConfig.Project), nil
+}
+
+func checkLambda(ctx context.Context, cp config.PipedCloudProvider, logger *zap.Logger) (string, error) {
+	client, err := lambda.DefaultRegistry().Client(cp.Name, cp.LambdaConfig, logger)
+	if err != nil {
+		return "", fmt.Errorf("failed to create client: %w", err)
+	}
+	// A not found error means the request was authorized by AWS.
+	_, err = client.GetFunctionState(ctx, lambdaProbeFunctionName)
+	if err != nil && !errors.Is(err, lambda.ErrNotFound) {
+		return "", fmt.Errorf("failed to get function: %w", err)
+	}
+	return fmt.Sprintf("Authorized to access Lambda in region %s", cp.LambdaConfig.Region), nil
+}
@@ -0,0 +1,297 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package doctor provides a piped component that diagnoses
+// whether piped is able to work correctly with its configuration
+// by checking the control plane connectivity, git credentials,
+// cloud provider credentials and the required tools.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	CategoryControlPlane  = "CONTROL_PLANE"
+	CategoryGit           = "GIT"
+	CategoryCloudProvider = "CLOUD_PROVIDER"
+	CategoryTool          = "TOOL"
+
+	defaultCheckTimeout = 30 * time.Second
+)
+
+type apiClient interface {
+	ListApplications(ctx context.Context, req *pipedservice.ListApplicationsRequest, opts ...grpc.CallOption) (*pipedservice.ListApplicationsResponse, error)
+	ReportPipedDiagnostics(ctx context.Context, req *pipedservice.ReportPipedDiagnosticsRequest, opts ...grpc.CallOption) (*pipedservice.ReportPipedDiagnosticsResponse, error)
+}
+
+// commandRunner runs the given command and returns its combined output.
+type commandRunner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// cloudProviderChecker verifies the credentials of a cloud provider
+// and returns a message describing the result.
+type cloudProviderChecker func(ctx context.Context, cp config.PipedCloudProvider, logger *zap.Logger) (string, error)
+
+type Doctor struct {
+	cfg                   *config.PipedSpec
+	apiClient             apiClient
+	toolRegistry          toolregistry.Registry
+	runCommand            commandRunner
+	cloudProviderCheckers map[model.CloudProviderType]cloudProviderChecker
+	checkTimeout          time.Duration
+	nowFunc               func() time.Time
+	logger                *zap.Logger
+}
+
+func NewDoctor(cfg *config.PipedSpec, apiClient apiClient, toolRegistry toolregistry.Registry, logger *zap.Logger) *Doctor {
+	return &Doctor{
+		cfg:          cfg,
+		apiClient:    apiClient,
+		toolRegistry: toolRegistry,
+		runCommand:   runCommand,
+		cloudProviderCheckers: map[model.CloudProviderType]cloudProviderChecker{
+			model.CloudProviderKubernetes: checkKubernetes,
+			model.CloudProviderCloudRun:   checkCloudRun,
+			model.CloudProviderLambda:     checkLambda,
+		},
+		checkTimeout: defaultCheckTimeout,
+		nowFunc:      time.Now,
+		logger:       logger.Named("doctor"),
+	}
+}
+
+// Diagnose runs all checks and returns their results.
+// This never returns an error, a failed check is recorded as a non-passed result instead.
+func (d *Doctor) Diagnose(ctx context.Context) *model.PipedDiagnostics {
+	checks := make([]*model.PipedDiagnostics_Check, 0)
+	checks = append(checks, d.checkControlPlane(ctx))
+	checks = append(checks, d.checkGitRepositories(ctx)...)
+	checks = append(checks, d.checkCloudProviders(ctx)...)
+	checks = append(checks, d.checkTools(ctx)...)
+
+	return &model.PipedDiagnostics{
+		Checks:    checks,
+		CreatedAt: d.nowFunc().Unix(),
+	}
+}
+
+// Report sends the given diagnostics to the control plane.
+func (d *Doctor) Report(ctx context.Context, diagnostics *model.PipedDiagnostics) error {
+	var (
+		req = &pipedservice.ReportPipedDiagnosticsRequest{
+			Diagnostics: diagnostics,
+		}
+		retry = pipedservice.NewRetry(3)
+		err   error
+	)
+	for retry.WaitNext(ctx) {
+		if _, err = d.apiClient.ReportPipedDiagnostics(ctx, req); err == nil {
+			return nil
+		}
+		d.logger.Warn("failed to report diagnostics to control-plane, wait to the next retry",
+			zap.Int("calls", retry.Calls()),
+			zap.Error(err),
+		)
+	}
+	return err
+}
+
+func (d *Doctor) checkControlPlane(ctx context.Context) *model.PipedDiagnostics_Check {
+	ctx, cancel := context.WithTimeout(ctx, d.checkTimeout)
+	defer cancel()
+
+	check := &model.PipedDiagnostics_Check{
+		Category: CategoryControlPlane,
+		Name:     d.cfg.APIAddress,
+	}
+	if check.Name == "" {
+		check.Name = "api"
+	}
+
+	if _, err := d.apiClient.ListApplications(ctx, &pipedservice.ListApplicationsRequest{}); err != nil {
+		check.Message = fmt.Sprintf("Unable to call the control plane API: %v", err)
+		return check
+	}
+	check.Passed = true
+	check.Message = "Successfully connected and authenticated to the control plane"
+	return check
+}
+
+func (d *Doctor) checkGitRepositories(ctx context.Context) []*model.PipedDiagnostics_Check {
+	checks := make([]*model.PipedDiagnostics_Check, 0, len(d.cfg.Repositories))
+	for _, repo := range d.cfg.Repositories {
+		check := &model.PipedDiagnostics_Check{
+			Category: CategoryGit,
+			Name:     repo.RepoID,
+		}
+
+		cctx, cancel := context.WithTimeout(ctx, d.checkTimeout)
+		out, err := d.runCommand(cctx, "git", "ls-remote", "--exit-code", repo.Remote, "refs/heads/"+repo.Branch)
+		cancel()
+
+		if err != nil {
+			check.Message = fmt.Sprintf("Unable to access branch %s of %s: %v (%s)", repo.Branch, repo.Remote, err, strings.TrimSpace(string(out)))
+		} else {
+			check.Passed = true
+			check.Message = fmt.Sprintf("Branch %s of %s is accessible", repo.Branch, repo.Remote)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+func (d *Doctor) checkCloudProviders(ctx context.Context) []*model.PipedDiagnostics_Check {
+	// The cloud providers are checked in parallel
+	// since each check may take up to checkTimeout.
+	var (
+		results = make([]*model.PipedDiagnostics_Check, len(d.cfg.CloudProviders))
+		wg      sync.WaitGroup
+	)
+	for i, cp := range d.cfg.CloudProviders {
+		checker, ok := d.cloudProviderCheckers[cp.Type]
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, cp config.PipedCloudProvider) {
+			defer wg.Done()
+			check := &model.PipedDiagnostics_Check{
+				Category: CategoryCloudProvider,
+				Name:     cp.Name,
+			}
+
+			cctx, cancel := context.WithTimeout(ctx, d.checkTimeout)
+			msg, err := checker(cctx, cp, d.logger)
+			cancel()
+
+			if err != nil {
+				check.Message = fmt.Sprintf("Unable to access %s cloud provider: %v", cp.Type, err)
+			} else {
+				check.Passed = true
+				check.Message = msg
+			}
+			results[i] = check
+		}(i, cp)
+	}
+	wg.Wait()
+
+	checks := make([]*model.PipedDiagnostics_Check, 0, len(results))
+	for _, check := range results {
+		if check != nil {
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+type tool struct {
+	name        string
+	get         func(ctx context.Context, version string) (string, bool, error)
+	versionArgs []string
+}
+
+func (d *Doctor) checkTools(ctx context.Context) []*model.PipedDiagnostics_Check {
+	var (
+		tools      []tool
+		kubernetes bool
+		terraform  bool
+	)
+	for _, cp := range d.cfg.CloudProviders {
+		switch cp.Type {
+		case model.CloudProviderKubernetes:
+			kubernetes = true
+		case model.CloudProviderTerraform:
+			terraform = true
+		}
+	}
+	if kubernetes {
+		tools = append(tools,
+			tool{name: "kubectl", get: d.toolRegistry.Kubectl, versionArgs: []string{"version", "--client", "--short"}},
+			tool{name: "kustomize", get: d.toolRegistry.Kustomize, versionArgs: []string{"version"}},
+			tool{name: "helm", get: d.toolRegistry.Helm, versionArgs: []string{"version", "--short"}},
+		)
+	}
+	if terraform {
+		tools = append(tools,
+			tool{name: "terraform", get: d.toolRegistry.Terraform, versionArgs: []string{"version"}},
+		)
+	}
+
+	checks := make([]*model.PipedDiagnostics_Check, 0, len(tools))
+	for _, t := range tools {
+		check := &model.PipedDiagnostics_Check{
+			Category: CategoryTool,
+			Name:     t.name,
+		}
+		checks = append(checks, check)
+
+		path, _, err := t.get(ctx, "")
+		if err != nil {
+			check.Message = fmt.Sprintf("Unable to install %s: %v", t.name, err)
+			continue
+		}
+
+		cctx, cancel := context.WithTimeout(ctx, d.checkTimeout)
+		out, err := d.runCommand(cctx, path, t.versionArgs...)
+		cancel()
+
+		if err != nil {
+			check.Message = fmt.Sprintf("Unable to run %s: %v (%s)", path, err, strings.TrimSpace(string(out)))
+			continue
+		}
+		check.Passed = true
+		check.Message = firstLine(string(out))
+	}
+	return checks
+}
+
+// Write writes a human-readable summary of the given diagnostics into w.
+func Write(w io.Writer, diagnostics *model.PipedDiagnostics) {
+	var failed int
+	for _, c := range diagnostics.Checks {
+		result := "PASS"
+		if !c.Passed {
+			result = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(w, "[%s] %s %s: %s\n", result, c.Category, c.Name, c.Message)
+	}
+	fmt.Fprintf(w, "\n%d checks, %d passed, %d failed\n", len(diagnostics.Checks), len(diagnostics.Checks)-failed, failed)
+}
+
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return toolexec.CommandContext(ctx, name, args...).CombinedOutput()
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeAPIClient struct {
+	listErr error
+}
+
+func (c *fakeAPIClient) ListApplications(_ context.Context, _ *pipedservice.ListApplicationsRequest, _ ...grpc.CallOption) (*pipedservice.ListApplicationsResponse, error) {
+	return &pipedservice.ListApplicationsResponse{}, c.listErr
+}
+
+func (c *fakeAPIClient) ReportPipedDiagnostics(_ context.Context, _ *pipedservice.ReportPipedDiagnosticsRequest, _ ...grpc.CallOption) (*pipedservice.ReportPipedDiagnosticsResponse, error) {
+	return &pipedservice.ReportPipedDiagnosticsResponse{}, nil
+}
+
+type fakeToolRegistry struct{}
+
+func (r fakeToolRegistry) Kubectl(_ context.Context, _ string) (string, bool, error) {
+	return "/tools/kubectl", false, nil
+}
+
+func (r fakeToolRegistry) Kustomize(_ context.Context, _ string) (string, bool, error) {
+	return "", false, errors.New("download failed")
+}
+
+func (r fakeToolRegistry) Helm(_ context.Context, _ string) (string, bool, error) {
+	return "/tools/helm", false, nil
+}
+
+func (r fakeToolRegistry) Terraform(_ context.Context, _ string) (string, bool, error) {
+	return "/tools/terraform", false, nil
+}
+
+func (r fakeToolRegistry) Opa(_ context.Context, _ string) (string, bool, error) {
+	return "/tools/opa", false, nil
+}
+
+func (r fakeToolRegistry) Cue(_ context.Context, _ string) (string, bool, error) {
+	return "/tools/cue", false, nil
+}
+
+func (r fakeToolRegistry) Trivy(_ context.Context, _ string) (string, bool, error) {
+	return "/tools/trivy", false, nil
+}
+
+func (r fakeToolRegistry) Cosign(_ context.Context, _ string) (string, bool, error) {
+	return "/tools/cosign", false, nil
+}
+
+func (r fakeToolRegistry) Pulumi(_ context.Context, _ string) (string, bool, error) {
+	return "/tools/pulumi", false, nil
+}
+
+func TestDiagnose(t *testing.T) {
+	cfg := &config.PipedSpec{
+		APIAddress: "pipecd.dev:443",
+		Repositories: []config.PipedRepository{
+			{RepoID: "repo-1", Remote: "git@github.com:org/repo-1.git", Branch: "master"},
+			{RepoID: "repo-2", Remote: "git@github.com:org/repo-2.git", Branch: "master"},
+		},
+		CloudProviders: []config.PipedCloudProvider{
+			{Name: "k8s", Type: model.CloudProviderKubernetes},
+			{Name: "ecs", Type: model.CloudProviderECS},
+		},
+	}
+	d := &Doctor{
+		cfg:          cfg,
+		apiClient:    &fakeAPIClient{listErr: errors.New("unauthenticated")},
+		toolRegistry: fakeToolRegistry{},
+		runCommand: func(_ context.Context, name string, args ...string) ([]byte, error) {
+			if name == "git" && args[2] == "git@github.com:org/repo-2.git" {
+				return []byte("Permission denied (publickey)."), errors.New("exit status 128")
+			}
+			return []byte("v1.0.0\nextra"), nil
+		},
+		cloudProviderCheckers: map[model.CloudProviderType]cloudProviderChecker{
+			model.CloudProviderKubernetes: func(_ context.Context, _ config.PipedCloudProvider, _ *zap.Logger) (string, error) {
+				return "ok", nil
+			},
+		},
+		checkTimeout: time.Second,
+		nowFunc:      func() time.Time { return time.Unix(100, 0) },
+		logger:       zap.NewNop(),
+	}
+
+	diag := d.Diagnose(context.Background())
+	require.NotNil(t, diag)
+	assert.Equal(t, int64(100), diag.CreatedAt)
+	assert.False(t, diag.Passed())
+
+	type result struct {
+		category string
+		name     string
+		passed   bool
+	}
+	got := make([]result, 0, len(diag.Checks))
+	for _, c := range diag.Checks {
+		got = append(got, result{c.Category, c.Name, c.Passed})
+	}
+	expected := []result{
+		{CategoryControlPlane, "pipecd.dev:443", false},
+		{CategoryGit, "repo-1", true},
+		{CategoryGit, "repo-2", false},
+		{CategoryCloudProvider, "k8s", true},
+		{CategoryTool, "kubectl", true},
+		{CategoryTool, "kustomize", false},
+		{CategoryTool, "helm", true},
+	}
+	assert.Equal(t, expected, got)
+	assert.Equal(t, "v1.0.0", diag.Checks[4].Message)
+}
+
+func TestWrite(t *testing.T) {
+	diag := &model.PipedDiagnostics{
+		Checks: []*model.PipedDiagnostics_Check{
+			{Category: CategoryGit, Name: "repo-1", Passed: true, Message: "ok"},
+			{Category: CategoryTool, Name: "helm", Passed: false, Message: "not found"},
+		},
+	}
+	var buf bytes.Buffer
+	Write(&buf, diag)
+
+	expected := "[PASS] GIT repo-1: ok\n[FAIL] TOOL helm: not found\n\n2 checks, 1 passed, 1 failed\n"
+	assert.Equal(t, expected, buf.String())
+}
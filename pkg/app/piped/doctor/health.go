@@ -0,0 +1,170 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doctor
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const defaultHealthCheckInterval = 5 * time.Minute
+
+type repositoryStatusLister interface {
+	RepositoryStatuses() []*model.PipedHealth_Repository
+}
+
+// HealthCollector collects the health of piped and the resources it depends on
+// to be periodically reported to the control plane.
+// The cloud providers and tools are checked by Run at its own interval
+// and Collect just returns the latest results of those checks
+// so that reporting the health never waits for the slow checks.
+type HealthCollector struct {
+	repositories repositoryStatusLister
+	interval     time.Duration
+	reloadCh     chan struct{}
+	logger       *zap.Logger
+
+	mu             sync.RWMutex
+	doctor         *Doctor
+	cloudProviders []*model.PipedHealth_CloudProvider
+	// The tool versions are checked only once per configuration
+	// since they do not change while piped is running.
+	tools []*model.PipedHealth_Tool
+}
+
+func NewHealthCollector(d *Doctor, repositories repositoryStatusLister) *HealthCollector {
+	return &HealthCollector{
+		doctor:       d,
+		repositories: repositories,
+		interval:     defaultHealthCheckInterval,
+		reloadCh:     make(chan struct{}, 1),
+		logger:       d.logger.Named("health-collector"),
+	}
+}
+
+// Run checks the cloud providers and tools at every interval
+// until the specified context has done.
+func (c *HealthCollector) Run(ctx context.Context) error {
+	c.logger.Info("start running health collector")
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	c.check(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("health collector has been stopped")
+			return nil
+
+		case <-ticker.C:
+			c.check(ctx)
+
+		case <-c.reloadCh:
+			c.check(ctx)
+		}
+	}
+}
+
+// ReloadConfig makes HealthCollector check the cloud providers and tools
+// of the given configuration from now on.
+func (c *HealthCollector) ReloadConfig(cfg *config.PipedSpec) error {
+	c.mu.Lock()
+	d := *c.doctor
+	d.cfg = cfg
+	c.doctor = &d
+	c.tools = nil
+	c.mu.Unlock()
+
+	select {
+	case c.reloadCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (c *HealthCollector) check(ctx context.Context) {
+	c.mu.RLock()
+	d, tools := c.doctor, c.tools
+	c.mu.RUnlock()
+
+	types := make(map[string]model.CloudProviderType, len(d.cfg.CloudProviders))
+	for _, cp := range d.cfg.CloudProviders {
+		types[cp.Name] = cp.Type
+	}
+	checks := d.checkCloudProviders(ctx)
+	cloudProviders := make([]*model.PipedHealth_CloudProvider, 0, len(checks))
+	for _, check := range checks {
+		cloudProviders = append(cloudProviders, &model.PipedHealth_CloudProvider{
+			Name:      check.Name,
+			Type:      types[check.Name].String(),
+			Reachable: check.Passed,
+			Message:   check.Message,
+		})
+	}
+
+	if tools == nil {
+		checks := d.checkTools(ctx)
+		tools = make([]*model.PipedHealth_Tool, 0, len(checks))
+		for _, check := range checks {
+			tool := &model.PipedHealth_Tool{Name: check.Name}
+			if check.Passed {
+				tool.Version = check.Message
+			}
+			tools = append(tools, tool)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Discard the results checked with the configuration
+	// which has been replaced while checking.
+	if c.doctor != d {
+		return
+	}
+	c.cloudProviders = cloudProviders
+	c.tools = tools
+}
+
+// Collect returns the latest check results of the cloud providers and tools
+// together with the latest fetch results of the repositories and the resource usage.
+func (c *HealthCollector) Collect(_ context.Context) *model.PipedHealth {
+	c.mu.RLock()
+	cloudProviders, tools, nowFunc := c.cloudProviders, c.tools, c.doctor.nowFunc
+	c.mu.RUnlock()
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	return &model.PipedHealth{
+		CloudProviders: cloudProviders,
+		Repositories:   c.repositories.RepositoryStatuses(),
+		Tools:          tools,
+		ResourceUsage: &model.PipedHealth_ResourceUsage{
+			MemoryBytes: ms.Sys,
+			HeapBytes:   ms.HeapAlloc,
+			Goroutines:  int32(runtime.NumGoroutine()),
+			Cpus:        int32(runtime.NumCPU()),
+		},
+		CreatedAt: nowFunc().Unix(),
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doctor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeRepositoryStatusLister struct {
+	statuses []*model.PipedHealth_Repository
+}
+
+func (l fakeRepositoryStatusLister) RepositoryStatuses() []*model.PipedHealth_Repository {
+	return l.statuses
+}
+
+func TestHealthCollectorCollect(t *testing.T) {
+	cfg := &config.PipedSpec{
+		CloudProviders: []config.PipedCloudProvider{
+			{Name: "k8s-dev", Type: model.CloudProviderKubernetes},
+			{Name: "k8s-prod", Type: model.CloudProviderKubernetes},
+		},
+	}
+	var commands int
+	d := &Doctor{
+		cfg:          cfg,
+		apiClient:    &fakeAPIClient{},
+		toolRegistry: fakeToolRegistry{},
+		runCommand: func(_ context.Context, _ string, _ ...string) ([]byte, error) {
+			commands++
+			return []byte("v1.0.0"), nil
+		},
+		cloudProviderCheckers: map[model.CloudProviderType]cloudProviderChecker{
+			model.CloudProviderKubernetes: func(_ context.Context, cp config.PipedCloudProvider, _ *zap.Logger) (string, error) {
+				if cp.Name == "k8s-prod" {
+					return "", errors.New("connection refused")
+				}
+				return "ok", nil
+			},
+		},
+		checkTimeout: time.Second,
+		nowFunc:      func() time.Time { return time.Unix(100, 0) },
+		logger:       zap.NewNop(),
+	}
+	repos := fakeRepositoryStatusLister{
+		statuses: []*model.PipedHealth_Repository{
+			{Id: "repo-1", LastFetchedAt: 90},
+		},
+	}
+	c := NewHealthCollector(d, repos)
+
+	// Nothing has been checked yet.
+	health := c.Collect(context.Background())
+	require.NotNil(t, health)
+	assert.Empty(t, health.CloudProviders)
+	assert.Empty(t, health.Tools)
+	assert.Equal(t, repos.statuses, health.Repositories)
+
+	c.check(context.Background())
+	health = c.Collect(context.Background())
+	require.NotNil(t, health)
+	assert.Equal(t, int64(100), health.CreatedAt)
+	assert.False(t, health.Healthy())
+
+	require.Len(t, health.CloudProviders, 2)
+	assert.Equal(t, "k8s-dev", health.CloudProviders[0].Name)
+	assert.Equal(t, model.CloudProviderKubernetes.String(), health.CloudProviders[0].Type)
+	assert.True(t, health.CloudProviders[0].Reachable)
+	assert.False(t, health.CloudProviders[1].Reachable)
+
+	assert.Equal(t, repos.statuses, health.Repositories)
+
+	expectedTools := []*model.PipedHealth_Tool{
+		{Name: "kubectl", Version: "v1.0.0"},
+		{Name: "kustomize"},
+		{Name: "helm", Version: "v1.0.0"},
+	}
+	assert.Equal(t, expectedTools, health.Tools)
+
+	require.NotNil(t, health.ResourceUsage)
+	assert.NotZero(t, health.ResourceUsage.MemoryBytes)
+	assert.NotZero(t, health.ResourceUsage.Goroutines)
+	assert.NotZero(t, health.ResourceUsage.Cpus)
+
+	// Tool versions should be checked only once.
+	ran := commands
+	c.check(context.Background())
+	assert.Equal(t, ran, commands)
+
+	// The cloud providers and tools of the reloaded configuration should be checked.
+	require.NoError(t, c.ReloadConfig(&config.PipedSpec{
+		CloudProviders: []config.PipedCloudProvider{
+			{Name: "k8s-dev", Type: model.CloudProviderKubernetes},
+		},
+	}))
+	c.check(context.Background())
+	assert.Greater(t, commands, ran)
+
+	health = c.Collect(context.Background())
+	require.Len(t, health.CloudProviders, 1)
+	assert.Equal(t, "k8s-dev", health.CloudProviders[0].Name)
+	assert.True(t, health.Healthy())
+}
@@ -193,12 +193,15 @@ func (d *detector) checkApplication(ctx context.Context, app *model.Application,
 		diff.WithEquateEmpty(),
 		diff.WithIgnoreAddingMapKeys(),
 		diff.WithCompareNumberAndNumericString(),
+		diff.WithCompareBoolAndBoolString(),
+		diff.WithIgnoreListOrder(),
+		diff.WithEquateDefaults(),
 	)
 	if err != nil {
 		return err
 	}
 
-	state := makeSyncState(result, headCommit.Hash)
+	state := makeSyncState(result, headCommit.Hash, diff.WithMaskValuePatterns(d.config.DiffMaskRegexps()...))
 	if state.Status == model.ApplicationSyncStatus_SYNCED {
 		return d.reporter.ReportApplicationSyncState(ctx, app.Id, state)
 	}
@@ -263,7 +266,7 @@ func (d *detector) loadHeadManifests(ctx context.Context, app *model.Application
 			}
 		}
 
-		loader := provider.NewManifestLoader(app.Name, appDir, repoDir, app.GitPath.ConfigFilename, cfg.KubernetesDeploymentSpec.Input, d.logger)
+		loader := provider.NewManifestLoader(app.Name, appDir, repoDir, app.GitPath.ConfigFilename, cfg.KubernetesDeploymentSpec.Input, d.provider.KubernetesConfig, d.logger)
 		manifests, err = loader.LoadManifests(ctx)
 		if err != nil {
 			err = fmt.Errorf("failed to load new manifests: %w", err)
@@ -310,8 +313,7 @@ func (d *detector) listGroupedApplication() map[string][]*model.Application {
 }
 
 func (d *detector) loadDeploymentConfiguration(repoPath string, app *model.Application) (*config.Config, error) {
-	path := filepath.Join(repoPath, app.GitPath.GetDeploymentConfigFilePath())
-	cfg, err := config.LoadFromYAML(path)
+	cfg, err := config.LoadApplication(repoPath, app.GitPath.GetDeploymentConfigFilePath())
 	if err != nil {
 		return nil, err
 	}
@@ -345,7 +347,7 @@ func filterIgnoringManifests(manifests []provider.Manifest) []provider.Manifest
 	return out
 }
 
-func makeSyncState(r *provider.DiffListResult, commit string) model.ApplicationSyncState {
+func makeSyncState(r *provider.DiffListResult, commit string, opts ...diff.RenderOption) model.ApplicationSyncState {
 	if r.NoChange() {
 		return model.ApplicationSyncState{
 			Status:      model.ApplicationSyncStatus_SYNCED,
@@ -364,7 +366,7 @@ func makeSyncState(r *provider.DiffListResult, commit string) model.ApplicationS
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("Diff between the defined state in Git at commit %s and actual state in cluster:\n\n", commit))
 	b.WriteString("--- Expected\n+++ Actual\n\n")
-	b.WriteString(r.DiffString())
+	b.WriteString(r.DiffString(opts...))
 
 	return model.ApplicationSyncState{
 		Status:      model.ApplicationSyncStatus_OUT_OF_SYNC,
@@ -20,6 +20,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"strings"
 	"text/template"
 	"time"
 
@@ -31,6 +33,7 @@ import (
 	logfactory "github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/log/factory"
 	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
 	metricsfactory "github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics/factory"
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/webhook"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
@@ -43,6 +46,7 @@ type Executor struct {
 	config              *config.Config
 	startTime           time.Time
 	previousElapsedTime time.Duration
+	recorder            *resultRecorder
 }
 
 type registerer interface {
@@ -68,6 +72,12 @@ type templateArgs struct {
 	K8s struct {
 		Namespace string
 	}
+	// The label values of each variant used to filter its metrics.
+	Variant struct {
+		Primary  string
+		Canary   string
+		Baseline string
+	}
 	// User-defined custom args.
 	Args map[string]string
 }
@@ -100,6 +110,28 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	// The traffic is checked only at the first attempt,
+	// a restarted analysis continues regardless of the traffic.
+	if options.TrafficGuard != nil && e.retrievePreviousElapsedTime() == 0 {
+		guard, err := e.newTrafficGuard(options.TrafficGuard)
+		if err != nil {
+			e.LogPersister.Errorf("Failed to spawn traffic guard: %v", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		enough, reason, err := guard.wait(ctx)
+		if err != nil {
+			e.LogPersister.Errorf("Traffic guard failed: %v", err)
+			return executor.DetermineStageStatus(sig.Signal(), e.Stage.Status, model.StageStatus_STAGE_FAILURE)
+		}
+		if !enough {
+			e.LogPersister.Infof("WARNING: Skipped the analysis since the analyzed variant is not receiving enough traffic: %s", reason)
+			e.saveSkippedResult(sig.Context(), reason)
+			return executor.DetermineStageStatus(sig.Signal(), e.Stage.Status, model.StageStatus_STAGE_SUCCESS)
+		}
+		// The analysis window starts after the traffic became enough.
+		e.startTime = time.Now()
+	}
+
 	timeout := time.Duration(options.Duration)
 	e.previousElapsedTime = e.retrievePreviousElapsedTime()
 	if e.previousElapsedTime > 0 {
@@ -108,6 +140,11 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 	}
 	defer e.saveElapsedTime(ctx)
 
+	// The time when the analysis was started at the first attempt.
+	from := e.startTime.Add(-e.previousElapsedTime)
+	e.recorder = newResultRecorder(e.Deployment.Id, e.Stage.Id, from)
+	defer e.reportAnalysisResult(sig.Context())
+
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -150,18 +187,101 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 		})
 	}
 
+	// Run analyses with external judges.
+	for i := range options.Webhooks {
+		analyzer, err := e.newAnalyzerForWebhook(i, &options.Webhooks[i], from)
+		if err != nil {
+			e.LogPersister.Errorf("Failed to spawn analyzer for %s: %v", options.Webhooks[i].Provider, err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		eg.Go(func() error {
+			e.LogPersister.Infof("[%s] Start analysis for %s", analyzer.id, analyzer.providerType)
+			return analyzer.run(ctx)
+		})
+	}
+
+	// Run analyses by comparing the canary with the baseline.
+	var judge *dynamicMetricsJudge
+	if len(options.Dynamic.Metrics) > 0 {
+		judge, err = e.newDynamicMetricsJudge(&options.Dynamic)
+		if err != nil {
+			e.LogPersister.Errorf("Failed to spawn judge for dynamic metrics: %v", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		eg.Go(func() error {
+			e.LogPersister.Infof("[dynamic] Start comparing %d metrics between canary and baseline", len(judge.metrics))
+			return judge.run(ctx, from)
+		})
+	}
+
 	if err := eg.Wait(); err != nil {
 		e.LogPersister.Errorf("Analysis failed: %s", err.Error())
+		e.saveAnalysisResult(sig.Context(), fmt.Sprintf("Failed: %s", err.Error()))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
 	status := executor.DetermineStageStatus(sig.Signal(), e.Stage.Status, model.StageStatus_STAGE_SUCCESS)
-	if status == model.StageStatus_STAGE_SUCCESS {
-		e.LogPersister.Success("All analyses were successful.")
+	if status != model.StageStatus_STAGE_SUCCESS {
+		return status
+	}
+
+	summary := fmt.Sprintf("All %d analyses passed", len(options.Metrics)+len(options.Logs)+len(options.Https)+len(options.Webhooks))
+	if judge != nil {
+		// The final score over the whole analysis duration decides the result.
+		score, err := judge.judge(sig.Context(), metrics.QueryRange{From: from, To: time.Now()})
+		if err != nil {
+			e.LogPersister.Errorf("Failed to compute the final score of dynamic metrics: %v", err)
+			e.saveAnalysisResult(sig.Context(), fmt.Sprintf("Failed: %s", err.Error()))
+			return model.StageStatus_STAGE_FAILURE
+		}
+		e.recorder.setScore(score)
+		pass := options.Dynamic.Score.GetPass()
+		if score < float64(pass) {
+			e.LogPersister.Errorf("Analysis failed: the final score (%.1f) is lower than the pass score (%d)", score, pass)
+			e.saveAnalysisResult(sig.Context(), fmt.Sprintf("Failed: score %.1f is lower than %d", score, pass))
+			return model.StageStatus_STAGE_FAILURE
+		}
+		e.LogPersister.Successf("The final score of dynamic metrics is %.1f", score)
+		summary = fmt.Sprintf("%s with score %.1f", summary, score)
 	}
+
+	e.LogPersister.Success("All analyses were successful.")
+	e.saveAnalysisResult(sig.Context(), summary)
 	return status
 }
 
+// saveAnalysisResult publishes the summary of the analyses as the stage result.
+func (e *Executor) saveAnalysisResult(ctx context.Context, summary string) {
+	results := map[string]string{
+		executor.StageResultAnalysis: summary,
+	}
+	if err := e.MetadataStore.SetStageResults(ctx, e.Stage.Id, results); err != nil {
+		e.Logger.Error("failed to save stage results", zap.Error(err))
+	}
+}
+
+// saveSkippedResult publishes the reason why the analysis was skipped as a warning of the stage.
+func (e *Executor) saveSkippedResult(ctx context.Context, reason string) {
+	results := map[string]string{
+		executor.StageResultAnalysis: "Skipped due to low traffic",
+		executor.StageResultWarning:  reason,
+	}
+	if err := e.MetadataStore.SetStageResults(ctx, e.Stage.Id, results); err != nil {
+		e.Logger.Error("failed to save stage results", zap.Error(err))
+	}
+}
+
+// reportAnalysisResult persists the data collected by the analyses
+// to visualize them after the stage completed.
+func (e *Executor) reportAnalysisResult(ctx context.Context) {
+	if e.AnalysisResultStore == nil {
+		return
+	}
+	if err := e.AnalysisResultStore.PutAnalysisResult(ctx, e.recorder.snapshot()); err != nil {
+		e.Logger.Error("failed to report analysis result", zap.Error(err))
+	}
+}
+
 const elapsedTimeKey = "elapsedTime"
 
 // saveElapsedTime stores the elapsed time of analysis stage into metadata persister.
@@ -211,9 +331,13 @@ func (e *Executor) newAnalyzerForMetrics(i int, templatable *config.TemplatableA
 			From: now.Add(-cfg.Interval.Duration()),
 			To:   now,
 		}
-		return provider.Evaluate(ctx, query, queryRange, &cfg.Expected)
+		evaluator := &recordingEvaluator{Evaluator: &cfg.Expected}
+		expected, reason, err := provider.Evaluate(ctx, query, queryRange, evaluator)
+		e.recorder.recordPoints(id, "", now, evaluator.values)
+		return expected, reason, err
 	}
-	return newAnalyzer(id, provider.Type(), cfg.Query, runner, time.Duration(cfg.Interval), cfg.FailureLimit, cfg.SkipOnNoData, e.Logger, e.LogPersister), nil
+	e.recorder.addQuery(id, provider.Type(), cfg.Query)
+	return newAnalyzer(id, provider.Type(), cfg.Query, runner, time.Duration(cfg.Interval), cfg.FailureLimit, cfg.SkipOnNoData, e.recorder, e.Logger, e.LogPersister), nil
 }
 
 func (e *Executor) newAnalyzerForLog(i int, templatable *config.TemplatableAnalysisLog, templateCfg *config.AnalysisTemplateSpec) (*analyzer, error) {
@@ -221,15 +345,21 @@ func (e *Executor) newAnalyzerForLog(i int, templatable *config.TemplatableAnaly
 	if err != nil {
 		return nil, err
 	}
-	provider, err := e.newLogProvider(cfg.Provider)
+	provider, err := e.newLogProvider(cfg.Provider, templatable)
 	if err != nil {
 		return nil, err
 	}
 	id := fmt.Sprintf("log-%d", i)
 	runner := func(ctx context.Context, query string) (bool, string, error) {
-		return provider.Evaluate(ctx, query)
+		now := time.Now()
+		queryRange := log.QueryRange{
+			From: now.Add(-cfg.Interval.Duration()),
+			To:   now,
+		}
+		return provider.Evaluate(ctx, query, queryRange, cfg.Threshold)
 	}
-	return newAnalyzer(id, provider.Type(), cfg.Query, runner, time.Duration(cfg.Interval), cfg.FailureLimit, cfg.SkipOnNoData, e.Logger, e.LogPersister), nil
+	e.recorder.addQuery(id, provider.Type(), cfg.Query)
+	return newAnalyzer(id, provider.Type(), cfg.Query, runner, time.Duration(cfg.Interval), cfg.FailureLimit, cfg.SkipOnNoData, e.recorder, e.Logger, e.LogPersister), nil
 }
 
 func (e *Executor) newAnalyzerForHTTP(i int, templatable *config.TemplatableAnalysisHTTP, templateCfg *config.AnalysisTemplateSpec) (*analyzer, error) {
@@ -242,7 +372,58 @@ func (e *Executor) newAnalyzerForHTTP(i int, templatable *config.TemplatableAnal
 	runner := func(ctx context.Context, query string) (bool, string, error) {
 		return provider.Run(ctx, cfg)
 	}
-	return newAnalyzer(id, provider.Type(), "", runner, time.Duration(cfg.Interval), cfg.FailureLimit, cfg.SkipOnNoData, e.Logger, e.LogPersister), nil
+	e.recorder.addQuery(id, provider.Type(), cfg.URL)
+	return newAnalyzer(id, provider.Type(), "", runner, time.Duration(cfg.Interval), cfg.FailureLimit, cfg.SkipOnNoData, e.recorder, e.Logger, e.LogPersister), nil
+}
+
+func (e *Executor) newAnalyzerForWebhook(i int, cfg *config.AnalysisWebhook, from time.Time) (*analyzer, error) {
+	providerCfg, ok := e.PipedConfig.GetAnalysisProvider(cfg.Provider)
+	if !ok {
+		return nil, fmt.Errorf("unknown provider name %s", cfg.Provider)
+	}
+	if providerCfg.WebhookConfig == nil {
+		return nil, fmt.Errorf("provider %s is not a webhook provider", cfg.Provider)
+	}
+	options := []webhook.Option{
+		webhook.WithLogger(e.Logger),
+		webhook.WithTimeout(cfg.Timeout.Duration()),
+		webhook.WithHeaders(providerCfg.WebhookConfig.Headers),
+	}
+	if f := providerCfg.WebhookConfig.TokenFile; f != "" {
+		token, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the token file: %w", err)
+		}
+		options = append(options, webhook.WithToken(strings.TrimSpace(string(token))))
+	}
+	provider, err := webhook.NewProvider(providerCfg.WebhookConfig.URL, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	id := fmt.Sprintf("webhook-%d", i)
+	runner := func(ctx context.Context, _ string) (bool, string, error) {
+		req := webhook.Request{
+			Deployment: webhook.Deployment{
+				ID:              e.Deployment.Id,
+				ApplicationID:   e.Deployment.ApplicationId,
+				ApplicationName: e.Deployment.ApplicationName,
+				EnvName:         e.EnvName,
+				Kind:            e.Deployment.Kind.String(),
+				CommitHash:      e.Deployment.Trigger.Commit.Hash,
+			},
+			Stage: webhook.Stage{
+				ID:   e.Stage.Id,
+				Name: e.Stage.Name,
+			},
+			Args: cfg.Args,
+			From: from.Unix(),
+			To:   time.Now().Unix(),
+		}
+		return provider.Evaluate(ctx, req, cfg.MinScore)
+	}
+	e.recorder.addQuery(id, provider.Type(), provider.URL())
+	return newAnalyzer(id, provider.Type(), "", runner, time.Duration(cfg.Interval), cfg.FailureLimit, false, e.recorder, e.Logger, e.LogPersister), nil
 }
 
 func (e *Executor) newMetricsProvider(providerName string, templatable *config.TemplatableAnalysisMetrics) (metrics.Provider, error) {
@@ -257,12 +438,12 @@ func (e *Executor) newMetricsProvider(providerName string, templatable *config.T
 	return provider, nil
 }
 
-func (e *Executor) newLogProvider(providerName string) (log.Provider, error) {
+func (e *Executor) newLogProvider(providerName string, templatable *config.TemplatableAnalysisLog) (log.Provider, error) {
 	cfg, ok := e.PipedConfig.GetAnalysisProvider(providerName)
 	if !ok {
 		return nil, fmt.Errorf("unknown provider name %s", providerName)
 	}
-	provider, err := logfactory.NewProvider(&cfg, e.Logger)
+	provider, err := logfactory.NewProvider(templatable, &cfg, e.Logger)
 	if err != nil {
 		return nil, err
 	}
@@ -353,6 +534,9 @@ func (e *Executor) render(templateCfg config.AnalysisTemplateSpec, customArgs ma
 		}
 		args.K8s = struct{ Namespace string }{Namespace: namespace}
 	}
+	args.Variant.Primary = "primary"
+	args.Variant.Canary = "canary"
+	args.Variant.Baseline = "baseline"
 
 	cfg, err := json.Marshal(templateCfg)
 	if err != nil {
@@ -23,6 +23,7 @@ type analyzer struct {
 	failureLimit int
 	skipOnNoData bool
 
+	recorder     *resultRecorder
 	logger       *zap.Logger
 	logPersister executor.LogPersister
 }
@@ -37,6 +38,7 @@ func newAnalyzer(
 	interval time.Duration,
 	failureLimit int,
 	skipOnNodata bool,
+	recorder *resultRecorder,
 	logger *zap.Logger,
 	logPersister executor.LogPersister,
 ) *analyzer {
@@ -48,6 +50,7 @@ func newAnalyzer(
 		interval:     interval,
 		failureLimit: failureLimit,
 		skipOnNoData: skipOnNodata,
+		recorder:     recorder,
 		logPersister: logPersister,
 		logger: logger.With(
 			zap.String("analyzer-id", id),
@@ -78,6 +81,7 @@ func (a *analyzer) run(ctx context.Context) error {
 			if err != nil {
 				reason = fmt.Sprintf("failed to run query: %s", err.Error())
 			}
+			a.recorder.recordEvaluation(a.id, time.Now(), expected, reason)
 
 			if expected {
 				a.logPersister.Successf("[%s] The query result is expected one. Reason: %s. Performed query: %q", a.id, reason, a.query)
@@ -0,0 +1,316 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	canaryVariantName   = "canary"
+	baselineVariantName = "baseline"
+
+	defaultDynamicQueryTimeout = 30 * time.Second
+)
+
+// dynamicMetricsJudge compares the canary with the baseline for each dynamic metrics
+// and aggregates the results into a score in the same way as Kayenta.
+type dynamicMetricsJudge struct {
+	metrics []dynamicMetrics
+	score   config.AnalysisDynamicScore
+
+	recorder     *resultRecorder
+	logger       *zap.Logger
+	logPersister executor.LogPersister
+}
+
+type dynamicMetrics struct {
+	id       string
+	cfg      *config.AnalysisDynamicMetrics
+	query    *template.Template
+	provider metrics.Provider
+}
+
+// dynamicMetricsResult represents the comparison result of a dynamic metrics.
+type dynamicMetricsResult struct {
+	id     string
+	weight int
+	// True if no data was returned for any of the variants.
+	noData     bool
+	regression bool
+	pValue     float64
+}
+
+// dynamicQueryArgs is the data passed to the query template of dynamic metrics.
+type dynamicQueryArgs struct {
+	Variant struct {
+		Name string
+	}
+}
+
+func (e *Executor) newDynamicMetricsJudge(cfg *config.AnalysisDynamic) (*dynamicMetricsJudge, error) {
+	j := &dynamicMetricsJudge{
+		score:        cfg.Score,
+		recorder:     e.recorder,
+		logger:       e.Logger.Named("dynamic-metrics-judge"),
+		logPersister: e.LogPersister,
+	}
+	for i := range cfg.Metrics {
+		m := &cfg.Metrics[i]
+		query, err := template.New("query").Parse(m.Query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse query of dynamic metrics at index %d: %w", i, err)
+		}
+		timeout := m.Timeout
+		if timeout == 0 {
+			timeout = config.Duration(defaultDynamicQueryTimeout)
+		}
+		templatable := &config.TemplatableAnalysisMetrics{
+			AnalysisMetrics: config.AnalysisMetrics{
+				Provider: m.Provider,
+				Timeout:  timeout,
+			},
+		}
+		provider, err := e.newMetricsProvider(m.Provider, templatable)
+		if err != nil {
+			return nil, err
+		}
+		id := fmt.Sprintf("dynamic-metrics-%d", i)
+		j.recorder.addQuery(id, provider.Type(), m.Query)
+		j.metrics = append(j.metrics, dynamicMetrics{
+			id:       id,
+			cfg:      m,
+			query:    query,
+			provider: provider,
+		})
+	}
+	return j, nil
+}
+
+// run computes the intermediate score at the configured interval, until the context is done.
+// It returns an error as soon as a score is lower than the marginal one.
+func (j *dynamicMetricsJudge) run(ctx context.Context, from time.Time) error {
+	if j.score.Interval == 0 {
+		return nil
+	}
+	ticker := time.NewTicker(j.score.Interval.Duration())
+	defer ticker.Stop()
+
+	marginal := float64(j.score.GetMarginal())
+	for {
+		select {
+		case <-ticker.C:
+			score, err := j.judge(ctx, metrics.QueryRange{From: from, To: time.Now()})
+			// Ignore parent's context deadline exceeded error, and return immediately.
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == context.DeadlineExceeded {
+				return nil
+			}
+			if err != nil {
+				j.logPersister.Errorf("[dynamic] Failed to compute the intermediate score: %v", err)
+				continue
+			}
+			if score < marginal {
+				return fmt.Errorf("the intermediate score (%.1f) is lower than the marginal score (%.0f)", score, marginal)
+			}
+			j.logPersister.Infof("[dynamic] The intermediate score is %.1f", score)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// judge compares the canary with the baseline for all metrics within the given range
+// and returns the score in range [0, 100].
+func (j *dynamicMetricsJudge) judge(ctx context.Context, queryRange metrics.QueryRange) (float64, error) {
+	results := make([]dynamicMetricsResult, 0, len(j.metrics))
+	for _, m := range j.metrics {
+		r, err := j.compare(ctx, m, queryRange)
+		if err != nil {
+			return 0, fmt.Errorf("failed to compare variants for %s: %w", m.id, err)
+		}
+		switch {
+		case r.noData:
+			j.logPersister.Infof("[%s] No data was returned for any of the variants so it was excluded from the score", m.id)
+		case r.regression:
+			j.logPersister.Errorf("[%s] The canary has a significant regression compared to the baseline (p-value: %.4f)", m.id, r.pValue)
+		default:
+			j.logPersister.Successf("[%s] No significant regression was found (p-value: %.4f)", m.id, r.pValue)
+		}
+		results = append(results, r)
+	}
+	return computeScore(results)
+}
+
+func (j *dynamicMetricsJudge) compare(ctx context.Context, m dynamicMetrics, queryRange metrics.QueryRange) (dynamicMetricsResult, error) {
+	result := dynamicMetricsResult{
+		id:     m.id,
+		weight: m.cfg.GetWeight(),
+	}
+	canary, err := j.collect(ctx, m, canaryVariantName, queryRange)
+	if err != nil {
+		return result, err
+	}
+	baseline, err := j.collect(ctx, m, baselineVariantName, queryRange)
+	if err != nil {
+		return result, err
+	}
+	j.recorder.recordPoints(m.id, canaryVariantName, queryRange.To, canary)
+	j.recorder.recordPoints(m.id, baselineVariantName, queryRange.To, baseline)
+	if len(canary) == 0 || len(baseline) == 0 {
+		result.noData = true
+		return result, nil
+	}
+	result.pValue = mannWhitneyPValue(canary, baseline, m.cfg.GetDirection())
+	result.regression = result.pValue < 1-m.cfg.GetConfidenceLevel()
+	reason := fmt.Sprintf("p-value %.4f with confidence level %g", result.pValue, m.cfg.GetConfidenceLevel())
+	j.recorder.recordEvaluation(m.id, queryRange.To, !result.regression, reason)
+	return result, nil
+}
+
+// collect runs the query of the given variant and returns all the data points.
+func (j *dynamicMetricsJudge) collect(ctx context.Context, m dynamicMetrics, variant string, queryRange metrics.QueryRange) ([]float64, error) {
+	var args dynamicQueryArgs
+	args.Variant.Name = variant
+	var b bytes.Buffer
+	if err := m.query.Execute(&b, args); err != nil {
+		return nil, fmt.Errorf("failed to render query: %w", err)
+	}
+	query := b.String()
+
+	c := &collector{}
+	_, _, err := m.provider.Evaluate(ctx, query, queryRange, c)
+	if errors.Is(err, metrics.ErrNoDataFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	j.logger.Debug("collected data points",
+		zap.String("variant", variant),
+		zap.String("query", query),
+		zap.Int("count", len(c.values)),
+	)
+	return c.values, nil
+}
+
+// collector is an evaluator which accepts all values to record them.
+type collector struct {
+	values []float64
+}
+
+func (c *collector) InRange(value float64) bool {
+	c.values = append(c.values, value)
+	return true
+}
+
+func (c *collector) String() string {
+	return "any"
+}
+
+// computeScore returns the percentage of the weights of metrics which had no regression.
+// The metrics without any data are excluded.
+func computeScore(results []dynamicMetricsResult) (float64, error) {
+	var total, passed int
+	for _, r := range results {
+		if r.noData {
+			continue
+		}
+		total += r.weight
+		if !r.regression {
+			passed += r.weight
+		}
+	}
+	if total == 0 {
+		return 0, fmt.Errorf("no data points found for any of the dynamic metrics: %w", metrics.ErrNoDataFound)
+	}
+	return float64(passed) * 100 / float64(total), nil
+}
+
+// mannWhitneyPValue performs the Mann-Whitney U test with the normal approximation
+// and returns the p-value of the hypothesis that the canary changed in the given direction
+// compared to the baseline.
+func mannWhitneyPValue(canary, baseline []float64, direction config.AnalysisDynamicDirection) float64 {
+	type sample struct {
+		value  float64
+		canary bool
+	}
+	samples := make([]sample, 0, len(canary)+len(baseline))
+	for _, v := range canary {
+		samples = append(samples, sample{v, true})
+	}
+	for _, v := range baseline {
+		samples = append(samples, sample{v, false})
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].value < samples[j].value
+	})
+
+	// The tied values are given the average of the ranks they span.
+	var rankSum, ties float64
+	for i := 0; i < len(samples); {
+		k := i
+		for k < len(samples) && samples[k].value == samples[i].value {
+			k++
+		}
+		rank := float64(i+k+1) / 2
+		for _, s := range samples[i:k] {
+			if s.canary {
+				rankSum += rank
+			}
+		}
+		t := float64(k - i)
+		ties += t*t*t - t
+		i = k
+	}
+
+	var (
+		n1       = float64(len(canary))
+		n2       = float64(len(baseline))
+		n        = n1 + n2
+		u        = rankSum - n1*(n1+1)/2
+		mean     = n1 * n2 / 2
+		variance = n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1)))
+	)
+	if variance <= 0 {
+		// All values are the same.
+		return 1
+	}
+	sd := math.Sqrt(variance)
+	// The continuity correction is applied since U is discrete.
+	greater := 0.5 * math.Erfc((u-mean-0.5)/sd/math.Sqrt2)
+	less := 0.5 * math.Erfc((mean-u-0.5)/sd/math.Sqrt2)
+
+	switch direction {
+	case config.AnalysisDynamicDirectionDecrease:
+		return less
+	case config.AnalysisDynamicDirectionEither:
+		return math.Min(1, 2*math.Min(greater, less))
+	default:
+		return greater
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestMannWhitneyPValue(t *testing.T) {
+	low := []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	high := []float64{10, 11, 12, 13, 14, 15, 16, 17, 18, 19}
+	mixed := []float64{0.5, 1.5, 2.5, 3.5, 4.5, 5.5, 6.5, 7.5, 8.5, 9.5}
+
+	testcases := []struct {
+		name       string
+		canary     []float64
+		baseline   []float64
+		direction  config.AnalysisDynamicDirection
+		regression bool
+	}{
+		{
+			name:       "canary increased",
+			canary:     high,
+			baseline:   low,
+			direction:  config.AnalysisDynamicDirectionIncrease,
+			regression: true,
+		},
+		{
+			name:       "canary decreased but only increase is regression",
+			canary:     low,
+			baseline:   high,
+			direction:  config.AnalysisDynamicDirectionIncrease,
+			regression: false,
+		},
+		{
+			name:       "canary decreased",
+			canary:     low,
+			baseline:   high,
+			direction:  config.AnalysisDynamicDirectionDecrease,
+			regression: true,
+		},
+		{
+			name:       "canary changed in either direction",
+			canary:     low,
+			baseline:   high,
+			direction:  config.AnalysisDynamicDirectionEither,
+			regression: true,
+		},
+		{
+			name:       "similar distributions",
+			canary:     mixed,
+			baseline:   low,
+			direction:  config.AnalysisDynamicDirectionEither,
+			regression: false,
+		},
+		{
+			name:       "all values are the same",
+			canary:     []float64{1, 1, 1},
+			baseline:   []float64{1, 1, 1},
+			direction:  config.AnalysisDynamicDirectionIncrease,
+			regression: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := mannWhitneyPValue(tc.canary, tc.baseline, tc.direction)
+			assert.Equal(t, tc.regression, p < 0.05, "p-value: %f", p)
+		})
+	}
+}
+
+func TestComputeScore(t *testing.T) {
+	testcases := []struct {
+		name     string
+		results  []dynamicMetricsResult
+		expected float64
+		wantErr  bool
+	}{
+		{
+			name:    "no result",
+			wantErr: true,
+		},
+		{
+			name: "only no data",
+			results: []dynamicMetricsResult{
+				{id: "a", weight: 1, noData: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "weighted",
+			results: []dynamicMetricsResult{
+				{id: "a", weight: 3},
+				{id: "b", weight: 1, regression: true},
+				{id: "c", weight: 5, noData: true},
+			},
+			expected: 75,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			score, err := computeScore(tc.results)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected, score)
+		})
+	}
+}
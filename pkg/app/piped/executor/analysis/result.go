@@ -0,0 +1,136 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// Limit the number of data points kept for each series
+// to bound the size of the persisted result.
+const maxPointsPerSeries = 1000
+
+// resultRecorder accumulates the data points and the evaluation outcomes
+// of all queries performed during the analysis.
+type resultRecorder struct {
+	mu      sync.Mutex
+	result  *model.AnalysisResult
+	queries map[string]*model.AnalysisQueryResult
+}
+
+func newResultRecorder(deploymentID, stageID string, startTime time.Time) *resultRecorder {
+	return &resultRecorder{
+		result: &model.AnalysisResult{
+			DeploymentId: deploymentID,
+			StageId:      stageID,
+			CreatedAt:    startTime.Unix(),
+			UpdatedAt:    startTime.Unix(),
+		},
+		queries: make(map[string]*model.AnalysisQueryResult),
+	}
+}
+
+// addQuery registers a query to record its data.
+func (r *resultRecorder) addQuery(id, providerType, query string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	q := &model.AnalysisQueryResult{
+		Id:           id,
+		ProviderType: providerType,
+		Query:        query,
+	}
+	r.queries[id] = q
+	r.result.Queries = append(r.result.Queries, q)
+}
+
+// recordPoints appends the values returned by the query performed at the given time.
+func (r *resultRecorder) recordPoints(id, variant string, at time.Time, values []float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	q, ok := r.queries[id]
+	if !ok {
+		return
+	}
+	var series *model.AnalysisDataSeries
+	for _, s := range q.Series {
+		if s.Variant == variant {
+			series = s
+			break
+		}
+	}
+	if series == nil {
+		series = &model.AnalysisDataSeries{Variant: variant}
+		q.Series = append(q.Series, series)
+	}
+	for _, v := range values {
+		series.Points = append(series.Points, &model.AnalysisDataPoint{
+			Timestamp: at.Unix(),
+			Value:     v,
+		})
+	}
+	if n := len(series.Points); n > maxPointsPerSeries {
+		series.Points = series.Points[n-maxPointsPerSeries:]
+	}
+	r.result.UpdatedAt = at.Unix()
+}
+
+// recordEvaluation appends the outcome of an evaluation performed at the given time.
+func (r *resultRecorder) recordEvaluation(id string, at time.Time, expected bool, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	q, ok := r.queries[id]
+	if !ok {
+		return
+	}
+	q.Evaluations = append(q.Evaluations, &model.AnalysisEvaluation{
+		Timestamp: at.Unix(),
+		Expected:  expected,
+		Reason:    reason,
+	})
+	r.result.UpdatedAt = at.Unix()
+}
+
+func (r *resultRecorder) setScore(score float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result.Score = score
+}
+
+// snapshot returns a copy of the recorded result.
+func (r *resultRecorder) snapshot() *model.AnalysisResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return proto.Clone(r.result).(*model.AnalysisResult)
+}
+
+// recordingEvaluator records all values passed to the underlying evaluator.
+type recordingEvaluator struct {
+	metrics.Evaluator
+	values []float64
+}
+
+func (e *recordingEvaluator) InRange(value float64) bool {
+	e.values = append(e.values, value)
+	return e.Evaluator.InRange(value)
+}
@@ -0,0 +1,75 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultRecorder(t *testing.T) {
+	start := time.Unix(1617000000, 0)
+	r := newResultRecorder("deployment-1", "stage-1", start)
+	r.addQuery("dynamic-metrics-0", "Prometheus", "errors")
+
+	at := start.Add(time.Minute)
+	r.recordPoints("dynamic-metrics-0", "canary", at, []float64{1, 2})
+	r.recordPoints("dynamic-metrics-0", "baseline", at, []float64{3})
+	r.recordPoints("dynamic-metrics-0", "canary", at.Add(time.Minute), []float64{4})
+	r.recordEvaluation("dynamic-metrics-0", at, true, "ok")
+	// Unknown queries are ignored.
+	r.recordPoints("unknown", "", at, []float64{5})
+	r.setScore(100)
+
+	result := r.snapshot()
+	assert.Equal(t, "deployment-1", result.DeploymentId)
+	assert.Equal(t, start.Unix(), result.CreatedAt)
+	assert.Equal(t, at.Unix(), result.UpdatedAt)
+	assert.Equal(t, 100.0, result.Score)
+	require.Len(t, result.Queries, 1)
+
+	q := result.Queries[0]
+	require.Len(t, q.Series, 2)
+	assert.Equal(t, "canary", q.Series[0].Variant)
+	assert.Len(t, q.Series[0].Points, 3)
+	assert.Equal(t, 4.0, q.Series[0].Points[2].Value)
+	assert.Equal(t, "baseline", q.Series[1].Variant)
+	assert.Len(t, q.Series[1].Points, 1)
+	require.Len(t, q.Evaluations, 1)
+	assert.True(t, q.Evaluations[0].Expected)
+
+	// The snapshot is not affected by the later records.
+	r.recordPoints("dynamic-metrics-0", "baseline", at, []float64{6})
+	assert.Len(t, q.Series[1].Points, 1)
+}
+
+func TestResultRecorderLimitPoints(t *testing.T) {
+	r := newResultRecorder("deployment-1", "stage-1", time.Now())
+	r.addQuery("metrics-0", "Prometheus", "errors")
+
+	values := make([]float64, maxPointsPerSeries+10)
+	for i := range values {
+		values[i] = float64(i)
+	}
+	r.recordPoints("metrics-0", "", time.Now(), values)
+
+	points := r.snapshot().Queries[0].Series[0].Points
+	require.Len(t, points, maxPointsPerSeries)
+	// The oldest points are dropped.
+	assert.Equal(t, 10.0, points[0].Value)
+}
@@ -0,0 +1,113 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// trafficGuard checks the request rate of the analyzed variant before starting the analysis
+// because the results of the variant receiving too few requests are not statistically meaningful.
+type trafficGuard struct {
+	cfg          *config.AnalysisTrafficGuard
+	provider     metrics.Provider
+	logger       *zap.Logger
+	logPersister executor.LogPersister
+}
+
+func (e *Executor) newTrafficGuard(cfg *config.AnalysisTrafficGuard) (*trafficGuard, error) {
+	templatable := &config.TemplatableAnalysisMetrics{
+		AnalysisMetrics: config.AnalysisMetrics{
+			Provider: cfg.Provider,
+			Timeout:  cfg.Timeout,
+		},
+	}
+	provider, err := e.newMetricsProvider(cfg.Provider, templatable)
+	if err != nil {
+		return nil, err
+	}
+	return &trafficGuard{
+		cfg:          cfg,
+		provider:     provider,
+		logger:       e.Logger.Named("traffic-guard"),
+		logPersister: e.LogPersister,
+	}, nil
+}
+
+// wait returns true as soon as the request rate reaches the minimum one.
+// When the action for low traffic is EXTEND, the request rate is checked at the configured interval
+// until the max extension elapsed. Otherwise it is checked only once.
+// The returned reason explains why the analysis should be skipped when false.
+func (g *trafficGuard) wait(ctx context.Context) (bool, string, error) {
+	var (
+		extend   = g.cfg.GetOnLowTraffic() == config.AnalysisLowTrafficActionExtend
+		interval = g.cfg.GetInterval().Duration()
+		deadline = time.Now().Add(g.cfg.GetMaxExtension().Duration())
+		ticker   = time.NewTicker(interval)
+	)
+	defer ticker.Stop()
+
+	for {
+		enough, reason, err := g.check(ctx, interval)
+		if err != nil {
+			return false, "", err
+		}
+		if enough {
+			g.logPersister.Successf("[traffic-guard] The analyzed variant is receiving enough traffic: %s", reason)
+			return true, reason, nil
+		}
+		if !extend || !time.Now().Add(interval).Before(deadline) {
+			return false, reason, nil
+		}
+		g.logPersister.Infof("[traffic-guard] Postponing the analysis since the traffic is too low: %s", reason)
+
+		select {
+		case <-ctx.Done():
+			return false, "", ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// check runs the guard query over the last interval and reports whether
+// all returned request rates are greater than or equal to the minimum one.
+func (g *trafficGuard) check(ctx context.Context, interval time.Duration) (bool, string, error) {
+	now := time.Now()
+	queryRange := metrics.QueryRange{
+		From: now.Add(-interval),
+		To:   now,
+	}
+	min := g.cfg.MinRequestRate
+	evaluator := &config.AnalysisExpected{Min: &min}
+
+	enough, reason, err := g.provider.Evaluate(ctx, g.cfg.Query, queryRange, evaluator)
+	if errors.Is(err, metrics.ErrNoDataFound) {
+		return false, fmt.Sprintf("no request was found while the minimum request rate is %g", min), nil
+	}
+	if err != nil {
+		g.logger.Error("failed to run the guard query", zap.Error(err))
+		return false, "", fmt.Errorf("failed to run the guard query: %w", err)
+	}
+	return enough, reason, nil
+}
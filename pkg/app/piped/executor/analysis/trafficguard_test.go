@@ -0,0 +1,125 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+type fakeLogPersister struct{}
+
+func (l *fakeLogPersister) Write(_ []byte) (int, error)         { return 0, nil }
+func (l *fakeLogPersister) Info(_ string)                       {}
+func (l *fakeLogPersister) Infof(_ string, _ ...interface{})    {}
+func (l *fakeLogPersister) Success(_ string)                    {}
+func (l *fakeLogPersister) Successf(_ string, _ ...interface{}) {}
+func (l *fakeLogPersister) Error(_ string)                      {}
+func (l *fakeLogPersister) Errorf(_ string, _ ...interface{})   {}
+
+// fakeRateProvider returns the given request rates in order, then repeats the last one.
+type fakeRateProvider struct {
+	rates []float64
+	calls int
+}
+
+func (p *fakeRateProvider) Type() string { return "fake" }
+
+func (p *fakeRateProvider) Evaluate(_ context.Context, _ string, _ metrics.QueryRange, evaluator metrics.Evaluator) (bool, string, error) {
+	i := p.calls
+	if i >= len(p.rates) {
+		i = len(p.rates) - 1
+	}
+	p.calls++
+	if p.rates[i] < 0 {
+		return false, "", metrics.ErrNoDataFound
+	}
+	return evaluator.InRange(p.rates[i]), "", nil
+}
+
+func TestTrafficGuardWait(t *testing.T) {
+	testcases := []struct {
+		name      string
+		rates     []float64
+		action    config.AnalysisLowTrafficAction
+		want      bool
+		wantCalls int
+	}{
+		{
+			name:      "enough traffic",
+			rates:     []float64{5},
+			want:      true,
+			wantCalls: 1,
+		},
+		{
+			name:      "skipped at once",
+			rates:     []float64{0.5, 5},
+			action:    config.AnalysisLowTrafficActionSkip,
+			want:      false,
+			wantCalls: 1,
+		},
+		{
+			name:      "no data is considered as low traffic",
+			rates:     []float64{-1},
+			want:      false,
+			wantCalls: 1,
+		},
+		{
+			name:      "extended until the traffic became enough",
+			rates:     []float64{0.5, -1, 5},
+			action:    config.AnalysisLowTrafficActionExtend,
+			want:      true,
+			wantCalls: 3,
+		},
+		{
+			name:   "skipped after the max extension",
+			rates:  []float64{0.5},
+			action: config.AnalysisLowTrafficActionExtend,
+			want:   false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := &fakeRateProvider{rates: tc.rates}
+			g := &trafficGuard{
+				cfg: &config.AnalysisTrafficGuard{
+					Provider:       "fake",
+					Query:          "requests",
+					MinRequestRate: 1,
+					OnLowTraffic:   tc.action,
+					Interval:       config.Duration(10 * time.Millisecond),
+					MaxExtension:   config.Duration(100 * time.Millisecond),
+				},
+				provider:     provider,
+				logger:       zap.NewNop(),
+				logPersister: &fakeLogPersister{},
+			}
+			got, _, err := g.wait(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+			if tc.wantCalls > 0 {
+				assert.Equal(t, tc.wantCalls, provider.calls)
+			}
+		})
+	}
+}
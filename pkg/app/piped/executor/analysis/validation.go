@@ -0,0 +1,136 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/log"
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// defaultQueryValidationTimeout is used for the queries whose timeout was not configured.
+const defaultQueryValidationTimeout = 30 * time.Second
+
+// ValidateQueries checks the metrics and log queries of an ANALYSIS stage before running it
+// so that a broken query fails the deployment at planning instead of in the middle of the analysis.
+// All templates are rendered and validated in the same way as the stage execution,
+// and then the rendered queries are sent to the providers supporting the query validation.
+// The queries which could not be validated, e.g. because the provider was unreachable, are just logged.
+func ValidateQueries(ctx context.Context, options *config.AnalysisStageOptions, appName string, cfg *config.Config, repoDir string, pipedConfig *config.PipedSpec, logger *zap.Logger) error {
+	templateCfg, err := config.LoadAnalysisTemplate(repoDir)
+	if errors.Is(err, config.ErrNotFound) {
+		templateCfg = &config.AnalysisTemplateSpec{}
+	} else if err != nil {
+		return err
+	}
+
+	// The executor is only used to share the rendering of templates and the creation of providers.
+	e := &Executor{
+		Input: executor.Input{
+			Application: &model.Application{Name: appName},
+			PipedConfig: pipedConfig,
+			Logger:      logger,
+		},
+		repoDir: repoDir,
+		config:  cfg,
+	}
+
+	for i := range options.Metrics {
+		id := fmt.Sprintf("metrics-%d", i)
+		metricsCfg, err := e.getMetricsConfig(&options.Metrics[i], templateCfg, options.Metrics[i].Template.Args)
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		templatable := options.Metrics[i]
+		if templatable.Timeout == 0 {
+			templatable.Timeout = config.Duration(defaultQueryValidationTimeout)
+		}
+		provider, err := e.newMetricsProvider(metricsCfg.Provider, &templatable)
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		v, ok := provider.(metrics.QueryValidator)
+		if !ok {
+			continue
+		}
+		if err := v.ValidateQuery(ctx, metricsCfg.Query); err != nil {
+			if errors.Is(err, metrics.ErrInvalidQuery) {
+				return fmt.Errorf("%s: %s rejected the query %q (%w)", id, provider.Type(), metricsCfg.Query, err)
+			}
+			logger.Warn("unable to validate the query", zap.String("id", id), zap.Error(err))
+		}
+	}
+
+	for i := range options.Logs {
+		id := fmt.Sprintf("log-%d", i)
+		logCfg, err := e.getLogConfig(&options.Logs[i], templateCfg, options.Logs[i].Template.Args)
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		templatable := options.Logs[i]
+		if templatable.Timeout == 0 {
+			templatable.Timeout = config.Duration(defaultQueryValidationTimeout)
+		}
+		provider, err := e.newLogProvider(logCfg.Provider, &templatable)
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		v, ok := provider.(log.QueryValidator)
+		if !ok {
+			continue
+		}
+		if err := v.ValidateQuery(ctx, logCfg.Query); err != nil {
+			if errors.Is(err, log.ErrInvalidQuery) {
+				return fmt.Errorf("%s: %s rejected the query %q (%w)", id, provider.Type(), logCfg.Query, err)
+			}
+			logger.Warn("unable to validate the query", zap.String("id", id), zap.Error(err))
+		}
+	}
+
+	if options.TrafficGuard != nil {
+		g := *options.TrafficGuard
+		if g.Timeout == 0 {
+			g.Timeout = config.Duration(defaultQueryValidationTimeout)
+		}
+		guard, err := e.newTrafficGuard(&g)
+		if err != nil {
+			return fmt.Errorf("traffic-guard: %w", err)
+		}
+		if v, ok := guard.provider.(metrics.QueryValidator); ok {
+			if err := v.ValidateQuery(ctx, g.Query); err != nil {
+				if errors.Is(err, metrics.ErrInvalidQuery) {
+					return fmt.Errorf("traffic-guard: %s rejected the query %q (%w)", guard.provider.Type(), g.Query, err)
+				}
+				logger.Warn("unable to validate the query", zap.String("id", "traffic-guard"), zap.Error(err))
+			}
+		}
+	}
+
+	for i := range options.Https {
+		if _, err := e.getHTTPConfig(&options.Https[i], templateCfg, options.Https[i].Template.Args); err != nil {
+			return fmt.Errorf("http-%d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestValidateQueries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("query") == "up" {
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+	}))
+	defer server.Close()
+
+	pipedConfig := &config.PipedSpec{
+		AnalysisProviders: []config.PipedAnalysisProvider{
+			{
+				Name: "prometheus-dev",
+				Type: model.AnalysisProviderPrometheus,
+				PrometheusConfig: &config.AnalysisProviderPrometheusConfig{
+					Address: server.URL,
+				},
+			},
+		},
+	}
+	max := 0.1
+	makeOptions := func(provider, query string) *config.AnalysisStageOptions {
+		return &config.AnalysisStageOptions{
+			Metrics: []config.TemplatableAnalysisMetrics{
+				{
+					AnalysisMetrics: config.AnalysisMetrics{
+						Provider: provider,
+						Query:    query,
+						Expected: config.AnalysisExpected{Max: &max},
+						Interval: config.Duration(60),
+					},
+				},
+			},
+		}
+	}
+
+	testcases := []struct {
+		name         string
+		options      *config.AnalysisStageOptions
+		wantErr      bool
+		invalidQuery bool
+	}{
+		{
+			name:    "valid query",
+			options: makeOptions("prometheus-dev", "up"),
+		},
+		{
+			name:         "invalid query",
+			options:      makeOptions("prometheus-dev", "up{"),
+			wantErr:      true,
+			invalidQuery: true,
+		},
+		{
+			name:    "missing query",
+			options: makeOptions("prometheus-dev", ""),
+			wantErr: true,
+		},
+		{
+			name:    "unknown provider",
+			options: makeOptions("unknown", "up"),
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateQueries(context.Background(), tc.options, "app", &config.Config{}, t.TempDir(), pipedConfig, zap.NewNop())
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.invalidQuery, errors.Is(err, metrics.ErrInvalidQuery))
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ansible
+
+import (
+	"context"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/ansible"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type registerer interface {
+	Register(stage model.Stage, f executor.Factory) error
+	RegisterRollback(kind model.ApplicationKind, f executor.Factory) error
+}
+
+func Register(r registerer) {
+	f := func(in executor.Input) executor.Executor {
+		return &deployExecutor{
+			Input: in,
+		}
+	}
+	r.Register(model.StageAnsibleSync, f)
+	r.Register(model.StageAnsibleCheck, f)
+
+	r.RegisterRollback(model.ApplicationKind_ANSIBLE, func(in executor.Input) executor.Executor {
+		return &rollbackExecutor{
+			Input: in,
+		}
+	})
+}
+
+// preparePlaybook returns a command to run the playbook of the given application
+// against the hosts of its inventory.
+func preparePlaybook(ctx context.Context, in *executor.Input, appDir string, deployCfg *config.AnsibleDeploymentSpec) (*provider.Ansible, bool) {
+	cloudProviderCfg, found := findCloudProvider(in)
+	if !found {
+		return nil, false
+	}
+
+	playbookPath, err := provider.FindPlaybookCommand(cloudProviderCfg)
+	if err != nil {
+		in.LogPersister.Errorf("Unable to find ansible-playbook command (%v)", err)
+		return nil, false
+	}
+
+	cmd := provider.NewAnsible(
+		playbookPath,
+		appDir,
+		deployCfg.Input.Playbook,
+		deployCfg.Input.Inventory,
+		provider.WithExtraVars(deployCfg.Input.ExtraVars),
+		provider.WithLimit(deployCfg.Input.Limit),
+		provider.WithTags(deployCfg.Input.Tags),
+		provider.WithCloudProviderConfig(cloudProviderCfg),
+	)
+
+	version, err := cmd.Version(ctx)
+	if err != nil {
+		in.LogPersister.Errorf("Failed to check ansible version (%v)", err)
+		return nil, false
+	}
+	in.LogPersister.Infof("Using %q to run the playbook", version)
+
+	return cmd, true
+}
+
+func findCloudProvider(in *executor.Input) (cfg *config.CloudProviderAnsibleConfig, found bool) {
+	name := in.Application.CloudProvider
+	if name == "" {
+		in.LogPersister.Error("Missing the CloudProvider name in the application configuration")
+		return
+	}
+
+	cp, ok := in.PipedConfig.FindCloudProvider(name, model.CloudProviderAnsible)
+	if !ok {
+		in.LogPersister.Errorf("The specified cloud provider %q was not found in piped configuration", name)
+		return
+	}
+
+	cfg = cp.AnsibleConfig
+	found = true
+	return
+}
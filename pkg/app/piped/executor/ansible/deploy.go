@@ -0,0 +1,93 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ansible
+
+import (
+	"context"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/ansible"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type deployExecutor struct {
+	executor.Input
+}
+
+func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	ctx := sig.Context()
+	ds, err := e.TargetDSP.Get(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	deployCfg := ds.DeploymentConfig.AnsibleDeploymentSpec
+	if deployCfg == nil {
+		e.LogPersister.Error("Malformed deployment configuration: missing AnsibleDeploymentSpec")
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	var (
+		originalStatus = e.Stage.Status
+		status         model.StageStatus
+	)
+
+	cmd, ok := preparePlaybook(ctx, &e.Input, ds.AppDir, deployCfg)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	switch model.Stage(e.Stage.Name) {
+	case model.StageAnsibleSync:
+		status = e.ensureSync(ctx, cmd)
+
+	case model.StageAnsibleCheck:
+		status = e.ensureCheck(ctx, cmd)
+
+	default:
+		e.LogPersister.Errorf("Unsupported stage %s for ansible application", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+}
+
+func (e *deployExecutor) ensureSync(ctx context.Context, cmd *provider.Ansible) model.StageStatus {
+	result, err := cmd.Run(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to run playbook (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully ran playbook: %s", result)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) ensureCheck(ctx context.Context, cmd *provider.Ansible) model.StageStatus {
+	result, err := cmd.Check(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to run playbook in check mode (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	if result.NoChanges() {
+		e.LogPersister.Success("No changes to apply")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	e.LogPersister.Successf("Detected %s.", result)
+	return model.StageStatus_STAGE_SUCCESS
+}
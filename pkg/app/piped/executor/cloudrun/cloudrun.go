@@ -16,6 +16,10 @@ package cloudrun
 
 import (
 	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudrun"
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
@@ -37,6 +41,8 @@ func Register(r registerer) {
 	}
 	r.Register(model.StageCloudRunSync, f)
 	r.Register(model.StageCloudRunPromote, f)
+	r.Register(model.StageCloudRunJobRun, f)
+	r.Register(model.StageCloudRunRevisionTag, f)
 
 	r.RegisterRollback(model.ApplicationKind_CLOUDRUN, func(in executor.Input) executor.Executor {
 		return &rollbackExecutor{
@@ -109,6 +115,11 @@ func configureServiceManifest(in *executor.Input, sm provider.ServiceManifest, r
 
 func apply(ctx context.Context, in *executor.Input, cloudProviderName string, cloudProviderCfg *config.CloudProviderCloudRunConfig, sm provider.ServiceManifest) bool {
 	in.LogPersister.Info("Start applying the service manifest")
+	sm.AddLabels(map[string]string{
+		provider.LabelManagedBy:   provider.ManagedByPiped,
+		provider.LabelApplication: in.Deployment.ApplicationId,
+	})
+
 	client, err := provider.DefaultRegistry().Client(ctx, cloudProviderName, cloudProviderCfg, in.Logger)
 	if err != nil {
 		in.LogPersister.Errorf("Unable to create ClourRun client for the provider (%v)", err)
@@ -136,3 +147,23 @@ func apply(ctx context.Context, in *executor.Input, cloudProviderName string, cl
 	in.LogPersister.Infof("Successfully created the service %s", sm.Name)
 	return true
 }
+
+// saveStageResults publishes the deployed revision and the traffic configuration
+// as the results of the current stage.
+func saveStageResults(ctx context.Context, in *executor.Input, revision string, traffics []provider.RevisionTraffic) {
+	parts := make([]string, 0, len(traffics))
+	for _, t := range traffics {
+		// The targets only for revision tags receive no traffic.
+		if t.Percent == 0 && t.Tag != "" {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d%%", t.RevisionName, t.Percent))
+	}
+	results := map[string]string{
+		executor.StageResultRevision: revision,
+		executor.StageResultTraffic:  strings.Join(parts, ", "),
+	}
+	if err := in.MetadataStore.SetStageResults(ctx, in.Stage.Id, results); err != nil {
+		in.Logger.Error("failed to save stage results", zap.Error(err))
+	}
+}
@@ -71,6 +71,12 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 	case model.StageCloudRunPromote:
 		status = e.ensurePromote(ctx)
 
+	case model.StageCloudRunJobRun:
+		status = e.ensureJobRun(ctx)
+
+	case model.StageCloudRunRevisionTag:
+		status = e.ensureRevisionTag(ctx)
+
 	default:
 		e.LogPersister.Errorf("Unsupported stage %s for cloudrun application", e.Stage.Name)
 		return model.StageStatus_STAGE_FAILURE
@@ -103,6 +109,7 @@ func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
 	if !apply(ctx, &e.Input, e.cloudProviderName, e.cloudProviderCfg, sm) {
 		return model.StageStatus_STAGE_FAILURE
 	}
+	saveStageResults(ctx, &e.Input, revision, traffics)
 
 	return model.StageStatus_STAGE_SUCCESS
 }
@@ -121,29 +128,7 @@ func (e *deployExecutor) ensurePromote(ctx context.Context) model.StageStatus {
 	}
 
 	// Loaded the last deployed data.
-	if e.Deployment.RunningCommitHash == "" {
-		e.LogPersister.Errorf("Unable to determine the last deployed commit")
-		return model.StageStatus_STAGE_FAILURE
-	}
-
-	runningDS, err := e.RunningDSP.GetReadOnly(ctx, e.LogPersister)
-	if err != nil {
-		e.LogPersister.Errorf("Failed to prepare running deploy source data (%v)", err)
-		return model.StageStatus_STAGE_FAILURE
-	}
-
-	runningDeployCfg := runningDS.DeploymentConfig.CloudRunDeploymentSpec
-	if runningDeployCfg == nil {
-		e.LogPersister.Error("Malformed deployment configuration in running commit: missing CloudRunDeploymentSpec")
-		return model.StageStatus_STAGE_FAILURE
-	}
-
-	lastDeployedSM, ok := loadServiceManifest(&e.Input, runningDeployCfg.Input.ServiceManifestFile, runningDS)
-	if !ok {
-		return model.StageStatus_STAGE_FAILURE
-	}
-
-	lastDeployedRevision, ok := decideRevisionName(&e.Input, lastDeployedSM, e.Deployment.RunningCommitHash)
+	lastDeployedRevision, ok := e.decideRunningRevision(ctx)
 	if !ok {
 		return model.StageStatus_STAGE_FAILURE
 	}
@@ -169,6 +154,7 @@ func (e *deployExecutor) ensurePromote(ctx context.Context) model.StageStatus {
 			Percent:      100 - options.Percent.Int(),
 		},
 	}
+	traffics = e.keepRevisionTags(ctx, sm.Name, traffics)
 	if !configureServiceManifest(&e.Input, sm, revision, traffics) {
 		return model.StageStatus_STAGE_FAILURE
 	}
@@ -176,6 +162,7 @@ func (e *deployExecutor) ensurePromote(ctx context.Context) model.StageStatus {
 	if !apply(ctx, &e.Input, e.cloudProviderName, e.cloudProviderCfg, sm) {
 		return model.StageStatus_STAGE_FAILURE
 	}
+	saveStageResults(ctx, &e.Input, revision, traffics)
 
 	// TODO: Wait to ensure the traffic was fully configured.
 	return model.StageStatus_STAGE_SUCCESS
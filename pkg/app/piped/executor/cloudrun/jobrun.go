@@ -0,0 +1,197 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudrun"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const executionMetadataKey = "job-execution"
+
+var executionPollInterval = 10 * time.Second
+
+type executionWatcher interface {
+	GetExecution(ctx context.Context, name string) (*provider.Execution, error)
+	ListExecutionLogs(ctx context.Context, executionName string, since time.Time) ([]*provider.LogEntry, error)
+}
+
+func (e *deployExecutor) ensureJobRun(ctx context.Context) model.StageStatus {
+	options := e.StageConfig.CloudRunJobRunStageOptions
+	if options == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	client, err := provider.DefaultRegistry().Client(ctx, e.cloudProviderName, e.cloudProviderCfg, e.Logger)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to create ClourRun client for the provider (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// Continue waiting for the execution started by the previous run of this stage
+	// instead of executing the job again.
+	if metadata, ok := e.MetadataStore.GetStageMetadata(e.Stage.Id); ok && metadata[executionMetadataKey] != "" {
+		name := metadata[executionMetadataKey]
+		e.LogPersister.Infof("Resuming to wait for the execution %s", name)
+		return waitExecution(ctx, &e.Input, client, name, !options.SkipLogs)
+	}
+
+	jm, ok := loadJobManifest(&e.Input, options.JobManifestFile, e.deploySource)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if !applyJob(ctx, &e.Input, client, jm) {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	execution, err := client.RunJob(ctx, jm.Name)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to execute the job %s (%v)", jm.Name, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if execution.Metadata == nil || execution.Metadata.Name == "" {
+		e.LogPersister.Errorf("Unable to determine the execution of the job %s", jm.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	name := execution.Metadata.Name
+	e.LogPersister.Infof("Successfully started the execution %s of the job %s", name, jm.Name)
+
+	metadata := map[string]string{
+		executionMetadataKey: name,
+	}
+	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
+		e.Logger.Error("failed to save job execution to metadata", zap.Error(err))
+	}
+	results := map[string]string{
+		executor.StageResultJobExecution: name,
+	}
+	if err := e.MetadataStore.SetStageResults(ctx, e.Stage.Id, results); err != nil {
+		e.Logger.Error("failed to save stage results", zap.Error(err))
+	}
+
+	return waitExecution(ctx, &e.Input, client, name, !options.SkipLogs)
+}
+
+func loadJobManifest(in *executor.Input, jobManifestFile string, ds *deploysource.DeploySource) (provider.JobManifest, bool) {
+	in.LogPersister.Infof("Loading job manifest at the %s commit (%s)", ds.RevisionName, ds.Revision)
+
+	jm, err := provider.LoadJobManifest(ds.AppDir, jobManifestFile)
+	if err != nil {
+		in.LogPersister.Errorf("Failed to load job manifest (%v)", err)
+		return provider.JobManifest{}, false
+	}
+
+	in.LogPersister.Infof("Successfully loaded the job manifest at the %s commit", ds.RevisionName)
+	return jm, true
+}
+
+func applyJob(ctx context.Context, in *executor.Input, client provider.Client, jm provider.JobManifest) bool {
+	in.LogPersister.Info("Start applying the job manifest")
+	jm.AddLabels(map[string]string{
+		provider.LabelManagedBy:   provider.ManagedByPiped,
+		provider.LabelApplication: in.Deployment.ApplicationId,
+	})
+
+	_, err := client.UpdateJob(ctx, jm)
+	if err == nil {
+		in.LogPersister.Infof("Successfully updated the job %s", jm.Name)
+		return true
+	}
+
+	if err != provider.ErrJobNotFound {
+		in.LogPersister.Errorf("Failed to update the job %s (%v)", jm.Name, err)
+		return false
+	}
+
+	in.LogPersister.Infof("Job %s was not found, a new job will be created", jm.Name)
+
+	if _, err := client.CreateJob(ctx, jm); err != nil {
+		in.LogPersister.Errorf("Failed to create the job %s (%v)", jm.Name, err)
+		return false
+	}
+
+	in.LogPersister.Infof("Successfully created the job %s", jm.Name)
+	return true
+}
+
+// waitExecution waits until the given execution is completed
+// while streaming the logs written by its tasks to the stage log.
+func waitExecution(ctx context.Context, in *executor.Input, client executionWatcher, name string, streamLogs bool) model.StageStatus {
+	var (
+		lastLogTime time.Time
+		ticker      = time.NewTicker(executionPollInterval)
+	)
+	defer ticker.Stop()
+
+	streamNewLogs := func() {
+		if !streamLogs {
+			return
+		}
+		entries, err := client.ListExecutionLogs(ctx, name, lastLogTime)
+		if err != nil {
+			in.Logger.Warn("failed to list logs of job execution", zap.String("execution", name), zap.Error(err))
+			return
+		}
+		for _, entry := range entries {
+			in.LogPersister.Info(entry.Message)
+			if entry.Timestamp.After(lastLogTime) {
+				lastLogTime = entry.Timestamp
+			}
+		}
+	}
+
+	for {
+		streamNewLogs()
+
+		execution, err := client.GetExecution(ctx, name)
+		if err != nil {
+			in.LogPersister.Errorf("Failed to get the execution %s (%v)", name, err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		if condition, completed := execution.Completed(); completed {
+			// The logs may be written until right before the completion.
+			streamNewLogs()
+			summary := executionSummary(execution)
+			if execution.Succeeded() {
+				in.LogPersister.Successf("The execution %s was completed successfully (%s)", name, summary)
+				return model.StageStatus_STAGE_SUCCESS
+			}
+			in.LogPersister.Errorf("The execution %s failed (%s): %s", name, summary, condition.Message)
+			return model.StageStatus_STAGE_FAILURE
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+}
+
+func executionSummary(e *provider.Execution) string {
+	if e.Status == nil {
+		return "no task status"
+	}
+	return fmt.Sprintf("succeeded: %d, failed: %d, cancelled: %d", e.Status.SucceededCount, e.Status.FailedCount, e.Status.CancelledCount)
+}
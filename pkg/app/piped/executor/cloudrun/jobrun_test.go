@@ -0,0 +1,143 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/api/run/v1"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudrun"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeLogPersister struct {
+	logs []string
+}
+
+func (l *fakeLogPersister) Write(_ []byte) (int, error) { return 0, nil }
+func (l *fakeLogPersister) Info(log string)             { l.logs = append(l.logs, log) }
+func (l *fakeLogPersister) Infof(f string, a ...interface{}) {
+	l.logs = append(l.logs, fmt.Sprintf(f, a...))
+}
+func (l *fakeLogPersister) Success(log string) { l.logs = append(l.logs, log) }
+func (l *fakeLogPersister) Successf(f string, a ...interface{}) {
+	l.logs = append(l.logs, fmt.Sprintf(f, a...))
+}
+func (l *fakeLogPersister) Error(log string) { l.logs = append(l.logs, log) }
+func (l *fakeLogPersister) Errorf(f string, a ...interface{}) {
+	l.logs = append(l.logs, fmt.Sprintf(f, a...))
+}
+
+// fakeExecutionWatcher returns the given executions in order, one per call.
+type fakeExecutionWatcher struct {
+	executions []*provider.Execution
+	logs       []*provider.LogEntry
+}
+
+func (w *fakeExecutionWatcher) GetExecution(_ context.Context, _ string) (*provider.Execution, error) {
+	e := w.executions[0]
+	if len(w.executions) > 1 {
+		w.executions = w.executions[1:]
+	}
+	return e, nil
+}
+
+func (w *fakeExecutionWatcher) ListExecutionLogs(_ context.Context, _ string, since time.Time) ([]*provider.LogEntry, error) {
+	var entries []*provider.LogEntry
+	for _, l := range w.logs {
+		if l.Timestamp.After(since) {
+			entries = append(entries, l)
+		}
+	}
+	return entries, nil
+}
+
+func TestWaitExecution(t *testing.T) {
+	executionPollInterval = time.Millisecond
+
+	running := &provider.Execution{
+		Status: &provider.ExecutionStatus{
+			Conditions: []*run.GoogleCloudRunV1Condition{{Type: "Completed", Status: "Unknown"}},
+		},
+	}
+	succeeded := &provider.Execution{
+		Status: &provider.ExecutionStatus{
+			Conditions:     []*run.GoogleCloudRunV1Condition{{Type: "Completed", Status: "True"}},
+			SucceededCount: 1,
+		},
+	}
+	failed := &provider.Execution{
+		Status: &provider.ExecutionStatus{
+			Conditions:  []*run.GoogleCloudRunV1Condition{{Type: "Completed", Status: "False", Message: "task failed"}},
+			FailedCount: 1,
+		},
+	}
+	now := time.Now()
+	logs := []*provider.LogEntry{
+		{Timestamp: now, Message: "migrating"},
+		{Timestamp: now.Add(time.Second), Message: "migrated"},
+	}
+
+	testcases := []struct {
+		name         string
+		executions   []*provider.Execution
+		streamLogs   bool
+		expected     model.StageStatus
+		expectedLogs []string
+	}{
+		{
+			name:       "succeeded with logs",
+			executions: []*provider.Execution{running, running, succeeded},
+			streamLogs: true,
+			expected:   model.StageStatus_STAGE_SUCCESS,
+			expectedLogs: []string{
+				"migrating",
+				"migrated",
+				"The execution exec-1 was completed successfully (succeeded: 1, failed: 0, cancelled: 0)",
+			},
+		},
+		{
+			name:       "failed without logs",
+			executions: []*provider.Execution{running, failed},
+			expected:   model.StageStatus_STAGE_FAILURE,
+			expectedLogs: []string{
+				"The execution exec-1 failed (succeeded: 0, failed: 1, cancelled: 0): task failed",
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			lp := &fakeLogPersister{}
+			in := &executor.Input{
+				LogPersister: lp,
+				Logger:       zap.NewNop(),
+			}
+			w := &fakeExecutionWatcher{
+				executions: tc.executions,
+				logs:       logs,
+			}
+			got := waitExecution(context.Background(), in, w, "exec-1", tc.streamLogs)
+			assert.Equal(t, tc.expected, got)
+			assert.Equal(t, tc.expectedLogs, lp.logs)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudrun"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func (e *deployExecutor) ensureRevisionTag(ctx context.Context) model.StageStatus {
+	options := e.StageConfig.CloudRunRevisionTagStageOptions
+	if options == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	sm, ok := loadServiceManifest(&e.Input, e.deployCfg.Input.ServiceManifestFile, e.deploySource)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	assign := make(map[string]string, len(options.Assign))
+	for _, t := range options.Assign {
+		var (
+			revision string
+			ok       bool
+		)
+		if t.Revision == config.CloudRunRevisionTargetRunning {
+			revision, ok = e.decideRunningRevision(ctx)
+		} else {
+			revision, ok = decideRevisionName(&e.Input, sm, e.Deployment.Trigger.Commit.Hash)
+		}
+		if !ok {
+			return model.StageStatus_STAGE_FAILURE
+		}
+		assign[t.Tag] = revision
+	}
+
+	client, err := provider.DefaultRegistry().Client(ctx, e.cloudProviderName, e.cloudProviderCfg, e.Logger)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to create ClourRun client for the provider (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	svc, err := client.GetService(ctx, sm.Name)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to get the service %s (%v)", sm.Name, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	live, err := provider.ServiceManifestFromService(svc)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to build the manifest of the service %s (%v)", sm.Name, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	traffics, err := live.Traffic()
+	if err != nil {
+		e.LogPersister.Errorf("Unable to read the traffic of the service %s (%v)", sm.Name, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	traffics = provider.UpdateRevisionTags(traffics, assign, options.Remove)
+	if err := live.UpdateTraffic(traffics); err != nil {
+		e.LogPersister.Errorf("Unable to configure revision tags to service manifest (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	updated, err := client.Update(ctx, live)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to update the revision tags of the service %s (%v)", sm.Name, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	var serviceURL string
+	if updated.Status != nil {
+		serviceURL = updated.Status.Url
+	}
+	tags := make([]string, 0, len(assign))
+	for tag := range assign {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	urls := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		url := provider.TaggedURL(serviceURL, tag)
+		e.LogPersister.Successf("Successfully assigned the tag %s to the revision %s %s", tag, assign[tag], url)
+		if url != "" {
+			urls = append(urls, fmt.Sprintf("%s: %s", tag, url))
+		}
+	}
+	for _, tag := range options.Remove {
+		e.LogPersister.Successf("Successfully removed the tag %s", tag)
+	}
+	saveTaggedURLs(ctx, &e.Input, urls)
+
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// keepRevisionTags returns the given traffic targets along with the tags currently assigned to the service
+// so that applying the service manifest does not remove the tags assigned by CLOUDRUN_REVISION_TAG stages.
+func (e *deployExecutor) keepRevisionTags(ctx context.Context, serviceName string, traffics []provider.RevisionTraffic) []provider.RevisionTraffic {
+	client, err := provider.DefaultRegistry().Client(ctx, e.cloudProviderName, e.cloudProviderCfg, e.Logger)
+	if err != nil {
+		return traffics
+	}
+	svc, err := client.GetService(ctx, serviceName)
+	if err != nil || svc.Spec == nil {
+		return traffics
+	}
+
+	assign := make(map[string]string)
+	for _, t := range svc.Spec.Traffic {
+		if t.Tag != "" && t.RevisionName != "" {
+			assign[t.Tag] = t.RevisionName
+		}
+	}
+	if len(assign) == 0 {
+		return traffics
+	}
+	e.LogPersister.Infof("Keeping the revision tags currently assigned to the service %s", serviceName)
+	return provider.UpdateRevisionTags(traffics, assign, nil)
+}
+
+func (e *deployExecutor) decideRunningRevision(ctx context.Context) (string, bool) {
+	if e.Deployment.RunningCommitHash == "" {
+		e.LogPersister.Errorf("Unable to determine the last deployed commit")
+		return "", false
+	}
+
+	runningDS, err := e.RunningDSP.GetReadOnly(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare running deploy source data (%v)", err)
+		return "", false
+	}
+
+	runningDeployCfg := runningDS.DeploymentConfig.CloudRunDeploymentSpec
+	if runningDeployCfg == nil {
+		e.LogPersister.Error("Malformed deployment configuration in running commit: missing CloudRunDeploymentSpec")
+		return "", false
+	}
+
+	sm, ok := loadServiceManifest(&e.Input, runningDeployCfg.Input.ServiceManifestFile, runningDS)
+	if !ok {
+		return "", false
+	}
+	return decideRevisionName(&e.Input, sm, e.Deployment.RunningCommitHash)
+}
+
+func saveTaggedURLs(ctx context.Context, in *executor.Input, urls []string) {
+	if len(urls) == 0 {
+		return
+	}
+	results := map[string]string{
+		executor.StageResultURL: strings.Join(urls, ", "),
+	}
+	if err := in.MetadataStore.SetStageResults(ctx, in.Stage.Id, results); err != nil {
+		in.Logger.Error("failed to save stage results", zap.Error(err))
+	}
+}
@@ -157,7 +157,7 @@ func (e *deployExecutor) ensureTrafficRouting(ctx context.Context) model.StageSt
 		return model.StageStatus_STAGE_FAILURE
 	}
 
-	if !routing(ctx, &e.Input, e.cloudProviderName, e.cloudProviderCfg, *primary, *canary) {
+	if !routing(ctx, &e.Input, e.cloudProviderName, e.cloudProviderCfg, e.deployCfg.Input.ListenerRuleArns, *primary, *canary) {
 		return model.StageStatus_STAGE_FAILURE
 	}
 	return model.StageStatus_STAGE_SUCCESS
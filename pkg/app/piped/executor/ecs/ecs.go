@@ -317,7 +317,7 @@ func clean(ctx context.Context, in *executor.Input, cloudProviderName string, cl
 	return true
 }
 
-func routing(ctx context.Context, in *executor.Input, cloudProviderName string, cloudProviderCfg *config.CloudProviderECSConfig, primaryTargetGroup types.LoadBalancer, canaryTargetGroup types.LoadBalancer) bool {
+func routing(ctx context.Context, in *executor.Input, cloudProviderName string, cloudProviderCfg *config.CloudProviderECSConfig, listenerRuleArns []string, primaryTargetGroup types.LoadBalancer, canaryTargetGroup types.LoadBalancer) bool {
 	client, err := provider.DefaultRegistry().Client(cloudProviderName, cloudProviderCfg, in.Logger)
 	if err != nil {
 		in.LogPersister.Errorf("Unable to create ECS client for the provider %s: %v", cloudProviderName, err)
@@ -349,6 +349,18 @@ func routing(ctx context.Context, in *executor.Input, cloudProviderName string,
 		in.Logger.Error("Failed to store traffic routing config to metadata store", zap.Error(err))
 	}
 
+	// Modify only the specified listener rules to keep the others,
+	// e.g. the rules for testing, untouched.
+	if len(listenerRuleArns) > 0 {
+		for _, ruleArn := range listenerRuleArns {
+			if err := client.ModifyListenerRule(ctx, ruleArn, routingTrafficCfg); err != nil {
+				in.LogPersister.Errorf("Failed to routing traffic to CANARY variant by listener rule %s: %v", ruleArn, err)
+				return false
+			}
+		}
+		return true
+	}
+
 	currListenerArn, err := client.GetListener(ctx, primaryTargetGroup)
 	if err != nil {
 		in.LogPersister.Errorf("Failed to get current active listener: %v", err)
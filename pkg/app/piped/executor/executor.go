@@ -26,6 +26,17 @@ import (
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
+// Well-known keys of the stage results those are shown to users.
+const (
+	StageResultRevision     = "Revision"
+	StageResultTraffic      = "Traffic"
+	StageResultAnalysis     = "Analysis"
+	StageResultJobExecution = "Execution"
+	StageResultURL          = "URL"
+	StageResultFeatureFlag  = "Flag"
+	StageResultWarning      = "Warning"
+)
+
 type Executor interface {
 	// Execute starts running executor until completion
 	// or the StopSignal has emitted.
@@ -50,12 +61,32 @@ type MetadataStore interface {
 
 	GetStageMetadata(stageID string) (map[string]string, bool)
 	SetStageMetadata(ctx context.Context, stageID string, metadata map[string]string) error
+
+	// SetStageResults publishes the key results of the given stage
+	// such as the applied revision or the traffic percentages to show them to users.
+	SetStageResults(ctx context.Context, stageID string, results map[string]string) error
+}
+
+type AnalysisResultStore interface {
+	// PutAnalysisResult persists the detailed data collected while running an ANALYSIS stage
+	// to visualize it after the stage completed.
+	PutAnalysisResult(ctx context.Context, result *model.AnalysisResult) error
+}
+
+type ProvenanceStore interface {
+	// PutProvenance persists the record of what exactly was deployed
+	// to allow tracing the running state back to its sources.
+	PutProvenance(ctx context.Context, provenance *model.DeploymentProvenance) error
 }
 
 type CommandLister interface {
 	ListCommands() []model.ReportableCommand
 }
 
+type Notifier interface {
+	Notify(event model.NotificationEvent)
+}
+
 type AppLiveResourceLister interface {
 	ListKubernetesResources() ([]provider.Manifest, bool)
 }
@@ -66,14 +97,18 @@ type Input struct {
 	// Readonly deployment model.
 	Deployment            *model.Deployment
 	Application           *model.Application
+	EnvName               string
 	PipedConfig           *config.PipedSpec
 	TargetDSP             deploysource.Provider
 	RunningDSP            deploysource.Provider
 	CommandLister         CommandLister
 	LogPersister          LogPersister
 	MetadataStore         MetadataStore
+	AnalysisResultStore   AnalysisResultStore
+	ProvenanceStore       ProvenanceStore
 	AppManifestsCache     cache.Cache
 	AppLiveResourceLister AppLiveResourceLister
+	Notifier              Notifier
 	Logger                *zap.Logger
 }
 
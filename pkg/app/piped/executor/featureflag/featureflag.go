@@ -0,0 +1,111 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featureflag
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/featureflag"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type Executor struct {
+	executor.Input
+}
+
+type registerer interface {
+	Register(stage model.Stage, f executor.Factory) error
+}
+
+// Register registers this executor factory into a given registerer.
+func Register(r registerer) {
+	f := func(in executor.Input) executor.Executor {
+		return &Executor{
+			Input: in,
+		}
+	}
+	r.Register(model.StageFeatureFlag, f)
+}
+
+// Execute rolls out the specified feature flag to the specified percentage of users.
+// The state of the flag before the deployment is recorded to restore it while rolling back.
+func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
+	ctx := sig.Context()
+	opts := e.StageConfig.FeatureFlagStageOptions
+	if opts == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	cfg, ok := e.PipedConfig.GetFeatureFlagProvider(opts.Provider)
+	if !ok {
+		e.LogPersister.Errorf("Feature flag provider %s was not found in the piped configuration", opts.Provider)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	provider, err := featureflag.NewProvider(cfg, e.Logger)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to initialize feature flag provider %s (%v)", opts.Provider, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	flag := featureflag.Flag{
+		Key:         opts.Flag,
+		Project:     opts.Project,
+		Environment: opts.Environment,
+	}
+	if !opts.SkipRollback {
+		snapshot, err := provider.Snapshot(ctx, flag)
+		if err != nil {
+			e.LogPersister.Errorf("Unable to get the current state of flag %s (%v)", flag, err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		current, _ := e.MetadataStore.Get(featureflag.ChangesMetadataKey)
+		value, added, err := featureflag.AppendChange(current, featureflag.Change{
+			Provider: opts.Provider,
+			Flag:     flag,
+			Snapshot: snapshot,
+		})
+		if err != nil {
+			e.LogPersister.Errorf("Unable to record the current state of flag %s (%v)", flag, err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		if added {
+			if err := e.MetadataStore.Set(ctx, featureflag.ChangesMetadataKey, value); err != nil {
+				e.LogPersister.Errorf("Unable to save the current state of flag %s to deployment (%v)", flag, err)
+				return model.StageStatus_STAGE_FAILURE
+			}
+		}
+	}
+
+	percentage := opts.Percentage.Int()
+	e.LogPersister.Infof("Rolling out flag %s to %d%% of users", flag, percentage)
+	if err := provider.Rollout(ctx, flag, percentage); err != nil {
+		e.LogPersister.Errorf("Unable to roll out flag %s (%v)", flag, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	results := map[string]string{
+		executor.StageResultFeatureFlag: fmt.Sprintf("%s: %d%%", opts.Flag, percentage),
+	}
+	if err := e.MetadataStore.SetStageResults(ctx, e.Stage.Id, results); err != nil {
+		e.Logger.Error("failed to save feature flag percentage to stage results", zap.Error(err))
+	}
+
+	e.LogPersister.Successf("Successfully rolled out flag %s to %d%% of users", flag, percentage)
+	return model.StageStatus_STAGE_SUCCESS
+}
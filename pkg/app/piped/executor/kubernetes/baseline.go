@@ -117,12 +117,17 @@ func (e *deployExecutor) generateBaselineManifests(manifests []provider.Manifest
 		return nil, fmt.Errorf("unable to find any workload manifests for BASELINE variant")
 	}
 
-	var baselineManifests []provider.Manifest
+	label := newVariantLabel(e.deployCfg.VariantLabel)
+
+	var (
+		baselineManifests           []provider.Manifest
+		services, generatedServices []provider.Manifest
+	)
 
 	// Find service manifests and duplicate them for BASELINE variant.
 	if opts.CreateService {
 		serviceName := e.deployCfg.Service.Name
-		services := findManifests(provider.KindService, serviceName, manifests)
+		services = findManifests(provider.KindService, serviceName, manifests)
 		if len(services) == 0 {
 			return nil, fmt.Errorf("unable to find any service for name=%q", serviceName)
 		}
@@ -130,7 +135,12 @@ func (e *deployExecutor) generateBaselineManifests(manifests []provider.Manifest
 		// so we duplicate them to avoid updating the shared manifests data in cache.
 		services = duplicateManifests(services, "")
 
-		generatedServices, err := generateVariantServiceManifests(services, baselineVariant, suffix)
+		serviceSuffix := suffix
+		if opts.ServiceNameSuffix != "" {
+			serviceSuffix = opts.ServiceNameSuffix
+		}
+		var err error
+		generatedServices, err = generateVariantServiceManifests(services, label, baselineVariant, serviceSuffix, opts.ServiceLabels)
 		if err != nil {
 			return nil, err
 		}
@@ -146,12 +156,18 @@ func (e *deployExecutor) generateBaselineManifests(manifests []provider.Manifest
 		num := opts.Replicas.Calculate(int(*cur), 1)
 		return int32(num)
 	}
-	generatedWorkloads, err := generateVariantWorkloadManifests(workloads, nil, nil, baselineVariant, suffix, replicasCalculator)
+	generatedWorkloads, err := generateVariantWorkloadManifests(workloads, nil, nil, label, baselineVariant, suffix, replicasCalculator)
 	if err != nil {
 		return nil, err
 	}
 	baselineManifests = append(baselineManifests, generatedWorkloads...)
 
+	// Ensure that the generated services are routing traffic only to the BASELINE pods
+	// before applying anything to the cluster.
+	if err := checkVariantServiceIsolation(services, generatedServices, generatedWorkloads); err != nil {
+		return nil, err
+	}
+
 	return baselineManifests, nil
 }
 
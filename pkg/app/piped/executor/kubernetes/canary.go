@@ -122,12 +122,17 @@ func (e *deployExecutor) generateCanaryManifests(manifests []provider.Manifest,
 		return nil, fmt.Errorf("unable to find any workload manifests for CANARY variant")
 	}
 
-	var canaryManifests []provider.Manifest
+	label := newVariantLabel(e.deployCfg.VariantLabel)
+
+	var (
+		canaryManifests             []provider.Manifest
+		services, generatedServices []provider.Manifest
+	)
 
 	// Find service manifests and duplicate them for CANARY variant.
 	if opts.CreateService {
 		serviceName := e.deployCfg.Service.Name
-		services := findManifests(provider.KindService, serviceName, manifests)
+		services = findManifests(provider.KindService, serviceName, manifests)
 		if len(services) == 0 {
 			return nil, fmt.Errorf("unable to find any service for name=%q", serviceName)
 		}
@@ -135,7 +140,12 @@ func (e *deployExecutor) generateCanaryManifests(manifests []provider.Manifest,
 		// so we duplicate them to avoid updating the shared manifests data in cache.
 		services = duplicateManifests(services, "")
 
-		generatedServices, err := generateVariantServiceManifests(services, canaryVariant, suffix)
+		serviceSuffix := suffix
+		if opts.ServiceNameSuffix != "" {
+			serviceSuffix = opts.ServiceNameSuffix
+		}
+		var err error
+		generatedServices, err = generateVariantServiceManifests(services, label, canaryVariant, serviceSuffix, opts.ServiceLabels)
 		if err != nil {
 			return nil, err
 		}
@@ -164,12 +174,18 @@ func (e *deployExecutor) generateCanaryManifests(manifests []provider.Manifest,
 	// We don't need to duplicate the workload manifests
 	// because generateVariantWorkloadManifests function is already making a duplicate while decoding.
 	// workloads = duplicateManifests(workloads, suffix)
-	generatedWorkloads, err := generateVariantWorkloadManifests(workloads, configMaps, secrets, canaryVariant, suffix, replicasCalculator)
+	generatedWorkloads, err := generateVariantWorkloadManifests(workloads, configMaps, secrets, label, canaryVariant, suffix, replicasCalculator)
 	if err != nil {
 		return nil, err
 	}
 	canaryManifests = append(canaryManifests, generatedWorkloads...)
 
+	// Ensure that the generated services are routing traffic only to the CANARY pods
+	// before applying anything to the cluster.
+	if err := checkVariantServiceIsolation(services, generatedServices, generatedWorkloads); err != nil {
+		return nil, err
+	}
+
 	return canaryManifests, nil
 }
 
@@ -0,0 +1,79 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func (e *deployExecutor) ensureDryRun(ctx context.Context) model.StageStatus {
+	// Load the manifests at the specified commit.
+	e.LogPersister.Infof("Loading manifests at commit %s for verifying", e.commit)
+	manifests, err := loadManifests(
+		ctx,
+		e.Deployment.ApplicationId,
+		e.commit,
+		e.AppManifestsCache,
+		e.provider,
+		e.Logger,
+	)
+	if err != nil {
+		e.LogPersister.Errorf("Failed while loading manifests (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.LogPersister.Successf("Successfully loaded %d manifests", len(manifests))
+
+	// Because the loaded manifests are read-only
+	// we duplicate them to avoid updating the shared manifests data in cache.
+	manifests = duplicateManifests(manifests, "")
+
+	// Add the same annotations with the sync stage
+	// to make the verified manifests as close as possible to the applied ones.
+	addBuiltinAnnontations(
+		manifests,
+		primaryVariant,
+		e.commit,
+		e.PipedConfig.PipedID,
+		e.Deployment.ApplicationId,
+	)
+	if err := annotateConfigHash(manifests); err != nil {
+		e.LogPersister.Errorf("Unable to set %q annotation into the workload manifest (%v)", provider.AnnotationConfigHash, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// Verify all manifests instead of stopping at the first failure
+	// to show all problems at once.
+	e.LogPersister.Infof("Start verifying %d manifests by server-side dry-run apply", len(manifests))
+	var failed int
+	for _, m := range manifests {
+		if err := e.provider.DryRunApplyManifest(ctx, m); err != nil {
+			e.LogPersister.Errorf("- failed to verify manifest: %s (%v)", m.Key.ReadableString(), err)
+			failed++
+			continue
+		}
+		e.LogPersister.Successf("- verified manifest: %s", m.Key.ReadableString())
+	}
+
+	if failed > 0 {
+		e.LogPersister.Errorf("%d of %d manifests were rejected by the cluster", failed, len(manifests))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully verified %d manifests without applying them", len(manifests))
+	return model.StageStatus_STAGE_SUCCESS
+}
@@ -0,0 +1,117 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/providertest"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/cache"
+	"github.com/pipe-cd/pipe/pkg/cache/cachetest"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestEnsureDryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manifests := []provider.Manifest{
+		provider.MakeManifest(provider.ResourceKey{
+			APIVersion: "apps/v1",
+			Kind:       provider.KindDeployment,
+			Name:       "foo",
+		}, &unstructured.Unstructured{
+			Object: map[string]interface{}{"spec": map[string]interface{}{}},
+		}),
+		provider.MakeManifest(provider.ResourceKey{
+			APIVersion: "v1",
+			Kind:       provider.KindService,
+			Name:       "foo",
+		}, &unstructured.Unstructured{
+			Object: map[string]interface{}{"spec": map[string]interface{}{}},
+		}),
+	}
+	newInput := func() executor.Input {
+		c := cachetest.NewMockCache(ctrl)
+		c.EXPECT().Get(gomock.Any()).Return(nil, fmt.Errorf("not found"))
+		c.EXPECT().Put(gomock.Any(), gomock.Any()).Return(nil)
+		return executor.Input{
+			Deployment: &model.Deployment{
+				Trigger: &model.DeploymentTrigger{
+					Commit: &model.Commit{},
+					DryRun: true,
+				},
+			},
+			PipedConfig:       &config.PipedSpec{},
+			LogPersister:      &fakeLogPersister{},
+			AppManifestsCache: cache.Cache(c),
+			Logger:            zap.NewNop(),
+		}
+	}
+
+	testcases := []struct {
+		name     string
+		executor *deployExecutor
+		want     model.StageStatus
+	}{
+		{
+			name: "all manifests were rejected",
+			want: model.StageStatus_STAGE_FAILURE,
+			executor: &deployExecutor{
+				Input: newInput(),
+				provider: func() provider.Provider {
+					p := providertest.NewMockProvider(ctrl)
+					p.EXPECT().LoadManifests(gomock.Any()).Return(manifests, nil)
+					p.EXPECT().DryRunApplyManifest(gomock.Any(), gomock.Any()).Return(fmt.Errorf("error")).Times(2)
+					return p
+				}(),
+				deployCfg: &config.KubernetesDeploymentSpec{},
+			},
+		},
+		{
+			name: "all manifests were verified without applying",
+			want: model.StageStatus_STAGE_SUCCESS,
+			executor: &deployExecutor{
+				Input: newInput(),
+				provider: func() provider.Provider {
+					p := providertest.NewMockProvider(ctrl)
+					p.EXPECT().LoadManifests(gomock.Any()).Return(manifests, nil)
+					p.EXPECT().DryRunApplyManifest(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+					p.EXPECT().ApplyManifest(gomock.Any(), gomock.Any()).Times(0)
+					return p
+				}(),
+				deployCfg: &config.KubernetesDeploymentSpec{},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			got := tc.executor.ensureDryRun(ctx)
+			assert.Equal(t, tc.want, got)
+			cancel()
+		})
+	}
+}
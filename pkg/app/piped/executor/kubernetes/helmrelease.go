@@ -0,0 +1,163 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	// The revision of the release before it was upgraded by the deployment.
+	// Zero means the release was installed by the deployment.
+	helmReleaseRevisionMetadataKey = "helm-release-revision"
+)
+
+func (e *deployExecutor) ensureHelmReleaseSync(ctx context.Context) model.StageStatus {
+	// Keep the revision running before the deployment to roll back to it on failure.
+	if _, ok := e.MetadataStore.Get(helmReleaseRevisionMetadataKey); !ok {
+		revision, err := e.releaser.ReleaseRevision(ctx)
+		if err != nil {
+			e.LogPersister.Errorf("Failed to get the current revision of the release (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		if err := e.MetadataStore.Set(ctx, helmReleaseRevisionMetadataKey, strconv.Itoa(revision)); err != nil {
+			e.LogPersister.Errorf("Unable to save the current revision of the release (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+
+	e.LogPersister.Info("Start installing or upgrading the release by helm")
+	if err := e.releaser.UpgradeInstall(ctx, e.LogPersister); err != nil {
+		e.LogPersister.Errorf("Failed to upgrade the release (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.LogPersister.Success("Successfully upgraded the release")
+
+	e.LogPersister.Infof("Loading manifests at commit %s for handling", e.commit)
+	manifests, err := loadManifests(
+		ctx,
+		e.Deployment.ApplicationId,
+		e.commit,
+		e.AppManifestsCache,
+		e.releaser,
+		e.Logger,
+	)
+	if err != nil {
+		e.LogPersister.Errorf("Failed while loading manifests (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	if err := annotateResources(ctx, e.releaser, manifests, e.commit, e.PipedConfig.PipedID, e.Deployment.ApplicationId, e.LogPersister); err != nil {
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.recordProvenance(ctx, manifests)
+
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// ensureHelmReleaseRollback rolls back the release upgraded by the deployment
+// to the given revision by helm.
+func (e *rollbackExecutor) ensureHelmReleaseRollback(ctx context.Context, value string) model.StageStatus {
+	revision, err := strconv.Atoi(value)
+	if err != nil {
+		e.LogPersister.Errorf("Malformed revision of the release %q (%v)", value, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if revision == 0 {
+		e.LogPersister.Error("Unable to roll back the release because it was installed by this deployment")
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// The release to roll back is the one specified at the target commit.
+	ds, err := e.TargetDSP.Get(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	deployCfg := ds.DeploymentConfig.KubernetesDeploymentSpec
+	if deployCfg == nil {
+		e.LogPersister.Error("Malformed deployment configuration: missing KubernetesDeploymentSpec")
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	if deployCfg.Input.HelmChart != nil {
+		chartRepoName := deployCfg.Input.HelmChart.Repository
+		if chartRepoName != "" {
+			deployCfg.Input.HelmChart.Insecure = e.PipedConfig.IsInsecureChartRepository(chartRepoName)
+		}
+	}
+
+	cluster, ok := findCloudProvider(&e.Input)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	releaser := provider.NewHelmReleaser(e.Deployment.ApplicationName, ds.AppDir, ds.RepoDir, e.Deployment.GitPath.ConfigFilename, deployCfg.Input, cluster, e.Logger)
+	current, err := releaser.ReleaseRevision(ctx)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to get the current revision of the release (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if current == revision {
+		e.LogPersister.Infof("The release is still at revision %d so there is nothing to roll back", revision)
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	e.LogPersister.Infof("Start rolling back the release from revision %d to %d", current, revision)
+	if err := releaser.RollbackRelease(ctx, revision, e.LogPersister); err != nil {
+		e.LogPersister.Errorf("Failed to roll back the release (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.LogPersister.Successf("Successfully rolled back the release to revision %d", revision)
+
+	// Point the annotations of the rolled back resources to the running commit again.
+	if commit := e.Deployment.RunningCommitHash; commit != "" {
+		manifests, err := releaser.LoadManifests(ctx)
+		if err != nil {
+			e.LogPersister.Errorf("Failed while loading manifests (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		if err := annotateResources(ctx, releaser, manifests, commit, e.PipedConfig.PipedID, e.Deployment.ApplicationId, e.LogPersister); err != nil {
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// annotateResources adds the builtin annotations to the live resources of the given manifests
+// for tracking application live state since they were deployed by helm instead of piped.
+// The resources no longer existing are ignored.
+func annotateResources(ctx context.Context, releaser provider.HelmReleaser, manifests []provider.Manifest, hash, pipedID, appID string, lp executor.LogPersister) error {
+	lp.Infof("Start annotating %d resources", len(manifests))
+	for _, m := range manifests {
+		annotations := builtinAnnotations(m, primaryVariant, hash, pipedID, appID)
+		err := releaser.Annotate(ctx, m.Key, annotations)
+		if err == nil || errors.Is(err, provider.ErrNotFound) {
+			continue
+		}
+		lp.Errorf("Failed to annotate resource: %s (%v)", m.Key.ReadableString(), err)
+		return err
+	}
+	lp.Successf("Successfully annotated %d resources", len(manifests))
+	return nil
+}
@@ -18,12 +18,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"go.uber.org/zap"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
@@ -32,16 +35,55 @@ import (
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
-const (
-	variantLabel = "pipecd.dev/variant" // Variant name: primary, stage, baseline
-)
+// variantLabel represents the label used to distinguish the resources of each variant.
+type variantLabel struct {
+	key    string
+	values map[string]string
+}
+
+// newVariantLabel returns the variant label configured for the application.
+// The default one is used when nothing was configured.
+func newVariantLabel(cfg *config.KubernetesVariantLabel) variantLabel {
+	if cfg == nil {
+		return variantLabel{
+			key: provider.LabelVariant,
+			values: map[string]string{
+				primaryVariant:  primaryVariant,
+				canaryVariant:   canaryVariant,
+				baselineVariant: baselineVariant,
+			},
+		}
+	}
+	return variantLabel{
+		key: cfg.Key,
+		values: map[string]string{
+			primaryVariant:  cfg.PrimaryValue,
+			canaryVariant:   cfg.CanaryValue,
+			baselineVariant: cfg.BaselineValue,
+		},
+	}
+}
+
+// value returns the label value of the given variant.
+func (l variantLabel) value(variant string) string {
+	return l.values[variant]
+}
+
+// format returns the label of the given variant in "key: value" format.
+func (l variantLabel) format(variant string) string {
+	return l.key + ": " + l.value(variant)
+}
 
 type deployExecutor struct {
 	executor.Input
 
 	commit    string
+	appDir    string
 	deployCfg *config.KubernetesDeploymentSpec
+	cluster   *config.CloudProviderKubernetesConfig
 	provider  provider.Provider
+	// Set only when the chart is deployed as a Helm release.
+	releaser provider.HelmReleaser
 }
 
 type registerer interface {
@@ -64,6 +106,10 @@ func Register(r registerer) {
 	r.Register(model.StageK8sBaselineRollout, f)
 	r.Register(model.StageK8sBaselineClean, f)
 	r.Register(model.StageK8sTrafficRouting, f)
+	r.Register(model.StageK8sDryRun, f)
+	r.Register(model.StageK8sPolicyCheck, f)
+	r.Register(model.StageK8sVulnerabilityScan, f)
+	r.Register(model.StageK8sVariantClean, f)
 
 	r.RegisterRollback(model.ApplicationKind_KUBERNETES, func(in executor.Input) executor.Executor {
 		return &rollbackExecutor{
@@ -95,7 +141,17 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 		}
 	}
 
-	e.provider = provider.NewProvider(e.Deployment.ApplicationName, ds.AppDir, ds.RepoDir, e.Deployment.GitPath.ConfigFilename, e.deployCfg.Input, e.Logger)
+	cluster, ok := findCloudProvider(&e.Input)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.cluster = cluster
+
+	e.appDir = ds.AppDir
+	e.provider = provider.NewProvider(e.Deployment.ApplicationName, ds.AppDir, ds.RepoDir, e.Deployment.GitPath.ConfigFilename, e.deployCfg.Input, cluster, e.Logger)
+	if e.deployCfg.Input.IsHelmReleaseMode() {
+		e.releaser = provider.NewHelmReleaser(e.Deployment.ApplicationName, ds.AppDir, ds.RepoDir, e.Deployment.GitPath.ConfigFilename, e.deployCfg.Input, cluster, e.Logger)
+	}
 	e.Logger.Info("start executing kubernetes stage",
 		zap.String("stage-name", e.Stage.Name),
 		zap.String("app-dir", ds.AppDir),
@@ -106,6 +162,14 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 		status         model.StageStatus
 	)
 
+	// The namespace must exist before applying the manifests into it.
+	switch model.Stage(e.Stage.Name) {
+	case model.StageK8sSync, model.StageK8sPrimaryRollout, model.StageK8sCanaryRollout, model.StageK8sBaselineRollout:
+		if err := ensureNamespace(ctx, e.provider, e.deployCfg.Input, e.Deployment.ApplicationId, e.LogPersister); err != nil {
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+
 	switch model.Stage(e.Stage.Name) {
 	case model.StageK8sSync:
 		status = e.ensureSync(ctx)
@@ -128,6 +192,18 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 	case model.StageK8sTrafficRouting:
 		status = e.ensureTrafficRouting(ctx)
 
+	case model.StageK8sDryRun:
+		status = e.ensureDryRun(ctx)
+
+	case model.StageK8sPolicyCheck:
+		status = e.ensurePolicyCheck(ctx)
+
+	case model.StageK8sVulnerabilityScan:
+		status = e.ensureVulnerabilityScan(ctx)
+
+	case model.StageK8sVariantClean:
+		status = e.ensureVariantClean(ctx)
+
 	default:
 		e.LogPersister.Errorf("Unsupported stage %s for kubernetes application", e.Stage.Name)
 		return model.StageStatus_STAGE_FAILURE
@@ -156,6 +232,7 @@ func (e *deployExecutor) loadRunningManifests(ctx context.Context) (manifests []
 				ds.RepoDir,
 				e.Deployment.GitPath.ConfigFilename,
 				e.deployCfg.Input,
+				e.cluster,
 				e.Logger,
 			)
 			return loader.LoadManifests(ctx)
@@ -195,35 +272,101 @@ func loadManifests(ctx context.Context, appID, commit string, manifestsCache cac
 
 func addBuiltinAnnontations(manifests []provider.Manifest, variant, hash, pipedID, appID string) {
 	for i := range manifests {
-		manifests[i].AddAnnotations(map[string]string{
-			provider.LabelManagedBy:          provider.ManagedByPiped,
-			provider.LabelPiped:              pipedID,
-			provider.LabelApplication:        appID,
-			variantLabel:                     variant,
-			provider.LabelOriginalAPIVersion: manifests[i].Key.APIVersion,
-			provider.LabelResourceKey:        manifests[i].Key.String(),
-			provider.LabelCommitHash:         hash,
-		})
+		manifests[i].AddAnnotations(builtinAnnotations(manifests[i], variant, hash, pipedID, appID))
 	}
 }
 
+// builtinAnnotations returns the annotations for tracking application live state.
+func builtinAnnotations(m provider.Manifest, variant, hash, pipedID, appID string) map[string]string {
+	return map[string]string{
+		provider.LabelManagedBy:          provider.ManagedByPiped,
+		provider.LabelPiped:              pipedID,
+		provider.LabelApplication:        appID,
+		provider.LabelVariant:            variant,
+		provider.LabelOriginalAPIVersion: m.Key.APIVersion,
+		provider.LabelResourceKey:        m.Key.String(),
+		provider.LabelCommitHash:         hash,
+	}
+}
+
+// applyManifests applies the given manifests in the order of their sync waves.
+// The resources of each wave must become ready before applying the next wave.
 func applyManifests(ctx context.Context, applier provider.Applier, manifests []provider.Manifest, namespace string, lp executor.LogPersister) error {
+	waves, err := groupManifestsBySyncWave(manifests)
+	if err != nil {
+		lp.Errorf("Unable to determine the sync waves of manifests (%v)", err)
+		return err
+	}
+
 	if namespace == "" {
 		lp.Infof("Start applying %d manifests", len(manifests))
 	} else {
 		lp.Infof("Start applying %d manifests to %q namespace", len(manifests), namespace)
 	}
-	for _, m := range manifests {
-		if err := applier.ApplyManifest(ctx, m); err != nil {
-			lp.Errorf("Failed to apply manifest: %s (%v)", m.Key.ReadableString(), err)
-			return err
+	for i, wave := range waves {
+		if len(waves) > 1 {
+			lp.Infof("Applying %d manifests of sync wave %d", len(wave.manifests), wave.number)
+		}
+		for _, m := range wave.manifests {
+			if err := applier.ApplyManifest(ctx, m); err != nil {
+				lp.Errorf("Failed to apply manifest: %s (%v)", m.Key.ReadableString(), err)
+				return err
+			}
+			lp.Successf("- applied manifest: %s", m.Key.ReadableString())
+		}
+
+		// There is no dependent resource to wait for after the last wave.
+		if i == len(waves)-1 {
+			break
+		}
+		lp.Infof("Waiting for the resources of sync wave %d to become ready", wave.number)
+		for _, m := range wave.manifests {
+			if err := applier.WaitForReady(ctx, m.Key); err != nil {
+				lp.Errorf("Failed while waiting for %s to become ready (%v)", m.Key.ReadableString(), err)
+				return err
+			}
 		}
-		lp.Successf("- applied manifest: %s", m.Key.ReadableString())
 	}
 	lp.Successf("Successfully applied %d manifests", len(manifests))
 	return nil
 }
 
+type syncWave struct {
+	number    int
+	manifests []provider.Manifest
+}
+
+// groupManifestsBySyncWave groups the given manifests by their sync wave annotation
+// and returns the groups in ascending order of the wave number.
+// The manifests without the annotation belong to wave 0
+// and the order of manifests inside each wave is kept as given.
+func groupManifestsBySyncWave(manifests []provider.Manifest) ([]syncWave, error) {
+	groups := make(map[int][]provider.Manifest)
+	for _, m := range manifests {
+		var number int
+		if v, ok := m.GetAnnotations()[provider.AnnotationSyncWave]; ok {
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s annotation %q of %s", provider.AnnotationSyncWave, v, m.Key.ReadableString())
+			}
+			number = n
+		}
+		groups[number] = append(groups[number], m)
+	}
+
+	waves := make([]syncWave, 0, len(groups))
+	for number, manifests := range groups {
+		waves = append(waves, syncWave{
+			number:    number,
+			manifests: manifests,
+		})
+	}
+	sort.Slice(waves, func(i, j int) bool {
+		return waves[i].number < waves[j].number
+	})
+	return waves, nil
+}
+
 func deleteResources(ctx context.Context, applier provider.Applier, resources []provider.ResourceKey, lp executor.LogPersister) error {
 	resourcesLen := len(resources)
 	if resourcesLen == 0 {
@@ -324,10 +467,17 @@ func duplicateManifest(m provider.Manifest, nameSuffix string) provider.Manifest
 	return m.Duplicate(name)
 }
 
-func generateVariantServiceManifests(services []provider.Manifest, variant, nameSuffix string) ([]provider.Manifest, error) {
+func generateVariantServiceManifests(services []provider.Manifest, label variantLabel, variant, nameSuffix string, labels map[string]string) ([]provider.Manifest, error) {
 	manifests := make([]provider.Manifest, 0, len(services))
 	updateService := func(s *corev1.Service) {
 		s.Name = makeSuffixedName(s.Name, nameSuffix)
+		// Add the configured extra labels to the generated service.
+		if len(labels) > 0 && s.Labels == nil {
+			s.Labels = make(map[string]string, len(labels))
+		}
+		for k, v := range labels {
+			s.Labels[k] = v
+		}
 		// Currently, we suppose that all generated services should be ClusterIP.
 		s.Spec.Type = corev1.ServiceTypeClusterIP
 		// Append the variant label to the selector
@@ -335,7 +485,7 @@ func generateVariantServiceManifests(services []provider.Manifest, variant, name
 		if s.Spec.Selector == nil {
 			s.Spec.Selector = map[string]string{}
 		}
-		s.Spec.Selector[variantLabel] = variant
+		s.Spec.Selector[label.key] = label.value(variant)
 		// Empty all unneeded fields.
 		s.Spec.ExternalIPs = nil
 		s.Spec.LoadBalancerIP = ""
@@ -357,7 +507,7 @@ func generateVariantServiceManifests(services []provider.Manifest, variant, name
 	return manifests, nil
 }
 
-func generateVariantWorkloadManifests(workloads, configmaps, secrets []provider.Manifest, variant, nameSuffix string, replicasCalculator func(*int32) int32) ([]provider.Manifest, error) {
+func generateVariantWorkloadManifests(workloads, configmaps, secrets []provider.Manifest, label variantLabel, variant, nameSuffix string, replicasCalculator func(*int32) int32) ([]provider.Manifest, error) {
 	manifests := make([]provider.Manifest, 0, len(workloads))
 
 	cmNames := make(map[string]struct{}, len(configmaps))
@@ -406,7 +556,7 @@ func generateVariantWorkloadManifests(workloads, configmaps, secrets []provider.
 		if pod.Labels == nil {
 			pod.Labels = map[string]string{}
 		}
-		pod.Labels[variantLabel] = variant
+		pod.Labels[label.key] = label.value(variant)
 
 		// Update volumes to use canary's ConfigMaps and Secrets.
 		for i := range pod.Spec.Volumes {
@@ -433,7 +583,7 @@ func generateVariantWorkloadManifests(workloads, configmaps, secrets []provider.
 			replicas := replicasCalculator(d.Spec.Replicas)
 			d.Spec.Replicas = &replicas
 		}
-		d.Spec.Selector = metav1.AddLabelToSelector(d.Spec.Selector, variantLabel, variant)
+		d.Spec.Selector = metav1.AddLabelToSelector(d.Spec.Selector, label.key, label.value(variant))
 		updatePod(&d.Spec.Template)
 	}
 
@@ -459,42 +609,96 @@ func generateVariantWorkloadManifests(workloads, configmaps, secrets []provider.
 	return manifests, nil
 }
 
-func checkVariantSelectorInWorkload(m provider.Manifest, variant string) error {
+// checkVariantServiceIsolation ensures that the generated services of a variant
+// are not conflicting with the original ones and are selecting only the pods of the generated workloads.
+func checkVariantServiceIsolation(services, generatedServices, generatedWorkloads []provider.Manifest) error {
+	names := make(map[string]struct{}, len(services))
+	for _, s := range services {
+		names[s.Key.Name] = struct{}{}
+	}
+
+	podLabels := make([]map[string]string, 0, len(generatedWorkloads))
+	for _, w := range generatedWorkloads {
+		labels, err := w.GetNestedStringMap("spec", "template", "metadata", "labels")
+		if err != nil {
+			return fmt.Errorf("unable to get pod labels of workload %s: %w", w.Key.ReadableString(), err)
+		}
+		podLabels = append(podLabels, labels)
+	}
+
+	for _, s := range generatedServices {
+		name := s.Key.Name
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("generated service %s has the same name with the original one, a non-empty service name suffix is required", name)
+		}
+		if errs := validation.IsDNS1035Label(name); len(errs) > 0 {
+			return fmt.Errorf("generated service name %s is invalid: %s", name, strings.Join(errs, ", "))
+		}
+
+		selector, err := s.GetNestedStringMap("spec", "selector")
+		if err != nil {
+			return fmt.Errorf("unable to get selector of service %s: %w", name, err)
+		}
+		if !selectsAnyPod(selector, podLabels) {
+			return fmt.Errorf("selector of generated service %s does not match the pods of any generated workload", name)
+		}
+	}
+	return nil
+}
+
+func selectsAnyPod(selector map[string]string, podLabels []map[string]string) bool {
+	for _, labels := range podLabels {
+		matched := true
+		for k, v := range selector {
+			if labels[k] != v {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func checkVariantSelectorInWorkload(m provider.Manifest, label variantLabel, variant string) error {
 	var (
 		matchLabelsFields = []string{"spec", "selector", "matchLabels"}
 		labelsFields      = []string{"spec", "template", "metadata", "labels"}
+		expected          = label.value(variant)
 	)
 
 	matchLabels, err := m.GetNestedStringMap(matchLabelsFields...)
 	if err != nil {
 		return err
 	}
-	value, ok := matchLabels[variantLabel]
+	value, ok := matchLabels[label.key]
 	if !ok {
-		return fmt.Errorf("missing %s key in spec.selector.matchLabels", variantLabel)
+		return fmt.Errorf("missing %s key in spec.selector.matchLabels", label.key)
 	}
-	if value != variant {
-		return fmt.Errorf("require %s but got %s for %s key in %s", variant, value, variantLabel, strings.Join(matchLabelsFields, "."))
+	if value != expected {
+		return fmt.Errorf("require %s but got %s for %s key in %s", expected, value, label.key, strings.Join(matchLabelsFields, "."))
 	}
 
 	labels, err := m.GetNestedStringMap(labelsFields...)
 	if err != nil {
 		return err
 	}
-	value, ok = labels[variantLabel]
+	value, ok = labels[label.key]
 	if !ok {
-		return fmt.Errorf("missing %s key in spec.template.metadata.labels", variantLabel)
+		return fmt.Errorf("missing %s key in spec.template.metadata.labels", label.key)
 	}
-	if value != variant {
-		return fmt.Errorf("require %s but got %s for %s key in %s", variant, value, variantLabel, strings.Join(labelsFields, "."))
+	if value != expected {
+		return fmt.Errorf("require %s but got %s for %s key in %s", expected, value, label.key, strings.Join(labelsFields, "."))
 	}
 
 	return nil
 }
 
-func ensureVariantSelectorInWorkload(m provider.Manifest, variant string) error {
+func ensureVariantSelectorInWorkload(m provider.Manifest, label variantLabel, variant string) error {
 	variantMap := map[string]string{
-		variantLabel: variant,
+		label.key: label.value(variant),
 	}
 	if err := m.AddStringMapValues(variantMap, "spec", "selector", "matchLabels"); err != nil {
 		return err
@@ -605,3 +809,21 @@ func annotateConfigHashToDeployment(m provider.Manifest, managedConfigMaps, mana
 	)
 	return nil
 }
+
+func findCloudProvider(in *executor.Input) (cfg *config.CloudProviderKubernetesConfig, found bool) {
+	name := in.Application.CloudProvider
+	if name == "" {
+		in.LogPersister.Error("Missing the CloudProvider name in the application configuration")
+		return
+	}
+
+	cp, ok := in.PipedConfig.FindCloudProvider(name, model.CloudProviderKubernetes)
+	if !ok {
+		in.LogPersister.Errorf("The specified cloud provider %q was not found in piped configuration", name)
+		return
+	}
+
+	cfg = cp.KubernetesConfig
+	found = true
+	return
+}
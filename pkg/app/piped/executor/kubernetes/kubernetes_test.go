@@ -25,6 +25,7 @@ import (
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
 	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/providertest"
+	"github.com/pipe-cd/pipe/pkg/config"
 )
 
 type fakeLogPersister struct{}
@@ -45,6 +46,46 @@ func (m *fakeMetadataStore) GetStageMetadata(_ string) (map[string]string, bool)
 func (m *fakeMetadataStore) SetStageMetadata(_ context.Context, _ string, _ map[string]string) error {
 	return nil
 }
+func (m *fakeMetadataStore) SetStageResults(_ context.Context, _ string, _ map[string]string) error {
+	return nil
+}
+
+func TestNewVariantLabel(t *testing.T) {
+	testcases := []struct {
+		name          string
+		cfg           *config.KubernetesVariantLabel
+		expectedKey   string
+		expectedValue string
+		expected      string
+	}{
+		{
+			name:          "default",
+			expectedKey:   "pipecd.dev/variant",
+			expectedValue: "canary",
+			expected:      "pipecd.dev/variant: canary",
+		},
+		{
+			name: "customized",
+			cfg: &config.KubernetesVariantLabel{
+				Key:           "version",
+				PrimaryValue:  "stable",
+				CanaryValue:   "next",
+				BaselineValue: "base",
+			},
+			expectedKey:   "version",
+			expectedValue: "next",
+			expected:      "version: next",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			label := newVariantLabel(tc.cfg)
+			assert.Equal(t, tc.expectedKey, label.key)
+			assert.Equal(t, tc.expectedValue, label.value(canaryVariant))
+			assert.Equal(t, tc.expected, label.format(canaryVariant))
+		})
+	}
+}
 
 func TestGenerateServiceManifests(t *testing.T) {
 	testcases := []struct {
@@ -62,7 +103,7 @@ func TestGenerateServiceManifests(t *testing.T) {
 			require.NoError(t, err)
 			require.Equal(t, 2, len(manifests))
 
-			generatedManifests, err := generateVariantServiceManifests(manifests[:1], "canary-variant", "canary")
+			generatedManifests, err := generateVariantServiceManifests(manifests[:1], variantLabel{key: provider.LabelVariant, values: map[string]string{canaryVariant: "canary-variant"}}, canaryVariant, "canary", nil)
 			require.NoError(t, err)
 			require.Equal(t, 1, len(generatedManifests))
 
@@ -105,7 +146,7 @@ func TestGenerateVariantWorkloadManifests(t *testing.T) {
 				require.NoError(t, err)
 			}
 
-			generatedManifests, err := generateVariantWorkloadManifests(manifests[:1], configmaps, secrets, "canary-variant", "canary", func(r *int32) int32 {
+			generatedManifests, err := generateVariantWorkloadManifests(manifests[:1], configmaps, secrets, variantLabel{key: provider.LabelVariant, values: map[string]string{canaryVariant: "canary-variant"}}, canaryVariant, "canary", func(r *int32) int32 {
 				return *r - 1
 			})
 			require.NoError(t, err)
@@ -246,10 +287,10 @@ spec:
 			require.NoError(t, err)
 			require.Equal(t, 1, len(manifests))
 
-			err = checkVariantSelectorInWorkload(manifests[0], primaryVariant)
+			err = checkVariantSelectorInWorkload(manifests[0], newVariantLabel(nil), primaryVariant)
 			assert.Equal(t, tc.expected, err)
 
-			err = ensureVariantSelectorInWorkload(manifests[0], primaryVariant)
+			err = ensureVariantSelectorInWorkload(manifests[0], newVariantLabel(nil), primaryVariant)
 			assert.NoError(t, err)
 			assert.Equal(t, generatedManifests[0], manifests[0])
 		})
@@ -257,6 +298,111 @@ spec:
 
 }
 
+func TestCheckVariantServiceIsolation(t *testing.T) {
+	const deployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: simple
+spec:
+  selector:
+    matchLabels:
+      app: simple
+  template:
+    metadata:
+      labels:
+        app: simple
+`
+	testcases := []struct {
+		name        string
+		service     string
+		suffix      string
+		expectedErr bool
+	}{
+		{
+			name: "ok",
+			service: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: simple
+spec:
+  selector:
+    app: simple
+`,
+			suffix:      "canary",
+			expectedErr: false,
+		},
+		{
+			name: "same name with the original service",
+			service: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: simple
+spec:
+  selector:
+    app: simple
+`,
+			suffix:      "",
+			expectedErr: true,
+		},
+		{
+			name: "invalid service name",
+			service: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: simple
+spec:
+  selector:
+    app: simple
+`,
+			suffix:      "Canary_v1",
+			expectedErr: true,
+		},
+		{
+			name: "selector does not match any pod",
+			service: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: simple
+spec:
+  selector:
+    app: simple
+    tier: web
+`,
+			suffix:      "canary",
+			expectedErr: true,
+		},
+	}
+
+	workloads, err := provider.ParseManifests(deployment)
+	require.NoError(t, err)
+	generatedWorkloads, err := generateVariantWorkloadManifests(workloads, nil, nil, newVariantLabel(nil), canaryVariant, "canary", nil)
+	require.NoError(t, err)
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			services, err := provider.ParseManifests(tc.service)
+			require.NoError(t, err)
+			require.Equal(t, 1, len(services))
+
+			labels := map[string]string{"istio-subset": canaryVariant}
+			generatedServices, err := generateVariantServiceManifests(services, newVariantLabel(nil), canaryVariant, tc.suffix, labels)
+			require.NoError(t, err)
+			require.Equal(t, 1, len(generatedServices))
+			generatedLabels, err := generatedServices[0].GetNestedStringMap("metadata", "labels")
+			require.NoError(t, err)
+			assert.Equal(t, labels, generatedLabels)
+
+			err = checkVariantServiceIsolation(services, generatedServices, generatedWorkloads)
+			assert.Equal(t, tc.expectedErr, err != nil)
+		})
+	}
+}
+
 func TestDeleteResources(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -556,3 +702,131 @@ data:
 		})
 	}
 }
+
+func TestGroupManifestsBySyncWave(t *testing.T) {
+	testcases := []struct {
+		name      string
+		manifests string
+		expected  map[int][]string
+		wantErr   bool
+	}{
+		{
+			name: "no annotation",
+			manifests: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+`,
+			expected: map[int][]string{
+				0: {"config", "app"},
+			},
+		},
+		{
+			name: "multiple waves",
+			manifests: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: crd
+  annotations:
+    pipecd.dev/sync-wave: "-1"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  annotations:
+    pipecd.dev/sync-wave: "1"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: operator
+  annotations:
+    pipecd.dev/sync-wave: "-1"
+`,
+			expected: map[int][]string{
+				-1: {"crd", "operator"},
+				0:  {"app"},
+				1:  {"config"},
+			},
+		},
+		{
+			name: "invalid annotation",
+			manifests: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  annotations:
+    pipecd.dev/sync-wave: "first"
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			manifests, err := provider.ParseManifests(tc.manifests)
+			require.NoError(t, err)
+
+			waves, err := groupManifestsBySyncWave(manifests)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err != nil {
+				return
+			}
+
+			got := make(map[int][]string, len(waves))
+			for i, w := range waves {
+				if i > 0 {
+					assert.Less(t, waves[i-1].number, w.number)
+				}
+				for _, m := range w.manifests {
+					got[w.number] = append(got[w.number], m.Key.Name)
+				}
+			}
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestApplyManifestsWithSyncWaves(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manifests, err := provider.ParseManifests(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: crd
+  annotations:
+    pipecd.dev/sync-wave: "-1"
+`)
+	require.NoError(t, err)
+
+	p := providertest.NewMockProvider(ctrl)
+	gomock.InOrder(
+		p.EXPECT().ApplyManifest(gomock.Any(), manifests[1]).Return(nil),
+		p.EXPECT().WaitForReady(gomock.Any(), manifests[1].Key).Return(nil),
+		p.EXPECT().ApplyManifest(gomock.Any(), manifests[0]).Return(nil),
+	)
+
+	err = applyManifests(context.Background(), p, manifests, "", &fakeLogPersister{})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// ensureNamespace creates the namespace of the application or adopts the existing one
+// when the namespace management was enabled.
+// The owner label is added only to the namespace created by piped so that
+// deleting the application never deletes a namespace it had adopted,
+// and a namespace owned by another application is refused.
+// The builtin annotations are not added to the namespace to prevent it
+// from being pruned or checked as a resource of the application.
+func ensureNamespace(ctx context.Context, p provider.Provider, input config.KubernetesDeploymentInput, appID string, lp executor.LogPersister) error {
+	nm := input.NamespaceManagement
+	if nm == nil || !nm.Create {
+		return nil
+	}
+
+	var owner string
+	live, err := p.GetNamespace(ctx, input.Namespace)
+	switch {
+	case errors.Is(err, provider.ErrNotFound):
+		if nm.DeleteOnAppDeletion && !provider.IsSystemNamespace(input.Namespace) {
+			owner = appID
+		}
+	case err != nil:
+		lp.Errorf("Failed to get namespace %q (%v)", input.Namespace, err)
+		return err
+	default:
+		switch o := live.GetLabels()[provider.LabelNamespaceOwner]; o {
+		case "":
+			if nm.DeleteOnAppDeletion {
+				lp.Infof("Namespace %q was not created by PipeCD so it will not be deleted on the application deletion", input.Namespace)
+			}
+		case appID:
+			owner = appID
+		default:
+			err := fmt.Errorf("namespace %q is owned by another application %s", input.Namespace, o)
+			lp.Errorf("Failed to ensure namespace %q (%v)", input.Namespace, err)
+			return err
+		}
+	}
+
+	labels := make(map[string]string, len(nm.Labels)+1)
+	for k, v := range nm.Labels {
+		labels[k] = v
+	}
+	if owner != "" {
+		labels[provider.LabelNamespaceOwner] = owner
+	} else {
+		delete(labels, provider.LabelNamespaceOwner)
+	}
+
+	m := provider.MakeNamespaceManifest(input.Namespace, labels, nm.Annotations)
+	if err := p.ApplyManifest(ctx, m); err != nil {
+		lp.Errorf("Failed to ensure namespace %q (%v)", input.Namespace, err)
+		return err
+	}
+	lp.Successf("Successfully ensured namespace %q", input.Namespace)
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/providertest"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestEnsureNamespace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	liveNamespace := func(labels map[string]string) provider.Manifest {
+		return provider.MakeNamespaceManifest("foo", labels, nil)
+	}
+	input := config.KubernetesDeploymentInput{
+		Namespace: "foo",
+		NamespaceManagement: &config.KubernetesNamespaceManagement{
+			Create:              true,
+			Labels:              map[string]string{"team": "a"},
+			DeleteOnAppDeletion: true,
+		},
+	}
+
+	testcases := []struct {
+		name       string
+		live       provider.Manifest
+		getErr     error
+		wantLabels map[string]string
+		wantErr    bool
+	}{
+		{
+			name:   "create a new namespace as owner",
+			getErr: provider.ErrNotFound,
+			wantLabels: map[string]string{
+				"team":                       "a",
+				provider.LabelNamespaceOwner: "app-id",
+			},
+		},
+		{
+			name: "adopt an existing namespace without owning it",
+			live: liveNamespace(map[string]string{"team": "b"}),
+			wantLabels: map[string]string{
+				"team": "a",
+			},
+		},
+		{
+			name: "keep owning the namespace created before",
+			live: liveNamespace(map[string]string{provider.LabelNamespaceOwner: "app-id"}),
+			wantLabels: map[string]string{
+				"team":                       "a",
+				provider.LabelNamespaceOwner: "app-id",
+			},
+		},
+		{
+			name:    "refuse the namespace owned by another application",
+			live:    liveNamespace(map[string]string{provider.LabelNamespaceOwner: "another-app-id"}),
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := providertest.NewMockProvider(ctrl)
+			p.EXPECT().GetNamespace(gomock.Any(), "foo").Return(tc.live, tc.getErr)
+			if !tc.wantErr {
+				p.EXPECT().ApplyManifest(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, m provider.Manifest) error {
+					assert.Equal(t, tc.wantLabels, m.GetLabels())
+					return nil
+				})
+			}
+			err := ensureNamespace(context.Background(), p, input, "app-id", &fakeLogPersister{})
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestIsSystemNamespace(t *testing.T) {
+	assert.True(t, provider.IsSystemNamespace("default"))
+	assert.True(t, provider.IsSystemNamespace("kube-system"))
+	assert.False(t, provider.IsSystemNamespace("foo"))
+	assert.False(t, provider.IsSystemNamespace("kubeflow"))
+}
@@ -0,0 +1,373 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const defaultOPAQuery = "data.main.deny"
+
+// policyChecker evaluates a manifest against the configured policies
+// and returns the messages of all violations.
+type policyChecker interface {
+	Check(ctx context.Context, m provider.Manifest) ([]string, error)
+}
+
+func (e *deployExecutor) ensurePolicyCheck(ctx context.Context) model.StageStatus {
+	options := e.StageConfig.K8sPolicyCheckStageOptions
+	if options == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// Load the manifests at the specified commit.
+	e.LogPersister.Infof("Loading manifests at commit %s for policy check", e.commit)
+	manifests, err := loadManifests(
+		ctx,
+		e.Deployment.ApplicationId,
+		e.commit,
+		e.AppManifestsCache,
+		e.provider,
+		e.Logger,
+	)
+	if err != nil {
+		e.LogPersister.Errorf("Failed while loading manifests (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.LogPersister.Successf("Successfully loaded %d manifests", len(manifests))
+
+	workDir, err := ioutil.TempDir("", "policy-check")
+	if err != nil {
+		e.LogPersister.Errorf("Unable to create a working directory (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	defer os.RemoveAll(workDir)
+
+	checker, err := e.newPolicyChecker(ctx, options, workDir)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to prepare the %s policy engine (%v)", options.Engine, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	return e.checkPolicies(ctx, checker, manifests)
+}
+
+func (e *deployExecutor) checkPolicies(ctx context.Context, checker policyChecker, manifests []provider.Manifest) model.StageStatus {
+	// Check all manifests instead of stopping at the first violation
+	// to show all problems at once.
+	e.LogPersister.Infof("Start checking %d manifests against the policies", len(manifests))
+	var failed int
+	for _, m := range manifests {
+		violations, err := checker.Check(ctx, m)
+		if err != nil {
+			e.LogPersister.Errorf("Failed while checking manifest %s (%v)", m.Key.ReadableString(), err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		if len(violations) == 0 {
+			e.LogPersister.Successf("- passed manifest: %s", m.Key.ReadableString())
+			continue
+		}
+		failed++
+		e.LogPersister.Errorf("- manifest %s violated %d policies:", m.Key.ReadableString(), len(violations))
+		for _, v := range violations {
+			e.LogPersister.Errorf("  - %s", v)
+		}
+	}
+
+	if failed > 0 {
+		e.LogPersister.Errorf("%d of %d manifests violated the policies", failed, len(manifests))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("All %d manifests satisfied the policies", len(manifests))
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) newPolicyChecker(ctx context.Context, opts *config.K8sPolicyCheckStageOptions, workDir string) (policyChecker, error) {
+	policies := make([]string, 0, len(opts.Policies))
+	for _, p := range opts.Policies {
+		policies = append(policies, filepath.Join(e.appDir, p))
+	}
+
+	switch opts.Engine {
+	case config.PolicyEngineOPA:
+		path, installed, err := toolregistry.DefaultRegistry().Opa(ctx, opts.Version)
+		if err != nil {
+			return nil, err
+		}
+		if installed {
+			e.LogPersister.Infof("Opa %q has just been installed to %q because of no pre-installed binary for that version", opts.Version, path)
+		}
+		c := &opaChecker{
+			execPath: path,
+			workDir:  workDir,
+			policies: policies,
+			query:    opts.Query,
+		}
+		if opts.Bundle != "" {
+			if c.bundle, err = e.prepareBundle(ctx, opts.Bundle, workDir); err != nil {
+				return nil, err
+			}
+		}
+		return c, nil
+
+	case config.PolicyEngineCUE:
+		path, installed, err := toolregistry.DefaultRegistry().Cue(ctx, opts.Version)
+		if err != nil {
+			return nil, err
+		}
+		if installed {
+			e.LogPersister.Infof("Cue %q has just been installed to %q because of no pre-installed binary for that version", opts.Version, path)
+		}
+		files, err := cueFiles(policies)
+		if err != nil {
+			return nil, err
+		}
+		return &cueChecker{
+			execPath: path,
+			workDir:  workDir,
+			policies: files,
+			expr:     opts.Query,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported policy engine %s", opts.Engine)
+	}
+}
+
+// prepareBundle returns the local path of the given bundle.
+// The bundle is downloaded into the working directory when it is a remote one.
+func (e *deployExecutor) prepareBundle(ctx context.Context, bundle, workDir string) (string, error) {
+	if !strings.HasPrefix(bundle, "http://") && !strings.HasPrefix(bundle, "https://") {
+		return filepath.Join(e.appDir, bundle), nil
+	}
+
+	e.LogPersister.Infof("Fetching policy bundle from %s", bundle)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bundle, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d while fetching bundle %s", resp.StatusCode, bundle)
+	}
+
+	path := filepath.Join(workDir, "bundle.tar.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+type opaChecker struct {
+	execPath string
+	workDir  string
+	policies []string
+	bundle   string
+	query    string
+}
+
+type opaOutput struct {
+	Result []struct {
+		Expressions []struct {
+			Value interface{} `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+func (c *opaChecker) Check(ctx context.Context, m provider.Manifest) ([]string, error) {
+	data, err := m.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	input := filepath.Join(c.workDir, "input.json")
+	if err := ioutil.WriteFile(input, data, 0644); err != nil {
+		return nil, err
+	}
+
+	args := []string{"eval", "--format", "json", "--input", input}
+	for _, p := range c.policies {
+		args = append(args, "--data", p)
+	}
+	if c.bundle != "" {
+		args = append(args, "--bundle", c.bundle)
+	}
+	query := c.query
+	if query == "" {
+		query = defaultOPAQuery
+	}
+	args = append(args, query)
+
+	var stdout, stderr bytes.Buffer
+	cmd := toolexec.CommandContext(ctx, c.execPath, args...)
+	cmd.Dir = c.workDir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to evaluate policies: %s (%w)", stderr.String(), err)
+	}
+	return parseOPAOutput(stdout.Bytes())
+}
+
+// parseOPAOutput collects the violation messages from the result of opa eval.
+// An undefined query is considered as no violation.
+// Beside plain strings, the conftest style objects containing a "msg" field are supported.
+func parseOPAOutput(data []byte) ([]string, error) {
+	var out opaOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("malformed output of opa eval (%w)", err)
+	}
+
+	var violations []string
+	add := func(v interface{}) {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			violations = append(violations, v)
+		case map[string]interface{}:
+			if msg, ok := v["msg"].(string); ok {
+				violations = append(violations, msg)
+				return
+			}
+			b, _ := json.Marshal(v)
+			violations = append(violations, string(b))
+		case bool:
+			// A boolean rule tells whether the manifest was denied or not.
+			if v {
+				violations = append(violations, "denied by policy")
+			}
+		default:
+			b, _ := json.Marshal(v)
+			violations = append(violations, string(b))
+		}
+	}
+	for _, r := range out.Result {
+		for _, e := range r.Expressions {
+			if values, ok := e.Value.([]interface{}); ok {
+				for _, v := range values {
+					add(v)
+				}
+				continue
+			}
+			add(e.Value)
+		}
+	}
+	return violations, nil
+}
+
+type cueChecker struct {
+	execPath string
+	workDir  string
+	policies []string
+	expr     string
+}
+
+func (c *cueChecker) Check(ctx context.Context, m provider.Manifest) ([]string, error) {
+	data, err := m.YamlBytes()
+	if err != nil {
+		return nil, err
+	}
+	input := filepath.Join(c.workDir, "manifest.yaml")
+	if err := ioutil.WriteFile(input, data, 0644); err != nil {
+		return nil, err
+	}
+
+	args := []string{"vet"}
+	if c.expr != "" {
+		args = append(args, "-d", c.expr)
+	}
+	args = append(args, c.policies...)
+	args = append(args, input)
+
+	cmd := toolexec.CommandContext(ctx, c.execPath, args...)
+	cmd.Dir = c.workDir
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil, nil
+	}
+	// cue vet exits with a non-zero code when the data does not satisfy the policies.
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return nil, err
+	}
+	violations := parseCueOutput(out)
+	if len(violations) == 0 {
+		return nil, fmt.Errorf("failed to evaluate policies (%w)", err)
+	}
+	return violations, nil
+}
+
+// parseCueOutput returns the non-empty lines of the cue vet output.
+func parseCueOutput(out []byte) []string {
+	var lines []string
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// cueFiles expands the given directories into the CUE files inside them.
+func cueFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(p, "*.cue"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no cue file was found in the policies")
+	}
+	return files, nil
+}
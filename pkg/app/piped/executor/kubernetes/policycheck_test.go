@@ -0,0 +1,137 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakePolicyChecker struct {
+	violations map[string][]string
+}
+
+func (c *fakePolicyChecker) Check(_ context.Context, m provider.Manifest) ([]string, error) {
+	return c.violations[m.Key.Name], nil
+}
+
+func TestCheckPolicies(t *testing.T) {
+	manifests := []provider.Manifest{
+		provider.MakeManifest(provider.ResourceKey{
+			APIVersion: "apps/v1",
+			Kind:       provider.KindDeployment,
+			Name:       "foo",
+		}, &unstructured.Unstructured{}),
+		provider.MakeManifest(provider.ResourceKey{
+			APIVersion: "v1",
+			Kind:       provider.KindService,
+			Name:       "bar",
+		}, &unstructured.Unstructured{}),
+	}
+
+	testcases := []struct {
+		name       string
+		violations map[string][]string
+		want       model.StageStatus
+	}{
+		{
+			name: "all manifests passed",
+			want: model.StageStatus_STAGE_SUCCESS,
+		},
+		{
+			name: "one manifest violated policies",
+			violations: map[string][]string{
+				"foo": {"containers must not run as root"},
+			},
+			want: model.StageStatus_STAGE_FAILURE,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &deployExecutor{
+				Input: executor.Input{
+					LogPersister: &fakeLogPersister{},
+					Logger:       zap.NewNop(),
+				},
+			}
+			got := e.checkPolicies(context.Background(), &fakePolicyChecker{violations: tc.violations}, manifests)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseOPAOutput(t *testing.T) {
+	testcases := []struct {
+		name    string
+		output  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "undefined query",
+			output: `{}`,
+		},
+		{
+			name:   "no violation",
+			output: `{"result":[{"expressions":[{"value":[],"text":"data.main.deny"}]}]}`,
+		},
+		{
+			name:   "string messages",
+			output: `{"result":[{"expressions":[{"value":["image tag must not be latest","replicas must be at least 2"]}]}]}`,
+			want:   []string{"image tag must not be latest", "replicas must be at least 2"},
+		},
+		{
+			name:   "conftest style messages",
+			output: `{"result":[{"expressions":[{"value":[{"msg":"missing owner label","details":{}}]}]}]}`,
+			want:   []string{"missing owner label"},
+		},
+		{
+			name:   "boolean rule",
+			output: `{"result":[{"expressions":[{"value":true}]}]}`,
+			want:   []string{"denied by policy"},
+		},
+		{
+			name:    "malformed output",
+			output:  `error`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseOPAOutput([]byte(tc.output))
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseCueOutput(t *testing.T) {
+	out := `spec.replicas: invalid value 1 (out of bound >=2):
+    ./policies/deployment.cue:5:13
+
+`
+	got := parseCueOutput([]byte(out))
+	require.Len(t, got, 2)
+	assert.Equal(t, "spec.replicas: invalid value 1 (out of bound >=2):", got[0])
+}
@@ -95,11 +95,12 @@ func (e *deployExecutor) ensurePrimaryRollout(ctx context.Context) model.StageSt
 		routingMethod == config.KubernetesTrafficRoutingMethodPodSelector &&
 		e.deployCfg.HasStage(model.StageK8sTrafficRouting) {
 		workloads := findWorkloadManifests(primaryManifests, e.deployCfg.Workloads)
+		label := newVariantLabel(e.deployCfg.VariantLabel)
 		var invalid bool
 		for _, m := range workloads {
-			if err := checkVariantSelectorInWorkload(m, primaryVariant); err != nil {
+			if err := checkVariantSelectorInWorkload(m, label, primaryVariant); err != nil {
 				invalid = true
-				e.LogPersister.Errorf("Missing %q in selector of workload %s (%v)", variantLabel+": "+primaryVariant, m.Key.ReadableString(), err)
+				e.LogPersister.Errorf("Missing %q in selector of workload %s (%v)", label.format(primaryVariant), m.Key.ReadableString(), err)
 			}
 		}
 		if invalid {
@@ -130,12 +131,21 @@ func (e *deployExecutor) ensurePrimaryRollout(ctx context.Context) model.StageSt
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	// The secrets must be synced before starting the workloads referencing them.
+	if options.WaitForSecrets {
+		workloads := findWorkloadManifests(primaryManifests, e.deployCfg.Workloads)
+		if err := ensureSecretsReady(ctx, e.provider, primaryManifests, workloads, e.LogPersister); err != nil {
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+
 	// Start applying all manifests to add or update running resources.
 	e.LogPersister.Info("Start rolling out PRIMARY variant...")
 	if err := applyManifests(ctx, e.provider, primaryManifests, e.deployCfg.Input.Namespace, e.LogPersister); err != nil {
 		return model.StageStatus_STAGE_FAILURE
 	}
 	e.LogPersister.Success("Successfully rolled out PRIMARY variant")
+	e.recordProvenance(ctx, primaryManifests)
 
 	if !options.Prune {
 		e.LogPersister.Info("Resource GC was skipped because sync.prune was not configured")
@@ -212,9 +222,10 @@ func (e *deployExecutor) generatePrimaryManifests(manifests []provider.Manifest,
 	// have the variant label in their selector.
 	if opts.AddVariantLabelToSelector {
 		workloads := findWorkloadManifests(manifests, e.deployCfg.Workloads)
+		label := newVariantLabel(e.deployCfg.VariantLabel)
 		for _, m := range workloads {
-			if err := ensureVariantSelectorInWorkload(m, primaryVariant); err != nil {
-				return nil, fmt.Errorf("unable to check/set %q in selector of workload %s (%v)", variantLabel+": "+primaryVariant, m.Key.ReadableString(), err)
+			if err := ensureVariantSelectorInWorkload(m, label, primaryVariant); err != nil {
+				return nil, fmt.Errorf("unable to check/set %q in selector of workload %s (%v)", label.format(primaryVariant), m.Key.ReadableString(), err)
 			}
 		}
 	}
@@ -228,7 +239,7 @@ func (e *deployExecutor) generatePrimaryManifests(manifests []provider.Manifest,
 		}
 		services = duplicateManifests(services, "")
 
-		generatedServices, err := generateVariantServiceManifests(services, primaryVariant, suffix)
+		generatedServices, err := generateVariantServiceManifests(services, newVariantLabel(e.deployCfg.VariantLabel), primaryVariant, suffix, nil)
 		if err != nil {
 			return nil, err
 		}
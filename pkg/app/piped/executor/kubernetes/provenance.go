@@ -0,0 +1,160 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/model"
+	"github.com/pipe-cd/pipe/pkg/version"
+)
+
+var (
+	toolVersionRegex = regexp.MustCompile(`\d+\.\d+\.\d+`)
+	// The versions of the tools bundled with piped keyed by their paths.
+	bundledToolVersions sync.Map
+)
+
+// recordProvenance reports what exactly was applied by this stage
+// to allow auditors to trace the running resources back to their sources.
+// Since this is just an additional record, a failure does not fail the stage.
+func (e *deployExecutor) recordProvenance(ctx context.Context, manifests []provider.Manifest) {
+	if e.ProvenanceStore == nil {
+		return
+	}
+	digest, err := digestManifests(manifests)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to compute the digest of the applied manifests (%v)", err)
+		return
+	}
+
+	provenance := &model.DeploymentProvenance{
+		DeploymentId:     e.Deployment.Id,
+		ApplicationId:    e.Deployment.ApplicationId,
+		Kind:             e.Deployment.Kind,
+		PipedId:          e.PipedConfig.PipedID,
+		PipedVersion:     version.Get().Version,
+		RepositoryRemote: e.Deployment.GetGitPath().GetRepo().GetRemote(),
+		CommitHash:       e.commit,
+		StageId:          e.Stage.Id,
+		ManifestsDigest:  digest,
+		Images:           provenanceImages(findImages(manifests)),
+		Tools:            e.provenanceTools(ctx),
+		CreatedAt:        time.Now().Unix(),
+	}
+	if err := e.ProvenanceStore.PutProvenance(ctx, provenance); err != nil {
+		e.LogPersister.Errorf("Unable to report the provenance of the applied manifests (%v)", err)
+		return
+	}
+	e.LogPersister.Infof("Recorded the provenance of the applied manifests (digest: %s)", digest)
+}
+
+// digestManifests computes the sha256 digest of the given manifests.
+// The manifests are sorted by their keys so that the digest does not depend on their order.
+func digestManifests(manifests []provider.Manifest) (string, error) {
+	sorted := make([]provider.Manifest, len(manifests))
+	copy(sorted, manifests)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Key.String() < sorted[j].Key.String()
+	})
+
+	var buf bytes.Buffer
+	for _, m := range sorted {
+		data, err := m.YamlBytes()
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal manifest %s: %w", m.Key.ReadableString(), err)
+		}
+		buf.WriteString("---\n")
+		buf.Write(data)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+func provenanceImages(images []string) []*model.ProvenanceImage {
+	out := make([]*model.ProvenanceImage, 0, len(images))
+	for _, image := range images {
+		p := &model.ProvenanceImage{
+			Reference: image,
+		}
+		// The digest is known only when the image is referenced by it, e.g. nginx@sha256:abc...
+		if i := strings.LastIndex(image, "@"); i >= 0 {
+			p.Digest = image[i+1:]
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// provenanceTools returns the tools used to render and apply the manifests along with their actual versions.
+func (e *deployExecutor) provenanceTools(ctx context.Context) []*model.ProvenanceTool {
+	var (
+		input = provider.ResolveToolVersions(e.deployCfg.Input, e.cluster)
+		reg   = toolregistry.DefaultRegistry()
+	)
+	tools := []*model.ProvenanceTool{
+		{Name: "kubectl", Version: e.toolVersion(ctx, input.KubectlVersion, reg.Kubectl, "version", "--client", "-o", "json")},
+	}
+	switch provider.DetermineTemplatingMethod(input, e.appDir) {
+	case provider.TemplatingMethodHelm:
+		tools = append(tools, &model.ProvenanceTool{Name: "helm", Version: e.toolVersion(ctx, input.HelmVersion, reg.Helm, "version", "--short")})
+	case provider.TemplatingMethodKustomize:
+		tools = append(tools, &model.ProvenanceTool{Name: "kustomize", Version: e.toolVersion(ctx, input.KustomizeVersion, reg.Kustomize, "version", "--short")})
+	}
+	return tools
+}
+
+// toolVersion returns the given version, or asks the binary bundled with piped
+// for its version when the application did not specify one.
+// Empty is returned when the version could not be determined.
+func (e *deployExecutor) toolVersion(ctx context.Context, version string, find func(context.Context, string) (string, bool, error), args ...string) string {
+	if version != "" {
+		return version
+	}
+	path, _, err := find(ctx, "")
+	if err != nil {
+		return ""
+	}
+	if v, ok := bundledToolVersions.Load(path); ok {
+		return v.(string)
+	}
+	out, err := toolexec.CommandContext(ctx, path, args...).Output()
+	if err != nil {
+		e.Logger.Warn("failed to get the version of bundled tool", zap.String("path", path), zap.Error(err))
+		return ""
+	}
+	v := parseToolVersion(out)
+	bundledToolVersions.Store(path, v)
+	return v
+}
+
+// parseToolVersion returns the first semantic version found in the given output of a version command.
+func parseToolVersion(out []byte) string {
+	return toolVersionRegex.FindString(string(out))
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestDigestManifests(t *testing.T) {
+	makeManifest := func(kind, name string, replicas int64) provider.Manifest {
+		return provider.MakeManifest(provider.ResourceKey{
+			APIVersion: "apps/v1",
+			Kind:       kind,
+			Name:       name,
+		}, &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"spec": map[string]interface{}{"replicas": replicas},
+			},
+		})
+	}
+	deployment := makeManifest(provider.KindDeployment, "foo", 2)
+	service := makeManifest(provider.KindService, "foo", 0)
+
+	digest, err := digestManifests([]provider.Manifest{deployment, service})
+	require.NoError(t, err)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", digest)
+
+	// The digest does not depend on the order of manifests.
+	reordered, err := digestManifests([]provider.Manifest{service, deployment})
+	require.NoError(t, err)
+	assert.Equal(t, digest, reordered)
+
+	// Any change in the manifests changes the digest.
+	changed, err := digestManifests([]provider.Manifest{makeManifest(provider.KindDeployment, "foo", 3), service})
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, changed)
+}
+
+func TestProvenanceImages(t *testing.T) {
+	images := []string{
+		"gcr.io/pipecd/helloworld:v0.1.0",
+		"gcr.io/pipecd/helloworld@sha256:0c1f4b3e5ad1b9b3b0d6f4a8b7a9f1c2e3d4c5b6a7980f1e2d3c4b5a69788796",
+	}
+	expected := []*model.ProvenanceImage{
+		{
+			Reference: "gcr.io/pipecd/helloworld:v0.1.0",
+		},
+		{
+			Reference: "gcr.io/pipecd/helloworld@sha256:0c1f4b3e5ad1b9b3b0d6f4a8b7a9f1c2e3d4c5b6a7980f1e2d3c4b5a69788796",
+			Digest:    "sha256:0c1f4b3e5ad1b9b3b0d6f4a8b7a9f1c2e3d4c5b6a7980f1e2d3c4b5a69788796",
+		},
+	}
+	assert.Equal(t, expected, provenanceImages(images))
+}
+
+func TestParseToolVersion(t *testing.T) {
+	testcases := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{
+			name:     "kubectl",
+			output:   `{"clientVersion": {"major": "1", "minor": "18", "gitVersion": "v1.18.2", "goVersion": "go1.13.9"}}`,
+			expected: "1.18.2",
+		},
+		{
+			name:     "helm",
+			output:   "v3.2.1+gfe51cd1\n",
+			expected: "3.2.1",
+		},
+		{
+			name:     "kustomize",
+			output:   "{kustomize/v3.8.1  2020-07-16T00:58:46Z  }\n",
+			expected: "3.8.1",
+		},
+		{
+			name:   "unknown",
+			output: "unknown flag: --short",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, parseToolVersion([]byte(tc.output)))
+		})
+	}
+}
@@ -49,6 +49,11 @@ func (e *rollbackExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 }
 
 func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus {
+	// The release upgraded by helm is rolled back by helm as well.
+	if value, ok := e.MetadataStore.Get(helmReleaseRevisionMetadataKey); ok {
+		return e.ensureHelmReleaseRollback(ctx, value)
+	}
+
 	// There is nothing to do if this is the first deployment.
 	if e.Deployment.RunningCommitHash == "" {
 		e.LogPersister.Errorf("Unable to determine the last deployed commit to rollback. It seems this is the first deployment.")
@@ -67,6 +72,12 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	// The release was not upgraded yet when no revision was saved.
+	if deployCfg.Input.IsHelmReleaseMode() {
+		e.LogPersister.Info("The release was not changed by this deployment so there is nothing to roll back")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
 	if deployCfg.Input.HelmChart != nil {
 		chartRepoName := deployCfg.Input.HelmChart.Repository
 		if chartRepoName != "" {
@@ -74,7 +85,12 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 		}
 	}
 
-	p := provider.NewProvider(e.Deployment.ApplicationName, ds.AppDir, ds.RepoDir, e.Deployment.GitPath.ConfigFilename, deployCfg.Input, e.Logger)
+	cluster, ok := findCloudProvider(&e.Input)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	p := provider.NewProvider(e.Deployment.ApplicationName, ds.AppDir, ds.RepoDir, e.Deployment.GitPath.ConfigFilename, deployCfg.Input, cluster, e.Logger)
 	e.Logger.Info("start executing kubernetes stage",
 		zap.String("stage-name", e.Stage.Name),
 		zap.String("app-dir", ds.AppDir),
@@ -100,9 +116,10 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 	// have the variant label in their selector.
 	if deployCfg.QuickSync.AddVariantLabelToSelector {
 		workloads := findWorkloadManifests(manifests, deployCfg.Workloads)
+		label := newVariantLabel(deployCfg.VariantLabel)
 		for _, m := range workloads {
-			if err := ensureVariantSelectorInWorkload(m, primaryVariant); err != nil {
-				e.LogPersister.Errorf("Unable to check/set %q in selector of workload %s (%v)", variantLabel+": "+primaryVariant, m.Key.ReadableString(), err)
+			if err := ensureVariantSelectorInWorkload(m, label, primaryVariant); err != nil {
+				e.LogPersister.Errorf("Unable to check/set %q in selector of workload %s (%v)", label.format(primaryVariant), m.Key.ReadableString(), err)
 				return model.StageStatus_STAGE_FAILURE
 			}
 		}
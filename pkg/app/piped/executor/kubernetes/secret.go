@@ -0,0 +1,161 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+)
+
+// ensureSecretsReady applies the ExternalSecret and SealedSecret manifests generating
+// the secrets referenced by the given workloads and waits until they become ready.
+// This prevents the workloads from crashing because their secrets were not synced yet.
+func ensureSecretsReady(ctx context.Context, applier provider.Applier, manifests, workloads []provider.Manifest, lp executor.LogPersister) error {
+	secrets, err := findReferencedSecretGenerators(manifests, workloads)
+	if err != nil {
+		lp.Errorf("Unable to find the secrets referenced by workloads (%v)", err)
+		return err
+	}
+	if len(secrets) == 0 {
+		lp.Info("There is no ExternalSecret or SealedSecret referenced by workloads to wait for")
+		return nil
+	}
+
+	lp.Infof("Start applying %d secret manifests referenced by workloads", len(secrets))
+	for _, m := range secrets {
+		if err := applier.ApplyManifest(ctx, m); err != nil {
+			lp.Errorf("Failed to apply manifest: %s (%v)", m.Key.ReadableString(), err)
+			return err
+		}
+		lp.Successf("- applied manifest: %s", m.Key.ReadableString())
+	}
+
+	lp.Info("Waiting for the secrets to become ready")
+	for _, m := range secrets {
+		if err := applier.WaitForReady(ctx, m.Key); err != nil {
+			lp.Errorf("Failed while waiting for %s to become ready (%v)", m.Key.ReadableString(), err)
+			return err
+		}
+	}
+	lp.Successf("Successfully synced %d secrets", len(secrets))
+	return nil
+}
+
+// findReferencedSecretGenerators returns the ExternalSecret and SealedSecret manifests
+// whose generating secret is referenced by at least one of the given workloads.
+func findReferencedSecretGenerators(manifests, workloads []provider.Manifest) ([]provider.Manifest, error) {
+	referenced := make(map[string]struct{})
+	for _, w := range workloads {
+		names, err := referencedSecretNames(w)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range names {
+			referenced[n] = struct{}{}
+		}
+	}
+
+	var out []provider.Manifest
+	for _, m := range manifests {
+		var target string
+		switch m.Key.Kind {
+		case provider.KindExternalSecret:
+			var es struct {
+				Spec struct {
+					Target struct {
+						Name string `json:"name"`
+					} `json:"target"`
+				} `json:"spec"`
+			}
+			if err := m.ConvertToStructuredObject(&es); err != nil {
+				return nil, err
+			}
+			target = es.Spec.Target.Name
+		case provider.KindSealedSecret:
+			var ss struct {
+				Spec struct {
+					Template struct {
+						Metadata struct {
+							Name string `json:"name"`
+						} `json:"metadata"`
+					} `json:"template"`
+				} `json:"spec"`
+			}
+			if err := m.ConvertToStructuredObject(&ss); err != nil {
+				return nil, err
+			}
+			target = ss.Spec.Template.Metadata.Name
+		default:
+			continue
+		}
+		// The generated secret has the same name with the generator by default.
+		if target == "" {
+			target = m.Key.Name
+		}
+		if _, ok := referenced[target]; ok {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// referencedSecretNames returns the names of all secrets referenced by the pod template of the given workload.
+func referencedSecretNames(workload provider.Manifest) ([]string, error) {
+	var w struct {
+		Spec struct {
+			Template corev1.PodTemplateSpec `json:"template"`
+		} `json:"spec"`
+	}
+	if err := workload.ConvertToStructuredObject(&w); err != nil {
+		return nil, err
+	}
+	pod := w.Spec.Template.Spec
+
+	var names []string
+	for _, v := range pod.Volumes {
+		if v.Secret != nil {
+			names = append(names, v.Secret.SecretName)
+		}
+		if v.Projected != nil {
+			for _, s := range v.Projected.Sources {
+				if s.Secret != nil {
+					names = append(names, s.Secret.Name)
+				}
+			}
+		}
+	}
+	for _, s := range pod.ImagePullSecrets {
+		names = append(names, s.Name)
+	}
+
+	containers := append(append([]corev1.Container{}, pod.InitContainers...), pod.Containers...)
+	for _, c := range containers {
+		for _, env := range c.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+				names = append(names, env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+		for _, envFrom := range c.EnvFrom {
+			if envFrom.SecretRef != nil {
+				names = append(names, envFrom.SecretRef.Name)
+			}
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+)
+
+func TestFindReferencedSecretGenerators(t *testing.T) {
+	const manifests = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: simple
+spec:
+  template:
+    spec:
+      volumes:
+      - name: credentials
+        secret:
+          secretName: credentials
+      containers:
+      - name: simple
+        env:
+        - name: TOKEN
+          valueFrom:
+            secretKeyRef:
+              name: token
+              key: value
+        envFrom:
+        - secretRef:
+            name: sealed-config
+---
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: credentials
+spec:
+  refreshInterval: 1h
+---
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: token-generator
+spec:
+  target:
+    name: token
+---
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: unused
+---
+apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: sealed
+spec:
+  template:
+    metadata:
+      name: sealed-config
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: token
+`
+	all, err := provider.ParseManifests(manifests)
+	require.NoError(t, err)
+	require.Equal(t, 6, len(all))
+
+	got, err := findReferencedSecretGenerators(all, all[:1])
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(got))
+	for _, m := range got {
+		names = append(names, m.Key.Kind+"/"+m.Key.Name)
+	}
+	assert.Equal(t, []string{"ExternalSecret/credentials", "ExternalSecret/token-generator", "SealedSecret/sealed"}, names)
+}
@@ -23,6 +23,10 @@ import (
 )
 
 func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
+	if e.releaser != nil {
+		return e.ensureHelmReleaseSync(ctx)
+	}
+
 	// Load the manifests at the specified commit.
 	e.LogPersister.Infof("Loading manifests at commit %s for handling", e.commit)
 	manifests, err := loadManifests(
@@ -47,9 +51,10 @@ func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
 	// have the variant label in their selector.
 	if e.deployCfg.QuickSync.AddVariantLabelToSelector {
 		workloads := findWorkloadManifests(manifests, e.deployCfg.Workloads)
+		label := newVariantLabel(e.deployCfg.VariantLabel)
 		for _, m := range workloads {
-			if err := ensureVariantSelectorInWorkload(m, primaryVariant); err != nil {
-				e.LogPersister.Errorf("Unable to check/set %q in selector of workload %s (%v)", variantLabel+": "+primaryVariant, m.Key.ReadableString(), err)
+			if err := ensureVariantSelectorInWorkload(m, label, primaryVariant); err != nil {
+				e.LogPersister.Errorf("Unable to check/set %q in selector of workload %s (%v)", label.format(primaryVariant), m.Key.ReadableString(), err)
 				return model.StageStatus_STAGE_FAILURE
 			}
 		}
@@ -70,10 +75,19 @@ func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	// The secrets must be synced before starting the workloads referencing them.
+	if e.deployCfg.QuickSync.WaitForSecrets {
+		workloads := findWorkloadManifests(manifests, e.deployCfg.Workloads)
+		if err := ensureSecretsReady(ctx, e.provider, manifests, workloads, e.LogPersister); err != nil {
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+
 	// Start applying all manifests to add or update running resources.
 	if err := applyManifests(ctx, e.provider, manifests, e.deployCfg.Input.Namespace, e.LogPersister); err != nil {
 		return model.StageStatus_STAGE_FAILURE
 	}
+	e.recordProvenance(ctx, manifests)
 
 	if !e.deployCfg.QuickSync.Prune {
 		e.LogPersister.Info("Resource GC was skipped because sync.prune was not configured")
@@ -114,24 +128,34 @@ func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
 	return model.StageStatus_STAGE_SUCCESS
 }
 
+// findRemoveResources returns the keys of the live resources which are no longer defined in the given manifests.
+// Since the manifests of one application can be placed in multiple namespaces,
+// the namespaced resources are compared with their namespaces while the ones
+// not belonging to any namespace are compared by their kinds and names only.
 func findRemoveResources(manifests []provider.Manifest, liveResources []provider.Manifest) []provider.ResourceKey {
 	var (
-		keys       = make(map[provider.ResourceKey]struct{}, len(manifests))
-		removeKeys = make([]provider.ResourceKey, 0)
+		keys              = make(map[provider.ResourceKey]struct{}, len(manifests))
+		namespacelessKeys = make(map[provider.ResourceKey]struct{}, len(manifests))
+		removeKeys        = make([]provider.ResourceKey, 0)
 	)
 	for _, m := range manifests {
+		keys[m.Key] = struct{}{}
 		key := m.Key
 		key.Namespace = ""
-		keys[key] = struct{}{}
+		namespacelessKeys[key] = struct{}{}
 	}
 	for _, m := range liveResources {
-		key := m.Key
-		key.Namespace = ""
-		if _, ok := keys[key]; ok {
+		if _, ok := keys[m.Key]; ok {
 			continue
 		}
-		key.Namespace = m.Key.Namespace
-		removeKeys = append(removeKeys, key)
+		if !m.HasNamespace() {
+			key := m.Key
+			key.Namespace = ""
+			if _, ok := namespacelessKeys[key]; ok {
+				continue
+			}
+		}
+		removeKeys = append(removeKeys, m.Key)
 	}
 	return removeKeys
 }
@@ -231,6 +231,59 @@ func TestFindRemoveResources(t *testing.T) {
 			},
 			want: []provider.ResourceKey{},
 		},
+		{
+			name: "remove resource running in namespace no longer defined in manifests",
+			manifests: []provider.Manifest{
+				provider.MakeManifest(provider.ResourceKey{
+					APIVersion: "v1",
+					Kind:       "Service",
+					Namespace:  "foo",
+					Name:       "foo",
+				}, makeNamespacedObject("foo")),
+			},
+			liveResources: []provider.Manifest{
+				provider.MakeManifest(provider.ResourceKey{
+					APIVersion: "v1",
+					Kind:       "Service",
+					Namespace:  "foo",
+					Name:       "foo",
+				}, makeNamespacedObject("foo")),
+				provider.MakeManifest(provider.ResourceKey{
+					APIVersion: "v1",
+					Kind:       "Service",
+					Namespace:  "bar",
+					Name:       "foo",
+				}, makeNamespacedObject("bar")),
+			},
+			want: []provider.ResourceKey{
+				{
+					APIVersion: "v1",
+					Kind:       "Service",
+					Namespace:  "bar",
+					Name:       "foo",
+				},
+			},
+		},
+		{
+			name: "don't remove resource not belonging to any namespace",
+			manifests: []provider.Manifest{
+				provider.MakeManifest(provider.ResourceKey{
+					APIVersion: "cert-manager.io/v1",
+					Kind:       "ClusterIssuer",
+					Namespace:  "app",
+					Name:       "foo",
+				}, makeNamespacedObject("")),
+			},
+			liveResources: []provider.Manifest{
+				provider.MakeManifest(provider.ResourceKey{
+					APIVersion: "cert-manager.io/v1",
+					Kind:       "ClusterIssuer",
+					Namespace:  provider.DefaultNamespace,
+					Name:       "foo",
+				}, makeNamespacedObject("")),
+			},
+			want: []provider.ResourceKey{},
+		},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -239,3 +292,9 @@ func TestFindRemoveResources(t *testing.T) {
 		})
 	}
 }
+
+func makeNamespacedObject(namespace string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	u.SetNamespace(namespace)
+	return u
+}
@@ -26,6 +26,7 @@ import (
 	istiov1beta1 "istio.io/api/networking/v1beta1"
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
@@ -45,6 +46,17 @@ func (e *deployExecutor) ensureTrafficRouting(ctx context.Context) model.StageSt
 		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
 		return model.StageStatus_STAGE_FAILURE
 	}
+
+	// Decide traffic routing percentage for all variants.
+	primaryPercent, canaryPercent, baselinePercent := options.Percentages()
+	e.saveTrafficRoutingMetadata(ctx, primaryPercent, canaryPercent, baselinePercent)
+
+	return e.routeTraffic(ctx, commitHash, primaryPercent, canaryPercent, baselinePercent)
+}
+
+// routeTraffic splits the traffic to the variants as the given percentages
+// by applying the traffic routing manifest at the given commit.
+func (e *deployExecutor) routeTraffic(ctx context.Context, commitHash string, primaryPercent, canaryPercent, baselinePercent int) model.StageStatus {
 	method := config.DetermineKubernetesTrafficRoutingMethod(e.deployCfg.TrafficRouting)
 
 	// Load the manifests at the triggered commit.
@@ -52,7 +64,7 @@ func (e *deployExecutor) ensureTrafficRouting(ctx context.Context) model.StageSt
 	manifests, err := loadManifests(
 		ctx,
 		e.Deployment.ApplicationId,
-		e.commit,
+		commitHash,
 		e.AppManifestsCache,
 		e.provider,
 		e.Logger,
@@ -68,10 +80,6 @@ func (e *deployExecutor) ensureTrafficRouting(ctx context.Context) model.StageSt
 		return model.StageStatus_STAGE_FAILURE
 	}
 
-	// Decide traffic routing percentage for all variants.
-	primaryPercent, canaryPercent, baselinePercent := options.Percentages()
-	e.saveTrafficRoutingMetadata(ctx, primaryPercent, canaryPercent, baselinePercent)
-
 	// Find traffic routing manifests.
 	trafficRoutingManifests, err := findTrafficRoutingManifests(manifests, e.deployCfg.Service.Name, e.deployCfg.TrafficRouting)
 	if err != nil {
@@ -94,11 +102,12 @@ func (e *deployExecutor) ensureTrafficRouting(ctx context.Context) model.StageSt
 	}
 	trafficRoutingManifest := trafficRoutingManifests[0]
 
-	// In case we are routing by PodSelector, the service manifest must contain variantLabel inside its selector.
+	// In case we are routing by PodSelector, the service manifest must contain the variant label inside its selector.
 	if method == config.KubernetesTrafficRoutingMethodPodSelector {
-		if err := checkVariantSelectorInService(trafficRoutingManifest, primaryVariant); err != nil {
+		label := newVariantLabel(e.deployCfg.VariantLabel)
+		if err := checkVariantSelectorInService(trafficRoutingManifest, label, primaryVariant); err != nil {
 			e.LogPersister.Errorf("Traffic routing by PodSelector requires %q inside the selector of Service manifest but it was unable to check that field in manifest %s (%v)",
-				variantLabel+": "+primaryVariant,
+				label.format(primaryVariant),
 				trafficRoutingManifest.Key.ReadableString(),
 				err,
 			)
@@ -171,6 +180,7 @@ func (e *deployExecutor) generateTrafficRoutingManifest(manifest provider.Manife
 		return manifest, nil
 	}
 
+	label := newVariantLabel(e.deployCfg.VariantLabel)
 	if cfg != nil && cfg.Method == config.KubernetesTrafficRoutingMethodIstio {
 		istioConfig := cfg.Istio
 		if istioConfig == nil {
@@ -178,9 +188,9 @@ func (e *deployExecutor) generateTrafficRoutingManifest(manifest provider.Manife
 		}
 
 		if strings.HasPrefix(manifest.Key.APIVersion, "v1alpha3") {
-			return generateVirtualServiceManifestV1Alpha3(manifest, istioConfig.Host, istioConfig.EditableRoutes, int32(canaryPercent), int32(baselinePercent))
+			return generateVirtualServiceManifestV1Alpha3(manifest, istioConfig.Host, istioConfig.EditableRoutes, label, int32(canaryPercent), int32(baselinePercent))
 		}
-		return generateVirtualServiceManifest(manifest, istioConfig.Host, istioConfig.EditableRoutes, int32(canaryPercent), int32(baselinePercent))
+		return generateVirtualServiceManifest(manifest, istioConfig.Host, istioConfig.EditableRoutes, label, int32(canaryPercent), int32(baselinePercent))
 	}
 
 	// Determine which variant will receive 100% percent of traffic.
@@ -194,7 +204,7 @@ func (e *deployExecutor) generateTrafficRoutingManifest(manifest provider.Manife
 		return manifest, fmt.Errorf("traffic routing by pod requires either PRIMARY or CANARY must be 100 (primary=%d, canary=%d)", primaryPercent, canaryPercent)
 	}
 
-	if err := manifest.AddStringMapValues(map[string]string{variantLabel: variant}, "spec", "selector"); err != nil {
+	if err := manifest.AddStringMapValues(map[string]string{label.key: label.value(variant)}, "spec", "selector"); err != nil {
 		return manifest, fmt.Errorf("unable to update selector for service %q because of: %v", manifest.Key.Name, err)
 	}
 
@@ -210,6 +220,13 @@ func (e *deployExecutor) saveTrafficRoutingMetadata(ctx context.Context, primary
 	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
 		e.Logger.Error("failed to save traffic routing percentages to metadata", zap.Error(err))
 	}
+
+	results := map[string]string{
+		executor.StageResultTraffic: fmt.Sprintf("primary: %d%%, canary: %d%%, baseline: %d%%", primary, canary, baseline),
+	}
+	if err := e.MetadataStore.SetStageResults(ctx, e.Stage.Id, results); err != nil {
+		e.Logger.Error("failed to save traffic routing percentages to stage results", zap.Error(err))
+	}
 }
 
 func findIstioVirtualServiceManifests(manifests []provider.Manifest, ref config.K8sResourceReference) ([]provider.Manifest, error) {
@@ -239,7 +256,7 @@ func findIstioVirtualServiceManifests(manifests []provider.Manifest, ref config.
 	return out, nil
 }
 
-func generateVirtualServiceManifest(m provider.Manifest, host string, editableRoutes []string, canaryPercent, baselinePercent int32) (provider.Manifest, error) {
+func generateVirtualServiceManifest(m provider.Manifest, host string, editableRoutes []string, label variantLabel, canaryPercent, baselinePercent int32) (provider.Manifest, error) {
 	// Because the loaded manifests are read-only
 	// so we duplicate them to avoid updating the shared manifests data in cache.
 	m = duplicateManifest(m, "")
@@ -292,7 +309,7 @@ func generateVirtualServiceManifest(m provider.Manifest, host string, editableRo
 		routes = append(routes, &istiov1beta1.HTTPRouteDestination{
 			Destination: &istiov1beta1.Destination{
 				Host:   host,
-				Subset: primaryVariant,
+				Subset: label.value(primaryVariant),
 			},
 			Weight: primaryWeight,
 		})
@@ -300,7 +317,7 @@ func generateVirtualServiceManifest(m provider.Manifest, host string, editableRo
 			routes = append(routes, &istiov1beta1.HTTPRouteDestination{
 				Destination: &istiov1beta1.Destination{
 					Host:   host,
-					Subset: canaryVariant,
+					Subset: label.value(canaryVariant),
 				},
 				Weight: canaryWeight,
 			})
@@ -309,7 +326,7 @@ func generateVirtualServiceManifest(m provider.Manifest, host string, editableRo
 			routes = append(routes, &istiov1beta1.HTTPRouteDestination{
 				Destination: &istiov1beta1.Destination{
 					Host:   host,
-					Subset: baselineVariant,
+					Subset: label.value(baselineVariant),
 				},
 				Weight: baselineWeight,
 			})
@@ -325,7 +342,7 @@ func generateVirtualServiceManifest(m provider.Manifest, host string, editableRo
 	return m, nil
 }
 
-func generateVirtualServiceManifestV1Alpha3(m provider.Manifest, host string, editableRoutes []string, canaryPercent, baselinePercent int32) (provider.Manifest, error) {
+func generateVirtualServiceManifestV1Alpha3(m provider.Manifest, host string, editableRoutes []string, label variantLabel, canaryPercent, baselinePercent int32) (provider.Manifest, error) {
 	// Because the loaded manifests are read-only
 	// so we duplicate them to avoid updating the shared manifests data in cache.
 	m = duplicateManifest(m, "")
@@ -378,7 +395,7 @@ func generateVirtualServiceManifestV1Alpha3(m provider.Manifest, host string, ed
 		routes = append(routes, &istiov1alpha3.HTTPRouteDestination{
 			Destination: &istiov1alpha3.Destination{
 				Host:   host,
-				Subset: primaryVariant,
+				Subset: label.value(primaryVariant),
 			},
 			Weight: primaryWeight,
 		})
@@ -386,7 +403,7 @@ func generateVirtualServiceManifestV1Alpha3(m provider.Manifest, host string, ed
 			routes = append(routes, &istiov1alpha3.HTTPRouteDestination{
 				Destination: &istiov1alpha3.Destination{
 					Host:   host,
-					Subset: canaryVariant,
+					Subset: label.value(canaryVariant),
 				},
 				Weight: canaryWeight,
 			})
@@ -395,7 +412,7 @@ func generateVirtualServiceManifestV1Alpha3(m provider.Manifest, host string, ed
 			routes = append(routes, &istiov1alpha3.HTTPRouteDestination{
 				Destination: &istiov1alpha3.Destination{
 					Host:   host,
-					Subset: baselineVariant,
+					Subset: label.value(baselineVariant),
 				},
 				Weight: baselineWeight,
 			})
@@ -411,19 +428,19 @@ func generateVirtualServiceManifestV1Alpha3(m provider.Manifest, host string, ed
 	return m, nil
 }
 
-func checkVariantSelectorInService(m provider.Manifest, variant string) error {
+func checkVariantSelectorInService(m provider.Manifest, label variantLabel, variant string) error {
 	selector, err := m.GetNestedStringMap("spec", "selector")
 	if err != nil {
 		return err
 	}
 
-	value, ok := selector[variantLabel]
+	value, ok := selector[label.key]
 	if !ok {
-		return fmt.Errorf("missing %s key in spec.selector", variantLabel)
+		return fmt.Errorf("missing %s key in spec.selector", label.key)
 	}
 
-	if value != variant {
-		return fmt.Errorf("require %s but got %s for %s key in spec.selector", variant, value, variantLabel)
+	if expected := label.value(variant); value != expected {
+		return fmt.Errorf("require %s but got %s for %s key in spec.selector", expected, value, label.key)
 	}
 	return nil
 }
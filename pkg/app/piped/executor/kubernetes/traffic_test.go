@@ -49,7 +49,7 @@ func TestGenerateVirtualServiceManifest(t *testing.T) {
 			require.NoError(t, err)
 			require.Equal(t, 1, len(manifests))
 
-			generatedManifest, err := generateVirtualServiceManifest(manifests[0], "helloworld", tc.editableRoutes, 30, 20)
+			generatedManifest, err := generateVirtualServiceManifest(manifests[0], "helloworld", tc.editableRoutes, newVariantLabel(nil), 30, 20)
 			assert.NoError(t, err)
 
 			expectedManifests, err := provider.LoadManifestsFromYAMLFile(tc.expectedFile)
@@ -120,7 +120,7 @@ spec:
 			require.NoError(t, err)
 			require.Equal(t, 1, len(manifests))
 
-			err = checkVariantSelectorInService(manifests[0], primaryVariant)
+			err = checkVariantSelectorInService(manifests[0], newVariantLabel(nil), primaryVariant)
 			assert.Equal(t, tc.expected, err)
 		})
 	}
@@ -0,0 +1,82 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// ensureVariantClean routes all traffic back to PRIMARY variant and removes
+// the CANARY, BASELINE variants left by the cancelled or failed deployment.
+// It is run instead of the rollback when the auto-rollback is disabled.
+func (e *deployExecutor) ensureVariantClean(ctx context.Context) model.StageStatus {
+	var failed bool
+
+	if e.isTrafficSplit() {
+		e.LogPersister.Info("Start routing all traffic back to PRIMARY variant")
+		if status := e.routeTraffic(ctx, e.commit, 100, 0, 0); status != model.StageStatus_STAGE_SUCCESS {
+			failed = true
+		}
+	}
+
+	e.LogPersister.Info("Start checking to ensure that the CANARY variant should be removed")
+	if value, ok := e.MetadataStore.Get(addedCanaryResourcesMetadataKey); ok {
+		resources := strings.Split(value, ",")
+		if err := removeCanaryResources(ctx, e.provider, resources, e.LogPersister); err != nil {
+			e.LogPersister.Errorf("Unable to remove canary resources: %v", err)
+			failed = true
+		}
+	}
+
+	e.LogPersister.Info("Start checking to ensure that the BASELINE variant should be removed")
+	if value, ok := e.MetadataStore.Get(addedBaselineResourcesMetadataKey); ok {
+		resources := strings.Split(value, ",")
+		if err := removeBaselineResources(ctx, e.provider, resources, e.LogPersister); err != nil {
+			e.LogPersister.Errorf("Unable to remove baseline resources: %v", err)
+			failed = true
+		}
+	}
+
+	if failed {
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.LogPersister.Success("Successfully cleaned all variants")
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// isTrafficSplit reports whether any K8S_TRAFFIC_ROUTING stage of the deployment
+// has routed a part of the traffic to CANARY or BASELINE variant.
+func (e *deployExecutor) isTrafficSplit() bool {
+	for _, s := range e.Deployment.Stages {
+		if s.Name != model.StageK8sTrafficRouting.String() {
+			continue
+		}
+		metadata, ok := e.MetadataStore.GetStageMetadata(s.Id)
+		if !ok {
+			continue
+		}
+		if isNonZeroPercentage(metadata[canaryMetadataKey]) || isNonZeroPercentage(metadata[baselineMetadataKey]) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNonZeroPercentage(v string) bool {
+	return v != "" && v != "0"
+}
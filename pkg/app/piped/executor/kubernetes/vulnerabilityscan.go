@@ -0,0 +1,148 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/imagescanner"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func (e *deployExecutor) ensureVulnerabilityScan(ctx context.Context) model.StageStatus {
+	options := e.StageConfig.K8sVulnerabilityScanStageOptions
+	if options == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	scannerCfg, ok := e.PipedConfig.GetImageScanner(options.Scanner)
+	if !ok {
+		e.LogPersister.Errorf("The specified image scanner %q was not found in piped configuration", options.Scanner)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	scanner, err := imagescanner.NewScanner(scannerCfg, e.Logger)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to create image scanner %s (%v)", options.Scanner, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// Load the manifests at the specified commit.
+	e.LogPersister.Infof("Loading manifests at commit %s for finding images", e.commit)
+	manifests, err := loadManifests(
+		ctx,
+		e.Deployment.ApplicationId,
+		e.commit,
+		e.AppManifestsCache,
+		e.provider,
+		e.Logger,
+	)
+	if err != nil {
+		e.LogPersister.Errorf("Failed while loading manifests (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.LogPersister.Successf("Successfully loaded %d manifests", len(manifests))
+
+	return e.scanImages(ctx, scanner, findImages(manifests), options)
+}
+
+func (e *deployExecutor) scanImages(ctx context.Context, scanner imagescanner.Scanner, images []string, options *config.K8sVulnerabilityScanStageOptions) model.StageStatus {
+	if len(images) == 0 {
+		e.LogPersister.Info("No image was found in the manifests")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	// Scan all images instead of stopping at the first vulnerable one
+	// to show all problems at once.
+	e.LogPersister.Infof("Start scanning %d images for the vulnerabilities with %s or higher severity", len(images), options.Severity)
+	var failed int
+	for _, image := range images {
+		vulns, err := scanner.Scan(ctx, image)
+		if err != nil {
+			e.LogPersister.Errorf("Failed while scanning image %s (%v)", image, err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+
+		blocking := filterBlockingVulnerabilities(image, vulns, options)
+		if len(blocking) == 0 {
+			e.LogPersister.Successf("- passed image: %s (%d vulnerabilities below the threshold or allowed)", image, len(vulns))
+			continue
+		}
+		failed++
+		e.LogPersister.Errorf("- image %s has %d blocking vulnerabilities:", image, len(blocking))
+		for _, v := range blocking {
+			fixed := v.FixedVersion
+			if fixed == "" {
+				fixed = "no fix"
+			}
+			e.LogPersister.Errorf("  - [%s] %s in %s %s (%s) %s", v.Severity, v.ID, v.Package, v.InstalledVersion, fixed, v.Title)
+		}
+	}
+
+	if failed > 0 {
+		e.LogPersister.Errorf("%d of %d images have vulnerabilities with %s or higher severity", failed, len(images), options.Severity)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("All %d images passed the vulnerability scan", len(images))
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// filterBlockingVulnerabilities returns the vulnerabilities which should fail the stage.
+func filterBlockingVulnerabilities(image string, vulns []imagescanner.Vulnerability, options *config.K8sVulnerabilityScanStageOptions) []imagescanner.Vulnerability {
+	var out []imagescanner.Vulnerability
+	for _, v := range vulns {
+		if !v.Severity.AtLeast(options.Severity) {
+			continue
+		}
+		if options.IgnoreUnfixed && v.FixedVersion == "" {
+			continue
+		}
+		if isAllowedVulnerability(v.ID, image, options.Allowlist) {
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func isAllowedVulnerability(id, image string, allowlist []config.VulnerabilityAllowlistEntry) bool {
+	for _, e := range allowlist {
+		if e.Allows(id, image) {
+			return true
+		}
+	}
+	return false
+}
+
+// findImages returns the images used by all workloads without duplication.
+func findImages(manifests []provider.Manifest) []string {
+	var (
+		images []string
+		seen   = make(map[string]struct{})
+	)
+	for _, m := range manifests {
+		for _, image := range provider.FindContainerImages(m) {
+			if _, ok := seen[image]; ok {
+				continue
+			}
+			seen[image] = struct{}{}
+			images = append(images, image)
+		}
+	}
+	return images
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/imagescanner"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeImageScanner struct {
+	vulns map[string][]imagescanner.Vulnerability
+}
+
+func (s *fakeImageScanner) Scan(_ context.Context, image string) ([]imagescanner.Vulnerability, error) {
+	return s.vulns[image], nil
+}
+
+func TestScanImages(t *testing.T) {
+	scanner := &fakeImageScanner{
+		vulns: map[string][]imagescanner.Vulnerability{
+			"gcr.io/pipecd/helloworld:v0.1.0": {
+				{ID: "CVE-2021-3711", Severity: config.VulnerabilitySeverityCritical, FixedVersion: "1.1.1l"},
+				{ID: "CVE-2021-33560", Severity: config.VulnerabilitySeverityHigh},
+				{ID: "CVE-2021-3449", Severity: config.VulnerabilitySeverityMedium, FixedVersion: "1.1.1k"},
+			},
+		},
+	}
+	images := []string{"gcr.io/pipecd/helloworld:v0.1.0", "envoyproxy/envoy:v1.18.3"}
+
+	testcases := []struct {
+		name    string
+		options *config.K8sVulnerabilityScanStageOptions
+		want    model.StageStatus
+	}{
+		{
+			name: "vulnerabilities above threshold",
+			options: &config.K8sVulnerabilityScanStageOptions{
+				Severity: config.VulnerabilitySeverityHigh,
+			},
+			want: model.StageStatus_STAGE_FAILURE,
+		},
+		{
+			name: "unfixed ignored and the rest allowed",
+			options: &config.K8sVulnerabilityScanStageOptions{
+				Severity:      config.VulnerabilitySeverityHigh,
+				IgnoreUnfixed: true,
+				Allowlist: []config.VulnerabilityAllowlistEntry{
+					{ID: "CVE-2021-3711", Images: []string{"gcr.io/pipecd/"}},
+				},
+			},
+			want: model.StageStatus_STAGE_SUCCESS,
+		},
+		{
+			name: "allowlist for another image",
+			options: &config.K8sVulnerabilityScanStageOptions{
+				Severity:      config.VulnerabilitySeverityCritical,
+				IgnoreUnfixed: true,
+				Allowlist: []config.VulnerabilityAllowlistEntry{
+					{ID: "CVE-2021-3711", Images: []string{"envoyproxy/"}},
+				},
+			},
+			want: model.StageStatus_STAGE_FAILURE,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &deployExecutor{
+				Input: executor.Input{
+					LogPersister: &fakeLogPersister{},
+					Logger:       zap.NewNop(),
+				},
+			}
+			got := e.scanImages(context.Background(), scanner, images, tc.options)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -16,6 +16,7 @@ package lambda
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
@@ -26,7 +27,11 @@ import (
 	"go.uber.org/zap"
 )
 
-const promotePercentageMetadataKey = "promote-percentage"
+const (
+	promotePercentageMetadataKey   = "promote-percentage"
+	trafficRoutePrimaryMetadataKey = "primary-percentage"
+	trafficRouteCanaryMetadataKey  = "canary-percentage"
+)
 
 type deployExecutor struct {
 	executor.Input
@@ -70,6 +75,8 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 		status = e.ensurePromote(ctx)
 	case model.StageLambdaCanaryRollout:
 		status = e.ensureRollout(ctx)
+	case model.StageLambdaTrafficRouting:
+		status = e.ensureTrafficRouting(ctx)
 	default:
 		e.LogPersister.Errorf("Unsupported stage %s for lambda application", e.Stage.Name)
 		return model.StageStatus_STAGE_FAILURE
@@ -128,3 +135,42 @@ func (e *deployExecutor) ensureRollout(ctx context.Context) model.StageStatus {
 
 	return model.StageStatus_STAGE_SUCCESS
 }
+
+func (e *deployExecutor) ensureTrafficRouting(ctx context.Context) model.StageStatus {
+	options := e.StageConfig.LambdaTrafficRoutingStageOptions
+	if options == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	routing := e.deployCfg.Input.TrafficRouting
+	if routing == nil {
+		e.LogPersister.Errorf("Malformed deployment configuration: missing input.trafficRouting")
+		return model.StageStatus_STAGE_FAILURE
+	}
+	primary, canary := options.Percentage()
+	metadata := map[string]string{
+		trafficRoutePrimaryMetadataKey: strconv.FormatInt(int64(primary), 10),
+		trafficRouteCanaryMetadataKey:  strconv.FormatInt(int64(canary), 10),
+	}
+	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
+		e.Logger.Error("failed to save routing percentages to metadata", zap.Error(err))
+	}
+
+	fm, ok := loadFunctionManifest(&e.Input, e.deployCfg.Input.FunctionManifestFile, e.deploySource)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// The version rolled out by LAMBDA_CANARY_ROLLOUT stage is the canary.
+	version, ok := e.MetadataStore.Get(fmt.Sprintf("%s-rollout", fm.Spec.Name))
+	if !ok && canary > 0 {
+		e.LogPersister.Errorf("Unable to find the rolled out version of Lambda function %s, LAMBDA_CANARY_ROLLOUT stage must be executed before", fm.Spec.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	if !routeTraffic(ctx, &e.Input, e.cloudProviderName, e.cloudProviderCfg, fm, routing, version, primary, canary) {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return model.StageStatus_STAGE_SUCCESS
+}
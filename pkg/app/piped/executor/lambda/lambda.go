@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"time"
 
+	"go.uber.org/zap"
+
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/lambda"
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
@@ -42,6 +44,7 @@ func Register(r registerer) {
 	r.Register(model.StageLambdaSync, f)
 	r.Register(model.StageLambdaPromote, f)
 	r.Register(model.StageLambdaCanaryRollout, f)
+	r.Register(model.StageLambdaTrafficRouting, f)
 
 	r.RegisterRollback(model.ApplicationKind_LAMBDA, func(in executor.Input) executor.Executor {
 		return &rollbackExecutor{
@@ -104,6 +107,7 @@ func sync(ctx context.Context, in *executor.Input, cloudProviderName string, clo
 			return false
 		}
 		in.LogPersister.Infof("Successfully applied the lambda function manifest")
+		saveStageResults(ctx, in, version, 100)
 		return true
 	}
 	if err != nil {
@@ -136,6 +140,7 @@ func sync(ctx context.Context, in *executor.Input, cloudProviderName string, clo
 	}
 
 	in.LogPersister.Infof("Successfully applied the manifest for Lambda function %s version (v%s)", fm.Spec.Name, version)
+	saveStageResults(ctx, in, version, 100)
 	return true
 }
 
@@ -212,6 +217,7 @@ func promote(ctx context.Context, in *executor.Input, cloudProviderName string,
 			return false
 		}
 		in.LogPersister.Infof("Successfully route all traffic to the lambda function %s (version %s)", fm.Spec.Name, version)
+		saveStageResults(ctx, in, version, 100)
 		return true
 	}
 	if err != nil {
@@ -243,9 +249,68 @@ func promote(ctx context.Context, in *executor.Input, cloudProviderName string,
 	}
 
 	in.LogPersister.Infof("Successfully promote new version (v%s) of Lambda function %s, it will handle %v percent of traffic", version, fm.Spec.Name, options.Percent)
+	saveStageResults(ctx, in, version, options.Percent.Int())
+	return true
+}
+
+// routeTraffic shifts the user traffic coming through the given ALB or API Gateway
+// between the Service alias (primary) and the rolled out version (canary).
+func routeTraffic(ctx context.Context, in *executor.Input, cloudProviderName string, cloudProviderCfg *config.CloudProviderLambdaConfig, fm provider.FunctionManifest, routing *config.LambdaTrafficRouting, version string, primary, canary int) bool {
+	client, err := provider.DefaultRegistry().Client(cloudProviderName, cloudProviderCfg, in.Logger)
+	if err != nil {
+		in.LogPersister.Errorf("Unable to create Lambda client for the provider %s: %v", cloudProviderName, err)
+		return false
+	}
+
+	switch {
+	case routing.ALB != nil:
+		if canary > 0 {
+			if err := client.RegisterALBTarget(ctx, routing.ALB.CanaryTargetGroupArn, fm, version); err != nil {
+				in.LogPersister.Errorf("Failed to register version %s of Lambda function %s to the canary target group: %v", version, fm.Spec.Name, err)
+				return false
+			}
+		}
+		weights := []provider.TargetGroupWeight{
+			{
+				TargetGroupArn: routing.ALB.PrimaryTargetGroupArn,
+				Weight:         primary,
+			},
+			{
+				TargetGroupArn: routing.ALB.CanaryTargetGroupArn,
+				Weight:         canary,
+			},
+		}
+		if err := client.ModifyListenerRule(ctx, routing.ALB.ListenerRuleArn, weights); err != nil {
+			in.LogPersister.Errorf("Failed to update the weights of listener rule %s: %v", routing.ALB.ListenerRuleArn, err)
+			return false
+		}
+	case routing.APIGateway != nil:
+		gw := routing.APIGateway
+		if err := client.UpdateAPIGatewayCanary(ctx, gw.RestAPIID, gw.Stage, gw.StageVariable, version, canary); err != nil {
+			in.LogPersister.Errorf("Failed to update the canary settings of stage %s of API %s: %v", gw.Stage, gw.RestAPIID, err)
+			return false
+		}
+	default:
+		in.LogPersister.Errorf("Malformed traffic routing configuration: neither alb nor apiGateway is specified")
+		return false
+	}
+
+	in.LogPersister.Infof("Successfully routed %d%% of traffic to the Service alias and %d%% to version %s of Lambda function %s", primary, canary, version, fm.Spec.Name)
 	return true
 }
 
+// saveStageResults publishes the deployed version and its traffic percentage
+// as the results of the current stage.
+func saveStageResults(ctx context.Context, in *executor.Input, version string, percent int) {
+	results := map[string]string{
+		executor.StageResultRevision: version,
+		executor.StageResultTraffic:  fmt.Sprintf("version %s: %d%%", version, percent),
+	}
+	if err := in.MetadataStore.SetStageResults(ctx, in.Stage.Id, results); err != nil {
+		in.Logger.Error("failed to save stage results", zap.Error(err))
+	}
+}
+
 func configureTrafficRouting(trafficCfg provider.RoutingTrafficConfig, version string, percent int) bool {
 	// The primary version has to be set on trafficCfg.
 	primary, ok := trafficCfg[provider.TrafficPrimaryVersionKeyName]
@@ -276,6 +341,16 @@ func configureTrafficRouting(trafficCfg provider.RoutingTrafficConfig, version s
 }
 
 func build(ctx context.Context, in *executor.Input, client provider.Client, fm provider.FunctionManifest) (version string, ok bool) {
+	// Tag the function with its application ID so that
+	// the live state of the application can be found later.
+	tags := make(map[string]string, len(fm.Spec.Tags)+2)
+	for k, v := range fm.Spec.Tags {
+		tags[k] = v
+	}
+	tags[provider.TagManagedBy] = provider.ManagedByPiped
+	tags[provider.TagApplication] = in.Deployment.ApplicationId
+	fm.Spec.Tags = tags
+
 	found, err := client.IsFunctionExist(ctx, fm.Spec.Name)
 	if err != nil {
 		in.LogPersister.Errorf("Unable to validate function name %s: %v", fm.Spec.Name, err)
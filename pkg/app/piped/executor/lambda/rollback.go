@@ -79,9 +79,30 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	// Stop routing the user traffic to the canary since the Service alias has been restored.
+	if routing := e.findTrafficRouting(ctx); routing != nil {
+		if !routeTraffic(ctx, &e.Input, cloudProviderName, cloudProviderCfg, fm, routing, "", 100, 0) {
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+
 	return model.StageStatus_STAGE_SUCCESS
 }
 
+// findTrafficRouting returns the traffic routing configured at the target commit
+// since that is the one the LAMBDA_TRAFFIC_ROUTING stages have modified.
+func (e *rollbackExecutor) findTrafficRouting(ctx context.Context) *config.LambdaTrafficRouting {
+	ds, err := e.TargetDSP.GetReadOnly(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		return nil
+	}
+	if spec := ds.DeploymentConfig.LambdaDeploymentSpec; spec != nil {
+		return spec.Input.TrafficRouting
+	}
+	return nil
+}
+
 func rollback(ctx context.Context, in *executor.Input, cloudProviderName string, cloudProviderCfg *config.CloudProviderLambdaConfig, fm provider.FunctionManifest) bool {
 	in.LogPersister.Infof("Start rollback the lambda function: %s to original stage", fm.Spec.Name)
 	client, err := provider.DefaultRegistry().Client(cloudProviderName, cloudProviderCfg, in.Logger)
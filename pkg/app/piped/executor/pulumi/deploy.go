@@ -0,0 +1,118 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pulumi
+
+import (
+	"context"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/pulumi"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type deployExecutor struct {
+	executor.Input
+}
+
+func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	ctx := sig.Context()
+	ds, err := e.TargetDSP.Get(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	deployCfg := ds.DeploymentConfig.PulumiDeploymentSpec
+	if deployCfg == nil {
+		e.LogPersister.Error("Malformed deployment configuration: missing PulumiDeploymentSpec")
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	var (
+		originalStatus = e.Stage.Status
+		status         model.StageStatus
+	)
+
+	cmd, ok := prepareStack(ctx, &e.Input, ds.AppDir, deployCfg)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	switch model.Stage(e.Stage.Name) {
+	case model.StagePulumiSync:
+		status = e.ensureSync(ctx, cmd)
+
+	case model.StagePulumiPreview:
+		status = e.ensurePreview(ctx, cmd)
+
+	case model.StagePulumiUp:
+		status = e.ensureUp(ctx, cmd)
+
+	default:
+		e.LogPersister.Errorf("Unsupported stage %s for pulumi application", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+}
+
+func (e *deployExecutor) ensureSync(ctx context.Context, cmd *provider.Pulumi) model.StageStatus {
+	result, err := cmd.Preview(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to preview (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	if result.NoChanges() {
+		e.LogPersister.Info("No changes to apply")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	e.LogPersister.Infof("Detected %s. Those changes will be applied automatically.", result)
+
+	if err := cmd.Up(ctx, e.LogPersister); err != nil {
+		e.LogPersister.Errorf("Failed to update stack (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Success("Successfully updated stack")
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) ensurePreview(ctx context.Context, cmd *provider.Pulumi) model.StageStatus {
+	result, err := cmd.Preview(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to preview (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	if result.NoChanges() {
+		e.LogPersister.Success("No changes to apply")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	e.LogPersister.Successf("Detected %s.", result)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) ensureUp(ctx context.Context, cmd *provider.Pulumi) model.StageStatus {
+	if err := cmd.Up(ctx, e.LogPersister); err != nil {
+		e.LogPersister.Errorf("Failed to update stack (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Success("Successfully updated stack")
+	return model.StageStatus_STAGE_SUCCESS
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pulumi
+
+import (
+	"context"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/pulumi"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type registerer interface {
+	Register(stage model.Stage, f executor.Factory) error
+	RegisterRollback(kind model.ApplicationKind, f executor.Factory) error
+}
+
+func Register(r registerer) {
+	f := func(in executor.Input) executor.Executor {
+		return &deployExecutor{
+			Input: in,
+		}
+	}
+	r.Register(model.StagePulumiSync, f)
+	r.Register(model.StagePulumiPreview, f)
+	r.Register(model.StagePulumiUp, f)
+
+	r.RegisterRollback(model.ApplicationKind_PULUMI, func(in executor.Input) executor.Executor {
+		return &rollbackExecutor{
+			Input: in,
+		}
+	})
+}
+
+// prepareStack returns a command to update the stack of the given application
+// after selecting the stack and setting its configuration values.
+func prepareStack(ctx context.Context, in *executor.Input, appDir string, deployCfg *config.PulumiDeploymentSpec) (*provider.Pulumi, bool) {
+	cloudProviderCfg, found := findCloudProvider(in)
+	if !found {
+		return nil, false
+	}
+
+	envs, err := provider.EnvsFromConfig(cloudProviderCfg)
+	if err != nil {
+		in.LogPersister.Errorf("Failed to load the configuration of cloud provider (%v)", err)
+		return nil, false
+	}
+
+	pulumiPath, ok := findPulumi(ctx, deployCfg.Input.PulumiVersion, in.LogPersister)
+	if !ok {
+		return nil, false
+	}
+
+	cmd := provider.NewPulumi(pulumiPath, appDir, provider.WithEnvs(envs))
+
+	version, err := cmd.Version(ctx)
+	if err != nil {
+		in.LogPersister.Errorf("Failed to check pulumi version (%v)", err)
+		return nil, false
+	}
+	in.LogPersister.Infof("Using pulumi version %q to execute the pulumi commands", version)
+
+	stack := deployCfg.Input.Stack
+	if err := cmd.SelectStack(ctx, stack); err != nil {
+		in.LogPersister.Errorf("Failed to select stack %q (%v). You might need to create the stack before using by command %q", stack, err, "pulumi stack init "+stack)
+		return nil, false
+	}
+	in.LogPersister.Infof("Selected stack %q", stack)
+
+	if err := cmd.SetConfig(ctx, deployCfg.Input.Config); err != nil {
+		in.LogPersister.Errorf("Failed to set configuration values (%v)", err)
+		return nil, false
+	}
+
+	if deployCfg.Input.Refresh {
+		if err := cmd.Refresh(ctx, in.LogPersister); err != nil {
+			in.LogPersister.Errorf("Failed to refresh (%v)", err)
+			return nil, false
+		}
+	}
+
+	return cmd, true
+}
+
+func findPulumi(ctx context.Context, version string, lp executor.LogPersister) (string, bool) {
+	path, installed, err := toolregistry.DefaultRegistry().Pulumi(ctx, version)
+	if err != nil {
+		lp.Errorf("Unable to find required pulumi %q (%v)", version, err)
+		return "", false
+	}
+	if installed {
+		lp.Infof("Pulumi %q has just been installed to %q because of no pre-installed binary for that version", version, path)
+	}
+	return path, true
+}
+
+func findCloudProvider(in *executor.Input) (cfg *config.CloudProviderPulumiConfig, found bool) {
+	name := in.Application.CloudProvider
+	if name == "" {
+		in.LogPersister.Error("Missing the CloudProvider name in the application configuration")
+		return
+	}
+
+	cp, ok := in.PipedConfig.FindCloudProvider(name, model.CloudProviderPulumi)
+	if !ok {
+		in.LogPersister.Errorf("The specified cloud provider %q was not found in piped configuration", name)
+		return
+	}
+
+	cfg = cp.PulumiConfig
+	found = true
+	return
+}
@@ -20,11 +20,15 @@ import (
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/analysis"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/ansible"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/cloudrun"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/ecs"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/featureflag"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/kubernetes"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/lambda"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/pulumi"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/terraform"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/vm"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/wait"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/waitapproval"
 	"github.com/pipe-cd/pipe/pkg/model"
@@ -94,6 +98,12 @@ func DefaultRegistry() Registry {
 	return defaultRegistry
 }
 
+// RegisterExecutor registers an executor factory for the given stage into the default registry.
+// This is used to add the stages provided by plugins at runtime.
+func RegisterExecutor(stage model.Stage, f executor.Factory) error {
+	return defaultRegistry.Register(stage, f)
+}
+
 // init registers all built-in executors to the default registry.
 func init() {
 	analysis.Register(defaultRegistry)
@@ -102,6 +112,10 @@ func init() {
 	lambda.Register(defaultRegistry)
 	terraform.Register(defaultRegistry)
 	ecs.Register(defaultRegistry)
+	featureflag.Register(defaultRegistry)
+	vm.Register(defaultRegistry)
+	pulumi.Register(defaultRegistry)
+	ansible.Register(defaultRegistry)
 	wait.Register(defaultRegistry)
 	waitapproval.Register(defaultRegistry)
 }
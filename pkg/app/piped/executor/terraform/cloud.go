@@ -0,0 +1,249 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/terraform"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// The key of the metadata storing the ID of the Terraform Cloud run
+// planned by TERRAFORM_PLAN stage to be applied by TERRAFORM_APPLY stage.
+const cloudRunIDKey = "terraform-cloud-run-id"
+
+// The maximum time to cancel the run after the deployment was cancelled.
+const cloudRunCancelTimeout = time.Minute
+
+func newCloud(cfg *config.TerraformCloudConfig, workspace string, lp executor.LogPersister) (*provider.Cloud, bool) {
+	if workspace == "" {
+		lp.Error("Missing the workspace name in the deployment configuration. It is required to execute runs on Terraform Cloud")
+		return nil, false
+	}
+	cloud, err := provider.NewCloudFromConfig(cfg)
+	if err != nil {
+		lp.Errorf("Failed to create Terraform Cloud client (%v)", err)
+		return nil, false
+	}
+	lp.Infof("Executing runs on Terraform Cloud workspace %q", workspace)
+	return cloud, true
+}
+
+func runMessage(d *model.Deployment) string {
+	return fmt.Sprintf("Triggered by PipeCD deployment %s at commit %s", d.Id, d.Trigger.Commit.Hash)
+}
+
+func (e *deployExecutor) executeOnCloud(sig executor.StopSignal, cfg *config.TerraformCloudConfig) model.StageStatus {
+	cloud, ok := newCloud(cfg, e.deployCfg.Input.Workspace, e.LogPersister)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if len(e.vars) > 0 || len(e.deployCfg.Input.VarFiles) > 0 {
+		e.LogPersister.Info("The configured vars and varFiles are ignored. The variables must be set on the Terraform Cloud workspace instead")
+	}
+
+	var (
+		ctx    = sig.Context()
+		status model.StageStatus
+	)
+	switch model.Stage(e.Stage.Name) {
+	case model.StageTerraformSync:
+		status = e.ensureCloudSync(ctx, cloud)
+
+	case model.StageTerraformPlan:
+		status = e.ensureCloudPlan(ctx, cloud)
+
+	case model.StageTerraformApply:
+		status = e.ensureCloudApply(ctx, cloud)
+
+	default:
+		e.LogPersister.Errorf("Unsupported stage %s for terraform application", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// The run keeps going on Terraform Cloud even after piped stopped waiting for it,
+	// so it is cancelled together with the deployment.
+	if status != model.StageStatus_STAGE_SUCCESS && e.cloudRunID != "" {
+		switch sig.Signal() {
+		case executor.StopSignalCancel, executor.StopSignalTimeout:
+			e.cancelCloudRun(cloud, e.cloudRunID)
+		}
+	}
+	return status
+}
+
+func (e *deployExecutor) cancelCloudRun(cloud *provider.Cloud, runID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cloudRunCancelTimeout)
+	defer cancel()
+
+	if err := cloud.Cancel(ctx, runID, fmt.Sprintf("Cancelled by PipeCD deployment %s", e.Deployment.Id)); err != nil {
+		e.LogPersister.Errorf("Failed to cancel run %s (%v)", runID, err)
+		return
+	}
+	e.LogPersister.Infof("Cancelled run %s", runID)
+}
+
+func (e *deployExecutor) createCloudRun(ctx context.Context, cloud *provider.Cloud) (string, error) {
+	workingDir, err := filepath.Rel(e.repoDir, e.appDir)
+	if err != nil {
+		return "", err
+	}
+	runID, err := cloud.CreateRun(ctx, e.deployCfg.Input.Workspace, e.repoDir, workingDir, runMessage(e.Deployment), false, e.LogPersister)
+	if err != nil {
+		return "", err
+	}
+	e.cloudRunID = runID
+	return runID, nil
+}
+
+func (e *deployExecutor) ensureCloudSync(ctx context.Context, cloud *provider.Cloud) model.StageStatus {
+	runID, err := e.createCloudRun(ctx, cloud)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to create run (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	plan, err := cloud.WaitPlan(ctx, runID, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to plan (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	if !plan.Confirmable {
+		e.LogPersister.Info("No changes to apply")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	e.LogPersister.Infof("Detected %d add, %d change, %d destroy. Those changes will be applied automatically.", plan.Adds, plan.Changes, plan.Destroys)
+
+	if err := cloud.Apply(ctx, runID, runMessage(e.Deployment), e.LogPersister); err != nil {
+		e.LogPersister.Errorf("Failed to apply changes (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Success("Successfully applied changes")
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) ensureCloudPlan(ctx context.Context, cloud *provider.Cloud) model.StageStatus {
+	runID, err := e.createCloudRun(ctx, cloud)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to create run (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// Store the run to be applied by the next TERRAFORM_APPLY stage
+	// so that exactly the reviewed plan will be applied.
+	if err := e.MetadataStore.Set(ctx, cloudRunIDKey, runID); err != nil {
+		e.LogPersister.Errorf("Failed to save the run ID to the metadata store (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	plan, err := cloud.WaitPlan(ctx, runID, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to plan (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	if !plan.Confirmable {
+		e.LogPersister.Success("No changes to apply")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	e.LogPersister.Successf("Detected %d add, %d change, %d destroy.", plan.Adds, plan.Changes, plan.Destroys)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) ensureCloudApply(ctx context.Context, cloud *provider.Cloud) model.StageStatus {
+	runID, ok := e.MetadataStore.Get(cloudRunIDKey)
+	if ok {
+		e.LogPersister.Infof("Applying run %s planned by the previous stage", runID)
+		e.cloudRunID = runID
+	} else {
+		var err error
+		runID, err = e.createCloudRun(ctx, cloud)
+		if err != nil {
+			e.LogPersister.Errorf("Failed to create run (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+
+	plan, err := cloud.WaitPlan(ctx, runID, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to plan (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	if !plan.Confirmable {
+		e.LogPersister.Success("No changes to apply")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	if err := cloud.Apply(ctx, runID, runMessage(e.Deployment), e.LogPersister); err != nil {
+		e.LogPersister.Errorf("Failed to apply changes (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Success("Successfully applied changes")
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *rollbackExecutor) ensureCloudRollback(ctx context.Context, cfg *config.TerraformCloudConfig, workspace, repoDir, appDir string) model.StageStatus {
+	cloud, ok := newCloud(cfg, workspace, e.LogPersister)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// The run planned by TERRAFORM_PLAN stage but not applied yet
+	// must be discarded since it blocks the runs queued after it.
+	if runID, ok := e.MetadataStore.Get(cloudRunIDKey); ok {
+		if err := cloud.Discard(ctx, runID, "Discarded by PipeCD to roll back"); err == nil {
+			e.LogPersister.Infof("Discarded run %s planned by the deployment", runID)
+		}
+	}
+
+	workingDir, err := filepath.Rel(repoDir, appDir)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to determine the working directory (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	runID, err := cloud.CreateRun(ctx, workspace, repoDir, workingDir, fmt.Sprintf("Rollback of PipeCD deployment %s", e.Deployment.Id), false, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to create run (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	plan, err := cloud.WaitPlan(ctx, runID, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to plan (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	if plan.Confirmable {
+		if err := cloud.Apply(ctx, runID, fmt.Sprintf("Rollback of PipeCD deployment %s", e.Deployment.Id), e.LogPersister); err != nil {
+			e.LogPersister.Errorf("Failed to apply changes (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+
+	e.LogPersister.Success("Successfully rolled back the changes")
+	return model.StageStatus_STAGE_SUCCESS
+}
@@ -31,6 +31,8 @@ type deployExecutor struct {
 	vars          []string
 	terraformPath string
 	deployCfg     *config.TerraformDeploymentSpec
+	// The run created on Terraform Cloud by this stage.
+	cloudRunID string
 }
 
 func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
@@ -64,6 +66,11 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 		status         model.StageStatus
 	)
 
+	if cloudProviderCfg.Cloud != nil {
+		status = e.executeOnCloud(sig, cloudProviderCfg.Cloud)
+		return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+	}
+
 	var ok bool
 	e.terraformPath, ok = findTerraform(ctx, e.deployCfg.Input.TerraformVersion, e.LogPersister)
 	if !ok {
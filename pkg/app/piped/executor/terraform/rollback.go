@@ -69,6 +69,11 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	if cloudProviderCfg.Cloud != nil {
+		e.LogPersister.Infof("Start rolling back to the state defined at commit %s", e.Deployment.RunningCommitHash)
+		return e.ensureCloudRollback(ctx, cloudProviderCfg.Cloud, deployCfg.Input.Workspace, ds.RepoDir, ds.AppDir)
+	}
+
 	terraformPath, ok := findTerraform(ctx, deployCfg.Input.TerraformVersion, e.LogPersister)
 	if !ok {
 		return model.StageStatus_STAGE_FAILURE
@@ -0,0 +1,194 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"context"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/vm"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type deployExecutor struct {
+	executor.Input
+
+	deploySource      *deploysource.DeploySource
+	deployCfg         *config.VMDeploymentSpec
+	cloudProviderName string
+	cloudProviderCfg  *config.CloudProviderVMConfig
+}
+
+func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	ctx := sig.Context()
+	ds, err := e.TargetDSP.GetReadOnly(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.deploySource = ds
+	e.deployCfg = ds.DeploymentConfig.VMDeploymentSpec
+	if e.deployCfg == nil {
+		e.LogPersister.Errorf("Malformed deployment configuration: missing VMDeploymentSpec")
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	var found bool
+	e.cloudProviderName, e.cloudProviderCfg, found = findCloudProvider(&e.Input)
+	if !found {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	var (
+		originalStatus = e.Stage.Status
+		status         model.StageStatus
+	)
+
+	switch model.Stage(e.Stage.Name) {
+	case model.StageVMSync:
+		status = e.ensureSync(ctx)
+	case model.StageVMCanaryRollout:
+		status = e.ensureCanaryRollout(ctx)
+	case model.StageVMPrimaryRollout:
+		status = e.ensurePrimaryRollout(ctx)
+	case model.StageVMCanaryClean:
+		status = e.ensureCanaryClean(ctx)
+	default:
+		e.LogPersister.Errorf("Unsupported stage %s for VM application", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+}
+
+// prepare loads the instance group manifest and resolves its image
+// to find the instances that are still running the other images.
+func (e *deployExecutor) prepare(ctx context.Context) (client provider.Client, imageID string, total int, outdated []provider.Instance, ok bool) {
+	manifest, ok := loadInstanceGroupManifest(&e.Input, e.deployCfg.Input.InstanceGroupManifestFile, e.deploySource)
+	if !ok {
+		return
+	}
+
+	client, err := provider.DefaultRegistry().Client(e.cloudProviderName, e.cloudProviderCfg, e.Logger)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to create VM client for the provider %s: %v", e.cloudProviderName, err)
+		return nil, "", 0, nil, false
+	}
+
+	imageID, err = client.ResolveImage(ctx, manifest.Spec.Image)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to find image %s: %v", manifest.Spec.Image, err)
+		return nil, "", 0, nil, false
+	}
+
+	total, outdated, ok = outdatedInstances(ctx, &e.Input, client, manifest.Spec.Name, imageID)
+	if ok {
+		e.LogPersister.Infof("%d of %d instances of group %s are not running image %s (%s)", len(outdated), total, manifest.Spec.Name, manifest.Spec.Image, imageID)
+	}
+	return
+}
+
+func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
+	options := e.StageConfig.VMSyncStageOptions
+	if options == nil {
+		options = &e.deployCfg.QuickSync
+	}
+	if e.cloudProviderCfg.Platform == config.VMPlatformGCE {
+		return e.ensureGCERollingUpdate(ctx, options.MaxSurge, options.MaxUnavailable, options.HealthCheckTimeout.Duration())
+	}
+
+	client, imageID, total, outdated, ok := e.prepare(ctx)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	batchSize := calculateBatchSize(options.MaxUnavailable, total)
+	if !rollingReplace(ctx, &e.Input, client, outdated, imageID, batchSize, options.HealthCheckTimeout.Duration()) {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully replaced all instances with image %s", imageID)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) ensureCanaryRollout(ctx context.Context) model.StageStatus {
+	options := e.StageConfig.VMCanaryRolloutStageOptions
+	if options == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if e.cloudProviderCfg.Platform == config.VMPlatformGCE {
+		return e.ensureGCECanaryRollout(ctx, options.Replicas, options.HealthCheckTimeout.Duration())
+	}
+
+	client, imageID, total, outdated, ok := e.prepare(ctx)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// The instances already running the new image are counted as CANARY
+	// so that this stage can be retried safely.
+	canary := options.Replicas.Calculate(total, 1)
+	replaced := total - len(outdated)
+	if replaced >= canary {
+		e.LogPersister.Infof("%d instances are already running the new image, no need to replace more", replaced)
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	targets := outdated[:canary-replaced]
+	if !rollingReplace(ctx, &e.Input, client, targets, imageID, len(targets), options.HealthCheckTimeout.Duration()) {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully replaced %d of %d instances with image %s as CANARY", canary, total, imageID)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) ensurePrimaryRollout(ctx context.Context) model.StageStatus {
+	options := e.StageConfig.VMPrimaryRolloutStageOptions
+	if options == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if e.cloudProviderCfg.Platform == config.VMPlatformGCE {
+		return e.ensureGCERollingUpdate(ctx, options.MaxSurge, options.MaxUnavailable, options.HealthCheckTimeout.Duration())
+	}
+
+	client, imageID, total, outdated, ok := e.prepare(ctx)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	batchSize := calculateBatchSize(options.MaxUnavailable, total)
+	if !rollingReplace(ctx, &e.Input, client, outdated, imageID, batchSize, options.HealthCheckTimeout.Duration()) {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully replaced all instances with image %s", imageID)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) ensureCanaryClean(ctx context.Context) model.StageStatus {
+	if e.cloudProviderCfg.Platform == config.VMPlatformGCE {
+		return e.ensureGCECanaryClean(ctx)
+	}
+
+	// The CANARY instances have been replaced in place and became PRIMARY ones by the primary rollout.
+	e.LogPersister.Infof("The instances are replaced in place on platform %s. There is no CANARY instance to clean.", e.cloudProviderCfg.Platform)
+	return model.StageStatus_STAGE_SUCCESS
+}
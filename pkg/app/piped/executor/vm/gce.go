@@ -0,0 +1,273 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"context"
+	"time"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/vm"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// originalTemplateKeyName is the metadata key to store the name of the template
+// the primary group was using before this deployment.
+const originalTemplateKeyName = "original-template"
+
+// prepareGCE loads the instance group manifest and ensures the template to run it exists.
+func (e *deployExecutor) prepareGCE(ctx context.Context) (client provider.ManagedGroupClient, manifest provider.InstanceGroupManifest, template *provider.Template, ok bool) {
+	manifest, ok = loadInstanceGroupManifest(&e.Input, e.deployCfg.Input.InstanceGroupManifestFile, e.deploySource)
+	if !ok {
+		return
+	}
+
+	client, err := provider.DefaultRegistry().ManagedGroupClient(ctx, e.cloudProviderName, e.cloudProviderCfg, e.Logger)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to create VM client for the provider %s: %v", e.cloudProviderName, err)
+		return nil, manifest, nil, false
+	}
+
+	current, template, err := client.BuildTemplate(ctx, manifest)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to build the instance template for group %s: %v", manifest.Spec.Name, err)
+		return nil, manifest, nil, false
+	}
+
+	// Remember the template used before this deployment to rollback to it.
+	if _, found := e.MetadataStore.Get(originalTemplateKeyName); !found {
+		if err := e.MetadataStore.Set(ctx, originalTemplateKeyName, current.Name); err != nil {
+			e.LogPersister.Errorf("Failed to store the original template name to metadata store: %v", err)
+			return nil, manifest, nil, false
+		}
+	}
+
+	if current.Name == template.Name {
+		e.LogPersister.Infof("Group %s is already using the instance template %s", manifest.Spec.Name, template.Name)
+		return client, manifest, template, true
+	}
+	if err := client.EnsureTemplate(ctx, template); err != nil {
+		e.LogPersister.Errorf("Failed to create the instance template %s: %v", template.Name, err)
+		return nil, manifest, nil, false
+	}
+	e.LogPersister.Infof("Successfully prepared the instance template %s for image %s", template.Name, manifest.Spec.Image)
+	return client, manifest, template, true
+}
+
+func (e *deployExecutor) ensureGCERollingUpdate(ctx context.Context, maxSurge, maxUnavailable *config.Replicas, timeout time.Duration) model.StageStatus {
+	client, manifest, template, ok := e.prepareGCE(ctx)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	group := manifest.Spec.Name
+	e.LogPersister.Infof("Start updating group %s to template %s (maxSurge: %s, maxUnavailable: %s)", group, template.Name, gceReplicasString(maxSurge), gceReplicasString(maxUnavailable))
+	if err := client.RollingUpdate(ctx, group, template, maxSurge, maxUnavailable); err != nil {
+		e.LogPersister.Errorf("Failed to start the rolling update of group %s: %v", group, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if !waitGroupStable(ctx, &e.Input, client, group, template.Name, timeout) {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully updated all instances of group %s with image %s", group, manifest.Spec.Image)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) ensureGCECanaryRollout(ctx context.Context, replicas config.Replicas, timeout time.Duration) model.StageStatus {
+	client, manifest, template, ok := e.prepareGCE(ctx)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	canaryGroup := gceCanaryGroup(manifest)
+	if canaryGroup == "" {
+		e.LogPersister.Errorf("Missing spec.gce.canaryGroup in the instance group manifest. It is required to run %s stage on GCE", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	primary, err := client.GetGroup(ctx, manifest.Spec.Name)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to get group %s: %v", manifest.Spec.Name, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	size := replicas.Calculate(primary.TargetSize, 1)
+	e.LogPersister.Infof("Scaling group %s to %d instances with template %s as CANARY", canaryGroup, size, template.Name)
+	if err := client.Resize(ctx, canaryGroup, template, size); err != nil {
+		e.LogPersister.Errorf("Failed to scale group %s: %v", canaryGroup, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if !waitGroupStable(ctx, &e.Input, client, canaryGroup, template.Name, timeout) {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully started %d instances of group %s with image %s as CANARY", size, canaryGroup, manifest.Spec.Image)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) ensureGCECanaryClean(ctx context.Context) model.StageStatus {
+	manifest, ok := loadInstanceGroupManifest(&e.Input, e.deployCfg.Input.InstanceGroupManifestFile, e.deploySource)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	canaryGroup := gceCanaryGroup(manifest)
+	if canaryGroup == "" {
+		e.LogPersister.Info("No canary group was specified in the instance group manifest. Nothing to clean.")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	client, err := provider.DefaultRegistry().ManagedGroupClient(ctx, e.cloudProviderName, e.cloudProviderCfg, e.Logger)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to create VM client for the provider %s: %v", e.cloudProviderName, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if !scaleInGroup(ctx, &e.Input, client, canaryGroup) {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully removed all instances of group %s", canaryGroup)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *rollbackExecutor) ensureGCERollback(ctx context.Context, cloudProviderName string, cloudProviderCfg *config.CloudProviderVMConfig, manifest provider.InstanceGroupManifest, opts config.VMSyncStageOptions) model.StageStatus {
+	client, err := provider.DefaultRegistry().ManagedGroupClient(ctx, cloudProviderName, cloudProviderCfg, e.Logger)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to create VM client for the provider %s: %v", cloudProviderName, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// Remove the CANARY instances first to stop routing the traffic to them.
+	if canaryGroup := e.canaryGroup(ctx, manifest); canaryGroup != "" {
+		if !scaleInGroup(ctx, &e.Input, client, canaryGroup) {
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+
+	// Prefer the template used before this deployment
+	// since it may contain the properties not managed by the manifest.
+	var template *provider.Template
+	if name, ok := e.MetadataStore.Get(originalTemplateKeyName); ok {
+		template = &provider.Template{Name: name}
+	} else {
+		current, desired, err := client.BuildTemplate(ctx, manifest)
+		if err != nil {
+			e.LogPersister.Errorf("Failed to build the instance template for group %s: %v", manifest.Spec.Name, err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		if current.Name == desired.Name {
+			e.LogPersister.Info("The group is using the instance template of the last deployed commit. No need to rollback.")
+			return model.StageStatus_STAGE_SUCCESS
+		}
+		if err := client.EnsureTemplate(ctx, desired); err != nil {
+			e.LogPersister.Errorf("Failed to create the instance template %s: %v", desired.Name, err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		template = desired
+	}
+
+	group := manifest.Spec.Name
+	e.LogPersister.Infof("Rolling back group %s to template %s", group, template.Name)
+	if err := client.RollingUpdate(ctx, group, template, opts.MaxSurge, opts.MaxUnavailable); err != nil {
+		e.LogPersister.Errorf("Failed to start the rolling update of group %s: %v", group, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if !waitGroupStable(ctx, &e.Input, client, group, template.Name, opts.HealthCheckTimeout.Duration()) {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully rolled back group %s to template %s", group, template.Name)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// canaryGroup returns the canary group specified by the manifest being deployed
+// since it may have been introduced by this deployment.
+// The one of the given running manifest is used when the target one could not be loaded.
+func (e *rollbackExecutor) canaryGroup(ctx context.Context, running provider.InstanceGroupManifest) string {
+	ds, err := e.TargetDSP.GetReadOnly(ctx, e.LogPersister)
+	if err != nil || ds.DeploymentConfig.VMDeploymentSpec == nil {
+		return gceCanaryGroup(running)
+	}
+	target, err := provider.LoadInstanceGroupManifest(ds.AppDir, ds.DeploymentConfig.VMDeploymentSpec.Input.InstanceGroupManifestFile)
+	if err != nil {
+		return gceCanaryGroup(running)
+	}
+	return gceCanaryGroup(target)
+}
+
+func gceReplicasString(r *config.Replicas) string {
+	if r == nil {
+		return "default"
+	}
+	return r.String()
+}
+
+func gceCanaryGroup(m provider.InstanceGroupManifest) string {
+	if m.Spec.GCE == nil {
+		return ""
+	}
+	return m.Spec.GCE.CanaryGroup
+}
+
+// scaleInGroup removes all instances of the given group.
+func scaleInGroup(ctx context.Context, in *executor.Input, client provider.ManagedGroupClient, group string) bool {
+	in.LogPersister.Infof("Removing all instances of group %s", group)
+	if err := client.Resize(ctx, group, nil, 0); err != nil {
+		in.LogPersister.Errorf("Failed to scale group %s to zero: %v", group, err)
+		return false
+	}
+	return waitGroupStable(ctx, in, client, group, "", 0)
+}
+
+// waitGroupStable waits until all instances of the given group have been updated
+// to the given template and became healthy.
+// Empty template means only the stability of the group is checked.
+// The health of instances is determined by the autohealing policy of the group.
+// Zero timeout means 10 minutes.
+func waitGroupStable(ctx context.Context, in *executor.Input, client provider.ManagedGroupClient, group, template string, timeout time.Duration) bool {
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(instancePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			in.LogPersister.Errorf("Group %s did not become stable in %v: %v", group, timeout, ctx.Err())
+			return false
+		case <-ticker.C:
+		}
+
+		g, err := client.GetGroup(ctx, group)
+		if err != nil {
+			in.LogPersister.Errorf("Failed to get the status of group %s: %v", group, err)
+			continue
+		}
+		if template != "" && g.Template != template {
+			in.LogPersister.Errorf("Group %s has been changed to use template %s by someone else", group, g.Template)
+			return false
+		}
+		if g.Stable {
+			in.LogPersister.Successf("Group %s became stable with %d instances", group, g.TargetSize)
+			return true
+		}
+	}
+}
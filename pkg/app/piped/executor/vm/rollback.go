@@ -0,0 +1,111 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"context"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/vm"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type rollbackExecutor struct {
+	executor.Input
+}
+
+func (e *rollbackExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	var (
+		ctx            = sig.Context()
+		originalStatus = e.Stage.Status
+		status         model.StageStatus
+	)
+
+	switch model.Stage(e.Stage.Name) {
+	case model.StageRollback:
+		status = e.ensureRollback(ctx)
+	default:
+		e.LogPersister.Errorf("Unsupported stage %s for VM application", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+}
+
+func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus {
+	// Not rollback in case this is the first deployment.
+	if e.Deployment.RunningCommitHash == "" {
+		e.LogPersister.Errorf("Unable to determine the last deployed commit to rollback. It seems this is the first deployment.")
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	runningDS, err := e.RunningDSP.GetReadOnly(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare running deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	deployCfg := runningDS.DeploymentConfig.VMDeploymentSpec
+	if deployCfg == nil {
+		e.LogPersister.Errorf("Malformed deployment configuration: missing VMDeploymentSpec")
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	cloudProviderName, cloudProviderCfg, found := findCloudProvider(&e.Input)
+	if !found {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	manifest, ok := loadInstanceGroupManifest(&e.Input, deployCfg.Input.InstanceGroupManifestFile, runningDS)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	if cloudProviderCfg.Platform == config.VMPlatformGCE {
+		return e.ensureGCERollback(ctx, cloudProviderName, cloudProviderCfg, manifest, deployCfg.QuickSync)
+	}
+
+	client, err := provider.DefaultRegistry().Client(cloudProviderName, cloudProviderCfg, e.Logger)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to create VM client for the provider %s: %v", cloudProviderName, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	imageID, err := client.ResolveImage(ctx, manifest.Spec.Image)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to find image %s: %v", manifest.Spec.Image, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// Replace back only the instances that have been replaced during this deployment.
+	total, outdated, ok := outdatedInstances(ctx, &e.Input, client, manifest.Spec.Name, imageID)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if len(outdated) == 0 {
+		e.LogPersister.Info("All instances are running the image of the last deployed commit. No need to rollback.")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	opts := deployCfg.QuickSync
+	batchSize := calculateBatchSize(opts.MaxUnavailable, total)
+	if !rollingReplace(ctx, &e.Input, client, outdated, imageID, batchSize, opts.HealthCheckTimeout.Duration()) {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully rolled back %d instances to image %s", len(outdated), manifest.Spec.Image)
+	return model.StageStatus_STAGE_SUCCESS
+}
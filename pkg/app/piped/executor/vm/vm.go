@@ -0,0 +1,181 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/vm"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const defaultHealthCheckTimeout = 10 * time.Minute
+
+// instancePollInterval is the interval to check the status of the replaced instances.
+var instancePollInterval = 10 * time.Second
+
+type registerer interface {
+	Register(stage model.Stage, f executor.Factory) error
+	RegisterRollback(kind model.ApplicationKind, f executor.Factory) error
+}
+
+func Register(r registerer) {
+	f := func(in executor.Input) executor.Executor {
+		return &deployExecutor{
+			Input: in,
+		}
+	}
+	r.Register(model.StageVMSync, f)
+	r.Register(model.StageVMCanaryRollout, f)
+	r.Register(model.StageVMPrimaryRollout, f)
+	r.Register(model.StageVMCanaryClean, f)
+
+	r.RegisterRollback(model.ApplicationKind_VM, func(in executor.Input) executor.Executor {
+		return &rollbackExecutor{
+			Input: in,
+		}
+	})
+}
+
+func findCloudProvider(in *executor.Input) (name string, cfg *config.CloudProviderVMConfig, found bool) {
+	name = in.Application.CloudProvider
+	if name == "" {
+		in.LogPersister.Errorf("Missing the CloudProvider name in the application configuration")
+		return
+	}
+
+	cp, ok := in.PipedConfig.FindCloudProvider(name, model.CloudProviderVM)
+	if !ok {
+		in.LogPersister.Errorf("The specified cloud provider %q was not found in piped configuration", name)
+		return
+	}
+
+	cfg = cp.VMConfig
+	found = true
+	return
+}
+
+func loadInstanceGroupManifest(in *executor.Input, manifestFile string, ds *deploysource.DeploySource) (provider.InstanceGroupManifest, bool) {
+	in.LogPersister.Infof("Loading instance group manifest at the %s commit (%s)", ds.RevisionName, ds.Revision)
+
+	manifest, err := provider.LoadInstanceGroupManifest(ds.AppDir, manifestFile)
+	if err != nil {
+		in.LogPersister.Errorf("Failed to load instance group manifest (%v)", err)
+		return provider.InstanceGroupManifest{}, false
+	}
+
+	in.LogPersister.Infof("Successfully loaded the instance group manifest at the %s commit", ds.RevisionName)
+	return manifest, true
+}
+
+// outdatedInstances returns the instances of the group which are not running the given image yet.
+// The result is sorted by name so that the same instances are picked across the stages.
+func outdatedInstances(ctx context.Context, in *executor.Input, client provider.Client, group, imageID string) (total int, outdated []provider.Instance, ok bool) {
+	instances, err := client.ListInstances(ctx, group)
+	if err != nil {
+		in.LogPersister.Errorf("Failed to list instances of group %s: %v", group, err)
+		return 0, nil, false
+	}
+	for _, inst := range instances {
+		if inst.ImageID != imageID {
+			outdated = append(outdated, inst)
+		}
+	}
+	sort.Slice(outdated, func(i, j int) bool {
+		return outdated[i].Name < outdated[j].Name
+	})
+	return len(instances), outdated, true
+}
+
+// calculateBatchSize returns the number of instances to replace at the same time.
+// Nil means only one instance is replaced at a time.
+func calculateBatchSize(maxUnavailable *config.Replicas, total int) int {
+	if maxUnavailable == nil {
+		return 1
+	}
+	return maxUnavailable.Calculate(total, 1)
+}
+
+// rollingReplace replaces the given instances with the given image batch by batch.
+// The next batch is started only after all instances of the current batch became healthy.
+func rollingReplace(ctx context.Context, in *executor.Input, client provider.Client, instances []provider.Instance, imageID string, batchSize int, timeout time.Duration) bool {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+
+	for start := 0; start < len(instances); start += batchSize {
+		end := start + batchSize
+		if end > len(instances) {
+			end = len(instances)
+		}
+		batch := instances[start:end]
+
+		for _, inst := range batch {
+			in.LogPersister.Infof("Replacing instance %s (%s) with image %s", inst.Name, inst.ID, imageID)
+			if err := client.ReplaceInstance(ctx, inst.ID, imageID); err != nil {
+				in.LogPersister.Errorf("Failed to replace instance %s: %v", inst.Name, err)
+				return false
+			}
+		}
+		for _, inst := range batch {
+			if !waitHealthy(ctx, in, client, inst, imageID, timeout) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// waitHealthy waits until the platform reports the given instance is running the given image.
+func waitHealthy(ctx context.Context, in *executor.Input, client provider.Client, inst provider.Instance, imageID string, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(instancePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			in.LogPersister.Errorf("Instance %s did not become healthy within %v", inst.Name, timeout)
+			return false
+		case <-ticker.C:
+		}
+
+		cur, err := client.GetInstance(ctx, inst.ID)
+		if err != nil {
+			in.LogPersister.Errorf("Failed to get the status of instance %s: %v", inst.Name, err)
+			continue
+		}
+		switch cur.Status {
+		case provider.InstanceStatusFailed:
+			in.LogPersister.Errorf("Instance %s failed to start with image %s", inst.Name, imageID)
+			return false
+		case provider.InstanceStatusRunning:
+			if cur.ImageID == imageID {
+				in.LogPersister.Successf("Instance %s is healthy with image %s", inst.Name, imageID)
+				return true
+			}
+		}
+	}
+}
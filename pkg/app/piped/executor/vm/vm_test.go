@@ -0,0 +1,201 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/vm"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+)
+
+type fakeLogPersister struct{}
+
+func (l *fakeLogPersister) Write(_ []byte) (int, error)         { return 0, nil }
+func (l *fakeLogPersister) Info(_ string)                       {}
+func (l *fakeLogPersister) Infof(_ string, _ ...interface{})    {}
+func (l *fakeLogPersister) Success(_ string)                    {}
+func (l *fakeLogPersister) Successf(_ string, _ ...interface{}) {}
+func (l *fakeLogPersister) Error(_ string)                      {}
+func (l *fakeLogPersister) Errorf(_ string, _ ...interface{})   {}
+
+// fakeClient replaces the instances immediately
+// and records the order of the operations.
+type fakeClient struct {
+	provider.Client
+	instances map[string]*provider.Instance
+	failing   map[string]bool
+	ops       []string
+}
+
+func (c *fakeClient) ReplaceInstance(_ context.Context, id, imageID string) error {
+	c.ops = append(c.ops, "replace "+id)
+	inst := c.instances[id]
+	inst.ImageID = imageID
+	inst.Status = provider.InstanceStatusRunning
+	if c.failing[id] {
+		inst.Status = provider.InstanceStatusFailed
+	}
+	return nil
+}
+
+func (c *fakeClient) GetInstance(_ context.Context, id string) (*provider.Instance, error) {
+	c.ops = append(c.ops, "wait "+id)
+	inst, ok := c.instances[id]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	return inst, nil
+}
+
+func TestRollingReplace(t *testing.T) {
+	instancePollInterval = time.Millisecond
+
+	testcases := []struct {
+		name        string
+		batchSize   int
+		failing     map[string]bool
+		expectedOK  bool
+		expectedOps []string
+	}{
+		{
+			name:       "one by one",
+			batchSize:  1,
+			expectedOK: true,
+			expectedOps: []string{
+				"replace i1", "wait i1",
+				"replace i2", "wait i2",
+				"replace i3", "wait i3",
+			},
+		},
+		{
+			name:       "two at a time",
+			batchSize:  2,
+			expectedOK: true,
+			expectedOps: []string{
+				"replace i1", "replace i2", "wait i1", "wait i2",
+				"replace i3", "wait i3",
+			},
+		},
+		{
+			name:       "stop at the failed instance",
+			batchSize:  1,
+			failing:    map[string]bool{"i2": true},
+			expectedOK: false,
+			expectedOps: []string{
+				"replace i1", "wait i1",
+				"replace i2", "wait i2",
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			instances := []provider.Instance{
+				{ID: "i1", Name: "web-1", ImageID: "old", Status: provider.InstanceStatusRunning},
+				{ID: "i2", Name: "web-2", ImageID: "old", Status: provider.InstanceStatusRunning},
+				{ID: "i3", Name: "web-3", ImageID: "old", Status: provider.InstanceStatusRunning},
+			}
+			client := &fakeClient{
+				instances: make(map[string]*provider.Instance, len(instances)),
+				failing:   tc.failing,
+			}
+			for i := range instances {
+				inst := instances[i]
+				client.instances[inst.ID] = &inst
+			}
+			in := &executor.Input{
+				LogPersister: &fakeLogPersister{},
+			}
+
+			ok := rollingReplace(context.Background(), in, client, instances, "new", tc.batchSize, time.Second)
+			assert.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedOps, client.ops)
+		})
+	}
+}
+
+// fakeManagedGroupClient returns the given states of the group one by one.
+type fakeManagedGroupClient struct {
+	provider.ManagedGroupClient
+	states []provider.Group
+}
+
+func (c *fakeManagedGroupClient) GetGroup(_ context.Context, _ string) (*provider.Group, error) {
+	g := c.states[0]
+	if len(c.states) > 1 {
+		c.states = c.states[1:]
+	}
+	return &g, nil
+}
+
+func TestWaitGroupStable(t *testing.T) {
+	instancePollInterval = time.Millisecond
+
+	testcases := []struct {
+		name       string
+		template   string
+		states     []provider.Group
+		expectedOK bool
+	}{
+		{
+			name:     "became stable",
+			template: "web-new",
+			states: []provider.Group{
+				{Template: "web-new", TargetSize: 3},
+				{Template: "web-new", TargetSize: 3, Stable: true},
+			},
+			expectedOK: true,
+		},
+		{
+			name:     "changed by someone else",
+			template: "web-new",
+			states: []provider.Group{
+				{Template: "web-new", TargetSize: 3},
+				{Template: "web-other", TargetSize: 3},
+			},
+			expectedOK: false,
+		},
+		{
+			name: "any template",
+			states: []provider.Group{
+				{Template: "web-old", Stable: true},
+			},
+			expectedOK: true,
+		},
+		{
+			name:     "never stable",
+			template: "web-new",
+			states: []provider.Group{
+				{Template: "web-new", TargetSize: 3},
+			},
+			expectedOK: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeManagedGroupClient{states: tc.states}
+			in := &executor.Input{
+				LogPersister: &fakeLogPersister{},
+			}
+			ok := waitGroupStable(context.Background(), in, client, "web", tc.template, 50*time.Millisecond)
+			assert.Equal(t, tc.expectedOK, ok)
+		})
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
@@ -29,6 +30,7 @@ const (
 	defaultDuration = time.Minute
 	logInterval     = 10 * time.Second
 	startTimeKey    = "startTime"
+	targetTimeKey   = "targetTime"
 )
 
 type Executor struct {
@@ -49,15 +51,16 @@ func Register(r registerer) {
 	r.Register(model.StageWait, f)
 }
 
-// Execute starts waiting for the specified duration.
+// Execute starts waiting for the specified duration
+// or until the scheduled time.
 func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
-	var (
-		originalStatus = e.Stage.Status
-		duration       = defaultDuration
-	)
+	duration := defaultDuration
 
 	// Apply the stage configurations.
 	if opts := e.StageConfig.WaitStageOptions; opts != nil {
+		if opts.IsScheduled() {
+			return e.executeScheduled(sig, opts)
+		}
 		if opts.Duration > 0 {
 			duration = opts.Duration.Duration()
 		}
@@ -65,7 +68,7 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 	totalDuration := duration
 
 	// Retrieve the saved startTime from the previous run.
-	startTime := e.retrieveStartTime()
+	startTime := e.retrieveTime(startTimeKey)
 	if !startTime.IsZero() {
 		duration -= time.Since(startTime)
 		if duration < 0 {
@@ -74,7 +77,48 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 	} else {
 		startTime = time.Now()
 	}
-	defer e.saveStartTime(sig.Context(), startTime)
+	defer e.saveTime(sig.Context(), startTimeKey, startTime)
+
+	e.LogPersister.Infof("Waiting for %v...", duration)
+	status := e.wait(sig, duration, startTime)
+	if status == model.StageStatus_STAGE_SUCCESS {
+		e.LogPersister.Infof("Waited for %v", totalDuration)
+	}
+	return status
+}
+
+// executeScheduled waits until the time scheduled by the given options.
+// The scheduled time is computed only at the first run and is reused after restarts.
+func (e *Executor) executeScheduled(sig executor.StopSignal, opts *config.WaitStageOptions) model.StageStatus {
+	startTime := time.Now()
+	targetTime := e.retrieveTime(targetTimeKey)
+	if targetTime.IsZero() {
+		t, err := opts.ScheduledTime(startTime)
+		if err != nil {
+			e.LogPersister.Errorf("Unable to determine the time to wait until (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		targetTime = t
+		e.saveTime(sig.Context(), targetTimeKey, targetTime)
+	}
+
+	duration := time.Until(targetTime)
+	if duration <= 0 {
+		e.LogPersister.Infof("The scheduled time %s has already come", targetTime.Format(time.RFC3339))
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	e.LogPersister.Infof("Waiting until %s (%v)...", targetTime.Format(time.RFC3339), duration.Round(time.Second))
+	status := e.wait(sig, duration, startTime)
+	if status == model.StageStatus_STAGE_SUCCESS {
+		e.LogPersister.Infof("Reached the scheduled time %s", targetTime.Format(time.RFC3339))
+	}
+	return status
+}
+
+// wait blocks until the given duration elapses or a stop signal is received.
+func (e *Executor) wait(sig executor.StopSignal, duration time.Duration, startTime time.Time) model.StageStatus {
+	originalStatus := e.Stage.Status
 
 	timer := time.NewTimer(duration)
 	defer timer.Stop()
@@ -82,11 +126,9 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 	ticker := time.NewTicker(logInterval)
 	defer ticker.Stop()
 
-	e.LogPersister.Infof("Waiting for %v...", duration)
 	for {
 		select {
 		case <-timer.C:
-			e.LogPersister.Infof("Waited for %v", totalDuration)
 			return model.StageStatus_STAGE_SUCCESS
 
 		case <-ticker.C:
@@ -105,12 +147,12 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 	}
 }
 
-func (e *Executor) retrieveStartTime() (t time.Time) {
+func (e *Executor) retrieveTime(key string) (t time.Time) {
 	metadata, ok := e.MetadataStore.GetStageMetadata(e.Stage.Id)
 	if !ok {
 		return
 	}
-	s, ok := metadata[startTimeKey]
+	s, ok := metadata[key]
 	if !ok {
 		return
 	}
@@ -121,9 +163,9 @@ func (e *Executor) retrieveStartTime() (t time.Time) {
 	return time.Unix(ut, 0)
 }
 
-func (e *Executor) saveStartTime(ctx context.Context, t time.Time) {
+func (e *Executor) saveTime(ctx context.Context, key string, t time.Time) {
 	metadata := map[string]string{
-		startTimeKey: strconv.FormatInt(t.Unix(), 10),
+		key: strconv.FormatInt(t.Unix(), 10),
 	}
 	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
 		e.Logger.Error("failed to store metadata", zap.Error(err))
@@ -0,0 +1,158 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package waitapproval
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/changeticket"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+var changeTicketPollInterval = 30 * time.Second
+
+// waitChangeTicket waits until the change ticket of this deployment
+// is approved or rejected in the external change management system.
+func (e *Executor) waitChangeTicket(sig executor.StopSignal, opts *config.ChangeTicketOptions, timer *time.Timer) model.StageStatus {
+	var (
+		originalStatus = e.Stage.Status
+		ctx            = sig.Context()
+	)
+	defer timer.Stop()
+
+	cfg, ok := e.PipedConfig.GetChangeManagementProvider(opts.Provider)
+	if !ok {
+		e.LogPersister.Errorf("Change management provider %s was not found in the piped configuration", opts.Provider)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	provider, err := changeticket.NewProvider(cfg, e.Logger)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to initialize change management provider %s (%v)", opts.Provider, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	ticket, err := e.prepareChangeTicket(ctx, provider, opts)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to prepare change ticket (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.LogPersister.Infof("Waiting for change ticket %s to be approved: %s", ticket.Number, ticket.URL)
+
+	ticker := time.NewTicker(changeTicketPollInterval)
+	defer ticker.Stop()
+
+	for {
+		switch ticket.Status {
+		case changeticket.StatusApproved:
+			e.LogPersister.Successf("Change ticket %s was approved", ticket.Number)
+			return model.StageStatus_STAGE_SUCCESS
+		case changeticket.StatusRejected:
+			e.LogPersister.Errorf("Change ticket %s was rejected", ticket.Number)
+			return model.StageStatus_STAGE_FAILURE
+		}
+
+		select {
+		case <-ticker.C:
+			t, err := provider.Get(ctx, ticket.ID)
+			if err != nil {
+				e.LogPersister.Errorf("Unable to get change ticket %s (%v)", ticket.Number, err)
+				continue
+			}
+			ticket = t
+
+		case s := <-sig.Ch():
+			switch s {
+			case executor.StopSignalCancel:
+				return model.StageStatus_STAGE_CANCELLED
+			case executor.StopSignalTerminate:
+				return originalStatus
+			default:
+				return model.StageStatus_STAGE_FAILURE
+			}
+
+		case <-timer.C:
+			e.LogPersister.Errorf("Timed out waiting for change ticket %s to be approved", ticket.Number)
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+}
+
+// prepareChangeTicket returns the ticket created by the previous run of this stage,
+// the referenced one or a newly created one, and records it into the metadata.
+func (e *Executor) prepareChangeTicket(ctx context.Context, provider changeticket.Provider, opts *config.ChangeTicketOptions) (*changeticket.Ticket, error) {
+	metadata := make(map[string]string)
+	if ori, ok := e.MetadataStore.GetStageMetadata(e.Stage.Id); ok {
+		for k, v := range ori {
+			metadata[k] = v
+		}
+	}
+
+	var (
+		ticket *changeticket.Ticket
+		err    error
+	)
+	switch id := metadata[changeTicketIDKey]; {
+	case id != "":
+		if ticket, err = provider.Get(ctx, id); err != nil {
+			return nil, fmt.Errorf("unable to get change ticket %s: %w", id, err)
+		}
+		return ticket, nil
+
+	case opts.Ticket != "":
+		if ticket, err = provider.Get(ctx, opts.Ticket); err != nil {
+			return nil, fmt.Errorf("unable to get change ticket %s: %w", opts.Ticket, err)
+		}
+		e.LogPersister.Infof("Referencing change ticket %s", ticket.Number)
+
+	default:
+		data := struct {
+			Deployment *model.Deployment
+			EnvName    string
+		}{
+			Deployment: e.Deployment,
+			EnvName:    e.EnvName,
+		}
+		req, err := changeticket.BuildCreateRequest(opts.Template, data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to build change ticket: %w", err)
+		}
+		req.DeploymentID = e.Deployment.Id
+		if ticket, err = provider.Create(ctx, req); err != nil {
+			return nil, fmt.Errorf("unable to create change ticket: %w", err)
+		}
+		e.LogPersister.Infof("Created change ticket %s", ticket.Number)
+	}
+
+	metadata[changeTicketKey] = ticket.Number
+	metadata[changeTicketIDKey] = ticket.ID
+	metadata[changeTicketURLKey] = ticket.URL
+	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
+		return nil, fmt.Errorf("unable to save change ticket information to deployment: %w", err)
+	}
+
+	if !opts.KeepOpen {
+		if err := e.MetadataStore.Set(ctx, changeticket.ProviderMetadataKey, opts.Provider); err != nil {
+			return nil, fmt.Errorf("unable to save change ticket information to deployment: %w", err)
+		}
+		if err := e.MetadataStore.Set(ctx, changeticket.IDMetadataKey, ticket.ID); err != nil {
+			return nil, fmt.Errorf("unable to save change ticket information to deployment: %w", err)
+		}
+	}
+	return ticket, nil
+}
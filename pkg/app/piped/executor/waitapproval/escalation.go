@@ -0,0 +1,155 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package waitapproval
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	approversKey        = "Approvers"
+	escalatedAtKey      = "EscalatedAt"
+	escalatedToKey      = "EscalatedTo"
+	escalationActionKey = "EscalationAction"
+	waitStartedAtKey    = "WaitStartedAt"
+
+	// The commander recorded as the approver or the rejecter
+	// when the stage was completed by the escalation policy.
+	escalationCommander = "escalation-policy"
+)
+
+// isEscalated reports whether the escalation of this stage was already done.
+func (e *Executor) isEscalated() bool {
+	metadata, ok := e.MetadataStore.GetStageMetadata(e.Stage.Id)
+	if !ok {
+		return false
+	}
+	_, ok = metadata[escalatedAtKey]
+	return ok
+}
+
+// escalationDelay returns how long to wait before escalating the approval.
+// The time this stage started waiting is saved into the stage metadata
+// so that a stage resumed by a restarted piped is escalated on time.
+func (e *Executor) escalationDelay(ctx context.Context, escalation *config.ApprovalEscalation, now time.Time) time.Duration {
+	metadata := e.copyStageMetadata()
+	if s, ok := metadata[waitStartedAtKey]; ok {
+		if ut, err := strconv.ParseInt(s, 10, 64); err == nil {
+			delay := escalation.After.Duration() - now.Sub(time.Unix(ut, 0))
+			if delay < 0 {
+				delay = 0
+			}
+			return delay
+		}
+	}
+
+	metadata[waitStartedAtKey] = strconv.FormatInt(now.Unix(), 10)
+	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
+		e.Logger.Error("failed to store metadata", zap.Error(err))
+	}
+	return escalation.After.Duration()
+}
+
+// escalate delegates the approval to the fallback approvers and
+// takes the configured action since no approval was given in time.
+// The returned bool is true when the stage was completed by the action.
+func (e *Executor) escalate(ctx context.Context, escalation *config.ApprovalEscalation) (model.StageStatus, bool) {
+	action := escalation.Action
+	if action == "" {
+		action = config.ApprovalEscalationActionNotify
+	}
+	e.LogPersister.Infof("No approval was given within %v, escalating the approval with action %s", escalation.After.Duration(), action)
+
+	// Record the escalation into the stage metadata to keep the audit trail of the approval.
+	metadata := e.copyStageMetadata()
+	metadata[escalatedAtKey] = strconv.FormatInt(time.Now().Unix(), 10)
+	metadata[escalationActionKey] = string(action)
+	if len(escalation.FallbackApprovers) > 0 {
+		metadata[escalatedToKey] = strings.Join(escalation.FallbackApprovers, ",")
+		metadata[approversKey] = strings.Join(mergeApprovers(metadata[approversKey], escalation.FallbackApprovers), ",")
+	}
+	switch action {
+	case config.ApprovalEscalationActionApprove:
+		metadata[model.StageMetadataKeyApprovedBy] = escalationCommander
+	case config.ApprovalEscalationActionReject:
+		metadata[rejectedByKey] = escalationCommander
+	}
+	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
+		e.LogPersister.Errorf("Unable to save escalation information to deployment, %v", err)
+	}
+
+	if len(escalation.FallbackApprovers) > 0 {
+		e.LogPersister.Infof("Delegated the approval to %s", strings.Join(escalation.FallbackApprovers, ", "))
+	}
+	e.notifyEscalation(escalation, action)
+
+	switch action {
+	case config.ApprovalEscalationActionApprove:
+		e.LogPersister.Info("The stage was approved automatically by the escalation policy")
+		return model.StageStatus_STAGE_SUCCESS, true
+	case config.ApprovalEscalationActionReject:
+		e.LogPersister.Error("The stage was rejected automatically by the escalation policy")
+		return model.StageStatus_STAGE_FAILURE, true
+	default:
+		e.LogPersister.Info("Waiting for an approval...")
+		return model.StageStatus_STAGE_RUNNING, false
+	}
+}
+
+func (e *Executor) notifyEscalation(escalation *config.ApprovalEscalation, action config.ApprovalEscalationAction) {
+	if e.Notifier == nil {
+		return
+	}
+	e.Notifier.Notify(model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_APPROVAL_ESCALATED,
+		Metadata: &model.NotificationEventDeploymentApprovalEscalated{
+			Deployment:        e.Deployment,
+			EnvName:           e.EnvName,
+			WaitedSeconds:     int64(escalation.After.Duration().Seconds()),
+			FallbackApprovers: escalation.FallbackApprovers,
+			Action:            string(action),
+		},
+	})
+}
+
+// mergeApprovers appends the fallback approvers to the comma-separated
+// list of the original approvers without duplication.
+// Empty original list means anyone can approve so it is kept as is.
+func mergeApprovers(original string, fallbacks []string) []string {
+	if original == "" {
+		return nil
+	}
+	approvers := strings.Split(original, ",")
+	seen := make(map[string]struct{}, len(approvers))
+	for _, a := range approvers {
+		seen[a] = struct{}{}
+	}
+	for _, f := range fallbacks {
+		if _, ok := seen[f]; ok {
+			continue
+		}
+		seen[f] = struct{}{}
+		approvers = append(approvers, f)
+	}
+	return approvers
+}
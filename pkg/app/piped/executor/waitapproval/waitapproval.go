@@ -16,6 +16,7 @@ package waitapproval
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"go.uber.org/zap"
@@ -25,7 +26,12 @@ import (
 )
 
 const (
-	approvedByKey = "ApprovedBy"
+	rejectedByKey       = "RejectedBy"
+	approvalCommentKey  = "ApprovalComment"
+	approvalDecisionKey = "ApprovalDecision"
+	changeTicketKey     = "ChangeTicket"
+	changeTicketIDKey   = "ChangeTicketID"
+	changeTicketURLKey  = "ChangeTicketURL"
 )
 
 type Executor struct {
@@ -57,14 +63,36 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 	timeout := e.StageConfig.WaitApprovalStageOptions.Timeout.Duration()
 	timer := time.NewTimer(timeout)
 
+	if opts := e.StageConfig.WaitApprovalStageOptions.ChangeTicket; opts != nil {
+		return e.waitChangeTicket(sig, opts, timer)
+	}
+
+	// The escalation is not started again when it was already done
+	// before this stage was resumed by a restarted piped.
+	var escalationCh <-chan time.Time
+	escalation := e.StageConfig.WaitApprovalStageOptions.Escalation
+	if escalation != nil && !e.isEscalated() {
+		escalationTimer := time.NewTimer(e.escalationDelay(ctx, escalation, time.Now()))
+		defer escalationTimer.Stop()
+		escalationCh = escalationTimer.C
+	}
+
 	e.LogPersister.Info("Waiting for an approval...")
 	for {
 		select {
 		case <-ticker.C:
-			if commander, ok := e.checkApproval(ctx); ok {
-				e.LogPersister.Infof("Got an approval from %s", commander)
-				return model.StageStatus_STAGE_SUCCESS
+			cmd, ok := e.checkApproval(ctx)
+			if !ok {
+				continue
 			}
+			if cmd.ApproveStage.Reject {
+				e.LogPersister.Errorf("Got a rejection from %s%s", cmd.Commander, formatComment(cmd.ApproveStage.Comment))
+				e.notify(model.NotificationEventType_EVENT_DEPLOYMENT_REJECTED, cmd)
+				return model.StageStatus_STAGE_FAILURE
+			}
+			e.LogPersister.Infof("Got an approval from %s%s", cmd.Commander, formatComment(cmd.ApproveStage.Comment))
+			e.notify(model.NotificationEventType_EVENT_DEPLOYMENT_APPROVED, cmd)
+			return model.StageStatus_STAGE_SUCCESS
 
 		case s := <-sig.Ch():
 			switch s {
@@ -75,6 +103,12 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 			default:
 				return model.StageStatus_STAGE_FAILURE
 			}
+		case <-escalationCh:
+			escalationCh = nil
+			if status, done := e.escalate(ctx, escalation); done {
+				return status
+			}
+
 		case <-timer.C:
 			e.LogPersister.Errorf("Timed out %v", timeout)
 			return model.StageStatus_STAGE_FAILURE
@@ -82,7 +116,7 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 	}
 }
 
-func (e *Executor) checkApproval(ctx context.Context) (string, bool) {
+func (e *Executor) checkApproval(ctx context.Context) (*model.ReportableCommand, bool) {
 	var approveCmd *model.ReportableCommand
 	commands := e.CommandLister.ListCommands()
 
@@ -93,24 +127,84 @@ func (e *Executor) checkApproval(ctx context.Context) (string, bool) {
 		}
 	}
 	if approveCmd == nil {
-		return "", false
+		return nil, false
 	}
 
-	metadata := map[string]string{
-		approvedByKey: approveCmd.Commander,
+	metadata := e.copyStageMetadata()
+	if approveCmd.ApproveStage.Reject {
+		metadata[rejectedByKey] = approveCmd.Commander
+	} else {
+		metadata[model.StageMetadataKeyApprovedBy] = approveCmd.Commander
 	}
+	if approveCmd.ApproveStage.Comment != "" {
+		metadata[approvalCommentKey] = approveCmd.ApproveStage.Comment
+	}
+	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
+		e.LogPersister.Errorf("Unabled to save approver information to deployment, %v", err)
+		return nil, false
+	}
+
+	// The decision is also saved into the handled command
+	// to keep an audit record of who approved or rejected the stage and why.
+	if err := approveCmd.Report(ctx, model.CommandStatus_COMMAND_SUCCEEDED, makeDecisionMetadata(approveCmd), nil); err != nil {
+		e.Logger.Error("failed to report handled command", zap.Error(err))
+	}
+	return approveCmd, true
+}
+
+// copyStageMetadata returns a copy of the current metadata of this stage
+// to be updated and saved back.
+func (e *Executor) copyStageMetadata() map[string]string {
+	metadata := make(map[string]string)
 	if ori, ok := e.MetadataStore.GetStageMetadata(e.Stage.Id); ok {
 		for k, v := range ori {
 			metadata[k] = v
 		}
 	}
-	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
-		e.LogPersister.Errorf("Unabled to save approver information to deployment, %v", err)
-		return "", false
+	return metadata
+}
+
+func (e *Executor) notify(t model.NotificationEventType, cmd *model.ReportableCommand) {
+	if e.Notifier == nil {
+		return
+	}
+	event := model.NotificationEvent{Type: t}
+	switch t {
+	case model.NotificationEventType_EVENT_DEPLOYMENT_APPROVED:
+		event.Metadata = &model.NotificationEventDeploymentApproved{
+			Deployment: e.Deployment,
+			EnvName:    e.EnvName,
+			Approver:   cmd.Commander,
+			Comment:    cmd.ApproveStage.Comment,
+		}
+	case model.NotificationEventType_EVENT_DEPLOYMENT_REJECTED:
+		event.Metadata = &model.NotificationEventDeploymentRejected{
+			Deployment: e.Deployment,
+			EnvName:    e.EnvName,
+			Rejecter:   cmd.Commander,
+			Comment:    cmd.ApproveStage.Comment,
+		}
 	}
+	e.Notifier.Notify(event)
+}
 
-	if err := approveCmd.Report(ctx, model.CommandStatus_COMMAND_SUCCEEDED, nil, nil); err != nil {
-		e.Logger.Error("failed to report handled command", zap.Error(err))
+func makeDecisionMetadata(cmd *model.ReportableCommand) map[string]string {
+	decision := "APPROVED"
+	if cmd.ApproveStage.Reject {
+		decision = "REJECTED"
+	}
+	metadata := map[string]string{
+		approvalDecisionKey: decision,
+	}
+	if cmd.ApproveStage.Comment != "" {
+		metadata[approvalCommentKey] = cmd.ApproveStage.Comment
+	}
+	return metadata
+}
+
+func formatComment(comment string) string {
+	if comment == "" {
+		return ""
 	}
-	return approveCmd.Commander, true
+	return fmt.Sprintf(" with comment: %s", comment)
 }
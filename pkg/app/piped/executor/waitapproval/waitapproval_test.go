@@ -0,0 +1,201 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package waitapproval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeLogPersister struct{}
+
+func (l *fakeLogPersister) Write(_ []byte) (int, error)         { return 0, nil }
+func (l *fakeLogPersister) Info(_ string)                       {}
+func (l *fakeLogPersister) Infof(_ string, _ ...interface{})    {}
+func (l *fakeLogPersister) Success(_ string)                    {}
+func (l *fakeLogPersister) Successf(_ string, _ ...interface{}) {}
+func (l *fakeLogPersister) Error(_ string)                      {}
+func (l *fakeLogPersister) Errorf(_ string, _ ...interface{})   {}
+
+type fakeMetadataStore struct {
+	stages map[string]map[string]string
+}
+
+func (m *fakeMetadataStore) Get(_ string) (string, bool)              { return "", false }
+func (m *fakeMetadataStore) Set(_ context.Context, _, _ string) error { return nil }
+func (m *fakeMetadataStore) GetStageMetadata(id string) (map[string]string, bool) {
+	md, ok := m.stages[id]
+	return md, ok
+}
+func (m *fakeMetadataStore) SetStageMetadata(_ context.Context, id string, md map[string]string) error {
+	m.stages[id] = md
+	return nil
+}
+func (m *fakeMetadataStore) SetStageResults(_ context.Context, _ string, _ map[string]string) error {
+	return nil
+}
+
+type fakeCommandLister struct {
+	commands []model.ReportableCommand
+}
+
+func (l *fakeCommandLister) ListCommands() []model.ReportableCommand {
+	return l.commands
+}
+
+type fakeNotifier struct {
+	events []model.NotificationEvent
+}
+
+func (n *fakeNotifier) Notify(event model.NotificationEvent) {
+	n.events = append(n.events, event)
+}
+
+func TestCheckApproval(t *testing.T) {
+	testcases := []struct {
+		name                   string
+		approveStage           *model.Command_ApproveStage
+		expectedStageMetadata  map[string]string
+		expectedReportMetadata map[string]string
+		expectedEvent          model.NotificationEventType
+	}{
+		{
+			name:         "approved without comment",
+			approveStage: &model.Command_ApproveStage{},
+			expectedStageMetadata: map[string]string{
+				"Approvers":                      "user-1",
+				model.StageMetadataKeyApprovedBy: "user-1",
+			},
+			expectedReportMetadata: map[string]string{
+				approvalDecisionKey: "APPROVED",
+			},
+			expectedEvent: model.NotificationEventType_EVENT_DEPLOYMENT_APPROVED,
+		},
+		{
+			name:         "rejected with comment",
+			approveStage: &model.Command_ApproveStage{Reject: true, Comment: "not in the release window"},
+			expectedStageMetadata: map[string]string{
+				"Approvers":        "user-1",
+				rejectedByKey:      "user-1",
+				approvalCommentKey: "not in the release window",
+			},
+			expectedReportMetadata: map[string]string{
+				approvalDecisionKey: "REJECTED",
+				approvalCommentKey:  "not in the release window",
+			},
+			expectedEvent: model.NotificationEventType_EVENT_DEPLOYMENT_REJECTED,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var reported map[string]string
+			cmd := model.ReportableCommand{
+				Command: &model.Command{
+					Commander:    "user-1",
+					ApproveStage: tc.approveStage,
+				},
+				Report: func(_ context.Context, status model.CommandStatus, metadata map[string]string, _ []byte) error {
+					assert.Equal(t, model.CommandStatus_COMMAND_SUCCEEDED, status)
+					reported = metadata
+					return nil
+				},
+			}
+			ms := &fakeMetadataStore{
+				stages: map[string]map[string]string{
+					"stage-1": {"Approvers": "user-1"},
+				},
+			}
+			notifier := &fakeNotifier{}
+			e := &Executor{
+				Input: executor.Input{
+					Stage:         &model.PipelineStage{Id: "stage-1"},
+					Deployment:    &model.Deployment{Id: "deployment-1"},
+					CommandLister: &fakeCommandLister{commands: []model.ReportableCommand{cmd}},
+					MetadataStore: ms,
+					LogPersister:  &fakeLogPersister{},
+					Notifier:      notifier,
+					Logger:        zap.NewNop(),
+				},
+			}
+
+			got, ok := e.checkApproval(context.Background())
+			require.True(t, ok)
+			e.notify(tc.expectedEvent, got)
+
+			assert.Equal(t, tc.expectedStageMetadata, ms.stages["stage-1"])
+			assert.Equal(t, tc.expectedReportMetadata, reported)
+			require.Len(t, notifier.events, 1)
+			assert.Equal(t, tc.expectedEvent, notifier.events[0].Type)
+		})
+	}
+}
+
+func TestCheckApprovalNoCommand(t *testing.T) {
+	e := &Executor{
+		Input: executor.Input{
+			Stage:         &model.PipelineStage{Id: "stage-1"},
+			CommandLister: &fakeCommandLister{},
+			MetadataStore: &fakeMetadataStore{stages: map[string]map[string]string{}},
+			LogPersister:  &fakeLogPersister{},
+			Logger:        zap.NewNop(),
+		},
+	}
+	_, ok := e.checkApproval(context.Background())
+	assert.False(t, ok)
+}
+
+func TestEscalationDelay(t *testing.T) {
+	escalation := &config.ApprovalEscalation{
+		After: config.Duration(time.Hour),
+	}
+	now := time.Unix(1600000000, 0)
+	ms := &fakeMetadataStore{
+		stages: map[string]map[string]string{
+			"stage-id": {
+				model.StageMetadataKeyApprovalCommentRequired: "true",
+			},
+		},
+	}
+	e := &Executor{
+		Input: executor.Input{
+			Stage:         &model.PipelineStage{Id: "stage-id"},
+			MetadataStore: ms,
+			Logger:        zap.NewNop(),
+		},
+	}
+
+	// The start time is saved when the stage started waiting for the first time.
+	delay := e.escalationDelay(context.Background(), escalation, now)
+	assert.Equal(t, time.Hour, delay)
+	assert.Equal(t, "1600000000", ms.stages["stage-id"][waitStartedAtKey])
+	assert.Equal(t, "true", ms.stages["stage-id"][model.StageMetadataKeyApprovalCommentRequired])
+
+	// The resumed stage only waits for the rest of the time.
+	delay = e.escalationDelay(context.Background(), escalation, now.Add(40*time.Minute))
+	assert.Equal(t, 20*time.Minute, delay)
+	assert.Equal(t, "1600000000", ms.stages["stage-id"][waitStartedAtKey])
+
+	delay = e.escalationDelay(context.Background(), escalation, now.Add(2*time.Hour))
+	assert.Equal(t, time.Duration(0), delay)
+}
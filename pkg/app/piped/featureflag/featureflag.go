@@ -0,0 +1,137 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package featureflag changes the feature flags managed in the external services
+// such as LaunchDarkly and restores them while rolling back the deployments.
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/httpjson"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// ChangesMetadataKey is the key of the deployment metadata storing
+// the list of flags changed by the deployment and their previous states.
+const ChangesMetadataKey = "feature-flag-changes"
+
+// Flag identifies a feature flag in an environment.
+type Flag struct {
+	Key         string `json:"key"`
+	Project     string `json:"project,omitempty"`
+	Environment string `json:"environment"`
+}
+
+func (f Flag) String() string {
+	if f.Project == "" {
+		return fmt.Sprintf("%s in %s", f.Key, f.Environment)
+	}
+	return fmt.Sprintf("%s/%s in %s", f.Project, f.Key, f.Environment)
+}
+
+// Provider changes the feature flags.
+type Provider interface {
+	// Snapshot returns the current state of the flag to restore it later.
+	Snapshot(ctx context.Context, flag Flag) (json.RawMessage, error)
+	// Rollout turns on the flag and serves its enabled variation to the given percentage of users.
+	// 0 turns off the flag.
+	Rollout(ctx context.Context, flag Flag, percentage int) error
+	// Restore brings the flag back to the state taken by Snapshot.
+	Restore(ctx context.Context, flag Flag, snapshot json.RawMessage) error
+}
+
+// NewProvider generates an appropriate provider according to the feature flag provider config.
+func NewProvider(cfg config.PipedFeatureFlagProvider, logger *zap.Logger) (Provider, error) {
+	logger = logger.Named("feature-flag").With(zap.String("provider", cfg.Name))
+	switch cfg.Type {
+	case config.FeatureFlagProviderLaunchDarkly:
+		c := cfg.LaunchDarklyConfig
+		token, err := httpjson.ReadSecretFile(c.APITokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the api token file: %w", err)
+		}
+		address := c.Address
+		if address == "" {
+			address = defaultLaunchDarklyAddress
+		}
+		return &launchDarkly{
+			address: strings.TrimSuffix(address, "/"),
+			token:   token,
+			logger:  logger,
+		}, nil
+
+	case config.FeatureFlagProviderHTTP:
+		c := cfg.HTTPConfig
+		p := &httpProvider{
+			address: strings.TrimSuffix(c.Address, "/"),
+			logger:  logger,
+		}
+		if c.TokenFile != "" {
+			token, err := httpjson.ReadSecretFile(c.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the token file: %w", err)
+			}
+			p.token = token
+		}
+		return p, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported feature flag provider type: %s", cfg.Type)
+	}
+}
+
+// Change records a flag changed by a deployment and its state before the change.
+type Change struct {
+	Provider string          `json:"provider"`
+	Flag     Flag            `json:"flag"`
+	Snapshot json.RawMessage `json:"snapshot"`
+}
+
+// DecodeChanges decodes the value of the ChangesMetadataKey metadata.
+func DecodeChanges(value string) ([]Change, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var changes []Change
+	if err := json.Unmarshal([]byte(value), &changes); err != nil {
+		return nil, fmt.Errorf("malformed feature flag changes: %w", err)
+	}
+	return changes, nil
+}
+
+// AppendChange adds the given change to the encoded changes.
+// Nothing is added when the same flag has been already recorded
+// to keep the state before the deployment.
+func AppendChange(value string, c Change) (string, bool, error) {
+	changes, err := DecodeChanges(value)
+	if err != nil {
+		return "", false, err
+	}
+	for _, ch := range changes {
+		if ch.Provider == c.Provider && ch.Flag == c.Flag {
+			return value, false, nil
+		}
+	}
+	data, err := json.Marshal(append(changes, c))
+	if err != nil {
+		return "", false, err
+	}
+	return string(data), true, nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featureflag
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendChange(t *testing.T) {
+	flag := Flag{Key: "new-checkout", Project: "default", Environment: "production"}
+	first := Change{Provider: "launchdarkly", Flag: flag, Snapshot: json.RawMessage(`{"on":false}`)}
+
+	value, added, err := AppendChange("", first)
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	// The state before the deployment is kept.
+	value, added, err = AppendChange(value, Change{Provider: "launchdarkly", Flag: flag, Snapshot: json.RawMessage(`{"on":true}`)})
+	require.NoError(t, err)
+	assert.False(t, added)
+
+	other := Change{Provider: "launchdarkly", Flag: Flag{Key: "new-search", Environment: "production"}, Snapshot: json.RawMessage(`{"on":true}`)}
+	value, added, err = AppendChange(value, other)
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	changes, err := DecodeChanges(value)
+	require.NoError(t, err)
+	assert.Equal(t, []Change{first, other}, changes)
+
+	_, _, err = AppendChange("{", first)
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/httpjson"
+)
+
+// httpProvider changes the flags through a simple HTTP API
+// reading and writing the state of a flag as httpFlagState.
+type httpProvider struct {
+	address string
+	token   string
+	client  *http.Client
+	logger  *zap.Logger
+}
+
+type httpFlagState struct {
+	Enabled    bool `json:"enabled"`
+	Percentage int  `json:"percentage"`
+}
+
+func (p *httpProvider) Snapshot(ctx context.Context, flag Flag) (json.RawMessage, error) {
+	req, err := httpjson.NewRequest(ctx, http.MethodGet, p.flagURL(flag), nil)
+	if err != nil {
+		return nil, err
+	}
+	p.authorize(req)
+
+	var state httpFlagState
+	if err := httpjson.Do(p.client, req, &state); err != nil {
+		return nil, fmt.Errorf("failed to get flag %s: %w", flag, err)
+	}
+	return json.Marshal(state)
+}
+
+func (p *httpProvider) Rollout(ctx context.Context, flag Flag, percentage int) error {
+	return p.put(ctx, flag, httpFlagState{
+		Enabled:    percentage > 0,
+		Percentage: percentage,
+	})
+}
+
+func (p *httpProvider) Restore(ctx context.Context, flag Flag, snapshot json.RawMessage) error {
+	var state httpFlagState
+	if err := json.Unmarshal(snapshot, &state); err != nil {
+		return fmt.Errorf("malformed snapshot of flag %s: %w", flag, err)
+	}
+	return p.put(ctx, flag, state)
+}
+
+func (p *httpProvider) put(ctx context.Context, flag Flag, state httpFlagState) error {
+	req, err := httpjson.NewRequest(ctx, http.MethodPut, p.flagURL(flag), state)
+	if err != nil {
+		return err
+	}
+	p.authorize(req)
+
+	if err := httpjson.Do(p.client, req, nil); err != nil {
+		return fmt.Errorf("failed to update flag %s: %w", flag, err)
+	}
+	p.logger.Info(fmt.Sprintf("updated flag %s", flag))
+	return nil
+}
+
+func (p *httpProvider) flagURL(flag Flag) string {
+	query := url.Values{}
+	query.Set("environment", flag.Environment)
+	if flag.Project != "" {
+		query.Set("project", flag.Project)
+	}
+	return fmt.Sprintf("%s/flags/%s?%s", p.address, url.PathEscape(flag.Key), query.Encode())
+}
+
+func (p *httpProvider) authorize(req *http.Request) {
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/httpjson"
+)
+
+const (
+	defaultLaunchDarklyAddress = "https://app.launchdarkly.com"
+	launchDarklySemanticPatch  = "application/json; domain-model=launchdarkly.semanticpatch"
+	// The rollout weights of LaunchDarkly are in thousandths of a percent.
+	launchDarklyWeightPerPercent = 1000
+)
+
+// launchDarkly changes the fallthrough rule of the boolean flags through the REST API v2.
+type launchDarkly struct {
+	address string
+	token   string
+	client  *http.Client
+	logger  *zap.Logger
+}
+
+type launchDarklyFlag struct {
+	Variations []struct {
+		ID    string      `json:"_id"`
+		Value interface{} `json:"value"`
+	} `json:"variations"`
+	Environments map[string]struct {
+		On          bool                    `json:"on"`
+		Fallthrough launchDarklyFallthrough `json:"fallthrough"`
+	} `json:"environments"`
+}
+
+type launchDarklyFallthrough struct {
+	Variation *int `json:"variation,omitempty"`
+	Rollout   *struct {
+		Variations []struct {
+			Variation int `json:"variation"`
+			Weight    int `json:"weight"`
+		} `json:"variations"`
+	} `json:"rollout,omitempty"`
+}
+
+type launchDarklySnapshot struct {
+	On          bool                    `json:"on"`
+	Fallthrough launchDarklyFallthrough `json:"fallthrough"`
+}
+
+func (p *launchDarkly) Snapshot(ctx context.Context, flag Flag) (json.RawMessage, error) {
+	f, err := p.getFlag(ctx, flag)
+	if err != nil {
+		return nil, err
+	}
+	env := f.Environments[flag.Environment]
+	return json.Marshal(launchDarklySnapshot{
+		On:          env.On,
+		Fallthrough: env.Fallthrough,
+	})
+}
+
+func (p *launchDarkly) Rollout(ctx context.Context, flag Flag, percentage int) error {
+	if percentage <= 0 {
+		return p.patch(ctx, flag, map[string]interface{}{"kind": "turnFlagOff"})
+	}
+
+	f, err := p.getFlag(ctx, flag)
+	if err != nil {
+		return err
+	}
+	var onID, offID string
+	for _, v := range f.Variations {
+		switch v.Value {
+		case true:
+			onID = v.ID
+		case false:
+			offID = v.ID
+		}
+	}
+	if onID == "" || offID == "" {
+		return fmt.Errorf("flag %s is not a boolean flag", flag)
+	}
+
+	rule := map[string]interface{}{
+		"kind":        "updateFallthroughVariationOrRollout",
+		"variationId": onID,
+	}
+	if percentage < 100 {
+		rule = map[string]interface{}{
+			"kind": "updateFallthroughVariationOrRollout",
+			"rolloutWeights": map[string]int{
+				onID:  percentage * launchDarklyWeightPerPercent,
+				offID: (100 - percentage) * launchDarklyWeightPerPercent,
+			},
+		}
+	}
+	return p.patch(ctx, flag, map[string]interface{}{"kind": "turnFlagOn"}, rule)
+}
+
+func (p *launchDarkly) Restore(ctx context.Context, flag Flag, snapshot json.RawMessage) error {
+	var s launchDarklySnapshot
+	if err := json.Unmarshal(snapshot, &s); err != nil {
+		return fmt.Errorf("malformed snapshot of flag %s: %w", flag, err)
+	}
+	f, err := p.getFlag(ctx, flag)
+	if err != nil {
+		return err
+	}
+	variationID := func(i int) (string, error) {
+		if i < 0 || i >= len(f.Variations) {
+			return "", fmt.Errorf("variation %d of flag %s was not found", i, flag)
+		}
+		return f.Variations[i].ID, nil
+	}
+
+	instructions := []map[string]interface{}{{"kind": "turnFlagOff"}}
+	if s.On {
+		instructions[0]["kind"] = "turnFlagOn"
+	}
+	switch ft := s.Fallthrough; {
+	case ft.Variation != nil:
+		id, err := variationID(*ft.Variation)
+		if err != nil {
+			return err
+		}
+		instructions = append(instructions, map[string]interface{}{
+			"kind":        "updateFallthroughVariationOrRollout",
+			"variationId": id,
+		})
+	case ft.Rollout != nil:
+		weights := make(map[string]int, len(ft.Rollout.Variations))
+		for _, v := range ft.Rollout.Variations {
+			id, err := variationID(v.Variation)
+			if err != nil {
+				return err
+			}
+			weights[id] = v.Weight
+		}
+		instructions = append(instructions, map[string]interface{}{
+			"kind":           "updateFallthroughVariationOrRollout",
+			"rolloutWeights": weights,
+		})
+	}
+	return p.patch(ctx, flag, instructions...)
+}
+
+func (p *launchDarkly) getFlag(ctx context.Context, flag Flag) (*launchDarklyFlag, error) {
+	u := fmt.Sprintf("%s/api/v2/flags/%s/%s?env=%s",
+		p.address,
+		url.PathEscape(flag.Project),
+		url.PathEscape(flag.Key),
+		url.QueryEscape(flag.Environment),
+	)
+	req, err := httpjson.NewRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", p.token)
+
+	var f launchDarklyFlag
+	if err := httpjson.Do(p.client, req, &f); err != nil {
+		return nil, fmt.Errorf("failed to get flag %s: %w", flag, err)
+	}
+	if _, ok := f.Environments[flag.Environment]; !ok {
+		return nil, fmt.Errorf("environment of flag %s was not found", flag)
+	}
+	return &f, nil
+}
+
+func (p *launchDarkly) patch(ctx context.Context, flag Flag, instructions ...map[string]interface{}) error {
+	u := fmt.Sprintf("%s/api/v2/flags/%s/%s", p.address, url.PathEscape(flag.Project), url.PathEscape(flag.Key))
+	body := map[string]interface{}{
+		"environmentKey": flag.Environment,
+		"instructions":   instructions,
+		"comment":        "Changed by PipeCD",
+	}
+	req, err := httpjson.NewRequest(ctx, http.MethodPatch, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", p.token)
+	req.Header.Set("Content-Type", launchDarklySemanticPatch)
+
+	if err := httpjson.Do(p.client, req, nil); err != nil {
+		return fmt.Errorf("failed to update flag %s: %w", flag, err)
+	}
+	p.logger.Info(fmt.Sprintf("updated flag %s", flag))
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLaunchDarkly(t *testing.T) {
+	var patches []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "api-token" || r.URL.Path != "/api/v2/flags/default/new-checkout" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{
+  "variations": [{"_id": "var-on", "value": true}, {"_id": "var-off", "value": false}],
+  "environments": {"production": {"on": false, "fallthrough": {"variation": 1}}}
+}`))
+		case http.MethodPatch:
+			assert.Equal(t, launchDarklySemanticPatch, r.Header.Get("Content-Type"))
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			patches = append(patches, body)
+		}
+	}))
+	defer server.Close()
+
+	p := &launchDarkly{
+		address: server.URL,
+		token:   "api-token",
+		client:  server.Client(),
+		logger:  zap.NewNop(),
+	}
+	ctx := context.Background()
+	flag := Flag{Key: "new-checkout", Project: "default", Environment: "production"}
+
+	snapshot, err := p.Snapshot(ctx, flag)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"on": false, "fallthrough": {"variation": 1}}`, string(snapshot))
+
+	testcases := []struct {
+		name         string
+		percentage   int
+		instructions string
+	}{
+		{
+			name:         "partial rollout",
+			percentage:   10,
+			instructions: `[{"kind": "turnFlagOn"}, {"kind": "updateFallthroughVariationOrRollout", "rolloutWeights": {"var-on": 10000, "var-off": 90000}}]`,
+		},
+		{
+			name:         "full rollout",
+			percentage:   100,
+			instructions: `[{"kind": "turnFlagOn"}, {"kind": "updateFallthroughVariationOrRollout", "variationId": "var-on"}]`,
+		},
+		{
+			name:         "turn off",
+			percentage:   0,
+			instructions: `[{"kind": "turnFlagOff"}]`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			patches = nil
+			err := p.Rollout(ctx, flag, tc.percentage)
+			require.NoError(t, err)
+			require.Len(t, patches, 1)
+			assert.Equal(t, "production", patches[0]["environmentKey"])
+			instructions, _ := json.Marshal(patches[0]["instructions"])
+			assert.JSONEq(t, tc.instructions, string(instructions))
+		})
+	}
+
+	patches = nil
+	err = p.Restore(ctx, flag, snapshot)
+	require.NoError(t, err)
+	require.Len(t, patches, 1)
+	instructions, _ := json.Marshal(patches[0]["instructions"])
+	assert.JSONEq(t, `[{"kind": "turnFlagOff"}, {"kind": "updateFallthroughVariationOrRollout", "variationId": "var-off"}]`, string(instructions))
+
+	_, err = p.Snapshot(ctx, Flag{Key: "new-checkout", Project: "default", Environment: "staging"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpjson provides the helpers shared by the clients
+// calling the JSON APIs of the external services such as
+// the change management systems, the feature flag services and PagerDuty.
+package httpjson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is the timeout of the clients created by NewClient.
+// The external services must not block the deployments for long.
+const DefaultTimeout = 30 * time.Second
+
+var defaultClient = NewClient()
+
+// NewClient returns a new HTTP client having DefaultTimeout.
+func NewClient() *http.Client {
+	return &http.Client{Timeout: DefaultTimeout}
+}
+
+// ReadSecretFile returns the content of the given secret file
+// without the surrounding spaces and newlines.
+func ReadSecretFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// NewRequest creates a request whose body is the JSON encoded in.
+// The request has no body when in is nil.
+func NewRequest(ctx context.Context, method, url string, in interface{}) (*http.Request, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// Do sends the given request and decodes the JSON response into out unless it is nil.
+// An error is returned when the response status code is not 2xx.
+// The client having DefaultTimeout is used when the given one is nil.
+func Do(client *http.Client, req *http.Request, out interface{}) error {
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code %d from %s %s: %s", resp.StatusCode, req.Method, req.URL.Path, string(data))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("malformed response from %s %s (%w)", req.Method, req.URL.Path, err)
+	}
+	return nil
+}
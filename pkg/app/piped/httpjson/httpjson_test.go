@@ -0,0 +1,84 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpjson
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadSecretFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpjson")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(path, []byte(" token\n"), 0600))
+
+	token, err := ReadSecretFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "token", token)
+
+	_, err = ReadSecretFile(filepath.Join(dir, "not-found"))
+	assert.Error(t, err)
+}
+
+func TestDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			body["content-type"] = r.Header.Get("Content-Type")
+			json.NewEncoder(w).Encode(body)
+		case "/malformed":
+			w.Write([]byte("not json"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	req, err := NewRequest(ctx, http.MethodPost, server.URL+"/echo", map[string]string{"name": "foo"})
+	require.NoError(t, err)
+	var out map[string]string
+	require.NoError(t, Do(server.Client(), req, &out))
+	assert.Equal(t, map[string]string{"name": "foo", "content-type": "application/json"}, out)
+
+	req, err = NewRequest(ctx, http.MethodGet, server.URL+"/malformed", nil)
+	require.NoError(t, err)
+	assert.NoError(t, Do(nil, req, nil))
+
+	req, err = NewRequest(ctx, http.MethodGet, server.URL+"/malformed", nil)
+	require.NoError(t, err)
+	err = Do(nil, req, &out)
+	assert.EqualError(t, err, "malformed response from GET /malformed (invalid character 'o' in literal null (expecting 'u'))")
+
+	req, err = NewRequest(ctx, http.MethodGet, server.URL+"/unknown", nil)
+	require.NoError(t, err)
+	err = Do(nil, req, &out)
+	assert.EqualError(t, err, "unexpected status code 404 from GET /unknown: not found")
+}
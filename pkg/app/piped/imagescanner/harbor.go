@@ -0,0 +1,123 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagescanner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/httpjson"
+)
+
+const harborReportMimeType = "application/vnd.security.vulnerability.report; version=1.1"
+
+// harborScanner uses the scan results of the artifacts stored in a Harbor registry.
+// The images must be pushed to that registry and scanned beforehand.
+type harborScanner struct {
+	address  string
+	username string
+	password string
+	client   *http.Client
+	logger   *zap.Logger
+}
+
+type harborReport struct {
+	Vulnerabilities []struct {
+		ID          string `json:"id"`
+		Package     string `json:"package"`
+		Version     string `json:"version"`
+		FixVersion  string `json:"fix_version"`
+		Severity    string `json:"severity"`
+		Description string `json:"description"`
+	} `json:"vulnerabilities"`
+}
+
+func (s *harborScanner) Scan(ctx context.Context, image string) ([]Vulnerability, error) {
+	project, repository, reference, err := s.parseImage(image)
+	if err != nil {
+		return nil, err
+	}
+
+	// The slashes in the repository name must be escaped twice.
+	u := fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts/%s/additions/vulnerabilities",
+		s.address,
+		url.PathEscape(project),
+		url.PathEscape(url.PathEscape(repository)),
+		url.PathEscape(reference),
+	)
+	req, err := httpjson.NewRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Accept-Vulnerabilities", harborReportMimeType)
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	// The reports are keyed by their mime types.
+	var reports map[string]harborReport
+	if err := httpjson.Do(s.client, req, &reports); err != nil {
+		return nil, fmt.Errorf("failed to get scan result of %s: %w", image, err)
+	}
+	report, ok := reports[harborReportMimeType]
+	if !ok {
+		return nil, fmt.Errorf("image %s has not been scanned yet", image)
+	}
+
+	vulns := make([]Vulnerability, 0, len(report.Vulnerabilities))
+	for _, v := range report.Vulnerabilities {
+		vulns = append(vulns, Vulnerability{
+			ID:               v.ID,
+			Package:          v.Package,
+			InstalledVersion: v.Version,
+			FixedVersion:     v.FixVersion,
+			Severity:         normalizeSeverity(v.Severity),
+			Title:            v.Description,
+		})
+	}
+	return vulns, nil
+}
+
+// parseImage splits the given image hosted in the registry
+// into its project, repository and reference (tag or digest).
+// e.g. harbor.example.com/library/nginx:1.21 -> library, nginx, 1.21
+func (s *harborScanner) parseImage(image string) (project, repository, reference string, err error) {
+	addr, err := url.Parse(s.address)
+	if err != nil {
+		return "", "", "", err
+	}
+	name := strings.TrimPrefix(image, addr.Host+"/")
+	if name == image {
+		return "", "", "", fmt.Errorf("image %s is not hosted in the registry %s", image, addr.Host)
+	}
+
+	reference = "latest"
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, reference = name[:i], name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, reference = name[:i], name[i+1:]
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) != 2 {
+		return "", "", "", fmt.Errorf("image %s must contain both project and repository", image)
+	}
+	return parts[0], parts[1], reference, nil
+}
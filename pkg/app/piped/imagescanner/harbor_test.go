@@ -0,0 +1,121 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagescanner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestHarborParseImage(t *testing.T) {
+	s := &harborScanner{address: "https://harbor.example.com"}
+	testcases := []struct {
+		image              string
+		expectedProject    string
+		expectedRepository string
+		expectedReference  string
+		expectedErr        bool
+	}{
+		{
+			image:              "harbor.example.com/library/nginx:1.21",
+			expectedProject:    "library",
+			expectedRepository: "nginx",
+			expectedReference:  "1.21",
+		},
+		{
+			image:              "harbor.example.com/pipecd/apps/helloworld@sha256:abc",
+			expectedProject:    "pipecd",
+			expectedRepository: "apps/helloworld",
+			expectedReference:  "sha256:abc",
+		},
+		{
+			image:              "harbor.example.com/library/nginx",
+			expectedProject:    "library",
+			expectedRepository: "nginx",
+			expectedReference:  "latest",
+		},
+		{
+			image:       "gcr.io/pipecd/helloworld:v0.1.0",
+			expectedErr: true,
+		},
+		{
+			image:       "harbor.example.com/nginx:1.21",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.image, func(t *testing.T) {
+			project, repository, reference, err := s.parseImage(tc.image)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Equal(t, tc.expectedProject, project)
+			assert.Equal(t, tc.expectedRepository, repository)
+			assert.Equal(t, tc.expectedReference, reference)
+		})
+	}
+}
+
+func TestHarborScan(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v2.0/projects/pipecd/repositories/apps%252Fhelloworld/artifacts/v0.1.0/additions/vulnerabilities" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{
+  "application/vnd.security.vulnerability.report; version=1.1": {
+    "vulnerabilities": [
+      {"id": "CVE-2021-3711", "package": "openssl", "version": "1.1.1k", "fix_version": "1.1.1l", "severity": "Critical"},
+      {"id": "CVE-2021-33560", "package": "libgcrypt20", "version": "1.8.4", "severity": "High"}
+    ]
+  }
+}`))
+	}))
+	defer server.Close()
+
+	s := &harborScanner{
+		address: server.URL,
+		logger:  zap.NewNop(),
+	}
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	vulns, err := s.Scan(context.Background(), host+"/pipecd/apps/helloworld:v0.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, []Vulnerability{
+		{
+			ID:               "CVE-2021-3711",
+			Package:          "openssl",
+			InstalledVersion: "1.1.1k",
+			FixedVersion:     "1.1.1l",
+			Severity:         config.VulnerabilitySeverityCritical,
+		},
+		{
+			ID:               "CVE-2021-33560",
+			Package:          "libgcrypt20",
+			InstalledVersion: "1.8.4",
+			Severity:         config.VulnerabilitySeverityHigh,
+		},
+	}, vulns)
+
+	_, err = s.Scan(context.Background(), host+"/pipecd/apps/unknown:v0.1.0")
+	assert.Error(t, err)
+}
@@ -0,0 +1,107 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package imagescanner finds the known vulnerabilities of container images
+// by asking the configured scanners such as a Trivy server or a Harbor registry.
+package imagescanner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/httpjson"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// Vulnerability represents a known vulnerability found in an image.
+type Vulnerability struct {
+	// The identifier of the vulnerability, e.g. CVE-2021-3711.
+	ID string
+	// The name of the package containing the vulnerability.
+	Package string
+	// The version of the package installed in the image.
+	InstalledVersion string
+	// The version of the package fixing the vulnerability.
+	// Empty means no fix is available yet.
+	FixedVersion string
+	Severity     config.VulnerabilitySeverity
+	Title        string
+}
+
+// Scanner finds the vulnerabilities of a given image.
+type Scanner interface {
+	Scan(ctx context.Context, image string) ([]Vulnerability, error)
+}
+
+// NewScanner generates an appropriate scanner according to the image scanner config.
+func NewScanner(cfg config.PipedImageScanner, logger *zap.Logger) (Scanner, error) {
+	logger = logger.Named("image-scanner").With(zap.String("scanner", cfg.Name))
+	switch cfg.Type {
+	case config.ImageScannerTrivy:
+		c := cfg.TrivyConfig
+		var token string
+		if c.TokenFile != "" {
+			t, err := httpjson.ReadSecretFile(c.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the token file: %w", err)
+			}
+			token = t
+		}
+		return &trivyScanner{
+			address: c.Address,
+			token:   token,
+			version: c.Version,
+			logger:  logger,
+		}, nil
+
+	case config.ImageScannerHarbor:
+		c := cfg.HarborConfig
+		s := &harborScanner{
+			address: strings.TrimSuffix(c.Address, "/"),
+			logger:  logger,
+		}
+		if c.UsernameFile != "" && c.PasswordFile != "" {
+			username, err := httpjson.ReadSecretFile(c.UsernameFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the username file: %w", err)
+			}
+			password, err := httpjson.ReadSecretFile(c.PasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the password file: %w", err)
+			}
+			s.username, s.password = username, password
+		}
+		return s, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported image scanner type: %s", cfg.Type)
+	}
+}
+
+// normalizeSeverity converts the severity returned by the scanners
+// into one of the configurable severities.
+func normalizeSeverity(s string) config.VulnerabilitySeverity {
+	switch sev := config.VulnerabilitySeverity(strings.ToUpper(s)); sev {
+	case config.VulnerabilitySeverityLow,
+		config.VulnerabilitySeverityMedium,
+		config.VulnerabilitySeverityHigh,
+		config.VulnerabilitySeverityCritical:
+		return sev
+	default:
+		return config.VulnerabilitySeverityUnknown
+	}
+}
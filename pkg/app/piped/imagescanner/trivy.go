@@ -0,0 +1,97 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagescanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+)
+
+// trivyScanner scans the images by running the Trivy client
+// which sends the image layers to the configured Trivy server.
+type trivyScanner struct {
+	address string
+	token   string
+	version string
+	logger  *zap.Logger
+}
+
+type trivyReport struct {
+	Results []struct {
+		Target          string `json:"Target"`
+		Vulnerabilities []struct {
+			VulnerabilityID  string `json:"VulnerabilityID"`
+			PkgName          string `json:"PkgName"`
+			InstalledVersion string `json:"InstalledVersion"`
+			FixedVersion     string `json:"FixedVersion"`
+			Severity         string `json:"Severity"`
+			Title            string `json:"Title"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+func (s *trivyScanner) Scan(ctx context.Context, image string) ([]Vulnerability, error) {
+	path, installed, err := toolregistry.DefaultRegistry().Trivy(ctx, s.version)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find required trivy %q (%w)", s.version, err)
+	}
+	if installed {
+		s.logger.Info(fmt.Sprintf("trivy %q has just been installed to %q because of no pre-installed binary for that version", s.version, path))
+	}
+
+	args := []string{"--quiet", "image", "--server", s.address, "--format", "json"}
+	if s.token != "" {
+		args = append(args, "--token", s.token)
+	}
+	args = append(args, image)
+
+	var stdout, stderr bytes.Buffer
+	cmd := toolexec.CommandContext(ctx, path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to scan image %s: %s (%w)", image, stderr.String(), err)
+	}
+	return parseTrivyReport(stdout.Bytes())
+}
+
+func parseTrivyReport(data []byte) ([]Vulnerability, error) {
+	var report trivyReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("malformed trivy report (%w)", err)
+	}
+
+	var vulns []Vulnerability
+	for _, r := range report.Results {
+		for _, v := range r.Vulnerabilities {
+			vulns = append(vulns, Vulnerability{
+				ID:               v.VulnerabilityID,
+				Package:          v.PkgName,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+				Severity:         normalizeSeverity(v.Severity),
+				Title:            v.Title,
+			})
+		}
+	}
+	return vulns, nil
+}
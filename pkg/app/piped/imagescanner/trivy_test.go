@@ -0,0 +1,57 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagescanner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestParseTrivyReport(t *testing.T) {
+	report := `{
+  "SchemaVersion": 2,
+  "ArtifactName": "gcr.io/pipecd/helloworld:v0.1.0",
+  "Results": [
+    {
+      "Target": "gcr.io/pipecd/helloworld:v0.1.0 (debian 10.10)",
+      "Vulnerabilities": [
+        {"VulnerabilityID": "CVE-2021-3711", "PkgName": "openssl", "InstalledVersion": "1.1.1d-0+deb10u6", "FixedVersion": "1.1.1d-0+deb10u7", "Severity": "CRITICAL", "Title": "openssl: SM2 Decryption Buffer Overflow"}
+      ]
+    },
+    {
+      "Target": "app/go.sum"
+    }
+  ]
+}`
+	vulns, err := parseTrivyReport([]byte(report))
+	require.NoError(t, err)
+	assert.Equal(t, []Vulnerability{
+		{
+			ID:               "CVE-2021-3711",
+			Package:          "openssl",
+			InstalledVersion: "1.1.1d-0+deb10u6",
+			FixedVersion:     "1.1.1d-0+deb10u7",
+			Severity:         config.VulnerabilitySeverityCritical,
+			Title:            "openssl: SM2 Decryption Buffer Overflow",
+		},
+	}, vulns)
+
+	_, err = parseTrivyReport([]byte("FATAL error"))
+	assert.Error(t, err)
+}
@@ -70,6 +70,22 @@ func NewReporter(appLister applicationLister, stateGetter livestatestore.Getter,
 			}
 			r.reporters = append(r.reporters, newKubernetesReporter(cp, appLister, sg, apiClient, logger))
 
+		case model.CloudProviderCloudRun:
+			sg, ok := stateGetter.CloudRunGetter(cp.Name)
+			if !ok {
+				r.logger.Error(fmt.Sprintf("unable to find live state getter for cloud provider: %s", cp.Name))
+				continue
+			}
+			r.reporters = append(r.reporters, newCloudRunReporter(cp, appLister, sg, apiClient, logger))
+
+		case model.CloudProviderLambda:
+			sg, ok := stateGetter.LambdaGetter(cp.Name)
+			if !ok {
+				r.logger.Error(fmt.Sprintf("unable to find live state getter for cloud provider: %s", cp.Name))
+				continue
+			}
+			r.reporters = append(r.reporters, newLambdaReporter(cp, appLister, sg, apiClient, logger))
+
 		default:
 		}
 	}
@@ -0,0 +1,154 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"sort"
+	"time"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudrun"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	conditionReady = "Ready"
+	statusTrue     = "True"
+)
+
+// makeAppLiveState builds the live state of an application from its service
+// by picking up the revisions that are receiving traffic or being the latest created one.
+func makeAppLiveState(svc *provider.Service, revisions []*provider.Revision) *model.CloudRunApplicationLiveState {
+	state := &model.CloudRunApplicationLiveState{}
+	if svc.Metadata != nil {
+		state.ServiceName = svc.Metadata.Name
+	}
+
+	var (
+		traffics     = make(map[string]int32)
+		latestReady  string
+		latestCreate string
+	)
+	if svc.Status != nil {
+		latestReady = svc.Status.LatestReadyRevisionName
+		latestCreate = svc.Status.LatestCreatedRevisionName
+		for _, t := range svc.Status.Traffic {
+			name := t.RevisionName
+			if name == "" && t.LatestRevision {
+				name = latestReady
+			}
+			if name == "" {
+				continue
+			}
+			traffics[name] += int32(t.Percent)
+		}
+	}
+
+	for _, r := range revisions {
+		if r.Metadata == nil {
+			continue
+		}
+		name := r.Metadata.Name
+		percent, ok := traffics[name]
+		if !ok && name != latestCreate {
+			continue
+		}
+
+		rs := &model.CloudRunRevisionState{
+			Name:           name,
+			TrafficPercent: percent,
+		}
+		if r.Spec != nil && len(r.Spec.Containers) > 0 {
+			rs.Image = r.Spec.Containers[0].Image
+		}
+		if t, err := time.Parse(time.RFC3339, r.Metadata.CreationTimestamp); err == nil {
+			rs.CreatedAt = t.Unix()
+		}
+		rs.HealthStatus, rs.HealthDescription = determineRevisionHealth(r)
+
+		state.Revisions = append(state.Revisions, rs)
+	}
+
+	sort.Slice(state.Revisions, func(i, j int) bool {
+		return state.Revisions[i].Name < state.Revisions[j].Name
+	})
+	return state
+}
+
+func determineRevisionHealth(r *provider.Revision) (model.CloudRunRevisionState_HealthStatus, string) {
+	if r.Status == nil {
+		return model.CloudRunRevisionState_UNKNOWN, ""
+	}
+	for _, c := range r.Status.Conditions {
+		if c.Type != conditionReady {
+			continue
+		}
+		if c.Status == statusTrue {
+			return model.CloudRunRevisionState_HEALTHY, ""
+		}
+		return model.CloudRunRevisionState_OTHER, c.Message
+	}
+	return model.CloudRunRevisionState_UNKNOWN, ""
+}
+
+// makeJobExecutionState builds the state of the latest execution of a job.
+// Nil is returned if the job has never been executed.
+func makeJobExecutionState(jobName string, executions []*provider.Execution) *model.CloudRunJobExecutionState {
+	var latest *provider.Execution
+	for _, e := range executions {
+		if e.Metadata == nil {
+			continue
+		}
+		// The timestamps in RFC3339 format are comparable as strings.
+		if latest == nil || e.Metadata.CreationTimestamp > latest.Metadata.CreationTimestamp {
+			latest = e
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+
+	state := &model.CloudRunJobExecutionState{
+		Name:    latest.Metadata.Name,
+		JobName: jobName,
+		Image:   latest.Image(),
+		Status:  model.CloudRunJobExecutionState_RUNNING,
+	}
+	if st := latest.Status; st != nil {
+		state.RunningCount = int32(st.RunningCount)
+		state.SucceededCount = int32(st.SucceededCount)
+		state.FailedCount = int32(st.FailedCount)
+		if t, err := time.Parse(time.RFC3339, st.StartTime); err == nil {
+			state.StartedAt = t.Unix()
+		}
+		if t, err := time.Parse(time.RFC3339, st.CompletionTime); err == nil {
+			state.CompletedAt = t.Unix()
+		}
+	}
+
+	condition, completed := latest.Completed()
+	switch {
+	case !completed:
+		// Keep the running status.
+	case latest.Succeeded():
+		state.Status = model.CloudRunJobExecutionState_SUCCEEDED
+	case latest.Status.CancelledCount > 0:
+		state.Status = model.CloudRunJobExecutionState_CANCELLED
+		state.StatusDescription = condition.Message
+	default:
+		state.Status = model.CloudRunJobExecutionState_FAILED
+		state.StatusDescription = condition.Message
+	}
+	return state
+}
@@ -0,0 +1,163 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/run/v1"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudrun"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestMakeAppLiveState(t *testing.T) {
+	svc := &provider.Service{
+		Metadata: &run.ObjectMeta{Name: "helloworld"},
+		Status: &run.ServiceStatus{
+			LatestReadyRevisionName:   "helloworld-v020-abcdefg",
+			LatestCreatedRevisionName: "helloworld-v030-hijklmn",
+			Traffic: []*run.TrafficTarget{
+				{RevisionName: "helloworld-v010-1234567", Percent: 20},
+				{LatestRevision: true, Percent: 80},
+			},
+		},
+	}
+	makeRevision := func(name, image, ready, message string) *provider.Revision {
+		return &provider.Revision{
+			Metadata: &run.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: "2021-06-01T00:00:00Z",
+			},
+			Spec: &run.RevisionSpec{
+				Containers: []*run.Container{{Image: image}},
+			},
+			Status: &run.RevisionStatus{
+				Conditions: []*run.GoogleCloudRunV1Condition{
+					{Type: "Ready", Status: ready, Message: message},
+				},
+			},
+		}
+	}
+	revisions := []*provider.Revision{
+		makeRevision("helloworld-v030-hijklmn", "gcr.io/pipecd/helloworld:v0.3.0", "False", "Container failed to start"),
+		makeRevision("helloworld-v020-abcdefg", "gcr.io/pipecd/helloworld:v0.2.0", "True", ""),
+		makeRevision("helloworld-v010-1234567", "gcr.io/pipecd/helloworld:v0.1.0", "True", ""),
+		makeRevision("helloworld-v000-0000000", "gcr.io/pipecd/helloworld:v0.0.0", "True", ""),
+	}
+
+	got := makeAppLiveState(svc, revisions)
+	expected := &model.CloudRunApplicationLiveState{
+		ServiceName: "helloworld",
+		Revisions: []*model.CloudRunRevisionState{
+			{
+				Name:           "helloworld-v010-1234567",
+				Image:          "gcr.io/pipecd/helloworld:v0.1.0",
+				TrafficPercent: 20,
+				HealthStatus:   model.CloudRunRevisionState_HEALTHY,
+				CreatedAt:      1622505600,
+			},
+			{
+				Name:           "helloworld-v020-abcdefg",
+				Image:          "gcr.io/pipecd/helloworld:v0.2.0",
+				TrafficPercent: 80,
+				HealthStatus:   model.CloudRunRevisionState_HEALTHY,
+				CreatedAt:      1622505600,
+			},
+			{
+				Name:              "helloworld-v030-hijklmn",
+				Image:             "gcr.io/pipecd/helloworld:v0.3.0",
+				HealthStatus:      model.CloudRunRevisionState_OTHER,
+				HealthDescription: "Container failed to start",
+				CreatedAt:         1622505600,
+			},
+		},
+	}
+	assert.Equal(t, expected, got)
+}
+
+func TestMakeJobExecutionState(t *testing.T) {
+	makeExecution := func(name, created string, conditionStatus, message string, status provider.ExecutionStatus) *provider.Execution {
+		status.Conditions = []*run.GoogleCloudRunV1Condition{
+			{Type: "Completed", Status: conditionStatus, Message: message},
+		}
+		return &provider.Execution{
+			Metadata: &run.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: created,
+			},
+			Spec: &provider.ExecutionSpec{
+				Template: &provider.TaskTemplateSpec{
+					Spec: &provider.TaskSpec{
+						Containers: []*run.Container{{Image: "gcr.io/pipecd/migration:v0.1.0"}},
+					},
+				},
+			},
+			Status: &status,
+		}
+	}
+
+	testcases := []struct {
+		name       string
+		executions []*provider.Execution
+		expected   *model.CloudRunJobExecutionState
+	}{
+		{
+			name:     "never executed",
+			expected: nil,
+		},
+		{
+			name: "latest one failed",
+			executions: []*provider.Execution{
+				makeExecution("migration-1", "2021-06-01T00:00:00Z", "True", "", provider.ExecutionStatus{SucceededCount: 1}),
+				makeExecution("migration-2", "2021-06-02T00:00:00Z", "False", "Task failed", provider.ExecutionStatus{
+					FailedCount:    1,
+					StartTime:      "2021-06-02T00:00:00Z",
+					CompletionTime: "2021-06-02T00:01:00Z",
+				}),
+			},
+			expected: &model.CloudRunJobExecutionState{
+				Name:              "migration-2",
+				JobName:           "migration",
+				Image:             "gcr.io/pipecd/migration:v0.1.0",
+				Status:            model.CloudRunJobExecutionState_FAILED,
+				StatusDescription: "Task failed",
+				FailedCount:       1,
+				StartedAt:         1622592000,
+				CompletedAt:       1622592060,
+			},
+		},
+		{
+			name: "running",
+			executions: []*provider.Execution{
+				makeExecution("migration-1", "2021-06-01T00:00:00Z", "Unknown", "", provider.ExecutionStatus{RunningCount: 1}),
+			},
+			expected: &model.CloudRunJobExecutionState{
+				Name:         "migration-1",
+				JobName:      "migration",
+				Image:        "gcr.io/pipecd/migration:v0.1.0",
+				Status:       model.CloudRunJobExecutionState_RUNNING,
+				RunningCount: 1,
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := makeJobExecutionState("migration", tc.executions)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
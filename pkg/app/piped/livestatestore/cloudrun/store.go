@@ -16,22 +16,45 @@ package cloudrun
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudrun"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
+const (
+	syncInterval = time.Minute
+)
+
 type applicationLister interface {
 	List() []*model.Application
 }
 
 type Store struct {
-	logger *zap.Logger
+	config        *config.CloudProviderCloudRunConfig
+	cloudProvider string
+	appLister     applicationLister
+	firstSyncedCh chan error
+	logger        *zap.Logger
+
+	mu     sync.RWMutex
+	states map[string]AppState
 }
 
 type Getter interface {
+	GetCloudRunAppLiveState(appID string) (AppState, bool)
+
+	WaitForReady(ctx context.Context, timeout time.Duration) error
+}
+
+type AppState struct {
+	State   *model.CloudRunApplicationLiveState
+	Version model.ApplicationLiveStateVersion
 }
 
 func NewStore(cfg *config.CloudProviderCloudRunConfig, cloudProvider string, appLister applicationLister, logger *zap.Logger) *Store {
@@ -39,13 +62,175 @@ func NewStore(cfg *config.CloudProviderCloudRunConfig, cloudProvider string, app
 		With(zap.String("cloud-provider", cloudProvider))
 
 	return &Store{
-		logger: logger,
+		config:        cfg,
+		cloudProvider: cloudProvider,
+		appLister:     appLister,
+		firstSyncedCh: make(chan error, 1),
+		logger:        logger,
+		states:        make(map[string]AppState),
 	}
 }
 
 func (s *Store) Run(ctx context.Context) error {
 	s.logger.Info("start running cloudrun app state store")
 
+	client, err := provider.DefaultRegistry().Client(ctx, s.cloudProvider, s.config, s.logger)
+	if err != nil {
+		s.logger.Error("failed to create cloudrun client", zap.Error(err))
+		s.firstSyncedCh <- err
+		return err
+	}
+
+	if err := s.sync(ctx, client); err != nil {
+		s.logger.Error("failed to do the first sync", zap.Error(err))
+	}
+	s.logger.Info("the store has done the first sync")
+	close(s.firstSyncedCh)
+
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+L:
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.sync(ctx, client); err != nil {
+				s.logger.Error("failed to sync live state of cloudrun services", zap.Error(err))
+			}
+
+		case <-ctx.Done():
+			break L
+		}
+	}
+
 	s.logger.Info("cloudrun app state store has been stopped")
 	return nil
 }
+
+// sync fetches all services deployed by piped with their revisions
+// and all jobs with their executions
+// and updates the cached live states of their applications.
+func (s *Store) sync(ctx context.Context, client provider.Client) error {
+	selector := fmt.Sprintf("%s=%s", provider.LabelManagedBy, provider.ManagedByPiped)
+	services, err := client.ListServices(ctx, selector)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	current := s.states
+	s.mu.RUnlock()
+
+	var (
+		now    = time.Now()
+		states = make(map[string]AppState, len(services))
+		// The applications whose resources could not be fetched completely.
+		// Their last known states are kept instead of dropping them due to a transient error.
+		failed = make(map[string]struct{})
+	)
+	for _, svc := range services {
+		if svc.Metadata == nil {
+			continue
+		}
+		appID := svc.Metadata.Labels[provider.LabelApplication]
+		if appID == "" {
+			continue
+		}
+
+		revisions, err := client.ListRevisions(ctx, svc.Metadata.Name)
+		if err != nil {
+			s.logger.Error("failed to list revisions of service",
+				zap.String("service", svc.Metadata.Name),
+				zap.Error(err),
+			)
+			failed[appID] = struct{}{}
+			continue
+		}
+
+		states[appID] = AppState{
+			State: makeAppLiveState(svc, revisions),
+			Version: model.ApplicationLiveStateVersion{
+				Timestamp: now.Unix(),
+			},
+		}
+	}
+
+	// The failure of listing jobs should not prevent updating the states of services.
+	jobs, err := client.ListJobs(ctx, selector)
+	if err != nil {
+		s.logger.Error("failed to list jobs", zap.Error(err))
+		for appID := range current {
+			if len(current[appID].State.JobExecutions) > 0 {
+				failed[appID] = struct{}{}
+			}
+		}
+	}
+	for _, job := range jobs {
+		if job.Metadata == nil {
+			continue
+		}
+		appID := job.Metadata.Labels[provider.LabelApplication]
+		if appID == "" {
+			continue
+		}
+
+		executions, err := client.ListExecutions(ctx, job.Metadata.Name)
+		if err != nil {
+			s.logger.Error("failed to list executions of job",
+				zap.String("job", job.Metadata.Name),
+				zap.Error(err),
+			)
+			failed[appID] = struct{}{}
+			continue
+		}
+		es := makeJobExecutionState(job.Metadata.Name, executions)
+		if es == nil {
+			continue
+		}
+
+		// The application may consist of only jobs.
+		state, ok := states[appID]
+		if !ok {
+			state = AppState{
+				State: &model.CloudRunApplicationLiveState{},
+				Version: model.ApplicationLiveStateVersion{
+					Timestamp: now.Unix(),
+				},
+			}
+		}
+		state.State.JobExecutions = append(state.State.JobExecutions, es)
+		states[appID] = state
+	}
+
+	for appID := range failed {
+		if state, ok := current[appID]; ok {
+			states[appID] = state
+		}
+	}
+
+	s.mu.Lock()
+	s.states = states
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *Store) WaitForReady(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-s.firstSyncedCh:
+		return err
+	}
+}
+
+func (s *Store) GetCloudRunAppLiveState(appID string) (AppState, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, ok := s.states[appID]
+	return state, ok
+}
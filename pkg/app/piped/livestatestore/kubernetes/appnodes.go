@@ -63,6 +63,7 @@ func (a *appNodes) addManagingResource(uid string, key provider.ResourceKey, obj
 	}
 
 	a.mu.Lock()
+	n.state.DependencyIds = findDependencyIDs(n, a.managingNodes, a.dependedNodes)
 	oriNode, hasOriNode := a.managingNodes[uid]
 	version := a.version
 	a.managingNodes[uid] = n
@@ -117,6 +118,7 @@ func (a *appNodes) addDependedResource(uid string, key provider.ResourceKey, obj
 	}
 
 	a.mu.Lock()
+	n.state.DependencyIds = findDependencyIDs(n, a.managingNodes, a.dependedNodes)
 	oriNode, hasOriNode := a.dependedNodes[uid]
 	version := a.version
 	a.dependedNodes[uid] = n
@@ -0,0 +1,119 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+)
+
+// findDependencyIDs returns the sorted list of IDs of the given nodes
+// that the specified node is routing traffic to or referring to.
+// Currently the following relations are supported:
+// - Service -> Pod: the pods matched by the service selector
+// - Ingress -> Service: the services used as the ingress backends
+func findDependencyIDs(n node, nodeSets ...map[string]node) []string {
+	var match func(candidate node) bool
+
+	switch n.key.Kind {
+	case provider.KindService:
+		selector, ok, _ := unstructured.NestedStringMap(n.unstructured.Object, "spec", "selector")
+		if !ok || len(selector) == 0 {
+			return nil
+		}
+		s := labels.SelectorFromSet(selector)
+		match = func(c node) bool {
+			return c.key.Kind == provider.KindPod && s.Matches(labels.Set(c.unstructured.GetLabels()))
+		}
+
+	case provider.KindIngress:
+		services := findIngressBackendServices(n.unstructured)
+		if len(services) == 0 {
+			return nil
+		}
+		match = func(c node) bool {
+			if c.key.Kind != provider.KindService {
+				return false
+			}
+			_, ok := services[c.key.Name]
+			return ok
+		}
+
+	default:
+		return nil
+	}
+
+	var ids []string
+	for _, nodes := range nodeSets {
+		for _, c := range nodes {
+			if c.uid == n.uid || c.unstructured == nil {
+				continue
+			}
+			if c.unstructured.GetNamespace() != n.unstructured.GetNamespace() {
+				continue
+			}
+			if match(c) {
+				ids = append(ids, c.uid)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// findIngressBackendServices returns the names of all services used as the backends of the given Ingress.
+// Both networking.k8s.io/v1 and the older v1beta1 backend formats are supported.
+func findIngressBackendServices(obj *unstructured.Unstructured) map[string]struct{} {
+	services := make(map[string]struct{})
+	addBackend := func(backend map[string]interface{}) {
+		// networking.k8s.io/v1
+		if name, ok, _ := unstructured.NestedString(backend, "service", "name"); ok && name != "" {
+			services[name] = struct{}{}
+		}
+		// networking.k8s.io/v1beta1, extensions/v1beta1
+		if name, ok, _ := unstructured.NestedString(backend, "serviceName"); ok && name != "" {
+			services[name] = struct{}{}
+		}
+	}
+
+	for _, field := range []string{"defaultBackend", "backend"} {
+		if backend, ok, _ := unstructured.NestedMap(obj.Object, "spec", field); ok {
+			addBackend(backend)
+		}
+	}
+
+	rules, _, _ := unstructured.NestedSlice(obj.Object, "spec", "rules")
+	for _, r := range rules {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		paths, _, _ := unstructured.NestedSlice(rule, "http", "paths")
+		for _, p := range paths {
+			path, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if backend, ok, _ := unstructured.NestedMap(path, "backend"); ok {
+				addBackend(backend)
+			}
+		}
+	}
+	return services
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+)
+
+func makeTestNode(uid, apiVersion, kind, namespace, name string, labels map[string]string, spec map[string]interface{}) node {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+	}}
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	if spec != nil {
+		obj.Object["spec"] = spec
+	}
+	return node{
+		uid: uid,
+		key: provider.ResourceKey{
+			APIVersion: apiVersion,
+			Kind:       kind,
+			Namespace:  namespace,
+			Name:       name,
+		},
+		unstructured: obj,
+	}
+}
+
+func TestFindDependencyIDs(t *testing.T) {
+	var (
+		service = makeTestNode("service", "v1", "Service", "default", "simple", nil, map[string]interface{}{
+			"selector": map[string]interface{}{"app": "simple"},
+		})
+		ingressV1 = makeTestNode("ingress-v1", "networking.k8s.io/v1", "Ingress", "default", "simple", nil, map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{
+					"http": map[string]interface{}{
+						"paths": []interface{}{
+							map[string]interface{}{
+								"backend": map[string]interface{}{
+									"service": map[string]interface{}{"name": "simple"},
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+		ingressV1beta1 = makeTestNode("ingress-v1beta1", "networking.k8s.io/v1beta1", "Ingress", "default", "legacy", nil, map[string]interface{}{
+			"backend": map[string]interface{}{"serviceName": "simple"},
+		})
+		pod1         = makeTestNode("pod-1", "v1", "Pod", "default", "simple-1", map[string]string{"app": "simple", "pod-template-hash": "1"}, nil)
+		pod2         = makeTestNode("pod-2", "v1", "Pod", "default", "simple-2", map[string]string{"app": "simple", "pod-template-hash": "2"}, nil)
+		otherPod     = makeTestNode("other-pod", "v1", "Pod", "default", "other", map[string]string{"app": "other"}, nil)
+		otherNsPod   = makeTestNode("other-ns-pod", "v1", "Pod", "staging", "simple", map[string]string{"app": "simple"}, nil)
+		otherService = makeTestNode("other-service", "v1", "Service", "default", "other", nil, nil)
+	)
+	nodes := map[string]node{}
+	for _, n := range []node{service, ingressV1, ingressV1beta1, pod1, pod2, otherPod, otherNsPod, otherService} {
+		nodes[n.uid] = n
+	}
+
+	testcases := []struct {
+		name     string
+		node     node
+		expected []string
+	}{
+		{
+			name:     "service selects pods in the same namespace",
+			node:     service,
+			expected: []string{"pod-1", "pod-2"},
+		},
+		{
+			name:     "service without selector",
+			node:     otherService,
+			expected: nil,
+		},
+		{
+			name:     "networking.k8s.io/v1 ingress",
+			node:     ingressV1,
+			expected: []string{"service"},
+		},
+		{
+			name:     "networking.k8s.io/v1beta1 ingress",
+			node:     ingressV1beta1,
+			expected: []string{"service"},
+		},
+		{
+			name:     "pod has no dependency",
+			node:     pod1,
+			expected: nil,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := findDependencyIDs(tc.node, nodes)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
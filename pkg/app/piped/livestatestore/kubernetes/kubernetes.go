@@ -20,7 +20,6 @@ import (
 
 	"go.uber.org/zap"
 	restclient "k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 
 	// Import to load the needs plugins such as gcp, azure, oidc, openstack.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -87,7 +86,7 @@ func (s *Store) Run(ctx context.Context) error {
 
 	// Build kubeconfig for initialing kubernetes clients later.
 	var err error
-	s.kubeConfig, err = clientcmd.BuildConfigFromFlags(s.config.MasterURL, s.config.KubeConfigPath)
+	s.kubeConfig, err = provider.BuildRESTConfig(s.config)
 	if err != nil {
 		s.logger.Error("failed to build kube config", zap.Error(err))
 		return err
@@ -17,6 +17,7 @@ package kubernetes
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -79,6 +80,10 @@ var (
 		"ClusterRoleBinding":       {},
 		"CustomResourceDefinition": {},
 	}
+	crdGroupResource = schema.GroupResource{
+		Group:    "apiextensions.k8s.io",
+		Resource: "customresourcedefinitions",
+	}
 	ignoreResourceKeys = map[string]struct{}{
 		"v1:Service:default:kubernetes":               {},
 		"v1:Service:kube-system:heapster":             {},
@@ -139,6 +144,13 @@ type reflector struct {
 func (r *reflector) start(ctx context.Context) error {
 	matcher := newResourceMatcher(r.config.AppStateInformer)
 
+	// Use dynamic to perform generic operations on arbitrary Kubernets API objects.
+	// https://godoc.org/k8s.io/client-go/dynamic
+	dynamicClient, err := dynamic.NewForConfig(r.kubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %v", err)
+	}
+
 	// Use discovery to discover APIs supported by the Kubernetes API server.
 	// This should be run periodically with a low rate because the APIs are not added frequently.
 	// https://godoc.org/k8s.io/client-go/discovery
@@ -146,6 +158,19 @@ func (r *reflector) start(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to create discovery client: %v", err)
 	}
+
+	if r.config.AppStateInformer.IncludeCustomResources {
+		crdResource, err := findCRDResource(discoveryClient)
+		if err != nil {
+			return fmt.Errorf("failed to find the API version of custom resource definitions: %v", err)
+		}
+		gks, err := listCustomResourceKinds(ctx, dynamicClient, crdResource)
+		if err != nil {
+			return fmt.Errorf("failed to list custom resource definitions: %v", err)
+		}
+		matcher.addCustomResources(gks...)
+		r.logger.Info(fmt.Sprintf("added %d custom resource kinds to the watching targets", len(gks)))
+	}
 	groupResources, err := discoveryClient.ServerPreferredResources()
 	if err != nil {
 		return fmt.Errorf("failed to fetch preferred resources: %v", err)
@@ -188,13 +213,6 @@ func (r *reflector) start(ctx context.Context) error {
 		zap.Any("namespacedTargetResources", namespacedTargetResources),
 	)
 
-	// Use dynamic to perform generic operations on arbitrary Kubernets API objects.
-	// https://godoc.org/k8s.io/client-go/dynamic
-	dynamicClient, err := dynamic.NewForConfig(r.kubeConfig)
-	if err != nil {
-		return fmt.Errorf("failed to create dynamic client: %v", err)
-	}
-
 	stopCh := make(chan struct{})
 
 	startInformer := func(namespace string, resources []schema.GroupVersionResource) {
@@ -326,6 +344,49 @@ func (r *reflector) onObjectDelete(obj interface{}) {
 	)
 }
 
+// findCRDResource returns the resource of CustomResourceDefinitions
+// in the version preferred by the cluster, e.g. v1beta1 for clusters older than 1.16.
+func findCRDResource(client discovery.ServerGroupsInterface) (schema.GroupVersionResource, error) {
+	groups, err := client.ServerGroups()
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+	for _, g := range groups.Groups {
+		if g.Name != crdGroupResource.Group {
+			continue
+		}
+		version := g.PreferredVersion.Version
+		if version == "" && len(g.Versions) > 0 {
+			version = g.Versions[0].Version
+		}
+		if version == "" {
+			break
+		}
+		return crdGroupResource.WithVersion(version), nil
+	}
+	return schema.GroupVersionResource{}, fmt.Errorf("api group %s is not served by the cluster", crdGroupResource.Group)
+}
+
+// listCustomResourceKinds returns the group kinds of all resources
+// defined by CustomResourceDefinitions in the cluster.
+func listCustomResourceKinds(ctx context.Context, client dynamic.Interface, crdResource schema.GroupVersionResource) ([]schema.GroupKind, error) {
+	list, err := client.Resource(crdResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	gks := make([]schema.GroupKind, 0, len(list.Items))
+	for _, item := range list.Items {
+		group, _, _ := unstructured.NestedString(item.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(item.Object, "spec", "names", "kind")
+		if kind == "" {
+			continue
+		}
+		gks = append(gks, schema.GroupKind{Group: group, Kind: kind})
+	}
+	return gks, nil
+}
+
 func isSupportedWatch(r metav1.APIResource) bool {
 	for _, v := range r.Verbs {
 		if v == "watch" {
@@ -345,53 +406,84 @@ func isSupportedList(r metav1.APIResource) bool {
 }
 
 type resourceMatcher struct {
-	includes map[string]struct{}
-	excludes map[string]struct{}
+	includes      map[string]struct{}
+	excludes      map[string]struct{}
+	includeGroups map[string]struct{}
+	excludeGroups map[string]struct{}
+	includeKinds  map[string]struct{}
+	excludeKinds  map[string]struct{}
+	// List of group kinds defined by CustomResourceDefinitions
+	// that should be included.
+	customResources map[schema.GroupKind]struct{}
 }
 
 func newResourceMatcher(cfg config.KubernetesAppStateInformer) *resourceMatcher {
 	r := &resourceMatcher{
-		includes: make(map[string]struct{}, len(cfg.IncludeResources)),
-		excludes: make(map[string]struct{}, len(cfg.ExcludeResources)),
+		includes:        make(map[string]struct{}, len(cfg.IncludeResources)),
+		excludes:        make(map[string]struct{}, len(cfg.ExcludeResources)),
+		includeGroups:   make(map[string]struct{}),
+		excludeGroups:   make(map[string]struct{}),
+		includeKinds:    make(map[string]struct{}),
+		excludeKinds:    make(map[string]struct{}),
+		customResources: make(map[schema.GroupKind]struct{}),
 	}
 
-	for _, m := range cfg.IncludeResources {
-		if m.Kind == "" {
-			r.includes[m.APIVersion] = struct{}{}
-		} else {
-			r.includes[m.APIVersion+":"+m.Kind] = struct{}{}
+	add := func(m config.KubernetesResourceMatcher, keys, groups, kinds map[string]struct{}) {
+		switch {
+		case m.APIVersion == "":
+			if m.Kind != "" {
+				kinds[m.Kind] = struct{}{}
+			}
+		case strings.HasSuffix(m.APIVersion, "/*"):
+			group := strings.TrimSuffix(m.APIVersion, "/*")
+			if m.Kind == "" {
+				groups[group] = struct{}{}
+			} else {
+				groups[group+":"+m.Kind] = struct{}{}
+			}
+		case m.Kind == "":
+			keys[m.APIVersion] = struct{}{}
+		default:
+			keys[m.APIVersion+":"+m.Kind] = struct{}{}
 		}
 	}
+	for _, m := range cfg.IncludeResources {
+		add(m, r.includes, r.includeGroups, r.includeKinds)
+	}
 	for _, m := range cfg.ExcludeResources {
-		if m.Kind == "" {
-			r.excludes[m.APIVersion] = struct{}{}
-		} else {
-			r.excludes[m.APIVersion+":"+m.Kind] = struct{}{}
-		}
+		add(m, r.excludes, r.excludeGroups, r.excludeKinds)
 	}
 	return r
 }
 
+// addCustomResources adds the given group kinds into the list of custom resources
+// that should be included in the watching targets.
+func (m *resourceMatcher) addCustomResources(gks ...schema.GroupKind) {
+	for _, gk := range gks {
+		m.customResources[gk] = struct{}{}
+	}
+}
+
 func (m *resourceMatcher) Match(gvk schema.GroupVersionKind) bool {
 	var (
 		gv         = gvk.GroupVersion()
 		apiVersion = gv.String()
 		key        = apiVersion + ":" + gvk.Kind
+		groupKey   = gvk.Group + ":" + gvk.Kind
 	)
 
 	// Any resource matches the specified ExcludeResources will be ignored.
-	if _, ok := m.excludes[apiVersion]; ok {
-		return false
-	}
-	if _, ok := m.excludes[key]; ok {
+	if matchAny(m.excludes, apiVersion, key) || matchAny(m.excludeGroups, gvk.Group, groupKey) || matchAny(m.excludeKinds, gvk.Kind) {
 		return false
 	}
 
 	// Any resources matches the specified IncludeResources will be included.
-	if _, ok := m.includes[apiVersion]; ok {
+	if matchAny(m.includes, apiVersion, key) || matchAny(m.includeGroups, gvk.Group, groupKey) || matchAny(m.includeKinds, gvk.Kind) {
 		return true
 	}
-	if _, ok := m.includes[key]; ok {
+
+	// Any resources defined by the included CustomResourceDefinitions will be included.
+	if _, ok := m.customResources[gvk.GroupKind()]; ok {
 		return true
 	}
 
@@ -408,3 +500,12 @@ func (m *resourceMatcher) Match(gvk schema.GroupVersionKind) bool {
 
 	return true
 }
+
+func matchAny(set map[string]struct{}, keys ...string) bool {
+	for _, k := range keys {
+		if _, ok := set[k]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/pipe-cd/pipe/pkg/config"
@@ -26,18 +27,19 @@ import (
 
 func TestResourceMatcher(t *testing.T) {
 	testcases := []struct {
-		name string
-		cfg  config.KubernetesAppStateInformer
-		gvks map[schema.GroupVersionKind]bool
+		name            string
+		cfg             config.KubernetesAppStateInformer
+		customResources []schema.GroupKind
+		gvks            map[schema.GroupVersionKind]bool
 	}{
 		{
 			name: "empty config",
 			cfg:  config.KubernetesAppStateInformer{},
 			gvks: map[schema.GroupVersionKind]bool{
-				schema.GroupVersionKind{"pipecd.dev", "v1beta1", "Foo"}:       false,
-				schema.GroupVersionKind{"", "v1", "Foo"}:                      false,
-				schema.GroupVersionKind{"", "v1", "Service"}:                  true,
-				schema.GroupVersionKind{"networking.k8s.io", "v1", "Ingress"}: true,
+				schema.GroupVersionKind{Group: "pipecd.dev", Version: "v1beta1", Kind: "Foo"}:       false,
+				schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Foo"}:                      false,
+				schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Service"}:                  true,
+				schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}: true,
 			},
 		},
 		{
@@ -49,9 +51,9 @@ func TestResourceMatcher(t *testing.T) {
 				},
 			},
 			gvks: map[schema.GroupVersionKind]bool{
-				schema.GroupVersionKind{"pipecd.dev", "v1beta1", "Foo"}:  true,
-				schema.GroupVersionKind{"pipecd.dev", "v1alpha1", "Foo"}: true,
-				schema.GroupVersionKind{"pipecd.dev", "v1alpha1", "Bar"}: false,
+				schema.GroupVersionKind{Group: "pipecd.dev", Version: "v1beta1", Kind: "Foo"}:  true,
+				schema.GroupVersionKind{Group: "pipecd.dev", Version: "v1alpha1", Kind: "Foo"}: true,
+				schema.GroupVersionKind{Group: "pipecd.dev", Version: "v1alpha1", Kind: "Bar"}: false,
 			},
 		},
 		{
@@ -63,15 +65,66 @@ func TestResourceMatcher(t *testing.T) {
 				},
 			},
 			gvks: map[schema.GroupVersionKind]bool{
-				schema.GroupVersionKind{"apps", "v1", "ReplicaSet"}:           true,
-				schema.GroupVersionKind{"apps", "v1", "Deployment"}:           false,
-				schema.GroupVersionKind{"networking.k8s.io", "v1", "Ingress"}: false,
+				schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}:           true,
+				schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}:           false,
+				schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}: false,
+			},
+		},
+		{
+			name: "group and kind-only config",
+			cfg: config.KubernetesAppStateInformer{
+				IncludeResources: []config.KubernetesResourceMatcher{
+					{APIVersion: "argoproj.io/*"},
+					{APIVersion: "cert-manager.io/*", Kind: "Certificate"},
+				},
+				ExcludeResources: []config.KubernetesResourceMatcher{
+					{Kind: "Endpoints"},
+					{APIVersion: "argoproj.io/*", Kind: "Workflow"},
+				},
+			},
+			gvks: map[schema.GroupVersionKind]bool{
+				schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"}:       true,
+				schema.GroupVersionKind{Group: "argoproj.io", Version: "v1beta1", Kind: "Rollout"}:        true,
+				schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Workflow"}:      false,
+				schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}:     true,
+				schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Issuer"}:          false,
+				schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Endpoints"}:                      false,
+				schema.GroupVersionKind{Group: "discovery.k8s.io", Version: "v1beta1", Kind: "Endpoints"}: false,
+				schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Service"}:                        true,
+			},
+		},
+		{
+			name: "custom resources",
+			cfg:  config.KubernetesAppStateInformer{},
+			customResources: []schema.GroupKind{
+				{Group: "pipecd.dev", Kind: "Foo"},
+			},
+			gvks: map[schema.GroupVersionKind]bool{
+				schema.GroupVersionKind{Group: "pipecd.dev", Version: "v1beta1", Kind: "Foo"}: true,
+				schema.GroupVersionKind{Group: "pipecd.dev", Version: "v1", Kind: "Foo"}:      true,
+				schema.GroupVersionKind{Group: "pipecd.dev", Version: "v1beta1", Kind: "Bar"}: false,
+			},
+		},
+		{
+			name: "excluded custom resources",
+			cfg: config.KubernetesAppStateInformer{
+				ExcludeResources: []config.KubernetesResourceMatcher{
+					{APIVersion: "pipecd.dev/v1beta1", Kind: "Foo"},
+				},
+			},
+			customResources: []schema.GroupKind{
+				{Group: "pipecd.dev", Kind: "Foo"},
+			},
+			gvks: map[schema.GroupVersionKind]bool{
+				schema.GroupVersionKind{Group: "pipecd.dev", Version: "v1beta1", Kind: "Foo"}: false,
+				schema.GroupVersionKind{Group: "pipecd.dev", Version: "v1", Kind: "Foo"}:      true,
 			},
 		},
 	}
 
 	for _, tc := range testcases {
 		m := newResourceMatcher(tc.cfg)
+		m.addCustomResources(tc.customResources...)
 		for gvk, expected := range tc.gvks {
 			desc := fmt.Sprintf("%s: %v", tc.name, gvk)
 			t.Run(desc, func(t *testing.T) {
@@ -81,3 +134,55 @@ func TestResourceMatcher(t *testing.T) {
 		}
 	}
 }
+
+type fakeServerGroups struct {
+	groups []metav1.APIGroup
+}
+
+func (f fakeServerGroups) ServerGroups() (*metav1.APIGroupList, error) {
+	return &metav1.APIGroupList{Groups: f.groups}, nil
+}
+
+func TestFindCRDResource(t *testing.T) {
+	testcases := []struct {
+		name     string
+		groups   []metav1.APIGroup
+		expected schema.GroupVersionResource
+		wantErr  bool
+	}{
+		{
+			name: "v1 is preferred",
+			groups: []metav1.APIGroup{
+				{Name: "apps", PreferredVersion: metav1.GroupVersionForDiscovery{Version: "v1"}},
+				{
+					Name:             "apiextensions.k8s.io",
+					Versions:         []metav1.GroupVersionForDiscovery{{Version: "v1"}, {Version: "v1beta1"}},
+					PreferredVersion: metav1.GroupVersionForDiscovery{Version: "v1"},
+				},
+			},
+			expected: schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"},
+		},
+		{
+			name: "only v1beta1 is served",
+			groups: []metav1.APIGroup{
+				{
+					Name:     "apiextensions.k8s.io",
+					Versions: []metav1.GroupVersionForDiscovery{{Version: "v1beta1"}},
+				},
+			},
+			expected: schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1beta1", Resource: "customresourcedefinitions"},
+		},
+		{
+			name:    "not served",
+			groups:  []metav1.APIGroup{{Name: "apps", PreferredVersion: metav1.GroupVersionForDiscovery{Version: "v1"}}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := findCRDResource(fakeServerGroups{groups: tc.groups})
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
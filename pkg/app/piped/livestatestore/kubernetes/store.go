@@ -278,6 +278,9 @@ func (s *store) getAppLiveState(appID string) (AppState, bool) {
 	)
 	for i := range nodes {
 		state := nodes[i].state
+		// Recompute the dependencies because the related resources
+		// may have been changed after this resource was updated.
+		state.DependencyIds = findDependencyIDs(nodes[i], nodes)
 		resources = append(resources, &state)
 	}
 
@@ -0,0 +1,66 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lambda
+
+import (
+	"fmt"
+	"sort"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/lambda"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	functionStateActive     = "Active"
+	lastUpdateStatusFailed  = "Failed"
+	lastUpdateStatusRunning = "InProgress"
+)
+
+// makeAppLiveState builds the live state of an application from the state
+// of its function and the traffic config of the alias.
+func makeAppLiveState(fs *provider.FunctionState, traffic provider.RoutingTrafficConfig) *model.LambdaApplicationLiveState {
+	state := &model.LambdaApplicationLiveState{
+		FunctionName: fs.Name,
+		Image:        fs.ImageURI,
+	}
+
+	for _, t := range traffic {
+		if t.Version == "" {
+			continue
+		}
+		state.Versions = append(state.Versions, &model.LambdaFunctionVersionState{
+			Version:        t.Version,
+			TrafficPercent: t.Percent,
+		})
+	}
+	sort.Slice(state.Versions, func(i, j int) bool {
+		return state.Versions[i].Version < state.Versions[j].Version
+	})
+
+	switch {
+	case fs.State != functionStateActive:
+		state.HealthStatus = model.LambdaApplicationLiveState_OTHER
+		state.HealthDescription = fmt.Sprintf("Function is in %s state: %s", fs.State, fs.StateReason)
+	case fs.LastUpdateStatus == lastUpdateStatusFailed:
+		state.HealthStatus = model.LambdaApplicationLiveState_OTHER
+		state.HealthDescription = fmt.Sprintf("The last update was failed: %s", fs.LastUpdateStatusReason)
+	case fs.LastUpdateStatus == lastUpdateStatusRunning:
+		state.HealthStatus = model.LambdaApplicationLiveState_OTHER
+		state.HealthDescription = "The function is being updated"
+	default:
+		state.HealthStatus = model.LambdaApplicationLiveState_HEALTHY
+	}
+	return state
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lambda
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/lambda"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestMakeAppLiveState(t *testing.T) {
+	testcases := []struct {
+		name     string
+		fs       *provider.FunctionState
+		traffic  provider.RoutingTrafficConfig
+		expected *model.LambdaApplicationLiveState
+	}{
+		{
+			name: "healthy function with canary traffic",
+			fs: &provider.FunctionState{
+				Name:             "SimpleFunction",
+				ImageURI:         "ecr.io/pipecd/simple:v0.2.0",
+				State:            "Active",
+				LastUpdateStatus: "Successful",
+			},
+			traffic: provider.RoutingTrafficConfig{
+				provider.TrafficPrimaryVersionKeyName:   {Version: "1", Percent: 90},
+				provider.TrafficSecondaryVersionKeyName: {Version: "2", Percent: 10},
+			},
+			expected: &model.LambdaApplicationLiveState{
+				FunctionName: "SimpleFunction",
+				Image:        "ecr.io/pipecd/simple:v0.2.0",
+				Versions: []*model.LambdaFunctionVersionState{
+					{Version: "1", TrafficPercent: 90},
+					{Version: "2", TrafficPercent: 10},
+				},
+				HealthStatus: model.LambdaApplicationLiveState_HEALTHY,
+			},
+		},
+		{
+			name: "function without alias whose last update was failed",
+			fs: &provider.FunctionState{
+				Name:                   "SimpleFunction",
+				State:                  "Active",
+				LastUpdateStatus:       "Failed",
+				LastUpdateStatusReason: "image not found",
+			},
+			expected: &model.LambdaApplicationLiveState{
+				FunctionName:      "SimpleFunction",
+				HealthStatus:      model.LambdaApplicationLiveState_OTHER,
+				HealthDescription: "The last update was failed: image not found",
+			},
+		},
+		{
+			name: "pending function",
+			fs: &provider.FunctionState{
+				Name:        "SimpleFunction",
+				State:       "Pending",
+				StateReason: "creating",
+			},
+			expected: &model.LambdaApplicationLiveState{
+				FunctionName:      "SimpleFunction",
+				HealthStatus:      model.LambdaApplicationLiveState_OTHER,
+				HealthDescription: "Function is in Pending state: creating",
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := makeAppLiveState(tc.fs, tc.traffic)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
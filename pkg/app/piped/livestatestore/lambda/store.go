@@ -16,22 +16,45 @@ package lambda
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/lambda"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
+const (
+	syncInterval = time.Minute
+)
+
 type applicationLister interface {
 	List() []*model.Application
 }
 
 type Store struct {
-	logger *zap.Logger
+	config        *config.CloudProviderLambdaConfig
+	cloudProvider string
+	appLister     applicationLister
+	firstSyncedCh chan error
+	logger        *zap.Logger
+
+	mu     sync.RWMutex
+	states map[string]AppState
 }
 
 type Getter interface {
+	GetLambdaAppLiveState(appID string) (AppState, bool)
+
+	WaitForReady(ctx context.Context, timeout time.Duration) error
+}
+
+type AppState struct {
+	State   *model.LambdaApplicationLiveState
+	Version model.ApplicationLiveStateVersion
 }
 
 func NewStore(cfg *config.CloudProviderLambdaConfig, cloudProvider string, appLister applicationLister, logger *zap.Logger) *Store {
@@ -39,13 +62,124 @@ func NewStore(cfg *config.CloudProviderLambdaConfig, cloudProvider string, appLi
 		With(zap.String("cloud-provider", cloudProvider))
 
 	return &Store{
-		logger: logger,
+		config:        cfg,
+		cloudProvider: cloudProvider,
+		appLister:     appLister,
+		firstSyncedCh: make(chan error, 1),
+		logger:        logger,
+		states:        make(map[string]AppState),
 	}
 }
 
 func (s *Store) Run(ctx context.Context) error {
 	s.logger.Info("start running lambda app state store")
 
+	client, err := provider.DefaultRegistry().Client(s.cloudProvider, s.config, s.logger)
+	if err != nil {
+		s.logger.Error("failed to create lambda client", zap.Error(err))
+		s.firstSyncedCh <- err
+		return err
+	}
+
+	if err := s.sync(ctx, client); err != nil {
+		s.logger.Error("failed to do the first sync", zap.Error(err))
+	}
+	s.logger.Info("the store has done the first sync")
+	close(s.firstSyncedCh)
+
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+L:
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.sync(ctx, client); err != nil {
+				s.logger.Error("failed to sync live state of lambda functions", zap.Error(err))
+			}
+
+		case <-ctx.Done():
+			break L
+		}
+	}
+
 	s.logger.Info("lambda app state store has been stopped")
 	return nil
 }
+
+// sync fetches all functions deployed by piped with their traffic configs
+// and updates the cached live states of their applications.
+func (s *Store) sync(ctx context.Context, client provider.Client) error {
+	functions, err := client.ListTaggedFunctions(ctx, provider.TagApplication)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	current := s.states
+	s.mu.RUnlock()
+
+	var (
+		now    = time.Now()
+		states = make(map[string]AppState, len(functions))
+	)
+	for name, appID := range functions {
+		fs, err := client.GetFunctionState(ctx, name)
+		if err != nil {
+			s.logger.Error("failed to get state of function",
+				zap.String("function", name),
+				zap.Error(err),
+			)
+			// The last known state is kept instead of dropping the application due to a transient error.
+			if state, ok := current[appID]; ok {
+				states[appID] = state
+			}
+			continue
+		}
+
+		traffic, err := client.GetAliasTrafficConfig(ctx, name)
+		if err != nil && !errors.Is(err, provider.ErrNotFound) {
+			s.logger.Error("failed to get traffic config of function",
+				zap.String("function", name),
+				zap.Error(err),
+			)
+			if state, ok := current[appID]; ok {
+				states[appID] = state
+			}
+			continue
+		}
+
+		states[appID] = AppState{
+			State: makeAppLiveState(fs, traffic),
+			Version: model.ApplicationLiveStateVersion{
+				Timestamp: now.Unix(),
+			},
+		}
+	}
+
+	s.mu.Lock()
+	s.states = states
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *Store) WaitForReady(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-s.firstSyncedCh:
+		return err
+	}
+}
+
+func (s *Store) GetLambdaAppLiveState(appID string) (AppState, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, ok := s.states[appID]
+	return state, ok
+}
@@ -61,10 +61,12 @@ type terraformStore interface {
 
 type cloudRunStore interface {
 	Run(ctx context.Context) error
+	cloudrun.Getter
 }
 
 type lambdaStore interface {
 	Run(ctx context.Context) error
+	lambda.Getter
 }
 
 // store manages a list of particular stores for all cloud providers.
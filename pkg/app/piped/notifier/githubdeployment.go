@@ -0,0 +1,325 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/git"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	defaultGitHubAPIAddress = "https://api.github.com"
+	// The key of GitHub deployment payload used to find
+	// the GitHub deployment created for a PipeCD deployment.
+	githubDeploymentPayloadKey = "pipecdDeploymentId"
+	// GitHub rejects the deployment status whose description is longer than this.
+	githubDescriptionMaxLength = 140
+)
+
+// githubDeployment reflects the deployments as GitHub Deployments
+// so that GitHub's environment view and protection rules follow the actual rollouts.
+// A GitHub deployment is created when a deployment was triggered
+// and its statuses are updated until the deployment was completed.
+type githubDeployment struct {
+	name        string
+	address     string
+	token       string
+	environment string
+	webURL      string
+	httpClient  *http.Client
+	eventCh     chan model.NotificationEvent
+	// The IDs of GitHub deployments of the uncompleted deployments keyed by deployment ID.
+	deployments map[string]int64
+	mu          sync.Mutex
+	logger      *zap.Logger
+}
+
+type githubDeploymentRequest struct {
+	Ref                   string            `json:"ref"`
+	Task                  string            `json:"task"`
+	AutoMerge             bool              `json:"auto_merge"`
+	RequiredContexts      []string          `json:"required_contexts"`
+	Payload               map[string]string `json:"payload"`
+	Environment           string            `json:"environment"`
+	Description           string            `json:"description"`
+	ProductionEnvironment bool              `json:"production_environment"`
+}
+
+type githubDeploymentStatusRequest struct {
+	State        string `json:"state"`
+	LogURL       string `json:"log_url,omitempty"`
+	Description  string `json:"description"`
+	Environment  string `json:"environment,omitempty"`
+	AutoInactive bool   `json:"auto_inactive"`
+}
+
+func newGitHubDeploymentSender(name string, cfg config.NotificationReceiverGitHubDeployment, webURL string, logger *zap.Logger) (*githubDeployment, error) {
+	token, err := ioutil.ReadFile(cfg.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the token file of github deployment receiver %s: %w", name, err)
+	}
+	address := cfg.Address
+	if address == "" {
+		address = defaultGitHubAPIAddress
+	}
+	return &githubDeployment{
+		name:        name,
+		address:     strings.TrimRight(address, "/"),
+		token:       strings.TrimSpace(string(token)),
+		environment: cfg.Environment,
+		webURL:      strings.TrimRight(webURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		eventCh:     make(chan model.NotificationEvent, 100),
+		deployments: make(map[string]int64),
+		logger:      logger.Named("github-deployment"),
+	}, nil
+}
+
+func (g *githubDeployment) Run(ctx context.Context) error {
+	for {
+		select {
+		case event, ok := <-g.eventCh:
+			if ok {
+				g.sendEvent(ctx, event)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (g *githubDeployment) Notify(event model.NotificationEvent) {
+	g.eventCh <- event
+}
+
+func (g *githubDeployment) Close(ctx context.Context) {
+	close(g.eventCh)
+
+	// Send all remaining events.
+	for {
+		select {
+		case event, ok := <-g.eventCh:
+			if !ok {
+				return
+			}
+			g.sendEvent(ctx, event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (g *githubDeployment) sendEvent(ctx context.Context, event model.NotificationEvent) {
+	var (
+		d           *model.Deployment
+		envName     string
+		state       string
+		description string
+	)
+	switch md := event.Metadata.(type) {
+	case *model.NotificationEventDeploymentTriggered:
+		d, envName, state = md.Deployment, md.EnvName, "queued"
+		description = "Deployment was triggered"
+	case *model.NotificationEventDeploymentPlanned:
+		d, envName, state = md.Deployment, md.EnvName, "in_progress"
+		description = md.Summary
+	case *model.NotificationEventDeploymentRollingBack:
+		d, envName, state = md.Deployment, md.EnvName, "in_progress"
+		description = "Deployment is rolling back"
+	case *model.NotificationEventDeploymentSucceeded:
+		d, envName, state = md.Deployment, md.EnvName, "success"
+		description = "Deployment was completed successfully"
+	case *model.NotificationEventDeploymentFailed:
+		d, envName, state = md.Deployment, md.EnvName, "failure"
+		description = md.Reason
+	case *model.NotificationEventDeploymentCancelled:
+		d, envName, state = md.Deployment, md.EnvName, "error"
+		description = fmt.Sprintf("Deployment was cancelled by %s", md.Commander)
+	default:
+		g.logger.Info(fmt.Sprintf("ignore event %s", event.Type.String()))
+		return
+	}
+
+	// A dry-run deployment does not change anything so it is not a rollout.
+	if d.IsDryRun() {
+		return
+	}
+	if err := g.updateDeployment(ctx, d, envName, state, description); err != nil {
+		g.logger.Error(fmt.Sprintf("unable to update github deployment: %v", err),
+			zap.String("deployment", d.Id),
+		)
+	}
+}
+
+// updateDeployment creates a GitHub deployment for the given deployment if it does not exist yet
+// and then adds a new status to that GitHub deployment.
+func (g *githubDeployment) updateDeployment(ctx context.Context, d *model.Deployment, envName, state, description string) error {
+	if d.GitPath == nil || d.GitPath.Repo == nil {
+		return fmt.Errorf("no repository information of deployment")
+	}
+	repo, err := git.ParseRepoPath(d.GitPath.Repo.Remote)
+	if err != nil {
+		return err
+	}
+	environment := g.environment
+	if environment == "" {
+		environment = envName
+	}
+
+	id, err := g.getOrCreateDeployment(ctx, repo, environment, d)
+	if err != nil {
+		return err
+	}
+
+	status := githubDeploymentStatusRequest{
+		State:        state,
+		Description:  truncateText(description, githubDescriptionMaxLength-len("...")),
+		Environment:  environment,
+		AutoInactive: true,
+	}
+	if g.webURL != "" {
+		status.LogURL = fmt.Sprintf("%s/deployments/%s", g.webURL, d.Id)
+	}
+	if err := g.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/deployments/%d/statuses", repo, id), status, nil); err != nil {
+		return err
+	}
+
+	switch state {
+	case "success", "failure", "error":
+		g.mu.Lock()
+		delete(g.deployments, d.Id)
+		g.mu.Unlock()
+	}
+	return nil
+}
+
+func (g *githubDeployment) getOrCreateDeployment(ctx context.Context, repo, environment string, d *model.Deployment) (int64, error) {
+	g.mu.Lock()
+	id, ok := g.deployments[d.Id]
+	g.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	// The GitHub deployment may be created by the previous piped process.
+	id, err := g.findDeployment(ctx, repo, environment, d)
+	if err != nil {
+		return 0, err
+	}
+	if id == 0 {
+		req := githubDeploymentRequest{
+			Ref:              d.Trigger.Commit.Hash,
+			Task:             "deploy",
+			AutoMerge:        false,
+			RequiredContexts: []string{},
+			Payload: map[string]string{
+				githubDeploymentPayloadKey: d.Id,
+			},
+			Environment: environment,
+			Description: truncateText(fmt.Sprintf("Deploy %s by PipeCD", d.ApplicationName), githubDescriptionMaxLength-len("...")),
+		}
+		var resp struct {
+			ID int64 `json:"id"`
+		}
+		if err := g.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/deployments", repo), req, &resp); err != nil {
+			return 0, err
+		}
+		id = resp.ID
+	}
+
+	g.mu.Lock()
+	g.deployments[d.Id] = id
+	g.mu.Unlock()
+	return id, nil
+}
+
+// findDeployment returns the ID of GitHub deployment created for the given deployment.
+// Zero is returned when no one was found.
+func (g *githubDeployment) findDeployment(ctx context.Context, repo, environment string, d *model.Deployment) (int64, error) {
+	query := url.Values{}
+	query.Set("sha", d.Trigger.Commit.Hash)
+	query.Set("environment", environment)
+
+	var deployments []struct {
+		ID      int64           `json:"id"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := g.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/deployments?%s", repo, query.Encode()), nil, &deployments); err != nil {
+		return 0, err
+	}
+	for _, gd := range deployments {
+		// The payload of the deployments created by others can be in any format.
+		var payload map[string]interface{}
+		if err := json.Unmarshal(gd.Payload, &payload); err != nil {
+			continue
+		}
+		if id, ok := payload[githubDeploymentPayloadKey].(string); ok && id == d.Id {
+			return gd.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+func (g *githubDeployment) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		buf := &bytes.Buffer{}
+		if err := json.NewEncoder(buf).Encode(in); err != nil {
+			return err
+		}
+		body = buf
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.address+path, body)
+	if err != nil {
+		return err
+	}
+	// The previews are required to use the queued and in_progress states on GitHub Enterprise Server.
+	req.Header.Set("Accept", "application/vnd.github.v3+json, application/vnd.github.flash-preview+json, application/vnd.github.ant-man-preview+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "token "+g.token)
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		return fmt.Errorf("%s from GitHub: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
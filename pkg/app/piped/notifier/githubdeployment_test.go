@@ -0,0 +1,144 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestGitHubDeploymentSendEvents(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+		body   map[string]interface{}
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token github-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, request{r.Method, r.URL.Path, body})
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Get("sha") == "restarted":
+			w.Write([]byte(`[{"id":1,"payload":"legacy"},{"id":7,"payload":{"pipecdDeploymentId":"deployment-2"}}]`))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`[]`))
+		case r.URL.Path == "/repos/org/repo/deployments":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":42}`))
+		default:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	g := &githubDeployment{
+		address:     server.URL,
+		token:       "github-token",
+		webURL:      "https://pipecd.dev",
+		httpClient:  server.Client(),
+		deployments: make(map[string]int64),
+		logger:      zap.NewNop(),
+	}
+	ctx := context.Background()
+	newDeployment := func(id, commit string) *model.Deployment {
+		return &model.Deployment{
+			Id:              id,
+			ApplicationName: "helloworld",
+			GitPath: &model.ApplicationGitPath{
+				Repo: &model.ApplicationGitRepository{
+					Remote: "git@github.com:org/repo.git",
+				},
+			},
+			Trigger: &model.DeploymentTrigger{
+				Commit: &model.Commit{Hash: commit},
+			},
+		}
+	}
+
+	// A new deployment creates a GitHub deployment and updates its statuses.
+	d := newDeployment("deployment-1", "0123456789abcdef")
+	g.sendEvent(ctx, model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_TRIGGERED,
+		Metadata: &model.NotificationEventDeploymentTriggered{
+			Deployment: d,
+			EnvName:    "prod",
+		},
+	})
+	g.sendEvent(ctx, model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_SUCCEEDED,
+		Metadata: &model.NotificationEventDeploymentSucceeded{
+			Deployment: d,
+			EnvName:    "prod",
+		},
+	})
+
+	assert.Equal(t, 4, len(requests))
+	assert.Equal(t, http.MethodGet, requests[0].method)
+	assert.Equal(t, "/repos/org/repo/deployments", requests[1].path)
+	assert.Equal(t, "0123456789abcdef", requests[1].body["ref"])
+	assert.Equal(t, "prod", requests[1].body["environment"])
+	assert.Equal(t, map[string]interface{}{"pipecdDeploymentId": "deployment-1"}, requests[1].body["payload"])
+	assert.Equal(t, "/repos/org/repo/deployments/42/statuses", requests[2].path)
+	assert.Equal(t, "queued", requests[2].body["state"])
+	assert.Equal(t, "https://pipecd.dev/deployments/deployment-1", requests[2].body["log_url"])
+	assert.Equal(t, "/repos/org/repo/deployments/42/statuses", requests[3].path)
+	assert.Equal(t, "success", requests[3].body["state"])
+	assert.Empty(t, g.deployments)
+
+	// The GitHub deployment created before restarting is reused.
+	requests = nil
+	d = newDeployment("deployment-2", "restarted")
+	g.sendEvent(ctx, model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_FAILED,
+		Metadata: &model.NotificationEventDeploymentFailed{
+			Deployment: d,
+			EnvName:    "prod",
+			Reason:     "failed to apply",
+		},
+	})
+
+	assert.Equal(t, 2, len(requests))
+	assert.Equal(t, "/repos/org/repo/deployments/7/statuses", requests[1].path)
+	assert.Equal(t, "failure", requests[1].body["state"])
+	assert.Equal(t, "failed to apply", requests[1].body["description"])
+
+	// Dry-run deployments are ignored.
+	requests = nil
+	d = newDeployment("deployment-3", "0123456789abcdef")
+	d.Trigger.DryRun = true
+	g.sendEvent(ctx, model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_TRIGGERED,
+		Metadata: &model.NotificationEventDeploymentTriggered{
+			Deployment: d,
+			EnvName:    "prod",
+		},
+	})
+	assert.Empty(t, requests)
+}
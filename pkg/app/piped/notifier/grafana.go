@@ -0,0 +1,252 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// grafana writes the deployments as annotations to Grafana
+// so that they can be correlated with the metrics on the dashboards.
+// A deployment is shown as a region from its trigger to its completion.
+type grafana struct {
+	name       string
+	address    string
+	apiKey     string
+	annotation config.NotificationAnnotation
+	webURL     string
+	httpClient *http.Client
+	eventCh    chan model.NotificationEvent
+	// The IDs of the annotations of the uncompleted deployments keyed by deployment ID.
+	regions map[string]int64
+	mu      sync.Mutex
+	logger  *zap.Logger
+}
+
+type grafanaAnnotation struct {
+	DashboardUID string   `json:"dashboardUID,omitempty"`
+	PanelID      int64    `json:"panelId,omitempty"`
+	Time         int64    `json:"time,omitempty"`
+	TimeEnd      int64    `json:"timeEnd,omitempty"`
+	Tags         []string `json:"tags"`
+	Text         string   `json:"text"`
+}
+
+func newGrafanaSender(name string, cfg config.NotificationReceiverGrafana, annotation *config.NotificationAnnotation, webURL string, logger *zap.Logger) (*grafana, error) {
+	key, err := ioutil.ReadFile(cfg.APIKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the api key file of grafana receiver %s: %w", name, err)
+	}
+	g := &grafana{
+		name:    name,
+		address: strings.TrimRight(cfg.Address, "/"),
+		apiKey:  strings.TrimSpace(string(key)),
+		webURL:  strings.TrimRight(webURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		eventCh: make(chan model.NotificationEvent, 100),
+		regions: make(map[string]int64),
+		logger:  logger.Named("grafana"),
+	}
+	if annotation != nil {
+		g.annotation = *annotation
+	}
+	return g, nil
+}
+
+func (g *grafana) Run(ctx context.Context) error {
+	for {
+		select {
+		case event, ok := <-g.eventCh:
+			if ok {
+				g.sendEvent(ctx, event)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (g *grafana) Notify(event model.NotificationEvent) {
+	g.eventCh <- event
+}
+
+func (g *grafana) Close(ctx context.Context) {
+	close(g.eventCh)
+
+	// Send all remaining events.
+	for {
+		select {
+		case event, ok := <-g.eventCh:
+			if !ok {
+				return
+			}
+			g.sendEvent(ctx, event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (g *grafana) sendEvent(ctx context.Context, event model.NotificationEvent) {
+	var (
+		d       *model.Deployment
+		envName string
+		action  string
+	)
+	switch md := event.Metadata.(type) {
+	case *model.NotificationEventDeploymentTriggered:
+		d, envName, action = md.Deployment, md.EnvName, "triggered"
+	case *model.NotificationEventDeploymentRollingBack:
+		d, envName, action = md.Deployment, md.EnvName, "rolling-back"
+	case *model.NotificationEventDeploymentSucceeded:
+		d, envName, action = md.Deployment, md.EnvName, "succeeded"
+	case *model.NotificationEventDeploymentFailed:
+		d, envName, action = md.Deployment, md.EnvName, "failed"
+	case *model.NotificationEventDeploymentCancelled:
+		d, envName, action = md.Deployment, md.EnvName, "cancelled"
+	default:
+		g.logger.Info(fmt.Sprintf("ignore event %s", event.Type.String()))
+		return
+	}
+
+	a := g.buildAnnotation(d, envName, action)
+	if err := g.writeAnnotation(ctx, d, action, a); err != nil {
+		g.logger.Error(fmt.Sprintf("unable to write annotation to grafana: %v", err))
+	}
+}
+
+func (g *grafana) buildAnnotation(d *model.Deployment, envName, action string) grafanaAnnotation {
+	tags := []string{"pipecd", "app:" + d.ApplicationName, "env:" + envName, action}
+	tags = append(tags, g.annotation.Tags...)
+
+	text := fmt.Sprintf("Deployment of %s to %s %s", d.ApplicationName, envName, strings.ReplaceAll(action, "-", " "))
+	if c := d.Trigger.GetCommit(); c != nil && c.Hash != "" {
+		text = fmt.Sprintf("%s (commit %s)", text, shortHash(c.Hash))
+	}
+	if g.webURL != "" {
+		text = fmt.Sprintf("%s\n%s/deployments/%s", text, g.webURL, d.Id)
+	}
+
+	return grafanaAnnotation{
+		DashboardUID: g.annotation.DashboardUID,
+		PanelID:      g.annotation.PanelID,
+		Tags:         tags,
+		Text:         text,
+	}
+}
+
+// writeAnnotation starts a region annotation when the deployment was triggered
+// and closes it when the deployment was completed.
+// A rolling back is written as a point annotation.
+func (g *grafana) writeAnnotation(ctx context.Context, d *model.Deployment, action string, a grafanaAnnotation) error {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	switch action {
+	case "triggered":
+		a.Time = d.CreatedAt * 1000
+		id, err := g.createAnnotation(ctx, a)
+		if err != nil {
+			return err
+		}
+		g.mu.Lock()
+		g.regions[d.Id] = id
+		g.mu.Unlock()
+		return nil
+
+	case "rolling-back":
+		a.Time = now
+		_, err := g.createAnnotation(ctx, a)
+		return err
+
+	default:
+		a.TimeEnd = now
+		if d.CompletedAt > 0 {
+			a.TimeEnd = d.CompletedAt * 1000
+		}
+		g.mu.Lock()
+		id, ok := g.regions[d.Id]
+		delete(g.regions, d.Id)
+		g.mu.Unlock()
+
+		if ok {
+			return g.do(ctx, http.MethodPatch, fmt.Sprintf("/api/annotations/%d", id), a, nil)
+		}
+		// The start of the deployment was not recorded by this piped process.
+		a.Time = d.CreatedAt * 1000
+		_, err := g.createAnnotation(ctx, a)
+		return err
+	}
+}
+
+func (g *grafana) createAnnotation(ctx context.Context, a grafanaAnnotation) (int64, error) {
+	var resp struct {
+		ID int64 `json:"id"`
+	}
+	if err := g.do(ctx, http.MethodPost, "/api/annotations", a, &resp); err != nil {
+		return 0, err
+	}
+	return resp.ID, nil
+}
+
+func (g *grafana) do(ctx context.Context, method, path string, in, out interface{}) error {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(in); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.address+path, buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+g.apiKey)
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		return fmt.Errorf("%s from Grafana: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
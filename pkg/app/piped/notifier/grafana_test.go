@@ -0,0 +1,129 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestGrafanaWriteAnnotations(t *testing.T) {
+	type request struct {
+		method     string
+		path       string
+		annotation grafanaAnnotation
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer api-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var a grafanaAnnotation
+		json.NewDecoder(r.Body).Decode(&a)
+		requests = append(requests, request{r.Method, r.URL.Path, a})
+		w.Write([]byte(`{"message":"Annotation added","id":42}`))
+	}))
+	defer server.Close()
+
+	g := &grafana{
+		address: server.URL,
+		apiKey:  "api-key",
+		annotation: config.NotificationAnnotation{
+			DashboardUID: "dashboard",
+			PanelID:      2,
+			Tags:         []string{"team:sre"},
+		},
+		webURL:     "https://pipecd.dev",
+		httpClient: server.Client(),
+		regions:    make(map[string]int64),
+		logger:     zap.NewNop(),
+	}
+	ctx := context.Background()
+	d := &model.Deployment{
+		Id:              "deployment-id",
+		ApplicationName: "helloworld",
+		Trigger: &model.DeploymentTrigger{
+			Commit: &model.Commit{Hash: "0123456789abcdef"},
+		},
+		CreatedAt: 100,
+	}
+
+	g.sendEvent(ctx, model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_TRIGGERED,
+		Metadata: &model.NotificationEventDeploymentTriggered{
+			Deployment: d,
+			EnvName:    "prod",
+		},
+	})
+	require.Len(t, requests, 1)
+	assert.Equal(t, request{
+		method: http.MethodPost,
+		path:   "/api/annotations",
+		annotation: grafanaAnnotation{
+			DashboardUID: "dashboard",
+			PanelID:      2,
+			Time:         100000,
+			Tags:         []string{"pipecd", "app:helloworld", "env:prod", "triggered", "team:sre"},
+			Text:         "Deployment of helloworld to prod triggered (commit 0123456)\nhttps://pipecd.dev/deployments/deployment-id",
+		},
+	}, requests[0])
+
+	completed := *d
+	completed.CompletedAt = 200
+	g.sendEvent(ctx, model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_FAILED,
+		Metadata: &model.NotificationEventDeploymentFailed{
+			Deployment: &completed,
+			EnvName:    "prod",
+		},
+	})
+	require.Len(t, requests, 2)
+	assert.Equal(t, http.MethodPatch, requests[1].method)
+	assert.Equal(t, "/api/annotations/42", requests[1].path)
+	assert.Equal(t, int64(200000), requests[1].annotation.TimeEnd)
+	assert.Contains(t, requests[1].annotation.Tags, "failed")
+	assert.Empty(t, g.regions)
+
+	// The region is created at once when its start was not recorded.
+	g.sendEvent(ctx, model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_SUCCEEDED,
+		Metadata: &model.NotificationEventDeploymentSucceeded{
+			Deployment: &completed,
+			EnvName:    "prod",
+		},
+	})
+	require.Len(t, requests, 3)
+	assert.Equal(t, http.MethodPost, requests[2].method)
+	assert.Equal(t, int64(100000), requests[2].annotation.Time)
+	assert.Equal(t, int64(200000), requests[2].annotation.TimeEnd)
+
+	// The events not related to the deployments are ignored.
+	g.sendEvent(ctx, model.NotificationEvent{
+		Type:     model.NotificationEventType_EVENT_PIPED_STARTED,
+		Metadata: &model.NotificationEventPipedStarted{},
+	})
+	assert.Len(t, requests, 3)
+}
@@ -19,6 +19,7 @@ package notifier
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"go.uber.org/atomic"
@@ -33,6 +34,8 @@ import (
 type Notifier struct {
 	config      *config.PipedSpec
 	handlers    []handler
+	mu          sync.RWMutex
+	reloadCh    chan []handler
 	gracePeriod time.Duration
 	closed      atomic.Bool
 	logger      *zap.Logger
@@ -51,6 +54,21 @@ type sender interface {
 
 func NewNotifier(cfg *config.PipedSpec, logger *zap.Logger) (*Notifier, error) {
 	logger = logger.Named("notifier")
+	handlers, err := buildHandlers(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		config:      cfg,
+		handlers:    handlers,
+		reloadCh:    make(chan []handler, 1),
+		gracePeriod: 10 * time.Second,
+		logger:      logger,
+	}, nil
+}
+
+func buildHandlers(cfg *config.PipedSpec, logger *zap.Logger) ([]handler, error) {
 	receivers := make(map[string]config.NotificationReceiver, len(cfg.Notifications.Receivers))
 	for _, r := range cfg.Notifications.Receivers {
 		receivers[r.Name] = r
@@ -63,12 +81,25 @@ func NewNotifier(cfg *config.PipedSpec, logger *zap.Logger) (*Notifier, error) {
 			return nil, fmt.Errorf("missing receiver %s that is used in route %s", route.Receiver, route.Name)
 		}
 
+		tmpl, err := newMessageTemplate(route.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template in route %s: %w", route.Name, err)
+		}
+
 		var sd sender
 		switch {
 		case receiver.Slack != nil:
-			sd = newSlackSender(receiver.Name, *receiver.Slack, cfg.WebAddress, logger)
+			sd = newSlackSender(receiver.Name, *receiver.Slack, tmpl, cfg.WebAddress, logger)
 		case receiver.Webhook != nil:
-			sd = newWebhookSender(receiver.Name, *receiver.Webhook, logger)
+			sd = newWebhookSender(receiver.Name, *receiver.Webhook, tmpl, cfg.WebAddress, logger)
+		case receiver.Grafana != nil:
+			if sd, err = newGrafanaSender(receiver.Name, *receiver.Grafana, route.Annotation, cfg.WebAddress, logger); err != nil {
+				return nil, err
+			}
+		case receiver.GitHubDeployment != nil:
+			if sd, err = newGitHubDeploymentSender(receiver.Name, *receiver.GitHubDeployment, cfg.WebAddress, logger); err != nil {
+				return nil, err
+			}
 		default:
 			continue
 		}
@@ -78,25 +109,53 @@ func NewNotifier(cfg *config.PipedSpec, logger *zap.Logger) (*Notifier, error) {
 			sender:  sd,
 		})
 	}
+	return handlers, nil
+}
 
-	return &Notifier{
-		config:      cfg,
-		handlers:    handlers,
-		gracePeriod: 10 * time.Second,
-		logger:      logger,
-	}, nil
+// ReloadConfig rebuilds the notification routes from the given configuration.
+// The senders of the old routes are closed after sending all of their queued events.
+func (n *Notifier) ReloadConfig(cfg *config.PipedSpec) error {
+	handlers, err := buildHandlers(cfg, n.logger)
+	if err != nil {
+		return err
+	}
+	// Drop the pending one since it is outdated.
+	select {
+	case <-n.reloadCh:
+	default:
+	}
+	n.reloadCh <- handlers
+	return nil
 }
 
 func (n *Notifier) Run(ctx context.Context) error {
 	group, ctx := errgroup.WithContext(ctx)
 
 	// Start running all senders.
-	for i := range n.handlers {
-		sender := n.handlers[i].sender
-		group.Go(func() error {
-			return sender.Run(ctx)
-		})
-	}
+	stopSenders := n.startSenders(ctx, group, n.getHandlers())
+
+	// Replace the senders when the configuration was reloaded.
+	group.Go(func() error {
+		for {
+			select {
+			case handlers := <-n.reloadCh:
+				stop := n.startSenders(ctx, group, handlers)
+				n.mu.Lock()
+				old := n.handlers
+				n.handlers = handlers
+				n.mu.Unlock()
+
+				stopSenders()
+				n.closeSenders(old)
+				stopSenders = stop
+				n.logger.Info(fmt.Sprintf("reloaded notification routes, %d notifiers are running", len(handlers)))
+
+			case <-ctx.Done():
+				stopSenders()
+				return nil
+			}
+		}
+	})
 
 	// Send the PIPED_STARTED event.
 	n.Notify(model.NotificationEvent{
@@ -107,7 +166,7 @@ func (n *Notifier) Run(ctx context.Context) error {
 		},
 	})
 
-	n.logger.Info(fmt.Sprintf("all %d notifiers have been started", len(n.handlers)))
+	n.logger.Info(fmt.Sprintf("all %d notifiers have been started", len(n.getHandlers())))
 	if err := group.Wait(); err != nil {
 		n.logger.Error("failed while running", zap.Error(err))
 		return err
@@ -124,16 +183,41 @@ func (n *Notifier) Run(ctx context.Context) error {
 
 	// Mark to ignore all incoming events from this time and close all senders.
 	n.closed.Store(true)
+	handlers := n.getHandlers()
+	n.closeSenders(handlers)
+
+	n.logger.Info(fmt.Sprintf("all %d notifiers have been stopped", len(handlers)))
+	return nil
+}
+
+// startSenders starts running the senders of the given handlers
+// and returns a function to stop them.
+func (n *Notifier) startSenders(ctx context.Context, group *errgroup.Group, handlers []handler) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	for i := range handlers {
+		sender := handlers[i].sender
+		group.Go(func() error {
+			return sender.Run(ctx)
+		})
+	}
+	return cancel
+}
+
+// closeSenders closes the senders of the given handlers
+// after trying to send their remaining events within the grace period.
+func (n *Notifier) closeSenders(handlers []handler) {
 	ctx, cancel := context.WithTimeout(context.Background(), n.gracePeriod)
 	defer cancel()
 
-	for i := range n.handlers {
-		sender := n.handlers[i].sender
-		sender.Close(ctx)
+	for i := range handlers {
+		handlers[i].sender.Close(ctx)
 	}
+}
 
-	n.logger.Info(fmt.Sprintf("all %d notifiers have been stopped", len(n.handlers)))
-	return nil
+func (n *Notifier) getHandlers() []handler {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.handlers
 }
 
 func (n *Notifier) Notify(event model.NotificationEvent) {
@@ -141,6 +225,9 @@ func (n *Notifier) Notify(event model.NotificationEvent) {
 		n.logger.Warn("ignore an event because notifier is already closed", zap.String("type", event.Type.String()))
 		return
 	}
+	// Hold the lock while sending to ensure that the senders are not closed by reloading.
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 	for _, h := range n.handlers {
 		if !h.matcher.Match(event) {
 			continue
@@ -22,6 +22,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -42,17 +43,19 @@ const (
 type slack struct {
 	name       string
 	config     config.NotificationReceiverSlack
+	template   *messageTemplate
 	webURL     string
 	httpClient *http.Client
 	eventCh    chan model.NotificationEvent
 	logger     *zap.Logger
 }
 
-func newSlackSender(name string, cfg config.NotificationReceiverSlack, webURL string, logger *zap.Logger) *slack {
+func newSlackSender(name string, cfg config.NotificationReceiverSlack, tmpl *messageTemplate, webURL string, logger *zap.Logger) *slack {
 	return &slack{
-		name:   name,
-		config: cfg,
-		webURL: strings.TrimRight(webURL, "/"),
+		name:     name,
+		config:   cfg,
+		template: tmpl,
+		webURL:   strings.TrimRight(webURL, "/"),
 		httpClient: &http.Client{
 			Timeout: 5 * time.Second,
 		},
@@ -101,6 +104,15 @@ func (s *slack) sendEvent(ctx context.Context, event model.NotificationEvent) {
 		s.logger.Info(fmt.Sprintf("ignore event %s", event.Type.String()))
 		return
 	}
+	if s.template != nil {
+		rendered, err := s.template.Render(event, s.webURL)
+		if err != nil {
+			// Still send the default message to not miss the event.
+			s.logger.Error(fmt.Sprintf("unable to render the notification template: %v", err))
+		} else {
+			applyRenderedMessage(&msg, rendered)
+		}
+	}
 	if err := s.sendMessage(ctx, msg); err != nil {
 		s.logger.Error(fmt.Sprintf("unable to send notification to slack: %v", err))
 	}
@@ -149,6 +161,13 @@ func (s *slack) buildSlackMessage(event model.NotificationEvent, webURL string)
 			{"Triggered By", d.TriggeredBy(), true},
 			{"Started At", makeSlackDate(d.CreatedAt), true},
 		}
+		if d.Version != "" {
+			fields = append(fields, slackField{"Version", d.Version, true})
+		}
+		if len(d.Versions) > 0 {
+			fields = append(fields, slackField{"Images", model.ArtifactVersionsText(d.Versions, "\n"), false})
+		}
+		fields = append(fields, makeCommitTrailerFields(d)...)
 	}
 	generatePipedEventData := func(id, version string) {
 		link = webURL + "/settings/piped"
@@ -170,11 +189,33 @@ func (s *slack) buildSlackMessage(event model.NotificationEvent, webURL string)
 		text = md.Summary
 		generateDeploymentEventData(md.Deployment, md.EnvName)
 
+	case model.NotificationEventType_EVENT_DEPLOYMENT_APPROVED:
+		md := event.Metadata.(*model.NotificationEventDeploymentApproved)
+		title = fmt.Sprintf("Deployment for %q was approved", md.Deployment.ApplicationName)
+		text = makeApprovalText("Approved", md.Approver, md.Comment)
+		color = slackSuccessColor
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
+	case model.NotificationEventType_EVENT_DEPLOYMENT_REJECTED:
+		md := event.Metadata.(*model.NotificationEventDeploymentRejected)
+		title = fmt.Sprintf("Deployment for %q was rejected", md.Deployment.ApplicationName)
+		text = makeApprovalText("Rejected", md.Rejecter, md.Comment)
+		color = slackErrorColor
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
+	case model.NotificationEventType_EVENT_DEPLOYMENT_APPROVAL_ESCALATED:
+		md := event.Metadata.(*model.NotificationEventDeploymentApprovalEscalated)
+		title = fmt.Sprintf("Approval for deployment of %q was escalated", md.Deployment.ApplicationName)
+		text = makeEscalationText(time.Duration(md.WaitedSeconds)*time.Second, md.FallbackApprovers, md.Action)
+		color = slackWarnColor
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
 	case model.NotificationEventType_EVENT_DEPLOYMENT_SUCCEEDED:
 		md := event.Metadata.(*model.NotificationEventDeploymentSucceeded)
 		title = fmt.Sprintf("Deployment for %q was completed successfully", md.Deployment.ApplicationName)
 		color = slackSuccessColor
 		generateDeploymentEventData(md.Deployment, md.EnvName)
+		fields = append(fields, makeStageResultFields(md.Deployment)...)
 
 	case model.NotificationEventType_EVENT_DEPLOYMENT_FAILED:
 		md := event.Metadata.(*model.NotificationEventDeploymentFailed)
@@ -182,6 +223,7 @@ func (s *slack) buildSlackMessage(event model.NotificationEvent, webURL string)
 		text = md.Reason
 		color = slackErrorColor
 		generateDeploymentEventData(md.Deployment, md.EnvName)
+		fields = append(fields, makeStageResultFields(md.Deployment)...)
 
 	case model.NotificationEventType_EVENT_DEPLOYMENT_CANCELLED:
 		md := event.Metadata.(*model.NotificationEventDeploymentCancelled)
@@ -229,6 +271,67 @@ type slackField struct {
 	Short bool   `json:"short"`
 }
 
+// makeCommitTrailerFields makes one field for each trailer
+// of the trigger commit, sorted by the trailer key.
+func makeCommitTrailerFields(d *model.Deployment) []slackField {
+	trailers := d.CommitTrailers()
+	keys := make([]string, 0, len(trailers))
+	for k := range trailers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]slackField, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, slackField{k, trailers[k], true})
+	}
+	return fields
+}
+
+// makeStageResultFields makes one field for each stage
+// those published their key results, in the order of the pipeline.
+func makeStageResultFields(d *model.Deployment) []slackField {
+	fields := make([]slackField, 0)
+	for _, s := range d.Stages {
+		if !s.Visible || len(s.Results) == 0 {
+			continue
+		}
+		keys := make([]string, 0, len(s.Results))
+		for k := range s.Results {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		lines := make([]string, 0, len(keys))
+		for _, k := range keys {
+			lines = append(lines, fmt.Sprintf("%s: %s", k, s.Results[k]))
+		}
+		fields = append(fields, slackField{s.Name, strings.Join(lines, "\n"), false})
+	}
+	return fields
+}
+
+func makeApprovalText(action, commander, comment string) string {
+	if comment == "" {
+		return fmt.Sprintf("%s by %s", action, commander)
+	}
+	return fmt.Sprintf("%s by %s: %s", action, commander, comment)
+}
+
+func makeEscalationText(waited time.Duration, fallbackApprovers []string, action string) string {
+	text := fmt.Sprintf("No approval was given within %v", waited)
+	if len(fallbackApprovers) > 0 {
+		text += fmt.Sprintf(", delegated to %s", strings.Join(fallbackApprovers, ", "))
+	}
+	switch action {
+	case "APPROVE":
+		text += ". Approved automatically"
+	case "REJECT":
+		text += ". Rejected automatically"
+	}
+	return text
+}
+
 func makeSlackLink(title, url string) string {
 	return fmt.Sprintf("<%s|%s>", url, title)
 }
@@ -244,6 +347,20 @@ func truncateText(text string, max int) string {
 	return text[:max] + "..."
 }
 
+// applyRenderedMessage overrides the default message with the rendered one.
+func applyRenderedMessage(msg *slackMessage, rendered renderedMessage) {
+	for i := range msg.Attachments {
+		a := &msg.Attachments[i]
+		if rendered.title != "" {
+			a.Title = rendered.title
+		}
+		if rendered.text != "" {
+			a.Text = rendered.text
+		}
+		a.Fields = append(a.Fields, rendered.fields...)
+	}
+}
+
 func makeSlackMessage(title, titleLink, text, color string, timestamp int64, fields ...slackField) slackMessage {
 	return slackMessage{
 		Username: slackUsername,
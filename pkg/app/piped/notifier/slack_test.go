@@ -0,0 +1,146 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestMakeStageResultFields(t *testing.T) {
+	d := &model.Deployment{
+		Stages: []*model.PipelineStage{
+			{
+				Name:    "K8S_CANARY_ROLLOUT",
+				Visible: true,
+			},
+			{
+				Name:    "ANALYSIS",
+				Visible: true,
+				Results: map[string]string{
+					"Analysis": "All 2 analyses passed",
+				},
+			},
+			{
+				Name:    "K8S_TRAFFIC_ROUTING",
+				Visible: true,
+				Results: map[string]string{
+					"Traffic":  "primary: 80%, canary: 20%, baseline: 0%",
+					"Revision": "v1",
+				},
+			},
+			{
+				Name:    "ROLLBACK",
+				Visible: false,
+				Results: map[string]string{
+					"Traffic": "primary: 100%",
+				},
+			},
+		},
+	}
+
+	expected := []slackField{
+		{"ANALYSIS", "Analysis: All 2 analyses passed", false},
+		{"K8S_TRAFFIC_ROUTING", "Revision: v1\nTraffic: primary: 80%, canary: 20%, baseline: 0%", false},
+	}
+	assert.Equal(t, expected, makeStageResultFields(d))
+}
+
+func TestMakeApprovalText(t *testing.T) {
+	testcases := []struct {
+		name      string
+		action    string
+		commander string
+		comment   string
+		expected  string
+	}{
+		{
+			name:      "without comment",
+			action:    "Approved",
+			commander: "user-a",
+			expected:  "Approved by user-a",
+		},
+		{
+			name:      "with comment",
+			action:    "Rejected",
+			commander: "user-b",
+			comment:   "The change window is closed",
+			expected:  "Rejected by user-b: The change window is closed",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := makeApprovalText(tc.action, tc.commander, tc.comment)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestMakeEscalationText(t *testing.T) {
+	testcases := []struct {
+		name              string
+		waited            time.Duration
+		fallbackApprovers []string
+		action            string
+		expected          string
+	}{
+		{
+			name:              "notify",
+			waited:            30 * time.Minute,
+			fallbackApprovers: []string{"user-a", "user-b"},
+			action:            "NOTIFY",
+			expected:          "No approval was given within 30m0s, delegated to user-a, user-b",
+		},
+		{
+			name:     "approve",
+			waited:   time.Hour,
+			action:   "APPROVE",
+			expected: "No approval was given within 1h0m0s. Approved automatically",
+		},
+		{
+			name:              "reject",
+			waited:            time.Hour,
+			fallbackApprovers: []string{"user-a"},
+			action:            "REJECT",
+			expected:          "No approval was given within 1h0m0s, delegated to user-a. Rejected automatically",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := makeEscalationText(tc.waited, tc.fallbackApprovers, tc.action)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestMakeCommitTrailerFields(t *testing.T) {
+	d := &model.Deployment{
+		Metadata: map[string]string{
+			"change-ticket-id": "CHG0001",
+			model.CommitTrailerMetadataKeyPrefix + "Ticket":      "PROJ-123",
+			model.CommitTrailerMetadataKeyPrefix + "Deploy-Note": "Run the migration first",
+		},
+	}
+
+	expected := []slackField{
+		{"Deploy-Note", "Run the migration first", true},
+		{"Ticket", "PROJ-123", true},
+	}
+	assert.Equal(t, expected, makeCommitTrailerFields(d))
+}
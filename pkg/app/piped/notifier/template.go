@@ -0,0 +1,132 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// messageTemplate builds the customized parts of a notification message
+// by executing the configured templates over the event payload.
+type messageTemplate struct {
+	title  *template.Template
+	text   *template.Template
+	fields []fieldTemplate
+}
+
+type fieldTemplate struct {
+	title string
+	value *template.Template
+	short bool
+}
+
+// templateData is the data passed to the templates.
+type templateData struct {
+	// Name of the event, e.g. DEPLOYMENT_SUCCEEDED.
+	Event string
+	// Name of the event group, e.g. DEPLOYMENT.
+	Group string
+	// The address of the web console.
+	WebURL string
+	// The payload of the event, e.g. NotificationEventDeploymentSucceeded.
+	Metadata interface{}
+}
+
+// renderedMessage contains the rendered parts of a notification message.
+// Empty title or text means the default one should be used.
+type renderedMessage struct {
+	title  string
+	text   string
+	fields []slackField
+}
+
+func newMessageTemplate(cfg *config.NotificationTemplate) (*messageTemplate, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	parse := func(name, text string) (*template.Template, error) {
+		if text == "" {
+			return nil, nil
+		}
+		return template.New(name).Option("missingkey=zero").Parse(text)
+	}
+
+	var (
+		t   = &messageTemplate{}
+		err error
+	)
+	if t.title, err = parse("title", cfg.Title); err != nil {
+		return nil, fmt.Errorf("invalid title template: %w", err)
+	}
+	if t.text, err = parse("text", cfg.Text); err != nil {
+		return nil, fmt.Errorf("invalid text template: %w", err)
+	}
+	for _, f := range cfg.Fields {
+		value, err := parse(f.Title, f.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value template of field %s: %w", f.Title, err)
+		}
+		t.fields = append(t.fields, fieldTemplate{
+			title: f.Title,
+			value: value,
+			short: f.Short,
+		})
+	}
+	return t, nil
+}
+
+func (t *messageTemplate) Render(event model.NotificationEvent, webURL string) (renderedMessage, error) {
+	var (
+		msg  renderedMessage
+		data = templateData{
+			Event:    strings.TrimPrefix(event.Type.String(), "EVENT_"),
+			Group:    strings.TrimPrefix(event.Group().String(), "EVENT_"),
+			WebURL:   webURL,
+			Metadata: event.Metadata,
+		}
+		err error
+	)
+	if msg.title, err = execute(t.title, data); err != nil {
+		return msg, err
+	}
+	if msg.text, err = execute(t.text, data); err != nil {
+		return msg, err
+	}
+	for _, f := range t.fields {
+		value, err := execute(f.value, data)
+		if err != nil {
+			return msg, err
+		}
+		msg.fields = append(msg.fields, slackField{f.title, value, f.short})
+	}
+	return msg, nil
+}
+
+func execute(t *template.Template, data templateData) (string, error) {
+	if t == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute %s template: %w", t.Name(), err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestMessageTemplateRender(t *testing.T) {
+	event := model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_SUCCEEDED,
+		Metadata: &model.NotificationEventDeploymentSucceeded{
+			Deployment: &model.Deployment{
+				Id:              "deployment-1",
+				ApplicationName: "app-1",
+			},
+			EnvName: "prod",
+		},
+	}
+
+	testcases := []struct {
+		name        string
+		cfg         *config.NotificationTemplate
+		expected    renderedMessage
+		expectedErr bool
+	}{
+		{
+			name: "only fields",
+			cfg: &config.NotificationTemplate{
+				Fields: []config.NotificationTemplateField{
+					{
+						Title: "Runbook",
+						Value: "https://runbook.example.com/{{ .Metadata.Deployment.ApplicationName }}",
+					},
+					{
+						Title: "Dashboard",
+						Value: "{{ .WebURL }}/deployments/{{ .Metadata.Deployment.Id }}",
+						Short: true,
+					},
+				},
+			},
+			expected: renderedMessage{
+				fields: []slackField{
+					{"Runbook", "https://runbook.example.com/app-1", false},
+					{"Dashboard", "https://pipecd.dev/deployments/deployment-1", true},
+				},
+			},
+		},
+		{
+			name: "title and text",
+			cfg: &config.NotificationTemplate{
+				Title: "[{{ .Metadata.EnvName }}] {{ .Metadata.Deployment.ApplicationName }}",
+				Text:  "{{ .Group }}/{{ .Event }}",
+			},
+			expected: renderedMessage{
+				title: "[prod] app-1",
+				text:  "DEPLOYMENT/DEPLOYMENT_SUCCEEDED",
+			},
+		},
+		{
+			name: "refer to an unknown field",
+			cfg: &config.NotificationTemplate{
+				Text: "{{ .Metadata.Unknown }}",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := newMessageTemplate(tc.cfg)
+			require.NoError(t, err)
+
+			got, err := tmpl.Render(event, "https://pipecd.dev")
+			assert.Equal(t, tc.expectedErr, err != nil)
+			if err == nil {
+				assert.Equal(t, tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewMessageTemplate(t *testing.T) {
+	_, err := newMessageTemplate(&config.NotificationTemplate{
+		Title: "{{ .Event ",
+	})
+	assert.Error(t, err)
+
+	tmpl, err := newMessageTemplate(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, tmpl)
+}
@@ -15,34 +15,152 @@
 package notifier
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
 type webhook struct {
-	name   string
-	config config.NotificationReceiverWebhook
-	logger *zap.Logger
+	name       string
+	config     config.NotificationReceiverWebhook
+	template   *messageTemplate
+	webURL     string
+	httpClient *http.Client
+	eventCh    chan model.NotificationEvent
+	logger     *zap.Logger
 }
 
-func newWebhookSender(name string, cfg config.NotificationReceiverWebhook, logger *zap.Logger) *webhook {
+// webhookMessage is the payload posted to the webhook endpoint.
+// Title, Text and Fields are set only when the route has a template.
+type webhookMessage struct {
+	Event    string          `json:"event"`
+	Group    string          `json:"group"`
+	Title    string          `json:"title,omitempty"`
+	Text     string          `json:"text,omitempty"`
+	Fields   []slackField    `json:"fields,omitempty"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+func newWebhookSender(name string, cfg config.NotificationReceiverWebhook, tmpl *messageTemplate, webURL string, logger *zap.Logger) *webhook {
 	return &webhook{
-		name:   name,
-		config: cfg,
-		logger: logger.Named("webhook"),
+		name:     name,
+		config:   cfg,
+		template: tmpl,
+		webURL:   strings.TrimRight(webURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		eventCh: make(chan model.NotificationEvent, 100),
+		logger:  logger.Named("webhook"),
 	}
 }
 
 func (s *webhook) Run(ctx context.Context) error {
-	return nil
+	for {
+		select {
+		case event, ok := <-s.eventCh:
+			if ok {
+				s.sendEvent(ctx, event)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
 }
 
-func (s *webhook) Notify(_ model.NotificationEvent) {
+func (s *webhook) Notify(event model.NotificationEvent) {
+	s.eventCh <- event
 }
 
 func (s *webhook) Close(ctx context.Context) {
+	close(s.eventCh)
+
+	// Send all remaining events.
+	for {
+		select {
+		case event, ok := <-s.eventCh:
+			if !ok {
+				return
+			}
+			s.sendEvent(ctx, event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *webhook) sendEvent(ctx context.Context, event model.NotificationEvent) {
+	msg, err := s.buildWebhookMessage(event)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("unable to build webhook message for event %s: %v", event.Type.String(), err))
+		return
+	}
+	if err := s.sendMessage(ctx, msg); err != nil {
+		s.logger.Error(fmt.Sprintf("unable to send notification to webhook: %v", err))
+	}
+}
+
+func (s *webhook) buildWebhookMessage(event model.NotificationEvent) (webhookMessage, error) {
+	msg := webhookMessage{
+		Event: strings.TrimPrefix(event.Type.String(), "EVENT_"),
+		Group: strings.TrimPrefix(event.Group().String(), "EVENT_"),
+	}
+	if m, ok := event.Metadata.(proto.Message); ok {
+		data, err := protojson.Marshal(m)
+		if err != nil {
+			return msg, err
+		}
+		msg.Metadata = data
+	}
+	if s.template != nil {
+		rendered, err := s.template.Render(event, s.webURL)
+		if err != nil {
+			// Still send the event without the customized parts to not miss it.
+			s.logger.Error(fmt.Sprintf("unable to render the notification template: %v", err))
+		} else {
+			msg.Title = rendered.title
+			msg.Text = rendered.text
+			msg.Fields = rendered.fields
+		}
+	}
+	return msg, nil
+}
+
+func (s *webhook) sendMessage(ctx context.Context, msg webhookMessage) error {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(msg); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.config.URL, buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		return fmt.Errorf("%s from webhook: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
 }
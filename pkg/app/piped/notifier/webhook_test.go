@@ -0,0 +1,83 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestWebhookSendEvent(t *testing.T) {
+	event := model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_TRIGGERED,
+		Metadata: &model.NotificationEventDeploymentTriggered{
+			Deployment: &model.Deployment{
+				Id:              "deployment-1",
+				ApplicationName: "app-1",
+			},
+			EnvName: "dev",
+		},
+	}
+	testcases := []struct {
+		name     string
+		template *config.NotificationTemplate
+		expected string
+	}{
+		{
+			name:     "no template",
+			expected: `{"event":"DEPLOYMENT_TRIGGERED","group":"DEPLOYMENT","metadata":{"deployment":{"id":"deployment-1","applicationName":"app-1"},"envName":"dev"}}`,
+		},
+		{
+			name: "with template",
+			template: &config.NotificationTemplate{
+				Title: "{{ .Metadata.Deployment.ApplicationName }} was triggered",
+				Fields: []config.NotificationTemplateField{
+					{Title: "Runbook", Value: "{{ .WebURL }}/runbooks/{{ .Metadata.EnvName }}"},
+				},
+			},
+			expected: `{"event":"DEPLOYMENT_TRIGGERED","group":"DEPLOYMENT","title":"app-1 was triggered","fields":[{"title":"Runbook","value":"https://pipecd.dev/runbooks/dev","short":false}],"metadata":{"deployment":{"id":"deployment-1","applicationName":"app-1"},"envName":"dev"}}`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = ioutil.ReadAll(r.Body)
+			}))
+			defer srv.Close()
+
+			tmpl, err := newMessageTemplate(tc.template)
+			require.NoError(t, err)
+			s := newWebhookSender("webhook", config.NotificationReceiverWebhook{URL: srv.URL}, tmpl, "https://pipecd.dev/", zap.NewNop())
+			s.sendEvent(context.Background(), event)
+
+			var got, expected map[string]interface{}
+			require.NoError(t, json.Unmarshal(body, &got))
+			require.NoError(t, json.Unmarshal([]byte(tc.expected), &expected))
+			assert.Equal(t, expected, got)
+		})
+	}
+}
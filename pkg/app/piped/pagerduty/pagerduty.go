@@ -0,0 +1,189 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pagerduty sends the change events of deployments to PagerDuty
+// and checks whether the linked services are under maintenance or incidents.
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/httpjson"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	defaultEventsAddress = "https://events.pagerduty.com"
+	defaultAPIAddress    = "https://api.pagerduty.com"
+)
+
+// ChangeEvent represents a change made to a service.
+// See https://developer.pagerduty.com/docs/events-api-v2/send-change-events/
+type ChangeEvent struct {
+	Summary       string
+	Source        string
+	Timestamp     time.Time
+	CustomDetails map[string]string
+	// The link to the deployment.
+	Link     string
+	LinkText string
+}
+
+type Client struct {
+	apiToken      string
+	routingKeys   map[string]string
+	eventsAddress string
+	apiAddress    string
+	client        *http.Client
+}
+
+var clients = struct {
+	mu     sync.Mutex
+	config *config.PipedPagerDuty
+	client *Client
+}{}
+
+// GetClient returns the client for the given config.
+// The client is created only once for the same config to not read the secret files on every call,
+// and is created again when the given config was changed, e.g. by reloading the piped config.
+func GetClient(cfg *config.PipedPagerDuty) (*Client, error) {
+	clients.mu.Lock()
+	defer clients.mu.Unlock()
+
+	if clients.config == cfg && clients.client != nil {
+		return clients.client, nil
+	}
+	c, err := NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	clients.config, clients.client = cfg, c
+	return c, nil
+}
+
+// NewClient creates a client by reading the secrets configured in the given config.
+func NewClient(cfg *config.PipedPagerDuty) (*Client, error) {
+	c := &Client{
+		routingKeys:   make(map[string]string, len(cfg.Services)),
+		eventsAddress: defaultEventsAddress,
+		apiAddress:    defaultAPIAddress,
+		client:        httpjson.NewClient(),
+	}
+	if cfg.APITokenFile != "" {
+		token, err := httpjson.ReadSecretFile(cfg.APITokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the api token file: %w", err)
+		}
+		c.apiToken = token
+	}
+	for _, s := range cfg.Services {
+		key, err := httpjson.ReadSecretFile(s.RoutingKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the routing key file of service %s: %w", s.ID, err)
+		}
+		c.routingKeys[s.ID] = key
+	}
+	return c, nil
+}
+
+// SendChangeEvent sends the given change event to the given service.
+func (c *Client) SendChangeEvent(ctx context.Context, serviceID string, event ChangeEvent) error {
+	key, ok := c.routingKeys[serviceID]
+	if !ok {
+		return fmt.Errorf("routing key of pagerduty service %s is not configured", serviceID)
+	}
+
+	type link struct {
+		Href string `json:"href"`
+		Text string `json:"text,omitempty"`
+	}
+	body := map[string]interface{}{
+		"routing_key": key,
+		"payload": map[string]interface{}{
+			"summary":        truncate(event.Summary, 1024),
+			"source":         event.Source,
+			"timestamp":      event.Timestamp.UTC().Format(time.RFC3339),
+			"custom_details": event.CustomDetails,
+		},
+	}
+	if event.Link != "" {
+		body["links"] = []link{{Href: event.Link, Text: event.LinkText}}
+	}
+	return c.do(ctx, http.MethodPost, c.eventsAddress+"/v2/change/enqueue", body, nil)
+}
+
+// InMaintenance returns true when the given service has an ongoing maintenance window.
+func (c *Client) InMaintenance(ctx context.Context, serviceID string) (bool, error) {
+	query := url.Values{}
+	query.Set("service_ids[]", serviceID)
+	query.Set("filter", "ongoing")
+	query.Set("limit", "1")
+
+	var resp struct {
+		MaintenanceWindows []json.RawMessage `json:"maintenance_windows"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.apiAddress+"/maintenance_windows?"+query.Encode(), nil, &resp); err != nil {
+		return false, err
+	}
+	return len(resp.MaintenanceWindows) > 0, nil
+}
+
+// HasOpenIncidents returns true when the given service has a triggered or acknowledged incident.
+func (c *Client) HasOpenIncidents(ctx context.Context, serviceID string) (bool, error) {
+	query := url.Values{}
+	query.Set("service_ids[]", serviceID)
+	query.Add("statuses[]", "triggered")
+	query.Add("statuses[]", "acknowledged")
+	query.Set("limit", "1")
+
+	var resp struct {
+		Incidents []json.RawMessage `json:"incidents"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.apiAddress+"/incidents?"+query.Encode(), nil, &resp); err != nil {
+		return false, err
+	}
+	return len(resp.Incidents) > 0, nil
+}
+
+func (c *Client) do(ctx context.Context, method, url string, in, out interface{}) error {
+	req, err := httpjson.NewRequest(ctx, method, url, in)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+	// The events API is authenticated by the routing key in the body.
+	if out != nil {
+		if c.apiToken == "" {
+			return fmt.Errorf("apiTokenFile must be configured to call pagerduty api")
+		}
+		req.Header.Set("Authorization", "Token token="+c.apiToken)
+	}
+	if err := httpjson.Do(c.client, req, out); err != nil {
+		return fmt.Errorf("failed to call pagerduty: %w", err)
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
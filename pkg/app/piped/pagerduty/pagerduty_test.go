@@ -0,0 +1,141 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestSendChangeEvent(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v2/change/enqueue" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	c := &Client{
+		routingKeys:   map[string]string{"PSERVICE": "routing-key"},
+		eventsAddress: server.URL,
+		client:        server.Client(),
+	}
+	err := c.SendChangeEvent(context.Background(), "PSERVICE", ChangeEvent{
+		Summary:   "Deployment of helloworld started",
+		Source:    "piped",
+		Timestamp: time.Date(2021, 9, 1, 10, 0, 0, 0, time.UTC),
+		Link:      "https://pipecd.dev/deployments/id",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "routing-key", got["routing_key"])
+	payload := got["payload"].(map[string]interface{})
+	assert.Equal(t, "Deployment of helloworld started", payload["summary"])
+	assert.Equal(t, "2021-09-01T10:00:00Z", payload["timestamp"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"href": "https://pipecd.dev/deployments/id"}}, got["links"])
+
+	err = c.SendChangeEvent(context.Background(), "PUNKNOWN", ChangeEvent{})
+	assert.Error(t, err)
+}
+
+func TestServiceStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token token=api-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		busy := r.URL.Query().Get("service_ids[]") == "PBUSY"
+		switch r.URL.Path {
+		case "/maintenance_windows":
+			assert.Equal(t, "ongoing", r.URL.Query().Get("filter"))
+			if busy {
+				w.Write([]byte(`{"maintenance_windows":[{"id":"PW1"}]}`))
+				return
+			}
+			w.Write([]byte(`{"maintenance_windows":[]}`))
+		case "/incidents":
+			assert.Equal(t, []string{"triggered", "acknowledged"}, r.URL.Query()["statuses[]"])
+			if busy {
+				w.Write([]byte(`{"incidents":[{"id":"PI1"}]}`))
+				return
+			}
+			w.Write([]byte(`{"incidents":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := &Client{
+		apiToken:   "api-token",
+		apiAddress: server.URL,
+		client:     server.Client(),
+	}
+	ctx := context.Background()
+
+	testcases := []struct {
+		serviceID string
+		expected  bool
+	}{
+		{
+			serviceID: "PBUSY",
+			expected:  true,
+		},
+		{
+			serviceID: "PIDLE",
+			expected:  false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.serviceID, func(t *testing.T) {
+			inMaintenance, err := c.InMaintenance(ctx, tc.serviceID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, inMaintenance)
+
+			hasIncidents, err := c.HasOpenIncidents(ctx, tc.serviceID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, hasIncidents)
+		})
+	}
+}
+
+func TestGetClient(t *testing.T) {
+	cfg := &config.PipedPagerDuty{}
+	c1, err := GetClient(cfg)
+	require.NoError(t, err)
+	c2, err := GetClient(cfg)
+	require.NoError(t, err)
+	assert.Same(t, c1, c2)
+
+	// A new client should be created for the reloaded config.
+	c3, err := GetClient(&config.PipedPagerDuty{})
+	require.NoError(t, err)
+	assert.NotSame(t, c1, c3)
+
+	_, err = GetClient(&config.PipedPagerDuty{APITokenFile: "not-found"})
+	assert.Error(t, err)
+}
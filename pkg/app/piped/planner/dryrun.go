@@ -0,0 +1,53 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// BuildDryRunPipeline builds the pipeline for a dry-run deployment of the given application kind.
+// The returned pipeline never changes anything in the target environment.
+// An empty pipeline is returned for the kinds that do not support verifying the changes
+// on their platform, in that case only the planning result is reported.
+func BuildDryRunPipeline(kind model.ApplicationKind, now time.Time) []*model.PipelineStage {
+	var id string
+	switch kind {
+	case model.ApplicationKind_KUBERNETES:
+		id = PredefinedStageK8sDryRun
+	case model.ApplicationKind_TERRAFORM:
+		id = PredefinedStageTerraformPlan
+	default:
+		return []*model.PipelineStage{}
+	}
+
+	s, _ := GetPredefinedStage(id)
+	return []*model.PipelineStage{
+		{
+			Id:         s.Id,
+			Name:       s.Name.String(),
+			Desc:       s.Desc,
+			Index:      0,
+			Predefined: true,
+			Visible:    true,
+			Status:     model.StageStatus_STAGE_NOT_STARTED_YET,
+			Metadata:   MakeInitialStageMetadata(s),
+			CreatedAt:  now.Unix(),
+			UpdatedAt:  now.Unix(),
+		},
+	}
+}
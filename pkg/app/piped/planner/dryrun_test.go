@@ -0,0 +1,61 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestBuildDryRunPipeline(t *testing.T) {
+	now := time.Now()
+	testcases := []struct {
+		name     string
+		kind     model.ApplicationKind
+		expected []string
+	}{
+		{
+			name:     "kubernetes",
+			kind:     model.ApplicationKind_KUBERNETES,
+			expected: []string{model.StageK8sDryRun.String()},
+		},
+		{
+			name:     "terraform",
+			kind:     model.ApplicationKind_TERRAFORM,
+			expected: []string{model.StageTerraformPlan.String()},
+		},
+		{
+			name:     "cloudrun",
+			kind:     model.ApplicationKind_CLOUDRUN,
+			expected: []string{},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			stages := BuildDryRunPipeline(tc.kind, now)
+			names := make([]string, 0, len(stages))
+			for _, s := range stages {
+				names = append(names, s.Name)
+				assert.True(t, s.Visible)
+				assert.Equal(t, model.StageStatus_STAGE_NOT_STARTED_YET, s.Status)
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
@@ -19,16 +19,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
 	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
 	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/resource"
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner"
+	"github.com/pipe-cd/pipe/pkg/app/piped/signatureverifier"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/diff"
 	"github.com/pipe-cd/pipe/pkg/model"
@@ -81,7 +84,7 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 	newManifests, ok := manifestCache.Get(in.Trigger.Commit.Hash)
 	if !ok {
 		// When the manifests were not in the cache we have to load them.
-		loader := provider.NewManifestLoader(in.ApplicationName, ds.AppDir, ds.RepoDir, in.GitPath.ConfigFilename, cfg.Input, in.Logger)
+		loader := provider.NewManifestLoader(in.ApplicationName, ds.AppDir, ds.RepoDir, in.GitPath.ConfigFilename, cfg.Input, findCluster(in), in.Logger)
 		newManifests, err = loader.LoadManifests(ctx)
 		if err != nil {
 			return
@@ -89,8 +92,29 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 		manifestCache.Put(in.Trigger.Commit.Hash, newManifests)
 	}
 
+	if cfg.SignatureVerification.Images {
+		v := signatureverifier.NewVerifier(in.PipedConfig.SignatureVerification, in.Logger)
+		if err = verifyImageSignatures(ctx, v, newManifests); err != nil {
+			return
+		}
+	}
+
+	// Build the manifest diff and the image versions after planning since the running manifests
+	// may have been loaded into the cache while deciding the strategy.
+	defer func() {
+		if err != nil {
+			return
+		}
+		oldManifests, ok := loadRunningManifests(ctx, in, cfg, manifestCache)
+		if !ok {
+			return
+		}
+		out.ManifestDiff = buildManifestDiff(in, oldManifests, newManifests)
+		out.Versions = provider.FindImageVersions(oldManifests, newManifests)
+	}()
+
 	// Determine application version from the manifests.
-	if version, e := determineVersion(newManifests); e != nil {
+	if version, e := determineVersion(newManifests, cfg, ds.RepoDir); e != nil {
 		in.Logger.Error("unable to determine version", zap.Error(e))
 		out.Version = versionUnknown
 	} else {
@@ -111,7 +135,7 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 			return
 		}
 		out.SyncStrategy = model.SyncStrategy_PIPELINE
-		out.Stages = buildProgressivePipeline(cfg.Pipeline, cfg.Input.AutoRollback, time.Now())
+		out.Stages = buildProgressivePipeline(cfg.Pipeline, cfg.Input.AutoRollback, !cfg.KeepVariantsOnFailure, time.Now())
 		out.Summary = "Sync with the specified pipeline (forced via web)"
 		return
 	}
@@ -135,7 +159,7 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 		}
 		if pipelineRegex.MatchString(in.Trigger.Commit.Message) {
 			out.SyncStrategy = model.SyncStrategy_PIPELINE
-			out.Stages = buildProgressivePipeline(cfg.Pipeline, cfg.Input.AutoRollback, time.Now())
+			out.Stages = buildProgressivePipeline(cfg.Pipeline, cfg.Input.AutoRollback, !cfg.KeepVariantsOnFailure, time.Now())
 			out.Summary = fmt.Sprintf("Sync progressively because the commit message was matching %q", p)
 			return out, err
 		}
@@ -178,7 +202,7 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 			return
 		}
 
-		loader := provider.NewManifestLoader(in.ApplicationName, runningDs.AppDir, runningDs.RepoDir, in.GitPath.ConfigFilename, cfg.Input, in.Logger)
+		loader := provider.NewManifestLoader(in.ApplicationName, runningDs.AppDir, runningDs.RepoDir, in.GitPath.ConfigFilename, cfg.Input, findCluster(in), in.Logger)
 		oldManifests, err = loader.LoadManifests(ctx)
 		if err != nil {
 			err = fmt.Errorf("failed to load previously deployed manifests: %w", err)
@@ -192,7 +216,7 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 
 	if progressive {
 		out.SyncStrategy = model.SyncStrategy_PIPELINE
-		out.Stages = buildProgressivePipeline(cfg.Pipeline, cfg.Input.AutoRollback, time.Now())
+		out.Stages = buildProgressivePipeline(cfg.Pipeline, cfg.Input.AutoRollback, !cfg.KeepVariantsOnFailure, time.Now())
 		return
 	}
 
@@ -201,6 +225,65 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 	return
 }
 
+// findCluster returns the configuration of the cluster where the application is deployed.
+// Nil is returned when it was not found, then the tools bundled with piped are used.
+func findCluster(in planner.Input) *config.CloudProviderKubernetesConfig {
+	cp, ok := in.PipedConfig.FindCloudProvider(in.CloudProvider, model.CloudProviderKubernetes)
+	if !ok {
+		return nil
+	}
+	return cp.KubernetesConfig
+}
+
+// loadRunningManifests returns the manifests at the most recently successful commit.
+// Nil is returned for the first deployment of the application.
+// This is best-effort, false is returned when it was unable to load them.
+func loadRunningManifests(ctx context.Context, in planner.Input, cfg *config.KubernetesDeploymentSpec, manifestCache provider.AppManifestsCache) ([]provider.Manifest, bool) {
+	if in.MostRecentSuccessfulCommitHash == "" {
+		return nil, true
+	}
+	if manifests, ok := manifestCache.Get(in.MostRecentSuccessfulCommitHash); ok {
+		return manifests, true
+	}
+
+	runningDs, err := in.RunningDSP.Get(ctx, ioutil.Discard)
+	if err != nil {
+		in.Logger.Warn("unable to prepare the running deploy source", zap.Error(err))
+		return nil, false
+	}
+	loader := provider.NewManifestLoader(in.ApplicationName, runningDs.AppDir, runningDs.RepoDir, in.GitPath.ConfigFilename, cfg.Input, findCluster(in), in.Logger)
+	manifests, err := loader.LoadManifests(ctx)
+	if err != nil {
+		in.Logger.Warn("unable to load the running manifests", zap.Error(err))
+		return nil, false
+	}
+	manifestCache.Put(in.MostRecentSuccessfulCommitHash, manifests)
+	return manifests, true
+}
+
+// buildManifestDiff returns the diff string between the manifests at the most recently
+// successful commit and the given new manifests.
+// This is best-effort, an empty string is returned when it was unable to compute.
+func buildManifestDiff(in planner.Input, oldManifests, newManifests []provider.Manifest) string {
+	result, err := provider.DiffList(
+		oldManifests,
+		newManifests,
+		diff.WithEquateEmpty(),
+		diff.WithCompareNumberAndNumericString(),
+		diff.WithCompareBoolAndBoolString(),
+		diff.WithIgnoreListOrder(),
+		diff.WithEquateDefaults(),
+	)
+	if err != nil {
+		in.Logger.Warn("unable to compare manifests to build manifest diff", zap.Error(err))
+		return ""
+	}
+	if result.NoChange() {
+		return ""
+	}
+	return fmt.Sprintf("--- Running Commit\n+++ Target Commit\n\n%s\n", result.DiffString(diff.WithMaskValuePatterns(in.PipedConfig.DiffMaskRegexps()...)))
+}
+
 // First up, checks to see if the workload's `spec.template` has been changed,
 // and then checks if the configmap/secret's data.
 func decideStrategy(olds, news []provider.Manifest, workloadRefs []config.K8sResourceReference) (progressive bool, desc string) {
@@ -218,6 +301,10 @@ func decideStrategy(olds, news []provider.Manifest, workloadRefs []config.K8sRes
 	workloads := findUpdatedWorkloads(oldWorkloads, newWorkloads)
 	diffs := make(map[provider.ResourceKey]diff.Nodes, len(workloads))
 
+	var (
+		changedWorkload string
+		imageChanges    []string
+	)
 	for _, w := range workloads {
 		// If the workload's pod template was touched
 		// do progressive deployment with the specified pipeline.
@@ -230,18 +317,24 @@ func decideStrategy(olds, news []provider.Manifest, workloadRefs []config.K8sRes
 		diffNodes := diffResult.Nodes()
 		diffs[w.new.Key] = diffNodes
 
+		// Continue checking the remaining workloads to report
+		// the image changes of all of them.
 		templateDiffs := diffNodes.FindByPrefix("spec.template")
 		if len(templateDiffs) > 0 {
 			progressive = true
-
-			if msg, changed := checkImageChange(templateDiffs); changed {
-				desc = msg
-				return
+			if changedWorkload == "" {
+				changedWorkload = w.new.Key.Name
 			}
-
-			desc = fmt.Sprintf("Sync progressively because pod template of workload %s was changed", w.new.Key.Name)
+			imageChanges = appendImageChanges(imageChanges, templateDiffs)
+		}
+	}
+	if progressive {
+		if len(imageChanges) > 0 {
+			desc = fmt.Sprintf("Sync progressively because of updating %s", strings.Join(imageChanges, ", "))
 			return
 		}
+		desc = fmt.Sprintf("Sync progressively because pod template of workload %s was changed", changedWorkload)
+		return
 	}
 
 	// If the config/secret was touched, we also need to do progressive
@@ -372,26 +465,36 @@ func findConfigs(manifests []provider.Manifest) map[provider.ResourceKey]provide
 	return configs
 }
 
-func checkImageChange(ns diff.Nodes) (string, bool) {
-	const containerImageQuery = `^spec\.template\.spec\.containers\.\d+.image$`
+// appendImageChanges appends the descriptions of the container image changes
+// found in the given diff nodes to the given list if they are not in it yet.
+func appendImageChanges(images []string, ns diff.Nodes) []string {
+	const containerImageQuery = `^spec\.template\.spec\.(init)?[cC]ontainers\.\d+.image$`
 	nodes, _ := ns.Find(containerImageQuery)
-	if len(nodes) == 0 {
-		return "", false
-	}
 
-	images := make([]string, 0, len(ns))
-	for _, n := range ns {
+	for _, n := range nodes {
+		var change string
 		beforeName, beforeTag := parseContainerImage(n.StringX())
 		afterName, afterTag := parseContainerImage(n.StringY())
 
 		if beforeName == afterName {
-			images = append(images, fmt.Sprintf("image %s from %s to %s", beforeName, beforeTag, afterTag))
+			change = fmt.Sprintf("image %s from %s to %s", beforeName, beforeTag, afterTag)
 		} else {
-			images = append(images, fmt.Sprintf("image %s:%s to %s:%s", beforeName, beforeTag, afterName, afterTag))
+			change = fmt.Sprintf("image %s:%s to %s:%s", beforeName, beforeTag, afterName, afterTag)
+		}
+		if !containsString(images, change) {
+			images = append(images, change)
 		}
 	}
-	desc := fmt.Sprintf("Sync progressively because of updating %s", strings.Join(images, ", "))
-	return desc, true
+	return images
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
 
 func checkReplicasChange(ns diff.Nodes) (before, after string, changed bool) {
@@ -417,8 +520,38 @@ func parseContainerImage(image string) (name, tag string) {
 	return
 }
 
+// verifyImageSignatures checks that all images used by the manifests were signed by a trusted key.
+func verifyImageSignatures(ctx context.Context, v imageVerifier, manifests []provider.Manifest) error {
+	verified := make(map[string]struct{})
+	for _, m := range manifests {
+		for _, image := range provider.FindContainerImages(m) {
+			if _, ok := verified[image]; ok {
+				continue
+			}
+			if err := v.VerifyImage(ctx, image); err != nil {
+				return fmt.Errorf("unable to verify the signature of image %s (%w)", image, err)
+			}
+			verified[image] = struct{}{}
+		}
+	}
+	return nil
+}
+
+type imageVerifier interface {
+	VerifyImage(ctx context.Context, image string) error
+}
+
 // TODO: Add ability to configure how to determine application version.
-func determineVersion(manifests []provider.Manifest) (string, error) {
+func determineVersion(manifests []provider.Manifest, cfg *config.KubernetesDeploymentSpec, repoDir string) (string, error) {
+	if e := cfg.VersionExtraction; e != nil {
+		switch e.Source {
+		case config.VersionSourceContainerImage:
+			return findContainerImageVersion(manifests, e)
+		case config.VersionSourceHelmChart:
+			return findHelmChartVersion(cfg.Input.HelmChart, repoDir, e)
+		}
+	}
+
 	for _, m := range manifests {
 		if !m.Key.IsDeployment() {
 			continue
@@ -441,3 +574,43 @@ func determineVersion(manifests []provider.Manifest) (string, error) {
 	}
 	return versionUnknown, nil
 }
+
+// findContainerImageVersion returns the version extracted from the image tag
+// of the container configured in the given rule.
+func findContainerImageVersion(manifests []provider.Manifest, e *config.VersionExtraction) (string, error) {
+	for _, m := range manifests {
+		image, ok := provider.FindContainerImage(m, e.ContainerName)
+		if !ok {
+			continue
+		}
+		_, tag := provider.ParseContainerImage(image)
+		return e.ExtractFrom(tag)
+	}
+	return "", fmt.Errorf("container %s was not found in the manifests", e.ContainerName)
+}
+
+// findHelmChartVersion returns the version extracted from the configured Helm chart.
+// The version of a local chart is read from its Chart.yaml file.
+func findHelmChartVersion(chart *config.InputHelmChart, repoDir string, e *config.VersionExtraction) (string, error) {
+	if chart == nil {
+		return "", fmt.Errorf("helm chart is not configured in the input")
+	}
+	if chart.Version != "" {
+		return e.ExtractFrom(chart.Version)
+	}
+	if chart.GitRemote != "" || chart.Path == "" {
+		return "", fmt.Errorf("version of the helm chart is not specified")
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(repoDir, chart.Path, "Chart.yaml"))
+	if err != nil {
+		return "", err
+	}
+	var meta struct {
+		Version string `json:"version"`
+	}
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return "", err
+	}
+	return e.ExtractFrom(meta.Version)
+}
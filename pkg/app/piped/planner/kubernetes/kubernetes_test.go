@@ -1,6 +1,8 @@
 package kubernetes
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -119,6 +121,23 @@ func TestDecideStrategy(t *testing.T) {
 			wantProgressive: true,
 			wantDesc:        "Sync progressively because pod template of workload name-2 was changed",
 		},
+		{
+			name: "mutilple workloads: images were changed",
+			olds: []provider.Manifest{
+				makeWorkload("name-1", "gcr.io/pipecd/foo:v0.1.0", "envoyproxy/envoy:v1.18.3"),
+				makeWorkload("name-2", "gcr.io/pipecd/bar:v0.1.0"),
+			},
+			news: []provider.Manifest{
+				makeWorkload("name-1", "gcr.io/pipecd/foo:v0.2.0", "envoyproxy/envoy:v1.19.0"),
+				makeWorkload("name-2", "gcr.io/pipecd/bar:v0.2.0"),
+			},
+			workloadRefs: []config.K8sResourceReference{
+				{Kind: provider.KindDeployment, Name: "name-1"},
+				{Kind: provider.KindDeployment, Name: "name-2"},
+			},
+			wantProgressive: true,
+			wantDesc:        "Sync progressively because of updating image foo from v0.1.0 to v0.2.0, image envoy from v1.18.3 to v1.19.0, image bar from v0.1.0 to v0.2.0",
+		},
 		{
 			name: "changed deployment was not the target",
 			olds: func() []provider.Manifest {
@@ -395,3 +414,177 @@ func TestDecideStrategy(t *testing.T) {
 		})
 	}
 }
+
+type fakeImageVerifier struct {
+	signed   map[string]bool
+	verified []string
+}
+
+func (v *fakeImageVerifier) VerifyImage(_ context.Context, image string) error {
+	v.verified = append(v.verified, image)
+	if !v.signed[image] {
+		return fmt.Errorf("no signature")
+	}
+	return nil
+}
+
+func TestVerifyImageSignatures(t *testing.T) {
+	makeDeployment := func(name, image string) provider.Manifest {
+		return provider.MakeManifest(provider.ResourceKey{
+			APIVersion: "apps/v1",
+			Kind:       provider.KindDeployment,
+			Name:       name,
+		}, &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"containers": []interface{}{
+								map[string]interface{}{"name": "app", "image": image},
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+	manifests := []provider.Manifest{
+		makeDeployment("foo", "gcr.io/pipecd/foo:v1"),
+		makeDeployment("foo-canary", "gcr.io/pipecd/foo:v1"),
+		makeDeployment("bar", "gcr.io/pipecd/bar:v1"),
+	}
+
+	v := &fakeImageVerifier{
+		signed: map[string]bool{
+			"gcr.io/pipecd/foo:v1": true,
+			"gcr.io/pipecd/bar:v1": true,
+		},
+	}
+	err := verifyImageSignatures(context.Background(), v, manifests)
+	assert.NoError(t, err)
+	// Each image is verified only once.
+	assert.Equal(t, []string{"gcr.io/pipecd/foo:v1", "gcr.io/pipecd/bar:v1"}, v.verified)
+
+	v = &fakeImageVerifier{
+		signed: map[string]bool{
+			"gcr.io/pipecd/foo:v1": true,
+		},
+	}
+	err = verifyImageSignatures(context.Background(), v, manifests)
+	assert.Error(t, err)
+}
+
+func TestDetermineVersion(t *testing.T) {
+	manifests := []provider.Manifest{
+		provider.MakeManifest(provider.ResourceKey{
+			APIVersion: "apps/v1",
+			Kind:       provider.KindDeployment,
+			Name:       "foo",
+		}, &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"containers": []interface{}{
+								map[string]interface{}{"name": "envoy", "image": "envoyproxy/envoy:v1.18.3"},
+								map[string]interface{}{"name": "app", "image": "gcr.io/pipecd/foo:v0.2.0"},
+							},
+						},
+					},
+				},
+			},
+		}),
+	}
+	testcases := []struct {
+		name     string
+		cfg      config.KubernetesDeploymentSpec
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "first container by default",
+			expected: "v1.18.3",
+		},
+		{
+			name: "named container",
+			cfg: config.KubernetesDeploymentSpec{
+				GenericDeploymentSpec: config.GenericDeploymentSpec{
+					VersionExtraction: &config.VersionExtraction{
+						Source:        config.VersionSourceContainerImage,
+						ContainerName: "app",
+						Pattern:       `^v(.+)$`,
+					},
+				},
+			},
+			expected: "0.2.0",
+		},
+		{
+			name: "missing container",
+			cfg: config.KubernetesDeploymentSpec{
+				GenericDeploymentSpec: config.GenericDeploymentSpec{
+					VersionExtraction: &config.VersionExtraction{
+						Source:        config.VersionSourceContainerImage,
+						ContainerName: "worker",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "helm chart version",
+			cfg: config.KubernetesDeploymentSpec{
+				GenericDeploymentSpec: config.GenericDeploymentSpec{
+					VersionExtraction: &config.VersionExtraction{
+						Source: config.VersionSourceHelmChart,
+					},
+				},
+				Input: config.KubernetesDeploymentInput{
+					HelmChart: &config.InputHelmChart{
+						Repository: "pipecd",
+						Name:       "helloworld",
+						Version:    "1.2.0",
+					},
+				},
+			},
+			expected: "1.2.0",
+		},
+		{
+			name: "helm chart not configured",
+			cfg: config.KubernetesDeploymentSpec{
+				GenericDeploymentSpec: config.GenericDeploymentSpec{
+					VersionExtraction: &config.VersionExtraction{
+						Source: config.VersionSourceHelmChart,
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := determineVersion(manifests, &tc.cfg, "")
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func makeWorkload(name string, images ...string) provider.Manifest {
+	containers := make([]interface{}, 0, len(images))
+	for _, image := range images {
+		containers = append(containers, map[string]interface{}{"image": image})
+	}
+	return provider.MakeManifest(provider.ResourceKey{
+		APIVersion: "apps/v1",
+		Kind:       provider.KindDeployment,
+		Name:       name,
+	}, &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{"containers": containers},
+				},
+			},
+		},
+	})
+}
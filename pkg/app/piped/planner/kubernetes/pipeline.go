@@ -72,7 +72,7 @@ func buildQuickSyncPipeline(autoRollback bool, now time.Time) []*model.PipelineS
 	return out
 }
 
-func buildProgressivePipeline(pp *config.DeploymentPipeline, autoRollback bool, now time.Time) []*model.PipelineStage {
+func buildProgressivePipeline(pp *config.DeploymentPipeline, autoRollback, cleanVariants bool, now time.Time) []*model.PipelineStage {
 	var (
 		preStageID = ""
 		out        = make([]*model.PipelineStage, 0, len(pp.Stages))
@@ -113,7 +113,33 @@ func buildProgressivePipeline(pp *config.DeploymentPipeline, autoRollback bool,
 			CreatedAt:  now.Unix(),
 			UpdatedAt:  now.Unix(),
 		})
+	} else if cleanVariants && hasVariantStage(pp) {
+		// Without rolling back, the variants must still be cleaned
+		// to not leave them receiving traffic after the deployment was cancelled or failed.
+		s, _ := planner.GetPredefinedStage(planner.PredefinedStageVariantClean)
+		out = append(out, &model.PipelineStage{
+			Id:         s.Id,
+			Name:       s.Name.String(),
+			Desc:       s.Desc,
+			Predefined: true,
+			Visible:    false,
+			Status:     model.StageStatus_STAGE_NOT_STARTED_YET,
+			CreatedAt:  now.Unix(),
+			UpdatedAt:  now.Unix(),
+		})
 	}
 
 	return out
 }
+
+// hasVariantStage reports whether the pipeline creates CANARY, BASELINE variants
+// or splits the traffic to them.
+func hasVariantStage(pp *config.DeploymentPipeline) bool {
+	for _, s := range pp.Stages {
+		switch s.Name {
+		case model.StageK8sCanaryRollout, model.StageK8sBaselineRollout, model.StageK8sTrafficRouting:
+			return true
+		}
+	}
+	return false
+}
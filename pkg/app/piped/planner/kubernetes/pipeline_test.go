@@ -54,7 +54,7 @@ func TestBuildProgressivePipeline(t *testing.T) {
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			gotStages := buildProgressivePipeline(&config.DeploymentPipeline{}, tc.wantAutoRollback, time.Now())
+			gotStages := buildProgressivePipeline(&config.DeploymentPipeline{}, tc.wantAutoRollback, false, time.Now())
 			var gotAutoRollback bool
 			for _, stage := range gotStages {
 				if stage.Name == string(model.StageRollback) {
@@ -65,3 +65,68 @@ func TestBuildProgressivePipeline(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildProgressivePipelineVariantClean(t *testing.T) {
+	canaryPipeline := &config.DeploymentPipeline{
+		Stages: []config.PipelineStage{
+			{Name: model.StageK8sCanaryRollout},
+			{Name: model.StageK8sPrimaryRollout},
+			{Name: model.StageK8sCanaryClean},
+		},
+	}
+	primaryPipeline := &config.DeploymentPipeline{
+		Stages: []config.PipelineStage{
+			{Name: model.StageWaitApproval},
+			{Name: model.StageK8sPrimaryRollout},
+		},
+	}
+	tests := []struct {
+		name             string
+		pipeline         *config.DeploymentPipeline
+		autoRollback     bool
+		cleanVariants    bool
+		wantVariantClean bool
+	}{
+		{
+			name:             "rollback cleans the variants",
+			pipeline:         canaryPipeline,
+			autoRollback:     true,
+			cleanVariants:    true,
+			wantVariantClean: false,
+		},
+		{
+			name:             "no rollback",
+			pipeline:         canaryPipeline,
+			autoRollback:     false,
+			cleanVariants:    true,
+			wantVariantClean: true,
+		},
+		{
+			name:             "variants are kept",
+			pipeline:         canaryPipeline,
+			autoRollback:     false,
+			cleanVariants:    false,
+			wantVariantClean: false,
+		},
+		{
+			name:             "no variant stage",
+			pipeline:         primaryPipeline,
+			autoRollback:     false,
+			cleanVariants:    true,
+			wantVariantClean: false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotStages := buildProgressivePipeline(tc.pipeline, tc.autoRollback, tc.cleanVariants, time.Now())
+			var gotVariantClean bool
+			for _, stage := range gotStages {
+				if stage.Name == string(model.StageK8sVariantClean) {
+					gotVariantClean = true
+					assert.False(t, stage.Visible)
+				}
+			}
+			assert.Equal(t, tc.wantVariantClean, gotVariantClean)
+		})
+	}
+}
@@ -121,5 +121,5 @@ func determineVersion(appDir, functionManifestFile string) (string, error) {
 		return "", err
 	}
 
-	return provider.FindImageTag(fm)
+	return provider.FindArtifactVersion(fm)
 }
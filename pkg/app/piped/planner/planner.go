@@ -39,6 +39,7 @@ type Planner interface {
 type Input struct {
 	ApplicationID                  string
 	ApplicationName                string
+	CloudProvider                  string
 	GitPath                        model.ApplicationGitPath
 	Trigger                        model.DeploymentTrigger
 	MostRecentSuccessfulCommitHash string
@@ -55,15 +56,27 @@ type Output struct {
 	SyncStrategy model.SyncStrategy
 	Summary      string
 	Stages       []*model.PipelineStage
+	// The versions of all artifacts such as container images
+	// with their running versions.
+	// Empty means the planner did not determine them.
+	Versions []*model.ArtifactVersion
+	// The human-readable diff between the running manifests
+	// and the manifests this deployment is going to apply.
+	// Empty means the planner did not compute it.
+	ManifestDiff string
 }
 
 // MakeInitialStageMetadata makes the initial metadata for the given state configuration.
 func MakeInitialStageMetadata(cfg config.PipelineStage) map[string]string {
 	switch cfg.Name {
 	case model.StageWaitApproval:
-		return map[string]string{
+		metadata := map[string]string{
 			"Approvers": strings.Join(cfg.WaitApprovalStageOptions.Approvers, ","),
 		}
+		if cfg.WaitApprovalStageOptions.RequireComment {
+			metadata[model.StageMetadataKeyApprovalCommentRequired] = "true"
+		}
+		return metadata
 	default:
 		return nil
 	}
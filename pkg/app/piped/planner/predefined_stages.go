@@ -25,7 +25,13 @@ const (
 	PredefinedStageCloudRunSync  = "CloudRunSync"
 	PredefinedStageLambdaSync    = "LambdaSync"
 	PredefinedStageECSSync       = "ECSSync"
+	PredefinedStageVMSync        = "VMSync"
+	PredefinedStagePulumiSync    = "PulumiSync"
+	PredefinedStageAnsibleSync   = "AnsibleSync"
 	PredefinedStageRollback      = "Rollback"
+	PredefinedStageVariantClean  = "VariantClean"
+	PredefinedStageK8sDryRun     = "K8sDryRun"
+	PredefinedStageTerraformPlan = "TerraformPlan"
 )
 
 var predefinedStages = map[string]config.PipelineStage{
@@ -54,11 +60,41 @@ var predefinedStages = map[string]config.PipelineStage{
 		Name: model.StageECSSync,
 		Desc: "Deploy the new version and configure all traffic to it",
 	},
+	PredefinedStageVMSync: {
+		Id:   PredefinedStageVMSync,
+		Name: model.StageVMSync,
+		Desc: "Replace all instances with the new image in a rolling manner",
+	},
+	PredefinedStagePulumiSync: {
+		Id:   PredefinedStagePulumiSync,
+		Name: model.StagePulumiSync,
+		Desc: "Sync by automatically applying any detected changes",
+	},
+	PredefinedStageAnsibleSync: {
+		Id:   PredefinedStageAnsibleSync,
+		Name: model.StageAnsibleSync,
+		Desc: "Sync by running the playbook against all hosts of the inventory",
+	},
 	PredefinedStageRollback: {
 		Id:   PredefinedStageRollback,
 		Name: model.StageRollback,
 		Desc: "Rollback the deployment",
 	},
+	PredefinedStageVariantClean: {
+		Id:   PredefinedStageVariantClean,
+		Name: model.StageK8sVariantClean,
+		Desc: "Route all traffic back to primary and remove canary, baseline variants",
+	},
+	PredefinedStageK8sDryRun: {
+		Id:   PredefinedStageK8sDryRun,
+		Name: model.StageK8sDryRun,
+		Desc: "Verify all manifests by a server-side dry-run apply",
+	},
+	PredefinedStageTerraformPlan: {
+		Id:   PredefinedStageTerraformPlan,
+		Name: model.StageTerraformPlan,
+		Desc: "Show the changes that would be applied",
+	},
 }
 
 // GetPredefinedStage finds and returns the predefined stage for the given id.
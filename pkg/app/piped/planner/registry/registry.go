@@ -19,11 +19,14 @@ import (
 	"sync"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner"
+	"github.com/pipe-cd/pipe/pkg/app/piped/planner/ansible"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner/cloudrun"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner/ecs"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner/kubernetes"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner/lambda"
+	"github.com/pipe-cd/pipe/pkg/app/piped/planner/pulumi"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner/terraform"
+	"github.com/pipe-cd/pipe/pkg/app/piped/planner/vm"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
@@ -73,4 +76,7 @@ func init() {
 	lambda.Register(defaultRegistry)
 	terraform.Register(defaultRegistry)
 	ecs.Register(defaultRegistry)
+	vm.Register(defaultRegistry)
+	pulumi.Register(defaultRegistry)
+	ansible.Register(defaultRegistry)
 }
@@ -0,0 +1,63 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// ReadVersionFile returns the version extracted from the file
+// specified in the given rule. The path to the file is relative to the application directory,
+// and the file is never read when it is outside of the application directory, even through symlinks.
+func ReadVersionFile(appDir string, e *config.VersionExtraction) (string, error) {
+	path, err := resolveInDir(appDir, e.File)
+	if err != nil {
+		return "", fmt.Errorf("invalid version file %s (%w)", e.File, err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read version file %s (%w)", e.File, err)
+	}
+	return e.ExtractFrom(string(data))
+}
+
+// resolveInDir returns the real path of the given relative path
+// after ensuring that it is located inside the given directory.
+func resolveInDir(dir, rel string) (string, error) {
+	if filepath.IsAbs(rel) {
+		return "", fmt.Errorf("path must be relative")
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(realDir, rel))
+	if err != nil {
+		return "", err
+	}
+	r, err := filepath.Rel(realDir, path)
+	if err != nil {
+		return "", err
+	}
+	if r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path must not be outside of the application directory")
+	}
+	return path, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestReadVersionFile(t *testing.T) {
+	appDir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(appDir, "Chart.yaml"), []byte("name: helloworld\nversion: 1.4.2\n"), 0644)
+	require.NoError(t, err)
+
+	outside := t.TempDir()
+	err = ioutil.WriteFile(filepath.Join(outside, "VERSION"), []byte("1.0.0"), 0644)
+	require.NoError(t, err)
+	err = os.Symlink(filepath.Join(outside, "VERSION"), filepath.Join(appDir, "LINKED_VERSION"))
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name     string
+		rule     config.VersionExtraction
+		expected string
+		wantErr  bool
+	}{
+		{
+			name: "matched pattern",
+			rule: config.VersionExtraction{
+				Source:  config.VersionSourceFile,
+				File:    "Chart.yaml",
+				Pattern: `(?m)^version:\s*(\S+)$`,
+			},
+			expected: "1.4.2",
+		},
+		{
+			name: "missing file",
+			rule: config.VersionExtraction{
+				Source: config.VersionSourceFile,
+				File:   "VERSION",
+			},
+			wantErr: true,
+		},
+		{
+			name: "absolute path",
+			rule: config.VersionExtraction{
+				Source: config.VersionSourceFile,
+				File:   filepath.Join(outside, "VERSION"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "outside of application directory",
+			rule: config.VersionExtraction{
+				Source: config.VersionSourceFile,
+				File:   filepath.Join("..", filepath.Base(outside), "VERSION"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "symlink to outside of application directory",
+			rule: config.VersionExtraction{
+				Source: config.VersionSourceFile,
+				File:   "LINKED_VERSION",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ReadVersionFile(appDir, &tc.rule)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planpreview
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	ansibleprovider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/ansible"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func (b *builder) ansibleDiff(
+	ctx context.Context,
+	app *model.Application,
+	cmd model.Command_BuildPlanPreview,
+	lastSuccessfulCommit string,
+	buf *bytes.Buffer,
+) (string, error) {
+
+	cp, ok := b.pipedCfg.FindCloudProvider(app.CloudProvider, model.CloudProviderAnsible)
+	if !ok {
+		err := fmt.Errorf("cloud provider %s was not found in Piped config", app.CloudProvider)
+		fmt.Fprintln(buf, err.Error())
+		return "", err
+	}
+
+	repoCfg := config.PipedRepository{
+		RepoID: b.repoCfg.RepoID,
+		Remote: b.repoCfg.Remote,
+		Branch: cmd.HeadBranch,
+	}
+
+	// Running the playbook in check mode still executes some code of the pull request
+	// on the target hosts, so it is done only when the last deployed configuration opted in.
+	if lastSuccessfulCommit == "" {
+		err := fmt.Errorf("plan-preview for ansible application requires enablePlanPreview to be set in the deployed configuration but no deployment has been done yet")
+		fmt.Fprintln(buf, err.Error())
+		return "", err
+	}
+	runningDSP := deploysource.NewProvider(
+		b.workingDir,
+		repoCfg,
+		"running",
+		lastSuccessfulCommit,
+		b.gitClient,
+		app.GitPath,
+		b.secretDecrypter,
+	)
+	runningDS, err := runningDSP.GetReadOnly(ctx, io.Discard)
+	if err != nil {
+		fmt.Fprintf(buf, "failed to prepare deploy source data at the running commit (%v)\n", err)
+		return "", err
+	}
+	if spec := runningDS.DeploymentConfig.AnsibleDeploymentSpec; spec == nil || !spec.Input.EnablePlanPreview {
+		err := fmt.Errorf("plan-preview is disabled for this application, set enablePlanPreview in the deployed configuration to enable it")
+		fmt.Fprintln(buf, err.Error())
+		return "", err
+	}
+
+	targetDSP := deploysource.NewProvider(
+		b.workingDir,
+		repoCfg,
+		"target",
+		cmd.HeadCommit,
+		b.gitClient,
+		app.GitPath,
+		b.secretDecrypter,
+	)
+
+	ds, err := targetDSP.Get(ctx, io.Discard)
+	if err != nil {
+		fmt.Fprintf(buf, "failed to prepare deploy source data at the head commit (%v)\n", err)
+		return "", err
+	}
+
+	deployCfg := ds.DeploymentConfig.AnsibleDeploymentSpec
+	if deployCfg == nil {
+		err := fmt.Errorf("missing Ansible spec field in deployment configuration")
+		fmt.Fprintln(buf, err.Error())
+		return "", err
+	}
+
+	playbookPath, err := ansibleprovider.FindPlaybookCommand(cp.AnsibleConfig)
+	if err != nil {
+		fmt.Fprintf(buf, "unable to find ansible-playbook command (%v)\n", err)
+		return "", err
+	}
+
+	executor := ansibleprovider.NewAnsible(
+		playbookPath,
+		ds.AppDir,
+		deployCfg.Input.Playbook,
+		deployCfg.Input.Inventory,
+		ansibleprovider.WithExtraVars(deployCfg.Input.ExtraVars),
+		ansibleprovider.WithLimit(deployCfg.Input.Limit),
+		ansibleprovider.WithTags(deployCfg.Input.Tags),
+		ansibleprovider.WithCloudProviderConfig(cp.AnsibleConfig),
+	)
+
+	result, err := executor.Check(ctx, buf)
+	if err != nil {
+		fmt.Fprintf(buf, "failed while running playbook in check mode (%v)\n", err)
+		return "", err
+	}
+
+	if result.NoChanges() {
+		fmt.Fprintln(buf, "No changes were detected")
+		return "No changes were detected", nil
+	}
+
+	summary := result.String()
+	fmt.Fprintln(buf, summary)
+	return summary, nil
+}
@@ -20,10 +20,15 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
@@ -32,6 +37,7 @@ import (
 	"github.com/pipe-cd/pipe/pkg/app/piped/trigger"
 	"github.com/pipe-cd/pipe/pkg/cache"
 	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/git"
 	"github.com/pipe-cd/pipe/pkg/model"
 	"github.com/pipe-cd/pipe/pkg/regexpool"
 )
@@ -60,12 +66,17 @@ type builder struct {
 	commitGetter      lastTriggeredCommitGetter
 	secretDecrypter   secretDecrypter
 	appManifestsCache cache.Cache
+	resultCache       cache.Cache
 	regexPool         *regexpool.Pool
 	pipedCfg          *config.PipedSpec
+	appWorkerNum      int
+	appTimeout        time.Duration
 	logger            *zap.Logger
 
 	workingDir string
 	repoCfg    config.PipedRepository
+	// Map from application ID to the key of its cached result.
+	resultKeys map[string]string
 }
 
 func newBuilder(
@@ -76,8 +87,11 @@ func newBuilder(
 	cg lastTriggeredCommitGetter,
 	sd secretDecrypter,
 	amc cache.Cache,
+	rc cache.Cache,
 	rp *regexpool.Pool,
 	cfg *config.PipedSpec,
+	appWorkerNum int,
+	appTimeout time.Duration,
 	logger *zap.Logger,
 ) *builder {
 
@@ -89,8 +103,11 @@ func newBuilder(
 		commitGetter:      cg,
 		secretDecrypter:   sd,
 		appManifestsCache: amc,
+		resultCache:       rc,
 		regexPool:         rp,
 		pipedCfg:          cfg,
+		appWorkerNum:      appWorkerNum,
+		appTimeout:        appTimeout,
 		logger:            logger.Named("plan-preview-builder"),
 	}
 }
@@ -132,7 +149,23 @@ func (b *builder) Build(ctx context.Context, id string, cmd model.Command_BuildP
 	results := failedResults
 
 	// Plan the trigger applications for more detailed feedback.
-	for _, app := range triggerApps {
+	results = append(results, b.buildApps(ctx, triggerApps, func(ctx context.Context, app *model.Application, envName string) *model.ApplicationPlanPreviewResult {
+		return b.buildApp(ctx, app, envName, cmd)
+	})...)
+
+	return results, nil
+}
+
+// buildApps builds the results of the given applications concurrently
+// by at most appWorkerNum goroutines. Each application is given its own timeout
+// so that a slow one does not prevent reporting the results of the others.
+func (b *builder) buildApps(ctx context.Context, apps []*model.Application, build func(context.Context, *model.Application, string) *model.ApplicationPlanPreviewResult) []*model.ApplicationPlanPreviewResult {
+	var (
+		results = make([]*model.ApplicationPlanPreviewResult, len(apps))
+		slots   = make(chan struct{}, b.appWorkerNum)
+		wg      sync.WaitGroup
+	)
+	for i, app := range apps {
 		// We only need the environment name
 		// so the returned error can be ignorable.
 		var envName string
@@ -140,60 +173,186 @@ func (b *builder) Build(ctx context.Context, id string, cmd model.Command_BuildP
 			envName = env.Name
 		}
 
-		r := model.MakeApplicationPlanPreviewResult(*app, envName)
-		results = append(results, r)
+		wg.Add(1)
+		go func(i int, app *model.Application, envName string) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				r := model.MakeApplicationPlanPreviewResult(*app, envName)
+				r.Error = fmt.Sprintf("canceled before starting to build, %v", ctx.Err())
+				results[i] = r
+				return
+			}
 
-		var preCommit string
-		// Find the commit of the last successful deployment.
-		if deploy, err := b.getMostRecentlySuccessfulDeployment(ctx, app.Id); err == nil {
-			preCommit = deploy.Trigger.Commit.Hash
-		} else if status.Code(err) != codes.NotFound {
-			r.Error = fmt.Sprintf("failed while finding the last successful deployment (%w)", err)
-			continue
-		}
+			appCtx, cancel := context.WithTimeout(ctx, b.appTimeout)
+			defer cancel()
+
+			// The result is received via a channel to not wait for
+			// the building which does not respect the canceled context.
+			doneCh := make(chan *model.ApplicationPlanPreviewResult, 1)
+			go func() {
+				doneCh <- build(appCtx, app, envName)
+			}()
+
+			select {
+			case r := <-doneCh:
+				results[i] = r
+			case <-appCtx.Done():
+				r := model.MakeApplicationPlanPreviewResult(*app, envName)
+				r.Error = fmt.Sprintf("failed to build in time, %v", appCtx.Err())
+				results[i] = r
+			}
+		}(i, app, envName)
+	}
+	wg.Wait()
+	return results
+}
 
-		b.logger.Info("will decide sync strategy for a application",
-			zap.String("id", app.Id),
-			zap.String("name", app.Name),
-			zap.String("kind", app.Kind.String()),
-		)
+// buildApp plans the given application and calculates its diff.
+// The returned result contains the error which occurred while building.
+func (b *builder) buildApp(ctx context.Context, app *model.Application, envName string, cmd model.Command_BuildPlanPreview) *model.ApplicationPlanPreviewResult {
+	r := model.MakeApplicationPlanPreviewResult(*app, envName)
+
+	var preCommit string
+	// Find the commit of the last successful deployment.
+	if deploy, err := b.getMostRecentlySuccessfulDeployment(ctx, app.Id); err == nil {
+		preCommit = deploy.Trigger.Commit.Hash
+	} else if status.Code(err) != codes.NotFound {
+		r.Error = fmt.Sprintf("failed while finding the last successful deployment (%v)", err)
+		return r
+	}
 
-		strategy, err := b.plan(ctx, app, cmd, preCommit)
-		if err != nil {
-			r.Error = fmt.Sprintf("failed while planning, %v", err)
-			continue
+	// The result is reused when neither the application nor the running commit was changed.
+	var cacheKey string
+	if key := b.resultKeys[app.Id]; key != "" {
+		cacheKey = fmt.Sprintf("%s/running=%s", key, preCommit)
+		if item, err := b.resultCache.Get(cacheKey); err == nil {
+			b.logger.Info("using the cached plan-preview result", zap.String("id", app.Id))
+			cached := proto.Clone(item.(*model.ApplicationPlanPreviewResult)).(*model.ApplicationPlanPreviewResult)
+			cached.EnvName = envName
+			cached.CreatedAt = r.CreatedAt
+			return cached
 		}
-		r.SyncStrategy = strategy
+	}
 
-		b.logger.Info("successfully decided sync strategy for a application",
-			zap.String("id", app.Id),
-			zap.String("name", app.Name),
-			zap.String("strategy", strategy.String()),
-			zap.String("kind", app.Kind.String()),
-		)
+	b.logger.Info("will decide sync strategy for a application",
+		zap.String("id", app.Id),
+		zap.String("name", app.Name),
+		zap.String("kind", app.Kind.String()),
+	)
 
-		var buf bytes.Buffer
-		var summary string
-
-		switch app.Kind {
-		case model.ApplicationKind_KUBERNETES:
-			summary, err = b.kubernetesDiff(ctx, app, cmd, preCommit, &buf)
-		case model.ApplicationKind_TERRAFORM:
-			summary, err = b.terraformDiff(ctx, app, cmd, &buf)
-		default:
-			// TODO: Calculating planpreview's diff for other application kinds.
-			err = fmt.Errorf("%s application is not implemented yet (coming soon)", app.Kind.String())
-		}
+	strategy, err := b.plan(ctx, app, cmd, preCommit)
+	if err != nil {
+		r.Error = fmt.Sprintf("failed while planning, %v", err)
+		return r
+	}
+	r.SyncStrategy = strategy
+
+	b.logger.Info("successfully decided sync strategy for a application",
+		zap.String("id", app.Id),
+		zap.String("name", app.Name),
+		zap.String("strategy", strategy.String()),
+		zap.String("kind", app.Kind.String()),
+	)
+
+	var buf bytes.Buffer
+	var summary string
+
+	switch app.Kind {
+	case model.ApplicationKind_KUBERNETES:
+		summary, err = b.kubernetesDiff(ctx, app, cmd, preCommit, &buf)
+	case model.ApplicationKind_TERRAFORM:
+		summary, err = b.terraformDiff(ctx, app, cmd, &buf)
+	case model.ApplicationKind_VM:
+		summary, err = b.vmDiff(ctx, app, cmd, preCommit, &buf)
+	case model.ApplicationKind_PULUMI:
+		summary, err = b.pulumiDiff(ctx, app, cmd, &buf)
+	case model.ApplicationKind_ANSIBLE:
+		summary, err = b.ansibleDiff(ctx, app, cmd, preCommit, &buf)
+	default:
+		// TODO: Calculating planpreview's diff for other application kinds.
+		err = fmt.Errorf("%s application is not implemented yet (coming soon)", app.Kind.String())
+	}
+
+	r.PlanSummary = []byte(summary)
+	r.PlanDetails = buf.Bytes()
+	if err != nil {
+		r.Error = fmt.Sprintf("failed while calculating diff, %v", err)
+		return r
+	}
+
+	if cacheKey != "" {
+		b.resultCache.Put(cacheKey, proto.Clone(r))
+	}
+	return r
+}
+
+// resultCacheKey returns the key built from the hashes of the git trees
+// the given application depends on at the given commit, which are
+// the application directory, the shared configuration directory and the trigger paths.
+// Empty means the result should not be cached.
+func resultCacheKey(ctx context.Context, repo git.Repo, commit string, app *model.Application) string {
+	cfg, err := config.LoadApplication(repo.GetPath(), app.GitPath.GetDeploymentConfigFilePath())
+	if err != nil {
+		return ""
+	}
+	if dependsOnExternalState(cfg) {
+		return ""
+	}
+	spec, ok := cfg.GetGenericDeployment()
+	if !ok {
+		return ""
+	}
 
-		r.PlanSummary = []byte(summary)
-		r.PlanDetails = buf.Bytes()
+	paths := []string{app.GitPath.Path, config.SharedConfigurationDirName}
+	for _, p := range spec.TriggerPaths {
+		paths = append(paths, staticPathPrefix(p))
+	}
+
+	parts := []string{app.Id}
+	for _, p := range paths {
+		hash, err := repo.GetCommitHashForRev(ctx, fmt.Sprintf("%s:%s", commit, p))
 		if err != nil {
-			r.Error = fmt.Sprintf("failed while calculating diff, %v", err)
-			continue
+			// The path does not exist at the given commit.
+			hash = "none"
 		}
+		parts = append(parts, fmt.Sprintf("%s=%s", p, hash))
 	}
+	return strings.Join(parts, "/")
+}
 
-	return results, nil
+// dependsOnExternalState reports whether the plan-preview result of the given application
+// also depends on state outside of the git repository, such as the Terraform state
+// or a Helm chart pulled from a remote repository.
+func dependsOnExternalState(cfg *config.Config) bool {
+	switch cfg.Kind {
+	case config.KindTerraformApp, config.KindPulumiApp, config.KindAnsibleApp:
+		return true
+	case config.KindKubernetesApp:
+		if cfg.KubernetesDeploymentSpec == nil {
+			return false
+		}
+		chart := cfg.KubernetesDeploymentSpec.Input.HelmChart
+		return chart != nil && (chart.GitRemote != "" || chart.Repository != "")
+	}
+	return false
+}
+
+// staticPathPrefix returns the deepest path containing
+// all files matched by the given pattern.
+// Empty means the root of the repository.
+func staticPathPrefix(pattern string) string {
+	i := strings.IndexAny(pattern, "*?[")
+	if i < 0 {
+		return strings.TrimSuffix(pattern, "/")
+	}
+	dir := path.Dir(pattern[:i])
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
 }
 
 func (b *builder) findTriggerApps(ctx context.Context, apps []*model.Application, cmd model.Command_BuildPlanPreview) (triggerApps []*model.Application, failedResults []*model.ApplicationPlanPreviewResult, err error) {
@@ -216,6 +375,7 @@ func (b *builder) findTriggerApps(ctx context.Context, apps []*model.Application
 		return
 	}
 
+	b.resultKeys = make(map[string]string, len(apps))
 	d := trigger.NewDeterminer(repo, cmd.HeadCommit, b.commitGetter, b.logger)
 	for _, app := range apps {
 		shouldTrigger, err := d.ShouldTrigger(ctx, app)
@@ -235,6 +395,9 @@ func (b *builder) findTriggerApps(ctx context.Context, apps []*model.Application
 
 		if shouldTrigger {
 			triggerApps = append(triggerApps, app)
+			if b.resultCache != nil {
+				b.resultKeys[app.Id] = resultCacheKey(ctx, repo, cmd.HeadCommit, app)
+			}
 		}
 	}
 	return
@@ -250,6 +413,7 @@ func (b *builder) plan(ctx context.Context, app *model.Application, cmd model.Co
 	in := planner.Input{
 		ApplicationID:   app.Id,
 		ApplicationName: app.Name,
+		CloudProvider:   app.CloudProvider,
 		GitPath:         *app.GitPath,
 		Trigger: model.DeploymentTrigger{
 			Commit: &model.Commit{
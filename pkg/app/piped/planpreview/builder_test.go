@@ -13,3 +13,170 @@
 // limitations under the License.
 
 package planpreview
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type testEnvironmentGetter struct{}
+
+func (g testEnvironmentGetter) Get(ctx context.Context, id string) (*model.Environment, error) {
+	return &model.Environment{Id: id, Name: id + "-name"}, nil
+}
+
+func TestBuildApps(t *testing.T) {
+	b := &builder{
+		environmentGetter: testEnvironmentGetter{},
+		appWorkerNum:      2,
+		appTimeout:        100 * time.Millisecond,
+		logger:            zap.NewNop(),
+	}
+	apps := []*model.Application{
+		{Id: "app-1", EnvId: "env-1", GitPath: &model.ApplicationGitPath{}},
+		{Id: "app-2", EnvId: "env-1", GitPath: &model.ApplicationGitPath{}},
+		{Id: "slow-app", EnvId: "env-2", GitPath: &model.ApplicationGitPath{}},
+		{Id: "app-3", EnvId: "env-2", GitPath: &model.ApplicationGitPath{}},
+	}
+
+	var running, maxRunning int32
+	results := b.buildApps(context.Background(), apps, func(ctx context.Context, app *model.Application, envName string) *model.ApplicationPlanPreviewResult {
+		if app.Id == "slow-app" {
+			// Ignore the canceled context to simulate a stuck tool.
+			time.Sleep(500 * time.Millisecond)
+			return model.MakeApplicationPlanPreviewResult(*app, envName)
+		}
+
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		r := model.MakeApplicationPlanPreviewResult(*app, envName)
+		r.SyncStrategy = model.SyncStrategy_QUICK_SYNC
+		return r
+	})
+
+	assert.LessOrEqual(t, maxRunning, int32(2))
+	assert.Len(t, results, len(apps))
+	for i, r := range results {
+		assert.Equal(t, apps[i].Id, r.ApplicationId)
+		assert.Equal(t, apps[i].EnvId+"-name", r.EnvName)
+		if apps[i].Id == "slow-app" {
+			assert.NotEmpty(t, r.Error)
+			continue
+		}
+		assert.Empty(t, r.Error)
+		assert.Equal(t, model.SyncStrategy_QUICK_SYNC, r.SyncStrategy)
+	}
+}
+
+func TestStaticPathPrefix(t *testing.T) {
+	testcases := []struct {
+		pattern  string
+		expected string
+	}{
+		{
+			pattern:  "charts/helloworld",
+			expected: "charts/helloworld",
+		},
+		{
+			pattern:  "charts/helloworld/",
+			expected: "charts/helloworld",
+		},
+		{
+			pattern:  "charts/helloworld/*.yaml",
+			expected: "charts/helloworld",
+		},
+		{
+			pattern:  "charts/hello*/values.yaml",
+			expected: "charts",
+		},
+		{
+			pattern:  "**/values.yaml",
+			expected: "",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			assert.Equal(t, tc.expected, staticPathPrefix(tc.pattern))
+		})
+	}
+}
+
+func TestDependsOnExternalState(t *testing.T) {
+	testcases := []struct {
+		name     string
+		cfg      *config.Config
+		expected bool
+	}{
+		{
+			name: "terraform application",
+			cfg: &config.Config{
+				Kind: config.KindTerraformApp,
+			},
+			expected: true,
+		},
+		{
+			name: "kubernetes application with local manifests",
+			cfg: &config.Config{
+				Kind:                     config.KindKubernetesApp,
+				KubernetesDeploymentSpec: &config.KubernetesDeploymentSpec{},
+			},
+			expected: false,
+		},
+		{
+			name: "kubernetes application with local helm chart",
+			cfg: &config.Config{
+				Kind: config.KindKubernetesApp,
+				KubernetesDeploymentSpec: &config.KubernetesDeploymentSpec{
+					Input: config.KubernetesDeploymentInput{
+						HelmChart: &config.InputHelmChart{Path: "charts/helloworld"},
+					},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "kubernetes application with helm chart from chart repository",
+			cfg: &config.Config{
+				Kind: config.KindKubernetesApp,
+				KubernetesDeploymentSpec: &config.KubernetesDeploymentSpec{
+					Input: config.KubernetesDeploymentInput{
+						HelmChart: &config.InputHelmChart{Repository: "pipecd", Name: "helloworld"},
+					},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "kubernetes application with helm chart from remote git",
+			cfg: &config.Config{
+				Kind: config.KindKubernetesApp,
+				KubernetesDeploymentSpec: &config.KubernetesDeploymentSpec{
+					Input: config.KubernetesDeploymentInput{
+						HelmChart: &config.InputHelmChart{GitRemote: "git@github.com:org/chart-repo.git", Path: "helloworld"},
+					},
+				},
+			},
+			expected: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, dependsOnExternalState(tc.cfg))
+		})
+	}
+}
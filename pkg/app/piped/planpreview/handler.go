@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -26,6 +27,7 @@ import (
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
 	metrics "github.com/pipe-cd/pipe/pkg/app/piped/planpreview/planpreviewmetrics"
 	"github.com/pipe-cd/pipe/pkg/cache"
+	"github.com/pipe-cd/pipe/pkg/cache/memorycache"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/git"
 	"github.com/pipe-cd/pipe/pkg/model"
@@ -37,6 +39,9 @@ const (
 	defaultCommandQueueBufferSize = 10
 	defaultCommandCheckInterval   = 5 * time.Second
 	defaultCommandHandleTimeout   = 5 * time.Minute
+	defaultAppWorkerNum           = 5
+	defaultAppHandleTimeout       = 3 * time.Minute
+	defaultResultCacheSize        = 1000
 )
 
 type options struct {
@@ -44,6 +49,8 @@ type options struct {
 	commandQueueBufferSize int
 	commandCheckInterval   time.Duration
 	commandHandleTimeout   time.Duration
+	appWorkerNum           int
+	appHandleTimeout       time.Duration
 	logger                 *zap.Logger
 }
 
@@ -73,6 +80,21 @@ func WithCommandHandleTimeout(t time.Duration) Option {
 	}
 }
 
+// WithAppWorkerNum sets the maximum number of applications
+// can be built at the same time while handling a command.
+func WithAppWorkerNum(n int) Option {
+	return func(opts *options) {
+		opts.appWorkerNum = n
+	}
+}
+
+// WithAppHandleTimeout sets the maximum duration for building each application.
+func WithAppHandleTimeout(t time.Duration) Option {
+	return func(opts *options) {
+		opts.appHandleTimeout = t
+	}
+}
+
 func WithLogger(l *zap.Logger) Option {
 	return func(opts *options) {
 		opts.logger = l
@@ -113,6 +135,13 @@ type Handler struct {
 	options        *options
 	builderFactory func() Builder
 	logger         *zap.Logger
+
+	// The piped configuration used by the new builders.
+	pipedConfig *config.PipedSpec
+	// The results of the applications which can be reused by the later commands.
+	// This is reset when the configuration was changed.
+	resultCache   cache.Cache
+	pipedConfigMu sync.RWMutex
 }
 
 func NewHandler(
@@ -133,6 +162,8 @@ func NewHandler(
 		commandQueueBufferSize: defaultCommandQueueBufferSize,
 		commandCheckInterval:   defaultCommandCheckInterval,
 		commandHandleTimeout:   defaultCommandHandleTimeout,
+		appWorkerNum:           defaultAppWorkerNum,
+		appHandleTimeout:       defaultAppHandleTimeout,
 		logger:                 zap.NewNop(),
 	}
 	for _, o := range opts {
@@ -146,16 +177,40 @@ func NewHandler(
 		prevCommands:  map[string]struct{}{},
 		options:       opt,
 		logger:        opt.logger.Named("plan-preview-handler"),
+		pipedConfig:   cfg,
+		resultCache:   newResultCache(),
 	}
 
 	regexPool := regexpool.DefaultPool()
 	h.builderFactory = func() Builder {
-		return newBuilder(gc, ac, al, eg, cg, sd, appManifestsCache, regexPool, cfg, h.logger)
+		h.pipedConfigMu.RLock()
+		cfg, rc := h.pipedConfig, h.resultCache
+		h.pipedConfigMu.RUnlock()
+		return newBuilder(gc, ac, al, eg, cg, sd, appManifestsCache, rc, regexPool, cfg, opt.appWorkerNum, opt.appHandleTimeout, h.logger)
 	}
 
 	return h
 }
 
+// ReloadConfig makes the commands handled from now use the given configuration.
+func (h *Handler) ReloadConfig(cfg *config.PipedSpec) error {
+	h.pipedConfigMu.Lock()
+	defer h.pipedConfigMu.Unlock()
+	h.pipedConfig = cfg
+	// The cached results may be changed by the new configuration.
+	h.resultCache = newResultCache()
+	return nil
+}
+
+func newResultCache() cache.Cache {
+	c, err := memorycache.NewLRUCache(defaultResultCacheSize)
+	if err != nil {
+		// Building without cache is still possible.
+		return nil
+	}
+	return c
+}
+
 // Run starts running Handler until the given context has done.
 func (h *Handler) Run(ctx context.Context) error {
 	h.logger.Info("start running planpreview handler")
@@ -165,8 +220,7 @@ func (h *Handler) Run(ctx context.Context) error {
 		for {
 			select {
 			case cmd := <-cmdCh:
-				childCtx, _ := context.WithTimeout(ctx, h.options.commandHandleTimeout)
-				h.handleCommand(childCtx, cmd)
+				h.handleCommand(ctx, cmd)
 
 			case <-ctx.Done():
 				h.logger.Info("a worker has been stopped")
@@ -274,8 +328,13 @@ func (h *Handler) handleCommand(ctx context.Context, cmd model.ReportableCommand
 		return
 	}
 
+	// The results are reported with the parent context
+	// even when the building was timed out.
+	buildCtx, cancel := context.WithTimeout(ctx, h.options.commandHandleTimeout)
+	defer cancel()
+
 	b := h.builderFactory()
-	appResults, err := b.Build(ctx, cmd.Id, *cmd.BuildPlanPreview)
+	appResults, err := b.Build(buildCtx, cmd.Id, *cmd.BuildPlanPreview)
 	if err != nil {
 		reportError(err)
 		return
@@ -41,6 +41,12 @@ func (b *builder) kubernetesDiff(
 	var oldManifests, newManifests []provider.Manifest
 	var err error
 
+	// The manifests are rendered by the tool versions configured for the cluster of the application.
+	var cluster *config.CloudProviderKubernetesConfig
+	if cp, ok := b.pipedCfg.FindCloudProvider(app.CloudProvider, model.CloudProviderKubernetes); ok {
+		cluster = cp.KubernetesConfig
+	}
+
 	repoCfg := config.PipedRepository{
 		RepoID: b.repoCfg.RepoID,
 		Remote: b.repoCfg.Remote,
@@ -56,7 +62,7 @@ func (b *builder) kubernetesDiff(
 		app.GitPath,
 		b.secretDecrypter,
 	)
-	newManifests, err = loadKubernetesManifests(ctx, *app, cmd.HeadCommit, targetDSP, b.appManifestsCache, b.logger)
+	newManifests, err = loadKubernetesManifests(ctx, *app, cmd.HeadCommit, targetDSP, cluster, b.appManifestsCache, b.logger)
 	if err != nil {
 		fmt.Fprintf(buf, "failed to load kubernetes manifests at the head commit (%v)\n", err)
 		return "", err
@@ -72,7 +78,7 @@ func (b *builder) kubernetesDiff(
 			app.GitPath,
 			b.secretDecrypter,
 		)
-		oldManifests, err = loadKubernetesManifests(ctx, *app, lastSuccessfulCommit, runningDSP, b.appManifestsCache, b.logger)
+		oldManifests, err = loadKubernetesManifests(ctx, *app, lastSuccessfulCommit, runningDSP, cluster, b.appManifestsCache, b.logger)
 		if err != nil {
 			fmt.Fprintf(buf, "failed to load kubernetes manifests at the running commit (%v)\n", err)
 			return "", err
@@ -84,6 +90,9 @@ func (b *builder) kubernetesDiff(
 		newManifests,
 		diff.WithEquateEmpty(),
 		diff.WithCompareNumberAndNumericString(),
+		diff.WithCompareBoolAndBoolString(),
+		diff.WithIgnoreListOrder(),
+		diff.WithEquateDefaults(),
 	)
 	if err != nil {
 		fmt.Fprintf(buf, "failed to compare manifests (%v)\n", err)
@@ -96,12 +105,28 @@ func (b *builder) kubernetesDiff(
 	}
 
 	summary := fmt.Sprintf("%d added manifests, %d changed manifests, %d deleted manifests", len(result.Adds), len(result.Changes), len(result.Deletes))
-	fmt.Fprintf(buf, "--- Last Deploy\n+++ Head Commit\n\n%s\n", result.DiffString())
+
+	// All images are listed to know what will be running after the deployment
+	// while only the changed ones are included in the summary.
+	versions := provider.FindImageVersions(oldManifests, newManifests)
+	changed := make([]*model.ArtifactVersion, 0, len(versions))
+	for _, v := range versions {
+		if v.Version != v.RunningVersion {
+			changed = append(changed, v)
+		}
+	}
+	if len(changed) > 0 {
+		summary = fmt.Sprintf("%s, updating %s", summary, model.ArtifactVersionsText(changed, ", "))
+	}
+	if len(versions) > 0 {
+		fmt.Fprintf(buf, "Images:\n  %s\n\n", model.ArtifactVersionsText(versions, "\n  "))
+	}
+	fmt.Fprintf(buf, "--- Last Deploy\n+++ Head Commit\n\n%s\n", result.DiffString(diff.WithMaskValuePatterns(b.pipedCfg.DiffMaskRegexps()...)))
 
 	return summary, nil
 }
 
-func loadKubernetesManifests(ctx context.Context, app model.Application, commit string, dsp deploysource.Provider, manifestsCache cache.Cache, logger *zap.Logger) (manifests []provider.Manifest, err error) {
+func loadKubernetesManifests(ctx context.Context, app model.Application, commit string, dsp deploysource.Provider, cluster *config.CloudProviderKubernetesConfig, manifestsCache cache.Cache, logger *zap.Logger) (manifests []provider.Manifest, err error) {
 	cache := provider.AppManifestsCache{
 		AppID:  app.Id,
 		Cache:  manifestsCache,
@@ -129,6 +154,7 @@ func loadKubernetesManifests(ctx context.Context, app model.Application, commit
 		ds.RepoDir,
 		app.GitPath.ConfigFilename,
 		deployCfg.Input,
+		cluster,
 		logger,
 	)
 	manifests, err = loader.LoadManifests(ctx)
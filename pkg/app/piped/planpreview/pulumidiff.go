@@ -0,0 +1,123 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planpreview
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	pulumiprovider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/pulumi"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func (b *builder) pulumiDiff(
+	ctx context.Context,
+	app *model.Application,
+	cmd model.Command_BuildPlanPreview,
+	buf *bytes.Buffer,
+) (string, error) {
+
+	cp, ok := b.pipedCfg.FindCloudProvider(app.CloudProvider, model.CloudProviderPulumi)
+	if !ok {
+		err := fmt.Errorf("cloud provider %s was not found in Piped config", app.CloudProvider)
+		fmt.Fprintln(buf, err.Error())
+		return "", err
+	}
+
+	repoCfg := config.PipedRepository{
+		RepoID: b.repoCfg.RepoID,
+		Remote: b.repoCfg.Remote,
+		Branch: cmd.HeadBranch,
+	}
+
+	targetDSP := deploysource.NewProvider(
+		b.workingDir,
+		repoCfg,
+		"target",
+		cmd.HeadCommit,
+		b.gitClient,
+		app.GitPath,
+		b.secretDecrypter,
+	)
+
+	ds, err := targetDSP.Get(ctx, io.Discard)
+	if err != nil {
+		fmt.Fprintf(buf, "failed to prepare deploy source data at the head commit (%v)\n", err)
+		return "", err
+	}
+
+	deployCfg := ds.DeploymentConfig.PulumiDeploymentSpec
+	if deployCfg == nil {
+		err := fmt.Errorf("missing Pulumi spec field in deployment configuration")
+		fmt.Fprintln(buf, err.Error())
+		return "", err
+	}
+
+	version := deployCfg.Input.PulumiVersion
+	pulumiPath, installed, err := toolregistry.DefaultRegistry().Pulumi(ctx, version)
+	if err != nil {
+		fmt.Fprintf(buf, "unable to find the specified pulumi version %q (%v)\n", version, err)
+		return "", err
+	}
+	if installed {
+		b.logger.Info(fmt.Sprintf("pulumi %q has just been installed to %q because of no pre-installed binary for that version", version, pulumiPath))
+	}
+
+	envs, err := pulumiprovider.EnvsFromConfig(cp.PulumiConfig)
+	if err != nil {
+		fmt.Fprintf(buf, "failed to load pulumi credentials (%v)\n", err)
+		return "", err
+	}
+	executor := pulumiprovider.NewPulumi(pulumiPath, ds.AppDir, pulumiprovider.WithEnvs(envs))
+
+	stack := deployCfg.Input.Stack
+	if err := executor.SelectStack(ctx, stack); err != nil {
+		fmt.Fprintf(buf, "failed to select stack %q (%v)\n", stack, err)
+		return "", err
+	}
+	fmt.Fprintf(buf, "selected stack %q\n", stack)
+
+	if err := executor.SetConfig(ctx, deployCfg.Input.Config); err != nil {
+		fmt.Fprintf(buf, "failed to set stack configuration (%v)\n", err)
+		return "", err
+	}
+
+	if deployCfg.Input.Refresh {
+		if err := executor.Refresh(ctx, buf); err != nil {
+			fmt.Fprintf(buf, "failed while executing pulumi refresh (%v)\n", err)
+			return "", err
+		}
+	}
+
+	result, err := executor.Preview(ctx, buf)
+	if err != nil {
+		fmt.Fprintf(buf, "failed while executing pulumi preview (%v)\n", err)
+		return "", err
+	}
+
+	if result.NoChanges() {
+		fmt.Fprintln(buf, "No changes were detected")
+		return "No changes were detected", nil
+	}
+
+	summary := result.String()
+	fmt.Fprintln(buf, summary)
+	return summary, nil
+}
@@ -19,6 +19,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"path/filepath"
 
 	terraformprovider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/terraform"
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
@@ -71,6 +72,10 @@ func (b *builder) terraformDiff(
 		return "", err
 	}
 
+	if cpCfg.Cloud != nil {
+		return b.terraformCloudDiff(ctx, cpCfg.Cloud, deployCfg.Input.Workspace, ds.RepoDir, ds.AppDir, cmd, buf)
+	}
+
 	version := deployCfg.Input.TerraformVersion
 	terraformPath, installed, err := toolregistry.DefaultRegistry().Terraform(ctx, version)
 	if err != nil {
@@ -125,3 +130,54 @@ func (b *builder) terraformDiff(
 	fmt.Fprintln(buf, summary)
 	return summary, nil
 }
+
+// terraformCloudDiff plans the changes by a speculative run on Terraform Cloud,
+// which can never be applied and does not block the other runs of the workspace.
+func (b *builder) terraformCloudDiff(
+	ctx context.Context,
+	cfg *config.TerraformCloudConfig,
+	workspace, repoDir, appDir string,
+	cmd model.Command_BuildPlanPreview,
+	buf *bytes.Buffer,
+) (string, error) {
+
+	if workspace == "" {
+		err := fmt.Errorf("missing workspace name in deployment configuration")
+		fmt.Fprintln(buf, err.Error())
+		return "", err
+	}
+
+	cloud, err := terraformprovider.NewCloudFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(buf, "failed to create terraform cloud client (%v)\n", err)
+		return "", err
+	}
+
+	workingDir, err := filepath.Rel(repoDir, appDir)
+	if err != nil {
+		fmt.Fprintf(buf, "failed to determine working directory (%v)\n", err)
+		return "", err
+	}
+
+	message := fmt.Sprintf("Plan preview of PipeCD at commit %s", cmd.HeadCommit)
+	runID, err := cloud.CreateRun(ctx, workspace, repoDir, workingDir, message, true, buf)
+	if err != nil {
+		fmt.Fprintf(buf, "failed to create speculative run (%v)\n", err)
+		return "", err
+	}
+
+	result, err := cloud.WaitPlan(ctx, runID, buf)
+	if err != nil {
+		fmt.Fprintf(buf, "failed while planning on terraform cloud (%v)\n", err)
+		return "", err
+	}
+
+	if result.NoChanges() {
+		fmt.Fprintln(buf, "No changes were detected")
+		return "No changes were detected", nil
+	}
+
+	summary := fmt.Sprintf("%d to add, %d to change, %d to destroy", result.Adds, result.Changes, result.Destroys)
+	fmt.Fprintln(buf, summary)
+	return summary, nil
+}
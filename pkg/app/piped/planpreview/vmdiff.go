@@ -0,0 +1,165 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planpreview
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/vm"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/diff"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func (b *builder) vmDiff(
+	ctx context.Context,
+	app *model.Application,
+	cmd model.Command_BuildPlanPreview,
+	lastSuccessfulCommit string,
+	buf *bytes.Buffer,
+) (string, error) {
+
+	cp, ok := b.pipedCfg.FindCloudProvider(app.CloudProvider, model.CloudProviderVM)
+	if !ok {
+		err := fmt.Errorf("cloud provider %s was not found in Piped config", app.CloudProvider)
+		fmt.Fprintln(buf, err.Error())
+		return "", err
+	}
+
+	repoCfg := config.PipedRepository{
+		RepoID: b.repoCfg.RepoID,
+		Remote: b.repoCfg.Remote,
+		Branch: cmd.HeadBranch,
+	}
+
+	targetDSP := deploysource.NewProvider(
+		b.workingDir,
+		repoCfg,
+		"target",
+		cmd.HeadCommit,
+		b.gitClient,
+		app.GitPath,
+		b.secretDecrypter,
+	)
+	newManifest, err := loadInstanceGroupManifest(ctx, targetDSP)
+	if err != nil {
+		fmt.Fprintf(buf, "failed to load instance group manifest at the head commit (%v)\n", err)
+		return "", err
+	}
+
+	var oldObj, newObj interface{}
+
+	switch cp.VMConfig.Platform {
+	case config.VMPlatformGCE:
+		// Compare the instance template the group is currently using
+		// with the one will be created for the head commit.
+		client, err := provider.DefaultRegistry().ManagedGroupClient(ctx, app.CloudProvider, cp.VMConfig, b.logger)
+		if err != nil {
+			fmt.Fprintf(buf, "failed to create VM client for the provider %s (%v)\n", app.CloudProvider, err)
+			return "", err
+		}
+		current, desired, err := client.BuildTemplate(ctx, newManifest)
+		if err != nil {
+			fmt.Fprintf(buf, "failed to build the instance template for group %s (%v)\n", newManifest.Spec.Name, err)
+			return "", err
+		}
+		oldObj, newObj = current.Properties, desired.Properties
+
+	default:
+		newObj = newManifest
+		if lastSuccessfulCommit != "" {
+			runningDSP := deploysource.NewProvider(
+				b.workingDir,
+				repoCfg,
+				"running",
+				lastSuccessfulCommit,
+				b.gitClient,
+				app.GitPath,
+				b.secretDecrypter,
+			)
+			oldManifest, err := loadInstanceGroupManifest(ctx, runningDSP)
+			if err != nil {
+				fmt.Fprintf(buf, "failed to load instance group manifest at the running commit (%v)\n", err)
+				return "", err
+			}
+			oldObj = oldManifest
+		}
+	}
+
+	result, err := diffObjects(oldObj, newObj)
+	if err != nil {
+		fmt.Fprintf(buf, "failed to compare instance groups (%v)\n", err)
+		return "", err
+	}
+
+	if !result.HasDiff() {
+		fmt.Fprintln(buf, "No changes were detected")
+		return "No changes were detected", nil
+	}
+
+	summary := fmt.Sprintf("%d changed fields of instance group %s", result.NumNodes(), newManifest.Spec.Name)
+	fmt.Fprintf(buf, "--- Last Deploy\n+++ Head Commit\n\n%s\n", diff.NewRenderer().Render(result.Nodes()))
+
+	return summary, nil
+}
+
+func loadInstanceGroupManifest(ctx context.Context, dsp deploysource.Provider) (provider.InstanceGroupManifest, error) {
+	ds, err := dsp.Get(ctx, io.Discard)
+	if err != nil {
+		return provider.InstanceGroupManifest{}, err
+	}
+
+	deployCfg := ds.DeploymentConfig.VMDeploymentSpec
+	if deployCfg == nil {
+		return provider.InstanceGroupManifest{}, fmt.Errorf("malformed deployment configuration file")
+	}
+	return provider.LoadInstanceGroupManifest(ds.AppDir, deployCfg.Input.InstanceGroupManifestFile)
+}
+
+// diffObjects compares the given objects through their JSON representations.
+// A nil object is treated as an empty one.
+func diffObjects(x, y interface{}) (*diff.Result, error) {
+	toUnstructured := func(obj interface{}) (unstructured.Unstructured, error) {
+		u := unstructured.Unstructured{Object: map[string]interface{}{}}
+		if obj == nil {
+			return u, nil
+		}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return u, err
+		}
+		if err := json.Unmarshal(data, &u.Object); err != nil {
+			return u, err
+		}
+		return u, nil
+	}
+
+	ux, err := toUnstructured(x)
+	if err != nil {
+		return nil, err
+	}
+	uy, err := toUnstructured(y)
+	if err != nil {
+		return nil, err
+	}
+	return diff.DiffUnstructureds(ux, uy, diff.WithEquateEmpty())
+}
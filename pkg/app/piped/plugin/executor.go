@@ -0,0 +1,114 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"io"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/plugin/pluginapi"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// Executor forwards the execution of a stage to the plugin providing it.
+type Executor struct {
+	executor.Input
+	client pluginapi.ExecutorPluginServiceClient
+}
+
+// Execute asks the plugin to execute the stage and records
+// all logs, metadata and results streamed back from it.
+func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
+	var (
+		ctx            = sig.Context()
+		originalStatus = e.Stage.Status
+	)
+
+	ds, err := e.TargetDSP.GetReadOnly(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	req := &pluginapi.ExecuteStageRequest{
+		Stage:        e.Stage,
+		StageOptions: e.StageConfig.PluginStageOptions,
+		Deployment:   e.Deployment,
+		Application:  e.Application,
+		EnvName:      e.EnvName,
+		TargetAppDir: ds.AppDir,
+	}
+	if e.Deployment.RunningCommitHash != "" {
+		runningDS, err := e.RunningDSP.GetReadOnly(ctx, e.LogPersister)
+		if err != nil {
+			e.LogPersister.Errorf("Failed to prepare running deploy source data (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		req.RunningAppDir = runningDS.AppDir
+	}
+	if metadata, ok := e.MetadataStore.GetStageMetadata(e.Stage.Id); ok {
+		req.StageMetadata = metadata
+	}
+
+	stream, err := e.client.ExecuteStage(ctx, req)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to start executing %s stage by plugin (%v)", e.Stage.Name, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	status := model.StageStatus_STAGE_FAILURE
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				e.LogPersister.Errorf("Failed while executing %s stage by plugin (%v)", e.Stage.Name, err)
+			}
+			break
+		}
+
+		switch r := resp.Response.(type) {
+		case *pluginapi.ExecuteStageResponse_Log_:
+			e.persistLog(r.Log)
+		case *pluginapi.ExecuteStageResponse_StageMetadata_:
+			if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, r.StageMetadata.Metadata); err != nil {
+				e.Logger.Error("failed to store metadata", zap.Error(err))
+			}
+		case *pluginapi.ExecuteStageResponse_StageResults_:
+			if err := e.MetadataStore.SetStageResults(ctx, e.Stage.Id, r.StageResults.Results); err != nil {
+				e.Logger.Error("failed to store stage results", zap.Error(err))
+			}
+		case *pluginapi.ExecuteStageResponse_Completed_:
+			status = r.Completed.Status
+		}
+	}
+
+	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+}
+
+func (e *Executor) persistLog(log *pluginapi.ExecuteStageResponse_Log) {
+	switch log.Severity {
+	case pluginapi.ExecuteStageResponse_Log_SUCCESS:
+		e.LogPersister.Success(log.Message)
+	case pluginapi.ExecuteStageResponse_Log_ERROR:
+		e.LogPersister.Error(log.Message)
+	default:
+		e.LogPersister.Info(log.Message)
+	}
+}
@@ -0,0 +1,404 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plugin provides a way to run the custom stages and analysis providers
+// provided by external plugin binaries.
+// Custom cloud providers are not supported by plugins.
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/plugin/pluginapi"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	startTimeout = 30 * time.Second
+	stopTimeout  = 10 * time.Second
+
+	// The interval between the restarts of a crashed plugin
+	// is doubled from the minimum up to the maximum.
+	minRestartInterval = time.Second
+	maxRestartInterval = time.Minute
+)
+
+// plugin represents a running plugin process.
+// It implements the plugin service clients by forwarding the calls to
+// the current connection, so the callers keep working across restarts.
+type plugin struct {
+	cfg      config.PipedPlugin
+	socket   string
+	version  string
+	stages   []model.Stage
+	analysis bool
+
+	mu     sync.RWMutex
+	cmd    *toolexec.Cmd
+	client pluginapi.Client
+	exited chan struct{}
+	logger *zap.Logger
+}
+
+// Manager starts and holds the connections to all configured plugins.
+// The plugins exited unexpectedly are restarted until the manager is stopped.
+type Manager struct {
+	socketDir string
+	plugins   []*plugin
+	stages    map[model.Stage]*plugin
+	done      chan struct{}
+	stopOnce  sync.Once
+	wg        sync.WaitGroup
+	logger    *zap.Logger
+}
+
+// NewManager starts all given plugins and collects the stages provided by them.
+// All already started plugins are stopped if any of them could not be started.
+func NewManager(ctx context.Context, cfgs []config.PipedPlugin, logger *zap.Logger) (*Manager, error) {
+	dir, err := ioutil.TempDir("", "piped-plugins")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket directory for plugins: %w", err)
+	}
+	m := &Manager{
+		socketDir: dir,
+		plugins:   make([]*plugin, 0, len(cfgs)),
+		stages:    make(map[model.Stage]*plugin),
+		done:      make(chan struct{}),
+		logger:    logger.Named("plugin-manager"),
+	}
+
+	for _, cfg := range cfgs {
+		p := &plugin{
+			cfg:    cfg,
+			socket: filepath.Join(m.socketDir, cfg.Name+".sock"),
+			logger: m.logger.With(zap.String("plugin", cfg.Name)),
+		}
+		if err := p.start(ctx); err != nil {
+			m.Stop()
+			return nil, fmt.Errorf("failed to start plugin %s: %w", cfg.Name, err)
+		}
+		m.plugins = append(m.plugins, p)
+
+		for _, s := range p.stages {
+			if other, ok := m.stages[s]; ok {
+				m.Stop()
+				return nil, fmt.Errorf("stage %s is provided by both plugin %s and %s", s, other.cfg.Name, p.cfg.Name)
+			}
+			m.stages[s] = p
+		}
+		m.logger.Info(fmt.Sprintf("plugin %s (%s) has been started", p.cfg.Name, p.version),
+			zap.Any("stages", p.stages),
+			zap.Bool("analysis-provider", p.analysis),
+		)
+	}
+
+	for _, p := range m.plugins {
+		m.wg.Add(1)
+		go m.supervise(p)
+	}
+	return m, nil
+}
+
+// supervise restarts the given plugin whenever its process exits
+// until the manager is stopped.
+func (m *Manager) supervise(p *plugin) {
+	defer m.wg.Done()
+
+	interval := minRestartInterval
+	for {
+		p.mu.RLock()
+		exited := p.exited
+		p.mu.RUnlock()
+
+		select {
+		case <-m.done:
+			return
+		case <-exited:
+		}
+		p.disconnect()
+
+		for {
+			p.logger.Warn(fmt.Sprintf("plugin %s exited unexpectedly, restarting it in %v", p.cfg.Name, interval))
+			select {
+			case <-m.done:
+				return
+			case <-time.After(interval):
+			}
+
+			err := p.restart(m.done)
+			if err == nil {
+				p.logger.Info(fmt.Sprintf("plugin %s has been restarted", p.cfg.Name))
+				interval = minRestartInterval
+				break
+			}
+			if err == errManagerStopped {
+				return
+			}
+			p.logger.Error(fmt.Sprintf("failed to restart plugin %s", p.cfg.Name), zap.Error(err))
+			if interval *= 2; interval > maxRestartInterval {
+				interval = maxRestartInterval
+			}
+		}
+	}
+}
+
+var errManagerStopped = errors.New("plugin manager was stopped")
+
+// start starts the plugin process for the first time
+// and collects the stages and analysis provider provided by it.
+func (p *plugin) start(ctx context.Context) error {
+	info, err := p.launch(ctx)
+	if err != nil {
+		return err
+	}
+	p.version = info.Version
+	p.analysis = info.AnalysisProvider
+	if len(info.Stages) == 0 && !info.AnalysisProvider {
+		p.stop()
+		return fmt.Errorf("the plugin provides neither stages nor analysis provider")
+	}
+	for _, s := range info.Stages {
+		stage := model.Stage(s)
+		if !stage.IsPlugin() {
+			p.stop()
+			return fmt.Errorf("stage %s must be prefixed with %s", s, model.PluginStagePrefix)
+		}
+		p.stages = append(p.stages, stage)
+	}
+	return nil
+}
+
+// restart starts the exited plugin process again.
+// The stages registered at the first start are kept since they were already
+// registered to the executor registry, so the changes of them are only logged.
+func (p *plugin) restart(done <-chan struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	info, err := p.launch(ctx)
+	if err != nil {
+		select {
+		case <-done:
+			return errManagerStopped
+		default:
+			return err
+		}
+	}
+	if info.Version != p.version {
+		p.logger.Warn(fmt.Sprintf("plugin %s was restarted with version %s but the stages of version %s are still used until piped restarts", p.cfg.Name, info.Version, p.version))
+	}
+	return nil
+}
+
+// launch starts the plugin process, connects to it and returns its information.
+func (p *plugin) launch(ctx context.Context) (*pluginapi.GetPluginInfoResponse, error) {
+	// Remove the socket left by the previous process.
+	if err := os.Remove(p.socket); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove the old socket: %w", err)
+	}
+
+	cmd := toolexec.ProcessContext(context.Background(), p.cfg.Path, p.cfg.Args...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", pluginapi.SocketEnv, p.socket))
+	for k, v := range p.cfg.Envs {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			p.logger.Warn(fmt.Sprintf("plugin %s exited", p.cfg.Name), zap.Error(err))
+		}
+		close(exited)
+	}()
+
+	p.mu.Lock()
+	p.cmd, p.client, p.exited = cmd, nil, exited
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+
+	client, err := pluginapi.NewClient(ctx, p.socket)
+	if err != nil {
+		p.stop()
+		return nil, fmt.Errorf("unable to connect to the plugin: %w", err)
+	}
+
+	info, err := client.GetPluginInfo(ctx, &pluginapi.GetPluginInfoRequest{})
+	if err != nil {
+		client.Close()
+		p.stop()
+		return nil, fmt.Errorf("unable to get the plugin information: %w", err)
+	}
+
+	p.mu.Lock()
+	p.client = client
+	p.mu.Unlock()
+	return info, nil
+}
+
+// Stages returns the list of all stages provided by the started plugins.
+func (m *Manager) Stages() []model.Stage {
+	stages := make([]model.Stage, 0, len(m.stages))
+	for s := range m.stages {
+		stages = append(stages, s)
+	}
+	sort.Slice(stages, func(i, j int) bool {
+		return stages[i] < stages[j]
+	})
+	return stages
+}
+
+// ExecutorFactory returns the factory of executors forwarding the given stage
+// to the plugin providing it.
+func (m *Manager) ExecutorFactory(stage model.Stage) (executor.Factory, bool) {
+	p, ok := m.stages[stage]
+	if !ok {
+		return nil, false
+	}
+	f := func(in executor.Input) executor.Executor {
+		return &Executor{
+			Input:  in,
+			client: p,
+		}
+	}
+	return f, true
+}
+
+// AnalysisClients returns the clients of all started plugins
+// providing a custom analysis provider, keyed by the plugin name.
+func (m *Manager) AnalysisClients() map[string]pluginapi.AnalysisPluginServiceClient {
+	clients := make(map[string]pluginapi.AnalysisPluginServiceClient)
+	for _, p := range m.plugins {
+		if p.analysis {
+			clients[p.cfg.Name] = p
+		}
+	}
+	return clients
+}
+
+// Stop stops all started plugins and cleans up their sockets.
+func (m *Manager) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.done)
+	})
+	m.wg.Wait()
+
+	for _, p := range m.plugins {
+		if err := p.stop(); err != nil {
+			m.logger.Error(fmt.Sprintf("failed to stop plugin %s", p.cfg.Name), zap.Error(err))
+		}
+	}
+	if err := os.RemoveAll(m.socketDir); err != nil {
+		m.logger.Error("failed to remove socket directory of plugins", zap.Error(err))
+	}
+}
+
+// disconnect closes the connection to the exited plugin process.
+func (p *plugin) disconnect() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client != nil {
+		p.client.Close()
+		p.client = nil
+	}
+}
+
+func (p *plugin) stop() error {
+	p.disconnect()
+
+	p.mu.RLock()
+	cmd, exited := p.cmd, p.exited
+	p.mu.RUnlock()
+	if cmd == nil {
+		return nil
+	}
+
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		select {
+		case <-exited:
+			return nil
+		default:
+			return err
+		}
+	}
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(stopTimeout):
+		return cmd.Process.Kill()
+	}
+}
+
+func (p *plugin) currentClient() (pluginapi.Client, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.client == nil {
+		return nil, status.Errorf(codes.Unavailable, "plugin %s is not running", p.cfg.Name)
+	}
+	return p.client, nil
+}
+
+func (p *plugin) GetPluginInfo(ctx context.Context, in *pluginapi.GetPluginInfoRequest, opts ...grpc.CallOption) (*pluginapi.GetPluginInfoResponse, error) {
+	c, err := p.currentClient()
+	if err != nil {
+		return nil, err
+	}
+	return c.GetPluginInfo(ctx, in, opts...)
+}
+
+func (p *plugin) ExecuteStage(ctx context.Context, in *pluginapi.ExecuteStageRequest, opts ...grpc.CallOption) (pluginapi.ExecutorPluginService_ExecuteStageClient, error) {
+	c, err := p.currentClient()
+	if err != nil {
+		return nil, err
+	}
+	return c.ExecuteStage(ctx, in, opts...)
+}
+
+func (p *plugin) Evaluate(ctx context.Context, in *pluginapi.EvaluateRequest, opts ...grpc.CallOption) (*pluginapi.EvaluateResponse, error) {
+	c, err := p.currentClient()
+	if err != nil {
+		return nil, err
+	}
+	return c.Evaluate(ctx, in, opts...)
+}
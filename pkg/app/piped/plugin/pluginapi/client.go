@@ -0,0 +1,62 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginapi
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+)
+
+// SocketEnv is the name of the environment variable used to tell
+// the plugin process the path to the unix socket it must listen on.
+const SocketEnv = "PIPED_PLUGIN_SOCKET"
+
+type Client interface {
+	ExecutorPluginServiceClient
+	AnalysisPluginServiceClient
+	Close() error
+}
+
+type client struct {
+	ExecutorPluginServiceClient
+	AnalysisPluginServiceClient
+	conn *grpc.ClientConn
+}
+
+// NewClient connects to the plugin listening on the given unix socket.
+func NewClient(ctx context.Context, socket string) (Client, error) {
+	conn, err := grpc.DialContext(ctx, socket,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", addr)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &client{
+		ExecutorPluginServiceClient: NewExecutorPluginServiceClient(conn),
+		AnalysisPluginServiceClient: NewAnalysisPluginServiceClient(conn),
+		conn:                        conn,
+	}, nil
+}
+
+func (c *client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,219 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pluginsdk provides the helpers for writing a piped plugin
+// that adds custom stages to piped.
+//
+// A plugin is a standalone binary started by piped.
+// It implements the StagePlugin and/or AnalysisPlugin interface
+// and calls Serve from its main function.
+package pluginsdk
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/plugin/pluginapi"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// Plugin is the interface that must be implemented by all plugins.
+type Plugin interface {
+	// Name returns the name of the plugin.
+	Name() string
+	// Version returns the version of the plugin.
+	Version() string
+}
+
+// StagePlugin is the interface implemented by a plugin providing custom stages.
+type StagePlugin interface {
+	Plugin
+	// Stages returns the list of stages provided by the plugin.
+	// Each of them must start with "PLUGIN_".
+	Stages() []model.Stage
+	// ExecuteStage executes the given stage until completion or the context is cancelled.
+	ExecuteStage(ctx context.Context, req *pluginapi.ExecuteStageRequest, r Reporter) model.StageStatus
+}
+
+// AnalysisPlugin is the interface implemented by a plugin providing a custom analysis provider.
+type AnalysisPlugin interface {
+	Plugin
+	// Evaluate runs the given query and returns the values of all data points
+	// found in the time window between from and to.
+	// The config is the JSON encoded config specified in the piped configuration.
+	Evaluate(ctx context.Context, query string, from, to time.Time, config []byte) ([]float64, error)
+}
+
+// Reporter sends the logs, metadata and results of the executing stage back to piped.
+type Reporter interface {
+	Info(log string)
+	Infof(format string, a ...interface{})
+	Success(log string)
+	Successf(format string, a ...interface{})
+	Error(log string)
+	Errorf(format string, a ...interface{})
+	SetStageMetadata(metadata map[string]string) error
+	SetStageResults(results map[string]string) error
+}
+
+// Serve starts serving the given plugin on the unix socket specified by piped
+// and blocks until the process is asked to terminate.
+func Serve(p Plugin) error {
+	socket := os.Getenv(pluginapi.SocketEnv)
+	if socket == "" {
+		return fmt.Errorf("%s must be set, the plugin is expected to be started by piped", pluginapi.SocketEnv)
+	}
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socket, err)
+	}
+
+	server := grpc.NewServer()
+	svc := &service{plugin: p}
+	pluginapi.RegisterExecutorPluginServiceServer(server, svc)
+	pluginapi.RegisterAnalysisPluginServiceServer(server, svc)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+	return server.Serve(lis)
+}
+
+type service struct {
+	plugin Plugin
+}
+
+func (s *service) GetPluginInfo(_ context.Context, _ *pluginapi.GetPluginInfoRequest) (*pluginapi.GetPluginInfoResponse, error) {
+	resp := &pluginapi.GetPluginInfoResponse{
+		Name:    s.plugin.Name(),
+		Version: s.plugin.Version(),
+	}
+	if sp, ok := s.plugin.(StagePlugin); ok {
+		for _, st := range sp.Stages() {
+			resp.Stages = append(resp.Stages, st.String())
+		}
+	}
+	_, resp.AnalysisProvider = s.plugin.(AnalysisPlugin)
+	return resp, nil
+}
+
+func (s *service) ExecuteStage(req *pluginapi.ExecuteStageRequest, stream pluginapi.ExecutorPluginService_ExecuteStageServer) error {
+	sp, ok := s.plugin.(StagePlugin)
+	if !ok {
+		return status.Error(codes.Unimplemented, "the plugin does not provide any stage")
+	}
+	r := &reporter{stream: stream}
+	st := sp.ExecuteStage(stream.Context(), req, r)
+	return r.send(&pluginapi.ExecuteStageResponse{
+		Response: &pluginapi.ExecuteStageResponse_Completed_{
+			Completed: &pluginapi.ExecuteStageResponse_Completed{
+				Status: st,
+			},
+		},
+	})
+}
+
+func (s *service) Evaluate(ctx context.Context, req *pluginapi.EvaluateRequest) (*pluginapi.EvaluateResponse, error) {
+	ap, ok := s.plugin.(AnalysisPlugin)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the plugin does not provide analysis provider")
+	}
+	values, err := ap.Evaluate(ctx, req.Query, time.Unix(req.From, 0), time.Unix(req.To, 0), req.ProviderConfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pluginapi.EvaluateResponse{
+		Values: values,
+	}, nil
+}
+
+type reporter struct {
+	stream pluginapi.ExecutorPluginService_ExecuteStageServer
+	// Guards the stream since the plugin may report from multiple goroutines.
+	mu sync.Mutex
+}
+
+func (r *reporter) send(resp *pluginapi.ExecuteStageResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stream.Send(resp)
+}
+
+func (r *reporter) log(severity pluginapi.ExecuteStageResponse_Log_Severity, log string) {
+	r.send(&pluginapi.ExecuteStageResponse{
+		Response: &pluginapi.ExecuteStageResponse_Log_{
+			Log: &pluginapi.ExecuteStageResponse_Log{
+				Severity: severity,
+				Message:  log,
+			},
+		},
+	})
+}
+
+func (r *reporter) Info(log string) {
+	r.log(pluginapi.ExecuteStageResponse_Log_INFO, log)
+}
+
+func (r *reporter) Infof(format string, a ...interface{}) {
+	r.Info(fmt.Sprintf(format, a...))
+}
+
+func (r *reporter) Success(log string) {
+	r.log(pluginapi.ExecuteStageResponse_Log_SUCCESS, log)
+}
+
+func (r *reporter) Successf(format string, a ...interface{}) {
+	r.Success(fmt.Sprintf(format, a...))
+}
+
+func (r *reporter) Error(log string) {
+	r.log(pluginapi.ExecuteStageResponse_Log_ERROR, log)
+}
+
+func (r *reporter) Errorf(format string, a ...interface{}) {
+	r.Error(fmt.Sprintf(format, a...))
+}
+
+func (r *reporter) SetStageMetadata(metadata map[string]string) error {
+	return r.send(&pluginapi.ExecuteStageResponse{
+		Response: &pluginapi.ExecuteStageResponse_StageMetadata_{
+			StageMetadata: &pluginapi.ExecuteStageResponse_StageMetadata{
+				Metadata: metadata,
+			},
+		},
+	})
+}
+
+func (r *reporter) SetStageResults(results map[string]string) error {
+	return r.send(&pluginapi.ExecuteStageResponse{
+		Response: &pluginapi.ExecuteStageResponse_StageResults_{
+			StageResults: &pluginapi.ExecuteStageResponse_StageResults{
+				Results: results,
+			},
+		},
+	})
+}
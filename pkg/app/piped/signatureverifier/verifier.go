@@ -0,0 +1,97 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package signatureverifier verifies the signatures of the commits and
+// the container images with the keys trusted by the piped
+// to enforce the supply-chain requirements before deploying them.
+package signatureverifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolexec"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+var ErrNoTrustedKey = errors.New("no trusted key was configured in piped configuration")
+
+type Verifier struct {
+	cfg    config.PipedSignatureVerification
+	logger *zap.Logger
+}
+
+func NewVerifier(cfg config.PipedSignatureVerification, logger *zap.Logger) *Verifier {
+	return &Verifier{
+		cfg:    cfg,
+		logger: logger.Named("signature-verifier"),
+	}
+}
+
+// VerifyCommit checks that the given commit of the repository placed at repoDir
+// was signed by one of the trusted GPG or SSH keys.
+func (v *Verifier) VerifyCommit(ctx context.Context, repoDir, commit string) error {
+	cmd := toolexec.CommandContext(ctx, "git", v.verifyCommitArgs(commit)...)
+	cmd.Dir = repoDir
+	cmd.Env = os.Environ()
+	if v.cfg.GPGHome != "" {
+		cmd.Env = append(cmd.Env, "GNUPGHOME="+v.cfg.GPGHome)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("commit %s was not signed by any trusted key: %s (%w)", commit, strings.TrimSpace(string(out)), err)
+	}
+	v.logger.Info("verified commit signature", zap.String("commit", commit))
+	return nil
+}
+
+func (v *Verifier) verifyCommitArgs(commit string) []string {
+	var args []string
+	if v.cfg.SSHAllowedSignersFile != "" {
+		args = append(args, "-c", "gpg.ssh.allowedSignersFile="+v.cfg.SSHAllowedSignersFile)
+	}
+	return append(args, "verify-commit", commit)
+}
+
+// VerifyImage checks that the given image was signed by one of the trusted cosign keys.
+func (v *Verifier) VerifyImage(ctx context.Context, image string) error {
+	if len(v.cfg.CosignPublicKeyFiles) == 0 {
+		return ErrNoTrustedKey
+	}
+	path, installed, err := toolregistry.DefaultRegistry().Cosign(ctx, v.cfg.CosignVersion)
+	if err != nil {
+		return fmt.Errorf("unable to find required cosign %q (%w)", v.cfg.CosignVersion, err)
+	}
+	if installed {
+		v.logger.Info(fmt.Sprintf("cosign %q has just been installed to %q because of no pre-installed binary for that version", v.cfg.CosignVersion, path))
+	}
+
+	var errs []string
+	for _, key := range v.cfg.CosignPublicKeyFiles {
+		cmd := toolexec.CommandContext(ctx, path, "verify", "--key", key, image)
+		out, err := cmd.CombinedOutput()
+		if err == nil {
+			v.logger.Info("verified image signature", zap.String("image", image), zap.String("key", key))
+			return nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", key, strings.TrimSpace(string(out))))
+	}
+	return fmt.Errorf("image %s was not signed by any trusted key (%s)", image, strings.Join(errs, "; "))
+}
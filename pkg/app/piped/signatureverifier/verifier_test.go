@@ -0,0 +1,71 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signatureverifier
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestVerifyCommitArgs(t *testing.T) {
+	v := NewVerifier(config.PipedSignatureVerification{}, zap.NewNop())
+	assert.Equal(t, []string{"verify-commit", "abc"}, v.verifyCommitArgs("abc"))
+
+	v = NewVerifier(config.PipedSignatureVerification{
+		SSHAllowedSignersFile: "/etc/piped/allowed_signers",
+	}, zap.NewNop())
+	assert.Equal(t, []string{"-c", "gpg.ssh.allowedSignersFile=/etc/piped/allowed_signers", "verify-commit", "abc"}, v.verifyCommitArgs("abc"))
+}
+
+func TestVerifyUnsignedCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "signatureverifier")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	run := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return string(out)
+	}
+	run("init")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app.yaml"), []byte("kind: KubernetesApp"), 0644))
+	run("add", ".")
+	run("-c", "user.name=test", "-c", "user.email=test@pipecd.dev", "-c", "commit.gpgsign=false", "commit", "-m", "unsigned")
+
+	v := NewVerifier(config.PipedSignatureVerification{GPGHome: dir}, zap.NewNop())
+	err = v.VerifyCommit(context.Background(), dir, "HEAD")
+	assert.Error(t, err)
+}
+
+func TestVerifyImageWithoutKey(t *testing.T) {
+	v := NewVerifier(config.PipedSignatureVerification{}, zap.NewNop())
+	err := v.VerifyImage(context.Background(), "gcr.io/pipecd/helloworld:v0.1.0")
+	assert.Equal(t, ErrNoTrustedKey, err)
+}
@@ -26,6 +26,7 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/model"
 )
 
 type apiClient interface {
@@ -33,6 +34,10 @@ type apiClient interface {
 	ReportStat(ctx context.Context, req *pipedservice.ReportStatRequest, opts ...grpc.CallOption) (*pipedservice.ReportStatResponse, error)
 }
 
+type healthCollector interface {
+	Collect(ctx context.Context) *model.PipedHealth
+}
+
 type Reporter interface {
 	Run(ctx context.Context) error
 }
@@ -41,15 +46,19 @@ type reporter struct {
 	metricsURL string
 	httpClient *http.Client
 	apiClient  apiClient
+	health     healthCollector
 	interval   time.Duration
 	logger     *zap.Logger
 }
 
-func NewReporter(metricsURL string, apiClient apiClient, logger *zap.Logger) *reporter {
+// NewReporter creates a new stats reporter.
+// The given health collector is optional, the health is not reported when it is nil.
+func NewReporter(metricsURL string, apiClient apiClient, health healthCollector, logger *zap.Logger) *reporter {
 	return &reporter{
 		metricsURL: metricsURL,
 		httpClient: &http.Client{Timeout: 10 * time.Second},
 		apiClient:  apiClient,
+		health:     health,
 		interval:   time.Minute,
 		logger:     logger.Named("stats-reporter"),
 	}
@@ -98,6 +107,9 @@ func (r *reporter) report(ctx context.Context) error {
 	req := &pipedservice.ReportStatRequest{
 		PipedStats: b,
 	}
+	if r.health != nil {
+		req.Health = r.health.Collect(ctx)
+	}
 	if _, err := r.apiClient.ReportStat(ctx, req); err != nil {
 		r.logger.Error("failed to report stats", zap.Error(err))
 		return err
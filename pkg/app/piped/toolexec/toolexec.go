@@ -0,0 +1,294 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package toolexec runs the external tools such as kubectl, helm, terraform
+// while limiting the resources they can consume on the piped host.
+// The tools can also be run inside ephemeral containers with the pinned images
+// to isolate the untrusted repository content from the piped process.
+package toolexec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// limiter holds the configured limits shared by all tool invocations.
+type limiter struct {
+	// Buffered channel used as a semaphore.
+	// Nil means no limit on the number of concurrent processes.
+	slots       chan struct{}
+	cpuSeconds  int
+	memoryBytes int64
+	// Nil means all tools are executed on the host.
+	container *config.PipedToolContainer
+}
+
+var (
+	// Holds *limiter.
+	defaultLimiter     atomic.Value
+	defaultLimiterOnce sync.Once
+)
+
+func init() {
+	defaultLimiter.Store(&limiter{})
+}
+
+// InitDefault configures the limits applied to all tool invocations.
+// Without calling this the tools are executed without any limit.
+// Only the first call takes effect so that all tool invocations share the same slots.
+func InitDefault(cfg config.PipedToolExecution) {
+	defaultLimiterOnce.Do(func() {
+		l := &limiter{
+			cpuSeconds:  cfg.MaxCPUSeconds,
+			memoryBytes: int64(cfg.MaxMemoryMB) * 1024 * 1024,
+			container:   cfg.Container,
+		}
+		if cfg.MaxConcurrency > 0 {
+			l.slots = make(chan struct{}, cfg.MaxConcurrency)
+		}
+		defaultLimiter.Store(l)
+	})
+}
+
+// Cmd wraps exec.Cmd to wait for an available slot before running the tool.
+type Cmd struct {
+	*exec.Cmd
+	ctx     context.Context
+	limiter *limiter
+	// The image of the container the tool should be run in.
+	// Empty means the tool is executed on the host.
+	image string
+	args  []string
+	// Releases the slot acquired by Start.
+	release func()
+}
+
+// CommandContext is like exec.CommandContext but the returned command
+// will be executed under the configured resource limits.
+func CommandContext(ctx context.Context, name string, args ...string) *Cmd {
+	return defaultLimiter.Load().(*limiter).command(ctx, name, args...)
+}
+
+// ProcessContext is like CommandContext but for the processes living as long as piped, e.g. plugins.
+// They are executed under the memory limit but neither occupy a slot
+// nor are killed by the CPU time limit which is meant for the short-lived tools.
+func ProcessContext(ctx context.Context, name string, args ...string) *Cmd {
+	l := defaultLimiter.Load().(*limiter)
+	return (&limiter{memoryBytes: l.memoryBytes}).command(ctx, name, args...)
+}
+
+func (l *limiter) command(ctx context.Context, name string, args ...string) *Cmd {
+	if image, ok := l.containerImage(name); ok {
+		// The arguments are completed right before running
+		// since the working directory is not known yet.
+		return &Cmd{
+			Cmd:     exec.CommandContext(ctx, l.containerRuntime()),
+			ctx:     ctx,
+			limiter: l,
+			image:   image,
+			args:    args,
+		}
+	}
+
+	var cmd *exec.Cmd
+	// The limits are applied by the shell builtin so they are not available on Windows.
+	if prefix := l.ulimitPrefix(); prefix != "" && runtime.GOOS != "windows" {
+		// The shell applies the limits to itself and then is replaced by the tool
+		// so that the limits are inherited by the tool process.
+		script := prefix + ` && exec "$0" "$@"`
+		cmd = exec.CommandContext(ctx, "/bin/sh", append([]string{"-c", script, name}, args...)...)
+	} else {
+		cmd = exec.CommandContext(ctx, name, args...)
+	}
+	return &Cmd{
+		Cmd:     cmd,
+		ctx:     ctx,
+		limiter: l,
+	}
+}
+
+func (l *limiter) ulimitPrefix() string {
+	var limits []string
+	if l.cpuSeconds > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -t %d", l.cpuSeconds))
+	}
+	if l.memoryBytes > 0 {
+		// The value of ulimit -v is in kilobytes.
+		limits = append(limits, fmt.Sprintf("ulimit -v %d", l.memoryBytes/1024))
+	}
+	return strings.Join(limits, " && ")
+}
+
+// containerImage returns the image configured for the tool at the given path.
+// The tools installed by the registry are named as "kubectl" or "kubectl-1.18.2".
+func (l *limiter) containerImage(path string) (string, bool) {
+	if l.container == nil {
+		return "", false
+	}
+	tool := strings.SplitN(filepath.Base(path), "-", 2)[0]
+	image, ok := l.container.Images[tool]
+	return image, ok
+}
+
+func (l *limiter) containerRuntime() string {
+	if l.container.Runtime != "" {
+		return l.container.Runtime
+	}
+	return "docker"
+}
+
+// containerArgs builds the arguments of the container runtime
+// to run the tool inside an ephemeral container.
+// The given envs are the names of the variables passed from the environment of the container runtime.
+func (l *limiter) containerArgs(image, dir string, envs, toolArgs []string) []string {
+	network := l.container.Network
+	if network == "" {
+		network = "host"
+	}
+	args := []string{"run", "--rm", "-i", "--network", network}
+	if dir != "" {
+		args = append(args, "-v", dir+":"+dir, "-w", dir)
+	}
+	for _, m := range l.container.Mounts {
+		args = append(args, "-v", m+":"+m)
+	}
+	for _, e := range append(l.container.Envs, envs...) {
+		// Only the name is given to pass the value from the current environment
+		// so that the values such as credentials are not exposed in the arguments.
+		args = append(args, "-e", e)
+	}
+	if l.cpuSeconds > 0 {
+		args = append(args, "--ulimit", fmt.Sprintf("cpu=%d", l.cpuSeconds))
+	}
+	if l.memoryBytes > 0 {
+		args = append(args, "--memory", fmt.Sprintf("%d", l.memoryBytes))
+	}
+	args = append(args, image)
+	return append(args, toolArgs...)
+}
+
+// prepare completes the arguments of the command running inside a container.
+func (c *Cmd) prepare() {
+	if c.image == "" {
+		return
+	}
+	args := c.limiter.containerArgs(c.image, c.Cmd.Dir, commandEnvNames(c.Cmd.Env), c.args)
+	c.Cmd.Args = append(c.Cmd.Args[:1], args...)
+}
+
+// commandEnvNames returns the names of the variables set for the command
+// in addition to the environment of piped, e.g. GNUPGHOME or the credentials of the tool.
+// The environment of piped itself is not passed into the container.
+func commandEnvNames(env []string) []string {
+	if len(env) == 0 {
+		return nil
+	}
+	inherited := make(map[string]struct{}, len(env))
+	for _, e := range os.Environ() {
+		inherited[e] = struct{}{}
+	}
+	var (
+		names = make([]string, 0, len(env))
+		seen  = make(map[string]struct{}, len(env))
+	)
+	for _, e := range env {
+		if _, ok := inherited[e]; ok {
+			continue
+		}
+		name := strings.SplitN(e, "=", 2)[0]
+		if _, ok := seen[name]; ok || name == "" {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	return names
+}
+
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("unable to acquire a slot to run tool: %w", ctx.Err())
+	}
+}
+
+// Start starts the command once a slot is available.
+// The slot is held until Wait returns.
+func (c *Cmd) Start() error {
+	release, err := c.limiter.acquire(c.ctx)
+	if err != nil {
+		return err
+	}
+	c.prepare()
+	if err := c.Cmd.Start(); err != nil {
+		release()
+		return err
+	}
+	c.release = release
+	return nil
+}
+
+// Wait waits for the command started by Start to exit and releases its slot.
+func (c *Cmd) Wait() error {
+	err := c.Cmd.Wait()
+	if c.release != nil {
+		c.release()
+		c.release = nil
+	}
+	return err
+}
+
+// Run starts the command and waits for it to complete.
+func (c *Cmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Output runs the command and returns its standard output.
+func (c *Cmd) Output() ([]byte, error) {
+	release, err := c.limiter.acquire(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	c.prepare()
+	return c.Cmd.Output()
+}
+
+// CombinedOutput runs the command and returns its combined standard output and standard error.
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	release, err := c.limiter.acquire(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	c.prepare()
+	return c.Cmd.CombinedOutput()
+}
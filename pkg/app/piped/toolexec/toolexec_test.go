@@ -0,0 +1,148 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolexec
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestCommand(t *testing.T) {
+	testcases := []struct {
+		name         string
+		limiter      *limiter
+		expectedArgs []string
+	}{
+		{
+			name:         "no limit",
+			limiter:      &limiter{},
+			expectedArgs: []string{"kubectl", "apply", "-f", "-"},
+		},
+		{
+			name: "cpu and memory limits",
+			limiter: &limiter{
+				cpuSeconds:  60,
+				memoryBytes: 512 * 1024 * 1024,
+			},
+			expectedArgs: []string{
+				"/bin/sh",
+				"-c",
+				`ulimit -t 60 && ulimit -v 524288 && exec "$0" "$@"`,
+				"kubectl",
+				"apply",
+				"-f",
+				"-",
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := tc.limiter.command(context.Background(), "kubectl", "apply", "-f", "-")
+			assert.Equal(t, tc.expectedArgs, cmd.Args)
+		})
+	}
+}
+
+func TestContainerCommand(t *testing.T) {
+	l := &limiter{
+		container: &config.PipedToolContainer{
+			Images: map[string]string{
+				"helm": "alpine/helm:3.5.3",
+			},
+			Mounts: []string{"/etc/pipecd"},
+			Envs:   []string{"KUBECONFIG"},
+		},
+	}
+
+	cmd := l.command(context.Background(), "/tools/helm-3.5.3", "template", "app")
+	cmd.Dir = "/repo/app"
+	cmd.prepare()
+	expected := []string{
+		"docker", "run", "--rm", "-i", "--network", "host",
+		"-v", "/repo/app:/repo/app", "-w", "/repo/app",
+		"-v", "/etc/pipecd:/etc/pipecd",
+		"-e", "KUBECONFIG",
+		"alpine/helm:3.5.3",
+		"template", "app",
+	}
+	assert.Equal(t, expected, cmd.Args)
+
+	// The variables set for the command are passed into the container.
+	cmd = l.command(context.Background(), "/tools/helm-3.5.3", "version")
+	cmd.Env = append(os.Environ(), "HELM_TOKEN=secret", "GNUPGHOME=/etc/gnupg")
+	cmd.prepare()
+	expected = []string{
+		"docker", "run", "--rm", "-i", "--network", "host",
+		"-v", "/etc/pipecd:/etc/pipecd",
+		"-e", "KUBECONFIG",
+		"-e", "HELM_TOKEN",
+		"-e", "GNUPGHOME",
+		"alpine/helm:3.5.3",
+		"version",
+	}
+	assert.Equal(t, expected, cmd.Args)
+
+	// The tools without configured image are executed on the host.
+	cmd = l.command(context.Background(), "/tools/kubectl", "version")
+	cmd.prepare()
+	assert.Equal(t, []string{"/tools/kubectl", "version"}, cmd.Args)
+}
+
+func TestAcquire(t *testing.T) {
+	l := &limiter{
+		slots: make(chan struct{}, 1),
+	}
+
+	release, err := l.acquire(context.Background())
+	require.NoError(t, err)
+
+	// No more slot is available until the first one was released.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx)
+	assert.Error(t, err)
+
+	release()
+	release, err = l.acquire(context.Background())
+	require.NoError(t, err)
+	release()
+}
+
+func TestStartHoldsSlotUntilWait(t *testing.T) {
+	l := &limiter{
+		slots: make(chan struct{}, 1),
+	}
+
+	cmd := l.command(context.Background(), "true")
+	require.NoError(t, cmd.Start())
+
+	// The slot is occupied by the started command.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := l.acquire(ctx)
+	assert.Error(t, err)
+
+	require.NoError(t, cmd.Wait())
+	release, err := l.acquire(context.Background())
+	require.NoError(t, err)
+	release()
+}
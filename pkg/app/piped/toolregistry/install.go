@@ -20,7 +20,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
+	"runtime"
 	"text/template"
 
 	"go.uber.org/zap"
@@ -31,6 +31,11 @@ const (
 	defaultKustomizeVersion = "3.8.1"
 	defaultHelmVersion      = "3.2.1"
 	defaultTerraformVersion = "0.13.0"
+	defaultOpaVersion       = "0.34.2"
+	defaultCueVersion       = "0.4.0"
+	defaultTrivyVersion     = "0.20.2"
+	defaultCosignVersion    = "1.2.1"
+	defaultPulumiVersion    = "3.17.0"
 )
 
 var (
@@ -38,6 +43,11 @@ var (
 	kustomizeInstallScriptTmpl = template.Must(template.New("kustomize").Parse(kustomizeInstallScript))
 	helmInstallScriptTmpl      = template.Must(template.New("helm").Parse(helmInstallScript))
 	terraformInstallScriptTmpl = template.Must(template.New("terraform").Parse(terraformInstallScript))
+	opaInstallScriptTmpl       = template.Must(template.New("opa").Parse(opaInstallScript))
+	cueInstallScriptTmpl       = template.Must(template.New("cue").Parse(cueInstallScript))
+	trivyInstallScriptTmpl     = template.Must(template.New("trivy").Parse(trivyInstallScript))
+	cosignInstallScriptTmpl    = template.Must(template.New("cosign").Parse(cosignInstallScript))
+	pulumiInstallScriptTmpl    = template.Must(template.New("pulumi").Parse(pulumiInstallScript))
 )
 
 func (r *registry) installKubectl(ctx context.Context, version string) error {
@@ -59,6 +69,8 @@ func (r *registry) installKubectl(ctx context.Context, version string) error {
 			"Version":    version,
 			"BinDir":     r.binDir,
 			"AsDefault":  asDefault,
+			"Os":         runtime.GOOS,
+			"Arch":       runtime.GOARCH,
 		}
 	)
 	if err := kubectlInstallScriptTmpl.Execute(&buf, data); err != nil {
@@ -71,7 +83,7 @@ func (r *registry) installKubectl(ctx context.Context, version string) error {
 
 	var (
 		script = buf.String()
-		cmd    = exec.CommandContext(ctx, "/bin/sh", "-c", script)
+		cmd    = installCommand(ctx, script)
 	)
 	if out, err := cmd.CombinedOutput(); err != nil {
 		r.logger.Error("failed to install kubectl",
@@ -106,6 +118,8 @@ func (r *registry) installKustomize(ctx context.Context, version string) error {
 			"Version":    version,
 			"BinDir":     r.binDir,
 			"AsDefault":  asDefault,
+			"Os":         runtime.GOOS,
+			"Arch":       runtime.GOARCH,
 		}
 	)
 	if err := kustomizeInstallScriptTmpl.Execute(&buf, data); err != nil {
@@ -118,7 +132,7 @@ func (r *registry) installKustomize(ctx context.Context, version string) error {
 
 	var (
 		script = buf.String()
-		cmd    = exec.CommandContext(ctx, "/bin/sh", "-c", script)
+		cmd    = installCommand(ctx, script)
 	)
 	if out, err := cmd.CombinedOutput(); err != nil {
 		r.logger.Error("failed to install kustomize",
@@ -153,6 +167,8 @@ func (r *registry) installHelm(ctx context.Context, version string) error {
 			"Version":    version,
 			"BinDir":     r.binDir,
 			"AsDefault":  asDefault,
+			"Os":         runtime.GOOS,
+			"Arch":       runtime.GOARCH,
 		}
 	)
 	if err := helmInstallScriptTmpl.Execute(&buf, data); err != nil {
@@ -165,7 +181,7 @@ func (r *registry) installHelm(ctx context.Context, version string) error {
 
 	var (
 		script = buf.String()
-		cmd    = exec.CommandContext(ctx, "/bin/sh", "-c", script)
+		cmd    = installCommand(ctx, script)
 	)
 	if out, err := cmd.CombinedOutput(); err != nil {
 		r.logger.Error("failed to install helm",
@@ -200,6 +216,8 @@ func (r *registry) installTerraform(ctx context.Context, version string) error {
 			"Version":    version,
 			"BinDir":     r.binDir,
 			"AsDefault":  asDefault,
+			"Os":         runtime.GOOS,
+			"Arch":       runtime.GOARCH,
 		}
 	)
 	if err := terraformInstallScriptTmpl.Execute(&buf, data); err != nil {
@@ -212,7 +230,7 @@ func (r *registry) installTerraform(ctx context.Context, version string) error {
 
 	var (
 		script = buf.String()
-		cmd    = exec.CommandContext(ctx, "/bin/sh", "-c", script)
+		cmd    = installCommand(ctx, script)
 	)
 	if out, err := cmd.CombinedOutput(); err != nil {
 		r.logger.Error("failed to install terraform",
@@ -227,3 +245,248 @@ func (r *registry) installTerraform(ctx context.Context, version string) error {
 	r.logger.Info("just installed terraform", zap.String("version", version))
 	return nil
 }
+
+func (r *registry) installOpa(ctx context.Context, version string) error {
+	workingDir, err := ioutil.TempDir("", "opa-install")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workingDir)
+
+	asDefault := version == ""
+	if asDefault {
+		version = defaultOpaVersion
+	}
+
+	var (
+		buf  bytes.Buffer
+		data = map[string]interface{}{
+			"WorkingDir": workingDir,
+			"Version":    version,
+			"BinDir":     r.binDir,
+			"AsDefault":  asDefault,
+			"Os":         runtime.GOOS,
+			"Arch":       runtime.GOARCH,
+		}
+	)
+	if err := opaInstallScriptTmpl.Execute(&buf, data); err != nil {
+		r.logger.Error("failed to render opa install script",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install opa %s (%w)", version, err)
+	}
+
+	var (
+		script = buf.String()
+		cmd    = installCommand(ctx, script)
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		r.logger.Error("failed to install opa",
+			zap.String("version", version),
+			zap.String("script", script),
+			zap.String("out", string(out)),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install opa %s, %s (%w)", version, string(out), err)
+	}
+
+	r.logger.Info("just installed opa", zap.String("version", version))
+	return nil
+}
+
+func (r *registry) installCue(ctx context.Context, version string) error {
+	workingDir, err := ioutil.TempDir("", "cue-install")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workingDir)
+
+	asDefault := version == ""
+	if asDefault {
+		version = defaultCueVersion
+	}
+
+	var (
+		buf  bytes.Buffer
+		data = map[string]interface{}{
+			"WorkingDir": workingDir,
+			"Version":    version,
+			"BinDir":     r.binDir,
+			"AsDefault":  asDefault,
+			"Os":         runtime.GOOS,
+			"Arch":       runtime.GOARCH,
+		}
+	)
+	if err := cueInstallScriptTmpl.Execute(&buf, data); err != nil {
+		r.logger.Error("failed to render cue install script",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install cue %s (%w)", version, err)
+	}
+
+	var (
+		script = buf.String()
+		cmd    = installCommand(ctx, script)
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		r.logger.Error("failed to install cue",
+			zap.String("version", version),
+			zap.String("script", script),
+			zap.String("out", string(out)),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install cue %s, %s (%w)", version, string(out), err)
+	}
+
+	r.logger.Info("just installed cue", zap.String("version", version))
+	return nil
+}
+
+func (r *registry) installTrivy(ctx context.Context, version string) error {
+	workingDir, err := ioutil.TempDir("", "trivy-install")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workingDir)
+
+	asDefault := version == ""
+	if asDefault {
+		version = defaultTrivyVersion
+	}
+
+	var (
+		buf  bytes.Buffer
+		data = map[string]interface{}{
+			"WorkingDir": workingDir,
+			"Version":    version,
+			"BinDir":     r.binDir,
+			"AsDefault":  asDefault,
+			"Os":         runtime.GOOS,
+			"Arch":       runtime.GOARCH,
+		}
+	)
+	if err := trivyInstallScriptTmpl.Execute(&buf, data); err != nil {
+		r.logger.Error("failed to render trivy install script",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install trivy %s (%w)", version, err)
+	}
+
+	var (
+		script = buf.String()
+		cmd    = installCommand(ctx, script)
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		r.logger.Error("failed to install trivy",
+			zap.String("version", version),
+			zap.String("script", script),
+			zap.String("out", string(out)),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install trivy %s, %s (%w)", version, string(out), err)
+	}
+
+	r.logger.Info("just installed trivy", zap.String("version", version))
+	return nil
+}
+
+func (r *registry) installCosign(ctx context.Context, version string) error {
+	workingDir, err := ioutil.TempDir("", "cosign-install")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workingDir)
+
+	asDefault := version == ""
+	if asDefault {
+		version = defaultCosignVersion
+	}
+
+	var (
+		buf  bytes.Buffer
+		data = map[string]interface{}{
+			"WorkingDir": workingDir,
+			"Version":    version,
+			"BinDir":     r.binDir,
+			"AsDefault":  asDefault,
+			"Os":         runtime.GOOS,
+			"Arch":       runtime.GOARCH,
+		}
+	)
+	if err := cosignInstallScriptTmpl.Execute(&buf, data); err != nil {
+		r.logger.Error("failed to render cosign install script",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install cosign %s (%w)", version, err)
+	}
+
+	var (
+		script = buf.String()
+		cmd    = installCommand(ctx, script)
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		r.logger.Error("failed to install cosign",
+			zap.String("version", version),
+			zap.String("script", script),
+			zap.String("out", string(out)),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install cosign %s, %s (%w)", version, string(out), err)
+	}
+
+	r.logger.Info("just installed cosign", zap.String("version", version))
+	return nil
+}
+
+func (r *registry) installPulumi(ctx context.Context, version string) error {
+	workingDir, err := ioutil.TempDir("", "pulumi-install")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workingDir)
+
+	asDefault := version == ""
+	if asDefault {
+		version = defaultPulumiVersion
+	}
+
+	var (
+		buf  bytes.Buffer
+		data = map[string]interface{}{
+			"WorkingDir": workingDir,
+			"Version":    version,
+			"BinDir":     r.binDir,
+			"AsDefault":  asDefault,
+			"Os":         runtime.GOOS,
+			"Arch":       runtime.GOARCH,
+		}
+	)
+	if err := pulumiInstallScriptTmpl.Execute(&buf, data); err != nil {
+		r.logger.Error("failed to render pulumi install script",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install pulumi %s (%w)", version, err)
+	}
+
+	var (
+		script = buf.String()
+		cmd    = installCommand(ctx, script)
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		r.logger.Error("failed to install pulumi",
+			zap.String("version", version),
+			zap.String("script", script),
+			zap.String("out", string(out)),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install pulumi %s, %s (%w)", version, string(out), err)
+	}
+
+	r.logger.Info("just installed pulumi", zap.String("version", version))
+	return nil
+}
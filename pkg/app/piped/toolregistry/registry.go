@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"go.uber.org/zap"
@@ -33,6 +34,11 @@ type Registry interface {
 	Kustomize(ctx context.Context, version string) (string, bool, error)
 	Helm(ctx context.Context, version string) (string, bool, error)
 	Terraform(ctx context.Context, version string) (string, bool, error)
+	Opa(ctx context.Context, version string) (string, bool, error)
+	Cue(ctx context.Context, version string) (string, bool, error)
+	Trivy(ctx context.Context, version string) (string, bool, error)
+	Cosign(ctx context.Context, version string) (string, bool, error)
+	Pulumi(ctx context.Context, version string) (string, bool, error)
 }
 
 var defaultRegistry *registry
@@ -81,7 +87,7 @@ func loadPreinstalledTool(binDir string) (map[string]struct{}, error) {
 		if !info.Mode().IsRegular() {
 			return nil
 		}
-		name := filepath.Base(path)
+		name := strings.TrimSuffix(filepath.Base(path), binExt)
 		tools[name] = struct{}{}
 		return nil
 	})
@@ -96,6 +102,11 @@ const (
 	kustomizePrefix = "kustomize"
 	helmPrefix      = "helm"
 	terraformPrefix = "terraform"
+	opaPrefix       = "opa"
+	cuePrefix       = "cue"
+	trivyPrefix     = "trivy"
+	cosignPrefix    = "cosign"
+	pulumiPrefix    = "pulumi"
 )
 
 type registry struct {
@@ -111,7 +122,7 @@ func (r *registry) Kubectl(ctx context.Context, version string) (string, bool, e
 	if version != "" {
 		name = fmt.Sprintf("%s-%s", kubectlPrefix, version)
 	}
-	path := filepath.Join(r.binDir, name)
+	path := filepath.Join(r.binDir, name+binExt)
 
 	r.mu.RLock()
 	_, ok := r.versions[name]
@@ -139,7 +150,7 @@ func (r *registry) Kustomize(ctx context.Context, version string) (string, bool,
 	if version != "" {
 		name = fmt.Sprintf("%s-%s", kustomizePrefix, version)
 	}
-	path := filepath.Join(r.binDir, name)
+	path := filepath.Join(r.binDir, name+binExt)
 
 	r.mu.RLock()
 	_, ok := r.versions[name]
@@ -167,7 +178,7 @@ func (r *registry) Helm(ctx context.Context, version string) (string, bool, erro
 	if version != "" {
 		name = fmt.Sprintf("%s-%s", helmPrefix, version)
 	}
-	path := filepath.Join(r.binDir, name)
+	path := filepath.Join(r.binDir, name+binExt)
 
 	r.mu.RLock()
 	_, ok := r.versions[name]
@@ -195,7 +206,7 @@ func (r *registry) Terraform(ctx context.Context, version string) (string, bool,
 	if version != "" {
 		name = fmt.Sprintf("%s-%s", terraformPrefix, version)
 	}
-	path := filepath.Join(r.binDir, name)
+	path := filepath.Join(r.binDir, name+binExt)
 
 	r.mu.RLock()
 	_, ok := r.versions[name]
@@ -217,3 +228,143 @@ func (r *registry) Terraform(ctx context.Context, version string) (string, bool,
 
 	return path, true, nil
 }
+
+func (r *registry) Opa(ctx context.Context, version string) (string, bool, error) {
+	name := opaPrefix
+	if version != "" {
+		name = fmt.Sprintf("%s-%s", opaPrefix, version)
+	}
+	path := filepath.Join(r.binDir, name+binExt)
+
+	r.mu.RLock()
+	_, ok := r.versions[name]
+	r.mu.RUnlock()
+	if ok {
+		return path, false, nil
+	}
+
+	_, err, _ := r.installGroup.Do(name, func() (interface{}, error) {
+		return nil, r.installOpa(ctx, version)
+	})
+	if err != nil {
+		return "", true, err
+	}
+
+	r.mu.Lock()
+	r.versions[name] = struct{}{}
+	r.mu.Unlock()
+
+	return path, true, nil
+}
+
+func (r *registry) Cue(ctx context.Context, version string) (string, bool, error) {
+	name := cuePrefix
+	if version != "" {
+		name = fmt.Sprintf("%s-%s", cuePrefix, version)
+	}
+	path := filepath.Join(r.binDir, name+binExt)
+
+	r.mu.RLock()
+	_, ok := r.versions[name]
+	r.mu.RUnlock()
+	if ok {
+		return path, false, nil
+	}
+
+	_, err, _ := r.installGroup.Do(name, func() (interface{}, error) {
+		return nil, r.installCue(ctx, version)
+	})
+	if err != nil {
+		return "", true, err
+	}
+
+	r.mu.Lock()
+	r.versions[name] = struct{}{}
+	r.mu.Unlock()
+
+	return path, true, nil
+}
+
+func (r *registry) Trivy(ctx context.Context, version string) (string, bool, error) {
+	name := trivyPrefix
+	if version != "" {
+		name = fmt.Sprintf("%s-%s", trivyPrefix, version)
+	}
+	path := filepath.Join(r.binDir, name+binExt)
+
+	r.mu.RLock()
+	_, ok := r.versions[name]
+	r.mu.RUnlock()
+	if ok {
+		return path, false, nil
+	}
+
+	_, err, _ := r.installGroup.Do(name, func() (interface{}, error) {
+		return nil, r.installTrivy(ctx, version)
+	})
+	if err != nil {
+		return "", true, err
+	}
+
+	r.mu.Lock()
+	r.versions[name] = struct{}{}
+	r.mu.Unlock()
+
+	return path, true, nil
+}
+
+func (r *registry) Cosign(ctx context.Context, version string) (string, bool, error) {
+	name := cosignPrefix
+	if version != "" {
+		name = fmt.Sprintf("%s-%s", cosignPrefix, version)
+	}
+	path := filepath.Join(r.binDir, name+binExt)
+
+	r.mu.RLock()
+	_, ok := r.versions[name]
+	r.mu.RUnlock()
+	if ok {
+		return path, false, nil
+	}
+
+	_, err, _ := r.installGroup.Do(name, func() (interface{}, error) {
+		return nil, r.installCosign(ctx, version)
+	})
+	if err != nil {
+		return "", true, err
+	}
+
+	r.mu.Lock()
+	r.versions[name] = struct{}{}
+	r.mu.Unlock()
+
+	return path, true, nil
+}
+
+func (r *registry) Pulumi(ctx context.Context, version string) (string, bool, error) {
+	name := pulumiPrefix
+	if version != "" {
+		name = fmt.Sprintf("%s-%s", pulumiPrefix, version)
+	}
+	path := filepath.Join(r.binDir, name+binExt)
+
+	r.mu.RLock()
+	_, ok := r.versions[name]
+	r.mu.RUnlock()
+	if ok {
+		return path, false, nil
+	}
+
+	_, err, _ := r.installGroup.Do(name, func() (interface{}, error) {
+		return nil, r.installPulumi(ctx, version)
+	})
+	if err != nil {
+		return "", true, err
+	}
+
+	r.mu.Lock()
+	r.versions[name] = struct{}{}
+	r.mu.Unlock()
+
+	return path, true, nil
+}
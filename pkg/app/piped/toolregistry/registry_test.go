@@ -13,3 +13,76 @@
 // limitations under the License.
 
 package toolregistry
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPreinstalledTool(t *testing.T) {
+	binDir, err := ioutil.TempDir("", "toolregistry")
+	require.NoError(t, err)
+	defer os.RemoveAll(binDir)
+
+	for _, name := range []string{"kubectl", "helm-3.2.1"} {
+		err := ioutil.WriteFile(filepath.Join(binDir, name+binExt), []byte{}, 0755)
+		require.NoError(t, err)
+	}
+
+	tools, err := loadPreinstalledTool(binDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]struct{}{
+		"kubectl":    {},
+		"helm-3.2.1": {},
+	}, tools)
+}
+
+func TestInstallScriptPlatform(t *testing.T) {
+	data := map[string]interface{}{
+		"WorkingDir": "/tmp/work",
+		"Version":    "3.5.3",
+		"BinDir":     "/tools",
+		"Os":         "linux",
+		"Arch":       "arm64",
+	}
+	var buf bytes.Buffer
+	err := helmInstallScriptTmpl.Execute(&buf, data)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "https://get.helm.sh/helm-v3.5.3-linux-arm64")
+}
+
+func TestTrivyInstallScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("trivy is not available on windows")
+	}
+	testcases := []struct {
+		os       string
+		arch     string
+		expected string
+	}{
+		{"linux", "amd64", "trivy_0.20.2_Linux-64bit.tar.gz"},
+		{"darwin", "arm64", "trivy_0.20.2_macOS-ARM64.tar.gz"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.os+"/"+tc.arch, func(t *testing.T) {
+			data := map[string]interface{}{
+				"WorkingDir": "/tmp/work",
+				"Version":    "0.20.2",
+				"BinDir":     "/tools",
+				"Os":         tc.os,
+				"Arch":       tc.arch,
+			}
+			var buf bytes.Buffer
+			err := trivyInstallScriptTmpl.Execute(&buf, data)
+			require.NoError(t, err)
+			assert.Contains(t, buf.String(), tc.expected)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !windows
+
+package toolregistry
+
+import (
+	"context"
+	"os/exec"
+)
+
+// binExt is the extension of the executable files on this platform.
+const binExt = ""
+
+var kubectlInstallScript = `
+cd {{ .WorkingDir }}
+curl -LO https://storage.googleapis.com/kubernetes-release/release/v{{ .Version }}/bin/{{ .Os }}/{{ .Arch }}/kubectl
+mv kubectl {{ .BinDir }}/kubectl-{{ .Version }}
+chmod +x {{ .BinDir }}/kubectl-{{ .Version }}
+{{ if .AsDefault }}
+cp -f {{ .BinDir }}/kubectl-{{ .Version }} {{ .BinDir }}/kubectl
+{{ end }}
+`
+
+var kustomizeInstallScript = `
+cd {{ .WorkingDir }}
+curl -L https://github.com/kubernetes-sigs/kustomize/releases/download/kustomize/v{{ .Version }}/kustomize_v{{ .Version }}_{{ .Os }}_{{ .Arch }}.tar.gz | tar xvz
+mv kustomize {{ .BinDir }}/kustomize-{{ .Version }}
+chmod +x {{ .BinDir }}/kustomize-{{ .Version }}
+{{ if .AsDefault }}
+cp -f {{ .BinDir }}/kustomize-{{ .Version }} {{ .BinDir }}/kustomize
+{{ end }}
+`
+
+var helmInstallScript = `
+cd {{ .WorkingDir }}
+curl -L https://get.helm.sh/helm-v{{ .Version }}-{{ .Os }}-{{ .Arch }}.tar.gz | tar xvz
+mv {{ .Os }}-{{ .Arch }}/helm {{ .BinDir }}/helm-{{ .Version }}
+chmod +x {{ .BinDir }}/helm-{{ .Version }}
+{{ if .AsDefault }}
+cp -f {{ .BinDir }}/helm-{{ .Version }} {{ .BinDir }}/helm
+{{ end }}
+`
+
+var terraformInstallScript = `
+cd {{ .WorkingDir }}
+curl https://releases.hashicorp.com/terraform/{{ .Version }}/terraform_{{ .Version }}_{{ .Os }}_{{ .Arch }}.zip -o terraform_{{ .Version }}_{{ .Os }}_{{ .Arch }}.zip
+unzip terraform_{{ .Version }}_{{ .Os }}_{{ .Arch }}.zip
+mv terraform {{ .BinDir }}/terraform-{{ .Version }}
+{{ if .AsDefault }}
+cp -f {{ .BinDir }}/terraform-{{ .Version }} {{ .BinDir }}/terraform
+{{ end }}
+`
+
+var opaInstallScript = `
+cd {{ .WorkingDir }}
+curl -L https://openpolicyagent.org/downloads/v{{ .Version }}/opa_{{ .Os }}_{{ .Arch }} -o opa
+mv opa {{ .BinDir }}/opa-{{ .Version }}
+chmod +x {{ .BinDir }}/opa-{{ .Version }}
+{{ if .AsDefault }}
+cp -f {{ .BinDir }}/opa-{{ .Version }} {{ .BinDir }}/opa
+{{ end }}
+`
+
+var cueInstallScript = `
+cd {{ .WorkingDir }}
+curl -L https://github.com/cue-lang/cue/releases/download/v{{ .Version }}/cue_v{{ .Version }}_{{ .Os }}_{{ .Arch }}.tar.gz | tar xvz
+mv cue {{ .BinDir }}/cue-{{ .Version }}
+chmod +x {{ .BinDir }}/cue-{{ .Version }}
+{{ if .AsDefault }}
+cp -f {{ .BinDir }}/cue-{{ .Version }} {{ .BinDir }}/cue
+{{ end }}
+`
+
+var trivyInstallScript = `
+cd {{ .WorkingDir }}
+curl -L https://github.com/aquasecurity/trivy/releases/download/v{{ .Version }}/trivy_{{ .Version }}_{{ if eq .Os "darwin" }}macOS{{ else }}Linux{{ end }}-{{ if eq .Arch "arm64" }}ARM64{{ else }}64bit{{ end }}.tar.gz | tar xvz
+mv trivy {{ .BinDir }}/trivy-{{ .Version }}
+chmod +x {{ .BinDir }}/trivy-{{ .Version }}
+{{ if .AsDefault }}
+cp -f {{ .BinDir }}/trivy-{{ .Version }} {{ .BinDir }}/trivy
+{{ end }}
+`
+
+var cosignInstallScript = `
+cd {{ .WorkingDir }}
+curl -L https://github.com/sigstore/cosign/releases/download/v{{ .Version }}/cosign-{{ .Os }}-{{ .Arch }} -o cosign
+mv cosign {{ .BinDir }}/cosign-{{ .Version }}
+chmod +x {{ .BinDir }}/cosign-{{ .Version }}
+{{ if .AsDefault }}
+cp -f {{ .BinDir }}/cosign-{{ .Version }} {{ .BinDir }}/cosign
+{{ end }}
+`
+
+// The archive of pulumi contains the language hosts which are looked up
+// from the directory of the pulumi binary, so they are put next to it.
+var pulumiInstallScript = `
+cd {{ .WorkingDir }}
+curl -L https://get.pulumi.com/releases/sdk/pulumi-v{{ .Version }}-{{ .Os }}-{{ if eq .Arch "arm64" }}arm64{{ else }}x64{{ end }}.tar.gz | tar xvz
+mv pulumi/pulumi {{ .BinDir }}/pulumi-{{ .Version }}
+chmod +x {{ .BinDir }}/pulumi-{{ .Version }}
+cp -f pulumi/* {{ .BinDir }}/
+{{ if .AsDefault }}
+cp -f {{ .BinDir }}/pulumi-{{ .Version }} {{ .BinDir }}/pulumi
+{{ end }}
+`
+
+func installCommand(ctx context.Context, script string) *exec.Cmd {
+	return exec.CommandContext(ctx, "/bin/sh", "-c", script)
+}
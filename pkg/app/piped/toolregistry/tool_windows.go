@@ -0,0 +1,120 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistry
+
+import (
+	"context"
+	"os/exec"
+)
+
+// binExt is the extension of the executable files on this platform.
+const binExt = ".exe"
+
+var kubectlInstallScript = `
+$ErrorActionPreference = "Stop"
+cd {{ .WorkingDir }}
+Invoke-WebRequest -Uri https://storage.googleapis.com/kubernetes-release/release/v{{ .Version }}/bin/{{ .Os }}/{{ .Arch }}/kubectl.exe -OutFile kubectl.exe
+Move-Item -Force kubectl.exe {{ .BinDir }}\kubectl-{{ .Version }}.exe
+{{ if .AsDefault }}
+Copy-Item -Force {{ .BinDir }}\kubectl-{{ .Version }}.exe {{ .BinDir }}\kubectl.exe
+{{ end }}
+`
+
+var kustomizeInstallScript = `
+$ErrorActionPreference = "Stop"
+cd {{ .WorkingDir }}
+Invoke-WebRequest -Uri https://github.com/kubernetes-sigs/kustomize/releases/download/kustomize/v{{ .Version }}/kustomize_v{{ .Version }}_{{ .Os }}_{{ .Arch }}.tar.gz -OutFile kustomize.tar.gz
+tar -xzf kustomize.tar.gz
+Move-Item -Force kustomize.exe {{ .BinDir }}\kustomize-{{ .Version }}.exe
+{{ if .AsDefault }}
+Copy-Item -Force {{ .BinDir }}\kustomize-{{ .Version }}.exe {{ .BinDir }}\kustomize.exe
+{{ end }}
+`
+
+var helmInstallScript = `
+$ErrorActionPreference = "Stop"
+cd {{ .WorkingDir }}
+Invoke-WebRequest -Uri https://get.helm.sh/helm-v{{ .Version }}-{{ .Os }}-{{ .Arch }}.zip -OutFile helm.zip
+Expand-Archive -Force helm.zip .
+Move-Item -Force {{ .Os }}-{{ .Arch }}\helm.exe {{ .BinDir }}\helm-{{ .Version }}.exe
+{{ if .AsDefault }}
+Copy-Item -Force {{ .BinDir }}\helm-{{ .Version }}.exe {{ .BinDir }}\helm.exe
+{{ end }}
+`
+
+var terraformInstallScript = `
+$ErrorActionPreference = "Stop"
+cd {{ .WorkingDir }}
+Invoke-WebRequest -Uri https://releases.hashicorp.com/terraform/{{ .Version }}/terraform_{{ .Version }}_{{ .Os }}_{{ .Arch }}.zip -OutFile terraform.zip
+Expand-Archive -Force terraform.zip .
+Move-Item -Force terraform.exe {{ .BinDir }}\terraform-{{ .Version }}.exe
+{{ if .AsDefault }}
+Copy-Item -Force {{ .BinDir }}\terraform-{{ .Version }}.exe {{ .BinDir }}\terraform.exe
+{{ end }}
+`
+
+var opaInstallScript = `
+$ErrorActionPreference = "Stop"
+cd {{ .WorkingDir }}
+Invoke-WebRequest -Uri https://openpolicyagent.org/downloads/v{{ .Version }}/opa_{{ .Os }}_{{ .Arch }}.exe -OutFile opa.exe
+Move-Item -Force opa.exe {{ .BinDir }}\opa-{{ .Version }}.exe
+{{ if .AsDefault }}
+Copy-Item -Force {{ .BinDir }}\opa-{{ .Version }}.exe {{ .BinDir }}\opa.exe
+{{ end }}
+`
+
+var cueInstallScript = `
+$ErrorActionPreference = "Stop"
+cd {{ .WorkingDir }}
+Invoke-WebRequest -Uri https://github.com/cue-lang/cue/releases/download/v{{ .Version }}/cue_v{{ .Version }}_{{ .Os }}_{{ .Arch }}.zip -OutFile cue.zip
+Expand-Archive -Force cue.zip .
+Move-Item -Force cue.exe {{ .BinDir }}\cue-{{ .Version }}.exe
+{{ if .AsDefault }}
+Copy-Item -Force {{ .BinDir }}\cue-{{ .Version }}.exe {{ .BinDir }}\cue.exe
+{{ end }}
+`
+
+// Trivy does not provide any release for Windows.
+var trivyInstallScript = `
+throw "trivy {{ .Version }} is not available on windows"
+`
+
+var cosignInstallScript = `
+$ErrorActionPreference = "Stop"
+cd {{ .WorkingDir }}
+Invoke-WebRequest -Uri https://github.com/sigstore/cosign/releases/download/v{{ .Version }}/cosign-{{ .Os }}-{{ .Arch }}.exe -OutFile cosign.exe
+Move-Item -Force cosign.exe {{ .BinDir }}\cosign-{{ .Version }}.exe
+{{ if .AsDefault }}
+Copy-Item -Force {{ .BinDir }}\cosign-{{ .Version }}.exe {{ .BinDir }}\cosign.exe
+{{ end }}
+`
+
+// The archive of pulumi contains the language hosts which are looked up
+// from the directory of the pulumi binary, so they are put next to it.
+var pulumiInstallScript = `
+$ErrorActionPreference = "Stop"
+cd {{ .WorkingDir }}
+Invoke-WebRequest -Uri https://get.pulumi.com/releases/sdk/pulumi-v{{ .Version }}-windows-x64.zip -OutFile pulumi.zip
+Expand-Archive -Force pulumi.zip .
+Move-Item -Force Pulumi\bin\pulumi.exe {{ .BinDir }}\pulumi-{{ .Version }}.exe
+Copy-Item -Force Pulumi\bin\* {{ .BinDir }}
+{{ if .AsDefault }}
+Copy-Item -Force {{ .BinDir }}\pulumi-{{ .Version }}.exe {{ .BinDir }}\pulumi.exe
+{{ end }}
+`
+
+func installCommand(ctx context.Context, script string) *exec.Cmd {
+	return exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+}
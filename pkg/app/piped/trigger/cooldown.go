@@ -0,0 +1,48 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trigger
+
+import "time"
+
+// cooldownStore keeps the time when the automatic deployment of each application
+// was triggered most recently to debounce the rapid successive commits.
+// Since this is kept in memory, the cooldown is reset when piped restarted.
+type cooldownStore struct {
+	triggeredAt map[string]time.Time
+}
+
+func newCooldownStore() *cooldownStore {
+	return &cooldownStore{
+		triggeredAt: make(map[string]time.Time),
+	}
+}
+
+// record marks that the automatic deployment of the given application was triggered at the given time.
+func (s *cooldownStore) record(applicationID string, now time.Time) {
+	s.triggeredAt[applicationID] = now
+}
+
+// remaining returns how long the next automatic deployment of the given application
+// must wait to keep the given cooldown. Zero means it can be triggered now.
+func (s *cooldownStore) remaining(applicationID string, cooldown time.Duration, now time.Time) time.Duration {
+	last, ok := s.triggeredAt[applicationID]
+	if !ok || cooldown <= 0 {
+		return 0
+	}
+	if r := last.Add(cooldown).Sub(now); r > 0 {
+		return r
+	}
+	return 0
+}
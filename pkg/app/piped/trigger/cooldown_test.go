@@ -0,0 +1,70 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trigger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCooldownStoreRemaining(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newCooldownStore()
+	s.record("app-1", now)
+
+	testcases := []struct {
+		name          string
+		applicationID string
+		cooldown      time.Duration
+		now           time.Time
+		expected      time.Duration
+	}{
+		{
+			name:          "never triggered",
+			applicationID: "app-2",
+			cooldown:      time.Minute,
+			now:           now,
+			expected:      0,
+		},
+		{
+			name:          "no cooldown",
+			applicationID: "app-1",
+			now:           now,
+			expected:      0,
+		},
+		{
+			name:          "in cooldown",
+			applicationID: "app-1",
+			cooldown:      5 * time.Minute,
+			now:           now.Add(2 * time.Minute),
+			expected:      3 * time.Minute,
+		},
+		{
+			name:          "cooldown ended",
+			applicationID: "app-1",
+			cooldown:      5 * time.Minute,
+			now:           now.Add(5 * time.Minute),
+			expected:      0,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := s.remaining(tc.applicationID, tc.cooldown, tc.now)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -21,6 +21,8 @@ import (
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
 	"github.com/pipe-cd/pipe/pkg/git"
@@ -34,8 +36,10 @@ func (t *Trigger) triggerDeployment(
 	commit git.Commit,
 	commander string,
 	syncStrategy model.SyncStrategy,
+	dryRun bool,
+	pinnedDirection model.PinnedDirection,
 ) (deployment *model.Deployment, err error) {
-	deployment, err = buildDeployment(app, branch, commit, commander, syncStrategy, time.Now())
+	deployment, err = buildDeployment(app, branch, commit, commander, syncStrategy, dryRun, pinnedDirection, time.Now())
 	if err != nil {
 		return
 	}
@@ -68,6 +72,11 @@ func (t *Trigger) triggerDeployment(
 		return
 	}
 
+	// A dry-run deployment must not be shown as the most recent one of the application.
+	if dryRun {
+		return
+	}
+
 	// TODO: Find a better way to ensure that the application should be updated correctly
 	// when the deployment was successfully triggered.
 	if e := t.reportMostRecentlyTriggeredDeployment(ctx, deployment); e != nil {
@@ -77,6 +86,18 @@ func (t *Trigger) triggerDeployment(
 	return
 }
 
+// isRetryableTriggerError reports whether the deployment failed to be triggered
+// because of a temporary error of the control-plane. The others such as
+// an invalid deployment are not retried until a new commit was pushed.
+func isRetryableTriggerError(err error) bool {
+	switch status.Code(err) {
+	case codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.Unavailable:
+		return true
+	default:
+		return false
+	}
+}
+
 func (t *Trigger) reportMostRecentlyTriggeredDeployment(ctx context.Context, d *model.Deployment) error {
 	var (
 		err error
@@ -110,6 +131,8 @@ func buildDeployment(
 	commit git.Commit,
 	commander string,
 	syncStrategy model.SyncStrategy,
+	dryRun bool,
+	pinnedDirection model.PinnedDirection,
 	now time.Time,
 ) (*model.Deployment, error) {
 	commitURL := ""
@@ -138,9 +161,11 @@ func buildDeployment(
 				Url:       commitURL,
 				CreatedAt: int64(commit.CreatedAt),
 			},
-			Commander:    commander,
-			Timestamp:    now.Unix(),
-			SyncStrategy: syncStrategy,
+			Commander:       commander,
+			Timestamp:       now.Unix(),
+			SyncStrategy:    syncStrategy,
+			DryRun:          dryRun,
+			PinnedDirection: pinnedDirection,
 		},
 		GitPath:       app.GitPath,
 		CloudProvider: app.CloudProvider,
@@ -150,5 +175,39 @@ func buildDeployment(
 		UpdatedAt:     now.Unix(),
 	}
 
+	// Keep the commit trailers to let the release context travel with the deployment.
+	if trailers := commit.Trailers(); len(trailers) > 0 {
+		deployment.Metadata = make(map[string]string, len(trailers))
+		for k, v := range trailers {
+			deployment.Metadata[model.CommitTrailerMetadataKeyPrefix+k] = v
+		}
+	}
+
 	return deployment, nil
 }
+
+// determinePinnedDirection returns how the commit pinned by the user relates
+// to the commit of the most recently successful deployment of the application.
+// The commit history is used instead of the commit time since the time
+// does not reflect the order of the commits, e.g. after rebasing or cherry-picking.
+func determinePinnedDirection(ctx context.Context, repo git.Repo, app *model.Application, commit, headCommit git.Commit) (model.PinnedDirection, error) {
+	if commit.Hash == headCommit.Hash {
+		return model.PinnedDirection_NOT_PINNED, nil
+	}
+	running := app.MostRecentlySuccessfulDeployment
+	if running == nil || running.Trigger == nil || running.Trigger.Commit == nil {
+		return model.PinnedDirection_PINNED_FORWARD, nil
+	}
+	runningHash := running.Trigger.Commit.Hash
+	if commit.Hash == runningHash {
+		return model.PinnedDirection_PINNED_FORWARD, nil
+	}
+	older, err := repo.IsAncestor(ctx, commit.Hash, runningHash)
+	if err != nil {
+		return model.PinnedDirection_NOT_PINNED, err
+	}
+	if older {
+		return model.PinnedDirection_PINNED_ROLLBACK, nil
+	}
+	return model.PinnedDirection_PINNED_FORWARD, nil
+}
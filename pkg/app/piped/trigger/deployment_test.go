@@ -0,0 +1,114 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trigger
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipe/pkg/git"
+	"github.com/pipe-cd/pipe/pkg/git/gittest"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestDeterminePinnedDirection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		head = git.Commit{Hash: "head"}
+		app  = &model.Application{
+			MostRecentlySuccessfulDeployment: &model.ApplicationDeploymentReference{
+				Trigger: &model.DeploymentTrigger{
+					Commit: &model.Commit{Hash: "running"},
+				},
+			},
+		}
+	)
+	repo := gittest.NewMockRepo(ctrl)
+	repo.EXPECT().IsAncestor(gomock.Any(), "old", "running").Return(true, nil).AnyTimes()
+	repo.EXPECT().IsAncestor(gomock.Any(), "new", "running").Return(false, nil).AnyTimes()
+	repo.EXPECT().IsAncestor(gomock.Any(), "missing", "running").Return(false, errors.New("bad object")).AnyTimes()
+
+	testcases := []struct {
+		name        string
+		app         *model.Application
+		commit      git.Commit
+		expected    model.PinnedDirection
+		expectedErr bool
+	}{
+		{
+			name:     "head commit",
+			app:      app,
+			commit:   head,
+			expected: model.PinnedDirection_NOT_PINNED,
+		},
+		{
+			name:     "ancestor of running commit",
+			app:      app,
+			commit:   git.Commit{Hash: "old"},
+			expected: model.PinnedDirection_PINNED_ROLLBACK,
+		},
+		{
+			name:     "not ancestor of running commit",
+			app:      app,
+			commit:   git.Commit{Hash: "new"},
+			expected: model.PinnedDirection_PINNED_FORWARD,
+		},
+		{
+			name:     "running commit",
+			app:      app,
+			commit:   git.Commit{Hash: "running"},
+			expected: model.PinnedDirection_PINNED_FORWARD,
+		},
+		{
+			name:     "no successful deployment",
+			app:      &model.Application{},
+			commit:   git.Commit{Hash: "old"},
+			expected: model.PinnedDirection_PINNED_FORWARD,
+		},
+		{
+			name:        "unknown commit",
+			app:         app,
+			commit:      git.Commit{Hash: "missing"},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := determinePinnedDirection(context.Background(), repo, tc.app, tc.commit, head)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestIsRetryableTriggerError(t *testing.T) {
+	assert.True(t, isRetryableTriggerError(status.Error(codes.Unavailable, "unavailable")))
+	assert.True(t, isRetryableTriggerError(status.Error(codes.DeadlineExceeded, "timeout")))
+	assert.False(t, isRetryableTriggerError(status.Error(codes.InvalidArgument, "invalid")))
+	assert.False(t, isRetryableTriggerError(status.Error(codes.AlreadyExists, "exists")))
+	assert.False(t, isRetryableTriggerError(errors.New("missing commit")))
+}
@@ -17,13 +17,10 @@ package trigger
 import (
 	"context"
 	"fmt"
-	"path/filepath"
-	"strings"
 
 	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/config"
-	"github.com/pipe-cd/pipe/pkg/filematcher"
 	"github.com/pipe-cd/pipe/pkg/git"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
@@ -88,7 +85,7 @@ func (d *Determiner) ShouldTrigger(ctx context.Context, app *model.Application)
 		return false, err
 	}
 
-	touched, err := isTouchedByChangedFiles(app.GitPath.Path, deployConfig.TriggerPaths, changedFiles)
+	touched, err := config.IsTouchedByChangedFiles(app.GitPath.Path, deployConfig.TriggerPaths, changedFiles)
 	if err != nil {
 		return false, err
 	}
@@ -102,8 +99,7 @@ func (d *Determiner) ShouldTrigger(ctx context.Context, app *model.Application)
 }
 
 func loadDeploymentConfiguration(repoPath string, app *model.Application) (*config.GenericDeploymentSpec, error) {
-	path := filepath.Join(repoPath, app.GitPath.GetDeploymentConfigFilePath())
-	cfg, err := config.LoadFromYAML(path)
+	cfg, err := config.LoadApplication(repoPath, app.GitPath.GetDeploymentConfigFilePath())
 	if err != nil {
 		return nil, err
 	}
@@ -118,31 +114,3 @@ func loadDeploymentConfiguration(repoPath string, app *model.Application) (*conf
 
 	return &spec, nil
 }
-
-func isTouchedByChangedFiles(appDir string, changes []string, changedFiles []string) (bool, error) {
-	if !strings.HasSuffix(appDir, "/") {
-		appDir += "/"
-	}
-
-	// If any files inside the application directory was changed
-	// this application is considered as touched.
-	for _, cf := range changedFiles {
-		if ok := strings.HasPrefix(cf, appDir); ok {
-			return true, nil
-		}
-	}
-
-	// If any changed files matches the specified "changes"
-	// this application is consided as touched too.
-	for _, change := range changes {
-		matcher, err := filematcher.NewPatternMatcher([]string{change})
-		if err != nil {
-			return false, err
-		}
-		if matcher.MatchesAny(changedFiles) {
-			return true, nil
-		}
-	}
-
-	return false, nil
-}
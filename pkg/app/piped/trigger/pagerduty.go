@@ -0,0 +1,68 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trigger
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/pagerduty"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// blockedByPagerDuty checks whether the PagerDuty service linked to the given application
+// is in an ongoing maintenance window or has open incidents.
+// A non-empty reason is returned when the automatic deployment must not be triggered.
+// The failures while asking PagerDuty are only logged to not block the deployments.
+func (t *Trigger) blockedByPagerDuty(ctx context.Context, app *model.Application, pd *config.DeploymentPagerDuty) string {
+	if pd == nil || (!pd.BlockDuringMaintenance && !pd.BlockDuringIncidents) {
+		return ""
+	}
+
+	logger := t.logger.With(
+		zap.String("app-id", app.Id),
+		zap.String("pagerduty-service", pd.ServiceID),
+	)
+	if t.config.PagerDuty == nil {
+		logger.Warn("pagerDuty is not configured in the piped configuration")
+		return ""
+	}
+	client, err := pagerduty.GetClient(t.config.PagerDuty)
+	if err != nil {
+		logger.Error("failed to create pagerduty client", zap.Error(err))
+		return ""
+	}
+
+	if pd.BlockDuringMaintenance {
+		in, err := client.InMaintenance(ctx, pd.ServiceID)
+		if err != nil {
+			logger.Error("failed to check pagerduty maintenance windows", zap.Error(err))
+		} else if in {
+			return fmt.Sprintf("pagerduty service %s is in an ongoing maintenance window", pd.ServiceID)
+		}
+	}
+	if pd.BlockDuringIncidents {
+		has, err := client.HasOpenIncidents(ctx, pd.ServiceID)
+		if err != nil {
+			logger.Error("failed to check pagerduty incidents", zap.Error(err))
+		} else if has {
+			return fmt.Sprintf("pagerduty service %s has open incidents", pd.ServiceID)
+		}
+	}
+	return ""
+}
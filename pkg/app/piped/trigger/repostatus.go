@@ -0,0 +1,89 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trigger
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// repositoryStatusStore keeps the result of the latest fetch of each repository
+// to be reported as a part of the piped health.
+type repositoryStatusStore struct {
+	statuses map[string]*repositoryStatus
+	mu       sync.RWMutex
+}
+
+type repositoryStatus struct {
+	lastFetchedAt int64
+	lastError     string
+	lastFailedAt  int64
+}
+
+func newRepositoryStatusStore() *repositoryStatusStore {
+	return &repositoryStatusStore{
+		statuses: make(map[string]*repositoryStatus),
+	}
+}
+
+// record updates the status of the given repository with the result of a fetch.
+// The time of the last successful fetch is kept even when the fetch failed.
+func (s *repositoryStatusStore) record(repoID string, err error, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, ok := s.statuses[repoID]
+	if !ok {
+		status = &repositoryStatus{}
+		s.statuses[repoID] = status
+	}
+	if err != nil {
+		status.lastError = err.Error()
+		status.lastFailedAt = now.Unix()
+		return
+	}
+	status.lastError = ""
+	status.lastFetchedAt = now.Unix()
+}
+
+func (s *repositoryStatusStore) remove(repoID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.statuses, repoID)
+}
+
+// list returns the statuses of all repositories sorted by the repository ID.
+func (s *repositoryStatusStore) list() []*model.PipedHealth_Repository {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*model.PipedHealth_Repository, 0, len(s.statuses))
+	for id, status := range s.statuses {
+		out = append(out, &model.PipedHealth_Repository{
+			Id:            id,
+			LastFetchedAt: status.lastFetchedAt,
+			LastError:     status.lastError,
+			LastFailedAt:  status.lastFailedAt,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Id < out[j].Id
+	})
+	return out
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trigger
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestRepositoryStatusStore(t *testing.T) {
+	var (
+		s   = newRepositoryStatusStore()
+		t1  = time.Unix(100, 0)
+		t2  = time.Unix(200, 0)
+		t3  = time.Unix(300, 0)
+		err = errors.New("authentication failed")
+	)
+
+	s.record("repo-2", nil, t1)
+	s.record("repo-1", nil, t1)
+	s.record("repo-1", err, t2)
+	s.record("repo-3", err, t2)
+	expected := []*model.PipedHealth_Repository{
+		{Id: "repo-1", LastFetchedAt: 100, LastError: "authentication failed", LastFailedAt: 200},
+		{Id: "repo-2", LastFetchedAt: 100},
+		{Id: "repo-3", LastError: "authentication failed", LastFailedAt: 200},
+	}
+	assert.Equal(t, expected, s.list())
+
+	s.record("repo-1", nil, t3)
+	s.remove("repo-3")
+	expected = []*model.PipedHealth_Repository{
+		{Id: "repo-1", LastFetchedAt: 300, LastFailedAt: 200},
+		{Id: "repo-2", LastFetchedAt: 100},
+	}
+	assert.Equal(t, expected, s.list())
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trigger
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const defaultSyncInterval = time.Minute
+
+// pollingScheduler decides when each repository should be checked for new commits.
+// Each repository is checked at its own interval with a random jitter,
+// and the interval is increased while the repository has not been changed for a long time.
+type pollingScheduler struct {
+	jitter     time.Duration
+	backoff    config.PipedSyncBackoff
+	repos      map[string]*repoPolling
+	randInt63n func(n int64) int64
+}
+
+type repoPolling struct {
+	// The configured interval.
+	baseInterval time.Duration
+	// The current interval which may be increased by the backoff.
+	interval    time.Duration
+	nextCheck   time.Time
+	headCommit  string
+	lastChanged time.Time
+}
+
+func newPollingScheduler(cfg *config.PipedSpec, now time.Time) *pollingScheduler {
+	s := &pollingScheduler{
+		repos:      make(map[string]*repoPolling, len(cfg.Repositories)),
+		randInt63n: rand.Int63n,
+	}
+	s.reload(cfg, now)
+	return s
+}
+
+// reload applies the given configuration.
+// The schedule of the repositories whose interval was not changed is kept as is.
+func (s *pollingScheduler) reload(cfg *config.PipedSpec, now time.Time) {
+	s.jitter = cfg.SyncJitter.Duration()
+	s.backoff = cfg.SyncBackoff
+
+	repos := make(map[string]*repoPolling, len(cfg.Repositories))
+	for _, r := range cfg.Repositories {
+		interval := cfg.GetSyncInterval(r)
+		if interval <= 0 {
+			interval = defaultSyncInterval
+		}
+		p, ok := s.repos[r.RepoID]
+		if !ok {
+			p = &repoPolling{lastChanged: now}
+		}
+		if p.baseInterval != interval {
+			p.baseInterval = interval
+			p.interval = interval
+			p.nextCheck = now.Add(s.delay(interval))
+		}
+		repos[r.RepoID] = p
+	}
+	s.repos = repos
+}
+
+// due returns the sorted IDs of the repositories which should be checked at the given time.
+func (s *pollingScheduler) due(now time.Time) []string {
+	var ids []string
+	for id, p := range s.repos {
+		if !now.Before(p.nextCheck) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// checked records the head commit of the checked repository and schedules its next check.
+// Empty head commit means the check was skipped or failed.
+func (s *pollingScheduler) checked(repoID, headCommit string, now time.Time) {
+	p, ok := s.repos[repoID]
+	if !ok {
+		return
+	}
+	switch {
+	case headCommit == "":
+	case headCommit != p.headCommit:
+		p.headCommit = headCommit
+		p.lastChanged = now
+		p.interval = p.baseInterval
+	case s.backoff.IdlePeriod > 0 && now.Sub(p.lastChanged) >= s.backoff.IdlePeriod.Duration():
+		maxInterval := s.backoff.MaxInterval.Duration()
+		if maxInterval < p.baseInterval {
+			maxInterval = p.baseInterval
+		}
+		if p.interval *= 2; p.interval > maxInterval {
+			p.interval = maxInterval
+		}
+	}
+	p.nextCheck = now.Add(s.delay(p.interval))
+}
+
+func (s *pollingScheduler) delay(interval time.Duration) time.Duration {
+	if s.jitter <= 0 {
+		return interval
+	}
+	return interval + time.Duration(s.randInt63n(int64(s.jitter)+1))
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trigger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestPollingScheduler(t *testing.T) {
+	var (
+		now = time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+		cfg = &config.PipedSpec{
+			SyncInterval: config.Duration(time.Minute),
+			SyncBackoff: config.PipedSyncBackoff{
+				IdlePeriod:  config.Duration(time.Hour),
+				MaxInterval: config.Duration(5 * time.Minute),
+			},
+			Repositories: []config.PipedRepository{
+				{RepoID: "repo-1"},
+				{RepoID: "repo-2", SyncInterval: config.Duration(3 * time.Minute)},
+			},
+		}
+		s = newPollingScheduler(cfg, now)
+	)
+
+	assert.Empty(t, s.due(now))
+	assert.Equal(t, []string{"repo-1"}, s.due(now.Add(time.Minute)))
+	assert.Equal(t, []string{"repo-1", "repo-2"}, s.due(now.Add(3*time.Minute)))
+
+	// The interval is kept until the idle period has passed.
+	now = now.Add(time.Minute)
+	s.checked("repo-1", "commit-1", now)
+	s.checked("repo-1", "commit-1", now)
+	assert.Equal(t, time.Minute, s.repos["repo-1"].interval)
+
+	// The interval is doubled on each check up to the max interval.
+	now = now.Add(time.Hour)
+	s.checked("repo-1", "commit-1", now)
+	assert.Equal(t, 2*time.Minute, s.repos["repo-1"].interval)
+	s.checked("repo-1", "commit-1", now)
+	s.checked("repo-1", "commit-1", now)
+	assert.Equal(t, 5*time.Minute, s.repos["repo-1"].interval)
+	assert.Equal(t, now.Add(5*time.Minute), s.repos["repo-1"].nextCheck)
+
+	// A failed check does not affect the interval.
+	s.checked("repo-1", "", now)
+	assert.Equal(t, 5*time.Minute, s.repos["repo-1"].interval)
+
+	// The interval is reset once a new commit was found.
+	s.checked("repo-1", "commit-2", now)
+	assert.Equal(t, time.Minute, s.repos["repo-1"].interval)
+
+	// The schedule of the unchanged repository is kept while reloading.
+	next := s.repos["repo-1"].nextCheck
+	cfg.Repositories = []config.PipedRepository{{RepoID: "repo-1"}, {RepoID: "repo-3"}}
+	s.reload(cfg, now.Add(10*time.Second))
+	assert.Equal(t, next, s.repos["repo-1"].nextCheck)
+	assert.Contains(t, s.repos, "repo-3")
+	assert.NotContains(t, s.repos, "repo-2")
+}
+
+func TestPollingSchedulerJitter(t *testing.T) {
+	var (
+		now = time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+		cfg = &config.PipedSpec{
+			SyncInterval: config.Duration(time.Minute),
+			SyncJitter:   config.Duration(10 * time.Second),
+			Repositories: []config.PipedRepository{
+				{RepoID: "repo-1"},
+			},
+		}
+		s = newPollingScheduler(cfg, now)
+	)
+	s.randInt63n = func(n int64) int64 {
+		assert.Equal(t, int64(10*time.Second)+1, n)
+		return int64(4 * time.Second)
+	}
+
+	s.checked("repo-1", "commit-1", now)
+	assert.Equal(t, now.Add(time.Minute+4*time.Second), s.repos["repo-1"].nextCheck)
+}
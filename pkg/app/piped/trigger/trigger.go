@@ -19,6 +19,7 @@ package trigger
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -34,7 +35,9 @@ import (
 
 const (
 	commandCheckInterval                = 10 * time.Second
+	pollingTickInterval                 = time.Second
 	defaultLastTriggeredCommitCacheSize = 500
+	syncRequestQueueSize                = 100
 )
 
 const (
@@ -68,6 +71,11 @@ type notifier interface {
 	Notify(event model.NotificationEvent)
 }
 
+type syncRequest struct {
+	applicationID string
+	commander     string
+}
+
 type Trigger struct {
 	apiClient         apiClient
 	gitClient         gitClient
@@ -76,8 +84,13 @@ type Trigger struct {
 	environmentLister environmentLister
 	notifier          notifier
 	config            *config.PipedSpec
+	configCh          chan *config.PipedSpec
+	syncCh            chan syncRequest
 	commitStore       *lastTriggeredCommitStore
+	scheduler         *pollingScheduler
 	gitRepos          map[string]git.Repo
+	repoStatuses      *repositoryStatusStore
+	cooldowns         *cooldownStore
 	gracePeriod       time.Duration
 	logger            *zap.Logger
 }
@@ -112,8 +125,13 @@ func NewTrigger(
 		environmentLister: environmentLister,
 		notifier:          notifier,
 		config:            cfg,
+		configCh:          make(chan *config.PipedSpec, 1),
+		syncCh:            make(chan syncRequest, syncRequestQueueSize),
 		commitStore:       commitStore,
+		scheduler:         newPollingScheduler(cfg, time.Now()),
 		gitRepos:          make(map[string]git.Repo, len(cfg.Repositories)),
+		repoStatuses:      newRepositoryStatusStore(),
+		cooldowns:         newCooldownStore(),
 		gracePeriod:       gracePeriod,
 		logger:            logger.Named("trigger"),
 	}
@@ -130,6 +148,7 @@ func (t *Trigger) Run(ctx context.Context) error {
 	t.gitRepos = make(map[string]git.Repo, len(t.config.Repositories))
 	for _, r := range t.config.Repositories {
 		repo, err := t.gitClient.Clone(ctx, r.RepoID, r.Remote, r.Branch, "")
+		t.repoStatuses.record(r.RepoID, err, time.Now())
 		if err != nil {
 			t.logger.Error("failed to clone repository",
 				zap.String("repo-id", r.RepoID),
@@ -140,8 +159,12 @@ func (t *Trigger) Run(ctx context.Context) error {
 		t.gitRepos[r.RepoID] = repo
 	}
 
-	commitTicker := time.NewTicker(time.Duration(t.config.SyncInterval))
-	defer commitTicker.Stop()
+	if len(t.gitRepos) == 0 {
+		t.logger.Info("no repositories were configured for this piped")
+	}
+
+	pollingTicker := time.NewTicker(pollingTickInterval)
+	defer pollingTicker.Stop()
 
 	commandTicker := time.NewTicker(commandCheckInterval)
 	defer commandTicker.Stop()
@@ -153,8 +176,17 @@ L:
 		case <-commandTicker.C:
 			t.checkNewCommands(ctx)
 
-		case <-commitTicker.C:
-			t.checkNewCommits(ctx)
+		case now := <-pollingTicker.C:
+			t.checkNewCommits(ctx, now)
+
+		case req := <-t.syncCh:
+			t.handleSyncRequest(ctx, req)
+
+		case cfg := <-t.configCh:
+			t.reloadRepos(ctx, cfg)
+			t.scheduler.reload(cfg, time.Now())
+			t.config = cfg
+			t.logger.Info("piped configuration was reloaded")
 
 		case <-ctx.Done():
 			break L
@@ -165,6 +197,96 @@ L:
 	return nil
 }
 
+// ReloadConfig makes Trigger use the given configuration
+// from the next check of new commits and commands.
+func (t *Trigger) ReloadConfig(cfg *config.PipedSpec) error {
+	// Drop the pending one since it is outdated.
+	select {
+	case <-t.configCh:
+	default:
+	}
+	t.configCh <- cfg
+	return nil
+}
+
+// reloadRepos clones the added or changed repositories and removes the deleted ones.
+// When a changed repository failed to be cloned, the old one is continued to use.
+func (t *Trigger) reloadRepos(ctx context.Context, cfg *config.PipedSpec) {
+	for _, r := range cfg.Repositories {
+		current, ok := t.config.GetRepository(r.RepoID)
+		if _, cloned := t.gitRepos[r.RepoID]; cloned && ok && current.Remote == r.Remote && current.Branch == r.Branch {
+			continue
+		}
+		repo, err := t.gitClient.Clone(ctx, r.RepoID, r.Remote, r.Branch, "")
+		t.repoStatuses.record(r.RepoID, err, time.Now())
+		if err != nil {
+			t.logger.Error("failed to clone repository",
+				zap.String("repo-id", r.RepoID),
+				zap.Error(err),
+			)
+			continue
+		}
+		if old, ok := t.gitRepos[r.RepoID]; ok {
+			old.Clean()
+		}
+		t.gitRepos[r.RepoID] = repo
+	}
+
+	for id, repo := range t.gitRepos {
+		if _, ok := cfg.GetRepository(id); ok {
+			continue
+		}
+		repo.Clean()
+		delete(t.gitRepos, id)
+		t.repoStatuses.remove(id)
+	}
+}
+
+// RepositoryStatuses returns the result of the latest fetch of each repository.
+func (t *Trigger) RepositoryStatuses() []*model.PipedHealth_Repository {
+	return t.repoStatuses.list()
+}
+
+// RequestSync enqueues a request to sync the given application with the latest commit.
+// The application is synced asynchronously from the main loop of Trigger.
+func (t *Trigger) RequestSync(applicationID, commander string) error {
+	if _, ok := t.applicationLister.Get(applicationID); !ok {
+		return fmt.Errorf("application %s is not handled by this piped", applicationID)
+	}
+	select {
+	case t.syncCh <- syncRequest{applicationID: applicationID, commander: commander}:
+		return nil
+	default:
+		return errors.New("too many sync requests are waiting to be handled")
+	}
+}
+
+func (t *Trigger) handleSyncRequest(ctx context.Context, req syncRequest) {
+	app, ok := t.applicationLister.Get(req.applicationID)
+	if !ok {
+		t.logger.Warn("detected a sync request for an unregistered application",
+			zap.String("app-id", req.applicationID),
+			zap.String("commander", req.commander),
+		)
+		return
+	}
+	if app.InMaintenance(time.Now()) {
+		t.logger.Info("ignored a sync request because the application is in maintenance mode",
+			zap.String("app-id", app.Id),
+			zap.String("commander", req.commander),
+			zap.String("reason", app.Maintenance.Reason),
+		)
+		return
+	}
+	if _, err := t.syncApplication(ctx, app, req.commander, model.SyncStrategy_AUTO, false, ""); err != nil {
+		t.logger.Error("failed to sync application",
+			zap.String("app-id", app.Id),
+			zap.String("commander", req.commander),
+			zap.Error(err),
+		)
+	}
+}
+
 func (t *Trigger) GetLastTriggeredCommitGetter() LastTriggeredCommitGetter {
 	return t.commitStore
 }
@@ -172,7 +294,13 @@ func (t *Trigger) GetLastTriggeredCommitGetter() LastTriggeredCommitGetter {
 func (t *Trigger) checkNewCommands(ctx context.Context) error {
 	commands := t.commandLister.ListApplicationCommands()
 
+	var refreshCommands []model.ReportableCommand
 	for _, cmd := range commands {
+		if cmd.GetRefreshRepository() != nil {
+			refreshCommands = append(refreshCommands, cmd)
+			continue
+		}
+
 		syncCmd := cmd.GetSyncApplication()
 		if syncCmd == nil {
 			continue
@@ -188,7 +316,7 @@ func (t *Trigger) checkNewCommands(ctx context.Context) error {
 			continue
 		}
 
-		d, err := t.syncApplication(ctx, app, cmd.Commander, syncCmd.SyncStrategy)
+		d, err := t.syncApplication(ctx, app, cmd.Commander, syncCmd.SyncStrategy, syncCmd.DryRun, syncCmd.TargetCommit)
 		if err != nil {
 			t.logger.Error("failed to sync application",
 				zap.String("app-id", app.Id),
@@ -208,12 +336,52 @@ func (t *Trigger) checkNewCommands(ctx context.Context) error {
 		}
 	}
 
+	t.refreshRepositories(ctx, refreshCommands)
 	return nil
 }
 
-func (t *Trigger) checkNewCommits(ctx context.Context) error {
-	if len(t.gitRepos) == 0 {
-		t.logger.Info("no repositories were configured for this piped")
+// refreshRepositories checks the new commits of the repositories notified
+// by the given commands without waiting for the next sync interval.
+// Each repository is checked only once even if it was notified by multiple commands.
+func (t *Trigger) refreshRepositories(ctx context.Context, commands []model.ReportableCommand) {
+	if len(commands) == 0 {
+		return
+	}
+
+	var (
+		applications = t.listApplications()
+		results      = make(map[string]error, len(commands))
+	)
+	for _, cmd := range commands {
+		repoID := cmd.GetRefreshRepository().RepositoryId
+		err, ok := results[repoID]
+		if !ok {
+			t.logger.Info("checking new commits of repository because of a refresh command",
+				zap.String("repo-id", repoID),
+				zap.String("commit", cmd.GetRefreshRepository().CommitHash),
+				zap.String("commander", cmd.Commander),
+			)
+			var headCommit string
+			headCommit, err = t.checkRepositoryCommits(ctx, repoID, applications[repoID])
+			t.scheduler.checked(repoID, headCommit, time.Now())
+			results[repoID] = err
+		}
+
+		status := model.CommandStatus_COMMAND_SUCCEEDED
+		if err != nil {
+			status = model.CommandStatus_COMMAND_FAILED
+		}
+		if err := cmd.Report(ctx, status, nil, nil); err != nil {
+			t.logger.Error("failed to report command status", zap.Error(err))
+		}
+	}
+}
+
+// checkNewCommits checks the new commits of the repositories
+// which are scheduled to be checked at the given time.
+func (t *Trigger) checkNewCommits(ctx context.Context, now time.Time) error {
+	repoIDs := t.scheduler.due(now)
+	if len(repoIDs) == 0 {
 		return nil
 	}
 
@@ -222,52 +390,133 @@ func (t *Trigger) checkNewCommits(ctx context.Context) error {
 	var applications = t.listApplications()
 
 	// ENHANCEMENT: We may want to apply worker model here to run them concurrently.
-	for repoID, apps := range applications {
-		gitRepo, branch, headCommit, err := t.updateRepoToLatest(ctx, repoID)
+	for _, repoID := range repoIDs {
+		apps := applications[repoID]
+		// No need to fetch the repository without any application.
+		if len(apps) == 0 {
+			t.scheduler.checked(repoID, "", now)
+			continue
+		}
+		headCommit, _ := t.checkRepositoryCommits(ctx, repoID, apps)
+		t.scheduler.checked(repoID, headCommit, time.Now())
+	}
+
+	return nil
+}
+
+// checkRepositoryCommits updates the given repository to the latest
+// and triggers the deployments of the given applications touched by the new commits.
+// The hash of the head commit is returned.
+func (t *Trigger) checkRepositoryCommits(ctx context.Context, repoID string, apps []*model.Application) (string, error) {
+	gitRepo, branch, headCommit, err := t.updateRepoToLatest(ctx, repoID)
+	if err != nil {
+		return "", err
+	}
+	d := NewDeterminer(gitRepo, headCommit.Hash, t.commitStore, t.logger)
+
+	for _, app := range apps {
+		shouldTrigger, err := d.ShouldTrigger(ctx, app)
 		if err != nil {
+			t.logger.Error(fmt.Sprintf("failed to check application: %s", app.Id), zap.Error(err))
 			continue
 		}
-		d := NewDeterminer(gitRepo, headCommit.Hash, t.commitStore, t.logger)
 
-		for _, app := range apps {
-			shouldTrigger, err := d.ShouldTrigger(ctx, app)
-			if err != nil {
-				t.logger.Error(fmt.Sprintf("failed to check application: %s", app.Id), zap.Error(err))
-				continue
-			}
+		if !shouldTrigger {
+			t.commitStore.Put(app.Id, headCommit.Hash)
+			continue
+		}
 
-			if !shouldTrigger {
-				t.commitStore.Put(app.Id, headCommit.Hash)
-				continue
-			}
+		// The commit is not marked as triggered so that it will be triggered at the next check
+		// once the reason was resolved, along with the commits pushed in the meantime.
+		if reason := t.shouldPostpone(ctx, gitRepo.GetPath(), app, time.Now()); reason != "" {
+			t.logger.Info(fmt.Sprintf("postponed triggering application %s because %s", app.Id, reason))
+			continue
+		}
 
-			// Build deployment model and send a request to API to create a new deployment.
-			t.logger.Info("application should be synced because of the new commit")
-			if _, err := t.triggerDeployment(ctx, app, branch, headCommit, "", model.SyncStrategy_AUTO); err != nil {
-				t.logger.Error(fmt.Sprintf("failed to trigger application: %s", app.Id), zap.Error(err))
+		// Build deployment model and send a request to API to create a new deployment.
+		t.logger.Info("application should be synced because of the new commit")
+		if _, err := t.triggerDeployment(ctx, app, branch, headCommit, "", model.SyncStrategy_AUTO, false, model.PinnedDirection_NOT_PINNED); err != nil {
+			t.logger.Error(fmt.Sprintf("failed to trigger application: %s", app.Id), zap.Error(err))
+			// The commit is not marked as triggered so that it will be
+			// triggered again at the next check.
+			if isRetryableTriggerError(err) {
+				continue
 			}
-			t.commitStore.Put(app.Id, headCommit.Hash)
+		} else {
+			t.cooldowns.record(app.Id, time.Now())
 		}
+		t.commitStore.Put(app.Id, headCommit.Hash)
 	}
 
-	return nil
+	return headCommit.Hash, nil
+}
+
+// shouldPostpone returns the reason why the automatic deployment of the given application
+// must be postponed, such as its maintenance mode, the status of its PagerDuty service
+// or its trigger cooldown. An empty string means it can be triggered now.
+func (t *Trigger) shouldPostpone(ctx context.Context, repoPath string, app *model.Application, now time.Time) string {
+	if app.InMaintenance(now) {
+		return fmt.Sprintf("it is in maintenance mode (%s)", app.Maintenance.Reason)
+	}
+
+	// The invalid deployment configuration does not postpone the deployment
+	// since it is reported by the deployment itself.
+	deployConfig, err := loadDeploymentConfiguration(repoPath, app)
+	if err != nil {
+		return ""
+	}
+	if reason := t.blockedByPagerDuty(ctx, app, deployConfig.PagerDuty); reason != "" {
+		return reason
+	}
+	if remaining := t.cooldowns.remaining(app.Id, deployConfig.TriggerCooldown.Duration(), now); remaining > 0 {
+		return fmt.Sprintf("it is in the trigger cooldown for %v", remaining.Round(time.Second))
+	}
+	return ""
 }
 
-func (t *Trigger) syncApplication(ctx context.Context, app *model.Application, commander string, syncStrategy model.SyncStrategy) (*model.Deployment, error) {
-	_, branch, headCommit, err := t.updateRepoToLatest(ctx, app.GitPath.Repo.Id)
+// syncApplication triggers a new deployment of the given application at the head commit of its branch.
+// When targetCommit is specified, that commit is deployed instead, e.g. to roll back the application.
+func (t *Trigger) syncApplication(ctx context.Context, app *model.Application, commander string, syncStrategy model.SyncStrategy, dryRun bool, targetCommit string) (*model.Deployment, error) {
+	repo, branch, headCommit, err := t.updateRepoToLatest(ctx, app.GitPath.Repo.Id)
 	if err != nil {
 		return nil, err
 	}
 
+	var (
+		commit          = headCommit
+		pinnedDirection = model.PinnedDirection_NOT_PINNED
+	)
+	if targetCommit != "" {
+		commit, err = repo.GetCommit(ctx, targetCommit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find target commit %s: %w", targetCommit, err)
+		}
+		pinnedDirection, err = determinePinnedDirection(ctx, repo, app, commit, headCommit)
+		if err != nil {
+			// The direction is only informative so the deployment is still triggered.
+			t.logger.Warn("unable to determine the pinned direction, assuming it is forward", zap.String("commit", commit.Hash), zap.Error(err))
+			pinnedDirection = model.PinnedDirection_PINNED_FORWARD
+		}
+	}
+
 	// Build deployment model and send a request to API to create a new deployment.
 	t.logger.Info(fmt.Sprintf("application %s will be synced because of a sync command", app.Id),
 		zap.String("head-commit", headCommit.Hash),
+		zap.String("commit", commit.Hash),
+		zap.String("pinned-direction", pinnedDirection.String()),
 	)
-	d, err := t.triggerDeployment(ctx, app, branch, headCommit, commander, syncStrategy)
+	d, err := t.triggerDeployment(ctx, app, branch, commit, commander, syncStrategy, dryRun, pinnedDirection)
 	if err != nil {
 		return nil, err
 	}
-	t.commitStore.Put(app.Id, headCommit.Hash)
+
+	// A dry-run deployment does not sync the application,
+	// so the head commit still has to be checked for triggering.
+	// The head commit is marked as handled even when a target commit was deployed
+	// to prevent the application from being synced back to the head automatically.
+	if !dryRun {
+		t.commitStore.Put(app.Id, headCommit.Hash)
+	}
 
 	return d, nil
 }
@@ -285,7 +534,11 @@ func (t *Trigger) updateRepoToLatest(ctx context.Context, repoID string) (repo g
 	branch = repo.GetClonedBranch()
 
 	// Fetch to update the repository and then
-	if err = repo.Pull(ctx, branch); err != nil {
+	err = repo.Pull(ctx, branch)
+	if ctx.Err() != context.Canceled {
+		t.repoStatuses.record(repoID, err, time.Now())
+	}
+	if err != nil {
 		if ctx.Err() != context.Canceled {
 			t.logger.Error("failed to update repository branch",
 				zap.String("repo-id", repoID),
@@ -0,0 +1,224 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package webhookreceiver provides a piped component
+// that receives webhook calls from the external systems such as container registries or CI systems
+// and then registers events or requests application syncs based on the configured receivers.
+package webhookreceiver
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/yamlprocessor"
+)
+
+const (
+	pathPrefix     = "/webhooks/"
+	maxPayloadSize = 1 << 20
+)
+
+type apiClient interface {
+	RegisterEvent(ctx context.Context, req *pipedservice.RegisterEventRequest, opts ...grpc.CallOption) (*pipedservice.RegisterEventResponse, error)
+}
+
+type applicationSyncer interface {
+	RequestSync(applicationID, commander string) error
+}
+
+type Receiver struct {
+	server      *http.Server
+	port        int
+	token       string
+	apiClient   apiClient
+	syncer      applicationSyncer
+	receivers   []config.PipedWebhookReceiver
+	mu          sync.RWMutex
+	gracePeriod time.Duration
+	logger      *zap.Logger
+}
+
+// NewReceiver creates a new Receiver which authenticates the calls by the given token.
+func NewReceiver(
+	cfg config.PipedWebhook,
+	token string,
+	apiClient apiClient,
+	syncer applicationSyncer,
+	gracePeriod time.Duration,
+	logger *zap.Logger,
+) *Receiver {
+	r := &Receiver{
+		port:        cfg.Port,
+		token:       token,
+		apiClient:   apiClient,
+		syncer:      syncer,
+		receivers:   cfg.Receivers,
+		gracePeriod: gracePeriod,
+		logger:      logger.Named("webhook-receiver"),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(pathPrefix, r.handle)
+	r.server = &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Port),
+		Handler: mux,
+	}
+	return r
+}
+
+// Run starts serving the webhook calls until the specified context has done.
+func (r *Receiver) Run(ctx context.Context) error {
+	doneCh := make(chan error, 1)
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		defer cancel()
+		r.logger.Info(fmt.Sprintf("webhook receiver is running on %d", r.port))
+		if err := r.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			r.logger.Error("failed to listen and serve webhook receiver", zap.Error(err))
+			doneCh <- err
+			return
+		}
+		doneCh <- nil
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), r.gracePeriod)
+	defer shutdownCancel()
+	r.logger.Info("stopping webhook receiver")
+	if err := r.server.Shutdown(shutdownCtx); err != nil {
+		r.logger.Error("failed to shutdown webhook receiver", zap.Error(err))
+	}
+	return <-doneCh
+}
+
+// ReloadConfig makes Receiver use the receivers of the given configuration
+// for the subsequent calls.
+func (r *Receiver) ReloadConfig(cfg *config.PipedSpec) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.receivers = cfg.Webhook.Receivers
+	return nil
+}
+
+func (r *Receiver) getReceiver(name string) (config.PipedWebhookReceiver, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, rcv := range r.receivers {
+		if rcv.Name == name {
+			return rcv, true
+		}
+	}
+	return config.PipedWebhookReceiver{}, false
+}
+
+func (r *Receiver) handle(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !r.authenticate(req) {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	name := strings.TrimPrefix(req.URL.Path, pathPrefix)
+	rcv, ok := r.getReceiver(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("receiver %s was not found", name), http.StatusNotFound)
+		return
+	}
+	payload, err := ioutil.ReadAll(io.LimitReader(req.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return
+	}
+	logger := r.logger.With(zap.String("receiver", name))
+
+	if e := rcv.Event; e != nil {
+		data, err := extractData(payload, e.DataPath)
+		if err != nil {
+			logger.Warn("failed to extract event data from payload", zap.Error(err))
+			http.Error(w, fmt.Sprintf("failed to extract event data: %v", err), http.StatusBadRequest)
+			return
+		}
+		_, err = r.apiClient.RegisterEvent(req.Context(), &pipedservice.RegisterEventRequest{
+			Name:   e.Name,
+			Data:   data,
+			Labels: e.Labels,
+		})
+		if err != nil {
+			logger.Error("failed to register event", zap.String("event", e.Name), zap.Error(err))
+			http.Error(w, "failed to register event", http.StatusInternalServerError)
+			return
+		}
+		logger.Info("successfully registered event", zap.String("event", e.Name), zap.String("data", data))
+	}
+
+	var failed []string
+	for _, appID := range rcv.SyncApplications {
+		if err := r.syncer.RequestSync(appID, "webhook:"+name); err != nil {
+			logger.Error("failed to request application sync", zap.String("app-id", appID), zap.Error(err))
+			failed = append(failed, appID)
+		}
+	}
+	if len(failed) > 0 {
+		http.Error(w, fmt.Sprintf("failed to request sync of applications: %s", strings.Join(failed, ", ")), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (r *Receiver) authenticate(req *http.Request) bool {
+	const prefix = "Bearer "
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return false
+	}
+	token := strings.TrimPrefix(auth, prefix)
+	// Never accept the calls without token even if piped was started with an empty one.
+	if token == "" || r.token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(r.token)) == 1
+}
+
+// extractData returns the value placed at the given path in the payload as a string.
+func extractData(payload []byte, path string) (string, error) {
+	value, err := yamlprocessor.GetValue(payload, path)
+	if err != nil {
+		return "", err
+	}
+	if value == nil {
+		return "", fmt.Errorf("no value was found at %s", path)
+	}
+	data := fmt.Sprint(value)
+	if data == "" {
+		return "", fmt.Errorf("empty value was found at %s", path)
+	}
+	return data, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhookreceiver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+type fakeAPIClient struct {
+	events []*pipedservice.RegisterEventRequest
+}
+
+func (c *fakeAPIClient) RegisterEvent(_ context.Context, req *pipedservice.RegisterEventRequest, _ ...grpc.CallOption) (*pipedservice.RegisterEventResponse, error) {
+	c.events = append(c.events, req)
+	return &pipedservice.RegisterEventResponse{}, nil
+}
+
+type fakeSyncer struct {
+	synced []string
+}
+
+func (s *fakeSyncer) RequestSync(appID, commander string) error {
+	if appID == "unknown" {
+		return errors.New("not found")
+	}
+	s.synced = append(s.synced, appID+"/"+commander)
+	return nil
+}
+
+func TestHandle(t *testing.T) {
+	cfg := config.PipedWebhook{
+		Port: 9086,
+		Receivers: []config.PipedWebhookReceiver{
+			{
+				Name: "harbor",
+				Event: &config.PipedWebhookEvent{
+					Name:     "image-update",
+					Labels:   map[string]string{"app": "helloworld"},
+					DataPath: "$.event_data.resources[0].tag",
+				},
+			},
+			{
+				Name:             "ci",
+				SyncApplications: []string{"app-1", "app-2"},
+			},
+			{
+				Name:             "broken",
+				SyncApplications: []string{"unknown"},
+			},
+		},
+	}
+
+	testcases := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		payload        string
+		expectedCode   int
+		expectedEvents []*pipedservice.RegisterEventRequest
+		expectedSynced []string
+	}{
+		{
+			name:         "wrong method",
+			method:       http.MethodGet,
+			path:         "/webhooks/ci",
+			token:        "secret",
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:         "wrong token",
+			method:       http.MethodPost,
+			path:         "/webhooks/ci",
+			token:        "wrong",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "empty token",
+			method:       http.MethodPost,
+			path:         "/webhooks/ci",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "unknown receiver",
+			method:       http.MethodPost,
+			path:         "/webhooks/unknown",
+			token:        "secret",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "register event",
+			method:       http.MethodPost,
+			path:         "/webhooks/harbor",
+			token:        "secret",
+			payload:      `{"type": "PUSH_ARTIFACT", "event_data": {"resources": [{"tag": "v0.1.0"}]}}`,
+			expectedCode: http.StatusAccepted,
+			expectedEvents: []*pipedservice.RegisterEventRequest{
+				{
+					Name:   "image-update",
+					Data:   "v0.1.0",
+					Labels: map[string]string{"app": "helloworld"},
+				},
+			},
+		},
+		{
+			name:         "missing event data",
+			method:       http.MethodPost,
+			path:         "/webhooks/harbor",
+			token:        "secret",
+			payload:      `{"type": "PUSH_ARTIFACT"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:           "sync applications",
+			method:         http.MethodPost,
+			path:           "/webhooks/ci",
+			token:          "secret",
+			expectedCode:   http.StatusAccepted,
+			expectedSynced: []string{"app-1/webhook:ci", "app-2/webhook:ci"},
+		},
+		{
+			name:         "failed to sync application",
+			method:       http.MethodPost,
+			path:         "/webhooks/broken",
+			token:        "secret",
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				client = &fakeAPIClient{}
+				syncer = &fakeSyncer{}
+				r      = NewReceiver(cfg, "secret", client, syncer, time.Second, zap.NewNop())
+				req    = httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.payload))
+				w      = httptest.NewRecorder()
+			)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			r.handle(w, req)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			assert.Equal(t, tc.expectedEvents, client.events)
+			assert.Equal(t, tc.expectedSynced, syncer.synced)
+		})
+	}
+}
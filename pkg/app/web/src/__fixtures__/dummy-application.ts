@@ -45,6 +45,7 @@ export const dummyApplication: Application.AsObject = {
     summary: "",
     startedAt: startedAt.unix(),
     version: "v1",
+    versionsList: [],
     trigger: dummyTrigger,
   },
   mostRecentlyTriggeredDeployment: {
@@ -53,6 +54,7 @@ export const dummyApplication: Application.AsObject = {
     summary: "summary",
     startedAt: startedAt.unix(),
     version: "v1",
+    versionsList: [],
     trigger: dummyTrigger,
   },
   syncState: dummyApplicationSyncState,
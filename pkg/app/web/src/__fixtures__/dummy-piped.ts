@@ -34,6 +34,7 @@ export const dummyPiped: Piped.AsObject = {
     { hash: "key-2", creator: "user", createdAt: createdAt.unix() },
   ],
   envIdsList: [dummyEnv.id],
+  configVersion: 0,
   sealedSecretEncryption: {
     encryptServiceAccount: "",
     publicKey: "",
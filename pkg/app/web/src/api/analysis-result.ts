@@ -0,0 +1,17 @@
+import { apiClient, apiRequest } from "./client";
+import {
+  GetAnalysisResultRequest,
+  GetAnalysisResultResponse,
+} from "pipe/pkg/app/web/api_client/service_pb";
+
+export const getAnalysisResult = ({
+  deploymentId,
+  stageId,
+}: GetAnalysisResultRequest.AsObject): Promise<
+  GetAnalysisResultResponse.AsObject
+> => {
+  const req = new GetAnalysisResultRequest();
+  req.setDeploymentId(deploymentId);
+  req.setStageId(stageId);
+  return apiRequest(req, apiClient.getAnalysisResult);
+};
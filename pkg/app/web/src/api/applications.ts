@@ -102,12 +102,14 @@ export const addApplication = async ({
 export const syncApplication = async ({
   applicationId,
   syncStrategy,
+  commit,
 }: SyncApplicationRequest.AsObject): Promise<
   SyncApplicationResponse.AsObject
 > => {
   const req = new SyncApplicationRequest();
   req.setApplicationId(applicationId);
   req.setSyncStrategy(syncStrategy);
+  req.setCommit(commit);
   return apiRequest(req, apiClient.syncApplication);
 };
 
@@ -138,7 +140,7 @@ export const updateApplication = async ({
   kind,
   name,
   pipedId,
-}: Required<UpdateApplicationRequest.AsObject>): Promise<
+}: Required<Omit<UpdateApplicationRequest.AsObject, "labels">>): Promise<
   UpdateApplicationResponse.AsObject
 > => {
   const req = new UpdateApplicationRequest();
@@ -8,6 +8,8 @@ import {
   CancelDeploymentResponse,
   ApproveStageRequest,
   ApproveStageResponse,
+  GetDeploymentStageGraphRequest,
+  GetDeploymentStageGraphResponse,
 } from "pipe/pkg/app/web/api_client/service_pb";
 
 export const getDeployment = ({
@@ -56,9 +58,23 @@ export const cancelDeployment = ({
 export const approveStage = ({
   deploymentId,
   stageId,
+  comment,
+  reject,
 }: ApproveStageRequest.AsObject): Promise<ApproveStageResponse.AsObject> => {
   const req = new ApproveStageRequest();
   req.setDeploymentId(deploymentId);
   req.setStageId(stageId);
+  req.setComment(comment);
+  req.setReject(reject);
   return apiRequest(req, apiClient.approveStage);
 };
+
+export const getDeploymentStageGraph = ({
+  deploymentId,
+}: GetDeploymentStageGraphRequest.AsObject): Promise<
+  GetDeploymentStageGraphResponse.AsObject
+> => {
+  const req = new GetDeploymentStageGraphRequest();
+  req.setDeploymentId(deploymentId);
+  return apiRequest(req, apiClient.getDeploymentStageGraph);
+};
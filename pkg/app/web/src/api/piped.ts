@@ -16,6 +16,10 @@ import {
   UpdatePipedResponse,
   DeleteOldPipedKeysRequest,
   DeleteOldPipedKeysResponse,
+  UpdatePipedConfigRequest,
+  UpdatePipedConfigResponse,
+  GetPipedConfigRequest,
+  GetPipedConfigResponse,
 } from "pipe/pkg/app/web/api_client/service_pb";
 
 export const getPipeds = ({
@@ -101,3 +105,29 @@ export const updatePiped = ({
   req.setEnvIdsList(envIdsList);
   return apiRequest(req, apiClient.updatePiped);
 };
+
+export const updatePipedConfig = ({
+  pipedId,
+  data,
+  comment,
+}: UpdatePipedConfigRequest.AsObject): Promise<
+  UpdatePipedConfigResponse.AsObject
+> => {
+  const req = new UpdatePipedConfigRequest();
+  req.setPipedId(pipedId);
+  req.setData(data);
+  req.setComment(comment);
+  return apiRequest(req, apiClient.updatePipedConfig);
+};
+
+export const getPipedConfig = ({
+  pipedId,
+  version,
+}: GetPipedConfigRequest.AsObject): Promise<
+  GetPipedConfigResponse.AsObject
+> => {
+  const req = new GetPipedConfigRequest();
+  req.setPipedId(pipedId);
+  req.setVersion(version);
+  return apiRequest(req, apiClient.getPipedConfig);
+};
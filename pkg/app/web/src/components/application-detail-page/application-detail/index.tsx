@@ -12,7 +12,7 @@ import Skeleton from "@material-ui/lab/Skeleton/Skeleton";
 import { SerializedError } from "@reduxjs/toolkit";
 import clsx from "clsx";
 import dayjs from "dayjs";
-import { FC, memo, useCallback } from "react";
+import { FC, memo, useCallback, useState } from "react";
 import { Link as RouterLink } from "react-router-dom";
 import { AppSyncStatus } from "~/components/app-sync-status";
 import { DetailTableRow } from "~/components/detail-table-row";
@@ -34,6 +34,7 @@ import { selectEnvById } from "~/modules/environments";
 import { selectPipedById } from "~/modules/pipeds";
 import { AppLiveState } from "./app-live-state";
 import { ApplicationDescription } from "./description";
+import { SyncCommitDialog } from "./sync-commit-dialog";
 import { SyncStateReason } from "./sync-state-reason";
 
 const useStyles = makeStyles((theme) => ({
@@ -134,7 +135,12 @@ const MostRecentlySuccessfulDeployment: FC<{
   );
 };
 
-const syncOptions = ["Sync", "Quick Sync", "Pipeline Sync"];
+const syncOptions = [
+  "Sync",
+  "Quick Sync",
+  "Pipeline Sync",
+  "Sync Specific Commit",
+];
 const syncStrategyByIndex: SyncStrategy[] = [
   SyncStrategy.AUTO,
   SyncStrategy.QUICK_SYNC,
@@ -156,6 +162,7 @@ export const ApplicationDetail: FC<ApplicationDetailProps> = memo(
     const env = useAppSelector(selectEnvById(app?.envId));
     const piped = useAppSelector(selectPipedById(app?.pipedId));
     const isSyncing = useIsSyncingApplication(app?.id);
+    const [isOpenSyncCommit, setIsOpenSyncCommit] = useState(false);
 
     const handleDescriptionEdit = useCallback(
       async (description: string) => {
@@ -165,6 +172,10 @@ export const ApplicationDetail: FC<ApplicationDetailProps> = memo(
     );
 
     const handleSync = (index: number): void => {
+      if (index >= syncStrategyByIndex.length) {
+        setIsOpenSyncCommit(true);
+        return;
+      }
       if (app) {
         dispatch(
           syncApplication({
@@ -175,6 +186,19 @@ export const ApplicationDetail: FC<ApplicationDetailProps> = memo(
       }
     };
 
+    const handleSyncCommit = (commit: string): void => {
+      setIsOpenSyncCommit(false);
+      if (app) {
+        dispatch(
+          syncApplication({
+            applicationId: app.id,
+            syncStrategy: SyncStrategy.AUTO,
+            commit,
+          })
+        );
+      }
+    };
+
     if (fetchApplicationError) {
       return (
         <Paper square elevation={1} className={classes.root}>
@@ -303,6 +327,12 @@ export const ApplicationDetail: FC<ApplicationDetailProps> = memo(
             startIcon={<SyncIcon />}
           />
         </Box>
+
+        <SyncCommitDialog
+          open={isOpenSyncCommit}
+          onClose={() => setIsOpenSyncCommit(false)}
+          onSync={handleSyncCommit}
+        />
       </Paper>
     );
   }
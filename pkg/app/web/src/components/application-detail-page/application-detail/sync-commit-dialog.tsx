@@ -0,0 +1,62 @@
+import {
+  Button,
+  Dialog,
+  DialogActions,
+  DialogContent,
+  DialogContentText,
+  DialogTitle,
+  TextField,
+} from "@material-ui/core";
+import { FC, FormEvent, useState } from "react";
+import { UI_TEXT_CANCEL } from "~/constants/ui-text";
+
+const DIALOG_TITLE = "Sync a specific commit";
+const DIALOG_DESCRIPTION =
+  "Deploy the application at the given commit instead of the latest one. The deployment will be marked as a pinned rollback or forward.";
+
+interface Props {
+  open: boolean;
+  onClose: () => void;
+  onSync: (commit: string) => void;
+}
+
+export const SyncCommitDialog: FC<Props> = ({ open, onClose, onSync }) => {
+  const [commit, setCommit] = useState("");
+
+  const handleSubmit = (e: FormEvent<HTMLFormElement>): void => {
+    e.preventDefault();
+    onSync(commit.trim());
+    setCommit("");
+  };
+
+  return (
+    <Dialog open={open} onClose={onClose} fullWidth>
+      <form onSubmit={handleSubmit}>
+        <DialogTitle>{DIALOG_TITLE}</DialogTitle>
+        <DialogContent>
+          <DialogContentText>{DIALOG_DESCRIPTION}</DialogContentText>
+          <TextField
+            value={commit}
+            variant="outlined"
+            margin="dense"
+            label="Commit hash"
+            fullWidth
+            required
+            autoFocus
+            onChange={(e) => setCommit(e.currentTarget.value)}
+          />
+        </DialogContent>
+        <DialogActions>
+          <Button onClick={onClose}>{UI_TEXT_CANCEL}</Button>
+          <Button
+            type="submit"
+            color="primary"
+            disabled={commit.trim() === ""}
+          >
+            Sync
+          </Button>
+        </DialogActions>
+      </form>
+    </Dialog>
+  );
+};
@@ -19,9 +19,11 @@ import { PAGE_PATH_APPLICATIONS } from "~/constants/path";
 import { useAppDispatch, useAppSelector } from "~/hooks/redux";
 import { useInterval } from "~/hooks/use-interval";
 import {
+  ArtifactVersion,
   cancelDeployment,
   Deployment,
   isDeploymentRunning,
+  PinnedDirection,
   selectById as selectDeploymentById,
   selectDeploymentIsCanceling,
 } from "~/modules/deployments";
@@ -75,6 +77,22 @@ const CANCEL_OPTIONS = [
   "Cancel without Rollback",
 ];
 const LOG_FETCH_INTERVAL = 2000;
+const PINNED_DIRECTION_TEXT: Record<PinnedDirection, string> = {
+  [PinnedDirection.NOT_PINNED]: "",
+  [PinnedDirection.PINNED_ROLLBACK]: "Rollback to a past commit",
+  [PinnedDirection.PINNED_FORWARD]: "Forward to a specific commit",
+};
+const COMMIT_TRAILER_METADATA_KEY_PREFIX = "commit-trailer/";
+
+const formatArtifactVersion = (v: ArtifactVersion.AsObject): string => {
+  if (v.runningVersion === "") {
+    return `${v.name}:${v.version} (new)`;
+  }
+  if (v.runningVersion === v.version) {
+    return `${v.name}:${v.version}`;
+  }
+  return `${v.name} from ${v.runningVersion} to ${v.version}`;
+};
 
 export const DeploymentDetail: FC<DeploymentDetailProps> = memo(
   function DeploymentDetail({ deploymentId }) {
@@ -164,6 +182,36 @@ export const DeploymentDetail: FC<DeploymentDetailProps> = memo(
                   />
                   <DetailTableRow label="Piped" value={piped.name} />
                   <DetailTableRow label="Summary" value={deployment.summary} />
+                  {deployment.versionsList.length > 0 && (
+                    <DetailTableRow
+                      label="Images"
+                      value={
+                        <>
+                          {deployment.versionsList.map((v) => (
+                            <Typography
+                              variant="body2"
+                              key={`${v.name}:${v.version}`}
+                            >
+                              {formatArtifactVersion(v)}
+                            </Typography>
+                          ))}
+                        </>
+                      }
+                    />
+                  )}
+                  {deployment.metadataMap
+                    .filter(([key]) =>
+                      key.startsWith(COMMIT_TRAILER_METADATA_KEY_PREFIX)
+                    )
+                    .map(([key, value]) => (
+                      <DetailTableRow
+                        key={key}
+                        label={key.slice(
+                          COMMIT_TRAILER_METADATA_KEY_PREFIX.length
+                        )}
+                        value={value}
+                      />
+                    ))}
                 </tbody>
               </table>
             </div>
@@ -198,6 +246,14 @@ export const DeploymentDetail: FC<DeploymentDetailProps> = memo(
                       }
                     />
                   )}
+                  {deployment.trigger?.pinnedDirection ? (
+                    <DetailTableRow
+                      label="Pinned"
+                      value={
+                        PINNED_DIRECTION_TEXT[deployment.trigger.pinnedDirection]
+                      }
+                    />
+                  ) : null}
                   <DetailTableRow
                     label="Triggered by"
                     value={
@@ -7,6 +7,7 @@ import {
   DialogContentText,
   DialogTitle,
   makeStyles,
+  TextField,
 } from "@material-ui/core";
 import clsx from "clsx";
 import { FC, memo, useCallback, useEffect, useState } from "react";
@@ -156,6 +157,7 @@ export const Pipeline: FC<PipelineProps> = memo(function Pipeline({
     selectById(state.deployments, deploymentId)
   );
   const [approveTargetId, setApproveTargetId] = useState<string | null>(null);
+  const [approvalComment, setApprovalComment] = useState("");
   const isOpenApproveDialog = Boolean(approveTargetId);
 
   const defaultActiveStage = findDefaultActiveStage(deployment);
@@ -197,10 +199,18 @@ export const Pipeline: FC<PipelineProps> = memo(function Pipeline({
     [dispatch, deploymentId]
   );
 
-  const handleApprove = (): void => {
+  const handleApprove = (reject: boolean): void => {
     if (approveTargetId) {
-      dispatch(approveStage({ deploymentId, stageId: approveTargetId }));
+      dispatch(
+        approveStage({
+          deploymentId,
+          stageId: approveTargetId,
+          comment: approvalComment,
+          reject,
+        })
+      );
       setApproveTargetId(null);
+      setApprovalComment("");
     }
   };
 
@@ -251,6 +261,7 @@ export const Pipeline: FC<PipelineProps> = memo(function Pipeline({
                         name={stage.name}
                         status={stage.status}
                         metadata={stage.metadataMap}
+                        results={stage.resultsMap}
                         onClick={handleOnClickStage}
                         active={isActive}
                         approver={approver}
@@ -273,12 +284,20 @@ export const Pipeline: FC<PipelineProps> = memo(function Pipeline({
           <DialogTitle>Approve stage</DialogTitle>
           <DialogContent>
             <DialogContentText>
-              {`To continue deploying, click "APPROVE".`}
+              {`To continue deploying, click "APPROVE". To stop it, click "REJECT".`}
             </DialogContentText>
+            <TextField
+              label="Comment"
+              value={approvalComment}
+              onChange={(e) => setApprovalComment(e.currentTarget.value)}
+              fullWidth
+              multiline
+            />
           </DialogContent>
           <DialogActions>
             <Button onClick={() => setApproveTargetId(null)}>CANCEL</Button>
-            <Button color="primary" onClick={handleApprove}>
+            <Button onClick={() => handleApprove(true)}>REJECT</Button>
+            <Button color="primary" onClick={() => handleApprove(false)}>
               APPROVE
             </Button>
           </DialogActions>
@@ -56,6 +56,7 @@ export interface PipelineStageProps {
   isDeploymentRunning: boolean;
   approver?: string;
   metadata: [string, string][];
+  results?: [string, string][];
   onClick: (stageId: string, stageName: string) => void;
 }
 
@@ -114,6 +115,7 @@ export const PipelineStage: FC<PipelineStageProps> = memo(
     active,
     approver,
     metadata,
+    results = [],
     isDeploymentRunning,
   }) {
     const classes = useStyles();
@@ -128,7 +130,10 @@ export const PipelineStage: FC<PipelineStageProps> = memo(
       onClick(id, name);
     }
 
-    const trafficPercentage = createTrafficPercentageText(metadata);
+    // The published results already contain the traffic percentages if any.
+    const trafficPercentage =
+      results.length === 0 ? createTrafficPercentageText(metadata) : "";
+    const sortedResults = [...results].sort(([a], [b]) => a.localeCompare(b));
 
     return (
       <Paper
@@ -162,6 +167,13 @@ export const PipelineStage: FC<PipelineStageProps> = memo(
             </Typography>
           </div>
         )}
+        {sortedResults.map(([key, value]) => (
+          <div className={classes.metadata} key={key}>
+            <Typography variant="body2" color="inherit">
+              {`${key}: ${value}`}
+            </Typography>
+          </div>
+        ))}
       </Paper>
     );
   }
@@ -0,0 +1,70 @@
+import { Story } from "@storybook/react";
+import { dummyPiped } from "~/__fixtures__/dummy-piped";
+import { createDecoratorRedux } from "~~/.storybook/redux-decorator";
+import { PipedHealthDrawer, PipedHealthDrawerProps } from ".";
+
+const now = Math.floor(Date.now() / 1000);
+const piped = {
+  ...dummyPiped,
+  health: {
+    cloudProvidersList: [
+      {
+        name: "kubernetes-default",
+        type: "KUBERNETES",
+        reachable: true,
+        message: "Kubernetes v1.20.0",
+      },
+      {
+        name: "terraform-default",
+        type: "TERRAFORM",
+        reachable: false,
+        message: "Unable to access TERRAFORM cloud provider: forbidden",
+      },
+    ],
+    repositoriesList: [
+      {
+        id: "debug",
+        lastFetchedAt: now - 60,
+        lastError: "",
+        lastFailedAt: 0,
+      },
+    ],
+    toolsList: [
+      { name: "kubectl", version: "Client Version: v1.18.2" },
+      { name: "helm", version: "" },
+    ],
+    resourceUsage: {
+      memoryBytes: 128 * 1024 * 1024,
+      heapBytes: 48 * 1024 * 1024,
+      goroutines: 120,
+      cpus: 4,
+    },
+    createdAt: now,
+  },
+};
+
+export default {
+  title: "Setting/Piped/PipedHealthDrawer",
+  component: PipedHealthDrawer,
+  decorators: [
+    createDecoratorRedux({
+      pipeds: {
+        entities: {
+          [piped.id]: piped,
+        },
+        ids: [piped.id],
+      },
+    }),
+  ],
+  argTypes: {
+    onClose: {
+      action: "onClose",
+    },
+  },
+};
+
+const Template: Story<PipedHealthDrawerProps> = (args) => (
+  <PipedHealthDrawer {...args} />
+);
+export const Overview = Template.bind({});
+Overview.args = { pipedId: piped.id };
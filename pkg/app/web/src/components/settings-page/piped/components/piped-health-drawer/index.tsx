@@ -0,0 +1,203 @@
+import {
+  Box,
+  Divider,
+  Drawer,
+  makeStyles,
+  Table,
+  TableBody,
+  TableCell,
+  TableHead,
+  TableRow,
+  Typography,
+} from "@material-ui/core";
+import {
+  CheckCircle as CheckCircleIcon,
+  Error as ErrorIcon,
+} from "@material-ui/icons";
+import dayjs from "dayjs";
+import { FC, memo } from "react";
+import { UI_TEXT_NOT_AVAILABLE_TEXT } from "~/constants/ui-text";
+import { useAppSelector } from "~/hooks/redux";
+import { selectPipedById } from "~/modules/pipeds";
+
+const useStyles = makeStyles((theme) => ({
+  root: {
+    width: 600,
+    padding: theme.spacing(2),
+  },
+  section: {
+    marginTop: theme.spacing(3),
+  },
+  okIcon: {
+    color: theme.palette.success.main,
+  },
+  errorIcon: {
+    color: theme.palette.error.main,
+  },
+  errorText: {
+    color: theme.palette.error.main,
+    wordBreak: "break-all",
+  },
+}));
+
+const MEGABYTE = 1024 * 1024;
+
+const formatTime = (unix: number): string =>
+  unix === 0 ? UI_TEXT_NOT_AVAILABLE_TEXT : dayjs(unix * 1000).fromNow();
+
+const StatusIcon: FC<{ ok: boolean }> = ({ ok }) => {
+  const classes = useStyles();
+  return ok ? (
+    <CheckCircleIcon fontSize="small" className={classes.okIcon} />
+  ) : (
+    <ErrorIcon fontSize="small" className={classes.errorIcon} />
+  );
+};
+
+export interface PipedHealthDrawerProps {
+  pipedId: string | null;
+  onClose: () => void;
+}
+
+export const PipedHealthDrawer: FC<PipedHealthDrawerProps> = memo(
+  function PipedHealthDrawer({ pipedId, onClose }) {
+    const classes = useStyles();
+    const piped = useAppSelector(selectPipedById(pipedId));
+    const health = piped?.health;
+
+    return (
+      <Drawer anchor="right" open={Boolean(piped)} onClose={onClose}>
+        <Box className={classes.root}>
+          <Typography variant="h6">
+            {`Health of piped "${piped?.name}"`}
+          </Typography>
+          {health ? (
+            <Typography variant="body2" color="textSecondary">
+              {`Reported ${formatTime(health.createdAt)}`}
+            </Typography>
+          ) : (
+            <Typography variant="body2" color="textSecondary">
+              This piped has not reported its health yet.
+            </Typography>
+          )}
+          <Divider />
+
+          {health && (
+            <>
+              <Box className={classes.section}>
+                <Typography variant="subtitle1">Cloud Providers</Typography>
+                <Table size="small">
+                  <TableHead>
+                    <TableRow>
+                      <TableCell />
+                      <TableCell>Name</TableCell>
+                      <TableCell>Type</TableCell>
+                      <TableCell>Message</TableCell>
+                    </TableRow>
+                  </TableHead>
+                  <TableBody>
+                    {health.cloudProvidersList.map((cp) => (
+                      <TableRow key={cp.name}>
+                        <TableCell>
+                          <StatusIcon ok={cp.reachable} />
+                        </TableCell>
+                        <TableCell>{cp.name}</TableCell>
+                        <TableCell>{cp.type}</TableCell>
+                        <TableCell
+                          className={cp.reachable ? "" : classes.errorText}
+                        >
+                          {cp.message}
+                        </TableCell>
+                      </TableRow>
+                    ))}
+                  </TableBody>
+                </Table>
+              </Box>
+
+              <Box className={classes.section}>
+                <Typography variant="subtitle1">Repositories</Typography>
+                <Table size="small">
+                  <TableHead>
+                    <TableRow>
+                      <TableCell />
+                      <TableCell>ID</TableCell>
+                      <TableCell>Last Fetched</TableCell>
+                      <TableCell>Last Error</TableCell>
+                    </TableRow>
+                  </TableHead>
+                  <TableBody>
+                    {health.repositoriesList.map((repo) => (
+                      <TableRow key={repo.id}>
+                        <TableCell>
+                          <StatusIcon ok={repo.lastError === ""} />
+                        </TableCell>
+                        <TableCell>{repo.id}</TableCell>
+                        <TableCell>{formatTime(repo.lastFetchedAt)}</TableCell>
+                        <TableCell className={classes.errorText}>
+                          {repo.lastError &&
+                            `${repo.lastError} (${formatTime(
+                              repo.lastFailedAt
+                            )})`}
+                        </TableCell>
+                      </TableRow>
+                    ))}
+                  </TableBody>
+                </Table>
+              </Box>
+
+              <Box className={classes.section}>
+                <Typography variant="subtitle1">Tools</Typography>
+                <Table size="small">
+                  <TableBody>
+                    {health.toolsList.map((tool) => (
+                      <TableRow key={tool.name}>
+                        <TableCell>{tool.name}</TableCell>
+                        <TableCell>
+                          {tool.version || UI_TEXT_NOT_AVAILABLE_TEXT}
+                        </TableCell>
+                      </TableRow>
+                    ))}
+                  </TableBody>
+                </Table>
+              </Box>
+
+              {health.resourceUsage && (
+                <Box className={classes.section}>
+                  <Typography variant="subtitle1">Resource Usage</Typography>
+                  <Table size="small">
+                    <TableBody>
+                      <TableRow>
+                        <TableCell>Memory</TableCell>
+                        <TableCell>
+                          {`${Math.round(
+                            health.resourceUsage.memoryBytes / MEGABYTE
+                          )} MB`}
+                        </TableCell>
+                      </TableRow>
+                      <TableRow>
+                        <TableCell>Heap</TableCell>
+                        <TableCell>
+                          {`${Math.round(
+                            health.resourceUsage.heapBytes / MEGABYTE
+                          )} MB`}
+                        </TableCell>
+                      </TableRow>
+                      <TableRow>
+                        <TableCell>Goroutines</TableCell>
+                        <TableCell>{health.resourceUsage.goroutines}</TableCell>
+                      </TableRow>
+                      <TableRow>
+                        <TableCell>CPUs</TableCell>
+                        <TableCell>{health.resourceUsage.cpus}</TableCell>
+                      </TableRow>
+                    </TableBody>
+                  </Table>
+                </Box>
+              )}
+            </>
+          )}
+        </Box>
+      </Drawer>
+    );
+  }
+);
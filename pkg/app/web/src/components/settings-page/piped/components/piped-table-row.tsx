@@ -14,7 +14,10 @@ import {
   TableRow,
   Typography,
 } from "@material-ui/core";
-import { MoreVert as MoreVertIcon } from "@material-ui/icons";
+import {
+  MoreVert as MoreVertIcon,
+  Warning as WarningIcon,
+} from "@material-ui/icons";
 import clsx from "clsx";
 import dayjs from "dayjs";
 import * as React from "react";
@@ -27,12 +30,14 @@ import {
   UI_TEXT_DISABLE,
   UI_TEXT_EDIT,
   UI_TEXT_ENABLE,
+  UI_TEXT_VIEW_HEALTH,
 } from "~/constants/ui-text";
 import { useAppDispatch, useAppSelector } from "~/hooks/redux";
 import {
   addNewPipedKey,
   deleteOldKey,
   fetchPipeds,
+  isHealthy,
   selectPipedById,
 } from "~/modules/pipeds";
 import { addToast } from "~/modules/toasts";
@@ -41,6 +46,10 @@ const useStyles = makeStyles((theme) => ({
   disabledItem: {
     background: theme.palette.grey[200],
   },
+  warningIcon: {
+    color: theme.palette.warning.main,
+    marginLeft: theme.spacing(1),
+  },
   idCell: {
     "& button": {
       visibility: "hidden",
@@ -54,6 +63,7 @@ const useStyles = makeStyles((theme) => ({
 interface Props {
   pipedId: string;
   onEdit: (id: string) => void;
+  onViewHealth: (id: string) => void;
   onDisable: (id: string) => void;
   onEnable: (id: string) => void;
 }
@@ -71,6 +81,7 @@ export const PipedTableRow: FC<Props> = memo(function PipedTableRow({
   onEnable,
   onDisable,
   onEdit,
+  onViewHealth,
 }) {
   const classes = useStyles();
   const piped = useAppSelector(selectPipedById(pipedId));
@@ -99,6 +110,11 @@ export const PipedTableRow: FC<Props> = memo(function PipedTableRow({
     onEdit(pipedId);
   }, [pipedId, onEdit]);
 
+  const handleViewHealth = useCallback(() => {
+    setAnchorEl(null);
+    onViewHealth(pipedId);
+  }, [pipedId, onViewHealth]);
+
   const handleAddNewKey = useCallback(() => {
     setAnchorEl(null);
     if (hasOldKey) {
@@ -142,7 +158,16 @@ export const PipedTableRow: FC<Props> = memo(function PipedTableRow({
         className={clsx({ [classes.disabledItem]: piped.disabled })}
       >
         <TableCell>
-          <Typography variant="subtitle2">{piped.name}</Typography>
+          <Box display="flex" alignItems="center">
+            <Typography variant="subtitle2">{piped.name}</Typography>
+            {piped.health && !isHealthy(piped.health) && (
+              <WarningIcon
+                fontSize="small"
+                titleAccess="Some cloud providers or repositories are unhealthy"
+                className={classes.warningIcon}
+              />
+            )}
+          </Box>
         </TableCell>
         <TableCell title={piped.id} className={classes.idCell}>
           <Box display="flex" alignItems="center" fontFamily="fontFamilyMono">
@@ -186,6 +211,9 @@ export const PipedTableRow: FC<Props> = memo(function PipedTableRow({
             <MenuItem key="piped-menu-edit" onClick={handleEdit}>
               {UI_TEXT_EDIT}
             </MenuItem>,
+            <MenuItem key="piped-menu-view-health" onClick={handleViewHealth}>
+              {UI_TEXT_VIEW_HEALTH}
+            </MenuItem>,
             <MenuItem key="piped-menu-add-new-key" onClick={handleAddNewKey}>
               {UI_TEXT_ADD_NEW_KEY}
             </MenuItem>,
@@ -45,6 +45,7 @@ import { useSettingsStyles } from "../styles";
 import { AddPipedDrawer } from "./components/add-piped-drawer";
 import { EditPipedDrawer } from "./components/edit-piped-drawer";
 import { FilterValues, PipedFilter } from "./components/piped-filter";
+import { PipedHealthDrawer } from "./components/piped-health-drawer";
 import { PipedTableRow } from "./components/piped-table-row";
 
 const useStyles = makeStyles(() => ({
@@ -83,6 +84,7 @@ export const SettingsPipedPage: FC = memo(function SettingsPipedPage() {
   const [openFilter, setOpenFilter] = useState(false);
   const [isOpenForm, setIsOpenForm] = useState(false);
   const [editPipedId, setEditPipedId] = useState<string | null>(null);
+  const [healthPipedId, setHealthPipedId] = useState<string | null>(null);
   const [filterValues, setFilterValues] = useState<FilterValues>({
     enabled: true,
   });
@@ -129,6 +131,14 @@ export const SettingsPipedPage: FC = memo(function SettingsPipedPage() {
     setEditPipedId(null);
   }, []);
 
+  const handleViewHealth = useCallback((id: string) => {
+    setHealthPipedId(id);
+  }, []);
+
+  const handleHealthClose = useCallback(() => {
+    setHealthPipedId(null);
+  }, []);
+
   return (
     <>
       <Toolbar variant="dense">
@@ -177,6 +187,7 @@ export const SettingsPipedPage: FC = memo(function SettingsPipedPage() {
                   key={piped.id}
                   pipedId={piped.id}
                   onEdit={handleEdit}
+                  onViewHealth={handleViewHealth}
                   onDisable={handleDisable}
                   onEnable={handleEnable}
                 />
@@ -192,6 +203,7 @@ export const SettingsPipedPage: FC = memo(function SettingsPipedPage() {
 
       <AddPipedDrawer open={isOpenForm} onClose={handleClose} />
       <EditPipedDrawer pipedId={editPipedId} onClose={handleEditClose} />
+      <PipedHealthDrawer pipedId={healthPipedId} onClose={handleHealthClose} />
 
       <Dialog fullWidth open={Boolean(registeredPiped)}>
         <DialogTitle>
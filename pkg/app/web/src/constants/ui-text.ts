@@ -16,3 +16,4 @@ export const UI_TEXT_NOT_AVAILABLE_TEXT = "N/A";
 // piped
 export const UI_TEXT_ADD_NEW_KEY = "Add new Key";
 export const UI_TEXT_DELETE_OLD_KEY = "Delete old Key";
+export const UI_TEXT_VIEW_HEALTH = "View health";
@@ -87,9 +87,13 @@ export const fetchApplication = createAsyncThunk<
 
 export const syncApplication = createAsyncThunk<
   void,
-  { applicationId: string; syncStrategy: SyncStrategy }
+  { applicationId: string; syncStrategy: SyncStrategy; commit?: string }
 >(`${MODULE_NAME}/sync`, async (values, thunkAPI) => {
-  const { commandId } = await applicationsAPI.syncApplication(values);
+  const { commandId } = await applicationsAPI.syncApplication({
+    applicationId: values.applicationId,
+    syncStrategy: values.syncStrategy,
+    commit: values.commit ?? "",
+  });
 
   await thunkAPI.dispatch(fetchCommand(commandId));
 });
@@ -19,6 +19,9 @@ export const COMMAND_TYPE_TEXT: Record<Command.Type, string> = {
   [Command.Type.SYNC_APPLICATION]: "Sync Application",
   [Command.Type.UPDATE_APPLICATION_CONFIG]: "Update Application Config",
   [Command.Type.BUILD_PLAN_PREVIEW]: "Build Plan Preview",
+  [Command.Type.RELOAD_PIPED_CONFIG]: "Reload Piped Config",
+  [Command.Type.REFRESH_REPOSITORY]: "Refresh Repository",
+  [Command.Type.DELETE_APPLICATION]: "Delete Application",
 };
 
 const commandsAdapter = createEntityAdapter<Command.AsObject>();
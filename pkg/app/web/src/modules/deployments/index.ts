@@ -146,7 +146,7 @@ export const fetchMoreDeployments = createAsyncThunk<
 
 export const approveStage = createAsyncThunk<
   void,
-  { deploymentId: string; stageId: string }
+  { deploymentId: string; stageId: string; comment: string; reject: boolean }
 >("deployments/approve", async (props, thunkAPI) => {
   const { commandId } = await deploymentsApi.approveStage(props);
   await thunkAPI.dispatch(fetchCommand(commandId));
@@ -252,8 +252,10 @@ export const selectDeploymentIsCanceling = (id: EntityId) => (
 export { SyncStrategy } from "pipe/pkg/app/web/model/common_pb";
 
 export {
+  ArtifactVersion,
   Deployment,
   DeploymentStatus,
   StageStatus,
   PipelineStage,
+  PinnedDirection,
 } from "pipe/pkg/app/web/model/deployment_pb";
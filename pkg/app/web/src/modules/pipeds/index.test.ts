@@ -8,6 +8,7 @@ import {
   selectPipedsByEnv,
   Piped,
   editPiped,
+  isHealthy,
 } from "./";
 
 const baseState = {
@@ -38,6 +39,41 @@ test("selectPipedsByEnv", () => {
   ).toEqual([dummyPiped]);
 });
 
+test("isHealthy", () => {
+  const health = {
+    cloudProvidersList: [
+      { name: "k8s", type: "KUBERNETES", reachable: true, message: "" },
+    ],
+    repositoriesList: [
+      { id: "repo-1", lastFetchedAt: 100, lastError: "", lastFailedAt: 0 },
+    ],
+    toolsList: [],
+    createdAt: 100,
+  };
+  expect(isHealthy(health)).toBe(true);
+  expect(
+    isHealthy({
+      ...health,
+      cloudProvidersList: [
+        { name: "k8s", type: "KUBERNETES", reachable: false, message: "" },
+      ],
+    })
+  ).toBe(false);
+  expect(
+    isHealthy({
+      ...health,
+      repositoriesList: [
+        {
+          id: "repo-1",
+          lastFetchedAt: 100,
+          lastError: "auth failed",
+          lastFailedAt: 120,
+        },
+      ],
+    })
+  ).toBe(false);
+});
+
 describe("pipedsSlice reducer", () => {
   it("should return the initial state", () => {
     expect(
@@ -5,7 +5,7 @@ import {
   EntityState,
   EntityId,
 } from "@reduxjs/toolkit";
-import { Piped } from "pipe/pkg/app/web/model/piped_pb";
+import { Piped, PipedHealth } from "pipe/pkg/app/web/model/piped_pb";
 import type { AppState } from "~/store";
 import * as pipedsApi from "~/api/piped";
 
@@ -143,5 +143,11 @@ export const selectPipedsByEnv = (
   );
 };
 
+// isHealthy returns true when all cloud providers are reachable
+// and the latest fetches of all repositories succeeded.
+export const isHealthy = (health: PipedHealth.AsObject): boolean =>
+  health.cloudProvidersList.every((cp) => cp.reachable) &&
+  health.repositoriesList.every((repo) => repo.lastError === "");
+
 export const { clearRegisteredPipedInfo } = pipedsSlice.actions;
 export { Piped, PipedKey } from "pipe/pkg/app/web/model/piped_pb";
@@ -35,6 +35,7 @@ type SourceLabel string
 const (
 	LabelSourceRedis    SourceLabel = "redis"
 	LabelSourceInmemory SourceLabel = "inmemory"
+	LabelSourceDisk     SourceLabel = "disk"
 )
 
 var (
@@ -0,0 +1,207 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diskcache provides a size-limited cache storing the values as files
+// inside a local directory so that they can survive restarts of the process.
+package diskcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/cache"
+	"github.com/pipe-cd/pipe/pkg/cache/cachemetrics"
+)
+
+var ErrInvalidValue = errors.New("value must be a byte slice")
+
+// Cache stores the byte values as files inside a directory.
+// The least recently used files are evicted when the total size
+// of the stored values exceeds the configured limit.
+type Cache struct {
+	dir      string
+	maxBytes int64
+	size     int64
+	mu       sync.Mutex
+}
+
+// New creates a cache storing values inside the given directory.
+// The values stored by the previous processes are kept and counted into the limit.
+func New(dir string, maxBytes int64) (*Cache, error) {
+	if maxBytes <= 0 {
+		return nil, errors.New("maxBytes must be greater than 0")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+	}
+	c := &Cache{
+		dir:      dir,
+		maxBytes: maxBytes,
+	}
+	files, err := c.listFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		c.size += f.Size()
+	}
+	// The limit may have been changed since the last run.
+	if err := c.evict(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get returns the bytes stored for the given key.
+func (c *Cache) Get(key string) (interface{}, error) {
+	path := c.path(key)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		cachemetrics.IncGetOperationCounter(
+			cachemetrics.LabelSourceDisk,
+			cachemetrics.LabelStatusMiss,
+		)
+		if os.IsNotExist(err) {
+			return nil, cache.ErrNotFound
+		}
+		return nil, err
+	}
+	cachemetrics.IncGetOperationCounter(
+		cachemetrics.LabelSourceDisk,
+		cachemetrics.LabelStatusHit,
+	)
+	// Update the modification time to mark it as recently used.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, nil
+}
+
+// Put stores the given bytes for the given key.
+func (c *Cache) Put(key string, value interface{}) error {
+	data, ok := value.([]byte)
+	if !ok {
+		return ErrInvalidValue
+	}
+	if int64(len(data)) > c.maxBytes {
+		return fmt.Errorf("value of %d bytes is larger than the cache size", len(data))
+	}
+
+	// Write to a temporary file first to not expose a partially written value.
+	tmp, err := ioutil.TempFile(c.dir, "tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path := c.path(key)
+	if info, err := os.Stat(path); err == nil {
+		c.size -= info.Size()
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	c.size += int64(len(data))
+	return c.evict()
+}
+
+// Delete removes the value stored for the given key.
+func (c *Cache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	c.size -= info.Size()
+	return nil
+}
+
+func (c *Cache) GetAll() (map[string]interface{}, error) {
+	return nil, cache.ErrUnimplemented
+}
+
+// path returns the file path for the given key.
+// The key is hashed since it may contain characters not allowed in file names.
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+// evict removes the least recently used files until the total size is under the limit.
+// The caller must hold the lock.
+func (c *Cache) evict() error {
+	if c.size <= c.maxBytes {
+		return nil
+	}
+	files, err := c.listFiles()
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	for _, f := range files {
+		if c.size <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.dir, f.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		c.size -= f.Size()
+	}
+	return nil
+}
+
+func (c *Cache) listFiles() ([]os.FileInfo, error) {
+	infos, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory %s: %w", c.dir, err)
+	}
+	files := make([]os.FileInfo, 0, len(infos))
+	for _, info := range infos {
+		if info.IsDir() || len(info.Name()) != sha256.Size*2 {
+			continue
+		}
+		files = append(files, info)
+	}
+	return files, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diskcache
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/cache"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 10)
+	require.NoError(t, err)
+
+	_, err = c.Get("key-1")
+	assert.Equal(t, cache.ErrNotFound, err)
+
+	assert.Equal(t, ErrInvalidValue, c.Put("key-1", "value"))
+	assert.Error(t, c.Put("key-1", []byte("too-large-value")))
+
+	require.NoError(t, c.Put("key-1", []byte("1234")))
+	got, err := c.Get("key-1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1234"), got)
+
+	// Make key-1 older than key-2.
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(c.path("key-1"), old, old))
+	require.NoError(t, c.Put("key-2", []byte("5678")))
+
+	// The least recently used key-1 is evicted to keep the size under the limit.
+	require.NoError(t, c.Put("key-3", []byte("90")))
+	require.NoError(t, c.Put("key-3", []byte("900")))
+	_, err = c.Get("key-1")
+	assert.Equal(t, cache.ErrNotFound, err)
+	_, err = c.Get("key-2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), c.size)
+
+	// The stored values are kept by the new cache using the same directory
+	// while the least recently used key-3 is evicted to fit the new limit.
+	require.NoError(t, os.Chtimes(c.path("key-3"), old, old))
+	c, err = New(dir, 4)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), c.size)
+	got, err = c.Get("key-2")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("5678"), got)
+
+	require.NoError(t, c.Delete("key-2"))
+	require.NoError(t, c.Delete("key-2"))
+	assert.Equal(t, int64(0), c.size)
+}
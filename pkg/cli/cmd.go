@@ -15,6 +15,7 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"net/http"
@@ -26,6 +27,7 @@ import (
 	"cloud.google.com/go/profiler"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"google.golang.org/api/option"
@@ -142,16 +144,42 @@ type MetricsBuilder interface {
 	Build() (io.Reader, error)
 }
 
-func (t Telemetry) CustomMetricsHandlerFor(mb MetricsBuilder) http.Handler {
+// GathererMetricsBuilder builds the metrics gathered by the given Prometheus gatherer in the text format.
+type GathererMetricsBuilder struct {
+	Gatherer prometheus.Gatherer
+}
+
+func (b GathererMetricsBuilder) Build() (io.Reader, error) {
+	mfs, err := b.Gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := expfmt.NewEncoder(&buf, expfmt.FmtText)
+	for _, mf := range mfs {
+		if err := enc.Encode(mf); err != nil {
+			return nil, err
+		}
+	}
+	return &buf, nil
+}
+
+// CustomMetricsHandlerFor returns a handler serving the metrics built by all of the given builders.
+func (t Telemetry) CustomMetricsHandlerFor(mbs ...MetricsBuilder) http.Handler {
 	if t.Flags.Metrics {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			rc, err := mb.Build()
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
+			readers := make([]io.Reader, 0, 2*len(mbs))
+			for _, mb := range mbs {
+				rc, err := mb.Build()
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				readers = append(readers, rc, bytes.NewReader([]byte("\n")))
 			}
 
-			_, err = io.Copy(w, rc)
+			_, err := io.Copy(w, io.MultiReader(readers...))
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // AnalysisMetrics contains common configurable values for deployment analysis with metrics.
@@ -110,6 +111,9 @@ func (e *AnalysisExpected) String() string {
 type AnalysisLog struct {
 	Query    string   `json:"query"`
 	Interval Duration `json:"interval"`
+	// Maximum number of matching logs within each interval before the query result is considered as failure.
+	// Default is 0.
+	Threshold int `json:"threshold"`
 	// Maximum number of failed checks before the query result is considered as failure.
 	FailureLimit int `json:"failureLimit"`
 	// If true, it considers as success when no data returned from the analysis provider.
@@ -137,6 +141,35 @@ type AnalysisHTTP struct {
 	Timeout      Duration `json:"timeout"`
 }
 
+// AnalysisWebhook contains configurable values for deployment analysis with an external judge.
+type AnalysisWebhook struct {
+	// The unique name of a WEBHOOK provider defined in the Piped Configuration.
+	// Required field.
+	Provider string `json:"provider"`
+	// Ask the judge at this intervals.
+	// Required field.
+	Interval Duration `json:"interval"`
+	// Maximum number of failed verdicts before the analysis is considered as failure.
+	FailureLimit int `json:"failureLimit"`
+	// The minimum score to pass when the judge returns only a score.
+	MinScore *float64 `json:"minScore"`
+	// Arbitrary values sent to the judge along with the deployment context.
+	Args map[string]string `json:"args"`
+	// How long after which the request times out.
+	// Default is 30s.
+	Timeout Duration `json:"timeout"`
+}
+
+func (w *AnalysisWebhook) Validate() error {
+	if w.Provider == "" {
+		return fmt.Errorf("missing \"provider\" field")
+	}
+	if w.Interval == 0 {
+		return fmt.Errorf("missing \"interval\" field")
+	}
+	return nil
+}
+
 type AnalysisHeader struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
@@ -147,12 +180,243 @@ type AnalysisDynamic struct {
 	Metrics []AnalysisDynamicMetrics `json:"metrics"`
 	Logs    []AnalysisDynamicLog     `json:"logs"`
 	Https   []AnalysisDynamicHTTP    `json:"https"`
+	// How the results of the dynamic metrics are aggregated into a score.
+	Score AnalysisDynamicScore `json:"score"`
+}
+
+func (a *AnalysisDynamic) Validate() error {
+	for i := range a.Metrics {
+		if err := a.Metrics[i].Validate(); err != nil {
+			return fmt.Errorf("invalid dynamic metrics at index %d: %w", i, err)
+		}
+	}
+	return a.Score.Validate()
 }
 
+// AnalysisDynamicStrategy represents the statistical method
+// used to compare the canary with the baseline.
+type AnalysisDynamicStrategy string
+
+const (
+	// AnalysisDynamicStrategyMannWhitney compares the data points of both variants
+	// with the Mann-Whitney U test.
+	AnalysisDynamicStrategyMannWhitney AnalysisDynamicStrategy = "MANN_WHITNEY"
+)
+
+// AnalysisDynamicDirection represents which change of the canary is considered as a regression.
+type AnalysisDynamicDirection string
+
+const (
+	AnalysisDynamicDirectionIncrease AnalysisDynamicDirection = "INCREASE"
+	AnalysisDynamicDirectionDecrease AnalysisDynamicDirection = "DECREASE"
+	AnalysisDynamicDirectionEither   AnalysisDynamicDirection = "EITHER"
+)
+
+const (
+	defaultDynamicConfidenceLevel = 0.95
+	defaultDynamicPassScore       = 75
+	defaultDynamicMarginalScore   = 50
+)
+
 type AnalysisDynamicMetrics struct {
+	// The query performed for both the canary and the baseline.
+	// The "{{ .Variant.Name }}" placeholder is replaced with the variant name.
 	Query    string   `json:"query"`
 	Provider string   `json:"provider"`
 	Timeout  Duration `json:"timeout"`
+	// The statistical method used to compare the variants.
+	// Default is MANN_WHITNEY.
+	Strategy AnalysisDynamicStrategy `json:"strategy"`
+	// Which change of the canary is considered as a regression.
+	// One of INCREASE, DECREASE, EITHER. Default is INCREASE.
+	Direction AnalysisDynamicDirection `json:"direction"`
+	// The confidence level required to consider the difference as significant.
+	// Default is 0.95.
+	ConfidenceLevel float64 `json:"confidenceLevel"`
+	// How much this metrics contributes to the score.
+	// Default is 1.
+	Weight int `json:"weight"`
+}
+
+func (m *AnalysisDynamicMetrics) Validate() error {
+	if m.Provider == "" {
+		return fmt.Errorf("missing \"provider\" field")
+	}
+	if m.Query == "" {
+		return fmt.Errorf("missing \"query\" field")
+	}
+	switch m.Strategy {
+	case "", AnalysisDynamicStrategyMannWhitney:
+	default:
+		return fmt.Errorf("unsupported strategy %q", m.Strategy)
+	}
+	switch m.Direction {
+	case "", AnalysisDynamicDirectionIncrease, AnalysisDynamicDirectionDecrease, AnalysisDynamicDirectionEither:
+	default:
+		return fmt.Errorf("unsupported direction %q", m.Direction)
+	}
+	if m.ConfidenceLevel < 0 || m.ConfidenceLevel >= 1 {
+		return fmt.Errorf("confidenceLevel must be in range [0, 1)")
+	}
+	if m.Weight < 0 {
+		return fmt.Errorf("weight must not be negative")
+	}
+	return nil
+}
+
+// GetConfidenceLevel returns the configured confidence level or the default one.
+func (m *AnalysisDynamicMetrics) GetConfidenceLevel() float64 {
+	if m.ConfidenceLevel == 0 {
+		return defaultDynamicConfidenceLevel
+	}
+	return m.ConfidenceLevel
+}
+
+// GetDirection returns the configured direction or the default one.
+func (m *AnalysisDynamicMetrics) GetDirection() AnalysisDynamicDirection {
+	if m.Direction == "" {
+		return AnalysisDynamicDirectionIncrease
+	}
+	return m.Direction
+}
+
+// GetWeight returns the configured weight or the default one.
+func (m *AnalysisDynamicMetrics) GetWeight() int {
+	if m.Weight == 0 {
+		return 1
+	}
+	return m.Weight
+}
+
+// AnalysisDynamicScore defines the thresholds of the score computed from the dynamic metrics.
+// The score is the percentage of the weights of metrics which had no regression.
+type AnalysisDynamicScore struct {
+	// How often the intermediate score is computed.
+	// The analysis ends with failure as soon as an intermediate score is lower than the marginal one.
+	// Default is 0, meaning the score is computed only once at the end of the analysis.
+	Interval Duration `json:"interval"`
+	// The minimum score required at the end of the analysis.
+	// Default is 75.
+	Pass int `json:"pass"`
+	// The minimum score required during the analysis.
+	// Default is 50.
+	Marginal int `json:"marginal"`
+}
+
+func (s *AnalysisDynamicScore) Validate() error {
+	pass, marginal := s.GetPass(), s.GetMarginal()
+	if pass > 100 || marginal < 0 {
+		return fmt.Errorf("scores must be in range [0, 100]")
+	}
+	if marginal > pass {
+		return fmt.Errorf("marginal score (%d) must not be greater than pass score (%d)", marginal, pass)
+	}
+	return nil
+}
+
+// GetPass returns the configured pass score or the default one.
+func (s *AnalysisDynamicScore) GetPass() int {
+	if s.Pass == 0 {
+		return defaultDynamicPassScore
+	}
+	return s.Pass
+}
+
+// GetMarginal returns the configured marginal score or the default one.
+func (s *AnalysisDynamicScore) GetMarginal() int {
+	if s.Marginal == 0 {
+		return defaultDynamicMarginalScore
+	}
+	return s.Marginal
+}
+
+// AnalysisLowTrafficAction represents what the ANALYSIS stage does
+// when the analyzed variant is not receiving enough traffic.
+type AnalysisLowTrafficAction string
+
+const (
+	// AnalysisLowTrafficActionSkip skips the analysis at once
+	// and completes the stage successfully with a warning.
+	AnalysisLowTrafficActionSkip AnalysisLowTrafficAction = "SKIP"
+	// AnalysisLowTrafficActionExtend keeps checking the traffic to postpone the analysis
+	// until the traffic becomes enough, and skips it when maxExtension elapsed.
+	AnalysisLowTrafficActionExtend AnalysisLowTrafficAction = "EXTEND"
+)
+
+const (
+	defaultTrafficGuardInterval     = Duration(time.Minute)
+	defaultTrafficGuardMaxExtension = Duration(time.Hour)
+)
+
+// AnalysisTrafficGuard checks the request rate of the analyzed variant
+// since the analysis results are not meaningful for the variant receiving too few requests.
+type AnalysisTrafficGuard struct {
+	// The unique name of provider defined in the Piped Configuration.
+	// Required field.
+	Provider string `json:"provider"`
+	// A query returning the request rate of the analyzed variant, e.g. requests per second.
+	// Required field.
+	Query string `json:"query"`
+	// The minimum request rate required to start the analysis.
+	// Required field.
+	MinRequestRate float64 `json:"minRequestRate"`
+	// What to do when the request rate is lower than the minimum one.
+	// One of SKIP, EXTEND. Default is SKIP.
+	OnLowTraffic AnalysisLowTrafficAction `json:"onLowTraffic"`
+	// How often the query is performed. The request rate is checked over this period.
+	// Default is 1m.
+	Interval Duration `json:"interval"`
+	// How long the analysis can be postponed when onLowTraffic is EXTEND.
+	// Default is 1h.
+	MaxExtension Duration `json:"maxExtension"`
+	// How long after which the query times out.
+	// Default is 30s.
+	Timeout Duration `json:"timeout"`
+}
+
+func (g *AnalysisTrafficGuard) Validate() error {
+	if g.Provider == "" {
+		return fmt.Errorf("missing \"provider\" field")
+	}
+	if g.Query == "" {
+		return fmt.Errorf("missing \"query\" field")
+	}
+	if g.MinRequestRate <= 0 {
+		return fmt.Errorf("minRequestRate must be greater than 0")
+	}
+	switch g.OnLowTraffic {
+	case "", AnalysisLowTrafficActionSkip, AnalysisLowTrafficActionExtend:
+	default:
+		return fmt.Errorf("unsupported onLowTraffic %q", g.OnLowTraffic)
+	}
+	if g.Interval < 0 || g.MaxExtension < 0 {
+		return fmt.Errorf("interval and maxExtension must not be negative")
+	}
+	return nil
+}
+
+// GetOnLowTraffic returns the configured action or the default one.
+func (g *AnalysisTrafficGuard) GetOnLowTraffic() AnalysisLowTrafficAction {
+	if g.OnLowTraffic == "" {
+		return AnalysisLowTrafficActionSkip
+	}
+	return g.OnLowTraffic
+}
+
+// GetInterval returns the configured interval or the default one.
+func (g *AnalysisTrafficGuard) GetInterval() Duration {
+	if g.Interval == 0 {
+		return defaultTrafficGuardInterval
+	}
+	return g.Interval
+}
+
+// GetMaxExtension returns the configured max extension or the default one.
+func (g *AnalysisTrafficGuard) GetMaxExtension() Duration {
+	if g.MaxExtension == 0 {
+		return defaultTrafficGuardMaxExtension
+	}
+	return g.MaxExtension
 }
 
 type AnalysisDynamicLog struct {
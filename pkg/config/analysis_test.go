@@ -63,3 +63,116 @@ func TestAnalysisExpectedString(t *testing.T) {
 		})
 	}
 }
+
+func TestAnalysisDynamicMetricsValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		metrics AnalysisDynamicMetrics
+		wantErr bool
+	}{
+		{
+			name: "valid with defaults",
+			metrics: AnalysisDynamicMetrics{
+				Provider: "prometheus",
+				Query:    "rate(errors{variant=\"{{ .Variant.Name }}\"}[1m])",
+			},
+			wantErr: false,
+		},
+		{
+			name: "unsupported direction",
+			metrics: AnalysisDynamicMetrics{
+				Provider:  "prometheus",
+				Query:     "errors",
+				Direction: "UP",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid confidence level",
+			metrics: AnalysisDynamicMetrics{
+				Provider:        "prometheus",
+				Query:           "errors",
+				ConfidenceLevel: 1,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.metrics.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestAnalysisDynamicScoreValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		score   AnalysisDynamicScore
+		wantErr bool
+	}{
+		{
+			name:    "defaults",
+			score:   AnalysisDynamicScore{},
+			wantErr: false,
+		},
+		{
+			name:    "marginal greater than pass",
+			score:   AnalysisDynamicScore{Pass: 60, Marginal: 80},
+			wantErr: true,
+		},
+		{
+			name:    "out of range",
+			score:   AnalysisDynamicScore{Pass: 120},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.score.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestAnalysisTrafficGuardValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		guard   AnalysisTrafficGuard
+		wantErr bool
+	}{
+		{
+			name: "valid with defaults",
+			guard: AnalysisTrafficGuard{
+				Provider:       "prometheus",
+				Query:          "sum(rate(requests{variant=\"canary\"}[1m]))",
+				MinRequestRate: 1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "missing min request rate",
+			guard: AnalysisTrafficGuard{
+				Provider: "prometheus",
+				Query:    "requests",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported action",
+			guard: AnalysisTrafficGuard{
+				Provider:       "prometheus",
+				Query:          "requests",
+				MinRequestRate: 1,
+				OnLowTraffic:   "FAIL",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.guard.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
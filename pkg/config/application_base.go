@@ -0,0 +1,205 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// The fields of the deployment configuration which can be defined
+// in the defaults of ApplicationBase since they are shared by all application kinds.
+var applicationBaseDefaultFields = map[string]struct{}{
+	"commitMatcher": {},
+	"pipeline":      {},
+	"triggerPaths":  {},
+	"timeout":       {},
+}
+
+// ApplicationBaseSpec represents the base configuration
+// extended by all deployment configuration files in the same repository.
+type ApplicationBaseSpec struct {
+	// The values applied to the applications of all kinds.
+	// Only commitMatcher, pipeline, triggerPaths and timeout can be specified.
+	Defaults map[string]interface{} `json:"defaults"`
+	// The values applied to the applications of a specific kind.
+	// The key is the kind of deployment configuration such as KubernetesApp
+	// and the value can contain any field of the spec of that kind.
+	// These take precedence over the defaults.
+	Kinds map[Kind]map[string]interface{} `json:"kinds"`
+}
+
+func (s *ApplicationBaseSpec) Validate() error {
+	for k := range s.Defaults {
+		if _, ok := applicationBaseDefaultFields[k]; !ok {
+			return fmt.Errorf("field %s is not allowed in defaults of ApplicationBase", k)
+		}
+	}
+	if err := decodeStrict(s.Defaults, &GenericDeploymentSpec{}); err != nil {
+		return fmt.Errorf("invalid defaults of ApplicationBase: %w", err)
+	}
+	for kind, values := range s.Kinds {
+		if _, ok := ToApplicationKind(kind); !ok {
+			return fmt.Errorf("%s is not an application kind", kind)
+		}
+		c := &Config{}
+		if err := c.init(kind, versionV1Beta1); err != nil {
+			return err
+		}
+		if err := decodeStrict(values, c.spec); err != nil {
+			return fmt.Errorf("invalid values of %s in ApplicationBase: %w", kind, err)
+		}
+	}
+	return nil
+}
+
+// Apply merges the base values into the given deployment configuration data with the following rules:
+//   - Objects are merged recursively.
+//   - The values in the deployment configuration take precedence over the base.
+//   - Lists such as pipeline stages are replaced as a whole.
+//   - A null value in the deployment configuration removes the field from the base.
+//
+// The data of the other kinds of configuration is returned as is.
+func (s *ApplicationBaseSpec) Apply(data []byte) ([]byte, error) {
+	obj, err := decodeObject(data)
+	if err != nil {
+		return nil, err
+	}
+	kind, _ := obj["kind"].(string)
+	if _, ok := ToApplicationKind(Kind(kind)); !ok {
+		return data, nil
+	}
+	spec, _ := obj["spec"].(map[string]interface{})
+
+	base, err := copyObject(s.Defaults)
+	if err != nil {
+		return nil, err
+	}
+	kindValues, err := copyObject(s.Kinds[Kind(kind)])
+	if err != nil {
+		return nil, err
+	}
+	base = extendObject(base, kindValues)
+	obj["spec"] = extendObject(base, spec)
+
+	return json.Marshal(obj)
+}
+
+// extendObject overrides the base object by the given one.
+// Unlike the overlays of piped configuration, lists are never merged item by item
+// because the order of pipeline stages matters.
+func extendObject(base, obj map[string]interface{}) map[string]interface{} {
+	if base == nil {
+		base = make(map[string]interface{}, len(obj))
+	}
+	for k, v := range obj {
+		if v == nil {
+			delete(base, k)
+			continue
+		}
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			base[k] = v
+			continue
+		}
+		b, ok := base[k].(map[string]interface{})
+		if !ok {
+			base[k] = o
+			continue
+		}
+		base[k] = extendObject(b, o)
+	}
+	return base
+}
+
+func copyObject(obj map[string]interface{}) (map[string]interface{}, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{}, len(obj))
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeStrict(values map[string]interface{}, out interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(out)
+}
+
+// LoadApplicationBase finds the config file for the application base in the .pipe
+// directory first up. And returns parsed config, ErrNotFound is returned if not found.
+func LoadApplicationBase(repoRoot string) (*ApplicationBaseSpec, error) {
+	dir := filepath.Join(repoRoot, SharedConfigurationDirName)
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		cfg, err := LoadFromYAML(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config file %s: %w", path, err)
+		}
+		if cfg.Kind == KindApplicationBase {
+			return cfg.ApplicationBaseSpec, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// LoadApplication reads the deployment configuration file at the given path relative to the repository root.
+// The ApplicationBase placed in the .pipe directory of the repository is applied before decoding if exists.
+func LoadApplication(repoRoot, configRelPath string) (*Config, error) {
+	data, err := ioutil.ReadFile(filepath.Join(repoRoot, configRelPath))
+	if err != nil {
+		return nil, err
+	}
+	base, err := LoadApplicationBase(repoRoot)
+	if err == ErrNotFound {
+		return DecodeYAML(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data, err = base.Apply(data); err != nil {
+		return nil, fmt.Errorf("failed to apply application base: %w", err)
+	}
+	return DecodeYAML(data)
+}
@@ -0,0 +1,161 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestApplicationBaseValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		spec    ApplicationBaseSpec
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			spec: ApplicationBaseSpec{
+				Defaults: map[string]interface{}{
+					"timeout":      "3h",
+					"triggerPaths": []interface{}{"shared"},
+				},
+				Kinds: map[Kind]map[string]interface{}{
+					KindKubernetesApp: {
+						"input": map[string]interface{}{"kubectlVersion": "1.18.2"},
+					},
+				},
+			},
+		},
+		{
+			name: "kind specific field in defaults",
+			spec: ApplicationBaseSpec{
+				Defaults: map[string]interface{}{
+					"input": map[string]interface{}{"kubectlVersion": "1.18.2"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown field of kind",
+			spec: ApplicationBaseSpec{
+				Kinds: map[Kind]map[string]interface{}{
+					KindLambdaApp: {"unknown": true},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "not an application kind",
+			spec: ApplicationBaseSpec{
+				Kinds: map[Kind]map[string]interface{}{
+					KindPiped: {},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.spec.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestLoadApplication(t *testing.T) {
+	repoRoot, err := ioutil.TempDir("", "application-base")
+	require.NoError(t, err)
+	defer os.RemoveAll(repoRoot)
+
+	writeFile := func(path, data string) {
+		path = filepath.Join(repoRoot, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, ioutil.WriteFile(path, []byte(data), 0600))
+	}
+	writeFile("app-1/.pipe.yaml", `
+apiVersion: pipecd.dev/v1beta1
+kind: KubernetesApp
+`)
+	writeFile("app-2/.pipe.yaml", `
+apiVersion: pipecd.dev/v1beta1
+kind: KubernetesApp
+spec:
+  timeout: 1h
+  commitMatcher:
+    quickSync: "hotfix:"
+  pipeline:
+    stages:
+      - name: K8S_PRIMARY_ROLLOUT
+  triggerPaths: null
+`)
+
+	// The deployment configuration is loaded as is if no base exists.
+	cfg, err := LoadApplication(repoRoot, "app-1/.pipe.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, Duration(6*time.Hour), cfg.KubernetesDeploymentSpec.Timeout)
+	assert.Nil(t, cfg.KubernetesDeploymentSpec.Pipeline)
+
+	writeFile(".pipe/application-base.yaml", `
+apiVersion: pipecd.dev/v1beta1
+kind: ApplicationBase
+spec:
+  defaults:
+    timeout: 3h
+    triggerPaths:
+      - shared
+    commitMatcher:
+      pipeline: "release:"
+  kinds:
+    KubernetesApp:
+      input:
+        kubectlVersion: 1.18.2
+      pipeline:
+        stages:
+          - name: K8S_CANARY_ROLLOUT
+          - name: K8S_PRIMARY_ROLLOUT
+          - name: K8S_CANARY_CLEAN
+`)
+
+	cfg, err = LoadApplication(repoRoot, "app-1/.pipe.yaml")
+	require.NoError(t, err)
+	spec := cfg.KubernetesDeploymentSpec
+	assert.Equal(t, Duration(3*time.Hour), spec.Timeout)
+	assert.Equal(t, []string{"shared"}, spec.TriggerPaths)
+	assert.Equal(t, "release:", spec.CommitMatcher.Pipeline)
+	assert.Equal(t, "1.18.2", spec.Input.KubectlVersion)
+	require.NotNil(t, spec.Pipeline)
+	assert.Len(t, spec.Pipeline.Stages, 3)
+
+	cfg, err = LoadApplication(repoRoot, "app-2/.pipe.yaml")
+	require.NoError(t, err)
+	spec = cfg.KubernetesDeploymentSpec
+	assert.Equal(t, Duration(time.Hour), spec.Timeout)
+	assert.Nil(t, spec.TriggerPaths)
+	assert.Equal(t, "hotfix:", spec.CommitMatcher.QuickSync)
+	assert.Equal(t, "release:", spec.CommitMatcher.Pipeline)
+	assert.Equal(t, "1.18.2", spec.Input.KubectlVersion)
+	require.NotNil(t, spec.Pipeline)
+	require.Len(t, spec.Pipeline.Stages, 1)
+	assert.Equal(t, model.StageK8sPrimaryRollout, spec.Pipeline.Stages[0].Name)
+}
@@ -51,6 +51,15 @@ const (
 	KindCloudRunApp Kind = "CloudRunApp"
 	// KindECSApp represents deployment configuration for an AWS ECS.
 	KindECSApp Kind = "ECSApp"
+	// KindVMApp represents deployment configuration for a group of virtual machines
+	// running the same machine image.
+	KindVMApp Kind = "VMApp"
+	// KindPulumiApp represents deployment configuration for a Pulumi application.
+	// This application contains a single stack of a Pulumi project.
+	KindPulumiApp Kind = "PulumiApp"
+	// KindAnsibleApp represents deployment configuration for an Ansible application.
+	// This application runs a playbook against the hosts of an inventory.
+	KindAnsibleApp Kind = "AnsibleApp"
 	// KindSealedSecret represents a sealed secret.
 	KindSealedSecret Kind = "SealedSecret"
 )
@@ -67,6 +76,11 @@ const (
 	KindAnalysisTemplate Kind = "AnalysisTemplate"
 	// KindEventWatcher represents configuration for Event Watcher.
 	KindEventWatcher Kind = "EventWatcher"
+	// KindApplicationBase represents the base configuration extended by
+	// all deployment configuration files in a repository.
+	// This configuration file should be placed in .pipe directory
+	// at the root of the repository.
+	KindApplicationBase Kind = "ApplicationBase"
 )
 
 var (
@@ -85,11 +99,15 @@ type Config struct {
 	CloudRunDeploymentSpec   *CloudRunDeploymentSpec
 	LambdaDeploymentSpec     *LambdaDeploymentSpec
 	ECSDeploymentSpec        *ECSDeploymentSpec
+	VMDeploymentSpec         *VMDeploymentSpec
+	PulumiDeploymentSpec     *PulumiDeploymentSpec
+	AnsibleDeploymentSpec    *AnsibleDeploymentSpec
 
 	PipedSpec            *PipedSpec
 	ControlPlaneSpec     *ControlPlaneSpec
 	AnalysisTemplateSpec *AnalysisTemplateSpec
 	EventWatcherSpec     *EventWatcherSpec
+	ApplicationBaseSpec  *ApplicationBaseSpec
 
 	SealedSecretSpec *SealedSecretSpec
 }
@@ -125,6 +143,18 @@ func (c *Config) init(kind Kind, apiVersion string) error {
 		c.ECSDeploymentSpec = &ECSDeploymentSpec{}
 		c.spec = c.ECSDeploymentSpec
 
+	case KindVMApp:
+		c.VMDeploymentSpec = &VMDeploymentSpec{}
+		c.spec = c.VMDeploymentSpec
+
+	case KindPulumiApp:
+		c.PulumiDeploymentSpec = &PulumiDeploymentSpec{}
+		c.spec = c.PulumiDeploymentSpec
+
+	case KindAnsibleApp:
+		c.AnsibleDeploymentSpec = &AnsibleDeploymentSpec{}
+		c.spec = c.AnsibleDeploymentSpec
+
 	case KindPiped:
 		c.PipedSpec = &PipedSpec{}
 		c.spec = c.PipedSpec
@@ -145,6 +175,10 @@ func (c *Config) init(kind Kind, apiVersion string) error {
 		c.EventWatcherSpec = &EventWatcherSpec{}
 		c.spec = c.EventWatcherSpec
 
+	case KindApplicationBase:
+		c.ApplicationBaseSpec = &ApplicationBaseSpec{}
+		c.spec = c.ApplicationBaseSpec
+
 	default:
 		return fmt.Errorf("unsupported kind: %s", c.Kind)
 	}
@@ -199,6 +233,26 @@ func (c *Config) Validate() error {
 	if err := spec.Validate(); err != nil {
 		return err
 	}
+	if g, ok := c.GetGenericDeployment(); ok && c.Kind != KindKubernetesApp {
+		if err := validateKubernetesOnlyFields(g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateKubernetesOnlyFields returns an error when the fields
+// supported only by Kubernetes application were configured.
+func validateKubernetesOnlyFields(s GenericDeploymentSpec) error {
+	if s.SignatureVerification.Images {
+		return fmt.Errorf("signatureVerification.images is supported only by %s", KindKubernetesApp)
+	}
+	if e := s.VersionExtraction; e != nil {
+		switch e.Source {
+		case VersionSourceContainerImage, VersionSourceHelmChart:
+			return fmt.Errorf("versionExtraction.source %s is supported only by %s", e.Source, KindKubernetesApp)
+		}
+	}
 	return nil
 }
 
@@ -246,6 +300,12 @@ func ToApplicationKind(k Kind) (model.ApplicationKind, bool) {
 		return model.ApplicationKind_CLOUDRUN, true
 	case KindECSApp:
 		return model.ApplicationKind_ECS, true
+	case KindVMApp:
+		return model.ApplicationKind_VM, true
+	case KindPulumiApp:
+		return model.ApplicationKind_PULUMI, true
+	case KindAnsibleApp:
+		return model.ApplicationKind_ANSIBLE, true
 	}
 	return model.ApplicationKind_KUBERNETES, false
 }
@@ -262,6 +322,12 @@ func (c *Config) GetGenericDeployment() (GenericDeploymentSpec, bool) {
 		return c.LambdaDeploymentSpec.GenericDeploymentSpec, true
 	case KindECSApp:
 		return c.ECSDeploymentSpec.GenericDeploymentSpec, true
+	case KindVMApp:
+		return c.VMDeploymentSpec.GenericDeploymentSpec, true
+	case KindPulumiApp:
+		return c.PulumiDeploymentSpec.GenericDeploymentSpec, true
+	case KindAnsibleApp:
+		return c.AnsibleDeploymentSpec.GenericDeploymentSpec, true
 	}
 	return GenericDeploymentSpec{}, false
 }
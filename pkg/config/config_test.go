@@ -75,3 +75,72 @@ func TestUnmarshalConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeYAMLKubernetesOnlyFields(t *testing.T) {
+	testcases := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "image signatures of KubernetesApp",
+			data: `
+apiVersion: pipecd.dev/v1beta1
+kind: KubernetesApp
+spec:
+  signatureVerification:
+    images: true
+`,
+		},
+		{
+			name: "commit signature of CloudRunApp",
+			data: `
+apiVersion: pipecd.dev/v1beta1
+kind: CloudRunApp
+spec:
+  signatureVerification:
+    commit: true
+`,
+		},
+		{
+			name: "image signatures of CloudRunApp",
+			data: `
+apiVersion: pipecd.dev/v1beta1
+kind: CloudRunApp
+spec:
+  signatureVerification:
+    images: true
+`,
+			wantErr: true,
+		},
+		{
+			name: "file version of CloudRunApp",
+			data: `
+apiVersion: pipecd.dev/v1beta1
+kind: CloudRunApp
+spec:
+  versionExtraction:
+    source: FILE
+    file: VERSION
+`,
+		},
+		{
+			name: "container image version of CloudRunApp",
+			data: `
+apiVersion: pipecd.dev/v1beta1
+kind: CloudRunApp
+spec:
+  versionExtraction:
+    source: CONTAINER_IMAGE
+    containerName: helloworld
+`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DecodeYAML([]byte(tc.data))
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
@@ -17,6 +17,7 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/golang/protobuf/jsonpb"
@@ -45,12 +46,164 @@ type ControlPlaneSpec struct {
 	Projects []ControlPlaneProject `json:"projects"`
 	// List of shared SSO configurations that can be used by any projects.
 	SharedSSOConfigs []SharedSSOConfig `json:"sharedSSOConfigs"`
+	// The configuration of the endpoints receiving push events from Git hosting services.
+	GitWebhook ControlPlaneGitWebhook `json:"gitWebhook"`
+	// The quotas limiting the resources each project can have.
+	Quotas ControlPlaneQuotas `json:"quotas"`
+	// The rate limits applied to the requests to the public API.
+	RateLimit ControlPlaneRateLimit `json:"rateLimit"`
+	// How long the events registered with the same idempotency key are deduplicated.
+	EventDeduplicationWindow Duration `json:"eventDeduplicationWindow" default:"1h"`
 }
 
 func (s *ControlPlaneSpec) Validate() error {
+	if err := s.Quotas.Validate(); err != nil {
+		return err
+	}
+	if err := s.RateLimit.Validate(); err != nil {
+		return err
+	}
+	if s.EventDeduplicationWindow < 0 {
+		return fmt.Errorf("eventDeduplicationWindow must not be negative")
+	}
+	return nil
+}
+
+type ControlPlaneRateLimit struct {
+	// The rate limit applied to the requests made with each API key.
+	APIKey RateLimit `json:"apiKey"`
+	// The rate limit applied to the requests made from each client IP address.
+	IP RateLimit `json:"ip"`
+	// List of IP addresses or CIDR ranges of the proxies, e.g. the load balancer,
+	// which are trusted to set the client IP address in X-Forwarded-For header.
+	// The API gateway of the server itself is always trusted.
+	TrustedProxies []string `json:"trustedProxies"`
+}
+
+func (r *ControlPlaneRateLimit) Validate() error {
+	if err := r.APIKey.Validate(); err != nil {
+		return fmt.Errorf("invalid rate limit for API key: %w", err)
+	}
+	if err := r.IP.Validate(); err != nil {
+		return fmt.Errorf("invalid rate limit for IP address: %w", err)
+	}
+	for _, p := range r.TrustedProxies {
+		if net.ParseIP(p) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(p); err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: must be an IP address or a CIDR range", p)
+		}
+	}
 	return nil
 }
 
+type RateLimit struct {
+	// The number of requests allowed per second on average.
+	// Zero means no limit.
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	// The maximum number of requests allowed at once.
+	// Default is the requestsPerSecond rounded up.
+	Burst int `json:"burst"`
+}
+
+// Enabled reports whether the requests should be limited or not.
+func (r *RateLimit) Enabled() bool {
+	return r.RequestsPerSecond > 0
+}
+
+func (r *RateLimit) Validate() error {
+	if r.RequestsPerSecond < 0 {
+		return fmt.Errorf("requestsPerSecond must not be negative")
+	}
+	if r.Burst < 0 {
+		return fmt.Errorf("burst must not be negative")
+	}
+	return nil
+}
+
+type ControlPlaneQuotas struct {
+	// The quota applied to all projects not listed in projects field.
+	Default ProjectQuota `json:"default"`
+	// List of quotas applied to the specific projects.
+	// These overwrite the default one entirely.
+	Projects []ProjectQuota `json:"projects"`
+}
+
+type ProjectQuota struct {
+	// The ID of the project this quota is applied to.
+	// This must be empty for the default quota.
+	ProjectID string `json:"projectId"`
+	// The maximum number of applications.
+	// Zero means no limit.
+	MaxApplications int `json:"maxApplications"`
+	// The maximum number of registered pipeds.
+	// Zero means no limit.
+	MaxPipeds int `json:"maxPipeds"`
+	// The maximum number of deployments being not completed at the same time.
+	// Zero means no limit.
+	MaxConcurrentDeployments int `json:"maxConcurrentDeployments"`
+	// The maximum number of enabled API keys.
+	// Zero means no limit.
+	MaxAPIKeys int `json:"maxAPIKeys"`
+}
+
+func (q *ControlPlaneQuotas) Validate() error {
+	if q.Default.ProjectID != "" {
+		return fmt.Errorf("projectId must be empty for the default quota")
+	}
+	if err := q.Default.Validate(); err != nil {
+		return fmt.Errorf("invalid default quota: %w", err)
+	}
+	ids := make(map[string]struct{}, len(q.Projects))
+	for _, p := range q.Projects {
+		if p.ProjectID == "" {
+			return fmt.Errorf("projectId is required for the quota of a project")
+		}
+		if _, ok := ids[p.ProjectID]; ok {
+			return fmt.Errorf("duplicated quota for project %s", p.ProjectID)
+		}
+		ids[p.ProjectID] = struct{}{}
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("invalid quota for project %s: %w", p.ProjectID, err)
+		}
+	}
+	return nil
+}
+
+// ProjectQuota returns the quota applied to the given project.
+func (q *ControlPlaneQuotas) ProjectQuota(projectID string) ProjectQuota {
+	for _, p := range q.Projects {
+		if p.ProjectID == projectID {
+			return p
+		}
+	}
+	return q.Default
+}
+
+func (q *ProjectQuota) Validate() error {
+	if q.MaxApplications < 0 {
+		return fmt.Errorf("maxApplications must not be negative")
+	}
+	if q.MaxPipeds < 0 {
+		return fmt.Errorf("maxPipeds must not be negative")
+	}
+	if q.MaxConcurrentDeployments < 0 {
+		return fmt.Errorf("maxConcurrentDeployments must not be negative")
+	}
+	if q.MaxAPIKeys < 0 {
+		return fmt.Errorf("maxAPIKeys must not be negative")
+	}
+	return nil
+}
+
+type ControlPlaneGitWebhook struct {
+	// The secret configured in the webhook settings of GitHub or GitLab.
+	// It is used to verify the signature of GitHub and the token of GitLab.
+	// The endpoints are disabled if this is empty.
+	Secret string `json:"secret"`
+}
+
 type ControlPlaneProject struct {
 	// The unique identifier of the project.
 	Id string `json:"id"`
@@ -92,6 +92,21 @@ func TestControlPlaneConfig(t *testing.T) {
 						RetryInterval: Duration(time.Hour),
 					},
 				},
+				Quotas: ControlPlaneQuotas{
+					Default: ProjectQuota{
+						MaxApplications: 100,
+						MaxPipeds:       10,
+					},
+					Projects: []ProjectQuota{
+						{
+							ProjectID:                "abc",
+							MaxApplications:          500,
+							MaxConcurrentDeployments: 20,
+							MaxAPIKeys:               5,
+						},
+					},
+				},
+				EventDeduplicationWindow: Duration(time.Hour),
 			},
 		},
 	}
@@ -115,3 +130,103 @@ func TestControlPlaneConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestControlPlaneQuotas(t *testing.T) {
+	quotas := ControlPlaneQuotas{
+		Default: ProjectQuota{
+			MaxApplications: 100,
+		},
+		Projects: []ProjectQuota{
+			{
+				ProjectID: "abc",
+				MaxPipeds: 5,
+			},
+		},
+	}
+	require.NoError(t, quotas.Validate())
+	assert.Equal(t, ProjectQuota{ProjectID: "abc", MaxPipeds: 5}, quotas.ProjectQuota("abc"))
+	assert.Equal(t, ProjectQuota{MaxApplications: 100}, quotas.ProjectQuota("xyz"))
+
+	testcases := []struct {
+		name   string
+		quotas ControlPlaneQuotas
+	}{
+		{
+			name: "negative limit",
+			quotas: ControlPlaneQuotas{
+				Default: ProjectQuota{MaxAPIKeys: -1},
+			},
+		},
+		{
+			name: "missing project id",
+			quotas: ControlPlaneQuotas{
+				Projects: []ProjectQuota{{MaxPipeds: 1}},
+			},
+		},
+		{
+			name: "duplicated project",
+			quotas: ControlPlaneQuotas{
+				Projects: []ProjectQuota{{ProjectID: "abc"}, {ProjectID: "abc"}},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Error(t, tc.quotas.Validate())
+		})
+	}
+}
+
+func TestControlPlaneRateLimit(t *testing.T) {
+	testcases := []struct {
+		name      string
+		rateLimit ControlPlaneRateLimit
+		expectErr bool
+	}{
+		{
+			name: "no limit",
+		},
+		{
+			name: "valid limits",
+			rateLimit: ControlPlaneRateLimit{
+				APIKey: RateLimit{RequestsPerSecond: 10, Burst: 20},
+				IP:     RateLimit{RequestsPerSecond: 0.5},
+			},
+		},
+		{
+			name: "negative rate",
+			rateLimit: ControlPlaneRateLimit{
+				APIKey: RateLimit{RequestsPerSecond: -1},
+			},
+			expectErr: true,
+		},
+		{
+			name: "negative burst",
+			rateLimit: ControlPlaneRateLimit{
+				IP: RateLimit{RequestsPerSecond: 1, Burst: -1},
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid trusted proxies",
+			rateLimit: ControlPlaneRateLimit{
+				IP:             RateLimit{RequestsPerSecond: 1},
+				TrustedProxies: []string{"10.0.0.1", "10.1.0.0/16", "fd00::/8"},
+			},
+		},
+		{
+			name: "invalid trusted proxy",
+			rateLimit: ControlPlaneRateLimit{
+				IP:             RateLimit{RequestsPerSecond: 1},
+				TrustedProxies: []string{"load-balancer"},
+			},
+			expectErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rateLimit.Validate()
+			assert.Equal(t, tc.expectErr, err != nil)
+		})
+	}
+}
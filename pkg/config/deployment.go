@@ -17,8 +17,12 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/pipe-cd/pipe/pkg/filematcher"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
@@ -37,13 +41,262 @@ type GenericDeploymentSpec struct {
 	// List of directories or files where their changes will trigger the deployment.
 	// Regular expression can be used.
 	TriggerPaths []string `json:"triggerPaths,omitempty"`
+	// The minimum interval between the automatic deployments of the application.
+	// The commits pushed during the cooldown are deployed together
+	// as one deployment of the latest commit once the cooldown ended.
+	// Zero means no cooldown.
+	TriggerCooldown Duration `json:"triggerCooldown,omitempty"`
 	// The maximum length of time to execute deployment before giving up.
 	// Default is 6h.
 	Timeout Duration `json:"timeout,omitempty" default:"6h"`
 	// List of encrypted secrets and targets that should be decoded before using.
 	Encryption *SecretEncryption `json:"encryption"`
+	// List of names of the locks the deployment must hold while running.
+	// The deployments of the applications sharing a lock name are not run at the same time
+	// even when they are handled by different pipeds in the same project.
+	Locks []string `json:"locks"`
+	// What to do with the older deployments when a newer one was triggered
+	// while they are queued or running.
+	// Empty means all triggered deployments are run one by one.
+	SupersedePolicy SupersedePolicy `json:"supersedePolicy"`
+	// The signatures must be verified before planning the deployments.
+	// The trusted keys are configured in the piped configuration.
+	SignatureVerification SignatureVerification `json:"signatureVerification"`
+	// The commands or HTTP calls executed by piped before planning
+	// and after finishing the deployment.
+	Hooks DeploymentHooks `json:"hooks"`
+	// The PagerDuty service linked to the application.
+	PagerDuty *DeploymentPagerDuty `json:"pagerDuty"`
+	// Post the commit status on the trigger commit when the deployment started and completed.
+	CommitStatus *DeploymentCommitStatus `json:"commitStatus"`
+	// How to extract the version of the application shown on the web console and the notifications.
+	// Empty means the default one of each application kind is used,
+	// e.g. the image tag of the first container of the first Deployment for Kubernetes application.
+	VersionExtraction *VersionExtraction `json:"versionExtraction"`
 }
 
+type VersionExtraction struct {
+	// Where to extract the version from.
+	Source VersionSource `json:"source"`
+	// The name of the container whose image tag is used as the version.
+	// Required when the source is CONTAINER_IMAGE.
+	ContainerName string `json:"containerName"`
+	// The path to the file relative to the application directory.
+	// Required when the source is FILE.
+	File string `json:"file"`
+	// The regular expression applied to the extracted value.
+	// Its first capturing group is used as the version.
+	// Empty means the whole value is used.
+	Pattern string `json:"pattern"`
+}
+
+type VersionSource string
+
+const (
+	// The image tag of the named container.
+	// Currently, only Kubernetes application is supported.
+	VersionSourceContainerImage VersionSource = "CONTAINER_IMAGE"
+	// The version of the Helm chart configured in the input.
+	// Currently, only Kubernetes application is supported.
+	VersionSourceHelmChart VersionSource = "HELM_CHART"
+	// The content of a file placed in the application directory.
+	VersionSourceFile VersionSource = "FILE"
+)
+
+func (e *VersionExtraction) Validate() error {
+	switch e.Source {
+	case VersionSourceContainerImage:
+		if e.ContainerName == "" {
+			return fmt.Errorf("versionExtraction.containerName must be set for %s source", e.Source)
+		}
+	case VersionSourceHelmChart:
+	case VersionSourceFile:
+		if e.File == "" {
+			return fmt.Errorf("versionExtraction.file must be set for %s source", e.Source)
+		}
+		if filepath.IsAbs(e.File) {
+			return fmt.Errorf("versionExtraction.file must be a relative path")
+		}
+		if f := filepath.ToSlash(filepath.Clean(e.File)); f == ".." || strings.HasPrefix(f, "../") {
+			return fmt.Errorf("versionExtraction.file must not be outside of the application directory")
+		}
+	default:
+		return fmt.Errorf("unsupported versionExtraction.source %q", e.Source)
+	}
+	if e.Pattern != "" {
+		r, err := regexp.Compile(e.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid versionExtraction.pattern (%w)", err)
+		}
+		if r.NumSubexp() == 0 {
+			return fmt.Errorf("versionExtraction.pattern must contain a capturing group")
+		}
+	}
+	return nil
+}
+
+// ExtractFrom returns the version from the given value by applying the configured pattern.
+func (e *VersionExtraction) ExtractFrom(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if e.Pattern == "" {
+		if value == "" {
+			return "", fmt.Errorf("extracted version is empty")
+		}
+		return value, nil
+	}
+	r, err := regexp.Compile(e.Pattern)
+	if err != nil {
+		return "", err
+	}
+	matches := r.FindStringSubmatch(value)
+	if len(matches) < 2 || matches[1] == "" {
+		return "", fmt.Errorf("pattern %q did not match", e.Pattern)
+	}
+	return matches[1], nil
+}
+
+type DeploymentCommitStatus struct {
+	// The name of commit status provider configured in the piped configuration.
+	Provider string `json:"provider"`
+	// The label to differentiate the status from the other ones of the commit.
+	// Default is pipecd/<application-name>.
+	Context string `json:"context"`
+}
+
+func (c *DeploymentCommitStatus) Validate() error {
+	if c.Provider == "" {
+		return fmt.Errorf("commitStatus.provider must be set")
+	}
+	return nil
+}
+
+type DeploymentPagerDuty struct {
+	// The ID of the PagerDuty service linked to the application.
+	ServiceID string `json:"serviceID"`
+	// Whether to send the change events to the service when the deployment started and finished.
+	// The routing key of the service must be configured in the piped configuration.
+	ChangeEvents bool `json:"changeEvents"`
+	// Whether to refuse triggering the deployments automatically
+	// while the service is in an ongoing maintenance window.
+	BlockDuringMaintenance bool `json:"blockDuringMaintenance"`
+	// Whether to refuse triggering the deployments automatically
+	// while the service has a triggered or acknowledged incident.
+	BlockDuringIncidents bool `json:"blockDuringIncidents"`
+}
+
+func (p *DeploymentPagerDuty) Validate() error {
+	if p.ServiceID == "" {
+		return fmt.Errorf("pagerDuty.serviceID must be set")
+	}
+	return nil
+}
+
+type SignatureVerification struct {
+	// Whether the commit triggering the deployment must be signed
+	// by one of the trusted GPG or SSH keys.
+	Commit bool `json:"commit"`
+	// Whether the container images referenced by the manifests must be signed
+	// by one of the trusted cosign keys.
+	// Only Kubernetes application is supported, enabling it for the other kinds is an error.
+	Images bool `json:"images"`
+}
+
+type DeploymentHooks struct {
+	// The hooks executed one by one before planning the deployment.
+	// The deployment fails when one of them failed.
+	PreSync []DeploymentHook `json:"preSync"`
+	// The hooks executed after the deployment finished.
+	PostSync PostSyncHooks `json:"postSync"`
+}
+
+type PostSyncHooks struct {
+	// The hooks executed when the deployment succeeded.
+	OnSuccess []DeploymentHook `json:"onSuccess"`
+	// The hooks executed when the deployment failed or was cancelled.
+	OnFailure []DeploymentHook `json:"onFailure"`
+}
+
+func (h DeploymentHooks) Validate() error {
+	phases := []struct {
+		name  string
+		hooks []DeploymentHook
+	}{
+		{"preSync", h.PreSync},
+		{"postSync.onSuccess", h.PostSync.OnSuccess},
+		{"postSync.onFailure", h.PostSync.OnFailure},
+	}
+	for _, p := range phases {
+		names := make(map[string]struct{}, len(p.hooks))
+		for _, hook := range p.hooks {
+			if err := hook.Validate(); err != nil {
+				return fmt.Errorf("invalid hook in hooks.%s: %w", p.name, err)
+			}
+			if _, ok := names[hook.Name]; ok {
+				return fmt.Errorf("duplicated hook name %s in hooks.%s", hook.Name, p.name)
+			}
+			names[hook.Name] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// DeploymentHook represents a command or an HTTP call executed by piped.
+// Exactly one of run and http must be specified.
+type DeploymentHook struct {
+	// The name of the hook. It is used as a part of the metadata keys of its output.
+	Name string `json:"name"`
+	// The shell script executed by "/bin/sh -c" in the application directory.
+	Run string `json:"run"`
+	// The HTTP request to send.
+	HTTP *DeploymentHookHTTP `json:"http"`
+	// How long to wait for the hook to finish.
+	// Empty means 5 minutes.
+	Timeout Duration `json:"timeout"`
+	// Whether to continue even when this hook failed.
+	// The failure of post-sync hooks never changes the status of the deployment.
+	ContinueOnError bool `json:"continueOnError"`
+}
+
+func (h DeploymentHook) Validate() error {
+	if h.Name == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	if (h.Run == "") == (h.HTTP == nil) {
+		return fmt.Errorf("exactly one of run and http must be specified for hook %s", h.Name)
+	}
+	if h.HTTP != nil && h.HTTP.URL == "" {
+		return fmt.Errorf("http.url must not be empty for hook %s", h.Name)
+	}
+	return nil
+}
+
+type DeploymentHookHTTP struct {
+	// The URL to send the request to.
+	// The environment variables such as $PIPECD_DEPLOYMENT_ID are expanded.
+	URL string `json:"url"`
+	// The HTTP method of the request.
+	// Empty means POST.
+	Method string `json:"method"`
+	// The headers of the request.
+	// The environment variables are expanded in the values
+	// so that the credentials can be passed from the piped environment.
+	Headers map[string]string `json:"headers"`
+	// The body of the request.
+	// The environment variables are expanded as well.
+	Body string `json:"body"`
+}
+
+type SupersedePolicy string
+
+const (
+	// Only the latest one of the queued deployments is run
+	// and the others are cancelled.
+	SupersedePolicyQueueLatest SupersedePolicy = "QUEUE_LATEST"
+	// The running deployment is cancelled as well as the older queued ones
+	// to start the latest one as soon as possible.
+	SupersedePolicyCancelRunning SupersedePolicy = "CANCEL_RUNNING"
+)
+
 func (s *GenericDeploymentSpec) Validate() error {
 	if s.Pipeline != nil {
 		for _, stage := range s.Pipeline.Stages {
@@ -52,6 +305,21 @@ func (s *GenericDeploymentSpec) Validate() error {
 					return err
 				}
 			}
+			if stage.FeatureFlagStageOptions != nil {
+				if err := stage.FeatureFlagStageOptions.Validate(); err != nil {
+					return err
+				}
+			}
+			if stage.WaitStageOptions != nil {
+				if err := stage.WaitStageOptions.Validate(); err != nil {
+					return err
+				}
+			}
+			if stage.WaitApprovalStageOptions != nil {
+				if err := stage.WaitApprovalStageOptions.Validate(); err != nil {
+					return err
+				}
+			}
 		}
 	}
 
@@ -61,6 +329,42 @@ func (s *GenericDeploymentSpec) Validate() error {
 		}
 	}
 
+	if s.TriggerCooldown < 0 {
+		return fmt.Errorf("triggerCooldown must not be negative")
+	}
+
+	for _, l := range s.Locks {
+		if l == "" {
+			return fmt.Errorf("lock name must not be empty")
+		}
+	}
+
+	switch s.SupersedePolicy {
+	case "", SupersedePolicyQueueLatest, SupersedePolicyCancelRunning:
+	default:
+		return fmt.Errorf("unsupported supersedePolicy %s", s.SupersedePolicy)
+	}
+
+	if err := s.Hooks.Validate(); err != nil {
+		return err
+	}
+
+	if s.CommitStatus != nil {
+		if err := s.CommitStatus.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.PagerDuty != nil {
+		if err := s.PagerDuty.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.VersionExtraction != nil {
+		if err := s.VersionExtraction.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -114,30 +418,52 @@ type PipelineStage struct {
 	WaitStageOptions         *WaitStageOptions
 	WaitApprovalStageOptions *WaitApprovalStageOptions
 	AnalysisStageOptions     *AnalysisStageOptions
+	FeatureFlagStageOptions  *FeatureFlagStageOptions
 
-	K8sPrimaryRolloutStageOptions  *K8sPrimaryRolloutStageOptions
-	K8sCanaryRolloutStageOptions   *K8sCanaryRolloutStageOptions
-	K8sCanaryCleanStageOptions     *K8sCanaryCleanStageOptions
-	K8sBaselineRolloutStageOptions *K8sBaselineRolloutStageOptions
-	K8sBaselineCleanStageOptions   *K8sBaselineCleanStageOptions
-	K8sTrafficRoutingStageOptions  *K8sTrafficRoutingStageOptions
+	K8sPrimaryRolloutStageOptions    *K8sPrimaryRolloutStageOptions
+	K8sCanaryRolloutStageOptions     *K8sCanaryRolloutStageOptions
+	K8sCanaryCleanStageOptions       *K8sCanaryCleanStageOptions
+	K8sBaselineRolloutStageOptions   *K8sBaselineRolloutStageOptions
+	K8sBaselineCleanStageOptions     *K8sBaselineCleanStageOptions
+	K8sTrafficRoutingStageOptions    *K8sTrafficRoutingStageOptions
+	K8sPolicyCheckStageOptions       *K8sPolicyCheckStageOptions
+	K8sVulnerabilityScanStageOptions *K8sVulnerabilityScanStageOptions
 
 	TerraformSyncStageOptions  *TerraformSyncStageOptions
 	TerraformPlanStageOptions  *TerraformPlanStageOptions
 	TerraformApplyStageOptions *TerraformApplyStageOptions
 
-	CloudRunSyncStageOptions    *CloudRunSyncStageOptions
-	CloudRunPromoteStageOptions *CloudRunPromoteStageOptions
+	CloudRunSyncStageOptions        *CloudRunSyncStageOptions
+	CloudRunPromoteStageOptions     *CloudRunPromoteStageOptions
+	CloudRunJobRunStageOptions      *CloudRunJobRunStageOptions
+	CloudRunRevisionTagStageOptions *CloudRunRevisionTagStageOptions
 
-	LambdaSyncStageOptions          *LambdaSyncStageOptions
-	LambdaCanaryRolloutStageOptions *LambdaCanaryRolloutStageOptions
-	LambdaPromoteStageOptions       *LambdaPromoteStageOptions
+	LambdaSyncStageOptions           *LambdaSyncStageOptions
+	LambdaCanaryRolloutStageOptions  *LambdaCanaryRolloutStageOptions
+	LambdaPromoteStageOptions        *LambdaPromoteStageOptions
+	LambdaTrafficRoutingStageOptions *LambdaTrafficRoutingStageOptions
 
 	ECSSyncStageOptions           *ECSSyncStageOptions
 	ECSCanaryRolloutStageOptions  *ECSCanaryRolloutStageOptions
 	ECSPrimaryRolloutStageOptions *ECSPrimaryRolloutStageOptions
 	ECSCanaryCleanStageOptions    *ECSCanaryCleanStageOptions
 	ECSTrafficRoutingStageOptions *ECSTrafficRoutingStageOptions
+
+	VMSyncStageOptions           *VMSyncStageOptions
+	VMCanaryRolloutStageOptions  *VMCanaryRolloutStageOptions
+	VMPrimaryRolloutStageOptions *VMPrimaryRolloutStageOptions
+	VMCanaryCleanStageOptions    *VMCanaryCleanStageOptions
+
+	PulumiSyncStageOptions    *PulumiSyncStageOptions
+	PulumiPreviewStageOptions *PulumiPreviewStageOptions
+	PulumiUpStageOptions      *PulumiUpStageOptions
+
+	AnsibleSyncStageOptions  *AnsibleSyncStageOptions
+	AnsibleCheckStageOptions *AnsibleCheckStageOptions
+
+	// The raw options of a stage provided by a piped plugin.
+	// They are passed to the plugin as is.
+	PluginStageOptions json.RawMessage
 }
 
 type genericPipelineStage struct {
@@ -183,6 +509,14 @@ func (s *PipelineStage) UnmarshalJSON(data []byte) error {
 				s.AnalysisStageOptions.Metrics[i].Timeout = defaultAnalysisQueryTimeout
 			}
 		}
+		if g := s.AnalysisStageOptions.TrafficGuard; g != nil && g.Timeout <= 0 {
+			g.Timeout = defaultAnalysisQueryTimeout
+		}
+	case model.StageFeatureFlag:
+		s.FeatureFlagStageOptions = &FeatureFlagStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.FeatureFlagStageOptions)
+		}
 	case model.StageK8sPrimaryRollout:
 		s.K8sPrimaryRolloutStageOptions = &K8sPrimaryRolloutStageOptions{}
 		if len(gs.With) > 0 {
@@ -213,6 +547,22 @@ func (s *PipelineStage) UnmarshalJSON(data []byte) error {
 		if len(gs.With) > 0 {
 			err = json.Unmarshal(gs.With, s.K8sTrafficRoutingStageOptions)
 		}
+	case model.StageK8sPolicyCheck:
+		s.K8sPolicyCheckStageOptions = &K8sPolicyCheckStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.K8sPolicyCheckStageOptions)
+		}
+		if s.K8sPolicyCheckStageOptions.Engine == "" {
+			s.K8sPolicyCheckStageOptions.Engine = PolicyEngineOPA
+		}
+	case model.StageK8sVulnerabilityScan:
+		s.K8sVulnerabilityScanStageOptions = &K8sVulnerabilityScanStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.K8sVulnerabilityScanStageOptions)
+		}
+		if s.K8sVulnerabilityScanStageOptions.Severity == "" {
+			s.K8sVulnerabilityScanStageOptions.Severity = VulnerabilitySeverityHigh
+		}
 
 	case model.StageTerraformSync:
 		s.TerraformSyncStageOptions = &TerraformSyncStageOptions{}
@@ -240,6 +590,16 @@ func (s *PipelineStage) UnmarshalJSON(data []byte) error {
 		if len(gs.With) > 0 {
 			err = json.Unmarshal(gs.With, s.CloudRunPromoteStageOptions)
 		}
+	case model.StageCloudRunJobRun:
+		s.CloudRunJobRunStageOptions = &CloudRunJobRunStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.CloudRunJobRunStageOptions)
+		}
+	case model.StageCloudRunRevisionTag:
+		s.CloudRunRevisionTagStageOptions = &CloudRunRevisionTagStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.CloudRunRevisionTagStageOptions)
+		}
 
 	case model.StageLambdaSync:
 		s.LambdaSyncStageOptions = &LambdaSyncStageOptions{}
@@ -256,6 +616,11 @@ func (s *PipelineStage) UnmarshalJSON(data []byte) error {
 		if len(gs.With) > 0 {
 			err = json.Unmarshal(gs.With, s.LambdaCanaryRolloutStageOptions)
 		}
+	case model.StageLambdaTrafficRouting:
+		s.LambdaTrafficRoutingStageOptions = &LambdaTrafficRoutingStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.LambdaTrafficRoutingStageOptions)
+		}
 
 	case model.StageECSSync:
 		s.ECSSyncStageOptions = &ECSSyncStageOptions{}
@@ -283,15 +648,81 @@ func (s *PipelineStage) UnmarshalJSON(data []byte) error {
 			err = json.Unmarshal(gs.With, s.ECSTrafficRoutingStageOptions)
 		}
 
+	case model.StageVMSync:
+		s.VMSyncStageOptions = &VMSyncStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.VMSyncStageOptions)
+		}
+	case model.StageVMCanaryRollout:
+		s.VMCanaryRolloutStageOptions = &VMCanaryRolloutStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.VMCanaryRolloutStageOptions)
+		}
+	case model.StageVMPrimaryRollout:
+		s.VMPrimaryRolloutStageOptions = &VMPrimaryRolloutStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.VMPrimaryRolloutStageOptions)
+		}
+	case model.StageVMCanaryClean:
+		s.VMCanaryCleanStageOptions = &VMCanaryCleanStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.VMCanaryCleanStageOptions)
+		}
+
+	case model.StagePulumiSync:
+		s.PulumiSyncStageOptions = &PulumiSyncStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.PulumiSyncStageOptions)
+		}
+	case model.StagePulumiPreview:
+		s.PulumiPreviewStageOptions = &PulumiPreviewStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.PulumiPreviewStageOptions)
+		}
+	case model.StagePulumiUp:
+		s.PulumiUpStageOptions = &PulumiUpStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.PulumiUpStageOptions)
+		}
+
+	case model.StageAnsibleSync:
+		s.AnsibleSyncStageOptions = &AnsibleSyncStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.AnsibleSyncStageOptions)
+		}
+	case model.StageAnsibleCheck:
+		s.AnsibleCheckStageOptions = &AnsibleCheckStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.AnsibleCheckStageOptions)
+		}
+
 	default:
-		err = fmt.Errorf("unsupported stage name: %s", s.Name)
+		if !s.Name.IsPlugin() {
+			err = fmt.Errorf("unsupported stage name: %s", s.Name)
+			break
+		}
+		s.PluginStageOptions = gs.With
 	}
 	return err
 }
 
 // WaitStageOptions contains all configurable values for a WAIT stage.
+// At most one of duration, until, cron and window can be specified.
 type WaitStageOptions struct {
+	// How long to wait.
+	// Default is 1m when none of the others is specified.
 	Duration Duration `json:"duration"`
+	// The time to wait until, in RFC3339 format, e.g. 2021-04-01T09:00:00+09:00.
+	Until string `json:"until"`
+	// A cron expression in the standard 5-field format, e.g. "0 9 * * MON-FRI".
+	// The stage waits until the next time matching this expression.
+	Cron string `json:"cron"`
+	// The window of time, e.g. business hours, inside which the stage completes.
+	// The stage waits until the next start of the window if it is not open now.
+	Window *WaitWindow `json:"window"`
+	// The IANA name of the time zone used to evaluate cron and window, e.g. Asia/Tokyo.
+	// Default is UTC.
+	TimeZone string `json:"timeZone"`
 }
 
 // WaitStageOptions contains all configurable values for a WAIT_APPROVAL stage.
@@ -300,6 +731,141 @@ type WaitApprovalStageOptions struct {
 	// Defaults to 6h.
 	Timeout   Duration `json:"timeout"`
 	Approvers []string `json:"approvers"`
+	// Whether a comment explaining the reason is required
+	// while approving or rejecting the stage.
+	// Default is false.
+	RequireComment bool `json:"requireComment"`
+	// Configuration for the change ticket managed in an external change management system.
+	// When specified, the stage also waits until the ticket is approved in that system.
+	ChangeTicket *ChangeTicketOptions `json:"changeTicket"`
+	// Configuration for escalating the approval
+	// when none of the approvers responds in time.
+	Escalation *ApprovalEscalation `json:"escalation"`
+}
+
+func (o *WaitApprovalStageOptions) Validate() error {
+	if o.ChangeTicket != nil {
+		if err := o.ChangeTicket.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.Escalation != nil {
+		if err := o.Escalation.Validate(); err != nil {
+			return err
+		}
+		if o.Timeout > 0 && o.Escalation.After >= o.Timeout {
+			return fmt.Errorf("escalation.after must be shorter than timeout of WAIT_APPROVAL stage")
+		}
+	}
+	return nil
+}
+
+type ApprovalEscalationAction string
+
+const (
+	// Only notify the fallback approvers and keep waiting for an approval.
+	ApprovalEscalationActionNotify ApprovalEscalationAction = "NOTIFY"
+	// Approve the stage automatically.
+	ApprovalEscalationActionApprove ApprovalEscalationAction = "APPROVE"
+	// Reject the stage automatically to fail the deployment.
+	ApprovalEscalationActionReject ApprovalEscalationAction = "REJECT"
+)
+
+// ApprovalEscalation contains configurable values for escalating a WAIT_APPROVAL stage.
+type ApprovalEscalation struct {
+	// How long to wait for an approval from the approvers before escalating.
+	After Duration `json:"after"`
+	// List of users who are delegated to approve the stage once escalated.
+	// They are mentioned in the escalation notification.
+	FallbackApprovers []string `json:"fallbackApprovers"`
+	// What to do once escalated.
+	// Default is NOTIFY.
+	Action ApprovalEscalationAction `json:"action"`
+}
+
+func (e *ApprovalEscalation) Validate() error {
+	if e.After <= 0 {
+		return fmt.Errorf("escalation.after must be greater than zero")
+	}
+	switch e.Action {
+	case "", ApprovalEscalationActionNotify:
+		if len(e.FallbackApprovers) == 0 {
+			return fmt.Errorf("escalation.fallbackApprovers must be set when escalation.action is %s", ApprovalEscalationActionNotify)
+		}
+	case ApprovalEscalationActionApprove, ApprovalEscalationActionReject:
+	default:
+		return fmt.Errorf("unsupported escalation.action %s", e.Action)
+	}
+	return nil
+}
+
+// ChangeTicketOptions contains configurable values for the change ticket of a deployment.
+type ChangeTicketOptions struct {
+	// The name of the change management provider configured in the piped configuration.
+	Provider string `json:"provider"`
+	// The ID of an existing ticket to be referenced.
+	// Jira issue key or ServiceNow change request number.
+	// Empty means a new ticket is created from the template.
+	Ticket string `json:"ticket"`
+	// The template used to create a new ticket.
+	Template ChangeTicketTemplate `json:"template"`
+	// Whether to leave the ticket as is when the deployment is completed.
+	// Default is false, meaning the result of the deployment is recorded into the ticket.
+	KeepOpen bool `json:"keepOpen"`
+}
+
+func (o *ChangeTicketOptions) Validate() error {
+	if o.Provider == "" {
+		return fmt.Errorf("changeTicket.provider must be set")
+	}
+	return nil
+}
+
+// ChangeTicketTemplate represents the content of the ticket to be created.
+// Each value is a Go template which can refer the deployment data
+// such as {{ .Deployment.ApplicationName }} or {{ .EnvName }}.
+type ChangeTicketTemplate struct {
+	// The summary of the ticket.
+	Summary string `json:"summary"`
+	// The description of the ticket.
+	Description string `json:"description"`
+	// Additional fields of the ticket specific to the provider.
+	Fields map[string]string `json:"fields"`
+}
+
+// FeatureFlagStageOptions contains all configurable values for a FEATURE_FLAG stage.
+type FeatureFlagStageOptions struct {
+	// The name of the feature flag provider configured in the piped configuration.
+	Provider string `json:"provider"`
+	// The key of the flag.
+	Flag string `json:"flag"`
+	// The key of the project containing the flag.
+	// Required by LaunchDarkly.
+	Project string `json:"project"`
+	// The key of the environment where the flag is changed.
+	Environment string `json:"environment"`
+	// The percentage of users served the enabled variation.
+	// 0 means the flag is turned off.
+	Percentage Percentage `json:"percentage"`
+	// Whether to keep the flag as is when the deployment was rolled back.
+	// Default is false, meaning the flag is restored to the state before the deployment.
+	SkipRollback bool `json:"skipRollback"`
+}
+
+func (o *FeatureFlagStageOptions) Validate() error {
+	if o.Provider == "" {
+		return fmt.Errorf("provider of FEATURE_FLAG stage must be set")
+	}
+	if o.Flag == "" {
+		return fmt.Errorf("flag of FEATURE_FLAG stage must be set")
+	}
+	if o.Environment == "" {
+		return fmt.Errorf("environment of FEATURE_FLAG stage must be set")
+	}
+	if p := o.Percentage.Int(); p < 0 || p > 100 {
+		return fmt.Errorf("percentage of FEATURE_FLAG stage must be between 0 and 100")
+	}
+	return nil
 }
 
 // AnalysisStageOptions contains all configurable values for a K8S_ANALYSIS stage.
@@ -312,13 +878,30 @@ type AnalysisStageOptions struct {
 	Metrics          []TemplatableAnalysisMetrics `json:"metrics"`
 	Logs             []TemplatableAnalysisLog     `json:"logs"`
 	Https            []TemplatableAnalysisHTTP    `json:"https"`
+	Webhooks         []AnalysisWebhook            `json:"webhooks"`
 	Dynamic          AnalysisDynamic              `json:"dynamic"`
+	// Checks that the analyzed variant is receiving enough traffic before starting the analysis.
+	// Nil means the analysis is started regardless of the traffic.
+	TrafficGuard *AnalysisTrafficGuard `json:"trafficGuard"`
 }
 
 func (a *AnalysisStageOptions) Validate() error {
 	if a.Duration == 0 {
 		return fmt.Errorf("the ANALYSIS stage requires duration field")
 	}
+	for i := range a.Webhooks {
+		if err := a.Webhooks[i].Validate(); err != nil {
+			return fmt.Errorf("invalid webhook at index %d: %w", i, err)
+		}
+	}
+	if err := a.Dynamic.Validate(); err != nil {
+		return err
+	}
+	if a.TrafficGuard != nil {
+		if err := a.TrafficGuard.Validate(); err != nil {
+			return fmt.Errorf("invalid trafficGuard: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -374,3 +957,34 @@ func (e *SecretEncryption) Validate() error {
 	}
 	return nil
 }
+
+// IsTouchedByChangedFiles reports whether the application placed in the given directory
+// is affected by the given changed files, that is whether any of them is placed inside
+// the application directory or matches any of the given trigger paths.
+func IsTouchedByChangedFiles(appDir string, triggerPaths []string, changedFiles []string) (bool, error) {
+	if !strings.HasSuffix(appDir, "/") {
+		appDir += "/"
+	}
+
+	// If any files inside the application directory was changed
+	// this application is considered as touched.
+	for _, cf := range changedFiles {
+		if ok := strings.HasPrefix(cf, appDir); ok {
+			return true, nil
+		}
+	}
+
+	// If any changed files matches the specified trigger paths
+	// this application is consided as touched too.
+	for _, change := range triggerPaths {
+		matcher, err := filematcher.NewPatternMatcher([]string{change})
+		if err != nil {
+			return false, err
+		}
+		if matcher.MatchesAny(changedFiles) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
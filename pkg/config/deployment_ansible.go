@@ -0,0 +1,71 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import "errors"
+
+// AnsibleDeploymentSpec represents a deployment configuration for Ansible application.
+type AnsibleDeploymentSpec struct {
+	GenericDeploymentSpec
+	// Input for Ansible deployment such as the playbook and the inventory...
+	Input AnsibleDeploymentInput `json:"input"`
+}
+
+// Validate returns an error if any wrong configuration value was found.
+func (s *AnsibleDeploymentSpec) Validate() error {
+	if err := s.GenericDeploymentSpec.Validate(); err != nil {
+		return err
+	}
+	if s.Input.Inventory == "" {
+		return errors.New("inventory must be set for ansible application")
+	}
+	return nil
+}
+
+type AnsibleDeploymentInput struct {
+	// The path to the playbook file placing in application directory.
+	// Default is playbook.yaml
+	Playbook string `json:"playbook" default:"playbook.yaml"`
+	// The path to the inventory file or directory placing in application directory.
+	Inventory string `json:"inventory"`
+	// Additional variables passed to the playbook.
+	// They take precedence over the variables defined in the inventory.
+	ExtraVars map[string]string `json:"extraVars,omitempty"`
+	// The pattern to further limit the hosts the playbook runs against.
+	// Empty means all hosts targeted by the playbook.
+	Limit string `json:"limit,omitempty"`
+	// Only the tasks and roles tagged with these values are run.
+	// Empty means all tasks are run.
+	Tags []string `json:"tags,omitempty"`
+	// Automatically reverts all changes from all stages when one of them failed
+	// by running the playbook of the last deployed commit.
+	// Default is false.
+	AutoRollback bool `json:"autoRollback"`
+	// Whether to run the playbook of the pull request in check mode to show its plan-preview.
+	// Check mode still runs the lookups, the custom modules and the tasks marked
+	// with check_mode: no against the target hosts, so enable it only when the
+	// authors of pull requests are trusted as much as the deployers.
+	// This value is read from the last deployed commit so that a pull request cannot enable it by itself.
+	// Default is false.
+	EnablePlanPreview bool `json:"enablePlanPreview"`
+}
+
+// AnsibleSyncStageOptions contains all configurable values for a ANSIBLE_SYNC stage.
+type AnsibleSyncStageOptions struct {
+}
+
+// AnsibleCheckStageOptions contains all configurable values for a ANSIBLE_CHECK stage.
+type AnsibleCheckStageOptions struct {
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestAnsibleDeploymentConfig(t *testing.T) {
+	testcases := []struct {
+		fileName           string
+		expectedKind       Kind
+		expectedAPIVersion string
+		expectedSpec       interface{}
+		wantErr            bool
+	}{
+		{
+			fileName:           "testdata/application/ansible-app.yaml",
+			expectedKind:       KindAnsibleApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &AnsibleDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:                     model.StageAnsibleCheck,
+								AnsibleCheckStageOptions: &AnsibleCheckStageOptions{},
+							},
+							{
+								Name: model.StageWaitApproval,
+								WaitApprovalStageOptions: &WaitApprovalStageOptions{
+									Timeout: defaultWaitApprovalTimeout,
+								},
+							},
+							{
+								Name:                    model.StageAnsibleSync,
+								AnsibleSyncStageOptions: &AnsibleSyncStageOptions{},
+							},
+						},
+					},
+				},
+				Input: AnsibleDeploymentInput{
+					Playbook:  "playbook.yaml",
+					Inventory: "inventories/production",
+					ExtraVars: map[string]string{
+						"app_version": "v1.2.0",
+					},
+					Limit:             "web",
+					Tags:              []string{"deploy"},
+					EnablePlanPreview: true,
+				},
+			},
+		},
+		{
+			fileName: "testdata/application/ansible-app-missing-inventory.yaml",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.fileName, func(t *testing.T) {
+			cfg, err := LoadFromYAML(tc.fileName)
+			require.Equal(t, tc.wantErr, err != nil)
+			if err == nil {
+				assert.Equal(t, tc.expectedKind, cfg.Kind)
+				assert.Equal(t, tc.expectedAPIVersion, cfg.APIVersion)
+				assert.Equal(t, tc.expectedSpec, cfg.spec)
+			}
+		})
+	}
+}
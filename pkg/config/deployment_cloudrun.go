@@ -14,6 +14,13 @@
 
 package config
 
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
 // CloudRunDeploymentSpec represents a deployment configuration for CloudRun application.
 type CloudRunDeploymentSpec struct {
 	GenericDeploymentSpec
@@ -28,6 +35,15 @@ func (s *CloudRunDeploymentSpec) Validate() error {
 	if err := s.GenericDeploymentSpec.Validate(); err != nil {
 		return err
 	}
+	if s.Pipeline != nil {
+		for _, stage := range s.Pipeline.Stages {
+			if stage.CloudRunRevisionTagStageOptions != nil {
+				if err := stage.CloudRunRevisionTagStageOptions.Validate(); err != nil {
+					return err
+				}
+			}
+		}
+	}
 	return nil
 }
 
@@ -49,3 +65,64 @@ type CloudRunPromoteStageOptions struct {
 	// Percentage of traffic should be routed to the new version.
 	Percent Percentage `json:"percent"`
 }
+
+// CloudRunJobRunStageOptions contains all configurable values for a CLOUDRUN_JOB_RUN stage.
+type CloudRunJobRunStageOptions struct {
+	// The name of job manifest file placing in application directory.
+	// Default is job.yaml
+	JobManifestFile string `json:"jobManifestFile"`
+	// Whether to stop streaming the logs of the execution to the stage log.
+	// Default is false.
+	SkipLogs bool `json:"skipLogs"`
+}
+
+type CloudRunRevisionTarget string
+
+const (
+	// CloudRunRevisionTargetNew points to the revision of the commit being deployed.
+	CloudRunRevisionTargetNew CloudRunRevisionTarget = "NEW"
+	// CloudRunRevisionTargetRunning points to the revision of the last deployed commit.
+	CloudRunRevisionTargetRunning CloudRunRevisionTarget = "RUNNING"
+)
+
+// The tag is used as the prefix of the subdomain of the tagged URL.
+var cloudRunRevisionTagRegex = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+
+// CloudRunRevisionTagStageOptions contains all configurable values for a CLOUDRUN_REVISION_TAG stage.
+type CloudRunRevisionTagStageOptions struct {
+	// The tags to assign to the revisions.
+	// The tag already assigned to another revision is moved to the specified one.
+	Assign []CloudRunRevisionTag `json:"assign"`
+	// The tags to remove from the service.
+	Remove []string `json:"remove"`
+}
+
+type CloudRunRevisionTag struct {
+	// The name of the tag such as preview, candidate.
+	Tag string `json:"tag"`
+	// The revision the tag points to. Must be one of NEW or RUNNING.
+	// Default is NEW.
+	Revision CloudRunRevisionTarget `json:"revision"`
+}
+
+func (opts *CloudRunRevisionTagStageOptions) Validate() error {
+	if len(opts.Assign) == 0 && len(opts.Remove) == 0 {
+		return fmt.Errorf("either assign or remove must be specified for %s stage", model.StageCloudRunRevisionTag)
+	}
+	for _, t := range opts.Assign {
+		if !cloudRunRevisionTagRegex.MatchString(t.Tag) {
+			return fmt.Errorf("invalid revision tag %q", t.Tag)
+		}
+		switch t.Revision {
+		case "", CloudRunRevisionTargetNew, CloudRunRevisionTargetRunning:
+		default:
+			return fmt.Errorf("unsupported revision %q of tag %s", t.Revision, t.Tag)
+		}
+	}
+	for _, t := range opts.Remove {
+		if t == "" {
+			return fmt.Errorf("revision tag to remove must not be empty")
+		}
+	}
+	return nil
+}
@@ -20,6 +20,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/model"
 )
 
 func TestCloudRunDeploymentConfig(t *testing.T) {
@@ -44,6 +46,38 @@ func TestCloudRunDeploymentConfig(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			fileName:           "testdata/application/cloudrun-app-job.yaml",
+			expectedKind:       KindCloudRunApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &CloudRunDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageCloudRunJobRun,
+								CloudRunJobRunStageOptions: &CloudRunJobRunStageOptions{
+									JobManifestFile: "migration-job.yaml",
+								},
+							},
+							{
+								Name: model.StageCloudRunPromote,
+								CloudRunPromoteStageOptions: &CloudRunPromoteStageOptions{
+									Percent: Percentage{
+										Number: 100,
+									},
+								},
+							},
+						},
+					},
+				},
+				Input: CloudRunDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.fileName, func(t *testing.T) {
@@ -57,3 +91,51 @@ func TestCloudRunDeploymentConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestCloudRunRevisionTagStageOptionsValidate(t *testing.T) {
+	testcases := []struct {
+		name        string
+		opts        CloudRunRevisionTagStageOptions
+		expectedErr bool
+	}{
+		{
+			name: "valid",
+			opts: CloudRunRevisionTagStageOptions{
+				Assign: []CloudRunRevisionTag{
+					{Tag: "preview"},
+					{Tag: "stable", Revision: CloudRunRevisionTargetRunning},
+				},
+				Remove: []string{"candidate"},
+			},
+		},
+		{
+			name:        "empty",
+			opts:        CloudRunRevisionTagStageOptions{},
+			expectedErr: true,
+		},
+		{
+			name: "invalid tag",
+			opts: CloudRunRevisionTagStageOptions{
+				Assign: []CloudRunRevisionTag{
+					{Tag: "Preview_1"},
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "unsupported revision",
+			opts: CloudRunRevisionTagStageOptions{
+				Assign: []CloudRunRevisionTag{
+					{Tag: "preview", Revision: "LATEST"},
+				},
+			},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.Validate()
+			assert.Equal(t, tc.expectedErr, err != nil)
+		})
+	}
+}
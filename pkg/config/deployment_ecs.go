@@ -42,6 +42,9 @@ type ECSDeploymentInput struct {
 	TaskDefinitionFile string `json:"taskDefinitionFile" default:"taskdef.json"`
 	// ECSTargetGroups
 	TargetGroups ECSTargetGroups `json:"targetGroups"`
+	// The ARNs of the ALB listener rules to be modified by ECS_TRAFFIC_ROUTING stage.
+	// When empty, the default actions of the listener of the primary target group are modified instead.
+	ListenerRuleArns []string `json:"listenerRuleArns"`
 	// Automatically reverts all changes from all stages when one of them failed.
 	// Default is true.
 	AutoRollback bool `json:"autoRollback" default:"true"`
@@ -14,6 +14,13 @@
 
 package config
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
 // KubernetesDeploymentSpec represents a deployment configuration for Kubernetes application.
 type KubernetesDeploymentSpec struct {
 	GenericDeploymentSpec
@@ -34,6 +41,14 @@ type KubernetesDeploymentSpec struct {
 	Workloads []K8sResourceReference `json:"workloads"`
 	// Which method should be used for traffic routing.
 	TrafficRouting *KubernetesTrafficRouting `json:"trafficRouting"`
+	// The label used to distinguish the resources of each variant.
+	// Empty means "pipecd.dev/variant" with "primary", "canary", "baseline" values will be used.
+	VariantLabel *KubernetesVariantLabel `json:"variantLabel"`
+	// Whether to leave the CANARY and BASELINE variants as is
+	// when the deployment was cancelled or failed without auto-rollback.
+	// Default is false, meaning all traffic is routed back to PRIMARY variant
+	// and the CANARY, BASELINE variants are removed.
+	KeepVariantsOnFailure bool `json:"keepVariantsOnFailure"`
 }
 
 // Validate returns an error if any wrong configuration value was found.
@@ -41,9 +56,59 @@ func (s *KubernetesDeploymentSpec) Validate() error {
 	if err := s.GenericDeploymentSpec.Validate(); err != nil {
 		return err
 	}
+	if s.Input.HelmOptions != nil && s.Input.HelmOptions.ReleaseMode && s.Input.HelmChart == nil {
+		return fmt.Errorf("helmChart must be set to use helm release mode")
+	}
+	if nm := s.Input.NamespaceManagement; nm != nil {
+		if s.Input.Namespace == "" {
+			return fmt.Errorf("namespace must be set to use namespaceManagement")
+		}
+		if nm.DeleteOnAppDeletion && !nm.Create {
+			return fmt.Errorf("namespaceManagement.deleteOnAppDeletion can be used only when namespaceManagement.create is true")
+		}
+	}
+	if s.VariantLabel != nil {
+		if err := s.VariantLabel.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Pipeline != nil {
+		for _, stage := range s.Pipeline.Stages {
+			if s.Input.IsHelmReleaseMode() && !isHelmReleaseModeStage(stage.Name) {
+				return fmt.Errorf("stage %s can not be used in helm release mode", stage.Name)
+			}
+			if stage.K8sPolicyCheckStageOptions != nil {
+				if err := stage.K8sPolicyCheckStageOptions.Validate(); err != nil {
+					return err
+				}
+			}
+			if stage.K8sVulnerabilityScanStageOptions != nil {
+				if err := stage.K8sVulnerabilityScanStageOptions.Validate(); err != nil {
+					return err
+				}
+			}
+		}
+	}
 	return nil
 }
 
+// isHelmReleaseModeStage reports whether the given stage can be used in helm release mode.
+// The stages deploying the variants or routing the traffic are not allowed
+// because the resources of the release are managed by Helm instead of PipeCD.
+func isHelmReleaseModeStage(stage model.Stage) bool {
+	switch stage {
+	case model.StageK8sPrimaryRollout,
+		model.StageK8sCanaryRollout,
+		model.StageK8sCanaryClean,
+		model.StageK8sBaselineRollout,
+		model.StageK8sBaselineClean,
+		model.StageK8sTrafficRouting,
+		model.StageK8sVariantClean:
+		return false
+	}
+	return true
+}
+
 // KubernetesDeploymentInput represents needed input for triggering a Kubernetes deployment.
 type KubernetesDeploymentInput struct {
 	// List of manifest files in the application directory used to deploy.
@@ -65,13 +130,43 @@ type KubernetesDeploymentInput struct {
 	HelmOptions *InputHelmOptions `json:"helmOptions"`
 
 	// The namespace where manifests will be applied.
+	// The manifests specifying their own namespace are applied to that namespace instead.
 	Namespace string `json:"namespace"`
+	// How the namespace should be managed by PipeCD.
+	// Nil means the namespace must already exist before deploying.
+	NamespaceManagement *KubernetesNamespaceManagement `json:"namespaceManagement"`
+
+	// How long to wait for the resources of a sync wave to become ready
+	// before applying the resources of the next wave.
+	// Default is 5m.
+	SyncWaveTimeout Duration `json:"syncWaveTimeout" default:"5m"`
 
 	// Automatically reverts all deployment changes on failure.
 	// Default is true.
 	AutoRollback bool `json:"autoRollback" default:"true"`
 }
 
+// IsHelmReleaseMode reports whether the chart of the given input
+// should be deployed as a Helm release.
+func (in KubernetesDeploymentInput) IsHelmReleaseMode() bool {
+	return in.HelmChart != nil && in.HelmOptions != nil && in.HelmOptions.ReleaseMode
+}
+
+// KubernetesNamespaceManagement represents how the namespace of an application is managed.
+type KubernetesNamespaceManagement struct {
+	// Whether to create the namespace if it does not exist.
+	// The existing namespace is adopted by applying the configured labels and annotations.
+	Create bool `json:"create"`
+	// Labels to be added to the namespace.
+	Labels map[string]string `json:"labels"`
+	// Annotations to be added to the namespace.
+	Annotations map[string]string `json:"annotations"`
+	// Whether to delete the namespace when the application was deleted from PipeCD.
+	// Only the namespace created by piped is deleted, the adopted ones are kept.
+	// Note that all resources remaining in the namespace are also deleted.
+	DeleteOnAppDeletion bool `json:"deleteOnAppDeletion"`
+}
+
 type InputHelmChart struct {
 	// Git remote address where the chart is placing.
 	// Empty means the same repository.
@@ -98,6 +193,17 @@ type InputHelmOptions struct {
 	ValueFiles []string `json:"valueFiles"`
 	// List of file path for values.
 	SetFiles map[string]string
+	// Whether to deploy the chart as a Helm release by "helm upgrade --install"
+	// instead of applying the rendered manifests by kubectl.
+	// This runs the chart hooks and keeps the release history so that
+	// the release is rolled back by "helm rollback", but only K8S_SYNC stage
+	// can be used to deploy the application.
+	// Default is false.
+	ReleaseMode bool `json:"releaseMode"`
+	// How long to wait for the resources of the release to become ready
+	// while installing, upgrading or rolling back the release in release mode.
+	// Default is 5m.
+	Timeout Duration `json:"timeout" default:"5m"`
 }
 
 type KubernetesTrafficRoutingMethod string
@@ -108,6 +214,37 @@ const (
 	KubernetesTrafficRoutingMethodSMI         KubernetesTrafficRoutingMethod = "smi"
 )
 
+// KubernetesVariantLabel represents the label injected into the workloads, services
+// and used as the Istio subsets to distinguish the resources of each variant.
+type KubernetesVariantLabel struct {
+	// The key of the label.
+	// Default is "pipecd.dev/variant".
+	Key string `json:"key" default:"pipecd.dev/variant"`
+	// The label value for PRIMARY variant.
+	// Default is "primary".
+	PrimaryValue string `json:"primaryValue" default:"primary"`
+	// The label value for CANARY variant.
+	// Default is "canary".
+	CanaryValue string `json:"canaryValue" default:"canary"`
+	// The label value for BASELINE variant.
+	// Default is "baseline".
+	BaselineValue string `json:"baselineValue" default:"baseline"`
+}
+
+// Validate returns an error if any wrong configuration value was found.
+func (l *KubernetesVariantLabel) Validate() error {
+	if l.Key == "" {
+		return fmt.Errorf("variantLabel.key must not be empty")
+	}
+	if l.PrimaryValue == "" || l.CanaryValue == "" || l.BaselineValue == "" {
+		return fmt.Errorf("variantLabel.primaryValue, canaryValue and baselineValue must not be empty")
+	}
+	if l.PrimaryValue == l.CanaryValue || l.PrimaryValue == l.BaselineValue || l.CanaryValue == l.BaselineValue {
+		return fmt.Errorf("variantLabel.primaryValue, canaryValue and baselineValue must be different from each other")
+	}
+	return nil
+}
+
 type KubernetesTrafficRouting struct {
 	Method KubernetesTrafficRoutingMethod `json:"method"`
 	Istio  *IstioTrafficRouting           `json:"istio"`
@@ -148,6 +285,9 @@ type K8sSyncStageOptions struct {
 	AddVariantLabelToSelector bool `json:"addVariantLabelToSelector"`
 	// Whether the resources that are no longer defined in Git should be removed or not.
 	Prune bool `json:"prune"`
+	// Whether to wait for the ExternalSecrets and SealedSecrets referenced by the workloads
+	// to become ready before applying the other manifests.
+	WaitForSecrets bool `json:"waitForSecrets"`
 }
 
 // K8sPrimaryRolloutStageOptions contains all configurable values for a K8S_PRIMARY_ROLLOUT stage.
@@ -161,6 +301,9 @@ type K8sPrimaryRolloutStageOptions struct {
 	AddVariantLabelToSelector bool `json:"addVariantLabelToSelector"`
 	// Whether the resources that are no longer defined in Git should be removed or not.
 	Prune bool `json:"prune"`
+	// Whether to wait for the ExternalSecrets and SealedSecrets referenced by the workloads
+	// to become ready before applying the other manifests.
+	WaitForSecrets bool `json:"waitForSecrets"`
 }
 
 // K8sCanaryRolloutStageOptions contains all configurable values for a K8S_CANARY_ROLLOUT stage.
@@ -174,7 +317,14 @@ type K8sCanaryRolloutStageOptions struct {
 	// Default is "canary".
 	Suffix string `json:"suffix"`
 	// Whether the CANARY service should be created.
+	// When false, no dedicated service is created and the PRIMARY one is reused,
+	// the CANARY pods can still be selected by the variant label (e.g. as an Istio subset).
 	CreateService bool `json:"createService"`
+	// Suffix that should be used when naming the CANARY service.
+	// Default is the same with the suffix of the variant's resources.
+	ServiceNameSuffix string `json:"serviceNameSuffix"`
+	// Additional labels that should be added to the CANARY service.
+	ServiceLabels map[string]string `json:"serviceLabels"`
 }
 
 // K8sCanaryCleanStageOptions contains all configurable values for a K8S_CANARY_CLEAN stage.
@@ -192,7 +342,14 @@ type K8sBaselineRolloutStageOptions struct {
 	// Default is "baseline".
 	Suffix string `json:"suffix"`
 	// Whether the BASELINE service should be created.
+	// When false, no dedicated service is created and the PRIMARY one is reused,
+	// the BASELINE pods can still be selected by the variant label (e.g. as an Istio subset).
 	CreateService bool `json:"createService"`
+	// Suffix that should be used when naming the BASELINE service.
+	// Default is the same with the suffix of the variant's resources.
+	ServiceNameSuffix string `json:"serviceNameSuffix"`
+	// Additional labels that should be added to the BASELINE service.
+	ServiceLabels map[string]string `json:"serviceLabels"`
 }
 
 // K8sBaselineCleanStageOptions contains all configurable values for a K8S_BASELINE_CLEAN stage.
@@ -212,6 +369,126 @@ type K8sTrafficRoutingStageOptions struct {
 	Baseline Percentage `json:"baseline"`
 }
 
+// K8sPolicyCheckStageOptions contains all configurable values for a K8S_POLICY_CHECK stage.
+type K8sPolicyCheckStageOptions struct {
+	// The policy engine used to evaluate the manifests.
+	// "OPA" or "CUE" can be populated. Default is "OPA".
+	Engine PolicyEngine `json:"engine"`
+	// List of policy files or directories relative to the application directory.
+	Policies []string `json:"policies"`
+	// The address of an OPA bundle which should be fetched from a registry.
+	// This is only available for OPA engine.
+	Bundle string `json:"bundle"`
+	// The query to evaluate for each manifest.
+	// For OPA, the query must return a set of violation messages. Default is "data.main.deny".
+	// For CUE, the expression of the definition each manifest must satisfy, e.g. "#Deployment".
+	Query string `json:"query"`
+	// Version of the policy engine will be used.
+	Version string `json:"version"`
+}
+
+type PolicyEngine string
+
+const (
+	PolicyEngineOPA PolicyEngine = "OPA"
+	PolicyEngineCUE PolicyEngine = "CUE"
+)
+
+func (opts *K8sPolicyCheckStageOptions) Validate() error {
+	switch opts.Engine {
+	case PolicyEngineOPA:
+	case PolicyEngineCUE:
+		if opts.Bundle != "" {
+			return fmt.Errorf("bundle is only available for %s policy engine", PolicyEngineOPA)
+		}
+	default:
+		return fmt.Errorf("unsupported policy engine: %s", opts.Engine)
+	}
+	if len(opts.Policies) == 0 && opts.Bundle == "" {
+		return fmt.Errorf("one of policies or bundle must be specified for %s stage", model.StageK8sPolicyCheck)
+	}
+	return nil
+}
+
+// K8sVulnerabilityScanStageOptions contains all configurable values for a K8S_VULNERABILITY_SCAN stage.
+type K8sVulnerabilityScanStageOptions struct {
+	// The name of the image scanner defined in the Piped Configuration.
+	Scanner string `json:"scanner"`
+	// The minimum severity of the vulnerabilities which fail the stage.
+	// "LOW", "MEDIUM", "HIGH" or "CRITICAL" can be populated. Default is "HIGH".
+	Severity VulnerabilitySeverity `json:"severity"`
+	// Whether the vulnerabilities without any available fix should be ignored.
+	IgnoreUnfixed bool `json:"ignoreUnfixed"`
+	// List of the vulnerabilities which are accepted regardless of their severity.
+	Allowlist []VulnerabilityAllowlistEntry `json:"allowlist"`
+}
+
+type VulnerabilityAllowlistEntry struct {
+	// The identifier of the vulnerability, e.g. CVE-2021-3711.
+	ID string `json:"id"`
+	// List of images the vulnerability is accepted for.
+	// An image matches when it starts with the given value.
+	// Empty means all images.
+	Images []string `json:"images"`
+	// Why the vulnerability is accepted.
+	Reason string `json:"reason"`
+}
+
+// Allows reports whether the given vulnerability of the image was accepted.
+func (e VulnerabilityAllowlistEntry) Allows(id, image string) bool {
+	if e.ID != id {
+		return false
+	}
+	if len(e.Images) == 0 {
+		return true
+	}
+	for _, prefix := range e.Images {
+		if strings.HasPrefix(image, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type VulnerabilitySeverity string
+
+const (
+	VulnerabilitySeverityUnknown  VulnerabilitySeverity = "UNKNOWN"
+	VulnerabilitySeverityLow      VulnerabilitySeverity = "LOW"
+	VulnerabilitySeverityMedium   VulnerabilitySeverity = "MEDIUM"
+	VulnerabilitySeverityHigh     VulnerabilitySeverity = "HIGH"
+	VulnerabilitySeverityCritical VulnerabilitySeverity = "CRITICAL"
+)
+
+var vulnerabilitySeverityLevels = map[VulnerabilitySeverity]int{
+	VulnerabilitySeverityUnknown:  0,
+	VulnerabilitySeverityLow:      1,
+	VulnerabilitySeverityMedium:   2,
+	VulnerabilitySeverityHigh:     3,
+	VulnerabilitySeverityCritical: 4,
+}
+
+// AtLeast reports whether the severity is equal to or higher than the given one.
+// Unrecognized severities are considered as UNKNOWN.
+func (s VulnerabilitySeverity) AtLeast(threshold VulnerabilitySeverity) bool {
+	return vulnerabilitySeverityLevels[s] >= vulnerabilitySeverityLevels[threshold]
+}
+
+func (opts *K8sVulnerabilityScanStageOptions) Validate() error {
+	if opts.Scanner == "" {
+		return fmt.Errorf("scanner must be specified for %s stage", model.StageK8sVulnerabilityScan)
+	}
+	if _, ok := vulnerabilitySeverityLevels[opts.Severity]; !ok || opts.Severity == VulnerabilitySeverityUnknown {
+		return fmt.Errorf("unsupported vulnerability severity: %s", opts.Severity)
+	}
+	for _, e := range opts.Allowlist {
+		if e.ID == "" {
+			return fmt.Errorf("id of vulnerability allowlist entry must be set")
+		}
+	}
+	return nil
+}
+
 func (opts K8sTrafficRoutingStageOptions) Percentages() (primary, canary, baseline int) {
 	switch opts.All {
 	case "primary":
@@ -78,7 +78,8 @@ func TestKubernetesDeploymentConfig(t *testing.T) {
 					Timeout: Duration(6 * time.Hour),
 				},
 				Input: KubernetesDeploymentInput{
-					AutoRollback: true,
+					SyncWaveTimeout: Duration(5 * time.Minute),
+					AutoRollback:    true,
 				},
 				TrafficRouting: &KubernetesTrafficRouting{
 					Method: KubernetesTrafficRoutingMethodPodSelector,
@@ -133,3 +134,295 @@ func TestKubernetesDeploymentConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestKubernetesDeploymentSpecValidateHelmReleaseMode(t *testing.T) {
+	chart := &InputHelmChart{
+		Repository: "pipecd",
+		Name:       "helloworld",
+		Version:    "v0.5.0",
+	}
+	testcases := []struct {
+		name    string
+		input   KubernetesDeploymentInput
+		stages  []model.Stage
+		wantErr bool
+	}{
+		{
+			name: "quick sync",
+			input: KubernetesDeploymentInput{
+				HelmChart:   chart,
+				HelmOptions: &InputHelmOptions{ReleaseMode: true},
+			},
+		},
+		{
+			name: "sync with approval",
+			input: KubernetesDeploymentInput{
+				HelmChart:   chart,
+				HelmOptions: &InputHelmOptions{ReleaseMode: true},
+			},
+			stages: []model.Stage{model.StageWaitApproval, model.StageK8sSync},
+		},
+		{
+			name: "canary rollout",
+			input: KubernetesDeploymentInput{
+				HelmChart:   chart,
+				HelmOptions: &InputHelmOptions{ReleaseMode: true},
+			},
+			stages:  []model.Stage{model.StageK8sCanaryRollout, model.StageK8sPrimaryRollout},
+			wantErr: true,
+		},
+		{
+			name: "canary rollout without release mode",
+			input: KubernetesDeploymentInput{
+				HelmChart:   chart,
+				HelmOptions: &InputHelmOptions{},
+			},
+			stages: []model.Stage{model.StageK8sCanaryRollout, model.StageK8sPrimaryRollout},
+		},
+		{
+			name: "missing chart",
+			input: KubernetesDeploymentInput{
+				HelmOptions: &InputHelmOptions{ReleaseMode: true},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(time.Hour),
+				},
+				Input: tc.input,
+			}
+			if len(tc.stages) > 0 {
+				s.Pipeline = &DeploymentPipeline{}
+				for _, stage := range tc.stages {
+					s.Pipeline.Stages = append(s.Pipeline.Stages, PipelineStage{Name: stage})
+				}
+			}
+			err := s.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestKubernetesDeploymentSpecValidateNamespaceManagement(t *testing.T) {
+	testcases := []struct {
+		name    string
+		input   KubernetesDeploymentInput
+		wantErr bool
+	}{
+		{
+			name: "create namespace",
+			input: KubernetesDeploymentInput{
+				Namespace: "demo",
+				NamespaceManagement: &KubernetesNamespaceManagement{
+					Create: true,
+					Labels: map[string]string{"team": "demo"},
+				},
+			},
+		},
+		{
+			name: "delete on app deletion",
+			input: KubernetesDeploymentInput{
+				Namespace: "demo",
+				NamespaceManagement: &KubernetesNamespaceManagement{
+					Create:              true,
+					DeleteOnAppDeletion: true,
+				},
+			},
+		},
+		{
+			name: "delete without create",
+			input: KubernetesDeploymentInput{
+				Namespace: "demo",
+				NamespaceManagement: &KubernetesNamespaceManagement{
+					DeleteOnAppDeletion: true,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing namespace",
+			input: KubernetesDeploymentInput{
+				NamespaceManagement: &KubernetesNamespaceManagement{
+					Create: true,
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(time.Hour),
+				},
+				Input: tc.input,
+			}
+			err := s.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestKubernetesVariantLabelValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		label   KubernetesVariantLabel
+		wantErr bool
+	}{
+		{
+			name: "ok",
+			label: KubernetesVariantLabel{
+				Key:           "version",
+				PrimaryValue:  "stable",
+				CanaryValue:   "canary",
+				BaselineValue: "baseline",
+			},
+		},
+		{
+			name: "missing key",
+			label: KubernetesVariantLabel{
+				PrimaryValue:  "stable",
+				CanaryValue:   "canary",
+				BaselineValue: "baseline",
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing value",
+			label: KubernetesVariantLabel{
+				Key:          "version",
+				PrimaryValue: "stable",
+				CanaryValue:  "canary",
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicated values",
+			label: KubernetesVariantLabel{
+				Key:           "version",
+				PrimaryValue:  "stable",
+				CanaryValue:   "stable",
+				BaselineValue: "baseline",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.label.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestK8sPolicyCheckStageOptionsValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		opts    K8sPolicyCheckStageOptions
+		wantErr bool
+	}{
+		{
+			name: "opa with policies",
+			opts: K8sPolicyCheckStageOptions{
+				Engine:   PolicyEngineOPA,
+				Policies: []string{"policies"},
+			},
+		},
+		{
+			name: "opa with bundle",
+			opts: K8sPolicyCheckStageOptions{
+				Engine: PolicyEngineOPA,
+				Bundle: "https://registry.example.com/bundles/k8s.tar.gz",
+			},
+		},
+		{
+			name: "cue with bundle",
+			opts: K8sPolicyCheckStageOptions{
+				Engine: PolicyEngineCUE,
+				Bundle: "https://registry.example.com/bundles/k8s.tar.gz",
+			},
+			wantErr: true,
+		},
+		{
+			name: "no policy",
+			opts: K8sPolicyCheckStageOptions{
+				Engine: PolicyEngineCUE,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown engine",
+			opts: K8sPolicyCheckStageOptions{
+				Engine:   "KYVERNO",
+				Policies: []string{"policies"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestK8sVulnerabilityScanStageOptionsValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		opts    K8sVulnerabilityScanStageOptions
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			opts: K8sVulnerabilityScanStageOptions{
+				Scanner:  "trivy",
+				Severity: VulnerabilitySeverityHigh,
+				Allowlist: []VulnerabilityAllowlistEntry{
+					{ID: "CVE-2021-3711"},
+				},
+			},
+		},
+		{
+			name: "missing scanner",
+			opts: K8sVulnerabilityScanStageOptions{
+				Severity: VulnerabilitySeverityHigh,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown severity",
+			opts: K8sVulnerabilityScanStageOptions{
+				Scanner:  "trivy",
+				Severity: VulnerabilitySeverityUnknown,
+			},
+			wantErr: true,
+		},
+		{
+			name: "allowlist entry without id",
+			opts: K8sVulnerabilityScanStageOptions{
+				Scanner:   "trivy",
+				Severity:  VulnerabilitySeverityLow,
+				Allowlist: []VulnerabilityAllowlistEntry{{Reason: "false positive"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestVulnerabilitySeverityAtLeast(t *testing.T) {
+	assert.True(t, VulnerabilitySeverityCritical.AtLeast(VulnerabilitySeverityHigh))
+	assert.True(t, VulnerabilitySeverityHigh.AtLeast(VulnerabilitySeverityHigh))
+	assert.False(t, VulnerabilitySeverityMedium.AtLeast(VulnerabilitySeverityHigh))
+	assert.False(t, VulnerabilitySeverity("NEGLIGIBLE").AtLeast(VulnerabilitySeverityLow))
+}
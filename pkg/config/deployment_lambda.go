@@ -14,6 +14,8 @@
 
 package config
 
+import "fmt"
+
 // LambdaDeploymentSpec represents a deployment configuration for Lambda application.
 type LambdaDeploymentSpec struct {
 	GenericDeploymentSpec
@@ -28,6 +30,18 @@ func (s *LambdaDeploymentSpec) Validate() error {
 	if err := s.GenericDeploymentSpec.Validate(); err != nil {
 		return err
 	}
+	if s.Input.TrafficRouting != nil {
+		if err := s.Input.TrafficRouting.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Pipeline != nil {
+		for _, stage := range s.Pipeline.Stages {
+			if stage.LambdaTrafficRoutingStageOptions != nil && s.Input.TrafficRouting == nil {
+				return fmt.Errorf("input.trafficRouting must be configured to use %s stage", stage.Name)
+			}
+		}
+	}
 	return nil
 }
 
@@ -38,6 +52,58 @@ type LambdaDeploymentInput struct {
 	// Automatically reverts all changes from all stages when one of them failed.
 	// Default is true.
 	AutoRollback bool `json:"autoRollback" default:"true"`
+	// The configuration of the ALB or the API Gateway in front of the function.
+	// This is required to use LAMBDA_TRAFFIC_ROUTING stage.
+	TrafficRouting *LambdaTrafficRouting `json:"trafficRouting"`
+}
+
+// LambdaTrafficRouting represents where the user traffic to the function comes through.
+// Exactly one of alb and apiGateway must be specified.
+type LambdaTrafficRouting struct {
+	ALB        *LambdaALBTrafficRouting        `json:"alb"`
+	APIGateway *LambdaAPIGatewayTrafficRouting `json:"apiGateway"`
+}
+
+func (r *LambdaTrafficRouting) Validate() error {
+	if (r.ALB == nil) == (r.APIGateway == nil) {
+		return fmt.Errorf("exactly one of trafficRouting.alb and trafficRouting.apiGateway must be specified")
+	}
+	if r.ALB != nil {
+		if r.ALB.ListenerRuleArn == "" {
+			return fmt.Errorf("trafficRouting.alb.listenerRuleArn is required")
+		}
+		if r.ALB.PrimaryTargetGroupArn == "" || r.ALB.CanaryTargetGroupArn == "" {
+			return fmt.Errorf("trafficRouting.alb.primaryTargetGroupArn and trafficRouting.alb.canaryTargetGroupArn are required")
+		}
+	}
+	if r.APIGateway != nil {
+		if r.APIGateway.RestAPIID == "" || r.APIGateway.Stage == "" || r.APIGateway.StageVariable == "" {
+			return fmt.Errorf("trafficRouting.apiGateway.restApiId, stage and stageVariable are required")
+		}
+	}
+	return nil
+}
+
+// LambdaALBTrafficRouting represents an ALB listener rule forwarding to two target groups.
+type LambdaALBTrafficRouting struct {
+	// The ARN of the listener rule whose forward action will be modified.
+	ListenerRuleArn string `json:"listenerRuleArn"`
+	// The ARN of the target group that is targeting the Service alias of the function.
+	PrimaryTargetGroupArn string `json:"primaryTargetGroupArn"`
+	// The ARN of the target group where the rolled out version will be registered.
+	CanaryTargetGroupArn string `json:"canaryTargetGroupArn"`
+}
+
+// LambdaAPIGatewayTrafficRouting represents a stage of a REST API whose integration
+// invokes the function qualified by a stage variable.
+type LambdaAPIGatewayTrafficRouting struct {
+	// The ID of the REST API.
+	RestAPIID string `json:"restApiId"`
+	// The name of the stage where the canary settings will be configured.
+	Stage string `json:"stage"`
+	// The name of the stage variable used as the qualifier of the function in the integration.
+	// The rolled out version is set to this variable in the canary settings.
+	StageVariable string `json:"stageVariable"`
 }
 
 // LambdaSyncStageOptions contains all configurable values for a LAMBDA_SYNC stage.
@@ -53,3 +119,29 @@ type LambdaPromoteStageOptions struct {
 	// Percentage of traffic should be routed to the new version.
 	Percent Percentage `json:"percent"`
 }
+
+// LambdaTrafficRoutingStageOptions contains all configurable values for a LAMBDA_TRAFFIC_ROUTING stage.
+type LambdaTrafficRoutingStageOptions struct {
+	// Canary represents the amount of traffic that the rolled out version will serve.
+	Canary Percentage `json:"canary"`
+	// Primary represents the amount of traffic that the Service alias will serve.
+	Primary Percentage `json:"primary"`
+}
+
+func (opts LambdaTrafficRoutingStageOptions) Percentage() (primary, canary int) {
+	primary = opts.Primary.Int()
+	if primary > 0 && primary <= 100 {
+		canary = 100 - primary
+		return
+	}
+
+	canary = opts.Canary.Int()
+	if canary > 0 && canary <= 100 {
+		primary = 100 - canary
+		return
+	}
+	// As default, the Service alias will receive 100% of traffic.
+	primary = 100
+	canary = 0
+	return
+}
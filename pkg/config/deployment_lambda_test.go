@@ -20,6 +20,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/model"
 )
 
 func TestLambdaDeploymentConfig(t *testing.T) {
@@ -45,6 +47,60 @@ func TestLambdaDeploymentConfig(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			fileName:           "testdata/application/lambda-app-traffic-routing.yaml",
+			expectedKind:       KindLambdaApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &LambdaDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:                            model.StageLambdaCanaryRollout,
+								LambdaCanaryRolloutStageOptions: &LambdaCanaryRolloutStageOptions{},
+							},
+							{
+								Name: model.StageLambdaTrafficRouting,
+								LambdaTrafficRoutingStageOptions: &LambdaTrafficRoutingStageOptions{
+									Canary: Percentage{
+										Number: 20,
+									},
+								},
+							},
+							{
+								Name: model.StageLambdaPromote,
+								LambdaPromoteStageOptions: &LambdaPromoteStageOptions{
+									Percent: Percentage{
+										Number: 100,
+									},
+								},
+							},
+							{
+								Name: model.StageLambdaTrafficRouting,
+								LambdaTrafficRoutingStageOptions: &LambdaTrafficRoutingStageOptions{
+									Primary: Percentage{
+										Number: 100,
+									},
+								},
+							},
+						},
+					},
+				},
+				Input: LambdaDeploymentInput{
+					FunctionManifestFile: "function.yaml",
+					AutoRollback:         true,
+					TrafficRouting: &LambdaTrafficRouting{
+						ALB: &LambdaALBTrafficRouting{
+							ListenerRuleArn:       "arn:aws:elasticloadbalancing:us-west-2:123456789012:listener-rule/app/demo/50dc6c495c0c9188/f2f7dc8efc522ab2/9683b2d02a6cabee",
+							PrimaryTargetGroupArn: "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/demo-primary/73e2d6bc24d8a067",
+							CanaryTargetGroupArn:  "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/demo-canary/6d0ecf831eec9f09",
+						},
+					},
+				},
+			},
+			expectedError: nil,
+		},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.fileName, func(t *testing.T) {
@@ -58,3 +114,59 @@ func TestLambdaDeploymentConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLambdaTrafficRoutingValidate(t *testing.T) {
+	testcases := []struct {
+		name        string
+		routing     LambdaTrafficRouting
+		expectedErr bool
+	}{
+		{
+			name:        "nothing specified",
+			expectedErr: true,
+		},
+		{
+			name: "both specified",
+			routing: LambdaTrafficRouting{
+				ALB: &LambdaALBTrafficRouting{
+					ListenerRuleArn:       "rule",
+					PrimaryTargetGroupArn: "primary",
+					CanaryTargetGroupArn:  "canary",
+				},
+				APIGateway: &LambdaAPIGatewayTrafficRouting{
+					RestAPIID:     "api",
+					Stage:         "prod",
+					StageVariable: "alias",
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "missing canary target group",
+			routing: LambdaTrafficRouting{
+				ALB: &LambdaALBTrafficRouting{
+					ListenerRuleArn:       "rule",
+					PrimaryTargetGroupArn: "primary",
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "valid api gateway",
+			routing: LambdaTrafficRouting{
+				APIGateway: &LambdaAPIGatewayTrafficRouting{
+					RestAPIID:     "api",
+					Stage:         "prod",
+					StageVariable: "alias",
+				},
+			},
+			expectedErr: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.routing.Validate()
+			assert.Equal(t, tc.expectedErr, err != nil)
+		})
+	}
+}
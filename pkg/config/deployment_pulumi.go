@@ -0,0 +1,66 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import "errors"
+
+// PulumiDeploymentSpec represents a deployment configuration for Pulumi application.
+type PulumiDeploymentSpec struct {
+	GenericDeploymentSpec
+	// Input for Pulumi deployment such as stack, pulumi version...
+	Input PulumiDeploymentInput `json:"input"`
+}
+
+// Validate returns an error if any wrong configuration value was found.
+func (s *PulumiDeploymentSpec) Validate() error {
+	if err := s.GenericDeploymentSpec.Validate(); err != nil {
+		return err
+	}
+	if s.Input.Stack == "" {
+		return errors.New("stack must be set for pulumi application")
+	}
+	return nil
+}
+
+type PulumiDeploymentInput struct {
+	// The name of the stack to update.
+	// The fully qualified name like "org/project/stack" can be used as well.
+	Stack string `json:"stack"`
+	// The version of pulumi should be used.
+	// Empty means the pre-installed version will be used.
+	PulumiVersion string `json:"pulumiVersion,omitempty"`
+	// Configuration values set to the stack before previewing or updating it.
+	// They take precedence over the values of the stack configuration file.
+	Config map[string]string `json:"config,omitempty"`
+	// Whether to refresh the state of the stack before previewing or updating it
+	// so that the changes made outside of Pulumi are taken into account.
+	// Default is false.
+	Refresh bool `json:"refresh,omitempty"`
+	// Automatically reverts all changes from all stages when one of them failed.
+	// Default is false.
+	AutoRollback bool `json:"autoRollback"`
+}
+
+// PulumiSyncStageOptions contains all configurable values for a PULUMI_SYNC stage.
+type PulumiSyncStageOptions struct {
+}
+
+// PulumiPreviewStageOptions contains all configurable values for a PULUMI_PREVIEW stage.
+type PulumiPreviewStageOptions struct {
+}
+
+// PulumiUpStageOptions contains all configurable values for a PULUMI_UP stage.
+type PulumiUpStageOptions struct {
+}
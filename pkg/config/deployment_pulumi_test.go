@@ -0,0 +1,87 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestPulumiDeploymentConfig(t *testing.T) {
+	testcases := []struct {
+		fileName           string
+		expectedKind       Kind
+		expectedAPIVersion string
+		expectedSpec       interface{}
+		wantErr            bool
+	}{
+		{
+			fileName:           "testdata/application/pulumi-app.yaml",
+			expectedKind:       KindPulumiApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &PulumiDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:                      model.StagePulumiPreview,
+								PulumiPreviewStageOptions: &PulumiPreviewStageOptions{},
+							},
+							{
+								Name: model.StageWaitApproval,
+								WaitApprovalStageOptions: &WaitApprovalStageOptions{
+									Timeout: defaultWaitApprovalTimeout,
+								},
+							},
+							{
+								Name:                 model.StagePulumiUp,
+								PulumiUpStageOptions: &PulumiUpStageOptions{},
+							},
+						},
+					},
+				},
+				Input: PulumiDeploymentInput{
+					Stack:         "dev",
+					PulumiVersion: "3.17.0",
+					Config: map[string]string{
+						"aws:region": "us-west-2",
+					},
+					Refresh: true,
+				},
+			},
+		},
+		{
+			fileName: "testdata/application/pulumi-app-missing-stack.yaml",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.fileName, func(t *testing.T) {
+			cfg, err := LoadFromYAML(tc.fileName)
+			require.Equal(t, tc.wantErr, err != nil)
+			if err == nil {
+				assert.Equal(t, tc.expectedKind, cfg.Kind)
+				assert.Equal(t, tc.expectedAPIVersion, cfg.APIVersion)
+				assert.Equal(t, tc.expectedSpec, cfg.spec)
+			}
+		})
+	}
+}
@@ -34,6 +34,8 @@ func (s *TerraformDeploymentSpec) Validate() error {
 type TerraformDeploymentInput struct {
 	// The terraform workspace name.
 	// Empty means "default" workpsace.
+	// When the cloud provider executes the runs on Terraform Cloud,
+	// this is the name of the Terraform Cloud workspace and must be set.
 	Workspace string `json:"workspace,omitempty"`
 	// The version of terraform should be used.
 	// Empty means the pre-installed version will be used.
@@ -16,6 +16,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -71,3 +72,367 @@ func TestHasStage(t *testing.T) {
 		})
 	}
 }
+
+func TestGenericDeploymentSpecValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		s       GenericDeploymentSpec
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			s:       GenericDeploymentSpec{},
+			wantErr: false,
+		},
+		{
+			name: "valid locks and supersede policy",
+			s: GenericDeploymentSpec{
+				Locks:           []string{"prod-db"},
+				SupersedePolicy: SupersedePolicyCancelRunning,
+			},
+			wantErr: false,
+		},
+		{
+			name: "empty lock name",
+			s: GenericDeploymentSpec{
+				Locks: []string{""},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid hooks",
+			s: GenericDeploymentSpec{
+				Hooks: DeploymentHooks{
+					PreSync: []DeploymentHook{
+						{Name: "purge-cache", Run: "./purge.sh"},
+					},
+					PostSync: PostSyncHooks{
+						OnSuccess: []DeploymentHook{
+							{Name: "purge-cache", HTTP: &DeploymentHookHTTP{URL: "https://cdn.example.com/purge"}},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "hook with both run and http",
+			s: GenericDeploymentSpec{
+				Hooks: DeploymentHooks{
+					PreSync: []DeploymentHook{
+						{Name: "purge-cache", Run: "./purge.sh", HTTP: &DeploymentHookHTTP{URL: "https://cdn.example.com/purge"}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicated hook name",
+			s: GenericDeploymentSpec{
+				Hooks: DeploymentHooks{
+					PostSync: PostSyncHooks{
+						OnFailure: []DeploymentHook{
+							{Name: "ticket", Run: "./update-ticket.sh"},
+							{Name: "ticket", Run: "./notify.sh"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid feature flag stage",
+			s: GenericDeploymentSpec{
+				Pipeline: &DeploymentPipeline{
+					Stages: []PipelineStage{
+						{
+							Name: model.StageFeatureFlag,
+							FeatureFlagStageOptions: &FeatureFlagStageOptions{
+								Provider:    "launchdarkly",
+								Flag:        "new-checkout",
+								Project:     "default",
+								Environment: "production",
+								Percentage:  Percentage{Number: 10},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "feature flag stage with invalid percentage",
+			s: GenericDeploymentSpec{
+				Pipeline: &DeploymentPipeline{
+					Stages: []PipelineStage{
+						{
+							Name: model.StageFeatureFlag,
+							FeatureFlagStageOptions: &FeatureFlagStageOptions{
+								Provider:    "launchdarkly",
+								Flag:        "new-checkout",
+								Environment: "production",
+								Percentage:  Percentage{Number: 120},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid approval escalation",
+			s: GenericDeploymentSpec{
+				Pipeline: &DeploymentPipeline{
+					Stages: []PipelineStage{
+						{
+							Name: model.StageWaitApproval,
+							WaitApprovalStageOptions: &WaitApprovalStageOptions{
+								Timeout:   Duration(6 * time.Hour),
+								Approvers: []string{"user-a"},
+								Escalation: &ApprovalEscalation{
+									After:             Duration(30 * time.Minute),
+									FallbackApprovers: []string{"user-b"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "approval escalation after timeout",
+			s: GenericDeploymentSpec{
+				Pipeline: &DeploymentPipeline{
+					Stages: []PipelineStage{
+						{
+							Name: model.StageWaitApproval,
+							WaitApprovalStageOptions: &WaitApprovalStageOptions{
+								Timeout: Duration(time.Hour),
+								Escalation: &ApprovalEscalation{
+									After:  Duration(2 * time.Hour),
+									Action: ApprovalEscalationActionApprove,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "approval escalation notifying nobody",
+			s: GenericDeploymentSpec{
+				Pipeline: &DeploymentPipeline{
+					Stages: []PipelineStage{
+						{
+							Name: model.StageWaitApproval,
+							WaitApprovalStageOptions: &WaitApprovalStageOptions{
+								Escalation: &ApprovalEscalation{
+									After: Duration(30 * time.Minute),
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid version extraction",
+			s: GenericDeploymentSpec{
+				VersionExtraction: &VersionExtraction{
+					Source:  VersionSourceFile,
+					File:    "VERSION",
+					Pattern: `^v(.+)$`,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "absolute version file",
+			s: GenericDeploymentSpec{
+				VersionExtraction: &VersionExtraction{
+					Source: VersionSourceFile,
+					File:   "/etc/passwd",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "version file outside of application directory",
+			s: GenericDeploymentSpec{
+				VersionExtraction: &VersionExtraction{
+					Source: VersionSourceFile,
+					File:   "foo/../../VERSION",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "version extraction without container name",
+			s: GenericDeploymentSpec{
+				VersionExtraction: &VersionExtraction{
+					Source: VersionSourceContainerImage,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "version extraction pattern without capturing group",
+			s: GenericDeploymentSpec{
+				VersionExtraction: &VersionExtraction{
+					Source:  VersionSourceHelmChart,
+					Pattern: `^v.+$`,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported supersede policy",
+			s: GenericDeploymentSpec{
+				SupersedePolicy: "LATEST",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.s.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestVersionExtractionExtractFrom(t *testing.T) {
+	testcases := []struct {
+		name     string
+		pattern  string
+		value    string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "whole value",
+			value:    "1.2.3\n",
+			expected: "1.2.3",
+		},
+		{
+			name:    "empty value",
+			value:   "  \n",
+			wantErr: true,
+		},
+		{
+			name:     "matched pattern",
+			pattern:  `version:\s*"?([^"\s]+)`,
+			value:    "name: app\nversion: \"2.0.1\"\n",
+			expected: "2.0.1",
+		},
+		{
+			name:    "unmatched pattern",
+			pattern: `^v(.+)$`,
+			value:   "1.2.3",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &VersionExtraction{Source: VersionSourceFile, File: "VERSION", Pattern: tc.pattern}
+			got, err := e.ExtractFrom(tc.value)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestPipelineStagePluginUnmarshal(t *testing.T) {
+	testcases := []struct {
+		name     string
+		data     string
+		expected PipelineStage
+		wantErr  bool
+	}{
+		{
+			name: "plugin stage",
+			data: `{"name":"PLUGIN_FOO","with":{"key":"value"}}`,
+			expected: PipelineStage{
+				Name:               model.Stage("PLUGIN_FOO"),
+				PluginStageOptions: []byte(`{"key":"value"}`),
+			},
+		},
+		{
+			name:    "unknown stage",
+			data:    `{"name":"FOO","with":{"key":"value"}}`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var s PipelineStage
+			err := s.UnmarshalJSON([]byte(tc.data))
+			assert.Equal(t, tc.wantErr, err != nil)
+			if !tc.wantErr {
+				assert.Equal(t, tc.expected.Name, s.Name)
+				assert.JSONEq(t, string(tc.expected.PluginStageOptions), string(s.PluginStageOptions))
+			}
+		})
+	}
+}
+
+func TestIsTouchedByChangedFiles(t *testing.T) {
+	testcases := []struct {
+		name         string
+		appDir       string
+		changes      []string
+		changedFiles []string
+		expected     bool
+	}{
+		{
+			name:   "not touched",
+			appDir: "app/demo",
+			changedFiles: []string{
+				"app/hello.txt",
+				"app/foo/deployment.yaml",
+			},
+			expected: false,
+		},
+		{
+			name:   "not touched in dir whose name does not match exactly",
+			appDir: "app/demo",
+			changedFiles: []string{
+				"app/demo-2",
+			},
+			expected: false,
+		},
+		{
+			name:   "touched in app dir",
+			appDir: "app/demo",
+			changedFiles: []string{
+				"app/hello.txt",
+				"app/demo/deployment.yaml",
+			},
+			expected: true,
+		},
+		{
+			name:   "touched in the changes",
+			appDir: "app/demo",
+			changes: []string{
+				"charts/demo",
+				"charts/bar/*",
+			},
+			changedFiles: []string{
+				"app/hello.txt",
+				"charts/bar/deployment.yaml",
+			},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := IsTouchedByChangedFiles(tc.appDir, tc.changes, tc.changedFiles)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
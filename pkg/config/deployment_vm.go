@@ -0,0 +1,84 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// VMDeploymentSpec represents a deployment configuration for VM application.
+type VMDeploymentSpec struct {
+	GenericDeploymentSpec
+	// Input for VM deployment such as where to fetch the instance group manifest...
+	Input VMDeploymentInput `json:"input"`
+	// Configuration for quick sync.
+	QuickSync VMSyncStageOptions `json:"quickSync"`
+}
+
+// Validate returns an error if any wrong configuration value was found.
+func (s *VMDeploymentSpec) Validate() error {
+	if err := s.GenericDeploymentSpec.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+type VMDeploymentInput struct {
+	// The name of instance group manifest file placing in application directory.
+	// Default is instancegroup.yaml
+	InstanceGroupManifestFile string `json:"instanceGroupManifestFile" default:"instancegroup.yaml"`
+	// Automatically reverts all changes from all stages when one of them failed.
+	// Default is true.
+	AutoRollback bool `json:"autoRollback" default:"true"`
+}
+
+// VMSyncStageOptions contains all configurable values for a VM_SYNC stage.
+type VMSyncStageOptions struct {
+	// The maximum number of instances that can be replaced at the same time.
+	// Empty means only one instance is replaced at a time.
+	MaxUnavailable *Replicas `json:"maxUnavailable"`
+	// The maximum number of instances that can be created over the target size
+	// during the update. This is used only by the platforms creating new instances
+	// instead of replacing them in place, such as GCE.
+	// Empty means one instance.
+	MaxSurge *Replicas `json:"maxSurge"`
+	// How long to wait for a replaced instance to become healthy.
+	// Empty means 10 minutes.
+	HealthCheckTimeout Duration `json:"healthCheckTimeout"`
+}
+
+// VMCanaryRolloutStageOptions contains all configurable values for a VM_CANARY_ROLLOUT stage.
+type VMCanaryRolloutStageOptions struct {
+	// How many instances should be replaced with the new image as CANARY.
+	Replicas Replicas `json:"replicas"`
+	// How long to wait for a replaced instance to become healthy.
+	// Empty means 10 minutes.
+	HealthCheckTimeout Duration `json:"healthCheckTimeout"`
+}
+
+// VMPrimaryRolloutStageOptions contains all configurable values for a VM_PRIMARY_ROLLOUT stage.
+type VMPrimaryRolloutStageOptions struct {
+	// The maximum number of instances that can be replaced at the same time.
+	// Empty means only one instance is replaced at a time.
+	MaxUnavailable *Replicas `json:"maxUnavailable"`
+	// The maximum number of instances that can be created over the target size
+	// during the update. This is used only by the platforms creating new instances
+	// instead of replacing them in place, such as GCE.
+	// Empty means one instance.
+	MaxSurge *Replicas `json:"maxSurge"`
+	// How long to wait for a replaced instance to become healthy.
+	// Empty means 10 minutes.
+	HealthCheckTimeout Duration `json:"healthCheckTimeout"`
+}
+
+// VMCanaryCleanStageOptions contains all configurable values for a VM_CANARY_CLEAN stage.
+type VMCanaryCleanStageOptions struct {
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestVMDeploymentConfig(t *testing.T) {
+	testcases := []struct {
+		fileName           string
+		expectedKind       Kind
+		expectedAPIVersion string
+		expectedSpec       interface{}
+		expectedError      error
+	}{
+		{
+			fileName:           "testdata/application/vm-app.yaml",
+			expectedKind:       KindVMApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &VMDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageVMCanaryRollout,
+								VMCanaryRolloutStageOptions: &VMCanaryRolloutStageOptions{
+									Replicas: Replicas{
+										Number:       20,
+										IsPercentage: true,
+									},
+								},
+							},
+							{
+								Name: model.StageVMPrimaryRollout,
+								VMPrimaryRolloutStageOptions: &VMPrimaryRolloutStageOptions{
+									MaxUnavailable: &Replicas{
+										Number: 2,
+									},
+									HealthCheckTimeout: Duration(5 * time.Minute),
+								},
+							},
+						},
+					},
+				},
+				Input: VMDeploymentInput{
+					InstanceGroupManifestFile: "instancegroup.yaml",
+					AutoRollback:              true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/vm-app-gce.yaml",
+			expectedKind:       KindVMApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &VMDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageVMCanaryRollout,
+								VMCanaryRolloutStageOptions: &VMCanaryRolloutStageOptions{
+									Replicas: Replicas{
+										Number: 1,
+									},
+								},
+							},
+							{
+								Name: model.StageVMPrimaryRollout,
+								VMPrimaryRolloutStageOptions: &VMPrimaryRolloutStageOptions{
+									MaxSurge: &Replicas{
+										Number:       25,
+										IsPercentage: true,
+									},
+								},
+							},
+							{
+								Name:                      model.StageVMCanaryClean,
+								VMCanaryCleanStageOptions: &VMCanaryCleanStageOptions{},
+							},
+						},
+					},
+				},
+				Input: VMDeploymentInput{
+					InstanceGroupManifestFile: "instancegroup.yaml",
+					AutoRollback:              true,
+				},
+			},
+			expectedError: nil,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.fileName, func(t *testing.T) {
+			cfg, err := LoadFromYAML(tc.fileName)
+			require.Equal(t, tc.expectedError, err)
+			if err == nil {
+				assert.Equal(t, tc.expectedKind, cfg.Kind)
+				assert.Equal(t, tc.expectedAPIVersion, cfg.APIVersion)
+				assert.Equal(t, tc.expectedSpec, cfg.spec)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"sigs.k8s.io/yaml"
+)
+
+// The keys used to identify the list items while merging overlays.
+// A list is merged item by item only when all of its items have the same one of these keys.
+var overlayMergeKeys = []string{"name", "repoId"}
+
+// LoadFromYAMLWithOverlays reads the base configuration file and merges the given overlay files
+// into it in order. The merged configuration is validated as a whole after decoding,
+// so overlays can contain only the fields that differ from the base.
+func LoadFromYAMLWithOverlays(base string, overlays ...string) (*Config, error) {
+	data, err := ioutil.ReadFile(base)
+	if err != nil {
+		return nil, err
+	}
+	overlayData := make([][]byte, 0, len(overlays))
+	for _, o := range overlays {
+		d, err := ioutil.ReadFile(o)
+		if err != nil {
+			return nil, err
+		}
+		overlayData = append(overlayData, d)
+	}
+	return DecodeYAMLWithOverlays(data, overlayData...)
+}
+
+// DecodeYAMLWithOverlays merges the given overlays into the base configuration data
+// and then decodes and validates the merged one.
+func DecodeYAMLWithOverlays(base []byte, overlays ...[]byte) (*Config, error) {
+	if len(overlays) == 0 {
+		return DecodeYAML(base)
+	}
+	merged, err := MergeYAML(base, overlays...)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeYAML(merged)
+}
+
+// MergeYAML merges the overlays into the base YAML data in order with the following rules:
+//   - Objects are merged recursively.
+//   - A null value removes the field from the base.
+//   - Lists whose items all have a "name" (or "repoId") are merged item by item using that key,
+//     and the items not existing in the base are appended.
+//   - Other values, including the other lists, are replaced.
+//
+// The apiVersion and kind of an overlay must be the same as the base if they are specified.
+func MergeYAML(base []byte, overlays ...[]byte) ([]byte, error) {
+	merged, err := decodeObject(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base configuration: %w", err)
+	}
+	for i, data := range overlays {
+		overlay, err := decodeObject(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode overlay at index %d: %w", i, err)
+		}
+		for _, k := range []string{"apiVersion", "kind"} {
+			v, ok := overlay[k]
+			if !ok {
+				continue
+			}
+			if v != merged[k] {
+				return nil, fmt.Errorf("%s of overlay at index %d must be %v but got %v", k, i, merged[k], v)
+			}
+		}
+		merged = mergeObject(merged, overlay)
+	}
+	return json.Marshal(merged)
+}
+
+func decodeObject(data []byte) (map[string]interface{}, error) {
+	js, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	obj := make(map[string]interface{})
+	if err := json.Unmarshal(js, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func mergeObject(base, overlay map[string]interface{}) map[string]interface{} {
+	if base == nil {
+		base = make(map[string]interface{}, len(overlay))
+	}
+	for k, v := range overlay {
+		if v == nil {
+			delete(base, k)
+			continue
+		}
+		base[k] = mergeValue(base[k], v)
+	}
+	return base
+}
+
+func mergeValue(base, overlay interface{}) interface{} {
+	switch o := overlay.(type) {
+	case map[string]interface{}:
+		if b, ok := base.(map[string]interface{}); ok {
+			return mergeObject(b, o)
+		}
+	case []interface{}:
+		if b, ok := base.([]interface{}); ok {
+			if key, ok := listMergeKey(b, o); ok {
+				return mergeList(b, o, key)
+			}
+		}
+	}
+	return overlay
+}
+
+// listMergeKey returns the key which all items of both lists have.
+func listMergeKey(base, overlay []interface{}) (string, bool) {
+	items := append(append([]interface{}{}, base...), overlay...)
+	if len(items) == 0 {
+		return "", false
+	}
+	for _, key := range overlayMergeKeys {
+		found := true
+		for _, item := range items {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				found = false
+				break
+			}
+			if _, ok := obj[key].(string); !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func mergeList(base, overlay []interface{}, key string) []interface{} {
+	index := make(map[string]int, len(base))
+	for i, item := range base {
+		index[item.(map[string]interface{})[key].(string)] = i
+	}
+	for _, item := range overlay {
+		obj := item.(map[string]interface{})
+		if i, ok := index[obj[key].(string)]; ok {
+			base[i] = mergeObject(base[i].(map[string]interface{}), obj)
+			continue
+		}
+		index[obj[key].(string)] = len(base)
+		base = append(base, obj)
+	}
+	return base
+}
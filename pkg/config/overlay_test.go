@@ -0,0 +1,158 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeYAML(t *testing.T) {
+	base := `
+apiVersion: pipecd.dev/v1beta1
+kind: Piped
+spec:
+  projectID: test-project
+  syncInterval: 1m
+  repositories:
+    - repoId: repo1
+      remote: git@github.com:org/repo1.git
+      branch: master
+  sealedSecretManagement:
+    type: SEALING_KEY
+    config:
+      privateKeyFile: /etc/piped-secret/private-key
+  analysisProviders:
+    - name: prometheus-dev
+      type: PROMETHEUS
+      config:
+        address: https://prometheus.dev
+`
+	testcases := []struct {
+		name        string
+		overlays    []string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name:     "no overlay",
+			expected: `{"apiVersion":"pipecd.dev/v1beta1","kind":"Piped","spec":{"analysisProviders":[{"config":{"address":"https://prometheus.dev"},"name":"prometheus-dev","type":"PROMETHEUS"}],"projectID":"test-project","repositories":[{"branch":"master","remote":"git@github.com:org/repo1.git","repoId":"repo1"}],"sealedSecretManagement":{"config":{"privateKeyFile":"/etc/piped-secret/private-key"},"type":"SEALING_KEY"},"syncInterval":"1m"}}`,
+		},
+		{
+			name: "override scalars and merge lists by key",
+			overlays: []string{`
+spec:
+  syncInterval: 5m
+  repositories:
+    - repoId: repo1
+      branch: prod
+    - repoId: repo2
+      remote: git@github.com:org/repo2.git
+  analysisProviders:
+    - name: prometheus-dev
+      config:
+        address: https://prometheus.prod
+`},
+			expected: `{"apiVersion":"pipecd.dev/v1beta1","kind":"Piped","spec":{"analysisProviders":[{"config":{"address":"https://prometheus.prod"},"name":"prometheus-dev","type":"PROMETHEUS"}],"projectID":"test-project","repositories":[{"branch":"prod","remote":"git@github.com:org/repo1.git","repoId":"repo1"},{"remote":"git@github.com:org/repo2.git","repoId":"repo2"}],"sealedSecretManagement":{"config":{"privateKeyFile":"/etc/piped-secret/private-key"},"type":"SEALING_KEY"},"syncInterval":"5m"}}`,
+		},
+		{
+			name: "remove fields by null and apply overlays in order",
+			overlays: []string{
+				`
+spec:
+  sealedSecretManagement: null
+  syncInterval: 5m
+`,
+				`
+spec:
+  syncInterval: 10m
+`,
+			},
+			expected: `{"apiVersion":"pipecd.dev/v1beta1","kind":"Piped","spec":{"analysisProviders":[{"config":{"address":"https://prometheus.dev"},"name":"prometheus-dev","type":"PROMETHEUS"}],"projectID":"test-project","repositories":[{"branch":"master","remote":"git@github.com:org/repo1.git","repoId":"repo1"}],"syncInterval":"10m"}}`,
+		},
+		{
+			name: "replace lists without key",
+			overlays: []string{`
+spec:
+  repositories:
+    - remote: git@github.com:org/repo2.git
+`},
+			expected: `{"apiVersion":"pipecd.dev/v1beta1","kind":"Piped","spec":{"analysisProviders":[{"config":{"address":"https://prometheus.dev"},"name":"prometheus-dev","type":"PROMETHEUS"}],"projectID":"test-project","repositories":[{"remote":"git@github.com:org/repo2.git"}],"sealedSecretManagement":{"config":{"privateKeyFile":"/etc/piped-secret/private-key"},"type":"SEALING_KEY"},"syncInterval":"1m"}}`,
+		},
+		{
+			name: "different kind",
+			overlays: []string{`
+kind: Application
+`},
+			expectedErr: true,
+		},
+		{
+			name: "malformed overlay",
+			overlays: []string{`
+spec: [
+`},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			overlays := make([][]byte, 0, len(tc.overlays))
+			for _, o := range tc.overlays {
+				overlays = append(overlays, []byte(o))
+			}
+			got, err := MergeYAML([]byte(base), overlays...)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			if err == nil {
+				assert.JSONEq(t, tc.expected, string(got))
+			}
+		})
+	}
+}
+
+func TestLoadFromYAMLWithOverlays(t *testing.T) {
+	cfg, err := LoadFromYAMLWithOverlays("testdata/piped/piped-config.yaml", "testdata/piped/piped-config-overlay-prod.yaml")
+	require.NoError(t, err)
+	require.NotNil(t, cfg.PipedSpec)
+
+	spec := cfg.PipedSpec
+	assert.Equal(t, "test-project", spec.ProjectID)
+	assert.Equal(t, "test-piped-prod", spec.PipedID)
+	assert.Equal(t, Duration(3*time.Minute), spec.SyncInterval)
+	assert.Equal(t, []PipedRepository{
+		{
+			RepoID: "repo1",
+			Remote: "git@github.com:org/repo1.git",
+			Branch: "master",
+		},
+		{
+			RepoID: "repo2",
+			Remote: "git@github.com:org/repo2.git",
+			Branch: "release",
+		},
+		{
+			RepoID: "repo3",
+			Remote: "git@github.com:org/repo3.git",
+			Branch: "master",
+		},
+	}, spec.Repositories)
+	assert.Empty(t, spec.ChartRepositories)
+
+	// All overlay files must exist.
+	_, err = LoadFromYAMLWithOverlays("testdata/piped/piped-config.yaml", "testdata/piped/not-found.yaml")
+	assert.Error(t, err)
+}
@@ -20,6 +20,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/pipe-cd/pipe/pkg/model"
 )
@@ -47,6 +51,13 @@ type PipedSpec struct {
 	// How often to check whether an application should be synced.
 	// Default is 1m.
 	SyncInterval Duration `json:"syncInterval" default:"1m"`
+	// The maximum random delay added to each check of the repositories
+	// to avoid checking all of them at the same time.
+	// Zero means no jitter.
+	SyncJitter Duration `json:"syncJitter"`
+	// Optional settings to check the repositories which have not been changed
+	// for a long time less frequently.
+	SyncBackoff PipedSyncBackoff `json:"syncBackoff"`
 	// Git configuration needed for git commands.
 	Git PipedGit `json:"git"`
 	// List of git repositories this piped will handle.
@@ -57,6 +68,14 @@ type PipedSpec struct {
 	CloudProviders []PipedCloudProvider `json:"cloudProviders"`
 	// List of analysis providers can be used by this piped.
 	AnalysisProviders []PipedAnalysisProvider `json:"analysisProviders"`
+	// List of image scanners can be used by this piped.
+	ImageScanners []PipedImageScanner `json:"imageScanners"`
+	// List of change management systems where the change tickets of the deployments are managed.
+	ChangeManagementProviders []PipedChangeManagementProvider `json:"changeManagementProviders"`
+	// List of feature flag services where the flags are changed by the FEATURE_FLAG stage.
+	FeatureFlagProviders []PipedFeatureFlagProvider `json:"featureFlagProviders"`
+	// List of Git hosting services where the commit statuses of the deployments are posted.
+	CommitStatusProviders []PipedCommitStatusProvider `json:"commitStatusProviders"`
 	// Sending notification to Slack, Webhook…
 	Notifications Notifications `json:"notifications"`
 	// How the sealed secret should be managed.
@@ -67,6 +86,35 @@ type PipedSpec struct {
 	SecretManagement *SecretManagement `json:"secretManagement"`
 	// Optional settings for event watcher.
 	EventWatcher PipedEventWatcher `json:"eventWatcher"`
+	// Optional settings to limit the resources used by the spawned tools
+	// such as kubectl, kustomize, helm, terraform.
+	ToolExecution PipedToolExecution `json:"toolExecution"`
+	// Optional settings to cache the rendered Kubernetes manifests on disk.
+	RenderCache PipedRenderCache `json:"renderCache"`
+	// Optional settings to receive webhook calls from the external systems
+	// such as container registries or CI systems.
+	Webhook PipedWebhook `json:"webhook"`
+	// The trusted keys used to verify the signatures of commits and images
+	// for the applications requiring the signature verification.
+	SignatureVerification PipedSignatureVerification `json:"signatureVerification"`
+	// The PagerDuty account used to send the change events
+	// and check the maintenance windows and incidents of the services.
+	PagerDuty *PipedPagerDuty `json:"pagerDuty"`
+	// List of executor plugins to be started by piped.
+	// Each plugin can provide custom stages whose names start with "PLUGIN_".
+	Plugins []PipedPlugin `json:"plugins"`
+	// Whether to remove the CANARY and BASELINE variant resources
+	// of the Kubernetes applications having no running deployment at startup.
+	// Those resources are left by the deployments interrupted before cleaning them.
+	// Default is false, meaning they are only reported in the log.
+	CleanOrphanedVariants bool `json:"cleanOrphanedVariants"`
+	// List of regular expressions matching the values to be masked
+	// in the manifest diffs shown in plan-preview comments, stage logs and drift reports.
+	// The data of Secrets is always masked.
+	DiffMaskPatterns []string `json:"diffMaskPatterns"`
+	// List of secrets which can be referenced by the deployment hooks of the applications.
+	// The environment variables of piped are never expanded in the HTTP calls of the hooks.
+	DeploymentHookSecrets []PipedDeploymentHookSecret `json:"deploymentHookSecrets"`
 }
 
 // Validate validates configured data of all fields.
@@ -92,6 +140,22 @@ func (s *PipedSpec) Validate() error {
 	if s.SyncInterval < 0 {
 		return errors.New("syncInterval must be greater than or equal to 0")
 	}
+	if s.SyncJitter < 0 {
+		return errors.New("syncJitter must be greater than or equal to 0")
+	}
+	if err := s.SyncBackoff.Validate(); err != nil {
+		return err
+	}
+	for _, p := range s.DiffMaskPatterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("invalid diffMaskPatterns %q: %w", p, err)
+		}
+	}
+	for _, r := range s.Repositories {
+		if r.SyncInterval < 0 {
+			return fmt.Errorf("syncInterval of repository %s must be greater than or equal to 0", r.RepoID)
+		}
+	}
 	if s.SealedSecretManagement != nil {
 		if err := s.SealedSecretManagement.Validate(); err != nil {
 			return err
@@ -105,11 +169,112 @@ func (s *PipedSpec) Validate() error {
 	if err := s.EventWatcher.Validate(); err != nil {
 		return err
 	}
+	if err := s.ToolExecution.Validate(); err != nil {
+		return err
+	}
+	if err := s.RenderCache.Validate(); err != nil {
+		return err
+	}
+	if err := s.Webhook.Validate(); err != nil {
+		return err
+	}
+	if s.PagerDuty != nil {
+		if err := s.PagerDuty.Validate(); err != nil {
+			return err
+		}
+	}
 	for _, p := range s.AnalysisProviders {
 		if err := p.Validate(); err != nil {
 			return err
 		}
 	}
+	for _, sc := range s.ImageScanners {
+		if err := sc.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, p := range s.ChangeManagementProviders {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, p := range s.FeatureFlagProviders {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, p := range s.CommitStatusProviders {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, r := range s.ChartRepositories {
+		if err := r.Azure.Validate(); err != nil {
+			return fmt.Errorf("invalid azure credentials of chart repository %s: %w", r.Name, err)
+		}
+	}
+	for _, cp := range s.CloudProviders {
+		if cp.KubernetesConfig == nil {
+			continue
+		}
+		if err := cp.KubernetesConfig.Azure.Validate(); err != nil {
+			return fmt.Errorf("invalid azure credentials of cloud provider %s: %w", cp.Name, err)
+		}
+		if cp.KubernetesConfig.Azure.IsEnabled() && cp.KubernetesConfig.KubeConfigPath == "" {
+			return fmt.Errorf("kubeConfigPath of cloud provider %s must be set to use azure credentials", cp.Name)
+		}
+		if err := cp.KubernetesConfig.RateLimit.Validate(); err != nil {
+			return fmt.Errorf("invalid rateLimit of cloud provider %s: %w", cp.Name, err)
+		}
+	}
+	for _, r := range s.Notifications.Receivers {
+		if r.Grafana != nil {
+			if err := r.Grafana.Validate(); err != nil {
+				return fmt.Errorf("invalid notification receiver %s: %w", r.Name, err)
+			}
+		}
+		if r.GitHubDeployment != nil {
+			if err := r.GitHubDeployment.Validate(); err != nil {
+				return fmt.Errorf("invalid notification receiver %s: %w", r.Name, err)
+			}
+		}
+	}
+	for _, r := range s.Notifications.Routes {
+		if r.Template == nil {
+			continue
+		}
+		if err := r.Template.Validate(); err != nil {
+			return fmt.Errorf("invalid template of notification route %s: %w", r.Name, err)
+		}
+	}
+	hookSecrets := make(map[string]struct{}, len(s.DeploymentHookSecrets))
+	for _, hs := range s.DeploymentHookSecrets {
+		if err := hs.Validate(); err != nil {
+			return err
+		}
+		if _, ok := hookSecrets[hs.Name]; ok {
+			return fmt.Errorf("duplicated deployment hook secret %s", hs.Name)
+		}
+		hookSecrets[hs.Name] = struct{}{}
+	}
+	plugins := make(map[string]struct{}, len(s.Plugins))
+	for _, p := range s.Plugins {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		if _, ok := plugins[p.Name]; ok {
+			return fmt.Errorf("duplicated plugin %s", p.Name)
+		}
+		plugins[p.Name] = struct{}{}
+	}
+	for _, p := range s.AnalysisProviders {
+		if p.PluginConfig == nil {
+			continue
+		}
+		if _, ok := plugins[p.PluginConfig.Plugin]; !ok {
+			return fmt.Errorf("analysis provider %s uses undefined plugin %s", p.Name, p.PluginConfig.Plugin)
+		}
+	}
 	return nil
 }
 
@@ -180,6 +345,58 @@ func (s *PipedSpec) GetAnalysisProvider(name string) (PipedAnalysisProvider, boo
 	return PipedAnalysisProvider{}, false
 }
 
+// GetImageScanner finds and returns an Image Scanner config whose name is the given string.
+func (s *PipedSpec) GetImageScanner(name string) (PipedImageScanner, bool) {
+	for _, sc := range s.ImageScanners {
+		if sc.Name == name {
+			return sc, true
+		}
+	}
+	return PipedImageScanner{}, false
+}
+
+// GetChangeManagementProvider finds and returns a Change Management Provider config whose name is the given string.
+func (s *PipedSpec) GetChangeManagementProvider(name string) (PipedChangeManagementProvider, bool) {
+	for _, p := range s.ChangeManagementProviders {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return PipedChangeManagementProvider{}, false
+}
+
+// GetFeatureFlagProvider finds and returns a Feature Flag Provider config whose name is the given string.
+func (s *PipedSpec) GetFeatureFlagProvider(name string) (PipedFeatureFlagProvider, bool) {
+	for _, p := range s.FeatureFlagProviders {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return PipedFeatureFlagProvider{}, false
+}
+
+// GetCommitStatusProvider finds and returns a Commit Status Provider config whose name is the given string.
+func (s *PipedSpec) GetCommitStatusProvider(name string) (PipedCommitStatusProvider, bool) {
+	for _, p := range s.CommitStatusProviders {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return PipedCommitStatusProvider{}, false
+}
+
+// DiffMaskRegexps returns the compiled regular expressions of DiffMaskPatterns.
+// The invalid ones are ignored since they are rejected by Validate.
+func (s *PipedSpec) DiffMaskRegexps() []*regexp.Regexp {
+	regexps := make([]*regexp.Regexp, 0, len(s.DiffMaskPatterns))
+	for _, p := range s.DiffMaskPatterns {
+		if r, err := regexp.Compile(p); err == nil {
+			regexps = append(regexps, r)
+		}
+	}
+	return regexps
+}
+
 func (s *PipedSpec) IsInsecureChartRepository(name string) bool {
 	for _, cr := range s.ChartRepositories {
 		if cr.Name == name {
@@ -257,6 +474,38 @@ type PipedRepository struct {
 	Remote string `json:"remote"`
 	// The branch will be handled.
 	Branch string `json:"branch"`
+	// How often to check new commits of this repository.
+	// Default is the syncInterval of piped.
+	SyncInterval Duration `json:"syncInterval"`
+}
+
+// GetSyncInterval returns the interval of checking the new commits of
+// the given repository.
+func (s *PipedSpec) GetSyncInterval(r PipedRepository) time.Duration {
+	if r.SyncInterval > 0 {
+		return r.SyncInterval.Duration()
+	}
+	return s.SyncInterval.Duration()
+}
+
+type PipedSyncBackoff struct {
+	// The period without new commits after which the interval of checking
+	// the repository starts being doubled on each check.
+	// Zero means the backoff is disabled.
+	IdlePeriod Duration `json:"idlePeriod"`
+	// The maximum interval the backoff can increase to.
+	// Default is 10m.
+	MaxInterval Duration `json:"maxInterval" default:"10m"`
+}
+
+func (b *PipedSyncBackoff) Validate() error {
+	if b.IdlePeriod < 0 {
+		return errors.New("syncBackoff.idlePeriod must be greater than or equal to 0")
+	}
+	if b.MaxInterval < 0 {
+		return errors.New("syncBackoff.maxInterval must be greater than or equal to 0")
+	}
+	return nil
 }
 
 type HelmChartRepository struct {
@@ -270,6 +519,48 @@ type HelmChartRepository struct {
 	Password string `json:"password"`
 	// Whether to skip TLS certificate checks for the repository or not.
 	Insecure bool `json:"insecure"`
+	// The Azure credentials used to pull the charts from Azure Container Registry.
+	// The username and password are obtained by exchanging an Azure AD token.
+	Azure AzureCredentials `json:"azure"`
+}
+
+type AzureCredentialsType string
+
+const (
+	AzureCredentialsManagedIdentity  AzureCredentialsType = "MANAGED_IDENTITY"
+	AzureCredentialsServicePrincipal AzureCredentialsType = "SERVICE_PRINCIPAL"
+)
+
+// AzureCredentials specifies how to authenticate to Azure Active Directory.
+type AzureCredentials struct {
+	// How to authenticate. Empty means Azure credentials are not used.
+	Type AzureCredentialsType `json:"type"`
+	// The ID of the Azure AD tenant. Required for service principal.
+	TenantID string `json:"tenantID"`
+	// The client ID of the service principal or the user-assigned managed identity.
+	// Empty means the system-assigned managed identity.
+	ClientID string `json:"clientID"`
+	// The path to the file containing the client secret of the service principal.
+	ClientSecretFile string `json:"clientSecretFile"`
+}
+
+// IsEnabled returns true if the Azure credentials were configured.
+func (c AzureCredentials) IsEnabled() bool {
+	return c.Type != ""
+}
+
+func (c AzureCredentials) Validate() error {
+	switch c.Type {
+	case "", AzureCredentialsManagedIdentity:
+		return nil
+	case AzureCredentialsServicePrincipal:
+		if c.TenantID == "" || c.ClientID == "" || c.ClientSecretFile == "" {
+			return errors.New("tenantID, clientID and clientSecretFile must be set for SERVICE_PRINCIPAL azure credentials")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported azure credentials type: %s", c.Type)
+	}
 }
 
 type PipedCloudProvider struct {
@@ -281,6 +572,9 @@ type PipedCloudProvider struct {
 	CloudRunConfig   *CloudProviderCloudRunConfig
 	LambdaConfig     *CloudProviderLambdaConfig
 	ECSConfig        *CloudProviderECSConfig
+	VMConfig         *CloudProviderVMConfig
+	PulumiConfig     *CloudProviderPulumiConfig
+	AnsibleConfig    *CloudProviderAnsibleConfig
 }
 
 type genericPipedCloudProvider struct {
@@ -309,6 +603,9 @@ func (p *PipedCloudProvider) UnmarshalJSON(data []byte) error {
 		if len(gp.Config) > 0 {
 			err = json.Unmarshal(gp.Config, p.TerraformConfig)
 		}
+		if err == nil {
+			err = p.TerraformConfig.Validate()
+		}
 	case model.CloudProviderCloudRun:
 		p.CloudRunConfig = &CloudProviderCloudRunConfig{}
 		if len(gp.Config) > 0 {
@@ -324,6 +621,24 @@ func (p *PipedCloudProvider) UnmarshalJSON(data []byte) error {
 		if len(gp.Config) > 0 {
 			err = json.Unmarshal(gp.Config, p.ECSConfig)
 		}
+	case model.CloudProviderVM:
+		p.VMConfig = &CloudProviderVMConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.VMConfig)
+		}
+		if err == nil {
+			err = p.VMConfig.Validate()
+		}
+	case model.CloudProviderPulumi:
+		p.PulumiConfig = &CloudProviderPulumiConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.PulumiConfig)
+		}
+	case model.CloudProviderAnsible:
+		p.AnsibleConfig = &CloudProviderAnsibleConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.AnsibleConfig)
+		}
 	default:
 		err = fmt.Errorf("unsupported cloud provider type: %s", p.Name)
 	}
@@ -337,14 +652,61 @@ type CloudProviderKubernetesConfig struct {
 	// The path to the kubeconfig file.
 	// Empty means in-cluster.
 	KubeConfigPath string `json:"kubeConfigPath"`
+	// The context in the kubeconfig file to use.
+	// This allows registering several clusters of the same kubeconfig file
+	// as separate cloud providers.
+	// Empty means the current context of the kubeconfig file.
+	KubeConfigContext string `json:"kubeConfigContext"`
+	// The Azure credentials used to authenticate to the AKS cluster with Azure AD integration.
+	// The user of the kubeconfig context is replaced by kubelogin using these credentials
+	// so that the tokens are refreshed without any sidecar.
+	Azure AzureCredentials `json:"azure"`
+	// Version of kubectl used for the applications deployed to this cluster.
+	// The version specified by the application takes precedence over this.
+	// Empty means the default version of piped will be used.
+	KubectlVersion string `json:"kubectlVersion"`
+	// Version of helm used for the applications deployed to this cluster.
+	// The version specified by the application takes precedence over this.
+	// Empty means the default version of piped will be used.
+	HelmVersion string `json:"helmVersion"`
+	// Client-side rate limit of the requests sent to the API server of this cluster
+	// by the appliers and the application resource informer.
+	RateLimit KubernetesClientRateLimit `json:"rateLimit"`
 	// Configuration for application resource informer.
 	AppStateInformer KubernetesAppStateInformer `json:"appStateInformer"`
 }
 
+// KubernetesClientRateLimit represents the client-side rate limit of the requests sent to a Kubernetes API server.
+type KubernetesClientRateLimit struct {
+	// The maximum number of requests per second.
+	// Zero means the kubectl commands are not limited
+	// and the informer uses the default of client-go, which is 5.
+	QPS float32 `json:"qps"`
+	// The maximum number of requests sent at once.
+	// Zero means the same with the QPS rounded up for the kubectl commands
+	// and the default of client-go, which is 10, for the informer.
+	Burst int `json:"burst"`
+}
+
+// Validate returns an error if any wrong configuration value was found.
+func (l KubernetesClientRateLimit) Validate() error {
+	if l.QPS < 0 {
+		return errors.New("qps must be greater than or equal to 0")
+	}
+	if l.Burst < 0 {
+		return errors.New("burst must be greater than or equal to 0")
+	}
+	return nil
+}
+
 type KubernetesAppStateInformer struct {
 	// Only watches the specified namespace.
 	// Empty means watching all namespaces.
 	Namespace string `json:"namespace"`
+	// Whether all custom resources defined by CustomResourceDefinitions
+	// in the cluster should be added to the watching targets.
+	// Default is false.
+	IncludeCustomResources bool `json:"includeCustomResources"`
 	// List of resources that should be added to the watching targets.
 	IncludeResources []KubernetesResourceMatcher `json:"includeResources"`
 	// List of resources that should be ignored from the watching targets.
@@ -353,6 +715,8 @@ type KubernetesAppStateInformer struct {
 
 type KubernetesResourceMatcher struct {
 	// The APIVersion of the kubernetes resource.
+	// Use "group/*" to match all versions of an API group.
+	// Empty means all API versions are matching, in that case Kind must be specified.
 	APIVersion string `json:"apiVersion"`
 	// The kind name of the kubernetes resource.
 	// Empty means all kinds are matching.
@@ -366,6 +730,74 @@ type CloudProviderTerraformConfig struct {
 	// 'image_id_list=["ami-abc123","ami-def456"]'
 	// 'image_id_map={"us-east-1":"ami-abc123","us-east-2":"ami-def456"}'
 	Vars []string `json:"vars"`
+	// Configuration to execute the plans and applies as runs of Terraform Cloud
+	// or Terraform Enterprise instead of running terraform inside piped.
+	// PipeCD still orchestrates the stages, approvals and plan-preview.
+	// Empty means terraform commands are executed by piped.
+	Cloud *TerraformCloudConfig `json:"cloud"`
+}
+
+func (c *CloudProviderTerraformConfig) Validate() error {
+	if c.Cloud != nil {
+		return c.Cloud.Validate()
+	}
+	return nil
+}
+
+// TerraformCloudConfig contains the information to connect to
+// Terraform Cloud or Terraform Enterprise.
+// The workspaces used by applications must be configured with the API-driven workflow.
+type TerraformCloudConfig struct {
+	// The address of Terraform Cloud or Terraform Enterprise.
+	// Default is https://app.terraform.io.
+	Address string `json:"address"`
+	// The name of the organization owning the workspaces.
+	Organization string `json:"organization"`
+	// The path to the file containing the API token used to create and apply runs.
+	TokenFile string `json:"tokenFile"`
+	// How often to check the status of the runs.
+	// Default is 10s.
+	PollInterval Duration `json:"pollInterval"`
+}
+
+func (c *TerraformCloudConfig) Validate() error {
+	if c.Organization == "" {
+		return errors.New("organization must be set for terraform cloud")
+	}
+	if c.TokenFile == "" {
+		return errors.New("tokenFile must be set for terraform cloud")
+	}
+	if c.PollInterval < 0 {
+		return errors.New("pollInterval must not be negative")
+	}
+	return nil
+}
+
+type CloudProviderPulumiConfig struct {
+	// The URL of the backend storing the state of the stacks,
+	// e.g. "s3://my-bucket", "gs://my-bucket", "file:///var/pulumi".
+	// Empty means the Pulumi Service is used.
+	BackendURL string `json:"backendURL"`
+	// The path to the file containing the access token of the Pulumi Service.
+	// Required when the Pulumi Service is used as the backend.
+	AccessTokenFile string `json:"accessTokenFile"`
+	// The path to the file containing the passphrase of the stacks
+	// using the passphrase secrets provider.
+	ConfigPassphraseFile string `json:"configPassphraseFile"`
+}
+
+type CloudProviderAnsibleConfig struct {
+	// The path to the ansible-playbook command.
+	// Empty means the one found in PATH is used.
+	PlaybookPath string `json:"playbookPath"`
+	// The user to connect to the hosts as.
+	// Empty means the one configured in the inventory or ansible.cfg is used.
+	RemoteUser string `json:"remoteUser"`
+	// The path to the private key file used to connect to the hosts by SSH.
+	PrivateKeyFile string `json:"privateKeyFile"`
+	// The path to the file containing the password to decrypt
+	// the files encrypted by Ansible Vault.
+	VaultPasswordFile string `json:"vaultPasswordFile"`
 }
 
 type CloudProviderCloudRunConfig struct {
@@ -374,7 +806,16 @@ type CloudProviderCloudRunConfig struct {
 	// The region of running CloudRun service.
 	Region string `json:"region"`
 	// The path to the service account file for accessing CloudRun service.
+	// Empty means the ambient credentials such as GKE Workload Identity
+	// or the service account of the running instance are used.
 	CredentialsFile string `json:"credentialsFile"`
+	// The email of the service account to impersonate.
+	// The loaded credentials must be granted the Service Account Token Creator role on it.
+	// Empty means the loaded credentials are directly used.
+	ImpersonateServiceAccount string `json:"impersonateServiceAccount"`
+	// The chain of service accounts to delegate the impersonation through.
+	// Each of them must be granted the Service Account Token Creator role on the next one.
+	ImpersonateDelegates []string `json:"impersonateDelegates"`
 }
 
 type CloudProviderLambdaConfig struct {
@@ -392,6 +833,13 @@ type CloudProviderLambdaConfig struct {
 	// If empty, the environment variable "AWS_PROFILE" is used.
 	// "default" is populated if the environment variable is also not set.
 	Profile string `json:"profile"`
+	// The IAM role to assume by using the loaded credentials.
+	// This allows chaining from the role of piped, e.g. given by EKS IRSA,
+	// to a role in the target account.
+	// Empty means the loaded credentials are directly used.
+	AssumeRoleARN string `json:"assumeRoleARN"`
+	// The external ID required by the trust policy of the role to assume.
+	AssumeRoleExternalID string `json:"assumeRoleExternalID"`
 }
 
 type CloudProviderECSConfig struct {
@@ -409,15 +857,129 @@ type CloudProviderECSConfig struct {
 	// If empty, the environment variable "AWS_PROFILE" is used.
 	// "default" is populated if the environment variable is also not set.
 	Profile string `json:"profile"`
+	// The IAM role to assume by using the loaded credentials.
+	// This allows chaining from the role of piped, e.g. given by EKS IRSA,
+	// to a role in the target account.
+	// Empty means the loaded credentials are directly used.
+	AssumeRoleARN string `json:"assumeRoleARN"`
+	// The external ID required by the trust policy of the role to assume.
+	AssumeRoleExternalID string `json:"assumeRoleExternalID"`
+}
+
+// VMPlatform represents the platform where the virtual machines are running on.
+type VMPlatform string
+
+const (
+	VMPlatformOpenStack VMPlatform = "OPENSTACK"
+	VMPlatformGCE       VMPlatform = "GCE"
+)
+
+type CloudProviderVMConfig struct {
+	// The platform where the virtual machines are running on.
+	// Currently, OPENSTACK and GCE are supported.
+	Platform VMPlatform `json:"platform"`
+	// Configuration for OPENSTACK platform.
+	OpenStack *VMOpenStackConfig `json:"openstack"`
+	// Configuration for GCE platform.
+	GCE *VMGCEConfig `json:"gce"`
+}
+
+func (c *CloudProviderVMConfig) Validate() error {
+	switch c.Platform {
+	case VMPlatformOpenStack:
+		if c.OpenStack == nil {
+			return fmt.Errorf("openstack must be configured for platform %s", c.Platform)
+		}
+		return c.OpenStack.Validate()
+	case VMPlatformGCE:
+		if c.GCE == nil {
+			return fmt.Errorf("gce must be configured for platform %s", c.Platform)
+		}
+		return c.GCE.Validate()
+	default:
+		return fmt.Errorf("unsupported vm platform: %s", c.Platform)
+	}
+}
+
+type VMOpenStackConfig struct {
+	// The URL of the Keystone v3 endpoint, e.g. "https://keystone.example.com:5000/v3".
+	AuthURL string `json:"authURL"`
+	// The region of the compute and image services.
+	// Empty means the first endpoint found in the service catalog is used.
+	Region string `json:"region"`
+	// The ID of the application credential.
+	// Application credentials are preferred over the password authentication.
+	ApplicationCredentialID string `json:"applicationCredentialID"`
+	// The path to the file containing the secret of the application credential.
+	ApplicationCredentialSecretFile string `json:"applicationCredentialSecretFile"`
+	// The name of the user for the password authentication.
+	Username string `json:"username"`
+	// The path to the file containing the password of the user.
+	PasswordFile string `json:"passwordFile"`
+	// The name of the domain the user belongs to.
+	// Default is "Default".
+	UserDomainName string `json:"userDomainName" default:"Default"`
+	// The name of the project to be scoped to.
+	ProjectName string `json:"projectName"`
+	// The name of the domain the project belongs to.
+	// Default is "Default".
+	ProjectDomainName string `json:"projectDomainName" default:"Default"`
+}
+
+func (c *VMOpenStackConfig) Validate() error {
+	if c.AuthURL == "" {
+		return fmt.Errorf("openstack.authURL is required")
+	}
+	if c.ApplicationCredentialID != "" {
+		if c.ApplicationCredentialSecretFile == "" {
+			return fmt.Errorf("openstack.applicationCredentialSecretFile is required when applicationCredentialID is specified")
+		}
+		return nil
+	}
+	if c.Username == "" || c.PasswordFile == "" || c.ProjectName == "" {
+		return fmt.Errorf("openstack.username, passwordFile and projectName are required when applicationCredentialID is not specified")
+	}
+	return nil
+}
+
+type VMGCEConfig struct {
+	// The GCP project hosting the managed instance groups.
+	Project string `json:"project"`
+	// The zone of the zonal managed instance groups.
+	// Either zone or region must be specified.
+	Zone string `json:"zone"`
+	// The region of the regional managed instance groups.
+	// Either zone or region must be specified.
+	Region string `json:"region"`
+	// The path to the service account file for accessing Compute Engine.
+	// Empty means the ambient credentials such as the service account
+	// of the running instance are used.
+	CredentialsFile string `json:"credentialsFile"`
+}
+
+func (c *VMGCEConfig) Validate() error {
+	if c.Project == "" {
+		return fmt.Errorf("gce.project is required")
+	}
+	if (c.Zone == "") == (c.Region == "") {
+		return fmt.Errorf("exactly one of gce.zone and gce.region must be specified")
+	}
+	return nil
 }
 
 type PipedAnalysisProvider struct {
 	Name string                     `json:"name"`
 	Type model.AnalysisProviderType `json:"type"`
 
-	PrometheusConfig  *AnalysisProviderPrometheusConfig  `json:"prometheus"`
-	DatadogConfig     *AnalysisProviderDatadogConfig     `json:"datadog"`
-	StackdriverConfig *AnalysisProviderStackdriverConfig `json:"stackdriver"`
+	PrometheusConfig    *AnalysisProviderPrometheusConfig    `json:"prometheus"`
+	DatadogConfig       *AnalysisProviderDatadogConfig       `json:"datadog"`
+	StackdriverConfig   *AnalysisProviderStackdriverConfig   `json:"stackdriver"`
+	GraphiteConfig      *AnalysisProviderGraphiteConfig      `json:"graphite"`
+	InfluxDBConfig      *AnalysisProviderInfluxDBConfig      `json:"influxdb"`
+	WavefrontConfig     *AnalysisProviderWavefrontConfig     `json:"wavefront"`
+	WebhookConfig       *AnalysisProviderWebhookConfig       `json:"webhook"`
+	ElasticsearchConfig *AnalysisProviderElasticsearchConfig `json:"elasticsearch"`
+	PluginConfig        *AnalysisProviderPluginConfig        `json:"plugin"`
 }
 
 type genericPipedAnalysisProvider struct {
@@ -451,6 +1013,36 @@ func (p *PipedAnalysisProvider) UnmarshalJSON(data []byte) error {
 		if len(gp.Config) > 0 {
 			err = json.Unmarshal(gp.Config, p.StackdriverConfig)
 		}
+	case model.AnalysisProviderGraphite:
+		p.GraphiteConfig = &AnalysisProviderGraphiteConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.GraphiteConfig)
+		}
+	case model.AnalysisProviderInfluxDB:
+		p.InfluxDBConfig = &AnalysisProviderInfluxDBConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.InfluxDBConfig)
+		}
+	case model.AnalysisProviderWavefront:
+		p.WavefrontConfig = &AnalysisProviderWavefrontConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.WavefrontConfig)
+		}
+	case model.AnalysisProviderWebhook:
+		p.WebhookConfig = &AnalysisProviderWebhookConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.WebhookConfig)
+		}
+	case model.AnalysisProviderElasticsearch:
+		p.ElasticsearchConfig = &AnalysisProviderElasticsearchConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.ElasticsearchConfig)
+		}
+	case model.AnalysisProviderPlugin:
+		p.PluginConfig = &AnalysisProviderPluginConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.PluginConfig)
+		}
 	default:
 		err = fmt.Errorf("unsupported analysis provider type: %s", p.Name)
 	}
@@ -465,6 +1057,18 @@ func (p *PipedAnalysisProvider) Validate() error {
 		return p.DatadogConfig.Validate()
 	case model.AnalysisProviderStackdriver:
 		return p.StackdriverConfig.Validate()
+	case model.AnalysisProviderGraphite:
+		return p.GraphiteConfig.Validate()
+	case model.AnalysisProviderInfluxDB:
+		return p.InfluxDBConfig.Validate()
+	case model.AnalysisProviderWavefront:
+		return p.WavefrontConfig.Validate()
+	case model.AnalysisProviderWebhook:
+		return p.WebhookConfig.Validate()
+	case model.AnalysisProviderElasticsearch:
+		return p.ElasticsearchConfig.Validate()
+	case model.AnalysisProviderPlugin:
+		return p.PluginConfig.Validate()
 	default:
 		return fmt.Errorf("unknow provider type: %s", p.Type)
 	}
@@ -515,62 +1119,680 @@ func (a *AnalysisProviderStackdriverConfig) Validate() error {
 	return nil
 }
 
-type Notifications struct {
-	// List of notification routes.
-	Routes []NotificationRoute `json:"routes"`
-	// List of notification receivers.
-	Receivers []NotificationReceiver `json:"receivers"`
+type AnalysisProviderGraphiteConfig struct {
+	// The address of Graphite server.
+	Address string `json:"address"`
+	// The path to the username file.
+	UsernameFile string `json:"usernameFile"`
+	// The path to the password file.
+	PasswordFile string `json:"passwordFile"`
 }
 
-type NotificationRoute struct {
-	Name         string   `json:"name"`
-	Receiver     string   `json:"receiver"`
-	Events       []string `json:"events"`
-	IgnoreEvents []string `json:"ignoreEvents"`
-	Groups       []string `json:"groups"`
-	IgnoreGroups []string `json:"ignoreGroups"`
-	Apps         []string `json:"apps"`
-	IgnoreApps   []string `json:"ignoreApps"`
-	Envs         []string `json:"envs"`
-	IgnoreEnvs   []string `json:"ignoreEnvs"`
+func (a *AnalysisProviderGraphiteConfig) Validate() error {
+	if a.Address == "" {
+		return fmt.Errorf("graphite analysis provider requires the address")
+	}
+	return nil
 }
 
-type NotificationReceiver struct {
-	Name    string                       `json:"name"`
-	Slack   *NotificationReceiverSlack   `json:"slack"`
-	Webhook *NotificationReceiverWebhook `json:"webhook"`
+type AnalysisProviderInfluxDBConfig struct {
+	// The address of InfluxDB server.
+	Address string `json:"address"`
+	// The organization name or ID where the queried buckets belong to.
+	Organization string `json:"organization"`
+	// The path to the API token file.
+	TokenFile string `json:"tokenFile"`
 }
 
-type NotificationReceiverSlack struct {
-	HookURL string `json:"hookURL"`
+func (a *AnalysisProviderInfluxDBConfig) Validate() error {
+	if a.Address == "" {
+		return fmt.Errorf("influxdb analysis provider requires the address")
+	}
+	if a.Organization == "" {
+		return fmt.Errorf("influxdb analysis provider requires the organization")
+	}
+	return nil
 }
 
-type NotificationReceiverWebhook struct {
+type AnalysisProviderWavefrontConfig struct {
+	// The address of Wavefront cluster, e.g. https://example.wavefront.com.
+	Address string `json:"address"`
+	// The path to the API token file.
+	TokenFile string `json:"tokenFile"`
+}
+
+func (a *AnalysisProviderWavefrontConfig) Validate() error {
+	if a.Address == "" {
+		return fmt.Errorf("wavefront analysis provider requires the address")
+	}
+	if a.TokenFile == "" {
+		return fmt.Errorf("wavefront analysis provider requires the token file")
+	}
+	return nil
+}
+
+type AnalysisProviderWebhookConfig struct {
+	// The URL of the external judge receiving the deployment context.
 	URL string `json:"url"`
+	// The path to the file containing the token sent as a bearer token.
+	TokenFile string `json:"tokenFile"`
+	// Additional headers sent with every request.
+	Headers map[string]string `json:"headers"`
 }
 
-type SecretManagement struct {
-	// Which management service should be used.
-	// Available values: KEY_PAIR, SEALING_KEY, GCP_KMS, AWS_KMS
-	Type model.SecretManagementType `json:"type"`
+func (a *AnalysisProviderWebhookConfig) Validate() error {
+	if a.URL == "" {
+		return fmt.Errorf("webhook analysis provider requires the url")
+	}
+	return nil
+}
 
-	KeyPair *SecretManagementKeyPair
-	GCPKMS  *SecretManagementGCPKMS
+type AnalysisProviderPluginConfig struct {
+	// The name of the piped plugin providing this analysis provider.
+	Plugin string `json:"plugin"`
+	// The options passed to the plugin as is on every query.
+	Options json.RawMessage `json:"options"`
 }
 
-func (s *SecretManagement) Validate() error {
-	switch s.Type {
-	case model.SecretManagementTypeKeyPair:
-		return s.KeyPair.Validate()
-	case model.SecretManagementTypeGCPKMS:
-		return s.GCPKMS.Validate()
-	default:
-		return fmt.Errorf("unsupported sealed secret management type: %s", s.Type)
+func (a *AnalysisProviderPluginConfig) Validate() error {
+	if a.Plugin == "" {
+		return fmt.Errorf("plugin analysis provider requires the plugin name")
 	}
+	return nil
 }
 
-type SecretManagementKeyPair struct {
-	// Configurable fields for SEALING_KEY.
+type AnalysisProviderElasticsearchConfig struct {
+	// The address of Elasticsearch or OpenSearch server.
+	Address string `json:"address"`
+	// The index or the index pattern to search, e.g. logs-*.
+	// All indices are searched if not specified.
+	Index string `json:"index"`
+	// The field used to filter the documents within the analysis window.
+	// Default is @timestamp.
+	TimestampField string `json:"timestampField"`
+	// The path to the username file.
+	UsernameFile string `json:"usernameFile"`
+	// The path to the password file.
+	PasswordFile string `json:"passwordFile"`
+	// The path to the file containing the base64 encoded API key.
+	APIKeyFile string `json:"apiKeyFile"`
+}
+
+func (a *AnalysisProviderElasticsearchConfig) Validate() error {
+	if a.Address == "" {
+		return fmt.Errorf("elasticsearch analysis provider requires the address")
+	}
+	if a.APIKeyFile != "" && (a.UsernameFile != "" || a.PasswordFile != "") {
+		return fmt.Errorf("only one of apiKeyFile or usernameFile/passwordFile can be specified for elasticsearch analysis provider")
+	}
+	return nil
+}
+
+type ImageScannerType string
+
+const (
+	// Scans the images by the Trivy client connecting to a Trivy server.
+	ImageScannerTrivy ImageScannerType = "TRIVY"
+	// Uses the scan results stored in a Harbor registry.
+	ImageScannerHarbor ImageScannerType = "HARBOR"
+)
+
+type PipedImageScanner struct {
+	Name string           `json:"name"`
+	Type ImageScannerType `json:"type"`
+
+	TrivyConfig  *ImageScannerTrivyConfig  `json:"trivy"`
+	HarborConfig *ImageScannerHarborConfig `json:"harbor"`
+}
+
+type genericPipedImageScanner struct {
+	Name   string           `json:"name"`
+	Type   ImageScannerType `json:"type"`
+	Config json.RawMessage  `json:"config"`
+}
+
+func (p *PipedImageScanner) UnmarshalJSON(data []byte) error {
+	var err error
+	gp := genericPipedImageScanner{}
+	if err = json.Unmarshal(data, &gp); err != nil {
+		return err
+	}
+	p.Name = gp.Name
+	p.Type = gp.Type
+
+	switch p.Type {
+	case ImageScannerTrivy:
+		p.TrivyConfig = &ImageScannerTrivyConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.TrivyConfig)
+		}
+	case ImageScannerHarbor:
+		p.HarborConfig = &ImageScannerHarborConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.HarborConfig)
+		}
+	default:
+		err = fmt.Errorf("unsupported image scanner type: %s", p.Type)
+	}
+	return err
+}
+
+func (p *PipedImageScanner) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("image scanner name must be set")
+	}
+	switch p.Type {
+	case ImageScannerTrivy:
+		return p.TrivyConfig.Validate()
+	case ImageScannerHarbor:
+		return p.HarborConfig.Validate()
+	default:
+		return fmt.Errorf("unknown image scanner type: %s", p.Type)
+	}
+}
+
+type ImageScannerTrivyConfig struct {
+	// The address of the Trivy server.
+	Address string `json:"address"`
+	// The path to the file containing the token to authenticate with the server.
+	TokenFile string `json:"tokenFile"`
+	// Version of the Trivy client will be used.
+	// Empty means the pre-installed or the default version.
+	Version string `json:"version"`
+}
+
+func (c *ImageScannerTrivyConfig) Validate() error {
+	if c.Address == "" {
+		return fmt.Errorf("trivy image scanner requires the address")
+	}
+	return nil
+}
+
+type ImageScannerHarborConfig struct {
+	// The address of the Harbor registry, e.g. https://harbor.example.com
+	Address string `json:"address"`
+	// The path to the username file.
+	UsernameFile string `json:"usernameFile"`
+	// The path to the password file.
+	PasswordFile string `json:"passwordFile"`
+}
+
+func (c *ImageScannerHarborConfig) Validate() error {
+	if c.Address == "" {
+		return fmt.Errorf("harbor image scanner requires the address")
+	}
+	if (c.UsernameFile == "") != (c.PasswordFile == "") {
+		return fmt.Errorf("both usernameFile and passwordFile must be set for harbor image scanner")
+	}
+	return nil
+}
+
+type ChangeManagementProviderType string
+
+const (
+	// Manages the change tickets as Jira issues.
+	ChangeManagementProviderJira ChangeManagementProviderType = "JIRA"
+	// Manages the change tickets as ServiceNow change requests.
+	ChangeManagementProviderServiceNow ChangeManagementProviderType = "SERVICENOW"
+)
+
+type PipedChangeManagementProvider struct {
+	Name string                       `json:"name"`
+	Type ChangeManagementProviderType `json:"type"`
+
+	JiraConfig       *ChangeManagementJiraConfig       `json:"jira"`
+	ServiceNowConfig *ChangeManagementServiceNowConfig `json:"servicenow"`
+}
+
+type genericPipedChangeManagementProvider struct {
+	Name   string                       `json:"name"`
+	Type   ChangeManagementProviderType `json:"type"`
+	Config json.RawMessage              `json:"config"`
+}
+
+func (p *PipedChangeManagementProvider) UnmarshalJSON(data []byte) error {
+	var err error
+	gp := genericPipedChangeManagementProvider{}
+	if err = json.Unmarshal(data, &gp); err != nil {
+		return err
+	}
+	p.Name = gp.Name
+	p.Type = gp.Type
+
+	switch p.Type {
+	case ChangeManagementProviderJira:
+		p.JiraConfig = &ChangeManagementJiraConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.JiraConfig)
+		}
+	case ChangeManagementProviderServiceNow:
+		p.ServiceNowConfig = &ChangeManagementServiceNowConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.ServiceNowConfig)
+		}
+	default:
+		err = fmt.Errorf("unsupported change management provider type: %s", p.Type)
+	}
+	return err
+}
+
+func (p *PipedChangeManagementProvider) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("change management provider name must be set")
+	}
+	switch p.Type {
+	case ChangeManagementProviderJira:
+		return p.JiraConfig.Validate()
+	case ChangeManagementProviderServiceNow:
+		return p.ServiceNowConfig.Validate()
+	default:
+		return fmt.Errorf("unknown change management provider type: %s", p.Type)
+	}
+}
+
+type ChangeManagementJiraConfig struct {
+	// The address of the Jira site, e.g. https://example.atlassian.net
+	Address string `json:"address"`
+	// The key of the project where the issues are created.
+	Project string `json:"project"`
+	// The name of the issue type of the created issues.
+	// Default is "Change".
+	IssueType string `json:"issueType" default:"Change"`
+	// The path to the file containing the username or the email of the account.
+	UsernameFile string `json:"usernameFile"`
+	// The path to the file containing the API token or the password of the account.
+	TokenFile string `json:"tokenFile"`
+	// The names of the statuses meaning the issue was approved.
+	// Default is ["Approved"].
+	ApprovedStatuses []string `json:"approvedStatuses"`
+	// The names of the statuses meaning the issue was rejected.
+	// Default is ["Declined", "Rejected"].
+	RejectedStatuses []string `json:"rejectedStatuses"`
+	// The name of the transition applied when the deployment succeeded.
+	// Empty means only a comment is added.
+	SuccessTransition string `json:"successTransition"`
+	// The name of the transition applied when the deployment failed or was cancelled.
+	// Empty means only a comment is added.
+	FailureTransition string `json:"failureTransition"`
+}
+
+func (c *ChangeManagementJiraConfig) Validate() error {
+	if c.Address == "" {
+		return fmt.Errorf("jira change management provider requires the address")
+	}
+	if c.Project == "" {
+		return fmt.Errorf("jira change management provider requires the project")
+	}
+	if c.UsernameFile == "" || c.TokenFile == "" {
+		return fmt.Errorf("both usernameFile and tokenFile must be set for jira change management provider")
+	}
+	return nil
+}
+
+type ChangeManagementServiceNowConfig struct {
+	// The address of the ServiceNow instance, e.g. https://example.service-now.com
+	Address string `json:"address"`
+	// The path to the username file.
+	UsernameFile string `json:"usernameFile"`
+	// The path to the password file.
+	PasswordFile string `json:"passwordFile"`
+	// The value of the state field set when the deployment finished.
+	// Default is "3" meaning Closed.
+	ClosedState string `json:"closedState" default:"3"`
+}
+
+func (c *ChangeManagementServiceNowConfig) Validate() error {
+	if c.Address == "" {
+		return fmt.Errorf("servicenow change management provider requires the address")
+	}
+	if c.UsernameFile == "" || c.PasswordFile == "" {
+		return fmt.Errorf("both usernameFile and passwordFile must be set for servicenow change management provider")
+	}
+	return nil
+}
+
+type FeatureFlagProviderType string
+
+const (
+	// Changes the flags through the LaunchDarkly REST API.
+	FeatureFlagProviderLaunchDarkly FeatureFlagProviderType = "LAUNCHDARKLY"
+	// Changes the flags through a simple HTTP API which can be implemented
+	// in front of any OpenFeature compliant flag management system.
+	FeatureFlagProviderHTTP FeatureFlagProviderType = "HTTP"
+)
+
+type PipedFeatureFlagProvider struct {
+	Name string                  `json:"name"`
+	Type FeatureFlagProviderType `json:"type"`
+
+	LaunchDarklyConfig *FeatureFlagLaunchDarklyConfig `json:"launchdarkly"`
+	HTTPConfig         *FeatureFlagHTTPConfig         `json:"http"`
+}
+
+type genericPipedFeatureFlagProvider struct {
+	Name   string                  `json:"name"`
+	Type   FeatureFlagProviderType `json:"type"`
+	Config json.RawMessage         `json:"config"`
+}
+
+func (p *PipedFeatureFlagProvider) UnmarshalJSON(data []byte) error {
+	var err error
+	gp := genericPipedFeatureFlagProvider{}
+	if err = json.Unmarshal(data, &gp); err != nil {
+		return err
+	}
+	p.Name = gp.Name
+	p.Type = gp.Type
+
+	switch p.Type {
+	case FeatureFlagProviderLaunchDarkly:
+		p.LaunchDarklyConfig = &FeatureFlagLaunchDarklyConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.LaunchDarklyConfig)
+		}
+	case FeatureFlagProviderHTTP:
+		p.HTTPConfig = &FeatureFlagHTTPConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.HTTPConfig)
+		}
+	default:
+		err = fmt.Errorf("unsupported feature flag provider type: %s", p.Type)
+	}
+	return err
+}
+
+func (p *PipedFeatureFlagProvider) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("feature flag provider name must be set")
+	}
+	switch p.Type {
+	case FeatureFlagProviderLaunchDarkly:
+		return p.LaunchDarklyConfig.Validate()
+	case FeatureFlagProviderHTTP:
+		return p.HTTPConfig.Validate()
+	default:
+		return fmt.Errorf("unknown feature flag provider type: %s", p.Type)
+	}
+}
+
+type FeatureFlagLaunchDarklyConfig struct {
+	// The address of the LaunchDarkly API.
+	// Default is https://app.launchdarkly.com
+	Address string `json:"address"`
+	// The path to the file containing the API access token
+	// which has the permission to update the flags.
+	APITokenFile string `json:"apiTokenFile"`
+}
+
+func (c *FeatureFlagLaunchDarklyConfig) Validate() error {
+	if c.APITokenFile == "" {
+		return fmt.Errorf("launchdarkly feature flag provider requires apiTokenFile")
+	}
+	return nil
+}
+
+type FeatureFlagHTTPConfig struct {
+	// The base address of the flag management API.
+	// The state of a flag is read by GET and written by PUT to <address>/flags/<flag>?environment=<environment>.
+	Address string `json:"address"`
+	// The path to the file containing the bearer token sent in the Authorization header.
+	TokenFile string `json:"tokenFile"`
+}
+
+func (c *FeatureFlagHTTPConfig) Validate() error {
+	if c.Address == "" {
+		return fmt.Errorf("http feature flag provider requires the address")
+	}
+	return nil
+}
+
+type CommitStatusProviderType string
+
+const (
+	CommitStatusProviderGitHub CommitStatusProviderType = "GITHUB"
+	CommitStatusProviderGitLab CommitStatusProviderType = "GITLAB"
+)
+
+type PipedCommitStatusProvider struct {
+	Name string                   `json:"name"`
+	Type CommitStatusProviderType `json:"type"`
+
+	GitHubConfig *CommitStatusGitHubConfig `json:"github"`
+	GitLabConfig *CommitStatusGitLabConfig `json:"gitlab"`
+}
+
+type genericPipedCommitStatusProvider struct {
+	Name   string                   `json:"name"`
+	Type   CommitStatusProviderType `json:"type"`
+	Config json.RawMessage          `json:"config"`
+}
+
+func (p *PipedCommitStatusProvider) UnmarshalJSON(data []byte) error {
+	var err error
+	gp := genericPipedCommitStatusProvider{}
+	if err = json.Unmarshal(data, &gp); err != nil {
+		return err
+	}
+	p.Name = gp.Name
+	p.Type = gp.Type
+
+	switch p.Type {
+	case CommitStatusProviderGitHub:
+		p.GitHubConfig = &CommitStatusGitHubConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.GitHubConfig)
+		}
+	case CommitStatusProviderGitLab:
+		p.GitLabConfig = &CommitStatusGitLabConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.GitLabConfig)
+		}
+	default:
+		err = fmt.Errorf("unsupported commit status provider type: %s", p.Type)
+	}
+	return err
+}
+
+func (p *PipedCommitStatusProvider) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("commit status provider name must be set")
+	}
+	switch p.Type {
+	case CommitStatusProviderGitHub:
+		return p.GitHubConfig.Validate()
+	case CommitStatusProviderGitLab:
+		return p.GitLabConfig.Validate()
+	default:
+		return fmt.Errorf("unknown commit status provider type: %s", p.Type)
+	}
+}
+
+type CommitStatusGitHubConfig struct {
+	// The base URL of GitHub API.
+	// Default is https://api.github.com
+	// For GitHub Enterprise Server, use https://HOSTNAME/api/v3
+	Address string `json:"address"`
+	// The path to the file containing the token
+	// which has the permission to create commit statuses.
+	TokenFile string `json:"tokenFile"`
+}
+
+func (c *CommitStatusGitHubConfig) Validate() error {
+	if c.TokenFile == "" {
+		return fmt.Errorf("github commit status provider requires tokenFile")
+	}
+	return nil
+}
+
+type CommitStatusGitLabConfig struct {
+	// The address of GitLab server.
+	// Default is https://gitlab.com
+	Address string `json:"address"`
+	// The path to the file containing the access token
+	// which has the api scope.
+	TokenFile string `json:"tokenFile"`
+}
+
+func (c *CommitStatusGitLabConfig) Validate() error {
+	if c.TokenFile == "" {
+		return fmt.Errorf("gitlab commit status provider requires tokenFile")
+	}
+	return nil
+}
+
+type Notifications struct {
+	// List of notification routes.
+	Routes []NotificationRoute `json:"routes"`
+	// List of notification receivers.
+	Receivers []NotificationReceiver `json:"receivers"`
+}
+
+type NotificationRoute struct {
+	Name         string   `json:"name"`
+	Receiver     string   `json:"receiver"`
+	Events       []string `json:"events"`
+	IgnoreEvents []string `json:"ignoreEvents"`
+	Groups       []string `json:"groups"`
+	IgnoreGroups []string `json:"ignoreGroups"`
+	Apps         []string `json:"apps"`
+	IgnoreApps   []string `json:"ignoreApps"`
+	Envs         []string `json:"envs"`
+	IgnoreEnvs   []string `json:"ignoreEnvs"`
+	// The template used to build the messages sent through this route.
+	// Empty means the default messages of the receiver will be used.
+	Template *NotificationTemplate `json:"template"`
+	// The dashboard, panel and tags of the annotations written through this route.
+	// Used only by the Grafana receiver.
+	Annotation *NotificationAnnotation `json:"annotation"`
+}
+
+// NotificationAnnotation specifies where the Grafana annotations are written.
+type NotificationAnnotation struct {
+	// The UID of the dashboard where the annotations are added.
+	// Empty means the annotations are organization wide.
+	DashboardUID string `json:"dashboardUID"`
+	// The ID of the panel where the annotations are added.
+	// Zero means the annotations are shown on all panels of the dashboard.
+	PanelID int64 `json:"panelID"`
+	// List of additional tags of the annotations.
+	Tags []string `json:"tags"`
+}
+
+// NotificationTemplate contains Go templates to customize the notification messages.
+// Each template is executed over the payload of the notification event.
+type NotificationTemplate struct {
+	// The template of the message title.
+	// Empty means the default title will be used.
+	Title string `json:"title"`
+	// The template of the message text.
+	// Empty means the default text will be used.
+	Text string `json:"text"`
+	// List of additional fields appended to the message.
+	Fields []NotificationTemplateField `json:"fields"`
+}
+
+type NotificationTemplateField struct {
+	Title string `json:"title"`
+	// The template of the field value.
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func (t *NotificationTemplate) Validate() error {
+	if _, err := template.New("title").Parse(t.Title); err != nil {
+		return fmt.Errorf("invalid title template: %w", err)
+	}
+	if _, err := template.New("text").Parse(t.Text); err != nil {
+		return fmt.Errorf("invalid text template: %w", err)
+	}
+	for i, f := range t.Fields {
+		if f.Title == "" {
+			return fmt.Errorf("title of field %d must be set", i)
+		}
+		if _, err := template.New(f.Title).Parse(f.Value); err != nil {
+			return fmt.Errorf("invalid value template of field %s: %w", f.Title, err)
+		}
+	}
+	return nil
+}
+
+type NotificationReceiver struct {
+	Name    string                       `json:"name"`
+	Slack   *NotificationReceiverSlack   `json:"slack"`
+	Webhook *NotificationReceiverWebhook `json:"webhook"`
+	Grafana *NotificationReceiverGrafana `json:"grafana"`
+	// Reflect the deployments as GitHub Deployments and Deployment Statuses.
+	GitHubDeployment *NotificationReceiverGitHubDeployment `json:"githubDeployment"`
+}
+
+type NotificationReceiverSlack struct {
+	HookURL string `json:"hookURL"`
+}
+
+type NotificationReceiverWebhook struct {
+	URL string `json:"url"`
+}
+
+type NotificationReceiverGrafana struct {
+	// The address of the Grafana server, e.g. https://grafana.example.com
+	Address string `json:"address"`
+	// The path to the file containing the API key or the service account token
+	// which has the permission to write annotations.
+	APIKeyFile string `json:"apiKeyFile"`
+}
+
+func (g *NotificationReceiverGrafana) Validate() error {
+	if g.Address == "" {
+		return errors.New("grafana.address must be set")
+	}
+	if g.APIKeyFile == "" {
+		return errors.New("grafana.apiKeyFile must be set")
+	}
+	return nil
+}
+
+type NotificationReceiverGitHubDeployment struct {
+	// The base URL of GitHub API.
+	// Default is https://api.github.com
+	// For GitHub Enterprise Server, use https://HOSTNAME/api/v3
+	Address string `json:"address" default:"https://api.github.com"`
+	// The path to the file containing the token which has
+	// the permission to create deployments of the repositories.
+	TokenFile string `json:"tokenFile"`
+	// The name of GitHub environment where the deployments are created.
+	// Empty means the name of PipeCD environment of the application will be used.
+	Environment string `json:"environment"`
+}
+
+func (g *NotificationReceiverGitHubDeployment) Validate() error {
+	if g.TokenFile == "" {
+		return errors.New("githubDeployment.tokenFile must be set")
+	}
+	return nil
+}
+
+type SecretManagement struct {
+	// Which management service should be used.
+	// Available values: KEY_PAIR, SEALING_KEY, GCP_KMS, AWS_KMS
+	Type model.SecretManagementType `json:"type"`
+
+	KeyPair *SecretManagementKeyPair
+	GCPKMS  *SecretManagementGCPKMS
+}
+
+func (s *SecretManagement) Validate() error {
+	switch s.Type {
+	case model.SecretManagementTypeKeyPair:
+		return s.KeyPair.Validate()
+	case model.SecretManagementTypeGCPKMS:
+		return s.GCPKMS.Validate()
+	default:
+		return fmt.Errorf("unsupported sealed secret management type: %s", s.Type)
+	}
+}
+
+type SecretManagementKeyPair struct {
+	// Configurable fields for SEALING_KEY.
 	// The path to the private RSA key file.
 	PrivateKeyFile string `json:"privateKeyFile"`
 	// Base64 encoded string of private key.
@@ -711,3 +1933,275 @@ type PipedEventWatcherGitRepo struct {
 	// This is prioritized if both includes and this one are given.
 	Excludes []string `json:"excludes"`
 }
+
+type PipedToolExecution struct {
+	// The maximum number of tool processes can be running at the same time.
+	// Zero means no limit.
+	MaxConcurrency int `json:"maxConcurrency"`
+	// The maximum CPU time in seconds each tool process can consume.
+	// Zero means no limit.
+	MaxCPUSeconds int `json:"maxCPUSeconds"`
+	// The maximum virtual memory in megabytes each tool process can use.
+	// Zero means no limit.
+	MaxMemoryMB int `json:"maxMemoryMB"`
+	// Optional settings to run the tools inside ephemeral containers
+	// with the pinned images instead of the binaries on the host.
+	Container *PipedToolContainer `json:"container"`
+}
+
+func (p *PipedToolExecution) Validate() error {
+	if p.MaxConcurrency < 0 {
+		return errors.New("toolExecution.maxConcurrency must be greater than or equal to 0")
+	}
+	if p.MaxCPUSeconds < 0 {
+		return errors.New("toolExecution.maxCPUSeconds must be greater than or equal to 0")
+	}
+	if p.MaxMemoryMB < 0 {
+		return errors.New("toolExecution.maxMemoryMB must be greater than or equal to 0")
+	}
+	if p.Container != nil {
+		if err := p.Container.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type PipedRenderCache struct {
+	// The maximum total size in megabytes of the cached manifests.
+	// Zero means the cache is disabled.
+	MaxSizeMB int `json:"maxSizeMB"`
+	// The path to the directory where the rendered manifests are stored.
+	// Default is ".piped/render-cache" under the home directory.
+	Dir string `json:"dir"`
+}
+
+func (p *PipedRenderCache) Validate() error {
+	if p.MaxSizeMB < 0 {
+		return errors.New("renderCache.maxSizeMB must be greater than or equal to 0")
+	}
+	return nil
+}
+
+type PipedToolContainer struct {
+	// The command of the container runtime.
+	// Default is docker.
+	Runtime string `json:"runtime"`
+	// Map from the tool name (kubectl, kustomize, helm, terraform)
+	// to the image used to run it.
+	// The tools not specified here are still executed on the host.
+	Images map[string]string `json:"images"`
+	// The network the containers should be connected to.
+	// Default is host.
+	Network string `json:"network"`
+	// List of host paths to be mounted at the same paths inside the containers.
+	// The working directory of the tool is always mounted.
+	Mounts []string `json:"mounts"`
+	// List of environment variable names to be passed from piped to the containers.
+	Envs []string `json:"envs"`
+}
+
+func (c *PipedToolContainer) Validate() error {
+	if len(c.Images) == 0 {
+		return errors.New("toolExecution.container.images must contain at least one image")
+	}
+	for tool, image := range c.Images {
+		if image == "" {
+			return fmt.Errorf("toolExecution.container.images.%s must not be empty", tool)
+		}
+	}
+	return nil
+}
+
+type PipedSignatureVerification struct {
+	// The path to the GnuPG home directory containing the public keys
+	// trusted to sign the commits.
+	// Empty means the default GnuPG home directory of the piped process is used.
+	GPGHome string `json:"gpgHome"`
+	// The path to the allowed signers file listing the SSH keys trusted to sign the commits.
+	// See "ALLOWED SIGNERS" section of ssh-keygen(1) for its format.
+	SSHAllowedSignersFile string `json:"sshAllowedSignersFile"`
+	// List of paths to the cosign public keys trusted to sign the images.
+	// An image is verified when it was signed by any of them.
+	CosignPublicKeyFiles []string `json:"cosignPublicKeyFiles"`
+	// Version of cosign will be used.
+	// Empty means the pre-installed or the default version.
+	CosignVersion string `json:"cosignVersion"`
+}
+
+type PipedPagerDuty struct {
+	// The path to the file containing the REST API token
+	// used to check the maintenance windows and incidents.
+	APITokenFile string `json:"apiTokenFile"`
+	// List of PagerDuty services where the change events are sent.
+	Services []PipedPagerDutyService `json:"services"`
+}
+
+func (p *PipedPagerDuty) Validate() error {
+	ids := make(map[string]struct{}, len(p.Services))
+	for _, s := range p.Services {
+		if s.ID == "" {
+			return errors.New("pagerDuty.services.id must be set")
+		}
+		if s.RoutingKeyFile == "" {
+			return fmt.Errorf("pagerDuty.services.routingKeyFile must be set for service %s", s.ID)
+		}
+		if _, ok := ids[s.ID]; ok {
+			return fmt.Errorf("duplicated pagerDuty service %s", s.ID)
+		}
+		ids[s.ID] = struct{}{}
+	}
+	return nil
+}
+
+type PipedPagerDutyService struct {
+	// The ID of the PagerDuty service, e.g. PABC123.
+	ID string `json:"id"`
+	// The path to the file containing the integration key of
+	// the Events API v2 integration of the service.
+	RoutingKeyFile string `json:"routingKeyFile"`
+}
+
+var deploymentHookSecretNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type PipedDeploymentHookSecret struct {
+	// The name of the variable used to reference the secret in the hooks, e.g. CDN_API_TOKEN.
+	Name string `json:"name"`
+	// The path to the file containing the value of the secret.
+	File string `json:"file"`
+}
+
+func (s PipedDeploymentHookSecret) Validate() error {
+	if !deploymentHookSecretNameRegex.MatchString(s.Name) {
+		return fmt.Errorf("invalid deployment hook secret name %q", s.Name)
+	}
+	if strings.HasPrefix(s.Name, "PIPECD_") {
+		return fmt.Errorf("deployment hook secret name %s must not start with PIPECD_", s.Name)
+	}
+	if s.File == "" {
+		return fmt.Errorf("file of deployment hook secret %s must be set", s.Name)
+	}
+	return nil
+}
+
+// LoadDeploymentHookSecrets reads the values of all deployment hook secrets keyed by their names.
+func (s *PipedSpec) LoadDeploymentHookSecrets() (map[string]string, error) {
+	secrets := make(map[string]string, len(s.DeploymentHookSecrets))
+	for _, hs := range s.DeploymentHookSecrets {
+		data, err := os.ReadFile(hs.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read deployment hook secret %s: %w", hs.Name, err)
+		}
+		secrets[hs.Name] = strings.TrimSpace(string(data))
+	}
+	return secrets, nil
+}
+
+type PipedWebhook struct {
+	// The port number used to listen for the webhook calls.
+	// Zero means the webhook receiver is disabled.
+	Port int `json:"port"`
+	// The path to the file containing the token used to authenticate the webhook calls.
+	// The token must be sent in the Authorization header as "Bearer <token>".
+	TokenFile string `json:"tokenFile"`
+	// List of receivers which map the webhook calls to events or application syncs.
+	// The calls to /webhooks/<name> are handled by the receiver with that name.
+	Receivers []PipedWebhookReceiver `json:"receivers"`
+}
+
+func (p *PipedWebhook) Validate() error {
+	if p.Port < 0 {
+		return errors.New("webhook.port must be greater than or equal to 0")
+	}
+	if p.Port == 0 {
+		return nil
+	}
+	if strings.TrimSpace(p.TokenFile) == "" {
+		return errors.New("webhook.tokenFile must be set")
+	}
+	seen := make(map[string]struct{}, len(p.Receivers))
+	for i, r := range p.Receivers {
+		if r.Name == "" {
+			return fmt.Errorf("missing name of webhook receiver at index %d", i)
+		}
+		if _, ok := seen[r.Name]; ok {
+			return fmt.Errorf("duplicated webhook receiver name (%s) found", r.Name)
+		}
+		seen[r.Name] = struct{}{}
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadToken reads the token used to authenticate the webhook calls.
+// An error is returned when the file is empty to not accept the calls without token.
+func (p *PipedWebhook) LoadToken() (string, error) {
+	data, err := os.ReadFile(p.TokenFile)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("webhook token file %s is empty", p.TokenFile)
+	}
+	return token, nil
+}
+
+type PipedWebhookReceiver struct {
+	// The unique name of the receiver.
+	Name string `json:"name"`
+	// Register an event with the value extracted from the received payload.
+	// The registered event can be handled by Event Watcher.
+	Event *PipedWebhookEvent `json:"event"`
+	// List of application IDs to be synced when a call was received.
+	// The applications must be handled by this piped.
+	SyncApplications []string `json:"syncApplications"`
+}
+
+func (r *PipedWebhookReceiver) Validate() error {
+	if r.Event == nil && len(r.SyncApplications) == 0 {
+		return fmt.Errorf("either event or syncApplications must be set for webhook receiver %s", r.Name)
+	}
+	if r.Event != nil {
+		if r.Event.Name == "" {
+			return fmt.Errorf("missing event name of webhook receiver %s", r.Name)
+		}
+		if r.Event.DataPath == "" {
+			return fmt.Errorf("missing event dataPath of webhook receiver %s", r.Name)
+		}
+	}
+	return nil
+}
+
+type PipedWebhookEvent struct {
+	// The name of the event to be registered.
+	Name string `json:"name"`
+	// Additional attributes of the event.
+	Labels map[string]string `json:"labels"`
+	// The path to the value in the received JSON payload which is used as the event data.
+	// e.g. "$.event_data.resources[0].tag"
+	DataPath string `json:"dataPath"`
+}
+
+type PipedPlugin struct {
+	// The unique name of the plugin.
+	Name string `json:"name"`
+	// The path to the executable binary of the plugin.
+	Path string `json:"path"`
+	// List of arguments passed to the plugin binary.
+	Args []string `json:"args"`
+	// Additional environment variables passed to the plugin process.
+	Envs map[string]string `json:"envs"`
+}
+
+func (p PipedPlugin) Validate() error {
+	if p.Name == "" {
+		return errors.New("plugins.name must be set")
+	}
+	if p.Path == "" {
+		return fmt.Errorf("plugins.path must be set for plugin %s", p.Name)
+	}
+	return nil
+}
@@ -15,6 +15,9 @@
 package config
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -43,6 +46,9 @@ func TestPipedConfig(t *testing.T) {
 				APIAddress:   "your-pipecd.domain",
 				WebAddress:   "https://your-pipecd.domain",
 				SyncInterval: Duration(time.Minute),
+				SyncBackoff: PipedSyncBackoff{
+					MaxInterval: Duration(10 * time.Minute),
+				},
 				Git: PipedGit{
 					Username:   "username",
 					Email:      "username@email.com",
@@ -79,6 +85,7 @@ func TestPipedConfig(t *testing.T) {
 						Type: model.CloudProviderKubernetes,
 						KubernetesConfig: &CloudProviderKubernetesConfig{
 							AppStateInformer: KubernetesAppStateInformer{
+								IncludeCustomResources: true,
 								IncludeResources: []KubernetesResourceMatcher{
 									{
 										APIVersion: "pipecd.dev/v1beta1",
@@ -93,6 +100,9 @@ func TestPipedConfig(t *testing.T) {
 										APIVersion: "v1",
 										Kind:       "Endpoints",
 									},
+									{
+										Kind: "EndpointSlice",
+									},
 								},
 							},
 						},
@@ -316,3 +326,523 @@ func TestPipedEventWatcherValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestPipedWebhookValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		webhook PipedWebhook
+		wantErr bool
+	}{
+		{
+			name:    "disabled",
+			webhook: PipedWebhook{},
+		},
+		{
+			name: "missing token file",
+			webhook: PipedWebhook{
+				Port: 9086,
+			},
+			wantErr: true,
+		},
+		{
+			name: "blank token file",
+			webhook: PipedWebhook{
+				Port:      9086,
+				TokenFile: " ",
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicated receiver name",
+			webhook: PipedWebhook{
+				Port:      9086,
+				TokenFile: "/etc/piped-secret/webhook-token",
+				Receivers: []PipedWebhookReceiver{
+					{Name: "ci", SyncApplications: []string{"app-1"}},
+					{Name: "ci", SyncApplications: []string{"app-2"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "receiver does nothing",
+			webhook: PipedWebhook{
+				Port:      9086,
+				TokenFile: "/etc/piped-secret/webhook-token",
+				Receivers: []PipedWebhookReceiver{
+					{Name: "ci"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing data path of event",
+			webhook: PipedWebhook{
+				Port:      9086,
+				TokenFile: "/etc/piped-secret/webhook-token",
+				Receivers: []PipedWebhookReceiver{
+					{Name: "harbor", Event: &PipedWebhookEvent{Name: "image-update"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid",
+			webhook: PipedWebhook{
+				Port:      9086,
+				TokenFile: "/etc/piped-secret/webhook-token",
+				Receivers: []PipedWebhookReceiver{
+					{Name: "harbor", Event: &PipedWebhookEvent{Name: "image-update", DataPath: "$.event_data.resources[0].tag"}},
+					{Name: "ci", SyncApplications: []string{"app-1"}},
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.webhook.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestPipedWebhookLoadToken(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+		return path
+	}
+
+	w := PipedWebhook{TokenFile: write("token", "secret\n")}
+	token, err := w.LoadToken()
+	require.NoError(t, err)
+	assert.Equal(t, "secret", token)
+
+	w = PipedWebhook{TokenFile: write("blank", " \n")}
+	_, err = w.LoadToken()
+	assert.Error(t, err)
+
+	w = PipedWebhook{TokenFile: filepath.Join(dir, "missing")}
+	_, err = w.LoadToken()
+	assert.Error(t, err)
+}
+
+func TestLoadDeploymentHookSecrets(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(file, []byte("secret\n"), 0600))
+
+	s := &PipedSpec{
+		DeploymentHookSecrets: []PipedDeploymentHookSecret{
+			{Name: "CDN_API_TOKEN", File: file},
+		},
+	}
+	secrets, err := s.LoadDeploymentHookSecrets()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"CDN_API_TOKEN": "secret"}, secrets)
+
+	s.DeploymentHookSecrets = append(s.DeploymentHookSecrets, PipedDeploymentHookSecret{Name: "MISSING", File: file + "-missing"})
+	_, err = s.LoadDeploymentHookSecrets()
+	assert.Error(t, err)
+
+	assert.NoError(t, PipedDeploymentHookSecret{Name: "CDN_API_TOKEN", File: file}.Validate())
+	assert.Error(t, PipedDeploymentHookSecret{Name: "CDN-API-TOKEN", File: file}.Validate())
+	assert.Error(t, PipedDeploymentHookSecret{Name: "PIPECD_DEPLOYMENT_ID", File: file}.Validate())
+	assert.Error(t, PipedDeploymentHookSecret{Name: "CDN_API_TOKEN"}.Validate())
+}
+
+func TestAzureCredentialsValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		creds   AzureCredentials
+		wantErr bool
+	}{
+		{
+			name:  "disabled",
+			creds: AzureCredentials{},
+		},
+		{
+			name: "system-assigned managed identity",
+			creds: AzureCredentials{
+				Type: AzureCredentialsManagedIdentity,
+			},
+		},
+		{
+			name: "service principal",
+			creds: AzureCredentials{
+				Type:             AzureCredentialsServicePrincipal,
+				TenantID:         "tenant",
+				ClientID:         "client",
+				ClientSecretFile: "/etc/piped-secret/azure-client-secret",
+			},
+		},
+		{
+			name: "service principal without secret",
+			creds: AzureCredentials{
+				Type:     AzureCredentialsServicePrincipal,
+				TenantID: "tenant",
+				ClientID: "client",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported type",
+			creds: AzureCredentials{
+				Type: "CERTIFICATE",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.creds.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestTerraformCloudConfigValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		cfg     CloudProviderTerraformConfig
+		wantErr bool
+	}{
+		{
+			name: "disabled",
+			cfg:  CloudProviderTerraformConfig{},
+		},
+		{
+			name: "ok",
+			cfg: CloudProviderTerraformConfig{
+				Cloud: &TerraformCloudConfig{
+					Organization: "org",
+					TokenFile:    "/etc/piped-secret/terraform-cloud-token",
+				},
+			},
+		},
+		{
+			name: "missing organization",
+			cfg: CloudProviderTerraformConfig{
+				Cloud: &TerraformCloudConfig{
+					TokenFile: "/etc/piped-secret/terraform-cloud-token",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing token file",
+			cfg: CloudProviderTerraformConfig{
+				Cloud: &TerraformCloudConfig{
+					Organization: "org",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestPipedChangeManagementProviderUnmarshal(t *testing.T) {
+	testcases := []struct {
+		name     string
+		data     string
+		expected PipedChangeManagementProvider
+		wantErr  bool
+	}{
+		{
+			name: "jira",
+			data: `{"name":"jira","type":"JIRA","config":{"address":"https://example.atlassian.net","project":"OPS","usernameFile":"/etc/piped-secret/jira-user","tokenFile":"/etc/piped-secret/jira-token"}}`,
+			expected: PipedChangeManagementProvider{
+				Name: "jira",
+				Type: ChangeManagementProviderJira,
+				JiraConfig: &ChangeManagementJiraConfig{
+					Address:      "https://example.atlassian.net",
+					Project:      "OPS",
+					UsernameFile: "/etc/piped-secret/jira-user",
+					TokenFile:    "/etc/piped-secret/jira-token",
+				},
+			},
+		},
+		{
+			name: "servicenow",
+			data: `{"name":"snow","type":"SERVICENOW","config":{"address":"https://example.service-now.com","usernameFile":"/etc/piped-secret/snow-user","passwordFile":"/etc/piped-secret/snow-password"}}`,
+			expected: PipedChangeManagementProvider{
+				Name: "snow",
+				Type: ChangeManagementProviderServiceNow,
+				ServiceNowConfig: &ChangeManagementServiceNowConfig{
+					Address:      "https://example.service-now.com",
+					UsernameFile: "/etc/piped-secret/snow-user",
+					PasswordFile: "/etc/piped-secret/snow-password",
+				},
+			},
+		},
+		{
+			name:    "unsupported type",
+			data:    `{"name":"unknown","type":"REMEDY"}`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var p PipedChangeManagementProvider
+			err := json.Unmarshal([]byte(tc.data), &p)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err == nil {
+				assert.Equal(t, tc.expected, p)
+				assert.NoError(t, p.Validate())
+			}
+		})
+	}
+}
+
+func TestPipedPagerDutyValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		pagerDuty PipedPagerDuty
+		wantErr   bool
+	}{
+		{
+			name: "valid",
+			pagerDuty: PipedPagerDuty{
+				APITokenFile: "/etc/piped-secret/pagerduty-token",
+				Services: []PipedPagerDutyService{
+					{ID: "PABC123", RoutingKeyFile: "/etc/piped-secret/pagerduty-abc"},
+					{ID: "PDEF456", RoutingKeyFile: "/etc/piped-secret/pagerduty-def"},
+				},
+			},
+		},
+		{
+			name: "missing routing key",
+			pagerDuty: PipedPagerDuty{
+				Services: []PipedPagerDutyService{
+					{ID: "PABC123"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicated service",
+			pagerDuty: PipedPagerDuty{
+				Services: []PipedPagerDutyService{
+					{ID: "PABC123", RoutingKeyFile: "/etc/piped-secret/pagerduty-abc"},
+					{ID: "PABC123", RoutingKeyFile: "/etc/piped-secret/pagerduty-def"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.pagerDuty.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestKubernetesClientRateLimitValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		rateLimit KubernetesClientRateLimit
+		wantErr   bool
+	}{
+		{
+			name: "not limited",
+		},
+		{
+			name: "valid",
+			rateLimit: KubernetesClientRateLimit{
+				QPS:   20,
+				Burst: 40,
+			},
+		},
+		{
+			name: "negative qps",
+			rateLimit: KubernetesClientRateLimit{
+				QPS: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative burst",
+			rateLimit: KubernetesClientRateLimit{
+				QPS:   20,
+				Burst: -1,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rateLimit.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestPipedCommitStatusProviderUnmarshal(t *testing.T) {
+	testcases := []struct {
+		name     string
+		data     string
+		expected PipedCommitStatusProvider
+		wantErr  bool
+	}{
+		{
+			name: "github",
+			data: `{"name":"github","type":"GITHUB","config":{"tokenFile":"/etc/piped-secret/github-token"}}`,
+			expected: PipedCommitStatusProvider{
+				Name: "github",
+				Type: CommitStatusProviderGitHub,
+				GitHubConfig: &CommitStatusGitHubConfig{
+					TokenFile: "/etc/piped-secret/github-token",
+				},
+			},
+		},
+		{
+			name: "gitlab",
+			data: `{"name":"gitlab","type":"GITLAB","config":{"address":"https://gitlab.example.com","tokenFile":"/etc/piped-secret/gitlab-token"}}`,
+			expected: PipedCommitStatusProvider{
+				Name: "gitlab",
+				Type: CommitStatusProviderGitLab,
+				GitLabConfig: &CommitStatusGitLabConfig{
+					Address:   "https://gitlab.example.com",
+					TokenFile: "/etc/piped-secret/gitlab-token",
+				},
+			},
+		},
+		{
+			name:    "unsupported type",
+			data:    `{"name":"unknown","type":"BITBUCKET"}`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var p PipedCommitStatusProvider
+			err := json.Unmarshal([]byte(tc.data), &p)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err == nil {
+				assert.Equal(t, tc.expected, p)
+				assert.NoError(t, p.Validate())
+			}
+		})
+	}
+}
+
+func TestPipedPluginsValidate(t *testing.T) {
+	testcases := []struct {
+		name              string
+		plugins           []PipedPlugin
+		analysisProviders []PipedAnalysisProvider
+		wantErr           bool
+	}{
+		{
+			name: "valid",
+			plugins: []PipedPlugin{
+				{Name: "foo", Path: "/usr/local/bin/foo-plugin"},
+				{Name: "bar", Path: "/usr/local/bin/bar-plugin", Args: []string{"--debug"}},
+			},
+		},
+		{
+			name: "missing path",
+			plugins: []PipedPlugin{
+				{Name: "foo"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicated name",
+			plugins: []PipedPlugin{
+				{Name: "foo", Path: "/usr/local/bin/foo-plugin"},
+				{Name: "foo", Path: "/usr/local/bin/bar-plugin"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "analysis provider using defined plugin",
+			plugins: []PipedPlugin{
+				{Name: "foo", Path: "/usr/local/bin/foo-plugin"},
+			},
+			analysisProviders: []PipedAnalysisProvider{
+				{
+					Name:         "foo-metrics",
+					Type:         model.AnalysisProviderPlugin,
+					PluginConfig: &AnalysisProviderPluginConfig{Plugin: "foo"},
+				},
+			},
+		},
+		{
+			name: "analysis provider using undefined plugin",
+			plugins: []PipedPlugin{
+				{Name: "foo", Path: "/usr/local/bin/foo-plugin"},
+			},
+			analysisProviders: []PipedAnalysisProvider{
+				{
+					Name:         "bar-metrics",
+					Type:         model.AnalysisProviderPlugin,
+					PluginConfig: &AnalysisProviderPluginConfig{Plugin: "bar"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := PipedSpec{
+				ProjectID:         "project",
+				PipedID:           "piped",
+				PipedKeyData:      "key",
+				APIAddress:        "api:443",
+				WebAddress:        "https://web",
+				Plugins:           tc.plugins,
+				AnalysisProviders: tc.analysisProviders,
+			}
+			err := s.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestPipedDiffMaskPatterns(t *testing.T) {
+	testcases := []struct {
+		name     string
+		patterns []string
+		expected int
+		wantErr  bool
+	}{
+		{
+			name: "no pattern",
+		},
+		{
+			name:     "valid patterns",
+			patterns: []string{`^AKIA[0-9A-Z]{16}$`, `password=.+`},
+			expected: 2,
+		},
+		{
+			name:     "invalid pattern",
+			patterns: []string{`(unclosed`},
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := PipedSpec{
+				ProjectID:        "project",
+				PipedID:          "piped",
+				PipedKeyData:     "key",
+				APIAddress:       "api:443",
+				WebAddress:       "https://web",
+				DiffMaskPatterns: tc.patterns,
+			}
+			err := s.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err == nil {
+				assert.Len(t, s.DiffMaskRegexps(), tc.expected)
+			}
+		})
+	}
+}
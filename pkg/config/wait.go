@@ -0,0 +1,184 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// WaitWindow represents a window of time repeated on the given days of week.
+type WaitWindow struct {
+	// The days of week on which the window opens, e.g. [MON, TUE, WED, THU, FRI].
+	// Default is every day.
+	Days []string `json:"days"`
+	// The time of day when the window opens, in HH:MM format.
+	// Required field.
+	Start string `json:"start"`
+	// The time of day when the window closes, in HH:MM format.
+	// It must be after start since the window crossing midnight is not supported.
+	// Required field.
+	End string `json:"end"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"SUN": time.Sunday,
+	"MON": time.Monday,
+	"TUE": time.Tuesday,
+	"WED": time.Wednesday,
+	"THU": time.Thursday,
+	"FRI": time.Friday,
+	"SAT": time.Saturday,
+}
+
+func (w *WaitWindow) Validate() error {
+	for _, d := range w.Days {
+		if _, ok := weekdays[strings.ToUpper(d)]; !ok {
+			return fmt.Errorf("unsupported day %q", d)
+		}
+	}
+	start, err := parseTimeOfDay(w.Start)
+	if err != nil {
+		return fmt.Errorf("invalid start: %w", err)
+	}
+	end, err := parseTimeOfDay(w.End)
+	if err != nil {
+		return fmt.Errorf("invalid end: %w", err)
+	}
+	if end <= start {
+		return fmt.Errorf("end (%s) must be after start (%s)", w.End, w.Start)
+	}
+	return nil
+}
+
+// next returns the given time if it is inside the window,
+// otherwise the time when the window opens next.
+func (w *WaitWindow) next(now time.Time) (time.Time, error) {
+	start, err := parseTimeOfDay(w.Start)
+	if err != nil {
+		return time.Time{}, err
+	}
+	end, err := parseTimeOfDay(w.End)
+	if err != nil {
+		return time.Time{}, err
+	}
+	days := make(map[time.Weekday]struct{}, len(w.Days))
+	for _, d := range w.Days {
+		days[weekdays[strings.ToUpper(d)]] = struct{}{}
+	}
+
+	for i := 0; i <= 7; i++ {
+		day := time.Date(now.Year(), now.Month(), now.Day()+i, 0, 0, 0, 0, now.Location())
+		if _, ok := days[day.Weekday()]; len(days) > 0 && !ok {
+			continue
+		}
+		// The wall clock time is used instead of adding the duration to midnight
+		// since a day is not always 24 hours long due to daylight saving time.
+		opens, closes := timeOfDay(day, start), timeOfDay(day, end)
+		if now.Before(opens) {
+			return opens, nil
+		}
+		if now.Before(closes) {
+			return now, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no window found within a week")
+}
+
+// timeOfDay returns the time at the given time of day on the same date as the given day.
+func timeOfDay(day time.Time, d time.Duration) time.Time {
+	hour, min := int(d/time.Hour), int(d%time.Hour/time.Minute)
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, min, 0, 0, day.Location())
+}
+
+// parseTimeOfDay parses the given HH:MM string into the duration since midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not in HH:MM format", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (o *WaitStageOptions) Validate() error {
+	var specified int
+	if o.Duration != 0 {
+		specified++
+	}
+	if o.Until != "" {
+		specified++
+		if _, err := time.Parse(time.RFC3339, o.Until); err != nil {
+			return fmt.Errorf("until must be in RFC3339 format: %w", err)
+		}
+	}
+	if o.Cron != "" {
+		specified++
+		if _, err := cron.ParseStandard(o.Cron); err != nil {
+			return fmt.Errorf("invalid cron %q: %w", o.Cron, err)
+		}
+	}
+	if o.Window != nil {
+		specified++
+		if err := o.Window.Validate(); err != nil {
+			return fmt.Errorf("invalid window: %w", err)
+		}
+	}
+	if specified > 1 {
+		return fmt.Errorf("only one of duration, until, cron and window can be specified")
+	}
+	if o.Duration < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+	if _, err := time.LoadLocation(o.TimeZone); err != nil {
+		return fmt.Errorf("invalid timeZone %q: %w", o.TimeZone, err)
+	}
+	return nil
+}
+
+// IsScheduled reports whether the stage waits until a scheduled time instead of a duration.
+func (o *WaitStageOptions) IsScheduled() bool {
+	return o.Until != "" || o.Cron != "" || o.Window != nil
+}
+
+// ScheduledTime returns the time the stage should wait until when it started at the given time.
+// The returned time is not after now if the stage can complete at once,
+// e.g. the until time has already passed or the window is open now.
+func (o *WaitStageOptions) ScheduledTime(now time.Time) (time.Time, error) {
+	if o.Until != "" {
+		return time.Parse(time.RFC3339, o.Until)
+	}
+
+	loc, err := time.LoadLocation(o.TimeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	now = now.In(loc)
+
+	switch {
+	case o.Cron != "":
+		schedule, err := cron.ParseStandard(o.Cron)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return schedule.Next(now), nil
+	case o.Window != nil:
+		return o.Window.next(now)
+	default:
+		return time.Time{}, fmt.Errorf("no schedule specified")
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitStageOptionsValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		opts    WaitStageOptions
+		wantErr bool
+	}{
+		{
+			name: "duration only",
+			opts: WaitStageOptions{Duration: Duration(time.Minute)},
+		},
+		{
+			name: "valid until",
+			opts: WaitStageOptions{Until: "2021-04-01T09:00:00+09:00"},
+		},
+		{
+			name:    "invalid until",
+			opts:    WaitStageOptions{Until: "2021-04-01 09:00"},
+			wantErr: true,
+		},
+		{
+			name: "valid cron with time zone",
+			opts: WaitStageOptions{Cron: "0 9 * * MON-FRI", TimeZone: "Asia/Tokyo"},
+		},
+		{
+			name:    "invalid cron",
+			opts:    WaitStageOptions{Cron: "0 9 * *"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid time zone",
+			opts:    WaitStageOptions{Cron: "0 9 * * *", TimeZone: "Mars/Base"},
+			wantErr: true,
+		},
+		{
+			name: "valid window",
+			opts: WaitStageOptions{Window: &WaitWindow{Days: []string{"MON", "fri"}, Start: "09:00", End: "17:30"}},
+		},
+		{
+			name:    "window with unknown day",
+			opts:    WaitStageOptions{Window: &WaitWindow{Days: []string{"MONDAY"}, Start: "09:00", End: "17:00"}},
+			wantErr: true,
+		},
+		{
+			name:    "window ending before start",
+			opts:    WaitStageOptions{Window: &WaitWindow{Start: "22:00", End: "06:00"}},
+			wantErr: true,
+		},
+		{
+			name:    "both duration and cron",
+			opts:    WaitStageOptions{Duration: Duration(time.Minute), Cron: "0 9 * * *"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.Validate()
+			assert.Equal(t, tc.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestWaitStageOptionsScheduledTime(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	// 2021-04-02 is a Friday.
+	now := time.Date(2021, 4, 2, 18, 0, 0, 0, tokyo)
+
+	testcases := []struct {
+		name     string
+		opts     WaitStageOptions
+		expected time.Time
+	}{
+		{
+			name:     "until",
+			opts:     WaitStageOptions{Until: "2021-04-03T09:00:00+09:00"},
+			expected: time.Date(2021, 4, 3, 9, 0, 0, 0, tokyo),
+		},
+		{
+			name:     "cron in UTC",
+			opts:     WaitStageOptions{Cron: "0 10 * * *"},
+			expected: time.Date(2021, 4, 2, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "cron in time zone",
+			opts:     WaitStageOptions{Cron: "0 9 * * MON-FRI", TimeZone: "Asia/Tokyo"},
+			expected: time.Date(2021, 4, 5, 9, 0, 0, 0, tokyo),
+		},
+		{
+			name:     "inside window",
+			opts:     WaitStageOptions{Window: &WaitWindow{Start: "17:00", End: "19:00"}, TimeZone: "Asia/Tokyo"},
+			expected: now,
+		},
+		{
+			name:     "window opening later today",
+			opts:     WaitStageOptions{Window: &WaitWindow{Start: "20:00", End: "21:00"}, TimeZone: "Asia/Tokyo"},
+			expected: time.Date(2021, 4, 2, 20, 0, 0, 0, tokyo),
+		},
+		{
+			name:     "window opening next business day",
+			opts:     WaitStageOptions{Window: &WaitWindow{Days: []string{"MON", "TUE", "WED", "THU", "FRI"}, Start: "09:00", End: "17:00"}, TimeZone: "Asia/Tokyo"},
+			expected: time.Date(2021, 4, 5, 9, 0, 0, 0, tokyo),
+		},
+		{
+			name:     "window opening next week",
+			opts:     WaitStageOptions{Window: &WaitWindow{Days: []string{"FRI"}, Start: "09:00", End: "17:00"}, TimeZone: "Asia/Tokyo"},
+			expected: time.Date(2021, 4, 9, 9, 0, 0, 0, tokyo),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.opts.ScheduledTime(now)
+			require.NoError(t, err)
+			assert.True(t, tc.expected.Equal(got), "expected %s, got %s", tc.expected, got)
+		})
+	}
+}
+
+func TestWaitWindowNextOnDSTTransition(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	w := &WaitWindow{Start: "09:00", End: "17:00"}
+	testcases := []struct {
+		name     string
+		now      time.Time
+		expected time.Time
+	}{
+		{
+			name:     "day starting daylight saving time",
+			now:      time.Date(2021, 3, 14, 1, 0, 0, 0, newYork),
+			expected: time.Date(2021, 3, 14, 9, 0, 0, 0, newYork),
+		},
+		{
+			name:     "day ending daylight saving time",
+			now:      time.Date(2021, 11, 7, 1, 0, 0, 0, newYork),
+			expected: time.Date(2021, 11, 7, 9, 0, 0, 0, newYork),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := w.next(tc.now)
+			require.NoError(t, err)
+			assert.True(t, tc.expected.Equal(got), "expected %s, got %s", tc.expected, got)
+		})
+	}
+}
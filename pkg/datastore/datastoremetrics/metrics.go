@@ -0,0 +1,158 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastoremetrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pipe-cd/pipe/pkg/datastore"
+)
+
+const (
+	kindKey      = "kind"
+	operationKey = "operation"
+	statusKey    = "status"
+)
+
+type OperationLabel string
+
+const (
+	LabelOperationFind   OperationLabel = "find"
+	LabelOperationNext   OperationLabel = "next"
+	LabelOperationGet    OperationLabel = "get"
+	LabelOperationCreate OperationLabel = "create"
+	LabelOperationPut    OperationLabel = "put"
+	LabelOperationUpdate OperationLabel = "update"
+)
+
+type StatusLabel string
+
+const (
+	LabelStatusOK            StatusLabel = "ok"
+	LabelStatusNotFound      StatusLabel = "not_found"
+	LabelStatusAlreadyExists StatusLabel = "already_exists"
+	LabelStatusError         StatusLabel = "error"
+)
+
+var (
+	operationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pipecd_datastore_operation_duration_seconds",
+			Help:    "Latency of datastore operations",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{
+			kindKey,
+			operationKey,
+			statusKey,
+		},
+	)
+)
+
+func Register(r prometheus.Registerer) {
+	r.MustRegister(operationHistogram)
+}
+
+func ObserveOperation(kind string, operation OperationLabel, status StatusLabel, d time.Duration) {
+	operationHistogram.With(prometheus.Labels{
+		kindKey:      kind,
+		operationKey: string(operation),
+		statusKey:    string(status),
+	}).Observe(d.Seconds())
+}
+
+func statusOf(err error) StatusLabel {
+	switch {
+	case err == nil, errors.Is(err, datastore.ErrIteratorDone):
+		return LabelStatusOK
+	case errors.Is(err, datastore.ErrNotFound):
+		return LabelStatusNotFound
+	case errors.Is(err, datastore.ErrAlreadyExists):
+		return LabelStatusAlreadyExists
+	default:
+		return LabelStatusError
+	}
+}
+
+// NewInstrumentedDataStore returns a datastore that observes the latency
+// of every operation of the given datastore.
+func NewInstrumentedDataStore(ds datastore.DataStore) datastore.DataStore {
+	return &instrumentedDataStore{
+		DataStore: ds,
+	}
+}
+
+type instrumentedDataStore struct {
+	datastore.DataStore
+}
+
+func (s *instrumentedDataStore) Find(ctx context.Context, kind string, opts datastore.ListOptions) (datastore.Iterator, error) {
+	start := time.Now()
+	it, err := s.DataStore.Find(ctx, kind, opts)
+	ObserveOperation(kind, LabelOperationFind, statusOf(err), time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedIterator{
+		Iterator: it,
+		kind:     kind,
+	}, nil
+}
+
+func (s *instrumentedDataStore) Get(ctx context.Context, kind, id string, entity interface{}) error {
+	start := time.Now()
+	err := s.DataStore.Get(ctx, kind, id, entity)
+	ObserveOperation(kind, LabelOperationGet, statusOf(err), time.Since(start))
+	return err
+}
+
+func (s *instrumentedDataStore) Create(ctx context.Context, kind, id string, entity interface{}) error {
+	start := time.Now()
+	err := s.DataStore.Create(ctx, kind, id, entity)
+	ObserveOperation(kind, LabelOperationCreate, statusOf(err), time.Since(start))
+	return err
+}
+
+func (s *instrumentedDataStore) Put(ctx context.Context, kind, id string, entity interface{}) error {
+	start := time.Now()
+	err := s.DataStore.Put(ctx, kind, id, entity)
+	ObserveOperation(kind, LabelOperationPut, statusOf(err), time.Since(start))
+	return err
+}
+
+func (s *instrumentedDataStore) Update(ctx context.Context, kind, id string, factory datastore.Factory, updater datastore.Updater) error {
+	start := time.Now()
+	err := s.DataStore.Update(ctx, kind, id, factory, updater)
+	ObserveOperation(kind, LabelOperationUpdate, statusOf(err), time.Since(start))
+	return err
+}
+
+// instrumentedIterator observes the latency of fetching each document
+// since some datastores query lazily while iterating.
+type instrumentedIterator struct {
+	datastore.Iterator
+	kind string
+}
+
+func (it *instrumentedIterator) Next(dst interface{}) error {
+	start := time.Now()
+	err := it.Iterator.Next(dst)
+	ObserveOperation(it.kind, LabelOperationNext, statusOf(err), time.Since(start))
+	return err
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const DeploymentSavedViewModelKind = "DeploymentSavedView"
+
+var deploymentSavedViewFactory = func() interface{} {
+	return &model.DeploymentSavedView{}
+}
+
+type DeploymentSavedViewStore interface {
+	AddDeploymentSavedView(ctx context.Context, v *model.DeploymentSavedView) error
+	DeleteDeploymentSavedView(ctx context.Context, id, projectID, owner string) error
+	ListDeploymentSavedViews(ctx context.Context, opts ListOptions) ([]*model.DeploymentSavedView, error)
+}
+
+type deploymentSavedViewStore struct {
+	backend
+	nowFunc func() time.Time
+}
+
+func NewDeploymentSavedViewStore(ds DataStore) DeploymentSavedViewStore {
+	return &deploymentSavedViewStore{
+		backend: backend{
+			ds: ds,
+		},
+		nowFunc: time.Now,
+	}
+}
+
+func (s *deploymentSavedViewStore) AddDeploymentSavedView(ctx context.Context, v *model.DeploymentSavedView) error {
+	now := s.nowFunc().Unix()
+	if v.CreatedAt == 0 {
+		v.CreatedAt = now
+	}
+	if v.UpdatedAt == 0 {
+		v.UpdatedAt = now
+	}
+	if err := v.Validate(); err != nil {
+		return err
+	}
+	return s.ds.Create(ctx, DeploymentSavedViewModelKind, v.Id, v)
+}
+
+func (s *deploymentSavedViewStore) DeleteDeploymentSavedView(ctx context.Context, id, projectID, owner string) error {
+	now := s.nowFunc().Unix()
+	return s.ds.Update(ctx, DeploymentSavedViewModelKind, id, deploymentSavedViewFactory, func(e interface{}) error {
+		v := e.(*model.DeploymentSavedView)
+		if v.ProjectId != projectID {
+			return fmt.Errorf("invalid project id, expected %s, got %s", v.ProjectId, projectID)
+		}
+		if v.Owner != owner {
+			return fmt.Errorf("invalid owner, expected %s, got %s", v.Owner, owner)
+		}
+		v.Deleted = true
+		v.UpdatedAt = now
+		return nil
+	})
+}
+
+func (s *deploymentSavedViewStore) ListDeploymentSavedViews(ctx context.Context, opts ListOptions) ([]*model.DeploymentSavedView, error) {
+	it, err := s.ds.Find(ctx, DeploymentSavedViewModelKind, opts)
+	if err != nil {
+		return nil, err
+	}
+	vs := make([]*model.DeploymentSavedView, 0)
+	for {
+		var v model.DeploymentSavedView
+		err := it.Next(&v)
+		if err == ErrIteratorDone {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, &v)
+	}
+	return vs, nil
+}
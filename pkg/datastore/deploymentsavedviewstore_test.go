@@ -0,0 +1,129 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestAddDeploymentSavedView(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testcases := []struct {
+		name      string
+		view      *model.DeploymentSavedView
+		dsFactory func(*model.DeploymentSavedView) DataStore
+		wantErr   bool
+	}{
+		{
+			name:      "Invalid view",
+			view:      &model.DeploymentSavedView{},
+			dsFactory: func(v *model.DeploymentSavedView) DataStore { return nil },
+			wantErr:   true,
+		},
+		{
+			name: "Valid view",
+			view: &model.DeploymentSavedView{
+				Id:        "id",
+				Name:      "failed deployments",
+				ProjectId: "project-id",
+				Owner:     "user",
+				Filter: &model.DeploymentFilter{
+					Statuses: []model.DeploymentStatus{model.DeploymentStatus_DEPLOYMENT_FAILURE},
+					Query:    "frontend",
+				},
+				CreatedAt: 1,
+				UpdatedAt: 1,
+			},
+			dsFactory: func(v *model.DeploymentSavedView) DataStore {
+				ds := NewMockDataStore(ctrl)
+				ds.EXPECT().Create(gomock.Any(), "DeploymentSavedView", v.Id, v)
+				return ds
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewDeploymentSavedViewStore(tc.dsFactory(tc.view))
+			err := s.AddDeploymentSavedView(context.Background(), tc.view)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestListDeploymentSavedViews(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testcases := []struct {
+		name    string
+		opts    ListOptions
+		ds      DataStore
+		wantErr error
+	}{
+		{
+			name: "iterator done",
+			opts: ListOptions{},
+			ds: func() DataStore {
+				it := NewMockIterator(ctrl)
+				it.EXPECT().
+					Next(&model.DeploymentSavedView{}).
+					Return(ErrIteratorDone)
+
+				ds := NewMockDataStore(ctrl)
+				ds.EXPECT().
+					Find(gomock.Any(), "DeploymentSavedView", ListOptions{}).
+					Return(it, nil)
+				return ds
+			}(),
+			wantErr: nil,
+		},
+		{
+			name: "unexpected error occurred",
+			opts: ListOptions{},
+			ds: func() DataStore {
+				it := NewMockIterator(ctrl)
+				it.EXPECT().
+					Next(&model.DeploymentSavedView{}).
+					Return(errors.New("test-error"))
+
+				ds := NewMockDataStore(ctrl)
+				ds.EXPECT().
+					Find(gomock.Any(), "DeploymentSavedView", ListOptions{}).
+					Return(it, nil)
+				return ds
+			}(),
+			wantErr: errors.New("test-error"),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewDeploymentSavedViewStore(tc.ds)
+			_, err := s.ListDeploymentSavedViews(context.Background(), tc.opts)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -29,13 +29,14 @@ var deploymentFactory = func() interface{} {
 }
 
 var (
-	DeploymentToPlannedUpdater = func(summary, statusReason, runningCommitHash, version string, stages []*model.PipelineStage) func(*model.Deployment) error {
+	DeploymentToPlannedUpdater = func(summary, statusReason, runningCommitHash, version string, versions []*model.ArtifactVersion, stages []*model.PipelineStage) func(*model.Deployment) error {
 		return func(d *model.Deployment) error {
 			d.Status = model.DeploymentStatus_DEPLOYMENT_PLANNED
 			d.Summary = summary
 			d.StatusReason = statusReason
 			d.RunningCommitHash = runningCommitHash
 			d.Version = version
+			d.Versions = versions
 			d.Stages = stages
 			return nil
 		}
@@ -68,10 +69,23 @@ var (
 		}
 	}
 
+	DeploymentCheckpointUpdater = func(checkpoint *model.DeploymentCheckpoint) func(*model.Deployment) error {
+		return func(d *model.Deployment) error {
+			if model.IsCompletedDeployment(d.Status) {
+				return fmt.Errorf("deployment %s is already completed: %w", d.Id, ErrInvalidArgument)
+			}
+			d.Checkpoint = checkpoint
+			return nil
+		}
+	}
+
 	StageStatusChangedUpdater = func(stageID string, status model.StageStatus, statusReason string, requires []string, visible bool, retriedCount int32, completedAt int64) func(*model.Deployment) error {
 		return func(d *model.Deployment) error {
 			for _, s := range d.Stages {
 				if s.Id == stageID {
+					if status == model.StageStatus_STAGE_RUNNING && s.Status != model.StageStatus_STAGE_RUNNING {
+						s.StartedAt = completedAt
+					}
 					s.Status = status
 					s.StatusReason = statusReason
 					if len(requires) > 0 {
@@ -93,6 +107,7 @@ type DeploymentStore interface {
 	UpdateDeployment(ctx context.Context, id string, updater func(*model.Deployment) error) error
 	PutDeploymentMetadata(ctx context.Context, id string, metadata map[string]string) error
 	PutDeploymentStageMetadata(ctx context.Context, deploymentID, stageID string, metadata map[string]string) error
+	PutDeploymentStageResults(ctx context.Context, deploymentID, stageID string, results map[string]string) error
 	ListDeployments(ctx context.Context, opts ListOptions) ([]*model.Deployment, string, error)
 	GetDeployment(ctx context.Context, id string) (*model.Deployment, error)
 }
@@ -162,6 +177,21 @@ func (s *deploymentStore) PutDeploymentStageMetadata(ctx context.Context, deploy
 	})
 }
 
+func (s *deploymentStore) PutDeploymentStageResults(ctx context.Context, deploymentID, stageID string, results map[string]string) error {
+	now := s.nowFunc().Unix()
+	return s.ds.Update(ctx, DeploymentModelKind, deploymentID, deploymentFactory, func(e interface{}) error {
+		d := e.(*model.Deployment)
+		for _, stage := range d.Stages {
+			if stage.Id == stageID {
+				stage.Results = results
+				d.UpdatedAt = now
+				return nil
+			}
+		}
+		return fmt.Errorf("stage %s is not found: %w", stageID, ErrInvalidArgument)
+	})
+}
+
 func (s *deploymentStore) ListDeployments(ctx context.Context, opts ListOptions) ([]*model.Deployment, string, error) {
 	it, err := s.ds.Find(ctx, DeploymentModelKind, opts)
 	if err != nil {
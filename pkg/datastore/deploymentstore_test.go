@@ -34,7 +34,14 @@ func TestDeploymentToPlannedUpdater(t *testing.T) {
 		expectedStatusDesc        = "update-status-desc"
 		expectedRunningCommitHash = "update-running-commit-hash"
 		expectedVersion           = "update-version"
-		expectedStages            = []*model.PipelineStage{
+		expectedVersions          = []*model.ArtifactVersion{
+			{
+				Name:           "gcr.io/pipecd/helloworld",
+				Version:        "v0.2.0",
+				RunningVersion: "v0.1.0",
+			},
+		}
+		expectedStages = []*model.PipelineStage{
 			{
 				Id:    "stage-id1",
 				Name:  "stage1",
@@ -61,6 +68,7 @@ func TestDeploymentToPlannedUpdater(t *testing.T) {
 			expectedStatusDesc,
 			expectedRunningCommitHash,
 			expectedVersion,
+			expectedVersions,
 			expectedStages,
 		)
 	)
@@ -72,6 +80,7 @@ func TestDeploymentToPlannedUpdater(t *testing.T) {
 	assert.Equal(t, expectedStatusDesc, d.StatusReason)
 	assert.Equal(t, expectedRunningCommitHash, d.RunningCommitHash)
 	assert.Equal(t, expectedVersion, d.Version)
+	assert.Equal(t, expectedVersions, d.Versions)
 	assert.Equal(t, expectedStages, d.Stages)
 }
 
@@ -93,6 +102,32 @@ func TestDeploymentStatusUpdater(t *testing.T) {
 	assert.Equal(t, expectedStatusDesc, d.StatusReason)
 }
 
+func TestDeploymentCheckpointUpdater(t *testing.T) {
+	checkpoint := &model.DeploymentCheckpoint{
+		StartedAt:      100,
+		RunningStageId: "stage-2",
+		ResumedCount:   1,
+		UpdatedAt:      200,
+	}
+
+	d := model.Deployment{
+		Id:     "deployment-id",
+		Status: model.DeploymentStatus_DEPLOYMENT_RUNNING,
+	}
+	err := DeploymentCheckpointUpdater(checkpoint)(&d)
+	require.NoError(t, err)
+	assert.Equal(t, checkpoint, d.Checkpoint)
+
+	// The checkpoint of the completed deployment is not updated anymore.
+	completed := model.Deployment{
+		Id:     "deployment-id",
+		Status: model.DeploymentStatus_DEPLOYMENT_SUCCESS,
+	}
+	err = DeploymentCheckpointUpdater(checkpoint)(&completed)
+	require.True(t, errors.Is(err, ErrInvalidArgument))
+	assert.Nil(t, completed.Checkpoint)
+}
+
 func TestDeploymentToCompletedUpdater(t *testing.T) {
 	now := time.Now()
 	testcases := []struct {
@@ -276,6 +311,38 @@ func TestStageStatusChangedUpdater(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name: "record the started time when the stage starts running",
+			deployment: model.Deployment{
+				Id:     "deployment-id",
+				Status: model.DeploymentStatus_DEPLOYMENT_RUNNING,
+				Stages: []*model.PipelineStage{
+					{
+						Id:     "stage-id1",
+						Status: model.StageStatus_STAGE_NOT_STARTED_YET,
+					},
+				},
+			},
+			stageID:     "stage-id1",
+			status:      model.StageStatus_STAGE_RUNNING,
+			visible:     true,
+			completedAt: now.Unix(),
+
+			expectedDeployment: model.Deployment{
+				Id:     "deployment-id",
+				Status: model.DeploymentStatus_DEPLOYMENT_RUNNING,
+				Stages: []*model.PipelineStage{
+					{
+						Id:          "stage-id1",
+						Status:      model.StageStatus_STAGE_RUNNING,
+						Visible:     true,
+						CompletedAt: now.Unix(),
+						StartedAt:   now.Unix(),
+					},
+				},
+			},
+			expectedErr: nil,
+		},
 	}
 
 	for _, tc := range testcases {
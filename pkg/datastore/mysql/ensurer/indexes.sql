@@ -83,6 +83,10 @@ CREATE INDEX event_project_id_created_at_asc ON Event (ProjectId, CreatedAt);
 ALTER TABLE Event ADD COLUMN EventKey VARCHAR(64) GENERATED ALWAYS AS (data->>"$.event_key") VIRTUAL NOT NULL, ADD COLUMN Name VARCHAR(50) GENERATED ALWAYS AS (data->>"$.name") VIRTUAL NOT NULL;
 CREATE INDEX event_key_name_project_id_created_at_desc ON Event (EventKey, Name, ProjectId, CreatedAt DESC);
 
+-- index on `ProjectId` ASC and `IdempotencyKey` ASC
+ALTER TABLE Event ADD COLUMN IdempotencyKey VARCHAR(128) GENERATED ALWAYS AS (IFNULL(data->>"$.idempotency_key", "")) VIRTUAL NOT NULL;
+CREATE INDEX event_project_id_idempotency_key ON Event (ProjectId, IdempotencyKey);
+
 --
 -- Piped table indexes
 --
@@ -90,3 +94,10 @@ CREATE INDEX event_key_name_project_id_created_at_desc ON Event (EventKey, Name,
 -- index on `ProjectId` ASC and `EnvIds` ASC
 ALTER TABLE Piped ADD COLUMN EnvIds JSON GENERATED ALWAYS AS (IFNULL(data ->> "$.env_ids", '[]')) VIRTUAL NOT NULL;
 CREATE INDEX piped_project_id_env_ids_asc ON Piped (ProjectId, (CAST(EnvIds AS CHAR(36) ARRAY)));
+
+--
+-- DeploymentSavedView table indexes
+--
+
+-- index on `ProjectId` ASC and `Owner` ASC
+CREATE INDEX deployment_saved_view_project_id_owner_asc ON DeploymentSavedView (ProjectId, Owner);
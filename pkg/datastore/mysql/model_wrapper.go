@@ -92,6 +92,14 @@ func wrapModel(entity interface{}) (interface{}, error) {
 			Event: *e,
 			Extra: e.Name,
 		}, nil
+	case *model.DeploymentSavedView:
+		if e == nil {
+			return nil, fmt.Errorf("nil entity given")
+		}
+		return &deploymentSavedView{
+			DeploymentSavedView: *e,
+			Extra:               e.Name,
+		}, nil
 	default:
 		return nil, fmt.Errorf("%T is not supported", e)
 	}
@@ -152,3 +160,8 @@ type event struct {
 	model.Event `json:",inline"`
 	Extra       string `json:"_extra"`
 }
+
+type deploymentSavedView struct {
+	model.DeploymentSavedView `json:",inline"`
+	Extra                     string `json:"_extra"`
+}
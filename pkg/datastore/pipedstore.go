@@ -50,6 +50,19 @@ var (
 			return nil
 		}
 	}
+	PipedDiagnosticsUpdater = func(diagnostics *model.PipedDiagnostics) func(piped *model.Piped) error {
+		return func(piped *model.Piped) error {
+			piped.Diagnostics = diagnostics
+			return nil
+		}
+	}
+
+	PipedHealthUpdater = func(health *model.PipedHealth) func(piped *model.Piped) error {
+		return func(piped *model.Piped) error {
+			piped.Health = health
+			return nil
+		}
+	}
 )
 
 type PipedStore interface {
@@ -26,8 +26,12 @@ type differ struct {
 	ignoreAddingMapKeys           bool
 	equateEmpty                   bool
 	compareNumberAndNumericString bool
+	compareBoolAndBoolString      bool
+	ignoreListOrder               bool
+	equateDefaults                bool
 
-	result *Result
+	defaults map[string]interface{}
+	result   *Result
 }
 
 type Option func(*differ)
@@ -56,6 +60,33 @@ func WithCompareNumberAndNumericString() Option {
 	}
 }
 
+// WithCompareBoolAndBoolString configures differ to compare a boolean with a boolean string.
+// e.g. true == "true"
+func WithCompareBoolAndBoolString() Option {
+	return func(d *differ) {
+		d.compareBoolAndBoolString = true
+	}
+}
+
+// WithIgnoreListOrder configures differ to compare the items of two lists
+// by their identifying keys instead of their indexes, so reordering the items is not a change.
+// This is applied only to the lists known to be order-insensitive such as containers and volumes,
+// and only when all items of both lists have a unique value for the identifying key.
+func WithIgnoreListOrder() Option {
+	return func(d *differ) {
+		d.ignoreListOrder = true
+	}
+}
+
+// WithEquateDefaults configures differ to consider a missing field
+// and the field set to its default value by Kubernetes to be equal.
+// e.g. a Deployment without replicas == a Deployment with "replicas: 1"
+func WithEquateDefaults() Option {
+	return func(d *differ) {
+		d.equateDefaults = true
+	}
+}
+
 // DiffUnstructureds calculates the diff between two unstructured objects.
 func DiffUnstructureds(x, y unstructured.Unstructured, opts ...Option) (*Result, error) {
 	var (
@@ -67,6 +98,13 @@ func DiffUnstructureds(x, y unstructured.Unstructured, opts ...Option) (*Result,
 	for _, opt := range opts {
 		opt(d)
 	}
+	if d.equateDefaults {
+		kind := x.GetKind()
+		if kind == "" {
+			kind = y.GetKind()
+		}
+		d.defaults = defaultValues[kind]
+	}
 
 	if err := d.diff(path, vx, vy); err != nil {
 		return nil, err
@@ -81,6 +119,9 @@ func (d *differ) diff(path []PathStep, vx, vy reflect.Value) error {
 		if d.equateEmpty && isEmptyInterface(vy) {
 			return nil
 		}
+		if d.equateDefaults && isDefaultValue(d.defaults, path, vy) {
+			return nil
+		}
 
 		d.result.addNode(path, nil, vy.Type(), vx, vy)
 		return nil
@@ -90,6 +131,9 @@ func (d *differ) diff(path []PathStep, vx, vy reflect.Value) error {
 		if d.equateEmpty && isEmptyInterface(vx) {
 			return nil
 		}
+		if d.equateDefaults && isDefaultValue(d.defaults, path, vx) {
+			return nil
+		}
 
 		d.result.addNode(path, vx.Type(), nil, vx, vy)
 		return nil
@@ -113,6 +157,20 @@ func (d *differ) diff(path []PathStep, vx, vy reflect.Value) error {
 		}
 	}
 
+	if d.compareBoolAndBoolString {
+		if vx.Kind() == reflect.Bool {
+			if y, ok := convertToBool(vy); ok {
+				return d.diffBool(path, vx, y)
+			}
+		}
+
+		if vy.Kind() == reflect.Bool {
+			if x, ok := convertToBool(vx); ok {
+				return d.diffBool(path, x, vy)
+			}
+		}
+	}
+
 	if vx.Type() != vy.Type() {
 		d.result.addNode(path, vx.Type(), vy.Type(), vx, vy)
 		return nil
@@ -145,6 +203,16 @@ func (d *differ) diffSlice(path []PathStep, vx, vy reflect.Value) error {
 		return nil
 	}
 
+	if d.ignoreListOrder {
+		if key, ok := orderInsensitiveLists[makePathPattern(path)]; ok {
+			idsX, okX := listItemIDs(vx, key)
+			idsY, okY := listItemIDs(vy, key)
+			if okX && okY {
+				return d.diffSliceByID(path, vx, vy, idsX, idsY)
+			}
+		}
+	}
+
 	minLen := vx.Len()
 	if minLen > vy.Len() {
 		minLen = vy.Len()
@@ -174,6 +242,79 @@ func (d *differ) diffSlice(path []PathStep, vx, vy reflect.Value) error {
 	return nil
 }
 
+// diffSliceByID compares the items having the same ID with each other.
+// The path of a compared item contains its index in the first list.
+func (d *differ) diffSliceByID(path []PathStep, vx, vy reflect.Value, idsX, idsY []string) error {
+	indexesX := make(map[string]int, len(idsX))
+	for i, id := range idsX {
+		indexesX[id] = i
+	}
+	indexesY := make(map[string]int, len(idsY))
+	for i, id := range idsY {
+		indexesY[id] = i
+	}
+
+	for i, id := range idsX {
+		nextPath := newSlicePath(path, i)
+		nextValueX := vx.Index(i)
+		j, ok := indexesY[id]
+		if !ok {
+			d.result.addNode(nextPath, nextValueX.Type(), nextValueX.Type(), nextValueX, reflect.Value{})
+			continue
+		}
+		if err := d.diff(nextPath, nextValueX, vy.Index(j)); err != nil {
+			return err
+		}
+	}
+
+	for j, id := range idsY {
+		if _, ok := indexesX[id]; ok {
+			continue
+		}
+		nextPath := newSlicePath(path, j)
+		nextValueY := vy.Index(j)
+		d.result.addNode(nextPath, nextValueY.Type(), nextValueY.Type(), reflect.Value{}, nextValueY)
+	}
+
+	return nil
+}
+
+// listItemIDs returns the values of the given key of all list items.
+// False is returned when any item is not a map having a unique primitive value for the key.
+func listItemIDs(v reflect.Value, key string) ([]string, bool) {
+	ids := make([]string, 0, v.Len())
+	checks := make(map[string]struct{}, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		if item.Kind() == reflect.Interface {
+			item = item.Elem()
+		}
+		if item.Kind() != reflect.Map || item.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+
+		value := item.MapIndex(reflect.ValueOf(key).Convert(item.Type().Key()))
+		if value.Kind() == reflect.Interface {
+			value = value.Elem()
+		}
+		switch value.Kind() {
+		case reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return nil, false
+		}
+
+		id := RenderPrimitiveValue(value)
+		if _, ok := checks[id]; ok {
+			return nil, false
+		}
+		checks[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
 func (d *differ) diffMap(path []PathStep, vx, vy reflect.Value) error {
 	if vx.IsNil() || vy.IsNil() {
 		d.result.addNode(path, vx.Type(), vy.Type(), vx, vy)
@@ -303,6 +444,23 @@ func convertToNumber(v reflect.Value) (reflect.Value, bool) {
 	}
 }
 
+func convertToBool(v reflect.Value) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.Bool:
+		return v, true
+	case reflect.String:
+		switch v.String() {
+		case "true":
+			return reflect.ValueOf(true), true
+		case "false":
+			return reflect.ValueOf(false), true
+		}
+		return v, false
+	default:
+		return v, false
+	}
+}
+
 func newSlicePath(path []PathStep, index int) []PathStep {
 	next := make([]PathStep, len(path))
 	copy(next, path)
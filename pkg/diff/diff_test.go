@@ -52,6 +52,67 @@ func TestDiff(t *testing.T) {
 			},
 			diffNum: 0,
 		},
+		{
+			name:     "ignore list order",
+			yamlFile: "testdata/ignore_list_order.yaml",
+			options: []Option{
+				WithIgnoreListOrder(),
+			},
+			diffNum: 6,
+			diffString: `  spec:
+    template:
+      spec:
+        containers:
+          - env:
+              -
+                #spec.template.spec.containers.0.env.0.value
+-               value: foo
++               value: foo-2
+
+              #spec.template.spec.containers.0.env.2
++             - name: BAZ
++               value: baz
+
+        initContainers:
+          -
+            #spec.template.spec.initContainers.0.image
+-           image: gcr.io/pipecd/migrate:v1.0.0
++           image: gcr.io/pipecd/seed:v1.0.0
+
+            #spec.template.spec.initContainers.0.name
+-           name: migrate
++           name: seed
+
+          -
+            #spec.template.spec.initContainers.1.image
+-           image: gcr.io/pipecd/seed:v1.0.0
++           image: gcr.io/pipecd/migrate:v1.0.0
+
+            #spec.template.spec.initContainers.1.name
+-           name: seed
++           name: migrate
+
+`,
+		},
+		{
+			name:     "equate defaults",
+			yamlFile: "testdata/equate_defaults.yaml",
+			options: []Option{
+				WithEquateDefaults(),
+				WithCompareBoolAndBoolString(),
+			},
+			diffNum: 1,
+			diffString: `  spec:
+    template:
+      spec:
+        containers:
+          - ports:
+              -
+                #spec.template.spec.containers.0.ports.1.protocol
++               protocol: UDP
+
+`,
+		},
 		{
 			name:     "has diff",
 			yamlFile: "testdata/has_diff.yaml",
@@ -0,0 +1,144 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"reflect"
+	"strings"
+)
+
+// The paths of pod specs inside the workload resources.
+var podSpecPaths = []string{
+	"spec",
+	"spec.template.spec",
+	"spec.jobTemplate.spec.template.spec",
+}
+
+// orderInsensitiveLists contains the lists whose item order has no meaning
+// along with the key identifying their items. The path uses "*" for any list index.
+// The lists whose order matters are intentionally not included, such as
+// env where a variable can reference the ones defined before it,
+// initContainers which are run one by one and the http routes of Istio VirtualService
+// which are matched in order.
+var orderInsensitiveLists = func() map[string]string {
+	lists := make(map[string]string)
+	for _, p := range podSpecPaths {
+		lists[p+".containers"] = "name"
+		lists[p+".containers.*.ports"] = "containerPort"
+		lists[p+".containers.*.volumeMounts"] = "mountPath"
+		lists[p+".volumes"] = "name"
+		lists[p+".imagePullSecrets"] = "name"
+	}
+	return lists
+}()
+
+// defaultValues contains the values set by Kubernetes to the fields missing
+// in the applied manifests, grouped by resource kind. The path uses "*" for any list index.
+var defaultValues = func() map[string]map[string]interface{} {
+	podTemplate := func(values map[string]interface{}) map[string]interface{} {
+		p := "spec.template.spec"
+		values[p+".restartPolicy"] = "Always"
+		values[p+".dnsPolicy"] = "ClusterFirst"
+		values[p+".schedulerName"] = "default-scheduler"
+		values[p+".terminationGracePeriodSeconds"] = 30
+		values[p+".containers.*.terminationMessagePath"] = "/dev/termination-log"
+		values[p+".containers.*.terminationMessagePolicy"] = "File"
+		values[p+".containers.*.ports.*.protocol"] = "TCP"
+		return values
+	}
+	return map[string]map[string]interface{}{
+		"Deployment": podTemplate(map[string]interface{}{
+			"spec.replicas":                              1,
+			"spec.revisionHistoryLimit":                  10,
+			"spec.progressDeadlineSeconds":               600,
+			"spec.strategy.type":                         "RollingUpdate",
+			"spec.strategy.rollingUpdate.maxSurge":       "25%",
+			"spec.strategy.rollingUpdate.maxUnavailable": "25%",
+		}),
+		"StatefulSet": podTemplate(map[string]interface{}{
+			"spec.replicas":             1,
+			"spec.revisionHistoryLimit": 10,
+			"spec.podManagementPolicy":  "OrderedReady",
+			"spec.updateStrategy.type":  "RollingUpdate",
+		}),
+		"DaemonSet": podTemplate(map[string]interface{}{
+			"spec.revisionHistoryLimit": 10,
+			"spec.updateStrategy.type":  "RollingUpdate",
+		}),
+		"Service": {
+			"spec.type":             "ClusterIP",
+			"spec.sessionAffinity":  "None",
+			"spec.ports.*.protocol": "TCP",
+		},
+	}
+}()
+
+// isDefaultValue reports whether the given value is the default value of the field at the path.
+// A map is a default value when all of its fields are default values.
+func isDefaultValue(defaults map[string]interface{}, path []PathStep, v reflect.Value) bool {
+	if len(defaults) == 0 || !v.IsValid() {
+		return false
+	}
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Map {
+		if v.Len() == 0 {
+			return false
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if iter.Key().Kind() != reflect.String {
+				return false
+			}
+			if !isDefaultValue(defaults, newMapPath(path, iter.Key().String()), iter.Value()) {
+				return false
+			}
+		}
+		return true
+	}
+
+	dv, ok := defaults[makePathPattern(path)]
+	if !ok {
+		return false
+	}
+	switch d := dv.(type) {
+	case string:
+		return v.Kind() == reflect.String && v.String() == d
+	case int:
+		return isNumberValue(v) && floatNumber(v) == float64(d)
+	case bool:
+		return v.Kind() == reflect.Bool && v.Bool() == d
+	default:
+		return false
+	}
+}
+
+// makePathPattern returns the path string where all list indexes are replaced with "*".
+func makePathPattern(path []PathStep) string {
+	steps := make([]string, 0, len(path))
+	for _, s := range path {
+		if s.Type == SliceIndexPathStep {
+			steps = append(steps, "*")
+			continue
+		}
+		steps = append(steps, s.String())
+	}
+	return strings.Join(steps, ".")
+}
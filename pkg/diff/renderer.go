@@ -17,14 +17,16 @@ package diff
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 )
 
 type Renderer struct {
-	leftPadding    int
-	maskPathPrefix string
+	leftPadding       int
+	maskPathPrefixes  []string
+	maskValuePatterns []*regexp.Regexp
 }
 
 type RenderOption func(*Renderer)
@@ -37,9 +39,20 @@ func WithLeftPadding(p int) RenderOption {
 	}
 }
 
+// WithMaskPath configures Renderer to mask the values of all nodes
+// whose path starts with the given prefix.
+// This can be specified multiple times to mask multiple paths.
 func WithMaskPath(prefix string) RenderOption {
 	return func(r *Renderer) {
-		r.maskPathPrefix = prefix
+		r.maskPathPrefixes = append(r.maskPathPrefixes, prefix)
+	}
+}
+
+// WithMaskValuePatterns configures Renderer to mask the values of all nodes
+// where either the old or the new value matches one of the given patterns.
+func WithMaskValuePatterns(patterns ...*regexp.Regexp) RenderOption {
+	return func(r *Renderer) {
+		r.maskValuePatterns = append(r.maskValuePatterns, patterns...)
 	}
 }
 
@@ -121,9 +134,9 @@ func (r *Renderer) Render(ns Nodes) string {
 
 		lastStep := n.Path[pathLen-1]
 		valueX, valueY := n.ValueX, n.ValueY
-		if r.maskPathPrefix != "" && strings.HasPrefix(n.PathString, r.maskPathPrefix) {
-			valueX = reflect.ValueOf(maskString)
-			valueY = reflect.ValueOf(maskString)
+		if r.shouldMask(n) {
+			valueX = maskValue(valueX)
+			valueY = maskValue(valueY)
 		}
 
 		b.WriteString(fmt.Sprintf("%*s#%s\n", (r.leftPadding+pathLen-1)*2, "", n.PathString))
@@ -135,6 +148,39 @@ func (r *Renderer) Render(ns Nodes) string {
 	return b.String()
 }
 
+func (r *Renderer) shouldMask(n Node) bool {
+	for _, prefix := range r.maskPathPrefixes {
+		if strings.HasPrefix(n.PathString, prefix) {
+			return true
+		}
+	}
+	if len(r.maskValuePatterns) == 0 {
+		return false
+	}
+	for _, v := range []reflect.Value{n.ValueX, n.ValueY} {
+		if !v.IsValid() {
+			continue
+		}
+		s, _ := renderNodeValue(v, "")
+		for _, p := range r.maskValuePatterns {
+			if p.MatchString(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maskValue replaces the given value with the constant mask string.
+// Nothing derived from the value such as its hash is shown since
+// low-entropy secrets could be recovered from it by brute force.
+func maskValue(v reflect.Value) reflect.Value {
+	if !v.IsValid() {
+		return v
+	}
+	return reflect.ValueOf(maskString)
+}
+
 func pathDuplicateDepth(x, y []PathStep) int {
 	minLen := len(x)
 	if minLen > len(y) {
@@ -17,10 +17,12 @@ package diff
 import (
 	"io/ioutil"
 	"reflect"
+	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 func TestRenderNodeValue(t *testing.T) {
@@ -204,3 +206,87 @@ func TestRenderPrimitiveValue(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderWithMask(t *testing.T) {
+	x := unstructured.Unstructured{Object: map[string]interface{}{
+		"data": map[string]interface{}{
+			"password": "old-password",
+			"removed":  "removed-value",
+		},
+		"spec": map[string]interface{}{
+			"token": "token-abc",
+			"image": "helloworld:v1",
+		},
+	}}
+	y := unstructured.Unstructured{Object: map[string]interface{}{
+		"data": map[string]interface{}{
+			"password": "new-password",
+		},
+		"spec": map[string]interface{}{
+			"token": "token-xyz",
+			"image": "helloworld:v2",
+		},
+	}}
+	result, err := DiffUnstructureds(x, y)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name     string
+		options  []RenderOption
+		expected string
+	}{
+		{
+			name: "no mask",
+			expected: `data:
+  #data.password
+- password: old-password
++ password: new-password
+
+  #data.removed
+- removed: removed-value
+
+spec:
+  #spec.image
+- image: helloworld:v1
++ image: helloworld:v2
+
+  #spec.token
+- token: token-abc
++ token: token-xyz
+
+`,
+		},
+		{
+			name: "mask by path and value pattern",
+			options: []RenderOption{
+				WithMaskPath("data"),
+				WithMaskValuePatterns(regexp.MustCompile(`^token-`)),
+			},
+			expected: `data:
+  #data.password
+- password: *****
++ password: *****
+
+  #data.removed
+- removed: *****
+
+spec:
+  #spec.image
+- image: helloworld:v1
++ image: helloworld:v2
+
+  #spec.token
+- token: *****
++ token: *****
+
+`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := NewRenderer(tc.options...).Render(result.Nodes())
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -16,6 +16,7 @@ package git
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -44,6 +45,49 @@ type Commit struct {
 	Body            string
 }
 
+var trailerRegex = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*):\s*(.*)$`)
+
+// Trailers returns the structured trailers such as "Ticket: PROJ-123"
+// placed in the last paragraph of the commit body.
+// The values of a key specified multiple times are joined by a comma.
+func (c Commit) Trailers() map[string]string {
+	return ParseTrailers(c.Body)
+}
+
+// ParseTrailers parses the trailers from the last paragraph of the given commit body.
+// The paragraph is treated as a trailer block only when all of its lines are
+// either "Key: value" lines or indented continuation lines of the previous value.
+func ParseTrailers(body string) map[string]string {
+	paragraphs := strings.Split(strings.TrimSpace(body), "\n\n")
+	last := strings.TrimSpace(paragraphs[len(paragraphs)-1])
+	if last == "" {
+		return nil
+	}
+
+	var (
+		trailers = make(map[string]string)
+		key      string
+	)
+	for _, line := range strings.Split(last, "\n") {
+		if key != "" && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			trailers[key] += " " + strings.TrimSpace(line)
+			continue
+		}
+		matches := trailerRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if matches == nil {
+			return nil
+		}
+		key = matches[1]
+		value := strings.TrimSpace(matches[2])
+		if v, ok := trailers[key]; ok {
+			trailers[key] = v + ", " + value
+			continue
+		}
+		trailers[key] = value
+	}
+	return trailers
+}
+
 // We was using json encoding to parse commit log,
 // but the commit message may contain various escape chars,
 // so I think reading each log line and map to Commit field is a good way.
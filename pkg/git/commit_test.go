@@ -78,3 +78,49 @@ This PR was merged by Kapetanios.`,
 	})
 	assert.Equal(t, expected, commits)
 }
+
+func TestParseTrailers(t *testing.T) {
+	testcases := []struct {
+		name     string
+		body     string
+		expected map[string]string
+	}{
+		{
+			name:     "empty body",
+			body:     "",
+			expected: nil,
+		},
+		{
+			name:     "no trailer",
+			body:     "This PR was merged by Kapetanios.",
+			expected: nil,
+		},
+		{
+			name: "only trailers",
+			body: "Deploy-Note: Run the migration first\nTicket: PROJ-123",
+			expected: map[string]string{
+				"Deploy-Note": "Run the migration first",
+				"Ticket":      "PROJ-123",
+			},
+		},
+		{
+			name: "trailers after description",
+			body: "Some description.\n\nTicket: PROJ-1\nTicket: PROJ-2\nDeploy-Note: Restart the workers\n  after the rollout",
+			expected: map[string]string{
+				"Ticket":      "PROJ-1, PROJ-2",
+				"Deploy-Note": "Restart the workers after the rollout",
+			},
+		},
+		{
+			name:     "last paragraph is not a trailer block",
+			body:     "Ticket: PROJ-1\n\nThis is a normal paragraph.\nTicket: PROJ-2",
+			expected: nil,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ParseTrailers(tc.body)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
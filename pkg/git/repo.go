@@ -37,8 +37,10 @@ type Repo interface {
 
 	ListCommits(ctx context.Context, visionRange string) ([]Commit, error)
 	GetLatestCommit(ctx context.Context) (Commit, error)
+	GetCommit(ctx context.Context, rev string) (Commit, error)
 	GetCommitHashForRev(ctx context.Context, rev string) (string, error)
 	ChangedFiles(ctx context.Context, from, to string) ([]string, error)
+	IsAncestor(ctx context.Context, ancestor, descendant string) (bool, error)
 	Checkout(ctx context.Context, commitish string) error
 	CheckoutPullRequest(ctx context.Context, number int, branch string) error
 	Clean() error
@@ -126,6 +128,30 @@ func (r *repo) GetLatestCommit(ctx context.Context) (Commit, error) {
 	return commits[0], nil
 }
 
+// GetCommit returns the commit for a given rev.
+func (r *repo) GetCommit(ctx context.Context, rev string) (Commit, error) {
+	out, err := r.runGitCommand(ctx,
+		"log",
+		"-1",
+		"--no-decorate",
+		fmt.Sprintf("--pretty=format:%s", commitLogFormat),
+		rev,
+	)
+	if err != nil {
+		return Commit{}, formatCommandError(err, out)
+	}
+
+	commits, err := parseCommits(string(out))
+	if err != nil {
+		return Commit{}, err
+	}
+	if len(commits) != 1 {
+		return Commit{}, fmt.Errorf("commits must contain one item, got: %d", len(commits))
+	}
+
+	return commits[0], nil
+}
+
 // GetCommitHashForRev returns the hash value of the commit for a given rev.
 func (r *repo) GetCommitHashForRev(ctx context.Context, rev string) (string, error) {
 	out, err := r.runGitCommand(ctx, "rev-parse", rev)
@@ -157,6 +183,21 @@ func (r *repo) ChangedFiles(ctx context.Context, from, to string) ([]string, err
 	return files, nil
 }
 
+// IsAncestor reports whether the ancestor commit is reachable from the descendant commit.
+// A commit is considered as an ancestor of itself.
+func (r *repo) IsAncestor(ctx context.Context, ancestor, descendant string) (bool, error) {
+	out, err := r.runGitCommand(ctx, "merge-base", "--is-ancestor", ancestor, descendant)
+	if err == nil {
+		return true, nil
+	}
+	// The command exits with 1 when it is not an ancestor and with other codes on errors.
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, formatCommandError(err, out)
+}
+
 // Checkout checkouts to a given commitish.
 func (r *repo) Checkout(ctx context.Context, commitish string) error {
 	out, err := r.runGitCommand(ctx, "checkout", commitish)
@@ -51,6 +51,13 @@ func TestGetCommitHashForRev(t *testing.T) {
 	latestCommitHash, err := r.GetCommitHashForRev(ctx, "HEAD")
 	require.NoError(t, err)
 	assert.Equal(t, commits[0].Hash, latestCommitHash)
+
+	commit, err := r.GetCommit(ctx, latestCommitHash)
+	require.NoError(t, err)
+	assert.Equal(t, commits[0], commit)
+
+	_, err = r.GetCommit(ctx, "non-existent-rev")
+	assert.Error(t, err)
 }
 
 func TestChangedFiles(t *testing.T) {
@@ -104,6 +111,47 @@ func TestChangedFiles(t *testing.T) {
 	assert.Equal(t, expectedChangedFiles, changedFiles)
 }
 
+func TestIsAncestor(t *testing.T) {
+	faker, err := newFaker()
+	require.NoError(t, err)
+	defer faker.clean()
+
+	var (
+		org      = "test-repo-org"
+		repoName = "repo-is-ancestor"
+		ctx      = context.Background()
+	)
+
+	err = faker.makeRepo(org, repoName)
+	require.NoError(t, err)
+	r := &repo{
+		dir:     faker.repoDir(org, repoName),
+		gitPath: faker.gitPath,
+	}
+
+	previousCommitHash, err := r.GetCommitHashForRev(ctx, "HEAD")
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(r.dir, "README.md"), []byte("new content"), os.ModePerm)
+	require.NoError(t, err)
+	err = r.addCommit(ctx, "Updated README")
+	require.NoError(t, err)
+
+	headCommitHash, err := r.GetCommitHashForRev(ctx, "HEAD")
+	require.NoError(t, err)
+
+	ok, err := r.IsAncestor(ctx, previousCommitHash, headCommitHash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = r.IsAncestor(ctx, headCommitHash, previousCommitHash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = r.IsAncestor(ctx, "unknown", headCommitHash)
+	assert.Error(t, err)
+}
+
 func TestAddCommit(t *testing.T) {
 	faker, err := newFaker()
 	require.NoError(t, err)
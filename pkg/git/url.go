@@ -52,6 +52,21 @@ func MakeCommitURL(repoURL, hash string) (string, error) {
 	return fmt.Sprintf("%s://%s/%s/%s/%s", scheme, u.Host, repoPath, subPath, hash), nil
 }
 
+// ParseRepoPath returns the path of the repository such as "org/repo"
+// from the given repoURL.
+func ParseRepoPath(repoURL string) (string, error) {
+	u, err := parseGitURL(repoURL)
+	if err != nil {
+		return "", err
+	}
+	repoPath := strings.Trim(u.Path, "/")
+	repoPath = strings.TrimSuffix(repoPath, ".git")
+	if repoPath == "" {
+		return "", fmt.Errorf("no repository path found in %q", repoURL)
+	}
+	return repoPath, nil
+}
+
 // MakeDirURL builds a link to the HTML page of the directory.
 func MakeDirURL(repoURL, dir, branch string) (string, error) {
 	if branch == "" {
@@ -384,3 +384,44 @@ func TestParseGitURL(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRepoPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		repoURL string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "ssh to github.com",
+			repoURL: "git@github.com:org/repo.git",
+			want:    "org/repo",
+			wantErr: false,
+		},
+		{
+			name:    "https to gitlab.com with subgroup",
+			repoURL: "https://gitlab.com/org/group/repo.git",
+			want:    "org/group/repo",
+			wantErr: false,
+		},
+		{
+			name:    "ssh with `/` suffix",
+			repoURL: "git@github.com:org/repo/",
+			want:    "org/repo",
+			wantErr: false,
+		},
+		{
+			name:    "unparseable url",
+			repoURL: "1234abcd",
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRepoPath(tt.repoURL)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -16,6 +16,7 @@ package insight
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -37,6 +38,12 @@ import (
 //            ├─ 2020-01.json
 //            ├─ 2020-02.json
 //            ...
+//        ├─ label
+//            ├─ team  # aggregated from all applications having the label
+//                ├─ team-a
+//                    ├─ years.json
+//                    ├─ 2020-01.json
+//                    ...
 func MakeYearsFilePath(projectID string, metricsKind model.InsightMetricsKind, appID string) string {
 	k := strings.ToLower(metricsKind.String())
 	return fmt.Sprintf("insights/%s/%s/%s/years.json", projectID, k, appID)
@@ -47,6 +54,12 @@ func MakeChunkFilePath(projectID string, metricsKind model.InsightMetricsKind, a
 	return fmt.Sprintf("insights/%s/%s/%s/%s.json", projectID, k, appID, month)
 }
 
+// MakeLabelGroupID returns the identifier used in place of an application ID
+// for the data aggregated from all applications having the given label.
+func MakeLabelGroupID(key, value string) string {
+	return fmt.Sprintf("label/%s/%s", url.PathEscape(key), url.PathEscape(value))
+}
+
 func DetermineFilePaths(projectID string, appID string, kind model.InsightMetricsKind, step model.InsightStep, from time.Time, count int) []string {
 	if appID == "" {
 		appID = "project"
@@ -126,3 +126,31 @@ func Test_determineFilePaths(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeLabelGroupID(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+		want  string
+	}{
+		{
+			name:  "simple label",
+			key:   "team",
+			value: "payment",
+			want:  "label/team/payment",
+		},
+		{
+			name:  "label containing slash",
+			key:   "pipecd.dev/tier",
+			value: "backend",
+			want:  "label/pipecd.dev%2Ftier/backend",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MakeLabelGroupID(tt.key, tt.value)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
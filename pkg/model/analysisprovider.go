@@ -17,9 +17,15 @@ package model
 type AnalysisProviderType string
 
 const (
-	AnalysisProviderPrometheus  AnalysisProviderType = "PROMETHEUS"
-	AnalysisProviderDatadog     AnalysisProviderType = "DATADOG"
-	AnalysisProviderStackdriver AnalysisProviderType = "STACKDRIVER"
+	AnalysisProviderPrometheus    AnalysisProviderType = "PROMETHEUS"
+	AnalysisProviderDatadog       AnalysisProviderType = "DATADOG"
+	AnalysisProviderStackdriver   AnalysisProviderType = "STACKDRIVER"
+	AnalysisProviderElasticsearch AnalysisProviderType = "ELASTICSEARCH"
+	AnalysisProviderGraphite      AnalysisProviderType = "GRAPHITE"
+	AnalysisProviderInfluxDB      AnalysisProviderType = "INFLUXDB"
+	AnalysisProviderWavefront     AnalysisProviderType = "WAVEFRONT"
+	AnalysisProviderWebhook       AnalysisProviderType = "WEBHOOK"
+	AnalysisProviderPlugin        AnalysisProviderType = "PLUGIN"
 )
 
 func (t AnalysisProviderType) String() string {
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const DefaultDeploymentConfigFileName = ".pipe.yaml"
@@ -46,6 +47,60 @@ func (s ApplicationSyncState) HasChanged(next ApplicationSyncState) bool {
 	return false
 }
 
+// InMaintenance reports whether the maintenance mode of the application
+// is enabled and not expired yet at the given time.
+func (a *Application) InMaintenance(now time.Time) bool {
+	m := a.Maintenance
+	if m == nil {
+		return false
+	}
+	return m.ExpiresAt == 0 || now.Unix() < m.ExpiresAt
+}
+
 func MakeApplicationURL(baseURL, applicationID string) string {
 	return fmt.Sprintf("%s/applications/%s", strings.TrimSuffix(baseURL, "/"), applicationID)
 }
+
+// SortApplicationDependencies returns the IDs of all applications the given
+// application depends on, directly or transitively, ordered so that every
+// application comes after all of its own dependencies.
+// The given application itself is not included in the returned list.
+// An error is returned when a dependency is unknown or a cycle is detected.
+func SortApplicationDependencies(id string, apps map[string]*Application) ([]string, error) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	var (
+		states = make(map[string]int, len(apps))
+		order  = make([]string, 0)
+		visit  func(id string, path []string) error
+	)
+	visit = func(id string, path []string) error {
+		switch states[id] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle detected: %s", strings.Join(append(path, id), " -> "))
+		}
+		app, ok := apps[id]
+		if !ok {
+			return fmt.Errorf("application %s was not found", id)
+		}
+		states[id] = visiting
+		for _, dep := range app.Dependencies {
+			if err := visit(dep, append(path, id)); err != nil {
+				return err
+			}
+		}
+		states[id] = visited
+		order = append(order, id)
+		return nil
+	}
+
+	if err := visit(id, nil); err != nil {
+		return nil, err
+	}
+	// The last one is the given application itself.
+	return order[:len(order)-1], nil
+}
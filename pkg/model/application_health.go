@@ -0,0 +1,98 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import "fmt"
+
+const (
+	healthPenaltyOutOfSync           = 30
+	healthPenaltyUnknownSyncState    = 10
+	healthPenaltyUnhealthyLiveState  = 40
+	healthPenaltyUnknownLiveState    = 10
+	healthPenaltyFailedDeployment    = 40
+	healthPenaltyCancelledDeployment = 20
+
+	// The minimum scores for each status.
+	healthyScore  = 80
+	degradedScore = 50
+)
+
+// MakeApplicationHealth rolls up the sync state, the live state and the most recent deployment
+// of the given application into a single health summary.
+// The snapshot and the deployment are nil when they are not available.
+func MakeApplicationHealth(app *Application, snapshot *ApplicationLiveStateSnapshot, mostRecent *Deployment) *ApplicationHealth {
+	h := &ApplicationHealth{
+		ApplicationId:   app.Id,
+		ApplicationName: app.Name,
+		EnvId:           app.EnvId,
+		Score:           100,
+	}
+	penalize := func(penalty int32, reason string) {
+		h.Score -= penalty
+		h.Reasons = append(h.Reasons, reason)
+	}
+
+	if s := app.SyncState; s != nil {
+		h.SyncStatus = s.Status
+	}
+	switch h.SyncStatus {
+	case ApplicationSyncStatus_OUT_OF_SYNC:
+		reason := "The application is out of sync"
+		if app.SyncState.ShortReason != "" {
+			reason = fmt.Sprintf("%s: %s", reason, app.SyncState.ShortReason)
+		}
+		penalize(healthPenaltyOutOfSync, reason)
+	case ApplicationSyncStatus_UNKNOWN:
+		penalize(healthPenaltyUnknownSyncState, "The sync state of the application is unknown")
+	}
+
+	if snapshot != nil {
+		h.LiveStateStatus = snapshot.HealthStatus
+	}
+	switch h.LiveStateStatus {
+	case ApplicationLiveStateSnapshot_OTHER:
+		penalize(healthPenaltyUnhealthyLiveState, "Some resources of the application are not healthy")
+	case ApplicationLiveStateSnapshot_UNKNOWN:
+		penalize(healthPenaltyUnknownLiveState, "The live state of the application is unknown")
+	}
+
+	if mostRecent != nil {
+		h.MostRecentDeploymentId = mostRecent.Id
+		h.MostRecentDeploymentStatus = mostRecent.Status
+		switch mostRecent.Status {
+		case DeploymentStatus_DEPLOYMENT_FAILURE:
+			reason := fmt.Sprintf("The most recent deployment %s failed", mostRecent.Id)
+			if mostRecent.StatusReason != "" {
+				reason = fmt.Sprintf("%s: %s", reason, mostRecent.StatusReason)
+			}
+			penalize(healthPenaltyFailedDeployment, reason)
+		case DeploymentStatus_DEPLOYMENT_CANCELLED:
+			penalize(healthPenaltyCancelledDeployment, fmt.Sprintf("The most recent deployment %s was cancelled", mostRecent.Id))
+		}
+	}
+
+	if h.Score < 0 {
+		h.Score = 0
+	}
+	switch {
+	case h.Score >= healthyScore:
+		h.Status = ApplicationHealthStatus_APPLICATION_HEALTHY
+	case h.Score >= degradedScore:
+		h.Status = ApplicationHealthStatus_APPLICATION_DEGRADED
+	default:
+		h.Status = ApplicationHealthStatus_APPLICATION_UNHEALTHY
+	}
+	return h
+}
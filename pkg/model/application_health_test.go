@@ -0,0 +1,144 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeApplicationHealth(t *testing.T) {
+	app := &Application{
+		Id:    "app-id",
+		Name:  "app-name",
+		EnvId: "env-id",
+		SyncState: &ApplicationSyncState{
+			Status: ApplicationSyncStatus_SYNCED,
+		},
+	}
+	healthy := &ApplicationLiveStateSnapshot{
+		HealthStatus: ApplicationLiveStateSnapshot_HEALTHY,
+	}
+	succeeded := &Deployment{
+		Id:     "deployment-id",
+		Status: DeploymentStatus_DEPLOYMENT_SUCCESS,
+	}
+
+	testcases := []struct {
+		name       string
+		app        *Application
+		snapshot   *ApplicationLiveStateSnapshot
+		mostRecent *Deployment
+		expected   *ApplicationHealth
+	}{
+		{
+			name:       "healthy",
+			app:        app,
+			snapshot:   healthy,
+			mostRecent: succeeded,
+			expected: &ApplicationHealth{
+				ApplicationId:              "app-id",
+				ApplicationName:            "app-name",
+				EnvId:                      "env-id",
+				Status:                     ApplicationHealthStatus_APPLICATION_HEALTHY,
+				Score:                      100,
+				SyncStatus:                 ApplicationSyncStatus_SYNCED,
+				LiveStateStatus:            ApplicationLiveStateSnapshot_HEALTHY,
+				MostRecentDeploymentId:     "deployment-id",
+				MostRecentDeploymentStatus: DeploymentStatus_DEPLOYMENT_SUCCESS,
+			},
+		},
+		{
+			name: "never deployed application without state",
+			app: &Application{
+				Id:    "app-id",
+				Name:  "app-name",
+				EnvId: "env-id",
+			},
+			expected: &ApplicationHealth{
+				ApplicationId:   "app-id",
+				ApplicationName: "app-name",
+				EnvId:           "env-id",
+				Status:          ApplicationHealthStatus_APPLICATION_HEALTHY,
+				Score:           80,
+				Reasons: []string{
+					"The sync state of the application is unknown",
+					"The live state of the application is unknown",
+				},
+			},
+		},
+		{
+			name: "out of sync",
+			app: &Application{
+				Id:    "app-id",
+				Name:  "app-name",
+				EnvId: "env-id",
+				SyncState: &ApplicationSyncState{
+					Status:      ApplicationSyncStatus_OUT_OF_SYNC,
+					ShortReason: "There are 2 manifests not synced",
+				},
+			},
+			snapshot:   healthy,
+			mostRecent: succeeded,
+			expected: &ApplicationHealth{
+				ApplicationId:              "app-id",
+				ApplicationName:            "app-name",
+				EnvId:                      "env-id",
+				Status:                     ApplicationHealthStatus_APPLICATION_DEGRADED,
+				Score:                      70,
+				Reasons:                    []string{"The application is out of sync: There are 2 manifests not synced"},
+				SyncStatus:                 ApplicationSyncStatus_OUT_OF_SYNC,
+				LiveStateStatus:            ApplicationLiveStateSnapshot_HEALTHY,
+				MostRecentDeploymentId:     "deployment-id",
+				MostRecentDeploymentStatus: DeploymentStatus_DEPLOYMENT_SUCCESS,
+			},
+		},
+		{
+			name: "unhealthy resources and failed deployment",
+			app:  app,
+			snapshot: &ApplicationLiveStateSnapshot{
+				HealthStatus: ApplicationLiveStateSnapshot_OTHER,
+			},
+			mostRecent: &Deployment{
+				Id:           "deployment-id",
+				Status:       DeploymentStatus_DEPLOYMENT_FAILURE,
+				StatusReason: "Analysis failed",
+			},
+			expected: &ApplicationHealth{
+				ApplicationId:   "app-id",
+				ApplicationName: "app-name",
+				EnvId:           "env-id",
+				Status:          ApplicationHealthStatus_APPLICATION_UNHEALTHY,
+				Score:           20,
+				Reasons: []string{
+					"Some resources of the application are not healthy",
+					"The most recent deployment deployment-id failed: Analysis failed",
+				},
+				SyncStatus:                 ApplicationSyncStatus_SYNCED,
+				LiveStateStatus:            ApplicationLiveStateSnapshot_OTHER,
+				MostRecentDeploymentId:     "deployment-id",
+				MostRecentDeploymentStatus: DeploymentStatus_DEPLOYMENT_FAILURE,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := MakeApplicationHealth(tc.app, tc.snapshot, tc.mostRecent)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
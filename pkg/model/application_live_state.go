@@ -43,16 +43,25 @@ func (s KubernetesResourceState) HasDiff(a KubernetesResourceState) bool {
 	if len(s.ParentIds) != len(a.ParentIds) {
 		return true
 	}
+	if len(s.DependencyIds) != len(a.DependencyIds) {
+		return true
+	}
 
 	for i := range s.OwnerIds {
 		if s.OwnerIds[i] != a.OwnerIds[i] {
-			return false
+			return true
 		}
 	}
 
 	for i := range s.ParentIds {
 		if s.ParentIds[i] != a.ParentIds[i] {
-			return false
+			return true
+		}
+	}
+
+	for i := range s.DependencyIds {
+		if s.DependencyIds[i] != a.DependencyIds[i] {
+			return true
 		}
 	}
 
@@ -75,6 +84,35 @@ func (s *ApplicationLiveStateSnapshot) DetermineAppHealthStatus() {
 			}
 		}
 		s.HealthStatus = status
+	case ApplicationKind_CLOUDRUN:
+		c := s.Cloudrun
+		if c == nil {
+			return
+		}
+		status := ApplicationLiveStateSnapshot_HEALTHY
+		for _, r := range c.Revisions {
+			if r.HealthStatus == CloudRunRevisionState_OTHER {
+				status = ApplicationLiveStateSnapshot_OTHER
+				break
+			}
+		}
+		for _, e := range c.JobExecutions {
+			if e.Status == CloudRunJobExecutionState_FAILED {
+				status = ApplicationLiveStateSnapshot_OTHER
+				break
+			}
+		}
+		s.HealthStatus = status
+	case ApplicationKind_LAMBDA:
+		l := s.Lambda
+		if l == nil {
+			return
+		}
+		status := ApplicationLiveStateSnapshot_HEALTHY
+		if l.HealthStatus == LambdaApplicationLiveState_OTHER {
+			status = ApplicationLiveStateSnapshot_OTHER
+		}
+		s.HealthStatus = status
 	default:
 		// TODO: Determine health state of other than k8s app
 		return
@@ -0,0 +1,153 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetermineAppHealthStatus(t *testing.T) {
+	testcases := []struct {
+		name     string
+		snapshot *ApplicationLiveStateSnapshot
+		expected ApplicationLiveStateSnapshot_Status
+	}{
+		{
+			name: "healthy kubernetes app",
+			snapshot: &ApplicationLiveStateSnapshot{
+				Kind: ApplicationKind_KUBERNETES,
+				Kubernetes: &KubernetesApplicationLiveState{
+					Resources: []*KubernetesResourceState{
+						{HealthStatus: KubernetesResourceState_HEALTHY},
+					},
+				},
+			},
+			expected: ApplicationLiveStateSnapshot_HEALTHY,
+		},
+		{
+			name: "unhealthy cloudrun app",
+			snapshot: &ApplicationLiveStateSnapshot{
+				Kind: ApplicationKind_CLOUDRUN,
+				Cloudrun: &CloudRunApplicationLiveState{
+					Revisions: []*CloudRunRevisionState{
+						{HealthStatus: CloudRunRevisionState_HEALTHY},
+						{HealthStatus: CloudRunRevisionState_OTHER},
+					},
+				},
+			},
+			expected: ApplicationLiveStateSnapshot_OTHER,
+		},
+		{
+			name: "healthy cloudrun app",
+			snapshot: &ApplicationLiveStateSnapshot{
+				Kind: ApplicationKind_CLOUDRUN,
+				Cloudrun: &CloudRunApplicationLiveState{
+					Revisions: []*CloudRunRevisionState{
+						{HealthStatus: CloudRunRevisionState_HEALTHY},
+					},
+				},
+			},
+			expected: ApplicationLiveStateSnapshot_HEALTHY,
+		},
+		{
+			name: "cloudrun app with failed job execution",
+			snapshot: &ApplicationLiveStateSnapshot{
+				Kind: ApplicationKind_CLOUDRUN,
+				Cloudrun: &CloudRunApplicationLiveState{
+					Revisions: []*CloudRunRevisionState{
+						{HealthStatus: CloudRunRevisionState_HEALTHY},
+					},
+					JobExecutions: []*CloudRunJobExecutionState{
+						{Status: CloudRunJobExecutionState_SUCCEEDED},
+						{Status: CloudRunJobExecutionState_FAILED},
+					},
+				},
+			},
+			expected: ApplicationLiveStateSnapshot_OTHER,
+		},
+		{
+			name: "unhealthy lambda app",
+			snapshot: &ApplicationLiveStateSnapshot{
+				Kind: ApplicationKind_LAMBDA,
+				Lambda: &LambdaApplicationLiveState{
+					HealthStatus: LambdaApplicationLiveState_OTHER,
+				},
+			},
+			expected: ApplicationLiveStateSnapshot_OTHER,
+		},
+		{
+			name: "missing lambda state",
+			snapshot: &ApplicationLiveStateSnapshot{
+				Kind: ApplicationKind_LAMBDA,
+			},
+			expected: ApplicationLiveStateSnapshot_UNKNOWN,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.snapshot.DetermineAppHealthStatus()
+			assert.Equal(t, tc.expected, tc.snapshot.HealthStatus)
+		})
+	}
+}
+
+func TestKubernetesResourceStateHasDiff(t *testing.T) {
+	base := KubernetesResourceState{
+		Name:          "foo",
+		OwnerIds:      []string{"owner-1"},
+		ParentIds:     []string{"parent-1"},
+		DependencyIds: []string{"dep-1"},
+	}
+	testcases := []struct {
+		name     string
+		modify   func(s *KubernetesResourceState)
+		expected bool
+	}{
+		{
+			name:     "no diff",
+			modify:   func(s *KubernetesResourceState) {},
+			expected: false,
+		},
+		{
+			name:     "different owner",
+			modify:   func(s *KubernetesResourceState) { s.OwnerIds = []string{"owner-2"} },
+			expected: true,
+		},
+		{
+			name:     "different parent",
+			modify:   func(s *KubernetesResourceState) { s.ParentIds = []string{"parent-2"} },
+			expected: true,
+		},
+		{
+			name:     "different dependency",
+			modify:   func(s *KubernetesResourceState) { s.DependencyIds = []string{"dep-2"} },
+			expected: true,
+		},
+		{
+			name:     "added dependency",
+			modify:   func(s *KubernetesResourceState) { s.DependencyIds = []string{"dep-1", "dep-2"} },
+			expected: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := base
+			tc.modify(&a)
+			assert.Equal(t, tc.expected, base.HasDiff(a))
+		})
+	}
+}
@@ -16,6 +16,7 @@ package model
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -47,3 +48,111 @@ func TestMakeApplicationURL(t *testing.T) {
 		})
 	}
 }
+
+func TestSortApplicationDependencies(t *testing.T) {
+	testcases := []struct {
+		name        string
+		id          string
+		apps        map[string]*Application
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			name: "no dependency",
+			id:   "app-1",
+			apps: map[string]*Application{
+				"app-1": {Id: "app-1"},
+			},
+			expected: []string{},
+		},
+		{
+			name: "transitive dependencies",
+			id:   "app-1",
+			apps: map[string]*Application{
+				"app-1": {Id: "app-1", Dependencies: []string{"app-2", "app-3"}},
+				"app-2": {Id: "app-2", Dependencies: []string{"app-4"}},
+				"app-3": {Id: "app-3", Dependencies: []string{"app-4"}},
+				"app-4": {Id: "app-4"},
+			},
+			expected: []string{"app-4", "app-2", "app-3"},
+		},
+		{
+			name: "missing dependency",
+			id:   "app-1",
+			apps: map[string]*Application{
+				"app-1": {Id: "app-1", Dependencies: []string{"app-2"}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "cycle",
+			id:   "app-1",
+			apps: map[string]*Application{
+				"app-1": {Id: "app-1", Dependencies: []string{"app-2"}},
+				"app-2": {Id: "app-2", Dependencies: []string{"app-3"}},
+				"app-3": {Id: "app-3", Dependencies: []string{"app-1"}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "self dependency",
+			id:   "app-1",
+			apps: map[string]*Application{
+				"app-1": {Id: "app-1", Dependencies: []string{"app-1"}},
+			},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := SortApplicationDependencies(tc.id, tc.apps)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			if err == nil {
+				assert.Equal(t, tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestApplicationInMaintenance(t *testing.T) {
+	now := time.Unix(1000, 0)
+	testcases := []struct {
+		name        string
+		maintenance *ApplicationMaintenance
+		expected    bool
+	}{
+		{
+			name:     "not enabled",
+			expected: false,
+		},
+		{
+			name: "enabled without expiry",
+			maintenance: &ApplicationMaintenance{
+				Reason: "incident",
+			},
+			expected: true,
+		},
+		{
+			name: "enabled and not expired yet",
+			maintenance: &ApplicationMaintenance{
+				Reason:    "incident",
+				ExpiresAt: 1001,
+			},
+			expected: true,
+		},
+		{
+			name: "expired",
+			maintenance: &ApplicationMaintenance{
+				Reason:    "incident",
+				ExpiresAt: 1000,
+			},
+			expected: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := &Application{Maintenance: tc.maintenance}
+			assert.Equal(t, tc.expected, app.InMaintenance(now))
+		})
+	}
+}
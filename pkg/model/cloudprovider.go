@@ -22,6 +22,9 @@ const (
 	CloudProviderCloudRun   CloudProviderType = "CLOUDRUN"
 	CloudProviderLambda     CloudProviderType = "LAMBDA"
 	CloudProviderECS        CloudProviderType = "ECS"
+	CloudProviderVM         CloudProviderType = "VM"
+	CloudProviderPulumi     CloudProviderType = "PULUMI"
+	CloudProviderAnsible    CloudProviderType = "ANSIBLE"
 )
 
 func (t CloudProviderType) String() string {
@@ -16,10 +16,15 @@ package model
 
 import (
 	"fmt"
+	"strings"
 
 	"google.golang.org/protobuf/proto"
 )
 
+// CommitTrailerMetadataKeyPrefix is the prefix of the deployment metadata keys
+// used to store the trailers of the commit that triggered the deployment.
+const CommitTrailerMetadataKeyPrefix = "commit-trailer/"
+
 var notCompletedDeploymentStatuses = []DeploymentStatus{
 	DeploymentStatus_DEPLOYMENT_PENDING,
 	DeploymentStatus_DEPLOYMENT_PLANNED,
@@ -118,6 +123,33 @@ func (d *Deployment) CommitHash() string {
 	return d.Trigger.Commit.Hash
 }
 
+// CommitTrailers returns the trailers of trigger commit
+// those were stored in the deployment metadata.
+func (d *Deployment) CommitTrailers() map[string]string {
+	trailers := make(map[string]string)
+	for k, v := range d.Metadata {
+		if strings.HasPrefix(k, CommitTrailerMetadataKeyPrefix) {
+			trailers[strings.TrimPrefix(k, CommitTrailerMetadataKeyPrefix)] = v
+		}
+	}
+	return trailers
+}
+
+// Approvers returns the names of users who approved
+// the WAIT_APPROVAL stages of the deployment.
+func (d *Deployment) Approvers() []string {
+	var approvers []string
+	for _, s := range d.Stages {
+		if s.Name != StageWaitApproval.String() {
+			continue
+		}
+		if approver := s.Metadata[StageMetadataKeyApprovedBy]; approver != "" {
+			approvers = append(approvers, approver)
+		}
+	}
+	return approvers
+}
+
 func (d *Deployment) TriggeredBy() string {
 	if d.Trigger.Commander != "" {
 		return d.Trigger.Commander
@@ -159,6 +191,22 @@ func (d *Deployment) CloudProviderType() CloudProviderType {
 	}
 }
 
+// IsDryRun reports whether the deployment only verifies the changes
+// without applying them into the target environment.
+func (d *Deployment) IsDryRun() bool {
+	return d.Trigger != nil && d.Trigger.DryRun
+}
+
+// FindStage finds the stage with the given id in stage list.
+func (d *Deployment) FindStage(id string) (*PipelineStage, bool) {
+	for _, s := range d.Stages {
+		if s.Id == id {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
 // FindRollbackStage finds the rollback stage in stage list.
 func (d *Deployment) FindRollbackStage() (*PipelineStage, bool) {
 	for i := len(d.Stages) - 1; i >= 0; i-- {
@@ -169,6 +217,23 @@ func (d *Deployment) FindRollbackStage() (*PipelineStage, bool) {
 	return nil, false
 }
 
+// IsPinned reports whether the deployment was triggered for the commit
+// specified by the user instead of the head commit of the branch.
+func (t *DeploymentTrigger) IsPinned() bool {
+	return t.PinnedDirection != PinnedDirection_NOT_PINNED
+}
+
+// FindVariantCleanStage finds the stage to clean the variants
+// left by the deployment when it was not rolled back.
+func (d *Deployment) FindVariantCleanStage() (*PipelineStage, bool) {
+	for i := len(d.Stages) - 1; i >= 0; i-- {
+		if d.Stages[i].Name == StageK8sVariantClean.String() {
+			return d.Stages[i], true
+		}
+	}
+	return nil, false
+}
+
 // DeploymentStatusesFromStrings converts a list of strings to list of DeploymentStatus.
 func DeploymentStatusesFromStrings(statuses []string) ([]DeploymentStatus, error) {
 	out := make([]DeploymentStatus, 0, len(statuses))
@@ -190,3 +255,25 @@ func DeploymentStatusStrings() []string {
 	}
 	return out
 }
+
+// ChangeText returns the human-readable text describing
+// how the artifact changes from its running version.
+func (v *ArtifactVersion) ChangeText() string {
+	switch v.RunningVersion {
+	case "":
+		return fmt.Sprintf("%s:%s (new)", v.Name, v.Version)
+	case v.Version:
+		return fmt.Sprintf("%s:%s", v.Name, v.Version)
+	default:
+		return fmt.Sprintf("%s from %s to %s", v.Name, v.RunningVersion, v.Version)
+	}
+}
+
+// ArtifactVersionsText returns the change texts of the given artifacts joined by the separator.
+func ArtifactVersionsText(versions []*ArtifactVersion, sep string) string {
+	texts := make([]string, 0, len(versions))
+	for _, v := range versions {
+		texts = append(texts, v.ChangeText())
+	}
+	return strings.Join(texts, sep)
+}
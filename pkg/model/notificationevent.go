@@ -46,6 +46,14 @@ func (e *NotificationEventDeploymentApproved) GetAppName() string {
 	return e.Deployment.ApplicationName
 }
 
+func (e *NotificationEventDeploymentRejected) GetAppName() string {
+	return e.Deployment.ApplicationName
+}
+
+func (e *NotificationEventDeploymentApprovalEscalated) GetAppName() string {
+	return e.Deployment.ApplicationName
+}
+
 func (e *NotificationEventDeploymentRollingBack) GetAppName() string {
 	return e.Deployment.ApplicationName
 }
@@ -54,6 +54,32 @@ func GetSecretEncryptionInPiped(p *Piped) *Piped_SecretEncryption {
 	return p.SecretEncryption
 }
 
+// Passed returns true when all checks of the diagnostics have passed.
+func (d *PipedDiagnostics) Passed() bool {
+	for _, c := range d.Checks {
+		if !c.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Healthy returns true when all cloud providers are reachable
+// and the latest fetches of all repositories succeeded.
+func (h *PipedHealth) Healthy() bool {
+	for _, cp := range h.CloudProviders {
+		if !cp.Reachable {
+			return false
+		}
+	}
+	for _, r := range h.Repositories {
+		if r.LastError != "" {
+			return false
+		}
+	}
+	return true
+}
+
 // GeneratePipedKey generates a new key for piped.
 // This returns raw key value for used by piped and
 // a hash value of the key for storing in datastore.
@@ -132,6 +158,21 @@ func (p *Piped) DeleteOldPipedKeys() {
 	p.Keys = []*PipedKey{latest}
 }
 
+// AllowsEnvironment reports whether the piped is allowed to handle
+// the applications of the given environment.
+// A piped not bound to any environment is allowed to handle all of them.
+func (p *Piped) AllowsEnvironment(envID string) bool {
+	if len(p.EnvIds) == 0 {
+		return true
+	}
+	for _, id := range p.EnvIds {
+		if id == envID {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Piped) RedactSensitiveData() {
 	p.KeyHash = redactedMessage
 	for i := range p.Keys {
@@ -178,6 +178,39 @@ func TestPipedDeleteOldPipedKeys(t *testing.T) {
 	}
 }
 
+func TestPipedAllowsEnvironment(t *testing.T) {
+	testcases := []struct {
+		name     string
+		envIDs   []string
+		envID    string
+		expected bool
+	}{
+		{
+			name:     "not bound to any environment",
+			envID:    "prod",
+			expected: true,
+		},
+		{
+			name:     "bound to the environment",
+			envIDs:   []string{"dev", "prod"},
+			envID:    "prod",
+			expected: true,
+		},
+		{
+			name:     "not bound to the environment",
+			envIDs:   []string{"dev"},
+			envID:    "prod",
+			expected: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Piped{EnvIds: tc.envIDs}
+			assert.Equal(t, tc.expected, p.AllowsEnvironment(tc.envID))
+		})
+	}
+}
+
 func TestPipedRedactSensitiveData(t *testing.T) {
 	testcases := []struct {
 		name     string
@@ -264,3 +297,92 @@ func TestMakePipedURL(t *testing.T) {
 		})
 	}
 }
+
+func TestPipedDiagnosticsPassed(t *testing.T) {
+	testcases := []struct {
+		name        string
+		diagnostics PipedDiagnostics
+		expected    bool
+	}{
+		{
+			name:        "no check",
+			diagnostics: PipedDiagnostics{},
+			expected:    true,
+		},
+		{
+			name: "all passed",
+			diagnostics: PipedDiagnostics{
+				Checks: []*PipedDiagnostics_Check{
+					{Category: "GIT", Name: "repo-1", Passed: true},
+					{Category: "TOOL", Name: "kubectl", Passed: true},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "one failed",
+			diagnostics: PipedDiagnostics{
+				Checks: []*PipedDiagnostics_Check{
+					{Category: "GIT", Name: "repo-1", Passed: true},
+					{Category: "TOOL", Name: "kubectl", Passed: false},
+				},
+			},
+			expected: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.diagnostics.Passed())
+		})
+	}
+}
+
+func TestPipedHealthHealthy(t *testing.T) {
+	testcases := []struct {
+		name     string
+		health   PipedHealth
+		expected bool
+	}{
+		{
+			name:     "nothing reported",
+			health:   PipedHealth{},
+			expected: true,
+		},
+		{
+			name: "all healthy",
+			health: PipedHealth{
+				CloudProviders: []*PipedHealth_CloudProvider{
+					{Name: "kubernetes-default", Reachable: true},
+				},
+				Repositories: []*PipedHealth_Repository{
+					{Id: "repo-1", LastFetchedAt: 100},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "unreachable cloud provider",
+			health: PipedHealth{
+				CloudProviders: []*PipedHealth_CloudProvider{
+					{Name: "kubernetes-default", Reachable: true},
+					{Name: "lambda-default", Reachable: false},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "failed repository fetch",
+			health: PipedHealth{
+				Repositories: []*PipedHealth_Repository{
+					{Id: "repo-1", LastFetchedAt: 100, LastError: "authentication failed", LastFailedAt: 200},
+				},
+			},
+			expected: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.health.Healthy())
+		})
+	}
+}
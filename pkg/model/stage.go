@@ -14,6 +14,8 @@
 
 package model
 
+import "strings"
+
 // Stage represents the middle and temporary state of application
 // before reaching its final desired state.
 type Stage string
@@ -27,6 +29,9 @@ const (
 	// StageAnalysis represents the waiting state for analysing
 	// the application status based on metrics, log, http request...
 	StageAnalysis Stage = "ANALYSIS"
+	// StageFeatureFlag represents the state where a feature flag
+	// has been rolled out to the specified percentage of users.
+	StageFeatureFlag Stage = "FEATURE_FLAG"
 
 	// StageK8sSync represents the state where
 	// all resources should be synced with the Git state.
@@ -49,6 +54,21 @@ const (
 	// StageK8sTrafficRouting represents the state where the traffic to application
 	// should be splitted as the specified percentage to PRIMARY, CANARY, BASELINE variants.
 	StageK8sTrafficRouting Stage = "K8S_TRAFFIC_ROUTING"
+	// StageK8sDryRun represents the state where all manifests have been
+	// verified by a server-side dry-run apply without persisting them.
+	StageK8sDryRun Stage = "K8S_DRY_RUN"
+	// StageK8sPolicyCheck represents the state where all manifests have been
+	// evaluated against the configured policies before applying them.
+	StageK8sPolicyCheck Stage = "K8S_POLICY_CHECK"
+	// StageK8sVulnerabilityScan represents the state where the images used by manifests
+	// have been checked to contain no vulnerability above the configured severity.
+	StageK8sVulnerabilityScan Stage = "K8S_VULNERABILITY_SCAN"
+	// StageK8sVariantClean represents the state where the traffic has been routed back
+	// to PRIMARY variant and the CANARY, BASELINE variants left by a cancelled or failed
+	// deployment have been removed.
+	// This stage is AUTOMATICALLY GENERATED and can not be used
+	// to specify in configuration file.
+	StageK8sVariantClean Stage = "K8S_VARIANT_CLEAN"
 
 	// StageTerraformSync synced infrastructure with all the tf defined in Git.
 	// Firstly, it does plan and if there are any changes detected it applies those changes automatically.
@@ -64,6 +84,11 @@ const (
 	StageCloudRunSync Stage = "CLOUDRUN_SYNC"
 	// StageCloudRunPromote promotes the new version to receive amount of traffic.
 	StageCloudRunPromote Stage = "CLOUDRUN_PROMOTE"
+	// StageCloudRunJobRun executes a Cloud Run job and waits until the execution is completed.
+	StageCloudRunJobRun Stage = "CLOUDRUN_JOB_RUN"
+	// StageCloudRunRevisionTag assigns or removes the tags of revisions
+	// to expose them via dedicated URLs without receiving traffic.
+	StageCloudRunRevisionTag Stage = "CLOUDRUN_REVISION_TAG"
 
 	// StageLambdaSync does quick sync by rolling out the new version
 	// and switching all traffic to it.
@@ -73,6 +98,9 @@ const (
 	StageLambdaCanaryRollout Stage = "LAMBDA_CANARY_ROLLOUT"
 	// StageLambdaPromote prmotes the new version to receive amount of traffic.
 	StageLambdaPromote Stage = "LAMBDA_PROMOTE"
+	// StageLambdaTrafficRouting represents the state where the user traffic
+	// coming through an ALB or an API Gateway is routed to the rolled out version.
+	StageLambdaTrafficRouting Stage = "LAMBDA_TRAFFIC_ROUTING"
 
 	// StageECSSync does quick sync by rolling out the new version
 	// and switching all traffic to it.
@@ -92,6 +120,34 @@ const (
 	// the CANARY variant resources has been cleaned.
 	StageECSCanaryClean Stage = "ECS_CANARY_CLEAN"
 
+	// StageVMSync does quick sync by replacing all instances of the group
+	// with the new image in a rolling manner.
+	StageVMSync Stage = "VM_SYNC"
+	// StageVMCanaryRollout represents the stage where
+	// a part of instances of the group are replaced with the new image.
+	StageVMCanaryRollout Stage = "VM_CANARY_ROLLOUT"
+	// StageVMPrimaryRollout represents the stage where
+	// the rest of instances of the group are replaced with the new image.
+	StageVMPrimaryRollout Stage = "VM_PRIMARY_ROLLOUT"
+	// StageVMCanaryClean represents the stage where
+	// the instances of the CANARY group are removed.
+	StageVMCanaryClean Stage = "VM_CANARY_CLEAN"
+
+	// StagePulumiSync updates the stack with the program defined in Git.
+	// Firstly, it does preview and if there are any changes detected it updates the stack automatically.
+	StagePulumiSync Stage = "PULUMI_SYNC"
+	// StagePulumiPreview shows the changes to the stack.
+	StagePulumiPreview Stage = "PULUMI_PREVIEW"
+	// StagePulumiUp represents the state where
+	// the stack has been updated with the new program.
+	StagePulumiUp Stage = "PULUMI_UP"
+
+	// StageAnsibleSync runs the playbook against the hosts of the inventory.
+	StageAnsibleSync Stage = "ANSIBLE_SYNC"
+	// StageAnsibleCheck runs the playbook in check mode
+	// to show the changes would be made without making them.
+	StageAnsibleCheck Stage = "ANSIBLE_CHECK"
+
 	// StageRollback represents a state where
 	// the all temporarily created stages will be reverted to
 	// bring back the pre-deploy stage.
@@ -100,6 +156,24 @@ const (
 	StageRollback Stage = "ROLLBACK"
 )
 
+// PluginStagePrefix is the required prefix of the names of stages
+// those are provided by piped plugins instead of the built-in executors.
+const PluginStagePrefix = "PLUGIN_"
+
+const (
+	// StageMetadataKeyApprovalCommentRequired is the metadata key of a WAIT_APPROVAL stage
+	// telling that a comment must be given while approving or rejecting it.
+	StageMetadataKeyApprovalCommentRequired = "ApprovalCommentRequired"
+	// StageMetadataKeyApprovedBy is the metadata key of a WAIT_APPROVAL stage
+	// holding the name of the user who approved it.
+	StageMetadataKeyApprovedBy = "ApprovedBy"
+)
+
 func (s Stage) String() string {
 	return string(s)
 }
+
+// IsPlugin reports whether the stage is provided by a piped plugin.
+func (s Stage) IsPlugin() bool {
+	return strings.HasPrefix(string(s), PluginStagePrefix) && len(s) > len(PluginStagePrefix)
+}
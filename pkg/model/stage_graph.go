@@ -0,0 +1,71 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+// MakeStageGraph builds the stage graph of the given deployment.
+// The level of each stage is computed from its required stages,
+// and the rollback stages are placed after all of the other stages.
+func MakeStageGraph(d *Deployment) *StageGraph {
+	g := &StageGraph{
+		DeploymentId: d.Id,
+		Status:       d.Status,
+		Nodes:        make([]*StageGraphNode, 0, len(d.Stages)),
+		CreatedAt:    d.CreatedAt,
+		CompletedAt:  d.CompletedAt,
+	}
+
+	var (
+		levels    = make(map[string]int32, len(d.Stages))
+		maxLevel  = int32(-1)
+		rollbacks = make([]*StageGraphNode, 0)
+	)
+	for _, s := range d.Stages {
+		n := &StageGraphNode{
+			StageId:   s.Id,
+			Name:      s.Name,
+			Desc:      s.Desc,
+			Requires:  s.Requires,
+			Rollback:  s.Name == StageRollback.String(),
+			Visible:   s.Visible,
+			Status:    s.Status,
+			StartedAt: s.StartedAt,
+		}
+		// The completed time is also reported while the stage is running
+		// so it is exposed only after the stage was completed.
+		if IsCompletedStage(s.Status) {
+			n.CompletedAt = s.CompletedAt
+		}
+		g.Nodes = append(g.Nodes, n)
+
+		if n.Rollback && len(s.Requires) == 0 {
+			rollbacks = append(rollbacks, n)
+			continue
+		}
+		for _, r := range s.Requires {
+			if l, ok := levels[r]; ok && l+1 > n.Level {
+				n.Level = l + 1
+			}
+		}
+		levels[s.Id] = n.Level
+		if n.Level > maxLevel {
+			maxLevel = n.Level
+		}
+	}
+
+	for _, n := range rollbacks {
+		n.Level = maxLevel + 1
+	}
+	return g
+}
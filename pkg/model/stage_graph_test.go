@@ -0,0 +1,187 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeStageGraph(t *testing.T) {
+	testcases := []struct {
+		name       string
+		deployment *Deployment
+		expected   *StageGraph
+	}{
+		{
+			name: "no stage",
+			deployment: &Deployment{
+				Id:        "deployment-id",
+				Status:    DeploymentStatus_DEPLOYMENT_PLANNED,
+				CreatedAt: 100,
+			},
+			expected: &StageGraph{
+				DeploymentId: "deployment-id",
+				Status:       DeploymentStatus_DEPLOYMENT_PLANNED,
+				Nodes:        []*StageGraphNode{},
+				CreatedAt:    100,
+			},
+		},
+		{
+			name: "sequential stages with rollback",
+			deployment: &Deployment{
+				Id:        "deployment-id",
+				Status:    DeploymentStatus_DEPLOYMENT_RUNNING,
+				CreatedAt: 100,
+				Stages: []*PipelineStage{
+					{
+						Id:          "stage-0",
+						Name:        "K8S_CANARY_ROLLOUT",
+						Visible:     true,
+						Status:      StageStatus_STAGE_SUCCESS,
+						StartedAt:   110,
+						CompletedAt: 120,
+					},
+					{
+						Id:          "stage-1",
+						Name:        "WAIT_APPROVAL",
+						Requires:    []string{"stage-0"},
+						Visible:     true,
+						Status:      StageStatus_STAGE_RUNNING,
+						StartedAt:   120,
+						CompletedAt: 125,
+					},
+					{
+						Id:       "stage-2",
+						Name:     "K8S_PRIMARY_ROLLOUT",
+						Requires: []string{"stage-1"},
+						Visible:  true,
+						Status:   StageStatus_STAGE_NOT_STARTED_YET,
+					},
+					{
+						Id:     "stage-rollback",
+						Name:   "ROLLBACK",
+						Status: StageStatus_STAGE_NOT_STARTED_YET,
+					},
+				},
+			},
+			expected: &StageGraph{
+				DeploymentId: "deployment-id",
+				Status:       DeploymentStatus_DEPLOYMENT_RUNNING,
+				CreatedAt:    100,
+				Nodes: []*StageGraphNode{
+					{
+						StageId:     "stage-0",
+						Name:        "K8S_CANARY_ROLLOUT",
+						Visible:     true,
+						Status:      StageStatus_STAGE_SUCCESS,
+						StartedAt:   110,
+						CompletedAt: 120,
+					},
+					{
+						StageId:   "stage-1",
+						Name:      "WAIT_APPROVAL",
+						Requires:  []string{"stage-0"},
+						Level:     1,
+						Visible:   true,
+						Status:    StageStatus_STAGE_RUNNING,
+						StartedAt: 120,
+					},
+					{
+						StageId:  "stage-2",
+						Name:     "K8S_PRIMARY_ROLLOUT",
+						Requires: []string{"stage-1"},
+						Level:    2,
+						Visible:  true,
+						Status:   StageStatus_STAGE_NOT_STARTED_YET,
+					},
+					{
+						StageId:  "stage-rollback",
+						Name:     "ROLLBACK",
+						Level:    3,
+						Rollback: true,
+						Status:   StageStatus_STAGE_NOT_STARTED_YET,
+					},
+				},
+			},
+		},
+		{
+			name: "parallel stages",
+			deployment: &Deployment{
+				Id:        "deployment-id",
+				Status:    DeploymentStatus_DEPLOYMENT_SUCCESS,
+				CreatedAt: 100,
+				Stages: []*PipelineStage{
+					{
+						Id:   "stage-0",
+						Name: "K8S_SYNC",
+					},
+					{
+						Id:       "stage-1",
+						Name:     "ANALYSIS",
+						Requires: []string{"stage-0"},
+					},
+					{
+						Id:       "stage-2",
+						Name:     "WAIT",
+						Requires: []string{"stage-0"},
+					},
+					{
+						Id:       "stage-3",
+						Name:     "K8S_TRAFFIC_ROUTING",
+						Requires: []string{"stage-1", "stage-2"},
+					},
+				},
+			},
+			expected: &StageGraph{
+				DeploymentId: "deployment-id",
+				Status:       DeploymentStatus_DEPLOYMENT_SUCCESS,
+				CreatedAt:    100,
+				Nodes: []*StageGraphNode{
+					{
+						StageId: "stage-0",
+						Name:    "K8S_SYNC",
+					},
+					{
+						StageId:  "stage-1",
+						Name:     "ANALYSIS",
+						Requires: []string{"stage-0"},
+						Level:    1,
+					},
+					{
+						StageId:  "stage-2",
+						Name:     "WAIT",
+						Requires: []string{"stage-0"},
+						Level:    1,
+					},
+					{
+						StageId:  "stage-3",
+						Name:     "K8S_TRAFFIC_ROUTING",
+						Requires: []string{"stage-1", "stage-2"},
+						Level:    2,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := MakeStageGraph(tc.deployment)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
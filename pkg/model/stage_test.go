@@ -0,0 +1,46 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStageIsPlugin(t *testing.T) {
+	testcases := []struct {
+		stage    Stage
+		expected bool
+	}{
+		{
+			stage:    StageK8sSync,
+			expected: false,
+		},
+		{
+			stage:    "PLUGIN_",
+			expected: false,
+		},
+		{
+			stage:    "PLUGIN_NOMAD_DEPLOY",
+			expected: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.stage.String(), func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.stage.IsPlugin())
+		})
+	}
+}
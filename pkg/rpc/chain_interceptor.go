@@ -37,3 +37,23 @@ func ChainUnaryServerInterceptors(is ...grpc.UnaryServerInterceptor) grpc.UnaryS
 		return next(ctx, req)
 	}
 }
+
+// ChainStreamServerInterceptors chains multiple stream interceptors into one.
+// The first interceptor will be the outer most.
+func ChainStreamServerInterceptors(is ...grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	if len(is) == 1 {
+		return is[0]
+	}
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		chain := func(interceptor grpc.StreamServerInterceptor, next grpc.StreamHandler) grpc.StreamHandler {
+			return func(srv interface{}, stream grpc.ServerStream) error {
+				return interceptor(srv, stream, info, next)
+			}
+		}
+		next := handler
+		for i := len(is) - 1; i >= 0; i-- {
+			next = chain(is[i], next)
+		}
+		return next(srv, stream)
+	}
+}
@@ -58,3 +58,53 @@ func TestChainUnaryServerInterceptors(t *testing.T) {
 	assert.True(t, secondRun)
 	assert.True(t, handlerRun)
 }
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestChainStreamServerInterceptors(t *testing.T) {
+	type parentKey string
+	parent := parentKey("parent")
+	stream := &fakeServerStream{
+		ctx: context.WithValue(context.Background(), parent, ""),
+	}
+	serverInfo := &grpc.StreamServerInfo{
+		FullMethod: "service.test",
+	}
+	var firstRun, secondRun, handlerRun bool
+	first := func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		require.Equal(t, serverInfo, info)
+		require.Equal(t, "", stream.Context().Value(parent).(string))
+		firstRun = true
+		return handler(srv, &fakeServerStream{
+			ServerStream: stream,
+			ctx:          context.WithValue(stream.Context(), parent, "first"),
+		})
+	}
+	second := func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		require.Equal(t, serverInfo, info)
+		require.Equal(t, "first", stream.Context().Value(parent).(string))
+		secondRun = true
+		return handler(srv, &fakeServerStream{
+			ServerStream: stream,
+			ctx:          context.WithValue(stream.Context(), parent, "second"),
+		})
+	}
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		require.Equal(t, "second", stream.Context().Value(parent).(string))
+		handlerRun = true
+		return nil
+	}
+	interceptors := ChainStreamServerInterceptors(first, second)
+	err := interceptors(nil, stream, serverInfo, handler)
+	assert.NoError(t, err)
+	assert.True(t, firstRun)
+	assert.True(t, secondRun)
+	assert.True(t, handlerRun)
+}
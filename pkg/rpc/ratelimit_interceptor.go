@@ -0,0 +1,266 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"context"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipe/pkg/rpc/rpcauth"
+)
+
+const (
+	limiterKey = "limiter"
+	methodKey  = "grpc_method"
+
+	limiterAPIKey = "api_key"
+	limiterIP     = "ip"
+
+	// How often the buckets which have been refilled completely are removed.
+	bucketCleanupInterval = time.Minute
+)
+
+var (
+	rateLimitedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pipecd_rpc_rate_limited_total",
+			Help: "Number of requests rejected by the rate limit",
+		},
+		[]string{
+			limiterKey,
+			methodKey,
+		},
+	)
+)
+
+// RegisterMetrics registers the metrics of the rate limit interceptors.
+func RegisterMetrics(r prometheus.Registerer) {
+	r.MustRegister(rateLimitedCounter)
+}
+
+// APIKeyRateLimitUnaryServerInterceptor limits the rate of requests made with each API key.
+// This must be placed after the interceptor verifying the API key.
+func APIKeyRateLimitUnaryServerInterceptor(rps float64, burst int, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	limiter := newKeyedLimiter(rps, burst)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		key, err := rpcauth.ExtractAPIKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !limiter.Allow(key.Id) {
+			logger.Info("rejected a request exceeding the rate limit of API key",
+				zap.String("method", info.FullMethod),
+				zap.String("api-key-id", key.Id),
+			)
+			return nil, rateLimitedError(limiterAPIKey, info.FullMethod)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// IPRateLimitUnaryServerInterceptor limits the rate of requests made from each client IP address.
+// X-Forwarded-For header is taken into account only when the request was sent
+// from the loopback address, i.e. the API gateway, or one of the given trusted proxies
+// which are specified as IP addresses or CIDR ranges.
+func IPRateLimitUnaryServerInterceptor(rps float64, burst int, trustedProxies []string, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	limiter := newKeyedLimiter(rps, burst)
+	trusted := ParseTrustedProxies(trustedProxies)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ip := clientIP(ctx, trusted)
+		if ip == "" {
+			return handler(ctx, req)
+		}
+		if !limiter.Allow(ip) {
+			logger.Info("rejected a request exceeding the rate limit of IP address",
+				zap.String("method", info.FullMethod),
+				zap.String("ip", ip),
+			)
+			return nil, rateLimitedError(limiterIP, info.FullMethod)
+		}
+		return handler(ctx, req)
+	}
+}
+
+func rateLimitedError(limiter, method string) error {
+	rateLimitedCounter.With(prometheus.Labels{
+		limiterKey: limiter,
+		methodKey:  method,
+	}).Inc()
+	return status.Error(codes.ResourceExhausted, "Too many requests, please retry later")
+}
+
+// clientIP returns the IP address of the client who made the request
+// by resolving the address of the peer with X-Forwarded-For header.
+func clientIP(ctx context.Context, trusted []*net.IPNet) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	var forwardedFor []string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		forwardedFor = md.Get("x-forwarded-for")
+	}
+	return ResolveClientIP(p.Addr.String(), forwardedFor, trusted)
+}
+
+// ResolveClientIP returns the IP address of the client from the address of the peer
+// and the values of X-Forwarded-For header.
+// The address of the peer is used unless it is a trusted proxy. In that case,
+// X-Forwarded-For header is walked from the nearest hop and the first address
+// which is not a trusted proxy is returned since the farther ones can be
+// set by anyone.
+func ResolveClientIP(peerAddr string, forwardedFor []string, trusted []*net.IPNet) string {
+	ip, _, err := net.SplitHostPort(peerAddr)
+	if err != nil {
+		ip = peerAddr
+	}
+	if !isTrustedProxy(ip, trusted) {
+		return ip
+	}
+
+	var hops []string
+	for _, v := range forwardedFor {
+		for _, h := range strings.Split(v, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				hops = append(hops, h)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip = hops[i]
+		if !isTrustedProxy(ip, trusted) {
+			break
+		}
+	}
+	return ip
+}
+
+func isTrustedProxy(ip string, trusted []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	if parsed.IsLoopback() {
+		return true
+	}
+	for _, n := range trusted {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTrustedProxies converts the given IP addresses and CIDR ranges into networks.
+// The invalid ones are ignored since they are rejected while validating the configuration.
+func ParseTrustedProxies(proxies []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if ip := net.ParseIP(p); ip != nil {
+			bits := 8 * net.IPv6len
+			if v4 := ip.To4(); v4 != nil {
+				ip, bits = v4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		if _, n, err := net.ParseCIDR(p); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
+
+// keyedLimiter limits the rate of events for each key separately
+// by using the token bucket algorithm.
+type keyedLimiter struct {
+	rate        float64
+	burst       float64
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+	nowFunc     func() time.Time
+	mu          sync.Mutex
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newKeyedLimiter(rps float64, burst int) *keyedLimiter {
+	b := float64(burst)
+	if b <= 0 {
+		b = math.Max(1, math.Ceil(rps))
+	}
+	return &keyedLimiter{
+		rate:        rps,
+		burst:       b,
+		buckets:     make(map[string]*tokenBucket),
+		lastCleanup: time.Now(),
+		nowFunc:     time.Now,
+	}
+}
+
+// Allow reports whether an event for the given key may happen now.
+// A token of the key is consumed when it is allowed.
+func (l *keyedLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.nowFunc()
+	if now.Sub(l.lastCleanup) >= bucketCleanupInterval {
+		l.cleanup(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{
+			tokens: l.burst,
+			last:   now,
+		}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// cleanup removes the buckets which have been refilled completely
+// since they are equivalent to the newly created ones.
+func (l *keyedLimiter) cleanup(now time.Time) {
+	for k, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, k)
+		}
+	}
+	l.lastCleanup = now
+}
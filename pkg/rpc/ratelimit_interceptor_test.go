@@ -0,0 +1,150 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestKeyedLimiter(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newKeyedLimiter(2, 3)
+	l.lastCleanup = now
+	l.nowFunc = func() time.Time { return now }
+
+	// The burst is allowed at once.
+	for i := 0; i < 3; i++ {
+		assert.True(t, l.Allow("key-1"))
+	}
+	assert.False(t, l.Allow("key-1"))
+
+	// The other keys are limited separately.
+	assert.True(t, l.Allow("key-2"))
+
+	// Two tokens are refilled per second.
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, l.Allow("key-1"))
+	assert.False(t, l.Allow("key-1"))
+
+	now = now.Add(10 * time.Second)
+	for i := 0; i < 3; i++ {
+		assert.True(t, l.Allow("key-1"))
+	}
+	assert.False(t, l.Allow("key-1"))
+
+	// The refilled buckets are removed while cleaning up.
+	now = now.Add(bucketCleanupInterval)
+	assert.True(t, l.Allow("key-1"))
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestNewKeyedLimiterDefaultBurst(t *testing.T) {
+	testcases := []struct {
+		name     string
+		rps      float64
+		burst    int
+		expected float64
+	}{
+		{
+			name:     "specified burst",
+			rps:      1,
+			burst:    5,
+			expected: 5,
+		},
+		{
+			name:     "rounded up rps",
+			rps:      2.5,
+			expected: 3,
+		},
+		{
+			name:     "at least one",
+			rps:      0.1,
+			expected: 1,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newKeyedLimiter(tc.rps, tc.burst)
+			assert.Equal(t, tc.expected, l.burst)
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := ParseTrustedProxies([]string{"10.0.0.1", "172.16.0.0/12"})
+	withPeer := func(ip string, xff ...string) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 12345},
+		})
+		if len(xff) > 0 {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", strings.Join(xff, ", ")))
+		}
+		return ctx
+	}
+	testcases := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+	}{
+		{
+			name:     "no client info",
+			ctx:      context.Background(),
+			expected: "",
+		},
+		{
+			name:     "peer address",
+			ctx:      withPeer("192.168.0.1"),
+			expected: "192.168.0.1",
+		},
+		{
+			name:     "forwarded address from untrusted peer is ignored",
+			ctx:      withPeer("192.168.0.1", "192.168.0.2"),
+			expected: "192.168.0.1",
+		},
+		{
+			name:     "forwarded address from gateway",
+			ctx:      withPeer("127.0.0.1", "192.168.0.1"),
+			expected: "192.168.0.1",
+		},
+		{
+			name:     "forwarded address from trusted proxy",
+			ctx:      withPeer("10.0.0.1", "192.168.0.1"),
+			expected: "192.168.0.1",
+		},
+		{
+			name:     "spoofed address before untrusted hop is ignored",
+			ctx:      withPeer("172.16.0.1", "1.2.3.4", "192.168.0.1", "172.16.0.2"),
+			expected: "192.168.0.1",
+		},
+		{
+			name:     "trusted peer without forwarded address",
+			ctx:      withPeer("10.0.0.1"),
+			expected: "10.0.0.1",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, clientIP(tc.ctx, trusted))
+		})
+	}
+}
@@ -21,6 +21,8 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 
 	"github.com/pipe-cd/pipe/pkg/jwt"
@@ -132,6 +134,62 @@ func PipedTokenStreamServerInterceptor(verifier PipedTokenVerifier, logger *zap.
 	}
 }
 
+// PipedCertificateUnaryServerInterceptor ensures that the piped is connecting
+// with a verified client certificate issued for that piped.
+// The common name of the certificate must be the piped ID.
+// This must be chained after PipedTokenUnaryServerInterceptor.
+func PipedCertificateUnaryServerInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		_, pipedID, _, err := ExtractPipedToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyPipedCertificate(ctx, pipedID); err != nil {
+			logger.Warn("unable to verify piped certificate", zap.String("piped-id", pipedID), zap.Error(err))
+			return nil, errUnauthenticated
+		}
+		return handler(ctx, req)
+	}
+}
+
+// PipedCertificateStreamServerInterceptor ensures that the piped is connecting
+// with a verified client certificate issued for that piped.
+// The common name of the certificate must be the piped ID.
+// This must be chained after PipedTokenStreamServerInterceptor.
+func PipedCertificateStreamServerInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := stream.Context()
+		_, pipedID, _, err := ExtractPipedToken(ctx)
+		if err != nil {
+			return err
+		}
+		if err := verifyPipedCertificate(ctx, pipedID); err != nil {
+			logger.Warn("unable to verify piped certificate", zap.String("piped-id", pipedID), zap.Error(err))
+			return errUnauthenticated
+		}
+		return handler(srv, stream)
+	}
+}
+
+func verifyPipedCertificate(ctx context.Context, pipedID string) error {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("no peer information was found")
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return fmt.Errorf("the connection is not using TLS")
+	}
+	chains := info.State.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return fmt.Errorf("no verified client certificate was found")
+	}
+	if cn := chains[0][0].Subject.CommonName; cn != pipedID {
+		return fmt.Errorf("the client certificate was issued for another piped %s", cn)
+	}
+	return nil
+}
+
 // ExtractPipedToken returns the verified piped key inside a given context.
 func ExtractPipedToken(ctx context.Context) (projectID, pipedID, pipedKey string, err error) {
 	v, ok := ctx.Value(pipedTokenKey).(pipedTokenContextValue)
@@ -16,6 +16,9 @@ package rpcauth
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
 	"fmt"
 	"testing"
@@ -24,7 +27,9 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 
 	"github.com/pipe-cd/pipe/pkg/model"
 )
@@ -106,6 +111,117 @@ func TestPipedTokenUnaryServerInterceptor(t *testing.T) {
 	}
 }
 
+func TestPipedCertificateUnaryServerInterceptor(t *testing.T) {
+	in := PipedCertificateUnaryServerInterceptor(zap.NewNop())
+	tokenCtx := context.WithValue(context.Background(), pipedTokenKey, pipedTokenContextValue{
+		ProjectID: "test-project-id",
+		PipedID:   "test-piped-id",
+		PipedKey:  "test-piped-key",
+	})
+	withCert := func(cn string) context.Context {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+		return peer.NewContext(tokenCtx, &peer.Peer{
+			AuthInfo: credentials.TLSInfo{
+				State: tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{{cert}},
+				},
+			},
+		})
+	}
+
+	testcases := []struct {
+		name   string
+		ctx    context.Context
+		failed bool
+	}{
+		{
+			name:   "missing piped token",
+			ctx:    peer.NewContext(context.Background(), &peer.Peer{}),
+			failed: true,
+		},
+		{
+			name:   "missing client certificate",
+			ctx:    peer.NewContext(tokenCtx, &peer.Peer{AuthInfo: credentials.TLSInfo{}}),
+			failed: true,
+		},
+		{
+			name:   "certificate issued for another piped",
+			ctx:    withCert("another-piped-id"),
+			failed: true,
+		},
+		{
+			name:   "should be ok with the certificate of the piped",
+			ctx:    withCert("test-piped-id"),
+			failed: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := in(tc.ctx, nil, nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, nil
+			})
+			assert.Equal(t, tc.failed, err != nil)
+		})
+	}
+}
+
+func TestPipedCertificateStreamServerInterceptor(t *testing.T) {
+	in := PipedCertificateStreamServerInterceptor(zap.NewNop())
+	tokenCtx := context.WithValue(context.Background(), pipedTokenKey, pipedTokenContextValue{
+		ProjectID: "test-project-id",
+		PipedID:   "test-piped-id",
+		PipedKey:  "test-piped-key",
+	})
+	withCert := func(cn string) context.Context {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+		return peer.NewContext(tokenCtx, &peer.Peer{
+			AuthInfo: credentials.TLSInfo{
+				State: tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{{cert}},
+				},
+			},
+		})
+	}
+
+	testcases := []struct {
+		name   string
+		ctx    context.Context
+		failed bool
+	}{
+		{
+			name:   "missing piped token",
+			ctx:    peer.NewContext(context.Background(), &peer.Peer{}),
+			failed: true,
+		},
+		{
+			name:   "missing client certificate",
+			ctx:    peer.NewContext(tokenCtx, &peer.Peer{AuthInfo: credentials.TLSInfo{}}),
+			failed: true,
+		},
+		{
+			name:   "certificate issued for another piped",
+			ctx:    withCert("another-piped-id"),
+			failed: true,
+		},
+		{
+			name:   "should be ok with the certificate of the piped",
+			ctx:    withCert("test-piped-id"),
+			failed: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			stream := &fakeServerStream{
+				ctx: tc.ctx,
+			}
+			err := in(nil, stream, nil, func(srv interface{}, stream grpc.ServerStream) error {
+				return nil
+			})
+			assert.Equal(t, tc.failed, err != nil)
+		})
+	}
+}
+
 func TestPipedTokenStreamServerInterceptor(t *testing.T) {
 	verifier := testPipedTokenVerifier{"test-piped-key"}
 	in := PipedTokenStreamServerInterceptor(verifier, zap.NewNop())
@@ -16,8 +16,12 @@ package rpc
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"strconv"
 	"time"
 
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
@@ -37,10 +41,12 @@ type Service interface {
 
 // Server used to register gRPC services then start and serve incoming requests.
 type Server struct {
+	host                 string
 	port                 int
 	tls                  bool
 	certFile             string
 	keyFile              string
+	clientCAFile         string
 	services             []Service
 	grpcServer           *grpc.Server
 	gracePeriod          time.Duration
@@ -49,7 +55,11 @@ type Server struct {
 
 	pipedKeyAuthUnaryInterceptor      grpc.UnaryServerInterceptor
 	pipedKeyAuthStreamInterceptor     grpc.StreamServerInterceptor
+	pipedCertAuthUnaryInterceptor     grpc.UnaryServerInterceptor
+	pipedCertAuthStreamInterceptor    grpc.StreamServerInterceptor
 	apiKeyAuthUnaryInterceptor        grpc.UnaryServerInterceptor
+	apiKeyRateLimitUnaryInterceptor   grpc.UnaryServerInterceptor
+	ipRateLimitUnaryInterceptor       grpc.UnaryServerInterceptor
 	jwtAuthUnaryInterceptor           grpc.UnaryServerInterceptor
 	requestValidationUnaryInterceptor grpc.UnaryServerInterceptor
 	logUnaryInterceptor               grpc.UnaryServerInterceptor
@@ -66,6 +76,14 @@ func WithPort(port int) Option {
 	}
 }
 
+// WithHost sets the host address to listen on.
+// The server listens on all interfaces when it is not specified.
+func WithHost(host string) Option {
+	return func(s *Server) {
+		s.host = host
+	}
+}
+
 // WithPipedTokenAuthUnaryInterceptor sets an interceptor for validating piped key.
 func WithPipedTokenAuthUnaryInterceptor(verifier rpcauth.PipedTokenVerifier, logger *zap.Logger) Option {
 	return func(s *Server) {
@@ -80,6 +98,23 @@ func WithPipedTokenAuthStreamInterceptor(verifier rpcauth.PipedTokenVerifier, lo
 	}
 }
 
+// WithPipedCertificateAuthUnaryInterceptor sets an interceptor for validating
+// the client certificate of piped. This requires both WithTLS and WithClientCA.
+func WithPipedCertificateAuthUnaryInterceptor(logger *zap.Logger) Option {
+	return func(s *Server) {
+		s.pipedCertAuthUnaryInterceptor = rpcauth.PipedCertificateUnaryServerInterceptor(logger)
+	}
+}
+
+// WithPipedCertificateAuthStreamInterceptor sets an interceptor for validating
+// the client certificate of piped on streaming RPCs.
+// This requires WithPipedTokenAuthStreamInterceptor.
+func WithPipedCertificateAuthStreamInterceptor(logger *zap.Logger) Option {
+	return func(s *Server) {
+		s.pipedCertAuthStreamInterceptor = rpcauth.PipedCertificateStreamServerInterceptor(logger)
+	}
+}
+
 // WithAPIKeyAuthUnaryInterceptor sets an interceptor for validating API key.
 func WithAPIKeyAuthUnaryInterceptor(verifier rpcauth.APIKeyVerifier, logger *zap.Logger) Option {
 	return func(s *Server) {
@@ -87,6 +122,25 @@ func WithAPIKeyAuthUnaryInterceptor(verifier rpcauth.APIKeyVerifier, logger *zap
 	}
 }
 
+// WithAPIKeyRateLimitUnaryInterceptor sets an interceptor for limiting the rate of requests per API key.
+// This requires WithAPIKeyAuthUnaryInterceptor.
+// The servers given the same option share the limit.
+func WithAPIKeyRateLimitUnaryInterceptor(rps float64, burst int, logger *zap.Logger) Option {
+	interceptor := APIKeyRateLimitUnaryServerInterceptor(rps, burst, logger)
+	return func(s *Server) {
+		s.apiKeyRateLimitUnaryInterceptor = interceptor
+	}
+}
+
+// WithIPRateLimitUnaryInterceptor sets an interceptor for limiting the rate of requests per client IP address.
+// The servers given the same option share the limit.
+func WithIPRateLimitUnaryInterceptor(rps float64, burst int, trustedProxies []string, logger *zap.Logger) Option {
+	interceptor := IPRateLimitUnaryServerInterceptor(rps, burst, trustedProxies, logger)
+	return func(s *Server) {
+		s.ipRateLimitUnaryInterceptor = interceptor
+	}
+}
+
 // WithJWTAuthUnaryInterceptor sets an interceprot for checking JWT token.
 func WithJWTAuthUnaryInterceptor(verifier jwt.Verifier, authorizer rpcauth.RBACAuthorizer, logger *zap.Logger) Option {
 	return func(s *Server) {
@@ -124,6 +178,15 @@ func WithTLS(certFile, keyFile string) Option {
 	}
 }
 
+// WithClientCA requires the clients to present a certificate
+// signed by one of the CA certificates in the given file.
+// This takes effect only when TLS is enabled.
+func WithClientCA(caFile string) Option {
+	return func(s *Server) {
+		s.clientCAFile = caFile
+	}
+}
+
 // WithService appends gPRC service to server.
 func WithService(service Service) Option {
 	return func(s *Server) {
@@ -171,6 +234,28 @@ func NewServer(service Service, opts ...Option) *Server {
 	return s
 }
 
+// mutualTLSConfig builds the TLS configuration requiring and verifying
+// the client certificates signed by the configured CA.
+func (s *Server) mutualTLSConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tls certificate file: %v", err)
+	}
+	ca, err := ioutil.ReadFile(s.clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client ca file: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate was found in client ca file")
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil
+}
+
 // Run starts running gRPC server for handling incoming requests.
 func (s *Server) Run(ctx context.Context) error {
 	doneCh := make(chan error, 1)
@@ -191,7 +276,13 @@ func (s *Server) init() error {
 
 	// If tls option is enabled we load and use certificate and
 	// key files from specified paths.
-	if s.tls {
+	if s.tls && s.clientCAFile != "" {
+		config, err := s.mutualTLSConfig()
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	} else if s.tls {
 		creds, err := credentials.NewServerTLSFromFile(s.certFile, s.keyFile)
 		if err != nil {
 			return fmt.Errorf("failed to load tls certificate file: %v", err)
@@ -205,12 +296,23 @@ func (s *Server) init() error {
 	if s.logUnaryInterceptor != nil {
 		unaryInterceptors = append(unaryInterceptors, s.logUnaryInterceptor)
 	}
+	// The requests are limited per IP address before authenticating
+	// to protect the server from the unauthenticated ones too.
+	if s.ipRateLimitUnaryInterceptor != nil {
+		unaryInterceptors = append(unaryInterceptors, s.ipRateLimitUnaryInterceptor)
+	}
 	if s.pipedKeyAuthUnaryInterceptor != nil {
 		unaryInterceptors = append(unaryInterceptors, s.pipedKeyAuthUnaryInterceptor)
 	}
+	if s.pipedCertAuthUnaryInterceptor != nil {
+		unaryInterceptors = append(unaryInterceptors, s.pipedCertAuthUnaryInterceptor)
+	}
 	if s.apiKeyAuthUnaryInterceptor != nil {
 		unaryInterceptors = append(unaryInterceptors, s.apiKeyAuthUnaryInterceptor)
 	}
+	if s.apiKeyRateLimitUnaryInterceptor != nil {
+		unaryInterceptors = append(unaryInterceptors, s.apiKeyRateLimitUnaryInterceptor)
+	}
 	if s.jwtAuthUnaryInterceptor != nil {
 		unaryInterceptors = append(unaryInterceptors, s.jwtAuthUnaryInterceptor)
 	}
@@ -224,8 +326,16 @@ func (s *Server) init() error {
 		c := ChainUnaryServerInterceptors(unaryInterceptors...)
 		opts = append(opts, grpc.UnaryInterceptor(c))
 	}
+	var streamInterceptors []grpc.StreamServerInterceptor
 	if s.pipedKeyAuthStreamInterceptor != nil {
-		opts = append(opts, grpc.StreamInterceptor(s.pipedKeyAuthStreamInterceptor))
+		streamInterceptors = append(streamInterceptors, s.pipedKeyAuthStreamInterceptor)
+	}
+	if s.pipedCertAuthStreamInterceptor != nil {
+		streamInterceptors = append(streamInterceptors, s.pipedCertAuthStreamInterceptor)
+	}
+	if len(streamInterceptors) > 0 {
+		c := ChainStreamServerInterceptors(streamInterceptors...)
+		opts = append(opts, grpc.StreamInterceptor(c))
 	}
 	s.grpcServer = grpc.NewServer(opts...)
 
@@ -246,7 +356,7 @@ func (s *Server) init() error {
 
 func (s *Server) run() error {
 	// Start listening at the specified port.
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	lis, err := net.Listen("tcp", net.JoinHostPort(s.host, strconv.Itoa(s.port)))
 	if err != nil {
 		s.logger.Error("failed to listen", zap.Error(err))
 		return err